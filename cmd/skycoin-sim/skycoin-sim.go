@@ -0,0 +1,176 @@
+/*
+skycoin-sim runs a network of skycoin nodes in a single process
+
+The nodes are connected over an in-memory network and share a simulated clock,
+so that protocol changes can be tried on a network deterministically, without Docker or real machines.
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/daemon"
+	"github.com/skycoin/skycoin/src/util/logging"
+)
+
+var help = `skycoin-sim runs a network of skycoin nodes in a single process.
+
+The first node is the block publisher. For each block, a transaction is injected on the last node,
+and once it reaches the block publisher, the simulated clock is advanced by the block interval and a block
+is published. The time the block takes to reach each node is printed.
+
+The topology of the network is one of:
+  line: each node connects to the previous node, so the blocks go through every node
+  star: each node connects to the block publisher
+  mesh: each node connects to all the previous nodes`
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "%s\n\nUsage of %s:\n", help, os.Args[0])
+		flag.PrintDefaults()
+	}
+}
+
+func main() {
+	nodes := flag.Int("nodes", 4, "number of nodes")
+	blocks := flag.Int("blocks", 10, "number of blocks to publish")
+	topology := flag.String("topology", "line", "topology of the network: line, star or mesh")
+	interval := flag.Duration("interval", time.Second*10, "time between the blocks on the simulated clock")
+	timeout := flag.Duration("timeout", time.Second*30, "how long to wait for each block to reach all the nodes")
+	seed := flag.String("seed", "skycoin-sim", "seed of the blockchain keys")
+	dir := flag.String("dir", "", "directory of the node databases. Defaults to a temporary directory, removed on exit")
+	disableCompactBlocks := flag.Bool("disable-compact-blocks", false, "send the blocks in full instead of as compact blocks")
+	logLevel := flag.String("log-level", "fatal", "log level of the nodes")
+
+	flag.Parse()
+
+	if err := run(*nodes, *blocks, *topology, *interval, *timeout, *seed, *dir, *disableCompactBlocks, *logLevel); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(nodes, blocks int, topology string, interval, timeout time.Duration, seed, dir string, disableCompactBlocks bool, logLevel string) error {
+	level, err := logging.LevelFromString(logLevel)
+	if err != nil {
+		return err
+	}
+	logging.SetLevel(level)
+
+	if dir == "" {
+		dir, err = ioutil.TempDir("", "skycoin-sim")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+	}
+
+	c := daemon.NewSimulationConfig()
+	c.Nodes = nodes
+	c.DataDirectory = dir
+	c.Seed = seed
+	c.Configure = func(i int, c *daemon.Config) {
+		c.Daemon.DisableCompactBlocks = disableCompactBlocks
+	}
+
+	s, err := daemon.NewSimulation(c)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Start(); err != nil {
+		s.Stop()
+		return err
+	}
+	defer s.Stop()
+
+	if err := connect(s, topology); err != nil {
+		return err
+	}
+
+	if err := s.WaitForConnections(timeout); err != nil {
+		return fmt.Errorf("Connecting the nodes failed: %v", err)
+	}
+
+	fmt.Printf("%d nodes connected in a %s\n", nodes, topology)
+
+	pk, _ := cipher.MustGenerateDeterministicKeyPair([]byte(seed + "-recipient"))
+	to := cipher.AddressFromPubKey(pk)
+	for n := 1; n <= blocks; n++ {
+		txn, err := s.Spend(nodes-1, to, 1e6)
+		if err != nil {
+			return err
+		}
+
+		if err := s.WaitFor(timeout, func() bool {
+			known, err := s.Nodes[0].Gateway.GetTransaction(txn.Hash())
+			return err == nil && known != nil
+		}); err != nil {
+			return fmt.Errorf("Transaction %s did not reach the block publisher: %v", txn.Hash().Hex(), err)
+		}
+
+		sb, err := s.CreateBlock(interval)
+		if err != nil {
+			return err
+		}
+
+		start := time.Now()
+		received := make([]time.Duration, nodes)
+		if err := s.WaitFor(timeout, func() bool {
+			done := true
+			for i := range s.Nodes {
+				if received[i] != 0 {
+					continue
+				}
+
+				if seq, err := s.HeadSeq(i); err == nil && seq >= sb.Head.BkSeq {
+					received[i] = time.Since(start)
+				} else {
+					done = false
+				}
+			}
+			return done
+		}); err != nil {
+			return fmt.Errorf("Block %d did not reach all the nodes: %v", sb.Head.BkSeq, err)
+		}
+
+		fmt.Printf("block %d %s time %s\n", sb.Head.BkSeq, sb.HashHeader().Hex(), time.Unix(int64(sb.Head.Time), 0).UTC().Format(time.RFC3339))
+		for i, d := range received {
+			fmt.Printf("  node %d %s %s\n", i, s.Addr(i), d)
+		}
+	}
+
+	return nil
+}
+
+// connect connects the nodes of the simulation in a topology
+func connect(s *daemon.Simulation, topology string) error {
+	for i := 1; i < len(s.Nodes); i++ {
+		var peers []int
+		switch topology {
+		case "line":
+			peers = []int{i - 1}
+		case "star":
+			peers = []int{0}
+		case "mesh":
+			for j := 0; j < i; j++ {
+				peers = append(peers, j)
+			}
+		default:
+			return fmt.Errorf("Invalid topology %q", topology)
+		}
+
+		for _, j := range peers {
+			if err := s.Connect(i, j); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
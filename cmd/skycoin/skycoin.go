@@ -60,6 +60,14 @@ var (
 		"139.162.7.132:6000",
 	}
 
+	// Checkpoints trusted block hashes at known heights, in the format seq:hash
+	Checkpoints = []string{
+		"0:0551a1e5af999fe8fff529f6f2ab341e1e33db95135eef1b2be44fe6981349f3",
+	}
+
+	// DNSSeeds hostnames whose A and AAAA records are the IPs of peers to bootstrap from
+	DNSSeeds = []string{}
+
 	nodeConfig = skycoin.NewNodeConfig(ConfigMode, skycoin.NodeParameters{
 		CoinName:                      CoinName,
 		GenesisSignatureStr:           GenesisSignatureStr,
@@ -69,7 +77,11 @@ var (
 		BlockchainPubkeyStr:           BlockchainPubkeyStr,
 		BlockchainSeckeyStr:           BlockchainSeckeyStr,
 		DefaultConnections:            DefaultConnections,
+		Checkpoints:                   Checkpoints,
 		PeerListURL:                   "https://downloads.skycoin.net/blockchain/peers.txt",
+		DNSSeeds:                      DNSSeeds,
+		SignedPeerListURL:             "",
+		PeerListPubkeyStr:             "",
 		Port:                          6000,
 		WebInterfacePort:              6420,
 		DataDirectory:                 "$HOME/.skycoin",
@@ -34,7 +34,7 @@ func SKY_cli_CreateRawTxFromWallet(_c C.WebRpcClient__Handle, _walletFile, _chgA
 		____error_code = SKY_BAD_HANDLE
 		return
 	}
-	__arg4, ____return_err := cli.CreateRawTxFromWallet(c, walletFile, chgAddr, toAddrs, *pr)
+	__arg4, ____return_err := cli.CreateRawTxFromWallet(c, walletFile, chgAddr, toAddrs, *pr, wallet.CoinSelectionMinimizeUxOuts)
 	____error_code = libErrorCode(____return_err)
 	if ____return_err == nil {
 		*_arg4 = registerTransactionHandle(__arg4)
@@ -58,7 +58,7 @@ func SKY_cli_CreateRawTxFromAddress(_c C.WebRpcClient__Handle, _addr, _walletFil
 		____error_code = SKY_BAD_HANDLE
 		return
 	}
-	__arg4, ____return_err := cli.CreateRawTxFromAddress(c, addr, walletFile, chgAddr, toAddrs, *pr)
+	__arg4, ____return_err := cli.CreateRawTxFromAddress(c, addr, walletFile, chgAddr, toAddrs, *pr, wallet.CoinSelectionMinimizeUxOuts)
 	____error_code = libErrorCode(____return_err)
 	if ____return_err == nil {
 		*_arg4 = registerTransactionHandle(__arg4)
@@ -83,7 +83,7 @@ func SKY_cli_CreateRawTx(_c C.WebRpcClient__Handle, _wlt C.Wallet__Handle, _inAd
 	chgAddr := _chgAddr
 	toAddrs := *(*[]cli.SendAmount)(unsafe.Pointer(&_toAddrs))
 	password := *(*[]byte)(unsafe.Pointer(&_password))
-	__arg6, ____return_err := cli.CreateRawTx(c, wlt, inAddrs, chgAddr, toAddrs, password)
+	__arg6, ____return_err := cli.CreateRawTx(c, wlt, inAddrs, chgAddr, toAddrs, password, wallet.CoinSelectionMinimizeUxOuts)
 	____error_code = libErrorCode(____return_err)
 	if ____return_err == nil {
 		*_arg6 = registerTransactionHandle(__arg6)
@@ -2,9 +2,12 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 
 	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/readable"
+	"github.com/skycoin/skycoin/src/visor"
 )
 
 // VerifyAddressRequest is the request data for POST /api/v2/address/verify
@@ -60,3 +63,78 @@ func addressVerifyHandler(w http.ResponseWriter, r *http.Request) {
 		},
 	})
 }
+
+// ScanAddressesRequest is the request data for POST /api/v2/addresses/scan
+type ScanAddressesRequest struct {
+	Addresses []string `json:"addresses"`
+	// RecentTxns is the number of most recent transactions to return for each address
+	RecentTxns uint64 `json:"recent_txns"`
+}
+
+// addressesScanHandler returns the confirmed balances, unspent outputs and most recent transactions
+// of many addresses at once, read from the same head block
+// Method: POST
+// URI: /api/v2/addresses/scan
+func addressesScanHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if r.Header.Get("Content-Type") != ContentTypeJSON {
+			resp := NewHTTPErrorResponse(http.StatusUnsupportedMediaType, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		var req ScanAddressesRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if len(req.Addresses) > visor.MaxScanAddresses {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, visor.ErrScanTooManyAddresses.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		addrs := make([]cipher.Address, len(req.Addresses))
+		for i, a := range req.Addresses {
+			addr, err := cipher.DecodeBase58Address(a)
+			if err != nil {
+				resp := NewHTTPErrorResponse(http.StatusBadRequest, fmt.Sprintf("address %q is invalid: %v", a, err))
+				writeHTTPResponse(w, resp)
+				return
+			}
+			addrs[i] = addr
+		}
+
+		scans, err := gateway.ScanAddresses(addrs, req.RecentTxns)
+		if err != nil {
+			var resp HTTPResponse
+			switch err {
+			case visor.ErrScanNoAddresses, visor.ErrScanTooManyAddresses, visor.ErrScanTooManyRecentTxns:
+				resp = NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			default:
+				resp = NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			}
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		rScans, err := readable.NewAddressScans(scans)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: rScans,
+		})
+	}
+}
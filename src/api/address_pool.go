@@ -0,0 +1,219 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+// AddressPoolResponse is the response data for GET /api/v2/wallet/address-pool
+type AddressPoolResponse struct {
+	Generated uint64 `json:"generated"`
+	Used      uint64 `json:"used"`
+	Issued    uint64 `json:"issued"`
+	Unused    uint64 `json:"unused"`
+}
+
+// NewAddressPoolResponse creates an AddressPoolResponse from a wallet.AddressPoolStatus
+func NewAddressPoolResponse(s wallet.AddressPoolStatus) AddressPoolResponse {
+	return AddressPoolResponse{
+		Generated: s.Generated,
+		Used:      s.Used,
+		Issued:    s.Issued,
+		Unused:    s.Unused,
+	}
+}
+
+// AddressPoolAddressesResponse is the response data for POST /api/v2/wallet/address-pool/fill
+// and POST /api/v2/wallet/address-pool/take
+type AddressPoolAddressesResponse struct {
+	Addresses []string            `json:"addresses"`
+	Pool      AddressPoolResponse `json:"pool"`
+}
+
+// FillAddressPoolRequest is the request data for POST /api/v2/wallet/address-pool/fill
+type FillAddressPoolRequest struct {
+	ID       string `json:"id"`
+	Account  string `json:"account,omitempty"`
+	Password string `json:"password"`
+	Size     uint64 `json:"size"`
+}
+
+// TakePoolAddressesRequest is the request data for POST /api/v2/wallet/address-pool/take
+type TakePoolAddressesRequest struct {
+	ID      string `json:"id"`
+	Account string `json:"account,omitempty"`
+	Num     uint64 `json:"num"`
+}
+
+// URI: /api/v2/wallet/address-pool
+// Method: GET
+// Args:
+//  id: wallet id
+//  account: [optional] name of the account of the address pool, the default account by default
+// Returns the status of the address pool of a wallet, after marking the addresses seen on the blockchain as used
+func walletAddressPoolHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		wltID := r.FormValue("id")
+		if wltID == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "id is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		status, err := gateway.WalletAddressPoolStatus(wltID, r.FormValue("account"))
+		if err != nil {
+			writeAddressPoolError(w, err)
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: NewAddressPoolResponse(status),
+		})
+	}
+}
+
+// URI: /api/v2/wallet/address-pool/fill
+// Method: POST
+// Content-Type: application/json
+// Args: JSON body:
+//  id: wallet id
+//  account: [optional] name of the account of the address pool, the default account by default
+//  password: [optional] wallet password, required for encrypted wallets
+//  size: number of unused addresses the account must have
+// Pre-generates addresses until the account has size unused addresses.
+// Returns the added addresses and the status of the address pool.
+func walletFillAddressPoolHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if r.Header.Get("Content-Type") != ContentTypeJSON {
+			resp := NewHTTPErrorResponse(http.StatusUnsupportedMediaType, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		var req FillAddressPoolRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if req.ID == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "id is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if req.Size == 0 {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "size is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		addrs, status, err := gateway.FillWalletAddressPool(req.ID, req.Account, []byte(req.Password), req.Size)
+		if err != nil {
+			writeAddressPoolError(w, err)
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: newAddressPoolAddressesResponse(addrs, status),
+		})
+	}
+}
+
+// URI: /api/v2/wallet/address-pool/take
+// Method: POST
+// Content-Type: application/json
+// Args: JSON body:
+//  id: wallet id
+//  account: [optional] name of the account of the address pool, the default account by default
+//  num: [optional] number of addresses to hand out, 1 by default
+// Hands out unused addresses of the account, which are not handed out again. The password is not needed.
+// Returns the addresses and the status of the address pool.
+func walletTakePoolAddressesHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if r.Header.Get("Content-Type") != ContentTypeJSON {
+			resp := NewHTTPErrorResponse(http.StatusUnsupportedMediaType, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		var req TakePoolAddressesRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if req.ID == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "id is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if req.Num == 0 {
+			req.Num = 1
+		}
+
+		addrs, status, err := gateway.TakeWalletPoolAddresses(req.ID, req.Account, req.Num)
+		if err != nil {
+			writeAddressPoolError(w, err)
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: newAddressPoolAddressesResponse(addrs, status),
+		})
+	}
+}
+
+func newAddressPoolAddressesResponse(addrs []cipher.Address, status wallet.AddressPoolStatus) AddressPoolAddressesResponse {
+	strs := make([]string, len(addrs))
+	for i, a := range addrs {
+		strs[i] = a.String()
+	}
+
+	return AddressPoolAddressesResponse{
+		Addresses: strs,
+		Pool:      NewAddressPoolResponse(status),
+	}
+}
+
+func writeAddressPoolError(w http.ResponseWriter, err error) {
+	var resp HTTPResponse
+	switch err {
+	case wallet.ErrWalletNotExist:
+		resp = NewHTTPErrorResponse(http.StatusNotFound, "")
+	case wallet.ErrWalletAPIDisabled:
+		resp = NewHTTPErrorResponse(http.StatusForbidden, "")
+	default:
+		switch err.(type) {
+		case wallet.Error:
+			resp = NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+		default:
+			resp = NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+		}
+	}
+	writeHTTPResponse(w, resp)
+}
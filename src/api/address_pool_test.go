@@ -0,0 +1,437 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+func TestWalletAddressPoolHandler(t *testing.T) {
+	cases := []struct {
+		name       string
+		method     string
+		id         string
+		account    string
+		status     int
+		err        string
+		gatewayRsp wallet.AddressPoolStatus
+		gatewayErr error
+		rsp        AddressPoolResponse
+	}{
+		{
+			name:   "405",
+			method: http.MethodPost,
+			status: http.StatusMethodNotAllowed,
+			err:    "Method Not Allowed",
+		},
+		{
+			name:   "id missing",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "id is required",
+		},
+		{
+			name:       "wallet not exist",
+			method:     http.MethodGet,
+			id:         "foo.wlt",
+			gatewayErr: wallet.ErrWalletNotExist,
+			status:     http.StatusNotFound,
+			err:        "Not Found",
+		},
+		{
+			name:       "wallet api disabled",
+			method:     http.MethodGet,
+			id:         "foo.wlt",
+			gatewayErr: wallet.ErrWalletAPIDisabled,
+			status:     http.StatusForbidden,
+			err:        "Forbidden",
+		},
+		{
+			name:       "account not exist",
+			method:     http.MethodGet,
+			id:         "foo.wlt",
+			account:    "savings",
+			gatewayErr: wallet.ErrAccountNotExist,
+			status:     http.StatusBadRequest,
+			err:        "account does not exist",
+		},
+		{
+			name:   "status",
+			method: http.MethodGet,
+			id:     "foo.wlt",
+			gatewayRsp: wallet.AddressPoolStatus{
+				Generated: 10,
+				Used:      3,
+				Issued:    2,
+				Unused:    5,
+			},
+			status: http.StatusOK,
+			rsp: AddressPoolResponse{
+				Generated: 10,
+				Used:      3,
+				Issued:    2,
+				Unused:    5,
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("WalletAddressPoolStatus", tc.id, tc.account).Return(tc.gatewayRsp, tc.gatewayErr)
+
+			v := url.Values{}
+			if tc.id != "" {
+				v.Add("id", tc.id)
+			}
+			if tc.account != "" {
+				v.Add("account", tc.account)
+			}
+
+			endpoint := "/api/v2/wallet/address-pool"
+			if len(v) > 0 {
+				endpoint += "?" + v.Encode()
+			}
+
+			req, err := http.NewRequest(tc.method, endpoint, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code, "got `%v` want `%v`", rr.Code, tc.status)
+
+			var rsp ReceivedHTTPResponse
+			err = json.NewDecoder(rr.Body).Decode(&rsp)
+			require.NoError(t, err)
+
+			if tc.status != http.StatusOK {
+				require.NotNil(t, rsp.Error)
+				require.Equal(t, tc.err, rsp.Error.Message)
+				return
+			}
+
+			require.Nil(t, rsp.Error)
+			var pool AddressPoolResponse
+			err = json.Unmarshal(rsp.Data, &pool)
+			require.NoError(t, err)
+			require.Equal(t, tc.rsp, pool)
+		})
+	}
+}
+
+func TestWalletFillAddressPoolHandler(t *testing.T) {
+	addrs := []cipher.Address{testutil.MakeAddress(), testutil.MakeAddress()}
+	poolStatus := wallet.AddressPoolStatus{
+		Generated: 5,
+		Used:      1,
+		Unused:    4,
+	}
+
+	cases := []struct {
+		name        string
+		method      string
+		contentType string
+		req         FillAddressPoolRequest
+		status      int
+		err         string
+		gatewayRsp  []cipher.Address
+		gatewayErr  error
+		rsp         AddressPoolAddressesResponse
+	}{
+		{
+			name:   "405",
+			method: http.MethodGet,
+			status: http.StatusMethodNotAllowed,
+			err:    "Method Not Allowed",
+		},
+		{
+			name:        "415",
+			method:      http.MethodPost,
+			contentType: ContentTypeForm,
+			status:      http.StatusUnsupportedMediaType,
+			err:         "Unsupported Media Type",
+		},
+		{
+			name:   "id missing",
+			method: http.MethodPost,
+			req: FillAddressPoolRequest{
+				Size: 4,
+			},
+			status: http.StatusBadRequest,
+			err:    "id is required",
+		},
+		{
+			name:   "size missing",
+			method: http.MethodPost,
+			req: FillAddressPoolRequest{
+				ID: "foo.wlt",
+			},
+			status: http.StatusBadRequest,
+			err:    "size is required",
+		},
+		{
+			name:   "size too large",
+			method: http.MethodPost,
+			req: FillAddressPoolRequest{
+				ID:   "foo.wlt",
+				Size: wallet.MaxAddressPoolSize + 1,
+			},
+			gatewayErr: wallet.ErrAddressPoolSizeTooLarge,
+			status:     http.StatusBadRequest,
+			err:        "address pool size can't be larger than 1000",
+		},
+		{
+			name:   "invalid password",
+			method: http.MethodPost,
+			req: FillAddressPoolRequest{
+				ID:       "foo.wlt",
+				Password: "wrong",
+				Size:     4,
+			},
+			gatewayErr: wallet.ErrInvalidPassword,
+			status:     http.StatusBadRequest,
+			err:        "invalid password",
+		},
+		{
+			name:   "wallet not exist",
+			method: http.MethodPost,
+			req: FillAddressPoolRequest{
+				ID:   "foo.wlt",
+				Size: 4,
+			},
+			gatewayErr: wallet.ErrWalletNotExist,
+			status:     http.StatusNotFound,
+			err:        "Not Found",
+		},
+		{
+			name:   "fill",
+			method: http.MethodPost,
+			req: FillAddressPoolRequest{
+				ID:       "foo.wlt",
+				Account:  "savings",
+				Password: "pwd",
+				Size:     4,
+			},
+			gatewayRsp: addrs,
+			status:     http.StatusOK,
+			rsp: AddressPoolAddressesResponse{
+				Addresses: []string{addrs[0].String(), addrs[1].String()},
+				Pool: AddressPoolResponse{
+					Generated: 5,
+					Used:      1,
+					Unused:    4,
+				},
+			},
+		},
+		{
+			name:   "already filled",
+			method: http.MethodPost,
+			req: FillAddressPoolRequest{
+				ID:   "foo.wlt",
+				Size: 4,
+			},
+			status: http.StatusOK,
+			rsp: AddressPoolAddressesResponse{
+				Addresses: []string{},
+				Pool: AddressPoolResponse{
+					Generated: 5,
+					Used:      1,
+					Unused:    4,
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("FillWalletAddressPool", tc.req.ID, tc.req.Account, []byte(tc.req.Password), tc.req.Size).Return(tc.gatewayRsp, poolStatus, tc.gatewayErr)
+
+			contentType := tc.contentType
+			if contentType == "" {
+				contentType = ContentTypeJSON
+			}
+
+			req, err := http.NewRequest(tc.method, "/api/v2/wallet/address-pool/fill", strings.NewReader(toJSON(t, tc.req)))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", contentType)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code, "got `%v` want `%v`", rr.Code, tc.status)
+
+			var rsp ReceivedHTTPResponse
+			err = json.NewDecoder(rr.Body).Decode(&rsp)
+			require.NoError(t, err)
+
+			if tc.status != http.StatusOK {
+				require.NotNil(t, rsp.Error)
+				require.Equal(t, tc.err, rsp.Error.Message)
+				return
+			}
+
+			require.Nil(t, rsp.Error)
+			var fillRsp AddressPoolAddressesResponse
+			err = json.Unmarshal(rsp.Data, &fillRsp)
+			require.NoError(t, err)
+			require.Equal(t, tc.rsp, fillRsp)
+		})
+	}
+}
+
+func TestWalletTakePoolAddressesHandler(t *testing.T) {
+	addrs := []cipher.Address{testutil.MakeAddress(), testutil.MakeAddress()}
+	poolStatus := wallet.AddressPoolStatus{
+		Generated: 5,
+		Used:      1,
+		Issued:    2,
+		Unused:    2,
+	}
+
+	cases := []struct {
+		name        string
+		method      string
+		contentType string
+		req         TakePoolAddressesRequest
+		gatewayNum  uint64
+		status      int
+		err         string
+		gatewayRsp  []cipher.Address
+		gatewayErr  error
+		rsp         AddressPoolAddressesResponse
+	}{
+		{
+			name:   "405",
+			method: http.MethodGet,
+			status: http.StatusMethodNotAllowed,
+			err:    "Method Not Allowed",
+		},
+		{
+			name:        "415",
+			method:      http.MethodPost,
+			contentType: ContentTypeForm,
+			status:      http.StatusUnsupportedMediaType,
+			err:         "Unsupported Media Type",
+		},
+		{
+			name:   "id missing",
+			method: http.MethodPost,
+			status: http.StatusBadRequest,
+			err:    "id is required",
+		},
+		{
+			name:   "pool exhausted",
+			method: http.MethodPost,
+			req: TakePoolAddressesRequest{
+				ID:  "foo.wlt",
+				Num: 3,
+			},
+			gatewayNum: 3,
+			gatewayErr: wallet.ErrAddressPoolExhausted,
+			status:     http.StatusBadRequest,
+			err:        "address pool doesn't have enough unused addresses",
+		},
+		{
+			name:   "wallet api disabled",
+			method: http.MethodPost,
+			req: TakePoolAddressesRequest{
+				ID: "foo.wlt",
+			},
+			gatewayNum: 1,
+			gatewayErr: wallet.ErrWalletAPIDisabled,
+			status:     http.StatusForbidden,
+			err:        "Forbidden",
+		},
+		{
+			name:   "take one address by default",
+			method: http.MethodPost,
+			req: TakePoolAddressesRequest{
+				ID: "foo.wlt",
+			},
+			gatewayNum: 1,
+			gatewayRsp: addrs[:1],
+			status:     http.StatusOK,
+			rsp: AddressPoolAddressesResponse{
+				Addresses: []string{addrs[0].String()},
+				Pool: AddressPoolResponse{
+					Generated: 5,
+					Used:      1,
+					Issued:    2,
+					Unused:    2,
+				},
+			},
+		},
+		{
+			name:   "take",
+			method: http.MethodPost,
+			req: TakePoolAddressesRequest{
+				ID:      "foo.wlt",
+				Account: "savings",
+				Num:     2,
+			},
+			gatewayNum: 2,
+			gatewayRsp: addrs,
+			status:     http.StatusOK,
+			rsp: AddressPoolAddressesResponse{
+				Addresses: []string{addrs[0].String(), addrs[1].String()},
+				Pool: AddressPoolResponse{
+					Generated: 5,
+					Used:      1,
+					Issued:    2,
+					Unused:    2,
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("TakeWalletPoolAddresses", tc.req.ID, tc.req.Account, tc.gatewayNum).Return(tc.gatewayRsp, poolStatus, tc.gatewayErr)
+
+			contentType := tc.contentType
+			if contentType == "" {
+				contentType = ContentTypeJSON
+			}
+
+			req, err := http.NewRequest(tc.method, "/api/v2/wallet/address-pool/take", strings.NewReader(toJSON(t, tc.req)))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", contentType)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code, "got `%v` want `%v`", rr.Code, tc.status)
+
+			var rsp ReceivedHTTPResponse
+			err = json.NewDecoder(rr.Body).Decode(&rsp)
+			require.NoError(t, err)
+
+			if tc.status != http.StatusOK {
+				require.NotNil(t, rsp.Error)
+				require.Equal(t, tc.err, rsp.Error.Message)
+				return
+			}
+
+			require.Nil(t, rsp.Error)
+			var takeRsp AddressPoolAddressesResponse
+			err = json.Unmarshal(rsp.Data, &takeRsp)
+			require.NoError(t, err)
+			require.Equal(t, tc.rsp, takeRsp)
+		})
+	}
+}
@@ -2,12 +2,21 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/readable"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor"
+	"github.com/skycoin/skycoin/src/visor/historydb"
+	"github.com/skycoin/skycoin/src/wallet"
 )
 
 func toJSON(t *testing.T, r interface{}) string {
@@ -148,3 +157,170 @@ func TestVerifyAddress(t *testing.T) {
 		})
 	}
 }
+
+func TestAddressesScan(t *testing.T) {
+	addr := testutil.MakeAddress()
+
+	ux := coin.UxOut{
+		Head: coin.UxHead{BkSeq: 3},
+		Body: coin.UxBody{
+			SrcTransaction: testutil.RandSHA256(t),
+			Address:        addr,
+			Coins:          10e6,
+			Hours:          20,
+		},
+	}
+
+	txn := coin.Transaction{
+		In:  []cipher.SHA256{testutil.RandSHA256(t)},
+		Out: []coin.TransactionOutput{{Address: addr, Coins: 10e6, Hours: 20}},
+	}
+	txn.InnerHash = txn.HashInner()
+
+	scans := []visor.AddressScan{
+		{
+			Address: addr,
+			Balance: wallet.Balance{Coins: 10e6, Hours: 25},
+			Outputs: []visor.UnspentOutput{
+				{
+					UxOut:           ux,
+					CalculatedHours: 25,
+				},
+			},
+			RecentTransactions: []historydb.Transaction{
+				{
+					Txn:      txn,
+					BlockSeq: 3,
+				},
+			},
+		},
+	}
+
+	rScans, err := readable.NewAddressScans(scans)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name         string
+		contentType  string
+		httpBody     string
+		gatewayAddrs []cipher.Address
+		gatewayTxns  uint64
+		gatewayResp  []visor.AddressScan
+		gatewayErr   error
+		status       int
+		httpResponse HTTPResponse
+	}{
+		{
+			name:         "415 - Unsupported Media Type",
+			contentType:  ContentTypeForm,
+			status:       http.StatusUnsupportedMediaType,
+			httpResponse: NewHTTPErrorResponse(http.StatusUnsupportedMediaType, ""),
+		},
+		{
+			name:         "400 - EOF",
+			status:       http.StatusBadRequest,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "EOF"),
+		},
+		{
+			name: "400 - invalid address",
+			httpBody: toJSON(t, ScanAddressesRequest{
+				Addresses: []string{"invalidAddr"},
+			}),
+			status:       http.StatusBadRequest,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, `address "invalidAddr" is invalid: Invalid base58 character`),
+		},
+		{
+			name: "400 - too many addresses",
+			httpBody: toJSON(t, ScanAddressesRequest{
+				Addresses: make([]string, visor.MaxScanAddresses+1),
+			}),
+			status:       http.StatusBadRequest,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, visor.ErrScanTooManyAddresses.Error()),
+		},
+		{
+			name: "400 - too many recent transactions",
+			httpBody: toJSON(t, ScanAddressesRequest{
+				Addresses:  []string{addr.String()},
+				RecentTxns: visor.MaxScanRecentTxns + 1,
+			}),
+			gatewayAddrs: []cipher.Address{addr},
+			gatewayTxns:  visor.MaxScanRecentTxns + 1,
+			gatewayErr:   visor.ErrScanTooManyRecentTxns,
+			status:       http.StatusBadRequest,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, visor.ErrScanTooManyRecentTxns.Error()),
+		},
+		{
+			name: "500 - gateway error",
+			httpBody: toJSON(t, ScanAddressesRequest{
+				Addresses:  []string{addr.String()},
+				RecentTxns: 5,
+			}),
+			gatewayAddrs: []cipher.Address{addr},
+			gatewayTxns:  5,
+			gatewayErr:   errors.New("ScanAddresses failed"),
+			status:       http.StatusInternalServerError,
+			httpResponse: NewHTTPErrorResponse(http.StatusInternalServerError, "ScanAddresses failed"),
+		},
+		{
+			name: "200",
+			httpBody: toJSON(t, ScanAddressesRequest{
+				Addresses:  []string{addr.String()},
+				RecentTxns: 5,
+			}),
+			gatewayAddrs: []cipher.Address{addr},
+			gatewayTxns:  5,
+			gatewayResp:  scans,
+			status:       http.StatusOK,
+			httpResponse: HTTPResponse{
+				Data: rScans,
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			endpoint := "/api/v2/addresses/scan"
+			gateway := &MockGatewayer{}
+			if tc.gatewayAddrs != nil {
+				gateway.On("ScanAddresses", tc.gatewayAddrs, tc.gatewayTxns).Return(tc.gatewayResp, tc.gatewayErr)
+			}
+
+			req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(tc.httpBody))
+			require.NoError(t, err)
+
+			contentType := tc.contentType
+			if contentType == "" {
+				contentType = ContentTypeJSON
+			}
+			req.Header.Set("Content-Type", contentType)
+
+			csrfStore := &CSRFStore{
+				Enabled: true,
+			}
+			setCSRFParameters(csrfStore, tokenValid, req)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, csrfStore, nil)
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code)
+
+			var rsp ReceivedHTTPResponse
+			err = json.NewDecoder(rr.Body).Decode(&rsp)
+			require.NoError(t, err)
+
+			require.Equal(t, tc.httpResponse.Error, rsp.Error)
+
+			if rsp.Data == nil {
+				require.Nil(t, tc.httpResponse.Data)
+			} else {
+				var scansRsp []readable.AddressScan
+				err := json.Unmarshal(rsp.Data, &scansRsp)
+				require.NoError(t, err)
+				require.Equal(t, tc.httpResponse.Data.([]readable.AddressScan), scansRsp)
+			}
+
+			gateway.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/readable"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+// AnnotateAddressRequest is the request data for POST /api/v2/wallet/address/annotate
+type AnnotateAddressRequest struct {
+	WalletID string `json:"wallet_id"`
+	Address  string `json:"address"`
+	readable.Annotation
+}
+
+// AnnotateTransactionRequest is the request data for POST /api/v2/wallet/transaction/annotate
+type AnnotateTransactionRequest struct {
+	WalletID string `json:"wallet_id"`
+	TxID     string `json:"txid"`
+	readable.Annotation
+}
+
+// URI: /api/v2/wallet/annotations
+// Method: GET
+// Args:
+//  id: wallet id
+// Returns the annotations of the addresses and the transactions of a wallet
+func walletAnnotationsHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		wltID := r.FormValue("id")
+		if wltID == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "id is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		wlt, err := gateway.GetWallet(wltID)
+		writeWalletAnnotationsResponse(w, wlt, err)
+	}
+}
+
+// URI: /api/v2/wallet/address/annotate
+// Method: POST
+// Args:
+//  wallet_id: wallet id
+//  address: address of the wallet
+//  label: [optional] label of the address
+//  note: [optional] note of the address
+//  category: [optional] category of the address
+// Annotates an address of a wallet. The annotation replaces the previous annotation of the address,
+// and an empty annotation removes it. Returns the annotations of the wallet.
+func walletAnnotateAddressHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if r.Header.Get("Content-Type") != ContentTypeJSON {
+			resp := NewHTTPErrorResponse(http.StatusUnsupportedMediaType, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		var req AnnotateAddressRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if req.WalletID == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "wallet_id is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if req.Address == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "address is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		addr, err := cipher.DecodeBase58Address(req.Address)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, fmt.Sprintf("invalid address: %v", err))
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		wlt, err := gateway.SetAddressAnnotation(req.WalletID, addr, wallet.Annotation(req.Annotation))
+		writeWalletAnnotationsResponse(w, wlt, err)
+	}
+}
+
+// URI: /api/v2/wallet/transaction/annotate
+// Method: POST
+// Args:
+//  wallet_id: wallet id
+//  txid: transaction id
+//  label: [optional] label of the transaction
+//  note: [optional] note of the transaction
+//  category: [optional] category of the transaction
+// Annotates a transaction in a wallet. The annotation replaces the previous annotation of the transaction,
+// and an empty annotation removes it. Returns the annotations of the wallet.
+func walletAnnotateTransactionHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if r.Header.Get("Content-Type") != ContentTypeJSON {
+			resp := NewHTTPErrorResponse(http.StatusUnsupportedMediaType, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		var req AnnotateTransactionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if req.WalletID == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "wallet_id is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if req.TxID == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "txid is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		txid, err := cipher.SHA256FromHex(req.TxID)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, fmt.Sprintf("invalid txid: %v", err))
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		wlt, err := gateway.SetTransactionAnnotation(req.WalletID, txid, wallet.Annotation(req.Annotation))
+		writeWalletAnnotationsResponse(w, wlt, err)
+	}
+}
+
+func writeWalletAnnotationsResponse(w http.ResponseWriter, wlt *wallet.Wallet, err error) {
+	if err != nil {
+		var resp HTTPResponse
+		switch err {
+		case wallet.ErrWalletNotExist:
+			resp = NewHTTPErrorResponse(http.StatusNotFound, "")
+		case wallet.ErrWalletAPIDisabled:
+			resp = NewHTTPErrorResponse(http.StatusForbidden, "")
+		default:
+			switch err.(type) {
+			case wallet.Error:
+				resp = NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			default:
+				resp = NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			}
+		}
+		writeHTTPResponse(w, resp)
+		return
+	}
+
+	writeHTTPResponse(w, HTTPResponse{
+		Data: readable.NewWalletAnnotations(wlt.Annotations),
+	})
+}
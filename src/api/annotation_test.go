@@ -0,0 +1,450 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/readable"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+func makeAnnotatedWallet(t *testing.T) *wallet.Wallet {
+	w, err := wallet.NewWallet("foo.wlt", wallet.Options{
+		Seed:  "seed",
+		Label: "label",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, w.SetAddressAnnotation(w.Entries[0].SkycoinAddress(), wallet.Annotation{
+		Label:    "rent",
+		Category: "home",
+	}))
+
+	txid := testutil.RandSHA256(t)
+	require.NoError(t, w.SetTransactionAnnotation(txid, wallet.Annotation{
+		Note: "paid by bob",
+	}))
+
+	return w
+}
+
+func TestWalletAnnotationsHandler(t *testing.T) {
+	w := makeAnnotatedWallet(t)
+
+	cases := []struct {
+		name       string
+		method     string
+		id         string
+		status     int
+		err        string
+		gatewayRsp *wallet.Wallet
+		gatewayErr error
+		rsp        readable.WalletAnnotations
+	}{
+		{
+			name:   "405",
+			method: http.MethodPost,
+			status: http.StatusMethodNotAllowed,
+			err:    "Method Not Allowed",
+		},
+		{
+			name:   "id missing",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "id is required",
+		},
+		{
+			name:       "wallet not exist",
+			method:     http.MethodGet,
+			id:         "foo.wlt",
+			gatewayErr: wallet.ErrWalletNotExist,
+			status:     http.StatusNotFound,
+			err:        "Not Found",
+		},
+		{
+			name:       "wallet api disabled",
+			method:     http.MethodGet,
+			id:         "foo.wlt",
+			gatewayErr: wallet.ErrWalletAPIDisabled,
+			status:     http.StatusForbidden,
+			err:        "Forbidden",
+		},
+		{
+			name:       "no annotations",
+			method:     http.MethodGet,
+			id:         "foo.wlt",
+			gatewayRsp: &wallet.Wallet{},
+			status:     http.StatusOK,
+			rsp: readable.WalletAnnotations{
+				Addresses:    map[string]readable.Annotation{},
+				Transactions: map[string]readable.Annotation{},
+			},
+		},
+		{
+			name:       "annotations",
+			method:     http.MethodGet,
+			id:         "foo.wlt",
+			gatewayRsp: w,
+			status:     http.StatusOK,
+			rsp:        readable.NewWalletAnnotations(w.Annotations),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("GetWallet", tc.id).Return(tc.gatewayRsp, tc.gatewayErr)
+
+			endpoint := "/api/v2/wallet/annotations"
+			if tc.id != "" {
+				endpoint += "?" + url.Values{"id": []string{tc.id}}.Encode()
+			}
+
+			req, err := http.NewRequest(tc.method, endpoint, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code, "got `%v` want `%v`", rr.Code, tc.status)
+
+			var rsp ReceivedHTTPResponse
+			err = json.NewDecoder(rr.Body).Decode(&rsp)
+			require.NoError(t, err)
+
+			if tc.status != http.StatusOK {
+				require.NotNil(t, rsp.Error)
+				require.Equal(t, tc.err, rsp.Error.Message)
+				return
+			}
+
+			require.Nil(t, rsp.Error)
+			var annotations readable.WalletAnnotations
+			err = json.Unmarshal(rsp.Data, &annotations)
+			require.NoError(t, err)
+			require.Equal(t, tc.rsp, annotations)
+		})
+	}
+}
+
+func TestWalletAnnotateAddressHandler(t *testing.T) {
+	w := makeAnnotatedWallet(t)
+	addr := w.Entries[0].SkycoinAddress()
+	annotation := readable.Annotation{
+		Label:    "rent",
+		Category: "home",
+	}
+
+	cases := []struct {
+		name        string
+		method      string
+		contentType string
+		req         AnnotateAddressRequest
+		status      int
+		err         string
+		gatewayRsp  *wallet.Wallet
+		gatewayErr  error
+		rsp         readable.WalletAnnotations
+	}{
+		{
+			name:   "405",
+			method: http.MethodGet,
+			status: http.StatusMethodNotAllowed,
+			err:    "Method Not Allowed",
+		},
+		{
+			name:        "415",
+			method:      http.MethodPost,
+			contentType: ContentTypeForm,
+			status:      http.StatusUnsupportedMediaType,
+			err:         "Unsupported Media Type",
+		},
+		{
+			name:   "wallet_id missing",
+			method: http.MethodPost,
+			req: AnnotateAddressRequest{
+				Address: addr.String(),
+			},
+			status: http.StatusBadRequest,
+			err:    "wallet_id is required",
+		},
+		{
+			name:   "address missing",
+			method: http.MethodPost,
+			req: AnnotateAddressRequest{
+				WalletID: "foo.wlt",
+			},
+			status: http.StatusBadRequest,
+			err:    "address is required",
+		},
+		{
+			name:   "address invalid",
+			method: http.MethodPost,
+			req: AnnotateAddressRequest{
+				WalletID: "foo.wlt",
+				Address:  "foo",
+			},
+			status: http.StatusBadRequest,
+			err:    "invalid address: Invalid address length",
+		},
+		{
+			name:   "wallet not exist",
+			method: http.MethodPost,
+			req: AnnotateAddressRequest{
+				WalletID:   "foo.wlt",
+				Address:    addr.String(),
+				Annotation: annotation,
+			},
+			gatewayErr: wallet.ErrWalletNotExist,
+			status:     http.StatusNotFound,
+			err:        "Not Found",
+		},
+		{
+			name:   "wallet api disabled",
+			method: http.MethodPost,
+			req: AnnotateAddressRequest{
+				WalletID:   "foo.wlt",
+				Address:    addr.String(),
+				Annotation: annotation,
+			},
+			gatewayErr: wallet.ErrWalletAPIDisabled,
+			status:     http.StatusForbidden,
+			err:        "Forbidden",
+		},
+		{
+			name:   "address not in wallet",
+			method: http.MethodPost,
+			req: AnnotateAddressRequest{
+				WalletID:   "foo.wlt",
+				Address:    addr.String(),
+				Annotation: annotation,
+			},
+			gatewayErr: wallet.ErrUnknownAddress,
+			status:     http.StatusBadRequest,
+			err:        wallet.ErrUnknownAddress.Error(),
+		},
+		{
+			name:   "label too long",
+			method: http.MethodPost,
+			req: AnnotateAddressRequest{
+				WalletID:   "foo.wlt",
+				Address:    addr.String(),
+				Annotation: annotation,
+			},
+			gatewayErr: wallet.ErrAnnotationLabelTooLong,
+			status:     http.StatusBadRequest,
+			err:        wallet.ErrAnnotationLabelTooLong.Error(),
+		},
+		{
+			name:   "internal error",
+			method: http.MethodPost,
+			req: AnnotateAddressRequest{
+				WalletID:   "foo.wlt",
+				Address:    addr.String(),
+				Annotation: annotation,
+			},
+			gatewayErr: errors.New("failed"),
+			status:     http.StatusInternalServerError,
+			err:        "failed",
+		},
+		{
+			name:   "annotated",
+			method: http.MethodPost,
+			req: AnnotateAddressRequest{
+				WalletID:   "foo.wlt",
+				Address:    addr.String(),
+				Annotation: annotation,
+			},
+			gatewayRsp: w,
+			status:     http.StatusOK,
+			rsp:        readable.NewWalletAnnotations(w.Annotations),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("SetAddressAnnotation", tc.req.WalletID, addr, wallet.Annotation(tc.req.Annotation)).Return(tc.gatewayRsp, tc.gatewayErr)
+
+			contentType := tc.contentType
+			if contentType == "" {
+				contentType = ContentTypeJSON
+			}
+
+			req, err := http.NewRequest(tc.method, "/api/v2/wallet/address/annotate", strings.NewReader(toJSON(t, tc.req)))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", contentType)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code, "got `%v` want `%v`", rr.Code, tc.status)
+
+			var rsp ReceivedHTTPResponse
+			err = json.NewDecoder(rr.Body).Decode(&rsp)
+			require.NoError(t, err)
+
+			if tc.status != http.StatusOK {
+				require.NotNil(t, rsp.Error)
+				require.Equal(t, tc.err, rsp.Error.Message)
+				return
+			}
+
+			require.Nil(t, rsp.Error)
+			var annotations readable.WalletAnnotations
+			err = json.Unmarshal(rsp.Data, &annotations)
+			require.NoError(t, err)
+			require.Equal(t, tc.rsp, annotations)
+		})
+	}
+}
+
+func TestWalletAnnotateTransactionHandler(t *testing.T) {
+	w := makeAnnotatedWallet(t)
+	var txid cipher.SHA256
+	for k := range w.Annotations.Transactions {
+		txid = k
+	}
+	annotation := readable.Annotation{
+		Note: "paid by bob",
+	}
+
+	cases := []struct {
+		name        string
+		method      string
+		contentType string
+		req         AnnotateTransactionRequest
+		status      int
+		err         string
+		gatewayRsp  *wallet.Wallet
+		gatewayErr  error
+		rsp         readable.WalletAnnotations
+	}{
+		{
+			name:   "405",
+			method: http.MethodGet,
+			status: http.StatusMethodNotAllowed,
+			err:    "Method Not Allowed",
+		},
+		{
+			name:        "415",
+			method:      http.MethodPost,
+			contentType: ContentTypeForm,
+			status:      http.StatusUnsupportedMediaType,
+			err:         "Unsupported Media Type",
+		},
+		{
+			name:   "wallet_id missing",
+			method: http.MethodPost,
+			req: AnnotateTransactionRequest{
+				TxID: txid.Hex(),
+			},
+			status: http.StatusBadRequest,
+			err:    "wallet_id is required",
+		},
+		{
+			name:   "txid missing",
+			method: http.MethodPost,
+			req: AnnotateTransactionRequest{
+				WalletID: "foo.wlt",
+			},
+			status: http.StatusBadRequest,
+			err:    "txid is required",
+		},
+		{
+			name:   "txid invalid",
+			method: http.MethodPost,
+			req: AnnotateTransactionRequest{
+				WalletID: "foo.wlt",
+				TxID:     "foo",
+			},
+			status: http.StatusBadRequest,
+			err:    "invalid txid: encoding/hex: invalid byte: U+006F 'o'",
+		},
+		{
+			name:   "wallet not exist",
+			method: http.MethodPost,
+			req: AnnotateTransactionRequest{
+				WalletID:   "foo.wlt",
+				TxID:       txid.Hex(),
+				Annotation: annotation,
+			},
+			gatewayErr: wallet.ErrWalletNotExist,
+			status:     http.StatusNotFound,
+			err:        "Not Found",
+		},
+		{
+			name:   "note too long",
+			method: http.MethodPost,
+			req: AnnotateTransactionRequest{
+				WalletID:   "foo.wlt",
+				TxID:       txid.Hex(),
+				Annotation: annotation,
+			},
+			gatewayErr: wallet.ErrAnnotationNoteTooLong,
+			status:     http.StatusBadRequest,
+			err:        wallet.ErrAnnotationNoteTooLong.Error(),
+		},
+		{
+			name:   "annotated",
+			method: http.MethodPost,
+			req: AnnotateTransactionRequest{
+				WalletID:   "foo.wlt",
+				TxID:       txid.Hex(),
+				Annotation: annotation,
+			},
+			gatewayRsp: w,
+			status:     http.StatusOK,
+			rsp:        readable.NewWalletAnnotations(w.Annotations),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("SetTransactionAnnotation", tc.req.WalletID, txid, wallet.Annotation(tc.req.Annotation)).Return(tc.gatewayRsp, tc.gatewayErr)
+
+			contentType := tc.contentType
+			if contentType == "" {
+				contentType = ContentTypeJSON
+			}
+
+			req, err := http.NewRequest(tc.method, "/api/v2/wallet/transaction/annotate", strings.NewReader(toJSON(t, tc.req)))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", contentType)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code, "got `%v` want `%v`", rr.Code, tc.status)
+
+			var rsp ReceivedHTTPResponse
+			err = json.NewDecoder(rr.Body).Decode(&rsp)
+			require.NoError(t, err)
+
+			if tc.status != http.StatusOK {
+				require.NotNil(t, rsp.Error)
+				require.Equal(t, tc.err, rsp.Error.Message)
+				return
+			}
+
+			require.Nil(t, rsp.Error)
+			var annotations readable.WalletAnnotations
+			err = json.Unmarshal(rsp.Data, &annotations)
+			require.NoError(t, err)
+			require.Equal(t, tc.rsp, annotations)
+		})
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/skycoin/skycoin/src/readable"
 	wh "github.com/skycoin/skycoin/src/util/http"
 	"github.com/skycoin/skycoin/src/visor"
+	"github.com/skycoin/skycoin/src/visor/blockdb"
 )
 
 // blockchainMetadataHandler returns the blockchain metadata
@@ -82,13 +83,14 @@ func parseBoolFlag(v string) (bool, error) {
 	return strconv.ParseBool(v)
 }
 
-// blockHandler returns a block by hash or seq
+// blockHandler returns a block by hash, seq or time
 // Method: GET
 // URI: /api/v1/block
 // Args:
 // 	hash [transaction hash string]
 //  seq [int]
-// 	Note: only one of hash or seq is allowed
+//  time [int] unix time, returns the last block at or before it
+// 	Note: only one of hash, seq or time is allowed
 func blockHandler(gateway Gatewayer) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
@@ -98,6 +100,7 @@ func blockHandler(gateway Gatewayer) http.HandlerFunc {
 
 		hash := r.FormValue("hash")
 		seq := r.FormValue("seq")
+		blockTime := r.FormValue("time")
 
 		verbose, err := parseBoolFlag(r.FormValue("verbose"))
 		if err != nil {
@@ -105,12 +108,20 @@ func blockHandler(gateway Gatewayer) http.HandlerFunc {
 			return
 		}
 
-		switch {
-		case hash == "" && seq == "":
-			wh.Error400(w, "should specify one filter, hash or seq")
+		var nFilters int
+		for _, v := range []string{hash, seq, blockTime} {
+			if v != "" {
+				nFilters++
+			}
+		}
+
+		switch nFilters {
+		case 0:
+			wh.Error400(w, "should specify one filter, hash, seq or time")
 			return
-		case hash != "" && seq != "":
-			wh.Error400(w, "should only specify one filter, hash or seq")
+		case 1:
+		default:
+			wh.Error400(w, "should only specify one filter, hash, seq or time")
 			return
 		}
 
@@ -134,6 +145,16 @@ func blockHandler(gateway Gatewayer) http.HandlerFunc {
 			}
 		}
 
+		var uTime uint64
+		if blockTime != "" {
+			var err error
+			uTime, err = strconv.ParseUint(blockTime, 10, 64)
+			if err != nil {
+				wh.Error400(w, fmt.Sprintf("Invalid time value %q", blockTime))
+				return
+			}
+		}
+
 		if verbose {
 			var b *coin.SignedBlock
 			var inputs [][]visor.TransactionInput
@@ -143,10 +164,17 @@ func blockHandler(gateway Gatewayer) http.HandlerFunc {
 				b, inputs, err = gateway.GetSignedBlockByHashVerbose(h)
 			case seq != "":
 				b, inputs, err = gateway.GetSignedBlockBySeqVerbose(uSeq)
+			case blockTime != "":
+				b, inputs, err = gateway.GetSignedBlockByTimeVerbose(uTime)
 			}
 
 			if err != nil {
-				wh.Error500(w, err.Error())
+				switch err.(type) {
+				case blockdb.ErrBlockPruned:
+					wh.Error410(w, err.Error())
+				default:
+					wh.Error500(w, err.Error())
+				}
 				return
 			}
 
@@ -171,10 +199,17 @@ func blockHandler(gateway Gatewayer) http.HandlerFunc {
 			b, err = gateway.GetSignedBlockByHash(h)
 		case seq != "":
 			b, err = gateway.GetSignedBlockBySeq(uSeq)
+		case blockTime != "":
+			b, err = gateway.GetSignedBlockByTime(uTime)
 		}
 
 		if err != nil {
-			wh.Error500(w, err.Error())
+			switch err.(type) {
+			case blockdb.ErrBlockPruned:
+				wh.Error410(w, err.Error())
+			default:
+				wh.Error500(w, err.Error())
+			}
 			return
 		}
 
@@ -193,6 +228,68 @@ func blockHandler(gateway Gatewayer) http.HandlerFunc {
 	}
 }
 
+// blockHeaderHandler returns a block header by hash or seq, with the hash of the unspent output set after the block
+// Method: GET
+// URI: /api/v1/block/header
+// Args:
+//	hash [block hash string]
+//	seq [int]
+//	Note: only one of hash or seq is allowed
+func blockHeaderHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			wh.Error405(w)
+			return
+		}
+
+		hash := r.FormValue("hash")
+		seq := r.FormValue("seq")
+
+		switch {
+		case hash == "" && seq == "":
+			wh.Error400(w, "should specify one filter, hash or seq")
+			return
+		case hash != "" && seq != "":
+			wh.Error400(w, "should only specify one filter, hash or seq")
+			return
+		}
+
+		var h *visor.CommittedBlockHeader
+		if hash != "" {
+			bh, err := cipher.SHA256FromHex(hash)
+			if err != nil {
+				wh.Error400(w, err.Error())
+				return
+			}
+
+			h, err = gateway.GetBlockHeaderByHash(bh)
+			if err != nil {
+				wh.Error500(w, err.Error())
+				return
+			}
+		} else {
+			uSeq, err := strconv.ParseUint(seq, 10, 64)
+			if err != nil {
+				wh.Error400(w, fmt.Sprintf("Invalid seq value %q", seq))
+				return
+			}
+
+			h, err = gateway.GetBlockHeaderBySeq(uSeq)
+			if err != nil {
+				wh.Error500(w, err.Error())
+				return
+			}
+		}
+
+		if h == nil {
+			wh.Error404(w, "")
+			return
+		}
+
+		wh.SendJSONOr500(logger, w, readable.NewCommittedBlockHeader(*h))
+	}
+}
+
 // blocksHandler returns blocks between a start and end point,
 // or an explicit list of sequences.
 // If using start and end, the block sequences include both the start and end point.
@@ -289,6 +386,8 @@ func blocksHandler(gateway Gatewayer) http.HandlerFunc {
 				switch err.(type) {
 				case visor.ErrBlockNotExist:
 					wh.Error404(w, err.Error())
+				case blockdb.ErrBlockPruned:
+					wh.Error410(w, err.Error())
 				default:
 					wh.Error500(w, err.Error())
 				}
@@ -316,6 +415,8 @@ func blocksHandler(gateway Gatewayer) http.HandlerFunc {
 				switch err.(type) {
 				case visor.ErrBlockNotExist:
 					wh.Error404(w, err.Error())
+				case blockdb.ErrBlockPruned:
+					wh.Error410(w, err.Error())
 				default:
 					wh.Error500(w, err.Error())
 				}
@@ -362,7 +463,12 @@ func lastBlocksHandler(gateway Gatewayer) http.HandlerFunc {
 		if verbose {
 			blocks, inputs, err := gateway.GetLastBlocksVerbose(n)
 			if err != nil {
-				wh.Error500(w, err.Error())
+				switch err.(type) {
+				case blockdb.ErrBlockPruned:
+					wh.Error410(w, err.Error())
+				default:
+					wh.Error500(w, err.Error())
+				}
 				return
 			}
 
@@ -378,7 +484,12 @@ func lastBlocksHandler(gateway Gatewayer) http.HandlerFunc {
 
 		blocks, err := gateway.GetLastBlocks(n)
 		if err != nil {
-			wh.Error500(w, err.Error())
+			switch err.(type) {
+			case blockdb.ErrBlockPruned:
+				wh.Error410(w, err.Error())
+			default:
+				wh.Error500(w, err.Error())
+			}
 			return
 		}
 
@@ -22,6 +22,7 @@ import (
 	"github.com/skycoin/skycoin/src/readable"
 	"github.com/skycoin/skycoin/src/testutil"
 	"github.com/skycoin/skycoin/src/visor"
+	"github.com/skycoin/skycoin/src/visor/blockdb"
 )
 
 func TestGetBlockchainMetadata(t *testing.T) {
@@ -174,6 +175,23 @@ func TestGetBlockchainProgress(t *testing.T) {
 				Highest: 102,
 			},
 		},
+		{
+			name:   "200 offline",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			getBlockchainProgressResult: &daemon.BlockchainProgress{
+				Peers:   []daemon.PeerBlockchainHeight{},
+				Current: 99,
+				Highest: 99,
+				Offline: true,
+			},
+			result: readable.BlockchainProgress{
+				Peers:   []readable.PeerBlockchainHeight{},
+				Current: 99,
+				Highest: 99,
+				Offline: true,
+			},
+		},
 	}
 
 	for _, tc := range cases {
@@ -267,6 +285,12 @@ func TestGetBlock(t *testing.T) {
 		gatewayGetBlockByHashVerboseErr    error
 		gatewayGetBlockBySeqVerboseResult  verboseResult
 		gatewayGetBlockBySeqVerboseErr     error
+		timeStr                            string
+		time                               uint64
+		gatewayGetBlockByTimeResult        *coin.SignedBlock
+		gatewayGetBlockByTimeErr           error
+		gatewayGetBlockByTimeVerboseResult verboseResult
+		gatewayGetBlockByTimeVerboseErr    error
 		response                           interface{}
 	}{
 		{
@@ -279,16 +303,119 @@ func TestGetBlock(t *testing.T) {
 			name:   "400 - no seq and hash",
 			method: http.MethodGet,
 			status: http.StatusBadRequest,
-			err:    "400 Bad Request - should specify one filter, hash or seq",
+			err:    "400 Bad Request - should specify one filter, hash, seq or time",
 		},
 		{
 			name:   "400 - seq and hash simultaneously",
 			method: http.MethodGet,
 			status: http.StatusBadRequest,
-			err:    "400 Bad Request - should only specify one filter, hash or seq",
+			err:    "400 Bad Request - should only specify one filter, hash, seq or time",
 			hash:   "hash",
 			seqStr: "seq",
 		},
+		{
+			name:    "400 - seq and time simultaneously",
+			method:  http.MethodGet,
+			status:  http.StatusBadRequest,
+			err:     "400 Bad Request - should only specify one filter, hash, seq or time",
+			seqStr:  "1",
+			timeStr: "1000",
+		},
+		{
+			name:    "400 - time error: invalid syntax",
+			method:  http.MethodGet,
+			status:  http.StatusBadRequest,
+			err:     "400 Bad Request - Invalid time value \"badtime\"",
+			timeStr: "badtime",
+		},
+		{
+			name:    "404 - no block at or before time",
+			method:  http.MethodGet,
+			status:  http.StatusNotFound,
+			err:     "404 Not Found",
+			timeStr: "1000",
+			time:    1000,
+		},
+		{
+			name:                     "410 - block by time is pruned",
+			method:                   http.MethodGet,
+			status:                   http.StatusGone,
+			err:                      "410 Gone - Block 1 is pruned",
+			timeStr:                  "1000",
+			time:                     1000,
+			gatewayGetBlockByTimeErr: blockdb.NewErrBlockPruned(1),
+		},
+		{
+			name:                     "500 - get block by time error",
+			method:                   http.MethodGet,
+			status:                   http.StatusInternalServerError,
+			err:                      "500 Internal Server Error - GetSignedBlockByTime failed",
+			timeStr:                  "1000",
+			time:                     1000,
+			gatewayGetBlockByTimeErr: errors.New("GetSignedBlockByTime failed"),
+		},
+		{
+			name:                        "200 - get block by time",
+			method:                      http.MethodGet,
+			status:                      http.StatusOK,
+			timeStr:                     "1000",
+			time:                        1000,
+			gatewayGetBlockByTimeResult: &coin.SignedBlock{},
+			response: &readable.Block{
+				Head: readable.BlockHeader{
+					BkSeq:        0x0,
+					Hash:         "7b8ec8dd836b564f0c85ad088fc744de820345204e154bc1503e04e9d6fdd9f1",
+					PreviousHash: "0000000000000000000000000000000000000000000000000000000000000000",
+					Time:         0x0,
+					Fee:          0x0,
+					Version:      0x0,
+					BodyHash:     "0000000000000000000000000000000000000000000000000000000000000000",
+					UxHash:       "0000000000000000000000000000000000000000000000000000000000000000",
+				},
+				Body: readable.BlockBody{
+					Transactions: []readable.Transaction{},
+				},
+			},
+		},
+		{
+			name:       "200 - get block by time verbose",
+			method:     http.MethodGet,
+			status:     http.StatusOK,
+			timeStr:    "1000",
+			time:       1000,
+			verbose:    true,
+			verboseStr: "1",
+			gatewayGetBlockByTimeVerboseResult: verboseResult{
+				Block:  &coin.SignedBlock{},
+				Inputs: nil,
+			},
+			response: &readable.BlockVerbose{
+				Head: readable.BlockHeader{
+					BkSeq:        0x0,
+					Hash:         "7b8ec8dd836b564f0c85ad088fc744de820345204e154bc1503e04e9d6fdd9f1",
+					PreviousHash: "0000000000000000000000000000000000000000000000000000000000000000",
+					Time:         0x0,
+					Fee:          0x0,
+					Version:      0x0,
+					BodyHash:     "0000000000000000000000000000000000000000000000000000000000000000",
+					UxHash:       "0000000000000000000000000000000000000000000000000000000000000000",
+				},
+				Body: readable.BlockBodyVerbose{
+					Transactions: []readable.BlockTransactionVerbose{},
+				},
+			},
+		},
+		{
+			name:                            "500 - get block by time verbose error",
+			method:                          http.MethodGet,
+			status:                          http.StatusInternalServerError,
+			timeStr:                         "1000",
+			time:                            1000,
+			verbose:                         true,
+			verboseStr:                      "1",
+			gatewayGetBlockByTimeVerboseErr: errors.New("GetSignedBlockByTimeVerbose failed"),
+			err:                             "500 Internal Server Error - GetSignedBlockByTimeVerbose failed",
+		},
 		{
 			name:   "400 - hash error: encoding/hex err invalid byte: U+0068 'h'",
 			method: http.MethodGet,
@@ -353,6 +480,15 @@ func TestGetBlock(t *testing.T) {
 			sha256:                   validSHA256,
 			gatewayGetBlockByHashErr: errors.New("GetSignedBlockByHash failed"),
 		},
+		{
+			name:                    "410 - block by seq is pruned",
+			method:                  http.MethodGet,
+			status:                  http.StatusGone,
+			err:                     "410 Gone - Block 1 is pruned",
+			seqStr:                  "1",
+			seq:                     1,
+			gatewayGetBlockBySeqErr: blockdb.NewErrBlockPruned(1),
+		},
 		{
 			name:                    "500 - get block by seq error",
 			method:                  http.MethodGet,
@@ -535,6 +671,9 @@ func TestGetBlock(t *testing.T) {
 				tc.gatewayGetBlockByHashVerboseResult.Inputs, tc.gatewayGetBlockByHashVerboseErr)
 			gateway.On("GetSignedBlockBySeqVerbose", tc.seq).Return(tc.gatewayGetBlockBySeqVerboseResult.Block,
 				tc.gatewayGetBlockBySeqVerboseResult.Inputs, tc.gatewayGetBlockBySeqVerboseErr)
+			gateway.On("GetSignedBlockByTime", tc.time).Return(tc.gatewayGetBlockByTimeResult, tc.gatewayGetBlockByTimeErr)
+			gateway.On("GetSignedBlockByTimeVerbose", tc.time).Return(tc.gatewayGetBlockByTimeVerboseResult.Block,
+				tc.gatewayGetBlockByTimeVerboseResult.Inputs, tc.gatewayGetBlockByTimeVerboseErr)
 
 			endpoint := "/api/v1/block"
 
@@ -545,6 +684,9 @@ func TestGetBlock(t *testing.T) {
 			if tc.seqStr != "" {
 				v.Add("seq", tc.seqStr)
 			}
+			if tc.timeStr != "" {
+				v.Add("time", tc.timeStr)
+			}
 			if tc.verboseStr != "" {
 				v.Add("verbose", tc.verboseStr)
 			}
@@ -588,6 +730,164 @@ func TestGetBlock(t *testing.T) {
 	}
 }
 
+func TestGetBlockHeader(t *testing.T) {
+	validHashString := testutil.RandSHA256(t).Hex()
+	validSHA256, err := cipher.SHA256FromHex(validHashString)
+	require.NoError(t, err)
+
+	commitment := testutil.RandSHA256(t)
+	header := &visor.CommittedBlockHeader{
+		Header: coin.BlockHeader{
+			BkSeq:  2,
+			UxHash: testutil.RandSHA256(t),
+		},
+		UnspentCommitment: &commitment,
+	}
+	legacyHeader := &visor.CommittedBlockHeader{
+		Header: header.Header,
+	}
+
+	tt := []struct {
+		name            string
+		method          string
+		status          int
+		err             string
+		hash            string
+		sha256          cipher.SHA256
+		seqStr          string
+		seq             uint64
+		getByHashResult *visor.CommittedBlockHeader
+		getByHashErr    error
+		getBySeqResult  *visor.CommittedBlockHeader
+		getBySeqErr     error
+		response        readable.CommittedBlockHeader
+	}{
+		{
+			name:   "405",
+			method: http.MethodPost,
+			status: http.StatusMethodNotAllowed,
+			err:    "405 Method Not Allowed",
+		},
+		{
+			name:   "400 - no seq and hash",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - should specify one filter, hash or seq",
+		},
+		{
+			name:   "400 - seq and hash simultaneously",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - should only specify one filter, hash or seq",
+			hash:   "hash",
+			seqStr: "seq",
+		},
+		{
+			name:   "400 - invalid hash",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - encoding/hex: invalid byte: U+0068 'h'",
+			hash:   "hash",
+		},
+		{
+			name:   "400 - invalid seq",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - Invalid seq value \"badseq\"",
+			seqStr: "badseq",
+		},
+		{
+			name:         "500 - get by hash error",
+			method:       http.MethodGet,
+			status:       http.StatusInternalServerError,
+			err:          "500 Internal Server Error - GetBlockHeaderByHash failed",
+			hash:         validHashString,
+			sha256:       validSHA256,
+			getByHashErr: errors.New("GetBlockHeaderByHash failed"),
+		},
+		{
+			name:        "500 - get by seq error",
+			method:      http.MethodGet,
+			status:      http.StatusInternalServerError,
+			err:         "500 Internal Server Error - GetBlockHeaderBySeq failed",
+			seqStr:      "2",
+			seq:         2,
+			getBySeqErr: errors.New("GetBlockHeaderBySeq failed"),
+		},
+		{
+			name:   "404 - block not found",
+			method: http.MethodGet,
+			status: http.StatusNotFound,
+			err:    "404 Not Found",
+			seqStr: "2",
+			seq:    2,
+		},
+		{
+			name:            "200 - by hash",
+			method:          http.MethodGet,
+			status:          http.StatusOK,
+			hash:            validHashString,
+			sha256:          validSHA256,
+			getByHashResult: header,
+			response: readable.CommittedBlockHeader{
+				Header:            readable.NewBlockHeader(header.Header),
+				UnspentCommitment: commitment.Hex(),
+			},
+		},
+		{
+			name:           "200 - by seq, no commitment",
+			method:         http.MethodGet,
+			status:         http.StatusOK,
+			seqStr:         "2",
+			seq:            2,
+			getBySeqResult: legacyHeader,
+			response: readable.CommittedBlockHeader{
+				Header: readable.NewBlockHeader(header.Header),
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("GetBlockHeaderByHash", tc.sha256).Return(tc.getByHashResult, tc.getByHashErr)
+			gateway.On("GetBlockHeaderBySeq", tc.seq).Return(tc.getBySeqResult, tc.getBySeqErr)
+
+			endpoint := "/api/v1/block/header"
+
+			v := url.Values{}
+			if tc.hash != "" {
+				v.Add("hash", tc.hash)
+			}
+			if tc.seqStr != "" {
+				v.Add("seq", tc.seqStr)
+			}
+			if len(v) > 0 {
+				endpoint += "?" + v.Encode()
+			}
+
+			req, err := http.NewRequest(tc.method, endpoint, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			status := rr.Code
+			require.Equal(t, tc.status, status, "got `%v` want `%v`", status, tc.status)
+
+			if status != http.StatusOK {
+				require.Equal(t, tc.err, strings.TrimSpace(rr.Body.String()))
+			} else {
+				var msg readable.CommittedBlockHeader
+				err := json.Unmarshal(rr.Body.Bytes(), &msg)
+				require.NoError(t, err)
+				require.Equal(t, tc.response, msg)
+			}
+		})
+	}
+}
+
 func TestGetBlocks(t *testing.T) {
 	type httpBody struct {
 		Start   string
@@ -747,6 +1047,19 @@ func TestGetBlocks(t *testing.T) {
 			gatewayGetBlocksVerboseError: visor.NewErrBlockNotExist(4),
 		},
 
+		{
+			name:   "410 - gatewayGetBlocksInRangeError block pruned",
+			method: http.MethodGet,
+			status: http.StatusGone,
+			err:    "410 Gone - Block 1 is pruned",
+			body: &httpBody{
+				Start: "1",
+				End:   "3",
+			},
+			start:                        1,
+			end:                          3,
+			gatewayGetBlocksInRangeError: blockdb.NewErrBlockPruned(1),
+		},
 		{
 			name:   "500 - gatewayGetBlocksInRangeError",
 			method: http.MethodGet,
@@ -1080,6 +1393,17 @@ func TestGetLastBlocks(t *testing.T) {
 				Verbose: "foo",
 			},
 		},
+		{
+			name:   "410 - gatewayGetLastBlocksError block pruned",
+			method: http.MethodGet,
+			status: http.StatusGone,
+			err:    "410 Gone - Block 1 is pruned",
+			body: httpBody{
+				Num: "1",
+			},
+			num:                       1,
+			gatewayGetLastBlocksError: blockdb.NewErrBlockPruned(1),
+		},
 		{
 			name:   "500 - gatewayGetLastBlocksError",
 			method: http.MethodGet,
@@ -17,6 +17,8 @@ import (
 	"github.com/skycoin/skycoin/src/coin"
 	"github.com/skycoin/skycoin/src/daemon"
 	"github.com/skycoin/skycoin/src/readable"
+	"github.com/skycoin/skycoin/src/wallet"
+	"github.com/skycoin/skycoin/src/wallet/history"
 )
 
 const (
@@ -28,6 +30,8 @@ const (
 	ContentTypeJSON = "application/json"
 	// ContentTypeForm form data content type header
 	ContentTypeForm = "application/x-www-form-urlencoded"
+	// ContentTypeCSV csv content type header
+	ContentTypeCSV = "text/csv"
 )
 
 // ClientError is used for non-200 API responses
@@ -429,6 +433,59 @@ func (c *Client) BlockBySeqVerbose(seq uint64) (*readable.BlockVerbose, error) {
 	return &b, nil
 }
 
+// BlockByTime makes a request to GET /api/v1/block?time=xxx
+func (c *Client) BlockByTime(t uint64) (*readable.Block, error) {
+	v := url.Values{}
+	v.Add("time", fmt.Sprint(t))
+	endpoint := "/api/v1/block?" + v.Encode()
+
+	var b readable.Block
+	if err := c.Get(endpoint, &b); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// BlockByTimeVerbose makes a request to GET /api/v1/block?time=xxx&verbose=1
+func (c *Client) BlockByTimeVerbose(t uint64) (*readable.BlockVerbose, error) {
+	v := url.Values{}
+	v.Add("time", fmt.Sprint(t))
+	v.Add("verbose", "1")
+	endpoint := "/api/v1/block?" + v.Encode()
+
+	var b readable.BlockVerbose
+	if err := c.Get(endpoint, &b); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// BlockHeaderByHash makes a request to GET /api/v1/block/header?hash=xxx
+func (c *Client) BlockHeaderByHash(hash string) (*readable.CommittedBlockHeader, error) {
+	v := url.Values{}
+	v.Add("hash", hash)
+	endpoint := "/api/v1/block/header?" + v.Encode()
+
+	var h readable.CommittedBlockHeader
+	if err := c.Get(endpoint, &h); err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// BlockHeaderBySeq makes a request to GET /api/v1/block/header?seq=xxx
+func (c *Client) BlockHeaderBySeq(seq uint64) (*readable.CommittedBlockHeader, error) {
+	v := url.Values{}
+	v.Add("seq", fmt.Sprint(seq))
+	endpoint := "/api/v1/block/header?" + v.Encode()
+
+	var h readable.CommittedBlockHeader
+	if err := c.Get(endpoint, &h); err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
 // Blocks makes a request to POST /api/v1/blocks?seqs=
 func (c *Client) Blocks(seqs []uint64) (*readable.Blocks, error) {
 	sSeqs := make([]string, len(seqs))
@@ -553,6 +610,24 @@ func (c *Client) Balance(addrs []string) (*BalanceResponse, error) {
 	return &b, nil
 }
 
+// BalanceHistory makes a request to GET /api/v1/balance/history?address=xxx&start_seq=xxx&end_seq=xxx.
+// The range ends at the head block if endSeq is nil.
+func (c *Client) BalanceHistory(addr string, startSeq uint64, endSeq *uint64) (*readable.AddressBalanceHistory, error) {
+	v := url.Values{}
+	v.Add("address", addr)
+	v.Add("start_seq", fmt.Sprint(startSeq))
+	if endSeq != nil {
+		v.Add("end_seq", fmt.Sprint(*endSeq))
+	}
+	endpoint := "/api/v1/balance/history?" + v.Encode()
+
+	var h readable.AddressBalanceHistory
+	if err := c.Get(endpoint, &h); err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
 // UxOut makes a request to GET /api/v1/uxout?uxid=xxx
 func (c *Client) UxOut(uxID string) (*readable.SpentOutput, error) {
 	v := url.Values{}
@@ -566,6 +641,19 @@ func (c *Client) UxOut(uxID string) (*readable.SpentOutput, error) {
 	return &b, nil
 }
 
+// UxOutSpender makes a request to GET /api/v1/uxout/spender?uxid=xxx
+func (c *Client) UxOutSpender(uxID string) (*readable.UxOutSpender, error) {
+	v := url.Values{}
+	v.Add("uxid", uxID)
+	endpoint := "/api/v1/uxout/spender?" + v.Encode()
+
+	var b readable.UxOutSpender
+	if err := c.Get(endpoint, &b); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
 // AddressUxOuts makes a request to GET /api/v1/address_uxouts
 func (c *Client) AddressUxOuts(addr string) ([]readable.SpentOutput, error) {
 	v := url.Values{}
@@ -677,6 +765,20 @@ func (c *Client) WalletBalance(id string) (*BalanceResponse, error) {
 	return &b, nil
 }
 
+// WalletAccountBalance makes a request to GET /api/v1/wallet/balance for an account of a wallet
+func (c *Client) WalletAccountBalance(id, account string) (*BalanceResponse, error) {
+	v := url.Values{}
+	v.Add("id", id)
+	v.Add("account", account)
+	endpoint := "/api/v1/wallet/balance?" + v.Encode()
+
+	var b BalanceResponse
+	if err := c.Get(endpoint, &b); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
 // Spend makes a request to POST /api/v1/wallet/spend
 func (c *Client) Spend(id, dst string, coins uint64, password string) (*SpendResult, error) {
 	v := url.Values{}
@@ -701,6 +803,10 @@ type CreateTransactionRequest struct {
 	Wallet            CreateTransactionRequestWallet `json:"wallet"`
 	ChangeAddress     *string                        `json:"change_address,omitempty"`
 	To                []Receiver                     `json:"to"`
+	Unsigned          bool                           `json:"unsigned"`
+	Device            string                         `json:"device,omitempty"`
+	CoinSelection     string                         `json:"coin_selection,omitempty"`
+	ChangePolicy      string                         `json:"change_policy,omitempty"`
 }
 
 // CreateTransactionRequestWallet defines a wallet to spend from and optionally which addresses in the wallet
@@ -709,6 +815,7 @@ type CreateTransactionRequestWallet struct {
 	UxOuts    []string `json:"unspents,omitempty"`
 	Addresses []string `json:"addresses,omitempty"`
 	Password  string   `json:"password"`
+	Account   string   `json:"account,omitempty"`
 }
 
 // HoursSelection defines options for hours distribution
@@ -736,6 +843,59 @@ func (c *Client) CreateTransaction(req CreateTransactionRequest) (*CreateTransac
 	return &r, nil
 }
 
+// CreatePayoutBatchRequest is sent to /api/v2/wallet/transaction/batch
+type CreatePayoutBatchRequest struct {
+	CreateTransactionRequest
+	// CSV are the payouts, one "address,coins[,hours]" line per payout, instead of To
+	CSV string `json:"csv,omitempty"`
+	// MaxOutputs is the maximum number of payouts per transaction, unlimited if 0
+	MaxOutputs int `json:"max_outputs,omitempty"`
+}
+
+// CreatePayoutBatch makes a request to POST /api/v2/wallet/transaction/batch
+func (c *Client) CreatePayoutBatch(req CreatePayoutBatchRequest) (*PayoutBatchResponse, error) {
+	var rsp PayoutBatchResponse
+	ok, err := c.PostJSONV2("/api/v2/wallet/transaction/batch", req, &rsp)
+	if ok {
+		return &rsp, err
+	}
+
+	return nil, err
+}
+
+// HoursRedistributionRequest is sent to /api/v2/wallet/transaction/redistribute-hours
+type HoursRedistributionRequest struct {
+	IgnoreUnconfirmed bool                           `json:"ignore_unconfirmed"`
+	Wallet            CreateTransactionRequestWallet `json:"wallet"`
+	// Mode is "consolidate", "even" or "proportional"
+	Mode string `json:"mode"`
+	// To is the address that receives the coin hours in the consolidate mode
+	To       string `json:"to,omitempty"`
+	Unsigned bool   `json:"unsigned"`
+}
+
+// RedistributeHours makes a request to POST /api/v2/wallet/transaction/redistribute-hours
+func (c *Client) RedistributeHours(req HoursRedistributionRequest) (*CreateTransactionResponse, error) {
+	var rsp CreateTransactionResponse
+	ok, err := c.PostJSONV2("/api/v2/wallet/transaction/redistribute-hours", req, &rsp)
+	if ok {
+		return &rsp, err
+	}
+
+	return nil, err
+}
+
+// RescanWallet makes a request to POST /api/v2/wallet/rescan
+func (c *Client) RescanWallet(req WalletRescanRequest) (*WalletRescanResponse, error) {
+	var rsp WalletRescanResponse
+	ok, err := c.PostJSONV2("/api/v2/wallet/rescan", req, &rsp)
+	if ok {
+		return &rsp, err
+	}
+
+	return nil, err
+}
+
 // WalletUnconfirmedTransactions makes a request to GET /api/v1/wallet/transactions
 func (c *Client) WalletUnconfirmedTransactions(id string) (*UnconfirmedTxnsResponse, error) {
 	v := url.Values{}
@@ -916,6 +1076,19 @@ func (c *Client) Transaction(txid string) (*readable.TransactionWithStatus, erro
 	return &r, nil
 }
 
+// TransactionProof makes a request to GET /api/v1/transaction/proof
+func (c *Client) TransactionProof(txid string) (*readable.TransactionInclusionProof, error) {
+	v := url.Values{}
+	v.Add("txid", txid)
+	endpoint := "/api/v1/transaction/proof?" + v.Encode()
+
+	var p readable.TransactionInclusionProof
+	if err := c.Get(endpoint, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
 // TransactionVerbose makes a request to GET /api/v1/transaction?verbose=1
 func (c *Client) TransactionVerbose(txid string) (*readable.TransactionWithStatusVerbose, error) {
 	v := url.Values{}
@@ -1107,6 +1280,22 @@ func (c *Client) VerifyAddress(addr string) (*VerifyAddressResponse, error) {
 	return nil, err
 }
 
+// ScanAddresses makes a request to POST /api/v2/addresses/scan
+func (c *Client) ScanAddresses(addrs []string, recentTxns uint64) ([]readable.AddressScan, error) {
+	req := ScanAddressesRequest{
+		Addresses:  addrs,
+		RecentTxns: recentTxns,
+	}
+
+	var rsp []readable.AddressScan
+	ok, err := c.PostJSONV2("/api/v2/addresses/scan", req, &rsp)
+	if ok {
+		return rsp, err
+	}
+
+	return nil, err
+}
+
 // AddressTransactions makes a request to GET /api/v1/explorer/address
 func (c *Client) AddressTransactions(addr string) ([]readable.TransactionVerbose, error) {
 	v := url.Values{}
@@ -1122,8 +1311,9 @@ func (c *Client) AddressTransactions(addr string) ([]readable.TransactionVerbose
 
 // RichlistParams are arguments to the /richlist endpoint
 type RichlistParams struct {
-	N                   int
-	IncludeDistribution bool
+	N                          int
+	IncludeDistribution        bool
+	IncludeBalanceDistribution bool
 }
 
 // Richlist makes a request to GET /api/v1/richlist
@@ -1134,6 +1324,9 @@ func (c *Client) Richlist(params *RichlistParams) (*Richlist, error) {
 		v := url.Values{}
 		v.Add("n", fmt.Sprint(params.N))
 		v.Add("include-distribution", fmt.Sprint(params.IncludeDistribution))
+		if params.IncludeBalanceDistribution {
+			v.Add("include-balance-distribution", "true")
+		}
 		endpoint = "/api/v1/richlist?" + v.Encode()
 	}
 
@@ -1156,6 +1349,50 @@ func (c *Client) AddressCount() (uint64, error) {
 
 }
 
+// AddressMeta makes a request to GET /api/v1/explorer/address_meta
+func (c *Client) AddressMeta(addr string) (*readable.AddressMeta, error) {
+	v := url.Values{}
+	v.Add("address", addr)
+	endpoint := "/api/v1/explorer/address_meta?" + v.Encode()
+
+	var m readable.AddressMeta
+	if err := c.Get(endpoint, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// AddressTransactionsPageParams are arguments to the /explorer/address_transactions endpoint
+type AddressTransactionsPageParams struct {
+	Address string
+	Limit   uint64
+	Offset  uint64
+	// Cursor is the NextCursor of the previous page
+	Cursor string
+}
+
+// AddressTransactionsPage makes a request to GET /api/v1/explorer/address_transactions
+func (c *Client) AddressTransactionsPage(params AddressTransactionsPageParams) (*AddressTransactionsPage, error) {
+	v := url.Values{}
+	v.Add("address", params.Address)
+	if params.Limit != 0 {
+		v.Add("limit", fmt.Sprint(params.Limit))
+	}
+	if params.Offset != 0 {
+		v.Add("offset", fmt.Sprint(params.Offset))
+	}
+	if params.Cursor != "" {
+		v.Add("cursor", params.Cursor)
+	}
+	endpoint := "/api/v1/explorer/address_transactions?" + v.Encode()
+
+	var p AddressTransactionsPage
+	if err := c.Get(endpoint, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
 // UnloadWallet makes a request to POST /api/v1/wallet/unload
 func (c *Client) UnloadWallet(id string) error {
 	v := url.Values{}
@@ -1200,13 +1437,15 @@ func (c *Client) DecryptWallet(id, password string) (*WalletResponse, error) {
 }
 
 // RecoverWallet makes a request to POST /api/v2/wallet/recover to recover an encrypted wallet by seed.
+// The seedPassphrase argument is the BIP39 passphrase of the seed, if the wallet was created with one.
 // The password argument is optional, if provided, the recovered wallet will be encrypted with this password,
 // otherwise the recovered wallet will be unencrypted.
-func (c *Client) RecoverWallet(id, seed, password string) (*WalletResponse, error) {
+func (c *Client) RecoverWallet(id, seed, seedPassphrase, password string) (*WalletResponse, error) {
 	req := WalletRecoverRequest{
-		ID:       id,
-		Seed:     seed,
-		Password: password,
+		ID:             id,
+		Seed:           seed,
+		SeedPassphrase: seedPassphrase,
+		Password:       password,
 	}
 
 	var rsp WalletResponse
@@ -1218,6 +1457,360 @@ func (c *Client) RecoverWallet(id, seed, password string) (*WalletResponse, erro
 	return nil, err
 }
 
+// CreateWatchOnlyWallet makes a request to POST /api/v2/wallet/watch-only/create
+func (c *Client) CreateWatchOnlyWallet(label string, addrs []string) (*WalletResponse, error) {
+	req := WatchOnlyWalletCreateRequest{
+		Label:     label,
+		Addresses: addrs,
+	}
+
+	var rsp WalletResponse
+	ok, err := c.PostJSONV2("/api/v2/wallet/watch-only/create", req, &rsp)
+	if ok {
+		return &rsp, err
+	}
+
+	return nil, err
+}
+
+// AddWatchOnlyAddresses makes a request to POST /api/v2/wallet/watch-only/add
+func (c *Client) AddWatchOnlyAddresses(id string, addrs []string) (*WalletResponse, error) {
+	req := WatchOnlyAddressesRequest{
+		ID:        id,
+		Addresses: addrs,
+	}
+
+	var rsp WalletResponse
+	ok, err := c.PostJSONV2("/api/v2/wallet/watch-only/add", req, &rsp)
+	if ok {
+		return &rsp, err
+	}
+
+	return nil, err
+}
+
+// AddMultisig makes a request to POST /api/v2/wallet/multisig/add
+func (c *Client) AddMultisig(id string, m int, pubKeys []string) (*WalletResponse, error) {
+	req := MultisigRequest{
+		ID:         id,
+		M:          m,
+		PublicKeys: pubKeys,
+	}
+
+	var rsp WalletResponse
+	ok, err := c.PostJSONV2("/api/v2/wallet/multisig/add", req, &rsp)
+	if ok {
+		return &rsp, err
+	}
+
+	return nil, err
+}
+
+// SignPartialTransaction makes a request to POST /api/v2/wallet/transaction/sign
+func (c *Client) SignPartialTransaction(walletID, password, partialTxn string) (*PartialTransactionResponse, error) {
+	req := SignPartialTransactionRequest{
+		WalletID:           walletID,
+		Password:           password,
+		PartialTransaction: partialTxn,
+	}
+
+	var rsp PartialTransactionResponse
+	ok, err := c.PostJSONV2("/api/v2/wallet/transaction/sign", req, &rsp)
+	if ok {
+		return &rsp, err
+	}
+
+	return nil, err
+}
+
+// CosignPartialTransaction makes a request to POST /api/v2/wallet/transaction/cosign
+func (c *Client) CosignPartialTransaction(walletID, password string, partialTxns []string) (*CosignPartialTransactionResponse, error) {
+	req := CosignPartialTransactionRequest{
+		WalletID:            walletID,
+		Password:            password,
+		PartialTransactions: partialTxns,
+	}
+
+	var rsp CosignPartialTransactionResponse
+	ok, err := c.PostJSONV2("/api/v2/wallet/transaction/cosign", req, &rsp)
+	if ok {
+		return &rsp, err
+	}
+
+	return nil, err
+}
+
+// CombinePartialTransactions makes a request to POST /api/v2/transaction/partial/combine
+func (c *Client) CombinePartialTransactions(partialTxns []string) (*PartialTransactionResponse, error) {
+	req := CombinePartialTransactionsRequest{
+		PartialTransactions: partialTxns,
+	}
+
+	var rsp PartialTransactionResponse
+	ok, err := c.PostJSONV2("/api/v2/transaction/partial/combine", req, &rsp)
+	if ok {
+		return &rsp, err
+	}
+
+	return nil, err
+}
+
+// WalletAnnotations makes a request to GET /api/v2/wallet/annotations
+func (c *Client) WalletAnnotations(id string) (*readable.WalletAnnotations, error) {
+	v := url.Values{}
+	v.Add("id", id)
+	endpoint := "/api/v2/wallet/annotations?" + v.Encode()
+
+	var rsp ReceivedHTTPResponse
+	if err := c.Get(endpoint, &rsp); err != nil {
+		return nil, err
+	}
+
+	var annotations readable.WalletAnnotations
+	if err := json.Unmarshal(rsp.Data, &annotations); err != nil {
+		return nil, err
+	}
+	return &annotations, nil
+}
+
+// AnnotateAddress makes a request to POST /api/v2/wallet/address/annotate
+func (c *Client) AnnotateAddress(req AnnotateAddressRequest) (*readable.WalletAnnotations, error) {
+	var rsp readable.WalletAnnotations
+	ok, err := c.PostJSONV2("/api/v2/wallet/address/annotate", req, &rsp)
+	if ok {
+		return &rsp, err
+	}
+
+	return nil, err
+}
+
+// AnnotateTransaction makes a request to POST /api/v2/wallet/transaction/annotate
+func (c *Client) AnnotateTransaction(req AnnotateTransactionRequest) (*readable.WalletAnnotations, error) {
+	var rsp readable.WalletAnnotations
+	ok, err := c.PostJSONV2("/api/v2/wallet/transaction/annotate", req, &rsp)
+	if ok {
+		return &rsp, err
+	}
+
+	return nil, err
+}
+
+// WalletCrypto makes a request to GET /api/v2/wallet/crypto
+func (c *Client) WalletCrypto(id string) (*WalletCryptoResponse, error) {
+	v := url.Values{}
+	v.Add("id", id)
+	endpoint := "/api/v2/wallet/crypto?" + v.Encode()
+
+	var rsp ReceivedHTTPResponse
+	if err := c.Get(endpoint, &rsp); err != nil {
+		return nil, err
+	}
+
+	var crypto WalletCryptoResponse
+	if err := json.Unmarshal(rsp.Data, &crypto); err != nil {
+		return nil, err
+	}
+	return &crypto, nil
+}
+
+// WalletSpendingPolicy makes a request to GET /api/v2/wallet/spending-policy
+func (c *Client) WalletSpendingPolicy(id string) (*SpendingPolicyResponse, error) {
+	v := url.Values{}
+	v.Add("id", id)
+	endpoint := "/api/v2/wallet/spending-policy?" + v.Encode()
+
+	var rsp ReceivedHTTPResponse
+	if err := c.Get(endpoint, &rsp); err != nil {
+		return nil, err
+	}
+
+	var policy SpendingPolicyResponse
+	if err := json.Unmarshal(rsp.Data, &policy); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// SetWalletSpendingPolicy makes a request to POST /api/v2/wallet/spending-policy/set
+func (c *Client) SetWalletSpendingPolicy(req SetSpendingPolicyRequest) (*SpendingPolicyResponse, error) {
+	var rsp SpendingPolicyResponse
+	ok, err := c.PostJSONV2("/api/v2/wallet/spending-policy/set", req, &rsp)
+	if ok {
+		return &rsp, err
+	}
+
+	return nil, err
+}
+
+// WalletCosigners makes a request to GET /api/v2/wallet/cosigners
+func (c *Client) WalletCosigners(id string) (*CosignersResponse, error) {
+	v := url.Values{}
+	v.Add("id", id)
+	endpoint := "/api/v2/wallet/cosigners?" + v.Encode()
+
+	var rsp ReceivedHTTPResponse
+	if err := c.Get(endpoint, &rsp); err != nil {
+		return nil, err
+	}
+
+	var cosigners CosignersResponse
+	if err := json.Unmarshal(rsp.Data, &cosigners); err != nil {
+		return nil, err
+	}
+	return &cosigners, nil
+}
+
+// SetWalletCosigners makes a request to POST /api/v2/wallet/cosigners/set
+func (c *Client) SetWalletCosigners(req SetCosignersRequest) (*CosignersResponse, error) {
+	var rsp CosignersResponse
+	ok, err := c.PostJSONV2("/api/v2/wallet/cosigners/set", req, &rsp)
+	if ok {
+		return &rsp, err
+	}
+
+	return nil, err
+}
+
+// Sweep makes a request to POST /api/v2/wallet/sweep
+func (c *Client) Sweep(req SweepRequest) (*SweepResponse, error) {
+	var rsp SweepResponse
+	ok, err := c.PostJSONV2("/api/v2/wallet/sweep", req, &rsp)
+	if ok {
+		return &rsp, err
+	}
+
+	return nil, err
+}
+
+// WalletAddressPool makes a request to GET /api/v2/wallet/address-pool
+func (c *Client) WalletAddressPool(id, account string) (*AddressPoolResponse, error) {
+	v := url.Values{}
+	v.Add("id", id)
+	if account != "" {
+		v.Add("account", account)
+	}
+	endpoint := "/api/v2/wallet/address-pool?" + v.Encode()
+
+	var rsp ReceivedHTTPResponse
+	if err := c.Get(endpoint, &rsp); err != nil {
+		return nil, err
+	}
+
+	var pool AddressPoolResponse
+	if err := json.Unmarshal(rsp.Data, &pool); err != nil {
+		return nil, err
+	}
+	return &pool, nil
+}
+
+// FillWalletAddressPool makes a request to POST /api/v2/wallet/address-pool/fill
+func (c *Client) FillWalletAddressPool(req FillAddressPoolRequest) (*AddressPoolAddressesResponse, error) {
+	var rsp AddressPoolAddressesResponse
+	ok, err := c.PostJSONV2("/api/v2/wallet/address-pool/fill", req, &rsp)
+	if ok {
+		return &rsp, err
+	}
+
+	return nil, err
+}
+
+// TakeWalletPoolAddresses makes a request to POST /api/v2/wallet/address-pool/take
+func (c *Client) TakeWalletPoolAddresses(req TakePoolAddressesRequest) (*AddressPoolAddressesResponse, error) {
+	var rsp AddressPoolAddressesResponse
+	ok, err := c.PostJSONV2("/api/v2/wallet/address-pool/take", req, &rsp)
+	if ok {
+		return &rsp, err
+	}
+
+	return nil, err
+}
+
+// WalletHistory makes a request to GET /api/v2/wallet/history/export with the json format.
+// account is the name of an account of the wallet to export the history of, or empty for the whole wallet.
+// priceSource is the name of a price source registered on the node, to annotate the entries with their fiat value,
+// or empty.
+func (c *Client) WalletHistory(id, account, priceSource string) (*WalletHistoryResponse, error) {
+	v := url.Values{}
+	v.Add("id", id)
+	if account != "" {
+		v.Add("account", account)
+	}
+	v.Add("format", string(history.FormatJSON))
+	if priceSource != "" {
+		v.Add("price_source", priceSource)
+	}
+	endpoint := "/api/v2/wallet/history/export?" + v.Encode()
+
+	var rsp ReceivedHTTPResponse
+	if err := c.Get(endpoint, &rsp); err != nil {
+		return nil, err
+	}
+
+	var h WalletHistoryResponse
+	if err := json.Unmarshal(rsp.Data, &h); err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// WalletAccounts makes a request to GET /api/v2/wallet/accounts
+func (c *Client) WalletAccounts(id string) (*WalletAccountsResponse, error) {
+	v := url.Values{}
+	v.Add("id", id)
+	endpoint := "/api/v2/wallet/accounts?" + v.Encode()
+
+	var rsp ReceivedHTTPResponse
+	if err := c.Get(endpoint, &rsp); err != nil {
+		return nil, err
+	}
+
+	var accounts WalletAccountsResponse
+	if err := json.Unmarshal(rsp.Data, &accounts); err != nil {
+		return nil, err
+	}
+	return &accounts, nil
+}
+
+// CreateWalletAccount makes a request to POST /api/v2/wallet/accounts/create
+func (c *Client) CreateWalletAccount(req CreateWalletAccountRequest) (*WalletAccountResponse, error) {
+	var rsp WalletAccountResponse
+	ok, err := c.PostJSONV2("/api/v2/wallet/accounts/create", req, &rsp)
+	if ok {
+		return &rsp, err
+	}
+	return nil, err
+}
+
+// BackupWallets makes a request to POST /api/v2/wallet/backup
+func (c *Client) BackupWallets(req WalletBackupRequest) (*wallet.BackupArchive, error) {
+	var rsp wallet.BackupArchive
+	ok, err := c.PostJSONV2("/api/v2/wallet/backup", req, &rsp)
+	if ok {
+		return &rsp, err
+	}
+	return nil, err
+}
+
+// RestoreWallets makes a request to POST /api/v2/wallet/restore
+func (c *Client) RestoreWallets(req WalletRestoreRequest) (*WalletRestoreResponse, error) {
+	var rsp WalletRestoreResponse
+	ok, err := c.PostJSONV2("/api/v2/wallet/restore", req, &rsp)
+	if ok {
+		return &rsp, err
+	}
+	return nil, err
+}
+
+// ImportWallet makes a request to POST /api/v2/wallet/import
+func (c *Client) ImportWallet(req WalletImportRequest) (*WalletResponse, error) {
+	var rsp WalletResponse
+	ok, err := c.PostJSONV2("/api/v2/wallet/import", req, &rsp)
+	if ok {
+		return &rsp, err
+	}
+	return nil, err
+}
+
 // Disconnect disconnect a connections by ID
 func (c *Client) Disconnect(id uint64) error {
 	v := url.Values{}
@@ -1226,3 +1819,79 @@ func (c *Client) Disconnect(id uint64) error {
 	var obj struct{}
 	return c.PostForm("/api/v1/network/connection/disconnect", strings.NewReader(v.Encode()), &obj)
 }
+
+// NetworkBans makes a request to GET /api/v1/network/bans
+func (c *Client) NetworkBans() (*Bans, error) {
+	var b Bans
+	if err := c.Get("/api/v1/network/bans", &b); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// NetworkPeers makes a request to GET /api/v1/network/peers
+func (c *Client) NetworkPeers() (*Peers, error) {
+	var p Peers
+	if err := c.Get("/api/v1/network/peers", &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// AddPeers makes a request to POST /api/v1/network/peers with action "add"
+func (c *Client) AddPeers(addrs []string) error {
+	return c.updatePeers("add", addrs, url.Values{})
+}
+
+// RemovePeers makes a request to POST /api/v1/network/peers with action "remove"
+func (c *Client) RemovePeers(addrs []string) error {
+	return c.updatePeers("remove", addrs, url.Values{})
+}
+
+// BanPeers makes a request to POST /api/v1/network/peers with action "ban".
+// If duration is 0, the node's ban duration is used
+func (c *Client) BanPeers(ips []string, reason string, duration time.Duration) error {
+	v := url.Values{}
+	if reason != "" {
+		v.Add("reason", reason)
+	}
+	if duration != 0 {
+		v.Add("duration", duration.String())
+	}
+	return c.updatePeers("ban", ips, v)
+}
+
+func (c *Client) updatePeers(action string, addrs []string, v url.Values) error {
+	v.Add("action", action)
+	v.Add("addrs", strings.Join(addrs, ","))
+
+	var obj struct{}
+	return c.PostForm("/api/v1/network/peers", strings.NewReader(v.Encode()), &obj)
+}
+
+// NetworkRelayPolicy makes a request to GET /api/v1/network/relay_policy
+func (c *Client) NetworkRelayPolicy() (*readable.RelayPolicy, error) {
+	var p readable.RelayPolicy
+	if err := c.Get("/api/v1/network/relay_policy", &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// NetworkCompression makes a request to GET /api/v1/network/compression
+func (c *Client) NetworkCompression() (*readable.Compression, error) {
+	var p readable.Compression
+	if err := c.Get("/api/v1/network/compression", &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Unban makes a request to POST /api/v1/network/bans/unban
+func (c *Client) Unban(ip string) error {
+	v := url.Values{}
+	v.Add("ip", ip)
+
+	var obj struct{}
+	return c.PostForm("/api/v1/network/bans/unban", strings.NewReader(v.Encode()), &obj)
+}
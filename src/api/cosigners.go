@@ -0,0 +1,292 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+// CosignerResponse is a cosigner of a wallet
+type CosignerResponse struct {
+	Label     string   `json:"label"`
+	Addresses []string `json:"addresses"`
+}
+
+// NewCosignerResponse creates a CosignerResponse
+func NewCosignerResponse(c wallet.Cosigner) CosignerResponse {
+	addrs := make([]string, len(c.Addresses))
+	for i, a := range c.Addresses {
+		addrs[i] = a.String()
+	}
+
+	return CosignerResponse{
+		Label:     c.Label,
+		Addresses: addrs,
+	}
+}
+
+// CosignersResponse is the response data for GET /api/v2/wallet/cosigners and POST /api/v2/wallet/cosigners/set
+type CosignersResponse struct {
+	Cosigners []CosignerResponse `json:"cosigners"`
+}
+
+// NewCosignersResponse creates a CosignersResponse from the cosigners of a wallet
+func NewCosignersResponse(w *wallet.Wallet) CosignersResponse {
+	cs := make([]CosignerResponse, len(w.Cosigners))
+	for i, c := range w.Cosigners {
+		cs[i] = NewCosignerResponse(c)
+	}
+
+	return CosignersResponse{
+		Cosigners: cs,
+	}
+}
+
+// CosignerRequest is a cosigner of a SetCosignersRequest
+type CosignerRequest struct {
+	Label     string   `json:"label"`
+	Addresses []string `json:"addresses"`
+}
+
+// SetCosignersRequest is the request data for POST /api/v2/wallet/cosigners/set
+type SetCosignersRequest struct {
+	ID        string            `json:"id"`
+	Cosigners []CosignerRequest `json:"cosigners"`
+}
+
+// ToCosigners parses the request to wallet.Cosigners
+func (r SetCosignersRequest) ToCosigners() (wallet.Cosigners, error) {
+	var cs wallet.Cosigners
+	for _, rc := range r.Cosigners {
+		c := wallet.Cosigner{
+			Label: rc.Label,
+		}
+
+		for _, s := range rc.Addresses {
+			addr, err := cipher.DecodeBase58Address(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid cosigner address %q: %v", s, err)
+			}
+			c.Addresses = append(c.Addresses, addr)
+		}
+
+		cs = append(cs, c)
+	}
+
+	return cs, nil
+}
+
+// URI: /api/v2/wallet/cosigners
+// Method: GET
+// Args:
+//  id: wallet id
+// Returns the cosigners of a wallet
+func walletCosignersHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		wltID := r.FormValue("id")
+		if wltID == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "id is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		wlt, err := gateway.GetWallet(wltID)
+		writeCosignersResponse(w, wlt, err)
+	}
+}
+
+// URI: /api/v2/wallet/cosigners/set
+// Method: POST
+// Args:
+//  id: wallet id
+//  cosigners: the cosigners, each with a label and the addresses it owns
+// Sets the cosigners of a wallet, replacing the previous ones. No cosigners removes them.
+// Returns the cosigners of the wallet.
+func walletSetCosignersHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if r.Header.Get("Content-Type") != ContentTypeJSON {
+			resp := NewHTTPErrorResponse(http.StatusUnsupportedMediaType, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		var req SetCosignersRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if req.ID == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "id is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		cs, err := req.ToCosigners()
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		wlt, err := gateway.SetCosigners(req.ID, cs)
+		writeCosignersResponse(w, wlt, err)
+	}
+}
+
+func writeCosignersResponse(w http.ResponseWriter, wlt *wallet.Wallet, err error) {
+	if err != nil {
+		writeCosignersError(w, err)
+		return
+	}
+
+	writeHTTPResponse(w, HTTPResponse{
+		Data: NewCosignersResponse(wlt),
+	})
+}
+
+func writeCosignersError(w http.ResponseWriter, err error) {
+	var resp HTTPResponse
+	switch err {
+	case wallet.ErrWalletNotExist:
+		resp = NewHTTPErrorResponse(http.StatusNotFound, "")
+	case wallet.ErrWalletAPIDisabled:
+		resp = NewHTTPErrorResponse(http.StatusForbidden, "")
+	default:
+		switch err.(type) {
+		case wallet.Error:
+			resp = NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+		default:
+			resp = NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+		}
+	}
+	writeHTTPResponse(w, resp)
+}
+
+// CosignerStatusResponse reports the inputs of a partial transaction that are owned by a cosigner
+type CosignerStatusResponse struct {
+	Label string `json:"label"`
+	// Inputs is the number of inputs owned by the cosigner
+	Inputs int  `json:"inputs"`
+	Signed bool `json:"signed"`
+	// UnsignedAddresses are the addresses of the cosigner whose inputs are not signed yet
+	UnsignedAddresses []string `json:"unsigned_addresses"`
+}
+
+// CosignPartialTransactionResponse is the response data for POST /api/v2/wallet/transaction/cosign
+type CosignPartialTransactionResponse struct {
+	PartialTransactionResponse
+	// Cosigners are the status of the cosigners of the wallet, in the order of the cosigners
+	Cosigners []CosignerStatusResponse `json:"cosigners"`
+}
+
+// NewCosignPartialTransactionResponse creates a CosignPartialTransactionResponse
+func NewCosignPartialTransactionResponse(ptx *wallet.PartialTransaction, status []wallet.CosignerStatus) CosignPartialTransactionResponse {
+	cs := make([]CosignerStatusResponse, len(status))
+	for i, s := range status {
+		unsigned := make([]string, len(s.UnsignedAddresses))
+		for j, a := range s.UnsignedAddresses {
+			unsigned[j] = a.String()
+		}
+
+		cs[i] = CosignerStatusResponse{
+			Label:             s.Cosigner.Label,
+			Inputs:            s.Inputs,
+			Signed:            s.Signed(),
+			UnsignedAddresses: unsigned,
+		}
+	}
+
+	return CosignPartialTransactionResponse{
+		PartialTransactionResponse: NewPartialTransactionResponse(ptx),
+		Cosigners:                  cs,
+	}
+}
+
+// CosignPartialTransactionRequest is the request data for POST /api/v2/wallet/transaction/cosign
+type CosignPartialTransactionRequest struct {
+	WalletID            string   `json:"wallet_id"`
+	Password            string   `json:"password"`
+	PartialTransactions []string `json:"partial_transactions"`
+}
+
+// URI: /api/v2/wallet/transaction/cosign
+// Method: POST
+// Args:
+//  wallet_id: wallet id
+//  password: [optional] wallet password, if the wallet is encrypted and signs inputs
+//  partial_transactions: hex-encoded partial transactions of the same transaction, signed by the cosigners
+// Combines the signatures of the partial transactions signed by the cosigners of a wallet,
+// signs the inputs that are owned by the wallet, if it has any, and returns the status of each cosigner.
+// The transaction must comply with the spending policy of the wallet if the wallet signs inputs.
+func cosignPartialTransactionHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if r.Header.Get("Content-Type") != ContentTypeJSON {
+			resp := NewHTTPErrorResponse(http.StatusUnsupportedMediaType, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		var req CosignPartialTransactionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if req.WalletID == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "wallet_id is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		ptx, err := combinePartialTransactions(req.PartialTransactions)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		var password []byte
+		if req.Password != "" {
+			password = []byte(req.Password)
+		}
+
+		defer func() {
+			req.Password = ""
+			password = nil
+		}()
+
+		signed, status, err := gateway.CosignPartialTransaction(req.WalletID, password, ptx)
+		if err != nil {
+			writeCosignersError(w, err)
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: NewCosignPartialTransactionResponse(signed, status),
+		})
+	}
+}
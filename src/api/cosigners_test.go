@@ -0,0 +1,503 @@
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+func makeCosignersWallet(t *testing.T, cs wallet.Cosigners) *wallet.Wallet {
+	w, err := wallet.NewWallet("foo.wlt", wallet.Options{
+		Seed:      "seed",
+		Label:     "label",
+		GenerateN: 1,
+	})
+	require.NoError(t, err)
+	require.NoError(t, w.SetCosigners(cs))
+	return w
+}
+
+func TestWalletCosignersHandler(t *testing.T) {
+	addr := testutil.MakeAddress()
+
+	cases := []struct {
+		name       string
+		method     string
+		id         string
+		status     int
+		err        string
+		gatewayRsp *wallet.Wallet
+		gatewayErr error
+		rsp        CosignersResponse
+	}{
+		{
+			name:   "405",
+			method: http.MethodPost,
+			status: http.StatusMethodNotAllowed,
+			err:    "Method Not Allowed",
+		},
+		{
+			name:   "id missing",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "id is required",
+		},
+		{
+			name:       "wallet not exist",
+			method:     http.MethodGet,
+			id:         "foo.wlt",
+			gatewayErr: wallet.ErrWalletNotExist,
+			status:     http.StatusNotFound,
+			err:        "Not Found",
+		},
+		{
+			name:       "wallet api disabled",
+			method:     http.MethodGet,
+			id:         "foo.wlt",
+			gatewayErr: wallet.ErrWalletAPIDisabled,
+			status:     http.StatusForbidden,
+			err:        "Forbidden",
+		},
+		{
+			name:       "no cosigners",
+			method:     http.MethodGet,
+			id:         "foo.wlt",
+			gatewayRsp: makeCosignersWallet(t, nil),
+			status:     http.StatusOK,
+			rsp: CosignersResponse{
+				Cosigners: []CosignerResponse{},
+			},
+		},
+		{
+			name:   "cosigners",
+			method: http.MethodGet,
+			id:     "foo.wlt",
+			gatewayRsp: makeCosignersWallet(t, wallet.Cosigners{
+				{Label: "alice", Addresses: []cipher.Address{addr}},
+			}),
+			status: http.StatusOK,
+			rsp: CosignersResponse{
+				Cosigners: []CosignerResponse{
+					{Label: "alice", Addresses: []string{addr.String()}},
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("GetWallet", tc.id).Return(tc.gatewayRsp, tc.gatewayErr)
+
+			endpoint := "/api/v2/wallet/cosigners"
+			if tc.id != "" {
+				endpoint += "?" + url.Values{"id": []string{tc.id}}.Encode()
+			}
+
+			req, err := http.NewRequest(tc.method, endpoint, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code, "got `%v` want `%v`", rr.Code, tc.status)
+
+			var rsp ReceivedHTTPResponse
+			err = json.NewDecoder(rr.Body).Decode(&rsp)
+			require.NoError(t, err)
+
+			if tc.status != http.StatusOK {
+				require.NotNil(t, rsp.Error)
+				require.Equal(t, tc.err, rsp.Error.Message)
+				return
+			}
+
+			require.Nil(t, rsp.Error)
+			var cs CosignersResponse
+			err = json.Unmarshal(rsp.Data, &cs)
+			require.NoError(t, err)
+			require.Equal(t, tc.rsp, cs)
+		})
+	}
+}
+
+func TestWalletSetCosignersHandler(t *testing.T) {
+	addr := testutil.MakeAddress()
+	cs := wallet.Cosigners{
+		{Label: "alice", Addresses: []cipher.Address{addr}},
+	}
+
+	cases := []struct {
+		name             string
+		method           string
+		contentType      string
+		req              SetCosignersRequest
+		status           int
+		err              string
+		gatewayCosigners wallet.Cosigners
+		gatewayRsp       *wallet.Wallet
+		gatewayErr       error
+		rsp              CosignersResponse
+	}{
+		{
+			name:   "405",
+			method: http.MethodGet,
+			status: http.StatusMethodNotAllowed,
+			err:    "Method Not Allowed",
+		},
+		{
+			name:        "415",
+			method:      http.MethodPost,
+			contentType: ContentTypeForm,
+			status:      http.StatusUnsupportedMediaType,
+			err:         "Unsupported Media Type",
+		},
+		{
+			name:   "id missing",
+			method: http.MethodPost,
+			status: http.StatusBadRequest,
+			err:    "id is required",
+		},
+		{
+			name:   "invalid address",
+			method: http.MethodPost,
+			req: SetCosignersRequest{
+				ID: "foo.wlt",
+				Cosigners: []CosignerRequest{
+					{Label: "alice", Addresses: []string{"x"}},
+				},
+			},
+			status: http.StatusBadRequest,
+			err:    `invalid cosigner address "x": Invalid address length`,
+		},
+		{
+			name:   "invalid cosigners",
+			method: http.MethodPost,
+			req: SetCosignersRequest{
+				ID: "foo.wlt",
+				Cosigners: []CosignerRequest{
+					{Label: "alice"},
+				},
+			},
+			gatewayCosigners: wallet.Cosigners{{Label: "alice"}},
+			gatewayErr:       wallet.ErrCosignerNoAddresses,
+			status:           http.StatusBadRequest,
+			err:              "cosigner has no addresses",
+		},
+		{
+			name:   "wallet not exist",
+			method: http.MethodPost,
+			req: SetCosignersRequest{
+				ID: "foo.wlt",
+			},
+			gatewayErr: wallet.ErrWalletNotExist,
+			status:     http.StatusNotFound,
+			err:        "Not Found",
+		},
+		{
+			name:   "wallet api disabled",
+			method: http.MethodPost,
+			req: SetCosignersRequest{
+				ID: "foo.wlt",
+			},
+			gatewayErr: wallet.ErrWalletAPIDisabled,
+			status:     http.StatusForbidden,
+			err:        "Forbidden",
+		},
+		{
+			name:   "set cosigners",
+			method: http.MethodPost,
+			req: SetCosignersRequest{
+				ID: "foo.wlt",
+				Cosigners: []CosignerRequest{
+					{Label: "alice", Addresses: []string{addr.String()}},
+				},
+			},
+			gatewayCosigners: cs,
+			gatewayRsp:       makeCosignersWallet(t, cs),
+			status:           http.StatusOK,
+			rsp: CosignersResponse{
+				Cosigners: []CosignerResponse{
+					{Label: "alice", Addresses: []string{addr.String()}},
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("SetCosigners", tc.req.ID, tc.gatewayCosigners).Return(tc.gatewayRsp, tc.gatewayErr)
+
+			contentType := tc.contentType
+			if contentType == "" {
+				contentType = ContentTypeJSON
+			}
+
+			req, err := http.NewRequest(tc.method, "/api/v2/wallet/cosigners/set", strings.NewReader(toJSON(t, tc.req)))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", contentType)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code, "got `%v` want `%v`", rr.Code, tc.status)
+
+			var rsp ReceivedHTTPResponse
+			err = json.NewDecoder(rr.Body).Decode(&rsp)
+			require.NoError(t, err)
+
+			if tc.status != http.StatusOK {
+				require.NotNil(t, rsp.Error)
+				require.Equal(t, tc.err, rsp.Error.Message)
+				return
+			}
+
+			require.Nil(t, rsp.Error)
+			var cs CosignersResponse
+			err = json.Unmarshal(rsp.Data, &cs)
+			require.NoError(t, err)
+			require.Equal(t, tc.rsp, cs)
+		})
+	}
+}
+
+func TestCosignPartialTransaction(t *testing.T) {
+	_, keys := cipher.MustGenerateDeterministicKeyPairsSeed([]byte("seed"), 2)
+	ptx := makeTestPartialTransaction(t, keys)
+	other := makeTestPartialTransaction(t, keys)
+	addrs := []cipher.Address{
+		cipher.MustAddressFromSecKey(keys[0]),
+		cipher.MustAddressFromSecKey(keys[1]),
+	}
+
+	sigs := []cipher.Sig{
+		cipher.MustSignHash(cipher.AddSHA256(ptx.Transaction.InnerHash, ptx.Transaction.In[0]), keys[0]),
+		cipher.MustSignHash(cipher.AddSHA256(ptx.Transaction.InnerHash, ptx.Transaction.In[1]), keys[1]),
+	}
+
+	// Signed by the first cosigner
+	signed1 := *ptx
+	signed1.Transaction.Sigs = []cipher.Sig{sigs[0], {}}
+	// Signed by the second cosigner
+	signed2 := *ptx
+	signed2.Transaction.Sigs = []cipher.Sig{{}, sigs[1]}
+	fullySigned := *ptx
+	fullySigned.Transaction.Sigs = sigs
+
+	cosigners := wallet.Cosigners{
+		{Label: "alice", Addresses: addrs[:1]},
+		{Label: "bob", Addresses: addrs[1:]},
+	}
+
+	cases := []struct {
+		name          string
+		method        string
+		contentType   string
+		req           CosignPartialTransactionRequest
+		password      []byte
+		gatewayPtx    *wallet.PartialTransaction
+		status        int
+		err           string
+		gatewayRsp    *wallet.PartialTransaction
+		gatewayStatus []wallet.CosignerStatus
+		gatewayErr    error
+		rsp           CosignPartialTransactionResponse
+	}{
+		{
+			name:   "405",
+			method: http.MethodGet,
+			status: http.StatusMethodNotAllowed,
+			err:    "Method Not Allowed",
+		},
+		{
+			name:        "415",
+			method:      http.MethodPost,
+			contentType: ContentTypeForm,
+			status:      http.StatusUnsupportedMediaType,
+			err:         "Unsupported Media Type",
+		},
+		{
+			name:   "wallet_id missing",
+			method: http.MethodPost,
+			req: CosignPartialTransactionRequest{
+				PartialTransactions: []string{ptx.SerializeHex()},
+			},
+			status: http.StatusBadRequest,
+			err:    "wallet_id is required",
+		},
+		{
+			name:   "partial_transactions missing",
+			method: http.MethodPost,
+			req: CosignPartialTransactionRequest{
+				WalletID: "foo.wlt",
+			},
+			status: http.StatusBadRequest,
+			err:    "partial_transactions is required",
+		},
+		{
+			name:   "different transactions",
+			method: http.MethodPost,
+			req: CosignPartialTransactionRequest{
+				WalletID:            "foo.wlt",
+				PartialTransactions: []string{signed1.SerializeHex(), other.SerializeHex()},
+			},
+			status: http.StatusBadRequest,
+			err:    wallet.ErrPartialTransactionMismatch.Error(),
+		},
+		{
+			name:   "wallet not exist",
+			method: http.MethodPost,
+			req: CosignPartialTransactionRequest{
+				WalletID:            "foo.wlt",
+				PartialTransactions: []string{ptx.SerializeHex()},
+			},
+			gatewayPtx: ptx,
+			gatewayErr: wallet.ErrWalletNotExist,
+			status:     http.StatusNotFound,
+			err:        "Not Found",
+		},
+		{
+			name:   "wallet api disabled",
+			method: http.MethodPost,
+			req: CosignPartialTransactionRequest{
+				WalletID:            "foo.wlt",
+				PartialTransactions: []string{ptx.SerializeHex()},
+			},
+			gatewayPtx: ptx,
+			gatewayErr: wallet.ErrWalletAPIDisabled,
+			status:     http.StatusForbidden,
+			err:        "Forbidden",
+		},
+		{
+			name:   "missing password",
+			method: http.MethodPost,
+			req: CosignPartialTransactionRequest{
+				WalletID:            "foo.wlt",
+				PartialTransactions: []string{ptx.SerializeHex()},
+			},
+			gatewayPtx: ptx,
+			gatewayErr: wallet.ErrMissingPassword,
+			status:     http.StatusBadRequest,
+			err:        "missing password",
+		},
+		{
+			name:   "spending policy violated",
+			method: http.MethodPost,
+			req: CosignPartialTransactionRequest{
+				WalletID:            "foo.wlt",
+				Password:            "pwd",
+				PartialTransactions: []string{ptx.SerializeHex()},
+			},
+			password:   []byte("pwd"),
+			gatewayPtx: ptx,
+			gatewayErr: wallet.ErrSpendingPolicyMaxTransactionCoins,
+			status:     http.StatusBadRequest,
+			err:        wallet.ErrSpendingPolicyMaxTransactionCoins.Error(),
+		},
+		{
+			name:   "cosigner has not signed",
+			method: http.MethodPost,
+			req: CosignPartialTransactionRequest{
+				WalletID:            "foo.wlt",
+				PartialTransactions: []string{signed1.SerializeHex()},
+			},
+			gatewayPtx: &signed1,
+			gatewayRsp: &signed1,
+			gatewayStatus: []wallet.CosignerStatus{
+				{Cosigner: cosigners[0], Inputs: 1},
+				{Cosigner: cosigners[1], Inputs: 1, UnsignedAddresses: addrs[1:]},
+			},
+			status: http.StatusOK,
+			rsp: CosignPartialTransactionResponse{
+				PartialTransactionResponse: PartialTransactionResponse{
+					PartialTransaction: signed1.SerializeHex(),
+					UnsignedAddresses:  []string{addrs[1].String()},
+				},
+				Cosigners: []CosignerStatusResponse{
+					{Label: "alice", Inputs: 1, Signed: true, UnsignedAddresses: []string{}},
+					{Label: "bob", Inputs: 1, UnsignedAddresses: []string{addrs[1].String()}},
+				},
+			},
+		},
+		{
+			name:   "signatures of the cosigners combined",
+			method: http.MethodPost,
+			req: CosignPartialTransactionRequest{
+				WalletID:            "foo.wlt",
+				PartialTransactions: []string{signed1.SerializeHex(), signed2.SerializeHex()},
+			},
+			gatewayPtx: &fullySigned,
+			gatewayRsp: &fullySigned,
+			gatewayStatus: []wallet.CosignerStatus{
+				{Cosigner: cosigners[0], Inputs: 1},
+				{Cosigner: cosigners[1], Inputs: 1},
+			},
+			status: http.StatusOK,
+			rsp: CosignPartialTransactionResponse{
+				PartialTransactionResponse: PartialTransactionResponse{
+					PartialTransaction: fullySigned.SerializeHex(),
+					FullySigned:        true,
+					UnsignedAddresses:  []string{},
+					EncodedTransaction: hex.EncodeToString(fullySigned.Transaction.Serialize()),
+				},
+				Cosigners: []CosignerStatusResponse{
+					{Label: "alice", Inputs: 1, Signed: true, UnsignedAddresses: []string{}},
+					{Label: "bob", Inputs: 1, Signed: true, UnsignedAddresses: []string{}},
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			if tc.gatewayPtx != nil {
+				gateway.On("CosignPartialTransaction", tc.req.WalletID, tc.password, tc.gatewayPtx).Return(tc.gatewayRsp, tc.gatewayStatus, tc.gatewayErr)
+			}
+
+			contentType := tc.contentType
+			if contentType == "" {
+				contentType = ContentTypeJSON
+			}
+
+			req, err := http.NewRequest(tc.method, "/api/v2/wallet/transaction/cosign", strings.NewReader(toJSON(t, tc.req)))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", contentType)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code, "got `%v` want `%v`", rr.Code, tc.status)
+
+			var rsp ReceivedHTTPResponse
+			err = json.NewDecoder(rr.Body).Decode(&rsp)
+			require.NoError(t, err)
+
+			if tc.status != http.StatusOK {
+				require.NotNil(t, rsp.Error)
+				require.Equal(t, tc.err, rsp.Error.Message)
+				return
+			}
+
+			require.Nil(t, rsp.Error)
+			var cosignRsp CosignPartialTransactionResponse
+			err = json.Unmarshal(rsp.Data, &cosignRsp)
+			require.NoError(t, err)
+			require.Equal(t, tc.rsp, cosignRsp)
+		})
+	}
+}
@@ -0,0 +1,315 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/skycoin/skycoin/src/readable"
+	wh "github.com/skycoin/skycoin/src/util/http"
+	"github.com/skycoin/skycoin/src/visor"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/historydb"
+)
+
+// DBVerifyStatusResponse is returned by GET /api/v1/db/verify
+type DBVerifyStatusResponse struct {
+	State           visor.DBVerifyState `json:"state"`
+	FullVerify      bool                `json:"full_verify"`
+	StartedAt       int64               `json:"started_at"`
+	FinishedAt      int64               `json:"finished_at"`
+	BlocksVerified  uint64              `json:"blocks_verified"`
+	BlocksRemaining uint64              `json:"blocks_remaining"`
+	ETA             int64               `json:"eta"`
+	Error           string              `json:"error,omitempty"`
+}
+
+// NewDBVerifyStatusResponse creates a DBVerifyStatusResponse from visor.DBVerifyStatus
+func NewDBVerifyStatusResponse(s visor.DBVerifyStatus) DBVerifyStatusResponse {
+	r := DBVerifyStatusResponse{
+		State:           s.State,
+		FullVerify:      s.FullVerify,
+		BlocksVerified:  s.Progress.Verified,
+		BlocksRemaining: s.Progress.Remaining,
+		ETA:             int64(s.Progress.ETA / time.Second),
+	}
+
+	if !s.StartedAt.IsZero() {
+		r.StartedAt = s.StartedAt.Unix()
+	}
+	if !s.FinishedAt.IsZero() {
+		r.FinishedAt = s.FinishedAt.Unix()
+	}
+	if s.Err != nil {
+		r.Error = s.Err.Error()
+	}
+
+	return r
+}
+
+// dbVerifyStatusHandler returns the status of the background database verification
+// Method: GET
+// URI: /api/v1/db/verify
+func dbVerifyStatusHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			wh.Error405(w)
+			return
+		}
+
+		status := gateway.GetDBVerifyStatus()
+
+		wh.SendJSONOr500(logger, w, NewDBVerifyStatusResponse(status))
+	}
+}
+
+// DBHealthAction is a recovery action taken on the database
+type DBHealthAction struct {
+	Time        int64  `json:"time"`
+	Description string `json:"description"`
+}
+
+// DBHealthResponse is returned by GET /api/v1/db/health
+type DBHealthResponse struct {
+	VerifiedAt             int64            `json:"verified_at"`
+	HeadSeq                uint64           `json:"head_seq"`
+	FullVerify             bool             `json:"full_verify"`
+	BlocksVerified         uint64           `json:"blocks_verified"`
+	Error                  string           `json:"error,omitempty"`
+	CorruptedBlocks        []uint64         `json:"corrupted_blocks"`
+	CorruptedHistoryBlocks []uint64         `json:"corrupted_history_blocks"`
+	ScrubbedAt             int64            `json:"scrubbed_at"`
+	BlocksScrubbed         uint64           `json:"blocks_scrubbed"`
+	Actions                []DBHealthAction `json:"actions"`
+}
+
+// NewDBHealthResponse creates a DBHealthResponse from a visor.DBIntegrityReport, which can be nil
+func NewDBHealthResponse(report *visor.DBIntegrityReport) DBHealthResponse {
+	r := DBHealthResponse{
+		CorruptedBlocks:        []uint64{},
+		CorruptedHistoryBlocks: []uint64{},
+		Actions:                []DBHealthAction{},
+	}
+
+	if report == nil {
+		return r
+	}
+
+	r.VerifiedAt = int64(report.Time)
+	r.HeadSeq = report.HeadSeq
+	r.FullVerify = report.FullVerify
+	r.BlocksVerified = report.BlocksVerified
+	r.Error = report.Error
+	r.CorruptedBlocks = append(r.CorruptedBlocks, report.CorruptedBlocks...)
+	r.CorruptedHistoryBlocks = append(r.CorruptedHistoryBlocks, report.CorruptedHistoryBlocks...)
+	r.ScrubbedAt = int64(report.ScrubTime)
+	r.BlocksScrubbed = report.BlocksScrubbed
+
+	for _, a := range report.Actions {
+		r.Actions = append(r.Actions, DBHealthAction{
+			Time:        int64(a.Time),
+			Description: a.Description,
+		})
+	}
+
+	return r
+}
+
+// dbHealthHandler returns the report of the last database verification and the recovery actions taken on the database
+// Method: GET
+// URI: /api/v1/db/health
+func dbHealthHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			wh.Error405(w)
+			return
+		}
+
+		report, err := gateway.GetDBIntegrityReport()
+		if err != nil {
+			wh.Error500(w, err.Error())
+			return
+		}
+
+		wh.SendJSONOr500(logger, w, NewDBHealthResponse(report))
+	}
+}
+
+// DBBackupResponse is returned by POST /api/v1/db/backup
+type DBBackupResponse struct {
+	Path    string `json:"path"`
+	HeadSeq uint64 `json:"head_seq"`
+	Size    int64  `json:"size"`
+}
+
+// dbBackupHandler writes a snapshot of the database to the backup directory, while the node keeps running
+// Method: POST
+// URI: /api/v1/db/backup
+func dbBackupHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			wh.Error405(w)
+			return
+		}
+
+		backup, err := gateway.BackupDB()
+		if err != nil {
+			switch err {
+			case visor.ErrDBBackupDisabled:
+				wh.Error403(w, err.Error())
+			default:
+				wh.Error500(w, err.Error())
+			}
+			return
+		}
+
+		wh.SendJSONOr500(logger, w, DBBackupResponse{
+			Path:    backup.Path,
+			HeadSeq: backup.HeadSeq,
+			Size:    backup.Size,
+		})
+	}
+}
+
+// DBOrphanedUxOut is an orphaned historydb output and its broken reference
+type DBOrphanedUxOut struct {
+	Reason historydb.OrphanedUxOutReason `json:"reason"`
+	Output readable.SpentOutput          `json:"output"`
+}
+
+// DBOrphanedUxOutsResponse is returned by GET and POST /api/v1/db/orphaned_uxouts
+type DBOrphanedUxOutsResponse struct {
+	ParsedSeq uint64                                   `json:"parsed_seq"`
+	Scanned   uint64                                   `json:"scanned"`
+	Total     uint64                                   `json:"total"`
+	Counts    map[historydb.OrphanedUxOutReason]uint64 `json:"counts"`
+	Orphans   []DBOrphanedUxOut                        `json:"orphans"`
+	Deleted   bool                                     `json:"deleted"`
+}
+
+// NewDBOrphanedUxOutsResponse creates a DBOrphanedUxOutsResponse from a visor.OrphanedUxOutsReport
+func NewDBOrphanedUxOutsResponse(report *visor.OrphanedUxOutsReport) DBOrphanedUxOutsResponse {
+	r := DBOrphanedUxOutsResponse{
+		ParsedSeq: report.ParsedSeq,
+		Scanned:   report.Scanned,
+		Total:     report.Total(),
+		Counts:    report.Counts,
+		Orphans:   make([]DBOrphanedUxOut, len(report.Orphans)),
+		Deleted:   report.Deleted,
+	}
+
+	for i, o := range report.Orphans {
+		r.Orphans[i] = DBOrphanedUxOut{
+			Reason: o.Reason,
+			Output: readable.NewSpentOutput(&o.UxOut),
+		}
+	}
+
+	return r
+}
+
+// dbOrphanedUxOutsHandler scans the historydb for the outputs referencing transactions or blocks that no longer exist.
+// GET only reports them, POST also deletes them.
+// Method: GET, POST
+// URI: /api/v1/db/orphaned_uxouts
+func dbOrphanedUxOutsHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodPost {
+			wh.Error405(w)
+			return
+		}
+
+		report, err := gateway.CollectOrphanedUxOuts(r.Method == http.MethodPost)
+		if err != nil {
+			wh.Error500(w, err.Error())
+			return
+		}
+
+		wh.SendJSONOr500(logger, w, NewDBOrphanedUxOutsResponse(report))
+	}
+}
+
+// DBFileStats are the size and free space of a database file
+type DBFileStats struct {
+	Part      string `json:"part,omitempty"`
+	Size      int64  `json:"size"`
+	PageSize  int    `json:"page_size"`
+	FreePages int    `json:"free_pages"`
+	FreeBytes int    `json:"free_bytes"`
+}
+
+// DBBucketStats are the key count, size and page utilization of a database bucket
+type DBBucketStats struct {
+	Name                string  `json:"name"`
+	Part                string  `json:"part,omitempty"`
+	Keys                int     `json:"keys"`
+	Depth               int     `json:"depth"`
+	BranchPages         int     `json:"branch_pages"`
+	BranchOverflowPages int     `json:"branch_overflow_pages"`
+	LeafPages           int     `json:"leaf_pages"`
+	LeafOverflowPages   int     `json:"leaf_overflow_pages"`
+	AllocBytes          int     `json:"alloc_bytes"`
+	InuseBytes          int     `json:"inuse_bytes"`
+	Utilization         float64 `json:"utilization"`
+}
+
+// DBStatsResponse is returned by GET /api/v1/db/stats
+type DBStatsResponse struct {
+	Files   []DBFileStats   `json:"files"`
+	Buckets []DBBucketStats `json:"buckets"`
+}
+
+// NewDBStatsResponse creates a DBStatsResponse from dbutil.Stats
+func NewDBStatsResponse(stats *dbutil.Stats) DBStatsResponse {
+	r := DBStatsResponse{
+		Files:   make([]DBFileStats, len(stats.Files)),
+		Buckets: make([]DBBucketStats, len(stats.Buckets)),
+	}
+
+	for i, f := range stats.Files {
+		r.Files[i] = DBFileStats{
+			Part:      f.Part,
+			Size:      f.Size,
+			PageSize:  f.PageSize,
+			FreePages: f.FreePages,
+			FreeBytes: f.FreeBytes,
+		}
+	}
+
+	for i, b := range stats.Buckets {
+		r.Buckets[i] = DBBucketStats{
+			Name:                b.Name,
+			Part:                b.Part,
+			Keys:                b.KeyN,
+			Depth:               b.Depth,
+			BranchPages:         b.BranchPageN,
+			BranchOverflowPages: b.BranchOverflowN,
+			LeafPages:           b.LeafPageN,
+			LeafOverflowPages:   b.LeafOverflowN,
+			AllocBytes:          b.AllocBytes(),
+			InuseBytes:          b.InuseBytes(),
+			Utilization:         b.Utilization(),
+		}
+	}
+
+	return r
+}
+
+// dbStatsHandler returns the size and page utilization of the database files and buckets
+// Method: GET
+// URI: /api/v1/db/stats
+func dbStatsHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			wh.Error405(w)
+			return
+		}
+
+		stats, err := gateway.GetDBStats()
+		if err != nil {
+			wh.Error500(w, err.Error())
+			return
+		}
+
+		wh.SendJSONOr500(logger, w, NewDBStatsResponse(stats))
+	}
+}
@@ -0,0 +1,553 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/readable"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/historydb"
+)
+
+func TestDBVerifyStatus(t *testing.T) {
+	startedAt := time.Unix(1540000000, 0)
+	finishedAt := time.Unix(1540000100, 0)
+
+	tt := []struct {
+		name                           string
+		method                         string
+		status                         int
+		err                            string
+		gatewayGetDBVerifyStatusResult visor.DBVerifyStatus
+		result                         DBVerifyStatusResponse
+	}{
+		{
+			name:   "405",
+			method: http.MethodPost,
+			status: http.StatusMethodNotAllowed,
+			err:    "405 Method Not Allowed",
+		},
+		{
+			name:   "200 - disabled",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			gatewayGetDBVerifyStatusResult: visor.DBVerifyStatus{
+				State: visor.DBVerifyStateDisabled,
+			},
+			result: DBVerifyStatusResponse{
+				State: visor.DBVerifyStateDisabled,
+			},
+		},
+		{
+			name:   "200 - running",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			gatewayGetDBVerifyStatusResult: visor.DBVerifyStatus{
+				State:      visor.DBVerifyStateRunning,
+				FullVerify: true,
+				StartedAt:  startedAt,
+				Progress: visor.VerifyProgress{
+					Verified:  1000,
+					Remaining: 3000,
+					ETA:       time.Second*90 + time.Millisecond*500,
+				},
+			},
+			result: DBVerifyStatusResponse{
+				State:           visor.DBVerifyStateRunning,
+				FullVerify:      true,
+				StartedAt:       startedAt.Unix(),
+				BlocksVerified:  1000,
+				BlocksRemaining: 3000,
+				ETA:             90,
+			},
+		},
+		{
+			name:   "200 - failed",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			gatewayGetDBVerifyStatusResult: visor.DBVerifyStatus{
+				State:      visor.DBVerifyStateFailed,
+				StartedAt:  startedAt,
+				FinishedAt: finishedAt,
+				Err:        errors.New("database is corrupted"),
+			},
+			result: DBVerifyStatusResponse{
+				State:      visor.DBVerifyStateFailed,
+				StartedAt:  startedAt.Unix(),
+				FinishedAt: finishedAt.Unix(),
+				Error:      "database is corrupted",
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			endpoint := "/api/v1/db/verify"
+			gateway := &MockGatewayer{}
+			gateway.On("GetDBVerifyStatus").Return(tc.gatewayGetDBVerifyStatusResult)
+
+			req, err := http.NewRequest(tc.method, endpoint, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			status := rr.Code
+			require.Equal(t, tc.status, status, "got `%v` want `%v`", status, tc.status)
+
+			if status != http.StatusOK {
+				require.Equal(t, tc.err, strings.TrimSpace(rr.Body.String()))
+				return
+			}
+
+			var msg DBVerifyStatusResponse
+			err = json.Unmarshal(rr.Body.Bytes(), &msg)
+			require.NoError(t, err)
+			require.Equal(t, tc.result, msg)
+		})
+	}
+}
+
+func TestDBHealth(t *testing.T) {
+	tt := []struct {
+		name                              string
+		method                            string
+		status                            int
+		err                               string
+		gatewayGetDBIntegrityReportResult *visor.DBIntegrityReport
+		gatewayGetDBIntegrityReportErr    error
+		result                            DBHealthResponse
+	}{
+		{
+			name:   "405",
+			method: http.MethodPost,
+			status: http.StatusMethodNotAllowed,
+			err:    "405 Method Not Allowed",
+		},
+		{
+			name:                           "500 - gateway error",
+			method:                         http.MethodGet,
+			status:                         http.StatusInternalServerError,
+			err:                            "500 Internal Server Error - database not open",
+			gatewayGetDBIntegrityReportErr: errors.New("database not open"),
+		},
+		{
+			name:   "200 - no report",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			result: DBHealthResponse{
+				CorruptedBlocks:        []uint64{},
+				CorruptedHistoryBlocks: []uint64{},
+				Actions:                []DBHealthAction{},
+			},
+		},
+		{
+			name:   "200 - corrupted",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			gatewayGetDBIntegrityReportResult: &visor.DBIntegrityReport{
+				Time:                   1540000100,
+				HeadSeq:                1234,
+				FullVerify:             true,
+				BlocksVerified:         1235,
+				Error:                  "history db is corrupted",
+				CorruptedHistoryBlocks: []uint64{12, 13},
+				ScrubTime:              1540003700,
+				BlocksScrubbed:         600,
+				Actions: []visor.DBIntegrityAction{
+					{
+						Time:        1540000000,
+						Description: "Repaired corrupted block 7 with a copy received from a peer",
+					},
+				},
+			},
+			result: DBHealthResponse{
+				VerifiedAt:             1540000100,
+				HeadSeq:                1234,
+				FullVerify:             true,
+				BlocksVerified:         1235,
+				Error:                  "history db is corrupted",
+				CorruptedBlocks:        []uint64{},
+				CorruptedHistoryBlocks: []uint64{12, 13},
+				ScrubbedAt:             1540003700,
+				BlocksScrubbed:         600,
+				Actions: []DBHealthAction{
+					{
+						Time:        1540000000,
+						Description: "Repaired corrupted block 7 with a copy received from a peer",
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			endpoint := "/api/v1/db/health"
+			gateway := &MockGatewayer{}
+			gateway.On("GetDBIntegrityReport").Return(tc.gatewayGetDBIntegrityReportResult, tc.gatewayGetDBIntegrityReportErr)
+
+			req, err := http.NewRequest(tc.method, endpoint, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			status := rr.Code
+			require.Equal(t, tc.status, status, "got `%v` want `%v`", status, tc.status)
+
+			if status != http.StatusOK {
+				require.Equal(t, tc.err, strings.TrimSpace(rr.Body.String()))
+				return
+			}
+
+			var msg DBHealthResponse
+			err = json.Unmarshal(rr.Body.Bytes(), &msg)
+			require.NoError(t, err)
+			require.Equal(t, tc.result, msg)
+		})
+	}
+}
+
+func TestDBBackup(t *testing.T) {
+	tt := []struct {
+		name                  string
+		method                string
+		status                int
+		err                   string
+		gatewayBackupDBResult *visor.DBBackup
+		gatewayBackupDBErr    error
+		result                DBBackupResponse
+	}{
+		{
+			name:   "405",
+			method: http.MethodGet,
+			status: http.StatusMethodNotAllowed,
+			err:    "405 Method Not Allowed",
+		},
+		{
+			name:               "403 - backup disabled",
+			method:             http.MethodPost,
+			status:             http.StatusForbidden,
+			err:                "403 Forbidden - Database backup directory is not configured",
+			gatewayBackupDBErr: visor.ErrDBBackupDisabled,
+		},
+		{
+			name:               "500 - gateway error",
+			method:             http.MethodPost,
+			status:             http.StatusInternalServerError,
+			err:                "500 Internal Server Error - no space left on device",
+			gatewayBackupDBErr: errors.New("no space left on device"),
+		},
+		{
+			name:   "200",
+			method: http.MethodPost,
+			status: http.StatusOK,
+			gatewayBackupDBResult: &visor.DBBackup{
+				Path:    "/home/user/.skycoin/backups/data.db.1234.bak",
+				HeadSeq: 1234,
+				Size:    524288,
+			},
+			result: DBBackupResponse{
+				Path:    "/home/user/.skycoin/backups/data.db.1234.bak",
+				HeadSeq: 1234,
+				Size:    524288,
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			endpoint := "/api/v1/db/backup"
+			gateway := &MockGatewayer{}
+			gateway.On("BackupDB").Return(tc.gatewayBackupDBResult, tc.gatewayBackupDBErr)
+
+			req, err := http.NewRequest(tc.method, endpoint, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			status := rr.Code
+			require.Equal(t, tc.status, status, "got `%v` want `%v`", status, tc.status)
+
+			if status != http.StatusOK {
+				require.Equal(t, tc.err, strings.TrimSpace(rr.Body.String()))
+				return
+			}
+
+			var msg DBBackupResponse
+			err = json.Unmarshal(rr.Body.Bytes(), &msg)
+			require.NoError(t, err)
+			require.Equal(t, tc.result, msg)
+		})
+	}
+}
+
+func TestDBOrphanedUxOuts(t *testing.T) {
+	orphan := historydb.UxOut{
+		Out: coin.UxOut{
+			Head: coin.UxHead{
+				Time:  1540000000,
+				BkSeq: 12,
+			},
+			Body: coin.UxBody{
+				SrcTransaction: testutil.RandSHA256(t),
+				Address:        testutil.MakeAddress(),
+				Coins:          1e6,
+				Hours:          3,
+			},
+		},
+	}
+
+	report := &visor.OrphanedUxOutsReport{
+		ParsedSeq: 10,
+		Scanned:   100,
+		Counts: map[historydb.OrphanedUxOutReason]uint64{
+			historydb.OrphanedUxOutUnparsedBlock: 1,
+			historydb.OrphanedUxOutMissingTxn:    2,
+		},
+		Orphans: []historydb.OrphanedUxOut{
+			{
+				UxOut:  orphan,
+				Reason: historydb.OrphanedUxOutUnparsedBlock,
+			},
+		},
+	}
+
+	result := DBOrphanedUxOutsResponse{
+		ParsedSeq: 10,
+		Scanned:   100,
+		Total:     3,
+		Counts: map[historydb.OrphanedUxOutReason]uint64{
+			historydb.OrphanedUxOutUnparsedBlock: 1,
+			historydb.OrphanedUxOutMissingTxn:    2,
+		},
+		Orphans: []DBOrphanedUxOut{
+			{
+				Reason: historydb.OrphanedUxOutUnparsedBlock,
+				Output: readable.NewSpentOutput(&orphan),
+			},
+		},
+	}
+
+	deletedReport := *report
+	deletedReport.Deleted = true
+	deletedResult := result
+	deletedResult.Deleted = true
+
+	tt := []struct {
+		name          string
+		method        string
+		status        int
+		err           string
+		gatewayDelete bool
+		gatewayResult *visor.OrphanedUxOutsReport
+		gatewayErr    error
+		result        DBOrphanedUxOutsResponse
+	}{
+		{
+			name:   "405",
+			method: http.MethodPut,
+			status: http.StatusMethodNotAllowed,
+			err:    "405 Method Not Allowed",
+		},
+		{
+			name:       "500 - gateway error",
+			method:     http.MethodGet,
+			status:     http.StatusInternalServerError,
+			err:        "500 Internal Server Error - database not open",
+			gatewayErr: errors.New("database not open"),
+		},
+		{
+			name:          "200 - GET",
+			method:        http.MethodGet,
+			status:        http.StatusOK,
+			gatewayResult: report,
+			result:        result,
+		},
+		{
+			name:          "200 - POST",
+			method:        http.MethodPost,
+			status:        http.StatusOK,
+			gatewayDelete: true,
+			gatewayResult: &deletedReport,
+			result:        deletedResult,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			endpoint := "/api/v1/db/orphaned_uxouts"
+			gateway := &MockGatewayer{}
+			gateway.On("CollectOrphanedUxOuts", tc.gatewayDelete).Return(tc.gatewayResult, tc.gatewayErr)
+
+			req, err := http.NewRequest(tc.method, endpoint, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			status := rr.Code
+			require.Equal(t, tc.status, status, "got `%v` want `%v`", status, tc.status)
+
+			if status != http.StatusOK {
+				require.Equal(t, tc.err, strings.TrimSpace(rr.Body.String()))
+				return
+			}
+
+			var msg DBOrphanedUxOutsResponse
+			err = json.Unmarshal(rr.Body.Bytes(), &msg)
+			require.NoError(t, err)
+			require.Equal(t, tc.result, msg)
+		})
+	}
+}
+
+func TestDBStats(t *testing.T) {
+	tt := []struct {
+		name                    string
+		method                  string
+		status                  int
+		err                     string
+		gatewayGetDBStatsResult *dbutil.Stats
+		gatewayGetDBStatsErr    error
+		result                  DBStatsResponse
+	}{
+		{
+			name:   "405",
+			method: http.MethodPost,
+			status: http.StatusMethodNotAllowed,
+			err:    "405 Method Not Allowed",
+		},
+		{
+			name:                 "500 - gateway error",
+			method:               http.MethodGet,
+			status:               http.StatusInternalServerError,
+			err:                  "500 Internal Server Error - database not open",
+			gatewayGetDBStatsErr: errors.New("database not open"),
+		},
+		{
+			name:   "200",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			gatewayGetDBStatsResult: &dbutil.Stats{
+				Files: []dbutil.FileStats{
+					{
+						Size:      1 << 30,
+						PageSize:  4096,
+						FreePages: 10,
+						FreeBytes: 40960,
+					},
+					{
+						Part:     "history",
+						Size:     1 << 29,
+						PageSize: 4096,
+					},
+				},
+				Buckets: []dbutil.BucketStats{
+					{
+						Name: "address_txns",
+						Part: "history",
+						BucketStats: bolt.BucketStats{
+							KeyN:          1000,
+							Depth:         3,
+							BranchPageN:   2,
+							LeafPageN:     100,
+							LeafOverflowN: 5,
+							BranchAlloc:   8192,
+							BranchInuse:   4096,
+							LeafAlloc:     409600,
+							LeafInuse:     204800,
+						},
+					},
+					{
+						Name: "blockchain_meta",
+						BucketStats: bolt.BucketStats{
+							KeyN:          2,
+							Depth:         1,
+							InlineBucketN: 1,
+						},
+					},
+				},
+			},
+			result: DBStatsResponse{
+				Files: []DBFileStats{
+					{
+						Size:      1 << 30,
+						PageSize:  4096,
+						FreePages: 10,
+						FreeBytes: 40960,
+					},
+					{
+						Part:     "history",
+						Size:     1 << 29,
+						PageSize: 4096,
+					},
+				},
+				Buckets: []DBBucketStats{
+					{
+						Name:              "address_txns",
+						Part:              "history",
+						Keys:              1000,
+						Depth:             3,
+						BranchPages:       2,
+						LeafPages:         100,
+						LeafOverflowPages: 5,
+						AllocBytes:        417792,
+						InuseBytes:        208896,
+						Utilization:       0.5,
+					},
+					{
+						Name:  "blockchain_meta",
+						Keys:  2,
+						Depth: 1,
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			endpoint := "/api/v1/db/stats"
+			gateway := &MockGatewayer{}
+			gateway.On("GetDBStats").Return(tc.gatewayGetDBStatsResult, tc.gatewayGetDBStatsErr)
+
+			req, err := http.NewRequest(tc.method, endpoint, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			status := rr.Code
+			require.Equal(t, tc.status, status, "got `%v` want `%v`", status, tc.status)
+
+			if status != http.StatusOK {
+				require.Equal(t, tc.err, strings.TrimSpace(rr.Body.String()))
+				return
+			}
+
+			var msg DBStatsResponse
+			err = json.Unmarshal(rr.Body.Bytes(), &msg)
+			require.NoError(t, err)
+			require.Equal(t, tc.result, msg)
+		})
+	}
+}
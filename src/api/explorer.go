@@ -1,9 +1,11 @@
 package api
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/coin"
@@ -11,6 +13,7 @@ import (
 	"github.com/skycoin/skycoin/src/readable"
 	"github.com/skycoin/skycoin/src/util/droplet"
 	wh "github.com/skycoin/skycoin/src/util/http"
+	"github.com/skycoin/skycoin/src/visor/historydb"
 )
 
 // CoinSupply records the coin supply info
@@ -204,17 +207,149 @@ func transactionsForAddressHandler(gateway Gatewayer) http.HandlerFunc {
 	}
 }
 
+const (
+	// defaultAddressTransactionsLimit is the number of transactions returned by /explorer/address_transactions
+	// if no limit is given
+	defaultAddressTransactionsLimit = 100
+	// maxAddressTransactionsLimit is the maximum number of transactions returned by /explorer/address_transactions
+	maxAddressTransactionsLimit = 1000
+)
+
+// AddressTransactionsPage is a page of the confirmed transactions of an address
+type AddressTransactionsPage struct {
+	Transactions []readable.TransactionVerbose `json:"transactions"`
+	// Cursor of the next page, empty if there are no more transactions
+	NextCursor string `json:"next_cursor"`
+}
+
+// formatAddressTxnsCursor formats a cursor as "$block_seq:$txn_index"
+func formatAddressTxnsCursor(c *historydb.AddressTxnsCursor) string {
+	if c == nil {
+		return ""
+	}
+	return fmt.Sprintf("%d:%d", c.BlockSeq, c.TxnIndex)
+}
+
+// parseAddressTxnsCursor parses a cursor formatted by formatAddressTxnsCursor
+func parseAddressTxnsCursor(s string) (*historydb.AddressTxnsCursor, error) {
+	pts := strings.Split(s, ":")
+	if len(pts) != 2 {
+		return nil, errors.New("invalid cursor")
+	}
+
+	seq, err := strconv.ParseUint(pts[0], 10, 64)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+
+	idx, err := strconv.ParseUint(pts[1], 10, 64)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+
+	return &historydb.AddressTxnsCursor{
+		BlockSeq: seq,
+		TxnIndex: idx,
+	}, nil
+}
+
+// addressTransactionsHandler returns a page of the confirmed transactions of an address, in block order
+// Method: GET
+// URI: /api/v1/explorer/address_transactions?address=${address}&limit=${limit}&offset=${offset}&cursor=${cursor}
+// Args:
+//	address [string, the address]
+//	limit [int, maximum number of transactions returned, defaults to 100, at most 1000]
+//	offset [int, number of transactions skipped]
+//	cursor [string, next_cursor of the previous page, the page starts from the first transaction if empty]
+func addressTransactionsHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			wh.Error405(w)
+			return
+		}
+
+		addr := r.FormValue("address")
+		if addr == "" {
+			wh.Error400(w, "address is empty")
+			return
+		}
+
+		cipherAddr, err := cipher.DecodeBase58Address(addr)
+		if err != nil {
+			wh.Error400(w, "invalid address")
+			return
+		}
+
+		limit := uint64(defaultAddressTransactionsLimit)
+		if limitStr := r.FormValue("limit"); limitStr != "" {
+			limit, err = strconv.ParseUint(limitStr, 10, 64)
+			if err != nil {
+				wh.Error400(w, "invalid limit")
+				return
+			}
+
+			if limit == 0 || limit > maxAddressTransactionsLimit {
+				wh.Error400(w, fmt.Sprintf("limit must be between 1 and %d", maxAddressTransactionsLimit))
+				return
+			}
+		}
+
+		var offset uint64
+		if offsetStr := r.FormValue("offset"); offsetStr != "" {
+			offset, err = strconv.ParseUint(offsetStr, 10, 64)
+			if err != nil {
+				wh.Error400(w, "invalid offset")
+				return
+			}
+		}
+
+		var cursor *historydb.AddressTxnsCursor
+		if cursorStr := r.FormValue("cursor"); cursorStr != "" {
+			cursor, err = parseAddressTxnsCursor(cursorStr)
+			if err != nil {
+				wh.Error400(w, err.Error())
+				return
+			}
+		}
+
+		txns, inputs, next, err := gateway.GetVerboseTransactionsForAddressPage(cipherAddr, cursor, offset, limit)
+		if err != nil {
+			err = fmt.Errorf("gateway.GetVerboseTransactionsForAddressPage failed: %v", err)
+			wh.Error500(w, err.Error())
+			return
+		}
+
+		vb := make([]readable.TransactionVerbose, len(txns))
+		for i, txn := range txns {
+			v, err := readable.NewTransactionVerbose(txn, inputs[i])
+			if err != nil {
+				wh.Error500(w, err.Error())
+				return
+			}
+
+			vb[i] = v
+		}
+
+		wh.SendJSONOr500(logger, w, AddressTransactionsPage{
+			Transactions: vb,
+			NextCursor:   formatAddressTxnsCursor(next),
+		})
+	}
+}
+
 // Richlist contains top address balances
 type Richlist struct {
-	Richlist []readable.RichlistBalance `json:"richlist"`
+	Richlist            []readable.RichlistBalance `json:"richlist"`
+	BalanceDistribution []readable.BalanceRange    `json:"balance_distribution,omitempty"`
 }
 
-// richlistHandler returns the top skycoin holders
+// richlistHandler returns the top skycoin holders, read from the richlist index of the history db
 // Method: GET
-// URI: /richlist?n=${number}&include-distribution=${bool}
+// URI: /richlist?n=${number}&include-distribution=${bool}&include-balance-distribution=${bool}
 // Args:
-//	n [int, number of results to include]
+//	n [int, number of results to include, all if <= 0]
 //  include-distribution [bool, include the distribution addresses in the richlist]
+//  include-balance-distribution [bool, include the number of addresses and coins in each balance range]
 func richlistHandler(gateway Gatewayer) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
@@ -248,19 +383,47 @@ func richlistHandler(gateway Gatewayer) http.HandlerFunc {
 			}
 		}
 
-		richlist, err := gateway.GetRichlist(includeDistribution)
+		var includeBalanceDistribution bool
+		includeBalanceDistributionStr := r.FormValue("include-balance-distribution")
+		if includeBalanceDistributionStr != "" {
+			var err error
+			includeBalanceDistribution, err = strconv.ParseBool(includeBalanceDistributionStr)
+			if err != nil {
+				wh.Error400(w, "invalid include-balance-distribution")
+				return
+			}
+		}
+
+		var n uint64
+		if topn > 0 {
+			n = uint64(topn)
+		}
+
+		richlist, err := gateway.GetRichlist(n, includeDistribution)
 		if err != nil {
 			wh.Error500(w, err.Error())
 			return
 		}
 
-		if topn > 0 && topn < len(richlist) {
-			richlist = richlist[:topn]
+		resp := Richlist{
+			Richlist: readable.NewRichlistBalances(richlist),
 		}
 
-		wh.SendJSONOr500(logger, w, Richlist{
-			Richlist: readable.NewRichlistBalances(richlist),
-		})
+		if includeBalanceDistribution {
+			ranges, err := gateway.GetBalanceDistribution()
+			if err != nil {
+				wh.Error500(w, err.Error())
+				return
+			}
+
+			resp.BalanceDistribution, err = readable.NewBalanceDistribution(ranges)
+			if err != nil {
+				wh.Error500(w, err.Error())
+				return
+			}
+		}
+
+		wh.SendJSONOr500(logger, w, resp)
 	}
 }
 
@@ -283,3 +446,48 @@ func addressCountHandler(gateway Gatewayer) http.HandlerFunc {
 		wh.SendJSONOr500(logger, w, &map[string]uint64{"count": addrCount})
 	}
 }
+
+// addressMetaHandler returns when an address was first and last used, its transaction count and the coins it received and sent
+// Method: GET
+// URI: /api/v1/explorer/address_meta?address=${address}
+// Args:
+//	address [string, the address]
+func addressMetaHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			wh.Error405(w)
+			return
+		}
+
+		addr := r.FormValue("address")
+		if addr == "" {
+			wh.Error400(w, "address is empty")
+			return
+		}
+
+		cipherAddr, err := cipher.DecodeBase58Address(addr)
+		if err != nil {
+			wh.Error400(w, "invalid address")
+			return
+		}
+
+		meta, err := gateway.GetAddressMeta(cipherAddr)
+		if err != nil {
+			wh.Error500(w, err.Error())
+			return
+		}
+
+		if meta == nil {
+			wh.Error404(w, "address was never used")
+			return
+		}
+
+		rMeta, err := readable.NewAddressMeta(cipherAddr, meta)
+		if err != nil {
+			wh.Error500(w, err.Error())
+			return
+		}
+
+		wh.SendJSONOr500(logger, w, rMeta)
+	}
+}
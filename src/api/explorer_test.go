@@ -20,6 +20,7 @@ import (
 	"github.com/skycoin/skycoin/src/testutil"
 	"github.com/skycoin/skycoin/src/util/droplet"
 	"github.com/skycoin/skycoin/src/visor"
+	"github.com/skycoin/skycoin/src/visor/historydb"
 )
 
 func makeSuccessCoinSupplyResult(t *testing.T, allUnspents readable.UnspentOutputsSummary) *CoinSupply {
@@ -395,20 +396,25 @@ func TestCoinSupply(t *testing.T) {
 
 func TestGetRichlist(t *testing.T) {
 	type httpParams struct {
-		topn                string
-		includeDistribution string
+		topn                       string
+		includeDistribution        string
+		includeBalanceDistribution string
 	}
 	tt := []struct {
-		name                     string
-		method                   string
-		status                   int
-		err                      string
-		httpParams               *httpParams
-		includeDistribution      bool
-		gatewayGetRichlistResult visor.Richlist
-		gatewayGetRichlistErr    error
-		result                   Richlist
-		csrfDisabled             bool
+		name                                string
+		method                              string
+		status                              int
+		err                                 string
+		httpParams                          *httpParams
+		n                                   uint64
+		includeDistribution                 bool
+		gatewayGetRichlistResult            visor.Richlist
+		gatewayGetRichlistErr               error
+		includeBalanceDistribution          bool
+		gatewayGetBalanceDistributionResult []historydb.BalanceRange
+		gatewayGetBalanceDistributionErr    error
+		result                              Richlist
+		csrfDisabled                        bool
 	}{
 		{
 			name:   "405",
@@ -435,6 +441,16 @@ func TestGetRichlist(t *testing.T) {
 				includeDistribution: "bad include-distribution",
 			},
 		},
+		{
+			name:   "400 - include-balance-distribution",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - invalid include-balance-distribution",
+			httpParams: &httpParams{
+				topn:                       "1",
+				includeBalanceDistribution: "bad include-balance-distribution",
+			},
+		},
 		{
 			name:   "500 - gw GetRichlist error",
 			method: http.MethodGet,
@@ -444,8 +460,29 @@ func TestGetRichlist(t *testing.T) {
 				topn:                "1",
 				includeDistribution: "false",
 			},
+			n:                     1,
 			gatewayGetRichlistErr: errors.New("gatewayGetRichlistErr"),
 		},
+		{
+			name:   "500 - gw GetBalanceDistribution error",
+			method: http.MethodGet,
+			status: http.StatusInternalServerError,
+			err:    "500 Internal Server Error - gatewayGetBalanceDistributionErr",
+			httpParams: &httpParams{
+				topn:                       "1",
+				includeBalanceDistribution: "true",
+			},
+			n: 1,
+			gatewayGetRichlistResult: visor.Richlist{
+				{
+					Address: "2fGC7kwAM9yZyEF1QqBqp8uo9RUsF6ENGJF",
+					Coins:   "1000000.000000",
+					Locked:  false,
+				},
+			},
+			includeBalanceDistribution:       true,
+			gatewayGetBalanceDistributionErr: errors.New("gatewayGetBalanceDistributionErr"),
+		},
 		{
 			name:   "200",
 			method: http.MethodGet,
@@ -454,6 +491,7 @@ func TestGetRichlist(t *testing.T) {
 				topn:                "3",
 				includeDistribution: "false",
 			},
+			n: 3,
 			gatewayGetRichlistResult: visor.Richlist{
 				{
 					Address: "2fGC7kwAM9yZyEF1QqBqp8uo9RUsF6ENGJF",
@@ -470,16 +508,6 @@ func TestGetRichlist(t *testing.T) {
 					Coins:   "500000.000000",
 					Locked:  false,
 				},
-				{
-					Address: "2TmvdBWJgxMwGs84R4drS9p5fYkva4dGdfs",
-					Coins:   "244458.000000",
-					Locked:  false,
-				},
-				{
-					Address: "24gvUHXHtSg5drKiFsMw7iMgoN2PbLub53C",
-					Coins:   "195503.000000",
-					Locked:  false,
-				},
 			},
 			result: Richlist{
 				Richlist: []readable.RichlistBalance{
@@ -566,13 +594,78 @@ func TestGetRichlist(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:   "200 with balance distribution",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			httpParams: &httpParams{
+				topn:                       "-1",
+				includeDistribution:        "true",
+				includeBalanceDistribution: "true",
+			},
+			includeDistribution: true,
+			gatewayGetRichlistResult: visor.Richlist{
+				{
+					Address: "2fGC7kwAM9yZyEF1QqBqp8uo9RUsF6ENGJF",
+					Coins:   "1000000.000000",
+					Locked:  true,
+				},
+			},
+			includeBalanceDistribution: true,
+			gatewayGetBalanceDistributionResult: []historydb.BalanceRange{
+				{
+					MinCoins:     1,
+					AddressCount: 2,
+					Coins:        500,
+				},
+				{
+					MinCoins:     1e6,
+					AddressCount: 0,
+					Coins:        0,
+				},
+				{
+					MinCoins:     1000000e6,
+					AddressCount: 1,
+					Coins:        1000000e6,
+				},
+			},
+			result: Richlist{
+				Richlist: []readable.RichlistBalance{
+					{
+						Address: "2fGC7kwAM9yZyEF1QqBqp8uo9RUsF6ENGJF",
+						Coins:   "1000000.000000",
+						Locked:  true,
+					},
+				},
+				BalanceDistribution: []readable.BalanceRange{
+					{
+						MinCoins:     "0.000001",
+						AddressCount: 2,
+						Coins:        "0.000500",
+					},
+					{
+						MinCoins:     "1.000000",
+						AddressCount: 0,
+						Coins:        "0.000000",
+					},
+					{
+						MinCoins:     "1000000.000000",
+						AddressCount: 1,
+						Coins:        "1000000.000000",
+					},
+				},
+			},
+		},
 	}
 
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
 			endpoint := "/api/v1/richlist"
 			gateway := &MockGatewayer{}
-			gateway.On("GetRichlist", tc.includeDistribution).Return(tc.gatewayGetRichlistResult, tc.gatewayGetRichlistErr)
+			gateway.On("GetRichlist", tc.n, tc.includeDistribution).Return(tc.gatewayGetRichlistResult, tc.gatewayGetRichlistErr)
+			if tc.includeBalanceDistribution {
+				gateway.On("GetBalanceDistribution").Return(tc.gatewayGetBalanceDistributionResult, tc.gatewayGetBalanceDistributionErr)
+			}
 
 			v := url.Values{}
 			if tc.httpParams != nil {
@@ -582,6 +675,9 @@ func TestGetRichlist(t *testing.T) {
 				if tc.httpParams.includeDistribution != "" {
 					v.Add("include-distribution", tc.httpParams.includeDistribution)
 				}
+				if tc.httpParams.includeBalanceDistribution != "" {
+					v.Add("include-balance-distribution", tc.httpParams.includeBalanceDistribution)
+				}
 			}
 			if len(v) > 0 {
 				endpoint += "?" + v.Encode()
@@ -692,3 +788,355 @@ func TestGetAddressCount(t *testing.T) {
 		})
 	}
 }
+
+func TestGetAddressMeta(t *testing.T) {
+	addr := testutil.MakeAddress()
+	meta := &historydb.AddressMeta{
+		FirstSeenSeq:   2,
+		FirstSeenTime:  1000,
+		LastActiveSeq:  7,
+		LastActiveTime: 6000,
+		TxnCount:       3,
+		Received:       12e6,
+		Sent:           1500000,
+	}
+
+	tt := []struct {
+		name                        string
+		method                      string
+		status                      int
+		err                         string
+		address                     string
+		gatewayGetAddressMetaArg    cipher.Address
+		gatewayGetAddressMetaResult *historydb.AddressMeta
+		gatewayGetAddressMetaErr    error
+		result                      *readable.AddressMeta
+	}{
+		{
+			name:   "405",
+			method: http.MethodPost,
+			status: http.StatusMethodNotAllowed,
+			err:    "405 Method Not Allowed",
+		},
+		{
+			name:   "400 - address is empty",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - address is empty",
+		},
+		{
+			name:    "400 - invalid address",
+			method:  http.MethodGet,
+			status:  http.StatusBadRequest,
+			err:     "400 Bad Request - invalid address",
+			address: "badaddr",
+		},
+		{
+			name:                     "500 - gw GetAddressMeta error",
+			method:                   http.MethodGet,
+			status:                   http.StatusInternalServerError,
+			err:                      "500 Internal Server Error - gatewayGetAddressMetaErr",
+			address:                  addr.String(),
+			gatewayGetAddressMetaArg: addr,
+			gatewayGetAddressMetaErr: errors.New("gatewayGetAddressMetaErr"),
+		},
+		{
+			name:                     "404 - address was never used",
+			method:                   http.MethodGet,
+			status:                   http.StatusNotFound,
+			err:                      "404 Not Found - address was never used",
+			address:                  addr.String(),
+			gatewayGetAddressMetaArg: addr,
+		},
+		{
+			name:                        "200",
+			method:                      http.MethodGet,
+			status:                      http.StatusOK,
+			address:                     addr.String(),
+			gatewayGetAddressMetaArg:    addr,
+			gatewayGetAddressMetaResult: meta,
+			result: &readable.AddressMeta{
+				Address:            addr.String(),
+				FirstSeenBlockSeq:  2,
+				FirstSeenTime:      1000,
+				LastActiveBlockSeq: 7,
+				LastActiveTime:     6000,
+				TxnCount:           3,
+				Received:           "12.000000",
+				Sent:               "1.500000",
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			endpoint := "/api/v1/explorer/address_meta"
+			gateway := &MockGatewayer{}
+			gateway.On("GetAddressMeta", tc.gatewayGetAddressMetaArg).Return(tc.gatewayGetAddressMetaResult, tc.gatewayGetAddressMetaErr)
+
+			v := url.Values{}
+			if tc.address != "" {
+				v.Add("address", tc.address)
+			}
+			if len(v) > 0 {
+				endpoint += "?" + v.Encode()
+			}
+
+			req, err := http.NewRequest(tc.method, endpoint, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			status := rr.Code
+			require.Equal(t, tc.status, status, "got `%v` want `%v`", status, tc.status)
+
+			if status != http.StatusOK {
+				require.Equal(t, tc.err, strings.TrimSpace(rr.Body.String()), "got `%v`| %d, want `%v`",
+					strings.TrimSpace(rr.Body.String()), status, tc.err)
+			} else {
+				var msg *readable.AddressMeta
+				err = json.Unmarshal(rr.Body.Bytes(), &msg)
+				require.NoError(t, err)
+				require.Equal(t, tc.result, msg)
+			}
+		})
+	}
+}
+
+func TestGetAddressTransactionsPage(t *testing.T) {
+	addr := testutil.MakeAddress()
+	inputAddress := "111111111111111111111691FSP"
+	inputAddressRaw, err := cipher.DecodeBase58Address(inputAddress)
+	require.NoError(t, err)
+
+	validHashRaw, err := cipher.SHA256FromHex("79216473e8f2c17095c6887cc9edca6c023afedfac2e0c5460e8b6f359684f8b")
+	require.NoError(t, err)
+
+	txns := []visor.Transaction{
+		{
+			Transaction: coin.Transaction{
+				In: []cipher.SHA256{
+					validHashRaw,
+				},
+			},
+			Status: visor.NewConfirmedTransactionStatus(3, 10),
+			Time:   1000,
+		},
+	}
+
+	inputs := [][]visor.TransactionInput{
+		{
+			{
+				UxOut: coin.UxOut{
+					Body: coin.UxBody{
+						Address: inputAddressRaw,
+						Coins:   99000000,
+						Hours:   100,
+					},
+				},
+				CalculatedHours: 101,
+			},
+		},
+	}
+
+	tt := []struct {
+		name          string
+		method        string
+		status        int
+		err           string
+		address       string
+		limit         string
+		offset        string
+		cursor        string
+		gatewayArg    *historydb.AddressTxnsCursor
+		gatewayLimit  uint64
+		gatewayOffset uint64
+		gatewayNext   *historydb.AddressTxnsCursor
+		gatewayErr    error
+		result        *AddressTransactionsPage
+	}{
+		{
+			name:   "405",
+			method: http.MethodPost,
+			status: http.StatusMethodNotAllowed,
+			err:    "405 Method Not Allowed",
+		},
+		{
+			name:   "400 - address is empty",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - address is empty",
+		},
+		{
+			name:    "400 - invalid address",
+			method:  http.MethodGet,
+			status:  http.StatusBadRequest,
+			err:     "400 Bad Request - invalid address",
+			address: "badaddr",
+		},
+		{
+			name:    "400 - invalid limit",
+			method:  http.MethodGet,
+			status:  http.StatusBadRequest,
+			err:     "400 Bad Request - invalid limit",
+			address: addr.String(),
+			limit:   "-1",
+		},
+		{
+			name:    "400 - limit is 0",
+			method:  http.MethodGet,
+			status:  http.StatusBadRequest,
+			err:     "400 Bad Request - limit must be between 1 and 1000",
+			address: addr.String(),
+			limit:   "0",
+		},
+		{
+			name:    "400 - limit too large",
+			method:  http.MethodGet,
+			status:  http.StatusBadRequest,
+			err:     "400 Bad Request - limit must be between 1 and 1000",
+			address: addr.String(),
+			limit:   "1001",
+		},
+		{
+			name:    "400 - invalid offset",
+			method:  http.MethodGet,
+			status:  http.StatusBadRequest,
+			err:     "400 Bad Request - invalid offset",
+			address: addr.String(),
+			offset:  "x",
+		},
+		{
+			name:    "400 - invalid cursor",
+			method:  http.MethodGet,
+			status:  http.StatusBadRequest,
+			err:     "400 Bad Request - invalid cursor",
+			address: addr.String(),
+			cursor:  "10",
+		},
+		{
+			name:    "400 - invalid cursor seq",
+			method:  http.MethodGet,
+			status:  http.StatusBadRequest,
+			err:     "400 Bad Request - invalid cursor",
+			address: addr.String(),
+			cursor:  "a:1",
+		},
+		{
+			name:         "500 - gw GetVerboseTransactionsForAddressPage error",
+			method:       http.MethodGet,
+			status:       http.StatusInternalServerError,
+			err:          "500 Internal Server Error - gateway.GetVerboseTransactionsForAddressPage failed: gatewayErr",
+			address:      addr.String(),
+			gatewayLimit: 100,
+			gatewayErr:   errors.New("gatewayErr"),
+		},
+		{
+			name:         "200 - default limit, last page",
+			method:       http.MethodGet,
+			status:       http.StatusOK,
+			address:      addr.String(),
+			gatewayLimit: 100,
+			result: &AddressTransactionsPage{
+				Transactions: []readable.TransactionVerbose{},
+			},
+		},
+		{
+			name:          "200 - cursor, offset and limit",
+			method:        http.MethodGet,
+			status:        http.StatusOK,
+			address:       addr.String(),
+			limit:         "1",
+			offset:        "2",
+			cursor:        "10:0",
+			gatewayArg:    &historydb.AddressTxnsCursor{BlockSeq: 10},
+			gatewayLimit:  1,
+			gatewayOffset: 2,
+			gatewayNext:   &historydb.AddressTxnsCursor{BlockSeq: 12, TxnIndex: 3},
+			result: &AddressTransactionsPage{
+				Transactions: []readable.TransactionVerbose{
+					{
+						Status: &readable.TransactionStatus{
+							Confirmed: true,
+							Height:    3,
+							BlockSeq:  10,
+						},
+						Timestamp: 1000,
+						BlockTransactionVerbose: readable.BlockTransactionVerbose{
+							Hash:      "4fa025f043d1e5e8895ca4dc6602dac8d5c315544c166044d80c98a09e950c71",
+							InnerHash: "0000000000000000000000000000000000000000000000000000000000000000",
+							Fee:       101,
+							Sigs:      []string{},
+							In: []readable.TransactionInput{
+								{
+									Hash:            "e8ca653d9953b548f0098dd303f8166e636856a5c40e478e3756e440c01e9cb9",
+									Address:         inputAddress,
+									Coins:           "99.000000",
+									Hours:           100,
+									CalculatedHours: 101,
+								},
+							},
+							Out: []readable.TransactionOutput{},
+						},
+					},
+				},
+				NextCursor: "12:3",
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			endpoint := "/api/v1/explorer/address_transactions"
+			gateway := &MockGatewayer{}
+
+			var gatewayTxns []visor.Transaction
+			var gatewayInputs [][]visor.TransactionInput
+			if tc.result != nil && len(tc.result.Transactions) > 0 {
+				gatewayTxns = txns
+				gatewayInputs = inputs
+			}
+			gateway.On("GetVerboseTransactionsForAddressPage", addr, tc.gatewayArg, tc.gatewayOffset, tc.gatewayLimit).Return(gatewayTxns,
+				gatewayInputs, tc.gatewayNext, tc.gatewayErr)
+
+			v := url.Values{}
+			if tc.address != "" {
+				v.Add("address", tc.address)
+			}
+			if tc.limit != "" {
+				v.Add("limit", tc.limit)
+			}
+			if tc.offset != "" {
+				v.Add("offset", tc.offset)
+			}
+			if tc.cursor != "" {
+				v.Add("cursor", tc.cursor)
+			}
+			if len(v) > 0 {
+				endpoint += "?" + v.Encode()
+			}
+
+			req, err := http.NewRequest(tc.method, endpoint, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			status := rr.Code
+			require.Equal(t, tc.status, status, "got `%v` want `%v`", status, tc.status)
+
+			if status != http.StatusOK {
+				require.Equal(t, tc.err, strings.TrimSpace(rr.Body.String()), "got `%v`| %d, want `%v`",
+					strings.TrimSpace(rr.Body.String()), status, tc.err)
+			} else {
+				var msg *AddressTransactionsPage
+				err = json.Unmarshal(rr.Body.Bytes(), &msg)
+				require.NoError(t, err)
+				require.Equal(t, tc.result, msg)
+			}
+		})
+	}
+}
@@ -1,12 +1,18 @@
 package api
 
 import (
+	"context"
+	"time"
+
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/coin"
 	"github.com/skycoin/skycoin/src/daemon"
+	"github.com/skycoin/skycoin/src/daemon/pex"
 	"github.com/skycoin/skycoin/src/visor"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
 	"github.com/skycoin/skycoin/src/visor/historydb"
 	"github.com/skycoin/skycoin/src/wallet"
+	"github.com/skycoin/skycoin/src/wallet/history"
 )
 
 //go:generate go install
@@ -16,23 +22,52 @@ import (
 type Gatewayer interface {
 	Spend(wltID string, password []byte, coins uint64, dest cipher.Address) (*coin.Transaction, error)
 	CreateTransaction(w wallet.CreateTransactionParams) (*coin.Transaction, []wallet.UxBalance, error)
+	CreatePayoutBatch(w wallet.CreateTransactionParams, maxOutputs int) (*wallet.PayoutBatch, error)
+	CreateHoursRedistribution(p wallet.HoursRedistributionParams) (*coin.Transaction, []wallet.UxBalance, error)
+	SweepPrivateKeys(keys []cipher.SecKey, wltID string, dest cipher.Address) (*wallet.Sweep, error)
 	GetWalletBalance(wltID string) (wallet.BalancePair, wallet.AddressBalances, error)
+	GetWalletAccountBalance(wltID, account string) (wallet.BalancePair, wallet.AddressBalances, error)
+	CreateWalletAccount(wltID, name string, password []byte) (wallet.Account, error)
 	GetWallet(wltID string) (*wallet.Wallet, error)
 	GetWallets() (wallet.Wallets, error)
 	UpdateWalletLabel(wltID, label string) error
+	SetAddressAnnotation(wltID string, addr cipher.Address, a wallet.Annotation) (*wallet.Wallet, error)
+	SetTransactionAnnotation(wltID string, txid cipher.SHA256, a wallet.Annotation) (*wallet.Wallet, error)
+	SetSpendingPolicy(wltID string, password []byte, p wallet.SpendingPolicy) (*wallet.Wallet, error)
+	SetCosigners(wltID string, cs wallet.Cosigners) (*wallet.Wallet, error)
+	RescanWallet(wltID string, password []byte, gapLimit, numTxns uint64) (*visor.WalletRescan, error)
+	WalletAddressPoolStatus(wltID, account string) (wallet.AddressPoolStatus, error)
+	FillWalletAddressPool(wltID, account string, password []byte, size uint64) ([]cipher.Address, wallet.AddressPoolStatus, error)
+	TakeWalletPoolAddresses(wltID, account string, n uint64) ([]cipher.Address, wallet.AddressPoolStatus, error)
 	GetWalletUnconfirmedTransactions(wltID string) ([]visor.UnconfirmedTransaction, error)
 	GetWalletUnconfirmedTransactionsVerbose(wltID string) ([]visor.UnconfirmedTransaction, [][]visor.TransactionInput, error)
+	GetWalletHistory(wltID, account string, ps history.PriceSource) ([]history.Entry, error)
+	GetWalletEvents(wltID string, after, limit uint64) ([]visor.WalletEvent, error)
+	WaitWalletEvents(ctx context.Context, wltID string, after, limit uint64) ([]visor.WalletEvent, error)
+	SubscribeWalletEvents(wltID string) (<-chan visor.WalletEvent, func(), error)
 	CreateWallet(wltName string, options wallet.Options) (*wallet.Wallet, error)
-	RecoverWallet(wltID, seed string, password []byte) (*wallet.Wallet, error)
+	RecoverWallet(wltID, seed, seedPassphrase string, password []byte) (*wallet.Wallet, error)
+	CreateWatchOnlyWallet(wltName, label string, addrs []cipher.Address) (*wallet.Wallet, error)
+	AddWatchOnlyAddresses(wltID string, addrs []cipher.Address) (*wallet.Wallet, error)
+	AddMultisig(wltID string, ms cipher.Multisig) (*wallet.Wallet, error)
+	SignPartialTransaction(wltID string, password []byte, ptx *wallet.PartialTransaction) (*wallet.PartialTransaction, error)
+	CosignPartialTransaction(wltID string, password []byte, ptx *wallet.PartialTransaction) (*wallet.PartialTransaction, []wallet.CosignerStatus, error)
 	NewAddresses(wltID string, password []byte, n uint64) ([]cipher.Address, error)
 	GetWalletDir() (string, error)
 	EncryptWallet(wltID string, password []byte) (*wallet.Wallet, error)
 	DecryptWallet(wltID string, password []byte) (*wallet.Wallet, error)
 	GetWalletSeed(wltID string, password []byte) (string, error)
+	BackupWallets(ids []string, passphrase []byte) (*wallet.BackupArchive, error)
+	RestoreWallets(a wallet.BackupArchive, passphrase []byte, skipConflicts bool) (*wallet.RestoreResult, error)
+	ImportWallet(wltName string, opts wallet.ImportOptions) (*wallet.Wallet, error)
 	GetSignedBlockByHash(hash cipher.SHA256) (*coin.SignedBlock, error)
 	GetSignedBlockByHashVerbose(hash cipher.SHA256) (*coin.SignedBlock, [][]visor.TransactionInput, error)
 	GetSignedBlockBySeq(seq uint64) (*coin.SignedBlock, error)
 	GetSignedBlockBySeqVerbose(seq uint64) (*coin.SignedBlock, [][]visor.TransactionInput, error)
+	GetSignedBlockByTime(t uint64) (*coin.SignedBlock, error)
+	GetSignedBlockByTimeVerbose(t uint64) (*coin.SignedBlock, [][]visor.TransactionInput, error)
+	GetBlockHeaderByHash(hash cipher.SHA256) (*visor.CommittedBlockHeader, error)
+	GetBlockHeaderBySeq(seq uint64) (*visor.CommittedBlockHeader, error)
 	GetBlocks(seqs []uint64) ([]coin.SignedBlock, error)
 	GetBlocksVerbose(seqs []uint64) ([]coin.SignedBlock, [][][]visor.TransactionInput, error)
 	GetBlocksInRange(start, end uint64) ([]coin.SignedBlock, error)
@@ -49,20 +84,50 @@ type Gatewayer interface {
 	GetDefaultConnections() []string
 	GetTrustConnections() []string
 	GetExchgConnection() []string
+	GetBans() []pex.Ban
+	Unban(ip string) error
+	GetPeers() []pex.Peer
+	AddPeers(addrs []string) error
+	RemovePeers(addrs []string) error
+	BanPeers(ips []string, reason string, duration time.Duration) error
+	GetRelayPolicy() daemon.RelayPolicyStatus
+	GetCompressionStatus() daemon.CompressionStatus
+	SubscribeConnectionEvents() (<-chan daemon.ConnectionEvent, func())
 	GetAllUnconfirmedTransactions() ([]visor.UnconfirmedTransaction, error)
 	GetAllUnconfirmedTransactionsVerbose() ([]visor.UnconfirmedTransaction, [][]visor.TransactionInput, error)
+	GetEvictedUnconfirmedTxns(after, limit uint64) ([]visor.EvictedUnconfirmedTxn, error)
+	GetRebroadcastStats() []daemon.RebroadcastStats
+	GetTxnRebroadcastStats(txid cipher.SHA256) *daemon.RebroadcastStats
 	GetTransaction(txid cipher.SHA256) (*visor.Transaction, error)
+	GetTransactionInclusionProof(txid cipher.SHA256) (*visor.TransactionInclusionProof, error)
 	GetTransactionVerbose(txid cipher.SHA256) (*visor.Transaction, []visor.TransactionInput, error)
 	GetTransactions(flts []visor.TxFilter) ([]visor.Transaction, error)
 	GetTransactionsVerbose(flts []visor.TxFilter) ([]visor.Transaction, [][]visor.TransactionInput, error)
 	InjectBroadcastTransaction(txn coin.Transaction) error
 	ResendUnconfirmedTxns() ([]cipher.SHA256, error)
 	GetUxOutByID(id cipher.SHA256) (*historydb.UxOut, error)
+	GetUxOutSpender(id cipher.SHA256) (*historydb.UxOutSpender, error)
+	GetOutputsByValue(q visor.OutputsByValueQuery, cursor *historydb.OutputValuesCursor, limit uint64) (*visor.OutputsByValuePage, error)
 	GetSpentOutputsForAddresses(addr []cipher.Address) ([][]historydb.UxOut, error)
 	GetVerboseTransactionsForAddress(a cipher.Address) ([]visor.Transaction, [][]visor.TransactionInput, error)
-	GetRichlist(includeDistribution bool) (visor.Richlist, error)
+	GetVerboseTransactionsForAddressPage(a cipher.Address, cursor *historydb.AddressTxnsCursor, offset, limit uint64) ([]visor.Transaction, [][]visor.TransactionInput, *historydb.AddressTxnsCursor, error)
+	GetRichlist(n uint64, includeDistribution bool) (visor.Richlist, error)
+	GetBalanceDistribution() ([]historydb.BalanceRange, error)
 	GetAddressCount() (uint64, error)
+	GetAddressMeta(addr cipher.Address) (*historydb.AddressMeta, error)
+	GetAddressBalanceHistory(addr cipher.Address, start, end uint64) (*visor.AddressBalanceHistory, error)
+	ScanAddresses(addrs []cipher.Address, numTxns uint64) ([]visor.AddressScan, error)
 	GetHealth() (*daemon.Health, error)
 	UnloadWallet(id string) error
 	VerifyTxnVerbose(txn *coin.Transaction) ([]wallet.UxBalance, bool, error)
+	GetDBVerifyStatus() visor.DBVerifyStatus
+	GetDBIntegrityReport() (*visor.DBIntegrityReport, error)
+	BackupDB() (*visor.DBBackup, error)
+	GetDBStats() (*dbutil.Stats, error)
+	CollectOrphanedUxOuts(del bool) (*visor.OrphanedUxOutsReport, error)
+	AddWatchAddresses(addrs []cipher.Address) (uint64, error)
+	RemoveWatchAddresses(addrs []cipher.Address) (uint64, error)
+	GetWatchAddresses() ([]cipher.Address, error)
+	GetWatchEvents(after, limit uint64) ([]visor.WatchEvent, error)
+	WaitWatchEvents(ctx context.Context, after, limit uint64) ([]visor.WatchEvent, error)
 }
@@ -16,6 +16,25 @@ type BlockchainMetadata struct {
 	TimeSinceLastBlock wh.Duration `json:"time_since_last_block"`
 }
 
+// DBReplicaHealth is the status of the read replica of the database, which serves the block and history queries
+type DBReplicaHealth struct {
+	HeadSeq     uint64 `json:"head_seq"`
+	RefreshedAt int64  `json:"refreshed_at"`
+	// BlocksBehind is the number of blocks the node has that the replica does not have yet
+	BlocksBehind uint64 `json:"blocks_behind"`
+}
+
+// ClockSkewHealth is the offset of the local clock from the clocks of the peers
+type ClockSkewHealth struct {
+	// Offset is the median of the peers' clocks minus the local clock
+	Offset wh.Duration `json:"offset"`
+	// Adjustment is the offset applied to the local clock for the network-adjusted time
+	Adjustment wh.Duration `json:"adjustment"`
+	Samples    int         `json:"samples"`
+	// Skewed is true if the offset exceeds the warning threshold, the local clock is probably wrong
+	Skewed bool `json:"skewed"`
+}
+
 // HealthResponse is returned by the /health endpoint
 type HealthResponse struct {
 	BlockchainMetadata            BlockchainMetadata `json:"blockchain"`
@@ -36,6 +55,9 @@ type HealthResponse struct {
 	UnconfirmedBurnFactor         uint32             `json:"unconfirmed_burn_factor"`
 	UserMaxTransactionSize        uint32             `json:"user_max_transaction_size"`
 	UnconfirmedMaxTransactionSize uint32             `json:"unconfirmed_max_transaction_size"`
+	DBReplica                     *DBReplicaHealth   `json:"db_replica,omitempty"`
+	Offline                       bool               `json:"offline"`
+	ClockSkew                     ClockSkewHealth    `json:"clock_skew"`
 }
 
 // healthHandler returns node health data
@@ -66,6 +88,17 @@ func healthHandler(c muxConfig, csrfStore *CSRFStore, gateway Gatewayer) http.Ha
 			return
 		}
 
+		var dbReplica *DBReplicaHealth
+		if health.DBReplica != nil && !health.DBReplica.RefreshedAt.IsZero() {
+			dbReplica = &DBReplicaHealth{
+				HeadSeq:     health.DBReplica.HeadSeq,
+				RefreshedAt: health.DBReplica.RefreshedAt.Unix(),
+			}
+			if headSeq := health.BlockchainMetadata.HeadBlock.Head.BkSeq; headSeq > dbReplica.HeadSeq {
+				dbReplica.BlocksBehind = headSeq - dbReplica.HeadSeq
+			}
+		}
+
 		wh.SendJSONOr500(logger, w, HealthResponse{
 			BlockchainMetadata: BlockchainMetadata{
 				BlockchainMetadata: readable.NewBlockchainMetadata(health.BlockchainMetadata),
@@ -88,6 +121,14 @@ func healthHandler(c muxConfig, csrfStore *CSRFStore, gateway Gatewayer) http.Ha
 			UserMaxTransactionSize:        params.UserMaxTransactionSize,
 			UnconfirmedBurnFactor:         health.UnconfirmedBurnFactor,
 			UnconfirmedMaxTransactionSize: health.UnconfirmedMaxTransactionSize,
+			DBReplica:                     dbReplica,
+			Offline:                       health.Offline,
+			ClockSkew: ClockSkewHealth{
+				Offset:     wh.FromDuration(health.ClockSkew.Offset),
+				Adjustment: wh.FromDuration(health.ClockSkew.Adjustment),
+				Samples:    health.ClockSkew.Samples,
+				Skewed:     health.ClockSkew.Skewed,
+			},
 		})
 	}
 }
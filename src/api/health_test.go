@@ -31,6 +31,9 @@ func TestHealthHandler(t *testing.T) {
 		cfg              muxConfig
 		csrfEnabled      bool
 		walletAPIEnabled bool
+		dbReplica        *visor.DBReplicaStatus
+		offline          bool
+		clockSkew        daemon.ClockSkew
 	}{
 		{
 			name:   "405 method not allowed",
@@ -75,6 +78,16 @@ func TestHealthHandler(t *testing.T) {
 			},
 			csrfEnabled:      true,
 			walletAPIEnabled: false,
+			dbReplica: &visor.DBReplicaStatus{
+				HeadSeq:     21170,
+				RefreshedAt: time.Unix(1523168700, 0),
+			},
+			offline: true,
+			clockSkew: daemon.ClockSkew{
+				Offset:  time.Minute * -10,
+				Samples: 12,
+				Skewed:  true,
+			},
 		},
 	}
 
@@ -131,6 +144,9 @@ func TestHealthHandler(t *testing.T) {
 				Uptime:                        time.Second * 4,
 				UnconfirmedBurnFactor:         params.UserBurnFactor * 2,
 				UnconfirmedMaxTransactionSize: params.UserMaxTransactionSize * 2,
+				DBReplica:                     tc.dbReplica,
+				Offline:                       tc.offline,
+				ClockSkew:                     tc.clockSkew,
 			}
 
 			gateway := &MockGatewayer{}
@@ -198,6 +214,21 @@ func TestHealthHandler(t *testing.T) {
 			require.Equal(t, uint32(32*1024), r.UserMaxTransactionSize)
 			require.Equal(t, health.UnconfirmedBurnFactor, r.UnconfirmedBurnFactor)
 			require.Equal(t, health.UnconfirmedMaxTransactionSize, r.UnconfirmedMaxTransactionSize)
+			require.Equal(t, tc.offline, r.Offline)
+			require.Equal(t, tc.clockSkew.Offset, r.ClockSkew.Offset.Duration)
+			require.Equal(t, tc.clockSkew.Adjustment, r.ClockSkew.Adjustment.Duration)
+			require.Equal(t, tc.clockSkew.Samples, r.ClockSkew.Samples)
+			require.Equal(t, tc.clockSkew.Skewed, r.ClockSkew.Skewed)
+
+			if tc.dbReplica == nil {
+				require.Nil(t, r.DBReplica)
+			} else {
+				require.Equal(t, &DBReplicaHealth{
+					HeadSeq:      21170,
+					RefreshedAt:  1523168700,
+					BlocksBehind: 5,
+				}, r.DBReplica)
+			}
 		})
 	}
 }
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/util/fee"
+	wh "github.com/skycoin/skycoin/src/util/http"
+	"github.com/skycoin/skycoin/src/visor"
+	"github.com/skycoin/skycoin/src/visor/blockdb"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+// hoursRedistributionRequest is sent to /api/v2/wallet/transaction/redistribute-hours
+type hoursRedistributionRequest struct {
+	IgnoreUnconfirmed bool                           `json:"ignore_unconfirmed"`
+	Wallet            createTransactionRequestWallet `json:"wallet"`
+	// Mode is "consolidate", "even" or "proportional"
+	Mode string `json:"mode"`
+	// To is the address that receives the coin hours in the consolidate mode
+	To       *wh.Address `json:"to,omitempty"`
+	Unsigned bool        `json:"unsigned"`
+}
+
+// Validate validates hoursRedistributionRequest data
+func (r hoursRedistributionRequest) Validate() error {
+	if r.Wallet.ID == "" {
+		return errors.New("missing wallet.id")
+	}
+
+	if r.Mode == "" {
+		return errors.New("missing mode")
+	}
+
+	if r.Unsigned && r.Wallet.Password != "" {
+		return errors.New("wallet.password must not be used for unsigned transactions")
+	}
+
+	if len(r.Wallet.UxOuts) != 0 && len(r.Wallet.Addresses) != 0 {
+		return errors.New("wallet.unspents and wallet.addresses cannot be combined")
+	}
+
+	return nil
+}
+
+// ToWalletParams converts hoursRedistributionRequest to wallet.HoursRedistributionParams
+func (r hoursRedistributionRequest) ToWalletParams() wallet.HoursRedistributionParams {
+	addresses := make([]cipher.Address, len(r.Wallet.Addresses))
+	for i, a := range r.Wallet.Addresses {
+		addresses[i] = a.Address
+	}
+
+	uxouts := make([]cipher.SHA256, len(r.Wallet.UxOuts))
+	for i, o := range r.Wallet.UxOuts {
+		uxouts[i] = o.SHA256
+	}
+
+	var to cipher.Address
+	if r.To != nil {
+		to = r.To.Address
+	}
+
+	return wallet.HoursRedistributionParams{
+		Wallet: wallet.CreateTransactionWalletParams{
+			ID:        r.Wallet.ID,
+			Addresses: addresses,
+			UxOuts:    uxouts,
+			Password:  []byte(r.Wallet.Password),
+			Account:   r.Wallet.Account,
+		},
+		IgnoreUnconfirmed: r.IgnoreUnconfirmed,
+		Mode:              r.Mode,
+		To:                to,
+		Unsigned:          r.Unsigned,
+	}
+}
+
+// URI: /api/v2/wallet/transaction/redistribute-hours
+// Method: POST
+// Content-Type: application/json
+// Args: JSON body:
+//  wallet: the wallet and the unspent outputs or addresses to spend, like for POST /api/v1/wallet/transaction
+//  mode: "consolidate", "even" or "proportional"
+//  to: [required for consolidate] the address that receives the coin hours, one of the addresses spent from
+//  ignore_unconfirmed: [optional] skip the unspent outputs spent by unconfirmed transactions
+//  unsigned: [optional] do not sign the transaction
+// Creates a transaction that redistributes the coin hours of a wallet's addresses between them without moving coins.
+// Each address spent from receives one output with its coins, and the coin hours left after the fee is burned
+// are all sent to one address, split evenly, or split proportionally to the coins of the addresses.
+// The transaction is not injected.
+func hoursRedistributionHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if r.Header.Get("Content-Type") != ContentTypeJSON {
+			resp := NewHTTPErrorResponse(http.StatusUnsupportedMediaType, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		var req hoursRedistributionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if err := req.Validate(); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		txn, inputs, err := gateway.CreateHoursRedistribution(req.ToWalletParams())
+		if err != nil {
+			var resp HTTPResponse
+			switch err.(type) {
+			case wallet.Error:
+				switch err {
+				case wallet.ErrWalletAPIDisabled:
+					resp = NewHTTPErrorResponse(http.StatusForbidden, "")
+				case wallet.ErrWalletNotExist:
+					resp = NewHTTPErrorResponse(http.StatusNotFound, "")
+				default:
+					resp = NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+				}
+			case blockdb.ErrUnspentNotExist,
+				visor.ErrTxnViolatesSoftConstraint,
+				visor.ErrTxnViolatesHardConstraint,
+				visor.ErrTxnViolatesUserConstraint:
+				resp = NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			default:
+				switch err {
+				case fee.ErrTxnNoFee,
+					wallet.ErrSpendingUnconfirmed:
+					resp = NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+				default:
+					resp = NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+				}
+			}
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		rsp, err := NewCreateTransactionResponse(txn, inputs)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: rsp,
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/util/fee"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+func TestHoursRedistributionHandler(t *testing.T) {
+	_, keys := cipher.MustGenerateDeterministicKeyPairsSeed([]byte("seed"), 2)
+	ptx := makeTestPartialTransaction(t, keys)
+	txn := ptx.Transaction
+	addr := txn.Out[0].Address
+	inputs, err := wallet.NewUxBalances(0, ptx.Inputs)
+	require.NoError(t, err)
+
+	txnRsp, err := NewCreateTransactionResponse(&txn, inputs)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name          string
+		method        string
+		contentType   string
+		body          string
+		gatewayParams *wallet.HoursRedistributionParams
+		gatewayTxn    *coin.Transaction
+		gatewayInputs []wallet.UxBalance
+		gatewayErr    error
+		status        int
+		err           string
+		rsp           *CreateTransactionResponse
+	}{
+		{
+			name:   "405",
+			method: http.MethodGet,
+			status: http.StatusMethodNotAllowed,
+			err:    "Method Not Allowed",
+		},
+		{
+			name:        "415",
+			method:      http.MethodPost,
+			contentType: ContentTypeForm,
+			status:      http.StatusUnsupportedMediaType,
+			err:         "Unsupported Media Type",
+		},
+		{
+			name:   "invalid json",
+			method: http.MethodPost,
+			body:   "{",
+			status: http.StatusBadRequest,
+			err:    "unexpected EOF",
+		},
+		{
+			name:   "missing wallet id",
+			method: http.MethodPost,
+			body:   `{"mode": "even"}`,
+			status: http.StatusBadRequest,
+			err:    "missing wallet.id",
+		},
+		{
+			name:   "missing mode",
+			method: http.MethodPost,
+			body:   `{"wallet": {"id": "foo.wlt"}}`,
+			status: http.StatusBadRequest,
+			err:    "missing mode",
+		},
+		{
+			name:   "unsigned with password",
+			method: http.MethodPost,
+			body:   `{"wallet": {"id": "foo.wlt", "password": "pwd"}, "mode": "even", "unsigned": true}`,
+			status: http.StatusBadRequest,
+			err:    "wallet.password must not be used for unsigned transactions",
+		},
+		{
+			name:   "invalid to",
+			method: http.MethodPost,
+			body:   `{"wallet": {"id": "foo.wlt"}, "mode": "consolidate", "to": "xxx"}`,
+			status: http.StatusBadRequest,
+			err:    "invalid address: Invalid address length",
+		},
+		{
+			name:   "wallet not exist",
+			method: http.MethodPost,
+			body:   `{"wallet": {"id": "foo.wlt"}, "mode": "even"}`,
+			gatewayParams: &wallet.HoursRedistributionParams{
+				Wallet: wallet.CreateTransactionWalletParams{
+					ID:        "foo.wlt",
+					Addresses: []cipher.Address{},
+					UxOuts:    []cipher.SHA256{},
+					Password:  []byte{},
+				},
+				Mode: wallet.HoursRedistributionEven,
+			},
+			gatewayErr: wallet.ErrWalletNotExist,
+			status:     http.StatusNotFound,
+			err:        "Not Found",
+		},
+		{
+			name:   "wallet api disabled",
+			method: http.MethodPost,
+			body:   `{"wallet": {"id": "foo.wlt"}, "mode": "even"}`,
+			gatewayParams: &wallet.HoursRedistributionParams{
+				Wallet: wallet.CreateTransactionWalletParams{
+					ID:        "foo.wlt",
+					Addresses: []cipher.Address{},
+					UxOuts:    []cipher.SHA256{},
+					Password:  []byte{},
+				},
+				Mode: wallet.HoursRedistributionEven,
+			},
+			gatewayErr: wallet.ErrWalletAPIDisabled,
+			status:     http.StatusForbidden,
+			err:        "Forbidden",
+		},
+		{
+			name:   "invalid mode",
+			method: http.MethodPost,
+			body:   `{"wallet": {"id": "foo.wlt"}, "mode": "foo"}`,
+			gatewayParams: &wallet.HoursRedistributionParams{
+				Wallet: wallet.CreateTransactionWalletParams{
+					ID:        "foo.wlt",
+					Addresses: []cipher.Address{},
+					UxOuts:    []cipher.SHA256{},
+					Password:  []byte{},
+				},
+				Mode: "foo",
+			},
+			gatewayErr: wallet.ErrInvalidHoursRedistributionMode,
+			status:     http.StatusBadRequest,
+			err:        wallet.ErrInvalidHoursRedistributionMode.Error(),
+		},
+		{
+			name:   "no fee",
+			method: http.MethodPost,
+			body:   `{"wallet": {"id": "foo.wlt"}, "mode": "even"}`,
+			gatewayParams: &wallet.HoursRedistributionParams{
+				Wallet: wallet.CreateTransactionWalletParams{
+					ID:        "foo.wlt",
+					Addresses: []cipher.Address{},
+					UxOuts:    []cipher.SHA256{},
+					Password:  []byte{},
+				},
+				Mode: wallet.HoursRedistributionEven,
+			},
+			gatewayErr: fee.ErrTxnNoFee,
+			status:     http.StatusBadRequest,
+			err:        fee.ErrTxnNoFee.Error(),
+		},
+		{
+			name:   "internal error",
+			method: http.MethodPost,
+			body:   `{"wallet": {"id": "foo.wlt"}, "mode": "even"}`,
+			gatewayParams: &wallet.HoursRedistributionParams{
+				Wallet: wallet.CreateTransactionWalletParams{
+					ID:        "foo.wlt",
+					Addresses: []cipher.Address{},
+					UxOuts:    []cipher.SHA256{},
+					Password:  []byte{},
+				},
+				Mode: wallet.HoursRedistributionEven,
+			},
+			gatewayErr: errors.New("failed"),
+			status:     http.StatusInternalServerError,
+			err:        "failed",
+		},
+		{
+			name:   "consolidate",
+			method: http.MethodPost,
+			body:   `{"wallet": {"id": "foo.wlt", "password": "pwd", "addresses": ["` + addr.String() + `"]}, "mode": "consolidate", "to": "` + addr.String() + `", "ignore_unconfirmed": true}`,
+			gatewayParams: &wallet.HoursRedistributionParams{
+				Wallet: wallet.CreateTransactionWalletParams{
+					ID:        "foo.wlt",
+					Addresses: []cipher.Address{addr},
+					UxOuts:    []cipher.SHA256{},
+					Password:  []byte("pwd"),
+				},
+				IgnoreUnconfirmed: true,
+				Mode:              wallet.HoursRedistributionConsolidate,
+				To:                addr,
+			},
+			gatewayTxn:    &txn,
+			gatewayInputs: inputs,
+			status:        http.StatusOK,
+			rsp:           txnRsp,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			if tc.gatewayParams != nil {
+				gateway.On("CreateHoursRedistribution", *tc.gatewayParams).Return(tc.gatewayTxn, tc.gatewayInputs, tc.gatewayErr)
+			}
+
+			contentType := tc.contentType
+			if contentType == "" {
+				contentType = ContentTypeJSON
+			}
+
+			req, err := http.NewRequest(tc.method, "/api/v2/wallet/transaction/redistribute-hours", strings.NewReader(tc.body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", contentType)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code, "got `%v` want `%v`", rr.Code, tc.status)
+
+			var rsp ReceivedHTTPResponse
+			err = json.NewDecoder(rr.Body).Decode(&rsp)
+			require.NoError(t, err)
+
+			if tc.status != http.StatusOK {
+				require.NotNil(t, rsp.Error)
+				require.Equal(t, tc.err, rsp.Error.Message)
+				return
+			}
+
+			require.Nil(t, rsp.Error)
+			var result CreateTransactionResponse
+			err = json.Unmarshal(rsp.Data, &result)
+			require.NoError(t, err)
+			require.Equal(t, *tc.rsp, result)
+		})
+	}
+}
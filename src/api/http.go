@@ -60,6 +60,10 @@ const (
 	EndpointsPrometheus = "PROMETHEUS"
 	// EndpointsNetCtrl endpoints for managing network connections
 	EndpointsNetCtrl = "NET_CTRL"
+	// EndpointsDBCtrl endpoints for managing the database
+	EndpointsDBCtrl = "DB_CTRL"
+	// EndpointsWatch endpoints for the watch list of addresses
+	EndpointsWatch = "WATCH"
 )
 
 // Server exposes an HTTP API
@@ -446,6 +450,7 @@ func newServerMux(c muxConfig, gateway Gatewayer, csrfStore *CSRFStore, rpc *web
 	webHandlerV1("/wallet/create", forAPISet(walletCreateHandler(gateway), []string{EndpointsWallet}))
 	webHandlerV1("/wallet/newAddress", forAPISet(walletNewAddressesHandler(gateway), []string{EndpointsWallet}))
 	webHandlerV1("/wallet/balance", forAPISet(walletBalanceHandler(gateway), []string{EndpointsWallet}))
+	webHandlerV1("/wallet/events", forAPISet(walletEventsHandler(gateway), []string{EndpointsWallet}))
 	webHandlerV1("/wallet/spend", forAPISet(walletSpendHandler(gateway), []string{EndpointsDeprecatedWalletSpend}))
 	webHandlerV1("/wallet/transaction", forAPISet(createTransactionHandler(gateway), []string{EndpointsWallet}))
 	webHandlerV1("/wallet/transactions", forAPISet(walletTransactionsHandler(gateway), []string{EndpointsWallet}))
@@ -459,11 +464,38 @@ func newServerMux(c muxConfig, gateway Gatewayer, csrfStore *CSRFStore, rpc *web
 	webHandlerV1("/wallet/encrypt", forAPISet(walletEncryptHandler(gateway), []string{EndpointsWallet}))
 	webHandlerV1("/wallet/decrypt", forAPISet(walletDecryptHandler(gateway), []string{EndpointsWallet}))
 	webHandlerV2("/wallet/recover", forAPISet(walletRecoverHandler(gateway), []string{EndpointsWallet}))
+	webHandlerV2("/wallet/watch-only/create", forAPISet(walletCreateWatchOnlyHandler(gateway), []string{EndpointsWallet}))
+	webHandlerV2("/wallet/watch-only/add", forAPISet(walletAddWatchOnlyAddressesHandler(gateway), []string{EndpointsWallet}))
+	webHandlerV2("/wallet/multisig/add", forAPISet(walletAddMultisigHandler(gateway), []string{EndpointsWallet}))
+	webHandlerV2("/wallet/transaction/sign", forAPISet(signPartialTransactionHandler(gateway), []string{EndpointsWallet}))
+	webHandlerV2("/wallet/transaction/cosign", forAPISet(cosignPartialTransactionHandler(gateway), []string{EndpointsWallet}))
+	webHandlerV2("/wallet/annotations", forAPISet(walletAnnotationsHandler(gateway), []string{EndpointsWallet}))
+	webHandlerV2("/wallet/address/annotate", forAPISet(walletAnnotateAddressHandler(gateway), []string{EndpointsWallet}))
+	webHandlerV2("/wallet/transaction/annotate", forAPISet(walletAnnotateTransactionHandler(gateway), []string{EndpointsWallet}))
+	webHandlerV2("/wallet/crypto", forAPISet(walletCryptoHandler(gateway), []string{EndpointsWallet}))
+	webHandlerV2("/wallet/transaction/batch", forAPISet(createPayoutBatchHandler(gateway), []string{EndpointsWallet}))
+	webHandlerV2("/wallet/transaction/redistribute-hours", forAPISet(hoursRedistributionHandler(gateway), []string{EndpointsWallet}))
+	webHandlerV2("/wallet/rescan", forAPISet(walletRescanHandler(gateway), []string{EndpointsWallet}))
+	webHandlerV2("/wallet/spending-policy", forAPISet(walletSpendingPolicyHandler(gateway), []string{EndpointsWallet}))
+	webHandlerV2("/wallet/spending-policy/set", forAPISet(walletSetSpendingPolicyHandler(gateway), []string{EndpointsWallet}))
+	webHandlerV2("/wallet/cosigners", forAPISet(walletCosignersHandler(gateway), []string{EndpointsWallet}))
+	webHandlerV2("/wallet/cosigners/set", forAPISet(walletSetCosignersHandler(gateway), []string{EndpointsWallet}))
+	webHandlerV2("/wallet/sweep", forAPISet(sweepHandler(gateway), []string{EndpointsWallet}))
+	webHandlerV2("/wallet/address-pool", forAPISet(walletAddressPoolHandler(gateway), []string{EndpointsWallet}))
+	webHandlerV2("/wallet/address-pool/fill", forAPISet(walletFillAddressPoolHandler(gateway), []string{EndpointsWallet}))
+	webHandlerV2("/wallet/address-pool/take", forAPISet(walletTakePoolAddressesHandler(gateway), []string{EndpointsWallet}))
+	webHandlerV2("/wallet/history/export", forAPISet(walletHistoryExportHandler(gateway), []string{EndpointsWallet}))
+	webHandlerV2("/wallet/accounts", forAPISet(walletAccountsHandler(gateway), []string{EndpointsWallet}))
+	webHandlerV2("/wallet/accounts/create", forAPISet(walletCreateAccountHandler(gateway), []string{EndpointsWallet}))
+	webHandlerV2("/wallet/backup", forAPISet(walletBackupHandler(gateway), []string{EndpointsWallet}))
+	webHandlerV2("/wallet/restore", forAPISet(walletRestoreHandler(gateway), []string{EndpointsWallet}))
+	webHandlerV2("/wallet/import", forAPISet(walletImportHandler(gateway), []string{EndpointsWallet}))
 
 	// Blockchain interface
 	webHandlerV1("/blockchain/metadata", forAPISet(blockchainMetadataHandler(gateway), []string{EndpointsRead, EndpointsStatus}))
 	webHandlerV1("/blockchain/progress", forAPISet(blockchainProgressHandler(gateway), []string{EndpointsRead, EndpointsStatus}))
 	webHandlerV1("/block", forAPISet(blockHandler(gateway), []string{EndpointsRead}))
+	webHandlerV1("/block/header", forAPISet(blockHeaderHandler(gateway), []string{EndpointsRead}))
 	webHandlerV1("/blocks", forAPISet(blocksHandler(gateway), []string{EndpointsRead}))
 	webHandlerV1("/last_blocks", forAPISet(lastBlocksHandler(gateway), []string{EndpointsRead}))
 
@@ -473,14 +505,27 @@ func newServerMux(c muxConfig, gateway Gatewayer, csrfStore *CSRFStore, rpc *web
 	webHandlerV1("/network/defaultConnections", forAPISet(defaultConnectionsHandler(gateway), []string{EndpointsRead, EndpointsStatus}))
 	webHandlerV1("/network/connections/trust", forAPISet(trustConnectionsHandler(gateway), []string{EndpointsRead, EndpointsStatus}))
 	webHandlerV1("/network/connections/exchange", forAPISet(exchgConnectionsHandler(gateway), []string{EndpointsRead, EndpointsStatus}))
+	webHandlerV1("/network/bans", forAPISet(bansHandler(gateway), []string{EndpointsRead, EndpointsStatus}))
+	webHandlerV1("/network/relay_policy", forAPISet(relayPolicyHandler(gateway), []string{EndpointsRead, EndpointsStatus}))
+	webHandlerV1("/network/compression", forAPISet(compressionHandler(gateway), []string{EndpointsRead, EndpointsStatus}))
+	webHandlerV1("/websocket", forAPISet(websocketHandler(gateway), []string{EndpointsRead, EndpointsStatus}))
 
 	// Network admin endpoints
 	webHandlerV1("/network/connection/disconnect", forAPISet(disconnectHandler(gateway), []string{EndpointsNetCtrl}))
+	webHandlerV1("/network/bans/unban", forAPISet(unbanHandler(gateway), []string{EndpointsNetCtrl}))
+	webHandlerV1("/network/peers", peersHandler(
+		forAPISet(listPeersHandler(gateway), []string{EndpointsRead, EndpointsStatus}),
+		forAPISet(updatePeersHandler(gateway), []string{EndpointsNetCtrl}),
+	))
 
 	// Transaction related endpoints
 	webHandlerV1("/pendingTxs", forAPISet(pendingTxnsHandler(gateway), []string{EndpointsRead}))
+	webHandlerV1("/pendingTxs/evicted", forAPISet(evictedTxnsHandler(gateway), []string{EndpointsRead}))
+	webHandlerV1("/pendingTxs/rebroadcast", forAPISet(rebroadcastTxnsHandler(gateway), []string{EndpointsRead}))
 	webHandlerV1("/transaction", forAPISet(transactionHandler(gateway), []string{EndpointsRead}))
+	webHandlerV1("/transaction/proof", forAPISet(transactionProofHandler(gateway), []string{EndpointsRead}))
 	webHandlerV2("/transaction/verify", forAPISet(verifyTxnHandler(gateway), []string{EndpointsRead}))
+	webHandlerV2("/transaction/partial/combine", forAPISet(combinePartialTransactionsHandler(), []string{EndpointsRead}))
 	webHandlerV1("/transactions", forAPISet(transactionsHandler(gateway), []string{EndpointsRead}))
 	webHandlerV1("/injectTransaction", forAPISet(injectTransactionHandler(gateway), []string{EndpointsTransaction, EndpointsWallet}))
 	webHandlerV1("/resendUnconfirmedTxns", forAPISet(resendUnconfirmedTxnsHandler(gateway), []string{EndpointsTransaction}))
@@ -489,7 +534,10 @@ func newServerMux(c muxConfig, gateway Gatewayer, csrfStore *CSRFStore, rpc *web
 	// Unspent output related endpoints
 	webHandlerV1("/outputs", forAPISet(outputsHandler(gateway), []string{EndpointsRead}))
 	webHandlerV1("/balance", forAPISet(balanceHandler(gateway), []string{EndpointsRead}))
+	webHandlerV1("/balance/history", forAPISet(balanceHistoryHandler(gateway), []string{EndpointsRead}))
 	webHandlerV1("/uxout", forAPISet(uxOutHandler(gateway), []string{EndpointsRead}))
+	webHandlerV1("/uxout/spender", forAPISet(uxOutSpenderHandler(gateway), []string{EndpointsRead}))
+	webHandlerV1("/uxout/value", forAPISet(uxOutsByValueHandler(gateway), []string{EndpointsRead}))
 	webHandlerV1("/address_uxouts", forAPISet(addrUxOutsHandler(gateway), []string{EndpointsRead}))
 
 	// golang process internal metrics for Prometheus
@@ -497,13 +545,29 @@ func newServerMux(c muxConfig, gateway Gatewayer, csrfStore *CSRFStore, rpc *web
 
 	// Address related endpoints
 	webHandlerV2("/address/verify", forAPISet(addressVerifyHandler, []string{EndpointsRead}))
+	webHandlerV2("/addresses/scan", forAPISet(addressesScanHandler(gateway), []string{EndpointsRead}))
 
 	// Explorer endpoints
 	webHandlerV1("/explorer/address", forAPISet(transactionsForAddressHandler(gateway), []string{EndpointsRead}))
+	webHandlerV1("/explorer/address_meta", forAPISet(addressMetaHandler(gateway), []string{EndpointsRead}))
+	webHandlerV1("/explorer/address_transactions", forAPISet(addressTransactionsHandler(gateway), []string{EndpointsRead}))
 	webHandlerV1("/coinSupply", forAPISet(coinSupplyHandler(gateway), []string{EndpointsRead}))
 	webHandlerV1("/richlist", forAPISet(richlistHandler(gateway), []string{EndpointsRead}))
 	webHandlerV1("/addresscount", forAPISet(addressCountHandler(gateway), []string{EndpointsRead}))
 
+	// Database endpoints
+	webHandlerV1("/db/verify", forAPISet(dbVerifyStatusHandler(gateway), []string{EndpointsRead, EndpointsStatus}))
+	webHandlerV1("/db/health", forAPISet(dbHealthHandler(gateway), []string{EndpointsRead, EndpointsStatus}))
+	webHandlerV1("/db/stats", forAPISet(dbStatsHandler(gateway), []string{EndpointsRead, EndpointsStatus}))
+	webHandlerV1("/db/backup", forAPISet(dbBackupHandler(gateway), []string{EndpointsDBCtrl}))
+	webHandlerV1("/db/orphaned_uxouts", forAPISet(dbOrphanedUxOutsHandler(gateway), []string{EndpointsDBCtrl}))
+
+	// Watch list endpoints
+	webHandlerV1("/watch/addresses", forAPISet(watchAddressesHandler(gateway), []string{EndpointsWatch}))
+	webHandlerV1("/watch/add", forAPISet(watchAddHandler(gateway), []string{EndpointsWatch}))
+	webHandlerV1("/watch/remove", forAPISet(watchRemoveHandler(gateway), []string{EndpointsWatch}))
+	webHandlerV1("/watch/events", forAPISet(watchEventsHandler(gateway), []string{EndpointsWatch}))
+
 	return mux
 }
 
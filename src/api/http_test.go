@@ -23,6 +23,8 @@ var allAPISetsEnabled = map[string]struct{}{
 	EndpointsDeprecatedWalletSpend: struct{}{},
 	EndpointsPrometheus:            struct{}{},
 	EndpointsNetCtrl:               struct{}{},
+	EndpointsDBCtrl:                struct{}{},
+	EndpointsWatch:                 struct{}{},
 }
 
 func defaultMuxConfig() muxConfig {
@@ -38,33 +40,53 @@ var endpoints = []string{
 	"/address_uxouts",
 	"/addresscount",
 	"/balance",
+	"/balance/history",
 	"/block",
+	"/block/header",
 	"/blockchain/metadata",
 	"/blockchain/progress",
 	"/blocks",
 	"/coinSupply",
+	"/db/backup",
+	"/db/health",
+	"/db/orphaned_uxouts",
+	"/db/stats",
+	"/db/verify",
 	"/explorer/address",
+	"/explorer/address_meta",
+	"/explorer/address_transactions",
 	"/health",
 	"/injectTransaction",
 	"/last_blocks",
 	"/version",
+	"/network/bans",
+	"/network/bans/unban",
+	"/network/relay_policy",
+	"/network/compression",
 	"/network/connection",
 	"/network/connection/disconnect",
 	"/network/connections",
 	"/network/connections/exchange",
 	"/network/connections/trust",
 	"/network/defaultConnections",
+	"/network/peers",
 	"/outputs",
 	"/pendingTxs",
+	"/pendingTxs/evicted",
+	"/pendingTxs/rebroadcast",
 	"/rawtx",
 	"/richlist",
 	"/resendUnconfirmedTxns",
 	"/transaction",
+	"/transaction/proof",
 	"/transactions",
 	"/uxout",
+	"/uxout/spender",
+	"/uxout/value",
 	"/wallet",
 	"/wallet/balance",
 	"/wallet/create",
+	"/wallet/events",
 	"/wallet/newAddress",
 	"/wallet/newSeed",
 	"/wallet/seed",
@@ -75,37 +97,62 @@ var endpoints = []string{
 	"/wallet/update",
 	"/wallets",
 	"/wallets/folderName",
+	"/watch/add",
+	"/watch/addresses",
+	"/watch/events",
+	"/watch/remove",
 	"/webrpc",
+	"/websocket",
 
 	"/api/v1/address_uxouts",
 	"/api/v1/addresscount",
 	"/api/v1/balance",
+	"/api/v1/balance/history",
 	"/api/v1/block",
+	"/api/v1/block/header",
 	"/api/v1/blockchain/metadata",
 	"/api/v1/blockchain/progress",
 	"/api/v1/blocks",
 	"/api/v1/coinSupply",
+	"/api/v1/db/backup",
+	"/api/v1/db/health",
+	"/api/v1/db/orphaned_uxouts",
+	"/api/v1/db/stats",
+	"/api/v1/db/verify",
 	"/api/v1/explorer/address",
+	"/api/v1/explorer/address_meta",
+	"/api/v1/explorer/address_transactions",
 	"/api/v1/health",
 	"/api/v1/injectTransaction",
 	"/api/v1/last_blocks",
 	"/api/v1/version",
+	"/api/v1/network/bans",
+	"/api/v1/network/bans/unban",
+	"/api/v1/network/relay_policy",
+	"/api/v1/network/compression",
 	"/api/v1/network/connection",
 	"/api/v1/network/connections",
 	"/api/v1/network/connections/exchange",
 	"/api/v1/network/connections/trust",
 	"/api/v1/network/defaultConnections",
+	"/api/v1/network/peers",
 	"/api/v1/outputs",
 	"/api/v1/pendingTxs",
+	"/api/v1/pendingTxs/evicted",
+	"/api/v1/pendingTxs/rebroadcast",
 	"/api/v1/rawtx",
 	"/api/v1/richlist",
 	"/api/v1/resendUnconfirmedTxns",
 	"/api/v1/transaction",
+	"/api/v1/transaction/proof",
 	"/api/v1/transactions",
 	"/api/v1/uxout",
+	"/api/v1/uxout/spender",
+	"/api/v1/uxout/value",
 	"/api/v1/wallet",
 	"/api/v1/wallet/balance",
 	"/api/v1/wallet/create",
+	"/api/v1/wallet/events",
 	"/api/v1/wallet/newAddress",
 	"/api/v1/wallet/newSeed",
 	"/api/v1/wallet/seed",
@@ -116,11 +163,44 @@ var endpoints = []string{
 	"/api/v1/wallet/update",
 	"/api/v1/wallets",
 	"/api/v1/wallets/folderName",
+	"/api/v1/watch/add",
+	"/api/v1/watch/addresses",
+	"/api/v1/watch/events",
+	"/api/v1/watch/remove",
 	"/api/v1/webrpc",
+	"/api/v1/websocket",
 
 	"/api/v2/transaction/verify",
 	"/api/v2/address/verify",
+	"/api/v2/addresses/scan",
 	"/api/v2/wallet/recover",
+	"/api/v2/wallet/watch-only/create",
+	"/api/v2/wallet/watch-only/add",
+	"/api/v2/wallet/multisig/add",
+	"/api/v2/wallet/transaction/sign",
+	"/api/v2/wallet/transaction/cosign",
+	"/api/v2/wallet/annotations",
+	"/api/v2/wallet/address/annotate",
+	"/api/v2/wallet/transaction/annotate",
+	"/api/v2/wallet/crypto",
+	"/api/v2/wallet/transaction/batch",
+	"/api/v2/wallet/transaction/redistribute-hours",
+	"/api/v2/wallet/rescan",
+	"/api/v2/wallet/spending-policy",
+	"/api/v2/wallet/spending-policy/set",
+	"/api/v2/wallet/cosigners",
+	"/api/v2/wallet/cosigners/set",
+	"/api/v2/wallet/sweep",
+	"/api/v2/wallet/address-pool",
+	"/api/v2/wallet/address-pool/fill",
+	"/api/v2/wallet/address-pool/take",
+	"/api/v2/wallet/history/export",
+	"/api/v2/wallet/accounts",
+	"/api/v2/wallet/accounts/create",
+	"/api/v2/wallet/backup",
+	"/api/v2/wallet/restore",
+	"/api/v2/wallet/import",
+	"/api/v2/transaction/partial/combine",
 }
 
 // TestEnableGUI tests enable gui option, EnableGUI isn't part of Gateway API,
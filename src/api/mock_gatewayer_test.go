@@ -4,9 +4,14 @@ package api
 
 import cipher "github.com/skycoin/skycoin/src/cipher"
 import coin "github.com/skycoin/skycoin/src/coin"
+import context "context"
 import daemon "github.com/skycoin/skycoin/src/daemon"
+import pex "github.com/skycoin/skycoin/src/daemon/pex"
+import dbutil "github.com/skycoin/skycoin/src/visor/dbutil"
+import history "github.com/skycoin/skycoin/src/wallet/history"
 import historydb "github.com/skycoin/skycoin/src/visor/historydb"
 import mock "github.com/stretchr/testify/mock"
+import time "time"
 import visor "github.com/skycoin/skycoin/src/visor"
 import wallet "github.com/skycoin/skycoin/src/wallet"
 
@@ -15,6 +20,257 @@ type MockGatewayer struct {
 	mock.Mock
 }
 
+// AddMultisig provides a mock function with given fields: wltID, ms
+func (_m *MockGatewayer) AddMultisig(wltID string, ms cipher.Multisig) (*wallet.Wallet, error) {
+	ret := _m.Called(wltID, ms)
+
+	var r0 *wallet.Wallet
+	if rf, ok := ret.Get(0).(func(string, cipher.Multisig) *wallet.Wallet); ok {
+		r0 = rf(wltID, ms)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*wallet.Wallet)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, cipher.Multisig) error); ok {
+		r1 = rf(wltID, ms)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AddPeers provides a mock function with given fields: addrs
+func (_m *MockGatewayer) AddPeers(addrs []string) error {
+	ret := _m.Called(addrs)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]string) error); ok {
+		r0 = rf(addrs)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// AddWatchAddresses provides a mock function with given fields: addrs
+func (_m *MockGatewayer) AddWatchAddresses(addrs []cipher.Address) (uint64, error) {
+	ret := _m.Called(addrs)
+
+	var r0 uint64
+	if rf, ok := ret.Get(0).(func([]cipher.Address) uint64); ok {
+		r0 = rf(addrs)
+	} else {
+		r0 = ret.Get(0).(uint64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func([]cipher.Address) error); ok {
+		r1 = rf(addrs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AddWatchOnlyAddresses provides a mock function with given fields: wltID, addrs
+func (_m *MockGatewayer) AddWatchOnlyAddresses(wltID string, addrs []cipher.Address) (*wallet.Wallet, error) {
+	ret := _m.Called(wltID, addrs)
+
+	var r0 *wallet.Wallet
+	if rf, ok := ret.Get(0).(func(string, []cipher.Address) *wallet.Wallet); ok {
+		r0 = rf(wltID, addrs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*wallet.Wallet)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, []cipher.Address) error); ok {
+		r1 = rf(wltID, addrs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// BackupDB provides a mock function with given fields:
+func (_m *MockGatewayer) BackupDB() (*visor.DBBackup, error) {
+	ret := _m.Called()
+
+	var r0 *visor.DBBackup
+	if rf, ok := ret.Get(0).(func() *visor.DBBackup); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*visor.DBBackup)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// BackupWallets provides a mock function with given fields: ids, passphrase
+func (_m *MockGatewayer) BackupWallets(ids []string, passphrase []byte) (*wallet.BackupArchive, error) {
+	ret := _m.Called(ids, passphrase)
+
+	var r0 *wallet.BackupArchive
+	if rf, ok := ret.Get(0).(func([]string, []byte) *wallet.BackupArchive); ok {
+		r0 = rf(ids, passphrase)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*wallet.BackupArchive)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func([]string, []byte) error); ok {
+		r1 = rf(ids, passphrase)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// BanPeers provides a mock function with given fields: ips, reason, duration
+func (_m *MockGatewayer) BanPeers(ips []string, reason string, duration time.Duration) error {
+	ret := _m.Called(ips, reason, duration)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]string, string, time.Duration) error); ok {
+		r0 = rf(ips, reason, duration)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CollectOrphanedUxOuts provides a mock function with given fields: del
+func (_m *MockGatewayer) CollectOrphanedUxOuts(del bool) (*visor.OrphanedUxOutsReport, error) {
+	ret := _m.Called(del)
+
+	var r0 *visor.OrphanedUxOutsReport
+	if rf, ok := ret.Get(0).(func(bool) *visor.OrphanedUxOutsReport); ok {
+		r0 = rf(del)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*visor.OrphanedUxOutsReport)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(bool) error); ok {
+		r1 = rf(del)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CosignPartialTransaction provides a mock function with given fields: wltID, password, ptx
+func (_m *MockGatewayer) CosignPartialTransaction(wltID string, password []byte, ptx *wallet.PartialTransaction) (*wallet.PartialTransaction, []wallet.CosignerStatus, error) {
+	ret := _m.Called(wltID, password, ptx)
+
+	var r0 *wallet.PartialTransaction
+	if rf, ok := ret.Get(0).(func(string, []byte, *wallet.PartialTransaction) *wallet.PartialTransaction); ok {
+		r0 = rf(wltID, password, ptx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*wallet.PartialTransaction)
+		}
+	}
+
+	var r1 []wallet.CosignerStatus
+	if rf, ok := ret.Get(1).(func(string, []byte, *wallet.PartialTransaction) []wallet.CosignerStatus); ok {
+		r1 = rf(wltID, password, ptx)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).([]wallet.CosignerStatus)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(string, []byte, *wallet.PartialTransaction) error); ok {
+		r2 = rf(wltID, password, ptx)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// CreateHoursRedistribution provides a mock function with given fields: p
+func (_m *MockGatewayer) CreateHoursRedistribution(p wallet.HoursRedistributionParams) (*coin.Transaction, []wallet.UxBalance, error) {
+	ret := _m.Called(p)
+
+	var r0 *coin.Transaction
+	if rf, ok := ret.Get(0).(func(wallet.HoursRedistributionParams) *coin.Transaction); ok {
+		r0 = rf(p)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*coin.Transaction)
+		}
+	}
+
+	var r1 []wallet.UxBalance
+	if rf, ok := ret.Get(1).(func(wallet.HoursRedistributionParams) []wallet.UxBalance); ok {
+		r1 = rf(p)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).([]wallet.UxBalance)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(wallet.HoursRedistributionParams) error); ok {
+		r2 = rf(p)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// CreatePayoutBatch provides a mock function with given fields: w, maxOutputs
+func (_m *MockGatewayer) CreatePayoutBatch(w wallet.CreateTransactionParams, maxOutputs int) (*wallet.PayoutBatch, error) {
+	ret := _m.Called(w, maxOutputs)
+
+	var r0 *wallet.PayoutBatch
+	if rf, ok := ret.Get(0).(func(wallet.CreateTransactionParams, int) *wallet.PayoutBatch); ok {
+		r0 = rf(w, maxOutputs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*wallet.PayoutBatch)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(wallet.CreateTransactionParams, int) error); ok {
+		r1 = rf(w, maxOutputs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // CreateTransaction provides a mock function with given fields: w
 func (_m *MockGatewayer) CreateTransaction(w wallet.CreateTransactionParams) (*coin.Transaction, []wallet.UxBalance, error) {
 	ret := _m.Called(w)
@@ -70,6 +326,50 @@ func (_m *MockGatewayer) CreateWallet(wltName string, options wallet.Options) (*
 	return r0, r1
 }
 
+// CreateWalletAccount provides a mock function with given fields: wltID, name, password
+func (_m *MockGatewayer) CreateWalletAccount(wltID string, name string, password []byte) (wallet.Account, error) {
+	ret := _m.Called(wltID, name, password)
+
+	var r0 wallet.Account
+	if rf, ok := ret.Get(0).(func(string, string, []byte) wallet.Account); ok {
+		r0 = rf(wltID, name, password)
+	} else {
+		r0 = ret.Get(0).(wallet.Account)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string, []byte) error); ok {
+		r1 = rf(wltID, name, password)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateWatchOnlyWallet provides a mock function with given fields: wltName, label, addrs
+func (_m *MockGatewayer) CreateWatchOnlyWallet(wltName string, label string, addrs []cipher.Address) (*wallet.Wallet, error) {
+	ret := _m.Called(wltName, label, addrs)
+
+	var r0 *wallet.Wallet
+	if rf, ok := ret.Get(0).(func(string, string, []cipher.Address) *wallet.Wallet); ok {
+		r0 = rf(wltName, label, addrs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*wallet.Wallet)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string, []cipher.Address) error); ok {
+		r1 = rf(wltName, label, addrs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // DecryptWallet provides a mock function with given fields: wltID, password
 func (_m *MockGatewayer) DecryptWallet(wltID string, password []byte) (*wallet.Wallet, error) {
 	ret := _m.Called(wltID, password)
@@ -130,6 +430,36 @@ func (_m *MockGatewayer) EncryptWallet(wltID string, password []byte) (*wallet.W
 	return r0, r1
 }
 
+// FillWalletAddressPool provides a mock function with given fields: wltID, account, password, size
+func (_m *MockGatewayer) FillWalletAddressPool(wltID string, account string, password []byte, size uint64) ([]cipher.Address, wallet.AddressPoolStatus, error) {
+	ret := _m.Called(wltID, account, password, size)
+
+	var r0 []cipher.Address
+	if rf, ok := ret.Get(0).(func(string, string, []byte, uint64) []cipher.Address); ok {
+		r0 = rf(wltID, account, password, size)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]cipher.Address)
+		}
+	}
+
+	var r1 wallet.AddressPoolStatus
+	if rf, ok := ret.Get(1).(func(string, string, []byte, uint64) wallet.AddressPoolStatus); ok {
+		r1 = rf(wltID, account, password, size)
+	} else {
+		r1 = ret.Get(1).(wallet.AddressPoolStatus)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(string, string, []byte, uint64) error); ok {
+		r2 = rf(wltID, account, password, size)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 // GetAddressCount provides a mock function with given fields:
 func (_m *MockGatewayer) GetAddressCount() (uint64, error) {
 	ret := _m.Called()
@@ -151,6 +481,52 @@ func (_m *MockGatewayer) GetAddressCount() (uint64, error) {
 	return r0, r1
 }
 
+// GetAddressBalanceHistory provides a mock function with given fields: addr, start, end
+func (_m *MockGatewayer) GetAddressBalanceHistory(addr cipher.Address, start uint64, end uint64) (*visor.AddressBalanceHistory, error) {
+	ret := _m.Called(addr, start, end)
+
+	var r0 *visor.AddressBalanceHistory
+	if rf, ok := ret.Get(0).(func(cipher.Address, uint64, uint64) *visor.AddressBalanceHistory); ok {
+		r0 = rf(addr, start, end)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*visor.AddressBalanceHistory)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(cipher.Address, uint64, uint64) error); ok {
+		r1 = rf(addr, start, end)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetAddressMeta provides a mock function with given fields: addr
+func (_m *MockGatewayer) GetAddressMeta(addr cipher.Address) (*historydb.AddressMeta, error) {
+	ret := _m.Called(addr)
+
+	var r0 *historydb.AddressMeta
+	if rf, ok := ret.Get(0).(func(cipher.Address) *historydb.AddressMeta); ok {
+		r0 = rf(addr)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*historydb.AddressMeta)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(cipher.Address) error); ok {
+		r1 = rf(addr)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetAllUnconfirmedTransactions provides a mock function with given fields:
 func (_m *MockGatewayer) GetAllUnconfirmedTransactions() ([]visor.UnconfirmedTransaction, error) {
 	ret := _m.Called()
@@ -206,9 +582,32 @@ func (_m *MockGatewayer) GetAllUnconfirmedTransactionsVerbose() ([]visor.Unconfi
 	return r0, r1, r2
 }
 
-// GetBalanceOfAddrs provides a mock function with given fields: addrs
-func (_m *MockGatewayer) GetBalanceOfAddrs(addrs []cipher.Address) ([]wallet.BalancePair, error) {
-	ret := _m.Called(addrs)
+// GetBalanceDistribution provides a mock function with given fields:
+func (_m *MockGatewayer) GetBalanceDistribution() ([]historydb.BalanceRange, error) {
+	ret := _m.Called()
+
+	var r0 []historydb.BalanceRange
+	if rf, ok := ret.Get(0).(func() []historydb.BalanceRange); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]historydb.BalanceRange)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetBalanceOfAddrs provides a mock function with given fields: addrs
+func (_m *MockGatewayer) GetBalanceOfAddrs(addrs []cipher.Address) ([]wallet.BalancePair, error) {
+	ret := _m.Called(addrs)
 
 	var r0 []wallet.BalancePair
 	if rf, ok := ret.Get(0).(func([]cipher.Address) []wallet.BalancePair); ok {
@@ -229,6 +628,68 @@ func (_m *MockGatewayer) GetBalanceOfAddrs(addrs []cipher.Address) ([]wallet.Bal
 	return r0, r1
 }
 
+// GetBans provides a mock function with given fields:
+func (_m *MockGatewayer) GetBans() []pex.Ban {
+	ret := _m.Called()
+
+	var r0 []pex.Ban
+	if rf, ok := ret.Get(0).(func() []pex.Ban); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]pex.Ban)
+		}
+	}
+
+	return r0
+}
+
+// GetBlockHeaderByHash provides a mock function with given fields: hash
+func (_m *MockGatewayer) GetBlockHeaderByHash(hash cipher.SHA256) (*visor.CommittedBlockHeader, error) {
+	ret := _m.Called(hash)
+
+	var r0 *visor.CommittedBlockHeader
+	if rf, ok := ret.Get(0).(func(cipher.SHA256) *visor.CommittedBlockHeader); ok {
+		r0 = rf(hash)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*visor.CommittedBlockHeader)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(cipher.SHA256) error); ok {
+		r1 = rf(hash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetBlockHeaderBySeq provides a mock function with given fields: seq
+func (_m *MockGatewayer) GetBlockHeaderBySeq(seq uint64) (*visor.CommittedBlockHeader, error) {
+	ret := _m.Called(seq)
+
+	var r0 *visor.CommittedBlockHeader
+	if rf, ok := ret.Get(0).(func(uint64) *visor.CommittedBlockHeader); ok {
+		r0 = rf(seq)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*visor.CommittedBlockHeader)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uint64) error); ok {
+		r1 = rf(seq)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetBlockchainMetadata provides a mock function with given fields:
 func (_m *MockGatewayer) GetBlockchainMetadata() (*visor.BlockchainMetadata, error) {
 	ret := _m.Called()
@@ -385,6 +846,20 @@ func (_m *MockGatewayer) GetBlocksVerbose(seqs []uint64) ([]coin.SignedBlock, []
 	return r0, r1, r2
 }
 
+// GetCompressionStatus provides a mock function with given fields:
+func (_m *MockGatewayer) GetCompressionStatus() daemon.CompressionStatus {
+	ret := _m.Called()
+
+	var r0 daemon.CompressionStatus
+	if rf, ok := ret.Get(0).(func() daemon.CompressionStatus); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(daemon.CompressionStatus)
+	}
+
+	return r0
+}
+
 // GetConnection provides a mock function with given fields: addr
 func (_m *MockGatewayer) GetConnection(addr string) (*daemon.Connection, error) {
 	ret := _m.Called(addr)
@@ -431,6 +906,66 @@ func (_m *MockGatewayer) GetConnections(f func(daemon.Connection) bool) ([]daemo
 	return r0, r1
 }
 
+// GetDBIntegrityReport provides a mock function with given fields:
+func (_m *MockGatewayer) GetDBIntegrityReport() (*visor.DBIntegrityReport, error) {
+	ret := _m.Called()
+
+	var r0 *visor.DBIntegrityReport
+	if rf, ok := ret.Get(0).(func() *visor.DBIntegrityReport); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*visor.DBIntegrityReport)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetDBStats provides a mock function with given fields:
+func (_m *MockGatewayer) GetDBStats() (*dbutil.Stats, error) {
+	ret := _m.Called()
+
+	var r0 *dbutil.Stats
+	if rf, ok := ret.Get(0).(func() *dbutil.Stats); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dbutil.Stats)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetDBVerifyStatus provides a mock function with given fields:
+func (_m *MockGatewayer) GetDBVerifyStatus() visor.DBVerifyStatus {
+	ret := _m.Called()
+
+	var r0 visor.DBVerifyStatus
+	if rf, ok := ret.Get(0).(func() visor.DBVerifyStatus); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(visor.DBVerifyStatus)
+	}
+
+	return r0
+}
+
 // GetDefaultConnections provides a mock function with given fields:
 func (_m *MockGatewayer) GetDefaultConnections() []string {
 	ret := _m.Called()
@@ -447,6 +982,29 @@ func (_m *MockGatewayer) GetDefaultConnections() []string {
 	return r0
 }
 
+// GetEvictedUnconfirmedTxns provides a mock function with given fields: after, limit
+func (_m *MockGatewayer) GetEvictedUnconfirmedTxns(after uint64, limit uint64) ([]visor.EvictedUnconfirmedTxn, error) {
+	ret := _m.Called(after, limit)
+
+	var r0 []visor.EvictedUnconfirmedTxn
+	if rf, ok := ret.Get(0).(func(uint64, uint64) []visor.EvictedUnconfirmedTxn); ok {
+		r0 = rf(after, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]visor.EvictedUnconfirmedTxn)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uint64, uint64) error); ok {
+		r1 = rf(after, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetExchgConnection provides a mock function with given fields:
 func (_m *MockGatewayer) GetExchgConnection() []string {
 	ret := _m.Called()
@@ -541,13 +1099,82 @@ func (_m *MockGatewayer) GetLastBlocksVerbose(num uint64) ([]coin.SignedBlock, [
 	return r0, r1, r2
 }
 
-// GetRichlist provides a mock function with given fields: includeDistribution
-func (_m *MockGatewayer) GetRichlist(includeDistribution bool) (visor.Richlist, error) {
-	ret := _m.Called(includeDistribution)
+// GetOutputsByValue provides a mock function with given fields: q, cursor, limit
+func (_m *MockGatewayer) GetOutputsByValue(q visor.OutputsByValueQuery, cursor *historydb.OutputValuesCursor, limit uint64) (*visor.OutputsByValuePage, error) {
+	ret := _m.Called(q, cursor, limit)
+
+	var r0 *visor.OutputsByValuePage
+	if rf, ok := ret.Get(0).(func(visor.OutputsByValueQuery, *historydb.OutputValuesCursor, uint64) *visor.OutputsByValuePage); ok {
+		r0 = rf(q, cursor, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*visor.OutputsByValuePage)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(visor.OutputsByValueQuery, *historydb.OutputValuesCursor, uint64) error); ok {
+		r1 = rf(q, cursor, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPeers provides a mock function with given fields:
+func (_m *MockGatewayer) GetPeers() []pex.Peer {
+	ret := _m.Called()
+
+	var r0 []pex.Peer
+	if rf, ok := ret.Get(0).(func() []pex.Peer); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]pex.Peer)
+		}
+	}
+
+	return r0
+}
+
+// GetRebroadcastStats provides a mock function with given fields:
+func (_m *MockGatewayer) GetRebroadcastStats() []daemon.RebroadcastStats {
+	ret := _m.Called()
+
+	var r0 []daemon.RebroadcastStats
+	if rf, ok := ret.Get(0).(func() []daemon.RebroadcastStats); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]daemon.RebroadcastStats)
+		}
+	}
+
+	return r0
+}
+
+// GetRelayPolicy provides a mock function with given fields:
+func (_m *MockGatewayer) GetRelayPolicy() daemon.RelayPolicyStatus {
+	ret := _m.Called()
+
+	var r0 daemon.RelayPolicyStatus
+	if rf, ok := ret.Get(0).(func() daemon.RelayPolicyStatus); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(daemon.RelayPolicyStatus)
+	}
+
+	return r0
+}
+
+// GetRichlist provides a mock function with given fields: n, includeDistribution
+func (_m *MockGatewayer) GetRichlist(n uint64, includeDistribution bool) (visor.Richlist, error) {
+	ret := _m.Called(n, includeDistribution)
 
 	var r0 visor.Richlist
-	if rf, ok := ret.Get(0).(func(bool) visor.Richlist); ok {
-		r0 = rf(includeDistribution)
+	if rf, ok := ret.Get(0).(func(uint64, bool) visor.Richlist); ok {
+		r0 = rf(n, includeDistribution)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(visor.Richlist)
@@ -555,8 +1182,8 @@ func (_m *MockGatewayer) GetRichlist(includeDistribution bool) (visor.Richlist,
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(bool) error); ok {
-		r1 = rf(includeDistribution)
+	if rf, ok := ret.Get(1).(func(uint64, bool) error); ok {
+		r1 = rf(n, includeDistribution)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -674,6 +1301,61 @@ func (_m *MockGatewayer) GetSignedBlockBySeqVerbose(seq uint64) (*coin.SignedBlo
 	return r0, r1, r2
 }
 
+// GetSignedBlockByTime provides a mock function with given fields: t
+func (_m *MockGatewayer) GetSignedBlockByTime(t uint64) (*coin.SignedBlock, error) {
+	ret := _m.Called(t)
+
+	var r0 *coin.SignedBlock
+	if rf, ok := ret.Get(0).(func(uint64) *coin.SignedBlock); ok {
+		r0 = rf(t)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*coin.SignedBlock)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uint64) error); ok {
+		r1 = rf(t)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetSignedBlockByTimeVerbose provides a mock function with given fields: t
+func (_m *MockGatewayer) GetSignedBlockByTimeVerbose(t uint64) (*coin.SignedBlock, [][]visor.TransactionInput, error) {
+	ret := _m.Called(t)
+
+	var r0 *coin.SignedBlock
+	if rf, ok := ret.Get(0).(func(uint64) *coin.SignedBlock); ok {
+		r0 = rf(t)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*coin.SignedBlock)
+		}
+	}
+
+	var r1 [][]visor.TransactionInput
+	if rf, ok := ret.Get(1).(func(uint64) [][]visor.TransactionInput); ok {
+		r1 = rf(t)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).([][]visor.TransactionInput)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(uint64) error); ok {
+		r2 = rf(t)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 // GetSpentOutputsForAddresses provides a mock function with given fields: addr
 func (_m *MockGatewayer) GetSpentOutputsForAddresses(addr []cipher.Address) ([][]historydb.UxOut, error) {
 	ret := _m.Called(addr)
@@ -720,6 +1402,29 @@ func (_m *MockGatewayer) GetTransaction(txid cipher.SHA256) (*visor.Transaction,
 	return r0, r1
 }
 
+// GetTransactionInclusionProof provides a mock function with given fields: txid
+func (_m *MockGatewayer) GetTransactionInclusionProof(txid cipher.SHA256) (*visor.TransactionInclusionProof, error) {
+	ret := _m.Called(txid)
+
+	var r0 *visor.TransactionInclusionProof
+	if rf, ok := ret.Get(0).(func(cipher.SHA256) *visor.TransactionInclusionProof); ok {
+		r0 = rf(txid)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*visor.TransactionInclusionProof)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(cipher.SHA256) error); ok {
+		r1 = rf(txid)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetTransactionVerbose provides a mock function with given fields: txid
 func (_m *MockGatewayer) GetTransactionVerbose(txid cipher.SHA256) (*visor.Transaction, []visor.TransactionInput, error) {
 	ret := _m.Called(txid)
@@ -823,6 +1528,22 @@ func (_m *MockGatewayer) GetTrustConnections() []string {
 	return r0
 }
 
+// GetTxnRebroadcastStats provides a mock function with given fields: txid
+func (_m *MockGatewayer) GetTxnRebroadcastStats(txid cipher.SHA256) *daemon.RebroadcastStats {
+	ret := _m.Called(txid)
+
+	var r0 *daemon.RebroadcastStats
+	if rf, ok := ret.Get(0).(func(cipher.SHA256) *daemon.RebroadcastStats); ok {
+		r0 = rf(txid)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*daemon.RebroadcastStats)
+		}
+	}
+
+	return r0
+}
+
 // GetUnspentOutputsSummary provides a mock function with given fields: filters
 func (_m *MockGatewayer) GetUnspentOutputsSummary(filters []visor.OutputsFilter) (*visor.UnspentOutputsSummary, error) {
 	ret := _m.Called(filters)
@@ -860,8 +1581,586 @@ func (_m *MockGatewayer) GetUxOutByID(id cipher.SHA256) (*historydb.UxOut, error
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(cipher.SHA256) error); ok {
-		r1 = rf(id)
+	if rf, ok := ret.Get(1).(func(cipher.SHA256) error); ok {
+		r1 = rf(id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUxOutSpender provides a mock function with given fields: id
+func (_m *MockGatewayer) GetUxOutSpender(id cipher.SHA256) (*historydb.UxOutSpender, error) {
+	ret := _m.Called(id)
+
+	var r0 *historydb.UxOutSpender
+	if rf, ok := ret.Get(0).(func(cipher.SHA256) *historydb.UxOutSpender); ok {
+		r0 = rf(id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*historydb.UxOutSpender)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(cipher.SHA256) error); ok {
+		r1 = rf(id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetVerboseTransactionsForAddress provides a mock function with given fields: a
+func (_m *MockGatewayer) GetVerboseTransactionsForAddress(a cipher.Address) ([]visor.Transaction, [][]visor.TransactionInput, error) {
+	ret := _m.Called(a)
+
+	var r0 []visor.Transaction
+	if rf, ok := ret.Get(0).(func(cipher.Address) []visor.Transaction); ok {
+		r0 = rf(a)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]visor.Transaction)
+		}
+	}
+
+	var r1 [][]visor.TransactionInput
+	if rf, ok := ret.Get(1).(func(cipher.Address) [][]visor.TransactionInput); ok {
+		r1 = rf(a)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).([][]visor.TransactionInput)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(cipher.Address) error); ok {
+		r2 = rf(a)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// GetVerboseTransactionsForAddressPage provides a mock function with given fields: a, cursor, offset, limit
+func (_m *MockGatewayer) GetVerboseTransactionsForAddressPage(a cipher.Address, cursor *historydb.AddressTxnsCursor, offset uint64, limit uint64) ([]visor.Transaction, [][]visor.TransactionInput, *historydb.AddressTxnsCursor, error) {
+	ret := _m.Called(a, cursor, offset, limit)
+
+	var r0 []visor.Transaction
+	if rf, ok := ret.Get(0).(func(cipher.Address, *historydb.AddressTxnsCursor, uint64, uint64) []visor.Transaction); ok {
+		r0 = rf(a, cursor, offset, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]visor.Transaction)
+		}
+	}
+
+	var r1 [][]visor.TransactionInput
+	if rf, ok := ret.Get(1).(func(cipher.Address, *historydb.AddressTxnsCursor, uint64, uint64) [][]visor.TransactionInput); ok {
+		r1 = rf(a, cursor, offset, limit)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).([][]visor.TransactionInput)
+		}
+	}
+
+	var r2 *historydb.AddressTxnsCursor
+	if rf, ok := ret.Get(2).(func(cipher.Address, *historydb.AddressTxnsCursor, uint64, uint64) *historydb.AddressTxnsCursor); ok {
+		r2 = rf(a, cursor, offset, limit)
+	} else {
+		if ret.Get(2) != nil {
+			r2 = ret.Get(2).(*historydb.AddressTxnsCursor)
+		}
+	}
+
+	var r3 error
+	if rf, ok := ret.Get(3).(func(cipher.Address, *historydb.AddressTxnsCursor, uint64, uint64) error); ok {
+		r3 = rf(a, cursor, offset, limit)
+	} else {
+		r3 = ret.Error(3)
+	}
+
+	return r0, r1, r2, r3
+}
+
+// GetWallet provides a mock function with given fields: wltID
+func (_m *MockGatewayer) GetWallet(wltID string) (*wallet.Wallet, error) {
+	ret := _m.Called(wltID)
+
+	var r0 *wallet.Wallet
+	if rf, ok := ret.Get(0).(func(string) *wallet.Wallet); ok {
+		r0 = rf(wltID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*wallet.Wallet)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(wltID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetWalletAccountBalance provides a mock function with given fields: wltID, account
+func (_m *MockGatewayer) GetWalletAccountBalance(wltID string, account string) (wallet.BalancePair, wallet.AddressBalances, error) {
+	ret := _m.Called(wltID, account)
+
+	var r0 wallet.BalancePair
+	if rf, ok := ret.Get(0).(func(string, string) wallet.BalancePair); ok {
+		r0 = rf(wltID, account)
+	} else {
+		r0 = ret.Get(0).(wallet.BalancePair)
+	}
+
+	var r1 wallet.AddressBalances
+	if rf, ok := ret.Get(1).(func(string, string) wallet.AddressBalances); ok {
+		r1 = rf(wltID, account)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(wallet.AddressBalances)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(string, string) error); ok {
+		r2 = rf(wltID, account)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// GetWalletBalance provides a mock function with given fields: wltID
+func (_m *MockGatewayer) GetWalletBalance(wltID string) (wallet.BalancePair, wallet.AddressBalances, error) {
+	ret := _m.Called(wltID)
+
+	var r0 wallet.BalancePair
+	if rf, ok := ret.Get(0).(func(string) wallet.BalancePair); ok {
+		r0 = rf(wltID)
+	} else {
+		r0 = ret.Get(0).(wallet.BalancePair)
+	}
+
+	var r1 wallet.AddressBalances
+	if rf, ok := ret.Get(1).(func(string) wallet.AddressBalances); ok {
+		r1 = rf(wltID)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(wallet.AddressBalances)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(string) error); ok {
+		r2 = rf(wltID)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// GetWalletDir provides a mock function with given fields:
+func (_m *MockGatewayer) GetWalletDir() (string, error) {
+	ret := _m.Called()
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetWalletEvents provides a mock function with given fields: wltID, after, limit
+func (_m *MockGatewayer) GetWalletEvents(wltID string, after uint64, limit uint64) ([]visor.WalletEvent, error) {
+	ret := _m.Called(wltID, after, limit)
+
+	var r0 []visor.WalletEvent
+	if rf, ok := ret.Get(0).(func(string, uint64, uint64) []visor.WalletEvent); ok {
+		r0 = rf(wltID, after, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]visor.WalletEvent)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, uint64, uint64) error); ok {
+		r1 = rf(wltID, after, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetWalletHistory provides a mock function with given fields: wltID, account, ps
+func (_m *MockGatewayer) GetWalletHistory(wltID string, account string, ps history.PriceSource) ([]history.Entry, error) {
+	ret := _m.Called(wltID, account, ps)
+
+	var r0 []history.Entry
+	if rf, ok := ret.Get(0).(func(string, string, history.PriceSource) []history.Entry); ok {
+		r0 = rf(wltID, account, ps)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]history.Entry)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string, history.PriceSource) error); ok {
+		r1 = rf(wltID, account, ps)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetWalletSeed provides a mock function with given fields: wltID, password
+func (_m *MockGatewayer) GetWalletSeed(wltID string, password []byte) (string, error) {
+	ret := _m.Called(wltID, password)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(string, []byte) string); ok {
+		r0 = rf(wltID, password)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, []byte) error); ok {
+		r1 = rf(wltID, password)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetWalletUnconfirmedTransactions provides a mock function with given fields: wltID
+func (_m *MockGatewayer) GetWalletUnconfirmedTransactions(wltID string) ([]visor.UnconfirmedTransaction, error) {
+	ret := _m.Called(wltID)
+
+	var r0 []visor.UnconfirmedTransaction
+	if rf, ok := ret.Get(0).(func(string) []visor.UnconfirmedTransaction); ok {
+		r0 = rf(wltID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]visor.UnconfirmedTransaction)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(wltID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetWalletUnconfirmedTransactionsVerbose provides a mock function with given fields: wltID
+func (_m *MockGatewayer) GetWalletUnconfirmedTransactionsVerbose(wltID string) ([]visor.UnconfirmedTransaction, [][]visor.TransactionInput, error) {
+	ret := _m.Called(wltID)
+
+	var r0 []visor.UnconfirmedTransaction
+	if rf, ok := ret.Get(0).(func(string) []visor.UnconfirmedTransaction); ok {
+		r0 = rf(wltID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]visor.UnconfirmedTransaction)
+		}
+	}
+
+	var r1 [][]visor.TransactionInput
+	if rf, ok := ret.Get(1).(func(string) [][]visor.TransactionInput); ok {
+		r1 = rf(wltID)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).([][]visor.TransactionInput)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(string) error); ok {
+		r2 = rf(wltID)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// GetWallets provides a mock function with given fields:
+func (_m *MockGatewayer) GetWallets() (wallet.Wallets, error) {
+	ret := _m.Called()
+
+	var r0 wallet.Wallets
+	if rf, ok := ret.Get(0).(func() wallet.Wallets); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(wallet.Wallets)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetWatchAddresses provides a mock function with given fields:
+func (_m *MockGatewayer) GetWatchAddresses() ([]cipher.Address, error) {
+	ret := _m.Called()
+
+	var r0 []cipher.Address
+	if rf, ok := ret.Get(0).(func() []cipher.Address); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]cipher.Address)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetWatchEvents provides a mock function with given fields: after, limit
+func (_m *MockGatewayer) GetWatchEvents(after uint64, limit uint64) ([]visor.WatchEvent, error) {
+	ret := _m.Called(after, limit)
+
+	var r0 []visor.WatchEvent
+	if rf, ok := ret.Get(0).(func(uint64, uint64) []visor.WatchEvent); ok {
+		r0 = rf(after, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]visor.WatchEvent)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uint64, uint64) error); ok {
+		r1 = rf(after, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ImportWallet provides a mock function with given fields: wltName, opts
+func (_m *MockGatewayer) ImportWallet(wltName string, opts wallet.ImportOptions) (*wallet.Wallet, error) {
+	ret := _m.Called(wltName, opts)
+
+	var r0 *wallet.Wallet
+	if rf, ok := ret.Get(0).(func(string, wallet.ImportOptions) *wallet.Wallet); ok {
+		r0 = rf(wltName, opts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*wallet.Wallet)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, wallet.ImportOptions) error); ok {
+		r1 = rf(wltName, opts)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// InjectBroadcastTransaction provides a mock function with given fields: txn
+func (_m *MockGatewayer) InjectBroadcastTransaction(txn coin.Transaction) error {
+	ret := _m.Called(txn)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(coin.Transaction) error); ok {
+		r0 = rf(txn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewAddresses provides a mock function with given fields: wltID, password, n
+func (_m *MockGatewayer) NewAddresses(wltID string, password []byte, n uint64) ([]cipher.Address, error) {
+	ret := _m.Called(wltID, password, n)
+
+	var r0 []cipher.Address
+	if rf, ok := ret.Get(0).(func(string, []byte, uint64) []cipher.Address); ok {
+		r0 = rf(wltID, password, n)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]cipher.Address)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, []byte, uint64) error); ok {
+		r1 = rf(wltID, password, n)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RecoverWallet provides a mock function with given fields: wltID, seed, seedPassphrase, password
+func (_m *MockGatewayer) RecoverWallet(wltID string, seed string, seedPassphrase string, password []byte) (*wallet.Wallet, error) {
+	ret := _m.Called(wltID, seed, seedPassphrase, password)
+
+	var r0 *wallet.Wallet
+	if rf, ok := ret.Get(0).(func(string, string, string, []byte) *wallet.Wallet); ok {
+		r0 = rf(wltID, seed, seedPassphrase, password)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*wallet.Wallet)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string, string, []byte) error); ok {
+		r1 = rf(wltID, seed, seedPassphrase, password)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RemovePeers provides a mock function with given fields: addrs
+func (_m *MockGatewayer) RemovePeers(addrs []string) error {
+	ret := _m.Called(addrs)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]string) error); ok {
+		r0 = rf(addrs)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RemoveWatchAddresses provides a mock function with given fields: addrs
+func (_m *MockGatewayer) RemoveWatchAddresses(addrs []cipher.Address) (uint64, error) {
+	ret := _m.Called(addrs)
+
+	var r0 uint64
+	if rf, ok := ret.Get(0).(func([]cipher.Address) uint64); ok {
+		r0 = rf(addrs)
+	} else {
+		r0 = ret.Get(0).(uint64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func([]cipher.Address) error); ok {
+		r1 = rf(addrs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RescanWallet provides a mock function with given fields: wltID, password, gapLimit, numTxns
+func (_m *MockGatewayer) RescanWallet(wltID string, password []byte, gapLimit uint64, numTxns uint64) (*visor.WalletRescan, error) {
+	ret := _m.Called(wltID, password, gapLimit, numTxns)
+
+	var r0 *visor.WalletRescan
+	if rf, ok := ret.Get(0).(func(string, []byte, uint64, uint64) *visor.WalletRescan); ok {
+		r0 = rf(wltID, password, gapLimit, numTxns)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*visor.WalletRescan)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, []byte, uint64, uint64) error); ok {
+		r1 = rf(wltID, password, gapLimit, numTxns)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ResendUnconfirmedTxns provides a mock function with given fields:
+func (_m *MockGatewayer) ResendUnconfirmedTxns() ([]cipher.SHA256, error) {
+	ret := _m.Called()
+
+	var r0 []cipher.SHA256
+	if rf, ok := ret.Get(0).(func() []cipher.SHA256); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]cipher.SHA256)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RestoreWallets provides a mock function with given fields: a, passphrase, skipConflicts
+func (_m *MockGatewayer) RestoreWallets(a wallet.BackupArchive, passphrase []byte, skipConflicts bool) (*wallet.RestoreResult, error) {
+	ret := _m.Called(a, passphrase, skipConflicts)
+
+	var r0 *wallet.RestoreResult
+	if rf, ok := ret.Get(0).(func(wallet.BackupArchive, []byte, bool) *wallet.RestoreResult); ok {
+		r0 = rf(a, passphrase, skipConflicts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*wallet.RestoreResult)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(wallet.BackupArchive, []byte, bool) error); ok {
+		r1 = rf(a, passphrase, skipConflicts)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -869,45 +2168,36 @@ func (_m *MockGatewayer) GetUxOutByID(id cipher.SHA256) (*historydb.UxOut, error
 	return r0, r1
 }
 
-// GetVerboseTransactionsForAddress provides a mock function with given fields: a
-func (_m *MockGatewayer) GetVerboseTransactionsForAddress(a cipher.Address) ([]visor.Transaction, [][]visor.TransactionInput, error) {
-	ret := _m.Called(a)
+// ScanAddresses provides a mock function with given fields: addrs, numTxns
+func (_m *MockGatewayer) ScanAddresses(addrs []cipher.Address, numTxns uint64) ([]visor.AddressScan, error) {
+	ret := _m.Called(addrs, numTxns)
 
-	var r0 []visor.Transaction
-	if rf, ok := ret.Get(0).(func(cipher.Address) []visor.Transaction); ok {
-		r0 = rf(a)
+	var r0 []visor.AddressScan
+	if rf, ok := ret.Get(0).(func([]cipher.Address, uint64) []visor.AddressScan); ok {
+		r0 = rf(addrs, numTxns)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]visor.Transaction)
-		}
-	}
-
-	var r1 [][]visor.TransactionInput
-	if rf, ok := ret.Get(1).(func(cipher.Address) [][]visor.TransactionInput); ok {
-		r1 = rf(a)
-	} else {
-		if ret.Get(1) != nil {
-			r1 = ret.Get(1).([][]visor.TransactionInput)
+			r0 = ret.Get(0).([]visor.AddressScan)
 		}
 	}
 
-	var r2 error
-	if rf, ok := ret.Get(2).(func(cipher.Address) error); ok {
-		r2 = rf(a)
+	var r1 error
+	if rf, ok := ret.Get(1).(func([]cipher.Address, uint64) error); ok {
+		r1 = rf(addrs, numTxns)
 	} else {
-		r2 = ret.Error(2)
+		r1 = ret.Error(1)
 	}
 
-	return r0, r1, r2
+	return r0, r1
 }
 
-// GetWallet provides a mock function with given fields: wltID
-func (_m *MockGatewayer) GetWallet(wltID string) (*wallet.Wallet, error) {
-	ret := _m.Called(wltID)
+// SetAddressAnnotation provides a mock function with given fields: wltID, addr, a
+func (_m *MockGatewayer) SetAddressAnnotation(wltID string, addr cipher.Address, a wallet.Annotation) (*wallet.Wallet, error) {
+	ret := _m.Called(wltID, addr, a)
 
 	var r0 *wallet.Wallet
-	if rf, ok := ret.Get(0).(func(string) *wallet.Wallet); ok {
-		r0 = rf(wltID)
+	if rf, ok := ret.Get(0).(func(string, cipher.Address, wallet.Annotation) *wallet.Wallet); ok {
+		r0 = rf(wltID, addr, a)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*wallet.Wallet)
@@ -915,8 +2205,8 @@ func (_m *MockGatewayer) GetWallet(wltID string) (*wallet.Wallet, error) {
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(wltID)
+	if rf, ok := ret.Get(1).(func(string, cipher.Address, wallet.Annotation) error); ok {
+		r1 = rf(wltID, addr, a)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -924,50 +2214,45 @@ func (_m *MockGatewayer) GetWallet(wltID string) (*wallet.Wallet, error) {
 	return r0, r1
 }
 
-// GetWalletBalance provides a mock function with given fields: wltID
-func (_m *MockGatewayer) GetWalletBalance(wltID string) (wallet.BalancePair, wallet.AddressBalances, error) {
-	ret := _m.Called(wltID)
-
-	var r0 wallet.BalancePair
-	if rf, ok := ret.Get(0).(func(string) wallet.BalancePair); ok {
-		r0 = rf(wltID)
-	} else {
-		r0 = ret.Get(0).(wallet.BalancePair)
-	}
+// SetCosigners provides a mock function with given fields: wltID, cs
+func (_m *MockGatewayer) SetCosigners(wltID string, cs wallet.Cosigners) (*wallet.Wallet, error) {
+	ret := _m.Called(wltID, cs)
 
-	var r1 wallet.AddressBalances
-	if rf, ok := ret.Get(1).(func(string) wallet.AddressBalances); ok {
-		r1 = rf(wltID)
+	var r0 *wallet.Wallet
+	if rf, ok := ret.Get(0).(func(string, wallet.Cosigners) *wallet.Wallet); ok {
+		r0 = rf(wltID, cs)
 	} else {
-		if ret.Get(1) != nil {
-			r1 = ret.Get(1).(wallet.AddressBalances)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*wallet.Wallet)
 		}
 	}
 
-	var r2 error
-	if rf, ok := ret.Get(2).(func(string) error); ok {
-		r2 = rf(wltID)
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, wallet.Cosigners) error); ok {
+		r1 = rf(wltID, cs)
 	} else {
-		r2 = ret.Error(2)
+		r1 = ret.Error(1)
 	}
 
-	return r0, r1, r2
+	return r0, r1
 }
 
-// GetWalletDir provides a mock function with given fields:
-func (_m *MockGatewayer) GetWalletDir() (string, error) {
-	ret := _m.Called()
+// SetSpendingPolicy provides a mock function with given fields: wltID, password, p
+func (_m *MockGatewayer) SetSpendingPolicy(wltID string, password []byte, p wallet.SpendingPolicy) (*wallet.Wallet, error) {
+	ret := _m.Called(wltID, password, p)
 
-	var r0 string
-	if rf, ok := ret.Get(0).(func() string); ok {
-		r0 = rf()
+	var r0 *wallet.Wallet
+	if rf, ok := ret.Get(0).(func(string, []byte, wallet.SpendingPolicy) *wallet.Wallet); ok {
+		r0 = rf(wltID, password, p)
 	} else {
-		r0 = ret.Get(0).(string)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*wallet.Wallet)
+		}
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func() error); ok {
-		r1 = rf()
+	if rf, ok := ret.Get(1).(func(string, []byte, wallet.SpendingPolicy) error); ok {
+		r1 = rf(wltID, password, p)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -975,20 +2260,22 @@ func (_m *MockGatewayer) GetWalletDir() (string, error) {
 	return r0, r1
 }
 
-// GetWalletSeed provides a mock function with given fields: wltID, password
-func (_m *MockGatewayer) GetWalletSeed(wltID string, password []byte) (string, error) {
-	ret := _m.Called(wltID, password)
+// SetTransactionAnnotation provides a mock function with given fields: wltID, txid, a
+func (_m *MockGatewayer) SetTransactionAnnotation(wltID string, txid cipher.SHA256, a wallet.Annotation) (*wallet.Wallet, error) {
+	ret := _m.Called(wltID, txid, a)
 
-	var r0 string
-	if rf, ok := ret.Get(0).(func(string, []byte) string); ok {
-		r0 = rf(wltID, password)
+	var r0 *wallet.Wallet
+	if rf, ok := ret.Get(0).(func(string, cipher.SHA256, wallet.Annotation) *wallet.Wallet); ok {
+		r0 = rf(wltID, txid, a)
 	} else {
-		r0 = ret.Get(0).(string)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*wallet.Wallet)
+		}
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string, []byte) error); ok {
-		r1 = rf(wltID, password)
+	if rf, ok := ret.Get(1).(func(string, cipher.SHA256, wallet.Annotation) error); ok {
+		r1 = rf(wltID, txid, a)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -996,22 +2283,22 @@ func (_m *MockGatewayer) GetWalletSeed(wltID string, password []byte) (string, e
 	return r0, r1
 }
 
-// GetWalletUnconfirmedTransactions provides a mock function with given fields: wltID
-func (_m *MockGatewayer) GetWalletUnconfirmedTransactions(wltID string) ([]visor.UnconfirmedTransaction, error) {
-	ret := _m.Called(wltID)
+// SignPartialTransaction provides a mock function with given fields: wltID, password, ptx
+func (_m *MockGatewayer) SignPartialTransaction(wltID string, password []byte, ptx *wallet.PartialTransaction) (*wallet.PartialTransaction, error) {
+	ret := _m.Called(wltID, password, ptx)
 
-	var r0 []visor.UnconfirmedTransaction
-	if rf, ok := ret.Get(0).(func(string) []visor.UnconfirmedTransaction); ok {
-		r0 = rf(wltID)
+	var r0 *wallet.PartialTransaction
+	if rf, ok := ret.Get(0).(func(string, []byte, *wallet.PartialTransaction) *wallet.PartialTransaction); ok {
+		r0 = rf(wltID, password, ptx)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]visor.UnconfirmedTransaction)
+			r0 = ret.Get(0).(*wallet.PartialTransaction)
 		}
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(wltID)
+	if rf, ok := ret.Get(1).(func(string, []byte, *wallet.PartialTransaction) error); ok {
+		r1 = rf(wltID, password, ptx)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -1019,114 +2306,102 @@ func (_m *MockGatewayer) GetWalletUnconfirmedTransactions(wltID string) ([]visor
 	return r0, r1
 }
 
-// GetWalletUnconfirmedTransactionsVerbose provides a mock function with given fields: wltID
-func (_m *MockGatewayer) GetWalletUnconfirmedTransactionsVerbose(wltID string) ([]visor.UnconfirmedTransaction, [][]visor.TransactionInput, error) {
-	ret := _m.Called(wltID)
+// Spend provides a mock function with given fields: wltID, password, coins, dest
+func (_m *MockGatewayer) Spend(wltID string, password []byte, coins uint64, dest cipher.Address) (*coin.Transaction, error) {
+	ret := _m.Called(wltID, password, coins, dest)
 
-	var r0 []visor.UnconfirmedTransaction
-	if rf, ok := ret.Get(0).(func(string) []visor.UnconfirmedTransaction); ok {
-		r0 = rf(wltID)
+	var r0 *coin.Transaction
+	if rf, ok := ret.Get(0).(func(string, []byte, uint64, cipher.Address) *coin.Transaction); ok {
+		r0 = rf(wltID, password, coins, dest)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]visor.UnconfirmedTransaction)
-		}
-	}
-
-	var r1 [][]visor.TransactionInput
-	if rf, ok := ret.Get(1).(func(string) [][]visor.TransactionInput); ok {
-		r1 = rf(wltID)
-	} else {
-		if ret.Get(1) != nil {
-			r1 = ret.Get(1).([][]visor.TransactionInput)
+			r0 = ret.Get(0).(*coin.Transaction)
 		}
 	}
 
-	var r2 error
-	if rf, ok := ret.Get(2).(func(string) error); ok {
-		r2 = rf(wltID)
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, []byte, uint64, cipher.Address) error); ok {
+		r1 = rf(wltID, password, coins, dest)
 	} else {
-		r2 = ret.Error(2)
+		r1 = ret.Error(1)
 	}
 
-	return r0, r1, r2
+	return r0, r1
 }
 
-// GetWallets provides a mock function with given fields:
-func (_m *MockGatewayer) GetWallets() (wallet.Wallets, error) {
+// SubscribeConnectionEvents provides a mock function with given fields:
+func (_m *MockGatewayer) SubscribeConnectionEvents() (<-chan daemon.ConnectionEvent, func()) {
 	ret := _m.Called()
 
-	var r0 wallet.Wallets
-	if rf, ok := ret.Get(0).(func() wallet.Wallets); ok {
+	var r0 <-chan daemon.ConnectionEvent
+	if rf, ok := ret.Get(0).(func() <-chan daemon.ConnectionEvent); ok {
 		r0 = rf()
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(wallet.Wallets)
+			r0 = ret.Get(0).(<-chan daemon.ConnectionEvent)
 		}
 	}
 
-	var r1 error
-	if rf, ok := ret.Get(1).(func() error); ok {
+	var r1 func()
+	if rf, ok := ret.Get(1).(func() func()); ok {
 		r1 = rf()
 	} else {
-		r1 = ret.Error(1)
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(func())
+		}
 	}
 
 	return r0, r1
 }
 
-// InjectBroadcastTransaction provides a mock function with given fields: txn
-func (_m *MockGatewayer) InjectBroadcastTransaction(txn coin.Transaction) error {
-	ret := _m.Called(txn)
+// SubscribeWalletEvents provides a mock function with given fields: wltID
+func (_m *MockGatewayer) SubscribeWalletEvents(wltID string) (<-chan visor.WalletEvent, func(), error) {
+	ret := _m.Called(wltID)
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(coin.Transaction) error); ok {
-		r0 = rf(txn)
+	var r0 <-chan visor.WalletEvent
+	if rf, ok := ret.Get(0).(func(string) <-chan visor.WalletEvent); ok {
+		r0 = rf(wltID)
 	} else {
-		r0 = ret.Error(0)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(<-chan visor.WalletEvent)
+		}
 	}
 
-	return r0
-}
-
-// NewAddresses provides a mock function with given fields: wltID, password, n
-func (_m *MockGatewayer) NewAddresses(wltID string, password []byte, n uint64) ([]cipher.Address, error) {
-	ret := _m.Called(wltID, password, n)
-
-	var r0 []cipher.Address
-	if rf, ok := ret.Get(0).(func(string, []byte, uint64) []cipher.Address); ok {
-		r0 = rf(wltID, password, n)
+	var r1 func()
+	if rf, ok := ret.Get(1).(func(string) func()); ok {
+		r1 = rf(wltID)
 	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]cipher.Address)
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(func())
 		}
 	}
 
-	var r1 error
-	if rf, ok := ret.Get(1).(func(string, []byte, uint64) error); ok {
-		r1 = rf(wltID, password, n)
+	var r2 error
+	if rf, ok := ret.Get(2).(func(string) error); ok {
+		r2 = rf(wltID)
 	} else {
-		r1 = ret.Error(1)
+		r2 = ret.Error(2)
 	}
 
-	return r0, r1
+	return r0, r1, r2
 }
 
-// RecoverWallet provides a mock function with given fields: wltID, seed, password
-func (_m *MockGatewayer) RecoverWallet(wltID string, seed string, password []byte) (*wallet.Wallet, error) {
-	ret := _m.Called(wltID, seed, password)
+// SweepPrivateKeys provides a mock function with given fields: keys, wltID, dest
+func (_m *MockGatewayer) SweepPrivateKeys(keys []cipher.SecKey, wltID string, dest cipher.Address) (*wallet.Sweep, error) {
+	ret := _m.Called(keys, wltID, dest)
 
-	var r0 *wallet.Wallet
-	if rf, ok := ret.Get(0).(func(string, string, []byte) *wallet.Wallet); ok {
-		r0 = rf(wltID, seed, password)
+	var r0 *wallet.Sweep
+	if rf, ok := ret.Get(0).(func([]cipher.SecKey, string, cipher.Address) *wallet.Sweep); ok {
+		r0 = rf(keys, wltID, dest)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*wallet.Wallet)
+			r0 = ret.Get(0).(*wallet.Sweep)
 		}
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string, string, []byte) error); ok {
-		r1 = rf(wltID, seed, password)
+	if rf, ok := ret.Get(1).(func([]cipher.SecKey, string, cipher.Address) error); ok {
+		r1 = rf(keys, wltID, dest)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -1134,50 +2409,48 @@ func (_m *MockGatewayer) RecoverWallet(wltID string, seed string, password []byt
 	return r0, r1
 }
 
-// ResendUnconfirmedTxns provides a mock function with given fields:
-func (_m *MockGatewayer) ResendUnconfirmedTxns() ([]cipher.SHA256, error) {
-	ret := _m.Called()
+// TakeWalletPoolAddresses provides a mock function with given fields: wltID, account, n
+func (_m *MockGatewayer) TakeWalletPoolAddresses(wltID string, account string, n uint64) ([]cipher.Address, wallet.AddressPoolStatus, error) {
+	ret := _m.Called(wltID, account, n)
 
-	var r0 []cipher.SHA256
-	if rf, ok := ret.Get(0).(func() []cipher.SHA256); ok {
-		r0 = rf()
+	var r0 []cipher.Address
+	if rf, ok := ret.Get(0).(func(string, string, uint64) []cipher.Address); ok {
+		r0 = rf(wltID, account, n)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]cipher.SHA256)
+			r0 = ret.Get(0).([]cipher.Address)
 		}
 	}
 
-	var r1 error
-	if rf, ok := ret.Get(1).(func() error); ok {
-		r1 = rf()
+	var r1 wallet.AddressPoolStatus
+	if rf, ok := ret.Get(1).(func(string, string, uint64) wallet.AddressPoolStatus); ok {
+		r1 = rf(wltID, account, n)
 	} else {
-		r1 = ret.Error(1)
+		r1 = ret.Get(1).(wallet.AddressPoolStatus)
 	}
 
-	return r0, r1
-}
-
-// Spend provides a mock function with given fields: wltID, password, coins, dest
-func (_m *MockGatewayer) Spend(wltID string, password []byte, coins uint64, dest cipher.Address) (*coin.Transaction, error) {
-	ret := _m.Called(wltID, password, coins, dest)
-
-	var r0 *coin.Transaction
-	if rf, ok := ret.Get(0).(func(string, []byte, uint64, cipher.Address) *coin.Transaction); ok {
-		r0 = rf(wltID, password, coins, dest)
+	var r2 error
+	if rf, ok := ret.Get(2).(func(string, string, uint64) error); ok {
+		r2 = rf(wltID, account, n)
 	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*coin.Transaction)
-		}
+		r2 = ret.Error(2)
 	}
 
-	var r1 error
-	if rf, ok := ret.Get(1).(func(string, []byte, uint64, cipher.Address) error); ok {
-		r1 = rf(wltID, password, coins, dest)
+	return r0, r1, r2
+}
+
+// Unban provides a mock function with given fields: ip
+func (_m *MockGatewayer) Unban(ip string) error {
+	ret := _m.Called(ip)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(ip)
 	} else {
-		r1 = ret.Error(1)
+		r0 = ret.Error(0)
 	}
 
-	return r0, r1
+	return r0
 }
 
 // UnloadWallet provides a mock function with given fields: id
@@ -1237,3 +2510,70 @@ func (_m *MockGatewayer) VerifyTxnVerbose(txn *coin.Transaction) ([]wallet.UxBal
 
 	return r0, r1, r2
 }
+
+// WaitWalletEvents provides a mock function with given fields: ctx, wltID, after, limit
+func (_m *MockGatewayer) WaitWalletEvents(ctx context.Context, wltID string, after uint64, limit uint64) ([]visor.WalletEvent, error) {
+	ret := _m.Called(ctx, wltID, after, limit)
+
+	var r0 []visor.WalletEvent
+	if rf, ok := ret.Get(0).(func(context.Context, string, uint64, uint64) []visor.WalletEvent); ok {
+		r0 = rf(ctx, wltID, after, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]visor.WalletEvent)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, uint64, uint64) error); ok {
+		r1 = rf(ctx, wltID, after, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// WaitWatchEvents provides a mock function with given fields: ctx, after, limit
+func (_m *MockGatewayer) WaitWatchEvents(ctx context.Context, after uint64, limit uint64) ([]visor.WatchEvent, error) {
+	ret := _m.Called(ctx, after, limit)
+
+	var r0 []visor.WatchEvent
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, uint64) []visor.WatchEvent); ok {
+		r0 = rf(ctx, after, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]visor.WatchEvent)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uint64, uint64) error); ok {
+		r1 = rf(ctx, after, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// WalletAddressPoolStatus provides a mock function with given fields: wltID, account
+func (_m *MockGatewayer) WalletAddressPoolStatus(wltID string, account string) (wallet.AddressPoolStatus, error) {
+	ret := _m.Called(wltID, account)
+
+	var r0 wallet.AddressPoolStatus
+	if rf, ok := ret.Get(0).(func(string, string) wallet.AddressPoolStatus); ok {
+		r0 = rf(wltID, account)
+	} else {
+		r0 = ret.Get(0).(wallet.AddressPoolStatus)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(wltID, account)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
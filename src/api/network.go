@@ -4,12 +4,15 @@ package api
 
 import (
 	"fmt"
+	"net"
 	"net/http"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/skycoin/skycoin/src/daemon"
+	"github.com/skycoin/skycoin/src/daemon/pex"
 	"github.com/skycoin/skycoin/src/readable"
 	wh "github.com/skycoin/skycoin/src/util/http"
 )
@@ -226,3 +229,239 @@ func disconnectHandler(gateway Gatewayer) http.HandlerFunc {
 		wh.SendJSONOr500(logger, w, struct{}{})
 	}
 }
+
+// Bans wraps []readable.Ban
+type Bans struct {
+	Bans []readable.Ban `json:"bans"`
+}
+
+// NewBans copies []pex.Ban to a struct with json tags
+func NewBans(pbans []pex.Ban) Bans {
+	bans := make([]readable.Ban, len(pbans))
+	for i, b := range pbans {
+		bans[i] = readable.NewBan(b)
+	}
+
+	return Bans{
+		Bans: bans,
+	}
+}
+
+// bansHandler returns the banned peer IPs, sorted by IP
+// URI: /api/v1/network/bans
+// Method: GET
+func bansHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			wh.Error405(w)
+			return
+		}
+
+		wh.SendJSONOr500(logger, w, NewBans(gateway.GetBans()))
+	}
+}
+
+// relayPolicyHandler returns the policy applied to the transactions received from peers,
+// and the number of transactions it rejected by reason
+// URI: /api/v1/network/relay_policy
+// Method: GET
+func relayPolicyHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			wh.Error405(w)
+			return
+		}
+
+		p, err := readable.NewRelayPolicy(gateway.GetRelayPolicy())
+		if err != nil {
+			wh.Error500(w, err.Error())
+			return
+		}
+
+		wh.SendJSONOr500(logger, w, p)
+	}
+}
+
+// compressionHandler returns whether compression is negotiated with peers,
+// and the sizes of the messages compressed and decompressed
+// URI: /api/v1/network/compression
+// Method: GET
+func compressionHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			wh.Error405(w)
+			return
+		}
+
+		wh.SendJSONOr500(logger, w, readable.NewCompression(gateway.GetCompressionStatus()))
+	}
+}
+
+// unbanHandler removes the ban of a peer IP
+// URI: /api/v1/network/bans/unban
+// Method: POST
+// Args:
+//	ip: IP to unban
+func unbanHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			wh.Error405(w)
+			return
+		}
+
+		ip := r.FormValue("ip")
+		if ip == "" {
+			wh.Error400(w, "ip is required")
+			return
+		}
+
+		if err := gateway.Unban(ip); err != nil {
+			switch err {
+			case pex.ErrInvalidAddress:
+				wh.Error400(w, "invalid ip")
+			case pex.ErrNotBanned:
+				wh.Error404(w, "")
+			default:
+				wh.Error500(w, err.Error())
+			}
+			return
+		}
+
+		wh.SendJSONOr500(logger, w, struct{}{})
+	}
+}
+
+// Peers wraps []readable.Peer
+type Peers struct {
+	Peers []readable.Peer `json:"peers"`
+}
+
+// NewPeers copies []pex.Peer to a struct with json tags
+func NewPeers(pps []pex.Peer) Peers {
+	peers := make([]readable.Peer, len(pps))
+	for i, p := range pps {
+		peers[i] = readable.NewPeer(p)
+	}
+
+	return Peers{
+		Peers: peers,
+	}
+}
+
+// peersHandler routes the requests to /api/v1/network/peers by method,
+// since listing the peers and changing them belong to different API sets
+func peersHandler(get, post http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			get(w, r)
+		case http.MethodPost:
+			post(w, r)
+		default:
+			wh.Error405(w)
+		}
+	}
+}
+
+// listPeersHandler returns the peers of the peer list, sorted by address
+// URI: /api/v1/network/peers
+// Method: GET
+func listPeersHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			wh.Error405(w)
+			return
+		}
+
+		wh.SendJSONOr500(logger, w, NewPeers(gateway.GetPeers()))
+	}
+}
+
+// updatePeersHandler adds, removes or bans peers. The peer list and the bans are persisted
+// URI: /api/v1/network/peers
+// Method: POST
+// Args:
+//	action: "add", "remove" or "ban"
+//	addrs: comma-separated list of IP:Port addresses to add or remove, or of IPs to ban
+//	reason: [optional] reason of the ban. Defaults to "operator"
+//	duration: [optional] duration of the ban, e.g. "48h". Defaults to the ban duration of the node
+func updatePeersHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			wh.Error405(w)
+			return
+		}
+
+		action := r.FormValue("action")
+		if action == "" {
+			wh.Error400(w, "action is required")
+			return
+		}
+
+		addrsStr := r.FormValue("addrs")
+		if addrsStr == "" {
+			wh.Error400(w, "addrs is required")
+			return
+		}
+
+		var addrs []string
+		for _, a := range strings.Split(addrsStr, ",") {
+			a = strings.TrimSpace(a)
+			if a == "" {
+				wh.Error400(w, "invalid addrs")
+				return
+			}
+			addrs = append(addrs, a)
+		}
+
+		var err error
+		switch action {
+		case "add":
+			err = gateway.AddPeers(addrs)
+		case "remove":
+			err = gateway.RemovePeers(addrs)
+		case "ban":
+			for _, ip := range addrs {
+				if net.ParseIP(ip) == nil && !pex.IsOnionHost(ip) {
+					wh.Error400(w, fmt.Sprintf("invalid ip %q", ip))
+					return
+				}
+			}
+
+			reason := r.FormValue("reason")
+			if reason == "" {
+				reason = "operator"
+			}
+
+			var duration time.Duration
+			if d := r.FormValue("duration"); d != "" {
+				duration, err = time.ParseDuration(d)
+				if err != nil || duration <= 0 {
+					wh.Error400(w, "invalid duration")
+					return
+				}
+			}
+
+			err = gateway.BanPeers(addrs, reason, duration)
+		default:
+			wh.Error400(w, fmt.Sprintf("invalid action %q", action))
+			return
+		}
+
+		if err != nil {
+			switch err {
+			case pex.ErrInvalidAddress, pex.ErrBlacklistedAddress:
+				wh.Error400(w, err.Error())
+			case pex.ErrPeerNotFound:
+				wh.Error404(w, "")
+			case pex.ErrPeerlistFull:
+				wh.Error503(w, err.Error())
+			default:
+				wh.Error500(w, err.Error())
+			}
+			return
+		}
+
+		wh.SendJSONOr500(logger, w, struct{}{})
+	}
+}
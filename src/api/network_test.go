@@ -14,6 +14,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/skycoin/skycoin/src/daemon"
+	"github.com/skycoin/skycoin/src/daemon/gnet"
 	"github.com/skycoin/skycoin/src/daemon/pex"
 	"github.com/skycoin/skycoin/src/readable"
 	"github.com/skycoin/skycoin/src/util/useragent"
@@ -629,3 +630,635 @@ func TestDisconnect(t *testing.T) {
 	}
 
 }
+
+func TestGetBans(t *testing.T) {
+	tt := []struct {
+		name                 string
+		method               string
+		status               int
+		err                  string
+		gatewayGetBansResult []pex.Ban
+		result               Bans
+	}{
+		{
+			name:   "405",
+			method: http.MethodPost,
+			status: http.StatusMethodNotAllowed,
+			err:    "405 Method Not Allowed",
+		},
+		{
+			name:   "200 no bans",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			result: Bans{
+				Bans: []readable.Ban{},
+			},
+		},
+		{
+			name:   "200",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			gatewayGetBansResult: []pex.Ban{
+				{
+					IP:      "11.44.66.88",
+					Reason:  "invalid_message",
+					Created: 1000,
+					Expires: 2000,
+				},
+			},
+			result: Bans{
+				Bans: []readable.Ban{
+					{
+						IP:      "11.44.66.88",
+						Reason:  "invalid_message",
+						Created: 1000,
+						Expires: 2000,
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			endpoint := "/api/v1/network/bans"
+			gateway := &MockGatewayer{}
+			gateway.On("GetBans").Return(tc.gatewayGetBansResult)
+
+			req, err := http.NewRequest(tc.method, endpoint, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			status := rr.Code
+			require.Equal(t, tc.status, status, "got `%v` want `%v`", status, tc.status)
+
+			if status != http.StatusOK {
+				require.Equal(t, tc.err, strings.TrimSpace(rr.Body.String()), "got `%v`| %d, want `%v`",
+					strings.TrimSpace(rr.Body.String()), status, tc.err)
+			} else {
+				var msg Bans
+				err = json.Unmarshal(rr.Body.Bytes(), &msg)
+				require.NoError(t, err)
+				require.Equal(t, tc.result, msg)
+			}
+		})
+	}
+}
+
+func TestGetRelayPolicy(t *testing.T) {
+	tt := []struct {
+		name                        string
+		method                      string
+		status                      int
+		err                         string
+		gatewayGetRelayPolicyResult daemon.RelayPolicyStatus
+		result                      readable.RelayPolicy
+	}{
+		{
+			name:   "405",
+			method: http.MethodPost,
+			status: http.StatusMethodNotAllowed,
+			err:    "405 Method Not Allowed",
+		},
+		{
+			name:   "200 no rejections",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			gatewayGetRelayPolicyResult: daemon.RelayPolicyStatus{
+				Policy: daemon.RelayPolicy{
+					BlocksOnly: true,
+				},
+			},
+			result: readable.RelayPolicy{
+				BlocksOnly:    true,
+				DustThreshold: "0.000000",
+				Rejected:      map[string]uint64{},
+			},
+		},
+		{
+			name:   "200",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			gatewayGetRelayPolicyResult: daemon.RelayPolicyStatus{
+				Policy: daemon.RelayPolicy{
+					MinBurnFactor:      4,
+					MaxTransactionSize: 32768,
+					MaxOutputs:         100,
+					DustThreshold:      1e3,
+				},
+				Rejected: map[daemon.RelayRejectReason]uint64{
+					daemon.RelayRejectDust:            3,
+					daemon.RelayRejectInsufficientFee: 1,
+				},
+			},
+			result: readable.RelayPolicy{
+				MinBurnFactor:      4,
+				MaxTransactionSize: 32768,
+				MaxOutputs:         100,
+				DustThreshold:      "0.001000",
+				Rejected: map[string]uint64{
+					"dust":             3,
+					"insufficient_fee": 1,
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			endpoint := "/api/v1/network/relay_policy"
+			gateway := &MockGatewayer{}
+			gateway.On("GetRelayPolicy").Return(tc.gatewayGetRelayPolicyResult)
+
+			req, err := http.NewRequest(tc.method, endpoint, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			status := rr.Code
+			require.Equal(t, tc.status, status, "got `%v` want `%v`", status, tc.status)
+
+			if status != http.StatusOK {
+				require.Equal(t, tc.err, strings.TrimSpace(rr.Body.String()), "got `%v`| %d, want `%v`",
+					strings.TrimSpace(rr.Body.String()), status, tc.err)
+			} else {
+				var msg readable.RelayPolicy
+				err = json.Unmarshal(rr.Body.Bytes(), &msg)
+				require.NoError(t, err)
+				require.Equal(t, tc.result, msg)
+			}
+		})
+	}
+}
+
+func TestGetCompression(t *testing.T) {
+	tt := []struct {
+		name                              string
+		method                            string
+		status                            int
+		err                               string
+		gatewayGetCompressionStatusResult daemon.CompressionStatus
+		result                            readable.Compression
+	}{
+		{
+			name:   "405",
+			method: http.MethodPost,
+			status: http.StatusMethodNotAllowed,
+			err:    "405 Method Not Allowed",
+		},
+		{
+			name:   "200 disabled",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			gatewayGetCompressionStatusResult: daemon.CompressionStatus{
+				Threshold: 1024,
+			},
+			result: readable.Compression{
+				Threshold: 1024,
+			},
+		},
+		{
+			name:   "200",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			gatewayGetCompressionStatusResult: daemon.CompressionStatus{
+				Enabled:   true,
+				Threshold: 1024,
+				Stats: gnet.CompressionStats{
+					SentMessages:           120,
+					SentSize:               3840000,
+					SentCompressedSize:     1536000,
+					ReceivedMessages:       2,
+					ReceivedSize:           4000,
+					ReceivedCompressedSize: 4000,
+				},
+			},
+			result: readable.Compression{
+				Enabled:   true,
+				Threshold: 1024,
+				Sent: readable.CompressedMessages{
+					Messages:       120,
+					Size:           3840000,
+					CompressedSize: 1536000,
+					Ratio:          2.5,
+				},
+				Received: readable.CompressedMessages{
+					Messages:       2,
+					Size:           4000,
+					CompressedSize: 4000,
+					Ratio:          1,
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			endpoint := "/api/v1/network/compression"
+			gateway := &MockGatewayer{}
+			gateway.On("GetCompressionStatus").Return(tc.gatewayGetCompressionStatusResult)
+
+			req, err := http.NewRequest(tc.method, endpoint, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			status := rr.Code
+			require.Equal(t, tc.status, status, "got `%v` want `%v`", status, tc.status)
+
+			if status != http.StatusOK {
+				require.Equal(t, tc.err, strings.TrimSpace(rr.Body.String()), "got `%v`| %d, want `%v`",
+					strings.TrimSpace(rr.Body.String()), status, tc.err)
+			} else {
+				var msg readable.Compression
+				err = json.Unmarshal(rr.Body.Bytes(), &msg)
+				require.NoError(t, err)
+				require.Equal(t, tc.result, msg)
+			}
+		})
+	}
+}
+
+func TestUnban(t *testing.T) {
+	tt := []struct {
+		name     string
+		method   string
+		status   int
+		err      string
+		unbanErr error
+		ip       string
+	}{
+		{
+			name:   "405",
+			method: http.MethodGet,
+			status: http.StatusMethodNotAllowed,
+			err:    "405 Method Not Allowed",
+		},
+
+		{
+			name:   "400 missing ip",
+			method: http.MethodPost,
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - ip is required",
+		},
+
+		{
+			name:     "400 invalid ip",
+			method:   http.MethodPost,
+			status:   http.StatusBadRequest,
+			err:      "400 Bad Request - invalid ip",
+			unbanErr: pex.ErrInvalidAddress,
+			ip:       "11.44.66",
+		},
+
+		{
+			name:     "404 not banned",
+			method:   http.MethodPost,
+			status:   http.StatusNotFound,
+			err:      "404 Not Found",
+			unbanErr: pex.ErrNotBanned,
+			ip:       "11.44.66.88",
+		},
+
+		{
+			name:     "500 Unban error",
+			method:   http.MethodPost,
+			status:   http.StatusInternalServerError,
+			err:      "500 Internal Server Error - foo",
+			unbanErr: errors.New("foo"),
+			ip:       "11.44.66.88",
+		},
+
+		{
+			name:   "200",
+			method: http.MethodPost,
+			status: http.StatusOK,
+			ip:     "11.44.66.88",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("Unban", tc.ip).Return(tc.unbanErr)
+
+			endpoint := "/api/v1/network/bans/unban"
+			v := url.Values{}
+			if tc.ip != "" {
+				v.Add("ip", tc.ip)
+			}
+
+			req, err := http.NewRequest(tc.method, endpoint, strings.NewReader(v.Encode()))
+			require.NoError(t, err)
+			req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			status := rr.Code
+			require.Equal(t, tc.status, status, "got `%v` want `%v`", status, tc.status)
+
+			if status != http.StatusOK {
+				require.Equal(t, tc.err, strings.TrimSpace(rr.Body.String()), "got `%v`| %d, want `%v`",
+					strings.TrimSpace(rr.Body.String()), status, tc.err)
+			} else {
+				var obj struct{}
+				err = json.Unmarshal(rr.Body.Bytes(), &obj)
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestListPeers(t *testing.T) {
+	tt := []struct {
+		name                  string
+		method                string
+		status                int
+		err                   string
+		gatewayGetPeersResult []pex.Peer
+		result                Peers
+	}{
+		{
+			name:   "405",
+			method: http.MethodPut,
+			status: http.StatusMethodNotAllowed,
+			err:    "405 Method Not Allowed",
+		},
+		{
+			name:   "200 no peers",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			result: Peers{
+				Peers: []readable.Peer{},
+			},
+		},
+		{
+			name:   "200",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			gatewayGetPeersResult: []pex.Peer{
+				{
+					Addr:            "11.44.66.88:6000",
+					LastSeen:        1000,
+					Trusted:         true,
+					HasIncomingPort: true,
+					UserAgent: useragent.Data{
+						Coin:    "skycoin",
+						Version: "0.25.0",
+					},
+					ProtocolVersion:    3,
+					Uptime:             time.Hour,
+					HandshakeAttempts:  2,
+					HandshakeSuccesses: 1,
+					BlockLatency:       time.Millisecond * 250,
+					Source:             "11.44.66.99:6000",
+					Tried:              true,
+				},
+			},
+			result: Peers{
+				Peers: []readable.Peer{
+					{
+						Addr:            "11.44.66.88:6000",
+						LastSeen:        1000,
+						Trusted:         true,
+						HasIncomingPort: true,
+						UserAgent: useragent.Data{
+							Coin:    "skycoin",
+							Version: "0.25.0",
+						},
+						ProtocolVersion:    3,
+						Uptime:             3600,
+						HandshakeAttempts:  2,
+						HandshakeSuccesses: 1,
+						BlockLatency:       250,
+						Source:             "11.44.66.99:6000",
+						Tried:              true,
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			endpoint := "/api/v1/network/peers"
+			gateway := &MockGatewayer{}
+			gateway.On("GetPeers").Return(tc.gatewayGetPeersResult)
+
+			req, err := http.NewRequest(tc.method, endpoint, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			status := rr.Code
+			require.Equal(t, tc.status, status, "got `%v` want `%v`", status, tc.status)
+
+			if status != http.StatusOK {
+				require.Equal(t, tc.err, strings.TrimSpace(rr.Body.String()), "got `%v`| %d, want `%v`",
+					strings.TrimSpace(rr.Body.String()), status, tc.err)
+			} else {
+				var msg Peers
+				err = json.Unmarshal(rr.Body.Bytes(), &msg)
+				require.NoError(t, err)
+				require.Equal(t, tc.result, msg)
+			}
+		})
+	}
+}
+
+func TestUpdatePeers(t *testing.T) {
+	tt := []struct {
+		name        string
+		status      int
+		err         string
+		form        url.Values
+		addrs       []string
+		reason      string
+		duration    time.Duration
+		gatewayErr  error
+		gatewayCall string
+	}{
+		{
+			name:   "400 missing action",
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - action is required",
+			form: url.Values{
+				"addrs": {"11.44.66.88:6000"},
+			},
+		},
+		{
+			name:   "400 missing addrs",
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - addrs is required",
+			form: url.Values{
+				"action": {"add"},
+			},
+		},
+		{
+			name:   "400 empty addr",
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - invalid addrs",
+			form: url.Values{
+				"action": {"add"},
+				"addrs":  {"11.44.66.88:6000,"},
+			},
+		},
+		{
+			name:   "400 invalid action",
+			status: http.StatusBadRequest,
+			err:    `400 Bad Request - invalid action "trust"`,
+			form: url.Values{
+				"action": {"trust"},
+				"addrs":  {"11.44.66.88:6000"},
+			},
+		},
+		{
+			name:   "400 add invalid address",
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - Invalid address",
+			form: url.Values{
+				"action": {"add"},
+				"addrs":  {"11.44.66.88:6000,11.44.66"},
+			},
+			addrs:       []string{"11.44.66.88:6000", "11.44.66"},
+			gatewayCall: "AddPeers",
+			gatewayErr:  pex.ErrInvalidAddress,
+		},
+		{
+			name:   "503 add peer list full",
+			status: http.StatusServiceUnavailable,
+			err:    "503 Service Unavailable - Peer list full",
+			form: url.Values{
+				"action": {"add"},
+				"addrs":  {"11.44.66.88:6000"},
+			},
+			addrs:       []string{"11.44.66.88:6000"},
+			gatewayCall: "AddPeers",
+			gatewayErr:  pex.ErrPeerlistFull,
+		},
+		{
+			name:   "200 add",
+			status: http.StatusOK,
+			form: url.Values{
+				"action": {"add"},
+				"addrs":  {"11.44.66.88:6000, 11.44.66.99:6000"},
+			},
+			addrs:       []string{"11.44.66.88:6000", "11.44.66.99:6000"},
+			gatewayCall: "AddPeers",
+		},
+		{
+			name:   "404 remove unknown peer",
+			status: http.StatusNotFound,
+			err:    "404 Not Found",
+			form: url.Values{
+				"action": {"remove"},
+				"addrs":  {"11.44.66.88:6000"},
+			},
+			addrs:       []string{"11.44.66.88:6000"},
+			gatewayCall: "RemovePeers",
+			gatewayErr:  pex.ErrPeerNotFound,
+		},
+		{
+			name:   "200 remove",
+			status: http.StatusOK,
+			form: url.Values{
+				"action": {"remove"},
+				"addrs":  {"11.44.66.88:6000"},
+			},
+			addrs:       []string{"11.44.66.88:6000"},
+			gatewayCall: "RemovePeers",
+		},
+		{
+			name:   "400 ban invalid ip",
+			status: http.StatusBadRequest,
+			err:    `400 Bad Request - invalid ip "11.44.66.88:6000"`,
+			form: url.Values{
+				"action": {"ban"},
+				"addrs":  {"11.44.66.88:6000"},
+			},
+		},
+		{
+			name:   "400 ban invalid duration",
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - invalid duration",
+			form: url.Values{
+				"action":   {"ban"},
+				"addrs":    {"11.44.66.88"},
+				"duration": {"-1h"},
+			},
+		},
+		{
+			name:   "500 ban error",
+			status: http.StatusInternalServerError,
+			err:    "500 Internal Server Error - foo",
+			form: url.Values{
+				"action": {"ban"},
+				"addrs":  {"11.44.66.88"},
+			},
+			addrs:       []string{"11.44.66.88"},
+			reason:      "operator",
+			gatewayCall: "BanPeers",
+			gatewayErr:  errors.New("foo"),
+		},
+		{
+			name:   "200 ban",
+			status: http.StatusOK,
+			form: url.Values{
+				"action":   {"ban"},
+				"addrs":    {"11.44.66.88,2001:db8::1"},
+				"reason":   {"spam"},
+				"duration": {"48h"},
+			},
+			addrs:       []string{"11.44.66.88", "2001:db8::1"},
+			reason:      "spam",
+			duration:    time.Hour * 48,
+			gatewayCall: "BanPeers",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			switch tc.gatewayCall {
+			case "AddPeers", "RemovePeers":
+				gateway.On(tc.gatewayCall, tc.addrs).Return(tc.gatewayErr)
+			case "BanPeers":
+				gateway.On(tc.gatewayCall, tc.addrs, tc.reason, tc.duration).Return(tc.gatewayErr)
+			}
+
+			endpoint := "/api/v1/network/peers"
+			req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(tc.form.Encode()))
+			require.NoError(t, err)
+			req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			status := rr.Code
+			require.Equal(t, tc.status, status, "got `%v` want `%v`", status, tc.status)
+
+			if status != http.StatusOK {
+				require.Equal(t, tc.err, strings.TrimSpace(rr.Body.String()), "got `%v`| %d, want `%v`",
+					strings.TrimSpace(rr.Body.String()), status, tc.err)
+			} else {
+				var obj struct{}
+				err = json.Unmarshal(rr.Body.Bytes(), &obj)
+				require.NoError(t, err)
+			}
+
+			gateway.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+// PartialTransactionResponse is returned by the endpoints that sign and combine partial transactions
+type PartialTransactionResponse struct {
+	// PartialTransaction is the serialized partial transaction, hex-encoded
+	PartialTransaction string `json:"partial_transaction"`
+	FullySigned        bool   `json:"fully_signed"`
+	// UnsignedAddresses are the owners of the inputs that are not signed yet
+	UnsignedAddresses []string `json:"unsigned_addresses"`
+	// EncodedTransaction is the serialized transaction, once it is fully signed.
+	// It can be injected with POST /api/v1/injectTransaction
+	EncodedTransaction string `json:"encoded_transaction,omitempty"`
+}
+
+// NewPartialTransactionResponse creates a PartialTransactionResponse
+func NewPartialTransactionResponse(ptx *wallet.PartialTransaction) PartialTransactionResponse {
+	unsigned := ptx.UnsignedAddresses()
+	addrs := make([]string, len(unsigned))
+	for i, a := range unsigned {
+		addrs[i] = a.String()
+	}
+
+	rsp := PartialTransactionResponse{
+		PartialTransaction: ptx.SerializeHex(),
+		FullySigned:        ptx.IsFullySigned(),
+		UnsignedAddresses:  addrs,
+	}
+
+	if rsp.FullySigned {
+		rsp.EncodedTransaction = hex.EncodeToString(ptx.Transaction.Serialize())
+	}
+
+	return rsp
+}
+
+// SignPartialTransactionRequest is the request data for POST /api/v2/wallet/transaction/sign
+type SignPartialTransactionRequest struct {
+	WalletID           string `json:"wallet_id"`
+	Password           string `json:"password"`
+	PartialTransaction string `json:"partial_transaction"`
+}
+
+// URI: /api/v2/wallet/transaction/sign
+// Method: POST
+// Args:
+//  wallet_id: wallet id
+//  password: [optional] wallet password, if the wallet is encrypted
+//  partial_transaction: hex-encoded partial transaction
+// Signs the inputs of a partial transaction that are owned by the wallet.
+// The transaction must comply with the spending policy of the wallet.
+// Partial transactions are created by POST /api/v1/wallet/transaction with "unsigned": true.
+func signPartialTransactionHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if r.Header.Get("Content-Type") != ContentTypeJSON {
+			resp := NewHTTPErrorResponse(http.StatusUnsupportedMediaType, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		var req SignPartialTransactionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if req.WalletID == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "wallet_id is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if req.PartialTransaction == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "partial_transaction is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		ptx, err := wallet.PartialTransactionDeserializeHex(req.PartialTransaction)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		var password []byte
+		if req.Password != "" {
+			password = []byte(req.Password)
+		}
+
+		defer func() {
+			req.Password = ""
+			password = nil
+		}()
+
+		signed, err := gateway.SignPartialTransaction(req.WalletID, password, ptx)
+		if err != nil {
+			var resp HTTPResponse
+			switch err {
+			case wallet.ErrWalletNotExist:
+				resp = NewHTTPErrorResponse(http.StatusNotFound, "")
+			case wallet.ErrWalletAPIDisabled:
+				resp = NewHTTPErrorResponse(http.StatusForbidden, "")
+			default:
+				switch err.(type) {
+				case wallet.Error:
+					resp = NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+				default:
+					resp = NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+				}
+			}
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: NewPartialTransactionResponse(signed),
+		})
+	}
+}
+
+// CombinePartialTransactionsRequest is the request data for POST /api/v2/transaction/partial/combine
+type CombinePartialTransactionsRequest struct {
+	PartialTransactions []string `json:"partial_transactions"`
+}
+
+// URI: /api/v2/transaction/partial/combine
+// Method: POST
+// Args:
+//  partial_transactions: hex-encoded partial transactions of the same transaction
+// Combines the signatures of partial transactions of the same transaction, signed separately by different wallets.
+func combinePartialTransactionsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if r.Header.Get("Content-Type") != ContentTypeJSON {
+			resp := NewHTTPErrorResponse(http.StatusUnsupportedMediaType, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		var req CombinePartialTransactionsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		ptx, err := combinePartialTransactions(req.PartialTransactions)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: NewPartialTransactionResponse(ptx),
+		})
+	}
+}
+
+func combinePartialTransactions(ptxStrs []string) (*wallet.PartialTransaction, error) {
+	if len(ptxStrs) == 0 {
+		return nil, errors.New("partial_transactions is required")
+	}
+
+	var combined *wallet.PartialTransaction
+	for _, s := range ptxStrs {
+		ptx, err := wallet.PartialTransactionDeserializeHex(s)
+		if err != nil {
+			return nil, err
+		}
+
+		if combined == nil {
+			combined = ptx
+			continue
+		}
+
+		if err := combined.Combine(ptx); err != nil {
+			return nil, err
+		}
+	}
+
+	return combined, nil
+}
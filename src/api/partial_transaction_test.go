@@ -0,0 +1,331 @@
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+// makeTestPartialTransaction creates an unsigned transaction spending an output of each key
+func makeTestPartialTransaction(t *testing.T, keys []cipher.SecKey) *wallet.PartialTransaction {
+	var txn coin.Transaction
+	inputs := make([]wallet.UxBalance, len(keys))
+	for i, k := range keys {
+		ux := coin.UxOut{
+			Body: coin.UxBody{
+				SrcTransaction: testutil.RandSHA256(t),
+				Address:        cipher.MustAddressFromSecKey(k),
+				Coins:          1e6,
+				Hours:          10,
+			},
+		}
+
+		b, err := wallet.NewUxBalance(0, ux)
+		require.NoError(t, err)
+		inputs[i] = b
+
+		txn.PushInput(ux.Hash())
+	}
+	txn.PushOutput(testutil.MakeAddress(), uint64(len(keys))*1e6, 1)
+	txn.Sigs = make([]cipher.Sig, len(txn.In))
+	require.NoError(t, txn.UpdateHeader())
+
+	ptx, err := wallet.NewPartialTransaction(txn, inputs)
+	require.NoError(t, err)
+	return ptx
+}
+
+func TestSignPartialTransaction(t *testing.T) {
+	_, keys := cipher.MustGenerateDeterministicKeyPairsSeed([]byte("seed"), 2)
+	ptx := makeTestPartialTransaction(t, keys)
+
+	signed := *ptx
+	signed.Transaction.Sigs = []cipher.Sig{cipher.MustSignHash(cipher.AddSHA256(ptx.Transaction.InnerHash, ptx.Transaction.In[0]), keys[0]), {}}
+
+	fullySigned := *ptx
+	fullySigned.Transaction.Sigs = []cipher.Sig{
+		signed.Transaction.Sigs[0],
+		cipher.MustSignHash(cipher.AddSHA256(ptx.Transaction.InnerHash, ptx.Transaction.In[1]), keys[1]),
+	}
+
+	cases := []struct {
+		name        string
+		method      string
+		contentType string
+		req         SignPartialTransactionRequest
+		password    []byte
+		status      int
+		err         string
+		gatewayRsp  *wallet.PartialTransaction
+		gatewayErr  error
+		rsp         PartialTransactionResponse
+	}{
+		{
+			name:   "405",
+			method: http.MethodGet,
+			status: http.StatusMethodNotAllowed,
+			err:    "Method Not Allowed",
+		},
+		{
+			name:        "415",
+			method:      http.MethodPost,
+			contentType: ContentTypeForm,
+			status:      http.StatusUnsupportedMediaType,
+			err:         "Unsupported Media Type",
+		},
+		{
+			name:   "wallet_id missing",
+			method: http.MethodPost,
+			req: SignPartialTransactionRequest{
+				PartialTransaction: ptx.SerializeHex(),
+			},
+			status: http.StatusBadRequest,
+			err:    "wallet_id is required",
+		},
+		{
+			name:   "partial_transaction missing",
+			method: http.MethodPost,
+			req: SignPartialTransactionRequest{
+				WalletID: "foo.wlt",
+			},
+			status: http.StatusBadRequest,
+			err:    "partial_transaction is required",
+		},
+		{
+			name:   "partial_transaction invalid",
+			method: http.MethodPost,
+			req: SignPartialTransactionRequest{
+				WalletID:           "foo.wlt",
+				PartialTransaction: hex.EncodeToString(ptx.Transaction.Serialize()),
+			},
+			status: http.StatusBadRequest,
+			err:    wallet.ErrPartialTransactionVersion.Error(),
+		},
+		{
+			name:   "wallet not exist",
+			method: http.MethodPost,
+			req: SignPartialTransactionRequest{
+				WalletID:           "foo.wlt",
+				PartialTransaction: ptx.SerializeHex(),
+			},
+			gatewayErr: wallet.ErrWalletNotExist,
+			status:     http.StatusNotFound,
+			err:        "Not Found",
+		},
+		{
+			name:   "wallet api disabled",
+			method: http.MethodPost,
+			req: SignPartialTransactionRequest{
+				WalletID:           "foo.wlt",
+				PartialTransaction: ptx.SerializeHex(),
+			},
+			gatewayErr: wallet.ErrWalletAPIDisabled,
+			status:     http.StatusForbidden,
+			err:        "Forbidden",
+		},
+		{
+			name:   "no inputs to sign",
+			method: http.MethodPost,
+			req: SignPartialTransactionRequest{
+				WalletID:           "foo.wlt",
+				PartialTransaction: ptx.SerializeHex(),
+			},
+			gatewayErr: wallet.ErrNoInputsToSign,
+			status:     http.StatusBadRequest,
+			err:        wallet.ErrNoInputsToSign.Error(),
+		},
+		{
+			name:   "spending policy violated",
+			method: http.MethodPost,
+			req: SignPartialTransactionRequest{
+				WalletID:           "foo.wlt",
+				Password:           "pwd",
+				PartialTransaction: ptx.SerializeHex(),
+			},
+			password:   []byte("pwd"),
+			gatewayErr: wallet.ErrSpendingPolicyDestination,
+			status:     http.StatusBadRequest,
+			err:        wallet.ErrSpendingPolicyDestination.Error(),
+		},
+		{
+			name:   "internal error",
+			method: http.MethodPost,
+			req: SignPartialTransactionRequest{
+				WalletID:           "foo.wlt",
+				PartialTransaction: ptx.SerializeHex(),
+			},
+			gatewayErr: errors.New("failed"),
+			status:     http.StatusInternalServerError,
+			err:        "failed",
+		},
+		{
+			name:   "partially signed",
+			method: http.MethodPost,
+			req: SignPartialTransactionRequest{
+				WalletID:           "foo.wlt",
+				Password:           "pwd",
+				PartialTransaction: ptx.SerializeHex(),
+			},
+			password:   []byte("pwd"),
+			gatewayRsp: &signed,
+			status:     http.StatusOK,
+			rsp: PartialTransactionResponse{
+				PartialTransaction: signed.SerializeHex(),
+				UnsignedAddresses:  []string{cipher.MustAddressFromSecKey(keys[1]).String()},
+			},
+		},
+		{
+			name:   "fully signed",
+			method: http.MethodPost,
+			req: SignPartialTransactionRequest{
+				WalletID:           "foo.wlt",
+				PartialTransaction: signed.SerializeHex(),
+			},
+			gatewayRsp: &fullySigned,
+			status:     http.StatusOK,
+			rsp: PartialTransactionResponse{
+				PartialTransaction: fullySigned.SerializeHex(),
+				FullySigned:        true,
+				UnsignedAddresses:  []string{},
+				EncodedTransaction: hex.EncodeToString(fullySigned.Transaction.Serialize()),
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			if tc.req.PartialTransaction != "" {
+				reqPtx, err := wallet.PartialTransactionDeserializeHex(tc.req.PartialTransaction)
+				if err == nil {
+					gateway.On("SignPartialTransaction", tc.req.WalletID, tc.password, reqPtx).Return(tc.gatewayRsp, tc.gatewayErr)
+				}
+			}
+
+			contentType := tc.contentType
+			if contentType == "" {
+				contentType = ContentTypeJSON
+			}
+
+			req, err := http.NewRequest(tc.method, "/api/v2/wallet/transaction/sign", strings.NewReader(toJSON(t, tc.req)))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", contentType)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code, "got `%v` want `%v`", rr.Code, tc.status)
+
+			var rsp ReceivedHTTPResponse
+			err = json.NewDecoder(rr.Body).Decode(&rsp)
+			require.NoError(t, err)
+
+			if tc.status != http.StatusOK {
+				require.NotNil(t, rsp.Error)
+				require.Equal(t, tc.err, rsp.Error.Message)
+				return
+			}
+
+			require.Nil(t, rsp.Error)
+			var ptxRsp PartialTransactionResponse
+			err = json.Unmarshal(rsp.Data, &ptxRsp)
+			require.NoError(t, err)
+			require.Equal(t, tc.rsp, ptxRsp)
+		})
+	}
+}
+
+func TestCombinePartialTransactions(t *testing.T) {
+	_, keys := cipher.MustGenerateDeterministicKeyPairsSeed([]byte("seed"), 2)
+	ptx := makeTestPartialTransaction(t, keys)
+	other := makeTestPartialTransaction(t, keys)
+
+	sigs := []cipher.Sig{
+		cipher.MustSignHash(cipher.AddSHA256(ptx.Transaction.InnerHash, ptx.Transaction.In[0]), keys[0]),
+		cipher.MustSignHash(cipher.AddSHA256(ptx.Transaction.InnerHash, ptx.Transaction.In[1]), keys[1]),
+	}
+
+	signed1 := *ptx
+	signed1.Transaction.Sigs = []cipher.Sig{sigs[0], {}}
+	signed2 := *ptx
+	signed2.Transaction.Sigs = []cipher.Sig{{}, sigs[1]}
+	fullySigned := *ptx
+	fullySigned.Transaction.Sigs = sigs
+
+	cases := []struct {
+		name   string
+		req    CombinePartialTransactionsRequest
+		status int
+		err    string
+		rsp    PartialTransactionResponse
+	}{
+		{
+			name:   "partial_transactions missing",
+			status: http.StatusBadRequest,
+			err:    "partial_transactions is required",
+		},
+		{
+			name: "different transactions",
+			req: CombinePartialTransactionsRequest{
+				PartialTransactions: []string{signed1.SerializeHex(), other.SerializeHex()},
+			},
+			status: http.StatusBadRequest,
+			err:    wallet.ErrPartialTransactionMismatch.Error(),
+		},
+		{
+			name: "ok",
+			req: CombinePartialTransactionsRequest{
+				PartialTransactions: []string{signed1.SerializeHex(), ptx.SerializeHex(), signed2.SerializeHex()},
+			},
+			status: http.StatusOK,
+			rsp: PartialTransactionResponse{
+				PartialTransaction: fullySigned.SerializeHex(),
+				FullySigned:        true,
+				UnsignedAddresses:  []string{},
+				EncodedTransaction: hex.EncodeToString(fullySigned.Transaction.Serialize()),
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/api/v2/transaction/partial/combine", strings.NewReader(toJSON(t, tc.req)))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", ContentTypeJSON)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), &MockGatewayer{}, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code, "got `%v` want `%v`", rr.Code, tc.status)
+
+			var rsp ReceivedHTTPResponse
+			err = json.NewDecoder(rr.Body).Decode(&rsp)
+			require.NoError(t, err)
+
+			if tc.status != http.StatusOK {
+				require.NotNil(t, rsp.Error)
+				require.Equal(t, tc.err, rsp.Error.Message)
+				return
+			}
+
+			require.Nil(t, rsp.Error)
+			var ptxRsp PartialTransactionResponse
+			err = json.Unmarshal(rsp.Data, &ptxRsp)
+			require.NoError(t, err)
+			require.Equal(t, tc.rsp, ptxRsp)
+		})
+	}
+}
@@ -0,0 +1,267 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/util/droplet"
+	"github.com/skycoin/skycoin/src/util/fee"
+	wh "github.com/skycoin/skycoin/src/util/http"
+	"github.com/skycoin/skycoin/src/visor/blockdb"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+// PayoutBatchResponse is returned by POST /api/v2/wallet/transaction/batch
+type PayoutBatchResponse struct {
+	Transactions []PayoutBatchTransaction `json:"transactions"`
+	Failures     []PayoutBatchFailure     `json:"failures"`
+	// Payouts is the number of payouts of the batch
+	Payouts int `json:"payouts"`
+	// Paid is the number of payouts paid by the transactions
+	Paid int `json:"paid"`
+	// TotalCoins are the coins paid by the transactions, without the change
+	TotalCoins string `json:"total_coins"`
+	// TotalFee are the coin hours burned by the transactions
+	TotalFee string `json:"total_fee"`
+}
+
+// PayoutBatchTransaction is a transaction of a payout batch
+type PayoutBatchTransaction struct {
+	// Payouts are the indexes of the payouts paid by the transaction, in the order of its outputs
+	Payouts []int `json:"payouts"`
+	CreateTransactionResponse
+}
+
+// PayoutBatchFailure are payouts of a payout batch that could not be paid
+type PayoutBatchFailure struct {
+	Payouts []int  `json:"payouts"`
+	Error   string `json:"error"`
+}
+
+// NewPayoutBatchResponse creates a PayoutBatchResponse from the batch created for the payouts to
+func NewPayoutBatchResponse(batch *wallet.PayoutBatch, to []coin.TransactionOutput) (*PayoutBatchResponse, error) {
+	rsp := &PayoutBatchResponse{
+		Transactions: make([]PayoutBatchTransaction, len(batch.Transactions)),
+		Failures:     make([]PayoutBatchFailure, len(batch.Failures)),
+		Payouts:      len(to),
+	}
+
+	var totalCoins, totalFee uint64
+	for i, bt := range batch.Transactions {
+		txnRsp, err := NewCreateTransactionResponse(bt.Transaction, bt.Inputs)
+		if err != nil {
+			return nil, err
+		}
+
+		rsp.Transactions[i] = PayoutBatchTransaction{
+			Payouts:                   bt.Payouts,
+			CreateTransactionResponse: *txnRsp,
+		}
+
+		for _, j := range bt.Payouts {
+			totalCoins, err = coin.AddUint64(totalCoins, to[j].Coins)
+			if err != nil {
+				return nil, err
+			}
+		}
+		rsp.Paid += len(bt.Payouts)
+
+		txnFee, err := strconv.ParseUint(txnRsp.Transaction.Fee, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		totalFee, err = coin.AddUint64(totalFee, txnFee)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i, f := range batch.Failures {
+		rsp.Failures[i] = PayoutBatchFailure{
+			Payouts: f.Payouts,
+			Error:   f.Err.Error(),
+		}
+	}
+
+	coins, err := droplet.ToString(totalCoins)
+	if err != nil {
+		return nil, err
+	}
+
+	rsp.TotalCoins = coins
+	rsp.TotalFee = fmt.Sprint(totalFee)
+
+	return rsp, nil
+}
+
+// createPayoutBatchRequest is sent to /api/v2/wallet/transaction/batch
+type createPayoutBatchRequest struct {
+	createTransactionRequest
+	// CSV are the payouts, one "address,coins[,hours]" line per payout, instead of To
+	CSV string `json:"csv,omitempty"`
+	// MaxOutputs is the maximum number of payouts per transaction, unlimited if 0
+	MaxOutputs int `json:"max_outputs,omitempty"`
+}
+
+// parseCSV parses the payouts of the CSV into To
+func (r *createPayoutBatchRequest) parseCSV() error {
+	if r.CSV == "" {
+		return nil
+	}
+
+	if len(r.To) != 0 {
+		return errors.New("to and csv cannot be combined")
+	}
+
+	cr := csv.NewReader(strings.NewReader(r.CSV))
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	records, err := cr.ReadAll()
+	if err != nil {
+		return fmt.Errorf("invalid csv: %v", err)
+	}
+
+	r.To = make([]receiver, len(records))
+	for i, fields := range records {
+		if len(fields) != 2 && len(fields) != 3 {
+			return fmt.Errorf("csv row %d: expected address,coins[,hours]", i)
+		}
+
+		addr, err := cipher.DecodeBase58Address(strings.TrimSpace(fields[0]))
+		if err != nil {
+			return fmt.Errorf("csv row %d: invalid address: %v", i, err)
+		}
+
+		coins, err := droplet.FromString(strings.TrimSpace(fields[1]))
+		if err != nil {
+			return fmt.Errorf("csv row %d: invalid coins: %v", i, err)
+		}
+
+		r.To[i] = receiver{
+			Address: wh.Address{
+				Address: addr,
+			},
+			Coins: wh.Coins(coins),
+		}
+
+		if len(fields) == 3 {
+			hours, err := strconv.ParseUint(strings.TrimSpace(fields[2]), 10, 64)
+			if err != nil {
+				return fmt.Errorf("csv row %d: invalid hours: %v", i, err)
+			}
+
+			h := wh.Hours(hours)
+			r.To[i].Hours = &h
+		}
+	}
+
+	return nil
+}
+
+// Validate validates createPayoutBatchRequest data
+func (r createPayoutBatchRequest) Validate() error {
+	if r.Device != "" {
+		return errors.New("device is not supported for payout batches")
+	}
+
+	if r.MaxOutputs < 0 {
+		return errors.New("max_outputs must not be negative")
+	}
+
+	return r.createTransactionRequest.Validate()
+}
+
+// URI: /api/v2/wallet/transaction/batch
+// Method: POST
+// Content-Type: application/json
+// Args: JSON body, the same as POST /api/v1/wallet/transaction, and:
+//  csv: [optional] payouts in CSV format, "address,coins[,hours]" per line, instead of to
+//  max_outputs: [optional] maximum number of payouts per transaction
+// Creates the transactions that pay a batch of payouts, which can be more than fit in a transaction.
+// The payouts are split into transactions within the maximum transaction size, which spend different
+// unspent outputs so that they can all be injected. Returns the transactions and the payouts that
+// could not be funded.
+func createPayoutBatchHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if r.Header.Get("Content-Type") != ContentTypeJSON {
+			resp := NewHTTPErrorResponse(http.StatusUnsupportedMediaType, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		var req createPayoutBatchRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if err := req.parseCSV(); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if err := req.Validate(); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		walletParams := req.ToWalletParams()
+
+		batch, err := gateway.CreatePayoutBatch(walletParams, req.MaxOutputs)
+		if err != nil {
+			var resp HTTPResponse
+			switch err.(type) {
+			case wallet.Error:
+				switch err {
+				case wallet.ErrWalletAPIDisabled:
+					resp = NewHTTPErrorResponse(http.StatusForbidden, "")
+				case wallet.ErrWalletNotExist:
+					resp = NewHTTPErrorResponse(http.StatusNotFound, "")
+				default:
+					resp = NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+				}
+			case blockdb.ErrUnspentNotExist:
+				resp = NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			default:
+				switch err {
+				case fee.ErrTxnNoFee,
+					fee.ErrTxnInsufficientCoinHours,
+					wallet.ErrSpendingUnconfirmed:
+					resp = NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+				default:
+					resp = NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+				}
+			}
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		rsp, err := NewPayoutBatchResponse(batch, walletParams.To)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: rsp,
+		})
+	}
+}
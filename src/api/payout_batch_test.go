@@ -0,0 +1,277 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/util/fee"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+func TestCreatePayoutBatchHandler(t *testing.T) {
+	_, keys := cipher.MustGenerateDeterministicKeyPairsSeed([]byte("seed"), 2)
+	ptx := makeTestPartialTransaction(t, keys)
+	txn := ptx.Transaction
+	paidAddr := txn.Out[0].Address
+	unpaidAddr := testutil.MakeAddress()
+	inputs, err := wallet.NewUxBalances(0, ptx.Inputs)
+	require.NoError(t, err)
+
+	batch := &wallet.PayoutBatch{
+		Transactions: []wallet.PayoutBatchTransaction{
+			{
+				Transaction: &txn,
+				Inputs:      inputs,
+				Payouts:     []int{0},
+			},
+		},
+		Failures: []wallet.PayoutBatchFailure{
+			{
+				Payouts: []int{1},
+				Err:     wallet.ErrInsufficientBalance,
+			},
+		},
+	}
+
+	to := []coin.TransactionOutput{
+		{
+			Address: paidAddr,
+			Coins:   2e6,
+			Hours:   1,
+		},
+		{
+			Address: unpaidAddr,
+			Coins:   1e6,
+			Hours:   2,
+		},
+	}
+
+	txnRsp, err := NewCreateTransactionResponse(&txn, inputs)
+	require.NoError(t, err)
+
+	manual := `"hours_selection":{"type":"manual"},"wallet":{"id":"foo.wlt"}`
+	csv := fmt.Sprintf("%s,2,1\n%s,1,2\n", paidAddr, unpaidAddr)
+	csvJSON, err := json.Marshal(csv)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name        string
+		method      string
+		contentType string
+		body        string
+		status      int
+		err         string
+		maxOutputs  int
+		gatewayTo   []coin.TransactionOutput
+		gatewayRsp  *wallet.PayoutBatch
+		gatewayErr  error
+		rsp         *PayoutBatchResponse
+	}{
+		{
+			name:   "405",
+			method: http.MethodGet,
+			status: http.StatusMethodNotAllowed,
+			err:    "Method Not Allowed",
+		},
+		{
+			name:        "415",
+			method:      http.MethodPost,
+			contentType: ContentTypeForm,
+			status:      http.StatusUnsupportedMediaType,
+			err:         "Unsupported Media Type",
+		},
+		{
+			name:   "invalid csv address",
+			method: http.MethodPost,
+			body:   fmt.Sprintf(`{%s,"csv":"%s,1\nfoo,1"}`, manual, paidAddr),
+			status: http.StatusBadRequest,
+			err:    "csv row 1: invalid address: Invalid address length",
+		},
+		{
+			name:   "invalid csv fields",
+			method: http.MethodPost,
+			body:   fmt.Sprintf(`{%s,"csv":"%s"}`, manual, paidAddr),
+			status: http.StatusBadRequest,
+			err:    "csv row 0: expected address,coins[,hours]",
+		},
+		{
+			name:   "invalid csv coins",
+			method: http.MethodPost,
+			body:   fmt.Sprintf(`{%s,"csv":"%s,foo"}`, manual, paidAddr),
+			status: http.StatusBadRequest,
+			err:    "csv row 0: invalid coins: can't convert foo to decimal",
+		},
+		{
+			name:   "to and csv combined",
+			method: http.MethodPost,
+			body:   fmt.Sprintf(`{%s,"to":[{"address":"%s","coins":"1","hours":"1"}],"csv":%s}`, manual, paidAddr, csvJSON),
+			status: http.StatusBadRequest,
+			err:    "to and csv cannot be combined",
+		},
+		{
+			name:   "missing hours",
+			method: http.MethodPost,
+			body:   fmt.Sprintf(`{%s,"csv":"%s,1"}`, manual, paidAddr),
+			status: http.StatusBadRequest,
+			err:    "to[0].hours must be specified for manual hours_selection.mode",
+		},
+		{
+			name:   "device",
+			method: http.MethodPost,
+			body:   fmt.Sprintf(`{%s,"csv":%s,"device":"foo"}`, manual, csvJSON),
+			status: http.StatusBadRequest,
+			err:    "device is not supported for payout batches",
+		},
+		{
+			name:   "negative max_outputs",
+			method: http.MethodPost,
+			body:   fmt.Sprintf(`{%s,"csv":%s,"max_outputs":-1}`, manual, csvJSON),
+			status: http.StatusBadRequest,
+			err:    "max_outputs must not be negative",
+		},
+		{
+			name:       "wallet not exist",
+			method:     http.MethodPost,
+			body:       fmt.Sprintf(`{%s,"csv":%s}`, manual, csvJSON),
+			gatewayTo:  to,
+			gatewayErr: wallet.ErrWalletNotExist,
+			status:     http.StatusNotFound,
+			err:        "Not Found",
+		},
+		{
+			name:       "wallet api disabled",
+			method:     http.MethodPost,
+			body:       fmt.Sprintf(`{%s,"csv":%s}`, manual, csvJSON),
+			gatewayTo:  to,
+			gatewayErr: wallet.ErrWalletAPIDisabled,
+			status:     http.StatusForbidden,
+			err:        "Forbidden",
+		},
+		{
+			name:       "insufficient coin hours",
+			method:     http.MethodPost,
+			body:       fmt.Sprintf(`{%s,"csv":%s}`, manual, csvJSON),
+			gatewayTo:  to,
+			gatewayErr: fee.ErrTxnInsufficientCoinHours,
+			status:     http.StatusBadRequest,
+			err:        fee.ErrTxnInsufficientCoinHours.Error(),
+		},
+		{
+			name:       "internal error",
+			method:     http.MethodPost,
+			body:       fmt.Sprintf(`{%s,"csv":%s}`, manual, csvJSON),
+			gatewayTo:  to,
+			gatewayErr: errors.New("failed"),
+			status:     http.StatusInternalServerError,
+			err:        "failed",
+		},
+		{
+			name:       "csv",
+			method:     http.MethodPost,
+			body:       fmt.Sprintf(`{%s,"csv":%s,"max_outputs":1}`, manual, csvJSON),
+			maxOutputs: 1,
+			gatewayTo:  to,
+			gatewayRsp: batch,
+			status:     http.StatusOK,
+			rsp: &PayoutBatchResponse{
+				Transactions: []PayoutBatchTransaction{
+					{
+						Payouts:                   []int{0},
+						CreateTransactionResponse: *txnRsp,
+					},
+				},
+				Failures: []PayoutBatchFailure{
+					{
+						Payouts: []int{1},
+						Error:   wallet.ErrInsufficientBalance.Error(),
+					},
+				},
+				Payouts:    2,
+				Paid:       1,
+				TotalCoins: "2.000000",
+				TotalFee:   "19",
+			},
+		},
+		{
+			name:   "to",
+			method: http.MethodPost,
+			body: fmt.Sprintf(`{%s,"to":[{"address":"%s","coins":"2","hours":"1"},{"address":"%s","coins":"1","hours":"2"}]}`,
+				manual, paidAddr, unpaidAddr),
+			gatewayTo:  to,
+			gatewayRsp: batch,
+			status:     http.StatusOK,
+			rsp: &PayoutBatchResponse{
+				Transactions: []PayoutBatchTransaction{
+					{
+						Payouts:                   []int{0},
+						CreateTransactionResponse: *txnRsp,
+					},
+				},
+				Failures: []PayoutBatchFailure{
+					{
+						Payouts: []int{1},
+						Error:   wallet.ErrInsufficientBalance.Error(),
+					},
+				},
+				Payouts:    2,
+				Paid:       1,
+				TotalCoins: "2.000000",
+				TotalFee:   "19",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			if tc.gatewayTo != nil {
+				gateway.On("CreatePayoutBatch", mock.MatchedBy(func(p wallet.CreateTransactionParams) bool {
+					return reflect.DeepEqual(tc.gatewayTo, p.To)
+				}), tc.maxOutputs).Return(tc.gatewayRsp, tc.gatewayErr)
+			}
+
+			contentType := tc.contentType
+			if contentType == "" {
+				contentType = ContentTypeJSON
+			}
+
+			req, err := http.NewRequest(tc.method, "/api/v2/wallet/transaction/batch", strings.NewReader(tc.body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", contentType)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code, "got `%v` want `%v`", rr.Code, tc.status)
+
+			var rsp ReceivedHTTPResponse
+			err = json.NewDecoder(rr.Body).Decode(&rsp)
+			require.NoError(t, err)
+
+			if tc.status != http.StatusOK {
+				require.NotNil(t, rsp.Error)
+				require.Equal(t, tc.err, rsp.Error.Message)
+				return
+			}
+
+			require.Nil(t, rsp.Error)
+			var batchRsp PayoutBatchResponse
+			err = json.Unmarshal(rsp.Data, &batchRsp)
+			require.NoError(t, err)
+			require.Equal(t, *tc.rsp, batchRsp)
+		})
+	}
+}
@@ -24,6 +24,9 @@ import (
 type CreateTransactionResponse struct {
 	Transaction        CreatedTransaction `json:"transaction"`
 	EncodedTransaction string             `json:"encoded_transaction"`
+	// PartialTransaction is the hex-encoded partial transaction of an unsigned transaction,
+	// which can be signed by POST /api/v2/wallet/transaction/sign
+	PartialTransaction string `json:"partial_transaction,omitempty"`
 }
 
 // NewCreateTransactionResponse creates a CreateTransactionResponse
@@ -33,10 +36,20 @@ func NewCreateTransactionResponse(txn *coin.Transaction, inputs []wallet.UxBalan
 		return nil, err
 	}
 
-	return &CreateTransactionResponse{
+	rsp := &CreateTransactionResponse{
 		Transaction:        *cTxn,
 		EncodedTransaction: hex.EncodeToString(txn.Serialize()),
-	}, nil
+	}
+
+	if !txn.IsFullySigned() {
+		ptx, err := wallet.NewPartialTransaction(*txn, inputs)
+		if err != nil {
+			return nil, err
+		}
+		rsp.PartialTransaction = ptx.SerializeHex()
+	}
+
+	return rsp, nil
 }
 
 // CreatedTransaction represents a transaction created by /wallet/transaction
@@ -259,6 +272,10 @@ type createTransactionRequest struct {
 	Wallet            createTransactionRequestWallet `json:"wallet"`
 	ChangeAddress     *wh.Address                    `json:"change_address,omitempty"`
 	To                []receiver                     `json:"to"`
+	Unsigned          bool                           `json:"unsigned"`
+	Device            string                         `json:"device,omitempty"`
+	CoinSelection     string                         `json:"coin_selection,omitempty"`
+	ChangePolicy      string                         `json:"change_policy,omitempty"`
 }
 
 // createTransactionRequestWallet defines a wallet to spend from and optionally which addresses in the wallet
@@ -267,6 +284,7 @@ type createTransactionRequestWallet struct {
 	UxOuts    []wh.SHA256  `json:"unspents,omitempty"`
 	Addresses []wh.Address `json:"addresses,omitempty"`
 	Password  string       `json:"password"`
+	Account   string       `json:"account,omitempty"`
 }
 
 // hoursSelection defines options for hours distribution
@@ -343,6 +361,45 @@ func (r createTransactionRequest) Validate() error {
 		return errors.New("missing wallet.id")
 	}
 
+	switch r.CoinSelection {
+	case "",
+		wallet.CoinSelectionMinimizeUxOuts,
+		wallet.CoinSelectionMinimizeBurn,
+		wallet.CoinSelectionOldestFirst,
+		wallet.CoinSelectionRandom,
+		wallet.CoinSelectionExact:
+	default:
+		return errors.New("invalid coin_selection")
+	}
+
+	switch r.ChangePolicy {
+	case "":
+	case wallet.ChangePolicyFixed:
+		if r.ChangeAddress == nil {
+			return errors.New("change_address is required for the fixed change_policy")
+		}
+	case wallet.ChangePolicyNewAddress, wallet.ChangePolicyLargestInput:
+		if r.ChangeAddress != nil {
+			return errors.New("change_address can only be used with the fixed change_policy")
+		}
+	default:
+		return errors.New("invalid change_policy")
+	}
+
+	if r.Unsigned && r.Wallet.Password != "" {
+		return errors.New("wallet.password must not be provided for unsigned transactions")
+	}
+
+	if r.Device != "" {
+		if r.Unsigned {
+			return errors.New("device must not be provided for unsigned transactions")
+		}
+
+		if r.Wallet.Password != "" {
+			return errors.New("wallet.password must not be provided for transactions signed by a device")
+		}
+	}
+
 	addressMap := make(map[cipher.Address]struct{}, len(r.Wallet.Addresses))
 	for i, a := range r.Wallet.Addresses {
 		if a.Null() {
@@ -431,6 +488,7 @@ func (r createTransactionRequest) ToWalletParams() wallet.CreateTransactionParam
 		Addresses: addresses,
 		UxOuts:    uxouts,
 		Password:  []byte(r.Wallet.Password),
+		Account:   r.Wallet.Account,
 	}
 
 	to := make([]coin.TransactionOutput, len(r.To))
@@ -462,10 +520,14 @@ func (r createTransactionRequest) ToWalletParams() wallet.CreateTransactionParam
 		Wallet:        walletParams,
 		ChangeAddress: changeAddress,
 		To:            to,
+		Unsigned:      r.Unsigned,
+		CoinSelection: r.CoinSelection,
+		ChangePolicy:  r.ChangePolicy,
 	}
 }
 
-// createTransactionHandler creates a signed transaction
+// createTransactionHandler creates a transaction, signed unless the request is unsigned.
+// If the request has a device, the transaction is signed by that registered hardware device
 // Method: POST
 // URI: /api/v1/wallet/transaction
 // Args: JSON body
@@ -495,7 +557,25 @@ func createTransactionHandler(gateway Gatewayer) http.HandlerFunc {
 			return
 		}
 
-		txn, inputs, err := gateway.CreateTransaction(params.ToWalletParams())
+		walletParams := params.ToWalletParams()
+
+		if params.Device != "" {
+			device, err := wallet.OpenHardwareDevice(params.Device)
+			if err != nil {
+				switch err {
+				case wallet.ErrHardwareDeviceNotRegistered:
+					wh.Error400(w, err.Error())
+				default:
+					wh.Error500(w, err.Error())
+				}
+				return
+			}
+			defer device.Close()
+
+			walletParams.Signer = wallet.NewHardwareSigner(device, wallet.DefaultHardwareSignerScanN)
+		}
+
+		txn, inputs, err := gateway.CreateTransaction(walletParams)
 		if err != nil {
 			switch err.(type) {
 			case wallet.Error:
@@ -11,6 +11,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	"github.com/skycoin/skycoin/src/cipher"
@@ -47,6 +48,10 @@ func TestCreateTransaction(t *testing.T) {
 		ChangeAddress  string            `json:"change_address,omitempty"`
 		To             []rawReceiver     `json:"to"`
 		Password       string            `json:"password"`
+		Unsigned       bool              `json:"unsigned,omitempty"`
+		Device         string            `json:"device,omitempty"`
+		CoinSelection  string            `json:"coin_selection,omitempty"`
+		ChangePolicy   string            `json:"change_policy,omitempty"`
 	}
 
 	changeAddress := testutil.MakeAddress()
@@ -88,6 +93,42 @@ func TestCreateTransaction(t *testing.T) {
 		EncodedTransaction: hex.EncodeToString(txn.Serialize()),
 	}
 
+	// An unsigned transaction is returned with its partial transaction
+	unsignedUx := coin.UxOut{
+		Head: coin.UxHead{
+			Time:  uint64(time.Now().UTC().Unix()),
+			BkSeq: 9999,
+		},
+		Body: coin.UxBody{
+			SrcTransaction: testutil.RandSHA256(t),
+			Address:        testutil.MakeAddress(),
+			Coins:          1e6,
+			Hours:          100,
+		},
+	}
+	unsignedInputs, err := wallet.NewUxBalances(unsignedUx.Head.Time, coin.UxArray{unsignedUx})
+	require.NoError(t, err)
+
+	unsignedTxn := &coin.Transaction{
+		In:   []cipher.SHA256{unsignedUx.Hash()},
+		Out:  txn.Out,
+		Sigs: []cipher.Sig{{}},
+	}
+	err = unsignedTxn.UpdateHeader()
+	require.NoError(t, err)
+
+	unsignedPtx, err := wallet.NewPartialTransaction(*unsignedTxn, unsignedInputs)
+	require.NoError(t, err)
+
+	createdUnsignedTxn, err := NewCreatedTransaction(unsignedTxn, unsignedInputs)
+	require.NoError(t, err)
+
+	createUnsignedTxnResponse := &CreateTransactionResponse{
+		Transaction:        *createdUnsignedTxn,
+		EncodedTransaction: hex.EncodeToString(unsignedTxn.Serialize()),
+		PartialTransaction: unsignedPtx.SerializeHex(),
+	}
+
 	validBody := &rawRequest{
 		HoursSelection: rawHoursSelection{
 			Type: wallet.HoursSelectionTypeManual,
@@ -375,6 +416,199 @@ func TestCreateTransaction(t *testing.T) {
 			err:    "400 Bad Request - missing wallet.id",
 		},
 
+		{
+			name:   "400 - invalid coin selection",
+			method: http.MethodPost,
+			body: &rawRequest{
+				HoursSelection: rawHoursSelection{
+					Type: wallet.HoursSelectionTypeManual,
+				},
+				To: []rawReceiver{
+					{
+						Address: destinationAddress.String(),
+						Coins:   "1.01",
+						Hours:   "100",
+					},
+				},
+				ChangeAddress: changeAddress.String(),
+				Wallet: rawRequestWallet{
+					ID: "foo.wlt",
+				},
+				CoinSelection: "foo",
+			},
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - invalid coin_selection",
+		},
+
+		{
+			name:   "400 - invalid change policy",
+			method: http.MethodPost,
+			body: &rawRequest{
+				HoursSelection: rawHoursSelection{
+					Type: wallet.HoursSelectionTypeManual,
+				},
+				To: []rawReceiver{
+					{
+						Address: destinationAddress.String(),
+						Coins:   "1.01",
+						Hours:   "100",
+					},
+				},
+				Wallet: rawRequestWallet{
+					ID: "foo.wlt",
+				},
+				ChangePolicy: "foo",
+			},
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - invalid change_policy",
+		},
+
+		{
+			name:   "400 - fixed change policy without change address",
+			method: http.MethodPost,
+			body: &rawRequest{
+				HoursSelection: rawHoursSelection{
+					Type: wallet.HoursSelectionTypeManual,
+				},
+				To: []rawReceiver{
+					{
+						Address: destinationAddress.String(),
+						Coins:   "1.01",
+						Hours:   "100",
+					},
+				},
+				Wallet: rawRequestWallet{
+					ID: "foo.wlt",
+				},
+				ChangePolicy: wallet.ChangePolicyFixed,
+			},
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - change_address is required for the fixed change_policy",
+		},
+
+		{
+			name:   "400 - change address with new address change policy",
+			method: http.MethodPost,
+			body: &rawRequest{
+				HoursSelection: rawHoursSelection{
+					Type: wallet.HoursSelectionTypeManual,
+				},
+				To: []rawReceiver{
+					{
+						Address: destinationAddress.String(),
+						Coins:   "1.01",
+						Hours:   "100",
+					},
+				},
+				ChangeAddress: changeAddress.String(),
+				Wallet: rawRequestWallet{
+					ID: "foo.wlt",
+				},
+				ChangePolicy: wallet.ChangePolicyNewAddress,
+			},
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - change_address can only be used with the fixed change_policy",
+		},
+
+		{
+			name:   "400 - password for unsigned transaction",
+			method: http.MethodPost,
+			body: &rawRequest{
+				HoursSelection: rawHoursSelection{
+					Type: wallet.HoursSelectionTypeManual,
+				},
+				To: []rawReceiver{
+					{
+						Address: destinationAddress.String(),
+						Coins:   "1.01",
+						Hours:   "100",
+					},
+				},
+				ChangeAddress: changeAddress.String(),
+				Wallet: rawRequestWallet{
+					ID:       "foo.wlt",
+					Password: "pwd",
+				},
+				Unsigned: true,
+			},
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - wallet.password must not be provided for unsigned transactions",
+		},
+
+		{
+			name:   "400 - device for unsigned transaction",
+			method: http.MethodPost,
+			body: &rawRequest{
+				HoursSelection: rawHoursSelection{
+					Type: wallet.HoursSelectionTypeManual,
+				},
+				To: []rawReceiver{
+					{
+						Address: destinationAddress.String(),
+						Coins:   "1.01",
+						Hours:   "100",
+					},
+				},
+				ChangeAddress: changeAddress.String(),
+				Wallet: rawRequestWallet{
+					ID: "foo.wlt",
+				},
+				Unsigned: true,
+				Device:   "foo",
+			},
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - device must not be provided for unsigned transactions",
+		},
+
+		{
+			name:   "400 - password for device transaction",
+			method: http.MethodPost,
+			body: &rawRequest{
+				HoursSelection: rawHoursSelection{
+					Type: wallet.HoursSelectionTypeManual,
+				},
+				To: []rawReceiver{
+					{
+						Address: destinationAddress.String(),
+						Coins:   "1.01",
+						Hours:   "100",
+					},
+				},
+				ChangeAddress: changeAddress.String(),
+				Wallet: rawRequestWallet{
+					ID:       "foo.wlt",
+					Password: "pwd",
+				},
+				Device: "foo",
+			},
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - wallet.password must not be provided for transactions signed by a device",
+		},
+
+		{
+			name:   "400 - device not registered",
+			method: http.MethodPost,
+			body: &rawRequest{
+				HoursSelection: rawHoursSelection{
+					Type: wallet.HoursSelectionTypeManual,
+				},
+				To: []rawReceiver{
+					{
+						Address: destinationAddress.String(),
+						Coins:   "1.01",
+						Hours:   "100",
+					},
+				},
+				ChangeAddress: changeAddress.String(),
+				Wallet: rawRequestWallet{
+					ID: "foo.wlt",
+				},
+				Device: "foo",
+			},
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - hardware device is not registered",
+		},
+
 		{
 			name:   "400 - wallet address is empty",
 			method: http.MethodPost,
@@ -714,6 +948,83 @@ func TestCreateTransaction(t *testing.T) {
 			createTransactionResponse:      createTxnResponse,
 		},
 
+		{
+			name:   "200 - unsigned",
+			method: http.MethodPost,
+			body: &rawRequest{
+				HoursSelection: rawHoursSelection{
+					Type: wallet.HoursSelectionTypeManual,
+				},
+				To: []rawReceiver{
+					{
+						Address: destinationAddress.String(),
+						Coins:   "100",
+						Hours:   "0",
+					},
+				},
+				ChangeAddress: changeAddress.String(),
+				Wallet: rawRequestWallet{
+					ID: "foo.wlt",
+				},
+				Unsigned: true,
+			},
+			status:                         http.StatusOK,
+			gatewayCreateTransactionResult: unsignedTxn,
+			gatewayCreateTransactionInputs: unsignedInputs,
+			createTransactionResponse:      createUnsignedTxnResponse,
+		},
+
+		{
+			name:   "200 - coin selection",
+			method: http.MethodPost,
+			body: &rawRequest{
+				HoursSelection: rawHoursSelection{
+					Type: wallet.HoursSelectionTypeManual,
+				},
+				To: []rawReceiver{
+					{
+						Address: destinationAddress.String(),
+						Coins:   "100",
+						Hours:   "0",
+					},
+				},
+				ChangeAddress: changeAddress.String(),
+				Wallet: rawRequestWallet{
+					ID: "foo.wlt",
+				},
+				CoinSelection: wallet.CoinSelectionOldestFirst,
+			},
+			status:                         http.StatusOK,
+			gatewayCreateTransactionResult: txn,
+			gatewayCreateTransactionInputs: inputs,
+			createTransactionResponse:      createTxnResponse,
+		},
+
+		{
+			name:   "200 - new address change policy",
+			method: http.MethodPost,
+			body: &rawRequest{
+				HoursSelection: rawHoursSelection{
+					Type: wallet.HoursSelectionTypeManual,
+				},
+				To: []rawReceiver{
+					{
+						Address: destinationAddress.String(),
+						Coins:   "100",
+						Hours:   "0",
+					},
+				},
+				Wallet: rawRequestWallet{
+					ID: "foo.wlt",
+				},
+				ChangePolicy: wallet.ChangePolicyNewAddress,
+			},
+			status:                         http.StatusOK,
+			gatewayCreateTransactionResult: txn,
+			gatewayCreateTransactionInputs: inputs,
+			createTransactionResponse:      createTxnResponse,
+		},
+
 		{
 			name:   "200 - manual type zero hours",
 			method: http.MethodPost,
@@ -901,3 +1212,67 @@ func TestCreateTransaction(t *testing.T) {
 func newStrPtr(s string) *string {
 	return &s
 }
+
+// hardwareDevice is a wallet.HardwareDevice that records whether it was closed
+type hardwareDevice struct {
+	closed bool
+}
+
+func (d *hardwareDevice) Address(n uint32, confirm bool) (cipher.Address, error) {
+	return cipher.Address{}, errors.New("not implemented")
+}
+
+func (d *hardwareDevice) SignHash(n uint32, hash cipher.SHA256) (cipher.Sig, error) {
+	return cipher.Sig{}, errors.New("not implemented")
+}
+
+func (d *hardwareDevice) Close() error {
+	d.closed = true
+	return nil
+}
+
+func TestCreateTransactionDevice(t *testing.T) {
+	device := &hardwareDevice{}
+	wallet.RegisterHardwareDevice("api-test-device", func() (wallet.HardwareDevice, error) {
+		return device, nil
+	})
+
+	body := CreateTransactionRequest{
+		HoursSelection: HoursSelection{
+			Type: wallet.HoursSelectionTypeManual,
+		},
+		Wallet: CreateTransactionRequestWallet{
+			ID: "foo.wlt",
+		},
+		To: []Receiver{
+			{
+				Address: testutil.MakeAddress().String(),
+				Coins:   "1",
+				Hours:   "10",
+			},
+		},
+		Device: "api-test-device",
+	}
+
+	// The transaction is signed by the device, instead of the keys of the wallet
+	gateway := &MockGatewayer{}
+	gateway.On("CreateTransaction", mock.MatchedBy(func(p wallet.CreateTransactionParams) bool {
+		_, ok := p.Signer.(*wallet.HardwareSigner)
+		return ok && !p.Unsigned && p.Wallet.ID == "foo.wlt"
+	})).Return(nil, nil, wallet.ErrSignerNotFullySigned)
+
+	req, err := http.NewRequest(http.MethodPost, "/api/v1/wallet/transaction", strings.NewReader(toJSON(t, body)))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", ContentTypeJSON)
+
+	rr := httptest.NewRecorder()
+	handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+	handler.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.Equal(t, "400 Bad Request - signer did not sign all the inputs of the transaction", strings.TrimSpace(rr.Body.String()))
+	gateway.AssertExpectations(t)
+
+	// The device is closed once the transaction is created
+	require.True(t, device.closed)
+}
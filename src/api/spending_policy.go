@@ -0,0 +1,211 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/util/droplet"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+// SpendingPolicyResponse is the response data for GET /api/v2/wallet/spending-policy and POST /api/v2/wallet/spending-policy/set
+type SpendingPolicyResponse struct {
+	MaxTransactionCoins string   `json:"max_transaction_coins"`
+	DailyLimitCoins     string   `json:"daily_limit_coins"`
+	AllowedDestinations []string `json:"allowed_destinations"`
+	DailySpentCoins     string   `json:"daily_spent_coins"`
+}
+
+// NewSpendingPolicyResponse creates a SpendingPolicyResponse from the spending policy of a wallet
+// and the coins it sent within the daily period before now
+func NewSpendingPolicyResponse(w *wallet.Wallet, now time.Time) (*SpendingPolicyResponse, error) {
+	p := w.SpendingPolicy
+
+	maxTransactionCoins, err := droplet.ToString(p.MaxTransactionCoins)
+	if err != nil {
+		return nil, err
+	}
+
+	dailyLimitCoins, err := droplet.ToString(p.DailyLimitCoins)
+	if err != nil {
+		return nil, err
+	}
+
+	dailySpent, err := w.DailySpent(now)
+	if err != nil {
+		return nil, err
+	}
+
+	dailySpentCoins, err := droplet.ToString(dailySpent)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := make([]string, len(p.AllowedDestinations))
+	for i, a := range p.AllowedDestinations {
+		allowed[i] = a.String()
+	}
+
+	return &SpendingPolicyResponse{
+		MaxTransactionCoins: maxTransactionCoins,
+		DailyLimitCoins:     dailyLimitCoins,
+		AllowedDestinations: allowed,
+		DailySpentCoins:     dailySpentCoins,
+	}, nil
+}
+
+// SetSpendingPolicyRequest is the request data for POST /api/v2/wallet/spending-policy/set
+type SetSpendingPolicyRequest struct {
+	ID                  string   `json:"id"`
+	Password            string   `json:"password"`
+	MaxTransactionCoins string   `json:"max_transaction_coins"`
+	DailyLimitCoins     string   `json:"daily_limit_coins"`
+	AllowedDestinations []string `json:"allowed_destinations"`
+}
+
+// ToSpendingPolicy parses the request to a wallet.SpendingPolicy
+func (r SetSpendingPolicyRequest) ToSpendingPolicy() (wallet.SpendingPolicy, error) {
+	var p wallet.SpendingPolicy
+
+	parseCoins := func(name, s string) (uint64, error) {
+		if s == "" {
+			return 0, nil
+		}
+
+		coins, err := droplet.FromString(s)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s: %v", name, err)
+		}
+		return coins, nil
+	}
+
+	var err error
+	p.MaxTransactionCoins, err = parseCoins("max_transaction_coins", r.MaxTransactionCoins)
+	if err != nil {
+		return wallet.SpendingPolicy{}, err
+	}
+
+	p.DailyLimitCoins, err = parseCoins("daily_limit_coins", r.DailyLimitCoins)
+	if err != nil {
+		return wallet.SpendingPolicy{}, err
+	}
+
+	for _, s := range r.AllowedDestinations {
+		addr, err := cipher.DecodeBase58Address(s)
+		if err != nil {
+			return wallet.SpendingPolicy{}, fmt.Errorf("invalid allowed destination %q: %v", s, err)
+		}
+		p.AllowedDestinations = append(p.AllowedDestinations, addr)
+	}
+
+	return p, nil
+}
+
+// URI: /api/v2/wallet/spending-policy
+// Method: GET
+// Args:
+//  id: wallet id
+// Returns the spending policy of a wallet and the coins it sent in the last 24 hours
+func walletSpendingPolicyHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		wltID := r.FormValue("id")
+		if wltID == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "id is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		wlt, err := gateway.GetWallet(wltID)
+		writeSpendingPolicyResponse(w, wlt, err)
+	}
+}
+
+// URI: /api/v2/wallet/spending-policy/set
+// Method: POST
+// Args:
+//  id: wallet id
+//  password: [optional] wallet password, required for encrypted wallets
+//  max_transaction_coins: [optional] maximum coins sent to other addresses by a transaction
+//  daily_limit_coins: [optional] maximum coins sent to other addresses in 24 hours
+//  allowed_destinations: [optional] the only addresses the wallet can send coins to
+// Sets the spending policy of a wallet, replacing the previous one. A policy without limits removes it.
+// Returns the spending policy of the wallet.
+func walletSetSpendingPolicyHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if r.Header.Get("Content-Type") != ContentTypeJSON {
+			resp := NewHTTPErrorResponse(http.StatusUnsupportedMediaType, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		var req SetSpendingPolicyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if req.ID == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "id is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		p, err := req.ToSpendingPolicy()
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		wlt, err := gateway.SetSpendingPolicy(req.ID, []byte(req.Password), p)
+		writeSpendingPolicyResponse(w, wlt, err)
+	}
+}
+
+func writeSpendingPolicyResponse(w http.ResponseWriter, wlt *wallet.Wallet, err error) {
+	if err != nil {
+		var resp HTTPResponse
+		switch err {
+		case wallet.ErrWalletNotExist:
+			resp = NewHTTPErrorResponse(http.StatusNotFound, "")
+		case wallet.ErrWalletAPIDisabled:
+			resp = NewHTTPErrorResponse(http.StatusForbidden, "")
+		default:
+			switch err.(type) {
+			case wallet.Error:
+				resp = NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			default:
+				resp = NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			}
+		}
+		writeHTTPResponse(w, resp)
+		return
+	}
+
+	rsp, err := NewSpendingPolicyResponse(wlt, time.Now())
+	if err != nil {
+		resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+		writeHTTPResponse(w, resp)
+		return
+	}
+
+	writeHTTPResponse(w, HTTPResponse{
+		Data: rsp,
+	})
+}
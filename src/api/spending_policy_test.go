@@ -0,0 +1,289 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+func makeSpendingPolicyWallet(t *testing.T, p wallet.SpendingPolicy) *wallet.Wallet {
+	w, err := wallet.NewWallet("foo.wlt", wallet.Options{
+		Seed:      "seed",
+		Label:     "label",
+		GenerateN: 1,
+	})
+	require.NoError(t, err)
+	require.NoError(t, w.SetSpendingPolicy(p))
+	return w
+}
+
+func TestWalletSpendingPolicyHandler(t *testing.T) {
+	dest := testutil.MakeAddress()
+
+	cases := []struct {
+		name       string
+		method     string
+		id         string
+		status     int
+		err        string
+		gatewayRsp *wallet.Wallet
+		gatewayErr error
+		rsp        SpendingPolicyResponse
+	}{
+		{
+			name:   "405",
+			method: http.MethodPost,
+			status: http.StatusMethodNotAllowed,
+			err:    "Method Not Allowed",
+		},
+		{
+			name:   "id missing",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "id is required",
+		},
+		{
+			name:       "wallet not exist",
+			method:     http.MethodGet,
+			id:         "foo.wlt",
+			gatewayErr: wallet.ErrWalletNotExist,
+			status:     http.StatusNotFound,
+			err:        "Not Found",
+		},
+		{
+			name:       "wallet api disabled",
+			method:     http.MethodGet,
+			id:         "foo.wlt",
+			gatewayErr: wallet.ErrWalletAPIDisabled,
+			status:     http.StatusForbidden,
+			err:        "Forbidden",
+		},
+		{
+			name:       "no policy",
+			method:     http.MethodGet,
+			id:         "foo.wlt",
+			gatewayRsp: makeSpendingPolicyWallet(t, wallet.SpendingPolicy{}),
+			status:     http.StatusOK,
+			rsp: SpendingPolicyResponse{
+				MaxTransactionCoins: "0.000000",
+				DailyLimitCoins:     "0.000000",
+				AllowedDestinations: []string{},
+				DailySpentCoins:     "0.000000",
+			},
+		},
+		{
+			name:   "policy",
+			method: http.MethodGet,
+			id:     "foo.wlt",
+			gatewayRsp: makeSpendingPolicyWallet(t, wallet.SpendingPolicy{
+				MaxTransactionCoins: 1e6,
+				DailyLimitCoins:     10e6,
+				AllowedDestinations: []cipher.Address{dest},
+			}),
+			status: http.StatusOK,
+			rsp: SpendingPolicyResponse{
+				MaxTransactionCoins: "1.000000",
+				DailyLimitCoins:     "10.000000",
+				AllowedDestinations: []string{dest.String()},
+				DailySpentCoins:     "0.000000",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("GetWallet", tc.id).Return(tc.gatewayRsp, tc.gatewayErr)
+
+			endpoint := "/api/v2/wallet/spending-policy"
+			if tc.id != "" {
+				endpoint += "?" + url.Values{"id": []string{tc.id}}.Encode()
+			}
+
+			req, err := http.NewRequest(tc.method, endpoint, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code, "got `%v` want `%v`", rr.Code, tc.status)
+
+			var rsp ReceivedHTTPResponse
+			err = json.NewDecoder(rr.Body).Decode(&rsp)
+			require.NoError(t, err)
+
+			if tc.status != http.StatusOK {
+				require.NotNil(t, rsp.Error)
+				require.Equal(t, tc.err, rsp.Error.Message)
+				return
+			}
+
+			require.Nil(t, rsp.Error)
+			var policy SpendingPolicyResponse
+			err = json.Unmarshal(rsp.Data, &policy)
+			require.NoError(t, err)
+			require.Equal(t, tc.rsp, policy)
+		})
+	}
+}
+
+func TestWalletSetSpendingPolicyHandler(t *testing.T) {
+	dest := testutil.MakeAddress()
+	policy := wallet.SpendingPolicy{
+		MaxTransactionCoins: 1e6,
+		DailyLimitCoins:     10e6,
+		AllowedDestinations: []cipher.Address{dest},
+	}
+
+	cases := []struct {
+		name          string
+		method        string
+		contentType   string
+		req           SetSpendingPolicyRequest
+		status        int
+		err           string
+		gatewayPolicy wallet.SpendingPolicy
+		gatewayRsp    *wallet.Wallet
+		gatewayErr    error
+		rsp           SpendingPolicyResponse
+	}{
+		{
+			name:   "405",
+			method: http.MethodGet,
+			status: http.StatusMethodNotAllowed,
+			err:    "Method Not Allowed",
+		},
+		{
+			name:        "415",
+			method:      http.MethodPost,
+			contentType: ContentTypeForm,
+			status:      http.StatusUnsupportedMediaType,
+			err:         "Unsupported Media Type",
+		},
+		{
+			name:   "id missing",
+			method: http.MethodPost,
+			status: http.StatusBadRequest,
+			err:    "id is required",
+		},
+		{
+			name:   "invalid max_transaction_coins",
+			method: http.MethodPost,
+			req: SetSpendingPolicyRequest{
+				ID:                  "foo.wlt",
+				MaxTransactionCoins: "x",
+			},
+			status: http.StatusBadRequest,
+			err:    "invalid max_transaction_coins: can't convert x to decimal",
+		},
+		{
+			name:   "invalid allowed destination",
+			method: http.MethodPost,
+			req: SetSpendingPolicyRequest{
+				ID:                  "foo.wlt",
+				AllowedDestinations: []string{"x"},
+			},
+			status: http.StatusBadRequest,
+			err:    `invalid allowed destination "x": Invalid address length`,
+		},
+		{
+			name:   "invalid password",
+			method: http.MethodPost,
+			req: SetSpendingPolicyRequest{
+				ID:       "foo.wlt",
+				Password: "wrong",
+			},
+			gatewayErr: wallet.ErrInvalidPassword,
+			status:     http.StatusBadRequest,
+			err:        "invalid password",
+		},
+		{
+			name:   "wallet not exist",
+			method: http.MethodPost,
+			req: SetSpendingPolicyRequest{
+				ID: "foo.wlt",
+			},
+			gatewayErr: wallet.ErrWalletNotExist,
+			status:     http.StatusNotFound,
+			err:        "Not Found",
+		},
+		{
+			name:   "wallet api disabled",
+			method: http.MethodPost,
+			req: SetSpendingPolicyRequest{
+				ID: "foo.wlt",
+			},
+			gatewayErr: wallet.ErrWalletAPIDisabled,
+			status:     http.StatusForbidden,
+			err:        "Forbidden",
+		},
+		{
+			name:   "set policy",
+			method: http.MethodPost,
+			req: SetSpendingPolicyRequest{
+				ID:                  "foo.wlt",
+				Password:            "pwd",
+				MaxTransactionCoins: "1",
+				DailyLimitCoins:     "10",
+				AllowedDestinations: []string{dest.String()},
+			},
+			gatewayPolicy: policy,
+			gatewayRsp:    makeSpendingPolicyWallet(t, policy),
+			status:        http.StatusOK,
+			rsp: SpendingPolicyResponse{
+				MaxTransactionCoins: "1.000000",
+				DailyLimitCoins:     "10.000000",
+				AllowedDestinations: []string{dest.String()},
+				DailySpentCoins:     "0.000000",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("SetSpendingPolicy", tc.req.ID, []byte(tc.req.Password), tc.gatewayPolicy).Return(tc.gatewayRsp, tc.gatewayErr)
+
+			contentType := tc.contentType
+			if contentType == "" {
+				contentType = ContentTypeJSON
+			}
+
+			req, err := http.NewRequest(tc.method, "/api/v2/wallet/spending-policy/set", strings.NewReader(toJSON(t, tc.req)))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", contentType)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code, "got `%v` want `%v`", rr.Code, tc.status)
+
+			var rsp ReceivedHTTPResponse
+			err = json.NewDecoder(rr.Body).Decode(&rsp)
+			require.NoError(t, err)
+
+			if tc.status != http.StatusOK {
+				require.NotNil(t, rsp.Error)
+				require.Equal(t, tc.err, rsp.Error.Message)
+				return
+			}
+
+			require.Nil(t, rsp.Error)
+			var policy SpendingPolicyResponse
+			err = json.Unmarshal(rsp.Data, &policy)
+			require.NoError(t, err)
+			require.Equal(t, tc.rsp, policy)
+		})
+	}
+}
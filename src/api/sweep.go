@@ -0,0 +1,198 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/util/droplet"
+	"github.com/skycoin/skycoin/src/util/fee"
+	"github.com/skycoin/skycoin/src/visor"
+	"github.com/skycoin/skycoin/src/visor/blockdb"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+// SweepRequest is the request data for POST /api/v2/wallet/sweep
+type SweepRequest struct {
+	// SecretKeys are the hex-encoded private keys to sweep
+	SecretKeys []string `json:"secret_keys"`
+	// Address is the destination address, optional if WalletID is set
+	Address string `json:"address,omitempty"`
+	// WalletID is the destination wallet, the address must be one of its addresses if set
+	WalletID string `json:"wallet_id,omitempty"`
+}
+
+// SweepResponse is the response data for POST /api/v2/wallet/sweep
+type SweepResponse struct {
+	Transactions []CreateTransactionResponse `json:"transactions"`
+	// Dust are the unspent outputs that could not be swept
+	Dust []CreatedTransactionInput `json:"dust"`
+	// TotalCoins are the coins swept by the transactions
+	TotalCoins string `json:"total_coins"`
+	// TotalFee are the coin hours burned by the transactions
+	TotalFee string `json:"total_fee"`
+}
+
+// NewSweepResponse creates a SweepResponse from a wallet.Sweep
+func NewSweepResponse(sweep *wallet.Sweep) (*SweepResponse, error) {
+	rsp := &SweepResponse{
+		Transactions: make([]CreateTransactionResponse, len(sweep.Transactions)),
+		Dust:         make([]CreatedTransactionInput, len(sweep.Dust)),
+	}
+
+	var totalCoins, totalFee uint64
+	for i, st := range sweep.Transactions {
+		txnRsp, err := NewCreateTransactionResponse(st.Transaction, st.Inputs)
+		if err != nil {
+			return nil, err
+		}
+		rsp.Transactions[i] = *txnRsp
+
+		for _, o := range st.Transaction.Out {
+			totalCoins, err = coin.AddUint64(totalCoins, o.Coins)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		txnFee, err := strconv.ParseUint(txnRsp.Transaction.Fee, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		totalFee, err = coin.AddUint64(totalFee, txnFee)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i, ux := range sweep.Dust {
+		in, err := NewCreatedTransactionInput(ux)
+		if err != nil {
+			return nil, err
+		}
+		rsp.Dust[i] = *in
+	}
+
+	coins, err := droplet.ToString(totalCoins)
+	if err != nil {
+		return nil, err
+	}
+
+	rsp.TotalCoins = coins
+	rsp.TotalFee = fmt.Sprint(totalFee)
+
+	return rsp, nil
+}
+
+// URI: /api/v2/wallet/sweep
+// Method: POST
+// Content-Type: application/json
+// Args: JSON body:
+//  secret_keys: hex-encoded private keys to sweep
+//  address: [optional if wallet_id is set] destination address
+//  wallet_id: [optional] destination wallet, address must be in the wallet and defaults to its first address
+// Creates the transactions that send all the coins and coin hours of the unspent outputs of private keys
+// to an address, burning the required fee. The transactions are not injected.
+// Returns the transactions and the unspent outputs that could not be swept.
+func sweepHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if r.Header.Get("Content-Type") != ContentTypeJSON {
+			resp := NewHTTPErrorResponse(http.StatusUnsupportedMediaType, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		var req SweepRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if len(req.SecretKeys) == 0 {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "secret_keys is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if req.Address == "" && req.WalletID == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "address or wallet_id is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		keys := make([]cipher.SecKey, len(req.SecretKeys))
+		for i, s := range req.SecretKeys {
+			k, err := cipher.SecKeyFromHex(s)
+			if err != nil {
+				resp := NewHTTPErrorResponse(http.StatusBadRequest, fmt.Sprintf("invalid secret key at index %d: %v", i, err))
+				writeHTTPResponse(w, resp)
+				return
+			}
+			keys[i] = k
+		}
+
+		var dest cipher.Address
+		if req.Address != "" {
+			var err error
+			dest, err = cipher.DecodeBase58Address(req.Address)
+			if err != nil {
+				resp := NewHTTPErrorResponse(http.StatusBadRequest, fmt.Sprintf("invalid address: %v", err))
+				writeHTTPResponse(w, resp)
+				return
+			}
+		}
+
+		sweep, err := gateway.SweepPrivateKeys(keys, req.WalletID, dest)
+		if err != nil {
+			var resp HTTPResponse
+			switch err.(type) {
+			case wallet.Error:
+				switch err {
+				case wallet.ErrWalletAPIDisabled:
+					resp = NewHTTPErrorResponse(http.StatusForbidden, "")
+				case wallet.ErrWalletNotExist:
+					resp = NewHTTPErrorResponse(http.StatusNotFound, "")
+				default:
+					resp = NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+				}
+			case blockdb.ErrUnspentNotExist,
+				visor.ErrTxnViolatesSoftConstraint,
+				visor.ErrTxnViolatesHardConstraint,
+				visor.ErrTxnViolatesUserConstraint:
+				resp = NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			default:
+				switch err {
+				case fee.ErrTxnNoFee,
+					fee.ErrTxnInsufficientCoinHours:
+					resp = NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+				default:
+					resp = NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+				}
+			}
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		rsp, err := NewSweepResponse(sweep)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: rsp,
+		})
+	}
+}
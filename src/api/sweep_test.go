@@ -0,0 +1,236 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/params"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/util/fee"
+	"github.com/skycoin/skycoin/src/visor"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+func makeSweep(t *testing.T, key cipher.SecKey, dest cipher.Address) *wallet.Sweep {
+	sk, err := wallet.NewSweepKeys([]cipher.SecKey{key})
+	require.NoError(t, err)
+
+	uxa := coin.UxArray{
+		{
+			Head: coin.UxHead{
+				Time:  100,
+				BkSeq: 1,
+			},
+			Body: coin.UxBody{
+				SrcTransaction: testutil.RandSHA256(t),
+				Address:        cipher.MustAddressFromSecKey(key),
+				Coins:          2e6,
+				Hours:          10,
+			},
+		},
+		{
+			Head: coin.UxHead{
+				Time:  100,
+				BkSeq: 2,
+			},
+			Body: coin.UxBody{
+				SrcTransaction: testutil.RandSHA256(t),
+				Address:        cipher.MustAddressFromSecKey(key),
+				Coins:          1e6 + 1,
+				Hours:          10,
+			},
+		},
+	}
+
+	uxb, err := wallet.NewUxBalances(100, uxa)
+	require.NoError(t, err)
+
+	sweep, err := sk.CreateSweep(uxb, dest, params.UserMaxTransactionSize)
+	require.NoError(t, err)
+	return sweep
+}
+
+func TestSweepHandler(t *testing.T) {
+	_, key := cipher.GenerateKeyPair()
+	dest := testutil.MakeAddress()
+	sweep := makeSweep(t, key, dest)
+
+	cases := []struct {
+		name        string
+		method      string
+		contentType string
+		req         SweepRequest
+		status      int
+		err         string
+		gatewayID   string
+		gatewayDest cipher.Address
+		gatewayRsp  *wallet.Sweep
+		gatewayErr  error
+	}{
+		{
+			name:   "405",
+			method: http.MethodGet,
+			status: http.StatusMethodNotAllowed,
+			err:    "Method Not Allowed",
+		},
+		{
+			name:        "415",
+			method:      http.MethodPost,
+			contentType: ContentTypeForm,
+			status:      http.StatusUnsupportedMediaType,
+			err:         "Unsupported Media Type",
+		},
+		{
+			name:   "secret_keys missing",
+			method: http.MethodPost,
+			req: SweepRequest{
+				Address: dest.String(),
+			},
+			status: http.StatusBadRequest,
+			err:    "secret_keys is required",
+		},
+		{
+			name:   "address and wallet_id missing",
+			method: http.MethodPost,
+			req: SweepRequest{
+				SecretKeys: []string{key.Hex()},
+			},
+			status: http.StatusBadRequest,
+			err:    "address or wallet_id is required",
+		},
+		{
+			name:   "invalid secret key",
+			method: http.MethodPost,
+			req: SweepRequest{
+				SecretKeys: []string{key.Hex(), "x"},
+				Address:    dest.String(),
+			},
+			status: http.StatusBadRequest,
+			err:    "invalid secret key at index 1: Invalid secret key",
+		},
+		{
+			name:   "invalid address",
+			method: http.MethodPost,
+			req: SweepRequest{
+				SecretKeys: []string{key.Hex()},
+				Address:    "x",
+			},
+			status: http.StatusBadRequest,
+			err:    "invalid address: Invalid address length",
+		},
+		{
+			name:   "wallet not exist",
+			method: http.MethodPost,
+			req: SweepRequest{
+				SecretKeys: []string{key.Hex()},
+				WalletID:   "foo.wlt",
+			},
+			gatewayID:  "foo.wlt",
+			gatewayErr: wallet.ErrWalletNotExist,
+			status:     http.StatusNotFound,
+			err:        "Not Found",
+		},
+		{
+			name:   "wallet api disabled",
+			method: http.MethodPost,
+			req: SweepRequest{
+				SecretKeys: []string{key.Hex()},
+				Address:    dest.String(),
+			},
+			gatewayDest: dest,
+			gatewayErr:  wallet.ErrWalletAPIDisabled,
+			status:      http.StatusForbidden,
+			err:         "Forbidden",
+		},
+		{
+			name:   "nothing to sweep",
+			method: http.MethodPost,
+			req: SweepRequest{
+				SecretKeys: []string{key.Hex()},
+				Address:    dest.String(),
+			},
+			gatewayDest: dest,
+			gatewayErr:  wallet.ErrSweepNothingToSweep,
+			status:      http.StatusBadRequest,
+			err:         wallet.ErrSweepNothingToSweep.Error(),
+		},
+		{
+			name:   "transaction violates constraints",
+			method: http.MethodPost,
+			req: SweepRequest{
+				SecretKeys: []string{key.Hex()},
+				Address:    dest.String(),
+			},
+			gatewayDest: dest,
+			gatewayErr:  visor.NewErrTxnViolatesSoftConstraint(fee.ErrTxnInsufficientFee),
+			status:      http.StatusBadRequest,
+			err:         "Transaction violates soft constraint: Transaction coinhour fee minimum not met",
+		},
+		{
+			name:   "sweep",
+			method: http.MethodPost,
+			req: SweepRequest{
+				SecretKeys: []string{key.Hex()},
+				Address:    dest.String(),
+				WalletID:   "foo.wlt",
+			},
+			gatewayID:   "foo.wlt",
+			gatewayDest: dest,
+			gatewayRsp:  sweep,
+			status:      http.StatusOK,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("SweepPrivateKeys", []cipher.SecKey{key}, tc.gatewayID, tc.gatewayDest).Return(tc.gatewayRsp, tc.gatewayErr)
+
+			contentType := tc.contentType
+			if contentType == "" {
+				contentType = ContentTypeJSON
+			}
+
+			req, err := http.NewRequest(tc.method, "/api/v2/wallet/sweep", strings.NewReader(toJSON(t, tc.req)))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", contentType)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code, "got `%v` want `%v`", rr.Code, tc.status)
+
+			var rsp ReceivedHTTPResponse
+			err = json.NewDecoder(rr.Body).Decode(&rsp)
+			require.NoError(t, err)
+
+			if tc.status != http.StatusOK {
+				require.NotNil(t, rsp.Error)
+				require.Equal(t, tc.err, rsp.Error.Message)
+				return
+			}
+
+			require.Nil(t, rsp.Error)
+			var sweepRsp SweepResponse
+			err = json.Unmarshal(rsp.Data, &sweepRsp)
+			require.NoError(t, err)
+
+			expected, err := NewSweepResponse(tc.gatewayRsp)
+			require.NoError(t, err)
+			require.Equal(t, *expected, sweepRsp)
+
+			require.Len(t, sweepRsp.Transactions, 1)
+			require.Len(t, sweepRsp.Dust, 1)
+			require.Equal(t, "2.000000", sweepRsp.TotalCoins)
+			require.Equal(t, "5", sweepRsp.TotalFee)
+		})
+	}
+}
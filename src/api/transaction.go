@@ -9,12 +9,14 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/coin"
 	"github.com/skycoin/skycoin/src/daemon"
 	"github.com/skycoin/skycoin/src/readable"
 	wh "github.com/skycoin/skycoin/src/util/http"
+	"github.com/skycoin/skycoin/src/util/timeutil"
 	"github.com/skycoin/skycoin/src/visor"
 	"github.com/skycoin/skycoin/src/wallet"
 )
@@ -69,6 +71,164 @@ func pendingTxnsHandler(gateway Gatewayer) http.HandlerFunc {
 	}
 }
 
+const (
+	defaultEvictedTxnsLimit = 100
+	maxEvictedTxnsLimit     = 1000
+)
+
+// EvictedTxn is a transaction evicted from the unconfirmed pool
+type EvictedTxn struct {
+	ID      uint64            `json:"id"`
+	TxnID   string            `json:"txid"`
+	Added   time.Time         `json:"added"`
+	Evicted time.Time         `json:"evicted"`
+	Reason  visor.EvictReason `json:"reason"`
+	IsValid bool              `json:"is_valid"`
+}
+
+// NewEvictedTxn creates an EvictedTxn from a visor.EvictedUnconfirmedTxn
+func NewEvictedTxn(e visor.EvictedUnconfirmedTxn) EvictedTxn {
+	return EvictedTxn{
+		ID:      e.ID,
+		TxnID:   e.TxnID.Hex(),
+		Added:   timeutil.NanoToTime(e.Added),
+		Evicted: timeutil.NanoToTime(e.Evicted),
+		Reason:  e.Reason,
+		IsValid: e.IsValid == 1,
+	}
+}
+
+// EvictedTxnsResponse is returned by GET /api/v1/pendingTxs/evicted
+type EvictedTxnsResponse struct {
+	Evicted []EvictedTxn `json:"evicted"`
+}
+
+// evictedTxnsHandler returns the transactions evicted from the unconfirmed pool for exceeding its max age
+// or max size, in the order they were evicted.
+// Clients poll for new evictions by passing the ID of the last eviction they received in after.
+// Method: GET
+// URI: /api/v1/pendingTxs/evicted?after=${after}&limit=${limit}
+// Args:
+//	after [int, return the evictions with an ID greater than after, defaults to 0]
+//	limit [int, maximum number of evictions returned, defaults to 100, at most 1000]
+func evictedTxnsHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			wh.Error405(w)
+			return
+		}
+
+		var after uint64
+		if afterStr := r.FormValue("after"); afterStr != "" {
+			var err error
+			after, err = strconv.ParseUint(afterStr, 10, 64)
+			if err != nil {
+				wh.Error400(w, "invalid after")
+				return
+			}
+		}
+
+		limit := uint64(defaultEvictedTxnsLimit)
+		if limitStr := r.FormValue("limit"); limitStr != "" {
+			var err error
+			limit, err = strconv.ParseUint(limitStr, 10, 64)
+			if err != nil {
+				wh.Error400(w, "invalid limit")
+				return
+			}
+
+			if limit == 0 || limit > maxEvictedTxnsLimit {
+				wh.Error400(w, fmt.Sprintf("limit must be between 1 and %d", maxEvictedTxnsLimit))
+				return
+			}
+		}
+
+		evicted, err := gateway.GetEvictedUnconfirmedTxns(after, limit)
+		if err != nil {
+			wh.Error500(w, err.Error())
+			return
+		}
+
+		resp := EvictedTxnsResponse{
+			Evicted: make([]EvictedTxn, len(evicted)),
+		}
+		for i, e := range evicted {
+			resp.Evicted[i] = NewEvictedTxn(e)
+		}
+
+		wh.SendJSONOr500(logger, w, resp)
+	}
+}
+
+// RebroadcastTxn is the broadcast history of an unconfirmed transaction injected by this node
+type RebroadcastTxn struct {
+	TxnID         string    `json:"txid"`
+	Added         time.Time `json:"added"`
+	Broadcasts    int       `json:"broadcasts"`
+	LastBroadcast time.Time `json:"last_broadcast"`
+	NextBroadcast time.Time `json:"next_broadcast"`
+	Expires       time.Time `json:"expires"`
+}
+
+// NewRebroadcastTxn creates a RebroadcastTxn from a daemon.RebroadcastStats
+func NewRebroadcastTxn(s daemon.RebroadcastStats) RebroadcastTxn {
+	return RebroadcastTxn{
+		TxnID:         s.Txid.Hex(),
+		Added:         s.Added,
+		Broadcasts:    s.Broadcasts,
+		LastBroadcast: s.LastBroadcast,
+		NextBroadcast: s.NextBroadcast,
+		Expires:       s.Expires,
+	}
+}
+
+// RebroadcastTxnsResponse is returned by GET /api/v1/pendingTxs/rebroadcast
+type RebroadcastTxnsResponse struct {
+	Rebroadcasts []RebroadcastTxn `json:"rebroadcasts"`
+}
+
+// rebroadcastTxnsHandler returns the broadcast history of the unconfirmed transactions injected by this node,
+// which are rebroadcast with an exponential backoff until they are confirmed or the rebroadcasts expire
+// Method: GET
+// URI: /api/v1/pendingTxs/rebroadcast?txid=${txid}
+// Args:
+//	txid [optional, return only this transaction. Returns 404 if it is not being rebroadcast]
+func rebroadcastTxnsHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			wh.Error405(w)
+			return
+		}
+
+		var stats []daemon.RebroadcastStats
+		if txid := r.FormValue("txid"); txid != "" {
+			h, err := cipher.SHA256FromHex(txid)
+			if err != nil {
+				wh.Error400(w, err.Error())
+				return
+			}
+
+			s := gateway.GetTxnRebroadcastStats(h)
+			if s == nil {
+				wh.Error404(w, "transaction is not being rebroadcast")
+				return
+			}
+			stats = append(stats, *s)
+		} else {
+			stats = gateway.GetRebroadcastStats()
+		}
+
+		resp := RebroadcastTxnsResponse{
+			Rebroadcasts: make([]RebroadcastTxn, len(stats)),
+		}
+		for i, s := range stats {
+			resp.Rebroadcasts[i] = NewRebroadcastTxn(s)
+		}
+
+		wh.SendJSONOr500(logger, w, resp)
+	}
+}
+
 // TransactionEncodedResponse represents the data struct of the response to /api/v1/transaction?encoded=1
 type TransactionEncodedResponse struct {
 	Status             readable.TransactionStatus `json:"status"`
@@ -171,6 +331,45 @@ func transactionHandler(gateway Gatewayer) http.HandlerFunc {
 	}
 }
 
+// transactionProofHandler returns a proof that a confirmed transaction is included in its block,
+// which light clients can verify with the blockchain pubkey without downloading the block
+// Method: GET
+// URI: /api/v1/transaction/proof
+// Args:
+//	txid: transaction hash
+func transactionProofHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			wh.Error405(w)
+			return
+		}
+
+		txid := r.FormValue("txid")
+		if txid == "" {
+			wh.Error400(w, "txid is empty")
+			return
+		}
+
+		h, err := cipher.SHA256FromHex(txid)
+		if err != nil {
+			wh.Error400(w, err.Error())
+			return
+		}
+
+		proof, err := gateway.GetTransactionInclusionProof(h)
+		if err != nil {
+			wh.Error500(w, err.Error())
+			return
+		}
+		if proof == nil {
+			wh.Error404(w, "transaction is not confirmed")
+			return
+		}
+
+		wh.SendJSONOr500(logger, w, readable.NewTransactionInclusionProof(proof))
+	}
+}
+
 // TransactionsWithStatus array of transaction results
 type TransactionsWithStatus struct {
 	Transactions []readable.TransactionWithStatus `json:"txns"`
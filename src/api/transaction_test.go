@@ -212,6 +212,256 @@ func TestGetPendingTxs(t *testing.T) {
 	}
 }
 
+func TestGetEvictedTxns(t *testing.T) {
+	txnID := testutil.RandSHA256(t)
+	added := time.Date(2018, 6, 20, 14, 14, 52, 0, time.UTC)
+	evictedAt := added.Add(14 * 24 * time.Hour)
+
+	evicted := []visor.EvictedUnconfirmedTxn{
+		{
+			ID:      7,
+			TxnID:   txnID,
+			Added:   added.UnixNano(),
+			Evicted: evictedAt.UnixNano(),
+			Reason:  visor.EvictReasonExpired,
+			IsValid: 1,
+		},
+	}
+
+	tt := []struct {
+		name          string
+		method        string
+		status        int
+		err           string
+		query         string
+		after         uint64
+		limit         uint64
+		gatewayResult []visor.EvictedUnconfirmedTxn
+		gatewayErr    error
+		result        EvictedTxnsResponse
+	}{
+		{
+			name:   "405",
+			method: http.MethodPost,
+			status: http.StatusMethodNotAllowed,
+			err:    "405 Method Not Allowed",
+		},
+		{
+			name:   "400 - invalid after",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - invalid after",
+			query:  "after=x",
+		},
+		{
+			name:   "400 - invalid limit",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - invalid limit",
+			query:  "limit=-1",
+		},
+		{
+			name:   "400 - limit too large",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - limit must be between 1 and 1000",
+			query:  "limit=1001",
+		},
+		{
+			name:       "500 - gateway error",
+			method:     http.MethodGet,
+			status:     http.StatusInternalServerError,
+			err:        "500 Internal Server Error - database not open",
+			limit:      100,
+			gatewayErr: errors.New("database not open"),
+		},
+		{
+			name:   "200 - no evictions",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			limit:  100,
+			result: EvictedTxnsResponse{
+				Evicted: []EvictedTxn{},
+			},
+		},
+		{
+			name:          "200",
+			method:        http.MethodGet,
+			status:        http.StatusOK,
+			query:         "after=6&limit=10",
+			after:         6,
+			limit:         10,
+			gatewayResult: evicted,
+			result: EvictedTxnsResponse{
+				Evicted: []EvictedTxn{
+					{
+						ID:      7,
+						TxnID:   txnID.Hex(),
+						Added:   added,
+						Evicted: evictedAt,
+						Reason:  visor.EvictReasonExpired,
+						IsValid: true,
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			endpoint := "/api/v1/pendingTxs/evicted"
+			if tc.query != "" {
+				endpoint += "?" + tc.query
+			}
+
+			gateway := &MockGatewayer{}
+			gateway.On("GetEvictedUnconfirmedTxns", tc.after, tc.limit).Return(tc.gatewayResult, tc.gatewayErr)
+
+			req, err := http.NewRequest(tc.method, endpoint, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			status := rr.Code
+			require.Equal(t, tc.status, status, "got `%v` want `%v`", status, tc.status)
+
+			if status != http.StatusOK {
+				require.Equal(t, tc.err, strings.TrimSpace(rr.Body.String()))
+				return
+			}
+
+			var msg EvictedTxnsResponse
+			err = json.Unmarshal(rr.Body.Bytes(), &msg)
+			require.NoError(t, err)
+			require.Equal(t, len(tc.result.Evicted), len(msg.Evicted))
+			for i := range msg.Evicted {
+				require.True(t, tc.result.Evicted[i].Added.Equal(msg.Evicted[i].Added))
+				require.True(t, tc.result.Evicted[i].Evicted.Equal(msg.Evicted[i].Evicted))
+				msg.Evicted[i].Added = tc.result.Evicted[i].Added
+				msg.Evicted[i].Evicted = tc.result.Evicted[i].Evicted
+			}
+			require.Equal(t, tc.result, msg)
+		})
+	}
+}
+
+func TestGetRebroadcastTxns(t *testing.T) {
+	txnID := testutil.RandSHA256(t)
+	added := time.Date(2018, 6, 20, 14, 14, 52, 0, time.UTC)
+
+	stats := daemon.RebroadcastStats{
+		Txid:          txnID,
+		Added:         added,
+		Broadcasts:    3,
+		LastBroadcast: added.Add(time.Minute * 15),
+		NextBroadcast: added.Add(time.Minute * 35),
+		Expires:       added.Add(time.Hour * 72),
+	}
+
+	result := RebroadcastTxnsResponse{
+		Rebroadcasts: []RebroadcastTxn{
+			{
+				TxnID:         txnID.Hex(),
+				Added:         added,
+				Broadcasts:    3,
+				LastBroadcast: added.Add(time.Minute * 15),
+				NextBroadcast: added.Add(time.Minute * 35),
+				Expires:       added.Add(time.Hour * 72),
+			},
+		},
+	}
+
+	tt := []struct {
+		name             string
+		method           string
+		status           int
+		err              string
+		txid             string
+		gatewayAllResult []daemon.RebroadcastStats
+		gatewayTxnResult *daemon.RebroadcastStats
+		result           RebroadcastTxnsResponse
+	}{
+		{
+			name:   "405",
+			method: http.MethodPost,
+			status: http.StatusMethodNotAllowed,
+			err:    "405 Method Not Allowed",
+		},
+		{
+			name:   "400 - invalid txid",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - encoding/hex: odd length hex string",
+			txid:   "abc",
+		},
+		{
+			name:   "404 - not rebroadcast",
+			method: http.MethodGet,
+			status: http.StatusNotFound,
+			err:    "404 Not Found - transaction is not being rebroadcast",
+			txid:   txnID.Hex(),
+		},
+		{
+			name:   "200 - no rebroadcasts",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			result: RebroadcastTxnsResponse{
+				Rebroadcasts: []RebroadcastTxn{},
+			},
+		},
+		{
+			name:             "200 - all",
+			method:           http.MethodGet,
+			status:           http.StatusOK,
+			gatewayAllResult: []daemon.RebroadcastStats{stats},
+			result:           result,
+		},
+		{
+			name:             "200 - txid",
+			method:           http.MethodGet,
+			status:           http.StatusOK,
+			txid:             txnID.Hex(),
+			gatewayTxnResult: &stats,
+			result:           result,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			endpoint := "/api/v1/pendingTxs/rebroadcast"
+			if tc.txid != "" {
+				endpoint += "?txid=" + tc.txid
+			}
+
+			gateway := &MockGatewayer{}
+			gateway.On("GetRebroadcastStats").Return(tc.gatewayAllResult)
+			gateway.On("GetTxnRebroadcastStats", txnID).Return(tc.gatewayTxnResult)
+
+			req, err := http.NewRequest(tc.method, endpoint, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			status := rr.Code
+			require.Equal(t, tc.status, status, "got `%v` want `%v`", status, tc.status)
+
+			if status != http.StatusOK {
+				require.Equal(t, tc.err, strings.TrimSpace(rr.Body.String()))
+				return
+			}
+
+			var msg RebroadcastTxnsResponse
+			err = json.Unmarshal(rr.Body.Bytes(), &msg)
+			require.NoError(t, err)
+			require.Equal(t, tc.result, msg)
+		})
+	}
+}
+
 func TestGetTransactionByID(t *testing.T) {
 	oddHash := "cafcb"
 	invalidHash := "cabrca"
@@ -1538,3 +1788,126 @@ func TestVerifyTransaction(t *testing.T) {
 		})
 	}
 }
+
+func TestGetTransactionProof(t *testing.T) {
+	validHash := "79216473e8f2c17095c6887cc9edca6c023afedfac2e0c5460e8b6f359684f8b"
+	txnHash := testutil.SHA256FromHex(t, validHash)
+
+	// A block with the transaction at index 1 of 3 transactions
+	hashes := []cipher.SHA256{testutil.RandSHA256(t), txnHash, testutil.RandSHA256(t)}
+	path, err := cipher.MerkleProof(hashes, 1)
+	require.NoError(t, err)
+
+	header := coin.BlockHeader{
+		Version:  1,
+		Time:     1000,
+		BkSeq:    7,
+		PrevHash: testutil.RandSHA256(t),
+		BodyHash: cipher.Merkle(hashes),
+		UxHash:   testutil.RandSHA256(t),
+	}
+	pubkey, seckey := cipher.GenerateKeyPair()
+	proof := &visor.TransactionInclusionProof{
+		TxnHash:    txnHash,
+		BlockSeq:   7,
+		TxnIndex:   1,
+		Header:     header,
+		Sig:        cipher.MustSignHash(header.Hash(), seckey),
+		MerklePath: path,
+	}
+	require.NoError(t, proof.Verify(pubkey))
+
+	tt := []struct {
+		name     string
+		method   string
+		status   int
+		err      string
+		txid     string
+		gwResult *visor.TransactionInclusionProof
+		gwErr    error
+	}{
+		{
+			name:   "405",
+			method: http.MethodPost,
+			status: http.StatusMethodNotAllowed,
+			err:    "405 Method Not Allowed",
+		},
+		{
+			name:   "400 - txid is empty",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - txid is empty",
+		},
+		{
+			name:   "400 - invalid hash",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - encoding/hex: odd length hex string",
+			txid:   "cafcb",
+		},
+		{
+			name:   "500 - gateway error",
+			method: http.MethodGet,
+			status: http.StatusInternalServerError,
+			err:    "500 Internal Server Error - gatewayErr",
+			txid:   validHash,
+			gwErr:  errors.New("gatewayErr"),
+		},
+		{
+			name:   "404 - not confirmed",
+			method: http.MethodGet,
+			status: http.StatusNotFound,
+			err:    "404 Not Found - transaction is not confirmed",
+			txid:   validHash,
+		},
+		{
+			name:     "200",
+			method:   http.MethodGet,
+			status:   http.StatusOK,
+			txid:     validHash,
+			gwResult: proof,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			endpoint := "/api/v1/transaction/proof"
+			gateway := &MockGatewayer{}
+			gateway.On("GetTransactionInclusionProof", txnHash).Return(tc.gwResult, tc.gwErr)
+
+			v := url.Values{}
+			if tc.txid != "" {
+				v.Add("txid", tc.txid)
+			}
+			if len(v) > 0 {
+				endpoint += "?" + v.Encode()
+			}
+
+			req, err := http.NewRequest(tc.method, endpoint, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			status := rr.Code
+			require.Equal(t, tc.status, status, "got `%v` want `%v`", status, tc.status)
+
+			if status != http.StatusOK {
+				require.Equal(t, tc.err, strings.TrimSpace(rr.Body.String()), "got `%v`| %d, want `%v`",
+					strings.TrimSpace(rr.Body.String()), status, tc.err)
+			} else {
+				var msg readable.TransactionInclusionProof
+				err = json.Unmarshal(rr.Body.Bytes(), &msg)
+				require.NoError(t, err)
+				require.Equal(t, readable.NewTransactionInclusionProof(tc.gwResult), msg)
+
+				// The readable proof converts back to a proof that verifies
+				p, err := msg.ToTransactionInclusionProof()
+				require.NoError(t, err)
+				require.Equal(t, tc.gwResult, p)
+				require.NoError(t, p.Verify(pubkey))
+			}
+		})
+	}
+}
@@ -1,11 +1,19 @@
 package api
 
 import (
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/readable"
+	"github.com/skycoin/skycoin/src/util/droplet"
 	wh "github.com/skycoin/skycoin/src/util/http"
+	"github.com/skycoin/skycoin/src/visor"
+	"github.com/skycoin/skycoin/src/visor/historydb"
 )
 
 // URI: /api/v1/uxout
@@ -47,6 +55,45 @@ func uxOutHandler(gateway Gatewayer) http.HandlerFunc {
 	}
 }
 
+// URI: /api/v1/uxout/spender
+// Method: GET
+// Args:
+//	uxid: output ID hash
+// Returns the transaction input which spent an output
+func uxOutSpenderHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			wh.Error405(w)
+			return
+		}
+
+		uxid := r.FormValue("uxid")
+		if uxid == "" {
+			wh.Error400(w, "uxid is empty")
+			return
+		}
+
+		id, err := cipher.SHA256FromHex(uxid)
+		if err != nil {
+			wh.Error400(w, err.Error())
+			return
+		}
+
+		spender, err := gateway.GetUxOutSpender(id)
+		if err != nil {
+			wh.Error500(w, err.Error())
+			return
+		}
+
+		if spender == nil {
+			wh.Error404(w, "uxout is unspent or does not exist")
+			return
+		}
+
+		wh.SendJSONOr500(logger, w, readable.NewUxOutSpender(id, spender))
+	}
+}
+
 // URI: /api/v1/address_uxouts
 // Method: GET
 // Args:
@@ -85,3 +132,180 @@ func addrUxOutsHandler(gateway Gatewayer) http.HandlerFunc {
 		wh.SendJSONOr500(logger, w, ret)
 	}
 }
+
+const (
+	// defaultUxOutsByValueLimit is the number of outputs returned by /uxout/value if no limit is given
+	defaultUxOutsByValueLimit = 100
+	// maxUxOutsByValueLimit is the maximum number of outputs returned by /uxout/value
+	maxUxOutsByValueLimit = 1000
+)
+
+// UxOutsByValuePage is a page of the outputs with coins in a range, created by the blocks in a range
+type UxOutsByValuePage struct {
+	// StartSeq and EndSeq are the block range the outputs were selected from
+	StartSeq uint64                 `json:"start_seq"`
+	EndSeq   uint64                 `json:"end_seq"`
+	Outputs  []readable.SpentOutput `json:"outputs"`
+	// Cursor of the next page, empty if there are no more outputs
+	NextCursor string `json:"next_cursor"`
+}
+
+// formatOutputValuesCursor formats a cursor as "$coins:$block_seq:$uxid"
+func formatOutputValuesCursor(c *historydb.OutputValuesCursor) string {
+	if c == nil {
+		return ""
+	}
+	return fmt.Sprintf("%d:%d:%s", c.Coins, c.BlockSeq, c.UxID.Hex())
+}
+
+// parseOutputValuesCursor parses a cursor formatted by formatOutputValuesCursor
+func parseOutputValuesCursor(s string) (*historydb.OutputValuesCursor, error) {
+	pts := strings.Split(s, ":")
+	if len(pts) != 3 {
+		return nil, errors.New("invalid cursor")
+	}
+
+	coins, err := strconv.ParseUint(pts[0], 10, 64)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+
+	seq, err := strconv.ParseUint(pts[1], 10, 64)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+
+	uxID, err := cipher.SHA256FromHex(pts[2])
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+
+	return &historydb.OutputValuesCursor{
+		Coins:    coins,
+		BlockSeq: seq,
+		UxID:     uxID,
+	}, nil
+}
+
+// URI: /api/v1/uxout/value
+// Method: GET
+// Args:
+//	min_coins: minimum coins of the outputs, defaults to 0
+//	max_coins: maximum coins of the outputs, defaults to no maximum
+//	blocks: select the outputs created by this many most recent blocks, can't be combined with start_seq and end_seq
+//	start_seq: the first block seq of the range, defaults to 0
+//	end_seq: the last block seq of the range, defaults to the head block seq
+//	limit: maximum number of outputs returned, defaults to 100, at most 1000
+//	cursor: next_cursor of the previous page, the page starts from the first output if empty
+// Returns a page of the outputs, spent or not, with coins in a range and created by the blocks in a range,
+// in coins, block seq and output hash order. The block range is capped at the head block.
+func uxOutsByValueHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			wh.Error405(w)
+			return
+		}
+
+		q := visor.OutputsByValueQuery{
+			MaxCoins: math.MaxUint64,
+			EndSeq:   math.MaxUint64,
+		}
+
+		var err error
+		if s := r.FormValue("min_coins"); s != "" {
+			q.MinCoins, err = droplet.FromString(s)
+			if err != nil {
+				wh.Error400(w, fmt.Sprintf("Invalid min_coins value: %v", err))
+				return
+			}
+		}
+
+		if s := r.FormValue("max_coins"); s != "" {
+			q.MaxCoins, err = droplet.FromString(s)
+			if err != nil {
+				wh.Error400(w, fmt.Sprintf("Invalid max_coins value: %v", err))
+				return
+			}
+		}
+
+		if q.MinCoins > q.MaxCoins {
+			wh.Error400(w, "min_coins must not be greater than max_coins")
+			return
+		}
+
+		if s := r.FormValue("blocks"); s != "" {
+			q.LastBlocks, err = strconv.ParseUint(s, 10, 64)
+			if err != nil || q.LastBlocks == 0 {
+				wh.Error400(w, fmt.Sprintf("Invalid blocks value %q", s))
+				return
+			}
+		}
+
+		if s := r.FormValue("start_seq"); s != "" {
+			q.StartSeq, err = strconv.ParseUint(s, 10, 64)
+			if err != nil {
+				wh.Error400(w, fmt.Sprintf("Invalid start_seq value %q", s))
+				return
+			}
+		}
+
+		if s := r.FormValue("end_seq"); s != "" {
+			q.EndSeq, err = strconv.ParseUint(s, 10, 64)
+			if err != nil {
+				wh.Error400(w, fmt.Sprintf("Invalid end_seq value %q", s))
+				return
+			}
+		}
+
+		if q.LastBlocks != 0 && (r.FormValue("start_seq") != "" || r.FormValue("end_seq") != "") {
+			wh.Error400(w, "blocks can't be combined with start_seq and end_seq")
+			return
+		}
+
+		if q.StartSeq > q.EndSeq {
+			wh.Error400(w, "start_seq must not be greater than end_seq")
+			return
+		}
+
+		limit := uint64(defaultUxOutsByValueLimit)
+		if s := r.FormValue("limit"); s != "" {
+			limit, err = strconv.ParseUint(s, 10, 64)
+			if err != nil {
+				wh.Error400(w, "invalid limit")
+				return
+			}
+
+			if limit == 0 || limit > maxUxOutsByValueLimit {
+				wh.Error400(w, fmt.Sprintf("limit must be between 1 and %d", maxUxOutsByValueLimit))
+				return
+			}
+		}
+
+		var cursor *historydb.OutputValuesCursor
+		if s := r.FormValue("cursor"); s != "" {
+			cursor, err = parseOutputValuesCursor(s)
+			if err != nil {
+				wh.Error400(w, err.Error())
+				return
+			}
+		}
+
+		page, err := gateway.GetOutputsByValue(q, cursor, limit)
+		if err != nil {
+			switch err.(type) {
+			case visor.ErrBlockNotExist:
+				wh.Error404(w, err.Error())
+			default:
+				wh.Error500(w, err.Error())
+			}
+			return
+		}
+
+		wh.SendJSONOr500(logger, w, UxOutsByValuePage{
+			StartSeq:   page.StartSeq,
+			EndSeq:     page.EndSeq,
+			Outputs:    readable.NewSpentOutputs(page.Outputs),
+			NextCursor: formatOutputValuesCursor(page.NextCursor),
+		})
+	}
+}
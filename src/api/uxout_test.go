@@ -2,6 +2,8 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -13,8 +15,10 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
 	"github.com/skycoin/skycoin/src/readable"
 	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor"
 	"github.com/skycoin/skycoin/src/visor/historydb"
 )
 
@@ -162,6 +166,111 @@ func TestGetUxOutByID(t *testing.T) {
 	}
 }
 
+func TestGetUxOutSpender(t *testing.T) {
+	validHash := "79216473e8f2c17095c6887cc9edca6c023afedfac2e0c5460e8b6f359684f8b"
+	spender := &historydb.UxOutSpender{
+		TxnID:      testutil.RandSHA256(t),
+		BlockSeq:   10,
+		InputIndex: 2,
+	}
+
+	tt := []struct {
+		name                  string
+		method                string
+		status                int
+		err                   string
+		uxid                  string
+		getUxOutSpenderArg    cipher.SHA256
+		getUxOutSpenderResult *historydb.UxOutSpender
+		getUxOutSpenderErr    error
+		httpResponse          readable.UxOutSpender
+	}{
+		{
+			name:   "405",
+			method: http.MethodPost,
+			status: http.StatusMethodNotAllowed,
+			err:    "405 Method Not Allowed",
+		},
+		{
+			name:   "400 - empty uxid",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - uxid is empty",
+		},
+		{
+			name:   "400 - invalid uxid",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - encoding/hex: invalid byte: U+0072 'r'",
+			uxid:   "carccb",
+		},
+		{
+			name:               "500 - GetUxOutSpender error",
+			method:             http.MethodGet,
+			status:             http.StatusInternalServerError,
+			err:                "500 Internal Server Error - GetUxOutSpender failed",
+			uxid:               validHash,
+			getUxOutSpenderArg: testutil.SHA256FromHex(t, validHash),
+			getUxOutSpenderErr: errors.New("GetUxOutSpender failed"),
+		},
+		{
+			name:               "404 - uxout unspent",
+			method:             http.MethodGet,
+			status:             http.StatusNotFound,
+			err:                "404 Not Found - uxout is unspent or does not exist",
+			uxid:               validHash,
+			getUxOutSpenderArg: testutil.SHA256FromHex(t, validHash),
+		},
+		{
+			name:                  "200",
+			method:                http.MethodGet,
+			status:                http.StatusOK,
+			uxid:                  validHash,
+			getUxOutSpenderArg:    testutil.SHA256FromHex(t, validHash),
+			getUxOutSpenderResult: spender,
+			httpResponse: readable.UxOutSpender{
+				Uxid:          validHash,
+				SpentTxnID:    spender.TxnID.Hex(),
+				SpentBlockSeq: 10,
+				InputIndex:    2,
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("GetUxOutSpender", tc.getUxOutSpenderArg).Return(tc.getUxOutSpenderResult, tc.getUxOutSpenderErr)
+
+			endpoint := "/api/v1/uxout/spender"
+			if tc.uxid != "" {
+				v := url.Values{}
+				v.Add("uxid", tc.uxid)
+				endpoint += "?" + v.Encode()
+			}
+
+			req, err := http.NewRequest(tc.method, endpoint, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			status := rr.Code
+			require.Equal(t, tc.status, status, "got `%v` want `%v`", status, tc.status)
+
+			if status != http.StatusOK {
+				require.Equal(t, tc.err, strings.TrimSpace(rr.Body.String()))
+			} else {
+				var msg readable.UxOutSpender
+				err = json.Unmarshal(rr.Body.Bytes(), &msg)
+				require.NoError(t, err)
+				require.Equal(t, tc.httpResponse, msg)
+			}
+		})
+	}
+}
+
 func TestGetAddrUxOuts(t *testing.T) {
 	addressForGwError := testutil.MakeAddress()
 	addressForGwResponse := testutil.MakeAddress()
@@ -275,3 +384,202 @@ func TestGetAddrUxOuts(t *testing.T) {
 		})
 	}
 }
+
+func TestGetUxOutsByValue(t *testing.T) {
+	uxID := testutil.RandSHA256(t)
+	uxOut := historydb.UxOut{
+		Out: coin.UxOut{
+			Head: coin.UxHead{
+				Time:  1540000000,
+				BkSeq: 7,
+			},
+			Body: coin.UxBody{
+				SrcTransaction: testutil.RandSHA256(t),
+				Address:        testutil.MakeAddress(),
+				Coins:          2e6,
+				Hours:          10,
+			},
+		},
+	}
+	next := &historydb.OutputValuesCursor{
+		Coins:    3e6,
+		BlockSeq: 8,
+		UxID:     uxID,
+	}
+	nextStr := fmt.Sprintf("3000000:8:%s", uxID.Hex())
+
+	tt := []struct {
+		name          string
+		method        string
+		status        int
+		err           string
+		query         string
+		gatewayQuery  visor.OutputsByValueQuery
+		gatewayCursor *historydb.OutputValuesCursor
+		gatewayLimit  uint64
+		gatewayResult *visor.OutputsByValuePage
+		gatewayErr    error
+		result        UxOutsByValuePage
+	}{
+		{
+			name:   "405",
+			method: http.MethodPost,
+			status: http.StatusMethodNotAllowed,
+			err:    "405 Method Not Allowed",
+		},
+		{
+			name:   "400 - invalid min_coins",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - Invalid min_coins value: Droplet string conversion failed: Too many decimal places",
+			query:  "min_coins=0.0000001",
+		},
+		{
+			name:   "400 - min_coins greater than max_coins",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - min_coins must not be greater than max_coins",
+			query:  "min_coins=2&max_coins=1",
+		},
+		{
+			name:   "400 - invalid blocks",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    `400 Bad Request - Invalid blocks value "0"`,
+			query:  "blocks=0",
+		},
+		{
+			name:   "400 - blocks with start_seq",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - blocks can't be combined with start_seq and end_seq",
+			query:  "blocks=10&start_seq=1",
+		},
+		{
+			name:   "400 - start_seq greater than end_seq",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - start_seq must not be greater than end_seq",
+			query:  "start_seq=2&end_seq=1",
+		},
+		{
+			name:   "400 - limit too large",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - limit must be between 1 and 1000",
+			query:  "limit=1001",
+		},
+		{
+			name:   "400 - invalid cursor",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - invalid cursor",
+			query:  "cursor=1:2",
+		},
+		{
+			name:   "404 - block does not exist",
+			method: http.MethodGet,
+			status: http.StatusNotFound,
+			err:    "404 Not Found - block does not exist seq=100",
+			query:  "start_seq=100",
+			gatewayQuery: visor.OutputsByValueQuery{
+				MaxCoins: math.MaxUint64,
+				StartSeq: 100,
+				EndSeq:   math.MaxUint64,
+			},
+			gatewayLimit: 100,
+			gatewayErr:   visor.NewErrBlockNotExist(100),
+		},
+		{
+			name:   "500 - gateway error",
+			method: http.MethodGet,
+			status: http.StatusInternalServerError,
+			err:    "500 Internal Server Error - database not open",
+			gatewayQuery: visor.OutputsByValueQuery{
+				MaxCoins: math.MaxUint64,
+				EndSeq:   math.MaxUint64,
+			},
+			gatewayLimit: 100,
+			gatewayErr:   errors.New("database not open"),
+		},
+		{
+			name:   "200",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			query:  "min_coins=1&max_coins=10000&blocks=50&limit=1&cursor=" + nextStr,
+			gatewayQuery: visor.OutputsByValueQuery{
+				MinCoins:   1e6,
+				MaxCoins:   1e10,
+				LastBlocks: 50,
+				EndSeq:     math.MaxUint64,
+			},
+			gatewayCursor: next,
+			gatewayLimit:  1,
+			gatewayResult: &visor.OutputsByValuePage{
+				StartSeq:   51,
+				EndSeq:     100,
+				Outputs:    []historydb.UxOut{uxOut},
+				NextCursor: next,
+			},
+			result: UxOutsByValuePage{
+				StartSeq:   51,
+				EndSeq:     100,
+				Outputs:    readable.NewSpentOutputs([]historydb.UxOut{uxOut}),
+				NextCursor: nextStr,
+			},
+		},
+		{
+			name:   "200 - no outputs",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			query:  "start_seq=3&end_seq=4",
+			gatewayQuery: visor.OutputsByValueQuery{
+				MaxCoins: math.MaxUint64,
+				StartSeq: 3,
+				EndSeq:   4,
+			},
+			gatewayLimit: 100,
+			gatewayResult: &visor.OutputsByValuePage{
+				StartSeq: 3,
+				EndSeq:   4,
+			},
+			result: UxOutsByValuePage{
+				StartSeq: 3,
+				EndSeq:   4,
+				Outputs:  []readable.SpentOutput{},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			endpoint := "/api/v1/uxout/value"
+			if tc.query != "" {
+				endpoint += "?" + tc.query
+			}
+
+			gateway := &MockGatewayer{}
+			gateway.On("GetOutputsByValue", tc.gatewayQuery, tc.gatewayCursor, tc.gatewayLimit).Return(tc.gatewayResult, tc.gatewayErr)
+
+			req, err := http.NewRequest(tc.method, endpoint, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			status := rr.Code
+			require.Equal(t, tc.status, status, "got `%v` want `%v`", status, tc.status)
+
+			if status != http.StatusOK {
+				require.Equal(t, tc.err, strings.TrimSpace(rr.Body.String()))
+				return
+			}
+
+			var msg UxOutsByValuePage
+			err = json.Unmarshal(rr.Body.Bytes(), &msg)
+			require.NoError(t, err)
+			require.Equal(t, tc.result, msg)
+		})
+	}
+}
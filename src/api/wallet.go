@@ -4,10 +4,13 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/cipher/go-bip39"
@@ -15,6 +18,7 @@ import (
 	"github.com/skycoin/skycoin/src/readable"
 	"github.com/skycoin/skycoin/src/util/fee"
 	wh "github.com/skycoin/skycoin/src/util/http"
+	"github.com/skycoin/skycoin/src/visor"
 	"github.com/skycoin/skycoin/src/wallet"
 )
 
@@ -28,17 +32,23 @@ type SpendResult struct {
 // UnconfirmedTxnsResponse contains unconfirmed transaction data
 type UnconfirmedTxnsResponse struct {
 	Transactions []readable.UnconfirmedTransactions `json:"transactions"`
+	// Annotations are the annotations of the transactions, by txid
+	Annotations map[string]readable.Annotation `json:"annotations,omitempty"`
 }
 
 // UnconfirmedTxnsVerboseResponse contains verbose unconfirmed transaction data
 type UnconfirmedTxnsVerboseResponse struct {
 	Transactions []readable.UnconfirmedTransactionVerbose `json:"transactions"`
+	// Annotations are the annotations of the transactions, by txid
+	Annotations map[string]readable.Annotation `json:"annotations,omitempty"`
 }
 
 // BalanceResponse address balance summary struct
 type BalanceResponse struct {
 	readable.BalancePair
 	Addresses readable.AddressBalances `json:"addresses"`
+	// Annotations are the annotations of the addresses of a wallet, by address
+	Annotations map[string]readable.Annotation `json:"annotations,omitempty"`
 }
 
 // WalletResponse wallet response struct for http apis
@@ -77,9 +87,24 @@ func NewWalletResponse(w *wallet.Wallet) (*WalletResponse, error) {
 	}
 
 	for _, e := range w.Entries {
+		// The public keys of the addresses of watch-only wallets are unknown
+		var public string
+		if !e.Public.Null() {
+			public = e.Public.Hex()
+		}
+
+		var annotation *readable.Annotation
+		if addr, ok := e.Address.(cipher.Address); ok {
+			if a, ok := w.AddressAnnotation(addr); ok {
+				ra := readable.NewAnnotation(a)
+				annotation = &ra
+			}
+		}
+
 		wr.Entries = append(wr.Entries, readable.WalletEntry{
-			Address: e.Address.String(),
-			Public:  e.Public.Hex(),
+			Address:    e.Address.String(),
+			Public:     public,
+			Annotation: annotation,
 		})
 	}
 
@@ -92,6 +117,7 @@ func NewWalletResponse(w *wallet.Wallet) (*WalletResponse, error) {
 // Method: GET
 // Args:
 //     id: wallet id [required]
+//     account: name of an account of the wallet, to get the balance of its addresses only [optional]
 func walletBalanceHandler(gateway Gatewayer) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
@@ -104,10 +130,35 @@ func walletBalanceHandler(gateway Gatewayer) http.HandlerFunc {
 			return
 		}
 
-		walletBalance, addressBalances, err := gateway.GetWalletBalance(wltID)
+		account := r.FormValue("account")
+
+		var walletBalance wallet.BalancePair
+		var addressBalances wallet.AddressBalances
+		var err error
+		if account != "" {
+			walletBalance, addressBalances, err = gateway.GetWalletAccountBalance(wltID, account)
+		} else {
+			walletBalance, addressBalances, err = gateway.GetWalletBalance(wltID)
+		}
 		if err != nil {
 			logger.Errorf("Get wallet balance failed: %v", err)
 			switch err {
+			case wallet.ErrWalletNotExist:
+				wh.Error404(w, "")
+			case wallet.ErrWalletAPIDisabled:
+				wh.Error403(w, "")
+			case wallet.ErrAccountNotExist:
+				wh.Error400(w, err.Error())
+			default:
+				wh.Error500(w, err.Error())
+			}
+			return
+		}
+
+		wlt, err := gateway.GetWallet(wltID)
+		if err != nil {
+			logger.Errorf("Get wallet failed: %v", err)
+			switch err {
 			case wallet.ErrWalletNotExist:
 				wh.Error404(w, "")
 			case wallet.ErrWalletAPIDisabled:
@@ -118,9 +169,22 @@ func walletBalanceHandler(gateway Gatewayer) http.HandlerFunc {
 			return
 		}
 
+		var annotations map[string]readable.Annotation
+		for addr, a := range wlt.Annotations.Addresses {
+			// Only annotate the addresses of the account
+			if _, ok := addressBalances[addr.String()]; !ok && account != "" {
+				continue
+			}
+			if annotations == nil {
+				annotations = make(map[string]readable.Annotation, len(wlt.Annotations.Addresses))
+			}
+			annotations[addr.String()] = readable.NewAnnotation(a)
+		}
+
 		wh.SendJSONOr500(logger, w, BalanceResponse{
 			BalancePair: readable.NewBalancePair(walletBalance),
 			Addresses:   readable.NewAddressBalances(addressBalances),
+			Annotations: annotations,
 		})
 	}
 }
@@ -192,6 +256,78 @@ func balanceHandler(gateway Gatewayer) http.HandlerFunc {
 	}
 }
 
+// Returns the confirmed balance history of an address over a range of blocks: the balance before
+// the first block, the balance after the last block and the balance change in each block that the
+// address received or spent coins in. The range is capped at the head block.
+// URI: /api/v1/balance/history
+// Method: GET
+// Args:
+//     address: the address [required]
+//     start_seq: the first block seq of the range, defaults to 0
+//     end_seq: the last block seq of the range, defaults to the head block seq
+func balanceHistoryHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			wh.Error405(w)
+			return
+		}
+
+		addrStr := r.FormValue("address")
+		if addrStr == "" {
+			wh.Error400(w, "address is required")
+			return
+		}
+
+		addr, err := cipher.DecodeBase58Address(addrStr)
+		if err != nil {
+			wh.Error400(w, fmt.Sprintf("address %s is invalid: %v", addrStr, err))
+			return
+		}
+
+		var start uint64
+		if sStart := r.FormValue("start_seq"); sStart != "" {
+			start, err = strconv.ParseUint(sStart, 10, 64)
+			if err != nil {
+				wh.Error400(w, fmt.Sprintf("Invalid start_seq value %q", sStart))
+				return
+			}
+		}
+
+		end := uint64(math.MaxUint64)
+		if sEnd := r.FormValue("end_seq"); sEnd != "" {
+			end, err = strconv.ParseUint(sEnd, 10, 64)
+			if err != nil {
+				wh.Error400(w, fmt.Sprintf("Invalid end_seq value %q", sEnd))
+				return
+			}
+		}
+
+		if start > end {
+			wh.Error400(w, "start_seq must not be greater than end_seq")
+			return
+		}
+
+		h, err := gateway.GetAddressBalanceHistory(addr, start, end)
+		if err != nil {
+			switch err.(type) {
+			case visor.ErrBlockNotExist:
+				wh.Error404(w, err.Error())
+			default:
+				wh.Error500(w, err.Error())
+			}
+			return
+		}
+
+		rh, err := readable.NewAddressBalanceHistory(h)
+		if err != nil {
+			wh.Error500(w, err.Error())
+			return
+		}
+
+		wh.SendJSONOr500(logger, w, rh)
+	}
+}
+
 // Creates and broadcasts a transaction sending money from one of our wallets
 // to destination address.
 // URI: /api/v1/wallet/spend
@@ -303,6 +439,8 @@ func walletSpendHandler(gateway Gatewayer) http.HandlerFunc {
 //     scan: the number of addresses to scan ahead for balances [optional, must be > 0]
 //     encrypt: bool value, whether encrypt the wallet [optional]
 //     password: password for encrypting wallet [optional, must be provided if "encrypt" is set]
+//     bip39: bool value, whether the seed must be a valid BIP39 mnemonic [optional]
+//     seed_passphrase: BIP39 passphrase of the seed [optional, implies "bip39"]
 func walletCreateHandler(gateway Gatewayer) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
@@ -364,12 +502,29 @@ func walletCreateHandler(gateway Gatewayer) http.HandlerFunc {
 			return
 		}
 
+		var isBIP39 bool
+		if v := r.FormValue("bip39"); v != "" {
+			var err error
+			isBIP39, err = strconv.ParseBool(v)
+			if err != nil {
+				wh.Error400(w, fmt.Sprintf("invalid bip39 value: %v", err))
+				return
+			}
+		}
+
+		seedPassphrase := r.FormValue("seed_passphrase")
+		defer func() {
+			seedPassphrase = ""
+		}()
+
 		wlt, err := gateway.CreateWallet("", wallet.Options{
-			Seed:     seed,
-			Label:    label,
-			Encrypt:  encrypt,
-			Password: []byte(password),
-			ScanN:    scanN,
+			Seed:           seed,
+			Label:          label,
+			Encrypt:        encrypt,
+			Password:       []byte(password),
+			ScanN:          scanN,
+			BIP39:          isBIP39,
+			SeedPassphrase: seedPassphrase,
 		})
 		if err != nil {
 			switch err.(type) {
@@ -576,6 +731,28 @@ func walletTransactionsHandler(gateway Gatewayer) http.HandlerFunc {
 			}
 		}
 
+		// transactionAnnotations returns the annotations of the listed transactions, by txid
+		transactionAnnotations := func(txids []cipher.SHA256) (map[string]readable.Annotation, error) {
+			wlt, err := gateway.GetWallet(wltID)
+			if err != nil {
+				return nil, err
+			}
+
+			var annotations map[string]readable.Annotation
+			for _, txid := range txids {
+				a, ok := wlt.TransactionAnnotation(txid)
+				if !ok {
+					continue
+				}
+				if annotations == nil {
+					annotations = make(map[string]readable.Annotation)
+				}
+				annotations[txid.Hex()] = readable.NewAnnotation(a)
+			}
+
+			return annotations, nil
+		}
+
 		if verbose {
 			txns, inputs, err := gateway.GetWalletUnconfirmedTransactionsVerbose(wltID)
 			if err != nil {
@@ -594,8 +771,21 @@ func walletTransactionsHandler(gateway Gatewayer) http.HandlerFunc {
 				vb[i] = *v
 			}
 
+			txids := make([]cipher.SHA256, len(txns))
+			for i, txn := range txns {
+				txids[i] = txn.Transaction.Hash()
+			}
+
+			annotations, err := transactionAnnotations(txids)
+			if err != nil {
+				logger.Errorf("get wallet failed: %v", err)
+				handleWalletError(err)
+				return
+			}
+
 			wh.SendJSONOr500(logger, w, UnconfirmedTxnsVerboseResponse{
 				Transactions: vb,
+				Annotations:  annotations,
 			})
 		} else {
 			txns, err := gateway.GetWalletUnconfirmedTransactions(wltID)
@@ -611,8 +801,21 @@ func walletTransactionsHandler(gateway Gatewayer) http.HandlerFunc {
 				return
 			}
 
+			txids := make([]cipher.SHA256, len(txns))
+			for i, txn := range txns {
+				txids[i] = txn.Transaction.Hash()
+			}
+
+			annotations, err := transactionAnnotations(txids)
+			if err != nil {
+				logger.Errorf("get wallet failed: %v", err)
+				handleWalletError(err)
+				return
+			}
+
 			wh.SendJSONOr500(logger, w, UnconfirmedTxnsResponse{
 				Transactions: unconfirmedTxns,
+				Annotations:  annotations,
 			})
 		}
 	}
@@ -694,7 +897,8 @@ func walletFolderHandler(gateway Gatewayer) http.HandlerFunc {
 // URI: /api/v1/wallet/newSeed
 // Method: GET
 // Args:
-//     entropy: entropy bitsize [optional, default value of 128 will be used if not set]
+//     entropy: entropy bitsize, 128, 160, 192, 224 or 256 for 12, 15, 18, 21 or 24 words [optional, default value of 128 will be used if not set]
+//     language: language of the wordlist [optional, default value of english will be used if not set]
 func newSeedHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
@@ -713,12 +917,17 @@ func newSeedHandler() http.HandlerFunc {
 			return
 		}
 
-		// Entropy bit size can either be 128 or 256
-		if entropyBits != 128 && entropyBits != 256 {
-			wh.Error400(w, "entropy length must be 128 or 256")
+		// Entropy bit size must be a multiple of 32 between 128 and 256
+		if entropyBits%32 != 0 || entropyBits < 128 || entropyBits > 256 {
+			wh.Error400(w, "entropy length must be 128, 160, 192, 224 or 256")
 			return
 		}
 
+		language := r.FormValue("language")
+		if language == "" {
+			language = bip39.DefaultLanguage
+		}
+
 		entropy, err := bip39.NewEntropy(entropyBits)
 		if err != nil {
 			err = fmt.Errorf("bip39.NewEntropy failed: %v", err)
@@ -726,10 +935,15 @@ func newSeedHandler() http.HandlerFunc {
 			return
 		}
 
-		mnemonic, err := bip39.NewMnemonic(entropy)
+		mnemonic, err := bip39.NewMnemonicWithLanguage(entropy, language)
 		if err != nil {
-			err = fmt.Errorf("bip39.NewDefaultMnemonic failed: %v", err)
-			wh.Error500(w, err.Error())
+			switch err {
+			case bip39.ErrUnknownLanguage:
+				wh.Error400(w, fmt.Sprintf("unknown language %q, must be one of %s", language, strings.Join(bip39.Languages(), ", ")))
+			default:
+				err = fmt.Errorf("bip39.NewMnemonicWithLanguage failed: %v", err)
+				wh.Error500(w, err.Error())
+			}
 			return
 		}
 
@@ -925,9 +1139,10 @@ func walletDecryptHandler(gateway Gatewayer) http.HandlerFunc {
 
 // WalletRecoverRequest is the request data for POST /api/v2/wallet/recover
 type WalletRecoverRequest struct {
-	ID       string `json:"id"`
-	Seed     string `json:"seed"`
-	Password string `json:"password"`
+	ID             string `json:"id"`
+	Seed           string `json:"seed"`
+	SeedPassphrase string `json:"seed_passphrase,omitempty"`
+	Password       string `json:"password"`
 }
 
 // URI: /api/v2/wallet/recover
@@ -981,11 +1196,12 @@ func walletRecoverHandler(gateway Gatewayer) http.HandlerFunc {
 
 		defer func() {
 			req.Seed = ""
+			req.SeedPassphrase = ""
 			req.Password = ""
 			password = nil
 		}()
 
-		wlt, err := gateway.RecoverWallet(req.ID, req.Seed, password)
+		wlt, err := gateway.RecoverWallet(req.ID, req.Seed, req.SeedPassphrase, password)
 		if err != nil {
 			var resp HTTPResponse
 			switch err {
@@ -1014,3 +1230,219 @@ func walletRecoverHandler(gateway Gatewayer) http.HandlerFunc {
 		})
 	}
 }
+
+// WatchOnlyWalletCreateRequest is the request data for POST /api/v2/wallet/watch-only/create
+type WatchOnlyWalletCreateRequest struct {
+	Label     string   `json:"label"`
+	Addresses []string `json:"addresses"`
+}
+
+// URI: /api/v2/wallet/watch-only/create
+// Method: POST
+// Args:
+//  label: [optional] wallet label
+//  addresses: addresses to watch
+// Creates a watch-only wallet of addresses. The wallet has no keys, it can only
+// be used to track the balance of the addresses and to create unsigned transactions.
+func walletCreateWatchOnlyHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if r.Header.Get("Content-Type") != ContentTypeJSON {
+			resp := NewHTTPErrorResponse(http.StatusUnsupportedMediaType, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		var req WatchOnlyWalletCreateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		addrs, err := decodeWatchOnlyAddresses(req.Addresses)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		wlt, err := gateway.CreateWatchOnlyWallet("", req.Label, addrs)
+		writeWatchOnlyWalletResponse(w, wlt, err)
+	}
+}
+
+// WatchOnlyAddressesRequest is the request data for POST /api/v2/wallet/watch-only/add
+type WatchOnlyAddressesRequest struct {
+	ID        string   `json:"id"`
+	Addresses []string `json:"addresses"`
+}
+
+// URI: /api/v2/wallet/watch-only/add
+// Method: POST
+// Args:
+//  id: wallet id
+//  addresses: addresses to watch
+// Adds addresses to a watch-only wallet. Addresses that are in the wallet already are ignored.
+func walletAddWatchOnlyAddressesHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if r.Header.Get("Content-Type") != ContentTypeJSON {
+			resp := NewHTTPErrorResponse(http.StatusUnsupportedMediaType, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		var req WatchOnlyAddressesRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if req.ID == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "id is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		addrs, err := decodeWatchOnlyAddresses(req.Addresses)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		wlt, err := gateway.AddWatchOnlyAddresses(req.ID, addrs)
+		writeWatchOnlyWalletResponse(w, wlt, err)
+	}
+}
+
+// MultisigRequest is the request data for POST /api/v2/wallet/multisig/add
+type MultisigRequest struct {
+	ID         string   `json:"id"`
+	M          int      `json:"m"`
+	PublicKeys []string `json:"public_keys"`
+}
+
+// URI: /api/v2/wallet/multisig/add
+// Method: POST
+// Args:
+//  id: wallet id
+//  m: number of signatures required
+//  public_keys: public keys of the multisig
+// Adds the address of an m-of-n multisig to a watch-only wallet. A multisig that is in the wallet already is ignored.
+func walletAddMultisigHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if r.Header.Get("Content-Type") != ContentTypeJSON {
+			resp := NewHTTPErrorResponse(http.StatusUnsupportedMediaType, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		var req MultisigRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if req.ID == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "id is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if len(req.PublicKeys) == 0 {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "public_keys is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		pks := make([]cipher.PubKey, len(req.PublicKeys))
+		for i, s := range req.PublicKeys {
+			pk, err := cipher.PubKeyFromHex(s)
+			if err != nil {
+				resp := NewHTTPErrorResponse(http.StatusBadRequest, fmt.Sprintf("public key %q is invalid: %v", s, err))
+				writeHTTPResponse(w, resp)
+				return
+			}
+			pks[i] = pk
+		}
+
+		ms, err := cipher.NewMultisig(req.M, pks)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		wlt, err := gateway.AddMultisig(req.ID, ms)
+		writeWatchOnlyWalletResponse(w, wlt, err)
+	}
+}
+
+func decodeWatchOnlyAddresses(addrStrs []string) ([]cipher.Address, error) {
+	if len(addrStrs) == 0 {
+		return nil, errors.New("addresses is required")
+	}
+
+	addrs := make([]cipher.Address, len(addrStrs))
+	for i, a := range addrStrs {
+		addr, err := cipher.DecodeBase58Address(a)
+		if err != nil {
+			return nil, fmt.Errorf("address %q is invalid: %v", a, err)
+		}
+		addrs[i] = addr
+	}
+
+	return addrs, nil
+}
+
+func writeWatchOnlyWalletResponse(w http.ResponseWriter, wlt *wallet.Wallet, err error) {
+	if err != nil {
+		var resp HTTPResponse
+		switch err {
+		case wallet.ErrWalletNotExist:
+			resp = NewHTTPErrorResponse(http.StatusNotFound, "")
+		case wallet.ErrWalletAPIDisabled:
+			resp = NewHTTPErrorResponse(http.StatusForbidden, "")
+		default:
+			switch err.(type) {
+			case wallet.Error:
+				resp = NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			default:
+				resp = NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			}
+		}
+		writeHTTPResponse(w, resp)
+		return
+	}
+
+	rlt, err := NewWalletResponse(wlt)
+	if err != nil {
+		resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+		writeHTTPResponse(w, resp)
+		return
+	}
+
+	writeHTTPResponse(w, HTTPResponse{
+		Data: rlt,
+	})
+}
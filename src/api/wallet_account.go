@@ -0,0 +1,175 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+// WalletAccountResponse is an account of a wallet, with its addresses
+type WalletAccountResponse struct {
+	Index     uint32   `json:"index"`
+	Name      string   `json:"name"`
+	Addresses []string `json:"addresses"`
+}
+
+// NewWalletAccountResponse creates a WalletAccountResponse for an account of a wallet
+func NewWalletAccountResponse(w *wallet.Wallet, a wallet.Account) (*WalletAccountResponse, error) {
+	addrs, err := w.AccountAddresses(a.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	strs := make([]string, len(addrs))
+	for i, addr := range addrs {
+		strs[i] = addr.String()
+	}
+
+	return &WalletAccountResponse{
+		Index:     a.Index,
+		Name:      a.Name,
+		Addresses: strs,
+	}, nil
+}
+
+// WalletAccountsResponse is the response data for GET /api/v2/wallet/accounts
+type WalletAccountsResponse struct {
+	Accounts []WalletAccountResponse `json:"accounts"`
+}
+
+// CreateWalletAccountRequest is the request data for POST /api/v2/wallet/accounts/create
+type CreateWalletAccountRequest struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Password string `json:"password"`
+}
+
+// URI: /api/v2/wallet/accounts
+// Method: GET
+// Args:
+//  id: wallet id
+// Returns the accounts of a wallet with their addresses, starting with the default account
+func walletAccountsHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		wltID := r.FormValue("id")
+		if wltID == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "id is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		wlt, err := gateway.GetWallet(wltID)
+		if err != nil {
+			writeWalletAccountError(w, err)
+			return
+		}
+
+		accounts := wlt.ListAccounts()
+		rsp := WalletAccountsResponse{
+			Accounts: make([]WalletAccountResponse, len(accounts)),
+		}
+		for i, a := range accounts {
+			ar, err := NewWalletAccountResponse(wlt, a)
+			if err != nil {
+				writeWalletAccountError(w, err)
+				return
+			}
+			rsp.Accounts[i] = *ar
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: rsp,
+		})
+	}
+}
+
+// URI: /api/v2/wallet/accounts/create
+// Method: POST
+// Content-Type: application/json
+// Args: JSON body:
+//  id: wallet id
+//  name: name of the new account
+//  password: [optional] wallet password, required for encrypted wallets
+// Adds a named account to a wallet, with its own derivation branch of the wallet seed,
+// and generates its first address. Returns the account.
+func walletCreateAccountHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if r.Header.Get("Content-Type") != ContentTypeJSON {
+			resp := NewHTTPErrorResponse(http.StatusUnsupportedMediaType, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		var req CreateWalletAccountRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if req.ID == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "id is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if req.Name == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "name is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		a, err := gateway.CreateWalletAccount(req.ID, req.Name, []byte(req.Password))
+		if err != nil {
+			writeWalletAccountError(w, err)
+			return
+		}
+
+		wlt, err := gateway.GetWallet(req.ID)
+		if err != nil {
+			writeWalletAccountError(w, err)
+			return
+		}
+
+		rsp, err := NewWalletAccountResponse(wlt, a)
+		if err != nil {
+			writeWalletAccountError(w, err)
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: rsp,
+		})
+	}
+}
+
+func writeWalletAccountError(w http.ResponseWriter, err error) {
+	var resp HTTPResponse
+	switch err {
+	case wallet.ErrWalletNotExist:
+		resp = NewHTTPErrorResponse(http.StatusNotFound, "")
+	case wallet.ErrWalletAPIDisabled:
+		resp = NewHTTPErrorResponse(http.StatusForbidden, "")
+	default:
+		switch err.(type) {
+		case wallet.Error:
+			resp = NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+		default:
+			resp = NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+		}
+	}
+	writeHTTPResponse(w, resp)
+}
@@ -0,0 +1,271 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+func makeAccountsWallet(t *testing.T) *wallet.Wallet {
+	w, err := wallet.NewWallet("foo.wlt", wallet.Options{
+		Seed: "seed",
+	})
+	require.NoError(t, err)
+	_, err = w.CreateAccount("savings")
+	require.NoError(t, err)
+	return w
+}
+
+func TestWalletAccountsHandler(t *testing.T) {
+	w := makeAccountsWallet(t)
+	defaultAddrs, err := w.AccountAddresses(wallet.DefaultAccount)
+	require.NoError(t, err)
+	savingsAddrs, err := w.AccountAddresses("savings")
+	require.NoError(t, err)
+
+	cases := []struct {
+		name       string
+		method     string
+		id         string
+		gatewayRsp *wallet.Wallet
+		gatewayErr error
+		status     int
+		err        string
+		rsp        *WalletAccountsResponse
+	}{
+		{
+			name:   "405",
+			method: http.MethodPost,
+			status: http.StatusMethodNotAllowed,
+			err:    "Method Not Allowed",
+		},
+		{
+			name:   "id missing",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "id is required",
+		},
+		{
+			name:       "wallet not exist",
+			method:     http.MethodGet,
+			id:         "foo.wlt",
+			gatewayErr: wallet.ErrWalletNotExist,
+			status:     http.StatusNotFound,
+			err:        "Not Found",
+		},
+		{
+			name:       "wallet api disabled",
+			method:     http.MethodGet,
+			id:         "foo.wlt",
+			gatewayErr: wallet.ErrWalletAPIDisabled,
+			status:     http.StatusForbidden,
+			err:        "Forbidden",
+		},
+		{
+			name:       "accounts",
+			method:     http.MethodGet,
+			id:         "foo.wlt",
+			gatewayRsp: w,
+			status:     http.StatusOK,
+			rsp: &WalletAccountsResponse{
+				Accounts: []WalletAccountResponse{
+					{
+						Index:     0,
+						Name:      wallet.DefaultAccount,
+						Addresses: []string{defaultAddrs[0].String()},
+					},
+					{
+						Index:     1,
+						Name:      "savings",
+						Addresses: []string{savingsAddrs[0].String()},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("GetWallet", tc.id).Return(tc.gatewayRsp, tc.gatewayErr)
+
+			endpoint := "/api/v2/wallet/accounts"
+			if tc.id != "" {
+				endpoint += "?" + url.Values{"id": []string{tc.id}}.Encode()
+			}
+
+			req, err := http.NewRequest(tc.method, endpoint, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code, "got `%v` want `%v`", rr.Code, tc.status)
+
+			var rsp ReceivedHTTPResponse
+			err = json.NewDecoder(rr.Body).Decode(&rsp)
+			require.NoError(t, err)
+
+			if tc.status != http.StatusOK {
+				require.NotNil(t, rsp.Error)
+				require.Equal(t, tc.err, rsp.Error.Message)
+				return
+			}
+
+			require.Nil(t, rsp.Error)
+			var accounts WalletAccountsResponse
+			err = json.Unmarshal(rsp.Data, &accounts)
+			require.NoError(t, err)
+			require.Equal(t, *tc.rsp, accounts)
+		})
+	}
+}
+
+func TestWalletCreateAccountHandler(t *testing.T) {
+	w := makeAccountsWallet(t)
+	savingsAddrs, err := w.AccountAddresses("savings")
+	require.NoError(t, err)
+
+	cases := []struct {
+		name        string
+		method      string
+		contentType string
+		req         CreateWalletAccountRequest
+		gatewayRsp  wallet.Account
+		gatewayErr  error
+		status      int
+		err         string
+		rsp         *WalletAccountResponse
+	}{
+		{
+			name:   "405",
+			method: http.MethodGet,
+			status: http.StatusMethodNotAllowed,
+			err:    "Method Not Allowed",
+		},
+		{
+			name:        "415",
+			method:      http.MethodPost,
+			contentType: ContentTypeForm,
+			status:      http.StatusUnsupportedMediaType,
+			err:         "Unsupported Media Type",
+		},
+		{
+			name:   "id missing",
+			method: http.MethodPost,
+			req: CreateWalletAccountRequest{
+				Name: "savings",
+			},
+			status: http.StatusBadRequest,
+			err:    "id is required",
+		},
+		{
+			name:   "name missing",
+			method: http.MethodPost,
+			req: CreateWalletAccountRequest{
+				ID: "foo.wlt",
+			},
+			status: http.StatusBadRequest,
+			err:    "name is required",
+		},
+		{
+			name:   "wallet not exist",
+			method: http.MethodPost,
+			req: CreateWalletAccountRequest{
+				ID:   "foo.wlt",
+				Name: "savings",
+			},
+			gatewayErr: wallet.ErrWalletNotExist,
+			status:     http.StatusNotFound,
+			err:        "Not Found",
+		},
+		{
+			name:   "account exists",
+			method: http.MethodPost,
+			req: CreateWalletAccountRequest{
+				ID:   "foo.wlt",
+				Name: "savings",
+			},
+			gatewayErr: wallet.ErrAccountExists,
+			status:     http.StatusBadRequest,
+			err:        "account already exists",
+		},
+		{
+			name:   "missing password",
+			method: http.MethodPost,
+			req: CreateWalletAccountRequest{
+				ID:   "foo.wlt",
+				Name: "savings",
+			},
+			gatewayErr: wallet.ErrMissingPassword,
+			status:     http.StatusBadRequest,
+			err:        "missing password",
+		},
+		{
+			name:   "create",
+			method: http.MethodPost,
+			req: CreateWalletAccountRequest{
+				ID:       "foo.wlt",
+				Name:     "savings",
+				Password: "pwd",
+			},
+			gatewayRsp: wallet.Account{
+				Index: 1,
+				Name:  "savings",
+			},
+			status: http.StatusOK,
+			rsp: &WalletAccountResponse{
+				Index:     1,
+				Name:      "savings",
+				Addresses: []string{savingsAddrs[0].String()},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("CreateWalletAccount", tc.req.ID, tc.req.Name, []byte(tc.req.Password)).Return(tc.gatewayRsp, tc.gatewayErr)
+			gateway.On("GetWallet", tc.req.ID).Return(w, nil)
+
+			contentType := tc.contentType
+			if contentType == "" {
+				contentType = ContentTypeJSON
+			}
+
+			req, err := http.NewRequest(tc.method, "/api/v2/wallet/accounts/create", strings.NewReader(toJSON(t, tc.req)))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", contentType)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code, "got `%v` want `%v`", rr.Code, tc.status)
+
+			var rsp ReceivedHTTPResponse
+			err = json.NewDecoder(rr.Body).Decode(&rsp)
+			require.NoError(t, err)
+
+			if tc.status != http.StatusOK {
+				require.NotNil(t, rsp.Error)
+				require.Equal(t, tc.err, rsp.Error.Message)
+				return
+			}
+
+			require.Nil(t, rsp.Error)
+			var account WalletAccountResponse
+			err = json.Unmarshal(rsp.Data, &account)
+			require.NoError(t, err)
+			require.Equal(t, *tc.rsp, account)
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+// WalletBackupRequest is the request data for POST /api/v2/wallet/backup
+type WalletBackupRequest struct {
+	IDs        []string `json:"ids"`
+	Passphrase string   `json:"passphrase"`
+}
+
+// WalletRestoreRequest is the request data for POST /api/v2/wallet/restore
+type WalletRestoreRequest struct {
+	Archive       *wallet.BackupArchive `json:"archive"`
+	Passphrase    string                `json:"passphrase"`
+	SkipConflicts bool                  `json:"skip_conflicts"`
+}
+
+// WalletRestoreConflict is a wallet of a backup archive that conflicts with an existing wallet
+type WalletRestoreConflict struct {
+	ID         string `json:"id"`
+	Reason     string `json:"reason"`
+	ExistingID string `json:"existing_id"`
+}
+
+// WalletRestoreResponse is the response data for POST /api/v2/wallet/restore
+type WalletRestoreResponse struct {
+	Restored []string                `json:"restored"`
+	Skipped  []WalletRestoreConflict `json:"skipped"`
+}
+
+// NewWalletRestoreResponse creates a WalletRestoreResponse from a wallet.RestoreResult
+func NewWalletRestoreResponse(r *wallet.RestoreResult) *WalletRestoreResponse {
+	rsp := &WalletRestoreResponse{
+		Restored: r.Restored,
+		Skipped:  make([]WalletRestoreConflict, len(r.Skipped)),
+	}
+	if rsp.Restored == nil {
+		rsp.Restored = []string{}
+	}
+
+	for i, c := range r.Skipped {
+		rsp.Skipped[i] = WalletRestoreConflict{
+			ID:         c.ID,
+			Reason:     string(c.Reason),
+			ExistingID: c.ExistingID,
+		}
+	}
+
+	return rsp
+}
+
+// URI: /api/v2/wallet/backup
+// Method: POST
+// Content-Type: application/json
+// Args: JSON body:
+//  ids: [optional] ids of the wallets to back up, all the wallets by default
+//  passphrase: passphrase that encrypts the backup archive
+// Returns a backup archive of the wallets, encrypted with the passphrase
+func walletBackupHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if r.Header.Get("Content-Type") != ContentTypeJSON {
+			resp := NewHTTPErrorResponse(http.StatusUnsupportedMediaType, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		var req WalletBackupRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if req.Passphrase == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "passphrase is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		a, err := gateway.BackupWallets(req.IDs, []byte(req.Passphrase))
+		if err != nil {
+			writeWalletBackupError(w, err)
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: a,
+		})
+	}
+}
+
+// URI: /api/v2/wallet/restore
+// Method: POST
+// Content-Type: application/json
+// Args: JSON body:
+//  archive: backup archive, as returned by /api/v2/wallet/backup
+//  passphrase: passphrase of the backup archive
+//  skip_conflicts: [optional] restore the wallets that don't conflict with existing wallets
+// Restores the wallets of a backup archive. Returns the restored and the skipped wallets.
+func walletRestoreHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if r.Header.Get("Content-Type") != ContentTypeJSON {
+			resp := NewHTTPErrorResponse(http.StatusUnsupportedMediaType, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		var req WalletRestoreRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if req.Archive == nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "archive is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if req.Passphrase == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "passphrase is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		result, err := gateway.RestoreWallets(*req.Archive, []byte(req.Passphrase), req.SkipConflicts)
+		if err != nil {
+			writeWalletBackupError(w, err)
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: NewWalletRestoreResponse(result),
+		})
+	}
+}
+
+func writeWalletBackupError(w http.ResponseWriter, err error) {
+	var resp HTTPResponse
+	switch err {
+	case wallet.ErrWalletNotExist:
+		resp = NewHTTPErrorResponse(http.StatusNotFound, "")
+	case wallet.ErrWalletAPIDisabled, wallet.ErrSeedAPIDisabled:
+		resp = NewHTTPErrorResponse(http.StatusForbidden, "")
+	default:
+		switch err.(type) {
+		case wallet.Error:
+			resp = NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+		default:
+			resp = NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+		}
+	}
+	writeHTTPResponse(w, resp)
+}
@@ -0,0 +1,302 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+func TestWalletBackupHandler(t *testing.T) {
+	archive := &wallet.BackupArchive{
+		Version:    wallet.BackupVersion,
+		Created:    1540000000,
+		CryptoType: wallet.CryptoTypeScryptChacha20poly1305,
+		Data:       "ZW5jcnlwdGVk",
+	}
+
+	cases := []struct {
+		name        string
+		method      string
+		contentType string
+		req         WalletBackupRequest
+		gatewayRsp  *wallet.BackupArchive
+		gatewayErr  error
+		status      int
+		err         string
+	}{
+		{
+			name:   "405",
+			method: http.MethodGet,
+			status: http.StatusMethodNotAllowed,
+			err:    "Method Not Allowed",
+		},
+		{
+			name:        "415",
+			method:      http.MethodPost,
+			contentType: ContentTypeForm,
+			status:      http.StatusUnsupportedMediaType,
+			err:         "Unsupported Media Type",
+		},
+		{
+			name:   "passphrase missing",
+			method: http.MethodPost,
+			req: WalletBackupRequest{
+				IDs: []string{"foo.wlt"},
+			},
+			status: http.StatusBadRequest,
+			err:    "passphrase is required",
+		},
+		{
+			name:   "wallet not exist",
+			method: http.MethodPost,
+			req: WalletBackupRequest{
+				IDs:        []string{"foo.wlt"},
+				Passphrase: "pass",
+			},
+			gatewayErr: wallet.ErrWalletNotExist,
+			status:     http.StatusNotFound,
+			err:        "Not Found",
+		},
+		{
+			name:   "seed api disabled",
+			method: http.MethodPost,
+			req: WalletBackupRequest{
+				Passphrase: "pass",
+			},
+			gatewayErr: wallet.ErrSeedAPIDisabled,
+			status:     http.StatusForbidden,
+			err:        "Forbidden",
+		},
+		{
+			name:   "wallet api disabled",
+			method: http.MethodPost,
+			req: WalletBackupRequest{
+				Passphrase: "pass",
+			},
+			gatewayErr: wallet.ErrWalletAPIDisabled,
+			status:     http.StatusForbidden,
+			err:        "Forbidden",
+		},
+		{
+			name:   "internal error",
+			method: http.MethodPost,
+			req: WalletBackupRequest{
+				Passphrase: "pass",
+			},
+			gatewayErr: errors.New("encrypt failed"),
+			status:     http.StatusInternalServerError,
+			err:        "encrypt failed",
+		},
+		{
+			name:   "backup",
+			method: http.MethodPost,
+			req: WalletBackupRequest{
+				IDs:        []string{"foo.wlt", "bar.wlt"},
+				Passphrase: "pass",
+			},
+			gatewayRsp: archive,
+			status:     http.StatusOK,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("BackupWallets", tc.req.IDs, []byte(tc.req.Passphrase)).Return(tc.gatewayRsp, tc.gatewayErr)
+
+			contentType := tc.contentType
+			if contentType == "" {
+				contentType = ContentTypeJSON
+			}
+
+			req, err := http.NewRequest(tc.method, "/api/v2/wallet/backup", strings.NewReader(toJSON(t, tc.req)))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", contentType)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code, "got `%v` want `%v`", rr.Code, tc.status)
+
+			var rsp ReceivedHTTPResponse
+			err = json.NewDecoder(rr.Body).Decode(&rsp)
+			require.NoError(t, err)
+
+			if tc.status != http.StatusOK {
+				require.NotNil(t, rsp.Error)
+				require.Equal(t, tc.err, rsp.Error.Message)
+				return
+			}
+
+			require.Nil(t, rsp.Error)
+			var a wallet.BackupArchive
+			err = json.Unmarshal(rsp.Data, &a)
+			require.NoError(t, err)
+			require.Equal(t, *tc.gatewayRsp, a)
+		})
+	}
+}
+
+func TestWalletRestoreHandler(t *testing.T) {
+	archive := &wallet.BackupArchive{
+		Version:    wallet.BackupVersion,
+		Created:    1540000000,
+		CryptoType: wallet.CryptoTypeScryptChacha20poly1305,
+		Data:       "ZW5jcnlwdGVk",
+	}
+
+	cases := []struct {
+		name        string
+		method      string
+		contentType string
+		req         WalletRestoreRequest
+		gatewayRsp  *wallet.RestoreResult
+		gatewayErr  error
+		status      int
+		err         string
+		rsp         *WalletRestoreResponse
+	}{
+		{
+			name:   "405",
+			method: http.MethodGet,
+			status: http.StatusMethodNotAllowed,
+			err:    "Method Not Allowed",
+		},
+		{
+			name:        "415",
+			method:      http.MethodPost,
+			contentType: ContentTypeForm,
+			status:      http.StatusUnsupportedMediaType,
+			err:         "Unsupported Media Type",
+		},
+		{
+			name:   "archive missing",
+			method: http.MethodPost,
+			req: WalletRestoreRequest{
+				Passphrase: "pass",
+			},
+			status: http.StatusBadRequest,
+			err:    "archive is required",
+		},
+		{
+			name:   "passphrase missing",
+			method: http.MethodPost,
+			req: WalletRestoreRequest{
+				Archive: archive,
+			},
+			status: http.StatusBadRequest,
+			err:    "passphrase is required",
+		},
+		{
+			name:   "invalid passphrase",
+			method: http.MethodPost,
+			req: WalletRestoreRequest{
+				Archive:    archive,
+				Passphrase: "pass",
+			},
+			gatewayErr: wallet.ErrInvalidBackupPassphrase,
+			status:     http.StatusBadRequest,
+			err:        "invalid backup passphrase or corrupted backup archive",
+		},
+		{
+			name:   "conflicts",
+			method: http.MethodPost,
+			req: WalletRestoreRequest{
+				Archive:    archive,
+				Passphrase: "pass",
+			},
+			gatewayErr: wallet.NewError(errors.New("backup conflicts with existing wallets: foo.wlt already exists")),
+			status:     http.StatusBadRequest,
+			err:        "backup conflicts with existing wallets: foo.wlt already exists",
+		},
+		{
+			name:   "wallet api disabled",
+			method: http.MethodPost,
+			req: WalletRestoreRequest{
+				Archive:    archive,
+				Passphrase: "pass",
+			},
+			gatewayErr: wallet.ErrWalletAPIDisabled,
+			status:     http.StatusForbidden,
+			err:        "Forbidden",
+		},
+		{
+			name:   "restore skip conflicts",
+			method: http.MethodPost,
+			req: WalletRestoreRequest{
+				Archive:       archive,
+				Passphrase:    "pass",
+				SkipConflicts: true,
+			},
+			gatewayRsp: &wallet.RestoreResult{
+				Restored: []string{"bar.wlt"},
+				Skipped: []wallet.RestoreConflict{
+					{
+						ID:         "foo.wlt",
+						Reason:     wallet.RestoreConflictSeedUsed,
+						ExistingID: "baz.wlt",
+					},
+				},
+			},
+			status: http.StatusOK,
+			rsp: &WalletRestoreResponse{
+				Restored: []string{"bar.wlt"},
+				Skipped: []WalletRestoreConflict{
+					{
+						ID:         "foo.wlt",
+						Reason:     "seed_used",
+						ExistingID: "baz.wlt",
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			if tc.req.Archive != nil {
+				gateway.On("RestoreWallets", *tc.req.Archive, []byte(tc.req.Passphrase), tc.req.SkipConflicts).Return(tc.gatewayRsp, tc.gatewayErr)
+			}
+
+			contentType := tc.contentType
+			if contentType == "" {
+				contentType = ContentTypeJSON
+			}
+
+			req, err := http.NewRequest(tc.method, "/api/v2/wallet/restore", strings.NewReader(toJSON(t, tc.req)))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", contentType)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code, "got `%v` want `%v`", rr.Code, tc.status)
+
+			var rsp ReceivedHTTPResponse
+			err = json.NewDecoder(rr.Body).Decode(&rsp)
+			require.NoError(t, err)
+
+			if tc.status != http.StatusOK {
+				require.NotNil(t, rsp.Error)
+				require.Equal(t, tc.err, rsp.Error.Message)
+				return
+			}
+
+			require.Nil(t, rsp.Error)
+			var result WalletRestoreResponse
+			err = json.Unmarshal(rsp.Data, &result)
+			require.NoError(t, err)
+			require.Equal(t, *tc.rsp, result)
+		})
+	}
+}
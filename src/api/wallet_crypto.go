@@ -0,0 +1,117 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+// ScryptParams are the scrypt key derivation parameters of a wallet
+type ScryptParams struct {
+	N      int `json:"n"`
+	R      int `json:"r"`
+	P      int `json:"p"`
+	KeyLen int `json:"key_len"`
+}
+
+// Argon2idParams are the argon2id key derivation parameters of a wallet
+type Argon2idParams struct {
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"`
+	Threads uint8  `json:"threads"`
+	KeyLen  uint32 `json:"key_len"`
+}
+
+// WalletCryptoResponse is the response data for GET /api/v2/wallet/crypto
+type WalletCryptoResponse struct {
+	CryptoType string          `json:"crypto_type"`
+	KDF        string          `json:"kdf"`
+	Scrypt     *ScryptParams   `json:"scrypt,omitempty"`
+	Argon2id   *Argon2idParams `json:"argon2id,omitempty"`
+}
+
+// NewWalletCryptoResponse creates a WalletCryptoResponse from wallet.KDFParams
+func NewWalletCryptoResponse(p *wallet.KDFParams) *WalletCryptoResponse {
+	rsp := &WalletCryptoResponse{
+		CryptoType: string(p.CryptoType),
+		KDF:        p.KDF(),
+	}
+
+	if p.Scrypt != nil {
+		rsp.Scrypt = &ScryptParams{
+			N:      p.Scrypt.N,
+			R:      p.Scrypt.R,
+			P:      p.Scrypt.P,
+			KeyLen: p.Scrypt.KeyLen,
+		}
+	}
+
+	if p.Argon2id != nil {
+		rsp.Argon2id = &Argon2idParams{
+			Time:    p.Argon2id.Time,
+			Memory:  p.Argon2id.Memory,
+			Threads: p.Argon2id.Threads,
+			KeyLen:  p.Argon2id.KeyLen,
+		}
+	}
+
+	return rsp
+}
+
+// URI: /api/v2/wallet/crypto
+// Method: GET
+// Args:
+//  id: wallet id
+// Returns the crypto type and the key derivation parameters of an encrypted wallet.
+// Wallets encrypted with an older crypto type are upgraded to the crypto type of the node
+// the next time they are unlocked, this endpoint can be used to check whether it happened.
+func walletCryptoHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		wltID := r.FormValue("id")
+		if wltID == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "id is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		wlt, err := gateway.GetWallet(wltID)
+		if err != nil {
+			writeWalletCryptoError(w, err)
+			return
+		}
+
+		p, err := wlt.KDFParams()
+		if err != nil {
+			writeWalletCryptoError(w, err)
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: NewWalletCryptoResponse(p),
+		})
+	}
+}
+
+func writeWalletCryptoError(w http.ResponseWriter, err error) {
+	var resp HTTPResponse
+	switch err {
+	case wallet.ErrWalletNotExist:
+		resp = NewHTTPErrorResponse(http.StatusNotFound, "")
+	case wallet.ErrWalletAPIDisabled:
+		resp = NewHTTPErrorResponse(http.StatusForbidden, "")
+	default:
+		switch err.(type) {
+		case wallet.Error:
+			resp = NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+		default:
+			resp = NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+		}
+	}
+	writeHTTPResponse(w, resp)
+}
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher/encrypt"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+func TestWalletCryptoHandler(t *testing.T) {
+	newWallet := func(opts wallet.Options) *wallet.Wallet {
+		opts.Seed = "seed"
+		opts.Label = "label"
+		w, err := wallet.NewWallet("foo.wlt", opts)
+		require.NoError(t, err)
+		return w
+	}
+
+	argon2id := encrypt.Argon2idChacha20poly1305{
+		Time:    1,
+		Memory:  64,
+		Threads: 1,
+		KeyLen:  encrypt.Argon2idKeyLen,
+	}
+
+	cases := []struct {
+		name       string
+		method     string
+		id         string
+		status     int
+		err        string
+		gatewayRsp *wallet.Wallet
+		gatewayErr error
+		rsp        WalletCryptoResponse
+	}{
+		{
+			name:   "405",
+			method: http.MethodPost,
+			status: http.StatusMethodNotAllowed,
+			err:    "Method Not Allowed",
+		},
+		{
+			name:   "id missing",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "id is required",
+		},
+		{
+			name:       "wallet not exist",
+			method:     http.MethodGet,
+			id:         "foo.wlt",
+			gatewayErr: wallet.ErrWalletNotExist,
+			status:     http.StatusNotFound,
+			err:        "Not Found",
+		},
+		{
+			name:       "wallet api disabled",
+			method:     http.MethodGet,
+			id:         "foo.wlt",
+			gatewayErr: wallet.ErrWalletAPIDisabled,
+			status:     http.StatusForbidden,
+			err:        "Forbidden",
+		},
+		{
+			name:       "wallet not encrypted",
+			method:     http.MethodGet,
+			id:         "foo.wlt",
+			gatewayRsp: newWallet(wallet.Options{}),
+			status:     http.StatusBadRequest,
+			err:        "wallet is not encrypted",
+		},
+		{
+			name:   "sha256-xor",
+			method: http.MethodGet,
+			id:     "foo.wlt",
+			gatewayRsp: newWallet(wallet.Options{
+				Encrypt:    true,
+				Password:   []byte("pwd"),
+				CryptoType: wallet.CryptoTypeSha256Xor,
+			}),
+			status: http.StatusOK,
+			rsp: WalletCryptoResponse{
+				CryptoType: string(wallet.CryptoTypeSha256Xor),
+				KDF:        "sha256",
+			},
+		},
+		{
+			name:   "scrypt-chacha20poly1305",
+			method: http.MethodGet,
+			id:     "foo.wlt",
+			gatewayRsp: newWallet(wallet.Options{
+				Encrypt:    true,
+				Password:   []byte("pwd"),
+				CryptoType: wallet.CryptoTypeScryptChacha20poly1305,
+			}),
+			status: http.StatusOK,
+			rsp: WalletCryptoResponse{
+				CryptoType: string(wallet.CryptoTypeScryptChacha20poly1305),
+				KDF:        "scrypt",
+				Scrypt: &ScryptParams{
+					N:      encrypt.ScryptN,
+					R:      encrypt.ScryptR,
+					P:      encrypt.ScryptP,
+					KeyLen: encrypt.ScryptKeyLen,
+				},
+			},
+		},
+		{
+			name:   "argon2id-chacha20poly1305",
+			method: http.MethodGet,
+			id:     "foo.wlt",
+			gatewayRsp: newWallet(wallet.Options{
+				Encrypt:    true,
+				Password:   []byte("pwd"),
+				CryptoType: wallet.CryptoTypeArgon2idChacha20poly1305,
+				Argon2id:   argon2id,
+			}),
+			status: http.StatusOK,
+			rsp: WalletCryptoResponse{
+				CryptoType: string(wallet.CryptoTypeArgon2idChacha20poly1305),
+				KDF:        "argon2id",
+				Argon2id: &Argon2idParams{
+					Time:    1,
+					Memory:  64,
+					Threads: 1,
+					KeyLen:  encrypt.Argon2idKeyLen,
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("GetWallet", tc.id).Return(tc.gatewayRsp, tc.gatewayErr)
+
+			endpoint := "/api/v2/wallet/crypto"
+			if tc.id != "" {
+				endpoint += "?" + url.Values{"id": []string{tc.id}}.Encode()
+			}
+
+			req, err := http.NewRequest(tc.method, endpoint, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code, "got `%v` want `%v`", rr.Code, tc.status)
+
+			var rsp ReceivedHTTPResponse
+			err = json.NewDecoder(rr.Body).Decode(&rsp)
+			require.NoError(t, err)
+
+			if tc.status != http.StatusOK {
+				require.NotNil(t, rsp.Error)
+				require.Equal(t, tc.err, rsp.Error.Message)
+				return
+			}
+
+			require.Nil(t, rsp.Error)
+			var crypto WalletCryptoResponse
+			err = json.Unmarshal(rsp.Data, &crypto)
+			require.NoError(t, err)
+			require.Equal(t, tc.rsp, crypto)
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/skycoin/skycoin/src/util/droplet"
+	wh "github.com/skycoin/skycoin/src/util/http"
+	"github.com/skycoin/skycoin/src/visor"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+const (
+	defaultWalletEventsLimit = 100
+	maxWalletEventsLimit     = 1000
+	// maxWalletEventsWait is below the default write timeout of the server
+	maxWalletEventsWait = 30 * time.Second
+)
+
+// WalletEvent is a transaction that sent coins to the addresses of a wallet
+type WalletEvent struct {
+	ID            uint64                `json:"id"`
+	Type          visor.WalletEventType `json:"type"`
+	WalletID      string                `json:"wallet_id"`
+	TxnID         string                `json:"txid"`
+	Amount        string                `json:"amount"`
+	Addresses     []string              `json:"addresses"`
+	Confirmations uint64                `json:"confirmations"`
+	BlockSeq      uint64                `json:"block_seq"`
+	Time          uint64                `json:"time"`
+}
+
+// NewWalletEvent creates a WalletEvent from a visor.WalletEvent
+func NewWalletEvent(e visor.WalletEvent) (WalletEvent, error) {
+	amount, err := droplet.ToString(e.Amount)
+	if err != nil {
+		return WalletEvent{}, err
+	}
+
+	addrs := make([]string, len(e.Addresses))
+	for i, a := range e.Addresses {
+		addrs[i] = a.String()
+	}
+
+	return WalletEvent{
+		ID:            e.ID,
+		Type:          e.Type,
+		WalletID:      e.WalletID,
+		TxnID:         e.TxnID.Hex(),
+		Amount:        amount,
+		Addresses:     addrs,
+		Confirmations: e.Confirmations,
+		BlockSeq:      e.BlockSeq,
+		Time:          e.Time,
+	}, nil
+}
+
+// WalletEventsResponse is returned by GET /api/v1/wallet/events
+type WalletEventsResponse struct {
+	Events []WalletEvent `json:"events"`
+}
+
+// walletEventsHandler returns the incoming funds events of a wallet, in the order they were recorded.
+// An event is recorded when a transaction sending coins to the addresses of the wallet is added to the unconfirmed pool,
+// and when it is executed in a block. The transactions that spend the outputs of the wallet are not recorded.
+// Clients poll for new events by passing the ID of the last event they received in after.
+// With wait, the request is held until new events are recorded, so clients are notified of them without polling.
+// Method: GET
+// URI: /api/v1/wallet/events?id=${id}&after=${after}&limit=${limit}&wait=${wait}
+// Args:
+//	id [wallet id, required]
+//	after [int, return the events with an ID greater than after, defaults to 0]
+//	limit [int, maximum number of events returned, defaults to 100, at most 1000]
+//	wait [int, seconds to wait for new events if there are none, defaults to 0, at most 30]
+func walletEventsHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			wh.Error405(w)
+			return
+		}
+
+		wltID := r.FormValue("id")
+		if wltID == "" {
+			wh.Error400(w, "missing wallet id")
+			return
+		}
+
+		var after uint64
+		if afterStr := r.FormValue("after"); afterStr != "" {
+			var err error
+			after, err = strconv.ParseUint(afterStr, 10, 64)
+			if err != nil {
+				wh.Error400(w, "invalid after")
+				return
+			}
+		}
+
+		limit := uint64(defaultWalletEventsLimit)
+		if limitStr := r.FormValue("limit"); limitStr != "" {
+			var err error
+			limit, err = strconv.ParseUint(limitStr, 10, 64)
+			if err != nil {
+				wh.Error400(w, "invalid limit")
+				return
+			}
+
+			if limit == 0 || limit > maxWalletEventsLimit {
+				wh.Error400(w, fmt.Sprintf("limit must be between 1 and %d", maxWalletEventsLimit))
+				return
+			}
+		}
+
+		var wait time.Duration
+		if waitStr := r.FormValue("wait"); waitStr != "" {
+			n, err := strconv.ParseUint(waitStr, 10, 64)
+			if err != nil {
+				wh.Error400(w, "invalid wait")
+				return
+			}
+
+			if n > uint64(maxWalletEventsWait/time.Second) {
+				wh.Error400(w, fmt.Sprintf("wait must be at most %d", maxWalletEventsWait/time.Second))
+				return
+			}
+			wait = time.Duration(n) * time.Second
+		}
+
+		var events []visor.WalletEvent
+		var err error
+		if wait == 0 {
+			events, err = gateway.GetWalletEvents(wltID, after, limit)
+		} else {
+			ctx, cancel := context.WithTimeout(r.Context(), wait)
+			events, err = gateway.WaitWalletEvents(ctx, wltID, after, limit)
+			cancel()
+		}
+		if err != nil {
+			switch err {
+			case wallet.ErrWalletNotExist:
+				wh.Error404(w, "")
+			case wallet.ErrWalletAPIDisabled:
+				wh.Error403(w, "")
+			default:
+				wh.Error500(w, err.Error())
+			}
+			return
+		}
+
+		resp := WalletEventsResponse{
+			Events: make([]WalletEvent, len(events)),
+		}
+		for i, e := range events {
+			resp.Events[i], err = NewWalletEvent(e)
+			if err != nil {
+				wh.Error500(w, err.Error())
+				return
+			}
+		}
+
+		wh.SendJSONOr500(logger, w, resp)
+	}
+}
@@ -0,0 +1,203 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+func TestWalletEvents(t *testing.T) {
+	addr := testutil.MakeAddress()
+	txnID := testutil.RandSHA256(t)
+
+	events := []visor.WalletEvent{
+		{
+			ID:            7,
+			Type:          visor.WalletEventConfirmed,
+			WalletID:      "foo.wlt",
+			TxnID:         txnID,
+			BlockSeq:      10,
+			Time:          1540000000,
+			Amount:        2500000,
+			Addresses:     []cipher.Address{addr},
+			Confirmations: 3,
+		},
+	}
+
+	result := WalletEventsResponse{
+		Events: []WalletEvent{
+			{
+				ID:            7,
+				Type:          visor.WalletEventConfirmed,
+				WalletID:      "foo.wlt",
+				TxnID:         txnID.Hex(),
+				Amount:        "2.500000",
+				Addresses:     []string{addr.String()},
+				Confirmations: 3,
+				BlockSeq:      10,
+				Time:          1540000000,
+			},
+		},
+	}
+
+	tt := []struct {
+		name          string
+		method        string
+		status        int
+		err           string
+		query         string
+		gatewayMethod string
+		after         uint64
+		limit         uint64
+		gatewayResult []visor.WalletEvent
+		gatewayErr    error
+		result        WalletEventsResponse
+	}{
+		{
+			name:   "405",
+			method: http.MethodPost,
+			status: http.StatusMethodNotAllowed,
+			err:    "405 Method Not Allowed",
+		},
+		{
+			name:   "400 - missing wallet id",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - missing wallet id",
+		},
+		{
+			name:   "400 - invalid after",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - invalid after",
+			query:  "id=foo.wlt&after=x",
+		},
+		{
+			name:   "400 - limit too large",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - limit must be between 1 and 1000",
+			query:  "id=foo.wlt&limit=1001",
+		},
+		{
+			name:   "400 - wait too long",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - wait must be at most 30",
+			query:  "id=foo.wlt&wait=31",
+		},
+		{
+			name:          "403 - wallet api disabled",
+			method:        http.MethodGet,
+			status:        http.StatusForbidden,
+			err:           "403 Forbidden",
+			query:         "id=foo.wlt",
+			gatewayMethod: "GetWalletEvents",
+			limit:         100,
+			gatewayErr:    wallet.ErrWalletAPIDisabled,
+		},
+		{
+			name:          "404 - wallet not exist",
+			method:        http.MethodGet,
+			status:        http.StatusNotFound,
+			err:           "404 Not Found",
+			query:         "id=foo.wlt",
+			gatewayMethod: "GetWalletEvents",
+			limit:         100,
+			gatewayErr:    wallet.ErrWalletNotExist,
+		},
+		{
+			name:          "500 - gateway error",
+			method:        http.MethodGet,
+			status:        http.StatusInternalServerError,
+			err:           "500 Internal Server Error - database not open",
+			query:         "id=foo.wlt",
+			gatewayMethod: "GetWalletEvents",
+			limit:         100,
+			gatewayErr:    errors.New("database not open"),
+		},
+		{
+			name:          "200 - no events",
+			method:        http.MethodGet,
+			status:        http.StatusOK,
+			query:         "id=foo.wlt",
+			gatewayMethod: "GetWalletEvents",
+			limit:         100,
+			result: WalletEventsResponse{
+				Events: []WalletEvent{},
+			},
+		},
+		{
+			name:          "200",
+			method:        http.MethodGet,
+			status:        http.StatusOK,
+			query:         "id=foo.wlt&after=6&limit=10",
+			gatewayMethod: "GetWalletEvents",
+			after:         6,
+			limit:         10,
+			gatewayResult: events,
+			result:        result,
+		},
+		{
+			name:          "200 - wait",
+			method:        http.MethodGet,
+			status:        http.StatusOK,
+			query:         "id=foo.wlt&after=6&wait=30",
+			gatewayMethod: "WaitWalletEvents",
+			after:         6,
+			limit:         100,
+			gatewayResult: events,
+			result:        result,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			endpoint := "/api/v1/wallet/events"
+			if tc.query != "" {
+				endpoint += "?" + tc.query
+			}
+
+			gateway := &MockGatewayer{}
+			switch tc.gatewayMethod {
+			case "GetWalletEvents":
+				gateway.On("GetWalletEvents", "foo.wlt", tc.after, tc.limit).Return(tc.gatewayResult, tc.gatewayErr)
+			case "WaitWalletEvents":
+				gateway.On("WaitWalletEvents", mock.Anything, "foo.wlt", tc.after, tc.limit).Return(tc.gatewayResult, tc.gatewayErr)
+			}
+
+			req, err := http.NewRequest(tc.method, endpoint, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			status := rr.Code
+			require.Equal(t, tc.status, status, "got `%v` want `%v`", status, tc.status)
+
+			if status != http.StatusOK {
+				require.Equal(t, tc.err, strings.TrimSpace(rr.Body.String()))
+				return
+			}
+
+			gateway.AssertExpectations(t)
+
+			var msg WalletEventsResponse
+			err = json.Unmarshal(rr.Body.Bytes(), &msg)
+			require.NoError(t, err)
+			require.Equal(t, tc.result, msg)
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	"github.com/skycoin/skycoin/src/wallet"
+	"github.com/skycoin/skycoin/src/wallet/history"
+)
+
+// WalletHistoryResponse is the response data for GET /api/v2/wallet/history/export with the json format
+type WalletHistoryResponse struct {
+	Entries []history.ReadableEntry `json:"entries"`
+}
+
+// URI: /api/v2/wallet/history/export
+// Method: GET
+// Args:
+//  id: wallet id
+//  account: [optional] name of an account of the wallet, to export the history of its addresses only
+//  format: [optional] "json" (default) or "csv"
+//  price_source: [optional] name of a registered price source, to annotate the entries with their fiat value
+// Returns the history of the confirmed transactions of a wallet, oldest first, with their direction, counterparties,
+// fee and the running balance of the wallet. The csv format is returned as a text/csv attachment.
+func walletHistoryExportHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		wltID := r.FormValue("id")
+		if wltID == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "id is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		format := history.Format(r.FormValue("format"))
+		switch format {
+		case "":
+			format = history.FormatJSON
+		case history.FormatJSON, history.FormatCSV:
+		default:
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "invalid format")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		var ps history.PriceSource
+		if name := r.FormValue("price_source"); name != "" {
+			var err error
+			ps, err = history.GetPriceSource(name)
+			if err != nil {
+				resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
+		}
+
+		entries, err := gateway.GetWalletHistory(wltID, r.FormValue("account"), ps)
+		if err != nil {
+			var resp HTTPResponse
+			switch err {
+			case wallet.ErrWalletNotExist:
+				resp = NewHTTPErrorResponse(http.StatusNotFound, "")
+			case wallet.ErrWalletAPIDisabled:
+				resp = NewHTTPErrorResponse(http.StatusForbidden, "")
+			case history.ErrNoPrice, wallet.ErrAccountNotExist:
+				resp = NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			default:
+				resp = NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			}
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if format == history.FormatJSON {
+			readableEntries, err := history.NewReadableEntries(entries)
+			if err != nil {
+				resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
+
+			writeHTTPResponse(w, HTTPResponse{
+				Data: WalletHistoryResponse{
+					Entries: readableEntries,
+				},
+			})
+			return
+		}
+
+		var buf bytes.Buffer
+		if err := history.WriteCSV(&buf, entries); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		w.Header().Set("Content-Type", ContentTypeCSV)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", wltID+".history.csv"))
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			logger.WithError(err).Error("http Write failed")
+		}
+	}
+}
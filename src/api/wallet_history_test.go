@@ -0,0 +1,223 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/wallet"
+	"github.com/skycoin/skycoin/src/wallet/history"
+)
+
+func TestWalletHistoryExportHandler(t *testing.T) {
+	prices := history.NewPriceTable("USD", nil)
+	history.RegisterPriceSource("api-test-prices", prices)
+
+	counterparty := testutil.MakeAddress()
+	txID := testutil.RandSHA256(t)
+	entries := []history.Entry{
+		{
+			TxID:           txID,
+			BlockSeq:       3,
+			Time:           1500000000,
+			Direction:      history.DirectionReceived,
+			Counterparties: []cipher.Address{counterparty},
+			Coins:          2e6,
+			Balance:        2e6,
+		},
+	}
+
+	fiatEntries := []history.Entry{entries[0]}
+	fiatEntries[0].Fiat = &history.FiatValue{
+		Currency: "USD",
+		Price:    decimal.New(3, 0),
+		Value:    decimal.New(6, 0),
+	}
+
+	cases := []struct {
+		name        string
+		method      string
+		id          string
+		account     string
+		format      string
+		priceSource string
+		gatewayPS   history.PriceSource
+		gatewayRsp  []history.Entry
+		gatewayErr  error
+		status      int
+		err         string
+		rsp         *WalletHistoryResponse
+		csv         string
+	}{
+		{
+			name:   "405",
+			method: http.MethodPost,
+			status: http.StatusMethodNotAllowed,
+			err:    "Method Not Allowed",
+		},
+		{
+			name:   "id missing",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "id is required",
+		},
+		{
+			name:   "invalid format",
+			method: http.MethodGet,
+			id:     "foo.wlt",
+			format: "xml",
+			status: http.StatusBadRequest,
+			err:    "invalid format",
+		},
+		{
+			name:        "price source not registered",
+			method:      http.MethodGet,
+			id:          "foo.wlt",
+			priceSource: "foo",
+			status:      http.StatusBadRequest,
+			err:         "price source is not registered",
+		},
+		{
+			name:       "wallet not exist",
+			method:     http.MethodGet,
+			id:         "foo.wlt",
+			gatewayErr: wallet.ErrWalletNotExist,
+			status:     http.StatusNotFound,
+			err:        "Not Found",
+		},
+		{
+			name:       "wallet api disabled",
+			method:     http.MethodGet,
+			id:         "foo.wlt",
+			gatewayErr: wallet.ErrWalletAPIDisabled,
+			status:     http.StatusForbidden,
+			err:        "Forbidden",
+		},
+		{
+			name:        "no price",
+			method:      http.MethodGet,
+			id:          "foo.wlt",
+			priceSource: "api-test-prices",
+			gatewayPS:   prices,
+			gatewayErr:  history.ErrNoPrice,
+			status:      http.StatusBadRequest,
+			err:         "no price is known at this time",
+		},
+		{
+			name:       "account not exist",
+			method:     http.MethodGet,
+			id:         "foo.wlt",
+			account:    "savings",
+			gatewayErr: wallet.ErrAccountNotExist,
+			status:     http.StatusBadRequest,
+			err:        "account does not exist",
+		},
+		{
+			name:       "gateway error",
+			method:     http.MethodGet,
+			id:         "foo.wlt",
+			gatewayErr: errors.New("failed"),
+			status:     http.StatusInternalServerError,
+			err:        "failed",
+		},
+		{
+			name:       "json",
+			method:     http.MethodGet,
+			id:         "foo.wlt",
+			account:    "savings",
+			gatewayRsp: entries,
+			status:     http.StatusOK,
+			rsp: &WalletHistoryResponse{
+				Entries: []history.ReadableEntry{
+					{
+						TxID:           txID.Hex(),
+						BlockSeq:       3,
+						Timestamp:      1500000000,
+						Direction:      history.DirectionReceived,
+						Counterparties: []string{counterparty.String()},
+						Coins:          "2.000000",
+						Balance:        "2.000000",
+					},
+				},
+			},
+		},
+		{
+			name:        "csv with price source",
+			method:      http.MethodGet,
+			id:          "foo.wlt",
+			format:      "csv",
+			priceSource: "api-test-prices",
+			gatewayPS:   prices,
+			gatewayRsp:  fiatEntries,
+			status:      http.StatusOK,
+			csv: "txid,block_seq,time,direction,counterparties,coins,fee,balance,fiat_currency,fiat_price,fiat_value\n" +
+				txID.Hex() + ",3,2017-07-14T02:40:00Z,received," + counterparty.String() + ",2.000000,0,2.000000,USD,3,6\n",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("GetWalletHistory", tc.id, tc.account, tc.gatewayPS).Return(tc.gatewayRsp, tc.gatewayErr)
+
+			v := url.Values{}
+			if tc.id != "" {
+				v.Add("id", tc.id)
+			}
+			if tc.account != "" {
+				v.Add("account", tc.account)
+			}
+			if tc.format != "" {
+				v.Add("format", tc.format)
+			}
+			if tc.priceSource != "" {
+				v.Add("price_source", tc.priceSource)
+			}
+
+			endpoint := "/api/v2/wallet/history/export"
+			if len(v) > 0 {
+				endpoint += "?" + v.Encode()
+			}
+
+			req, err := http.NewRequest(tc.method, endpoint, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code, "got `%v` want `%v`", rr.Code, tc.status)
+
+			if tc.csv != "" {
+				require.Equal(t, ContentTypeCSV, rr.Header().Get("Content-Type"))
+				require.Equal(t, `attachment; filename="foo.wlt.history.csv"`, rr.Header().Get("Content-Disposition"))
+				require.Equal(t, tc.csv, rr.Body.String())
+				return
+			}
+
+			var rsp ReceivedHTTPResponse
+			err = json.NewDecoder(rr.Body).Decode(&rsp)
+			require.NoError(t, err)
+
+			if tc.status != http.StatusOK {
+				require.NotNil(t, rsp.Error)
+				require.Equal(t, tc.err, rsp.Error.Message)
+				return
+			}
+
+			require.Nil(t, rsp.Error)
+			var historyRsp WalletHistoryResponse
+			err = json.Unmarshal(rsp.Data, &historyRsp)
+			require.NoError(t, err)
+			require.Equal(t, *tc.rsp, historyRsp)
+		})
+	}
+}
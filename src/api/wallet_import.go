@@ -0,0 +1,132 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+// WalletImportRequest is the request data for POST /api/v2/wallet/import
+type WalletImportRequest struct {
+	ID             string `json:"id,omitempty"`
+	Format         string `json:"format"`
+	Data           string `json:"data"`
+	Label          string `json:"label,omitempty"`
+	Num            uint64 `json:"num,omitempty"`
+	SeedPassphrase string `json:"seed_passphrase,omitempty"`
+	Encrypt        bool   `json:"encrypt,omitempty"`
+	Password       string `json:"password,omitempty"`
+}
+
+// URI: /api/v2/wallet/import
+// Method: POST
+// Content-Type: application/json
+// Args: JSON body:
+//  id: [optional] id of the wallet, a wallet file name ending with .wlt, generated by default
+//  format: format of data, "keys", "seed" or "keystore"
+//  data: private key list, seed phrase or JSON keystore to import
+//  label: [optional] wallet label
+//  num: [optional] number of addresses generated from a seed phrase, defaults to 1
+//  seed_passphrase: [optional] BIP39 passphrase of a BIP39 mnemonic seed phrase
+//  encrypt: [optional] encrypt the wallet
+//  password: [optional] wallet password, required if encrypt is true
+// Imports a wallet from a private key list, an Electrum seed or BIP39 mnemonic, or a JSON keystore.
+// A seed phrase creates a deterministic wallet, the private keys create a collection wallet.
+// The wallet is not imported if a wallet file has its id, or if a wallet already has one of its addresses.
+func walletImportHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if r.Header.Get("Content-Type") != ContentTypeJSON {
+			resp := NewHTTPErrorResponse(http.StatusUnsupportedMediaType, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		var req WalletImportRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		var password []byte
+		if req.Password != "" {
+			password = []byte(req.Password)
+		}
+
+		defer func() {
+			req.Data = ""
+			req.SeedPassphrase = ""
+			req.Password = ""
+			password = nil
+		}()
+
+		format, err := wallet.ImportFormatFromString(req.Format)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if req.Data == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "data is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if req.Encrypt && req.Password == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "password is required for encryption")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if !req.Encrypt && req.Password != "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "password is set but encrypt is false")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		wlt, err := gateway.ImportWallet(req.ID, wallet.ImportOptions{
+			Format:         format,
+			Data:           []byte(req.Data),
+			Label:          req.Label,
+			GenerateN:      req.Num,
+			SeedPassphrase: req.SeedPassphrase,
+			Encrypt:        req.Encrypt,
+			Password:       password,
+		})
+		if err != nil {
+			var resp HTTPResponse
+			switch err {
+			case wallet.ErrWalletAPIDisabled:
+				resp = NewHTTPErrorResponse(http.StatusForbidden, "")
+			default:
+				switch err.(type) {
+				case wallet.Error:
+					resp = NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+				default:
+					resp = NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+				}
+			}
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		rlt, err := NewWalletResponse(wlt)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: rlt,
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+func TestWalletImportHandler(t *testing.T) {
+	wlt, err := wallet.NewWallet("foo.wlt", wallet.Options{
+		Seed:  "wild father tree among universe such mobile favorite target dynamic credit away",
+		Label: "imported",
+	})
+	require.NoError(t, err)
+
+	wltRsp, err := NewWalletResponse(wlt)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name        string
+		method      string
+		contentType string
+		req         WalletImportRequest
+		opts        wallet.ImportOptions
+		gatewayRsp  *wallet.Wallet
+		gatewayErr  error
+		status      int
+		err         string
+		rsp         *WalletResponse
+	}{
+		{
+			name:   "405",
+			method: http.MethodGet,
+			status: http.StatusMethodNotAllowed,
+			err:    "Method Not Allowed",
+		},
+		{
+			name:        "415",
+			method:      http.MethodPost,
+			contentType: ContentTypeForm,
+			status:      http.StatusUnsupportedMediaType,
+			err:         "Unsupported Media Type",
+		},
+		{
+			name:   "unknown format",
+			method: http.MethodPost,
+			req: WalletImportRequest{
+				Format: "electrum",
+				Data:   "foo",
+			},
+			status: http.StatusBadRequest,
+			err:    "unknown import format, must be keys, seed or keystore",
+		},
+		{
+			name:   "data missing",
+			method: http.MethodPost,
+			req: WalletImportRequest{
+				Format: "keys",
+			},
+			status: http.StatusBadRequest,
+			err:    "data is required",
+		},
+		{
+			name:   "password missing",
+			method: http.MethodPost,
+			req: WalletImportRequest{
+				Format:  "keys",
+				Data:    "foo",
+				Encrypt: true,
+			},
+			status: http.StatusBadRequest,
+			err:    "password is required for encryption",
+		},
+		{
+			name:   "encrypt missing",
+			method: http.MethodPost,
+			req: WalletImportRequest{
+				Format:   "keys",
+				Data:     "foo",
+				Password: "pwd",
+			},
+			status: http.StatusBadRequest,
+			err:    "password is set but encrypt is false",
+		},
+		{
+			name:   "invalid data",
+			method: http.MethodPost,
+			req: WalletImportRequest{
+				Format: "keys",
+				Data:   "foo",
+			},
+			opts: wallet.ImportOptions{
+				Format: wallet.ImportFormatKeys,
+				Data:   []byte("foo"),
+			},
+			gatewayErr: wallet.NewError(errors.New("line 1: invalid private key")),
+			status:     http.StatusBadRequest,
+			err:        "line 1: invalid private key",
+		},
+		{
+			name:   "wallet exists",
+			method: http.MethodPost,
+			req: WalletImportRequest{
+				ID:     "foo.wlt",
+				Format: "seed",
+				Data:   "foo",
+			},
+			opts: wallet.ImportOptions{
+				Format: wallet.ImportFormatSeed,
+				Data:   []byte("foo"),
+			},
+			gatewayErr: wallet.ErrImportWalletExists,
+			status:     http.StatusBadRequest,
+			err:        "a wallet already exists with this id",
+		},
+		{
+			name:   "wallet api disabled",
+			method: http.MethodPost,
+			req: WalletImportRequest{
+				Format: "seed",
+				Data:   "foo",
+			},
+			opts: wallet.ImportOptions{
+				Format: wallet.ImportFormatSeed,
+				Data:   []byte("foo"),
+			},
+			gatewayErr: wallet.ErrWalletAPIDisabled,
+			status:     http.StatusForbidden,
+			err:        "Forbidden",
+		},
+		{
+			name:   "import seed",
+			method: http.MethodPost,
+			req: WalletImportRequest{
+				ID:       "foo.wlt",
+				Format:   "seed",
+				Data:     "wild father tree among universe such mobile favorite target dynamic credit away",
+				Label:    "imported",
+				Num:      1,
+				Encrypt:  true,
+				Password: "pwd",
+			},
+			opts: wallet.ImportOptions{
+				Format:    wallet.ImportFormatSeed,
+				Data:      []byte("wild father tree among universe such mobile favorite target dynamic credit away"),
+				Label:     "imported",
+				GenerateN: 1,
+				Encrypt:   true,
+				Password:  []byte("pwd"),
+			},
+			gatewayRsp: wlt,
+			status:     http.StatusOK,
+			rsp:        wltRsp,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			if tc.gatewayRsp != nil || tc.gatewayErr != nil {
+				gateway.On("ImportWallet", tc.req.ID, tc.opts).Return(tc.gatewayRsp, tc.gatewayErr)
+			}
+
+			contentType := tc.contentType
+			if contentType == "" {
+				contentType = ContentTypeJSON
+			}
+
+			req, err := http.NewRequest(tc.method, "/api/v2/wallet/import", strings.NewReader(toJSON(t, tc.req)))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", contentType)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code, "got `%v` want `%v`", rr.Code, tc.status)
+
+			var rsp ReceivedHTTPResponse
+			err = json.NewDecoder(rr.Body).Decode(&rsp)
+			require.NoError(t, err)
+
+			if tc.status != http.StatusOK {
+				require.NotNil(t, rsp.Error)
+				require.Equal(t, tc.err, rsp.Error.Message)
+				return
+			}
+
+			require.Nil(t, rsp.Error)
+			var result WalletResponse
+			err = json.Unmarshal(rsp.Data, &result)
+			require.NoError(t, err)
+			require.Equal(t, *tc.rsp, result)
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/skycoin/skycoin/src/readable"
+	"github.com/skycoin/skycoin/src/visor"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+// WalletRescanRequest is the request body of POST /api/v2/wallet/rescan
+type WalletRescanRequest struct {
+	ID       string `json:"id"`
+	Password string `json:"password"`
+	// GapLimit is the number of consecutive unused addresses that ends the extension of the wallet, 0 to not extend it
+	GapLimit uint64 `json:"gap_limit"`
+	// RecentTxns is the number of most recent transactions to return per address
+	RecentTxns uint64 `json:"recent_txns"`
+}
+
+// WalletRescanResponse is returned by POST /api/v2/wallet/rescan
+type WalletRescanResponse struct {
+	// NewAddresses are the addresses added to the wallet by the gap limit extension
+	NewAddresses []string `json:"new_addresses"`
+	// DiscoveredAddresses are the new addresses that have been used in the blockchain
+	DiscoveredAddresses []string `json:"discovered_addresses"`
+	// Balance is the confirmed balance of the wallet
+	Balance readable.Balance `json:"balance"`
+	// Addresses are the scans of the addresses of the wallet, in the order of the wallet
+	Addresses []readable.AddressScan `json:"addresses"`
+}
+
+// NewWalletRescanResponse creates a WalletRescanResponse from a visor.WalletRescan
+func NewWalletRescanResponse(r *visor.WalletRescan) (*WalletRescanResponse, error) {
+	scans, err := readable.NewAddressScans(r.Addresses)
+	if err != nil {
+		return nil, err
+	}
+
+	newAddrs := make([]string, len(r.NewAddresses))
+	for i, a := range r.NewAddresses {
+		newAddrs[i] = a.String()
+	}
+
+	discovered := make([]string, len(r.Discovered))
+	for i, a := range r.Discovered {
+		discovered[i] = a.String()
+	}
+
+	return &WalletRescanResponse{
+		NewAddresses:        newAddrs,
+		DiscoveredAddresses: discovered,
+		Balance:             readable.NewBalance(r.Balance),
+		Addresses:           scans,
+	}, nil
+}
+
+// URI: /api/v2/wallet/rescan
+// Method: POST
+// Content-Type: application/json
+// Args: JSON body
+//  id: wallet id
+//  password: [optional] wallet password, to extend an encrypted wallet
+//  gap_limit: [optional] number of consecutive unused addresses that ends the extension of the wallet
+//  recent_txns: [optional] number of most recent transactions to return per address
+// Scans the historydb for the activity of the addresses of a wallet. If gap_limit is set, the wallet is first
+// extended with the used addresses that follow its last address. Returns the new addresses, the discovered ones,
+// and the confirmed balances and recent transactions of all the addresses of the wallet.
+func walletRescanHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if r.Header.Get("Content-Type") != ContentTypeJSON {
+			resp := NewHTTPErrorResponse(http.StatusUnsupportedMediaType, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		var req WalletRescanRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if req.ID == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "id is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		rescan, err := gateway.RescanWallet(req.ID, []byte(req.Password), req.GapLimit, req.RecentTxns)
+		if err != nil {
+			var resp HTTPResponse
+			switch err {
+			case wallet.ErrWalletNotExist:
+				resp = NewHTTPErrorResponse(http.StatusNotFound, "")
+			case wallet.ErrWalletAPIDisabled:
+				resp = NewHTTPErrorResponse(http.StatusForbidden, "")
+			case visor.ErrRescanGapLimitTooLarge,
+				visor.ErrRescanTooManyAddresses,
+				visor.ErrScanTooManyRecentTxns:
+				resp = NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			default:
+				switch err.(type) {
+				case wallet.Error:
+					resp = NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+				default:
+					resp = NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+				}
+			}
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		rsp, err := NewWalletRescanResponse(rescan)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: rsp,
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/readable"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+func TestWalletRescanHandler(t *testing.T) {
+	addrA := testutil.MakeAddress()
+	addrB := testutil.MakeAddress()
+	addrC := testutil.MakeAddress()
+
+	rescan := &visor.WalletRescan{
+		NewAddresses: []cipher.Address{addrB, addrC},
+		Discovered:   []cipher.Address{addrC},
+		Balance: wallet.Balance{
+			Coins: 12e6,
+			Hours: 7,
+		},
+		Addresses: []visor.AddressScan{
+			{
+				Address: addrA,
+				Balance: wallet.Balance{
+					Coins: 2e6,
+					Hours: 3,
+				},
+				Outputs: []visor.UnspentOutput{},
+			},
+			{
+				Address: addrB,
+				Outputs: []visor.UnspentOutput{},
+			},
+			{
+				Address: addrC,
+				Balance: wallet.Balance{
+					Coins: 10e6,
+					Hours: 4,
+				},
+				Outputs: []visor.UnspentOutput{},
+			},
+		},
+	}
+
+	scans, err := readable.NewAddressScans(rescan.Addresses)
+	require.NoError(t, err)
+
+	type gatewayArgs struct {
+		id         string
+		password   []byte
+		gapLimit   uint64
+		recentTxns uint64
+	}
+
+	cases := []struct {
+		name        string
+		method      string
+		contentType string
+		body        string
+		status      int
+		err         string
+		gatewayArgs *gatewayArgs
+		gatewayRsp  *visor.WalletRescan
+		gatewayErr  error
+		rsp         *WalletRescanResponse
+	}{
+		{
+			name:   "405",
+			method: http.MethodGet,
+			status: http.StatusMethodNotAllowed,
+			err:    "Method Not Allowed",
+		},
+		{
+			name:        "415",
+			method:      http.MethodPost,
+			contentType: ContentTypeForm,
+			status:      http.StatusUnsupportedMediaType,
+			err:         "Unsupported Media Type",
+		},
+		{
+			name:   "id missing",
+			method: http.MethodPost,
+			body:   `{"gap_limit":20}`,
+			status: http.StatusBadRequest,
+			err:    "id is required",
+		},
+		{
+			name:   "wallet not exist",
+			method: http.MethodPost,
+			body:   `{"id":"foo.wlt"}`,
+			gatewayArgs: &gatewayArgs{
+				id:       "foo.wlt",
+				password: []byte{},
+			},
+			gatewayErr: wallet.ErrWalletNotExist,
+			status:     http.StatusNotFound,
+			err:        "Not Found",
+		},
+		{
+			name:   "wallet api disabled",
+			method: http.MethodPost,
+			body:   `{"id":"foo.wlt"}`,
+			gatewayArgs: &gatewayArgs{
+				id:       "foo.wlt",
+				password: []byte{},
+			},
+			gatewayErr: wallet.ErrWalletAPIDisabled,
+			status:     http.StatusForbidden,
+			err:        "Forbidden",
+		},
+		{
+			name:   "missing password",
+			method: http.MethodPost,
+			body:   `{"id":"foo.wlt","gap_limit":20}`,
+			gatewayArgs: &gatewayArgs{
+				id:       "foo.wlt",
+				password: []byte{},
+				gapLimit: 20,
+			},
+			gatewayErr: wallet.ErrMissingPassword,
+			status:     http.StatusBadRequest,
+			err:        wallet.ErrMissingPassword.Error(),
+		},
+		{
+			name:   "gap limit too large",
+			method: http.MethodPost,
+			body:   `{"id":"foo.wlt","gap_limit":1001}`,
+			gatewayArgs: &gatewayArgs{
+				id:       "foo.wlt",
+				password: []byte{},
+				gapLimit: 1001,
+			},
+			gatewayErr: visor.ErrRescanGapLimitTooLarge,
+			status:     http.StatusBadRequest,
+			err:        visor.ErrRescanGapLimitTooLarge.Error(),
+		},
+		{
+			name:   "internal error",
+			method: http.MethodPost,
+			body:   `{"id":"foo.wlt"}`,
+			gatewayArgs: &gatewayArgs{
+				id:       "foo.wlt",
+				password: []byte{},
+			},
+			gatewayErr: errors.New("failed"),
+			status:     http.StatusInternalServerError,
+			err:        "failed",
+		},
+		{
+			name:   "rescan",
+			method: http.MethodPost,
+			body:   `{"id":"foo.wlt","password":"pwd","gap_limit":20,"recent_txns":5}`,
+			gatewayArgs: &gatewayArgs{
+				id:         "foo.wlt",
+				password:   []byte("pwd"),
+				gapLimit:   20,
+				recentTxns: 5,
+			},
+			gatewayRsp: rescan,
+			status:     http.StatusOK,
+			rsp: &WalletRescanResponse{
+				NewAddresses:        []string{addrB.String(), addrC.String()},
+				DiscoveredAddresses: []string{addrC.String()},
+				Balance: readable.Balance{
+					Coins: 12e6,
+					Hours: 7,
+				},
+				Addresses: scans,
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			if tc.gatewayArgs != nil {
+				gateway.On("RescanWallet", tc.gatewayArgs.id, tc.gatewayArgs.password, tc.gatewayArgs.gapLimit, tc.gatewayArgs.recentTxns).Return(tc.gatewayRsp, tc.gatewayErr)
+			}
+
+			contentType := tc.contentType
+			if contentType == "" {
+				contentType = ContentTypeJSON
+			}
+
+			req, err := http.NewRequest(tc.method, "/api/v2/wallet/rescan", strings.NewReader(tc.body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", contentType)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code, "got `%v` want `%v`", rr.Code, tc.status)
+
+			var rsp ReceivedHTTPResponse
+			err = json.NewDecoder(rr.Body).Decode(&rsp)
+			require.NoError(t, err)
+
+			if tc.status != http.StatusOK {
+				require.NotNil(t, rsp.Error)
+				require.Equal(t, tc.err, rsp.Error.Message)
+				return
+			}
+
+			require.Nil(t, rsp.Error)
+			var rescanRsp WalletRescanResponse
+			err = json.Unmarshal(rsp.Data, &rescanRsp)
+			require.NoError(t, err)
+			require.Equal(t, *tc.rsp, rescanRsp)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"math"
 	"net/http"
@@ -16,11 +17,13 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/go-bip39"
 	"github.com/skycoin/skycoin/src/coin"
 	"github.com/skycoin/skycoin/src/readable"
 	"github.com/skycoin/skycoin/src/testutil"
 	"github.com/skycoin/skycoin/src/util/fee"
 	"github.com/skycoin/skycoin/src/visor"
+	"github.com/skycoin/skycoin/src/visor/historydb"
 	"github.com/skycoin/skycoin/src/wallet"
 )
 
@@ -209,6 +212,171 @@ func TestGetBalanceHandler(t *testing.T) {
 	}
 }
 
+func TestBalanceHistoryHandler(t *testing.T) {
+	validAddr := "2eZYSbzBKJ7QCL4kd5LSqV478rJQGb4UNkf"
+	address, err := cipher.DecodeBase58Address(validAddr)
+	require.NoError(t, err)
+
+	history := &visor.AddressBalanceHistory{
+		Address:      address,
+		StartSeq:     2,
+		EndSeq:       10,
+		StartBalance: 5e6,
+		EndBalance:   13e6,
+		Deltas: []historydb.AddressBalanceDelta{
+			{
+				BlockSeq:  3,
+				BlockTime: 1000,
+				Received:  10e6,
+				Balance:   15e6,
+			},
+			{
+				BlockSeq:  5,
+				BlockTime: 2000,
+				Sent:      2e6,
+				Balance:   13e6,
+			},
+		},
+	}
+
+	tt := []struct {
+		name        string
+		method      string
+		status      int
+		err         string
+		query       url.Values
+		gatewayArgs []interface{}
+		gatewayResp *visor.AddressBalanceHistory
+		gatewayErr  error
+		response    *readable.AddressBalanceHistory
+	}{
+		{
+			name:   "405",
+			method: http.MethodPost,
+			status: http.StatusMethodNotAllowed,
+			err:    "405 Method Not Allowed",
+		},
+		{
+			name:   "400 - no address",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - address is required",
+		},
+		{
+			name:   "400 - invalid address",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - address invalidAddr is invalid: Invalid base58 character",
+			query:  url.Values{"address": {"invalidAddr"}},
+		},
+		{
+			name:   "400 - invalid start_seq",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - Invalid start_seq value \"-1\"",
+			query:  url.Values{"address": {validAddr}, "start_seq": {"-1"}},
+		},
+		{
+			name:   "400 - invalid end_seq",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - Invalid end_seq value \"x\"",
+			query:  url.Values{"address": {validAddr}, "end_seq": {"x"}},
+		},
+		{
+			name:   "400 - start_seq after end_seq",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - start_seq must not be greater than end_seq",
+			query:  url.Values{"address": {validAddr}, "start_seq": {"5"}, "end_seq": {"4"}},
+		},
+		{
+			name:        "404 - start_seq after head",
+			method:      http.MethodGet,
+			status:      http.StatusNotFound,
+			err:         "404 Not Found - block does not exist seq=20",
+			query:       url.Values{"address": {validAddr}, "start_seq": {"20"}},
+			gatewayArgs: []interface{}{address, uint64(20), uint64(math.MaxUint64)},
+			gatewayErr:  visor.NewErrBlockNotExist(20),
+		},
+		{
+			name:        "500 - gateway error",
+			method:      http.MethodGet,
+			status:      http.StatusInternalServerError,
+			err:         "500 Internal Server Error - GetAddressBalanceHistory failed",
+			query:       url.Values{"address": {validAddr}},
+			gatewayArgs: []interface{}{address, uint64(0), uint64(math.MaxUint64)},
+			gatewayErr:  errors.New("GetAddressBalanceHistory failed"),
+		},
+		{
+			name:        "200",
+			method:      http.MethodGet,
+			status:      http.StatusOK,
+			query:       url.Values{"address": {validAddr}, "start_seq": {"2"}, "end_seq": {"20"}},
+			gatewayArgs: []interface{}{address, uint64(2), uint64(20)},
+			gatewayResp: history,
+			response: &readable.AddressBalanceHistory{
+				Address:      validAddr,
+				StartSeq:     2,
+				EndSeq:       10,
+				StartBalance: "5.000000",
+				EndBalance:   "13.000000",
+				Deltas: []readable.AddressBalanceDelta{
+					{
+						BlockSeq:  3,
+						BlockTime: 1000,
+						Received:  "10.000000",
+						Sent:      "0.000000",
+						Balance:   "15.000000",
+					},
+					{
+						BlockSeq:  5,
+						BlockTime: 2000,
+						Received:  "0.000000",
+						Sent:      "2.000000",
+						Balance:   "13.000000",
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			if tc.gatewayArgs != nil {
+				gateway.On("GetAddressBalanceHistory", tc.gatewayArgs...).Return(tc.gatewayResp, tc.gatewayErr)
+			}
+
+			endpoint := "/api/v1/balance/history"
+			if len(tc.query) > 0 {
+				endpoint += "?" + tc.query.Encode()
+			}
+
+			req, err := http.NewRequest(tc.method, endpoint, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			status := rr.Code
+			require.Equal(t, tc.status, status, "got `%v` want `%v`", status, tc.status)
+
+			if status != http.StatusOK {
+				require.Equal(t, tc.err, strings.TrimSpace(rr.Body.String()))
+			} else {
+				var msg readable.AddressBalanceHistory
+				err = json.Unmarshal(rr.Body.Bytes(), &msg)
+				require.NoError(t, err)
+				require.Equal(t, tc.response, &msg)
+			}
+
+			gateway.AssertExpectations(t)
+		})
+	}
+}
+
 func TestWalletSpendHandler(t *testing.T) {
 	type httpBody struct {
 		WalletID string
@@ -789,6 +957,7 @@ func TestWalletGet(t *testing.T) {
 func TestWalletBalanceHandler(t *testing.T) {
 	type httpBody struct {
 		WalletID string
+		Account  string
 		Dst      string
 		Coins    string
 	}
@@ -798,6 +967,8 @@ func TestWalletBalanceHandler(t *testing.T) {
 		Addresses   wallet.AddressBalances
 	}
 
+	accountAddr := testutil.MakeAddress()
+
 	tt := []struct {
 		name                          string
 		method                        string
@@ -807,7 +978,9 @@ func TestWalletBalanceHandler(t *testing.T) {
 		walletID                      string
 		gatewayGetWalletBalanceResult balanceResult
 		gatewayBalanceErr             error
+		gatewayGetWalletResult        *wallet.Wallet
 		result                        *readable.BalancePair
+		annotations                   map[string]readable.Annotation
 	}{
 		{
 			name:     "405",
@@ -873,10 +1046,77 @@ func TestWalletBalanceHandler(t *testing.T) {
 			body: &httpBody{
 				WalletID: "foo",
 			},
+			status:                 http.StatusOK,
+			err:                    "",
+			walletID:               "foo",
+			gatewayGetWalletResult: &wallet.Wallet{},
+			result:                 &readable.BalancePair{},
+		},
+		{
+			name:   "200 - OK with annotations",
+			method: http.MethodGet,
+			body: &httpBody{
+				WalletID: "foo",
+			},
 			status:   http.StatusOK,
-			err:      "",
 			walletID: "foo",
-			result:   &readable.BalancePair{},
+			gatewayGetWalletResult: &wallet.Wallet{
+				Annotations: wallet.Annotations{
+					Addresses: map[cipher.Address]wallet.Annotation{
+						testutil.MakeAddress(): {Label: "rent"},
+					},
+				},
+			},
+			result: &readable.BalancePair{},
+		},
+		{
+			name:   "400 - account doesn't exist",
+			method: http.MethodGet,
+			body: &httpBody{
+				WalletID: "foo",
+				Account:  "savings",
+			},
+			status:            http.StatusBadRequest,
+			err:               "400 Bad Request - account does not exist",
+			walletID:          "foo",
+			gatewayBalanceErr: wallet.ErrAccountNotExist,
+		},
+		{
+			name:   "200 - OK account",
+			method: http.MethodGet,
+			body: &httpBody{
+				WalletID: "foo",
+				Account:  "savings",
+			},
+			status:   http.StatusOK,
+			walletID: "foo",
+			gatewayGetWalletBalanceResult: balanceResult{
+				BalancePair: wallet.BalancePair{
+					Confirmed: wallet.Balance{Coins: 2e6, Hours: 10},
+					Predicted: wallet.Balance{Coins: 2e6, Hours: 10},
+				},
+				Addresses: wallet.AddressBalances{
+					accountAddr.String(): wallet.BalancePair{
+						Confirmed: wallet.Balance{Coins: 2e6, Hours: 10},
+						Predicted: wallet.Balance{Coins: 2e6, Hours: 10},
+					},
+				},
+			},
+			gatewayGetWalletResult: &wallet.Wallet{
+				Annotations: wallet.Annotations{
+					Addresses: map[cipher.Address]wallet.Annotation{
+						accountAddr:            {Label: "savings deposit"},
+						testutil.MakeAddress(): {Label: "rent"},
+					},
+				},
+			},
+			result: &readable.BalancePair{
+				Confirmed: readable.Balance{Coins: 2e6, Hours: 10},
+				Predicted: readable.Balance{Coins: 2e6, Hours: 10},
+			},
+			annotations: map[string]readable.Annotation{
+				accountAddr.String(): {Label: "savings deposit"},
+			},
 		},
 	}
 
@@ -885,6 +1125,9 @@ func TestWalletBalanceHandler(t *testing.T) {
 			gateway := &MockGatewayer{}
 			gateway.On("GetWalletBalance", tc.walletID).Return(tc.gatewayGetWalletBalanceResult.BalancePair,
 				tc.gatewayGetWalletBalanceResult.Addresses, tc.gatewayBalanceErr)
+			gateway.On("GetWalletAccountBalance", tc.walletID, "savings").Return(tc.gatewayGetWalletBalanceResult.BalancePair,
+				tc.gatewayGetWalletBalanceResult.Addresses, tc.gatewayBalanceErr)
+			gateway.On("GetWallet", tc.walletID).Return(tc.gatewayGetWalletResult, nil)
 
 			endpoint := "/api/v1/wallet/balance"
 
@@ -893,6 +1136,9 @@ func TestWalletBalanceHandler(t *testing.T) {
 				if tc.body.WalletID != "" {
 					v.Add("id", tc.body.WalletID)
 				}
+				if tc.body.Account != "" {
+					v.Add("account", tc.body.Account)
+				}
 			}
 			if len(v) > 0 {
 				endpoint += "?" + v.Encode()
@@ -920,10 +1166,21 @@ func TestWalletBalanceHandler(t *testing.T) {
 				require.Equal(t, tc.err, strings.TrimSpace(rr.Body.String()), "got `%v`| %d, want `%v`",
 					strings.TrimSpace(rr.Body.String()), status, tc.err)
 			} else {
-				var msg readable.BalancePair
+				var msg BalanceResponse
 				err = json.Unmarshal(rr.Body.Bytes(), &msg)
 				require.NoError(t, err)
-				require.Equal(t, tc.result, &msg, tc.name)
+				require.Equal(t, tc.result, &msg.BalancePair, tc.name)
+
+				annotations := tc.annotations
+				if annotations == nil {
+					for addr, a := range tc.gatewayGetWalletResult.Annotations.Addresses {
+						if annotations == nil {
+							annotations = make(map[string]readable.Annotation)
+						}
+						annotations[addr.String()] = readable.NewAnnotation(a)
+					}
+				}
+				require.Equal(t, annotations, msg.Annotations)
 			}
 		})
 	}
@@ -1244,6 +1501,7 @@ func TestWalletTransactionsHandler(t *testing.T) {
 		gateway := &MockGatewayer{}
 		gateway.On("GetWalletUnconfirmedTransactions", tc.walletID).Return(tc.gatewayGetWalletUnconfirmedTxnsResult, tc.gatewayGetWalletUnconfirmedTxnsErr)
 		gateway.On("GetWalletUnconfirmedTransactionsVerbose", tc.walletID).Return(tc.gatewayGetWalletUnconfirmedTxnsVerboseResult, tc.gatewayGetWalletUnconfirmedTxnsVerboseErr)
+		gateway.On("GetWallet", tc.walletID).Return(&wallet.Wallet{}, nil)
 
 		endpoint := "/api/v1/wallet/transactions"
 
@@ -1302,11 +1560,13 @@ func TestWalletTransactionsHandler(t *testing.T) {
 func TestWalletCreateHandler(t *testing.T) {
 	entries, responseEntries := makeEntries([]byte("seed"), 5)
 	type httpBody struct {
-		Seed     string
-		Label    string
-		ScanN    string
-		Encrypt  bool
-		Password string
+		Seed           string
+		Label          string
+		ScanN          string
+		Encrypt        bool
+		Password       string
+		BIP39          string
+		SeedPassphrase string
 	}
 	tt := []struct {
 		name                      string
@@ -1530,6 +1790,64 @@ func TestWalletCreateHandler(t *testing.T) {
 			status: http.StatusBadRequest,
 			err:    "400 Bad Request - missing password",
 		},
+		{
+			name:   "400 - invalid bip39 value",
+			method: http.MethodPost,
+			body: &httpBody{
+				Seed:  "foo",
+				Label: "bar",
+				BIP39: "maybe",
+			},
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - invalid bip39 value: strconv.ParseBool: parsing \"maybe\": invalid syntax",
+		},
+		{
+			name:   "400 - bip39 checksum mismatch",
+			method: http.MethodPost,
+			body: &httpBody{
+				Seed:           "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon",
+				Label:          "bar",
+				SeedPassphrase: "baz",
+			},
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - seed is not a valid BIP39 mnemonic: Mnemonic checksum is invalid, check the order and the spelling of the words",
+			options: wallet.Options{
+				Label:          "bar",
+				Seed:           "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon",
+				Password:       []byte{},
+				SeedPassphrase: "baz",
+			},
+			gatewayCreateWalletErr: wallet.NewError(fmt.Errorf("seed is not a valid BIP39 mnemonic: %v", bip39.ErrChecksumMismatch)),
+		},
+		{
+			name:   "200 - OK - bip39 with seed passphrase",
+			method: http.MethodPost,
+			body: &httpBody{
+				Seed:           "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+				Label:          "bar",
+				BIP39:          "true",
+				SeedPassphrase: "baz",
+			},
+			status:  http.StatusOK,
+			wltName: "filename",
+			options: wallet.Options{
+				Label:          "bar",
+				Seed:           "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+				Password:       []byte{},
+				BIP39:          true,
+				SeedPassphrase: "baz",
+			},
+			gatewayCreateWalletResult: wallet.Wallet{
+				Meta: map[string]string{
+					"filename": "filename",
+				},
+			},
+			responseBody: WalletResponse{
+				Meta: readable.WalletMeta{
+					Filename: "filename",
+				},
+			},
+		},
 	}
 
 	for _, tc := range tt {
@@ -1561,6 +1879,14 @@ func TestWalletCreateHandler(t *testing.T) {
 				if tc.body.Password != "" {
 					v.Add("password", tc.body.Password)
 				}
+
+				if tc.body.BIP39 != "" {
+					v.Add("bip39", tc.body.BIP39)
+				}
+
+				if tc.body.SeedPassphrase != "" {
+					v.Add("seed_passphrase", tc.body.SeedPassphrase)
+				}
 			}
 
 			req, err := http.NewRequest(tc.method, endpoint, strings.NewReader(v.Encode()))
@@ -1600,7 +1926,8 @@ func TestWalletCreateHandler(t *testing.T) {
 
 func TestWalletNewSeed(t *testing.T) {
 	type httpBody struct {
-		Entropy string
+		Entropy  string
+		Language string
 	}
 	tt := []struct {
 		name      string
@@ -1634,9 +1961,18 @@ func TestWalletNewSeed(t *testing.T) {
 				Entropy: "200",
 			},
 			status:  http.StatusBadRequest,
-			err:     "400 Bad Request - entropy length must be 128 or 256",
+			err:     "400 Bad Request - entropy length must be 128, 160, 192, 224 or 256",
 			entropy: "200",
 		},
+		{
+			name:   "400 - unknown language",
+			method: http.MethodGet,
+			body: &httpBody{
+				Language: "klingon",
+			},
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - unknown language \"klingon\", must be one of english",
+		},
 		{
 			name:      "200 - OK with no entropy",
 			method:    http.MethodGet,
@@ -1665,6 +2001,17 @@ func TestWalletNewSeed(t *testing.T) {
 			entropy:   "256",
 			resultLen: 24,
 		},
+		{
+			name:   "200 - OK | 15 word english seed",
+			method: http.MethodGet,
+			body: &httpBody{
+				Entropy:  "160",
+				Language: "english",
+			},
+			status:    http.StatusOK,
+			entropy:   "160",
+			resultLen: 15,
+		},
 	}
 
 	// Loop over each test case
@@ -1680,6 +2027,9 @@ func TestWalletNewSeed(t *testing.T) {
 				if tc.body.Entropy != "" {
 					v.Add("entropy", tc.body.Entropy)
 				}
+				if tc.body.Language != "" {
+					v.Add("language", tc.body.Language)
+				}
 			}
 			if len(v) > 0 {
 				endpoint += "?" + v.Encode()
@@ -3026,7 +3376,7 @@ func TestWalletRecover(t *testing.T) {
 				if tc.req.Password != "" {
 					password = []byte(tc.req.Password)
 				}
-				gateway.On("RecoverWallet", tc.req.ID, tc.req.Seed, password).Return(tc.gatewayReturn.w, tc.gatewayReturn.err)
+				gateway.On("RecoverWallet", tc.req.ID, tc.req.Seed, tc.req.SeedPassphrase, password).Return(tc.gatewayReturn.w, tc.gatewayReturn.err)
 			}
 
 			if tc.httpBody == "" && tc.req != nil {
@@ -3076,3 +3426,390 @@ func TestWalletRecover(t *testing.T) {
 		})
 	}
 }
+
+func TestWalletCreateWatchOnly(t *testing.T) {
+	type gatewayReturnPair struct {
+		w   *wallet.Wallet
+		err error
+	}
+
+	addr := testutil.MakeAddress()
+	okWallet, err := wallet.NewWatchOnlyWallet("foo.wlt", "foolabel", []cipher.Address{addr})
+	require.NoError(t, err)
+	okWalletResponse, err := NewWalletResponse(okWallet)
+	require.NoError(t, err)
+	require.Equal(t, wallet.WalletTypeWatchOnly, okWalletResponse.Meta.Type)
+	require.Empty(t, okWalletResponse.Entries[0].Public)
+
+	cases := []struct {
+		name          string
+		method        string
+		status        int
+		contentType   string
+		req           *WatchOnlyWalletCreateRequest
+		httpBody      string
+		httpResponse  HTTPResponse
+		gatewayReturn gatewayReturnPair
+	}{
+		{
+			name:         "method not allowed",
+			method:       http.MethodGet,
+			status:       http.StatusMethodNotAllowed,
+			httpBody:     toJSON(t, WatchOnlyWalletCreateRequest{}),
+			httpResponse: NewHTTPErrorResponse(http.StatusMethodNotAllowed, "Method Not Allowed"),
+		},
+		{
+			name:         "wrong content-type",
+			method:       http.MethodPost,
+			status:       http.StatusUnsupportedMediaType,
+			contentType:  ContentTypeForm,
+			httpBody:     toJSON(t, WatchOnlyWalletCreateRequest{}),
+			httpResponse: NewHTTPErrorResponse(http.StatusUnsupportedMediaType, "Unsupported Media Type"),
+		},
+		{
+			name:         "addresses missing",
+			method:       http.MethodPost,
+			status:       http.StatusBadRequest,
+			httpBody:     toJSON(t, WatchOnlyWalletCreateRequest{Label: "foolabel"}),
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "addresses is required"),
+		},
+		{
+			name:   "invalid address",
+			method: http.MethodPost,
+			status: http.StatusBadRequest,
+			httpBody: toJSON(t, WatchOnlyWalletCreateRequest{
+				Addresses: []string{"foo"},
+			}),
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, `address "foo" is invalid: Invalid address length`),
+		},
+		{
+			name:   "wallet api disabled",
+			method: http.MethodPost,
+			status: http.StatusForbidden,
+			req: &WatchOnlyWalletCreateRequest{
+				Label:     "foolabel",
+				Addresses: []string{addr.String()},
+			},
+			gatewayReturn: gatewayReturnPair{
+				err: wallet.ErrWalletAPIDisabled,
+			},
+			httpResponse: NewHTTPErrorResponse(http.StatusForbidden, ""),
+		},
+		{
+			name:   "null address",
+			method: http.MethodPost,
+			status: http.StatusBadRequest,
+			req: &WatchOnlyWalletCreateRequest{
+				Label:     "foolabel",
+				Addresses: []string{addr.String()},
+			},
+			gatewayReturn: gatewayReturnPair{
+				err: wallet.NewError(errors.New("watched address must not be the null address")),
+			},
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "watched address must not be the null address"),
+		},
+		{
+			name:   "ok",
+			method: http.MethodPost,
+			status: http.StatusOK,
+			req: &WatchOnlyWalletCreateRequest{
+				Label:     "foolabel",
+				Addresses: []string{addr.String()},
+			},
+			gatewayReturn: gatewayReturnPair{
+				w: okWallet,
+			},
+			httpResponse: HTTPResponse{
+				Data: *okWalletResponse,
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			if tc.req != nil {
+				gateway.On("CreateWatchOnlyWallet", "", tc.req.Label, []cipher.Address{addr}).Return(tc.gatewayReturn.w, tc.gatewayReturn.err)
+				tc.httpBody = toJSON(t, tc.req)
+			}
+
+			req, err := http.NewRequest(tc.method, "/api/v2/wallet/watch-only/create", strings.NewReader(tc.httpBody))
+			require.NoError(t, err)
+
+			contentType := tc.contentType
+			if contentType == "" {
+				contentType = ContentTypeJSON
+			}
+			req.Header.Set("Content-Type", contentType)
+
+			csrfStore := &CSRFStore{
+				Enabled: true,
+			}
+			setCSRFParameters(csrfStore, tokenValid, req)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, csrfStore, nil)
+			handler.ServeHTTP(rr, req)
+
+			status := rr.Code
+			require.Equal(t, tc.status, status, "got `%v` want `%v`", status, tc.status)
+
+			var rsp ReceivedHTTPResponse
+			err = json.NewDecoder(rr.Body).Decode(&rsp)
+			require.NoError(t, err)
+
+			require.Equal(t, tc.httpResponse.Error, rsp.Error)
+
+			if rsp.Data == nil {
+				require.Nil(t, tc.httpResponse.Data)
+			} else {
+				require.NotNil(t, tc.httpResponse.Data)
+
+				var wltRsp WalletResponse
+				err := json.Unmarshal(rsp.Data, &wltRsp)
+				require.NoError(t, err)
+
+				require.Equal(t, tc.httpResponse.Data.(WalletResponse), wltRsp)
+			}
+		})
+	}
+}
+
+func TestWalletAddWatchOnlyAddresses(t *testing.T) {
+	addr := testutil.MakeAddress()
+	okWallet, err := wallet.NewWatchOnlyWallet("foo.wlt", "foolabel", []cipher.Address{addr})
+	require.NoError(t, err)
+
+	cases := []struct {
+		name       string
+		req        WatchOnlyAddressesRequest
+		status     int
+		err        string
+		gatewayErr error
+	}{
+		{
+			name: "id missing",
+			req: WatchOnlyAddressesRequest{
+				Addresses: []string{addr.String()},
+			},
+			status: http.StatusBadRequest,
+			err:    "id is required",
+		},
+		{
+			name: "addresses missing",
+			req: WatchOnlyAddressesRequest{
+				ID: "foo.wlt",
+			},
+			status: http.StatusBadRequest,
+			err:    "addresses is required",
+		},
+		{
+			name: "wallet not exist",
+			req: WatchOnlyAddressesRequest{
+				ID:        "foo.wlt",
+				Addresses: []string{addr.String()},
+			},
+			gatewayErr: wallet.ErrWalletNotExist,
+			status:     http.StatusNotFound,
+			err:        "Not Found",
+		},
+		{
+			name: "wallet not watch-only",
+			req: WatchOnlyAddressesRequest{
+				ID:        "foo.wlt",
+				Addresses: []string{addr.String()},
+			},
+			gatewayErr: wallet.ErrWalletNotWatchOnly,
+			status:     http.StatusBadRequest,
+			err:        wallet.ErrWalletNotWatchOnly.Error(),
+		},
+		{
+			name: "internal error",
+			req: WatchOnlyAddressesRequest{
+				ID:        "foo.wlt",
+				Addresses: []string{addr.String()},
+			},
+			gatewayErr: errors.New("failed to save"),
+			status:     http.StatusInternalServerError,
+			err:        "failed to save",
+		},
+		{
+			name: "ok",
+			req: WatchOnlyAddressesRequest{
+				ID:        "foo.wlt",
+				Addresses: []string{addr.String()},
+			},
+			status: http.StatusOK,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			if tc.gatewayErr != nil {
+				gateway.On("AddWatchOnlyAddresses", tc.req.ID, []cipher.Address{addr}).Return(nil, tc.gatewayErr)
+			} else {
+				gateway.On("AddWatchOnlyAddresses", tc.req.ID, []cipher.Address{addr}).Return(okWallet, nil)
+			}
+
+			req, err := http.NewRequest(http.MethodPost, "/api/v2/wallet/watch-only/add", strings.NewReader(toJSON(t, tc.req)))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", ContentTypeJSON)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code, "got `%v` want `%v`", rr.Code, tc.status)
+
+			var rsp ReceivedHTTPResponse
+			err = json.NewDecoder(rr.Body).Decode(&rsp)
+			require.NoError(t, err)
+
+			if tc.status != http.StatusOK {
+				require.NotNil(t, rsp.Error)
+				require.Equal(t, tc.err, rsp.Error.Message)
+				return
+			}
+
+			require.Nil(t, rsp.Error)
+			var wltRsp WalletResponse
+			err = json.Unmarshal(rsp.Data, &wltRsp)
+			require.NoError(t, err)
+			require.Equal(t, "foo.wlt", wltRsp.Meta.Filename)
+			require.Equal(t, addr.String(), wltRsp.Entries[0].Address)
+		})
+	}
+}
+
+func TestWalletAddMultisig(t *testing.T) {
+	pks := make([]cipher.PubKey, 3)
+	pkStrs := make([]string, 3)
+	for i := range pks {
+		pks[i], _ = cipher.GenerateKeyPair()
+		pkStrs[i] = pks[i].Hex()
+	}
+	ms, err := cipher.NewMultisig(2, pks)
+	require.NoError(t, err)
+
+	okWallet, err := wallet.NewWatchOnlyWallet("foo.wlt", "foolabel", []cipher.Address{testutil.MakeAddress()})
+	require.NoError(t, err)
+	require.NoError(t, okWallet.AddMultisig(ms))
+
+	cases := []struct {
+		name       string
+		req        MultisigRequest
+		status     int
+		err        string
+		gatewayErr error
+	}{
+		{
+			name: "id missing",
+			req: MultisigRequest{
+				M:          2,
+				PublicKeys: pkStrs,
+			},
+			status: http.StatusBadRequest,
+			err:    "id is required",
+		},
+		{
+			name: "public keys missing",
+			req: MultisigRequest{
+				ID: "foo.wlt",
+				M:  2,
+			},
+			status: http.StatusBadRequest,
+			err:    "public_keys is required",
+		},
+		{
+			name: "public key invalid",
+			req: MultisigRequest{
+				ID:         "foo.wlt",
+				M:          2,
+				PublicKeys: []string{pkStrs[0], "foo"},
+			},
+			status: http.StatusBadRequest,
+			err:    `public key "foo" is invalid: Invalid public key`,
+		},
+		{
+			name: "m invalid",
+			req: MultisigRequest{
+				ID:         "foo.wlt",
+				M:          4,
+				PublicKeys: pkStrs,
+			},
+			status: http.StatusBadRequest,
+			err:    cipher.ErrMultisigRequiredSigs.Error(),
+		},
+		{
+			name: "wallet not exist",
+			req: MultisigRequest{
+				ID:         "foo.wlt",
+				M:          2,
+				PublicKeys: pkStrs,
+			},
+			gatewayErr: wallet.ErrWalletNotExist,
+			status:     http.StatusNotFound,
+			err:        "Not Found",
+		},
+		{
+			name: "wallet not watch-only",
+			req: MultisigRequest{
+				ID:         "foo.wlt",
+				M:          2,
+				PublicKeys: pkStrs,
+			},
+			gatewayErr: wallet.ErrWalletNotWatchOnly,
+			status:     http.StatusBadRequest,
+			err:        wallet.ErrWalletNotWatchOnly.Error(),
+		},
+		{
+			name: "ok",
+			req: MultisigRequest{
+				ID:         "foo.wlt",
+				M:          2,
+				PublicKeys: []string{pkStrs[2], pkStrs[0], pkStrs[1]},
+			},
+			status: http.StatusOK,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			if tc.gatewayErr != nil {
+				gateway.On("AddMultisig", tc.req.ID, ms).Return(nil, tc.gatewayErr)
+			} else {
+				gateway.On("AddMultisig", tc.req.ID, ms).Return(okWallet, nil)
+			}
+
+			req, err := http.NewRequest(http.MethodPost, "/api/v2/wallet/multisig/add", strings.NewReader(toJSON(t, tc.req)))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", ContentTypeJSON)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code, "got `%v` want `%v`", rr.Code, tc.status)
+
+			var rsp ReceivedHTTPResponse
+			err = json.NewDecoder(rr.Body).Decode(&rsp)
+			require.NoError(t, err)
+
+			if tc.status != http.StatusOK {
+				require.NotNil(t, rsp.Error)
+				require.Equal(t, tc.err, rsp.Error.Message)
+				return
+			}
+
+			require.Nil(t, rsp.Error)
+			var wltRsp WalletResponse
+			err = json.Unmarshal(rsp.Data, &wltRsp)
+			require.NoError(t, err)
+			require.Equal(t, "foo.wlt", wltRsp.Meta.Filename)
+			require.Len(t, wltRsp.Entries, 2)
+			require.Equal(t, ms.Address().String(), wltRsp.Entries[1].Address)
+		})
+	}
+}
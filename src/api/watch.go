@@ -0,0 +1,235 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/util/droplet"
+	wh "github.com/skycoin/skycoin/src/util/http"
+	"github.com/skycoin/skycoin/src/visor"
+)
+
+const (
+	defaultWatchEventsLimit = 100
+	maxWatchEventsLimit     = 1000
+	// maxWatchEventsWait is below the default write timeout of the server
+	maxWatchEventsWait = 30 * time.Second
+)
+
+// WatchAddressesResponse is returned by GET /api/v1/watch/addresses
+type WatchAddressesResponse struct {
+	Addresses []string `json:"addresses"`
+}
+
+// watchAddressesHandler returns the addresses of the watch list
+// Method: GET
+// URI: /api/v1/watch/addresses
+func watchAddressesHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			wh.Error405(w)
+			return
+		}
+
+		addrs, err := gateway.GetWatchAddresses()
+		if err != nil {
+			err = fmt.Errorf("gateway.GetWatchAddresses failed: %v", err)
+			wh.Error500(w, err.Error())
+			return
+		}
+
+		resp := WatchAddressesResponse{
+			Addresses: make([]string, len(addrs)),
+		}
+		for i, a := range addrs {
+			resp.Addresses[i] = a.String()
+		}
+
+		wh.SendJSONOr500(logger, w, resp)
+	}
+}
+
+// WatchAddressesUpdateResponse is returned by POST /api/v1/watch/add and POST /api/v1/watch/remove
+type WatchAddressesUpdateResponse struct {
+	// Count is the number of addresses added to or removed from the watch list
+	Count uint64 `json:"count"`
+}
+
+// watchAddHandler adds addresses to the watch list. Events are recorded for the transactions
+// that touch the addresses from now on.
+// Method: POST
+// URI: /api/v1/watch/add
+// Args:
+//	addrs: comma separated addresses [required]
+func watchAddHandler(gateway Gatewayer) http.HandlerFunc {
+	return watchUpdateHandler(gateway.AddWatchAddresses)
+}
+
+// watchRemoveHandler removes addresses from the watch list. The events recorded for the addresses are kept.
+// Method: POST
+// URI: /api/v1/watch/remove
+// Args:
+//	addrs: comma separated addresses [required]
+func watchRemoveHandler(gateway Gatewayer) http.HandlerFunc {
+	return watchUpdateHandler(gateway.RemoveWatchAddresses)
+}
+
+func watchUpdateHandler(update func([]cipher.Address) (uint64, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			wh.Error405(w)
+			return
+		}
+
+		addrs, err := parseAddressesFromStr(r.FormValue("addrs"))
+		if err != nil {
+			wh.Error400(w, fmt.Sprintf("parse parameter: 'addrs' failed: %v", err))
+			return
+		}
+
+		if len(addrs) == 0 {
+			wh.Error400(w, "addrs is required")
+			return
+		}
+
+		n, err := update(addrs)
+		if err != nil {
+			wh.Error500(w, err.Error())
+			return
+		}
+
+		wh.SendJSONOr500(logger, w, WatchAddressesUpdateResponse{
+			Count: n,
+		})
+	}
+}
+
+// WatchEvent is a transaction that touched a watched address
+type WatchEvent struct {
+	ID       uint64               `json:"id"`
+	Type     visor.WatchEventType `json:"type"`
+	Address  string               `json:"address"`
+	TxnID    string               `json:"txid"`
+	BlockSeq uint64               `json:"block_seq"`
+	Time     uint64               `json:"time"`
+	Received string               `json:"received"`
+	Sent     string               `json:"sent"`
+}
+
+// NewWatchEvent creates a WatchEvent from a visor.WatchEvent
+func NewWatchEvent(e visor.WatchEvent) (WatchEvent, error) {
+	received, err := droplet.ToString(e.Received)
+	if err != nil {
+		return WatchEvent{}, err
+	}
+
+	sent, err := droplet.ToString(e.Sent)
+	if err != nil {
+		return WatchEvent{}, err
+	}
+
+	return WatchEvent{
+		ID:       e.ID,
+		Type:     e.Type,
+		Address:  e.Address.String(),
+		TxnID:    e.TxnID.Hex(),
+		BlockSeq: e.BlockSeq,
+		Time:     e.Time,
+		Received: received,
+		Sent:     sent,
+	}, nil
+}
+
+// WatchEventsResponse is returned by GET /api/v1/watch/events
+type WatchEventsResponse struct {
+	Events []WatchEvent `json:"events"`
+}
+
+// watchEventsHandler returns the events recorded for the watched addresses, in the order they were recorded.
+// Clients poll for new events by passing the ID of the last event they received in after.
+// With wait, the request is held until new events are recorded, so clients are notified of them without polling.
+// Method: GET
+// URI: /api/v1/watch/events?after=${after}&limit=${limit}&wait=${wait}
+// Args:
+//	after [int, return the events with an ID greater than after, defaults to 0]
+//	limit [int, maximum number of events returned, defaults to 100, at most 1000]
+//	wait [int, seconds to wait for new events if there are none, defaults to 0, at most 30]
+func watchEventsHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			wh.Error405(w)
+			return
+		}
+
+		var after uint64
+		if afterStr := r.FormValue("after"); afterStr != "" {
+			var err error
+			after, err = strconv.ParseUint(afterStr, 10, 64)
+			if err != nil {
+				wh.Error400(w, "invalid after")
+				return
+			}
+		}
+
+		limit := uint64(defaultWatchEventsLimit)
+		if limitStr := r.FormValue("limit"); limitStr != "" {
+			var err error
+			limit, err = strconv.ParseUint(limitStr, 10, 64)
+			if err != nil {
+				wh.Error400(w, "invalid limit")
+				return
+			}
+
+			if limit == 0 || limit > maxWatchEventsLimit {
+				wh.Error400(w, fmt.Sprintf("limit must be between 1 and %d", maxWatchEventsLimit))
+				return
+			}
+		}
+
+		var wait time.Duration
+		if waitStr := r.FormValue("wait"); waitStr != "" {
+			n, err := strconv.ParseUint(waitStr, 10, 64)
+			if err != nil {
+				wh.Error400(w, "invalid wait")
+				return
+			}
+
+			if n > uint64(maxWatchEventsWait/time.Second) {
+				wh.Error400(w, fmt.Sprintf("wait must be at most %d", maxWatchEventsWait/time.Second))
+				return
+			}
+			wait = time.Duration(n) * time.Second
+		}
+
+		var events []visor.WatchEvent
+		var err error
+		if wait == 0 {
+			events, err = gateway.GetWatchEvents(after, limit)
+		} else {
+			ctx, cancel := context.WithTimeout(r.Context(), wait)
+			events, err = gateway.WaitWatchEvents(ctx, after, limit)
+			cancel()
+		}
+		if err != nil {
+			wh.Error500(w, err.Error())
+			return
+		}
+
+		resp := WatchEventsResponse{
+			Events: make([]WatchEvent, len(events)),
+		}
+		for i, e := range events {
+			resp.Events[i], err = NewWatchEvent(e)
+			if err != nil {
+				wh.Error500(w, err.Error())
+				return
+			}
+		}
+
+		wh.SendJSONOr500(logger, w, resp)
+	}
+}
@@ -0,0 +1,351 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor"
+)
+
+func TestWatchAddresses(t *testing.T) {
+	addr := testutil.MakeAddress()
+
+	tt := []struct {
+		name                           string
+		method                         string
+		status                         int
+		err                            string
+		gatewayGetWatchAddressesResult []cipher.Address
+		gatewayGetWatchAddressesErr    error
+		result                         WatchAddressesResponse
+	}{
+		{
+			name:   "405",
+			method: http.MethodPost,
+			status: http.StatusMethodNotAllowed,
+			err:    "405 Method Not Allowed",
+		},
+		{
+			name:                        "500 - gateway error",
+			method:                      http.MethodGet,
+			status:                      http.StatusInternalServerError,
+			err:                         "500 Internal Server Error - gateway.GetWatchAddresses failed: database not open",
+			gatewayGetWatchAddressesErr: errors.New("database not open"),
+		},
+		{
+			name:   "200 - no addresses",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			result: WatchAddressesResponse{
+				Addresses: []string{},
+			},
+		},
+		{
+			name:                           "200",
+			method:                         http.MethodGet,
+			status:                         http.StatusOK,
+			gatewayGetWatchAddressesResult: []cipher.Address{addr},
+			result: WatchAddressesResponse{
+				Addresses: []string{addr.String()},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			endpoint := "/api/v1/watch/addresses"
+			gateway := &MockGatewayer{}
+			gateway.On("GetWatchAddresses").Return(tc.gatewayGetWatchAddressesResult, tc.gatewayGetWatchAddressesErr)
+
+			req, err := http.NewRequest(tc.method, endpoint, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			status := rr.Code
+			require.Equal(t, tc.status, status, "got `%v` want `%v`", status, tc.status)
+
+			if status != http.StatusOK {
+				require.Equal(t, tc.err, strings.TrimSpace(rr.Body.String()))
+				return
+			}
+
+			var msg WatchAddressesResponse
+			err = json.Unmarshal(rr.Body.Bytes(), &msg)
+			require.NoError(t, err)
+			require.Equal(t, tc.result, msg)
+		})
+	}
+}
+
+func TestWatchAddressesUpdate(t *testing.T) {
+	addr1 := testutil.MakeAddress()
+	addr2 := testutil.MakeAddress()
+
+	tt := []struct {
+		name          string
+		method        string
+		status        int
+		err           string
+		addrs         string
+		gatewayAddrs  []cipher.Address
+		gatewayResult uint64
+		gatewayErr    error
+		result        WatchAddressesUpdateResponse
+	}{
+		{
+			name:   "405",
+			method: http.MethodGet,
+			status: http.StatusMethodNotAllowed,
+			err:    "405 Method Not Allowed",
+		},
+		{
+			name:   "400 - missing addrs",
+			method: http.MethodPost,
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - addrs is required",
+		},
+		{
+			name:   "400 - invalid address",
+			method: http.MethodPost,
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - parse parameter: 'addrs' failed: Invalid address length",
+			addrs:  "badaddr",
+		},
+		{
+			name:         "500 - gateway error",
+			method:       http.MethodPost,
+			status:       http.StatusInternalServerError,
+			err:          "500 Internal Server Error - database not open",
+			addrs:        addr1.String(),
+			gatewayAddrs: []cipher.Address{addr1},
+			gatewayErr:   errors.New("database not open"),
+		},
+		{
+			name:          "200",
+			method:        http.MethodPost,
+			status:        http.StatusOK,
+			addrs:         fmt.Sprintf("%s,%s", addr1, addr2),
+			gatewayAddrs:  []cipher.Address{addr1, addr2},
+			gatewayResult: 2,
+			result: WatchAddressesUpdateResponse{
+				Count: 2,
+			},
+		},
+	}
+
+	for _, gatewayMethod := range []string{"AddWatchAddresses", "RemoveWatchAddresses"} {
+		endpoint := "/api/v1/watch/add"
+		if gatewayMethod == "RemoveWatchAddresses" {
+			endpoint = "/api/v1/watch/remove"
+		}
+
+		for _, tc := range tt {
+			t.Run(fmt.Sprintf("%s %s", endpoint, tc.name), func(t *testing.T) {
+				gateway := &MockGatewayer{}
+				gateway.On(gatewayMethod, tc.gatewayAddrs).Return(tc.gatewayResult, tc.gatewayErr)
+
+				v := url.Values{}
+				if tc.addrs != "" {
+					v.Add("addrs", tc.addrs)
+				}
+
+				req, err := http.NewRequest(tc.method, endpoint, strings.NewReader(v.Encode()))
+				require.NoError(t, err)
+				req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+
+				rr := httptest.NewRecorder()
+				handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+				handler.ServeHTTP(rr, req)
+
+				status := rr.Code
+				require.Equal(t, tc.status, status, "got `%v` want `%v`", status, tc.status)
+
+				if status != http.StatusOK {
+					require.Equal(t, tc.err, strings.TrimSpace(rr.Body.String()))
+					return
+				}
+
+				var msg WatchAddressesUpdateResponse
+				err = json.Unmarshal(rr.Body.Bytes(), &msg)
+				require.NoError(t, err)
+				require.Equal(t, tc.result, msg)
+			})
+		}
+	}
+}
+
+func TestWatchEvents(t *testing.T) {
+	addr := testutil.MakeAddress()
+	txnID := testutil.RandSHA256(t)
+
+	events := []visor.WatchEvent{
+		{
+			ID:       7,
+			Type:     visor.WatchEventConfirmed,
+			Address:  addr,
+			TxnID:    txnID,
+			BlockSeq: 10,
+			Time:     1540000000,
+			Received: 1e6,
+			Sent:     2500000,
+		},
+	}
+
+	result := WatchEventsResponse{
+		Events: []WatchEvent{
+			{
+				ID:       7,
+				Type:     visor.WatchEventConfirmed,
+				Address:  addr.String(),
+				TxnID:    txnID.Hex(),
+				BlockSeq: 10,
+				Time:     1540000000,
+				Received: "1.000000",
+				Sent:     "2.500000",
+			},
+		},
+	}
+
+	tt := []struct {
+		name          string
+		method        string
+		status        int
+		err           string
+		query         string
+		gatewayMethod string
+		after         uint64
+		limit         uint64
+		gatewayResult []visor.WatchEvent
+		gatewayErr    error
+		result        WatchEventsResponse
+	}{
+		{
+			name:   "405",
+			method: http.MethodPost,
+			status: http.StatusMethodNotAllowed,
+			err:    "405 Method Not Allowed",
+		},
+		{
+			name:   "400 - invalid after",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - invalid after",
+			query:  "after=x",
+		},
+		{
+			name:   "400 - limit too large",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - limit must be between 1 and 1000",
+			query:  "limit=1001",
+		},
+		{
+			name:   "400 - invalid wait",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - invalid wait",
+			query:  "wait=-1",
+		},
+		{
+			name:   "400 - wait too long",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - wait must be at most 30",
+			query:  "wait=18446744073709551615",
+		},
+		{
+			name:          "500 - gateway error",
+			method:        http.MethodGet,
+			status:        http.StatusInternalServerError,
+			err:           "500 Internal Server Error - database not open",
+			gatewayMethod: "GetWatchEvents",
+			limit:         100,
+			gatewayErr:    errors.New("database not open"),
+		},
+		{
+			name:          "200 - no events",
+			method:        http.MethodGet,
+			status:        http.StatusOK,
+			gatewayMethod: "GetWatchEvents",
+			limit:         100,
+			result: WatchEventsResponse{
+				Events: []WatchEvent{},
+			},
+		},
+		{
+			name:          "200",
+			method:        http.MethodGet,
+			status:        http.StatusOK,
+			query:         "after=6&limit=10",
+			gatewayMethod: "GetWatchEvents",
+			after:         6,
+			limit:         10,
+			gatewayResult: events,
+			result:        result,
+		},
+		{
+			name:          "200 - wait",
+			method:        http.MethodGet,
+			status:        http.StatusOK,
+			query:         "after=6&wait=30",
+			gatewayMethod: "WaitWatchEvents",
+			after:         6,
+			limit:         100,
+			gatewayResult: events,
+			result:        result,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			endpoint := "/api/v1/watch/events"
+			if tc.query != "" {
+				endpoint += "?" + tc.query
+			}
+
+			gateway := &MockGatewayer{}
+			switch tc.gatewayMethod {
+			case "GetWatchEvents":
+				gateway.On("GetWatchEvents", tc.after, tc.limit).Return(tc.gatewayResult, tc.gatewayErr)
+			case "WaitWatchEvents":
+				gateway.On("WaitWatchEvents", mock.Anything, tc.after, tc.limit).Return(tc.gatewayResult, tc.gatewayErr)
+			}
+
+			req, err := http.NewRequest(tc.method, endpoint, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			status := rr.Code
+			require.Equal(t, tc.status, status, "got `%v` want `%v`", status, tc.status)
+
+			if status != http.StatusOK {
+				require.Equal(t, tc.err, strings.TrimSpace(rr.Body.String()))
+				return
+			}
+
+			gateway.AssertExpectations(t)
+
+			var msg WatchEventsResponse
+			err = json.Unmarshal(rr.Body.Bytes(), &msg)
+			require.NoError(t, err)
+			require.Equal(t, tc.result, msg)
+		})
+	}
+}
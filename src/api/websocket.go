@@ -0,0 +1,128 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/skycoin/skycoin/src/daemon"
+	"github.com/skycoin/skycoin/src/readable"
+	wh "github.com/skycoin/skycoin/src/util/http"
+	"github.com/skycoin/skycoin/src/util/websocket"
+	"github.com/skycoin/skycoin/src/visor"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+const (
+	// websocketTopicConnections is the topic of the connection lifecycle events
+	websocketTopicConnections = "connections"
+	// websocketTopicWallet is the topic of the incoming funds events of a wallet
+	websocketTopicWallet = "wallet"
+)
+
+// WebsocketMessage is a message sent over the websocket of GET /api/v1/websocket
+type WebsocketMessage struct {
+	Topic string      `json:"topic"`
+	Event interface{} `json:"event"`
+}
+
+// websocketHandler upgrades the request to a websocket, and pushes the events of a topic to it
+// as they happen, each in a text message encoded as a WebsocketMessage.
+// Topics:
+//	connections: the connection lifecycle events, as readable.ConnectionEvent: a connection was established,
+//	completed the introduction, was closed with a reason, or a peer IP was banned.
+//	wallet: the incoming funds events of the wallet given by id, as WalletEvent, like GET /api/v1/wallet/events.
+//	The confirmations are the confirmations of the transaction when the event was recorded.
+//	Events are dropped for a client that does not keep up
+// Method: GET
+// URI: /api/v1/websocket
+// Args:
+//	topic: the topic to subscribe to [required]
+//	id: the wallet id, for the wallet topic [required for wallet]
+func websocketHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			wh.Error405(w)
+			return
+		}
+
+		// Only the channel of the topic is set, receiving from a nil channel blocks forever
+		var connectionEvents <-chan daemon.ConnectionEvent
+		var walletEvents <-chan visor.WalletEvent
+
+		topic := r.FormValue("topic")
+		switch topic {
+		case "":
+			wh.Error400(w, "topic is required")
+			return
+		case websocketTopicConnections:
+			events, unsubscribe := gateway.SubscribeConnectionEvents()
+			defer unsubscribe()
+			connectionEvents = events
+		case websocketTopicWallet:
+			wltID := r.FormValue("id")
+			if wltID == "" {
+				wh.Error400(w, "missing wallet id")
+				return
+			}
+
+			events, unsubscribe, err := gateway.SubscribeWalletEvents(wltID)
+			if err != nil {
+				switch err {
+				case wallet.ErrWalletNotExist:
+					wh.Error404(w, "")
+				case wallet.ErrWalletAPIDisabled:
+					wh.Error403(w, "")
+				default:
+					wh.Error500(w, err.Error())
+				}
+				return
+			}
+			defer unsubscribe()
+			walletEvents = events
+		default:
+			wh.Error400(w, "invalid topic")
+			return
+		}
+
+		conn, err := websocket.Upgrade(w, r)
+		if err != nil {
+			if err == websocket.ErrNotWebsocket {
+				wh.Error400(w, err.Error())
+				return
+			}
+			logger.WithError(err).Error("websocket.Upgrade failed")
+			return
+		}
+		defer conn.Close() // nolint: errcheck
+
+		for {
+			var event interface{}
+			select {
+			case <-conn.Done():
+				return
+			case e, ok := <-connectionEvents:
+				if !ok {
+					return
+				}
+				event = readable.NewConnectionEvent(e)
+			case e, ok := <-walletEvents:
+				if !ok {
+					return
+				}
+
+				event, err = NewWalletEvent(e)
+				if err != nil {
+					logger.WithError(err).Error("NewWalletEvent failed")
+					return
+				}
+			}
+
+			if err := conn.WriteJSON(WebsocketMessage{
+				Topic: topic,
+				Event: event,
+			}); err != nil {
+				logger.WithError(err).Debug("websocket write failed")
+				return
+			}
+		}
+	}
+}
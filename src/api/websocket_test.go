@@ -0,0 +1,262 @@
+package api
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/daemon"
+	"github.com/skycoin/skycoin/src/readable"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+func TestWebsocketErrors(t *testing.T) {
+	tt := []struct {
+		name      string
+		method    string
+		query     string
+		walletErr error
+		status    int
+		err       string
+	}{
+		{
+			name:   "405",
+			method: http.MethodPost,
+			query:  "?topic=connections",
+			status: http.StatusMethodNotAllowed,
+			err:    "405 Method Not Allowed",
+		},
+		{
+			name:   "400 missing topic",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - topic is required",
+		},
+		{
+			name:   "400 invalid topic",
+			method: http.MethodGet,
+			query:  "?topic=blocks",
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - invalid topic",
+		},
+		{
+			name:   "400 not a websocket",
+			method: http.MethodGet,
+			query:  "?topic=connections",
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - Not a websocket handshake request",
+		},
+		{
+			name:   "400 missing wallet id",
+			method: http.MethodGet,
+			query:  "?topic=wallet",
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - missing wallet id",
+		},
+		{
+			name:      "403 wallet api disabled",
+			method:    http.MethodGet,
+			query:     "?topic=wallet&id=foo.wlt",
+			walletErr: wallet.ErrWalletAPIDisabled,
+			status:    http.StatusForbidden,
+			err:       "403 Forbidden",
+		},
+		{
+			name:      "404 wallet not exist",
+			method:    http.MethodGet,
+			query:     "?topic=wallet&id=foo.wlt",
+			walletErr: wallet.ErrWalletNotExist,
+			status:    http.StatusNotFound,
+			err:       "404 Not Found",
+		},
+		{
+			name:   "400 wallet not a websocket",
+			method: http.MethodGet,
+			query:  "?topic=wallet&id=foo.wlt",
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - Not a websocket handshake request",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			endpoint := "/api/v1/websocket"
+			gateway := &MockGatewayer{}
+			unsubscribed := false
+			gateway.On("SubscribeConnectionEvents").Return(make(<-chan daemon.ConnectionEvent), func() {
+				unsubscribed = true
+			})
+			gateway.On("SubscribeWalletEvents", "foo.wlt").Return(make(<-chan visor.WalletEvent), func() {
+				unsubscribed = true
+			}, tc.walletErr)
+
+			req, err := http.NewRequest(tc.method, endpoint+tc.query, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil)
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code)
+			require.Equal(t, tc.err, strings.TrimSpace(rr.Body.String()))
+
+			if len(gateway.Calls) != 0 && tc.walletErr == nil {
+				require.True(t, unsubscribed)
+			}
+		})
+	}
+}
+
+func TestWebsocketConnectionEvents(t *testing.T) {
+	events := make(chan daemon.ConnectionEvent, 1)
+	unsubscribed := make(chan struct{})
+
+	gateway := &MockGatewayer{}
+	gateway.On("SubscribeConnectionEvents").Return((<-chan daemon.ConnectionEvent)(events), func() {
+		close(unsubscribed)
+	})
+
+	s := httptest.NewServer(newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil))
+	defer s.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(s.URL, "http://"))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("GET /api/v1/websocket?topic=connections HTTP/1.1\r\nHost: " + configuredHost + "\r\n" +
+		"Connection: Upgrade\r\nUpgrade: websocket\r\nSec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n"))
+	require.NoError(t, err)
+
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+
+	events <- daemon.ConnectionEvent{
+		Type:     daemon.ConnectionEventDisconnected,
+		Time:     time.Unix(1540000000, 0),
+		Addr:     "1.2.3.4:6000",
+		GnetID:   7,
+		Outgoing: true,
+		Reason:   daemon.ErrDisconnectIdle.Error(),
+	}
+
+	// The server sends a single unmasked text frame, with a 16 bit length
+	var header [4]byte
+	_, err = io.ReadFull(r, header[:])
+	require.NoError(t, err)
+	require.Equal(t, byte(0x81), header[0])
+	require.Equal(t, byte(126), header[1])
+
+	payload := make([]byte, binary.BigEndian.Uint16(header[2:]))
+	_, err = io.ReadFull(r, payload)
+	require.NoError(t, err)
+
+	var msg struct {
+		Topic string                   `json:"topic"`
+		Event readable.ConnectionEvent `json:"event"`
+	}
+	require.NoError(t, json.Unmarshal(payload, &msg))
+	require.Equal(t, "connections", msg.Topic)
+	require.Equal(t, readable.ConnectionEvent{
+		Type:     daemon.ConnectionEventDisconnected,
+		Time:     1540000000,
+		Addr:     "1.2.3.4:6000",
+		GnetID:   7,
+		Outgoing: true,
+		Reason:   daemon.ErrDisconnectIdle.Error(),
+	}, msg.Event)
+
+	// The subscription ends when the client goes away
+	require.NoError(t, conn.Close())
+	select {
+	case <-unsubscribed:
+	case <-time.After(time.Second * 5):
+		t.Fatal("subscription was not closed")
+	}
+}
+
+func TestWebsocketWalletEvents(t *testing.T) {
+	events := make(chan visor.WalletEvent, 1)
+	unsubscribed := make(chan struct{})
+
+	gateway := &MockGatewayer{}
+	gateway.On("SubscribeWalletEvents", "foo.wlt").Return((<-chan visor.WalletEvent)(events), func() {
+		close(unsubscribed)
+	}, nil)
+
+	s := httptest.NewServer(newServerMux(defaultMuxConfig(), gateway, &CSRFStore{}, nil))
+	defer s.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(s.URL, "http://"))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("GET /api/v1/websocket?topic=wallet&id=foo.wlt HTTP/1.1\r\nHost: " + configuredHost + "\r\n" +
+		"Connection: Upgrade\r\nUpgrade: websocket\r\nSec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n"))
+	require.NoError(t, err)
+
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+
+	addr := testutil.MakeAddress()
+	txnID := testutil.RandSHA256(t)
+	events <- visor.WalletEvent{
+		ID:        3,
+		Type:      visor.WalletEventUnconfirmed,
+		WalletID:  "foo.wlt",
+		TxnID:     txnID,
+		Time:      1540000000,
+		Amount:    1e6,
+		Addresses: []cipher.Address{addr},
+	}
+
+	// The server sends a single unmasked text frame, with a 16 bit length
+	var header [4]byte
+	_, err = io.ReadFull(r, header[:])
+	require.NoError(t, err)
+	require.Equal(t, byte(0x81), header[0])
+	require.Equal(t, byte(126), header[1])
+
+	payload := make([]byte, binary.BigEndian.Uint16(header[2:]))
+	_, err = io.ReadFull(r, payload)
+	require.NoError(t, err)
+
+	var msg struct {
+		Topic string      `json:"topic"`
+		Event WalletEvent `json:"event"`
+	}
+	require.NoError(t, json.Unmarshal(payload, &msg))
+	require.Equal(t, "wallet", msg.Topic)
+	require.Equal(t, WalletEvent{
+		ID:        3,
+		Type:      visor.WalletEventUnconfirmed,
+		WalletID:  "foo.wlt",
+		TxnID:     txnID.Hex(),
+		Amount:    "1.000000",
+		Addresses: []string{addr.String()},
+		Time:      1540000000,
+	}, msg.Event)
+
+	// The subscription ends when the client goes away
+	require.NoError(t, conn.Close())
+	select {
+	case <-unsubscribed:
+	case <-time.After(time.Second * 5):
+		t.Fatal("subscription was not closed")
+	}
+}
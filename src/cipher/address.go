@@ -106,7 +106,7 @@ func MustDecodeBase58Address(addr string) Address {
 	return a
 }
 
-// AddressFromBytes converts []byte to an Address
+// AddressFromBytes converts []byte to an Address. The version must be 0, or MultisigAddressVersion
 func AddressFromBytes(b []byte) (Address, error) {
 	if len(b) != 20+1+4 {
 		return Address{}, ErrAddressInvalidLength
@@ -123,7 +123,7 @@ func AddressFromBytes(b []byte) (Address, error) {
 		return Address{}, ErrAddressInvalidChecksum
 	}
 
-	if a.Version != 0 {
+	if a.Version != 0 && a.Version != MultisigAddressVersion {
 		return Address{}, ErrAddressInvalidVersion
 	}
 
@@ -348,6 +348,11 @@ func (s Sig) Hex() string {
 	return hex.EncodeToString(s[:])
 }
 
+// Null returns true if Sig is the null Sig
+func (s Sig) Null() bool {
+	return s == Sig{}
+}
+
 // SignHash sign hash
 func SignHash(hash SHA256, sec SecKey) (Sig, error) {
 	if secp256k1.VerifySeckey(sec[:]) != 1 {
@@ -624,6 +624,25 @@ func (d *decoder) int64() (int64, error) {
 
 func (e *encoder) int64(x int64) { e.uint64(uint64(x)) }
 
+// fits returns ErrBufferUnderflow if the buffer is too short for length elements made of values of types ts.
+// A value is encoded in at least the size of its zero value, so a bogus length is rejected before the elements are allocated
+func (d *decoder) fits(length int, ts ...reflect.Type) error {
+	size := 0
+	for _, t := range ts {
+		n, err := datasizeWrite(reflect.Zero(t))
+		if err != nil {
+			return err
+		}
+		size += n
+	}
+
+	if size*length > len(d.buf) {
+		return ErrBufferUnderflow
+	}
+
+	return nil
+}
+
 func (d *decoder) value(v reflect.Value, maxlen int) error {
 	kind := v.Kind()
 	switch kind {
@@ -670,6 +689,10 @@ func (d *decoder) value(v reflect.Value, maxlen int) error {
 		key := t.Key()
 		elem := t.Elem()
 
+		if err := d.fits(length, key, elem); err != nil {
+			return err
+		}
+
 		if v.IsNil() {
 			v.Set(reflect.Indirect(reflect.MakeMap(t)))
 		}
@@ -697,6 +720,10 @@ func (d *decoder) value(v reflect.Value, maxlen int) error {
 		}
 
 		length := int(ul)
+		if maxlen > 0 && length > maxlen {
+			return ErrMaxLenExceeded
+		}
+
 		if length < 0 || length > len(d.buf) {
 			return ErrBufferUnderflow
 		}
@@ -705,10 +732,6 @@ func (d *decoder) value(v reflect.Value, maxlen int) error {
 			return nil
 		}
 
-		if maxlen > 0 && length > maxlen {
-			return ErrMaxLenExceeded
-		}
-
 		t := v.Type()
 		elem := t.Elem()
 
@@ -717,6 +740,10 @@ func (d *decoder) value(v reflect.Value, maxlen int) error {
 			v.SetBytes(d.buf[:length])
 			d.buf = d.buf[length:]
 		default:
+			if err := d.fits(length, elem); err != nil {
+				return err
+			}
+
 			elemvs := reflect.MakeSlice(t, length, length)
 			for i := 0; i < length; i++ {
 				elemv := reflect.Indirect(elemvs.Index(i))
@@ -776,14 +803,14 @@ func (d *decoder) value(v reflect.Value, maxlen int) error {
 		}
 
 		length := int(ul)
-		if length < 0 || length > len(d.buf) {
-			return ErrBufferUnderflow
-		}
-
 		if maxlen > 0 && length > maxlen {
 			return ErrMaxLenExceeded
 		}
 
+		if length < 0 || length > len(d.buf) {
+			return ErrBufferUnderflow
+		}
+
 		v.SetString(string(d.buf[:length]))
 		d.buf = d.buf[length:]
 
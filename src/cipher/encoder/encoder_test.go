@@ -1264,6 +1264,46 @@ func TestDeserializeMaxLenExceeded(t *testing.T) {
 	require.Equal(t, v, w)
 }
 
+func TestDeserializeLengthBeforeAllocation(t *testing.T) {
+	type Elem struct {
+		A uint64
+		B [32]byte
+		C []byte
+	}
+
+	// A length is rejected if the buffer can't hold that many elements of the minimum encoded size,
+	// before the elements are allocated
+	type Foo struct {
+		X []Elem
+	}
+
+	b := Serialize(Foo{X: []Elem{{A: 1}, {A: 2, C: []byte("c")}}})
+	var f Foo
+	require.NoError(t, DeserializeRaw(b, &f))
+	require.Len(t, f.X, 2)
+
+	// The minimum size of an Elem is 8+32+4 bytes, the bytes of 10 elements cannot hold 11
+	bogus := append(SerializeAtomic(uint32(11)), make([]byte, 10*44)...)
+	f = Foo{}
+	require.Equal(t, ErrBufferUnderflow, DeserializeRaw(bogus, &f))
+
+	type Bar struct {
+		X map[uint64]Elem
+	}
+
+	bogus = append(SerializeAtomic(uint32(5)), make([]byte, 4*52)...)
+	var g Bar
+	require.Equal(t, ErrBufferUnderflow, DeserializeRaw(bogus, &g))
+
+	// The maximum length of a field is checked before the buffer length
+	type Baz struct {
+		X []uint64 `enc:",maxlen=4"`
+	}
+
+	var z Baz
+	require.Equal(t, ErrMaxLenExceeded, DeserializeRaw(SerializeAtomic(uint32(1<<30)), &z))
+}
+
 func TestSerializeString(t *testing.T) {
 	cases := []struct {
 		s string
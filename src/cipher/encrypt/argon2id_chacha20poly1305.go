@@ -0,0 +1,252 @@
+package encrypt
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/argon2"
+	"github.com/skycoin/skycoin/src/cipher/chacha20poly1305"
+)
+
+const (
+	// Argon2idChacha20poly1305Version is the version of the envelope format of Argon2idChacha20poly1305
+	Argon2idChacha20poly1305Version = 1
+
+	argon2idChacha20VersionSize    = 1  // envelope version field size in bytes
+	argon2idChacha20MetaLengthSize = 2  // meta data length field size in bytes
+	argon2idChacha20SaltSize       = 32 // salt bytes number
+
+	// KDFArgon2id is the name of the argon2id key derivation function in the envelope metadata
+	KDFArgon2id = "argon2id"
+)
+
+// Default argon2id parameters, the second recommended option of RFC 9106
+const (
+	// Argon2idTime: the number of passes over the memory.
+	Argon2idTime = 3
+	// Argon2idMemory: the memory size in KiB, 64 MiB.
+	Argon2idMemory = 64 * 1024
+	// Argon2idThreads: the number of threads, each thread fills a lane of the memory.
+	Argon2idThreads = 4
+	// Argon2idKeyLen: The length of returned byte slice that can be used as cryptographic key.
+	Argon2idKeyLen = 32
+)
+
+// Bounds of the argon2id parameters accepted when decrypting, so that a tampered envelope
+// can not make the key derivation exhaust the memory or the CPU before the authentication fails
+const (
+	// Argon2idMaxTime is the maximum number of passes
+	Argon2idMaxTime = 64
+	// Argon2idMaxMemory is the maximum memory size in KiB, 4 GiB
+	Argon2idMaxMemory = 4 * 1024 * 1024
+)
+
+var (
+	// ErrArgon2idChacha20poly1305Version is returned when decrypting data of an unknown envelope version
+	ErrArgon2idChacha20poly1305Version = errors.New("unsupported argon2id-chacha20poly1305 envelope version")
+	// ErrInvalidArgon2idParams is returned if the argon2id parameters are out of bounds
+	ErrInvalidArgon2idParams = errors.New("invalid argon2id parameters")
+)
+
+// DefaultArgon2idChacha20poly1305 default Argon2idChacha20poly1305 encryptor
+var DefaultArgon2idChacha20poly1305 = Argon2idChacha20poly1305{
+	Time:    Argon2idTime,
+	Memory:  Argon2idMemory,
+	Threads: Argon2idThreads,
+	KeyLen:  Argon2idKeyLen,
+}
+
+// Argon2idChacha20poly1305 provides methods for encryption/decryption with argon2id and chacha20poly1305
+type Argon2idChacha20poly1305 struct {
+	Time    uint32
+	Memory  uint32
+	Threads uint8
+	KeyLen  uint32
+}
+
+// Validate checks that the argon2id parameters are within bounds
+func (a Argon2idChacha20poly1305) Validate() error {
+	switch {
+	case a.Time == 0 || a.Time > Argon2idMaxTime:
+		return fmt.Errorf("%v: time must be between 1 and %d", ErrInvalidArgon2idParams, Argon2idMaxTime)
+	case a.Threads == 0:
+		return fmt.Errorf("%v: threads must be positive", ErrInvalidArgon2idParams)
+	case a.Memory < 8*uint32(a.Threads) || a.Memory > Argon2idMaxMemory:
+		return fmt.Errorf("%v: memory must be between 8*threads and %d KiB", ErrInvalidArgon2idParams, Argon2idMaxMemory)
+	case a.KeyLen != chacha20poly1305.KeySize:
+		return fmt.Errorf("%v: key length must be %d", ErrInvalidArgon2idParams, chacha20poly1305.KeySize)
+	default:
+		return nil
+	}
+}
+
+type argon2idMeta struct {
+	KDF     string `json:"kdf"`
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"`
+	Threads uint8  `json:"threads"`
+	KeyLen  uint32 `json:"keyLen"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+}
+
+// Encrypt encrypts data with password,
+// 1. Argon2id derives the key from password
+// 2. Chacha20poly1305 generates AEAD from the derived key
+// 3. Puts the kdf name, argon2id paramenters, salt and nonce into metadata, json serialize it and get the serialized metadata length
+// 4. AEAD.Seal encrypts the data, and use [version][length][metadata] as additional data
+// 5. Final format: base64([[version][length][metadata]][ciphertext]), version is 1 byte and length is 2 bytes.
+// The envelope version and the metadata are authenticated, tampering with them fails the decryption.
+func (a Argon2idChacha20poly1305) Encrypt(data, password []byte) ([]byte, error) {
+	if len(password) == 0 {
+		return nil, errors.New("missing password")
+	}
+
+	if err := a.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Argon2id derives key from password
+	salt := cipher.RandByte(argon2idChacha20SaltSize)
+	dk := argon2.IDKey(password, salt, a.Time, a.Memory, a.Threads, a.KeyLen)
+
+	// Prepare metadata
+	m := argon2idMeta{
+		KDF:     KDFArgon2id,
+		Time:    a.Time,
+		Memory:  a.Memory,
+		Threads: a.Threads,
+		KeyLen:  a.KeyLen,
+		Salt:    salt,
+		Nonce:   cipher.RandByte(chacha20poly1305.NonceSize),
+	}
+	// json serialize the metadata
+	ms, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ms) > math.MaxUint16 {
+		return nil, errors.New("metadata length beyond the math.MaxUint16")
+	}
+
+	header := make([]byte, argon2idChacha20VersionSize+argon2idChacha20MetaLengthSize)
+	header[0] = Argon2idChacha20poly1305Version
+	binary.LittleEndian.PutUint16(header[argon2idChacha20VersionSize:], uint16(len(ms)))
+
+	// Additional data for AEAD
+	ad := append(header, ms...)
+	aead, err := chacha20poly1305.New(dk)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext := aead.Seal(nil, m.Nonce, data, ad)
+
+	// Base64 encode the [[version][length][metadata]][ciphertext]
+	rawData := append(ad, ciphertext...)
+	enc := base64.StdEncoding
+	buf := make([]byte, enc.EncodedLen(len(rawData)))
+	enc.Encode(buf, rawData)
+	return buf, nil
+}
+
+// Decrypt decrypts the data with password
+// 1. Base64 decodes the data
+// 2. Checks the envelope version, reads the metadata length and reads out the metadata.
+// 3. Argon2id derives key from password and paramenters in metadata
+// 4. Chacha20poly1305 geneates AEAD
+// 5. AEAD decrypts ciphertext with nonce in metadata and [version][length][metadata] as additional data.
+func (a Argon2idChacha20poly1305) Decrypt(data, password []byte) ([]byte, error) {
+	if len(password) == 0 {
+		return nil, errors.New("missing password")
+	}
+
+	encData, m, headerLen, err := decodeArgon2idChacha20poly1305(data)
+	if err != nil {
+		return nil, err
+	}
+
+	ad := encData[:headerLen]
+	// Argon2id derives key
+	dk := argon2.IDKey(password, m.Salt, m.Time, m.Memory, m.Threads, m.KeyLen)
+
+	// Geneates AEAD
+	aead, err := chacha20poly1305.New(dk)
+	if err != nil {
+		return nil, err
+	}
+
+	return aead.Open(nil, m.Nonce, encData[headerLen:], ad)
+}
+
+// Argon2idChacha20poly1305Params returns the argon2id parameters that the data was encrypted with
+func Argon2idChacha20poly1305Params(data []byte) (Argon2idChacha20poly1305, error) {
+	_, m, _, err := decodeArgon2idChacha20poly1305(data)
+	if err != nil {
+		return Argon2idChacha20poly1305{}, err
+	}
+
+	return Argon2idChacha20poly1305{
+		Time:    m.Time,
+		Memory:  m.Memory,
+		Threads: m.Threads,
+		KeyLen:  m.KeyLen,
+	}, nil
+}
+
+// decodeArgon2idChacha20poly1305 base64 decodes the data and parses the envelope header.
+// Returns the decoded data, the metadata and the length of the header, which is the additional data of the AEAD.
+func decodeArgon2idChacha20poly1305(data []byte) ([]byte, *argon2idMeta, int, error) {
+	enc := base64.StdEncoding
+	encData := make([]byte, enc.DecodedLen(len(data)))
+	n, err := enc.Decode(encData, data)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	encData = encData[:n]
+
+	if len(encData) < argon2idChacha20VersionSize+argon2idChacha20MetaLengthSize {
+		return nil, nil, 0, errors.New("invalid data length")
+	}
+
+	if encData[0] != Argon2idChacha20poly1305Version {
+		return nil, nil, 0, ErrArgon2idChacha20poly1305Version
+	}
+
+	length := int(binary.LittleEndian.Uint16(encData[argon2idChacha20VersionSize:]))
+	headerLen := argon2idChacha20VersionSize + argon2idChacha20MetaLengthSize + length
+	if headerLen > len(encData) {
+		return nil, nil, 0, errors.New("invalid metadata length")
+	}
+
+	var m argon2idMeta
+	if err := json.Unmarshal(encData[argon2idChacha20VersionSize+argon2idChacha20MetaLengthSize:headerLen], &m); err != nil {
+		return nil, nil, 0, err
+	}
+
+	if m.KDF != KDFArgon2id {
+		return nil, nil, 0, fmt.Errorf("unsupported kdf %q", m.KDF)
+	}
+
+	if len(m.Nonce) != chacha20poly1305.NonceSize {
+		return nil, nil, 0, errors.New("invalid nonce length")
+	}
+
+	params := Argon2idChacha20poly1305{
+		Time:    m.Time,
+		Memory:  m.Memory,
+		Threads: m.Threads,
+		KeyLen:  m.KeyLen,
+	}
+	if err := params.Validate(); err != nil {
+		return nil, nil, 0, err
+	}
+
+	return encData, &m, headerLen, nil
+}
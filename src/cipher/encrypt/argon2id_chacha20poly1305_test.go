@@ -0,0 +1,180 @@
+package encrypt
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testArgon2id uses cheap parameters to keep the tests fast
+var testArgon2id = Argon2idChacha20poly1305{
+	Time:    1,
+	Memory:  64,
+	Threads: 1,
+	KeyLen:  32,
+}
+
+func TestArgon2idChacha20poly1305Encrypt(t *testing.T) {
+	encData, err := testArgon2id.Encrypt([]byte("plaintext"), []byte("password"))
+	require.NoError(t, err)
+
+	data, err := base64.StdEncoding.DecodeString(string(encData))
+	require.NoError(t, err)
+
+	// Checks the header
+	require.Equal(t, byte(Argon2idChacha20poly1305Version), data[0])
+	ml := int(binary.LittleEndian.Uint16(data[argon2idChacha20VersionSize:]))
+	headerLen := argon2idChacha20VersionSize + argon2idChacha20MetaLengthSize + ml
+	require.True(t, headerLen <= len(data))
+
+	var m argon2idMeta
+	require.NoError(t, json.Unmarshal(data[argon2idChacha20VersionSize+argon2idChacha20MetaLengthSize:headerLen], &m))
+	require.Equal(t, KDFArgon2id, m.KDF)
+	require.Equal(t, uint32(1), m.Time)
+	require.Equal(t, uint32(64), m.Memory)
+	require.Equal(t, uint8(1), m.Threads)
+	require.Equal(t, uint32(32), m.KeyLen)
+	require.Len(t, m.Salt, argon2idChacha20SaltSize)
+
+	params, err := Argon2idChacha20poly1305Params(encData)
+	require.NoError(t, err)
+	require.Equal(t, testArgon2id, params)
+
+	// The params of the encryptor do not matter when decrypting, they are read from the envelope
+	plaintext, err := DefaultArgon2idChacha20poly1305.Decrypt(encData, []byte("password"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("plaintext"), plaintext)
+
+	_, err = testArgon2id.Encrypt([]byte("plaintext"), nil)
+	require.Equal(t, errors.New("missing password"), err)
+
+	_, err = Argon2idChacha20poly1305{Time: 1, Memory: 64, Threads: 1, KeyLen: 16}.Encrypt([]byte("plaintext"), []byte("password"))
+	require.Error(t, err)
+}
+
+func TestArgon2idChacha20poly1305Decrypt(t *testing.T) {
+	encData, err := testArgon2id.Encrypt([]byte("plaintext"), []byte("pwd"))
+	require.NoError(t, err)
+
+	data, err := base64.StdEncoding.DecodeString(string(encData))
+	require.NoError(t, err)
+
+	reencode := func(f func(d []byte) []byte) []byte {
+		d := f(append([]byte{}, data...))
+		return []byte(base64.StdEncoding.EncodeToString(d))
+	}
+
+	tamperMeta := func(f func(m *argon2idMeta)) []byte {
+		ml := int(binary.LittleEndian.Uint16(data[argon2idChacha20VersionSize:]))
+		headerLen := argon2idChacha20VersionSize + argon2idChacha20MetaLengthSize + ml
+		var m argon2idMeta
+		require.NoError(t, json.Unmarshal(data[argon2idChacha20VersionSize+argon2idChacha20MetaLengthSize:headerLen], &m))
+		f(&m)
+		ms, err := json.Marshal(m)
+		require.NoError(t, err)
+
+		d := []byte{Argon2idChacha20poly1305Version, 0, 0}
+		binary.LittleEndian.PutUint16(d[argon2idChacha20VersionSize:], uint16(len(ms)))
+		d = append(d, ms...)
+		d = append(d, data[headerLen:]...)
+		return []byte(base64.StdEncoding.EncodeToString(d))
+	}
+
+	tt := []struct {
+		name    string
+		encData []byte
+		pwd     []byte
+		err     error
+	}{
+		{
+			name:    "ok",
+			encData: encData,
+			pwd:     []byte("pwd"),
+		},
+		{
+			name:    "invalid password",
+			encData: encData,
+			pwd:     []byte("wrong password"),
+			err:     errors.New("chacha20poly1305: message authentication failed"),
+		},
+		{
+			name:    "missing password",
+			encData: encData,
+			err:     errors.New("missing password"),
+		},
+		{
+			name: "unknown version",
+			encData: reencode(func(d []byte) []byte {
+				d[0] = 2
+				return d
+			}),
+			pwd: []byte("pwd"),
+			err: ErrArgon2idChacha20poly1305Version,
+		},
+		{
+			name: "invalid metadata length",
+			encData: reencode(func(d []byte) []byte {
+				binary.LittleEndian.PutUint16(d[argon2idChacha20VersionSize:], uint16(len(d)))
+				return d
+			}),
+			pwd: []byte("pwd"),
+			err: errors.New("invalid metadata length"),
+		},
+		{
+			name: "too short",
+			encData: reencode(func(d []byte) []byte {
+				return d[:2]
+			}),
+			pwd: []byte("pwd"),
+			err: errors.New("invalid data length"),
+		},
+		{
+			name: "tampered metadata",
+			encData: tamperMeta(func(m *argon2idMeta) {
+				m.Time = 2
+			}),
+			pwd: []byte("pwd"),
+			err: errors.New("chacha20poly1305: message authentication failed"),
+		},
+		{
+			name: "unknown kdf",
+			encData: tamperMeta(func(m *argon2idMeta) {
+				m.KDF = "scrypt"
+			}),
+			pwd: []byte("pwd"),
+			err: errors.New(`unsupported kdf "scrypt"`),
+		},
+		{
+			name: "memory out of bounds",
+			encData: tamperMeta(func(m *argon2idMeta) {
+				m.Memory = Argon2idMaxMemory + 1
+			}),
+			pwd: []byte("pwd"),
+			err: errors.New("invalid argon2id parameters: memory must be between 8*threads and 4194304 KiB"),
+		},
+		{
+			name: "invalid nonce",
+			encData: tamperMeta(func(m *argon2idMeta) {
+				m.Nonce = m.Nonce[:4]
+			}),
+			pwd: []byte("pwd"),
+			err: errors.New("invalid nonce length"),
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			plaintext, err := Argon2idChacha20poly1305{}.Decrypt(tc.encData, tc.pwd)
+			require.Equal(t, tc.err, err)
+			if err != nil {
+				return
+			}
+
+			require.Equal(t, []byte("plaintext"), plaintext)
+		})
+	}
+}
@@ -3,6 +3,7 @@ Package encrypt provides encryption methods.
 
 Encryption methods provided:
 
+* chacha20-poly1305 with argon2id key derivation, in a versioned envelope
 * chacha20-poly1305 with scrypt key derivation
 * sha256xor with sha256 key derivation
 
@@ -169,3 +170,35 @@ func (s ScryptChacha20poly1305) Decrypt(data, password []byte) ([]byte, error) {
 
 	return aead.Open(nil, m.Nonce, encData[scryptChacha20MetaLengthSize+length:], ad)
 }
+
+// ScryptChacha20poly1305Params returns the scrypt parameters that the data was encrypted with
+func ScryptChacha20poly1305Params(data []byte) (ScryptChacha20poly1305, error) {
+	enc := base64.StdEncoding
+	encData := make([]byte, enc.DecodedLen(len(data)))
+	n, err := enc.Decode(encData, data)
+	if err != nil {
+		return ScryptChacha20poly1305{}, err
+	}
+	encData = encData[:n]
+
+	if len(encData) < scryptChacha20MetaLengthSize {
+		return ScryptChacha20poly1305{}, errors.New("invalid data length")
+	}
+
+	length := binary.LittleEndian.Uint16(encData[:scryptChacha20MetaLengthSize])
+	if int(scryptChacha20MetaLengthSize+length) > len(encData) {
+		return ScryptChacha20poly1305{}, errors.New("invalid metadata length")
+	}
+
+	var m meta
+	if err := json.Unmarshal(encData[scryptChacha20MetaLengthSize:scryptChacha20MetaLengthSize+length], &m); err != nil {
+		return ScryptChacha20poly1305{}, err
+	}
+
+	return ScryptChacha20poly1305{
+		N:      m.N,
+		R:      m.R,
+		P:      m.P,
+		KeyLen: m.KeyLen,
+	}, nil
+}
@@ -5,13 +5,18 @@ package bip39
 
 import (
 	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"math/big"
 	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
 
 	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/pbkdf2"
 )
 
 // Some bitwise operands for working with big.Ints
@@ -25,6 +30,44 @@ var (
 // DefaultMnemonicEntropyBitSize is the default bit size for NewDefaultMnemonic's entropy
 const DefaultMnemonicEntropyBitSize = 128
 
+var (
+	// ErrSurroundingWhitespace is returned by ValidateMnemonic if the mnemonic has leading or trailing whitespace
+	ErrSurroundingWhitespace = errors.New("Mnemonic must not have leading or trailing whitespace")
+	// ErrConsecutiveWhitespace is returned by ValidateMnemonic if the words of the mnemonic are not separated by single spaces
+	ErrConsecutiveWhitespace = errors.New("Mnemonic words must be separated by single spaces")
+	// ErrInvalidNumberOfWords is returned by ValidateMnemonic if the mnemonic does not have 12, 15, 18, 21 or 24 words
+	ErrInvalidNumberOfWords = errors.New("Mnemonic must have 12, 15, 18, 21 or 24 words")
+	// ErrChecksumMismatch is returned by ValidateMnemonic if all the words are valid but the checksum of the mnemonic is not
+	ErrChecksumMismatch = errors.New("Mnemonic checksum is invalid, check the order and the spelling of the words")
+)
+
+// UnknownWordError is returned by ValidateMnemonic if a word of the mnemonic is not in the wordlist.
+// The word itself is not part of the error message, so that the error can be logged
+type UnknownWordError struct {
+	// Index is the position of the word in the mnemonic, starting from 0
+	Index int
+	// Language is the language of the wordlist
+	Language string
+}
+
+func (e UnknownWordError) Error() string {
+	return fmt.Sprintf("Mnemonic word %d is not in the %s wordlist", e.Index+1, e.Language)
+}
+
+// MnemonicWordCounts are the valid numbers of words of a mnemonic
+var MnemonicWordCounts = []int{12, 15, 18, 21, 24}
+
+// EntropyBitSizeForWords returns the entropy bit size of a mnemonic with n words
+func EntropyBitSizeForWords(n int) (int, error) {
+	for _, c := range MnemonicWordCounts {
+		if c == n {
+			// Each word is 11 bits, and the checksum is 1 bit for each 32 bits of entropy
+			return n * 11 * 32 / 33, nil
+		}
+	}
+	return 0, ErrInvalidNumberOfWords
+}
+
 // NewDefaultMnemonic returns a generated mnemomic using entropy with bitSize 128
 func NewDefaultMnemonic() (string, error) {
 	entropy, err := NewEntropy(DefaultMnemonicEntropyBitSize)
@@ -60,6 +103,20 @@ func NewEntropy(bitSize int) ([]byte, error) {
 // the given entropy.
 // If the provide entropy is invalid, an error will be returned.
 func NewMnemonic(entropy []byte) (string, error) {
+	return newMnemonic(entropy, WordList)
+}
+
+// NewMnemonicWithLanguage returns the mnemonic of the entropy in the wordlist of a language
+func NewMnemonicWithLanguage(entropy []byte, language string) (string, error) {
+	wl, err := getWordlist(language)
+	if err != nil {
+		return "", err
+	}
+
+	return newMnemonic(entropy, wl.words)
+}
+
+func newMnemonic(entropy []byte, wordList []string) (string, error) {
 	// Compute some lengths for convenience
 	entropyBitLength := len(entropy) * 8
 	checksumBitLength := entropyBitLength / 32
@@ -99,7 +156,7 @@ func NewMnemonic(entropy []byte) (string, error) {
 		wordBytes := padByteSlice(word.Bytes(), 2)
 
 		// Convert bytes to an index and add that word to the list
-		words[i] = WordList[binary.BigEndian.Uint16(wordBytes)]
+		words[i] = wordList[binary.BigEndian.Uint16(wordBytes)]
 	}
 
 	return strings.Join(words, " "), nil
@@ -180,20 +237,76 @@ func MnemonicToByteArray(mnemonic string) ([]byte, error) {
 }
 
 // NewSeedWithErrorChecking creates a hashed seed output given the mnemonic string and a password.
-// An error is returned if the mnemonic is not convertible to a byte array.
-// func NewSeedWithErrorChecking(mnemonic string, password string) ([]byte, error) {
-// 	_, err := MnemonicToByteArray(mnemonic)
-// 	if err != nil {
-// 		return nil, err
-// 	}
-// 	return NewSeed(mnemonic, password), nil
-// }
+// An error is returned if the mnemonic is not valid.
+func NewSeedWithErrorChecking(mnemonic string, password string) ([]byte, error) {
+	if err := ValidateMnemonic(mnemonic); err != nil {
+		return nil, err
+	}
+	return NewSeed(mnemonic, password), nil
+}
 
 // NewSeed creates a hashed seed output given a provided string and password.
+// The mnemonic and the password are NFKD normalized as BIP39 requires.
 // No checking is performed to validate that the string provided is a valid mnemonic.
-// func NewSeed(mnemonic string, password string) []byte {
-// 	return pbkdf2.Key([]byte(mnemonic), []byte("mnemonic"+password), 2048, 64, sha512.New)
-// }
+func NewSeed(mnemonic string, password string) []byte {
+	m := norm.NFKD.String(mnemonic)
+	salt := norm.NFKD.String("mnemonic" + password)
+	return pbkdf2.Key([]byte(m), []byte(salt), 2048, 64, sha512.New)
+}
+
+// ValidateMnemonic checks that the mnemonic is a valid BIP39 mnemonic in one of the registered wordlists.
+// Returns UnknownWordError if a word is not in the wordlist, and ErrChecksumMismatch
+// if all the words are valid but the checksum is not
+func ValidateMnemonic(mnemonic string) error {
+	_, err := MnemonicLanguage(mnemonic)
+	return err
+}
+
+// MnemonicLanguage validates the mnemonic like ValidateMnemonic and returns the language of its wordlist
+func MnemonicLanguage(mnemonic string) (string, error) {
+	if mnemonic != strings.TrimSpace(mnemonic) {
+		return "", ErrSurroundingWhitespace
+	}
+
+	words := strings.Split(mnemonic, " ")
+	for _, w := range words {
+		if w == "" || strings.IndexFunc(w, unicode.IsSpace) != -1 {
+			return "", ErrConsecutiveWhitespace
+		}
+	}
+
+	entropyBitSize, err := EntropyBitSizeForWords(len(words))
+	if err != nil {
+		return "", err
+	}
+
+	language, wl := detectWordlist(words)
+
+	// Each word is 11 bits of the entropy followed by the checksum
+	b := new(big.Int)
+	for i, w := range words {
+		index, ok := wl.index[w]
+		if !ok {
+			return "", UnknownWordError{
+				Index:    i,
+				Language: language,
+			}
+		}
+		b.Mul(b, RightShift11BitsDivider)
+		b.Or(b, big.NewInt(int64(index)))
+	}
+
+	checksumBitSize := uint(entropyBitSize / 32)
+	checksum := new(big.Int).And(b, big.NewInt(int64(1<<checksumBitSize)-1))
+	entropy := padByteSlice(new(big.Int).Rsh(b, checksumBitSize).Bytes(), entropyBitSize/8)
+
+	hash := sha256.Sum256(entropy)
+	if uint64(hash[0]>>(8-checksumBitSize)) != checksum.Uint64() {
+		return "", ErrChecksumMismatch
+	}
+
+	return language, nil
+}
 
 // Appends to data the first (len(data) / 32)bits of the result of sha256(data)
 // Currently only supports data up to 32 bytes
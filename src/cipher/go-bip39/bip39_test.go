@@ -1,6 +1,7 @@
 package bip39
 
 import (
+	"encoding/hex"
 	"strings"
 	"testing"
 
@@ -60,3 +61,176 @@ func TestIsMnemonicValid(t *testing.T) {
 	m = strings.Join(ms[:len(ms)-1], " ")
 	require.False(t, IsMnemonicValid(m))
 }
+
+// Test vectors of https://github.com/trezor/python-mnemonic/blob/master/vectors.json
+var testVectors = []struct {
+	entropy  string
+	mnemonic string
+	seed     string
+}{
+	{
+		entropy:  "00000000000000000000000000000000",
+		mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+		seed:     "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+	},
+	{
+		entropy:  "7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+		mnemonic: "legal winner thank year wave sausage worth useful legal winner thank yellow",
+		seed:     "2e8905819b8723fe2c1d161860e5ee1830318dbf49a83bd451cfb8440c28bd6fa457fe1296106559a3c80937a1c1069be3a3a5bd381ee6260e8d9739fce1f607",
+	},
+	{
+		entropy:  "ffffffffffffffffffffffffffffffff",
+		mnemonic: "zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong",
+		seed:     "ac27495480225222079d7be181583751e86f571027b0497b5b5d11218e0a8a13332572917f0f8e5a589620c6f15b11c61dee327651a14c34e18231052e48c069",
+	},
+	{
+		entropy:  "0000000000000000000000000000000000000000000000000000000000000000",
+		mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon art",
+		seed:     "bda85446c68413707090a52022edd26a1c9462295029f2e60cd7c4f2bbd3097170af7a4d73245cafa9c3cca8d561a7c3de6f5d4a10be8ed2a5e608d68f92fcc8",
+	},
+}
+
+func TestVectors(t *testing.T) {
+	for _, tc := range testVectors {
+		t.Run(tc.mnemonic, func(t *testing.T) {
+			entropy, err := hex.DecodeString(tc.entropy)
+			require.NoError(t, err)
+
+			m, err := NewMnemonicWithLanguage(entropy, DefaultLanguage)
+			require.NoError(t, err)
+			require.Equal(t, tc.mnemonic, m)
+
+			require.NoError(t, ValidateMnemonic(m))
+
+			seed, err := NewSeedWithErrorChecking(m, "TREZOR")
+			require.NoError(t, err)
+			require.Equal(t, tc.seed, hex.EncodeToString(seed))
+		})
+	}
+}
+
+func TestValidateMnemonic(t *testing.T) {
+	for _, n := range MnemonicWordCounts {
+		bitSize, err := EntropyBitSizeForWords(n)
+		require.NoError(t, err)
+		entropy, err := NewEntropy(bitSize)
+		require.NoError(t, err)
+		m, err := NewMnemonic(entropy)
+		require.NoError(t, err)
+		require.Len(t, strings.Split(m, " "), n)
+		require.NoError(t, ValidateMnemonic(m))
+	}
+
+	valid := testVectors[0].mnemonic
+	words := strings.Split(valid, " ")
+
+	cases := []struct {
+		name     string
+		mnemonic string
+		err      error
+	}{
+		{
+			name:     "leading whitespace",
+			mnemonic: " " + valid,
+			err:      ErrSurroundingWhitespace,
+		},
+		{
+			name:     "trailing whitespace",
+			mnemonic: valid + "\n",
+			err:      ErrSurroundingWhitespace,
+		},
+		{
+			name:     "consecutive whitespace",
+			mnemonic: strings.Join(words, "  "),
+			err:      ErrConsecutiveWhitespace,
+		},
+		{
+			name:     "tab separated",
+			mnemonic: strings.Join(words, "\t"),
+			err:      ErrConsecutiveWhitespace,
+		},
+		{
+			name:     "too few words",
+			mnemonic: strings.Join(words[:11], " "),
+			err:      ErrInvalidNumberOfWords,
+		},
+		{
+			name:     "13 words",
+			mnemonic: valid + " abandon",
+			err:      ErrInvalidNumberOfWords,
+		},
+		{
+			name:     "unknown word",
+			mnemonic: strings.Join(append(append([]string{}, words[:3]...), append([]string{"foo"}, words[4:]...)...), " "),
+			err: UnknownWordError{
+				Index:    3,
+				Language: DefaultLanguage,
+			},
+		},
+		{
+			name:     "bad checksum",
+			mnemonic: strings.Join(append(append([]string{}, words[:11]...), "abandon"), " "),
+			err:      ErrChecksumMismatch,
+		},
+		{
+			name:     "swapped words",
+			mnemonic: "legal winner thank year wave sausage worth useful legal winner yellow thank",
+			err:      ErrChecksumMismatch,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateMnemonic(tc.mnemonic)
+			require.Equal(t, tc.err, err)
+			require.False(t, IsMnemonicValid(tc.mnemonic) && tc.err != ErrChecksumMismatch)
+		})
+	}
+}
+
+func TestRegisterWordlist(t *testing.T) {
+	require.Equal(t, []string{DefaultLanguage}, Languages())
+
+	_, err := NewMnemonicWithLanguage(make([]byte, 16), "reversed")
+	require.Equal(t, ErrUnknownLanguage, err)
+
+	require.Error(t, RegisterWordlist("reversed", EnglishWordList[:2047]))
+	require.Error(t, RegisterWordlist("reversed", append(append([]string{}, EnglishWordList[:2047]...), "abandon")))
+
+	reversed := make([]string, len(EnglishWordList))
+	for i, w := range EnglishWordList {
+		r := []rune(w)
+		for j, k := 0, len(r)-1; j < k; j, k = j+1, k-1 {
+			r[j], r[k] = r[k], r[j]
+		}
+		reversed[i] = string(r)
+	}
+	require.NoError(t, RegisterWordlist("reversed", reversed))
+	defer func() {
+		wordlistsLock.Lock()
+		delete(wordlists, "reversed")
+		wordlistsLock.Unlock()
+	}()
+
+	require.Equal(t, []string{DefaultLanguage, "reversed"}, Languages())
+
+	wl, err := GetWordlist("reversed")
+	require.NoError(t, err)
+	require.Equal(t, reversed, wl)
+
+	m, err := NewMnemonicWithLanguage(make([]byte, 16), "reversed")
+	require.NoError(t, err)
+	require.Equal(t, "nodnaba nodnaba nodnaba nodnaba nodnaba nodnaba nodnaba nodnaba nodnaba nodnaba nodnaba tuoba", m)
+
+	language, err := MnemonicLanguage(m)
+	require.NoError(t, err)
+	require.Equal(t, "reversed", language)
+
+	// The wordlist is detected from all the words, so the error points to the unknown word
+	ms := strings.Split(m, " ")
+	ms[5] = "abandon"
+	require.Equal(t, UnknownWordError{
+		Index:    5,
+		Language: "reversed",
+	}, ValidateMnemonic(strings.Join(ms, " ")))
+}
@@ -1,7 +1,11 @@
 package bip39
 
 import (
+	"errors"
+	"fmt"
+	"sort"
 	"strings"
+	"sync"
 )
 
 // WordList The wordlist to use
@@ -10,10 +14,142 @@ var WordList = EnglishWordList
 // ReverseWordMap reverse word map
 var ReverseWordMap = map[string]int{}
 
+// DefaultLanguage is the language of the wordlist used by NewMnemonic
+const DefaultLanguage = "english"
+
+// wordlistSize is the number of words of a BIP39 wordlist, one for each 11 bit value
+const wordlistSize = 2048
+
+var (
+	// ErrUnknownLanguage is returned if no wordlist is registered for a language
+	ErrUnknownLanguage = errors.New("Unknown mnemonic language")
+
+	wordlistsLock sync.RWMutex
+	wordlists     = map[string]*wordlist{}
+)
+
+// wordlist is a BIP39 wordlist with the index of each word
+type wordlist struct {
+	words []string
+	index map[string]int
+}
+
+func newWordlist(words []string) (*wordlist, error) {
+	if len(words) != wordlistSize {
+		return nil, fmt.Errorf("Wordlist must have %d words, has %d", wordlistSize, len(words))
+	}
+
+	index := make(map[string]int, len(words))
+	for i, w := range words {
+		if w == "" || strings.TrimSpace(w) != w {
+			return nil, fmt.Errorf("Wordlist word %d is empty or has whitespace", i)
+		}
+		if _, ok := index[w]; ok {
+			return nil, fmt.Errorf("Wordlist word %q is duplicated", w)
+		}
+		index[w] = i
+	}
+
+	return &wordlist{
+		words: words,
+		index: index,
+	}, nil
+}
+
 func init() {
 	for i, v := range WordList {
 		ReverseWordMap[v] = i
 	}
+
+	if err := RegisterWordlist(DefaultLanguage, EnglishWordList); err != nil {
+		panic(err)
+	}
+}
+
+// RegisterWordlist registers the wordlist of a language, for generating and validating mnemonics in that language.
+// Only the english wordlist is built in; the other wordlists of BIP39
+// (https://github.com/bitcoin/bips/blob/master/bip-0039/bip-0039-wordlists.md) can be registered by the application.
+// The wordlist must have 2048 distinct words.
+// Registering a wordlist for a language that has one already replaces it.
+func RegisterWordlist(language string, words []string) error {
+	if language == "" {
+		return errors.New("Wordlist language must not be empty")
+	}
+
+	wl, err := newWordlist(words)
+	if err != nil {
+		return err
+	}
+
+	wordlistsLock.Lock()
+	defer wordlistsLock.Unlock()
+	wordlists[language] = wl
+	return nil
+}
+
+// GetWordlist returns the wordlist of a language
+func GetWordlist(language string) ([]string, error) {
+	wl, err := getWordlist(language)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]string{}, wl.words...), nil
+}
+
+// Languages returns the languages that have a wordlist, sorted
+func Languages() []string {
+	wordlistsLock.RLock()
+	defer wordlistsLock.RUnlock()
+
+	languages := make([]string, 0, len(wordlists))
+	for l := range wordlists {
+		languages = append(languages, l)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+func getWordlist(language string) (*wordlist, error) {
+	wordlistsLock.RLock()
+	defer wordlistsLock.RUnlock()
+
+	wl, ok := wordlists[language]
+	if !ok {
+		return nil, ErrUnknownLanguage
+	}
+	return wl, nil
+}
+
+// detectWordlist returns the language and the wordlist that has all the words.
+// Wordlists of different languages can share some words, so all the words are checked.
+// If no wordlist has all the words, the wordlist with the most words is returned
+func detectWordlist(words []string) (string, *wordlist) {
+	wordlistsLock.RLock()
+	defer wordlistsLock.RUnlock()
+
+	languages := make([]string, 0, len(wordlists))
+	for l := range wordlists {
+		languages = append(languages, l)
+	}
+	sort.Strings(languages)
+
+	bestLanguage := DefaultLanguage
+	best := -1
+	for _, l := range languages {
+		n := 0
+		for _, w := range words {
+			if _, ok := wordlists[l].index[w]; ok {
+				n++
+			}
+		}
+		if n > best || (n == best && l == DefaultLanguage) {
+			bestLanguage = l
+			best = n
+		}
+	}
+
+	return bestLanguage, wordlists[bestLanguage]
 }
 
 // EnglishWordList Language-specific wordlists
@@ -188,3 +188,44 @@ func Merkle(h0 []SHA256) SHA256 {
 	}
 	return h1[0]
 }
+
+// MerkleProof returns the sibling hashes on the path from the i-th hash of h0 to the merkle root computed by Merkle,
+// from the bottom of the tree up. The root is recomputed from the i-th hash with MerkleRootFromProof.
+func MerkleProof(h0 []SHA256, i uint64) ([]SHA256, error) {
+	lh := uint64(len(h0))
+	if i >= lh {
+		return nil, errors.New("Merkle proof index out of range")
+	}
+
+	np := nextPowerOfTwo(lh)
+	h1 := make([]SHA256, np)
+	copy(h1, h0)
+
+	var proof []SHA256
+	for len(h1) != 1 {
+		proof = append(proof, h1[i^1])
+
+		h2 := make([]SHA256, len(h1)/2)
+		for j := 0; j < len(h2); j++ {
+			h2[j] = AddSHA256(h1[2*j], h1[2*j+1])
+		}
+		h1 = h2
+		i /= 2
+	}
+
+	return proof, nil
+}
+
+// MerkleRootFromProof computes the merkle root of a hash array from its i-th hash h
+// and the proof returned by MerkleProof
+func MerkleRootFromProof(h SHA256, i uint64, proof []SHA256) SHA256 {
+	for _, p := range proof {
+		if i%2 == 0 {
+			h = AddSHA256(h, p)
+		} else {
+			h = AddSHA256(p, h)
+		}
+		i /= 2
+	}
+	return h
+}
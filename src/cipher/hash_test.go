@@ -333,3 +333,34 @@ func TestMerkle(t *testing.T) {
 		AddSHA256(SHA256{}, SHA256{})))
 	require.Equal(t, Merkle([]SHA256{h, h2, h3, h4, h5}), out)
 }
+
+func TestMerkleProof(t *testing.T) {
+	for n := 1; n <= 9; n++ {
+		hashes := make([]SHA256, n)
+		for i := range hashes {
+			hashes[i] = SumSHA256(randBytes(t, 128))
+		}
+		root := Merkle(append([]SHA256{}, hashes...))
+
+		depth := 0
+		for k := nextPowerOfTwo(uint64(n)); k > 1; k /= 2 {
+			depth++
+		}
+
+		for i := range hashes {
+			proof, err := MerkleProof(hashes, uint64(i))
+			require.NoError(t, err)
+			require.Len(t, proof, depth)
+			require.Equal(t, root, MerkleRootFromProof(hashes[i], uint64(i), proof))
+
+			// The proof doesn't hold for another hash or index
+			require.NotEqual(t, root, MerkleRootFromProof(SumSHA256(randBytes(t, 128)), uint64(i), proof))
+			if n > 1 {
+				require.NotEqual(t, root, MerkleRootFromProof(hashes[i], uint64((i+1)%n), proof))
+			}
+		}
+
+		_, err := MerkleProof(hashes, uint64(n))
+		require.Error(t, err)
+	}
+}
@@ -0,0 +1,110 @@
+package cipher
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+)
+
+const (
+	// MultisigAddressVersion is the version of the addresses of m-of-n multisignatures
+	MultisigAddressVersion byte = 0x01
+	// MaxMultisigPubKeys is the maximum number of public keys of a multisignature
+	MaxMultisigPubKeys = 16
+)
+
+var (
+	// ErrMultisigRequiredSigs is returned if the number of signatures required by a multisignature is invalid
+	ErrMultisigRequiredSigs = errors.New("Multisig required signatures must be between 1 and the number of public keys")
+	// ErrMultisigTooManyPubKeys is returned if a multisignature has too many public keys
+	ErrMultisigTooManyPubKeys = errors.New("Multisig has too many public keys")
+	// ErrMultisigPubKeysOrder is returned if the public keys of a multisignature are not sorted or have duplicates
+	ErrMultisigPubKeysOrder = errors.New("Multisig public keys must be sorted and unique")
+)
+
+// Multisig is an m-of-n multisignature: M signatures of the N public keys are required
+// to spend the outputs of its address. The public keys are sorted, so that the parties
+// derive the same address whatever the order they exchanged their keys in.
+type Multisig struct {
+	M       uint8
+	PubKeys []PubKey
+}
+
+// NewMultisig creates a Multisig requiring m signatures of pubKeys, which are sorted
+func NewMultisig(m int, pubKeys []PubKey) (Multisig, error) {
+	if len(pubKeys) > MaxMultisigPubKeys {
+		return Multisig{}, ErrMultisigTooManyPubKeys
+	}
+
+	if m < 1 || m > len(pubKeys) {
+		return Multisig{}, ErrMultisigRequiredSigs
+	}
+
+	sorted := make([]PubKey, len(pubKeys))
+	copy(sorted, pubKeys)
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i][:], sorted[j][:]) < 0
+	})
+
+	ms := Multisig{
+		M:       uint8(m),
+		PubKeys: sorted,
+	}
+
+	if err := ms.Verify(); err != nil {
+		return Multisig{}, err
+	}
+
+	return ms, nil
+}
+
+// Verify checks that the number of required signatures is valid,
+// and that the public keys are valid, sorted and unique
+func (ms Multisig) Verify() error {
+	if len(ms.PubKeys) > MaxMultisigPubKeys {
+		return ErrMultisigTooManyPubKeys
+	}
+
+	if ms.M < 1 || int(ms.M) > len(ms.PubKeys) {
+		return ErrMultisigRequiredSigs
+	}
+
+	for i, pk := range ms.PubKeys {
+		if i > 0 && bytes.Compare(ms.PubKeys[i-1][:], pk[:]) >= 0 {
+			return ErrMultisigPubKeysOrder
+		}
+
+		if err := pk.Verify(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Address returns the address of the multisignature, ripemd160(sha256(sha256(m+pubkeys)))
+// with MultisigAddressVersion
+func (ms Multisig) Address() Address {
+	b := make([]byte, 0, 1+len(ms.PubKeys)*len(PubKey{}))
+	b = append(b, ms.M)
+	for _, pk := range ms.PubKeys {
+		b = append(b, pk[:]...)
+	}
+
+	r1 := SumSHA256(b)
+	r2 := SumSHA256(r1[:])
+	return Address{
+		Version: MultisigAddressVersion,
+		Key:     HashRipemd160(r2[:]),
+	}
+}
+
+// PubKeyIndex returns the index of a public key of the multisignature, -1 if it isn't one of its keys
+func (ms Multisig) PubKeyIndex(pk PubKey) int {
+	for i, k := range ms.PubKeys {
+		if k == pk {
+			return i
+		}
+	}
+	return -1
+}
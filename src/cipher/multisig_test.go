@@ -0,0 +1,72 @@
+package cipher
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMultisig(t *testing.T) {
+	pks := make([]PubKey, 3)
+	for i := range pks {
+		pks[i], _ = GenerateKeyPair()
+	}
+
+	ms, err := NewMultisig(2, pks)
+	require.NoError(t, err)
+	require.NoError(t, ms.Verify())
+	require.Equal(t, uint8(2), ms.M)
+	require.Len(t, ms.PubKeys, 3)
+	for _, pk := range pks {
+		require.NotEqual(t, -1, ms.PubKeyIndex(pk))
+	}
+	require.Equal(t, -1, ms.PubKeyIndex(PubKey{}))
+
+	// The address doesn't depend on the order of the keys
+	ms2, err := NewMultisig(2, []PubKey{pks[2], pks[0], pks[1]})
+	require.NoError(t, err)
+	require.Equal(t, ms, ms2)
+
+	a := ms.Address()
+	require.Equal(t, MultisigAddressVersion, a.Version)
+	require.Equal(t, a, ms2.Address())
+
+	// The address depends on the number of required signatures and on the keys
+	ms3, err := NewMultisig(3, pks)
+	require.NoError(t, err)
+	require.NotEqual(t, a, ms3.Address())
+
+	ms4, err := NewMultisig(2, pks[:2])
+	require.NoError(t, err)
+	require.NotEqual(t, a, ms4.Address())
+
+	// The address can't be spent with a single key
+	require.Equal(t, ErrAddressInvalidVersion, a.Verify(pks[0]))
+
+	// The address is encoded like a single key address
+	a2, err := DecodeBase58Address(a.String())
+	require.NoError(t, err)
+	require.Equal(t, a, a2)
+
+	_, err = NewMultisig(0, pks)
+	require.Equal(t, ErrMultisigRequiredSigs, err)
+
+	_, err = NewMultisig(4, pks)
+	require.Equal(t, ErrMultisigRequiredSigs, err)
+
+	_, err = NewMultisig(1, make([]PubKey, MaxMultisigPubKeys+1))
+	require.Equal(t, ErrMultisigTooManyPubKeys, err)
+
+	_, err = NewMultisig(2, []PubKey{pks[0], pks[0]})
+	require.Equal(t, ErrMultisigPubKeysOrder, err)
+
+	_, err = NewMultisig(1, []PubKey{pks[0], {}})
+	require.Equal(t, ErrInvalidPubKey, err)
+
+	// Unsorted keys are invalid
+	unsorted := Multisig{
+		M:       1,
+		PubKeys: []PubKey{ms.PubKeys[1], ms.PubKeys[0]},
+	}
+	require.Equal(t, ErrMultisigPubKeysOrder, unsorted.Verify())
+}
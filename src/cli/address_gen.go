@@ -47,10 +47,23 @@ func addressGenCmd() gcli.Command {
 				Name:  "seed,s",
 				Usage: "Seed for deterministic key generation. Will use bip39 as the seed if not provided.",
 			},
+			gcli.BoolFlag{
+				Name:  "strict-seed",
+				Usage: "Require the seed to be a valid bip39 mnemonic, with a valid checksum",
+			},
+			gcli.StringFlag{
+				Name:  "seed-passphrase",
+				Usage: "bip39 passphrase of the seed. The seed must be a valid bip39 mnemonic",
+			},
 			gcli.IntFlag{
 				Name:  "entropy",
 				Value: 128,
-				Usage: "Entropy of the autogenerated bip39 seed, when the seed is not provided. Can be 128 or 256",
+				Usage: "Entropy of the autogenerated bip39 seed, when the seed is not provided. Can be 128, 160, 192, 224 or 256",
+			},
+			gcli.StringFlag{
+				Name:  "language",
+				Value: bip39.DefaultLanguage,
+				Usage: "Language of the wordlist of the autogenerated bip39 seed",
 			},
 			gcli.BoolFlag{
 				Name:  "hide-secrets,hs",
@@ -104,13 +117,14 @@ func addressGenCmd() gcli.Command {
 			}
 
 			w, err := wallet.NewWallet(wallet.NewWalletFilename(), wallet.Options{
-				Coin:       coinType,
-				Label:      label,
-				Seed:       seed,
-				Encrypt:    encrypt,
-				Password:   password,
-				CryptoType: wallet.CryptoTypeScryptChacha20poly1305,
-				GenerateN:  uint64(num),
+				Coin:           coinType,
+				Label:          label,
+				Seed:           seed,
+				Encrypt:        encrypt,
+				Password:       password,
+				CryptoType:     wallet.CryptoTypeArgon2idChacha20poly1305,
+				GenerateN:      uint64(num),
+				SeedPassphrase: c.String("seed-passphrase"),
 			})
 			if err != nil {
 				return err
@@ -159,34 +173,26 @@ func resolveSeed(c *gcli.Context) (string, error) {
 	entropy := c.Int("entropy")
 
 	switch entropy {
-	case 128, 256:
+	case 128, 160, 192, 224, 256:
 	default:
-		return "", errors.New("entropy must be 128 or 256")
+		return "", errors.New("entropy must be 128, 160, 192, 224 or 256")
 	}
 
 	if seed != "" {
-		if strict && !bip39.IsMnemonicValid(seed) {
-			return "", errors.New("seed is not a valid bip39 seed")
+		if strict {
+			if err := bip39.ValidateMnemonic(seed); err != nil {
+				return "", fmt.Errorf("seed is not a valid bip39 seed: %v", err)
+			}
 		}
 
 		return seed, nil
 	}
 
 	if useHex {
-		seed = cipher.SumSHA256(cipher.RandByte(1024)).Hex()
-	} else {
-		e, err := bip39.NewEntropy(entropy)
-		if err != nil {
-			return "", err
-		}
-
-		seed, err = bip39.NewMnemonic(e)
-		if err != nil {
-			return "", err
-		}
+		return cipher.SumSHA256(cipher.RandByte(1024)).Hex(), nil
 	}
 
-	return seed, nil
+	return newMnemonic(entropy, c.String("language"))
 }
 
 func fiberAddressGenCmd() gcli.Command {
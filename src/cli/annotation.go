@@ -0,0 +1,182 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	gcli "github.com/urfave/cli"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/readable"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+func annotationFlags() []gcli.Flag {
+	return []gcli.Flag{
+		gcli.StringFlag{
+			Name:  "label,l",
+			Usage: "[label] Short label",
+		},
+		gcli.StringFlag{
+			Name:  "note,n",
+			Usage: "[note] Free text note",
+		},
+		gcli.StringFlag{
+			Name:  "category,c",
+			Usage: "[category] Category, to group payments",
+		},
+	}
+}
+
+func annotationFromContext(c *gcli.Context) wallet.Annotation {
+	return wallet.Annotation{
+		Label:    c.String("label"),
+		Note:     c.String("note"),
+		Category: c.String("category"),
+	}
+}
+
+func walletAnnotationsCmd(cfg Config) gcli.Command {
+	name := "walletAnnotations"
+	return gcli.Command{
+		Name:  name,
+		Usage: "List the annotations of the addresses and the transactions of a wallet",
+		Description: fmt.Sprintf(`List the annotations of the addresses and the transactions of a wallet.
+		The default wallet (%s) will be used if no wallet was specified.
+
+		All results are returned in JSON format.`, cfg.FullWalletPath()),
+		Flags: []gcli.Flag{
+			gcli.StringFlag{
+				Name:  "f",
+				Value: cfg.FullWalletPath(),
+				Usage: "[wallet file or path] List the annotations of this wallet",
+			},
+		},
+		OnUsageError: onCommandUsageError(name),
+		Action: func(c *gcli.Context) error {
+			cfg := ConfigFromContext(c)
+
+			walletFile, err := resolveWalletPath(cfg, c.String("f"))
+			if err != nil {
+				return err
+			}
+
+			wlt, err := wallet.Load(walletFile)
+			if err != nil {
+				printHelp(c)
+				return WalletLoadError{err}
+			}
+
+			return printJSON(readable.NewWalletAnnotations(wlt.Annotations))
+		},
+	}
+}
+
+func annotateAddressCmd(cfg Config) gcli.Command {
+	name := "annotateAddress"
+	return gcli.Command{
+		Name:      name,
+		Usage:     "Annotate an address of a wallet with a label, a note and a category",
+		ArgsUsage: "[address]",
+		Description: fmt.Sprintf(`Annotate an address of a wallet. The annotation replaces the previous
+		annotation of the address, and an annotation without label, note and category removes it.
+		The default wallet (%s) will be used if no wallet was specified.
+
+		Annotations are not encrypted, so encrypted wallets can be annotated without a password.`, cfg.FullWalletPath()),
+		Flags: append([]gcli.Flag{
+			gcli.StringFlag{
+				Name:  "f",
+				Value: cfg.FullWalletPath(),
+				Usage: "[wallet file or path] Annotate an address of this wallet",
+			},
+		}, annotationFlags()...),
+		OnUsageError: onCommandUsageError(name),
+		Action: func(c *gcli.Context) error {
+			cfg := ConfigFromContext(c)
+
+			addrStr := c.Args().First()
+			if addrStr == "" {
+				return errors.New("missing address")
+			}
+
+			addr, err := cipher.DecodeBase58Address(addrStr)
+			if err != nil {
+				return fmt.Errorf("invalid address %q: %v", addrStr, err)
+			}
+
+			walletFile, err := resolveWalletPath(cfg, c.String("f"))
+			if err != nil {
+				return err
+			}
+
+			return annotateWalletFile(c, walletFile, func(w *wallet.Wallet) error {
+				return w.SetAddressAnnotation(addr, annotationFromContext(c))
+			})
+		},
+	}
+}
+
+func annotateTransactionCmd(cfg Config) gcli.Command {
+	name := "annotateTransaction"
+	return gcli.Command{
+		Name:      name,
+		Usage:     "Annotate a transaction in a wallet with a label, a note and a category",
+		ArgsUsage: "[txid]",
+		Description: fmt.Sprintf(`Annotate a transaction in a wallet. The annotation replaces the previous
+		annotation of the transaction, and an annotation without label, note and category removes it.
+		The default wallet (%s) will be used if no wallet was specified.
+
+		Annotations are not encrypted, so encrypted wallets can be annotated without a password.`, cfg.FullWalletPath()),
+		Flags: append([]gcli.Flag{
+			gcli.StringFlag{
+				Name:  "f",
+				Value: cfg.FullWalletPath(),
+				Usage: "[wallet file or path] Annotate a transaction in this wallet",
+			},
+		}, annotationFlags()...),
+		OnUsageError: onCommandUsageError(name),
+		Action: func(c *gcli.Context) error {
+			cfg := ConfigFromContext(c)
+
+			txidStr := c.Args().First()
+			if txidStr == "" {
+				return errors.New("missing txid")
+			}
+
+			txid, err := cipher.SHA256FromHex(txidStr)
+			if err != nil {
+				return fmt.Errorf("invalid txid %q: %v", txidStr, err)
+			}
+
+			walletFile, err := resolveWalletPath(cfg, c.String("f"))
+			if err != nil {
+				return err
+			}
+
+			return annotateWalletFile(c, walletFile, func(w *wallet.Wallet) error {
+				return w.SetTransactionAnnotation(txid, annotationFromContext(c))
+			})
+		},
+	}
+}
+
+// annotateWalletFile loads a wallet, annotates it with f and saves it
+func annotateWalletFile(c *gcli.Context, walletFile string, f func(*wallet.Wallet) error) error {
+	wlt, err := wallet.Load(walletFile)
+	if err != nil {
+		printHelp(c)
+		return WalletLoadError{err}
+	}
+
+	if err := f(wlt); err != nil {
+		return err
+	}
+
+	if err := wlt.Save(filepath.Dir(walletFile)); err != nil {
+		return WalletSaveError{err}
+	}
+
+	fmt.Println("success")
+	return nil
+}
@@ -2,14 +2,15 @@ package cli
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/boltdb/bolt"
 	gcli "github.com/urfave/cli"
 
 	"github.com/skycoin/skycoin/src/cipher"
-	"github.com/skycoin/skycoin/src/util/apputil"
 	"github.com/skycoin/skycoin/src/visor"
 	"github.com/skycoin/skycoin/src/visor/dbutil"
 )
@@ -18,24 +19,50 @@ const (
 	blockchainPubkey = "0328c576d3f420e7682058a981173a4b374c7cc5ff55bf394d3cf57059bbe6456a"
 )
 
-// wrapDB calls dbutil.WrapDB and disables all logging
-func wrapDB(db *bolt.DB) *dbutil.DB {
-	wdb := dbutil.WrapDB(db)
-	wdb.ViewLog = false
-	wdb.ViewTrace = false
-	wdb.UpdateLog = false
-	wdb.UpdateTrace = false
-	wdb.DurationLog = false
-	return wdb
+// openDB opens the database file with visor.OpenDBWithConfig and disables all logging.
+// An encrypted database is opened with the passphrase of the DB_PASSPHRASE environment variable.
+func openDB(cfg Config, dbpath string, readOnly bool) (*dbutil.DB, error) {
+	openCfg := visor.NewOpenDBConfig()
+	openCfg.ReadOnly = readOnly
+	if cfg.DBPassphrase != "" {
+		openCfg.EncryptionPassphrase = []byte(cfg.DBPassphrase)
+	}
+
+	db, err := visor.OpenDBWithConfig(dbpath, openCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	db.ViewLog = false
+	db.ViewTrace = false
+	db.UpdateLog = false
+	db.UpdateTrace = false
+	db.DurationLog = false
+	return db, nil
 }
 
 func checkdbCmd() gcli.Command {
 	name := "checkdb"
 	return gcli.Command{
-		Name:         name,
-		Usage:        "Verify the database",
-		ArgsUsage:    "[db path]",
-		Description:  "If no argument is specificed, the default data.db in $HOME/.$COIN/ will be checked.",
+		Name:        name,
+		Usage:       "Verify the database",
+		ArgsUsage:   "[db path]",
+		Description: "If no argument is specificed, the default data.db in $HOME/.$COIN/ will be checked.",
+		Flags: []gcli.Flag{
+			gcli.BoolFlag{
+				Name:  "full-verify",
+				Usage: "Verify the entire blockchain, instead of only the blocks added since the last verification",
+			},
+			gcli.BoolFlag{
+				Name:  "no-progress",
+				Usage: "Don't print the verification progress",
+			},
+			gcli.StringFlag{
+				Name:  "workers",
+				Value: strconv.Itoa(visor.BlockchainVerifyTheadNum),
+				Usage: "Number of goroutines used for verification, or \"auto\" to size it from the number of CPUs and the measured verification speed",
+			},
+		},
 		OnUsageError: onCommandUsageError(name),
 		Action:       checkdb,
 	}
@@ -55,11 +82,12 @@ func checkdb(c *gcli.Context) error {
 		return fmt.Errorf("db file: %v does not exist", dbpath)
 	}
 
-	db, err := bolt.Open(dbpath, 0600, &bolt.Options{
-		Timeout:  5 * time.Second,
-		ReadOnly: true,
-	})
+	workers, err := visor.ParseVerifyWorkers(c.String("workers"))
+	if err != nil {
+		return err
+	}
 
+	db, err := openDB(cfg, dbpath, true)
 	if err != nil {
 		return fmt.Errorf("open db failed: %v", err)
 	}
@@ -68,12 +96,24 @@ func checkdb(c *gcli.Context) error {
 		return fmt.Errorf("decode blockchain pubkey failed: %v", err)
 	}
 
-	quit := QuitChanFromContext(c)
-	go func() {
-		apputil.CatchInterrupt(quit)
-	}()
+	ctx := interruptContext(c)
+
+	checkCfg := visor.CheckDatabaseConfig{
+		Pubkey:     pubkey,
+		FullVerify: c.Bool("full-verify"),
+		Workers:    workers,
+	}
+	if !c.Bool("no-progress") {
+		checkCfg.Progress = printVerifyProgress
+	}
+
+	err = visor.CheckDatabase(ctx, db, checkCfg)
+	if checkCfg.Progress != nil {
+		// Terminate the progress bar line
+		fmt.Println()
+	}
 
-	if err := visor.CheckDatabase(wrapDB(db), pubkey, quit); err != nil {
+	if err != nil {
 		if err == visor.ErrVerifyStopped {
 			return nil
 		}
@@ -83,3 +123,24 @@ func checkdb(c *gcli.Context) error {
 	fmt.Println("check db success")
 	return nil
 }
+
+// printVerifyProgress prints a progress bar for the database verification, overwriting the current line
+func printVerifyProgress(p visor.VerifyProgress) {
+	fprintVerifyProgress(os.Stdout, p)
+}
+
+// fprintVerifyProgress prints a progress bar for the database verification to w, overwriting the current line
+func fprintVerifyProgress(w io.Writer, p visor.VerifyProgress) {
+	const barWidth = 40
+
+	total := p.Verified + p.Remaining
+	ratio := 1.0
+	if total > 0 {
+		ratio = float64(p.Verified) / float64(total)
+	}
+
+	filled := int(ratio * barWidth)
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", barWidth-filled)
+
+	fmt.Fprintf(w, "\r[%s] %d/%d blocks (%.1f%%) ETA %s   ", bar, p.Verified, total, ratio*100, p.ETA.Round(time.Second))
+}
@@ -7,6 +7,7 @@ REST API to query a skycoin node's status.
 package cli
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -21,6 +22,7 @@ import (
 	"golang.org/x/crypto/ssh/terminal"
 
 	"github.com/skycoin/skycoin/src/api"
+	"github.com/skycoin/skycoin/src/util/apputil"
 	"github.com/skycoin/skycoin/src/util/file"
 )
 
@@ -46,7 +48,8 @@ var (
     COIN: Name of the coin. Default "%s"
     WALLET_DIR: Directory where wallets are stored. This value is overridden by any subcommand flag specifying a wallet filename, if that filename includes a path. Default "%s"
     WALLET_NAME: Name of wallet file (without path). This value is overridden by any subcommand flag specifying a wallet filename. Default "%s"
-    DATA_DIR: Directory where everything is stored. Default "%s"`, defaultRPCAddress, defaultCoin, defaultWalletDir, defaultWalletName, defaultDataDir)
+    DATA_DIR: Directory where everything is stored. Default "%s"
+    DB_PASSPHRASE: Passphrase of an encrypted database, used by the commands that open the database file`, defaultRPCAddress, defaultCoin, defaultWalletDir, defaultWalletName, defaultDataDir)
 
 	commandHelpTemplate = fmt.Sprintf(`USAGE:
         {{.HelpName}}{{if .VisibleFlags}} [command options]{{end}} {{if .ArgsUsage}}{{.ArgsUsage}}{{else}}[arguments...]{{end}}{{if .Category}}
@@ -108,13 +111,14 @@ type App struct {
 
 // Config cli's configuration struct
 type Config struct {
-	WalletDir   string `json:"wallet_directory"`
-	WalletName  string `json:"wallet_name"`
-	DataDir     string `json:"data_directory"`
-	Coin        string `json:"coin"`
-	RPCAddress  string `json:"rpc_address"`
-	RPCUsername string `json:"-"`
-	RPCPassword string `json:"-"`
+	WalletDir    string `json:"wallet_directory"`
+	WalletName   string `json:"wallet_name"`
+	DataDir      string `json:"data_directory"`
+	Coin         string `json:"coin"`
+	RPCAddress   string `json:"rpc_address"`
+	RPCUsername  string `json:"-"`
+	RPCPassword  string `json:"-"`
+	DBPassphrase string `json:"-"`
 }
 
 // LoadConfig loads config from environment, prior to parsing CLI flags
@@ -138,6 +142,8 @@ func LoadConfig() (Config, error) {
 	rpcUser := os.Getenv("RPC_USER")
 	rpcPass := os.Getenv("RPC_PASS")
 
+	dbPassphrase := os.Getenv("DB_PASSPHRASE")
+
 	home := file.UserHome()
 
 	// get data dir dir from env
@@ -163,13 +169,14 @@ func LoadConfig() (Config, error) {
 	}
 
 	return Config{
-		WalletDir:   wltDir,
-		WalletName:  wltName,
-		DataDir:     dataDir,
-		Coin:        coin,
-		RPCAddress:  rpcAddr,
-		RPCUsername: rpcUser,
-		RPCPassword: rpcPass,
+		WalletDir:    wltDir,
+		WalletName:   wltName,
+		DataDir:      dataDir,
+		Coin:         coin,
+		RPCAddress:   rpcAddr,
+		RPCUsername:  rpcUser,
+		RPCPassword:  rpcPass,
+		DBPassphrase: dbPassphrase,
 	}, nil
 }
 
@@ -236,34 +243,63 @@ func NewApp(cfg Config) (*App, error) {
 	}
 
 	commands := []gcli.Command{
+		addPeersCmd(),
 		addPrivateKeyCmd(cfg),
 		addressBalanceCmd(),
 		addressGenCmd(),
 		fiberAddressGenCmd(),
 		addressOutputsCmd(),
+		annotateAddressCmd(cfg),
+		annotateTransactionCmd(cfg),
+		banPeersCmd(),
 		blocksCmd(),
 		broadcastTxCmd(),
 		checkdbCmd(),
+		combineTransactionsCmd(),
+		compactdbCmd(),
+		createPayoutBatchCmd(cfg),
 		createRawTxCmd(cfg),
+		dbFingerprintCmd(),
+		dbForensicsCmd(),
+		dbOrphansCmd(),
+		dbStatsCmd(),
 		decodeRawTxCmd(),
 		decryptWalletCmd(cfg),
+		deviceAddressesCmd(),
 		encryptWalletCmd(cfg),
+		exportSnapshotCmd(),
+		exportUnspentSnapshotCmd(),
+		importSnapshotCmd(),
+		importUnspentSnapshotCmd(),
 		lastBlocksCmd(),
 		listAddressesCmd(),
 		listWalletsCmd(),
+		networkPeersCmd(),
+		redistributeHoursCmd(cfg),
+		removePeersCmd(),
 		sendCmd(),
 		showConfigCmd(),
 		showSeedCmd(cfg),
+		signTransactionCmd(cfg),
 		statusCmd(),
+		sweepPrivateKeysCmd(cfg),
 		transactionCmd(),
 		verifyAddressCmd(),
+		verifydbCmd(),
 		versionCmd(),
 		walletCreateCmd(cfg),
 		walletAddAddressesCmd(cfg),
+		walletAddWatchOnlyAddressesCmd(cfg),
+		walletAnnotationsCmd(cfg),
+		walletBackupCmd(cfg),
+		walletCreateWatchOnlyCmd(),
 		walletBalanceCmd(cfg),
 		walletDirCmd(),
 		walletHisCmd(),
+		walletImportCmd(cfg),
 		walletOutputsCmd(cfg),
+		walletRescanCmd(cfg),
+		walletRestoreCmd(cfg),
 	}
 
 	app.Name = fmt.Sprintf("%s-cli", cfg.Coin)
@@ -313,6 +349,17 @@ func QuitChanFromContext(c *gcli.Context) chan struct{} {
 	return c.App.Metadata["quitChan"].(chan struct{})
 }
 
+// interruptContext returns a context that is canceled when CTRL-C closes the quit channel of a urfave/cli Context
+func interruptContext(c *gcli.Context) context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	quit := QuitChanFromContext(c)
+	go func() {
+		apputil.CatchInterrupt(quit)
+		cancel()
+	}()
+	return ctx
+}
+
 func onCommandUsageError(command string) gcli.OnUsageErrorFunc {
 	return func(c *gcli.Context, err error, isSubcommand bool) error {
 		fmt.Fprintf(c.App.Writer, "Error: %v\n\n", err)
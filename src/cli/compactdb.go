@@ -0,0 +1,131 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	gcli "github.com/urfave/cli"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/visor"
+)
+
+func compactdbCmd() gcli.Command {
+	name := "compactdb"
+	return gcli.Command{
+		Name:      name,
+		Usage:     "Compact the database",
+		ArgsUsage: "[db path]",
+		Description: `Copies the database into a new file without the free space left by deleted data,
+		verifies the copy and replaces the database with it. The node must not be running.
+		If no argument is specificed, the default data.db in $HOME/.$COIN/ will be compacted.`,
+		Flags: []gcli.Flag{
+			gcli.BoolFlag{
+				Name:  "no-progress",
+				Usage: "Don't print the compaction progress",
+			},
+			gcli.StringFlag{
+				Name:  "workers",
+				Value: strconv.Itoa(visor.BlockchainVerifyTheadNum),
+				Usage: "Number of goroutines used for verification, or \"auto\" to size it from the number of CPUs and the measured verification speed",
+			},
+		},
+		OnUsageError: onCommandUsageError(name),
+		Action:       compactdb,
+	}
+}
+
+func compactdb(c *gcli.Context) error {
+	cfg := ConfigFromContext(c)
+
+	// get db path
+	dbpath, err := resolveDBPath(cfg, c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	// check if this file is exist
+	if _, err := os.Stat(dbpath); os.IsNotExist(err) {
+		return fmt.Errorf("db file: %v does not exist", dbpath)
+	}
+
+	workers, err := visor.ParseVerifyWorkers(c.String("workers"))
+	if err != nil {
+		return err
+	}
+
+	db, err := openDB(cfg, dbpath, false)
+	if err != nil {
+		return fmt.Errorf("open db failed: %v", err)
+	}
+
+	pubkey, err := cipher.PubKeyFromHex(blockchainPubkey)
+	if err != nil {
+		return fmt.Errorf("decode blockchain pubkey failed: %v", err)
+	}
+
+	ctx := interruptContext(c)
+
+	compactCfg := visor.CompactDBConfig{
+		Check: visor.CheckDatabaseConfig{
+			Pubkey:     pubkey,
+			FullVerify: true,
+			Workers:    workers,
+		},
+		TxMaxSize: visor.DefaultCompactTxMaxSize,
+	}
+	showProgress := !c.Bool("no-progress")
+	if showProgress {
+		compactCfg.Progress = printCompactProgress
+
+		// Start the verification progress bar on a new line, after the compaction progress bar
+		var verifying bool
+		compactCfg.Check.Progress = func(p visor.VerifyProgress) {
+			if !verifying {
+				fmt.Println()
+				verifying = true
+			}
+			printVerifyProgress(p)
+		}
+	}
+
+	newDB, err := visor.CompactDB(ctx, db, compactCfg)
+	if showProgress {
+		// Terminate the progress bar line
+		fmt.Println()
+	}
+
+	if err != nil {
+		if closeErr := db.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "close db failed: %v\n", closeErr)
+		}
+		if err == visor.ErrVerifyStopped {
+			return nil
+		}
+		return fmt.Errorf("compactdb failed: %v", err)
+	}
+
+	if err := newDB.Close(); err != nil {
+		return fmt.Errorf("close db failed: %v", err)
+	}
+
+	fmt.Println("compact db success")
+	return nil
+}
+
+// printCompactProgress prints a progress bar for the database compaction, overwriting the current line
+func printCompactProgress(p visor.CompactProgress) {
+	const barWidth = 40
+
+	ratio := 1.0
+	if p.Total > 0 {
+		ratio = float64(p.Copied) / float64(p.Total)
+	}
+
+	filled := int(ratio * barWidth)
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", barWidth-filled)
+
+	fmt.Printf("\r[%s] %d/%d keys copied (%.1f%%)   ", bar, p.Copied, p.Total, ratio*100)
+}
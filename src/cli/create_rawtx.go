@@ -58,7 +58,20 @@ func createRawTxCmd(cfg Config) gcli.Command {
         Use caution when using the "-p" command. If you have command history enabled
         your wallet encryption password can be recovered from the history log. If you
         do not include the "-p" option you will be prompted to enter your password
-        after you enter your command.`, cfg.FullWalletPath()),
+        after you enter your command.
+
+        Use the "-unsigned" option to create a partial transaction that is not signed,
+        without the keys of the wallet, for instance from a watch-only wallet on an online
+        machine. The partial transaction is signed with the "signTransaction" command,
+        which can run on an offline machine.
+
+        Use the "-coin-selection" option to choose how the unspent outputs to spend are
+        selected. The strategies are:
+          minimize_uxouts: spend the least number of outputs (default)
+          minimize_burn: spend the outputs with the least coin hours, to burn less coin hours
+          oldest_first: spend the oldest outputs first
+          random: spend outputs in random order, for privacy
+          exact: spend all the outputs of the from address or wallet`, cfg.FullWalletPath()),
 		Flags: []gcli.Flag{
 			gcli.StringFlag{
 				Name:  "f",
@@ -90,9 +103,22 @@ func createRawTxCmd(cfg Config) gcli.Command {
 				Name:  "csv",
 				Usage: "[filepath] CSV file containing addresses and amounts to send",
 			},
+			gcli.BoolFlag{
+				Name:  "unsigned",
+				Usage: "Create a partial transaction that is not signed, to be signed by signTransaction",
+			},
+			gcli.StringFlag{
+				Name:  "coin-selection",
+				Value: wallet.CoinSelectionMinimizeUxOuts,
+				Usage: "[strategy] Strategy to select the unspent outputs to spend",
+			},
 		},
 		OnUsageError: onCommandUsageError(name),
 		Action: func(c *gcli.Context) error {
+			if c.Bool("unsigned") {
+				return createUnsignedRawTxnCmdAction(c)
+			}
+
 			txn, err := createRawTxnCmdHandler(c)
 			switch err.(type) {
 			case nil:
@@ -317,6 +343,7 @@ type createRawTxArgs struct {
 	ChangeAddress string
 	SendAmounts   []SendAmount
 	Password      PasswordReader
+	CoinSelection string
 }
 
 func parseCreateRawTxArgs(c *gcli.Context) (*createRawTxArgs, error) {
@@ -339,6 +366,17 @@ func parseCreateRawTxArgs(c *gcli.Context) (*createRawTxArgs, error) {
 		return nil, err
 	}
 
+	coinSelection := c.String("coin-selection")
+	switch coinSelection {
+	case wallet.CoinSelectionMinimizeUxOuts,
+		wallet.CoinSelectionMinimizeBurn,
+		wallet.CoinSelectionOldestFirst,
+		wallet.CoinSelectionRandom,
+		wallet.CoinSelectionExact:
+	default:
+		return nil, fmt.Errorf("invalid coin selection strategy %q", coinSelection)
+	}
+
 	pr := NewPasswordReader([]byte(c.String("p")))
 
 	return &createRawTxArgs{
@@ -347,6 +385,7 @@ func parseCreateRawTxArgs(c *gcli.Context) (*createRawTxArgs, error) {
 		ChangeAddress: chgAddr,
 		SendAmounts:   toAddrs,
 		Password:      pr,
+		CoinSelection: coinSelection,
 	}, nil
 }
 
@@ -359,10 +398,75 @@ func createRawTxnCmdHandler(c *gcli.Context) (*coin.Transaction, error) {
 	}
 
 	if args.Address == "" {
-		return CreateRawTxFromWallet(apiClient, args.WalletID, args.ChangeAddress, args.SendAmounts, args.Password)
+		return CreateRawTxFromWallet(apiClient, args.WalletID, args.ChangeAddress, args.SendAmounts, args.Password, args.CoinSelection)
+	}
+
+	return CreateRawTxFromAddress(apiClient, args.Address, args.WalletID, args.ChangeAddress, args.SendAmounts, args.Password, args.CoinSelection)
+}
+
+func createUnsignedRawTxnCmdAction(c *gcli.Context) error {
+	ptx, err := createUnsignedRawTxnCmdHandler(c)
+	switch err.(type) {
+	case nil:
+	case WalletLoadError:
+		printHelp(c)
+		return err
+	default:
+		return err
+	}
+
+	if c.Bool("json") {
+		return printJSON(struct {
+			PartialTransaction string `json:"partial_transaction"`
+		}{
+			PartialTransaction: ptx.SerializeHex(),
+		})
 	}
 
-	return CreateRawTxFromAddress(apiClient, args.Address, args.WalletID, args.ChangeAddress, args.SendAmounts, args.Password)
+	fmt.Println(ptx.SerializeHex())
+
+	return nil
+}
+
+func createUnsignedRawTxnCmdHandler(c *gcli.Context) (*wallet.PartialTransaction, error) {
+	if c.String("p") != "" {
+		return nil, errors.New("password must not be provided for unsigned transactions")
+	}
+
+	apiClient := APIClientFromContext(c)
+
+	args, err := parseCreateRawTxArgs(c)
+	if err != nil {
+		return nil, err
+	}
+
+	wlt, err := wallet.Load(args.WalletID)
+	if err != nil {
+		return nil, WalletLoadError{err}
+	}
+
+	cAddr, err := cipher.DecodeBase58Address(args.ChangeAddress)
+	if err != nil {
+		return nil, ErrAddress
+	}
+
+	if _, ok := wlt.GetEntry(cAddr); !ok {
+		return nil, fmt.Errorf("change address %v is not in wallet", args.ChangeAddress)
+	}
+
+	var inAddrs []string
+	if args.Address != "" {
+		if _, ok := wlt.GetEntry(cipher.MustDecodeBase58Address(args.Address)); !ok {
+			return nil, fmt.Errorf("%v address is not in wallet", args.Address)
+		}
+		inAddrs = []string{args.Address}
+	} else {
+		for _, a := range wlt.GetAddresses() {
+			inAddrs = append(inAddrs, a.String())
+		}
+	}
+
+	return CreateUnsignedRawTx(apiClient, inAddrs, args.ChangeAddress, args.SendAmounts, args.CoinSelection)
 }
 
 func validateSendAmounts(toAddrs []SendAmount) error {
@@ -387,8 +491,9 @@ func validateSendAmounts(toAddrs []SendAmount) error {
 
 // PUBLIC
 
-// CreateRawTxFromWallet creates a transaction from any address or combination of addresses in a wallet
-func CreateRawTxFromWallet(c GetOutputser, walletFile, chgAddr string, toAddrs []SendAmount, pr PasswordReader) (*coin.Transaction, error) {
+// CreateRawTxFromWallet creates a transaction from any address or combination of addresses in a wallet.
+// The outputs to spend are chosen with the coinSelection strategy, see wallet.ChooseSpendsStrategy.
+func CreateRawTxFromWallet(c GetOutputser, walletFile, chgAddr string, toAddrs []SendAmount, pr PasswordReader, coinSelection string) (*coin.Transaction, error) {
 	// check change address
 	cAddr, err := cipher.DecodeBase58Address(chgAddr)
 	if err != nil {
@@ -438,11 +543,12 @@ func CreateRawTxFromWallet(c GetOutputser, walletFile, chgAddr string, toAddrs [
 		addrStrArray[i] = a.String()
 	}
 
-	return CreateRawTx(c, wlt, addrStrArray, chgAddr, toAddrs, password)
+	return CreateRawTx(c, wlt, addrStrArray, chgAddr, toAddrs, password, coinSelection)
 }
 
-// CreateRawTxFromAddress creates a transaction from a specific address in a wallet
-func CreateRawTxFromAddress(c GetOutputser, addr, walletFile, chgAddr string, toAddrs []SendAmount, pr PasswordReader) (*coin.Transaction, error) {
+// CreateRawTxFromAddress creates a transaction from a specific address in a wallet.
+// The outputs to spend are chosen with the coinSelection strategy, see wallet.ChooseSpendsStrategy.
+func CreateRawTxFromAddress(c GetOutputser, addr, walletFile, chgAddr string, toAddrs []SendAmount, pr PasswordReader, coinSelection string) (*coin.Transaction, error) {
 	// check if the address is in the default wallet.
 	wlt, err := wallet.Load(walletFile)
 	if err != nil {
@@ -495,7 +601,7 @@ func CreateRawTxFromAddress(c GetOutputser, addr, walletFile, chgAddr string, to
 		}
 	}
 
-	return CreateRawTx(c, wlt, []string{addr}, chgAddr, toAddrs, password)
+	return CreateRawTx(c, wlt, []string{addr}, chgAddr, toAddrs, password, coinSelection)
 }
 
 // GetOutputser implements unspent output querying
@@ -504,25 +610,56 @@ type GetOutputser interface {
 }
 
 // CreateRawTx creates a transaction from a set of addresses contained in a loaded *wallet.Wallet
-func CreateRawTx(c GetOutputser, wlt *wallet.Wallet, inAddrs []string, chgAddr string, toAddrs []SendAmount, password []byte) (*coin.Transaction, error) {
-	if err := validateSendAmounts(toAddrs); err != nil {
+func CreateRawTx(c GetOutputser, wlt *wallet.Wallet, inAddrs []string, chgAddr string, toAddrs []SendAmount, password []byte, coinSelection string) (*coin.Transaction, error) {
+	txn, _, err := createVerifiedRawTx(c, inAddrs, toAddrs, false, func(outputs *readable.UnspentOutputsSummary) (*coin.Transaction, error) {
+		return createRawTx(outputs, wlt, chgAddr, toAddrs, password, coinSelection)
+	})
+	return txn, err
+}
+
+// CreateUnsignedRawTx creates a partial transaction from a set of addresses, without signing it.
+// The partial transaction can be signed later by the wallets that own the addresses, for instance on an offline machine.
+func CreateUnsignedRawTx(c GetOutputser, inAddrs []string, chgAddr string, toAddrs []SendAmount, coinSelection string) (*wallet.PartialTransaction, error) {
+	txn, inputs, err := createVerifiedRawTx(c, inAddrs, toAddrs, true, func(outputs *readable.UnspentOutputsSummary) (*coin.Transaction, error) {
+		return createUnsignedRawTx(outputs, chgAddr, toAddrs, coinSelection)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ptx := &wallet.PartialTransaction{
+		Transaction: *txn,
+		Inputs:      inputs,
+	}
+
+	if err := ptx.Verify(); err != nil {
 		return nil, err
 	}
 
+	return ptx, nil
+}
+
+// createVerifiedRawTx creates a transaction with makeTx from the unspent outputs of inAddrs,
+// verifies it and returns it with the unspent outputs that it spends
+func createVerifiedRawTx(c GetOutputser, inAddrs []string, toAddrs []SendAmount, unsigned bool, makeTx func(*readable.UnspentOutputsSummary) (*coin.Transaction, error)) (*coin.Transaction, coin.UxArray, error) {
+	if err := validateSendAmounts(toAddrs); err != nil {
+		return nil, nil, err
+	}
+
 	// Get unspent outputs of those addresses
 	outputs, err := c.OutputsForAddresses(inAddrs)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	inUxs, err := outputs.SpendableOutputs().ToUxArray()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	txn, err := createRawTx(outputs, wlt, chgAddr, toAddrs, password)
+	txn, err := makeTx(outputs)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// filter out unspents which are not used in transaction
@@ -537,23 +674,28 @@ func CreateRawTx(c GetOutputser, wlt *wallet.Wallet, inAddrs []string, chgAddr s
 
 	head, err := outputs.Head.ToCoinBlockHeader()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if err := visor.VerifySingleTxnSoftConstraints(*txn, head.Time, inUxsFiltered, params.UserMaxTransactionSize, params.UserBurnFactor); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	if err := visor.VerifySingleTxnHardConstraints(*txn, head, inUxsFiltered); err != nil {
-		return nil, err
+
+	verifyHardConstraints := visor.VerifySingleTxnHardConstraints
+	if unsigned {
+		verifyHardConstraints = visor.VerifySingleTxnHardConstraintsUnsigned
+	}
+	if err := verifyHardConstraints(*txn, head, inUxsFiltered); err != nil {
+		return nil, nil, err
 	}
 	if err := visor.VerifySingleTxnUserConstraints(*txn); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return txn, nil
+	return txn, inUxsFiltered, nil
 }
 
-func createRawTx(uxouts *readable.UnspentOutputsSummary, wlt *wallet.Wallet, chgAddr string, toAddrs []SendAmount, password []byte) (*coin.Transaction, error) {
+func createRawTx(uxouts *readable.UnspentOutputsSummary, wlt *wallet.Wallet, chgAddr string, toAddrs []SendAmount, password []byte, coinSelection string) (*coin.Transaction, error) {
 	// Calculate total required coins
 	var totalCoins uint64
 	for _, arg := range toAddrs {
@@ -564,7 +706,7 @@ func createRawTx(uxouts *readable.UnspentOutputsSummary, wlt *wallet.Wallet, chg
 		}
 	}
 
-	spendOutputs, err := chooseSpends(uxouts, totalCoins)
+	spendOutputs, err := chooseSpends(uxouts, totalCoins, coinSelection)
 	if err != nil {
 		return nil, err
 	}
@@ -605,7 +747,31 @@ func createRawTx(uxouts *readable.UnspentOutputsSummary, wlt *wallet.Wallet, chg
 	return makeTx()
 }
 
-func chooseSpends(uxouts *readable.UnspentOutputsSummary, coins uint64) ([]wallet.UxBalance, error) {
+func createUnsignedRawTx(uxouts *readable.UnspentOutputsSummary, chgAddr string, toAddrs []SendAmount, coinSelection string) (*coin.Transaction, error) {
+	// Calculate total required coins
+	var totalCoins uint64
+	for _, arg := range toAddrs {
+		var err error
+		totalCoins, err = coin.AddUint64(totalCoins, arg.Coins)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	spendOutputs, err := chooseSpends(uxouts, totalCoins, coinSelection)
+	if err != nil {
+		return nil, err
+	}
+
+	txOuts, err := makeChangeOut(spendOutputs, chgAddr, toAddrs)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewUnsignedTransaction(spendOutputs, txOuts)
+}
+
+func chooseSpends(uxouts *readable.UnspentOutputsSummary, coins uint64, coinSelection string) ([]wallet.UxBalance, error) {
 	// Convert spendable unspent outputs to []wallet.UxBalance
 	spendableOutputs, err := readable.OutputsToUxBalances(uxouts.SpendableOutputs())
 	if err != nil {
@@ -613,11 +779,11 @@ func chooseSpends(uxouts *readable.UnspentOutputsSummary, coins uint64) ([]walle
 	}
 
 	// Choose which unspent outputs to spend
-	// Use the MinimizeUxOuts strategy, since this is most likely used by
+	// The MinimizeUxOuts strategy is the default, since this is most likely used by
 	// application that may need to send frequently.
 	// Using fewer UxOuts will leave more available for other transactions,
 	// instead of waiting for confirmation.
-	outs, err := wallet.ChooseSpendsMinimizeUxOuts(spendableOutputs, coins, 0)
+	outs, err := wallet.ChooseSpendsStrategy(coinSelection, spendableOutputs, coins, 0)
 	if err != nil {
 		// If there is not enough balance in the spendable outputs,
 		// see if there is enough balance when including incoming outputs
@@ -627,7 +793,7 @@ func chooseSpends(uxouts *readable.UnspentOutputsSummary, coins uint64) ([]walle
 				return nil, otherErr
 			}
 
-			if _, otherErr := wallet.ChooseSpendsMinimizeUxOuts(expectedOutputs, coins, 0); otherErr != nil {
+			if _, otherErr := wallet.ChooseSpendsStrategy(coinSelection, expectedOutputs, coins, 0); otherErr != nil {
 				return nil, err
 			}
 
@@ -744,3 +910,24 @@ func NewTransaction(utxos []wallet.UxBalance, keys []cipher.SecKey, outs []coin.
 
 	return &txn, nil
 }
+
+// NewUnsignedTransaction creates a transaction with null signatures, to be signed later as a wallet.PartialTransaction
+func NewUnsignedTransaction(utxos []wallet.UxBalance, outs []coin.TransactionOutput) (*coin.Transaction, error) {
+	txn := coin.Transaction{}
+	for _, u := range utxos {
+		txn.PushInput(u.Hash)
+	}
+
+	for _, o := range outs {
+		txn.PushOutput(o.Address, o.Coins, o.Hours)
+	}
+
+	txn.Sigs = make([]cipher.Sig, len(txn.In))
+
+	err := txn.UpdateHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	return &txn, nil
+}
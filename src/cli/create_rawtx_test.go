@@ -288,6 +288,37 @@ func TestMakeChangeOutInsufficientCoinHours(t *testing.T) {
 	testutil.RequireError(t, err, fee.ErrTxnNoFee.Error())
 }
 
+func TestNewUnsignedTransaction(t *testing.T) {
+	uxb := []wallet.UxBalance{
+		{
+			Hash:    testutil.RandSHA256(t),
+			Address: testutil.MakeAddress(),
+			Coins:   2e6,
+			Hours:   10,
+		},
+		{
+			Hash:    testutil.RandSHA256(t),
+			Address: testutil.MakeAddress(),
+			Coins:   1e6,
+			Hours:   10,
+		},
+	}
+
+	outs, err := makeChangeOut(uxb, testutil.MakeAddress().String(), []SendAmount{{
+		Addr:  testutil.MakeAddress().String(),
+		Coins: 2e6,
+	}})
+	require.NoError(t, err)
+
+	txn, err := NewUnsignedTransaction(uxb, outs)
+	require.NoError(t, err)
+	require.Equal(t, []cipher.SHA256{uxb[0].Hash, uxb[1].Hash}, txn.In)
+	require.Equal(t, outs, txn.Out)
+	require.Equal(t, []cipher.Sig{{}, {}}, txn.Sigs)
+	require.False(t, txn.IsFullySigned())
+	require.NoError(t, txn.VerifyUnsigned())
+}
+
 func TestChooseSpends(t *testing.T) {
 	// Start with readable.UnspentOutputsSummary
 	// Spends should be minimized
@@ -512,7 +543,7 @@ func TestChooseSpends(t *testing.T) {
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
-			spends, err := chooseSpends(&tc.ros, coins)
+			spends, err := chooseSpends(&tc.ros, coins, wallet.CoinSelectionMinimizeUxOuts)
 
 			if tc.err != nil {
 				testutil.RequireError(t, err, tc.err.Error())
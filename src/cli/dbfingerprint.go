@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	gcli "github.com/urfave/cli"
+
+	"github.com/skycoin/skycoin/src/visor"
+)
+
+func dbFingerprintCmd() gcli.Command {
+	name := "dbfingerprint"
+	return gcli.Command{
+		Name:      name,
+		Usage:     "Print a canonical hash of the blocks, signatures and unspent outputs of a database file at a block height",
+		ArgsUsage: "[db path]",
+		Description: `Opens the database read-only and hashes the blocks and signatures up to the block of --seq,
+		and the unspent output set after that block. Two databases with the same fingerprint at a height store
+		the same blocks, signatures and unspent outputs, whatever their block compression or split layout.
+		The body of pruned blocks is not stored, so a pruned and an unpruned database have different fingerprints.
+		If --seq is not given, the fingerprint is computed at the head block.
+		If no argument is specificed, the default data.db in $HOME/.$COIN/ will be fingerprinted.`,
+		Flags: []gcli.Flag{
+			gcli.Uint64Flag{
+				Name:  "seq",
+				Usage: "Seq of the block to compute the fingerprint at, defaults to the head block",
+			},
+		},
+		OnUsageError: onCommandUsageError(name),
+		Action:       dbFingerprint,
+	}
+}
+
+func dbFingerprint(c *gcli.Context) error {
+	cfg := ConfigFromContext(c)
+
+	dbpath, err := resolveDBPath(cfg, c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(dbpath); os.IsNotExist(err) {
+		return fmt.Errorf("db file: %v does not exist", dbpath)
+	}
+
+	var seq *uint64
+	if c.IsSet("seq") {
+		s := c.Uint64("seq")
+		seq = &s
+	}
+
+	db, err := openDB(cfg, dbpath, true)
+	if err != nil {
+		return fmt.Errorf("open db failed: %v", err)
+	}
+	defer db.Close()
+
+	ctx := interruptContext(c)
+
+	fp, err := visor.ComputeDBFingerprint(ctx, db, seq)
+	if err != nil {
+		if err == visor.ErrVerifyStopped {
+			return nil
+		}
+		return fmt.Errorf("dbfingerprint failed: %v", err)
+	}
+
+	return printJSON(fp)
+}
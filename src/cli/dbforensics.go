@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	gcli "github.com/urfave/cli"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/visor"
+)
+
+func dbForensicsCmd() gcli.Command {
+	name := "dbforensics"
+	return gcli.Command{
+		Name:      name,
+		Usage:     "Create a forensic bundle of a database file, to attach to corruption bug reports",
+		ArgsUsage: "[db path]",
+		Description: `Opens the database read-only, verifies it and prints a JSON bundle with the head block,
+		dumps of the corrupted blocks, bucket stats, bolt page stats and the node version.
+		The node writes the same bundle next to the data.db.corrupt.$HASH file when it recovers a corrupted database.
+		If no argument is specificed, the default data.db in $HOME/.$COIN/ will be inspected.`,
+		Flags: []gcli.Flag{
+			gcli.StringFlag{
+				Name:  "o,out",
+				Usage: "Write the bundle to this file instead of stdout",
+			},
+			gcli.IntFlag{
+				Name:  "max-blocks",
+				Value: visor.DefaultDBForensicsMaxBlockDumps,
+				Usage: "Maximum number of corrupted blocks dumped, the others are only listed",
+			},
+			gcli.BoolFlag{
+				Name:  "no-progress",
+				Usage: "Don't print the verification progress to stderr",
+			},
+		},
+		OnUsageError: onCommandUsageError(name),
+		Action:       dbForensics,
+	}
+}
+
+func dbForensics(c *gcli.Context) error {
+	cfg := ConfigFromContext(c)
+
+	dbpath, err := resolveDBPath(cfg, c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(dbpath); os.IsNotExist(err) {
+		return fmt.Errorf("db file: %v does not exist", dbpath)
+	}
+
+	maxBlockDumps := c.Int("max-blocks")
+	if maxBlockDumps <= 0 {
+		return fmt.Errorf("--max-blocks must be > 0")
+	}
+
+	pubkey, err := cipher.PubKeyFromHex(blockchainPubkey)
+	if err != nil {
+		return fmt.Errorf("decode blockchain pubkey failed: %v", err)
+	}
+
+	db, err := openDB(cfg, dbpath, true)
+	if err != nil {
+		return fmt.Errorf("open db failed: %v", err)
+	}
+	defer db.Close()
+
+	ctx := interruptContext(c)
+
+	forensicsCfg := visor.DBForensicsConfig{
+		Pubkey:        pubkey,
+		NodeVersion:   Version,
+		MaxBlockDumps: maxBlockDumps,
+	}
+	if !c.Bool("no-progress") {
+		// The bundle can be printed to stdout, so print the progress to stderr
+		forensicsCfg.Progress = func(p visor.VerifyProgress) {
+			fprintVerifyProgress(os.Stderr, p)
+		}
+	}
+
+	f, err := visor.NewDBForensics(ctx, db, forensicsCfg)
+	if forensicsCfg.Progress != nil {
+		// Terminate the progress bar line
+		fmt.Fprintln(os.Stderr)
+	}
+
+	if err != nil {
+		if err == visor.ErrVerifyStopped {
+			return nil
+		}
+		return fmt.Errorf("dbforensics failed: %v", err)
+	}
+
+	out := c.String("out")
+	if out == "" {
+		return printJSON(f)
+	}
+
+	if err := visor.WriteDBForensics(out, f); err != nil {
+		return err
+	}
+
+	fmt.Printf("Forensic bundle written to %s\n", out)
+	return nil
+}
@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	gcli "github.com/urfave/cli"
+
+	"github.com/skycoin/skycoin/src/api"
+	"github.com/skycoin/skycoin/src/visor"
+)
+
+func dbOrphansCmd() gcli.Command {
+	name := "dborphans"
+	return gcli.Command{
+		Name:      name,
+		Usage:     "Find the history db outputs referencing transactions or blocks that no longer exist",
+		ArgsUsage: "[db path]",
+		Description: `Scans the outputs of the history db for outputs left behind by partial history rebuilds:
+		outputs whose source transaction does not exist or does not create them, outputs created by blocks
+		that are not parsed, and outputs spent by transactions that do not exist.
+		Prints a JSON report of the orphaned outputs. With --delete, they are deleted, and the outputs
+		spent by transactions that do not exist are marked as unspent. The node must not be running to delete them.
+		If no argument is specificed, the default data.db in $HOME/.$COIN/ will be inspected.`,
+		Flags: []gcli.Flag{
+			gcli.BoolFlag{
+				Name:  "delete",
+				Usage: "Delete the orphaned outputs",
+			},
+		},
+		OnUsageError: onCommandUsageError(name),
+		Action:       dbOrphans,
+	}
+}
+
+func dbOrphans(c *gcli.Context) error {
+	cfg := ConfigFromContext(c)
+
+	dbpath, err := resolveDBPath(cfg, c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(dbpath); os.IsNotExist(err) {
+		return fmt.Errorf("db file: %v does not exist", dbpath)
+	}
+
+	del := c.Bool("delete")
+
+	db, err := openDB(cfg, dbpath, !del)
+	if err != nil {
+		return fmt.Errorf("open db failed: %v", err)
+	}
+	defer db.Close()
+
+	report, err := visor.CollectOrphanedUxOuts(db, del)
+	if err != nil {
+		return fmt.Errorf("dborphans failed: %v", err)
+	}
+
+	return printJSON(api.NewDBOrphanedUxOutsResponse(report))
+}
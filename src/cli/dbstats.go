@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	gcli "github.com/urfave/cli"
+
+	"github.com/skycoin/skycoin/src/api"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+func dbStatsCmd() gcli.Command {
+	name := "dbstats"
+	return gcli.Command{
+		Name:      name,
+		Usage:     "Print the size, key count and page utilization of every bucket of a database file",
+		ArgsUsage: "[db path]",
+		Description: `Opens the database read-only and prints a JSON report of the size and free space of the database files,
+		and the key count, pages and page utilization of every bucket, largest first.
+		Every page of the database is visited, so this takes a while on a large database.
+		If no argument is specificed, the default data.db in $HOME/.$COIN/ will be inspected.`,
+		OnUsageError: onCommandUsageError(name),
+		Action:       dbStats,
+	}
+}
+
+func dbStats(c *gcli.Context) error {
+	cfg := ConfigFromContext(c)
+
+	dbpath, err := resolveDBPath(cfg, c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(dbpath); os.IsNotExist(err) {
+		return fmt.Errorf("db file: %v does not exist", dbpath)
+	}
+
+	db, err := openDB(cfg, dbpath, true)
+	if err != nil {
+		return fmt.Errorf("open db failed: %v", err)
+	}
+	defer db.Close()
+
+	var stats *dbutil.Stats
+	if err := db.View("dbStats", func(tx *dbutil.Tx) error {
+		var err error
+		stats, err = dbutil.GetStats(tx)
+		return err
+	}); err != nil {
+		return fmt.Errorf("dbstats failed: %v", err)
+	}
+
+	return printJSON(api.NewDBStatsResponse(stats))
+}
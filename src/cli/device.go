@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	gcli "github.com/urfave/cli"
+
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+func deviceAddressesCmd() gcli.Command {
+	name := "deviceAddresses"
+	return gcli.Command{
+		Name:  name,
+		Usage: "Display the addresses of a hardware device",
+		Description: `Display the first addresses of a registered hardware device. With "-confirm",
+		each address is also displayed on the device for the user to confirm it.
+
+		The addresses can be watched with "walletCreateWatchOnly", to create unsigned
+		transactions that are signed on the device with "signTransaction -device".
+
+		Hardware devices are registered by their drivers, which are built into the CLI.`,
+		Flags: []gcli.Flag{
+			gcli.StringFlag{
+				Name:  "device",
+				Usage: "[device name] Name of the hardware device",
+			},
+			gcli.Uint64Flag{
+				Name:  "n",
+				Value: 1,
+				Usage: "[number] Number of addresses to display",
+			},
+			gcli.BoolFlag{
+				Name:  "confirm",
+				Usage: "Confirm each address on the device",
+			},
+		},
+		OnUsageError: onCommandUsageError(name),
+		Action: func(c *gcli.Context) error {
+			name := c.String("device")
+			if name == "" {
+				return gcli.ShowSubcommandHelp(c)
+			}
+
+			n := c.Uint64("n")
+			if n == 0 || n > wallet.DefaultHardwareSignerScanN {
+				return fmt.Errorf("-n must be between 1 and %d", wallet.DefaultHardwareSignerScanN)
+			}
+
+			device, err := openHardwareDevice(name)
+			if err != nil {
+				return err
+			}
+			defer device.Close()
+
+			signer := wallet.NewHardwareSigner(device, wallet.DefaultHardwareSignerScanN)
+			addrs, err := signer.Addresses(uint32(n))
+			if err != nil {
+				return err
+			}
+
+			if c.Bool("confirm") {
+				for _, a := range addrs {
+					if err := signer.ConfirmAddress(a); err != nil {
+						return err
+					}
+				}
+			}
+
+			addrStrs := make([]string, len(addrs))
+			for i, a := range addrs {
+				addrStrs[i] = a.String()
+			}
+
+			return printJSON(struct {
+				Addresses []string `json:"addresses"`
+			}{
+				Addresses: addrStrs,
+			})
+		},
+	}
+}
+
+func openHardwareDevice(name string) (wallet.HardwareDevice, error) {
+	device, err := wallet.OpenHardwareDevice(name)
+	switch err {
+	case nil:
+		return device, nil
+	case wallet.ErrHardwareDeviceNotRegistered:
+		devices := "none"
+		if names := wallet.HardwareDevices(); len(names) > 0 {
+			devices = strings.Join(names, ", ")
+		}
+		return nil, fmt.Errorf("%v, registered devices: %s", err, devices)
+	default:
+		return nil, err
+	}
+}
+
+func signPartialTransactionWithDevice(name string, ptx *wallet.PartialTransaction) error {
+	device, err := openHardwareDevice(name)
+	if err != nil {
+		return err
+	}
+	defer device.Close()
+
+	_, err = wallet.NewHardwareSigner(device, wallet.DefaultHardwareSignerScanN).SignPartialTransaction(ptx)
+	return err
+}
@@ -30,8 +30,8 @@ func encryptWalletCmd(cfg Config) gcli.Command {
 			},
 			gcli.StringFlag{
 				Name:  "x,crypto-type",
-				Value: string(wallet.CryptoTypeScryptChacha20poly1305),
-				Usage: "[crypto type] The crypto type for wallet encryption, can be scrypt-chacha20poly1305 or sha256-xor",
+				Value: string(wallet.CryptoTypeArgon2idChacha20poly1305),
+				Usage: "[crypto type] The crypto type for wallet encryption, can be argon2id-chacha20poly1305, scrypt-chacha20poly1305 or sha256-xor",
 			},
 		},
 		OnUsageError: onCommandUsageError(name),
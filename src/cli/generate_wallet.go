@@ -44,12 +44,30 @@ func walletCreateCmd(cfg Config) gcli.Command {
 			},
 			gcli.BoolFlag{
 				Name:  "rd",
-				Usage: "A random seed consisting of 12 dictionary words will be generated for you",
+				Usage: "A random BIP39 seed consisting of dictionary words will be generated for you",
+			},
+			gcli.UintFlag{
+				Name:  "words",
+				Value: 12,
+				Usage: "Number of words of the seed generated by -rd. Can be 12, 15, 18, 21 or 24",
+			},
+			gcli.StringFlag{
+				Name:  "language",
+				Value: bip39.DefaultLanguage,
+				Usage: "Language of the wordlist of the seed generated by -rd",
 			},
 			gcli.StringFlag{
 				Name:  "s",
 				Usage: "Your seed",
 			},
+			gcli.BoolFlag{
+				Name:  "bip39",
+				Usage: "Require the seed to be a valid BIP39 mnemonic, with a valid checksum",
+			},
+			gcli.StringFlag{
+				Name:  "seed-passphrase",
+				Usage: "BIP39 passphrase of the seed. The seed must be a valid BIP39 mnemonic",
+			},
 			gcli.UintFlag{
 				Name:  "n",
 				Value: 1,
@@ -72,8 +90,8 @@ func walletCreateCmd(cfg Config) gcli.Command {
 			},
 			gcli.StringFlag{
 				Name:  "x,crypto-type",
-				Value: string(wallet.CryptoTypeScryptChacha20poly1305),
-				Usage: "[crypto type] The crypto type for wallet encryption, can be scrypt-chacha20poly1305 or sha256-xor",
+				Value: string(wallet.CryptoTypeArgon2idChacha20poly1305),
+				Usage: "[crypto type] The crypto type for wallet encryption, can be argon2id-chacha20poly1305, scrypt-chacha20poly1305 or sha256-xor",
 			},
 			gcli.StringFlag{
 				Name:  "p",
@@ -133,7 +151,7 @@ func generateWalletHandler(c *gcli.Context) error {
 
 	encrypt := c.Bool("e")
 
-	sd, err := makeSeed(s, r, rd)
+	sd, err := makeSeed(s, r, rd, int(c.Uint("words")), c.String("language"))
 	if err != nil {
 		return err
 	}
@@ -166,11 +184,13 @@ func generateWalletHandler(c *gcli.Context) error {
 	}
 
 	opts := wallet.Options{
-		Label:      label,
-		Seed:       sd,
-		Encrypt:    encrypt,
-		CryptoType: cryptoType,
-		Password:   password,
+		Label:          label,
+		Seed:           sd,
+		Encrypt:        encrypt,
+		CryptoType:     cryptoType,
+		Password:       password,
+		BIP39:          c.Bool("bip39"),
+		SeedPassphrase: c.String("seed-passphrase"),
 	}
 
 	wlt, err := GenerateWallet(wltName, opts, num)
@@ -185,7 +205,7 @@ func generateWalletHandler(c *gcli.Context) error {
 	return printJSON(wallet.NewReadableWallet(wlt))
 }
 
-func makeSeed(s string, r, rd bool) (string, error) {
+func makeSeed(s string, r, rd bool, words int, language string) (string, error) {
 	if s != "" {
 		// 111, 101, 110
 		if r || rd {
@@ -206,7 +226,26 @@ func makeSeed(s string, r, rd bool) (string, error) {
 	}
 
 	// 001, 000
-	return bip39.NewDefaultMnemonic()
+	entropy, err := bip39.EntropyBitSizeForWords(words)
+	if err != nil {
+		return "", err
+	}
+
+	return newMnemonic(entropy, language)
+}
+
+// newMnemonic generates a BIP39 mnemonic from entropyBits bits of entropy, in the wordlist of language
+func newMnemonic(entropyBits int, language string) (string, error) {
+	e, err := bip39.NewEntropy(entropyBits)
+	if err != nil {
+		return "", err
+	}
+
+	m, err := bip39.NewMnemonicWithLanguage(e, language)
+	if err == bip39.ErrUnknownLanguage {
+		return "", fmt.Errorf("unknown language %q, must be one of %s", language, strings.Join(bip39.Languages(), ", "))
+	}
+	return m, err
 }
 
 // PUBLIC
@@ -217,8 +256,10 @@ func GenerateWallet(walletFile string, opts wallet.Options, numAddrs uint64) (*w
 	walletFile = filepath.Base(walletFile)
 
 	wlt, err := wallet.NewWallet(walletFile, wallet.Options{
-		Seed:  opts.Seed,
-		Label: opts.Label,
+		Seed:           opts.Seed,
+		Label:          opts.Label,
+		BIP39:          opts.BIP39,
+		SeedPassphrase: opts.SeedPassphrase,
 	})
 	if err != nil {
 		return nil, err
@@ -0,0 +1,307 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/shopspring/decimal"
+	gcli "github.com/urfave/cli"
+
+	"github.com/skycoin/skycoin/src/api"
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/params"
+	"github.com/skycoin/skycoin/src/visor"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+func createPayoutBatchCmd(cfg Config) gcli.Command {
+	name := "createPayoutBatch"
+	return gcli.Command{
+		Name:  name,
+		Usage: "Create the transactions that pay a batch of payouts from a CSV or JSON file",
+		Description: fmt.Sprintf(`Create the transactions that pay a batch of payouts, such as the
+		withdrawals of an exchange, which can be more than fit in a transaction.
+		The default wallet (%s) will be used if no wallet was specified.
+
+		The payouts are read from a CSV file with "-csv", one "address,coins" row per payout,
+		or from a JSON file with "-json-file", in the format of the "-m" option of
+		"createRawTransaction": [{"addr":"$addr1", "coins": "10.2"}, ...].
+		All the rows are validated before creating any transaction, and the invalid rows are
+		reported by their index, starting from 0.
+
+		The payouts are split in order into transactions of at most "-max-outputs" payouts,
+		which are split again until they fit in the maximum transaction size. Each transaction
+		spends different unspent outputs, so they can all be broadcast together. The change of a
+		transaction can not fund the next ones until it is confirmed, so payouts that can not be
+		funded by the remaining unspent outputs are reported as failures, with the reason, and
+		the following payouts are still paid.
+
+		The coin hours of each transaction are shared with its payouts by "-share-factor",
+		the rest goes to the change.
+
+		Use "-unsigned" to create partial transactions, which are signed with "signTransaction".
+
+		Use caution when using the "-p" command. If you have command history enabled
+		your wallet encryption password can be recovered from the history log. If you
+		do not include the "-p" option you will be prompted to enter your password
+		after you enter your command.
+
+		The raw transactions are in "encoded_transaction" and can be broadcast with
+		"broadcastTransaction". All results are returned in JSON format.`, cfg.FullWalletPath()),
+		Flags: []gcli.Flag{
+			gcli.StringFlag{
+				Name:  "f",
+				Usage: "[wallet file or path] From wallet",
+			},
+			gcli.StringFlag{
+				Name: "c",
+				Usage: `[changeAddress] Specify different change address.
+				By default the wallet's coinbase address will be used.`,
+			},
+			gcli.StringFlag{
+				Name:  "p",
+				Usage: "[password] Wallet password",
+			},
+			gcli.StringFlag{
+				Name:  "csv",
+				Usage: "[filepath] CSV file containing addresses and amounts to send",
+			},
+			gcli.StringFlag{
+				Name:  "json-file",
+				Usage: "[filepath] JSON file containing addresses and amounts to send",
+			},
+			gcli.IntFlag{
+				Name:  "max-outputs",
+				Usage: "[number] Maximum number of payouts per transaction, unlimited if 0",
+			},
+			gcli.StringFlag{
+				Name:  "share-factor",
+				Value: "0.5",
+				Usage: "[factor] Share of the coin hours of a transaction sent to its payouts, between 0 and 1",
+			},
+			gcli.BoolFlag{
+				Name:  "unsigned",
+				Usage: "Create partial transactions that are not signed, to be signed by signTransaction",
+			},
+			gcli.StringFlag{
+				Name:  "coin-selection",
+				Value: wallet.CoinSelectionMinimizeUxOuts,
+				Usage: "[strategy] Strategy to select the unspent outputs to spend",
+			},
+		},
+		OnUsageError: onCommandUsageError(name),
+		Action: func(c *gcli.Context) error {
+			cfg := ConfigFromContext(c)
+
+			payouts, err := getPayouts(c.String("csv"), c.String("json-file"))
+			if err != nil {
+				return err
+			}
+
+			shareFactor, err := decimal.NewFromString(c.String("share-factor"))
+			if err != nil {
+				return fmt.Errorf("invalid share factor: %v", err)
+			}
+
+			walletFile, err := resolveWalletPath(cfg, c.String("f"))
+			if err != nil {
+				return err
+			}
+
+			wlt, err := wallet.Load(walletFile)
+			if err != nil {
+				printHelp(c)
+				return WalletLoadError{err}
+			}
+
+			chgAddr, err := getChangeAddress(walletAddress{Wallet: walletFile}, c.String("c"))
+			if err != nil {
+				return err
+			}
+
+			changeAddress := cipher.MustDecodeBase58Address(chgAddr)
+			if _, ok := wlt.GetEntry(changeAddress); !ok {
+				return fmt.Errorf("change address %v is not in wallet", chgAddr)
+			}
+
+			unsigned := c.Bool("unsigned")
+			if unsigned && c.String("p") != "" {
+				return errors.New("password must not be provided for unsigned transactions")
+			}
+
+			if !wlt.IsEncrypted() && c.String("p") != "" {
+				return wallet.ErrWalletNotEncrypted
+			}
+
+			p := wallet.CreateTransactionParams{
+				HoursSelection: wallet.HoursSelection{
+					Type:        wallet.HoursSelectionTypeAuto,
+					Mode:        wallet.HoursSelectionModeShare,
+					ShareFactor: &shareFactor,
+				},
+				Wallet: wallet.CreateTransactionWalletParams{
+					ID: wlt.Filename(),
+				},
+				ChangeAddress: &changeAddress,
+				To:            payouts,
+				Unsigned:      unsigned,
+				CoinSelection: c.String("coin-selection"),
+			}
+
+			batch, err := CreatePayoutBatch(APIClientFromContext(c), wlt, p, c.Int("max-outputs"), NewPasswordReader([]byte(c.String("p"))))
+			if err != nil {
+				return err
+			}
+
+			rsp, err := api.NewPayoutBatchResponse(batch, p.To)
+			if err != nil {
+				return err
+			}
+
+			return printJSON(rsp)
+		},
+	}
+}
+
+// getPayouts reads and validates the payouts from a CSV file or a JSON file
+func getPayouts(csvFile, jsonFile string) ([]coin.TransactionOutput, error) {
+	var sends []SendAmount
+	switch {
+	case csvFile != "" && jsonFile != "":
+		return nil, errors.New("-csv and -json-file cannot be combined")
+	case csvFile != "":
+		fields, err := openCSV(csvFile)
+		if err != nil {
+			return nil, err
+		}
+
+		sends, err = parseSendAmountsFromCSV(fields)
+		if err != nil {
+			return nil, err
+		}
+	case jsonFile != "":
+		m, err := ioutil.ReadFile(jsonFile)
+		if err != nil {
+			return nil, err
+		}
+
+		sends, err = parseSendAmountsFromJSON(string(m))
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("missing -csv or -json-file")
+	}
+
+	return parsePayouts(sends)
+}
+
+// parsePayouts converts the send amounts to the payouts of a batch, checking that they are valid
+func parsePayouts(sends []SendAmount) ([]coin.TransactionOutput, error) {
+	if len(sends) == 0 {
+		return nil, errors.New("No destination addresses")
+	}
+
+	payouts := make([]coin.TransactionOutput, len(sends))
+	for i, s := range sends {
+		addr, err := cipher.DecodeBase58Address(s.Addr)
+		if err != nil {
+			return nil, fmt.Errorf("[row %d] Invalid address %s: %v", i, s.Addr, err)
+		}
+
+		if s.Coins == 0 {
+			return nil, fmt.Errorf("[row %d] Cannot send 0 coins", i)
+		}
+
+		if s.Coins%params.MaxDropletDivisor() != 0 {
+			return nil, fmt.Errorf("[row %d] Too many decimal places", i)
+		}
+
+		payouts[i] = coin.TransactionOutput{
+			Address: addr,
+			Coins:   s.Coins,
+		}
+	}
+
+	return payouts, nil
+}
+
+// CreatePayoutBatch creates the transactions that pay the payouts of p.To from the unspent outputs of a wallet,
+// see wallet.Wallet.CreatePayoutBatch. The transactions are signed unless p.Unsigned is set.
+func CreatePayoutBatch(c GetOutputser, wlt *wallet.Wallet, p wallet.CreateTransactionParams, maxOutputs int, pr PasswordReader) (*wallet.PayoutBatch, error) {
+	// Get the unspent outputs of the wallet
+	addrs := wlt.GetAddresses()
+	addrStrs := make([]string, len(addrs))
+	for i, a := range addrs {
+		addrStrs[i] = a.String()
+	}
+
+	outputs, err := c.OutputsForAddresses(addrStrs)
+	if err != nil {
+		return nil, err
+	}
+
+	uxa, err := outputs.SpendableOutputs().ToUxArray()
+	if err != nil {
+		return nil, err
+	}
+
+	head, err := outputs.Head.ToCoinBlockHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	auxs := coin.NewAddressUxOuts(uxa)
+
+	var batch *wallet.PayoutBatch
+	if p.Unsigned || !wlt.IsEncrypted() {
+		batch, err = wlt.CreatePayoutBatch(p, maxOutputs, auxs, head.Time)
+	} else {
+		var password []byte
+		password, err = pr.Password()
+		if err != nil {
+			return nil, err
+		}
+
+		err = wlt.GuardView(password, func(w *wallet.Wallet) error {
+			var err error
+			batch, err = w.CreatePayoutBatch(p, maxOutputs, auxs, head.Time)
+			return err
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify the transactions like the node would
+	uxMap := make(map[cipher.SHA256]coin.UxOut, len(uxa))
+	for _, ux := range uxa {
+		uxMap[ux.Hash()] = ux
+	}
+
+	verifyHardConstraints := visor.VerifySingleTxnHardConstraints
+	if p.Unsigned {
+		verifyHardConstraints = visor.VerifySingleTxnHardConstraintsUnsigned
+	}
+
+	for _, bt := range batch.Transactions {
+		inUxs := make(coin.UxArray, len(bt.Transaction.In))
+		for i, h := range bt.Transaction.In {
+			inUxs[i] = uxMap[h]
+		}
+
+		if err := visor.VerifySingleTxnSoftConstraints(*bt.Transaction, head.Time, inUxs, params.UserMaxTransactionSize, params.UserBurnFactor); err != nil {
+			return nil, err
+		}
+		if err := verifyHardConstraints(*bt.Transaction, head, inUxs); err != nil {
+			return nil, err
+		}
+		if err := visor.VerifySingleTxnUserConstraints(*bt.Transaction); err != nil {
+			return nil, err
+		}
+	}
+
+	return batch, nil
+}
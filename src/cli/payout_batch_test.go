@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+)
+
+func TestParsePayouts(t *testing.T) {
+	cases := []struct {
+		name    string
+		sends   []SendAmount
+		payouts []coin.TransactionOutput
+		err     error
+	}{
+		{
+			name: "valid",
+			sends: []SendAmount{
+				{
+					Addr:  "2Niqzo12tZ9ioZq5vwPHMVR4g7UVpp9TCmP",
+					Coins: 123e6,
+				},
+				{
+					Addr:  "2UDzBKnxZf4d9pdrBJAqbtoeH641RFLYKxd",
+					Coins: 123456e3,
+				},
+			},
+			payouts: []coin.TransactionOutput{
+				{
+					Address: cipher.MustDecodeBase58Address("2Niqzo12tZ9ioZq5vwPHMVR4g7UVpp9TCmP"),
+					Coins:   123e6,
+				},
+				{
+					Address: cipher.MustDecodeBase58Address("2UDzBKnxZf4d9pdrBJAqbtoeH641RFLYKxd"),
+					Coins:   123456e3,
+				},
+			},
+		},
+		{
+			name: "no payouts",
+			err:  errors.New("No destination addresses"),
+		},
+		{
+			name: "invalid address",
+			sends: []SendAmount{
+				{
+					Addr:  "2Niqzo12tZ9ioZq5vwPHMVR4g7UVpp9TCmP",
+					Coins: 123e6,
+				},
+				{
+					Addr:  "xxx",
+					Coins: 1e6,
+				},
+			},
+			err: errors.New("[row 1] Invalid address xxx: Invalid address length"),
+		},
+		{
+			name: "zero coins",
+			sends: []SendAmount{
+				{
+					Addr: "2Niqzo12tZ9ioZq5vwPHMVR4g7UVpp9TCmP",
+				},
+			},
+			err: errors.New("[row 0] Cannot send 0 coins"),
+		},
+		{
+			name: "too many decimal places",
+			sends: []SendAmount{
+				{
+					Addr:  "2Niqzo12tZ9ioZq5vwPHMVR4g7UVpp9TCmP",
+					Coins: 123456789,
+				},
+			},
+			err: errors.New("[row 0] Too many decimal places"),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			payouts, err := parsePayouts(tc.sends)
+			if tc.err != nil {
+				require.Equal(t, tc.err, err)
+				require.Nil(t, payouts)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.payouts, payouts)
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"errors"
+	"io/ioutil"
+	"strings"
+
+	gcli "github.com/urfave/cli"
+)
+
+func networkPeersCmd() gcli.Command {
+	name := "networkPeers"
+	return gcli.Command{
+		Name:         name,
+		Usage:        "List the peers known by the node",
+		ArgsUsage:    " ",
+		OnUsageError: onCommandUsageError(name),
+		Action: func(c *gcli.Context) error {
+			client := APIClientFromContext(c)
+			peers, err := client.NetworkPeers()
+			if err != nil {
+				return err
+			}
+
+			return printJSON(peers)
+		},
+	}
+}
+
+func addPeersCmd() gcli.Command {
+	name := "addPeers"
+	return gcli.Command{
+		Name:      name,
+		Usage:     "Add peers to the peer list of the node",
+		ArgsUsage: "[addr1 addr2 ...]",
+		Description: `Adds peers given as ip:port addresses to the peer list of the node,
+		which is persisted. The addresses can also be read from a file, in the peers.txt
+		format: one address per line, empty lines and lines that begin with # are ignored.`,
+		Flags: []gcli.Flag{
+			gcli.StringFlag{
+				Name:  "f",
+				Usage: "[peers file] Read the addresses from a file",
+			},
+		},
+		OnUsageError: onCommandUsageError(name),
+		Action: func(c *gcli.Context) error {
+			addrs, err := peerAddrsFromContext(c)
+			if err != nil {
+				return err
+			}
+
+			client := APIClientFromContext(c)
+			return client.AddPeers(addrs)
+		},
+	}
+}
+
+func removePeersCmd() gcli.Command {
+	name := "removePeers"
+	return gcli.Command{
+		Name:      name,
+		Usage:     "Remove peers from the peer list of the node",
+		ArgsUsage: "[addr1 addr2 ...]",
+		Flags: []gcli.Flag{
+			gcli.StringFlag{
+				Name:  "f",
+				Usage: "[peers file] Read the addresses from a file",
+			},
+		},
+		OnUsageError: onCommandUsageError(name),
+		Action: func(c *gcli.Context) error {
+			addrs, err := peerAddrsFromContext(c)
+			if err != nil {
+				return err
+			}
+
+			client := APIClientFromContext(c)
+			return client.RemovePeers(addrs)
+		},
+	}
+}
+
+func banPeersCmd() gcli.Command {
+	name := "banPeers"
+	return gcli.Command{
+		Name:      name,
+		Usage:     "Ban peer IPs and disconnect them",
+		ArgsUsage: "[ip1 ip2 ...]",
+		Flags: []gcli.Flag{
+			gcli.StringFlag{
+				Name:  "r",
+				Usage: "[reason] Reason of the ban",
+			},
+			gcli.DurationFlag{
+				Name:  "d",
+				Usage: "[duration] Duration of the ban, e.g. 48h. Defaults to the ban duration of the node",
+			},
+		},
+		OnUsageError: onCommandUsageError(name),
+		Action: func(c *gcli.Context) error {
+			ips := []string(c.Args())
+			if len(ips) == 0 {
+				printHelp(c)
+				return errors.New("no IP specified")
+			}
+
+			client := APIClientFromContext(c)
+			return client.BanPeers(ips, c.String("r"), c.Duration("d"))
+		},
+	}
+}
+
+// peerAddrsFromContext returns the addresses given as arguments, and those of the file of the -f flag
+func peerAddrsFromContext(c *gcli.Context) ([]string, error) {
+	addrs := []string(c.Args())
+
+	if fn := c.String("f"); fn != "" {
+		b, err := ioutil.ReadFile(fn)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, line := range strings.Split(string(b), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			addrs = append(addrs, line)
+		}
+	}
+
+	if len(addrs) == 0 {
+		printHelp(c)
+		return nil, errors.New("no address specified")
+	}
+
+	return addrs, nil
+}
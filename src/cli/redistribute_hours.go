@@ -0,0 +1,179 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+
+	gcli "github.com/urfave/cli"
+
+	"github.com/skycoin/skycoin/src/api"
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/params"
+	"github.com/skycoin/skycoin/src/visor"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+func redistributeHoursCmd(cfg Config) gcli.Command {
+	name := "redistributeHours"
+	return gcli.Command{
+		Name:      name,
+		Usage:     "Create a transaction that redistributes the coin hours of a wallet's addresses without moving coins",
+		ArgsUsage: "[mode]",
+		Description: fmt.Sprintf(`Create a transaction that spends the unspent outputs of the addresses
+		of a wallet and sends their coins back to the same addresses, one output per address,
+		so that no coins leave the addresses. The coin hours left after the fee is burned are
+		redistributed between the addresses according to [mode]:
+		  consolidate: send all the coin hours to the address given by "-to"
+		  even: split the coin hours evenly between the addresses
+		  proportional: split the coin hours proportionally to the coins of the addresses
+
+		The default wallet (%s) will be used if no wallet was specified.
+		The unspent outputs spent by unconfirmed transactions are not spent.
+
+		Use caution when using the "-p" command. If you have command history enabled
+		your wallet encryption password can be recovered from the history log. If you
+		do not include the "-p" option you will be prompted to enter your password
+		after you enter your command.
+
+		The raw transaction is in "encoded_transaction" and can be broadcast with
+		"broadcastTransaction". All results are returned in JSON format.`, cfg.FullWalletPath()),
+		Flags: []gcli.Flag{
+			gcli.StringFlag{
+				Name:  "f",
+				Usage: "[wallet file or path] Wallet to redistribute the coin hours of",
+			},
+			gcli.StringFlag{
+				Name:  "to",
+				Usage: "[address] Address that receives the coin hours in the consolidate mode",
+			},
+			gcli.StringFlag{
+				Name:  "p",
+				Usage: "[password] Wallet password",
+			},
+		},
+		OnUsageError: onCommandUsageError(name),
+		Action: func(c *gcli.Context) error {
+			cfg := ConfigFromContext(c)
+
+			if c.NArg() != 1 {
+				printHelp(c)
+				return errors.New("mode is required")
+			}
+
+			var to cipher.Address
+			if s := c.String("to"); s != "" {
+				var err error
+				to, err = cipher.DecodeBase58Address(s)
+				if err != nil {
+					return fmt.Errorf("invalid address %s: %v", s, err)
+				}
+			}
+
+			walletPath, err := resolveWalletPath(cfg, c.String("f"))
+			if err != nil {
+				return err
+			}
+
+			wlt, err := wallet.Load(walletPath)
+			if err != nil {
+				printHelp(c)
+				return WalletLoadError{err}
+			}
+
+			pr := NewPasswordReader([]byte(c.String("p")))
+			txn, inputs, err := RedistributeHours(APIClientFromContext(c), wlt, c.Args().First(), to, pr)
+			if err != nil {
+				return err
+			}
+
+			rsp, err := api.NewCreateTransactionResponse(txn, inputs)
+			if err != nil {
+				return err
+			}
+
+			return printJSON(rsp)
+		},
+	}
+}
+
+// RedistributeHours creates a transaction that redistributes the coin hours of the addresses of a wallet
+// without moving coins, see wallet.Wallet.CreateHoursRedistribution
+func RedistributeHours(c GetOutputser, wlt *wallet.Wallet, mode string, to cipher.Address, pr PasswordReader) (*coin.Transaction, []wallet.UxBalance, error) {
+	p := wallet.HoursRedistributionParams{
+		Wallet: wallet.CreateTransactionWalletParams{
+			ID: wlt.Filename(),
+		},
+		Mode: mode,
+		To:   to,
+	}
+
+	if err := p.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	addrs := wlt.GetAddresses()
+	addrStrs := make([]string, len(addrs))
+	for i, a := range addrs {
+		addrStrs[i] = a.String()
+	}
+
+	outputs, err := c.OutputsForAddresses(addrStrs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	uxa, err := outputs.SpendableOutputs().ToUxArray()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	head, err := outputs.Head.ToCoinBlockHeader()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var txn *coin.Transaction
+	var inputs []wallet.UxBalance
+	create := func(w *wallet.Wallet) error {
+		var err error
+		txn, inputs, err = w.CreateHoursRedistribution(p, coin.NewAddressUxOuts(uxa), head.Time)
+		return err
+	}
+
+	if wlt.IsEncrypted() {
+		password, err := pr.Password()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if err := wlt.GuardView(password, create); err != nil {
+			return nil, nil, err
+		}
+	} else if err := create(wlt); err != nil {
+		return nil, nil, err
+	}
+
+	// Verify the transaction like the node would
+	uxMap := make(map[cipher.SHA256]coin.UxOut, len(uxa))
+	for _, ux := range uxa {
+		uxMap[ux.Hash()] = ux
+	}
+
+	inUxs := make(coin.UxArray, len(txn.In))
+	for i, h := range txn.In {
+		inUxs[i] = uxMap[h]
+	}
+
+	if err := visor.VerifySingleTxnSoftConstraints(*txn, head.Time, inUxs, params.UserMaxTransactionSize, params.UserBurnFactor); err != nil {
+		return nil, nil, err
+	}
+	if err := visor.VerifySingleTxnHardConstraints(*txn, head, inUxs); err != nil {
+		return nil, nil, err
+	}
+	if err := visor.VerifySingleTxnUserConstraints(*txn); err != nil {
+		return nil, nil, err
+	}
+
+	return txn, inputs, nil
+}
@@ -0,0 +1,164 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+
+	gcli "github.com/urfave/cli"
+
+	"github.com/skycoin/skycoin/src/api"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+func signTransactionCmd(cfg Config) gcli.Command {
+	name := "signTransaction"
+	return gcli.Command{
+		Name:      name,
+		Usage:     "Sign a partial transaction with the keys of a local wallet",
+		ArgsUsage: "[partial transactions]",
+		Description: fmt.Sprintf(`Sign the inputs of a partial transaction that are owned by a wallet.
+		The default wallet (%s) will be used if no wallet was specified.
+
+		Partial transactions are created by "createRawTransaction -unsigned" or by the
+		create transaction API with "unsigned": true. This command does not connect to
+		a node, so it can run on an offline machine that holds the wallet.
+
+		If several partial transactions of the same transaction are given, for instance
+		signed separately by different wallets, they are combined before signing.
+
+		Once all the inputs are signed, the result includes the raw transaction, which
+		can be broadcast with "broadcastTransaction".
+
+		With "-device", the transaction is signed on a registered hardware device instead,
+		one input at a time, and the keys never leave the device.
+
+		Use caution when using the "-p" command. If you have command history enabled
+		your wallet encryption password can be recovered from the history log. If you
+		do not include the "-p" option you will be prompted to enter your password
+		after you enter your command.
+
+		All results are returned in JSON format.`, cfg.FullWalletPath()),
+		Flags: []gcli.Flag{
+			gcli.StringFlag{
+				Name:  "wallet,f",
+				Value: cfg.FullWalletPath(),
+				Usage: "[wallet file or path] Sign with the keys of this wallet",
+			},
+			gcli.StringFlag{
+				Name:  "p",
+				Usage: "[password] Wallet password",
+			},
+			gcli.StringFlag{
+				Name:  "device",
+				Usage: "[device name] Sign on this hardware device instead of with a wallet",
+			},
+		},
+		OnUsageError: onCommandUsageError(name),
+		Action: func(c *gcli.Context) error {
+			cfg := ConfigFromContext(c)
+
+			ptx, err := combinePartialTransactions(c.Args())
+			if err != nil {
+				return err
+			}
+
+			if device := c.String("device"); device != "" {
+				if c.String("p") != "" {
+					return errors.New("password must not be provided when signing with a device")
+				}
+
+				if err := signPartialTransactionWithDevice(device, ptx); err != nil {
+					return err
+				}
+
+				return printJSON(api.NewPartialTransactionResponse(ptx))
+			}
+
+			walletFile, err := resolveWalletPath(cfg, c.String("wallet"))
+			if err != nil {
+				return err
+			}
+
+			wlt, err := wallet.Load(walletFile)
+			if err != nil {
+				printHelp(c)
+				return WalletLoadError{err}
+			}
+
+			if err := signPartialTransaction(wlt, ptx, c.String("p")); err != nil {
+				return err
+			}
+
+			return printJSON(api.NewPartialTransactionResponse(ptx))
+		},
+	}
+}
+
+func combineTransactionsCmd() gcli.Command {
+	name := "combineTransactions"
+	return gcli.Command{
+		Name:      name,
+		Usage:     "Combine the signatures of partial transactions of the same transaction",
+		ArgsUsage: "[partial transactions]",
+		Description: `Combine partial transactions of the same transaction, signed separately
+		by different wallets with "signTransaction". Once all the inputs are signed, the
+		result includes the raw transaction, which can be broadcast with "broadcastTransaction".
+
+		All results are returned in JSON format.`,
+		OnUsageError: onCommandUsageError(name),
+		Action: func(c *gcli.Context) error {
+			ptx, err := combinePartialTransactions(c.Args())
+			if err != nil {
+				return err
+			}
+
+			return printJSON(api.NewPartialTransactionResponse(ptx))
+		},
+	}
+}
+
+func combinePartialTransactions(args []string) (*wallet.PartialTransaction, error) {
+	if len(args) == 0 {
+		return nil, errors.New("missing partial transaction")
+	}
+
+	var combined *wallet.PartialTransaction
+	for _, a := range args {
+		ptx, err := wallet.PartialTransactionDeserializeHex(a)
+		if err != nil {
+			return nil, err
+		}
+
+		if combined == nil {
+			combined = ptx
+			continue
+		}
+
+		if err := combined.Combine(ptx); err != nil {
+			return nil, err
+		}
+	}
+
+	return combined, nil
+}
+
+func signPartialTransaction(wlt *wallet.Wallet, ptx *wallet.PartialTransaction, password string) error {
+	if !wlt.IsEncrypted() {
+		if password != "" {
+			return wallet.ErrWalletNotEncrypted
+		}
+
+		_, err := wlt.SignPartialTransaction(ptx)
+		return err
+	}
+
+	p, err := NewPasswordReader([]byte(password)).Password()
+	if err != nil {
+		return err
+	}
+
+	return wlt.GuardView(p, func(w *wallet.Wallet) error {
+		_, err := w.SignPartialTransaction(ptx)
+		return err
+	})
+}
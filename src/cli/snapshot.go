@@ -0,0 +1,373 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	gcli "github.com/urfave/cli"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/visor"
+)
+
+func exportSnapshotCmd() gcli.Command {
+	name := "exportSnapshot"
+	return gcli.Command{
+		Name:      name,
+		Usage:     "Export the blockchain to a snapshot file",
+		ArgsUsage: "[snapshot file] [db path]",
+		Description: `Writes all blocks and their signatures to a snapshot file,
+		which can be imported with importSnapshot to bootstrap a new database.
+		If no db path is specificed, the default data.db in $HOME/.$COIN/ will be exported.`,
+		OnUsageError: onCommandUsageError(name),
+		Action:       exportSnapshot,
+	}
+}
+
+func exportSnapshot(c *gcli.Context) error {
+	cfg := ConfigFromContext(c)
+
+	snapshotPath := c.Args().First()
+	if snapshotPath == "" {
+		return fmt.Errorf("snapshot file is required")
+	}
+
+	dbpath, err := resolveDBPath(cfg, c.Args().Get(1))
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(dbpath); os.IsNotExist(err) {
+		return fmt.Errorf("db file: %v does not exist", dbpath)
+	}
+
+	db, err := openDB(cfg, dbpath, true)
+	if err != nil {
+		return fmt.Errorf("open db failed: %v", err)
+	}
+	defer db.Close()
+
+	f, err := os.OpenFile(snapshotPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("create snapshot file failed: %v", err)
+	}
+	defer f.Close()
+
+	ctx := interruptContext(c)
+
+	n, err := visor.ExportSnapshot(ctx, db, f)
+	if err == nil {
+		err = f.Close()
+	}
+	if err != nil {
+		if rmErr := os.Remove(snapshotPath); rmErr != nil {
+			fmt.Fprintf(os.Stderr, "remove snapshot file failed: %v\n", rmErr)
+		}
+		if err == visor.ErrVerifyStopped {
+			return nil
+		}
+		return fmt.Errorf("export snapshot failed: %v", err)
+	}
+
+	fmt.Printf("exported %d blocks to %s\n", n, snapshotPath)
+	return nil
+}
+
+func importSnapshotCmd() gcli.Command {
+	name := "importSnapshot"
+	return gcli.Command{
+		Name:      name,
+		Usage:     "Create a database from a snapshot file",
+		ArgsUsage: "[snapshot file] [db path]",
+		Description: `Creates a new database from a snapshot file made with exportSnapshot.
+		The signature and validity of every block is verified.
+		The database must not exist already. If no db path is specificed,
+		the default data.db in $HOME/.$COIN/ will be created.
+
+		Blocks must match the trusted block hashes given with --checkpoints.
+		With --fast, the signatures of the blocks up to the latest checkpoint are not verified,
+		these blocks are authenticated by the checkpoint hash instead. If the import fails or is
+		stopped, the database is deleted, since it may contain blocks that were not authenticated.`,
+		Flags: []gcli.Flag{
+			gcli.StringFlag{
+				Name:  "checkpoints",
+				Usage: "Comma-separated list of trusted block hashes, in the format seq:hash",
+			},
+			gcli.BoolFlag{
+				Name:  "fast",
+				Usage: "Skip the signature verification of the blocks up to the latest checkpoint",
+			},
+		},
+		OnUsageError: onCommandUsageError(name),
+		Action:       importSnapshot,
+	}
+}
+
+func importSnapshot(c *gcli.Context) error {
+	cfg := ConfigFromContext(c)
+
+	snapshotPath := c.Args().First()
+	if snapshotPath == "" {
+		return fmt.Errorf("snapshot file is required")
+	}
+
+	dbpath, err := resolveDBPath(cfg, c.Args().Get(1))
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(dbpath); err == nil {
+		return fmt.Errorf("db file: %v already exists", dbpath)
+	}
+
+	pubkey, err := cipher.PubKeyFromHex(blockchainPubkey)
+	if err != nil {
+		return fmt.Errorf("decode blockchain pubkey failed: %v", err)
+	}
+
+	checkpoints, err := visor.ParseCheckpoints(strings.Split(c.String("checkpoints"), ","))
+	if err != nil {
+		return err
+	}
+
+	fast := c.Bool("fast")
+	if fast && len(checkpoints) == 0 {
+		return fmt.Errorf("--fast requires --checkpoints")
+	}
+
+	f, err := os.Open(snapshotPath)
+	if err != nil {
+		return fmt.Errorf("open snapshot file failed: %v", err)
+	}
+	defer f.Close()
+
+	db, err := openDB(cfg, dbpath, false)
+	if err != nil {
+		return fmt.Errorf("open db failed: %v", err)
+	}
+	defer db.Close()
+
+	ctx := interruptContext(c)
+
+	n, err := visor.ImportSnapshot(ctx, db, f, visor.ImportSnapshotConfig{
+		Pubkey:                     pubkey,
+		Checkpoints:                checkpoints,
+		SkipCheckpointedSignatures: fast,
+	})
+	if err != nil && fast {
+		// The blocks imported so far may not have been authenticated by a checkpoint yet
+		db.Close()
+		if rmErr := os.Remove(dbpath); rmErr != nil {
+			return fmt.Errorf("import snapshot failed after %d blocks: %v. Remove the unverified database %s failed: %v", n, err, dbpath, rmErr)
+		}
+		return fmt.Errorf("import snapshot failed after %d blocks, the database was deleted: %v", n, err)
+	}
+	if err != nil {
+		if err == visor.ErrVerifyStopped {
+			fmt.Printf("import stopped after %d blocks\n", n)
+			return nil
+		}
+		return fmt.Errorf("import snapshot failed after %d blocks: %v", n, err)
+	}
+
+	fmt.Printf("imported %d blocks to %s\n", n, dbpath)
+	return nil
+}
+
+func exportUnspentSnapshotCmd() gcli.Command {
+	name := "exportUnspentSnapshot"
+	return gcli.Command{
+		Name:      name,
+		Usage:     "Export the unspent output set to a snapshot file",
+		ArgsUsage: "[snapshot file] [db path]",
+		Description: `Writes the unspent output set after a block, with the headers of the blocks up to it,
+		to a snapshot file which can be imported with importUnspentSnapshot to bootstrap a new database
+		that only syncs the later blocks. The head block is used if --seq is not given, the unspent output set
+		after an earlier block is rebuilt from the history of the database.
+		If no db path is specificed, the default data.db in $HOME/.$COIN/ will be exported.`,
+		Flags: []gcli.Flag{
+			gcli.StringFlag{
+				Name:  "seq",
+				Usage: "Seq of the block after which the unspent output set is exported, the head block by default",
+			},
+		},
+		OnUsageError: onCommandUsageError(name),
+		Action:       exportUnspentSnapshot,
+	}
+}
+
+func exportUnspentSnapshot(c *gcli.Context) error {
+	cfg := ConfigFromContext(c)
+
+	snapshotPath := c.Args().First()
+	if snapshotPath == "" {
+		return fmt.Errorf("snapshot file is required")
+	}
+
+	var seq *uint64
+	if s := c.String("seq"); s != "" {
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid --seq: %v", err)
+		}
+		seq = &n
+	}
+
+	dbpath, err := resolveDBPath(cfg, c.Args().Get(1))
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(dbpath); os.IsNotExist(err) {
+		return fmt.Errorf("db file: %v does not exist", dbpath)
+	}
+
+	db, err := openDB(cfg, dbpath, true)
+	if err != nil {
+		return fmt.Errorf("open db failed: %v", err)
+	}
+	defer db.Close()
+
+	f, err := os.OpenFile(snapshotPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("create snapshot file failed: %v", err)
+	}
+	defer f.Close()
+
+	ctx := interruptContext(c)
+
+	hdr, err := visor.ExportUnspentSnapshot(ctx, db, f, seq)
+	if err == nil {
+		err = f.Close()
+	}
+	if err != nil {
+		if rmErr := os.Remove(snapshotPath); rmErr != nil {
+			fmt.Fprintf(os.Stderr, "remove snapshot file failed: %v\n", rmErr)
+		}
+		if err == visor.ErrVerifyStopped {
+			return nil
+		}
+		return fmt.Errorf("export unspent snapshot failed: %v", err)
+	}
+
+	fmt.Printf("exported %d unspent outputs after block %d to %s\n", hdr.UxCount, hdr.Seq, snapshotPath)
+	fmt.Printf("unspent commitment: %s\n", hdr.Commitment.Hex())
+	return nil
+}
+
+func importUnspentSnapshotCmd() gcli.Command {
+	name := "importUnspentSnapshot"
+	return gcli.Command{
+		Name:      name,
+		Usage:     "Create a database from an unspent output set snapshot file",
+		ArgsUsage: "[snapshot file] [db path]",
+		Description: `Creates a new database from a snapshot file made with exportUnspentSnapshot.
+		The node started on the database only syncs the blocks after the snapshot block.
+		The signature of every block header is verified, the block bodies are not imported.
+		The database must not exist already. If no db path is specificed,
+		the default data.db in $HOME/.$COIN/ will be created.
+
+		The unspent outputs must match the trusted unspent commitment given with --commitment.
+		Without it, they are authenticated by the header of the block after the snapshot block
+		once it is synced, and the node rejects every block if they were forged.
+		Blocks must match the trusted block hashes given with --checkpoints.
+		With --fast, the signatures of the blocks up to the latest checkpoint are not verified.
+		If the import fails or is stopped, the database is deleted.`,
+		Flags: []gcli.Flag{
+			gcli.StringFlag{
+				Name:  "commitment",
+				Usage: "Trusted hash of the unspent output set, as printed by exportUnspentSnapshot",
+			},
+			gcli.StringFlag{
+				Name:  "checkpoints",
+				Usage: "Comma-separated list of trusted block hashes, in the format seq:hash",
+			},
+			gcli.BoolFlag{
+				Name:  "fast",
+				Usage: "Skip the signature verification of the blocks up to the latest checkpoint",
+			},
+		},
+		OnUsageError: onCommandUsageError(name),
+		Action:       importUnspentSnapshot,
+	}
+}
+
+func importUnspentSnapshot(c *gcli.Context) error {
+	cfg := ConfigFromContext(c)
+
+	snapshotPath := c.Args().First()
+	if snapshotPath == "" {
+		return fmt.Errorf("snapshot file is required")
+	}
+
+	dbpath, err := resolveDBPath(cfg, c.Args().Get(1))
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(dbpath); err == nil {
+		return fmt.Errorf("db file: %v already exists", dbpath)
+	}
+
+	pubkey, err := cipher.PubKeyFromHex(blockchainPubkey)
+	if err != nil {
+		return fmt.Errorf("decode blockchain pubkey failed: %v", err)
+	}
+
+	var commitment *cipher.SHA256
+	if s := c.String("commitment"); s != "" {
+		h, err := cipher.SHA256FromHex(s)
+		if err != nil {
+			return fmt.Errorf("invalid --commitment: %v", err)
+		}
+		commitment = &h
+	}
+
+	checkpoints, err := visor.ParseCheckpoints(strings.Split(c.String("checkpoints"), ","))
+	if err != nil {
+		return err
+	}
+
+	fast := c.Bool("fast")
+	if fast && len(checkpoints) == 0 {
+		return fmt.Errorf("--fast requires --checkpoints")
+	}
+
+	f, err := os.Open(snapshotPath)
+	if err != nil {
+		return fmt.Errorf("open snapshot file failed: %v", err)
+	}
+	defer f.Close()
+
+	db, err := openDB(cfg, dbpath, false)
+	if err != nil {
+		return fmt.Errorf("open db failed: %v", err)
+	}
+	defer db.Close()
+
+	ctx := interruptContext(c)
+
+	hdr, err := visor.ImportUnspentSnapshot(ctx, db, f, visor.ImportUnspentSnapshotConfig{
+		Pubkey:                     pubkey,
+		Checkpoints:                checkpoints,
+		SkipCheckpointedSignatures: fast,
+		Commitment:                 commitment,
+	})
+	if err != nil {
+		// The snapshot is imported in a single transaction, so the database is empty
+		db.Close()
+		if rmErr := os.Remove(dbpath); rmErr != nil {
+			fmt.Fprintf(os.Stderr, "remove database %s failed: %v\n", dbpath, rmErr)
+		}
+		if err == visor.ErrVerifyStopped {
+			fmt.Println("import stopped")
+			return nil
+		}
+		return fmt.Errorf("import unspent snapshot failed: %v", err)
+	}
+
+	fmt.Printf("imported %d unspent outputs after block %d to %s\n", hdr.UxCount, hdr.Seq, dbpath)
+	return nil
+}
@@ -0,0 +1,228 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+
+	gcli "github.com/urfave/cli"
+
+	"github.com/skycoin/skycoin/src/api"
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/params"
+	"github.com/skycoin/skycoin/src/visor"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+func sweepPrivateKeysCmd(cfg Config) gcli.Command {
+	name := "sweepPrivateKeys"
+	return gcli.Command{
+		Name:  name,
+		Usage: "Create the transactions that send all the coins and coin hours of private keys to a wallet address",
+		Description: fmt.Sprintf(`Create the transactions that send all the coins and coin hours of the
+		unspent outputs of private keys to an address, such as the keys of a paper wallet.
+		The destination is the address given by "-a", or the first address of the wallet.
+		The default wallet (%s) will be used if no wallet was specified.
+		If both are given, the address must be in the wallet.
+
+		The private keys are read from the file given by "-keys-file", or from stdin,
+		as hex strings separated by whitespace. They are not accepted as arguments, so that
+		they are not recorded in the command history. The transactions are created and
+		signed locally, the private keys are not sent to the node.
+
+		The unspent outputs are split into as few transactions within the maximum transaction
+		size as possible, and each transaction burns the required coin hours fee. The unspent
+		outputs that can't be swept, because their coins have too many decimal places or
+		because they have no coin hours to pay the fee of their transaction, are returned
+		in "dust".
+
+		The raw transactions are in "encoded_transaction" and can be broadcast with
+		"broadcastTransaction". All results are returned in JSON format.`, cfg.FullWalletPath()),
+		Flags: []gcli.Flag{
+			gcli.StringFlag{
+				Name:  "f",
+				Usage: "[wallet file or path] Destination wallet",
+			},
+			gcli.StringFlag{
+				Name:  "a",
+				Usage: "[address] Destination address",
+			},
+			gcli.StringFlag{
+				Name:  "keys-file",
+				Usage: "[filepath] File containing the hex-encoded private keys, stdin if not set",
+			},
+		},
+		OnUsageError: onCommandUsageError(name),
+		Action: func(c *gcli.Context) error {
+			cfg := ConfigFromContext(c)
+
+			dest, err := getSweepDestination(cfg, c.String("f"), c.String("a"))
+			if err != nil {
+				return err
+			}
+
+			r := io.Reader(os.Stdin)
+			if keysFile := c.String("keys-file"); keysFile != "" {
+				f, err := os.Open(keysFile)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				r = f
+			}
+
+			keys, err := parseSweepKeys(r)
+			if err != nil {
+				return err
+			}
+
+			sweep, err := SweepPrivateKeys(APIClientFromContext(c), keys, dest)
+			if err != nil {
+				return err
+			}
+
+			rsp, err := api.NewSweepResponse(sweep)
+			if err != nil {
+				return err
+			}
+
+			return printJSON(rsp)
+		},
+	}
+}
+
+// getSweepDestination returns the destination address of a sweep, which is addr if set,
+// or else the first address of the wallet. addr must be in the wallet if both are set.
+func getSweepDestination(cfg Config, walletFile, addr string) (cipher.Address, error) {
+	var dest cipher.Address
+	if addr != "" {
+		var err error
+		dest, err = cipher.DecodeBase58Address(addr)
+		if err != nil {
+			return cipher.Address{}, fmt.Errorf("invalid address %s: %v", addr, err)
+		}
+
+		if walletFile == "" {
+			return dest, nil
+		}
+	}
+
+	walletPath, err := resolveWalletPath(cfg, walletFile)
+	if err != nil {
+		return cipher.Address{}, err
+	}
+
+	wlt, err := wallet.Load(walletPath)
+	if err != nil {
+		return cipher.Address{}, WalletLoadError{err}
+	}
+
+	addrs, err := wlt.GetSkycoinAddresses()
+	if err != nil {
+		return cipher.Address{}, err
+	}
+
+	if len(addrs) == 0 {
+		return cipher.Address{}, fmt.Errorf("wallet %s has no addresses", walletPath)
+	}
+
+	if dest.Null() {
+		return addrs[0], nil
+	}
+
+	for _, a := range addrs {
+		if a == dest {
+			return dest, nil
+		}
+	}
+
+	return cipher.Address{}, fmt.Errorf("address %s is not in wallet", addr)
+}
+
+// parseSweepKeys reads hex-encoded private keys separated by whitespace
+func parseSweepKeys(r io.Reader) ([]cipher.SecKey, error) {
+	s := bufio.NewScanner(r)
+	s.Split(bufio.ScanWords)
+
+	var keys []cipher.SecKey
+	for s.Scan() {
+		k, err := cipher.SecKeyFromHex(s.Text())
+		if err != nil {
+			return nil, fmt.Errorf("invalid private key at index %d: %v", len(keys), err)
+		}
+		keys = append(keys, k)
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// SweepPrivateKeys creates the transactions that send all the coins and coin hours of the unspent outputs
+// of private keys to dest, see wallet.SweepKeys.CreateSweep
+func SweepPrivateKeys(c GetOutputser, keys []cipher.SecKey, dest cipher.Address) (*wallet.Sweep, error) {
+	sk, err := wallet.NewSweepKeys(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs := sk.Addresses()
+	addrStrs := make([]string, len(addrs))
+	for i, a := range addrs {
+		addrStrs[i] = a.String()
+	}
+
+	outputs, err := c.OutputsForAddresses(addrStrs)
+	if err != nil {
+		return nil, err
+	}
+
+	uxa, err := outputs.SpendableOutputs().ToUxArray()
+	if err != nil {
+		return nil, err
+	}
+
+	head, err := outputs.Head.ToCoinBlockHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	uxb, err := wallet.NewUxBalances(head.Time, uxa)
+	if err != nil {
+		return nil, err
+	}
+
+	sweep, err := sk.CreateSweep(uxb, dest, params.UserMaxTransactionSize)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify the transactions like the node would
+	uxMap := make(map[cipher.SHA256]coin.UxOut, len(uxa))
+	for _, ux := range uxa {
+		uxMap[ux.Hash()] = ux
+	}
+
+	for _, st := range sweep.Transactions {
+		inUxs := make(coin.UxArray, len(st.Transaction.In))
+		for i, h := range st.Transaction.In {
+			inUxs[i] = uxMap[h]
+		}
+
+		if err := visor.VerifySingleTxnSoftConstraints(*st.Transaction, head.Time, inUxs, params.UserMaxTransactionSize, params.UserBurnFactor); err != nil {
+			return nil, err
+		}
+		if err := visor.VerifySingleTxnHardConstraints(*st.Transaction, head, inUxs); err != nil {
+			return nil, err
+		}
+		if err := visor.VerifySingleTxnUserConstraints(*st.Transaction); err != nil {
+			return nil, err
+		}
+	}
+
+	return sweep, nil
+}
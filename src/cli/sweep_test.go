@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+func TestParseSweepKeys(t *testing.T) {
+	_, k1 := cipher.GenerateKeyPair()
+	_, k2 := cipher.GenerateKeyPair()
+
+	cases := []struct {
+		name  string
+		input string
+		keys  []cipher.SecKey
+		err   error
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:  "keys separated by whitespace",
+			input: k1.Hex() + "\n\n " + k2.Hex() + "\t\n",
+			keys:  []cipher.SecKey{k1, k2},
+		},
+		{
+			name:  "invalid key",
+			input: k1.Hex() + "\nxxx\n",
+			err:   errors.New("invalid private key at index 1: Invalid secret key"),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			keys, err := parseSweepKeys(strings.NewReader(tc.input))
+			if tc.err != nil {
+				require.Equal(t, tc.err, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.keys, keys)
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	gcli "github.com/urfave/cli"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/visor"
+)
+
+func verifydbCmd() gcli.Command {
+	name := "verifydb"
+	return gcli.Command{
+		Name:      name,
+		Usage:     "Verify a database file without modifying it and print a JSON report",
+		ArgsUsage: "[db path]",
+		Description: `Opens the database read-only and verifies the signature and historydb indexes of every block.
+		Any database file can be verified, for example the database of another node or a quarantined
+		data.db.corrupt.$HASH file. The database is never modified, rebuilt or quarantined.
+		Prints a JSON report listing all corrupted blocks, and exits with an error if the database is corrupted.
+		If no argument is specificed, the default data.db in $HOME/.$COIN/ will be verified.`,
+		Flags: []gcli.Flag{
+			gcli.BoolFlag{
+				Name:  "no-progress",
+				Usage: "Don't print the verification progress to stderr",
+			},
+		},
+		OnUsageError: onCommandUsageError(name),
+		Action:       verifydb,
+	}
+}
+
+func verifydb(c *gcli.Context) error {
+	cfg := ConfigFromContext(c)
+
+	dbpath, err := resolveDBPath(cfg, c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(dbpath); os.IsNotExist(err) {
+		return fmt.Errorf("db file: %v does not exist", dbpath)
+	}
+
+	pubkey, err := cipher.PubKeyFromHex(blockchainPubkey)
+	if err != nil {
+		return fmt.Errorf("decode blockchain pubkey failed: %v", err)
+	}
+
+	ctx := interruptContext(c)
+
+	verifyCfg := visor.VerifyDBFileConfig{
+		Pubkey: pubkey,
+	}
+	if !c.Bool("no-progress") {
+		// The report is printed to stdout, so print the progress to stderr
+		verifyCfg.Progress = func(p visor.VerifyProgress) {
+			fprintVerifyProgress(os.Stderr, p)
+		}
+	}
+
+	report, err := visor.VerifyDBFile(ctx, dbpath, verifyCfg)
+	if verifyCfg.Progress != nil {
+		// Terminate the progress bar line
+		fmt.Fprintln(os.Stderr)
+	}
+
+	if err != nil {
+		if err == visor.ErrVerifyStopped {
+			return nil
+		}
+		return fmt.Errorf("verifydb failed: %v", err)
+	}
+
+	if err := printJSON(report); err != nil {
+		return err
+	}
+
+	if report.Corrupted {
+		return errors.New("database is corrupted")
+	}
+
+	return nil
+}
@@ -0,0 +1,217 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	gcli "github.com/urfave/cli"
+
+	"github.com/skycoin/skycoin/src/api"
+	"github.com/skycoin/skycoin/src/util/file"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+// WalletBackupResult is the result of the walletBackup command
+type WalletBackupResult struct {
+	File    string   `json:"file"`
+	Wallets []string `json:"wallets"`
+}
+
+func walletBackupCmd(cfg Config) gcli.Command {
+	name := "walletBackup"
+	return gcli.Command{
+		Name:      name,
+		Usage:     "Back up wallets into a passphrase-encrypted archive",
+		ArgsUsage: "[archive file]",
+		Description: fmt.Sprintf(`Back up the wallets of the wallet directory (%s), or the wallet
+		specified with "-f", into a single archive encrypted with a passphrase.
+		The wallets are kept with their labels, annotations and other metadata.
+		The secrets of encrypted wallets stay encrypted with the wallet password.
+
+		The archive is restored with "walletRestore".
+
+		Use caution when using the "-p" command. If you have command history enabled
+		your backup passphrase can be recovered from the history log. If you
+		do not include the "-p" option you will be prompted to enter your passphrase
+		after you enter your command.`, cfg.WalletDir),
+		Flags: []gcli.Flag{
+			gcli.StringFlag{
+				Name:  "f",
+				Usage: "[wallet file or path] Back up this wallet only",
+			},
+			gcli.StringFlag{
+				Name:  "p",
+				Usage: "[passphrase] Backup passphrase",
+			},
+			gcli.StringFlag{
+				Name:  "x,crypto-type",
+				Value: string(wallet.CryptoTypeArgon2idChacha20poly1305),
+				Usage: "[crypto type] The crypto type for backup encryption, can be argon2id-chacha20poly1305, scrypt-chacha20poly1305 or sha256-xor",
+			},
+		},
+		OnUsageError: onCommandUsageError(name),
+		Action: func(c *gcli.Context) error {
+			cfg := ConfigFromContext(c)
+
+			if c.NArg() != 1 {
+				printHelp(c)
+				return errors.New("archive file is required")
+			}
+
+			cryptoType, err := wallet.CryptoTypeFromString(c.String("x"))
+			if err != nil {
+				printHelp(c)
+				return err
+			}
+
+			var walletFiles []string
+			if c.String("f") != "" {
+				w, err := resolveWalletPath(cfg, c.String("f"))
+				if err != nil {
+					return err
+				}
+				walletFiles = []string{w}
+			}
+
+			pr := NewPasswordReader([]byte(c.String("p")))
+			result, err := backupWallets(cfg.WalletDir, walletFiles, c.Args().First(), pr, cryptoType)
+			switch err.(type) {
+			case nil:
+			case WalletLoadError:
+				printHelp(c)
+				return err
+			default:
+				return err
+			}
+
+			return printJSON(result)
+		},
+	}
+}
+
+// backupWallets saves a backup archive of wallet files to archiveFile.
+// If walletFiles is empty, all the wallets of walletDir are backed up.
+func backupWallets(walletDir string, walletFiles []string, archiveFile string, pr PasswordReader, cryptoType wallet.CryptoType) (*WalletBackupResult, error) {
+	var wlts []*wallet.Wallet
+	if len(walletFiles) == 0 {
+		ws, err := wallet.LoadWallets(walletDir)
+		if err != nil {
+			return nil, WalletLoadError{err}
+		}
+
+		for _, w := range ws {
+			wlts = append(wlts, w)
+		}
+		sort.Slice(wlts, func(i, j int) bool {
+			return wlts[i].Filename() < wlts[j].Filename()
+		})
+	} else {
+		for _, f := range walletFiles {
+			w, err := wallet.Load(f)
+			if err != nil {
+				return nil, WalletLoadError{err}
+			}
+			wlts = append(wlts, w)
+		}
+	}
+
+	if len(wlts) == 0 {
+		return nil, errors.New("no wallet to back up")
+	}
+
+	passphrase, err := pr.Password()
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := wallet.NewBackupArchive(wlts, passphrase, cryptoType)
+	if err != nil {
+		return nil, err
+	}
+
+	// Refuses to overwrite an existing file, which may be a previous backup
+	if err := file.SaveJSONSafe(archiveFile, a, 0600); err != nil {
+		return nil, err
+	}
+
+	result := &WalletBackupResult{
+		File:    archiveFile,
+		Wallets: make([]string, len(wlts)),
+	}
+	for i, w := range wlts {
+		result.Wallets[i] = w.Filename()
+	}
+
+	return result, nil
+}
+
+func walletRestoreCmd(cfg Config) gcli.Command {
+	name := "walletRestore"
+	return gcli.Command{
+		Name:      name,
+		Usage:     "Restore the wallets of a backup archive",
+		ArgsUsage: "[archive file]",
+		Description: fmt.Sprintf(`Restore the wallets of an archive created with "walletBackup"
+		or the /api/v2/wallet/backup endpoint into the wallet directory (%s).
+
+		A wallet conflicts with the wallet directory if it has the file name of a wallet
+		of the directory, or the seed of a wallet of the directory. If some wallets conflict,
+		nothing is restored, unless "-skip-conflicts" is set, in which case the other
+		wallets are restored. The restored and the skipped wallets are returned.
+
+		Use caution when using the "-p" command. If you have command history enabled
+		your backup passphrase can be recovered from the history log. If you
+		do not include the "-p" option you will be prompted to enter your passphrase
+		after you enter your command.
+
+		All results are returned in JSON format.`, cfg.WalletDir),
+		Flags: []gcli.Flag{
+			gcli.StringFlag{
+				Name:  "p",
+				Usage: "[passphrase] Backup passphrase",
+			},
+			gcli.BoolFlag{
+				Name:  "skip-conflicts",
+				Usage: "Restore the wallets that don't conflict with the wallet directory",
+			},
+		},
+		OnUsageError: onCommandUsageError(name),
+		Action: func(c *gcli.Context) error {
+			cfg := ConfigFromContext(c)
+
+			if c.NArg() != 1 {
+				printHelp(c)
+				return errors.New("archive file is required")
+			}
+
+			pr := NewPasswordReader([]byte(c.String("p")))
+			rsp, err := restoreWallets(cfg.WalletDir, c.Args().First(), pr, c.Bool("skip-conflicts"))
+			if err != nil {
+				return err
+			}
+
+			return printJSON(rsp)
+		},
+	}
+}
+
+// restoreWallets restores the wallets of a backup archive file into walletDir, see wallet.RestoreBackupToDir
+func restoreWallets(walletDir, archiveFile string, pr PasswordReader, skipConflicts bool) (*api.WalletRestoreResponse, error) {
+	var a wallet.BackupArchive
+	if err := file.LoadJSON(archiveFile, &a); err != nil {
+		return nil, fmt.Errorf("invalid backup archive %s: %v", archiveFile, err)
+	}
+
+	passphrase, err := pr.Password()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := wallet.RestoreBackupToDir(walletDir, a, passphrase, skipConflicts)
+	if err != nil {
+		return nil, err
+	}
+
+	return api.NewWalletRestoreResponse(result), nil
+}
@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/api"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+func TestBackupRestoreWallets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "backup")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	walletDir := filepath.Join(dir, "wallets")
+	require.NoError(t, os.Mkdir(walletDir, 0700))
+
+	for _, seed := range []string{"a", "b"} {
+		w, err := wallet.NewWallet(seed+".wlt", wallet.Options{
+			Seed:  seed,
+			Label: seed,
+		})
+		require.NoError(t, err)
+		require.NoError(t, w.Save(walletDir))
+	}
+
+	archiveFile := filepath.Join(dir, "backup.json")
+	result, err := backupWallets(walletDir, nil, archiveFile, PasswordFromBytes("passphrase"), wallet.CryptoTypeSha256Xor)
+	require.NoError(t, err)
+	require.Equal(t, &WalletBackupResult{
+		File:    archiveFile,
+		Wallets: []string{"a.wlt", "b.wlt"},
+	}, result)
+
+	// The archive is not overwritten
+	_, err = backupWallets(walletDir, nil, archiveFile, PasswordFromBytes("passphrase"), wallet.CryptoTypeSha256Xor)
+	require.Error(t, err)
+
+	oneFile := filepath.Join(dir, "one.json")
+	result, err = backupWallets(walletDir, []string{filepath.Join(walletDir, "b.wlt")}, oneFile, PasswordFromBytes("passphrase"), wallet.CryptoTypeSha256Xor)
+	require.NoError(t, err)
+	require.Equal(t, []string{"b.wlt"}, result.Wallets)
+
+	// Restore into a directory that already has a.wlt
+	restoreDir := filepath.Join(dir, "restore")
+	require.NoError(t, os.Mkdir(restoreDir, 0700))
+	w, err := wallet.NewWallet("a.wlt", wallet.Options{
+		Seed: "c",
+	})
+	require.NoError(t, err)
+	require.NoError(t, w.Save(restoreDir))
+
+	_, err = restoreWallets(restoreDir, archiveFile, PasswordFromBytes("wrong"), false)
+	require.Equal(t, wallet.ErrInvalidBackupPassphrase, err)
+
+	_, err = restoreWallets(restoreDir, archiveFile, PasswordFromBytes("passphrase"), false)
+	require.Error(t, err)
+
+	rsp, err := restoreWallets(restoreDir, archiveFile, PasswordFromBytes("passphrase"), true)
+	require.NoError(t, err)
+	require.Equal(t, &api.WalletRestoreResponse{
+		Restored: []string{"b.wlt"},
+		Skipped: []api.WalletRestoreConflict{
+			{
+				ID:         "a.wlt",
+				Reason:     "wallet_exists",
+				ExistingID: "a.wlt",
+			},
+		},
+	}, rsp)
+
+	restored, err := wallet.Load(filepath.Join(restoreDir, "b.wlt"))
+	require.NoError(t, err)
+	require.Equal(t, "b", restored.Label())
+}
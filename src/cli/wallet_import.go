@@ -0,0 +1,159 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	gcli "github.com/urfave/cli"
+
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+func walletImportCmd(cfg Config) gcli.Command {
+	name := "walletImport"
+	return gcli.Command{
+		Name:      name,
+		Usage:     "Import a wallet from a private key list, a seed phrase or a JSON keystore",
+		ArgsUsage: "[format] [file]",
+		Description: fmt.Sprintf(`Import the wallet data of file into a new wallet of the wallet directory (%s).
+		The format of the data can be:
+
+		keys: a list of private keys, one per line, hex encoded or in wallet import format.
+		      Empty lines and lines starting with "#" are ignored.
+		seed: an Electrum seed phrase or a BIP39 mnemonic. The wallet is a deterministic
+		      wallet of the seed phrase, its addresses are not the addresses of Electrum.
+		keystore: a JSON object with a "keys" list of private keys, or the "entries"
+		      of an unencrypted wallet file.
+
+		The private keys of a key list or keystore are imported into a "collection" wallet,
+		which can't generate new addresses.
+
+		The wallet is not imported if a wallet of the directory has its file name,
+		or if a wallet of the directory already has one of its addresses.
+
+		Use caution when using the "-p" command. If you have command
+		history enabled your wallet encryption password can be recovered
+		from the history log. If you do not include the "-p" option you will
+		be prompted to enter your password after you enter your command.
+
+		All results are returned in JSON format.`, cfg.WalletDir),
+		Flags: []gcli.Flag{
+			gcli.StringFlag{
+				Name: "f",
+				Usage: `[walletName] Name of wallet. The final format will be "yourName.wlt".
+						 If no wallet name is specified a generic name will be selected.`,
+			},
+			gcli.StringFlag{
+				Name:  "l",
+				Usage: "[label] Label used to idetify your wallet.",
+			},
+			gcli.UintFlag{
+				Name:  "n",
+				Value: 1,
+				Usage: "[numberOfAddresses] Number of addresses to generate from a seed phrase",
+			},
+			gcli.StringFlag{
+				Name:  "seed-passphrase",
+				Usage: "BIP39 passphrase of the seed phrase. The seed phrase must be a valid BIP39 mnemonic",
+			},
+			gcli.BoolFlag{
+				Name:  "e,encrypt",
+				Usage: "Whether imports the wallet with encryption",
+			},
+			gcli.StringFlag{
+				Name:  "x,crypto-type",
+				Value: string(wallet.CryptoTypeArgon2idChacha20poly1305),
+				Usage: "[crypto type] The crypto type for wallet encryption, can be argon2id-chacha20poly1305, scrypt-chacha20poly1305 or sha256-xor",
+			},
+			gcli.StringFlag{
+				Name:  "p",
+				Usage: "[password] Wallet password",
+			},
+		},
+		OnUsageError: onCommandUsageError(name),
+		Action: func(c *gcli.Context) error {
+			cfg := ConfigFromContext(c)
+
+			if c.NArg() != 2 {
+				printHelp(c)
+				return errors.New("format and file are required")
+			}
+
+			format, err := wallet.ImportFormatFromString(c.Args().Get(0))
+			if err != nil {
+				return err
+			}
+
+			cryptoType, err := wallet.CryptoTypeFromString(c.String("x"))
+			if err != nil {
+				return err
+			}
+
+			opts := wallet.ImportOptions{
+				Format:         format,
+				Label:          c.String("l"),
+				SeedPassphrase: c.String("seed-passphrase"),
+				Encrypt:        c.Bool("e"),
+				CryptoType:     cryptoType,
+			}
+
+			// The number of addresses only applies to a seed phrase
+			if format == wallet.ImportFormatSeed {
+				opts.GenerateN = c.Uint64("n")
+			}
+
+			pr := NewPasswordReader([]byte(c.String("p")))
+			switch pr.(type) {
+			case PasswordFromBytes:
+				p, err := pr.Password()
+				if err != nil {
+					return err
+				}
+
+				if !opts.Encrypt && len(p) != 0 {
+					return errors.New("password should not be set as we're not going to import a wallet with encryption")
+				}
+			}
+
+			if opts.Encrypt {
+				opts.Password, err = pr.Password()
+				if err != nil {
+					return err
+				}
+			}
+
+			// create wallet dir if not exist
+			if _, err := os.Stat(cfg.WalletDir); os.IsNotExist(err) {
+				if err := os.MkdirAll(cfg.WalletDir, 0750); err != nil {
+					return errors.New("create dir failed")
+				}
+			}
+
+			wlt, err := importWallet(cfg.WalletDir, c.String("f"), c.Args().Get(1), opts)
+			if err != nil {
+				return err
+			}
+
+			return printJSON(wallet.NewReadableWallet(wlt))
+		},
+	}
+}
+
+// importWallet imports the wallet data of dataFile into walletDir, see wallet.ImportWalletToDir
+func importWallet(walletDir, wltName, dataFile string, opts wallet.ImportOptions) (*wallet.Wallet, error) {
+	data, err := ioutil.ReadFile(dataFile)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		for i := range data {
+			data[i] = 0
+		}
+	}()
+
+	opts.Data = data
+	return wallet.ImportWalletToDir(walletDir, wltName, opts)
+}
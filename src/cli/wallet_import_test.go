@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+func TestImportWallet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "import")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	walletDir := filepath.Join(dir, "wallets")
+	require.NoError(t, os.Mkdir(walletDir, 0700))
+
+	_, s := cipher.GenerateKeyPair()
+	keysFile := filepath.Join(dir, "keys.txt")
+	require.NoError(t, ioutil.WriteFile(keysFile, []byte(s.Hex()+"\n"), 0600))
+
+	w, err := importWallet(walletDir, "keys.wlt", keysFile, wallet.ImportOptions{
+		Format: wallet.ImportFormatKeys,
+		Label:  "keys",
+	})
+	require.NoError(t, err)
+	require.Equal(t, wallet.WalletTypeCollection, w.Type())
+	require.Equal(t, cipher.MustAddressFromSecKey(s), w.Entries[0].SkycoinAddress())
+
+	loaded, err := wallet.Load(filepath.Join(walletDir, "keys.wlt"))
+	require.NoError(t, err)
+	require.Equal(t, w.GetAddresses(), loaded.GetAddresses())
+
+	// The keys can't be imported twice
+	_, err = importWallet(walletDir, "other.wlt", keysFile, wallet.ImportOptions{
+		Format: wallet.ImportFormatKeys,
+	})
+	require.Error(t, err)
+
+	_, err = importWallet(walletDir, "missing.wlt", filepath.Join(dir, "missing.txt"), wallet.ImportOptions{
+		Format: wallet.ImportFormatKeys,
+	})
+	require.Error(t, err)
+}
@@ -0,0 +1,235 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+
+	gcli "github.com/urfave/cli"
+
+	"github.com/skycoin/skycoin/src/api"
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/readable"
+	"github.com/skycoin/skycoin/src/visor"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+// AddressScanner scans the confirmed state of addresses
+type AddressScanner interface {
+	ScanAddresses(addrs []string, recentTxns uint64) ([]readable.AddressScan, error)
+}
+
+func walletRescanCmd(cfg Config) gcli.Command {
+	name := "walletRescan"
+	return gcli.Command{
+		Name:      name,
+		Usage:     "Rescan the blockchain for the activity of the addresses of a wallet. Requires skycoin node rpc.",
+		ArgsUsage: " ",
+		Description: fmt.Sprintf(`Rescan the blockchain for the activity of the addresses of a wallet.
+		The default wallet (%s) will be used if no wallet was specified.
+
+		First, the addresses that follow the last address of the wallet are generated, "-gap-limit"
+		at a time, and the ones up to the last address that has ever received or spent coins are added
+		to the wallet, until "-gap-limit" consecutive addresses were never used. This finds the addresses
+		of a wallet restored from its seed that were generated by another copy of the wallet.
+		Use "-gap-limit 0" to skip it. Watch-only wallets are not extended.
+
+		Then the confirmed balance and the "-recent-txns" most recent transactions of every address
+		of the wallet are returned, with the new addresses and the new addresses that have been used.
+
+		Use caution when using the "-p" command. If you have command history enabled
+		your wallet encryption password can be recovered from the history log. If you
+		do not include the "-p" option you will be prompted to enter your password
+		after you enter your command.
+
+		All results are returned in JSON format.`, cfg.FullWalletPath()),
+		Flags: []gcli.Flag{
+			gcli.StringFlag{
+				Name:  "f",
+				Value: cfg.FullWalletPath(),
+				Usage: "[wallet file or path] Rescan this wallet",
+			},
+			gcli.StringFlag{
+				Name:  "p",
+				Usage: "[password] Wallet password",
+			},
+			gcli.Uint64Flag{
+				Name:  "gap-limit",
+				Value: 20,
+				Usage: "[number] Number of consecutive unused addresses that ends the extension of the wallet",
+			},
+			gcli.Uint64Flag{
+				Name:  "recent-txns",
+				Usage: "[number] Number of most recent transactions to return per address",
+			},
+		},
+		OnUsageError: onCommandUsageError(name),
+		Action: func(c *gcli.Context) error {
+			cfg := ConfigFromContext(c)
+
+			if c.NArg() > 0 {
+				fmt.Printf("Error: invalid argument\n\n")
+				return gcli.ShowSubcommandHelp(c)
+			}
+
+			if c.Uint64("gap-limit") > visor.MaxRescanGapLimit {
+				return visor.ErrRescanGapLimitTooLarge
+			}
+
+			if c.Uint64("recent-txns") > visor.MaxScanRecentTxns {
+				return visor.ErrScanTooManyRecentTxns
+			}
+
+			walletFile, err := resolveWalletPath(cfg, c.String("f"))
+			if err != nil {
+				return err
+			}
+
+			pr := NewPasswordReader([]byte(c.String("p")))
+			rsp, err := RescanWalletFile(APIClientFromContext(c), walletFile, c.Uint64("gap-limit"), c.Uint64("recent-txns"), pr)
+			switch err.(type) {
+			case nil:
+			case WalletLoadError:
+				printHelp(c)
+				return err
+			default:
+				return err
+			}
+
+			return printJSON(rsp)
+		},
+	}
+}
+
+// scannerActivityGetter implements wallet.AddressActivityGetter with an AddressScanner,
+// an address is active if it has a confirmed transaction
+type scannerActivityGetter struct {
+	scanner AddressScanner
+}
+
+func (g scannerActivityGetter) GetAddressesActivity(addrs []cipher.Address) ([]bool, error) {
+	scans, err := g.scanner.ScanAddresses(addressesToStrings(addrs), 1)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(scans) != len(addrs) {
+		return nil, fmt.Errorf("scanned %d addresses, expected %d", len(scans), len(addrs))
+	}
+
+	active := make([]bool, len(addrs))
+	for i, s := range scans {
+		active[i] = len(s.RecentTransactions) != 0
+	}
+
+	return active, nil
+}
+
+// RescanWalletFile extends the wallet in a file with its used addresses, see wallet.Wallet.ExtendActiveAddresses,
+// saving it if addresses were added, and returns the confirmed balances and the recentTxns most recent transactions
+// of its addresses
+func RescanWalletFile(c AddressScanner, walletFile string, gapLimit, recentTxns uint64, pr PasswordReader) (*api.WalletRescanResponse, error) {
+	wlt, err := wallet.Load(walletFile)
+	if err != nil {
+		return nil, WalletLoadError{err}
+	}
+
+	ag := scannerActivityGetter{
+		scanner: c,
+	}
+
+	var newAddrs []cipher.Address
+	if gapLimit > 0 && !wlt.IsWatchOnly() {
+		if wlt.IsEncrypted() {
+			password, err := pr.Password()
+			if err != nil {
+				return nil, err
+			}
+
+			if err := wlt.GuardUpdate(password, func(w *wallet.Wallet) error {
+				var err error
+				newAddrs, err = w.ExtendActiveAddresses(gapLimit, ag)
+				return err
+			}); err != nil {
+				return nil, err
+			}
+		} else {
+			newAddrs, err = wlt.ExtendActiveAddresses(gapLimit, ag)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if len(newAddrs) != 0 {
+			dir, err := filepath.Abs(filepath.Dir(walletFile))
+			if err != nil {
+				return nil, err
+			}
+
+			if err := wlt.Save(dir); err != nil {
+				return nil, WalletSaveError{err}
+			}
+		}
+	}
+
+	addrs, err := wlt.GetSkycoinAddresses()
+	if err != nil {
+		return nil, err
+	}
+
+	rsp := &api.WalletRescanResponse{
+		NewAddresses:        addressesToStrings(newAddrs),
+		DiscoveredAddresses: []string{},
+		Addresses:           []readable.AddressScan{},
+	}
+
+	// Scan the addresses by chunks of the most addresses that the node scans at once
+	for i := 0; i < len(addrs); i += visor.MaxScanAddresses {
+		j := i + visor.MaxScanAddresses
+		if j > len(addrs) {
+			j = len(addrs)
+		}
+
+		scans, err := c.ScanAddresses(addressesToStrings(addrs[i:j]), recentTxns)
+		if err != nil {
+			return nil, err
+		}
+
+		rsp.Addresses = append(rsp.Addresses, scans...)
+	}
+
+	for _, s := range rsp.Addresses {
+		rsp.Balance.Coins, err = coin.AddUint64(rsp.Balance.Coins, s.Balance.Coins)
+		if err != nil {
+			return nil, err
+		}
+
+		rsp.Balance.Hours, err = coin.AddUint64(rsp.Balance.Hours, s.Balance.Hours)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(newAddrs) != 0 {
+		active, err := ag.GetAddressesActivity(newAddrs)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, a := range newAddrs {
+			if active[i] {
+				rsp.DiscoveredAddresses = append(rsp.DiscoveredAddresses, a.String())
+			}
+		}
+	}
+
+	return rsp, nil
+}
+
+func addressesToStrings(addrs []cipher.Address) []string {
+	strs := make([]string, len(addrs))
+	for i, a := range addrs {
+		strs[i] = a.String()
+	}
+	return strs
+}
@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/readable"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+// fakeAddressScanner scans addresses with the confirmed coins and the transaction count of a map
+type fakeAddressScanner map[string]uint64
+
+func (f fakeAddressScanner) ScanAddresses(addrs []string, recentTxns uint64) ([]readable.AddressScan, error) {
+	scans := make([]readable.AddressScan, len(addrs))
+	for i, a := range addrs {
+		scans[i] = readable.AddressScan{
+			Address: a,
+			Balance: readable.Balance{
+				Coins: f[a],
+			},
+			Outputs:            readable.UnspentOutputs{},
+			RecentTransactions: []readable.AddressScanTransaction{},
+		}
+
+		if _, ok := f[a]; ok && recentTxns > 0 {
+			scans[i].RecentTransactions = append(scans[i].RecentTransactions, readable.AddressScanTransaction{
+				BlockSeq: 1,
+			})
+		}
+	}
+	return scans, nil
+}
+
+func TestRescanWalletFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rescan")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// The addresses of the seed, in the order the wallet generates them
+	seedWlt, err := wallet.NewWallet("seed.wlt", wallet.Options{
+		Seed:      "seed",
+		Label:     "seed",
+		GenerateN: 10,
+	})
+	require.NoError(t, err)
+	seedAddrs := make([]string, len(seedWlt.Entries))
+	for i, e := range seedWlt.Entries {
+		seedAddrs[i] = e.SkycoinAddress().String()
+	}
+
+	w, err := wallet.NewWallet("t.wlt", wallet.Options{
+		Seed:       "seed",
+		Label:      "t",
+		Encrypt:    true,
+		Password:   []byte("pwd"),
+		CryptoType: wallet.CryptoTypeSha256Xor,
+	})
+	require.NoError(t, err)
+	require.NoError(t, w.Save(dir))
+	walletFile := filepath.Join(dir, "t.wlt")
+
+	// The 4th address spent all its coins, the 2nd has coins
+	scanner := fakeAddressScanner{
+		seedAddrs[1]: 2e6,
+		seedAddrs[3]: 0,
+	}
+
+	_, err = RescanWalletFile(scanner, walletFile, 3, 0, NewPasswordReader([]byte("wrong")))
+	require.Equal(t, wallet.ErrInvalidPassword, err)
+
+	rsp, err := RescanWalletFile(scanner, walletFile, 3, 0, NewPasswordReader([]byte("pwd")))
+	require.NoError(t, err)
+	require.Equal(t, seedAddrs[1:4], rsp.NewAddresses)
+	require.Equal(t, []string{seedAddrs[1], seedAddrs[3]}, rsp.DiscoveredAddresses)
+	require.Equal(t, uint64(2e6), rsp.Balance.Coins)
+	require.Len(t, rsp.Addresses, 4)
+	for i, s := range rsp.Addresses {
+		require.Equal(t, seedAddrs[i], s.Address)
+	}
+
+	// The wallet was saved encrypted with the new addresses
+	w, err = wallet.Load(walletFile)
+	require.NoError(t, err)
+	require.True(t, w.IsEncrypted())
+	require.Len(t, w.Entries, 4)
+
+	// Without the gap limit, the wallet is scanned only, so no password is needed
+	rsp, err = RescanWalletFile(scanner, walletFile, 0, 0, nil)
+	require.NoError(t, err)
+	require.Empty(t, rsp.NewAddresses)
+	require.Empty(t, rsp.DiscoveredAddresses)
+	require.Len(t, rsp.Addresses, 4)
+}
@@ -0,0 +1,145 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	gcli "github.com/urfave/cli"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+func walletCreateWatchOnlyCmd() gcli.Command {
+	name := "walletCreateWatchOnly"
+	return gcli.Command{
+		Name:      name,
+		Usage:     "Create a watch-only wallet of addresses",
+		ArgsUsage: "[addresses]",
+		Description: `Create a watch-only wallet of the addresses. A watch-only wallet stores
+		only addresses, no keys. It can be used to check the balance and the history of
+		the addresses and to create unsigned transactions, on a machine that holds no keys.
+
+		All results are returned in JSON format.`,
+		Flags: []gcli.Flag{
+			gcli.StringFlag{
+				Name:  "f",
+				Usage: "[wallet file name] Name of the wallet file to create in the wallet directory",
+			},
+			gcli.StringFlag{
+				Name:  "l",
+				Usage: "[label] Label used to identify your wallet",
+			},
+		},
+		OnUsageError: onCommandUsageError(name),
+		Action: func(c *gcli.Context) error {
+			cfg := ConfigFromContext(c)
+
+			wltName := c.String("f")
+			if wltName == "" {
+				return gcli.ShowSubcommandHelp(c)
+			}
+
+			if !strings.HasSuffix(wltName, walletExt) {
+				return ErrWalletName
+			}
+
+			if filepath.Base(wltName) != wltName {
+				return errors.New("wallet file name must not contain path")
+			}
+
+			if _, err := os.Stat(filepath.Join(cfg.WalletDir, wltName)); err == nil {
+				return fmt.Errorf("%v already exist", wltName)
+			}
+
+			addrs, err := parseWatchOnlyAddresses(c.Args())
+			if err != nil {
+				return err
+			}
+
+			wlt, err := wallet.NewWatchOnlyWallet(wltName, c.String("l"), addrs)
+			if err != nil {
+				return err
+			}
+
+			if err := os.MkdirAll(cfg.WalletDir, 0750); err != nil {
+				return err
+			}
+
+			if err := wlt.Save(cfg.WalletDir); err != nil {
+				return WalletSaveError{err}
+			}
+
+			return printJSON(wallet.NewReadableWallet(wlt))
+		},
+	}
+}
+
+func walletAddWatchOnlyAddressesCmd(cfg Config) gcli.Command {
+	name := "walletAddWatchOnlyAddresses"
+	return gcli.Command{
+		Name:      name,
+		Usage:     "Add addresses to a watch-only wallet",
+		ArgsUsage: "[addresses]",
+		Description: fmt.Sprintf(`Add addresses to a watch-only wallet. The default wallet (%s)
+		will be used if no wallet was specified. Addresses that are in the wallet already are ignored.`, cfg.FullWalletPath()),
+		Flags: []gcli.Flag{
+			gcli.StringFlag{
+				Name:  "f",
+				Value: cfg.FullWalletPath(),
+				Usage: "[wallet file or path] Add the addresses to this wallet",
+			},
+		},
+		OnUsageError: onCommandUsageError(name),
+		Action: func(c *gcli.Context) error {
+			cfg := ConfigFromContext(c)
+
+			addrs, err := parseWatchOnlyAddresses(c.Args())
+			if err != nil {
+				return err
+			}
+
+			walletFile, err := resolveWalletPath(cfg, c.String("f"))
+			if err != nil {
+				return err
+			}
+
+			wlt, err := wallet.Load(walletFile)
+			if err != nil {
+				printHelp(c)
+				return WalletLoadError{err}
+			}
+
+			if err := wlt.AddWatchOnlyAddresses(addrs); err != nil {
+				return err
+			}
+
+			if err := wlt.Save(filepath.Dir(walletFile)); err != nil {
+				return WalletSaveError{err}
+			}
+
+			fmt.Println("success")
+			return nil
+		},
+	}
+}
+
+func parseWatchOnlyAddresses(args []string) ([]cipher.Address, error) {
+	if len(args) == 0 {
+		return nil, errors.New("missing addresses")
+	}
+
+	addrs := make([]cipher.Address, len(args))
+	for i, a := range args {
+		addr, err := cipher.DecodeBase58Address(a)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address %q: %v", a, err)
+		}
+		addrs[i] = addr
+	}
+
+	return addrs, nil
+}
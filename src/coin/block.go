@@ -55,6 +55,25 @@ func (b SignedBlock) VerifySignature(pubkey cipher.PubKey) error {
 	return cipher.VerifyPubKeySignedHash(pubkey, b.Sig, b.HashHeader())
 }
 
+// SignedHeader returns the header of the block with its signature
+func (b SignedBlock) SignedHeader() SignedBlockHeader {
+	return SignedBlockHeader{
+		Head: b.Head,
+		Sig:  b.Sig,
+	}
+}
+
+// SignedBlockHeader is the header of a signed block, with the block signature
+type SignedBlockHeader struct {
+	Head BlockHeader
+	Sig  cipher.Sig
+}
+
+// VerifySignature verifies that the block of the header is signed by pubkey
+func (h SignedBlockHeader) VerifySignature(pubkey cipher.PubKey) error {
+	return cipher.VerifyPubKeySignedHash(pubkey, h.Sig, h.Head.Hash())
+}
+
 // NewBlock creates new block.
 func NewBlock(prev Block, currentTime uint64, uxHash cipher.SHA256, txns Transactions, calc FeeCalculator) (*Block, error) {
 	if len(txns) == 0 {
@@ -112,6 +112,28 @@ func TestBlockHashBody(t *testing.T) {
 	require.Equal(t, b.HashBody(), b.Body.Hash())
 }
 
+func TestSignedBlockHeaderVerifySignature(t *testing.T) {
+	b, err := makeNewBlock(testutil.RandSHA256(t))
+	require.NoError(t, err)
+
+	sb := SignedBlock{
+		Block: *b,
+		Sig:   cipher.MustSignHash(b.HashHeader(), genSecret),
+	}
+	require.NoError(t, sb.VerifySignature(genPublic))
+
+	h := sb.SignedHeader()
+	require.Equal(t, b.Head, h.Head)
+	require.Equal(t, sb.Sig, h.Sig)
+	require.NoError(t, h.VerifySignature(genPublic))
+
+	p, _ := cipher.GenerateKeyPair()
+	require.Error(t, h.VerifySignature(p))
+
+	h.Head.Fee++
+	require.Error(t, h.VerifySignature(genPublic))
+}
+
 func TestNewGenesisBlock(t *testing.T) {
 	gb, err := NewGenesisBlock(genAddress, _genCoins, _genTime)
 	require.NoError(t, err)
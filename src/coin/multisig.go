@@ -0,0 +1,288 @@
+package coin
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+/*
+Multisig transactions
+
+A transaction of type TransactionTypeMultisig can spend the outputs of multisig addresses, see cipher.Multisig.
+Its Sigs start with a signature for each input, like the other transactions, where the signature of
+a multisig input is null. They are followed by the witnesses of the multisig inputs, which are
+encoded in 65 byte signature slots so that the serialization of transactions is unchanged:
+
+	header slot   input index (uint16 little endian) | M | N | zeros
+	N key slots   the public keys of the multisig, 33 bytes then zeros
+	N sig slots   the signature of each public key, null if the key did not sign
+
+The signed hash is the same as for the other inputs, SHA256(inner hash + hash of the output being spent).
+The inner hash does not cover the witnesses, so the parties sign the transaction in any order.
+*/
+
+const (
+	// TransactionTypeDefault is the type of the transactions that spend the outputs of single key addresses
+	TransactionTypeDefault uint8 = 0
+	// TransactionTypeMultisig is the type of the transactions that also spend the outputs of multisig addresses
+	TransactionTypeMultisig uint8 = 1
+)
+
+var (
+	// ErrMultisigWitnessInvalid is returned if the multisig witnesses of a transaction can't be decoded
+	ErrMultisigWitnessInvalid = errors.New("Invalid multisig witness")
+	// ErrMultisigKeyNotFound is returned when signing a multisig input with a key that is not one of its keys
+	ErrMultisigKeyNotFound = errors.New("Key is not a key of the multisig input")
+)
+
+// MultisigWitness is the multisignature of an input that spends an output of a multisig address
+type MultisigWitness struct {
+	// Input is the index of the input
+	Input    int
+	Multisig cipher.Multisig
+	// Sigs are the signatures of the public keys of Multisig, in the same order, null for the keys that did not sign
+	Sigs []cipher.Sig
+
+	// sigsIndex is the index of the first signature in Transaction.Sigs
+	sigsIndex int
+}
+
+// SigCount returns the number of signatures of the witness
+func (w MultisigWitness) SigCount() int {
+	n := 0
+	for _, s := range w.Sigs {
+		if !s.Null() {
+			n++
+		}
+	}
+	return n
+}
+
+// IsFullySigned returns true if the witness has the number of signatures required by its multisig
+func (w MultisigWitness) IsFullySigned() bool {
+	return w.SigCount() >= int(w.Multisig.M)
+}
+
+// verify checks the signatures of the witness. If signed is true, the required number of signatures must be present
+func (w MultisigWitness) verify(hash cipher.SHA256, signed bool) error {
+	for i, s := range w.Sigs {
+		if s.Null() {
+			continue
+		}
+
+		if err := cipher.VerifyPubKeySignedHash(w.Multisig.PubKeys[i], s, hash); err != nil {
+			return err
+		}
+	}
+
+	if signed && !w.IsFullySigned() {
+		return errors.New("Not enough multisig signatures")
+	}
+
+	return nil
+}
+
+// MultisigWitnesses returns the multisig witnesses of the transaction, in the order they are stored.
+// A transaction that is not of type TransactionTypeMultisig has none.
+func (txn *Transaction) MultisigWitnesses() ([]MultisigWitness, error) {
+	if txn.Type != TransactionTypeMultisig {
+		return nil, nil
+	}
+
+	var ws []MultisigWitness
+	for i := len(txn.In); i < len(txn.Sigs); {
+		header := txn.Sigs[i]
+		n := int(header[3])
+		if header != multisigHeaderSlot(int(binary.LittleEndian.Uint16(header[:2])), header[2], n) {
+			return nil, ErrMultisigWitnessInvalid
+		}
+
+		if i+1+2*n > len(txn.Sigs) {
+			return nil, ErrMultisigWitnessInvalid
+		}
+
+		w := MultisigWitness{
+			Input: int(binary.LittleEndian.Uint16(header[:2])),
+			Multisig: cipher.Multisig{
+				M:       header[2],
+				PubKeys: make([]cipher.PubKey, n),
+			},
+			Sigs:      make([]cipher.Sig, n),
+			sigsIndex: i + 1 + n,
+		}
+
+		for j := 0; j < n; j++ {
+			slot := txn.Sigs[i+1+j]
+			copy(w.Multisig.PubKeys[j][:], slot[:])
+			if slot != multisigPubKeySlot(w.Multisig.PubKeys[j]) {
+				return nil, ErrMultisigWitnessInvalid
+			}
+		}
+		copy(w.Sigs, txn.Sigs[w.sigsIndex:w.sigsIndex+n])
+
+		ws = append(ws, w)
+		i += 1 + 2*n
+	}
+
+	return ws, nil
+}
+
+// validSigCount returns true if the transaction has a signature for each input. A transaction of type
+// TransactionTypeMultisig has the witnesses of its multisig inputs after the signatures of the inputs.
+func (txn *Transaction) validSigCount() bool {
+	if txn.Type == TransactionTypeMultisig {
+		return len(txn.Sigs) >= len(txn.In)
+	}
+	return len(txn.Sigs) == len(txn.In)
+}
+
+// multisigWitnessMap returns the multisig witnesses of the transaction by input index
+func (txn *Transaction) multisigWitnessMap() (map[int]MultisigWitness, error) {
+	ws, err := txn.MultisigWitnesses()
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[int]MultisigWitness, len(ws))
+	for _, w := range ws {
+		m[w.Input] = w
+	}
+	return m, nil
+}
+
+// verifyMultisigWitnesses checks the multisig witnesses of the transaction and their signatures,
+// and returns them by input index. If signed is true, the multisig inputs must have the required signatures.
+func (txn *Transaction) verifyMultisigWitnesses(signed bool) (map[int]MultisigWitness, error) {
+	ws, err := txn.MultisigWitnesses()
+	if err != nil {
+		return nil, err
+	}
+
+	if txn.Type == TransactionTypeMultisig && len(ws) == 0 {
+		return nil, errors.New("Multisig transaction has no multisig witnesses")
+	}
+
+	m := make(map[int]MultisigWitness, len(ws))
+	for _, w := range ws {
+		if w.Input >= len(txn.In) {
+			return nil, errors.New("Multisig witness input index out of range")
+		}
+
+		if _, ok := m[w.Input]; ok {
+			return nil, errors.New("Duplicate multisig witness")
+		}
+
+		if !txn.Sigs[w.Input].Null() {
+			return nil, errors.New("Multisig input has a signature")
+		}
+
+		if err := w.Multisig.Verify(); err != nil {
+			return nil, err
+		}
+
+		if err := w.verify(cipher.AddSHA256(txn.InnerHash, txn.In[w.Input]), signed); err != nil {
+			return nil, err
+		}
+
+		m[w.Input] = w
+	}
+
+	return m, nil
+}
+
+// PushMultisigWitness adds the witness of an input that spends an output of a multisig address,
+// with null signatures, and sets the type of the transaction to TransactionTypeMultisig.
+// The signatures of the inputs must be allocated already, and the signature of the input must be null.
+// Call UpdateHeader afterwards, the witness changes the size of the transaction.
+func (txn *Transaction) PushMultisigWitness(idx int, ms cipher.Multisig) error {
+	if !txn.validSigCount() {
+		return errors.New("Invalid number of signatures")
+	}
+	if idx < 0 || idx >= len(txn.In) || idx > math.MaxUint16 {
+		return errors.New("Signature index out of range")
+	}
+	if !txn.Sigs[idx].Null() {
+		return errors.New("Input is already signed")
+	}
+
+	if err := ms.Verify(); err != nil {
+		return err
+	}
+
+	ws, err := txn.multisigWitnessMap()
+	if err != nil {
+		return err
+	}
+	if _, ok := ws[idx]; ok {
+		return errors.New("Input has a multisig witness already")
+	}
+
+	txn.Type = TransactionTypeMultisig
+	txn.Sigs = append(txn.Sigs, multisigHeaderSlot(idx, ms.M, len(ms.PubKeys)))
+	for _, pk := range ms.PubKeys {
+		txn.Sigs = append(txn.Sigs, multisigPubKeySlot(pk))
+	}
+	txn.Sigs = append(txn.Sigs, make([]cipher.Sig, len(ms.PubKeys))...)
+
+	return nil
+}
+
+// SignMultisigInput adds the signature of a key of the multisig of the input at index idx.
+// The key must not have signed already.
+func (txn *Transaction) SignMultisigInput(key cipher.SecKey, idx int) error {
+	ws, err := txn.multisigWitnessMap()
+	if err != nil {
+		return err
+	}
+
+	w, ok := ws[idx]
+	if !ok {
+		return errors.New("Input has no multisig witness")
+	}
+
+	pk, err := cipher.PubKeyFromSecKey(key)
+	if err != nil {
+		return err
+	}
+
+	j := w.Multisig.PubKeyIndex(pk)
+	if j == -1 {
+		return ErrMultisigKeyNotFound
+	}
+	if !w.Sigs[j].Null() {
+		return errors.New("Input is already signed by the key")
+	}
+
+	innerHash := txn.HashInner()
+	if innerHash != txn.InnerHash {
+		return errors.New("InnerHash does not match computed hash")
+	}
+
+	h := cipher.AddSHA256(innerHash, txn.In[idx]) // hash to sign
+	sig, err := cipher.SignHash(h, key)
+	if err != nil {
+		return err
+	}
+	txn.Sigs[w.sigsIndex+j] = sig
+
+	return nil
+}
+
+// multisigHeaderSlot returns the first signature slot of a multisig witness
+func multisigHeaderSlot(idx int, m uint8, n int) cipher.Sig {
+	var s cipher.Sig
+	binary.LittleEndian.PutUint16(s[:2], uint16(idx))
+	s[2] = m
+	s[3] = byte(n)
+	return s
+}
+
+// multisigPubKeySlot returns the signature slot of a public key of a multisig witness
+func multisigPubKeySlot(pk cipher.PubKey) cipher.Sig {
+	var s cipher.Sig
+	copy(s[:], pk[:])
+	return s
+}
@@ -0,0 +1,157 @@
+package coin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/encoder"
+	"github.com/skycoin/skycoin/src/testutil"
+)
+
+func makeMultisig(t *testing.T, m, n int) (cipher.Multisig, []cipher.SecKey) {
+	pks := make([]cipher.PubKey, n)
+	sks := make([]cipher.SecKey, n)
+	for i := range pks {
+		pks[i], sks[i] = cipher.GenerateKeyPair()
+	}
+
+	ms, err := cipher.NewMultisig(m, pks)
+	require.NoError(t, err)
+	return ms, sks
+}
+
+func makeMultisigUxOut(t *testing.T, ms cipher.Multisig) UxOut {
+	return UxOut{
+		Head: UxHead{
+			Time:  100,
+			BkSeq: 2,
+		},
+		Body: UxBody{
+			SrcTransaction: testutil.RandSHA256(t),
+			Address:        ms.Address(),
+			Coins:          10e6,
+			Hours:          100,
+		},
+	}
+}
+
+func TestMultisigTransaction(t *testing.T) {
+	ms, sks := makeMultisig(t, 2, 3)
+	msUx := makeMultisigUxOut(t, ms)
+	ux, s := makeUxOutWithSecret(t)
+
+	// A transaction spending an output of a single key address and an output of a 2-of-3 multisig address
+	txn := Transaction{}
+	txn.PushInput(ux.Hash())
+	txn.PushInput(msUx.Hash())
+	txn.PushOutput(makeAddress(), 11e6, 50)
+	txn.Sigs = make([]cipher.Sig, len(txn.In))
+	require.NoError(t, txn.PushMultisigWitness(1, ms))
+	require.NoError(t, txn.UpdateHeader())
+	require.Equal(t, TransactionTypeMultisig, txn.Type)
+
+	ws, err := txn.MultisigWitnesses()
+	require.NoError(t, err)
+	require.Len(t, ws, 1)
+	require.Equal(t, 1, ws[0].Input)
+	require.Equal(t, ms, ws[0].Multisig)
+	require.Equal(t, 0, ws[0].SigCount())
+
+	uxIn := UxArray{ux, msUx}
+	require.False(t, txn.IsFullySigned())
+	require.NoError(t, txn.VerifyUnsigned())
+	require.NoError(t, txn.VerifyInputUnsigned(uxIn))
+
+	// The multisig input is signed with the keys of the multisig
+	require.Error(t, txn.SignInput(sks[0], 1))
+	require.NoError(t, txn.SignInput(s, 0))
+	require.NoError(t, txn.SignMultisigInput(sks[0], 1))
+	require.Error(t, txn.SignMultisigInput(sks[0], 1))
+	require.Error(t, txn.SignMultisigInput(s, 0))
+	require.Equal(t, ErrMultisigKeyNotFound, txn.SignMultisigInput(s, 1))
+
+	require.False(t, txn.IsFullySigned())
+	require.NoError(t, txn.VerifyUnsigned())
+	testutil.RequireError(t, txn.Verify(), "Not enough multisig signatures")
+	require.NoError(t, txn.VerifyInputUnsigned(uxIn))
+	testutil.RequireError(t, txn.VerifyInput(uxIn), "Signature not valid for output being spent")
+
+	require.NoError(t, txn.SignMultisigInput(sks[2], 1))
+	require.True(t, txn.IsFullySigned())
+	require.NoError(t, txn.Verify())
+	require.NoError(t, txn.VerifyInput(uxIn))
+
+	ws, err = txn.MultisigWitnesses()
+	require.NoError(t, err)
+	require.Equal(t, 2, ws[0].SigCount())
+	require.True(t, ws[0].IsFullySigned())
+
+	// The witness is serialized in the signatures, within a block body too
+	txn2, err := TransactionDeserialize(txn.Serialize())
+	require.NoError(t, err)
+	require.Equal(t, txn, txn2)
+
+	body := BlockBody{Transactions: Transactions{txn, makeTransaction(t)}}
+	var body2 BlockBody
+	require.NoError(t, encoder.DeserializeRaw(encoder.Serialize(body), &body2))
+	require.Equal(t, body, body2)
+
+	// The multisig must match the address of the output
+	otherMs, _ := makeMultisig(t, 2, 3)
+	otherUx := makeMultisigUxOut(t, otherMs)
+	other := Transaction{}
+	other.PushInput(otherUx.Hash())
+	other.PushOutput(makeAddress(), 10e6, 50)
+	other.Sigs = make([]cipher.Sig, len(other.In))
+	require.NoError(t, other.PushMultisigWitness(0, ms))
+	require.NoError(t, other.UpdateHeader())
+	require.NoError(t, other.VerifyUnsigned())
+	testutil.RequireError(t, other.VerifyInputUnsigned(UxArray{otherUx}), "Multisig does not match the address of the output being spent")
+
+	// A signature of another key is invalid
+	bad := copyTransaction(txn)
+	bad.Sigs[len(bad.Sigs)-1] = bad.Sigs[len(bad.Sigs)-3]
+	require.Error(t, bad.Verify())
+
+	// A multisig input must not have a signature of its own
+	bad = copyTransaction(txn)
+	bad.Sigs[1] = bad.Sigs[0]
+	testutil.RequireError(t, bad.Verify(), "Multisig input has a signature")
+
+	// A multisig transaction needs a witness
+	bad = copyTransaction(txn)
+	bad.Sigs = bad.Sigs[:len(bad.In)]
+	require.NoError(t, bad.UpdateHeader())
+	testutil.RequireError(t, bad.Verify(), "Multisig transaction has no multisig witnesses")
+
+	// A corrupted witness can't be decoded
+	bad = copyTransaction(txn)
+	bad.Sigs[len(bad.In)][10] = 1
+	testutil.RequireError(t, bad.Verify(), ErrMultisigWitnessInvalid.Error())
+	require.False(t, bad.IsFullySigned())
+
+	// Other transactions can't have witnesses
+	bad = copyTransaction(txn)
+	bad.Type = TransactionTypeDefault
+	testutil.RequireError(t, bad.Verify(), "Invalid number of signatures")
+}
+
+func TestMultisigTransactionNoWitness(t *testing.T) {
+	ms, _ := makeMultisig(t, 1, 2)
+	msUx := makeMultisigUxOut(t, ms)
+
+	// The output of a multisig address can't be spent without its witness
+	txn := Transaction{}
+	txn.PushInput(msUx.Hash())
+	txn.PushOutput(makeAddress(), 10e6, 50)
+	txn.Sigs = make([]cipher.Sig, len(txn.In))
+	require.NoError(t, txn.UpdateHeader())
+	testutil.RequireError(t, txn.VerifyInputUnsigned(UxArray{msUx}), "Output of a multisig address spent without a multisig witness")
+
+	require.NoError(t, txn.PushMultisigWitness(0, ms))
+	require.Error(t, txn.PushMultisigWitness(0, ms))
+	require.Error(t, txn.PushMultisigWitness(1, ms))
+	require.Error(t, txn.PushMultisigWitness(0, cipher.Multisig{M: 1}))
+}
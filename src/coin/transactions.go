@@ -62,6 +62,41 @@ type TransactionOutput struct {
 // Verify cannot check if the transaction would create or destroy coins
 // or if the inputs have the required coin base
 func (txn *Transaction) Verify() error {
+	return txn.verify(true)
+}
+
+// VerifyUnsigned is Verify for a transaction that is not fully signed yet.
+// The signatures of the inputs that are not signed must be null, the other signatures are checked
+func (txn *Transaction) VerifyUnsigned() error {
+	return txn.verify(false)
+}
+
+// IsFullySigned returns true if all the inputs of the transaction are signed,
+// with the required number of signatures for the multisig inputs
+func (txn *Transaction) IsFullySigned() bool {
+	multisigs, err := txn.multisigWitnessMap()
+	if err != nil {
+		return false
+	}
+
+	for i, s := range txn.Sigs {
+		// The witnesses of the multisig inputs follow the signatures of the inputs
+		if i >= len(txn.In) {
+			break
+		}
+
+		if w, ok := multisigs[i]; ok {
+			if !w.IsFullySigned() {
+				return false
+			}
+		} else if s.Null() {
+			return false
+		}
+	}
+	return true
+}
+
+func (txn *Transaction) verify(signed bool) error {
 	h := txn.HashInner()
 	if h != txn.InnerHash {
 		return errors.New("InnerHash does not match computed hash")
@@ -75,7 +110,7 @@ func (txn *Transaction) Verify() error {
 	}
 
 	// Check signature index fields
-	if len(txn.Sigs) != len(txn.In) {
+	if !txn.validSigCount() {
 		return errors.New("Invalid number of signatures")
 	}
 	if len(txn.Sigs) >= math.MaxUint16 {
@@ -91,7 +126,7 @@ func (txn *Transaction) Verify() error {
 		return errors.New("Duplicate spend")
 	}
 
-	if txn.Type != 0 {
+	if txn.Type != TransactionTypeDefault && txn.Type != TransactionTypeMultisig {
 		return errors.New("transaction type invalid")
 	}
 
@@ -120,7 +155,18 @@ func (txn *Transaction) Verify() error {
 	}
 
 	// Validate signature
-	for i, sig := range txn.Sigs {
+	multisigs, err := txn.verifyMultisigWitnesses(signed)
+	if err != nil {
+		return err
+	}
+
+	for i, sig := range txn.Sigs[:len(txn.In)] {
+		if _, ok := multisigs[i]; ok {
+			continue
+		}
+		if !signed && sig.Null() {
+			continue
+		}
 		hash := cipher.AddSHA256(txn.InnerHash, txn.In[i])
 		if err := cipher.VerifySignedHash(sig, hash); err != nil {
 			return err
@@ -150,11 +196,21 @@ func (txn *Transaction) Verify() error {
 
 // VerifyInput verifies the input
 func (txn Transaction) VerifyInput(uxIn UxArray) error {
+	return txn.verifyInput(uxIn, true)
+}
+
+// VerifyInputUnsigned is VerifyInput for a transaction that is not fully signed yet.
+// The inputs with a null signature are not checked
+func (txn Transaction) VerifyInputUnsigned(uxIn UxArray) error {
+	return txn.verifyInput(uxIn, false)
+}
+
+func (txn Transaction) verifyInput(uxIn UxArray, signed bool) error {
 	if err := func() error {
 		if len(txn.In) != len(uxIn) {
 			return errors.New("txn.In != uxIn")
 		}
-		if len(txn.In) != len(txn.Sigs) {
+		if !txn.validSigCount() {
 			return errors.New("txn.In != txn.Sigs")
 		}
 		if txn.InnerHash != txn.HashInner() {
@@ -173,9 +229,31 @@ func (txn Transaction) VerifyInput(uxIn UxArray) error {
 		return err
 	}
 
+	multisigs, err := txn.multisigWitnessMap()
+	if err != nil {
+		return err
+	}
+
 	// Check signatures against unspent address
 	for i := range txn.In {
 		hash := cipher.AddSHA256(txn.InnerHash, txn.In[i]) // use inner hash, not outer hash
+
+		if w, ok := multisigs[i]; ok {
+			if uxIn[i].Body.Address != w.Multisig.Address() {
+				return errors.New("Multisig does not match the address of the output being spent")
+			}
+			if err := w.verify(hash, signed); err != nil {
+				return errors.New("Signature not valid for output being spent")
+			}
+			continue
+		}
+
+		if uxIn[i].Body.Address.Version == cipher.MultisigAddressVersion {
+			return errors.New("Output of a multisig address spent without a multisig witness")
+		}
+		if !signed && txn.Sigs[i].Null() {
+			continue
+		}
 		err := cipher.VerifyAddressSignedHash(uxIn[i].Body.Address, txn.Sigs[i], hash)
 		if err != nil {
 			return errors.New("Signature not valid for output being spent")
@@ -241,6 +319,43 @@ func (txn *Transaction) SignInputs(keys []cipher.SecKey) {
 	txn.Sigs = sigs
 }
 
+// SignInput signs the input at index idx of a transaction whose signatures are allocated already,
+// such as an unsigned transaction created with null signatures.
+// The input must not be signed already.
+func (txn *Transaction) SignInput(key cipher.SecKey, idx int) error {
+	if !txn.validSigCount() {
+		return errors.New("Invalid number of signatures")
+	}
+	if idx < 0 || idx >= len(txn.In) {
+		return errors.New("Signature index out of range")
+	}
+	if !txn.Sigs[idx].Null() {
+		return errors.New("Input is already signed")
+	}
+
+	multisigs, err := txn.multisigWitnessMap()
+	if err != nil {
+		return err
+	}
+	if _, ok := multisigs[idx]; ok {
+		return errors.New("Input is a multisig input, see SignMultisigInput")
+	}
+
+	innerHash := txn.HashInner()
+	if innerHash != txn.InnerHash {
+		return errors.New("InnerHash does not match computed hash")
+	}
+
+	h := cipher.AddSHA256(innerHash, txn.In[idx]) // hash to sign
+	sig, err := cipher.SignHash(h, key)
+	if err != nil {
+		return err
+	}
+	txn.Sigs[idx] = sig
+
+	return nil
+}
+
 // Size returns the encoded byte size of the transaction
 func (txn *Transaction) Size() (uint32, error) {
 	return IntToUint32(len(txn.Serialize()))
@@ -280,7 +395,10 @@ func (txn *Transaction) UpdateHeader() error {
 		return err
 	}
 	txn.Length = s
-	txn.Type = byte(0x00)
+	// The type of a transaction with multisig witnesses is set by PushMultisigWitness
+	if txn.Type != TransactionTypeMultisig {
+		txn.Type = TransactionTypeDefault
+	}
 	txn.InnerHash = txn.HashInner()
 	return nil
 }
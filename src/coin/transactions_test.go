@@ -217,6 +217,39 @@ func TestTransactionVerifyInput(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestTransactionVerifyUnsigned(t *testing.T) {
+	ux, s := makeUxOutWithSecret(t)
+	txn := makeTransactionFromUxOut(t, ux, s)
+	require.True(t, txn.IsFullySigned())
+	require.NoError(t, txn.VerifyUnsigned())
+	require.NoError(t, txn.VerifyInputUnsigned(UxArray{ux}))
+
+	// Null signatures are allowed by the unsigned verification only
+	txn.Sigs[0] = cipher.Sig{}
+	err := txn.UpdateHeader()
+	require.NoError(t, err)
+	require.False(t, txn.IsFullySigned())
+	require.NoError(t, txn.VerifyUnsigned())
+	require.NoError(t, txn.VerifyInputUnsigned(UxArray{ux}))
+	testutil.RequireError(t, txn.Verify(), "Failed to recover pubkey from signature")
+	testutil.RequireError(t, txn.VerifyInput(UxArray{ux}), "Signature not valid for output being spent")
+
+	// Signatures that are not null are still verified
+	txn = makeTransactionFromUxOut(t, ux, s)
+	txn.Sigs[0] = cipher.MustSignHash(testutil.RandSHA256(t), s)
+	err = txn.UpdateHeader()
+	require.NoError(t, err)
+	testutil.RequireError(t, txn.VerifyInputUnsigned(UxArray{ux}), "Signature not valid for output being spent")
+
+	// The other constraints are still verified
+	txn = makeTransactionFromUxOut(t, ux, s)
+	txn.Sigs[0] = cipher.Sig{}
+	txn.Out = nil
+	err = txn.UpdateHeader()
+	require.NoError(t, err)
+	testutil.RequireError(t, txn.VerifyUnsigned(), "No outputs")
+}
+
 func TestTransactionPushInput(t *testing.T) {
 	txn := &Transaction{}
 	ux := makeUxOut(t)
@@ -280,6 +313,40 @@ func TestTransactionSignInputs(t *testing.T) {
 	require.Error(t, cipher.VerifyAddressSignedHash(a2, txn.Sigs[0], h))
 }
 
+func TestTransactionSignInput(t *testing.T) {
+	txn := &Transaction{}
+	ux, s := makeUxOutWithSecret(t)
+	txn.PushInput(ux.Hash())
+	ux2, s2 := makeUxOutWithSecret(t)
+	txn.PushInput(ux2.Hash())
+	txn.PushOutput(makeAddress(), 40, 80)
+
+	// The transaction must have a signature for each input
+	require.Error(t, txn.SignInput(s, 0))
+
+	txn.Sigs = make([]cipher.Sig, len(txn.In))
+	err := txn.UpdateHeader()
+	require.NoError(t, err)
+
+	require.Equal(t, errors.New("Signature index out of range"), txn.SignInput(s, 2))
+	require.Equal(t, errors.New("Signature index out of range"), txn.SignInput(s, -1))
+
+	// Sign the inputs one at a time
+	require.NoError(t, txn.SignInput(s2, 1))
+	require.False(t, txn.IsFullySigned())
+	require.Equal(t, errors.New("Input is already signed"), txn.SignInput(s2, 1))
+	require.NoError(t, txn.SignInput(s, 0))
+	require.True(t, txn.IsFullySigned())
+
+	require.NoError(t, txn.Verify())
+	require.NoError(t, txn.VerifyInput(UxArray{ux, ux2}))
+
+	// The inner hash must be up to date
+	txn.Sigs[0] = cipher.Sig{}
+	txn.PushOutput(makeAddress(), 1, 1)
+	require.Equal(t, errors.New("InnerHash does not match computed hash"), txn.SignInput(s, 0))
+}
+
 func TestTransactionHash(t *testing.T) {
 	txn := makeTransaction(t)
 	require.NotEqual(t, txn.Hash(), cipher.SHA256{})
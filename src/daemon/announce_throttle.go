@@ -0,0 +1,115 @@
+package daemon
+
+import (
+	"sync"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/daemon/gnet"
+	"github.com/skycoin/skycoin/src/util/ratelimit"
+)
+
+// announceThrottle limits the rate of the block and transaction announcements broadcast to peers.
+// Announcements over the limit are coalesced until the limit allows them: only the highest announced
+// block is kept, and the announced transactions are merged.
+// A nil *announceThrottle does not throttle.
+type announceThrottle struct {
+	sync.Mutex
+	limiter  *ratelimit.Limiter
+	blockSeq uint64
+	hasBlock bool
+	txns     []cipher.SHA256
+	txnsSet  map[cipher.SHA256]struct{}
+}
+
+// newAnnounceThrottle creates an announceThrottle which allows burst announcements at once,
+// then one announcement per rate. Returns nil if burst is 0
+func newAnnounceThrottle(burst int, rate time.Duration) *announceThrottle {
+	if burst == 0 {
+		return nil
+	}
+
+	return &announceThrottle{
+		limiter: ratelimit.NewLimiter(float64(time.Second)/float64(rate), burst),
+		txnsSet: make(map[cipher.SHA256]struct{}),
+	}
+}
+
+// allow returns true if the announcement can be broadcast now.
+// Otherwise the announcement is kept, to be returned by flush later.
+// Messages other than AnnounceBlocksMessage and AnnounceTxnsMessage are always allowed
+func (t *announceThrottle) allow(now time.Time, msg gnet.Message) bool {
+	if t == nil {
+		return true
+	}
+
+	switch msg.(type) {
+	case *AnnounceBlocksMessage, *AnnounceTxnsMessage:
+	default:
+		return true
+	}
+
+	t.Lock()
+	defer t.Unlock()
+
+	if t.limiter.Allow(now, 1) {
+		return true
+	}
+
+	switch m := msg.(type) {
+	case *AnnounceBlocksMessage:
+		if !t.hasBlock || m.MaxBkSeq > t.blockSeq {
+			t.blockSeq = m.MaxBkSeq
+		}
+		t.hasBlock = true
+	case *AnnounceTxnsMessage:
+		for _, h := range m.Transactions {
+			if _, ok := t.txnsSet[h]; ok {
+				continue
+			}
+			t.txnsSet[h] = struct{}{}
+			t.txns = append(t.txns, h)
+		}
+	}
+
+	return false
+}
+
+// flush returns the kept announcements that the limit allows now, the block announcement first.
+// The transactions are announced in messages of up to maxTxns hashes
+func (t *announceThrottle) flush(now time.Time, maxTxns int) []gnet.Message {
+	if t == nil {
+		return nil
+	}
+
+	t.Lock()
+	defer t.Unlock()
+
+	var msgs []gnet.Message
+
+	if t.hasBlock {
+		if !t.limiter.Allow(now, 1) {
+			return nil
+		}
+
+		msgs = append(msgs, NewAnnounceBlocksMessage(t.blockSeq))
+		t.blockSeq = 0
+		t.hasBlock = false
+	}
+
+	for len(t.txns) > 0 && t.limiter.Allow(now, 1) {
+		n := maxTxns
+		if n <= 0 || n > len(t.txns) {
+			n = len(t.txns)
+		}
+
+		msgs = append(msgs, NewAnnounceTxnsMessage(t.txns[:n]))
+
+		for _, h := range t.txns[:n] {
+			delete(t.txnsSet, h)
+		}
+		t.txns = t.txns[n:]
+	}
+
+	return msgs
+}
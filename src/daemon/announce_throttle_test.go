@@ -0,0 +1,72 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/daemon/gnet"
+)
+
+func TestAnnounceThrottleDisabled(t *testing.T) {
+	throttle := newAnnounceThrottle(0, time.Second)
+	require.Nil(t, throttle)
+
+	now := time.Now()
+	for i := 0; i < 100; i++ {
+		require.True(t, throttle.allow(now, NewAnnounceBlocksMessage(uint64(i))))
+	}
+	require.Empty(t, throttle.flush(now, 16))
+}
+
+func TestAnnounceThrottle(t *testing.T) {
+	throttle := newAnnounceThrottle(2, time.Second)
+	now := time.Now()
+
+	h := func(b byte) cipher.SHA256 {
+		return cipher.SumSHA256([]byte{b})
+	}
+
+	// Within the burst
+	require.True(t, throttle.allow(now, NewAnnounceBlocksMessage(10)))
+	require.True(t, throttle.allow(now, NewAnnounceTxnsMessage([]cipher.SHA256{h(1)})))
+
+	// Other messages are not throttled
+	require.True(t, throttle.allow(now, NewGetBlocksMessage(10, 20)))
+
+	// Over the burst, the announcements are coalesced
+	require.False(t, throttle.allow(now, NewAnnounceBlocksMessage(12)))
+	require.False(t, throttle.allow(now, NewAnnounceBlocksMessage(11)))
+	require.False(t, throttle.allow(now, NewAnnounceTxnsMessage([]cipher.SHA256{h(1), h(2)})))
+	require.False(t, throttle.allow(now, NewAnnounceTxnsMessage([]cipher.SHA256{h(2), h(3), h(4)})))
+
+	// Nothing can be sent until the limit allows
+	require.Empty(t, throttle.flush(now, 3))
+
+	// One announcement per second, the block announcement first
+	now = now.Add(time.Second)
+	require.Equal(t, []gnet.Message{NewAnnounceBlocksMessage(12)}, throttle.flush(now, 3))
+
+	now = now.Add(time.Second)
+	require.Equal(t, []gnet.Message{
+		NewAnnounceTxnsMessage([]cipher.SHA256{h(1), h(2), h(3)}),
+	}, throttle.flush(now, 3))
+
+	// The remaining transactions are merged with new ones
+	require.False(t, throttle.allow(now, NewAnnounceTxnsMessage([]cipher.SHA256{h(5), h(4)})))
+
+	now = now.Add(time.Second * 2)
+	require.Equal(t, []gnet.Message{
+		NewAnnounceTxnsMessage([]cipher.SHA256{h(4), h(5)}),
+	}, throttle.flush(now, 3))
+
+	require.Empty(t, throttle.txns)
+	require.Empty(t, throttle.txnsSet)
+	require.False(t, throttle.hasBlock)
+
+	// The unused allowance is kept for new announcements
+	require.True(t, throttle.allow(now, NewAnnounceBlocksMessage(13)))
+	require.False(t, throttle.allow(now, NewAnnounceBlocksMessage(14)))
+}
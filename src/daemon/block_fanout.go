@@ -0,0 +1,246 @@
+package daemon
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/daemon/gnet"
+)
+
+// fanoutTarget is a block message to send to a peer
+type fanoutTarget struct {
+	Addr string
+	Msg  gnet.Message
+}
+
+// fanoutItem is a block message queued for a peer
+type fanoutItem struct {
+	hash cipher.SHA256
+	seq  uint64
+	msg  gnet.Message
+	due  time.Time
+}
+
+// fanoutPeer is the latency and the queue of a peer
+type fanoutPeer struct {
+	// Smoothed ping round trip time, 0 if not measured yet
+	latency  time.Duration
+	pingSent time.Time
+	lastPong time.Time
+	queue    []fanoutItem
+}
+
+// blockFanout spreads the new blocks over the peers by latency, instead of sending them to all peers at once:
+// a block is sent at once to the waveSize peers with the lowest ping latency, then queued for the other peers,
+// which are sent the block in waves of waveSize peers staggered by the stagger delay. By then, most peers have
+// received the block from other peers, and a peer that reported a height including the block is not sent it.
+// A block is queued once per peer.
+// A nil *blockFanout sends the blocks to all peers at once.
+type blockFanout struct {
+	sync.Mutex
+	waveSize int
+	stagger  time.Duration
+	pingRate time.Duration
+	peers    map[string]*fanoutPeer
+}
+
+// newBlockFanout creates a blockFanout, which measures the latency of the peers every pingRate.
+// Returns nil if waveSize is 0
+func newBlockFanout(waveSize int, stagger, pingRate time.Duration) *blockFanout {
+	if waveSize == 0 {
+		return nil
+	}
+
+	return &blockFanout{
+		waveSize: waveSize,
+		stagger:  stagger,
+		pingRate: pingRate,
+		peers:    make(map[string]*fanoutPeer),
+	}
+}
+
+func (f *blockFanout) peer(addr string) *fanoutPeer {
+	p, ok := f.peers[addr]
+	if !ok {
+		p = &fanoutPeer{}
+		f.peers[addr] = p
+	}
+	return p
+}
+
+// ping returns true if a ping should be sent to addr now to measure its latency, and records it as sent.
+// A peer is pinged when its latency is unknown or older than pingRate, unless a ping is pending.
+// A ping without a reply for pingRate is considered lost
+func (f *blockFanout) ping(addr string, now time.Time) bool {
+	if f == nil {
+		return false
+	}
+
+	f.Lock()
+	defer f.Unlock()
+
+	p := f.peer(addr)
+	if !p.pingSent.IsZero() && now.Sub(p.pingSent) < f.pingRate {
+		return false
+	}
+	if !p.lastPong.IsZero() && now.Sub(p.lastPong) < f.pingRate {
+		return false
+	}
+
+	p.pingSent = now
+	return true
+}
+
+// pong records the round trip time of the pending ping of addr
+func (f *blockFanout) pong(addr string, now time.Time) {
+	if f == nil {
+		return
+	}
+
+	f.Lock()
+	defer f.Unlock()
+
+	p, ok := f.peers[addr]
+	if !ok || p.pingSent.IsZero() {
+		return
+	}
+
+	rtt := now.Sub(p.pingSent)
+	if p.latency == 0 {
+		p.latency = rtt
+	} else {
+		p.latency = (p.latency*7 + rtt) / 8
+	}
+
+	p.pingSent = time.Time{}
+	p.lastPong = now
+}
+
+// latency returns the smoothed ping round trip time of addr, 0 if not measured yet
+func (f *blockFanout) latency(addr string) time.Duration {
+	if f == nil {
+		return 0
+	}
+
+	f.Lock()
+	defer f.Unlock()
+
+	if p, ok := f.peers[addr]; ok {
+		return p.latency
+	}
+	return 0
+}
+
+// removePeer forgets the latency and the queue of a disconnected peer
+func (f *blockFanout) removePeer(addr string) {
+	if f == nil {
+		return
+	}
+
+	f.Lock()
+	defer f.Unlock()
+
+	delete(f.peers, addr)
+}
+
+// schedule returns the targets to send a block to now, the ones with the lowest latency, and queues the others
+// in staggered waves. The peers with an unknown latency come last
+func (f *blockFanout) schedule(hash cipher.SHA256, seq uint64, targets []fanoutTarget, now time.Time) []fanoutTarget {
+	if f == nil {
+		return targets
+	}
+
+	f.Lock()
+	defer f.Unlock()
+
+	targets = append([]fanoutTarget(nil), targets...)
+	sort.SliceStable(targets, func(i, j int) bool {
+		a := f.peer(targets[i].Addr).latency
+		b := f.peer(targets[j].Addr).latency
+		if a == 0 || b == 0 {
+			return a != 0
+		}
+		return a < b
+	})
+
+	var send []fanoutTarget
+	queued := 0
+	for _, t := range targets {
+		p := f.peer(t.Addr)
+		if p.hasQueued(hash) {
+			continue
+		}
+
+		if len(send) < f.waveSize {
+			send = append(send, t)
+			continue
+		}
+
+		wave := 1 + queued/f.waveSize
+		p.queue = append(p.queue, fanoutItem{
+			hash: hash,
+			seq:  seq,
+			msg:  t.Msg,
+			due:  now.Add(f.stagger * time.Duration(wave)),
+		})
+		queued++
+	}
+
+	return send
+}
+
+func (p *fanoutPeer) hasQueued(hash cipher.SHA256) bool {
+	for _, it := range p.queue {
+		if it.hash == hash {
+			return true
+		}
+	}
+	return false
+}
+
+// flush removes the queued blocks that are due and returns their targets. heights returns the height reported
+// by a connected peer: the blocks at or below it are dropped, and the queue of a peer that is not connected is dropped
+func (f *blockFanout) flush(now time.Time, heights func(addr string) (uint64, bool)) []fanoutTarget {
+	if f == nil {
+		return nil
+	}
+
+	f.Lock()
+	defer f.Unlock()
+
+	var targets []fanoutTarget
+	for addr, p := range f.peers {
+		if len(p.queue) == 0 {
+			continue
+		}
+
+		height, ok := heights(addr)
+		if !ok {
+			p.queue = nil
+			continue
+		}
+
+		var queue []fanoutItem
+		for _, it := range p.queue {
+			switch {
+			case it.seq <= height:
+			case it.due.After(now):
+				queue = append(queue, it)
+			default:
+				targets = append(targets, fanoutTarget{
+					Addr: addr,
+					Msg:  it.msg,
+				})
+			}
+		}
+		p.queue = queue
+	}
+
+	sort.SliceStable(targets, func(i, j int) bool {
+		return targets[i].Addr < targets[j].Addr
+	})
+
+	return targets
+}
@@ -0,0 +1,121 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+func TestBlockFanoutLatency(t *testing.T) {
+	start := time.Now()
+	pingRate := time.Minute
+
+	// A nil blockFanout does not ping
+	var f *blockFanout
+	require.Nil(t, newBlockFanout(0, time.Second, pingRate))
+	require.False(t, f.ping("1.1.1.1:6000", start))
+	f.pong("1.1.1.1:6000", start)
+	require.Equal(t, time.Duration(0), f.latency("1.1.1.1:6000"))
+
+	f = newBlockFanout(2, time.Second, pingRate)
+
+	// A pong without a pending ping is ignored
+	f.pong("1.1.1.1:6000", start)
+	require.NotContains(t, f.peers, "1.1.1.1:6000")
+
+	require.True(t, f.ping("1.1.1.1:6000", start))
+	require.False(t, f.ping("1.1.1.1:6000", start.Add(time.Second)))
+	f.pong("1.1.1.1:6000", start.Add(time.Millisecond*400))
+	require.Equal(t, time.Millisecond*400, f.peers["1.1.1.1:6000"].latency)
+
+	// The latency is measured again after the ping rate, and smoothed
+	require.False(t, f.ping("1.1.1.1:6000", start.Add(time.Second)))
+	now := start.Add(pingRate * 2)
+	require.True(t, f.ping("1.1.1.1:6000", now))
+	f.pong("1.1.1.1:6000", now.Add(time.Millisecond*800))
+	require.Equal(t, time.Millisecond*450, f.latency("1.1.1.1:6000"))
+
+	// A lost ping is sent again after the ping rate
+	require.True(t, f.ping("2.2.2.2:6000", start))
+	require.False(t, f.ping("2.2.2.2:6000", start.Add(pingRate/2)))
+	require.True(t, f.ping("2.2.2.2:6000", start.Add(pingRate)))
+
+	require.Equal(t, time.Duration(0), f.latency("2.2.2.2:6000"))
+
+	f.removePeer("1.1.1.1:6000")
+	require.NotContains(t, f.peers, "1.1.1.1:6000")
+}
+
+func TestBlockFanoutSchedule(t *testing.T) {
+	start := time.Now()
+	stagger := time.Second
+	hash := cipher.SumSHA256([]byte("block"))
+	compact := NewCompactBlockMessage(makeCompactTestBlock(10, nil))
+	announce := NewAnnounceBlocksMessage(10)
+
+	targets := []fanoutTarget{
+		{Addr: "1.1.1.1:6000", Msg: compact},
+		{Addr: "2.2.2.2:6000", Msg: compact},
+		{Addr: "3.3.3.3:6000", Msg: announce},
+		{Addr: "4.4.4.4:6000", Msg: compact},
+		{Addr: "5.5.5.5:6000", Msg: announce},
+		{Addr: "6.6.6.6:6000", Msg: compact},
+	}
+
+	// A nil blockFanout sends the blocks to all peers at once
+	var f *blockFanout
+	require.Equal(t, targets, f.schedule(hash, 10, targets, start))
+	require.Empty(t, f.flush(start, nil))
+
+	f = newBlockFanout(2, stagger, time.Minute)
+
+	latencies := map[string]time.Duration{
+		"1.1.1.1:6000": time.Millisecond * 300,
+		"3.3.3.3:6000": time.Millisecond * 100,
+		"4.4.4.4:6000": time.Millisecond * 200,
+		"5.5.5.5:6000": time.Millisecond * 400,
+	}
+	for addr, latency := range latencies {
+		require.True(t, f.ping(addr, start))
+		f.pong(addr, start.Add(latency))
+	}
+
+	// The block is sent to the peers with the lowest latency first, the peers with an unknown latency come last
+	require.Equal(t, []fanoutTarget{
+		{Addr: "3.3.3.3:6000", Msg: announce},
+		{Addr: "4.4.4.4:6000", Msg: compact},
+	}, f.schedule(hash, 10, targets, start))
+
+	// The block is queued once per peer
+	require.Empty(t, f.schedule(hash, 10, targets[:1], start))
+
+	heights := map[string]uint64{
+		"1.1.1.1:6000": 9,
+		"2.2.2.2:6000": 9,
+		"5.5.5.5:6000": 9,
+		"6.6.6.6:6000": 9,
+	}
+	height := func(addr string) (uint64, bool) {
+		h, ok := heights[addr]
+		return h, ok
+	}
+
+	require.Empty(t, f.flush(start.Add(stagger/2), height))
+
+	// The next wave is due after the stagger
+	require.Equal(t, []fanoutTarget{
+		{Addr: "1.1.1.1:6000", Msg: compact},
+		{Addr: "5.5.5.5:6000", Msg: announce},
+	}, f.flush(start.Add(stagger), height))
+
+	// A peer that reported the block is not sent it, nor a disconnected peer
+	heights["2.2.2.2:6000"] = 10
+	delete(heights, "6.6.6.6:6000")
+	require.Empty(t, f.flush(start.Add(stagger*2), height))
+	for _, p := range f.peers {
+		require.Empty(t, p.queue)
+	}
+}
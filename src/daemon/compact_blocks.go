@@ -0,0 +1,188 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+)
+
+const (
+	// partialBlockTimeout is how long a compact block waits for its missing transactions
+	partialBlockTimeout = time.Second * 30
+	// maxPartialBlocks is the maximum number of compact blocks waiting for their missing transactions
+	maxPartialBlocks = 8
+)
+
+var (
+	// ErrCompactBlockBodyMismatch is returned when the transactions of a reconstructed compact block
+	// do not match the body hash of its header
+	ErrCompactBlockBodyMismatch = errors.New("Reconstructed compact block transactions do not match the block body hash")
+)
+
+// ShortTxnID identifies a transaction of a compact block. It is the first 6 bytes of the hash of the block hash
+// concatenated with the transaction hash, so that the short IDs of the same transaction differ between blocks
+// and a collision can't be crafted for all blocks
+type ShortTxnID [6]byte
+
+// NewShortTxnID creates the ShortTxnID of a transaction in a block
+func NewShortTxnID(blockHash, txnHash cipher.SHA256) ShortTxnID {
+	var id ShortTxnID
+	h := cipher.AddSHA256(blockHash, txnHash)
+	copy(id[:], h[:len(id)])
+	return id
+}
+
+// partialBlock is a block being reconstructed from a CompactBlockMessage
+type partialBlock struct {
+	block    coin.SignedBlock
+	shortIDs []ShortTxnID
+	// Indexes of the transactions that are not known yet
+	missing []uint16
+	// Connection the compact block was received from
+	addr    string
+	created time.Time
+}
+
+// newPartialBlock creates a partialBlock from a CompactBlockMessage, filling in the transactions of the block
+// found in txns by their short ID. Transactions with the same short ID are treated as missing
+func newPartialBlock(m *CompactBlockMessage, txns coin.Transactions, addr string, now time.Time) *partialBlock {
+	blockHash := m.Head.Hash()
+
+	known := make(map[ShortTxnID]int, len(txns))
+	for i, txn := range txns {
+		id := NewShortTxnID(blockHash, txn.Hash())
+		if _, ok := known[id]; ok {
+			known[id] = -1
+			continue
+		}
+		known[id] = i
+	}
+
+	pb := &partialBlock{
+		block: coin.SignedBlock{
+			Block: coin.Block{
+				Head: m.Head,
+				Body: coin.BlockBody{
+					Transactions: make(coin.Transactions, len(m.ShortIDs)),
+				},
+			},
+			Sig: m.Sig,
+		},
+		shortIDs: m.ShortIDs,
+		addr:     addr,
+		created:  now,
+	}
+
+	for i, id := range m.ShortIDs {
+		j, ok := known[id]
+		if !ok || j < 0 {
+			pb.missing = append(pb.missing, uint16(i))
+			continue
+		}
+		pb.block.Body.Transactions[i] = txns[j]
+	}
+
+	return pb
+}
+
+// hash returns the hash of the block
+func (pb *partialBlock) hash() cipher.SHA256 {
+	return pb.block.HashHeader()
+}
+
+// complete returns true if no transactions are missing
+func (pb *partialBlock) complete() bool {
+	return len(pb.missing) == 0
+}
+
+// fill fills in the missing transactions, which must be in the order of the missing indexes
+func (pb *partialBlock) fill(txns coin.Transactions) error {
+	if len(txns) != len(pb.missing) {
+		return fmt.Errorf("Expected %d missing transactions, received %d", len(pb.missing), len(txns))
+	}
+
+	blockHash := pb.hash()
+	for i, idx := range pb.missing {
+		if NewShortTxnID(blockHash, txns[i].Hash()) != pb.shortIDs[idx] {
+			return fmt.Errorf("Transaction %s does not match the short ID at index %d", txns[i].Hash().Hex(), idx)
+		}
+	}
+
+	for i, idx := range pb.missing {
+		pb.block.Body.Transactions[idx] = txns[i]
+	}
+	pb.missing = nil
+
+	return nil
+}
+
+// signedBlock returns the reconstructed block. Returns ErrCompactBlockBodyMismatch if its transactions
+// do not match the body hash, which happens if different transactions had the same short ID
+func (pb *partialBlock) signedBlock() (coin.SignedBlock, error) {
+	if !pb.complete() {
+		return coin.SignedBlock{}, errors.New("Compact block is missing transactions")
+	}
+
+	if pb.block.HashBody() != pb.block.Head.BodyHash {
+		return coin.SignedBlock{}, ErrCompactBlockBodyMismatch
+	}
+
+	return pb.block, nil
+}
+
+// partialBlocks holds the compact blocks waiting for their missing transactions
+type partialBlocks struct {
+	sync.Mutex
+	blocks map[cipher.SHA256]*partialBlock
+}
+
+func newPartialBlocks() *partialBlocks {
+	return &partialBlocks{
+		blocks: make(map[cipher.SHA256]*partialBlock),
+	}
+}
+
+// add adds a partialBlock, replacing one with the same hash. The blocks older than partialBlockTimeout
+// are removed, and the oldest block if there are more than maxPartialBlocks
+func (p *partialBlocks) add(pb *partialBlock, now time.Time) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.blocks[pb.hash()] = pb
+
+	var oldest *cipher.SHA256
+	for h, b := range p.blocks {
+		if now.Sub(b.created) > partialBlockTimeout {
+			delete(p.blocks, h)
+			continue
+		}
+
+		if oldest == nil || b.created.Before(p.blocks[*oldest].created) {
+			h := h
+			oldest = &h
+		}
+	}
+
+	if len(p.blocks) > maxPartialBlocks && oldest != nil {
+		delete(p.blocks, *oldest)
+	}
+}
+
+// remove removes and returns the partialBlock of a hash received from addr. Returns nil if there is none
+func (p *partialBlocks) remove(addr string, hash cipher.SHA256) *partialBlock {
+	p.Lock()
+	defer p.Unlock()
+
+	pb, ok := p.blocks[hash]
+	if !ok || pb.addr != addr {
+		return nil
+	}
+
+	delete(p.blocks, hash)
+
+	return pb
+}
@@ -0,0 +1,310 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/daemon/gnet"
+)
+
+func makeCompactTestTxn(b byte) coin.Transaction {
+	return coin.Transaction{
+		Length:    100,
+		InnerHash: cipher.SumSHA256([]byte{b}),
+		In:        []cipher.SHA256{cipher.SumSHA256([]byte{b, b})},
+	}
+}
+
+func makeCompactTestBlock(seq uint64, txns coin.Transactions) coin.SignedBlock {
+	body := coin.BlockBody{
+		Transactions: txns,
+	}
+
+	return coin.SignedBlock{
+		Block: coin.Block{
+			Head: coin.BlockHeader{
+				Version:  1,
+				Time:     1538036613,
+				BkSeq:    seq,
+				PrevHash: cipher.SumSHA256([]byte("prev")),
+				BodyHash: body.Hash(),
+			},
+			Body: body,
+		},
+	}
+}
+
+func TestNewShortTxnID(t *testing.T) {
+	blockHash := cipher.SumSHA256([]byte("block"))
+	txnHash := cipher.SumSHA256([]byte("txn"))
+
+	id := NewShortTxnID(blockHash, txnHash)
+	h := cipher.AddSHA256(blockHash, txnHash)
+	require.Equal(t, h[:6], id[:])
+
+	// The short ID of a transaction differs between blocks
+	require.NotEqual(t, id, NewShortTxnID(cipher.SumSHA256([]byte("block2")), txnHash))
+}
+
+func TestPartialBlock(t *testing.T) {
+	txns := coin.Transactions{makeCompactTestTxn(1), makeCompactTestTxn(2), makeCompactTestTxn(3)}
+	sb := makeCompactTestBlock(11, txns)
+	m := NewCompactBlockMessage(sb)
+	require.Len(t, m.ShortIDs, 3)
+	now := time.Now()
+
+	// All transactions are known
+	pool := coin.Transactions{makeCompactTestTxn(9), txns[2], txns[0], txns[1]}
+	pb := newPartialBlock(m, pool, "1.2.3.4:6000", now)
+	require.True(t, pb.complete())
+	require.Equal(t, sb.HashHeader(), pb.hash())
+	b, err := pb.signedBlock()
+	require.NoError(t, err)
+	require.Equal(t, sb, b)
+
+	// Missing transactions
+	pb = newPartialBlock(m, coin.Transactions{txns[1]}, "1.2.3.4:6000", now)
+	require.False(t, pb.complete())
+	require.Equal(t, []uint16{0, 2}, pb.missing)
+	_, err = pb.signedBlock()
+	require.Error(t, err)
+
+	err = pb.fill(coin.Transactions{txns[0]})
+	require.Equal(t, "Expected 2 missing transactions, received 1", err.Error())
+
+	err = pb.fill(coin.Transactions{txns[2], txns[0]})
+	require.Error(t, err)
+	require.False(t, pb.complete())
+
+	require.NoError(t, pb.fill(coin.Transactions{txns[0], txns[2]}))
+	require.True(t, pb.complete())
+	b, err = pb.signedBlock()
+	require.NoError(t, err)
+	require.Equal(t, sb, b)
+
+	// Transactions of the pool with the same short ID are treated as missing
+	pb = newPartialBlock(m, coin.Transactions{txns[0], txns[0], txns[1], txns[2]}, "1.2.3.4:6000", now)
+	require.Equal(t, []uint16{0}, pb.missing)
+
+	// A reconstructed block that does not match the body hash
+	bad := sb
+	bad.Head.BodyHash = cipher.SumSHA256([]byte("bad"))
+	pb = newPartialBlock(NewCompactBlockMessage(bad), txns, "1.2.3.4:6000", now)
+	require.True(t, pb.complete())
+	_, err = pb.signedBlock()
+	require.Equal(t, ErrCompactBlockBodyMismatch, err)
+}
+
+func TestPartialBlocks(t *testing.T) {
+	p := newPartialBlocks()
+	now := time.Now()
+
+	var pbs []*partialBlock
+	for i := 0; i < maxPartialBlocks+1; i++ {
+		sb := makeCompactTestBlock(uint64(i), coin.Transactions{makeCompactTestTxn(byte(i))})
+		pb := newPartialBlock(NewCompactBlockMessage(sb), nil, "1.2.3.4:6000", now.Add(time.Duration(i)*time.Second))
+		pbs = append(pbs, pb)
+		p.add(pb, pb.created)
+	}
+
+	// The oldest block is removed when there are too many
+	require.Len(t, p.blocks, maxPartialBlocks)
+	require.Nil(t, p.remove("1.2.3.4:6000", pbs[0].hash()))
+
+	// Only the peer that sent the compact block can complete it
+	require.Nil(t, p.remove("5.6.7.8:6000", pbs[1].hash()))
+	require.Equal(t, pbs[1], p.remove("1.2.3.4:6000", pbs[1].hash()))
+	require.Nil(t, p.remove("1.2.3.4:6000", pbs[1].hash()))
+
+	// Expired blocks are removed, the blocks created in the first 5 seconds
+	sb := makeCompactTestBlock(100, coin.Transactions{makeCompactTestTxn(100)})
+	later := now.Add(partialBlockTimeout + time.Second*5)
+	pb := newPartialBlock(NewCompactBlockMessage(sb), nil, "1.2.3.4:6000", later)
+	p.add(pb, later)
+	require.Len(t, p.blocks, maxPartialBlocks-3)
+	require.Equal(t, pb, p.blocks[pb.hash()])
+	require.Equal(t, pbs[5], p.blocks[pbs[5].hash()])
+}
+
+func TestCompactBlockMessageProcess(t *testing.T) {
+	addr := "1.2.3.4:6000"
+	txns := coin.Transactions{makeCompactTestTxn(1), makeCompactTestTxn(2)}
+	sb := makeCompactTestBlock(11, txns)
+
+	cases := []struct {
+		name        string
+		headSeq     uint64
+		unconfirmed coin.Transactions
+		expect      func(t *testing.T, d *mockDaemoner)
+	}{
+		{
+			name:    "known block",
+			headSeq: 11,
+			expect: func(t *testing.T, d *mockDaemoner) {
+				d.AssertNotCalled(t, "getAllUnconfirmedTxns")
+				d.AssertNotCalled(t, "executeSignedBlocks", mock.Anything)
+			},
+		},
+		{
+			name:    "missing previous blocks",
+			headSeq: 9,
+			expect: func(t *testing.T, d *mockDaemoner) {
+				d.AssertCalled(t, "requestBlocksFromAddr", addr)
+				d.AssertNotCalled(t, "executeSignedBlocks", mock.Anything)
+			},
+		},
+		{
+			name:        "all transactions known",
+			headSeq:     10,
+			unconfirmed: coin.Transactions{txns[1], txns[0]},
+			expect: func(t *testing.T, d *mockDaemoner) {
+				d.AssertCalled(t, "executeSignedBlocks", []coin.SignedBlock{sb})
+				d.AssertCalled(t, "recordBlocksReceived", uint64(1))
+				d.AssertCalled(t, "relayBlock", sb, addr)
+				d.AssertNotCalled(t, "sendMessage", mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name:        "missing transactions",
+			headSeq:     10,
+			unconfirmed: coin.Transactions{txns[1]},
+			expect: func(t *testing.T, d *mockDaemoner) {
+				d.AssertCalled(t, "addPartialBlock", mock.Anything)
+				d.AssertCalled(t, "sendMessage", addr, NewGetBlockTxnsMessage(sb.HashHeader(), []uint16{0}))
+				d.AssertNotCalled(t, "executeSignedBlocks", mock.Anything)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := NewCompactBlockMessage(sb)
+			m.c = &gnet.MessageContext{
+				Addr:   addr,
+				ConnID: 1,
+			}
+
+			d := &mockDaemoner{}
+			d.On("daemonConfig").Return(DaemonConfig{})
+			d.On("recordPeerHeight", addr, uint64(1), uint64(11))
+			d.On("headBkSeq").Return(tc.headSeq, true, nil)
+			d.On("requestBlocksFromAddr", addr).Return(nil)
+			d.On("getAllUnconfirmedTxns").Return(tc.unconfirmed, nil)
+			d.On("addPartialBlock", mock.Anything)
+			d.On("sendMessage", addr, mock.Anything).Return(nil)
+			d.On("recordBlocksReceived", uint64(1))
+			d.On("executeSignedBlocks", mock.Anything).Return(1, nil)
+			d.On("relayBlock", mock.Anything, addr).Return(nil)
+
+			m.process(d)
+
+			tc.expect(t, d)
+		})
+	}
+}
+
+func TestGetBlockTxnsMessageProcess(t *testing.T) {
+	addr := "1.2.3.4:6000"
+	txns := coin.Transactions{makeCompactTestTxn(1), makeCompactTestTxn(2), makeCompactTestTxn(3)}
+	sb := makeCompactTestBlock(11, txns)
+	mc := &gnet.MessageContext{
+		Addr:   addr,
+		ConnID: 1,
+	}
+
+	d := &mockDaemoner{}
+	d.On("daemonConfig").Return(DaemonConfig{})
+	d.On("getSignedBlockByHash", sb.HashHeader()).Return(&sb, nil)
+	d.On("getSignedBlockByHash", mock.Anything).Return(nil, nil)
+	d.On("sendMessage", addr, mock.Anything).Return(nil)
+	d.On("recordPeerMisbehavior", addr, PeerMisbehaviorInvalidMessage)
+
+	m := NewGetBlockTxnsMessage(sb.HashHeader(), []uint16{2, 0})
+	m.c = mc
+	m.process(d)
+	d.AssertCalled(t, "sendMessage", addr, NewGiveBlockTxnsMessage(sb.HashHeader(), coin.Transactions{txns[2], txns[0]}))
+
+	// Unknown block
+	m = NewGetBlockTxnsMessage(cipher.SumSHA256([]byte("unknown")), []uint16{0})
+	m.c = mc
+	m.process(d)
+	d.AssertNumberOfCalls(t, "sendMessage", 1)
+
+	// Index out of range
+	m = NewGetBlockTxnsMessage(sb.HashHeader(), []uint16{3})
+	m.c = mc
+	m.process(d)
+	d.AssertNumberOfCalls(t, "sendMessage", 1)
+	d.AssertCalled(t, "recordPeerMisbehavior", addr, PeerMisbehaviorInvalidMessage)
+}
+
+func TestGiveBlockTxnsMessageProcess(t *testing.T) {
+	addr := "1.2.3.4:6000"
+	txns := coin.Transactions{makeCompactTestTxn(1), makeCompactTestTxn(2), makeCompactTestTxn(3)}
+	sb := makeCompactTestBlock(11, txns)
+	mc := &gnet.MessageContext{
+		Addr:   addr,
+		ConnID: 1,
+	}
+
+	newPB := func() *partialBlock {
+		return newPartialBlock(NewCompactBlockMessage(sb), coin.Transactions{txns[1]}, addr, time.Now())
+	}
+
+	cases := []struct {
+		name   string
+		pb     *partialBlock
+		txns   coin.Transactions
+		expect func(t *testing.T, d *mockDaemoner)
+	}{
+		{
+			name: "no compact block waiting",
+			txns: coin.Transactions{txns[0], txns[2]},
+			expect: func(t *testing.T, d *mockDaemoner) {
+				d.AssertNotCalled(t, "executeSignedBlocks", mock.Anything)
+				d.AssertNotCalled(t, "recordPeerMisbehavior", mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name: "wrong transactions",
+			pb:   newPB(),
+			txns: coin.Transactions{txns[2], txns[0]},
+			expect: func(t *testing.T, d *mockDaemoner) {
+				d.AssertNotCalled(t, "executeSignedBlocks", mock.Anything)
+				d.AssertCalled(t, "recordPeerMisbehavior", addr, PeerMisbehaviorInvalidMessage)
+			},
+		},
+		{
+			name: "block completed",
+			pb:   newPB(),
+			txns: coin.Transactions{txns[0], txns[2]},
+			expect: func(t *testing.T, d *mockDaemoner) {
+				d.AssertCalled(t, "executeSignedBlocks", []coin.SignedBlock{sb})
+				d.AssertCalled(t, "relayBlock", sb, addr)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			d := &mockDaemoner{}
+			d.On("daemonConfig").Return(DaemonConfig{})
+			d.On("removePartialBlock", addr, sb.HashHeader()).Return(tc.pb)
+			d.On("recordPeerMisbehavior", addr, PeerMisbehaviorInvalidMessage)
+			d.On("recordBlocksReceived", uint64(1))
+			d.On("executeSignedBlocks", mock.Anything).Return(1, nil)
+			d.On("relayBlock", mock.Anything, addr).Return(nil)
+
+			m := NewGiveBlockTxnsMessage(sb.HashHeader(), tc.txns)
+			m.c = mc
+			m.process(d)
+
+			tc.expect(t, d)
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package daemon
+
+import (
+	"sync"
+	"time"
+
+	"github.com/skycoin/skycoin/src/util/useragent"
+)
+
+// connectionEventsBufferSize is the number of events buffered for a subscriber.
+// The events are dropped for a subscriber that does not keep up
+const connectionEventsBufferSize = 256
+
+// ConnectionEventType is the type of a ConnectionEvent
+type ConnectionEventType string
+
+const (
+	// ConnectionEventConnected a connection was established, before the introduction
+	ConnectionEventConnected ConnectionEventType = "connected"
+	// ConnectionEventIntroduced a connection completed the introduction handshake
+	ConnectionEventIntroduced ConnectionEventType = "introduced"
+	// ConnectionEventDisconnected a connection was closed
+	ConnectionEventDisconnected ConnectionEventType = "disconnected"
+	// ConnectionEventBanned a peer IP was banned for misbehaving or by the operator
+	ConnectionEventBanned ConnectionEventType = "banned"
+)
+
+// ConnectionEvent is a step in the lifecycle of a peer connection
+type ConnectionEvent struct {
+	Type ConnectionEventType
+	Time time.Time
+	// Address of the connection, or the banned IP for ConnectionEventBanned
+	Addr     string
+	GnetID   uint64
+	Outgoing bool
+	// Self-reported listen port and user agent of the peer, for ConnectionEventIntroduced
+	ListenPort uint16
+	UserAgent  useragent.Data
+	// Disconnect reason for ConnectionEventDisconnected, misbehavior or ban reason for ConnectionEventBanned
+	Reason string
+}
+
+// connectionEvents publishes the ConnectionEvents to their subscribers
+type connectionEvents struct {
+	sync.Mutex
+	subscribers map[chan ConnectionEvent]struct{}
+}
+
+func newConnectionEvents() *connectionEvents {
+	return &connectionEvents{
+		subscribers: make(map[chan ConnectionEvent]struct{}),
+	}
+}
+
+// subscribe returns a channel that receives the events published from now on,
+// and a function that unsubscribes and closes the channel
+func (ce *connectionEvents) subscribe() (<-chan ConnectionEvent, func()) {
+	c := make(chan ConnectionEvent, connectionEventsBufferSize)
+
+	ce.Lock()
+	defer ce.Unlock()
+	ce.subscribers[c] = struct{}{}
+
+	var once sync.Once
+	return c, func() {
+		once.Do(func() {
+			ce.Lock()
+			defer ce.Unlock()
+			delete(ce.subscribers, c)
+			close(c)
+		})
+	}
+}
+
+// publish sends an event to the subscribers, without blocking
+func (ce *connectionEvents) publish(e ConnectionEvent) {
+	ce.Lock()
+	defer ce.Unlock()
+
+	for c := range ce.subscribers {
+		select {
+		case c <- e:
+		default:
+			logger.WithField("type", e.Type).Debug("Connection event subscriber is full, dropping event")
+		}
+	}
+}
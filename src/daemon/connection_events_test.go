@@ -0,0 +1,45 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnectionEvents(t *testing.T) {
+	ce := newConnectionEvents()
+
+	// Events published without subscribers are dropped
+	ce.publish(ConnectionEvent{Type: ConnectionEventConnected, Addr: "1.2.3.4:6000"})
+
+	c1, unsubscribe1 := ce.subscribe()
+	c2, unsubscribe2 := ce.subscribe()
+
+	e := ConnectionEvent{
+		Type:   ConnectionEventDisconnected,
+		Time:   time.Now().UTC(),
+		Addr:   "1.2.3.4:6000",
+		GnetID: 1,
+		Reason: ErrDisconnectIdle.Error(),
+	}
+	ce.publish(e)
+	require.Equal(t, e, <-c1)
+	require.Equal(t, e, <-c2)
+
+	// Unsubscribing closes the channel, and can be repeated
+	unsubscribe1()
+	unsubscribe1()
+	_, ok := <-c1
+	require.False(t, ok)
+
+	// A subscriber that does not keep up misses the events over its buffer, without blocking the publisher
+	for i := 0; i < connectionEventsBufferSize+10; i++ {
+		ce.publish(ConnectionEvent{Type: ConnectionEventConnected, GnetID: uint64(i)})
+	}
+	require.Len(t, c2, connectionEventsBufferSize)
+	require.Equal(t, uint64(0), (<-c2).GnetID)
+
+	unsubscribe2()
+	require.Empty(t, ce.subscribers)
+}
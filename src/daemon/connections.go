@@ -2,7 +2,8 @@ package daemon
 
 import (
 	"errors"
-	"fmt"
+	"net"
+	"strconv"
 	"sync"
 	"time"
 
@@ -63,6 +64,8 @@ type ConnectionDetails struct {
 	UserAgent                     useragent.Data
 	UnconfirmedBurnFactor         uint32
 	UnconfirmedMaxTransactionSize uint32
+	// Features negotiated with the peer
+	Features PeerFeatures
 }
 
 // HasIntroduced returns true if the connection has introduced
@@ -93,7 +96,7 @@ func (c *connection) ListenAddr() string {
 		return ""
 	}
 
-	return fmt.Sprintf("%s:%d", ip, c.ListenPort)
+	return net.JoinHostPort(ip, strconv.Itoa(int(c.ListenPort)))
 }
 
 // Connections manages a collection of Connection
@@ -298,6 +301,7 @@ func (c *Connections) introduced(addr string, gnetID uint64, m *IntroductionMess
 	conn.UserAgent = m.userAgent
 	conn.UnconfirmedBurnFactor = m.unconfirmedBurnFactor
 	conn.UnconfirmedMaxTransactionSize = m.unconfirmedMaxTransactionSize
+	conn.Features = m.features
 
 	if !conn.Outgoing {
 		listenAddr := conn.ListenAddr()
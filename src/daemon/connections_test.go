@@ -24,6 +24,16 @@ func getMirrorPort(c *Connections, ip string, mirror uint32) uint16 {
 	return x[ip]
 }
 
+func TestConnectionListenAddrIPv6(t *testing.T) {
+	c := &connection{
+		Addr: "[2001:db8::1]:50000",
+		ConnectionDetails: ConnectionDetails{
+			ListenPort: 6000,
+		},
+	}
+	require.Equal(t, "[2001:db8::1]:6000", c.ListenAddr())
+}
+
 func TestConnectionsOutgoingFlow(t *testing.T) {
 	conns := NewConnections()
 
@@ -0,0 +1,283 @@
+package daemon
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/skycoin/skycoin/src/util/useragent"
+)
+
+// CrawledNode is a node of the peer network that the crawler attempted to visit
+type CrawledNode struct {
+	Addr string `json:"address"`
+	// Reachable is true if the node accepted the connection and completed the introduction
+	Reachable       bool           `json:"reachable"`
+	ProtocolVersion int32          `json:"protocol_version,omitempty"`
+	UserAgent       useragent.Data `json:"user_agent"`
+	// Head block seq reported by the node, if HeightKnown
+	Height      uint64 `json:"height"`
+	HeightKnown bool   `json:"height_known"`
+	// Number of peers the node sent in reply to a GetPeersMessage
+	PeersGiven int       `json:"peers_given"`
+	Visited    time.Time `json:"visited"`
+	// Why the connection failed or was closed before the introduction, if not Reachable
+	Error string `json:"error,omitempty"`
+}
+
+// CrawlReport summarizes the nodes of the peer network visited by the crawler
+type CrawlReport struct {
+	Started time.Time `json:"started"`
+	Updated time.Time `json:"updated"`
+	// Number of known addresses that were not visited yet
+	Queued int `json:"queued"`
+	// Number of addresses being visited
+	Visiting int `json:"visiting"`
+	// Number of addresses visited, reachable or not
+	Visited   int `json:"visited"`
+	Reachable int `json:"reachable"`
+	// Highest head block seq reported by a reachable node
+	MaxHeight uint64 `json:"max_height"`
+	// Number of reachable nodes per protocol version and per user agent
+	ProtocolVersions map[int32]int  `json:"protocol_versions"`
+	UserAgents       map[string]int `json:"user_agents"`
+	// Visited nodes, sorted by address
+	Nodes []CrawledNode `json:"nodes"`
+}
+
+// crawlVisit is a connection of the crawler to a node, which lasts until the node sent its height
+// and its peers, or the visit timed out
+type crawlVisit struct {
+	node       CrawledNode
+	introduced time.Time
+	peers      bool
+	finishing  bool
+}
+
+// crawler walks the peer graph: it connects to each known peer once, records the introduction of the peer,
+// its height and the number of peers it gives, then disconnects from it. The peers it gives are added to the
+// pex, and visited in turn. A nil *crawler is disabled.
+type crawler struct {
+	sync.Mutex
+	visitTimeout time.Duration
+	started      time.Time
+	queue        []string
+	// Known addresses, queued, being visited or visited
+	known    map[string]struct{}
+	visiting map[string]*crawlVisit
+	visited  map[string]CrawledNode
+}
+
+// newCrawler creates a crawler whose visits time out after visitTimeout. Returns nil if not enabled
+func newCrawler(enabled bool, visitTimeout time.Duration, now time.Time) *crawler {
+	if !enabled {
+		return nil
+	}
+
+	return &crawler{
+		visitTimeout: visitTimeout,
+		started:      now,
+		known:        make(map[string]struct{}),
+		visiting:     make(map[string]*crawlVisit),
+		visited:      make(map[string]CrawledNode),
+	}
+}
+
+// add queues the addresses that are not known yet
+func (cr *crawler) add(addrs []string) {
+	if cr == nil {
+		return
+	}
+
+	cr.Lock()
+	defer cr.Unlock()
+
+	for _, addr := range addrs {
+		if _, ok := cr.known[addr]; ok {
+			continue
+		}
+		cr.known[addr] = struct{}{}
+		cr.queue = append(cr.queue, addr)
+	}
+}
+
+// next removes up to n addresses from the queue, to be visited with visit.
+// The addresses are visited in the order they were learned
+func (cr *crawler) next(n int) []string {
+	if cr == nil || n <= 0 {
+		return nil
+	}
+
+	cr.Lock()
+	defer cr.Unlock()
+
+	if n > len(cr.queue) {
+		n = len(cr.queue)
+	}
+
+	addrs := cr.queue[:n:n]
+	cr.queue = cr.queue[n:]
+	return addrs
+}
+
+// visit records that a connection to addr is being made
+func (cr *crawler) visit(addr string, now time.Time) {
+	if cr == nil {
+		return
+	}
+
+	cr.Lock()
+	defer cr.Unlock()
+
+	cr.visiting[addr] = &crawlVisit{
+		node: CrawledNode{
+			Addr:    addr,
+			Visited: now,
+		},
+	}
+}
+
+// introduced records the introduction of a visited node
+func (cr *crawler) introduced(addr string, protocolVersion int32, userAgent useragent.Data, now time.Time) {
+	if cr == nil {
+		return
+	}
+
+	cr.Lock()
+	defer cr.Unlock()
+
+	v, ok := cr.visiting[addr]
+	if !ok {
+		return
+	}
+
+	v.introduced = now
+	v.node.Reachable = true
+	v.node.ProtocolVersion = protocolVersion
+	v.node.UserAgent = userAgent
+}
+
+// heightReceived records the head block seq of a visited node
+func (cr *crawler) heightReceived(addr string, height uint64) {
+	if cr == nil {
+		return
+	}
+
+	cr.Lock()
+	defer cr.Unlock()
+
+	if v, ok := cr.visiting[addr]; ok {
+		v.node.Height = height
+		v.node.HeightKnown = true
+	}
+}
+
+// peersReceived records the number of peers given by a visited node
+func (cr *crawler) peersReceived(addr string, n int) {
+	if cr == nil {
+		return
+	}
+
+	cr.Lock()
+	defer cr.Unlock()
+
+	if v, ok := cr.visiting[addr]; ok {
+		v.node.PeersGiven += n
+		v.peers = true
+	}
+}
+
+// finished returns the addresses of the visits that are complete or timed out, which should be disconnected.
+// An address is only returned once
+func (cr *crawler) finished(now time.Time) []string {
+	if cr == nil {
+		return nil
+	}
+
+	cr.Lock()
+	defer cr.Unlock()
+
+	var addrs []string
+	for addr, v := range cr.visiting {
+		if v.finishing || v.introduced.IsZero() {
+			continue
+		}
+
+		if (v.node.HeightKnown && v.peers) || now.Sub(v.introduced) >= cr.visitTimeout {
+			v.finishing = true
+			addrs = append(addrs, addr)
+		}
+	}
+
+	sort.Strings(addrs)
+	return addrs
+}
+
+// disconnected ends the visit of addr. reason is recorded if the node did not complete the introduction
+func (cr *crawler) disconnected(addr, reason string) {
+	if cr == nil {
+		return
+	}
+
+	cr.Lock()
+	defer cr.Unlock()
+
+	v, ok := cr.visiting[addr]
+	if !ok {
+		return
+	}
+
+	if !v.node.Reachable {
+		v.node.Error = reason
+	}
+
+	delete(cr.visiting, addr)
+	cr.visited[addr] = v.node
+}
+
+// report returns the CrawlReport of the nodes visited so far
+func (cr *crawler) report(now time.Time) CrawlReport {
+	if cr == nil {
+		return CrawlReport{}
+	}
+
+	cr.Lock()
+	defer cr.Unlock()
+
+	r := CrawlReport{
+		Started:          cr.started,
+		Updated:          now,
+		Queued:           len(cr.queue),
+		Visiting:         len(cr.visiting),
+		Visited:          len(cr.visited),
+		ProtocolVersions: make(map[int32]int),
+		UserAgents:       make(map[string]int),
+		Nodes:            make([]CrawledNode, 0, len(cr.visited)),
+	}
+
+	for _, n := range cr.visited {
+		r.Nodes = append(r.Nodes, n)
+		if !n.Reachable {
+			continue
+		}
+
+		r.Reachable++
+		r.ProtocolVersions[n.ProtocolVersion]++
+
+		userAgent, err := n.UserAgent.Build()
+		if err != nil {
+			userAgent = "unknown"
+		}
+		r.UserAgents[userAgent]++
+
+		if n.HeightKnown && n.Height > r.MaxHeight {
+			r.MaxHeight = n.Height
+		}
+	}
+
+	sort.Slice(r.Nodes, func(i, j int) bool {
+		return r.Nodes[i].Addr < r.Nodes[j].Addr
+	})
+
+	return r
+}
@@ -0,0 +1,120 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/util/useragent"
+)
+
+func TestCrawler(t *testing.T) {
+	start := time.Now().UTC()
+	timeout := time.Second * 15
+
+	// A nil crawler is disabled
+	var cr *crawler
+	require.Nil(t, newCrawler(false, timeout, start))
+	cr.add([]string{"1.1.1.1:6000"})
+	require.Empty(t, cr.next(8))
+	require.Empty(t, cr.finished(start))
+	require.Equal(t, CrawlReport{}, cr.report(start))
+
+	cr = newCrawler(true, timeout, start)
+
+	// The addresses are queued once, in the order they are learned
+	cr.add([]string{"1.1.1.1:6000", "2.2.2.2:6000"})
+	cr.add([]string{"2.2.2.2:6000", "3.3.3.3:6000", "4.4.4.4:6000"})
+	require.Equal(t, []string{"1.1.1.1:6000", "2.2.2.2:6000", "3.3.3.3:6000"}, cr.next(3))
+	require.Equal(t, []string{"4.4.4.4:6000"}, cr.next(3))
+	require.Empty(t, cr.next(3))
+	require.Empty(t, cr.next(0))
+
+	for _, addr := range []string{"1.1.1.1:6000", "2.2.2.2:6000", "3.3.3.3:6000", "4.4.4.4:6000"} {
+		cr.visit(addr, start)
+	}
+
+	r := cr.report(start)
+	require.Equal(t, 4, r.Visiting)
+	require.Equal(t, 0, r.Visited)
+
+	ua := useragent.Data{
+		Coin:    "skycoin",
+		Version: "0.25.0",
+	}
+
+	// A node that sent its height and its peers is finished
+	cr.introduced("1.1.1.1:6000", 3, ua, start)
+	cr.heightReceived("1.1.1.1:6000", 100)
+	require.Empty(t, cr.finished(start))
+	cr.peersReceived("1.1.1.1:6000", 30)
+	require.Equal(t, []string{"1.1.1.1:6000"}, cr.finished(start))
+	require.Empty(t, cr.finished(start))
+
+	// A node that did not send its peers is finished after the timeout
+	cr.introduced("2.2.2.2:6000", 2, useragent.Data{}, start)
+	cr.heightReceived("2.2.2.2:6000", 90)
+	require.Empty(t, cr.finished(start.Add(timeout-time.Second)))
+	require.Equal(t, []string{"2.2.2.2:6000"}, cr.finished(start.Add(timeout)))
+
+	// The nodes that did not introduce are not finished by the crawler, their connection is culled by the daemon
+	require.Empty(t, cr.finished(start.Add(timeout*10)))
+
+	// Nodes that are not visited are ignored
+	cr.introduced("5.5.5.5:6000", 3, ua, start)
+	cr.heightReceived("5.5.5.5:6000", 1000)
+	cr.peersReceived("5.5.5.5:6000", 10)
+	cr.disconnected("5.5.5.5:6000", "EOF")
+
+	cr.disconnected("1.1.1.1:6000", ErrDisconnectCrawled.Error())
+	cr.disconnected("2.2.2.2:6000", ErrDisconnectCrawled.Error())
+	cr.disconnected("3.3.3.3:6000", "connect: connection refused")
+	cr.introduced("4.4.4.4:6000", 3, ua, start)
+
+	cr.add([]string{"1.1.1.1:6000", "5.5.5.5:6000"})
+
+	now := start.Add(time.Minute)
+	require.Equal(t, CrawlReport{
+		Started:   start,
+		Updated:   now,
+		Queued:    1,
+		Visiting:  1,
+		Visited:   3,
+		Reachable: 2,
+		MaxHeight: 100,
+		ProtocolVersions: map[int32]int{
+			2: 1,
+			3: 1,
+		},
+		UserAgents: map[string]int{
+			"skycoin:0.25.0": 1,
+			"unknown":        1,
+		},
+		Nodes: []CrawledNode{
+			{
+				Addr:            "1.1.1.1:6000",
+				Reachable:       true,
+				ProtocolVersion: 3,
+				UserAgent:       ua,
+				Height:          100,
+				HeightKnown:     true,
+				PeersGiven:      30,
+				Visited:         start,
+			},
+			{
+				Addr:            "2.2.2.2:6000",
+				Reachable:       true,
+				ProtocolVersion: 2,
+				Height:          90,
+				HeightKnown:     true,
+				Visited:         start,
+			},
+			{
+				Addr:    "3.3.3.3:6000",
+				Visited: start,
+				Error:   "connect: connection refused",
+			},
+		},
+	}, cr.report(now))
+}
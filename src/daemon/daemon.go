@@ -4,9 +4,12 @@ Package daemon controls the networking layer of the skycoin daemon
 package daemon
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math/rand"
+	"net"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"sync"
@@ -20,6 +23,7 @@ import (
 	"github.com/skycoin/skycoin/src/daemon/pex"
 	"github.com/skycoin/skycoin/src/params"
 	"github.com/skycoin/skycoin/src/util/elapse"
+	"github.com/skycoin/skycoin/src/util/file"
 	"github.com/skycoin/skycoin/src/util/iputil"
 	"github.com/skycoin/skycoin/src/util/logging"
 	"github.com/skycoin/skycoin/src/util/useragent"
@@ -30,6 +34,9 @@ import (
 var (
 	// ErrNetworkingDisabled is returned if networking is disabled
 	ErrNetworkingDisabled = errors.New("Networking is disabled")
+	// ErrBlockTooFarInFuture is returned if a block received from a peer is timestamped too far in the future
+	// of the network-adjusted time
+	ErrBlockTooFarInFuture = errors.New("Block time is too far in the future of the network-adjusted time")
 
 	logger = logging.MustGetLogger("daemon")
 )
@@ -50,6 +57,12 @@ func IsBroadcastFailure(err error) bool {
 
 const (
 	daemonRunDurationThreshold = time.Millisecond * 200
+
+	// ipv6PeersProtocolVersion is the protocol version from which peers accept GiveIPv6PeersMessage
+	ipv6PeersProtocolVersion int32 = 3
+	// compactBlocksProtocolVersion is the protocol version from which peers accept CompactBlockMessage,
+	// GetBlockTxnsMessage and GiveBlockTxnsMessage. Peers that advertise their features use FeatureCompactBlocks instead
+	compactBlocksProtocolVersion int32 = 4
 )
 
 // Config subsystem configurations
@@ -93,6 +106,52 @@ func (cfg *Config) preprocess() (Config, error) {
 	}
 	config.Pool.port = config.Daemon.Port
 	config.Pool.address = config.Daemon.Address
+	config.Pex.DNSSeedPort = config.Daemon.Port
+
+	if config.Daemon.ProxyAddress != "" {
+		if config.Daemon.LocalhostOnly {
+			return Config{}, errors.New("ProxyAddress cannot be used with LocalhostOnly")
+		}
+
+		if _, _, err := iputil.SplitAddr(config.Daemon.ProxyAddress); err != nil {
+			return Config{}, fmt.Errorf("Invalid ProxyAddress %q: %v", config.Daemon.ProxyAddress, err)
+		}
+
+		logger.WithField("proxy", config.Daemon.ProxyAddress).Info("Outgoing connections are made through a SOCKS5 proxy")
+		config.Pex.AllowOnion = true
+		config.Pex.ProxyAddress = config.Daemon.ProxyAddress
+	}
+	config.Pool.proxyAddress = config.Daemon.ProxyAddress
+
+	if config.Daemon.DisableIPv4 && config.Daemon.DisableIPv6 {
+		return Config{}, errors.New("DisableIPv4 and DisableIPv6 cannot both be set")
+	}
+
+	switch config.Daemon.PreferIPFamily {
+	case "":
+	case pex.IPFamilyIPv4:
+		if config.Daemon.DisableIPv4 {
+			return Config{}, errors.New("PreferIPFamily cannot be a disabled IP family")
+		}
+	case pex.IPFamilyIPv6:
+		if config.Daemon.DisableIPv6 {
+			return Config{}, errors.New("PreferIPFamily cannot be a disabled IP family")
+		}
+	default:
+		return Config{}, fmt.Errorf("Invalid PreferIPFamily %q", config.Daemon.PreferIPFamily)
+	}
+
+	if ip := net.ParseIP(config.Daemon.Address); ip != nil {
+		if isIPv4 := ip.To4() != nil; (isIPv4 && config.Daemon.DisableIPv4) || (!isIPv4 && config.Daemon.DisableIPv6) {
+			return Config{}, errors.New("Address is of a disabled IP family")
+		}
+	}
+
+	config.Pex.DisableIPv4 = config.Daemon.DisableIPv4
+	config.Pex.DisableIPv6 = config.Daemon.DisableIPv6
+	config.Pex.PreferIPFamily = config.Daemon.PreferIPFamily
+	config.Pool.disableIPv4 = config.Daemon.DisableIPv4
+	config.Pool.disableIPv6 = config.Daemon.DisableIPv6
 
 	if config.Daemon.DisableNetworking {
 		logger.Info("Networking is disabled")
@@ -112,6 +171,47 @@ func (cfg *Config) preprocess() (Config, error) {
 		return Config{}, errors.New("MaxOutgoingConnections cannot be more than MaxConnections")
 	}
 
+	if config.Daemon.BanScoreThreshold < 0 {
+		return Config{}, errors.New("BanScoreThreshold cannot be negative")
+	}
+
+	if config.Daemon.BanScoreThreshold > 0 && (config.Daemon.BanScoreWindow <= 0 || config.Daemon.BanDuration <= 0) {
+		return Config{}, errors.New("BanScoreWindow and BanDuration must be positive when BanScoreThreshold is enabled")
+	}
+
+	if !config.Daemon.DisableHeadersFirstSync && config.Daemon.HeadersSyncRate <= 0 {
+		return Config{}, errors.New("HeadersSyncRate must be positive when the header-first synchronization is enabled")
+	}
+
+	if config.Daemon.AnnounceBurst < 0 {
+		return Config{}, errors.New("AnnounceBurst cannot be negative")
+	}
+
+	if config.Daemon.TxnRequestTimeout <= 0 || config.Daemon.RequestRetryRate <= 0 {
+		return Config{}, errors.New("TxnRequestTimeout and RequestRetryRate must be positive")
+	}
+
+	if config.Daemon.TxnRequestRetries < 0 || config.Daemon.RequestStallThreshold < 0 {
+		return Config{}, errors.New("TxnRequestRetries and RequestStallThreshold cannot be negative")
+	}
+
+	if config.Daemon.RelayMaxOutputs < 0 {
+		return Config{}, errors.New("RelayMaxOutputs cannot be negative")
+	}
+
+	if config.Daemon.AnnounceBurst > 0 && config.Daemon.AnnounceThrottleRate <= 0 {
+		return Config{}, errors.New("AnnounceThrottleRate must be positive when AnnounceBurst is enabled")
+	}
+
+	if config.Pool.CompressionThreshold < 0 {
+		return Config{}, errors.New("CompressionThreshold cannot be negative")
+	}
+
+	if config.Pool.UploadRateLimit < 0 || config.Pool.DownloadRateLimit < 0 ||
+		config.Pool.PeerUploadRateLimit < 0 || config.Pool.PeerDownloadRateLimit < 0 {
+		return Config{}, errors.New("Rate limits cannot be negative")
+	}
+
 	if config.Daemon.MaxPendingConnections > config.Daemon.MaxOutgoingConnections {
 		config.Daemon.MaxPendingConnections = config.Daemon.MaxOutgoingConnections
 	}
@@ -119,6 +219,50 @@ func (cfg *Config) preprocess() (Config, error) {
 	config.Pool.MaxConnections = config.Daemon.MaxConnections
 	config.Pool.MaxOutgoingConnections = config.Daemon.MaxOutgoingConnections
 
+	if config.Daemon.MaxWhitelistedConnections < 0 {
+		return Config{}, errors.New("MaxWhitelistedConnections cannot be negative")
+	}
+
+	whitelist := make([]string, len(config.Daemon.Whitelist))
+	for i, ip := range config.Daemon.Whitelist {
+		parsedIP := net.ParseIP(ip)
+		if parsedIP == nil {
+			return Config{}, fmt.Errorf("Invalid whitelisted IP %q", ip)
+		}
+		whitelist[i] = parsedIP.String()
+	}
+	config.Daemon.Whitelist = whitelist
+	config.Pool.whitelist = whitelist
+	config.Pool.maxWhitelistedConnections = config.Daemon.MaxWhitelistedConnections
+	config.Pool.encryptedTransport = config.Daemon.EncryptedTransport
+	config.Pool.transportKey = config.Daemon.TransportKey
+
+	for a, pk := range config.Daemon.PeerKeys {
+		if _, _, err := iputil.SplitAddr(a); err != nil {
+			return Config{}, fmt.Errorf("Invalid PeerKeys address %q: %v", a, err)
+		}
+		if err := pk.Verify(); err != nil {
+			return Config{}, fmt.Errorf("Invalid PeerKeys key of %q: %v", a, err)
+		}
+	}
+
+	listeners := make([]gnet.ListenerConfig, len(config.Daemon.ListenAddresses))
+	for i, a := range config.Daemon.ListenAddresses {
+		l, err := parseListenAddress(a)
+		if err != nil {
+			return Config{}, fmt.Errorf("Invalid ListenAddresses %q: %v", a, err)
+		}
+
+		if ip := net.ParseIP(l.Address); ip != nil {
+			if isIPv4 := ip.To4() != nil; (isIPv4 && config.Daemon.DisableIPv4) || (!isIPv4 && config.Daemon.DisableIPv6) {
+				return Config{}, fmt.Errorf("ListenAddresses %q is of a disabled IP family", a)
+			}
+		}
+
+		listeners[i] = l
+	}
+	config.Pool.listeners = listeners
+
 	userAgent, err := config.Daemon.UserAgent.Build()
 	if err != nil {
 		return Config{}, err
@@ -128,6 +272,84 @@ func (cfg *Config) preprocess() (Config, error) {
 	}
 	config.Daemon.userAgent = userAgent
 
+	config.Daemon.pruned = config.Visor.PruneDepth != 0
+	config.Pool.transportFeatures = config.Daemon.features()
+
+	if config.Daemon.BlockPublisherFailoverTimeout < 0 {
+		return Config{}, errors.New("BlockPublisherFailoverTimeout cannot be negative")
+	}
+
+	if config.Daemon.BlockPublisherStandby {
+		if !config.Visor.IsBlockPublisher {
+			return Config{}, errors.New("BlockPublisherStandby requires a block publisher")
+		}
+		if config.Daemon.BlockPublisherFailoverTimeout == 0 {
+			return Config{}, errors.New("BlockPublisherStandby requires a BlockPublisherFailoverTimeout")
+		}
+	}
+
+	if config.Daemon.ClockSkewWarnThreshold <= 0 {
+		return Config{}, errors.New("ClockSkewWarnThreshold must be positive")
+	}
+
+	if config.Daemon.MaxNetworkTimeAdjustment < 0 || config.Daemon.MaxBlockTimeDrift < 0 {
+		return Config{}, errors.New("MaxNetworkTimeAdjustment and MaxBlockTimeDrift cannot be negative")
+	}
+
+	if config.Daemon.RebroadcastInterval < 0 {
+		return Config{}, errors.New("RebroadcastInterval cannot be negative")
+	}
+
+	if config.Daemon.RebroadcastInterval > 0 {
+		if config.Daemon.RebroadcastMaxInterval < config.Daemon.RebroadcastInterval {
+			return Config{}, errors.New("RebroadcastMaxInterval cannot be less than RebroadcastInterval")
+		}
+		if config.Daemon.RebroadcastExpiry <= 0 || config.Daemon.RebroadcastCheckRate <= 0 {
+			return Config{}, errors.New("RebroadcastExpiry and RebroadcastCheckRate must be positive")
+		}
+	}
+
+	if config.Daemon.IncomingIntroductionWait <= 0 {
+		return Config{}, errors.New("IncomingIntroductionWait must be positive")
+	}
+
+	if config.Daemon.MaxIncomingPerIP < 0 {
+		return Config{}, errors.New("MaxIncomingPerIP cannot be negative")
+	}
+
+	if config.Daemon.MaxIncomingPerNetgroup < 0 {
+		return Config{}, errors.New("MaxIncomingPerNetgroup cannot be negative")
+	}
+
+	if config.Daemon.BlockFanoutWaveSize < 0 {
+		return Config{}, errors.New("BlockFanoutWaveSize cannot be negative")
+	}
+
+	if config.Daemon.BlockFanoutWaveSize != 0 && (config.Daemon.BlockFanoutStagger <= 0 || config.Daemon.BlockFanoutPingRate <= 0) {
+		return Config{}, errors.New("BlockFanoutStagger and BlockFanoutPingRate must be positive")
+	}
+
+	if config.Daemon.Crawler {
+		if config.Daemon.DisableNetworking || config.Daemon.DisableOutgoingConnections {
+			return Config{}, errors.New("Crawler requires outgoing connections")
+		}
+		if config.Pex.Disabled {
+			return Config{}, errors.New("Crawler requires the peer exchange")
+		}
+		if config.Visor.IsBlockPublisher {
+			return Config{}, errors.New("Crawler cannot be a block publisher")
+		}
+		if config.Daemon.CrawlerVisitTimeout <= 0 {
+			return Config{}, errors.New("CrawlerVisitTimeout must be positive")
+		}
+		if config.Daemon.CrawlerReportRate <= 0 {
+			return Config{}, errors.New("CrawlerReportRate must be positive")
+		}
+		if config.Daemon.CrawlerReportFile == "" {
+			return Config{}, errors.New("Crawler requires a CrawlerReportFile")
+		}
+	}
+
 	return config, nil
 }
 
@@ -159,12 +381,30 @@ type DaemonConfig struct { // nolint: golint
 	MaxPendingConnections int
 	// How long to wait for a version packet
 	IntroductionWait time.Duration
+	// How long to wait for a version packet on incoming connections, which are cheap to open for an attacker
+	IncomingIntroductionWait time.Duration
 	// How often to check for peers that have decided to stop communicating
 	CullInvalidRate time.Duration
 	// How often to update the database with transaction announcement timestamps
 	FlushAnnouncedTxnsRate time.Duration
 	// How many connections are allowed from the same base IP
 	IPCountsMax int
+	// How many incoming connections are allowed from the same IP. 0 for no limit
+	MaxIncomingPerIP int
+	// How many incoming connections are allowed from the same netgroup, e.g. an IPv4 /16. 0 for no limit
+	MaxIncomingPerNetgroup int
+	// Reject new incoming connections when the incoming slots are full, instead of evicting the worst incoming peer
+	DisableIncomingEviction bool
+	// IPs of whitelisted peers. Their incoming connections are accepted in MaxWhitelistedConnections reserved slots
+	// when MaxConnections is reached, their traffic is not rate limited, they are not limited by IPCountsMax,
+	// and they are never banned or removed from the peers list
+	Whitelist []string
+	// Number of incoming connection slots reserved for whitelisted peers, in excess of MaxConnections
+	MaxWhitelistedConnections int
+	// Additional addresses to listen on, as "ip:port" or "ip:port/max", e.g. "127.0.0.1:6001".
+	// The incoming connections accepted on each address are limited to its own max instead of MaxConnections.
+	// Without a max or with a max of 0, the address accepts any number of connections, which are not limited by IPCountsMax
+	ListenAddresses []string
 	// Disable all networking activity
 	DisableNetworking bool
 	// Don't make outgoing connections
@@ -173,22 +413,94 @@ type DaemonConfig struct { // nolint: golint
 	DisableIncomingConnections bool
 	// Run on localhost and only connect to localhost peers
 	LocalhostOnly bool
+	// Make outgoing connections through this SOCKS5 proxy, e.g. the SOCKS port of Tor.
+	// Hostnames are resolved by the proxy and .onion peers are allowed. Leave empty to connect directly
+	ProxyAddress string
+	// Don't listen on or connect to IPv4 addresses
+	DisableIPv4 bool
+	// Don't listen on or connect to IPv6 addresses
+	DisableIPv6 bool
+	// Connect to the peers of this IP family first, "ipv4" or "ipv6". Empty for no preference
+	PreferIPFamily pex.IPFamily
 	// Log ping and pong messages
 	LogPings bool
 	// How often to request blocks from peers
 	BlocksRequestRate time.Duration
+	// Don't download the blocks header-first from the peers that support it, request them with GetBlocksMessage instead
+	DisableHeadersFirstSync bool
+	// How often the header-first synchronization requests more headers and bodies from peers
+	HeadersSyncRate time.Duration
 	// How often to announce our blocks to peers
 	BlocksAnnounceRate time.Duration
 	// How many blocks to respond with to a GetBlocksMessage
 	BlocksResponseCount uint64
+	// How often to request the corrupted blocks of the database from peers, until they are repaired
+	BlockRepairRequestRate time.Duration
 	// Max announce txns hash number
 	MaxTxnAnnounceNum int
+	// How long a peer has to send the transactions requested from it, before they are requested from
+	// another peer that announced them
+	TxnRequestTimeout time.Duration
+	// How many times a transaction is requested from another peer after a request timed out
+	TxnRequestRetries int
+	// How often to check for timed out transaction requests
+	RequestRetryRate time.Duration
+	// Number of consecutive requests a peer must leave unanswered to be penalized for stalling. 0 disables the penalty
+	RequestStallThreshold int
+	// Maximum number of block and transaction announcements broadcast at once. Announcements over the burst
+	// are coalesced and broadcast at AnnounceThrottleRate. 0 disables the throttling
+	AnnounceBurst int
+	// How often one throttled announcement can be broadcast once the burst is used up
+	AnnounceThrottleRate time.Duration
+	// Don't relay new blocks as compact blocks, nor accept them from peers
+	DisableCompactBlocks bool
+	// Don't request, accept or relay the transactions of peers, and ask peers not to relay transactions.
+	// Transactions created by this node are still broadcast
+	DisableTxnRelay bool
+	pruned          bool // set from the visor's PruneDepth in preprocess()
+	// Accept the encrypted transport on incoming connections, and use it for the outgoing connections
+	// to peers that advertised it
+	EncryptedTransport bool
+	// Static key that authenticates the node in the encrypted transport handshake. If it is not set,
+	// the key is loaded from TransportKeyFilename in DataDirectory, and created there the first time
+	TransportKey cipher.SecKey
+	// Encrypted transport keys of peers, by address. The connections to these peers are always encrypted,
+	// and are closed if the peer authenticates with another key. The keys of the other peers are learned
+	// on the first encrypted outgoing connection to them, and kept in the peer list
+	PeerKeys map[string]cipher.PubKey
+	// Don't compress the messages sent to peers, nor ask peers to compress their messages
+	DisableCompression bool
+	// Number of peers with the lowest latency that a new block is sent to at once. The block is sent to the other
+	// peers in waves of the same size, staggered by BlockFanoutStagger, unless they have it by then.
+	// 0 sends the new blocks to all peers at once
+	BlockFanoutWaveSize int
+	// Delay between the waves of peers that a new block is sent to
+	BlockFanoutStagger time.Duration
+	// How often the latency of the peers is measured, for the block fan-out
+	BlockFanoutPingRate time.Duration
 	// How often new blocks are created by the signing node, in seconds
 	BlockCreationInterval uint64
+	// Start as a standby block publisher, which only creates blocks after the active block publisher,
+	// configured with the same blockchain secret key, was silent for BlockPublisherFailoverTimeout
+	BlockPublisherStandby bool
+	// How long the active block publisher must be silent while transactions are pending before a passive
+	// block publisher takes over block creation. 0 disables the failover
+	BlockPublisherFailoverTimeout time.Duration
 	// How often to check the unconfirmed pool for transactions that become valid
 	UnconfirmedRefreshRate time.Duration
 	// How often to remove transactions that become permanently invalid from the unconfirmed pool
 	UnconfirmedRemoveInvalidRate time.Duration
+	// How often to evict the transactions that exceed the max age or the max size of the unconfirmed pool
+	UnconfirmedEvictRate time.Duration
+	// Delay before the first rebroadcast of a transaction injected by this node, if it is not confirmed by then.
+	// The delay doubles after each rebroadcast, up to RebroadcastMaxInterval. 0 disables the rebroadcasts
+	RebroadcastInterval time.Duration
+	// Maximum delay between the rebroadcasts of a transaction
+	RebroadcastMaxInterval time.Duration
+	// How long after it is injected a transaction is rebroadcast
+	RebroadcastExpiry time.Duration
+	// How often to check for the transactions to rebroadcast
+	RebroadcastCheckRate time.Duration
 	// Default "trusted" peers
 	DefaultConnections []string
 	// User agent (sent in introduction messages)
@@ -198,14 +510,45 @@ type DaemonConfig struct { // nolint: golint
 	UnconfirmedBurnFactor uint32
 	// Max transaction size applied to unconfirmed txns
 	UnconfirmedMaxTransactionSize uint32
+	// Minimum coinhour burn factor of the transactions accepted from peers. 0 disables the check
+	RelayMinBurnFactor uint32
+	// Maximum size of the transactions accepted from peers. 0 disables the check
+	RelayMaxTransactionSize uint32
+	// Maximum number of outputs of the transactions accepted from peers. 0 disables the check
+	RelayMaxOutputs int
+	// Minimum number of droplets of the outputs of the transactions accepted from peers. 0 disables the check
+	RelayDustThreshold uint64
 	// Random nonce value for detecting self-connection in introduction messages
 	Mirror uint32
+	// Ban score at which a misbehaving peer is banned. 0 disables automatic bans
+	BanScoreThreshold int
+	// How long the misbehavior of a peer counts toward its ban score
+	BanScoreWindow time.Duration
+	// How long misbehaving peers are banned for
+	BanDuration time.Duration
+	// Offset of the local clock from the median clock of the peers at which the node warns that its clock is wrong
+	ClockSkewWarnThreshold time.Duration
+	// Maximum adjustment of the local clock to the median clock of the peers for the network-adjusted time.
+	// A larger offset is not applied, since the peers are as likely to be wrong as the local clock
+	MaxNetworkTimeAdjustment time.Duration
+	// How far in the future of the network-adjusted time the blocks received from peers can be timestamped.
+	// 0 disables the check
+	MaxBlockTimeDrift time.Duration
+	// Walk the peer graph instead of synchronizing the blockchain: connect to each known peer once,
+	// record its version, user agent, height and number of peers, and write them to CrawlerReportFile
+	Crawler bool
+	// How long a peer visited by the crawler has to send its height and its peers before it is disconnected
+	CrawlerVisitTimeout time.Duration
+	// File the crawl report is written to, as JSON
+	CrawlerReportFile string
+	// How often the crawl report is written
+	CrawlerReportRate time.Duration
 }
 
 // NewDaemonConfig creates daemon config
 func NewDaemonConfig() DaemonConfig {
 	return DaemonConfig{
-		ProtocolVersion:               2,
+		ProtocolVersion:               4,
 		MinProtocolVersion:            2,
 		Address:                       "",
 		Port:                          6677,
@@ -216,25 +559,92 @@ func NewDaemonConfig() DaemonConfig {
 		MaxOutgoingConnections:        8,
 		MaxPendingConnections:         8,
 		IntroductionWait:              time.Second * 30,
+		IncomingIntroductionWait:      time.Second * 10,
 		CullInvalidRate:               time.Second * 3,
 		FlushAnnouncedTxnsRate:        time.Second * 3,
 		IPCountsMax:                   3,
+		MaxIncomingPerIP:              2,
+		MaxIncomingPerNetgroup:        8,
+		MaxWhitelistedConnections:     8,
 		DisableNetworking:             false,
 		DisableOutgoingConnections:    false,
 		DisableIncomingConnections:    false,
 		LocalhostOnly:                 false,
 		LogPings:                      true,
 		BlocksRequestRate:             time.Second * 60,
+		HeadersSyncRate:               time.Second,
 		BlocksAnnounceRate:            time.Second * 60,
 		BlocksResponseCount:           20,
+		BlockRepairRequestRate:        time.Second * 10,
 		MaxTxnAnnounceNum:             16,
+		TxnRequestTimeout:             time.Second * 20,
+		TxnRequestRetries:             3,
+		RequestRetryRate:              time.Second,
+		RequestStallThreshold:         3,
+		AnnounceBurst:                 32,
+		AnnounceThrottleRate:          time.Millisecond * 500,
+		BlockFanoutWaveSize:           4,
+		BlockFanoutStagger:            time.Millisecond * 250,
+		BlockFanoutPingRate:           time.Minute,
 		BlockCreationInterval:         10,
 		UnconfirmedRefreshRate:        time.Minute,
 		UnconfirmedRemoveInvalidRate:  time.Minute,
+		UnconfirmedEvictRate:          time.Minute,
+		RebroadcastInterval:           time.Minute * 5,
+		RebroadcastMaxInterval:        time.Hour * 2,
+		RebroadcastExpiry:             time.Hour * 72,
+		RebroadcastCheckRate:          time.Second * 30,
 		Mirror:                        rand.New(rand.NewSource(time.Now().UTC().UnixNano())).Uint32(),
 		UnconfirmedBurnFactor:         params.UserBurnFactor,
 		UnconfirmedMaxTransactionSize: params.UserMaxTransactionSize,
+		BanScoreThreshold:             100,
+		BanScoreWindow:                time.Hour,
+		ClockSkewWarnThreshold:        time.Minute * 5,
+		MaxNetworkTimeAdjustment:      time.Minute * 70,
+		MaxBlockTimeDrift:             time.Hour * 2,
+		BanDuration:                   time.Hour * 24,
+		DisableCompactBlocks:          false,
+		DisableTxnRelay:               false,
+		Crawler:                       false,
+		CrawlerVisitTimeout:           time.Second * 15,
+		CrawlerReportRate:             time.Minute,
+	}
+}
+
+// relayPolicy returns the policy applied to the transactions received from peers.
+// DisableTxnRelay is the blocks-only mode
+func (c DaemonConfig) relayPolicy() RelayPolicy {
+	return RelayPolicy{
+		BlocksOnly:         c.DisableTxnRelay,
+		MinBurnFactor:      c.RelayMinBurnFactor,
+		MaxTransactionSize: c.RelayMaxTransactionSize,
+		MaxOutputs:         c.RelayMaxOutputs,
+		DustThreshold:      c.RelayDustThreshold,
+	}
+}
+
+// features returns the features advertised to peers in the IntroductionMessage
+func (c DaemonConfig) features() PeerFeatures {
+	var f PeerFeatures
+	if !c.DisableCompactBlocks {
+		f |= FeatureCompactBlocks
+	}
+	if !c.DisableHeadersFirstSync {
+		f |= FeatureHeadersFirst
 	}
+	if c.DisableTxnRelay {
+		f |= FeatureNoTxnRelay
+	}
+	if c.pruned {
+		f |= FeaturePruned
+	}
+	if c.EncryptedTransport {
+		f |= FeatureEncryptedTransport
+	}
+	if !c.DisableCompression {
+		f |= FeatureCompression
+	}
+	return f
 }
 
 //go:generate go install
@@ -246,11 +656,12 @@ type daemoner interface {
 	sendMessage(addr string, msg gnet.Message) error
 	broadcastMessage(msg gnet.Message) (int, error)
 	disconnectNow(addr string, r gnet.DisconnectReason) error
-	addPeers(addrs []string) int
+	addPeers(source string, addrs []string) int
 	recordPeerHeight(addr string, gnetID, height uint64)
 	getSignedBlocksSince(seq, count uint64) ([]coin.SignedBlock, error)
 	headBkSeq() (uint64, bool, error)
-	executeSignedBlock(b coin.SignedBlock) error
+	executeSignedBlocks(blocks []coin.SignedBlock) (int, error)
+	repairBlocks(blocks []coin.SignedBlock) (int, error)
 	filterKnownUnconfirmed(txns []cipher.SHA256) ([]cipher.SHA256, error)
 	getKnownUnconfirmed(txns []cipher.SHA256) (coin.Transactions, error)
 	requestBlocksFromAddr(addr string) error
@@ -258,9 +669,23 @@ type daemoner interface {
 	daemonConfig() DaemonConfig
 	pexConfig() pex.Config
 	injectTransaction(txn coin.Transaction) (bool, *visor.ErrTxnViolatesSoftConstraint, error)
+	checkRelayPolicy(txn coin.Transaction) bool
+	scheduleTxnRequests(addr string, hashes []cipher.SHA256) []cipher.SHA256
+	txnsReceived(addr string, hashes []cipher.SHA256)
 	recordMessageEvent(m asyncMessage, c *gnet.MessageContext) error
 	connectionIntroduced(addr string, gnetID uint64, m *IntroductionMessage) (*connection, error)
 	sendRandomPeers(addr string) error
+	recordPeerMisbehavior(addr string, m PeerMisbehavior)
+	recordBlocksReceived(gnetID uint64)
+	getAllUnconfirmedTxns() (coin.Transactions, error)
+	getSignedBlockByHash(hash cipher.SHA256) (*coin.SignedBlock, error)
+	addPartialBlock(pb *partialBlock)
+	removePartialBlock(addr string, hash cipher.SHA256) *partialBlock
+	relayBlock(sb coin.SignedBlock, fromAddr string) error
+	getSignedBlockHeadersSince(seq, count uint64) ([]coin.SignedBlockHeader, error)
+	addSyncHeaders(addr string, headers []coin.SignedBlockHeader) error
+	addSyncBodies(addr string, bodies []HashedBlockBody) error
+	syncHeadersFirst() error
 }
 
 // Daemon stateful properties of the daemon
@@ -279,6 +704,32 @@ type Daemon struct {
 	announcedTxns *announcedTxnsCache
 	// Cache of connection metadata
 	connections *Connections
+	// Ban scores of peers
+	peerScores *peerScores
+	// Throttles the block and transaction announcements
+	announceThrottle *announceThrottle
+	// Counts of the transactions rejected by the relay policy
+	relayRejections *relayRejections
+	// Pending transaction requests to peers
+	requestScheduler *requestScheduler
+	// Compact blocks waiting for their missing transactions
+	partialBlocks *partialBlocks
+	// State of the header-first synchronization
+	headerSync *headerSync
+	// Subscribers of the connection lifecycle events
+	connectionEvents *connectionEvents
+	// Decides when a block publisher creates blocks, if a standby publisher can take over
+	publisherFailover *publisherFailover
+	// Peers visited in crawler mode
+	crawler *crawler
+	// Sends the new blocks to the peers by latency
+	blockFanout *blockFanout
+	// Clock offsets of the peers and network-adjusted time
+	networkTime *networkTime
+	// Rebroadcasts of the transactions injected by this node
+	rebroadcaster *rebroadcaster
+	// Clock set by a Simulation. The system clock is used if it is nil
+	clock func() time.Time
 	// connect, disconnect, message, error events channel
 	events chan interface{}
 	// quit channel
@@ -289,6 +740,14 @@ type Daemon struct {
 
 // NewDaemon returns a Daemon with primitives allocated
 func NewDaemon(config Config, db *dbutil.DB) (*Daemon, error) {
+	if config.Daemon.EncryptedTransport && config.Daemon.TransportKey.Null() && config.Daemon.DataDirectory != "" {
+		key, err := loadTransportKey(filepath.Join(config.Daemon.DataDirectory, TransportKeyFilename))
+		if err != nil {
+			return nil, err
+		}
+		config.Daemon.TransportKey = key
+	}
+
 	config, err := config.preprocess()
 	if err != nil {
 		return nil, err
@@ -310,11 +769,24 @@ func NewDaemon(config Config, db *dbutil.DB) (*Daemon, error) {
 		pex:      pex,
 		visor:    vs,
 
-		announcedTxns: newAnnouncedTxnsCache(),
-		connections:   NewConnections(),
-		events:        make(chan interface{}, config.Pool.EventChannelSize),
-		quit:          make(chan struct{}),
-		done:          make(chan struct{}),
+		announcedTxns:    newAnnouncedTxnsCache(),
+		connections:      NewConnections(),
+		peerScores:       newPeerScores(),
+		partialBlocks:    newPartialBlocks(),
+		relayRejections:  newRelayRejections(),
+		headerSync:       newHeaderSync(config.Daemon.BlockchainPubkey),
+		connectionEvents: newConnectionEvents(),
+		events:           make(chan interface{}, config.Pool.EventChannelSize),
+		quit:             make(chan struct{}),
+		done:             make(chan struct{}),
+
+		announceThrottle:  newAnnounceThrottle(config.Daemon.AnnounceBurst, config.Daemon.AnnounceThrottleRate),
+		requestScheduler:  newRequestScheduler(config.Daemon.TxnRequestTimeout, config.Daemon.TxnRequestRetries, config.Daemon.RequestStallThreshold),
+		publisherFailover: newPublisherFailover(config.Daemon.BlockPublisherStandby, config.Daemon.BlockPublisherFailoverTimeout, time.Now()),
+		blockFanout:       newBlockFanout(config.Daemon.BlockFanoutWaveSize, config.Daemon.BlockFanoutStagger, config.Daemon.BlockFanoutPingRate),
+		crawler:           newCrawler(config.Daemon.Crawler, config.Daemon.CrawlerVisitTimeout, time.Now().UTC()),
+		networkTime:       newNetworkTime(config.Daemon.ClockSkewWarnThreshold, config.Daemon.MaxNetworkTimeAdjustment),
+		rebroadcaster:     newRebroadcaster(config.Daemon.RebroadcastInterval, config.Daemon.RebroadcastMaxInterval, config.Daemon.RebroadcastExpiry),
 	}
 
 	d.pool, err = NewPool(config.Pool, d)
@@ -322,12 +794,24 @@ func NewDaemon(config Config, db *dbutil.DB) (*Daemon, error) {
 		return nil, err
 	}
 
+	if config.Daemon.EncryptedTransport {
+		logger.WithField("pubkey", d.pool.Pool.TransportPubKey().Hex()).Info("Encrypted transport key of the node")
+	}
+
 	d.Gateway = NewGateway(config.Gateway, d)
 	d.Messages.Config.Register()
 
 	return d, nil
 }
 
+// now returns the current time of the daemon's clock
+func (dm *Daemon) now() time.Time {
+	if dm.clock == nil {
+		return time.Now()
+	}
+	return dm.clock()
+}
+
 // ConnectEvent generated when a client connects
 type ConnectEvent struct {
 	GnetID    uint64
@@ -435,6 +919,8 @@ func (dm *Daemon) Run() error {
 	defer unconfirmedRefreshTicker.Stop()
 	unconfirmedRemoveInvalidTicker := time.NewTicker(dm.Config.UnconfirmedRemoveInvalidRate)
 	defer unconfirmedRemoveInvalidTicker.Stop()
+	unconfirmedEvictTicker := time.NewTicker(dm.Config.UnconfirmedEvictRate)
+	defer unconfirmedEvictTicker.Stop()
 	blocksRequestTicker := time.NewTicker(dm.Config.BlocksRequestRate)
 	defer blocksRequestTicker.Stop()
 	blocksAnnounceTicker := time.NewTicker(dm.Config.BlocksAnnounceRate)
@@ -469,6 +955,52 @@ func (dm *Daemon) Run() error {
 	flushAnnouncedTxnsTicker := time.NewTicker(dm.Config.FlushAnnouncedTxnsRate)
 	defer flushAnnouncedTxnsTicker.Stop()
 
+	requestRetryTicker := time.NewTicker(dm.Config.RequestRetryRate)
+	defer requestRetryTicker.Stop()
+
+	// headersSyncTicker drives the header-first synchronization. Its channel is nil if it is disabled
+	var headersSyncTickerC <-chan time.Time
+	if !dm.Config.DisableHeadersFirstSync && !dm.Config.Crawler {
+		headersSyncTicker := time.NewTicker(dm.Config.HeadersSyncRate)
+		defer headersSyncTicker.Stop()
+		headersSyncTickerC = headersSyncTicker.C
+	}
+
+	// blockFanoutTicker sends the queued blocks to the peers. Its channel is nil if the blocks are sent
+	// to all peers at once
+	var blockFanoutTickerC <-chan time.Time
+	if dm.blockFanout != nil {
+		blockFanoutTicker := time.NewTicker(dm.Config.BlockFanoutStagger)
+		defer blockFanoutTicker.Stop()
+		blockFanoutTickerC = blockFanoutTicker.C
+	}
+
+	// rebroadcastTicker rebroadcasts the unconfirmed transactions injected by this node.
+	// Its channel is nil if the rebroadcasts are disabled
+	var rebroadcastTickerC <-chan time.Time
+	if dm.rebroadcaster != nil {
+		rebroadcastTicker := time.NewTicker(dm.Config.RebroadcastCheckRate)
+		defer rebroadcastTicker.Stop()
+		rebroadcastTickerC = rebroadcastTicker.C
+	}
+
+	// crawlerReportTicker writes the crawl report. Its channel is nil if the crawler is disabled
+	var crawlerReportTickerC <-chan time.Time
+	if dm.crawler != nil {
+		crawlerReportTicker := time.NewTicker(dm.Config.CrawlerReportRate)
+		defer crawlerReportTicker.Stop()
+		crawlerReportTickerC = crawlerReportTicker.C
+	}
+
+	// announceThrottleTicker broadcasts the throttled announcements. Its channel is nil if announcements
+	// are not throttled
+	var announceThrottleTickerC <-chan time.Time
+	if dm.announceThrottle != nil {
+		announceThrottleTicker := time.NewTicker(dm.Config.AnnounceThrottleRate)
+		defer announceThrottleTicker.Stop()
+		announceThrottleTickerC = announceThrottleTicker.C
+	}
+
 	// Connect to all trusted peers on startup to try to ensure a connection establishes quickly.
 	// The number of connections to default peers is restricted;
 	// if multiple connections succeed, extra connections beyond the limit will be disconnected.
@@ -480,6 +1012,112 @@ func (dm *Daemon) Run() error {
 		}()
 	}
 
+	// Verify the database in the background, if enabled.
+	// A corrupted database stops the daemon, so that the database can be recovered once it is no longer in use.
+	if dm.visor.Config.VerifyDBInBackground {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			// Stop the verification when the daemon shuts down
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go func() {
+				select {
+				case <-dm.quit:
+					cancel()
+				case <-ctx.Done():
+				}
+			}()
+
+			err := dm.visor.VerifyDB(ctx)
+
+			// Corrupted blocks are repaired with copies requested from peers, if possible
+			if e, ok := err.(visor.ErrCorruptDB); ok && dm.visor.StartBlockRepair(e) {
+				err = dm.repairCorruptedBlocks()
+			}
+
+			if err != nil && err != visor.ErrVerifyStopped {
+				logger.WithError(err).Error("dm.visor.VerifyDB failed")
+				errC <- err
+			}
+		}()
+	}
+
+	// Re-verify random ranges of blocks in the background, if enabled.
+	// The corruption found is reported in the database integrity report, it does not stop the daemon.
+	if dm.visor.Config.DBScrubInterval != 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go func() {
+				select {
+				case <-dm.quit:
+					cancel()
+				case <-ctx.Done():
+				}
+			}()
+
+			dm.visor.ScrubDB(ctx)
+		}()
+	}
+
+	// Serve the block and history queries of the API from a periodically refreshed copy of the database, if enabled
+	if dm.visor.Config.DBReplicaInterval != 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go func() {
+				select {
+				case <-dm.quit:
+					cancel()
+				case <-ctx.Done():
+				}
+			}()
+
+			dm.visor.RunDBReplica(ctx)
+		}()
+	}
+
+	// Export the events of the parsed blocks to an external store, if enabled
+	if dm.visor.Config.EventExportSink != nil || dm.visor.Config.EventExportFile != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go func() {
+				select {
+				case <-dm.quit:
+					cancel()
+				case <-ctx.Done():
+				}
+			}()
+
+			dm.visor.RunEventExport(ctx)
+		}()
+	}
+
+	// Repair the corrupted blocks found by the database verification on startup.
+	// If the repair fails, the corruption error stops the daemon, so that the database can be recovered.
+	if len(dm.visor.PendingBlockRepairs()) != 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := dm.repairCorruptedBlocks(); err != nil {
+				logger.WithError(err).Error("dm.repairCorruptedBlocks failed")
+				errC <- err
+			}
+		}()
+	}
+
 	var setupErr error
 	elapser := elapse.NewElapser(daemonRunDurationThreshold, logger)
 
@@ -563,12 +1201,35 @@ loop:
 			elapser.Register("idleCheckTicker")
 			if !dm.Config.DisableNetworking {
 				dm.pool.sendPings()
+				dm.pingForLatency()
+			}
+
+		case <-blockFanoutTickerC:
+			elapser.Register("blockFanoutTicker")
+			if !dm.Config.DisableNetworking {
+				dm.flushBlockFanout()
+			}
+
+		case <-rebroadcastTickerC:
+			elapser.Register("rebroadcastTicker")
+			if !dm.Config.DisableNetworking {
+				dm.rebroadcastTxns(dm.now().UTC())
 			}
 
 		case <-outgoingConnectionsTicker.C:
 			// Fill up our outgoing connections
 			elapser.Register("outgoingConnectionsTicker")
-			dm.connectToRandomPeer()
+			if dm.crawler != nil {
+				dm.crawlPeers()
+			} else {
+				dm.connectToRandomPeer()
+			}
+
+		case <-crawlerReportTickerC:
+			elapser.Register("crawlerReportTicker")
+			if err := dm.saveCrawlReport(); err != nil {
+				logger.WithError(err).Error("saveCrawlReport failed")
+			}
 
 		case <-outgoingTrustedConnectionsTicker.C:
 			// Try to maintain at least one trusted connection
@@ -612,6 +1273,14 @@ loop:
 				logger.WithError(err).Error("Failed to set unconfirmed txn announce time")
 			}
 
+		case <-announceThrottleTickerC:
+			elapser.Register("announceThrottleTicker")
+			for _, m := range dm.announceThrottle.flush(dm.now(), dm.Config.MaxTxnAnnounceNum) {
+				if _, err := dm.broadcastToIntroduced(m); err != nil {
+					logger.WithError(err).Debug("Broadcast throttled announcement failed")
+				}
+			}
+
 		case req := <-dm.Gateway.requests:
 			// Process any pending RPC requests
 			elapser.Register("dm.Gateway.requests")
@@ -623,6 +1292,13 @@ loop:
 			// Create blocks, if block publisher
 			elapser.Register("blockCreationTicker.C")
 			if dm.visor.Config.IsBlockPublisher {
+				if ok, err := dm.canPublishBlock(); err != nil {
+					logger.WithError(err).Error("canPublishBlock failed")
+					continue
+				} else if !ok {
+					continue
+				}
+
 				sb, err := dm.createAndPublishBlock()
 				if err != nil {
 					logger.WithError(err).Error("Failed to create and publish block")
@@ -663,12 +1339,36 @@ loop:
 				logger.Infof("Remove %d txns from pool that began violating hard constraints", len(removedTxns))
 			}
 
+		case <-unconfirmedEvictTicker.C:
+			elapser.Register("unconfirmedEvictTicker")
+			// Evict transactions that exceed the max age or the max size of the pool
+			evictedTxns, err := dm.visor.EvictUnconfirmed()
+			if err != nil {
+				logger.WithError(err).Error("dm.Visor.EvictUnconfirmed failed")
+				continue
+			}
+			if len(evictedTxns) > 0 {
+				logger.Infof("Evicted %d txns from pool", len(evictedTxns))
+			}
+
 		case <-blocksRequestTicker.C:
 			elapser.Register("blocksRequestTicker")
 			if err := dm.requestBlocks(); err != nil {
 				logger.WithError(err).Warning("requestBlocks failed")
 			}
 
+		case <-headersSyncTickerC:
+			elapser.Register("headersSyncTicker")
+			if err := dm.syncHeadersFirst(); err != nil {
+				logger.WithError(err).Warning("syncHeadersFirst failed")
+			}
+
+		case <-requestRetryTicker.C:
+			elapser.Register("requestRetryTicker")
+			if !dm.Config.DisableNetworking {
+				dm.retryRequests()
+			}
+
 		case <-blocksAnnounceTicker.C:
 			elapser.Register("blocksAnnounceTicker")
 			if err := dm.announceBlocks(); err != nil {
@@ -685,6 +1385,12 @@ loop:
 		return setupErr
 	}
 
+	if dm.crawler != nil {
+		if err := dm.saveCrawlReport(); err != nil {
+			logger.WithError(err).Error("saveCrawlReport failed")
+		}
+	}
+
 	wg.Wait()
 
 	return nil
@@ -712,8 +1418,13 @@ func (dm *Daemon) connectToPeer(p pex.Peer) error {
 		return errors.New("Already connected to this peer")
 	}
 
+	if dm.pex.IsBanned(p.Addr) {
+		return errors.New("Peer is banned")
+	}
+
+	// The crawler visits the nodes sharing a base IP too
 	cnt := dm.connections.IPCount(a)
-	if !dm.Config.LocalhostOnly && cnt != 0 {
+	if !dm.Config.LocalhostOnly && !dm.Config.Crawler && cnt != 0 {
 		return errors.New("Already connected to a peer with this base IP")
 	}
 
@@ -724,8 +1435,19 @@ func (dm *Daemon) connectToPeer(p pex.Peer) error {
 		return err
 	}
 
+	connect := dm.pool.Pool.Connect
+	if dm.Config.EncryptedTransport {
+		// The connections to the peers whose key is known are always encrypted, so that they can't be downgraded
+		key := dm.peerKey(p.Addr)
+		if !key.Null() || PeerFeatures(p.Features).Has(FeatureEncryptedTransport) {
+			connect = func(addr string) error {
+				return dm.pool.Pool.ConnectEncrypted(addr, key)
+			}
+		}
+	}
+
 	go func() {
-		if err := dm.pool.Pool.Connect(p.Addr); err != nil {
+		if err := connect(p.Addr); err != nil {
 			dm.events <- ConnectFailureEvent{
 				Addr:      p.Addr,
 				Solicited: true,
@@ -815,8 +1537,15 @@ func (dm *Daemon) connectToRandomPeer() {
 		return
 	}
 
-	// Make a connection to a random (public) peer
-	peers := dm.pex.RandomPublic(dm.Config.MaxOutgoingConnections - dm.connections.OutgoingLen())
+	// Make connections to the (public) peers with the best score, in netgroups we are not connected to yet
+	var outgoing []string
+	for _, c := range dm.connections.all() {
+		if c.Outgoing {
+			outgoing = append(outgoing, c.Addr)
+		}
+	}
+
+	peers := dm.pex.DiversePublic(dm.Config.MaxOutgoingConnections-dm.connections.OutgoingLen(), outgoing)
 	for _, p := range peers {
 		if err := dm.connectToPeer(p); err != nil {
 			logger.WithError(err).WithField("addr", p.Addr).Warning("connectToPeer failed")
@@ -829,15 +1558,69 @@ func (dm *Daemon) connectToRandomPeer() {
 	}
 }
 
+// crawlPeers disconnects the peers that the crawler finished visiting, and connects to the peers
+// that were not visited yet, up to MaxOutgoingConnections
+func (dm *Daemon) crawlPeers() {
+	for _, addr := range dm.crawler.finished(dm.now().UTC()) {
+		if err := dm.Disconnect(addr, ErrDisconnectCrawled); err != nil {
+			logger.WithError(err).WithField("addr", addr).Debug("Disconnect visited peer failed")
+		}
+	}
+
+	peers := dm.pex.RandomPublic(0)
+	addrs := make([]string, len(peers))
+	for i, p := range peers {
+		addrs[i] = p.Addr
+	}
+	dm.crawler.add(addrs)
+
+	n := dm.Config.MaxOutgoingConnections - dm.connections.OutgoingLen()
+	if pending := dm.Config.MaxPendingConnections - dm.connections.PendingLen(); pending < n {
+		n = pending
+	}
+
+	for _, addr := range dm.crawler.next(n) {
+		p, ok := dm.pex.GetPeer(addr)
+		if !ok {
+			p = *pex.NewPeer(addr)
+		}
+
+		now := dm.now().UTC()
+		dm.crawler.visit(addr, now)
+		if err := dm.connectToPeer(p); err != nil {
+			logger.WithError(err).WithField("addr", addr).Debug("Crawler connectToPeer failed")
+			dm.crawler.disconnected(addr, err.Error())
+		}
+	}
+}
+
+// saveCrawlReport writes the crawl report to CrawlerReportFile
+func (dm *Daemon) saveCrawlReport() error {
+	r := dm.crawler.report(dm.now().UTC())
+
+	logger.WithFields(logrus.Fields{
+		"visited":   r.Visited,
+		"reachable": r.Reachable,
+		"queued":    r.Queued,
+	}).Info("Saving crawl report")
+
+	return file.SaveJSON(dm.Config.CrawlerReportFile, r, 0600)
+}
+
 // Removes connections who haven't sent a version after connecting
 func (dm *Daemon) cullInvalidConnections() {
-	now := time.Now().UTC()
+	now := dm.now().UTC()
 	for _, c := range dm.connections.all() {
 		if c.State != ConnectionStateConnected {
 			continue
 		}
 
-		if c.ConnectedAt.Add(dm.Config.IntroductionWait).Before(now) {
+		wait := dm.Config.IntroductionWait
+		if !c.Outgoing {
+			wait = dm.Config.IncomingIntroductionWait
+		}
+
+		if c.ConnectedAt.Add(wait).Before(now) {
 			logger.WithField("addr", c.Addr).Info("Disconnecting peer for not sending a version")
 			if err := dm.Disconnect(c.Addr, ErrDisconnectIntroductionTimeout); err != nil {
 				logger.WithError(err).WithField("addr", c.Addr).Error("Disconnect")
@@ -846,6 +1629,16 @@ func (dm *Daemon) cullInvalidConnections() {
 	}
 }
 
+// isWhitelistedAddr returns true if the IP of an ip:port address is in DaemonConfig.Whitelist
+func (dm *Daemon) isWhitelistedAddr(addr string) bool {
+	ip, _, err := iputil.SplitAddr(addr)
+	if err != nil {
+		return false
+	}
+
+	return dm.isWhitelistedIP(ip)
+}
+
 func (dm *Daemon) isTrustedPeer(addr string) bool {
 	peer, ok := dm.pex.GetPeer(addr)
 	if !ok {
@@ -943,13 +1736,29 @@ func (dm *Daemon) onConnectEvent(e ConnectEvent) {
 		return
 	}
 
+	dm.connectionEvents.publish(ConnectionEvent{
+		Type:     ConnectionEventConnected,
+		Time:     dm.now().UTC(),
+		Addr:     e.Addr,
+		GnetID:   e.GnetID,
+		Outgoing: e.Solicited,
+	})
+
 	// The connection should already be known as outgoing/solicited due to an earlier connections.pending call.
 	// If they do not match, there is e.Addr flaw in the concept or implementation of the state machine.
 	if c.Outgoing != e.Solicited {
 		logger.Critical().WithFields(fields).Warning("Connection.Outgoing does not match ConnectEvent.Solicited state")
 	}
 
-	if dm.ipCountMaxed(e.Addr) {
+	if dm.pex.IsBanned(e.Addr) {
+		logger.WithFields(fields).Info("Peer is banned, disconnecting")
+		if err := dm.Disconnect(e.Addr, ErrDisconnectIsBlacklisted); err != nil {
+			logger.WithError(err).WithFields(fields).Error("Disconnect")
+		}
+		return
+	}
+
+	if dm.ipCountMaxed(e.Addr) && !dm.isUnlimitedListenerConnection(e.Addr) {
 		logger.WithFields(fields).Info("Max connections for this IP address reached, disconnecting")
 		if err := dm.Disconnect(e.Addr, ErrDisconnectIPLimitReached); err != nil {
 			logger.WithError(err).WithFields(fields).Error("Disconnect")
@@ -957,6 +1766,16 @@ func (dm *Daemon) onConnectEvent(e ConnectEvent) {
 		return
 	}
 
+	if !e.Solicited && !dm.isUnlimitedListenerConnection(e.Addr) {
+		if reason := dm.incomingLimitReached(e.Addr); reason != nil {
+			logger.WithFields(fields).WithField("reason", reason).Info("Max incoming connections for this IP address or range reached, disconnecting")
+			if err := dm.Disconnect(e.Addr, reason); err != nil {
+				logger.WithError(err).WithFields(fields).Error("Disconnect")
+			}
+			return
+		}
+	}
+
 	logger.WithFields(fields).Debug("Sending introduction message")
 
 	if err := dm.sendMessage(e.Addr, NewIntroductionMessage(
@@ -967,6 +1786,8 @@ func (dm *Daemon) onConnectEvent(e ConnectEvent) {
 		dm.Config.userAgent,
 		dm.Config.UnconfirmedBurnFactor,
 		dm.Config.UnconfirmedMaxTransactionSize,
+		dm.Config.features(),
+		dm.now().UTC().Unix(),
 	)); err != nil {
 		logger.WithFields(fields).WithError(err).Error("Send IntroductionMessage failed")
 		return
@@ -981,18 +1802,41 @@ func (dm *Daemon) onDisconnectEvent(e DisconnectEvent) {
 	}
 	logger.WithFields(fields).Info("onDisconnectEvent")
 
+	c := dm.connections.get(e.Addr)
+	if c != nil && c.gnetID == e.GnetID {
+		dm.recordPeerStats(*c)
+	}
+
+	dm.connectionEvents.publish(ConnectionEvent{
+		Type:     ConnectionEventDisconnected,
+		Time:     dm.now().UTC(),
+		Addr:     e.Addr,
+		GnetID:   e.GnetID,
+		Outgoing: c != nil && c.Outgoing,
+		Reason:   e.Reason.Error(),
+	})
+
 	if err := dm.connections.remove(e.Addr, e.GnetID); err != nil {
 		logger.WithError(err).WithFields(fields).Error("connections.Remove failed")
 		return
 	}
 
-	// TODO -- blacklist peer for certain reasons, not just remove
+	dm.crawler.disconnected(e.Addr, e.Reason.Error())
+
+	dm.headerSync.removePeer(e.Addr)
+	dm.requestScheduler.removePeer(e.Addr)
+	dm.blockFanout.removePeer(e.Addr)
+
+	if m, ok := disconnectReasonMisbehavior(e.Reason); ok {
+		dm.recordPeerMisbehavior(e.Addr, m)
+	}
+
 	switch e.Reason {
 	case ErrDisconnectIntroductionTimeout,
 		ErrDisconnectBlockchainPubkeyNotMatched,
 		ErrDisconnectInvalidExtraData,
 		ErrDisconnectInvalidUserAgent:
-		if !dm.isTrustedPeer(e.Addr) {
+		if !dm.isTrustedPeer(e.Addr) && !dm.isWhitelistedAddr(e.Addr) {
 			dm.pex.RemovePeer(e.Addr)
 		}
 	case ErrDisconnectNoIntroduction,
@@ -1020,9 +1864,41 @@ func (dm *Daemon) onConnectFailure(c ConnectFailureEvent) {
 		logger.Critical().WithField("addr", c.Addr).WithError(err).Error("connections.remove")
 	}
 
+	dm.crawler.disconnected(c.Addr, c.Error.Error())
+
 	if strings.HasSuffix(c.Error.Error(), "connect: connection refused") {
 		dm.pex.IncreaseRetryTimes(c.Addr)
 	}
+
+	if c.Solicited {
+		dm.pex.RecordHandshake(c.Addr, false)
+	}
+
+	// Connect in plaintext next time if the peer declined the encrypted transport.
+	// Other handshake errors, such as timeouts, don't clear the feature.
+	// A peer whose key is known authenticated before, so its decline is treated as a downgrade attempt
+	if e, ok := c.Error.(gnet.HandshakeError); ok && e.Err == gnet.ErrEncryptedTransportDeclined {
+		if !dm.peerKey(c.Addr).Null() {
+			logger.WithField("addr", c.Addr).Warning("Peer with a known encrypted transport key declined the encrypted transport")
+		} else if p, ok := dm.pex.GetPeer(c.Addr); ok {
+			dm.pex.SetFeatures(c.Addr, uint64(PeerFeatures(p.Features)&^FeatureEncryptedTransport))
+		}
+	}
+}
+
+// recordPeerStats records the uptime of a disconnected connection in the peer's statistics,
+// or a failed handshake if it is an outgoing connection that did not introduce
+func (dm *Daemon) recordPeerStats(c connection) {
+	if !c.HasIntroduced() {
+		if c.Outgoing && c.State == ConnectionStateConnected {
+			dm.pex.RecordHandshake(c.Addr, false)
+		}
+		return
+	}
+
+	if listenAddr := c.ListenAddr(); listenAddr != "" {
+		dm.pex.RecordUptime(listenAddr, time.Now().UTC().Sub(c.ConnectedAt))
+	}
 }
 
 // onGnetDisconnect triggered when a gnet.Connection terminates
@@ -1061,7 +1937,34 @@ func (dm *Daemon) ipCountMaxed(addr string) bool {
 		return true
 	}
 
-	return !dm.Config.LocalhostOnly && dm.connections.IPCount(ip) >= dm.Config.IPCountsMax
+	return !dm.Config.LocalhostOnly && !dm.isWhitelistedIP(ip) && dm.connections.IPCount(ip) >= dm.Config.IPCountsMax
+}
+
+// isUnlimitedListenerConnection returns true if the connection was accepted on one of the ListenAddresses
+// without a maximum of connections
+func (dm *Daemon) isUnlimitedListenerConnection(addr string) bool {
+	c, err := dm.pool.Pool.GetConnection(addr)
+	if err != nil {
+		logger.WithError(err).WithField("addr", addr).Error("GetConnection failed")
+		return false
+	}
+
+	return c != nil && c.Listener != nil && c.Listener.MaxConnections == 0
+}
+
+// isWhitelistedIP returns true if the IP is in DaemonConfig.Whitelist
+func (dm *Daemon) isWhitelistedIP(ip string) bool {
+	if parsedIP := net.ParseIP(ip); parsedIP != nil {
+		ip = parsedIP.String()
+	}
+
+	for _, w := range dm.Config.Whitelist {
+		if w == ip {
+			return true
+		}
+	}
+
+	return false
 }
 
 // When an async message send finishes, its result is handled by this.
@@ -1093,6 +1996,11 @@ func (dm *Daemon) requestBlocks() error {
 		return ErrNetworkingDisabled
 	}
 
+	// The crawler does not synchronize the blockchain
+	if dm.Config.Crawler {
+		return nil
+	}
+
 	headSeq, ok, err := dm.visor.HeadBkSeq()
 	if err != nil {
 		return err
@@ -1101,9 +2009,20 @@ func (dm *Daemon) requestBlocks() error {
 		return errors.New("Cannot request blocks, there is no head block")
 	}
 
+	// Penalize the peers that did not respond to the previous request
+	dm.checkStalledBlockRequests(headSeq)
+
 	m := NewGetBlocksMessage(headSeq, dm.Config.BlocksResponseCount)
 
-	if _, err := dm.broadcastMessage(m); err != nil {
+	// The peers that negotiated FeatureHeadersFirst are synchronized by syncHeadersFirst
+	var addrs []string
+	for _, c := range dm.connections.all() {
+		if c.HasIntroduced() && !c.Features.Has(FeatureHeadersFirst) && canServeBlocks(c, headSeq) {
+			addrs = append(addrs, c.Addr)
+		}
+	}
+
+	if _, err := dm.pool.Pool.BroadcastMessage(m, addrs); err != nil {
 		logger.WithError(err).Debug("Broadcast GetBlocksMessage failed")
 		return err
 	}
@@ -1111,6 +2030,88 @@ func (dm *Daemon) requestBlocks() error {
 	return nil
 }
 
+// canServeBlocks returns false if the connection is a pruned peer that is too far ahead
+// to still have the transactions of the blocks after headSeq
+func canServeBlocks(c connection, headSeq uint64) bool {
+	return !c.Features.Has(FeaturePruned) || c.Height <= headSeq+visor.MinPruneDepth
+}
+
+// repairCorruptedBlocks requests the corrupted blocks waiting to be repaired from peers until they are all repaired.
+// If they are not repaired within the visor's BlockRepairTimeout, it returns visor.ErrCorruptDB
+// with the remaining corrupted blocks.
+func (dm *Daemon) repairCorruptedBlocks() error {
+	timeout := time.NewTimer(dm.visor.Config.BlockRepairTimeout)
+	defer timeout.Stop()
+	ticker := time.NewTicker(dm.Config.BlockRepairRequestRate)
+	defer ticker.Stop()
+
+	for {
+		pending := dm.visor.PendingBlockRepairs()
+		if len(pending) == 0 {
+			logger.Critical().Info("All corrupted blocks were repaired")
+			return nil
+		}
+
+		if err := dm.requestBlockRepairs(pending); err != nil {
+			logger.WithError(err).Warning("requestBlockRepairs failed")
+		}
+
+		select {
+		case <-dm.quit:
+			return nil
+		case <-ticker.C:
+		case <-timeout.C:
+			pending = dm.visor.PendingBlockRepairs()
+			if len(pending) == 0 {
+				return nil
+			}
+
+			err := fmt.Errorf("%d corrupted blocks were not repaired by peers within %s", len(pending), dm.visor.Config.BlockRepairTimeout)
+			return visor.NewErrCorruptDB(err, pending)
+		}
+	}
+}
+
+// requestBlockRepairs sends GetBlocksMessages for the corrupted blocks to all connections
+func (dm *Daemon) requestBlockRepairs(seqs []uint64) error {
+	if dm.Config.DisableNetworking {
+		return ErrNetworkingDisabled
+	}
+
+	for _, m := range newBlockRepairMessages(seqs, dm.Config.BlocksResponseCount) {
+		if _, err := dm.broadcastMessage(m); err != nil {
+			logger.WithError(err).Debug("Broadcast GetBlocksMessage failed")
+			return err
+		}
+	}
+
+	return nil
+}
+
+// newBlockRepairMessages creates the GetBlocksMessages requesting the blocks of seqs, which must be sorted.
+// Consecutive blocks are requested together, at most count blocks per message.
+// The genesis block can't be requested with a GetBlocksMessage, so it is skipped.
+func newBlockRepairMessages(seqs []uint64, count uint64) []*GetBlocksMessage {
+	var msgs []*GetBlocksMessage
+	for _, seq := range seqs {
+		if seq == 0 {
+			continue
+		}
+
+		if n := len(msgs); n != 0 {
+			m := msgs[n-1]
+			if m.LastBlock+m.RequestedBlocks+1 == seq && m.RequestedBlocks < count {
+				m.RequestedBlocks++
+				continue
+			}
+		}
+
+		msgs = append(msgs, NewGetBlocksMessage(seq-1, 1))
+	}
+
+	return msgs
+}
+
 // announceBlocks sends an AnnounceBlocksMessage to all connections
 func (dm *Daemon) announceBlocks() error {
 	if dm.Config.DisableNetworking {
@@ -1167,7 +2168,7 @@ func (dm *Daemon) createAndPublishBlock() (*coin.SignedBlock, error) {
 		return nil, ErrNetworkingDisabled
 	}
 
-	sb, err := dm.visor.CreateAndExecuteBlock()
+	sb, err := dm.visor.CreateAndExecuteBlockAt(dm.now())
 	if err != nil {
 		return nil, err
 	}
@@ -1177,6 +2178,29 @@ func (dm *Daemon) createAndPublishBlock() (*coin.SignedBlock, error) {
 	return &sb, err
 }
 
+// canPublishBlock returns true if the block publisher can create a block now, according to the
+// signing policy shared with a standby block publisher
+func (dm *Daemon) canPublishBlock() (bool, error) {
+	if dm.publisherFailover == nil {
+		return true, nil
+	}
+
+	hashes, err := dm.visor.GetAllValidUnconfirmedTxHashes()
+	if err != nil {
+		return false, err
+	}
+
+	headSeq, _, err := dm.visor.HeadBkSeq()
+	if err != nil {
+		return false, err
+	}
+
+	peers := newPeerBlockchainHeights(dm.connections.all())
+	behind := EstimateBlockchainHeight(headSeq, peers) > headSeq
+
+	return dm.publisherFailover.canPublish(dm.now(), len(hashes) != 0, behind), nil
+}
+
 // ResendUnconfirmedTxns resends all unconfirmed transactions and returns the hashes that were successfully rebroadcast.
 // It does not return an error if broadcasting fails.
 func (dm *Daemon) ResendUnconfirmedTxns() ([]cipher.SHA256, error) {
@@ -1194,12 +2218,52 @@ func (dm *Daemon) ResendUnconfirmedTxns() ([]cipher.SHA256, error) {
 		logger.WithField("txid", txns[i].Hash().Hex()).Debug("Rebroadcast transaction")
 		if err := dm.BroadcastTransaction(txns[i].Transaction); err == nil {
 			txids = append(txids, txns[i].Transaction.Hash())
+			dm.rebroadcaster.broadcast(txns[i].Transaction.Hash(), dm.now().UTC())
 		}
 	}
 
 	return txids, nil
 }
 
+// rebroadcastTxns rebroadcasts the transactions injected by this node that are due at now.
+// The transactions that are no longer in the unconfirmed pool, because they were confirmed,
+// evicted or became invalid, are no longer rebroadcast
+func (dm *Daemon) rebroadcastTxns(now time.Time) {
+	due, expired := dm.rebroadcaster.due(now)
+
+	for _, txid := range expired {
+		logger.WithField("txid", txid.Hex()).Info("Transaction not confirmed before the rebroadcast expiry, no longer rebroadcasting it")
+	}
+
+	for _, txid := range due {
+		txn, err := dm.visor.GetUnconfirmedTxn(txid)
+		if err != nil {
+			logger.WithError(err).WithField("txid", txid.Hex()).Error("GetUnconfirmedTxn failed")
+			continue
+		}
+
+		if txn == nil {
+			dm.rebroadcaster.remove(txid)
+			continue
+		}
+
+		// If the broadcast fails, the transaction is retried at the next check, without backing off
+		if err := dm.BroadcastTransaction(txn.Transaction); err != nil {
+			continue
+		}
+
+		dm.rebroadcaster.broadcast(txid, now)
+
+		if s, ok := dm.rebroadcaster.stats(txid); ok {
+			logger.WithFields(logrus.Fields{
+				"txid":          txid.Hex(),
+				"broadcasts":    s.Broadcasts,
+				"nextBroadcast": s.NextBroadcast,
+			}).Debug("Rebroadcast transaction")
+		}
+	}
+}
+
 // BroadcastTransaction broadcasts a single transaction to all peers.
 func (dm *Daemon) BroadcastTransaction(t coin.Transaction) error {
 	if dm.Config.DisableNetworking {
@@ -1229,6 +2293,52 @@ func (dm *Daemon) Disconnect(addr string, r gnet.DisconnectReason) error {
 	return dm.sendMessage(addr, NewDisconnectMessage(r))
 }
 
+// peerKey returns the encrypted transport key of a peer, the key pinned in PeerKeys or else the key learned
+// on the first encrypted connection to it. Returns a null key if it is not known
+func (dm *Daemon) peerKey(addr string) cipher.PubKey {
+	if key, ok := dm.Config.PeerKeys[addr]; ok {
+		return key
+	}
+
+	if p, ok := dm.pex.GetPeer(addr); ok {
+		return p.TransportKey
+	}
+
+	return cipher.PubKey{}
+}
+
+// verifyTransport checks the encrypted transport of an introduced connection. The features of the introduction
+// must be the features bound into the handshake, and a peer whose key is known must authenticate with it.
+// The outgoing connections to a peer whose key is known must be encrypted.
+// The key of a peer is learned on the first encrypted outgoing connection to it
+func (dm *Daemon) verifyTransport(c *connection, remoteFeatures PeerFeatures) error {
+	gc, err := dm.pool.Pool.GetConnection(c.Addr)
+	if err != nil {
+		return err
+	}
+	if gc == nil {
+		return ErrConnectionNotExist
+	}
+
+	if gc.Encrypted && PeerFeatures(gc.HandshakeFeatures) != remoteFeatures {
+		return ErrDisconnectTransportFeaturesMismatch
+	}
+
+	listenAddr := c.ListenAddr()
+	key := dm.peerKey(listenAddr)
+
+	switch {
+	case gc.Encrypted && !key.Null() && gc.PeerKey != key:
+		return ErrDisconnectTransportKeyMismatch
+	case !gc.Encrypted && !key.Null() && c.Outgoing && dm.Config.EncryptedTransport:
+		return ErrDisconnectTransportKeyMismatch
+	case gc.Encrypted && key.Null() && c.Outgoing:
+		dm.pex.SetTransportKey(listenAddr, gc.PeerKey)
+	}
+
+	return nil
+}
+
 // Implements private daemoner interface methods:
 
 // requestBlocksFromAddr sends a GetBlocksMessage to one connected address
@@ -1237,6 +2347,11 @@ func (dm *Daemon) requestBlocksFromAddr(addr string) error {
 		return ErrNetworkingDisabled
 	}
 
+	// The crawler does not synchronize the blockchain
+	if dm.Config.Crawler {
+		return nil
+	}
+
 	headSeq, ok, err := dm.visor.HeadBkSeq()
 	if err != nil {
 		return err
@@ -1245,19 +2360,200 @@ func (dm *Daemon) requestBlocksFromAddr(addr string) error {
 		return errors.New("Cannot request blocks from addr, there is no head block")
 	}
 
+	// The blocks of the peers that negotiated FeatureHeadersFirst are downloaded header-first.
+	// These peers don't learn our height from a GetBlocksMessage, announce it instead
+	if c := dm.connections.get(addr); c != nil && c.Features.Has(FeatureHeadersFirst) {
+		if err := dm.sendMessage(addr, NewAnnounceBlocksMessage(headSeq)); err != nil {
+			return err
+		}
+		return dm.syncHeadersFirst()
+	}
+
 	m := NewGetBlocksMessage(headSeq, dm.Config.BlocksResponseCount)
 	return dm.sendMessage(addr, m)
 }
 
-// broadcastBlock sends a signed block to all connections
+// syncHeadersFirst executes the blocks of the header-first synchronization that are complete, in order,
+// then requests more headers and bodies from the peers that negotiated FeatureHeadersFirst and are ahead of us
+func (dm *Daemon) syncHeadersFirst() error {
+	if dm.Config.DisableNetworking {
+		return ErrNetworkingDisabled
+	}
+
+	if dm.Config.DisableHeadersFirstSync {
+		return nil
+	}
+
+	head, ok, err := dm.visor.GetHeadBlockHeader()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("Cannot synchronize blocks, there is no head block")
+	}
+
+	if blocks := dm.headerSync.ready(head.Head.BkSeq, head.Head.Hash()); len(blocks) != 0 {
+		n, err := dm.executeSignedBlocks(blocks)
+		for _, b := range blocks[:n] {
+			logger.Critical().WithField("seq", b.Block.Head.BkSeq).Info("Added new block")
+		}
+		if err != nil {
+			// The blocks after the failed block were removed from the synchronization, start over from the head block
+			logger.Critical().WithError(err).WithField("seq", blocks[n].Block.Head.BkSeq).Error("Failed to execute synchronized block")
+			dm.headerSync.reset()
+		}
+
+		if n != 0 {
+			head = blocks[n-1].SignedHeader()
+
+			if _, err := dm.broadcastMessage(NewAnnounceBlocksMessage(head.Head.BkSeq)); err != nil {
+				logger.WithError(err).Warning("Broadcast AnnounceBlocksMessage failed")
+			}
+		}
+	}
+
+	var peers []syncPeer
+	for _, c := range dm.connections.all() {
+		if !c.HasIntroduced() || !c.Features.Has(FeatureHeadersFirst) || c.Height <= head.Head.BkSeq {
+			continue
+		}
+
+		peers = append(peers, syncPeer{
+			addr:   c.Addr,
+			height: c.Height,
+			pruned: c.Features.Has(FeaturePruned),
+		})
+	}
+
+	for _, r := range dm.headerSync.requests(dm.now(), head.Head.BkSeq, head.Head.Hash(), peers) {
+		if err := dm.sendMessage(r.addr, r.msg); err != nil {
+			logger.WithError(err).WithField("addr", r.addr).Warning("Send header-first synchronization request failed")
+		}
+	}
+
+	return nil
+}
+
+// broadcastBlock sends a signed block to all connections. The peers that support compact blocks
+// are sent a CompactBlockMessage, the others a GiveBlocksMessage
 func (dm *Daemon) broadcastBlock(sb coin.SignedBlock) error {
 	if dm.Config.DisableNetworking {
 		return ErrNetworkingDisabled
 	}
 
-	m := NewGiveBlocksMessage([]coin.SignedBlock{sb})
-	_, err := dm.broadcastMessage(m)
-	return err
+	compactAddrs, fullAddrs := dm.blockRelayAddrs("")
+	if len(compactAddrs) == 0 && len(fullAddrs) == 0 {
+		return gnet.ErrNoAddresses
+	}
+
+	return dm.fanoutBlock(sb, compactAddrs, NewCompactBlockMessage(sb), fullAddrs, NewGiveBlocksMessage([]coin.SignedBlock{sb}))
+}
+
+// relayBlock relays a block received as a compact block to the other connections.
+// The peers that support compact blocks are sent a CompactBlockMessage, the others an AnnounceBlocksMessage
+func (dm *Daemon) relayBlock(sb coin.SignedBlock, fromAddr string) error {
+	if dm.Config.DisableNetworking {
+		return ErrNetworkingDisabled
+	}
+
+	compactAddrs, announceAddrs := dm.blockRelayAddrs(fromAddr)
+
+	return dm.fanoutBlock(sb, compactAddrs, NewCompactBlockMessage(sb), announceAddrs, NewAnnounceBlocksMessage(sb.Head.BkSeq))
+}
+
+// fanoutBlock sends compactMsg to compactAddrs and otherMsg to otherAddrs, for a new block.
+// The block is sent at once to the peers with the lowest latency, and queued for the others
+func (dm *Daemon) fanoutBlock(sb coin.SignedBlock, compactAddrs []string, compactMsg gnet.Message, otherAddrs []string, otherMsg gnet.Message) error {
+	targets := make([]fanoutTarget, 0, len(compactAddrs)+len(otherAddrs))
+	for _, addr := range compactAddrs {
+		targets = append(targets, fanoutTarget{
+			Addr: addr,
+			Msg:  compactMsg,
+		})
+	}
+	for _, addr := range otherAddrs {
+		targets = append(targets, fanoutTarget{
+			Addr: addr,
+			Msg:  otherMsg,
+		})
+	}
+
+	compactAddrs, otherAddrs = nil, nil
+	for _, t := range dm.blockFanout.schedule(sb.HashHeader(), sb.Head.BkSeq, targets, dm.now()) {
+		if t.Msg == compactMsg {
+			compactAddrs = append(compactAddrs, t.Addr)
+		} else {
+			otherAddrs = append(otherAddrs, t.Addr)
+		}
+	}
+
+	if len(compactAddrs) != 0 {
+		if _, err := dm.pool.Pool.BroadcastMessage(compactMsg, compactAddrs); err != nil {
+			return err
+		}
+	}
+
+	if len(otherAddrs) != 0 {
+		if _, err := dm.pool.Pool.BroadcastMessage(otherMsg, otherAddrs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// flushBlockFanout sends the queued blocks that are due to the peers that did not report them meanwhile
+func (dm *Daemon) flushBlockFanout() {
+	targets := dm.blockFanout.flush(dm.now(), func(addr string) (uint64, bool) {
+		c := dm.connections.get(addr)
+		if c == nil || !c.HasIntroduced() {
+			return 0, false
+		}
+		return c.Height, true
+	})
+
+	for _, t := range targets {
+		if err := dm.sendMessage(t.Addr, t.Msg); err != nil {
+			logger.WithError(err).WithField("addr", t.Addr).Warning("Send queued block failed")
+		}
+	}
+}
+
+// pingForLatency pings the introduced connections whose latency should be measured for the block fan-out
+func (dm *Daemon) pingForLatency() {
+	if dm.blockFanout == nil {
+		return
+	}
+
+	now := dm.now()
+	for _, c := range dm.connections.all() {
+		if !c.HasIntroduced() || !dm.blockFanout.ping(c.Addr, now) {
+			continue
+		}
+
+		if err := dm.sendMessage(c.Addr, &PingMessage{}); err != nil {
+			logger.WithError(err).WithField("addr", c.Addr).Warning("Send PingMessage failed")
+		}
+	}
+}
+
+// blockRelayAddrs returns the addresses of the introduced connections other than exceptAddr,
+// split by whether compact blocks were negotiated with them
+func (dm *Daemon) blockRelayAddrs(exceptAddr string) ([]string, []string) {
+	var compactAddrs, otherAddrs []string
+	for _, c := range dm.connections.all() {
+		if !c.HasIntroduced() || c.Addr == exceptAddr {
+			continue
+		}
+
+		if c.Features.Has(FeatureCompactBlocks) {
+			compactAddrs = append(compactAddrs, c.Addr)
+		} else {
+			otherAddrs = append(otherAddrs, c.Addr)
+		}
+	}
+
+	return compactAddrs, otherAddrs
 }
 
 // daemonConfig returns the daemon config
@@ -1283,6 +2579,30 @@ func (dm *Daemon) connectionIntroduced(addr string, gnetID uint64, m *Introducti
 		"listenAddr": listenAddr,
 	}
 
+	if err := dm.verifyTransport(c, m.remoteFeatures); err != nil {
+		logger.WithError(err).WithFields(fields).Warning("Encrypted transport verification failed")
+		return nil, err
+	}
+
+	dm.connectionEvents.publish(ConnectionEvent{
+		Type:       ConnectionEventIntroduced,
+		Time:       dm.now().UTC(),
+		Addr:       addr,
+		GnetID:     gnetID,
+		Outgoing:   c.Outgoing,
+		ListenPort: c.ListenPort,
+		UserAgent:  c.UserAgent,
+	})
+
+	dm.recordPeerClock(addr, m.timestamp)
+
+	// The large messages sent to the peers that negotiated compression are compressed
+	if c.Features.Has(FeatureCompression) {
+		if err := dm.pool.Pool.EnableCompression(addr); err != nil {
+			logger.WithError(err).WithFields(fields).Warning("pool.EnableCompression failed")
+		}
+	}
+
 	if c.Outgoing {
 		// For successful outgoing connections, mark the peer as having an incoming port in the pex peerlist
 		// The peer should already be in the peerlist, since we use the peerlist to choose an outgoing connection to make
@@ -1291,9 +2611,14 @@ func (dm *Daemon) connectionIntroduced(addr string, gnetID uint64, m *Introducti
 			return nil, err
 		}
 	} else {
-		// For successful incoming connections, add the peer to the peer list, with their self-reported listen port
-		if err := dm.pex.AddPeer(listenAddr); err != nil {
-			logger.Critical().WithError(err).WithFields(fields).Error("pex.AddPeer failed")
+		// For successful incoming connections, add the peer to the peer list, with their self-reported listen port.
+		// The peer announced its own address, so it is the source of the address
+		if err := dm.pex.AddPeerFrom(addr, listenAddr); err == pex.ErrBucketFull {
+			// The peer stays connected, but is not added to the peer list
+			logger.WithFields(fields).Info("Peer bucket is full, not adding the peer to the peer list")
+			return c, nil
+		} else if err != nil {
+			logger.Critical().WithError(err).WithFields(fields).Error("pex.AddPeerFrom failed")
 			return nil, err
 		}
 	}
@@ -1304,6 +2629,26 @@ func (dm *Daemon) connectionIntroduced(addr string, gnetID uint64, m *Introducti
 	}
 
 	dm.pex.ResetRetryTimes(listenAddr)
+	dm.pex.SetProtocolVersion(listenAddr, c.ProtocolVersion)
+	dm.pex.SetFeatures(listenAddr, uint64(c.Features))
+	if c.Outgoing {
+		dm.pex.RecordHandshake(listenAddr, true)
+	}
+
+	// Measure the latency of the peer for the block fan-out
+	if dm.blockFanout.ping(addr, dm.now()) {
+		if err := dm.sendMessage(addr, &PingMessage{}); err != nil {
+			logger.WithError(err).WithFields(fields).Warning("Send PingMessage failed")
+		}
+	}
+
+	// The crawler asks the peers it visits for their peers. Their height is received in reply to the introduction
+	if c.Outgoing && dm.crawler != nil {
+		dm.crawler.introduced(addr, c.ProtocolVersion, c.UserAgent, dm.now().UTC())
+		if err := dm.sendMessage(addr, NewGetPeersMessage()); err != nil {
+			logger.WithError(err).WithFields(fields).Warning("Send GetPeersMessage to visited peer failed")
+		}
+	}
 
 	return c, nil
 }
@@ -1317,7 +2662,22 @@ func (dm *Daemon) sendRandomPeers(addr string) error {
 	}
 
 	m := NewGivePeersMessage(peers)
-	return dm.sendMessage(addr, m)
+	if err := dm.sendMessage(addr, m); err != nil {
+		return err
+	}
+
+	// Only the peers of a recent protocol version know the GiveIPv6PeersMessage
+	c := dm.connections.get(addr)
+	if c == nil || !c.HasIntroduced() || c.ProtocolVersion < ipv6PeersProtocolVersion {
+		return nil
+	}
+
+	m6 := NewGiveIPv6PeersMessage(peers)
+	if len(m6.Peers) == 0 {
+		return nil
+	}
+
+	return dm.sendMessage(addr, m6)
 }
 
 // announceAllTxns announces local unconfirmed transactions
@@ -1376,18 +2736,47 @@ func (dm *Daemon) sendMessage(addr string, msg gnet.Message) error {
 	return dm.pool.Pool.SendMessage(addr, msg)
 }
 
-// broadcastMessage sends a Message to all introduced connections in the Pool
+// broadcastMessage sends a Message to all introduced connections in the Pool.
+// Block and transaction announcements over the announcement burst are throttled; for these,
+// 0 connections and no error are returned, and the announcement is broadcast later
 func (dm *Daemon) broadcastMessage(msg gnet.Message) (int, error) {
 	if dm.Config.DisableNetworking {
 		return 0, ErrNetworkingDisabled
 	}
 
+	if !dm.announceThrottle.allow(dm.now(), msg) {
+		logger.WithField("msgType", reflect.TypeOf(msg)).Debug("Announcement throttled")
+		return 0, nil
+	}
+
+	return dm.broadcastToIntroduced(msg)
+}
+
+// broadcastToIntroduced sends a Message to all introduced connections in the Pool, without throttling.
+// Transactions are not sent to the peers that advertised FeatureNoTxnRelay, and GetBlocksMessage
+// is not sent to the peers that negotiated FeatureHeadersFirst
+func (dm *Daemon) broadcastToIntroduced(msg gnet.Message) (int, error) {
+	if dm.Config.DisableNetworking {
+		return 0, ErrNetworkingDisabled
+	}
+
+	// Transactions are not sent to the peers that don't want them, and blocks are not requested
+	// from the peers that are synchronized header-first
+	var skip PeerFeatures
+	switch msg.(type) {
+	case *AnnounceTxnsMessage, *GiveTxnsMessage:
+		skip = FeatureNoTxnRelay
+	case *GetBlocksMessage:
+		skip = FeatureHeadersFirst
+	}
+
 	conns := dm.connections.all()
 	var addrs []string
 	for _, c := range conns {
-		if c.HasIntroduced() {
-			addrs = append(addrs, c.Addr)
+		if !c.HasIntroduced() || (skip != 0 && c.Features.Has(skip)) {
+			continue
 		}
+		addrs = append(addrs, c.Addr)
 	}
 
 	return dm.pool.Pool.BroadcastMessage(msg, addrs)
@@ -1404,13 +2793,15 @@ func (dm *Daemon) pexConfig() pex.Config {
 	return dm.pex.Config
 }
 
-// addPeers adds peers to the pex
-func (dm *Daemon) addPeers(addrs []string) int {
-	return dm.pex.AddPeers(addrs)
+// addPeers adds peers announced by the peer at source to the pex
+func (dm *Daemon) addPeers(source string, addrs []string) int {
+	dm.crawler.peersReceived(source, len(addrs))
+	return dm.pex.AddPeersFrom(source, addrs)
 }
 
 // recordPeerHeight records the height of specific peer
 func (dm *Daemon) recordPeerHeight(addr string, gnetID, height uint64) {
+	dm.crawler.heightReceived(addr, height)
 	if err := dm.connections.SetHeight(addr, gnetID, height); err != nil {
 		logger.Critical().WithError(err).WithField("addr", addr).Error("connections.SetHeight failed")
 	}
@@ -1426,9 +2817,70 @@ func (dm *Daemon) headBkSeq() (uint64, bool, error) {
 	return dm.visor.HeadBkSeq()
 }
 
-// executeSignedBlock executes the signed block
-func (dm *Daemon) executeSignedBlock(b coin.SignedBlock) error {
-	return dm.visor.ExecuteSignedBlock(b)
+// executeSignedBlocks executes a sequence of signed blocks in batches, returning the number of blocks executed.
+// The blocks are received from peers, so a block publisher with a standby yields block creation to their publisher.
+// The blocks from the first one timestamped more than MaxBlockTimeDrift after the network-adjusted time
+// are not executed
+func (dm *Daemon) executeSignedBlocks(blocks []coin.SignedBlock) (int, error) {
+	var futureErr error
+	if dm.Config.MaxBlockTimeDrift > 0 {
+		maxTime := dm.networkTime.now(dm.now().UTC()).Add(dm.Config.MaxBlockTimeDrift).Unix()
+		for i, b := range blocks {
+			if int64(b.Head.Time) > maxTime {
+				logger.WithFields(logrus.Fields{
+					"seq":     b.Head.BkSeq,
+					"time":    b.Head.Time,
+					"maxTime": maxTime,
+				}).Warning("Block time is too far in the future")
+				blocks = blocks[:i]
+				futureErr = ErrBlockTooFarInFuture
+				break
+			}
+		}
+	}
+
+	if len(blocks) == 0 {
+		return 0, futureErr
+	}
+
+	n, err := dm.visor.ExecuteSignedBlocks(blocks)
+	if n != 0 && dm.visor.Config.IsBlockPublisher {
+		dm.publisherFailover.blockReceived(dm.now())
+	}
+	if err == nil {
+		err = futureErr
+	}
+	return n, err
+}
+
+// recordPeerClock records the clock offset of a peer from the timestamp of its introduction, and warns
+// when the local clock differs from the clocks of the peers
+func (dm *Daemon) recordPeerClock(addr string, timestamp int64) {
+	if timestamp == 0 {
+		return
+	}
+
+	before := dm.networkTime.clockSkew()
+	offset := time.Unix(timestamp, 0).Sub(dm.now().UTC()).Round(time.Second)
+	after := dm.networkTime.add(pex.Netgroup(addr), offset)
+
+	fields := logrus.Fields{
+		"offset":     after.Offset,
+		"adjustment": after.Adjustment,
+		"samples":    after.Samples,
+	}
+
+	switch {
+	case after.Skewed && !before.Skewed:
+		logger.Critical().WithFields(fields).Warning("The local clock differs from the median clock of the peers. Check that the system clock is correct, blocks may be rejected")
+	case !after.Skewed && before.Skewed:
+		logger.WithFields(fields).Info("The local clock agrees with the median clock of the peers again")
+	}
+}
+
+// repairBlocks repairs the corrupted blocks of the database with the blocks received from a peer
+func (dm *Daemon) repairBlocks(blocks []coin.SignedBlock) (int, error) {
+	return dm.visor.RepairBlocks(blocks)
 }
 
 // filterKnownUnconfirmed returns unconfirmed txn hashes with known ones removed
@@ -1441,6 +2893,53 @@ func (dm *Daemon) getKnownUnconfirmed(txns []cipher.SHA256) (coin.Transactions,
 	return dm.visor.GetKnownUnconfirmed(txns)
 }
 
+// getAllUnconfirmedTxns returns all transactions of the unconfirmed pool
+func (dm *Daemon) getAllUnconfirmedTxns() (coin.Transactions, error) {
+	uTxns, err := dm.visor.GetAllUnconfirmedTransactions()
+	if err != nil {
+		return nil, err
+	}
+
+	txns := make(coin.Transactions, len(uTxns))
+	for i, ut := range uTxns {
+		txns[i] = ut.Transaction
+	}
+
+	return txns, nil
+}
+
+// getSignedBlockByHash returns the block of a hash, or nil if it is not found
+func (dm *Daemon) getSignedBlockByHash(hash cipher.SHA256) (*coin.SignedBlock, error) {
+	return dm.visor.GetSignedBlockByHash(hash)
+}
+
+// addPartialBlock adds a compact block waiting for its missing transactions
+func (dm *Daemon) addPartialBlock(pb *partialBlock) {
+	dm.partialBlocks.add(pb, dm.now())
+}
+
+// removePartialBlock removes and returns the compact block of a hash received from addr, or nil if there is none
+func (dm *Daemon) removePartialBlock(addr string, hash cipher.SHA256) *partialBlock {
+	return dm.partialBlocks.remove(addr, hash)
+}
+
+// getSignedBlockHeadersSince returns the signed headers of the blocks since seq
+func (dm *Daemon) getSignedBlockHeadersSince(seq, count uint64) ([]coin.SignedBlockHeader, error) {
+	return dm.visor.GetSignedBlockHeadersSince(seq, count)
+}
+
+// addSyncHeaders adds the block headers received from a peer to the header-first synchronization
+func (dm *Daemon) addSyncHeaders(addr string, headers []coin.SignedBlockHeader) error {
+	dm.requestScheduler.responded(addr)
+	return dm.headerSync.addHeaders(addr, headers)
+}
+
+// addSyncBodies adds the block bodies received from a peer to the header-first synchronization
+func (dm *Daemon) addSyncBodies(addr string, bodies []HashedBlockBody) error {
+	dm.requestScheduler.responded(addr)
+	return dm.headerSync.addBodies(addr, bodies)
+}
+
 // injectTransaction records a coin.Transaction to the UnconfirmedTxnPool if the txn is not
 // already in the blockchain.
 // The bool return value is whether or not the transaction was already in the pool.
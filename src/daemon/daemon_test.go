@@ -103,3 +103,51 @@ func TestDivideHashes(t *testing.T) {
 		})
 	}
 }
+
+func TestNewBlockRepairMessages(t *testing.T) {
+	tt := []struct {
+		name  string
+		seqs  []uint64
+		count uint64
+		msgs  []*GetBlocksMessage
+	}{
+		{
+			name:  "empty",
+			count: 20,
+		},
+		{
+			name:  "genesis block is skipped",
+			seqs:  []uint64{0, 1, 2},
+			count: 20,
+			msgs: []*GetBlocksMessage{
+				NewGetBlocksMessage(0, 2),
+			},
+		},
+		{
+			name:  "consecutive blocks are grouped",
+			seqs:  []uint64{3, 4, 5, 9, 20, 21},
+			count: 20,
+			msgs: []*GetBlocksMessage{
+				NewGetBlocksMessage(2, 3),
+				NewGetBlocksMessage(8, 1),
+				NewGetBlocksMessage(19, 2),
+			},
+		},
+		{
+			name:  "groups are limited to count blocks",
+			seqs:  []uint64{1, 2, 3, 4, 5},
+			count: 2,
+			msgs: []*GetBlocksMessage{
+				NewGetBlocksMessage(0, 2),
+				NewGetBlocksMessage(2, 2),
+				NewGetBlocksMessage(4, 1),
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.msgs, newBlockRepairMessages(tc.seqs, tc.count))
+		})
+	}
+}
@@ -43,6 +43,14 @@ var (
 	ErrDisconnectInvalidBurnFactor gnet.DisconnectReason = errors.New("Invalid burn factor in introduction message")
 	// ErrDisconnectInvalidMaxTransactionSize invalid max transaction size in introduction message
 	ErrDisconnectInvalidMaxTransactionSize gnet.DisconnectReason = errors.New("Invalid max transaction size in introduction message")
+	// ErrDisconnectCrawled the crawler finished visiting the peer
+	ErrDisconnectCrawled gnet.DisconnectReason = errors.New("Peer was visited by the crawler")
+	// ErrDisconnectNetgroupLimitReached netgroup limit reached
+	ErrDisconnectNetgroupLimitReached gnet.DisconnectReason = errors.New("Maximum number of incoming connections for this address range was reached")
+	// ErrDisconnectTransportFeaturesMismatch the features of the introduction differ from the features of the encrypted transport handshake
+	ErrDisconnectTransportFeaturesMismatch gnet.DisconnectReason = errors.New("Introduction features do not match the encrypted transport handshake")
+	// ErrDisconnectTransportKeyMismatch the peer did not authenticate with its known encrypted transport key
+	ErrDisconnectTransportKeyMismatch gnet.DisconnectReason = errors.New("Peer did not authenticate with its encrypted transport key")
 
 	// ErrDisconnectUnknownReason used when mapping an unknown reason code to an error. Is not sent over the network.
 	ErrDisconnectUnknownReason gnet.DisconnectReason = errors.New("Unknown DisconnectReason")
@@ -68,17 +76,25 @@ var (
 		ErrDisconnectPeerlistFull:                  16,
 		ErrDisconnectInvalidBurnFactor:             17,
 		ErrDisconnectInvalidMaxTransactionSize:     18,
+		ErrDisconnectCrawled:                       19,
+		ErrDisconnectNetgroupLimitReached:          20,
+		ErrDisconnectTransportFeaturesMismatch:     21,
+		ErrDisconnectTransportKeyMismatch:          22,
 
 		// gnet codes are registered here, but they are not sent in a DISC
 		// message by gnet. Only daemon sends a DISC packet.
 		// If gnet chooses to disconnect it will not send a DISC packet.
-		gnet.ErrDisconnectSetReadDeadlineFailed:  1001,
-		gnet.ErrDisconnectInvalidMessageLength:   1002,
-		gnet.ErrDisconnectMalformedMessage:       1003,
-		gnet.ErrDisconnectUnknownMessage:         1004,
-		gnet.ErrDisconnectShutdown:               1005,
-		gnet.ErrDisconnectMessageDecodeUnderflow: 1006,
-		gnet.ErrDisconnectTruncatedMessageID:     1007,
+		gnet.ErrDisconnectSetReadDeadlineFailed:    1001,
+		gnet.ErrDisconnectInvalidMessageLength:     1002,
+		gnet.ErrDisconnectMalformedMessage:         1003,
+		gnet.ErrDisconnectUnknownMessage:           1004,
+		gnet.ErrDisconnectShutdown:                 1005,
+		gnet.ErrDisconnectMessageDecodeUnderflow:   1006,
+		gnet.ErrDisconnectTruncatedMessageID:       1007,
+		gnet.ErrDisconnectInvalidCompressedMessage: 1008,
+		gnet.ErrDisconnectMessageTooLong:           1009,
+		gnet.ErrDisconnectMessageFieldTooLong:      1010,
+		gnet.ErrDisconnectEvicted:                  1011,
 	}
 
 	disconnectCodeReasons map[uint16]gnet.DisconnectReason
@@ -0,0 +1,81 @@
+package daemon
+
+import (
+	"strings"
+)
+
+// PeerFeatures is a set of feature bits that a peer advertises in its IntroductionMessage
+type PeerFeatures uint64
+
+const (
+	// FeatureCompactBlocks the peer accepts CompactBlockMessage, GetBlockTxnsMessage and GiveBlockTxnsMessage
+	FeatureCompactBlocks PeerFeatures = 1 << iota
+	// FeatureHeadersFirst the peer supports header-first synchronization
+	FeatureHeadersFirst
+	// FeatureNoTxnRelay the peer does not want transactions to be announced or sent to it
+	FeatureNoTxnRelay
+	// FeaturePruned the peer is pruned and only has the transactions of its recent blocks
+	FeaturePruned
+	// FeatureEncryptedTransport the peer accepts the encrypted transport handshake on incoming connections
+	FeatureEncryptedTransport
+	// FeatureCompression the peer decompresses the messages compressed with snappy
+	FeatureCompression
+
+	// knownFeatures are the features known to this version. Unknown bits sent by a peer are ignored
+	knownFeatures = FeatureCompactBlocks | FeatureHeadersFirst | FeatureNoTxnRelay | FeaturePruned | FeatureEncryptedTransport |
+		FeatureCompression
+
+	// capabilityFeatures are the features that can only be used if both peers support them.
+	// The other features describe the state of the peer that advertises them
+	capabilityFeatures = FeatureCompactBlocks | FeatureHeadersFirst | FeatureEncryptedTransport | FeatureCompression
+)
+
+var featureNames = []struct {
+	feature PeerFeatures
+	name    string
+}{
+	{FeatureCompactBlocks, "compact_blocks"},
+	{FeatureHeadersFirst, "headers_first"},
+	{FeatureNoTxnRelay, "no_txn_relay"},
+	{FeaturePruned, "pruned"},
+	{FeatureEncryptedTransport, "encrypted_transport"},
+	{FeatureCompression, "compression"},
+}
+
+// Has returns true if all of the features in g are set
+func (f PeerFeatures) Has(g PeerFeatures) bool {
+	return f&g == g
+}
+
+// Names returns the names of the known features that are set
+func (f PeerFeatures) Names() []string {
+	names := []string{}
+	for _, n := range featureNames {
+		if f.Has(n.feature) {
+			names = append(names, n.name)
+		}
+	}
+	return names
+}
+
+// String implements fmt.Stringer
+func (f PeerFeatures) String() string {
+	return strings.Join(f.Names(), ",")
+}
+
+// protocolVersionFeatures returns the features of a peer that does not advertise them,
+// derived from its protocol version
+func protocolVersionFeatures(version int32) PeerFeatures {
+	var f PeerFeatures
+	if version >= compactBlocksProtocolVersion {
+		f |= FeatureCompactBlocks
+	}
+	return f
+}
+
+// negotiateFeatures returns the features that can be used with a peer that advertised remote.
+// A capability is used only if both peers support it, while the state features of the peer are kept as is
+func negotiateFeatures(local, remote PeerFeatures) PeerFeatures {
+	remote &= knownFeatures
+	return (local & remote & capabilityFeatures) | (remote &^ capabilityFeatures)
+}
@@ -0,0 +1,190 @@
+package daemon
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/daemon/gnet"
+	"github.com/skycoin/skycoin/src/daemon/pex"
+	"github.com/skycoin/skycoin/src/visor"
+)
+
+func TestPeerFeaturesNames(t *testing.T) {
+	require.Equal(t, []string{}, PeerFeatures(0).Names())
+	require.Equal(t, "", PeerFeatures(0).String())
+
+	f := FeatureCompactBlocks | FeaturePruned | 1<<40
+	require.Equal(t, []string{"compact_blocks", "pruned"}, f.Names())
+	require.Equal(t, "compact_blocks,pruned", f.String())
+
+	require.True(t, f.Has(FeatureCompactBlocks))
+	require.True(t, f.Has(FeatureCompactBlocks|FeaturePruned))
+	require.False(t, f.Has(FeatureCompactBlocks|FeatureNoTxnRelay))
+}
+
+func TestProtocolVersionFeatures(t *testing.T) {
+	require.Equal(t, PeerFeatures(0), protocolVersionFeatures(2))
+	require.Equal(t, PeerFeatures(0), protocolVersionFeatures(compactBlocksProtocolVersion-1))
+	require.Equal(t, FeatureCompactBlocks, protocolVersionFeatures(compactBlocksProtocolVersion))
+}
+
+func TestNegotiateFeatures(t *testing.T) {
+	cases := []struct {
+		name   string
+		local  PeerFeatures
+		remote PeerFeatures
+		expect PeerFeatures
+	}{
+		{
+			name:   "no features",
+			local:  0,
+			remote: 0,
+			expect: 0,
+		},
+		{
+			name:   "capability supported by both",
+			local:  FeatureCompactBlocks | FeatureHeadersFirst,
+			remote: FeatureCompactBlocks,
+			expect: FeatureCompactBlocks,
+		},
+		{
+			name:   "capability not supported locally",
+			local:  FeatureNoTxnRelay,
+			remote: FeatureCompactBlocks | FeatureHeadersFirst,
+			expect: 0,
+		},
+		{
+			name:   "remote state features are kept",
+			local:  FeatureCompactBlocks,
+			remote: FeatureCompactBlocks | FeatureNoTxnRelay | FeaturePruned,
+			expect: FeatureCompactBlocks | FeatureNoTxnRelay | FeaturePruned,
+		},
+		{
+			name:   "local state features are not negotiated",
+			local:  FeatureCompactBlocks | FeatureNoTxnRelay | FeaturePruned,
+			remote: FeatureCompactBlocks,
+			expect: FeatureCompactBlocks,
+		},
+		{
+			name:   "encrypted transport supported by both",
+			local:  FeatureCompactBlocks | FeatureEncryptedTransport,
+			remote: FeatureCompactBlocks | FeatureEncryptedTransport,
+			expect: FeatureCompactBlocks | FeatureEncryptedTransport,
+		},
+		{
+			name:   "unknown features are ignored",
+			local:  FeatureCompactBlocks | 1<<40,
+			remote: FeatureCompactBlocks | 1<<40 | 1<<41,
+			expect: FeatureCompactBlocks,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expect, negotiateFeatures(tc.local, tc.remote))
+		})
+	}
+}
+
+func TestDaemonConfigFeatures(t *testing.T) {
+	dc := NewDaemonConfig()
+	require.Equal(t, FeatureCompactBlocks|FeatureHeadersFirst|FeatureCompression, dc.features())
+
+	dc.DisableCompactBlocks = true
+	dc.DisableHeadersFirstSync = true
+	dc.DisableCompression = true
+	dc.DisableTxnRelay = true
+	dc.pruned = true
+	require.Equal(t, FeatureNoTxnRelay|FeaturePruned, dc.features())
+
+	dc.EncryptedTransport = true
+	require.Equal(t, FeatureNoTxnRelay|FeaturePruned|FeatureEncryptedTransport, dc.features())
+}
+
+func TestDaemonEncryptedTransportDeclined(t *testing.T) {
+	dir, err := ioutil.TempDir("", "daemon")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cfg := pex.NewConfig()
+	cfg.DataDirectory = dir
+	px, err := pex.New(cfg)
+	require.NoError(t, err)
+
+	dm := &Daemon{
+		Config:      NewDaemonConfig(),
+		pex:         px,
+		connections: NewConnections(),
+	}
+
+	addr := "112.32.32.14:6000"
+	require.NoError(t, px.AddPeer(addr))
+	px.SetFeatures(addr, uint64(FeatureEncryptedTransport|FeatureCompactBlocks))
+
+	features := func() PeerFeatures {
+		p, ok := px.GetPeer(addr)
+		require.True(t, ok)
+		return PeerFeatures(p.Features)
+	}
+
+	// A failed handshake does not clear the feature
+	_, err = dm.connections.pending(addr)
+	require.NoError(t, err)
+	dm.onConnectFailure(ConnectFailureEvent{
+		Addr:      addr,
+		Solicited: true,
+		Error:     gnet.HandshakeError{Err: errors.New("i/o timeout")},
+	})
+	require.Equal(t, FeatureEncryptedTransport|FeatureCompactBlocks, features())
+
+	// The feature is cleared if the peer declines the encrypted transport
+	_, err = dm.connections.pending(addr)
+	require.NoError(t, err)
+	dm.onConnectFailure(ConnectFailureEvent{
+		Addr:      addr,
+		Solicited: true,
+		Error:     gnet.HandshakeError{Err: gnet.ErrEncryptedTransportDeclined},
+	})
+	require.Equal(t, FeatureCompactBlocks, features())
+
+	// The feature is not cleared if the key of the peer is known, a decline is a downgrade attempt
+	key, _ := cipher.GenerateKeyPair()
+	for _, pin := range []bool{false, true} {
+		px.SetFeatures(addr, uint64(FeatureEncryptedTransport|FeatureCompactBlocks))
+		if pin {
+			px.SetTransportKey(addr, cipher.PubKey{})
+			dm.Config.PeerKeys = map[string]cipher.PubKey{addr: key}
+		} else {
+			px.SetTransportKey(addr, key)
+		}
+		require.Equal(t, key, dm.peerKey(addr))
+
+		_, err = dm.connections.pending(addr)
+		require.NoError(t, err)
+		dm.onConnectFailure(ConnectFailureEvent{
+			Addr:      addr,
+			Solicited: true,
+			Error:     gnet.HandshakeError{Err: gnet.ErrEncryptedTransportDeclined},
+		})
+		require.Equal(t, FeatureEncryptedTransport|FeatureCompactBlocks, features())
+	}
+}
+
+func TestCanServeBlocks(t *testing.T) {
+	var headSeq uint64 = 1000
+
+	c := connection{}
+	c.Height = headSeq + visor.MinPruneDepth + 1
+	require.True(t, canServeBlocks(c, headSeq))
+
+	c.Features = FeaturePruned
+	require.False(t, canServeBlocks(c, headSeq))
+
+	c.Height = headSeq + visor.MinPruneDepth
+	require.True(t, canServeBlocks(c, headSeq))
+}
@@ -1,6 +1,7 @@
 package daemon
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sort"
@@ -12,11 +13,11 @@ import (
 	"github.com/skycoin/skycoin/src/daemon/gnet"
 	"github.com/skycoin/skycoin/src/daemon/pex"
 	"github.com/skycoin/skycoin/src/daemon/strand"
-	"github.com/skycoin/skycoin/src/params"
 	"github.com/skycoin/skycoin/src/visor"
 	"github.com/skycoin/skycoin/src/visor/dbutil"
 	"github.com/skycoin/skycoin/src/visor/historydb"
 	"github.com/skycoin/skycoin/src/wallet"
+	"github.com/skycoin/skycoin/src/wallet/history"
 )
 
 var (
@@ -100,6 +101,7 @@ type GnetConnectionDetails struct {
 	ID           uint64
 	LastSent     time.Time
 	LastReceived time.Time
+	Encrypted    bool
 }
 
 func newConnection(dc *connection, gc *gnet.Connection, pp *pex.Peer) Connection {
@@ -115,6 +117,7 @@ func newConnection(dc *connection, gc *gnet.Connection, pp *pex.Peer) Connection
 			ID:           gc.ID,
 			LastSent:     gc.LastSent,
 			LastReceived: gc.LastReceived,
+			Encrypted:    gc.Encrypted,
 		}
 	}
 
@@ -248,6 +251,116 @@ func (gw *Gateway) GetExchgConnection() []string {
 	return conn
 }
 
+// GetBans returns the banned peer IPs
+func (gw *Gateway) GetBans() []pex.Ban {
+	var bans []pex.Ban
+	gw.strand("GetBans", func() {
+		bans = gw.d.pex.Bans()
+	})
+	return bans
+}
+
+// Unban removes the ban of a peer IP
+func (gw *Gateway) Unban(ip string) error {
+	var err error
+	gw.strand("Unban", func() {
+		err = gw.d.pex.Unban(ip)
+	})
+	return err
+}
+
+// GetPeers returns the peers of the peer list, sorted by address
+func (gw *Gateway) GetPeers() []pex.Peer {
+	var peers []pex.Peer
+	gw.strand("GetPeers", func() {
+		peers = gw.d.pex.Peers()
+	})
+	return peers
+}
+
+// AddPeers adds peers to the peer list and persists the peer list.
+// No peer is added if an address is invalid or banned, or if the peer list is full
+func (gw *Gateway) AddPeers(addrs []string) error {
+	var err error
+	gw.strand("AddPeers", func() {
+		err = gw.d.pex.ImportPeers(addrs)
+	})
+	return err
+}
+
+// RemovePeers removes peers from the peer list and persists the peer list.
+// No peer is removed if an address is invalid or not in the peer list
+func (gw *Gateway) RemovePeers(addrs []string) error {
+	var err error
+	gw.strand("RemovePeers", func() {
+		err = gw.d.pex.RemovePeers(addrs)
+	})
+	return err
+}
+
+// BanPeers bans peer IPs for duration and disconnects their connections.
+// If duration is 0, the IPs are banned for DaemonConfig.BanDuration
+func (gw *Gateway) BanPeers(ips []string, reason string, duration time.Duration) error {
+	var err error
+	gw.strand("BanPeers", func() {
+		if duration == 0 {
+			duration = gw.d.Config.BanDuration
+		}
+		for _, ip := range ips {
+			if err = gw.d.banIP(ip, reason, duration); err != nil {
+				return
+			}
+		}
+	})
+	return err
+}
+
+// RelayPolicyStatus is the relay policy applied to the transactions received from peers,
+// with the number of transactions it rejected by reason
+type RelayPolicyStatus struct {
+	Policy   RelayPolicy
+	Rejected map[RelayRejectReason]uint64
+}
+
+// GetRelayPolicy returns the relay policy and the number of transactions it rejected
+func (gw *Gateway) GetRelayPolicy() RelayPolicyStatus {
+	var s RelayPolicyStatus
+	gw.strand("GetRelayPolicy", func() {
+		s = RelayPolicyStatus{
+			Policy:   gw.d.Config.relayPolicy(),
+			Rejected: gw.d.relayRejections.get(),
+		}
+	})
+	return s
+}
+
+// CompressionStatus is whether compression is negotiated with peers, the minimum size of the messages
+// compressed, and the sizes of the messages compressed and decompressed since the node started
+type CompressionStatus struct {
+	Enabled   bool
+	Threshold int
+	Stats     gnet.CompressionStats
+}
+
+// GetCompressionStatus returns the compression configuration and the sizes of the compressed messages
+func (gw *Gateway) GetCompressionStatus() CompressionStatus {
+	var s CompressionStatus
+	gw.strand("GetCompressionStatus", func() {
+		s = CompressionStatus{
+			Enabled:   !gw.d.Config.DisableCompression,
+			Threshold: gw.d.pool.Config.CompressionThreshold,
+			Stats:     gw.d.pool.Pool.CompressionStats(),
+		}
+	})
+	return s
+}
+
+// SubscribeConnectionEvents returns a channel that receives the connection lifecycle events from now on,
+// and a function that unsubscribes. The events are dropped for a subscriber that does not keep up
+func (gw *Gateway) SubscribeConnectionEvents() (<-chan ConnectionEvent, func()) {
+	return gw.d.connectionEvents.subscribe()
+}
+
 /* Blockchain & Transaction status */
 
 // BlockchainProgress is the current blockchain syncing status
@@ -258,6 +371,8 @@ type BlockchainProgress struct {
 	Highest uint64
 	// Individual blockchain length reports from peers
 	Peers []PeerBlockchainHeight
+	// Offline is true if networking is disabled, in which case the blockchain is not synced
+	Offline bool
 }
 
 // newBlockchainProgress creates BlockchainProgress from the local head blockchain sequence number
@@ -320,7 +435,9 @@ func (gw *Gateway) GetBlockchainProgress() (*BlockchainProgress, error) {
 		return nil, err
 	}
 
-	return newBlockchainProgress(headSeq, conns), nil
+	progress := newBlockchainProgress(headSeq, conns)
+	progress.Offline = gw.d.Config.DisableNetworking
+	return progress, nil
 }
 
 // ResendUnconfirmedTxns resents all unconfirmed transactions, returning the txids
@@ -375,6 +492,26 @@ func (gw *Gateway) GetSignedBlockBySeq(seq uint64) (*coin.SignedBlock, error) {
 	return b, err
 }
 
+// GetBlockHeaderByHash returns the block header of hash with its unspent output set commitment
+func (gw *Gateway) GetBlockHeaderByHash(hash cipher.SHA256) (*visor.CommittedBlockHeader, error) {
+	var h *visor.CommittedBlockHeader
+	var err error
+	gw.strand("GetBlockHeaderByHash", func() {
+		h, err = gw.v.GetBlockHeaderByHash(hash)
+	})
+	return h, err
+}
+
+// GetBlockHeaderBySeq returns the block header of seq with its unspent output set commitment
+func (gw *Gateway) GetBlockHeaderBySeq(seq uint64) (*visor.CommittedBlockHeader, error) {
+	var h *visor.CommittedBlockHeader
+	var err error
+	gw.strand("GetBlockHeaderBySeq", func() {
+		h, err = gw.v.GetBlockHeaderBySeq(seq)
+	})
+	return h, err
+}
+
 // GetSignedBlockBySeqVerbose returns the block by seq with verbose transaction inputs
 func (gw *Gateway) GetSignedBlockBySeqVerbose(seq uint64) (*coin.SignedBlock, [][]visor.TransactionInput, error) {
 	var b *coin.SignedBlock
@@ -386,6 +523,27 @@ func (gw *Gateway) GetSignedBlockBySeqVerbose(seq uint64) (*coin.SignedBlock, []
 	return b, inputs, err
 }
 
+// GetSignedBlockByTime returns the last block whose time is at or before t
+func (gw *Gateway) GetSignedBlockByTime(t uint64) (*coin.SignedBlock, error) {
+	var b *coin.SignedBlock
+	var err error
+	gw.strand("GetSignedBlockByTime", func() {
+		b, err = gw.v.GetSignedBlockByTime(t)
+	})
+	return b, err
+}
+
+// GetSignedBlockByTimeVerbose returns the last block whose time is at or before t with verbose transaction inputs
+func (gw *Gateway) GetSignedBlockByTimeVerbose(t uint64) (*coin.SignedBlock, [][]visor.TransactionInput, error) {
+	var b *coin.SignedBlock
+	var inputs [][]visor.TransactionInput
+	var err error
+	gw.strand("GetSignedBlockByTimeVerbose", func() {
+		b, inputs, err = gw.v.GetSignedBlockByTimeVerbose(t)
+	})
+	return b, inputs, err
+}
+
 // GetBlocks returns blocks matching given block sequences
 func (gw *Gateway) GetBlocks(seqs []uint64) ([]coin.SignedBlock, error) {
 	var blocks []coin.SignedBlock
@@ -473,6 +631,17 @@ func (gw *Gateway) GetTransaction(txid cipher.SHA256) (*visor.Transaction, error
 	return txn, err
 }
 
+// GetTransactionInclusionProof returns a proof that a confirmed transaction is included in its block,
+// nil if the transaction is not confirmed
+func (gw *Gateway) GetTransactionInclusionProof(txid cipher.SHA256) (*visor.TransactionInclusionProof, error) {
+	var proof *visor.TransactionInclusionProof
+	var err error
+	gw.strand("GetTransactionInclusionProof", func() {
+		proof, err = gw.v.GetTransactionInclusionProof(txid)
+	})
+	return proof, err
+}
+
 // GetTransactionVerbose gets verbose transaction result by txid.
 func (gw *Gateway) GetTransactionVerbose(txid cipher.SHA256) (*visor.Transaction, []visor.TransactionInput, error) {
 	var txn *visor.Transaction
@@ -489,6 +658,7 @@ func (gw *Gateway) GetTransactionVerbose(txid cipher.SHA256) (*visor.Transaction
 // This method is to be used by user-initiated transaction injections.
 // For transactions received over the network, use daemon.injectTransaction and check the result to
 // decide on repropagation.
+// The transaction is rebroadcast until it is confirmed, see DaemonConfig.RebroadcastInterval.
 func (gw *Gateway) InjectBroadcastTransaction(txn coin.Transaction) error {
 	var err error
 	gw.strand("InjectBroadcastTransaction", func() {
@@ -503,12 +673,36 @@ func (gw *Gateway) InjectBroadcastTransaction(txn coin.Transaction) error {
 				return err
 			}
 
+			gw.d.rebroadcaster.add(txn.Hash(), gw.d.now().UTC())
+
 			return nil
 		})
 	})
 	return err
 }
 
+// GetRebroadcastStats returns the broadcast history of the unconfirmed transactions injected by this node
+// that are being rebroadcast, sorted by txid
+func (gw *Gateway) GetRebroadcastStats() []RebroadcastStats {
+	var stats []RebroadcastStats
+	gw.strand("GetRebroadcastStats", func() {
+		stats = gw.d.rebroadcaster.all()
+	})
+	return stats
+}
+
+// GetTxnRebroadcastStats returns the broadcast history of an unconfirmed transaction injected by this node.
+// Returns nil if the transaction is not being rebroadcast
+func (gw *Gateway) GetTxnRebroadcastStats(txid cipher.SHA256) *RebroadcastStats {
+	var stats *RebroadcastStats
+	gw.strand("GetTxnRebroadcastStats", func() {
+		if s, ok := gw.d.rebroadcaster.stats(txid); ok {
+			stats = &s
+		}
+	})
+	return stats
+}
+
 // GetVerboseTransactionsForAddress returns transactions and their verbose input data for a given address.
 // These transactions include confirmed and unconfirmed transactions
 func (gw *Gateway) GetVerboseTransactionsForAddress(a cipher.Address) ([]visor.Transaction, [][]visor.TransactionInput, error) {
@@ -521,6 +715,19 @@ func (gw *Gateway) GetVerboseTransactionsForAddress(a cipher.Address) ([]visor.T
 	return txns, inputs, err
 }
 
+// GetVerboseTransactionsForAddressPage returns a page of the confirmed transactions of an address
+// and their verbose input data, in block seq order
+func (gw *Gateway) GetVerboseTransactionsForAddressPage(a cipher.Address, cursor *historydb.AddressTxnsCursor, offset, limit uint64) ([]visor.Transaction, [][]visor.TransactionInput, *historydb.AddressTxnsCursor, error) {
+	var err error
+	var txns []visor.Transaction
+	var inputs [][]visor.TransactionInput
+	var next *historydb.AddressTxnsCursor
+	gw.strand("GetVerboseTransactionsForAddressPage", func() {
+		txns, inputs, next, err = gw.v.GetVerboseTransactionsForAddressPage(a, cursor, offset, limit)
+	})
+	return txns, inputs, next, err
+}
+
 // GetTransactions returns transactions filtered by zero or more visor.TxFilter
 func (gw *Gateway) GetTransactions(flts []visor.TxFilter) ([]visor.Transaction, error) {
 	var txns []visor.Transaction
@@ -542,6 +749,36 @@ func (gw *Gateway) GetTransactionsVerbose(flts []visor.TxFilter) ([]visor.Transa
 	return txns, inputs, err
 }
 
+// GetAddressMeta returns the activity summary of an address, nil if the address was never used
+func (gw *Gateway) GetAddressMeta(addr cipher.Address) (*historydb.AddressMeta, error) {
+	var meta *historydb.AddressMeta
+	var err error
+	gw.strand("GetAddressMeta", func() {
+		meta, err = gw.v.GetAddressMeta(addr)
+	})
+	return meta, err
+}
+
+// GetAddressBalanceHistory returns the balance history of an address in the blocks of the seq range [start, end]
+func (gw *Gateway) GetAddressBalanceHistory(addr cipher.Address, start, end uint64) (*visor.AddressBalanceHistory, error) {
+	var h *visor.AddressBalanceHistory
+	var err error
+	gw.strand("GetAddressBalanceHistory", func() {
+		h, err = gw.v.GetAddressBalanceHistory(addr, start, end)
+	})
+	return h, err
+}
+
+// ScanAddresses returns the confirmed balances, unspent outputs and most recent transactions of the addresses
+func (gw *Gateway) ScanAddresses(addrs []cipher.Address, numTxns uint64) ([]visor.AddressScan, error) {
+	var scans []visor.AddressScan
+	var err error
+	gw.strand("ScanAddresses", func() {
+		scans, err = gw.v.ScanAddresses(addrs, numTxns)
+	})
+	return scans, err
+}
+
 // GetUxOutByID gets UxOut by hash id.
 func (gw *Gateway) GetUxOutByID(id cipher.SHA256) (*historydb.UxOut, error) {
 	var uxout *historydb.UxOut
@@ -552,6 +789,26 @@ func (gw *Gateway) GetUxOutByID(id cipher.SHA256) (*historydb.UxOut, error) {
 	return uxout, err
 }
 
+// GetUxOutSpender returns the transaction input which spent an output, nil if the output is unspent or not found
+func (gw *Gateway) GetUxOutSpender(id cipher.SHA256) (*historydb.UxOutSpender, error) {
+	var spender *historydb.UxOutSpender
+	var err error
+	gw.strand("GetUxOutSpender", func() {
+		spender, err = gw.v.GetUxOutSpender(id)
+	})
+	return spender, err
+}
+
+// GetOutputsByValue returns a page of the outputs with coins in a range, created by the blocks in a range
+func (gw *Gateway) GetOutputsByValue(q visor.OutputsByValueQuery, cursor *historydb.OutputValuesCursor, limit uint64) (*visor.OutputsByValuePage, error) {
+	var page *visor.OutputsByValuePage
+	var err error
+	gw.strand("GetOutputsByValue", func() {
+		page, err = gw.v.GetOutputsByValue(q, cursor, limit)
+	})
+	return page, err
+}
+
 // GetSpentOutputsForAddresses gets all the spent outputs of a set of addresses
 func (gw *Gateway) GetSpentOutputsForAddresses(addresses []cipher.Address) ([][]historydb.UxOut, error) {
 	var uxOuts [][]historydb.UxOut
@@ -583,6 +840,16 @@ func (gw *Gateway) GetAllUnconfirmedTransactionsVerbose() ([]visor.UnconfirmedTr
 	return txns, inputs, err
 }
 
+// GetEvictedUnconfirmedTxns returns at most limit evictions from the unconfirmed pool with an ID greater than after
+func (gw *Gateway) GetEvictedUnconfirmedTxns(after, limit uint64) ([]visor.EvictedUnconfirmedTxn, error) {
+	var evicted []visor.EvictedUnconfirmedTxn
+	var err error
+	gw.strand("GetEvictedUnconfirmedTxns", func() {
+		evicted, err = gw.v.GetEvictedUnconfirmedTxns(after, limit)
+	})
+	return evicted, err
+}
+
 // GetUnconfirmedTransactions returns addresses related unconfirmed transactions
 func (gw *Gateway) GetUnconfirmedTransactions(addrs []cipher.Address) ([]visor.UnconfirmedTransaction, error) {
 	var txns []visor.UnconfirmedTransaction
@@ -652,6 +919,123 @@ func (gw *Gateway) CreateTransaction(params wallet.CreateTransactionParams) (*co
 	return txn, inputs, err
 }
 
+// CreatePayoutBatch creates the transactions that pay a batch of payouts, see visor.Visor.CreatePayoutBatch
+func (gw *Gateway) CreatePayoutBatch(params wallet.CreateTransactionParams, maxOutputs int) (*wallet.PayoutBatch, error) {
+	if !gw.Config.EnableWalletAPI {
+		return nil, wallet.ErrWalletAPIDisabled
+	}
+
+	var batch *wallet.PayoutBatch
+	var err error
+	gw.strand("CreatePayoutBatch", func() {
+		batch, err = gw.v.CreatePayoutBatch(params, maxOutputs)
+	})
+	return batch, err
+}
+
+// CreateHoursRedistribution creates a transaction that redistributes the coin hours of a wallet's addresses,
+// see visor.Visor.CreateHoursRedistribution
+func (gw *Gateway) CreateHoursRedistribution(p wallet.HoursRedistributionParams) (*coin.Transaction, []wallet.UxBalance, error) {
+	if !gw.Config.EnableWalletAPI {
+		return nil, nil, wallet.ErrWalletAPIDisabled
+	}
+
+	var txn *coin.Transaction
+	var inputs []wallet.UxBalance
+	var err error
+	gw.strand("CreateHoursRedistribution", func() {
+		txn, inputs, err = gw.v.CreateHoursRedistribution(p)
+	})
+	return txn, inputs, err
+}
+
+// SweepPrivateKeys creates the transactions that send all the coins and coin hours of private keys to an address,
+// see visor.Visor.SweepPrivateKeys
+func (gw *Gateway) SweepPrivateKeys(keys []cipher.SecKey, wltID string, dest cipher.Address) (*wallet.Sweep, error) {
+	if !gw.Config.EnableWalletAPI {
+		return nil, wallet.ErrWalletAPIDisabled
+	}
+
+	var sweep *wallet.Sweep
+	var err error
+	gw.strand("SweepPrivateKeys", func() {
+		sweep, err = gw.v.SweepPrivateKeys(keys, wltID, dest)
+	})
+	return sweep, err
+}
+
+// RescanWallet scans the historydb for the activity of the addresses of a wallet, see visor.Visor.RescanWallet
+func (gw *Gateway) RescanWallet(wltID string, password []byte, gapLimit, numTxns uint64) (*visor.WalletRescan, error) {
+	if !gw.Config.EnableWalletAPI {
+		return nil, wallet.ErrWalletAPIDisabled
+	}
+
+	var rescan *visor.WalletRescan
+	var err error
+	gw.strand("RescanWallet", func() {
+		rescan, err = gw.v.RescanWallet(wltID, password, gapLimit, numTxns)
+	})
+	return rescan, err
+}
+
+// WalletAddressPoolStatus returns the status of the address pool of an account of a wallet, see visor.Visor.WalletAddressPoolStatus
+func (gw *Gateway) WalletAddressPoolStatus(wltID, account string) (wallet.AddressPoolStatus, error) {
+	if !gw.Config.EnableWalletAPI {
+		return wallet.AddressPoolStatus{}, wallet.ErrWalletAPIDisabled
+	}
+
+	var status wallet.AddressPoolStatus
+	var err error
+	gw.strand("WalletAddressPoolStatus", func() {
+		status, err = gw.v.WalletAddressPoolStatus(wltID, account)
+	})
+	return status, err
+}
+
+// FillWalletAddressPool pre-generates unused addresses of an account of a wallet, see visor.Visor.FillWalletAddressPool
+func (gw *Gateway) FillWalletAddressPool(wltID, account string, password []byte, size uint64) ([]cipher.Address, wallet.AddressPoolStatus, error) {
+	if !gw.Config.EnableWalletAPI {
+		return nil, wallet.AddressPoolStatus{}, wallet.ErrWalletAPIDisabled
+	}
+
+	var addrs []cipher.Address
+	var status wallet.AddressPoolStatus
+	var err error
+	gw.strand("FillWalletAddressPool", func() {
+		addrs, status, err = gw.v.FillWalletAddressPool(wltID, account, password, size)
+	})
+	return addrs, status, err
+}
+
+// TakeWalletPoolAddresses hands out unused addresses of an account of a wallet, see visor.Visor.TakeWalletPoolAddresses
+func (gw *Gateway) TakeWalletPoolAddresses(wltID, account string, n uint64) ([]cipher.Address, wallet.AddressPoolStatus, error) {
+	if !gw.Config.EnableWalletAPI {
+		return nil, wallet.AddressPoolStatus{}, wallet.ErrWalletAPIDisabled
+	}
+
+	var addrs []cipher.Address
+	var status wallet.AddressPoolStatus
+	var err error
+	gw.strand("TakeWalletPoolAddresses", func() {
+		addrs, status, err = gw.v.TakeWalletPoolAddresses(wltID, account, n)
+	})
+	return addrs, status, err
+}
+
+// CreateWalletAccount adds a named account to a wallet, see wallet.Service.CreateAccount
+func (gw *Gateway) CreateWalletAccount(wltID, name string, password []byte) (wallet.Account, error) {
+	if !gw.Config.EnableWalletAPI {
+		return wallet.Account{}, wallet.ErrWalletAPIDisabled
+	}
+
+	var a wallet.Account
+	var err error
+	gw.strand("CreateWalletAccount", func() {
+		a, err = gw.v.Wallets.CreateAccount(wltID, name, password)
+	})
+	return a, err
+}
+
 // CreateWallet creates wallet
 func (gw *Gateway) CreateWallet(wltName string, options wallet.Options) (*wallet.Wallet, error) {
 	if !gw.Config.EnableWalletAPI {
@@ -666,8 +1050,8 @@ func (gw *Gateway) CreateWallet(wltName string, options wallet.Options) (*wallet
 	return wlt, err
 }
 
-// RecoverWallet recovers an encrypted wallet from seed
-func (gw *Gateway) RecoverWallet(wltName, seed string, password []byte) (*wallet.Wallet, error) {
+// RecoverWallet recovers an encrypted wallet from seed and its BIP39 passphrase
+func (gw *Gateway) RecoverWallet(wltName, seed, seedPassphrase string, password []byte) (*wallet.Wallet, error) {
 	if !gw.Config.EnableWalletAPI {
 		return nil, wallet.ErrWalletAPIDisabled
 	}
@@ -675,7 +1059,79 @@ func (gw *Gateway) RecoverWallet(wltName, seed string, password []byte) (*wallet
 	var err error
 	var w *wallet.Wallet
 	gw.strand("RecoverWallet", func() {
-		w, err = gw.v.Wallets.RecoverWallet(wltName, seed, password)
+		w, err = gw.v.Wallets.RecoverWallet(wltName, seed, seedPassphrase, password)
+	})
+	return w, err
+}
+
+// SignPartialTransaction signs the inputs of a partial transaction that are owned by a wallet
+func (gw *Gateway) SignPartialTransaction(wltID string, password []byte, ptx *wallet.PartialTransaction) (*wallet.PartialTransaction, error) {
+	if !gw.Config.EnableWalletAPI {
+		return nil, wallet.ErrWalletAPIDisabled
+	}
+
+	var err error
+	var signed *wallet.PartialTransaction
+	gw.strand("SignPartialTransaction", func() {
+		signed, err = gw.v.Wallets.SignPartialTransaction(wltID, password, ptx)
+	})
+	return signed, err
+}
+
+// CosignPartialTransaction signs the inputs of a partial transaction that are owned by a wallet, if it has any,
+// and returns the status of the cosigners of the wallet
+func (gw *Gateway) CosignPartialTransaction(wltID string, password []byte, ptx *wallet.PartialTransaction) (*wallet.PartialTransaction, []wallet.CosignerStatus, error) {
+	if !gw.Config.EnableWalletAPI {
+		return nil, nil, wallet.ErrWalletAPIDisabled
+	}
+
+	var err error
+	var signed *wallet.PartialTransaction
+	var status []wallet.CosignerStatus
+	gw.strand("CosignPartialTransaction", func() {
+		signed, status, err = gw.v.Wallets.CosignPartialTransaction(wltID, password, ptx)
+	})
+	return signed, status, err
+}
+
+// CreateWatchOnlyWallet creates a watch-only wallet of addresses
+func (gw *Gateway) CreateWatchOnlyWallet(wltName, label string, addrs []cipher.Address) (*wallet.Wallet, error) {
+	if !gw.Config.EnableWalletAPI {
+		return nil, wallet.ErrWalletAPIDisabled
+	}
+
+	var err error
+	var w *wallet.Wallet
+	gw.strand("CreateWatchOnlyWallet", func() {
+		w, err = gw.v.Wallets.CreateWatchOnlyWallet(wltName, label, addrs)
+	})
+	return w, err
+}
+
+// AddWatchOnlyAddresses adds addresses to a watch-only wallet
+func (gw *Gateway) AddWatchOnlyAddresses(wltID string, addrs []cipher.Address) (*wallet.Wallet, error) {
+	if !gw.Config.EnableWalletAPI {
+		return nil, wallet.ErrWalletAPIDisabled
+	}
+
+	var err error
+	var w *wallet.Wallet
+	gw.strand("AddWatchAddresses", func() {
+		w, err = gw.v.Wallets.AddWatchOnlyAddresses(wltID, addrs)
+	})
+	return w, err
+}
+
+// AddMultisig adds the address of an m-of-n multisig to a watch-only wallet
+func (gw *Gateway) AddMultisig(wltID string, ms cipher.Multisig) (*wallet.Wallet, error) {
+	if !gw.Config.EnableWalletAPI {
+		return nil, wallet.ErrWalletAPIDisabled
+	}
+
+	var err error
+	var w *wallet.Wallet
+	gw.strand("AddMultisig", func() {
+		w, err = gw.v.Wallets.AddMultisig(wltID, ms)
 	})
 	return w, err
 }
@@ -724,6 +1180,22 @@ func (gw *Gateway) GetWalletBalance(wltID string) (wallet.BalancePair, wallet.Ad
 	return walletBalance, addressBalances, err
 }
 
+// GetWalletAccountBalance returns balance pairs of an account of a wallet, see visor.Visor.GetWalletAccountBalance
+func (gw *Gateway) GetWalletAccountBalance(wltID, account string) (wallet.BalancePair, wallet.AddressBalances, error) {
+	var walletBalance wallet.BalancePair
+	var addressBalances wallet.AddressBalances
+
+	if !gw.Config.EnableWalletAPI {
+		return walletBalance, addressBalances, wallet.ErrWalletAPIDisabled
+	}
+
+	var err error
+	gw.strand("GetWalletAccountBalance", func() {
+		walletBalance, addressBalances, err = gw.v.GetWalletAccountBalance(wltID, account)
+	})
+	return walletBalance, addressBalances, err
+}
+
 // GetBalanceOfAddrs gets balance of given addresses
 func (gw *Gateway) GetBalanceOfAddrs(addrs []cipher.Address) ([]wallet.BalancePair, error) {
 	var balance []wallet.BalancePair
@@ -775,6 +1247,62 @@ func (gw *Gateway) UpdateWalletLabel(wltID, label string) error {
 	return err
 }
 
+// SetAddressAnnotation annotates an address of a wallet
+func (gw *Gateway) SetAddressAnnotation(wltID string, addr cipher.Address, a wallet.Annotation) (*wallet.Wallet, error) {
+	if !gw.Config.EnableWalletAPI {
+		return nil, wallet.ErrWalletAPIDisabled
+	}
+
+	var err error
+	var w *wallet.Wallet
+	gw.strand("SetAddressAnnotation", func() {
+		w, err = gw.v.Wallets.SetAddressAnnotation(wltID, addr, a)
+	})
+	return w, err
+}
+
+// SetTransactionAnnotation annotates a transaction in a wallet
+func (gw *Gateway) SetTransactionAnnotation(wltID string, txid cipher.SHA256, a wallet.Annotation) (*wallet.Wallet, error) {
+	if !gw.Config.EnableWalletAPI {
+		return nil, wallet.ErrWalletAPIDisabled
+	}
+
+	var err error
+	var w *wallet.Wallet
+	gw.strand("SetTransactionAnnotation", func() {
+		w, err = gw.v.Wallets.SetTransactionAnnotation(wltID, txid, a)
+	})
+	return w, err
+}
+
+// SetSpendingPolicy sets the spending policy of a wallet
+func (gw *Gateway) SetSpendingPolicy(wltID string, password []byte, p wallet.SpendingPolicy) (*wallet.Wallet, error) {
+	if !gw.Config.EnableWalletAPI {
+		return nil, wallet.ErrWalletAPIDisabled
+	}
+
+	var err error
+	var w *wallet.Wallet
+	gw.strand("SetSpendingPolicy", func() {
+		w, err = gw.v.Wallets.SetSpendingPolicy(wltID, password, p)
+	})
+	return w, err
+}
+
+// SetCosigners sets the cosigners of a wallet
+func (gw *Gateway) SetCosigners(wltID string, cs wallet.Cosigners) (*wallet.Wallet, error) {
+	if !gw.Config.EnableWalletAPI {
+		return nil, wallet.ErrWalletAPIDisabled
+	}
+
+	var err error
+	var w *wallet.Wallet
+	gw.strand("SetCosigners", func() {
+		w, err = gw.v.Wallets.SetCosigners(wltID, cs)
+	})
+	return w, err
+}
+
 // GetWallet returns wallet by id
 func (gw *Gateway) GetWallet(wltID string) (*wallet.Wallet, error) {
 	if !gw.Config.EnableWalletAPI {
@@ -832,6 +1360,67 @@ func (gw *Gateway) GetWalletUnconfirmedTransactionsVerbose(wltID string) ([]viso
 	return txns, inputs, err
 }
 
+// GetWalletHistory returns the history of the confirmed transactions of a wallet, see visor.Visor.GetWalletHistory.
+// If ps is not nil, the entries are annotated with their fiat value. The prices are looked up outside of the strand,
+// since a price source may be slow.
+func (gw *Gateway) GetWalletHistory(wltID, account string, ps history.PriceSource) ([]history.Entry, error) {
+	if !gw.Config.EnableWalletAPI {
+		return nil, wallet.ErrWalletAPIDisabled
+	}
+
+	var entries []history.Entry
+	var err error
+	gw.strand("GetWalletHistory", func() {
+		entries, err = gw.v.GetWalletHistory(wltID, account)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if ps != nil {
+		if err := history.AnnotateFiatValue(entries, ps); err != nil {
+			return nil, err
+		}
+	}
+
+	return entries, nil
+}
+
+// GetWalletEvents returns at most limit incoming funds events of a wallet with an ID greater than after
+func (gw *Gateway) GetWalletEvents(wltID string, after, limit uint64) ([]visor.WalletEvent, error) {
+	if !gw.Config.EnableWalletAPI {
+		return nil, wallet.ErrWalletAPIDisabled
+	}
+
+	var events []visor.WalletEvent
+	var err error
+	gw.strand("GetWalletEvents", func() {
+		events, err = gw.v.GetWalletEvents(wltID, after, limit)
+	})
+	return events, err
+}
+
+// WaitWalletEvents returns at most limit incoming funds events of a wallet with an ID greater than after,
+// waiting for new events until ctx is done if there are none.
+// It is not run in the daemon strand, since it blocks until events are recorded by the daemon.
+func (gw *Gateway) WaitWalletEvents(ctx context.Context, wltID string, after, limit uint64) ([]visor.WalletEvent, error) {
+	if !gw.Config.EnableWalletAPI {
+		return nil, wallet.ErrWalletAPIDisabled
+	}
+
+	return gw.v.WaitWalletEvents(ctx, wltID, after, limit)
+}
+
+// SubscribeWalletEvents returns a channel that receives the incoming funds events of a wallet from now on,
+// and a function that unsubscribes. The events are dropped for a subscriber that does not keep up
+func (gw *Gateway) SubscribeWalletEvents(wltID string) (<-chan visor.WalletEvent, func(), error) {
+	if !gw.Config.EnableWalletAPI {
+		return nil, nil, wallet.ErrWalletAPIDisabled
+	}
+
+	return gw.v.SubscribeWalletEvents(wltID)
+}
+
 // UnloadWallet removes wallet of given id from memory.
 func (gw *Gateway) UnloadWallet(id string) error {
 	if !gw.Config.EnableWalletAPI {
@@ -860,48 +1449,68 @@ func (gw *Gateway) GetWalletSeed(id string, password []byte) (string, error) {
 	return seed, err
 }
 
-// GetRichlist returns rich list as desc order.
-func (gw *Gateway) GetRichlist(includeDistribution bool) (visor.Richlist, error) {
-	rbOuts, err := gw.GetUnspentOutputsSummary(nil)
-	if err != nil {
-		return nil, err
+// BackupWallets creates a backup archive of wallets, encrypted with a passphrase, see wallet.Service.Backup
+func (gw *Gateway) BackupWallets(ids []string, passphrase []byte) (*wallet.BackupArchive, error) {
+	if !gw.Config.EnableWalletAPI {
+		return nil, wallet.ErrWalletAPIDisabled
 	}
 
-	// Build a map from addresses to total coins held
-	allAccounts := map[string]uint64{}
-	for _, out := range rbOuts.Confirmed {
-		addr := out.Body.Address.String()
-		if _, ok := allAccounts[addr]; ok {
-			var err error
-			allAccounts[addr], err = coin.AddUint64(allAccounts[addr], out.Body.Coins)
-			if err != nil {
-				return nil, err
-			}
-		} else {
-			allAccounts[addr] = out.Body.Coins
-		}
-	}
+	var a *wallet.BackupArchive
+	var err error
+	gw.strand("BackupWallets", func() {
+		a, err = gw.v.Wallets.Backup(ids, passphrase)
+	})
+	return a, err
+}
 
-	lockedAddrs := params.GetLockedDistributionAddresses()
-	addrsMap := make(map[string]struct{}, len(lockedAddrs))
-	for _, a := range lockedAddrs {
-		addrsMap[a] = struct{}{}
+// RestoreWallets restores the wallets of a backup archive, see wallet.Service.RestoreBackup
+func (gw *Gateway) RestoreWallets(a wallet.BackupArchive, passphrase []byte, skipConflicts bool) (*wallet.RestoreResult, error) {
+	if !gw.Config.EnableWalletAPI {
+		return nil, wallet.ErrWalletAPIDisabled
 	}
 
-	richlist, err := visor.NewRichlist(allAccounts, addrsMap)
-	if err != nil {
-		return nil, err
-	}
+	var result *wallet.RestoreResult
+	var err error
+	gw.strand("RestoreWallets", func() {
+		result, err = gw.v.Wallets.RestoreBackup(a, passphrase, skipConflicts)
+	})
+	return result, err
+}
 
-	if !includeDistribution {
-		unlockedAddrs := params.GetUnlockedDistributionAddresses()
-		for _, a := range unlockedAddrs {
-			addrsMap[a] = struct{}{}
-		}
-		richlist = richlist.FilterAddresses(addrsMap)
+// ImportWallet imports a wallet from the data of another format, see wallet.Service.ImportWallet
+func (gw *Gateway) ImportWallet(wltName string, opts wallet.ImportOptions) (*wallet.Wallet, error) {
+	if !gw.Config.EnableWalletAPI {
+		return nil, wallet.ErrWalletAPIDisabled
 	}
 
-	return richlist, nil
+	var w *wallet.Wallet
+	var err error
+	gw.strand("ImportWallet", func() {
+		w, err = gw.v.Wallets.ImportWallet(wltName, opts)
+	})
+	return w, err
+}
+
+// GetRichlist returns the n addresses with the highest balances, in descending balance order.
+// If n is 0, all addresses with a balance are returned.
+func (gw *Gateway) GetRichlist(n uint64, includeDistribution bool) (visor.Richlist, error) {
+	var richlist visor.Richlist
+	var err error
+	gw.strand("GetRichlist", func() {
+		richlist, err = gw.v.GetRichlist(n, includeDistribution)
+	})
+	return richlist, err
+}
+
+// GetBalanceDistribution returns the number of addresses and the coins they hold in each range
+// of the balance distribution
+func (gw *Gateway) GetBalanceDistribution() ([]historydb.BalanceRange, error) {
+	var ranges []historydb.BalanceRange
+	var err error
+	gw.strand("GetBalanceDistribution", func() {
+		ranges, err = gw.v.GetBalanceDistribution()
+	})
+	return ranges, err
 }
 
 // GetAddressCount returns count number of unique address with uxouts > 0.
@@ -924,6 +1533,12 @@ type Health struct {
 	Uptime                        time.Duration
 	UnconfirmedBurnFactor         uint32
 	UnconfirmedMaxTransactionSize uint32
+	// DBReplica is the status of the read replica of the database, nil if the replica is disabled
+	DBReplica *visor.DBReplicaStatus
+	// Offline is true if networking is disabled
+	Offline bool
+	// ClockSkew is the offset of the local clock from the clocks of the peers
+	ClockSkew ClockSkew
 }
 
 // GetHealth returns statistics about the running node
@@ -961,12 +1576,101 @@ func (gw *Gateway) GetHealth() (*Health, error) {
 			Uptime:                        time.Since(gw.v.StartedAt),
 			UnconfirmedBurnFactor:         gw.d.Config.UnconfirmedBurnFactor,
 			UnconfirmedMaxTransactionSize: gw.d.Config.UnconfirmedMaxTransactionSize,
+			DBReplica:                     gw.v.GetDBReplicaStatus(),
+			Offline:                       gw.d.Config.DisableNetworking,
+			ClockSkew:                     gw.d.networkTime.clockSkew(),
 		}
 	})
 
 	return health, err
 }
 
+// BackupDB writes a snapshot of the database to the backup directory.
+// It is not run in the daemon strand, since the backup can take a long time
+// and the bolt read transaction it uses does not block the daemon.
+func (gw *Gateway) BackupDB() (*visor.DBBackup, error) {
+	return gw.v.BackupDB()
+}
+
+// GetDBStats returns the size and page utilization of the database files and buckets.
+// It is not run in the daemon strand, since every page of the database is read.
+func (gw *Gateway) GetDBStats() (*dbutil.Stats, error) {
+	return gw.v.GetDBStats()
+}
+
+// CollectOrphanedUxOuts scans the historydb for orphaned outputs and deletes them if del is true.
+// It is not run in the daemon strand, since every output of the historydb is read.
+func (gw *Gateway) CollectOrphanedUxOuts(del bool) (*visor.OrphanedUxOutsReport, error) {
+	return gw.v.CollectOrphanedUxOuts(del)
+}
+
+// AddWatchAddresses adds addresses to the watch list, returns the number of addresses that were not watched already
+func (gw *Gateway) AddWatchAddresses(addrs []cipher.Address) (uint64, error) {
+	var n uint64
+	var err error
+	gw.strand("AddWatchAddresses", func() {
+		n, err = gw.v.AddWatchAddresses(addrs)
+	})
+	return n, err
+}
+
+// RemoveWatchAddresses removes addresses from the watch list, returns the number of addresses that were watched
+func (gw *Gateway) RemoveWatchAddresses(addrs []cipher.Address) (uint64, error) {
+	var n uint64
+	var err error
+	gw.strand("RemoveWatchAddresses", func() {
+		n, err = gw.v.RemoveWatchAddresses(addrs)
+	})
+	return n, err
+}
+
+// GetWatchAddresses returns the watched addresses
+func (gw *Gateway) GetWatchAddresses() ([]cipher.Address, error) {
+	var addrs []cipher.Address
+	var err error
+	gw.strand("GetWatchAddresses", func() {
+		addrs, err = gw.v.GetWatchAddresses()
+	})
+	return addrs, err
+}
+
+// GetWatchEvents returns at most limit watch events with an ID greater than after
+func (gw *Gateway) GetWatchEvents(after, limit uint64) ([]visor.WatchEvent, error) {
+	var events []visor.WatchEvent
+	var err error
+	gw.strand("GetWatchEvents", func() {
+		events, err = gw.v.GetWatchEvents(after, limit)
+	})
+	return events, err
+}
+
+// WaitWatchEvents returns at most limit watch events with an ID greater than after,
+// waiting for new events until ctx is done if there are none.
+// It is not run in the daemon strand, since it blocks until events are recorded by the daemon.
+func (gw *Gateway) WaitWatchEvents(ctx context.Context, after, limit uint64) ([]visor.WatchEvent, error) {
+	return gw.v.WaitWatchEvents(ctx, after, limit)
+}
+
+// GetDBVerifyStatus returns the status of the background database verification
+func (gw *Gateway) GetDBVerifyStatus() visor.DBVerifyStatus {
+	var status visor.DBVerifyStatus
+	gw.strand("GetDBVerifyStatus", func() {
+		status = gw.v.GetDBVerifyStatus()
+	})
+	return status
+}
+
+// GetDBIntegrityReport returns the report of the last database verification and the recovery actions
+// taken on the database, or nil if there is none
+func (gw *Gateway) GetDBIntegrityReport() (*visor.DBIntegrityReport, error) {
+	var report *visor.DBIntegrityReport
+	var err error
+	gw.strand("GetDBIntegrityReport", func() {
+		report, err = gw.v.GetDBIntegrityReport()
+	})
+	return report, err
+}
+
 // VerifyTxnVerbose verifies an isolated transaction and returns []wallet.UxBalance of
 // transaction inputs, whether the transaction is confirmed and error if any
 func (gw *Gateway) VerifyTxnVerbose(txn *coin.Transaction) ([]wallet.UxBalance, bool, error) {
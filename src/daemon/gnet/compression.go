@@ -0,0 +1,96 @@
+package gnet
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/golang/snappy"
+
+	"github.com/skycoin/skycoin/src/cipher/encoder"
+)
+
+// compressedMessagePrefix identifies a message whose id and data are compressed with snappy.
+// It is reserved, and can't be registered with RegisterMessage
+var compressedMessagePrefix = MessagePrefix{'S', 'N', 'P', 'Y'}
+
+// CompressionStats are the sizes of the messages compressed and decompressed by the pool,
+// including their length prefix
+type CompressionStats struct {
+	// Number of compressed messages sent
+	SentMessages uint64
+	// Size of the compressed messages sent, before compression
+	SentSize uint64
+	// Size of the compressed messages sent
+	SentCompressedSize uint64
+	// Number of compressed messages received
+	ReceivedMessages uint64
+	// Size of the compressed messages received, after decompression
+	ReceivedSize uint64
+	// Size of the compressed messages received
+	ReceivedCompressedSize uint64
+}
+
+// compressionStats records the CompressionStats of a pool
+type compressionStats struct {
+	sync.Mutex
+	stats CompressionStats
+}
+
+func (s *compressionStats) sent(size, compressedSize int) {
+	s.Lock()
+	defer s.Unlock()
+	s.stats.SentMessages++
+	s.stats.SentSize += uint64(size)
+	s.stats.SentCompressedSize += uint64(compressedSize)
+}
+
+func (s *compressionStats) received(size, compressedSize int) {
+	s.Lock()
+	defer s.Unlock()
+	s.stats.ReceivedMessages++
+	s.stats.ReceivedSize += uint64(size)
+	s.stats.ReceivedCompressedSize += uint64(compressedSize)
+}
+
+func (s *compressionStats) get() CompressionStats {
+	s.Lock()
+	defer s.Unlock()
+	return s.stats
+}
+
+// compressMessage compresses the id and data of a message encoded by EncodeMessage.
+// Returns false if the compressed message would not be smaller
+func compressMessage(b []byte) ([]byte, bool) {
+	compressed := snappy.Encode(nil, b[messageLengthSize:])
+	if messagePrefixLength+len(compressed) >= len(b)-messageLengthSize {
+		return nil, false
+	}
+
+	m := make([]byte, 0, messageLengthSize+messagePrefixLength+len(compressed))
+	m = append(m, encoder.SerializeAtomic(uint32(messagePrefixLength+len(compressed)))...)
+	m = append(m, compressedMessagePrefix[:]...)
+	return append(m, compressed...), true
+}
+
+// isCompressedMessage returns true if the message read from a connection is compressed
+func isCompressedMessage(msg []byte) bool {
+	return len(msg) >= messagePrefixLength && bytes.Equal(msg[:messagePrefixLength], compressedMessagePrefix[:])
+}
+
+// decompressMessage returns the id and data of a compressed message read from a connection.
+// The decompressed message can't be larger than maxMsgLength, nor be compressed again
+func decompressMessage(msg []byte, maxMsgLength int) ([]byte, error) {
+	compressed := msg[messagePrefixLength:]
+
+	n, err := snappy.DecodedLen(compressed)
+	if err != nil || n < messagePrefixLength || n > maxMsgLength {
+		return nil, ErrDisconnectInvalidCompressedMessage
+	}
+
+	data, err := snappy.Decode(nil, compressed)
+	if err != nil || isCompressedMessage(data) {
+		return nil, ErrDisconnectInvalidCompressedMessage
+	}
+
+	return data, nil
+}
@@ -0,0 +1,142 @@
+package gnet
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressMessage(t *testing.T) {
+	// A message that compression would not make smaller is not compressed
+	b := append([]byte{5, 0, 0, 0}, append(BytePrefix[:], 7)...)
+	_, ok := compressMessage(b)
+	require.False(t, ok)
+
+	data := append(BytePrefix[:], bytes.Repeat([]byte{7}, 4096)...)
+	b = append([]byte{0, 16, 0, 0}, data...)
+	cb, ok := compressMessage(b)
+	require.True(t, ok)
+	require.True(t, len(cb) < len(b))
+
+	msgs, err := decodeData(bytes.NewBuffer(cb), 256*1024)
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	require.True(t, isCompressedMessage(msgs[0]))
+
+	d, err := decompressMessage(msgs[0], 256*1024)
+	require.NoError(t, err)
+	require.Equal(t, data, d)
+
+	require.False(t, isCompressedMessage(data))
+}
+
+func TestDecodeDataMessageTooLong(t *testing.T) {
+	EraseMessages()
+	RegisterMessage(BytePrefix, ByteMessage{})
+	RegisterMessageMaxLength(BytePrefix, 5)
+	defer EraseMessages()
+
+	b := append([]byte{5, 0, 0, 0}, append(BytePrefix[:], 7)...)
+	msgs, err := decodeData(bytes.NewBuffer(b), 256*1024)
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{append(BytePrefix[:], 7)}, msgs)
+
+	// The length of a message is rejected once its id is received, before the rest of the message
+	b = append([]byte{0, 1, 0, 0}, BytePrefix[:]...)
+	msgs, err = decodeData(bytes.NewBuffer(b), 256*1024)
+	require.Equal(t, ErrDisconnectMessageTooLong, err)
+	require.Empty(t, msgs)
+
+	// The length is not rejected before the id is received
+	msgs, err = decodeData(bytes.NewBuffer(b[:6]), 256*1024)
+	require.NoError(t, err)
+	require.Empty(t, msgs)
+}
+
+func TestDecompressMessageInvalid(t *testing.T) {
+	compressed := func(data []byte) []byte {
+		return append(compressedMessagePrefix[:], snappy.Encode(nil, data)...)
+	}
+
+	cases := []struct {
+		name string
+		msg  []byte
+	}{
+		{
+			name: "invalid snappy data",
+			msg:  append(compressedMessagePrefix[:], 0xFF, 0xFF, 0xFF),
+		},
+		{
+			name: "too large",
+			msg:  compressed(append(BytePrefix[:], bytes.Repeat([]byte{7}, 1024)...)),
+		},
+		{
+			name: "truncated message id",
+			msg:  compressed([]byte{'B'}),
+		},
+		{
+			name: "compressed twice",
+			msg:  compressed(compressed(append(BytePrefix[:], 7))),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := decompressMessage(tc.msg, 512)
+			require.Equal(t, ErrDisconnectInvalidCompressedMessage, err)
+		})
+	}
+}
+
+func TestRegisterMessageCompressedPrefix(t *testing.T) {
+	EraseMessages()
+	assert.Panics(t, func() { RegisterMessage(compressedMessagePrefix, DummyMessage{}) })
+}
+
+func TestPoolCompression(t *testing.T) {
+	wait()
+	resetHandler()
+	EraseMessages()
+	RegisterMessage(BytePrefix, ByteMessage{})
+	VerifyMessages()
+
+	cfg := newTestConfig()
+	p, err := NewConnectionPool(cfg, nil)
+	require.NoError(t, err)
+
+	c := NewConnection(p, 1, NewDummyConn(addr), 10, true)
+	p.pool[c.ID] = c
+	p.addresses[addr] = c
+
+	q := make(chan struct{})
+	go func() {
+		defer close(q)
+		err := p.Run()
+		require.NoError(t, err)
+	}()
+	wait()
+
+	require.False(t, c.Compression())
+	require.NoError(t, p.EnableCompression(addr))
+	require.True(t, c.Compression())
+	require.Error(t, p.EnableCompression("127.0.0.1:6000"))
+
+	// A compressed message is decompressed and handled
+	msg := append(compressedMessagePrefix[:], snappy.Encode(nil, append(BytePrefix[:], 7))...)
+	err = p.receiveMessage(c, msg)
+	require.NoError(t, err)
+	require.Equal(t, CompressionStats{
+		ReceivedMessages:       1,
+		ReceivedSize:           uint64(messageLengthSize + len(BytePrefix) + 1),
+		ReceivedCompressedSize: uint64(messageLengthSize + len(msg)),
+	}, p.CompressionStats())
+
+	err = p.receiveMessage(c, append(compressedMessagePrefix[:], 0xFF))
+	require.Equal(t, ErrDisconnectInvalidCompressedMessage, err)
+
+	p.Shutdown()
+	<-q
+}
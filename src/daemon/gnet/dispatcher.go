@@ -53,7 +53,6 @@ func convertToMessage(id uint64, msg []byte, debugPrint bool) (Message, error) {
 		logger.WithField("msgID", msgIDStringSafe(msgID)).Debug("Received message")
 	}
 
-	msg = msg[len(msgID):]
 	t, ok := MessageIDReverseMap[msgID]
 	if !ok {
 		logger.WithError(ErrDisconnectUnknownMessage).WithFields(logrus.Fields{
@@ -63,6 +62,18 @@ func convertToMessage(id uint64, msg []byte, debugPrint bool) (Message, error) {
 		return nil, ErrDisconnectUnknownMessage
 	}
 
+	// Decompressed messages are only checked against the maximum length of their type here
+	if !checkMessageMaxLength(msgID, len(msg)) {
+		logger.WithError(ErrDisconnectMessageTooLong).WithFields(logrus.Fields{
+			"msgID":  msgIDStringSafe(msgID),
+			"connID": id,
+			"length": len(msg),
+		}).Warning()
+		return nil, ErrDisconnectMessageTooLong
+	}
+
+	msg = msg[len(msgID):]
+
 	if debugPrint {
 		logger.WithFields(logrus.Fields{
 			"connID":      id,
@@ -77,6 +88,9 @@ func convertToMessage(id uint64, msg []byte, debugPrint bool) (Message, error) {
 			"connID":      id,
 			"messageType": fmt.Sprintf("%v", t),
 		}).Warning("deserializeMessage failed")
+		if err == encoder.ErrMaxLenExceeded {
+			return nil, ErrDisconnectMessageFieldTooLong
+		}
 		return nil, ErrDisconnectMalformedMessage
 	}
 
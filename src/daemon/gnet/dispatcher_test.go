@@ -98,6 +98,44 @@ func TestConvertToMessageBadDeserialize(t *testing.T) {
 	require.Nil(t, m)
 }
 
+func TestConvertToMessageTooLong(t *testing.T) {
+	EraseMessages()
+	resetHandler()
+	RegisterMessage(BytePrefix, ByteMessage{})
+	RegisterMessageMaxLength(BytePrefix, 5)
+	VerifyMessages()
+	c := &Connection{}
+
+	// The maximum length includes the message id
+	m, err := convertToMessage(c.ID, append(BytePrefix[:], 7), testing.Verbose())
+	require.NoError(t, err)
+	require.Equal(t, &ByteMessage{X: 7}, m)
+
+	m, err = convertToMessage(c.ID, append(BytePrefix[:], 7, 7), testing.Verbose())
+	require.Equal(t, ErrDisconnectMessageTooLong, err)
+	require.Nil(t, m)
+}
+
+func TestConvertToMessageFieldTooLong(t *testing.T) {
+	EraseMessages()
+	resetHandler()
+	RegisterMessage(StringPrefix, StringMessage{})
+	VerifyMessages()
+	c := &Connection{}
+
+	b := append(StringPrefix[:], 8, 0, 0, 0)
+	b = append(b, "12345678"...)
+	m, err := convertToMessage(c.ID, b, testing.Verbose())
+	require.NoError(t, err)
+	require.Equal(t, &StringMessage{S: "12345678"}, m)
+
+	// The field length is rejected before the field is read
+	b = append(StringPrefix[:], 0, 0, 0, 1)
+	m, err = convertToMessage(c.ID, b, testing.Verbose())
+	require.Equal(t, ErrDisconnectMessageFieldTooLong, err)
+	require.Nil(t, m)
+}
+
 func TestConvertToMessageNotMessage(t *testing.T) {
 	EraseMessages()
 	resetHandler()
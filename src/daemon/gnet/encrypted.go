@@ -0,0 +1,494 @@
+package gnet
+
+import (
+	"bytes"
+	gocipher "crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/chacha20poly1305"
+)
+
+// The encrypted transport is a Noise XX handshake over secp256k1: the peers exchange ephemeral keys,
+// then each sends its static key encrypted, and proves it with an ECDH of the static key and the
+// ephemeral key of the other peer. One chacha20poly1305 key per direction is derived from the chaining key,
+// and the data is then sent in authenticated frames.
+//
+//	-> e
+//	<- e, ee, s, es, features
+//	-> s, se, features
+//
+// The static key of a peer identifies it: the connections to a peer whose key is known fail if it authenticates
+// with another key. Each peer also sends the feature bits of its introduction, which are bound into the
+// handshake transcript, so that a peer can check that the features of the introduction were not altered.
+//
+// The outgoing connection sends its handshake first. An incoming connection that does not start with
+// encryptedTransportMagic is a plaintext connection.
+
+// encryptedTransportMagic starts the handshake. Read as a message length prefix it exceeds the maximum
+// message length, so it can't be confused with the first message of a plaintext connection
+var encryptedTransportMagic = [4]byte{'S', 'K', 'Y', 'E'}
+
+const (
+	encryptedTransportVersion = 2
+	// Size of the handshake header of each peer: magic, version and ephemeral public key.
+	// A responder that declines the encrypted transport sends an empty public key, and nothing else
+	encryptedHandshakeSize = len(encryptedTransportMagic) + 1 + len(cipher.PubKey{})
+	// Byte size of the poly1305 tag of the encrypted handshake payloads
+	encryptedHandshakeTagSize = 16
+	// Size of the encrypted static key and feature bits that each peer sends after the ephemeral keys
+	encryptedHandshakePayloadSize = len(cipher.PubKey{}) + encryptedHandshakeTagSize + 8 + encryptedHandshakeTagSize
+	// encryptedHandshakeTimeout is how long the peers have to complete the handshake
+	encryptedHandshakeTimeout = time.Second * 10
+	// maxEncryptedFrameSize is the maximum size of the data of a frame
+	maxEncryptedFrameSize = 64 * 1024
+	// Byte size of the length prefix of a frame
+	encryptedFrameLengthSize = 4
+	// encryptedTransportLabel is hashed into the handshake transcript
+	encryptedTransportLabel = "skycoin-gnet-encrypted-transport-xx"
+)
+
+var (
+	// ErrInvalidEncryptedHandshake the handshake of the peer is invalid
+	ErrInvalidEncryptedHandshake = errors.New("Invalid encrypted transport handshake")
+	// ErrInvalidEncryptedFrame an encrypted frame has an invalid length or does not authenticate
+	ErrInvalidEncryptedFrame = errors.New("Invalid encrypted frame")
+	// ErrEncryptedTransportDeclined the peer declined the encrypted transport
+	ErrEncryptedTransportDeclined = errors.New("Encrypted transport declined by the peer")
+	// ErrEncryptedTransportPeerKey the peer authenticated with another key than the key expected for it
+	ErrEncryptedTransportPeerKey = errors.New("Encrypted transport peer key does not match the expected key")
+)
+
+// HandshakeError is returned when the encrypted transport handshake of a connection fails
+type HandshakeError struct {
+	Err error
+}
+
+func (e HandshakeError) Error() string {
+	return fmt.Sprintf("encrypted transport handshake failed: %v", e.Err)
+}
+
+// encryptedConn is a net.Conn that encrypts the data written and decrypts the data read
+type encryptedConn struct {
+	net.Conn
+
+	writeLock  sync.Mutex
+	writeAEAD  gocipher.AEAD
+	writeNonce uint64
+
+	readLock  sync.Mutex
+	readAEAD  gocipher.AEAD
+	readNonce uint64
+	// Decrypted data of the last frame that was not read yet
+	readBuf []byte
+
+	// Static key the peer authenticated with in the handshake
+	peerKey cipher.PubKey
+	// Feature bits the peer sent in the handshake
+	peerFeatures uint64
+}
+
+func newEncryptedConn(conn net.Conn, writeKey, readKey []byte) (*encryptedConn, error) {
+	writeAEAD, err := chacha20poly1305.New(writeKey)
+	if err != nil {
+		return nil, err
+	}
+
+	readAEAD, err := chacha20poly1305.New(readKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &encryptedConn{
+		Conn:      conn,
+		writeAEAD: writeAEAD,
+		readAEAD:  readAEAD,
+	}, nil
+}
+
+func frameNonce(n uint64) []byte {
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	binary.LittleEndian.PutUint64(nonce[chacha20poly1305.NonceSize-8:], n)
+	return nonce
+}
+
+// Write encrypts b in frames of at most maxEncryptedFrameSize bytes and writes them
+func (c *encryptedConn) Write(b []byte) (int, error) {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	n := 0
+	for n < len(b) {
+		end := n + maxEncryptedFrameSize
+		if end > len(b) {
+			end = len(b)
+		}
+
+		frame := make([]byte, encryptedFrameLengthSize, encryptedFrameLengthSize+end-n+c.writeAEAD.Overhead())
+		binary.LittleEndian.PutUint32(frame, uint32(end-n+c.writeAEAD.Overhead()))
+		frame = c.writeAEAD.Seal(frame, frameNonce(c.writeNonce), b[n:end], frame[:encryptedFrameLengthSize])
+		c.writeNonce++
+
+		if _, err := c.Conn.Write(frame); err != nil {
+			return n, err
+		}
+
+		n = end
+	}
+
+	return n, nil
+}
+
+// Read reads and decrypts a frame if the data of the previous frame was read
+func (c *encryptedConn) Read(b []byte) (int, error) {
+	c.readLock.Lock()
+	defer c.readLock.Unlock()
+
+	if len(c.readBuf) == 0 {
+		if err := c.readFrame(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(b, c.readBuf)
+	c.readBuf = c.readBuf[n:]
+	return n, nil
+}
+
+func (c *encryptedConn) readFrame() error {
+	var prefix [encryptedFrameLengthSize]byte
+	if _, err := io.ReadFull(c.Conn, prefix[:]); err != nil {
+		return err
+	}
+
+	length := int(binary.LittleEndian.Uint32(prefix[:]))
+	if length <= c.readAEAD.Overhead() || length > maxEncryptedFrameSize+c.readAEAD.Overhead() {
+		return ErrInvalidEncryptedFrame
+	}
+
+	frame := make([]byte, length)
+	if _, err := io.ReadFull(c.Conn, frame); err != nil {
+		return err
+	}
+
+	data, err := c.readAEAD.Open(frame[:0], frameNonce(c.readNonce), frame, prefix[:])
+	if err != nil {
+		return ErrInvalidEncryptedFrame
+	}
+	c.readNonce++
+
+	c.readBuf = data
+	return nil
+}
+
+// prefixConn is a net.Conn whose first bytes were already read
+type prefixConn struct {
+	net.Conn
+	prefix []byte
+}
+
+// Read reads the bytes already read first
+func (c *prefixConn) Read(b []byte) (int, error) {
+	if len(c.prefix) == 0 {
+		return c.Conn.Read(b)
+	}
+
+	n := copy(b, c.prefix)
+	c.prefix = c.prefix[n:]
+	return n, nil
+}
+
+func encodeEncryptedHandshake(pubkey cipher.PubKey) []byte {
+	b := make([]byte, 0, encryptedHandshakeSize)
+	b = append(b, encryptedTransportMagic[:]...)
+	b = append(b, encryptedTransportVersion)
+	return append(b, pubkey[:]...)
+}
+
+// handshakeState is the symmetric state of the handshake: the chaining key that the ECDH results are mixed into,
+// the hash of the transcript, and the key that encrypts the handshake payloads
+type handshakeState struct {
+	ck   cipher.SHA256
+	h    cipher.SHA256
+	aead gocipher.AEAD
+	n    uint64
+}
+
+func newHandshakeState() *handshakeState {
+	h := cipher.SumSHA256([]byte(encryptedTransportLabel))
+	return &handshakeState{
+		ck: h,
+		h:  h,
+	}
+}
+
+func hmacSHA256(key, data []byte) []byte {
+	m := hmac.New(sha256.New, key)
+	m.Write(data) // nolint: errcheck
+	return m.Sum(nil)
+}
+
+// hkdf derives two keys from the chaining key and ikm
+func (hs *handshakeState) hkdf(ikm []byte) ([]byte, []byte) {
+	tmp := hmacSHA256(hs.ck[:], ikm)
+	k1 := hmacSHA256(tmp, []byte{1})
+	k2 := hmacSHA256(tmp, append(append([]byte{}, k1...), 2))
+	return k1, k2
+}
+
+// mixHash adds data to the transcript
+func (hs *handshakeState) mixHash(data []byte) {
+	hs.h = cipher.SumSHA256(append(append([]byte{}, hs.h[:]...), data...))
+}
+
+// mixKey mixes the result of an ECDH into the chaining key, and derives the key of the next payloads
+func (hs *handshakeState) mixKey(pub cipher.PubKey, sec cipher.SecKey) error {
+	shared, err := cipher.ECDH(pub, sec)
+	if err != nil {
+		return ErrInvalidEncryptedHandshake
+	}
+
+	ck, k := hs.hkdf(shared)
+	copy(hs.ck[:], ck)
+	hs.aead, err = chacha20poly1305.New(k)
+	if err != nil {
+		return err
+	}
+	hs.n = 0
+
+	return nil
+}
+
+// encryptAndHash encrypts a payload, authenticating the transcript, and adds it to the transcript
+func (hs *handshakeState) encryptAndHash(b []byte) []byte {
+	c := hs.aead.Seal(nil, frameNonce(hs.n), b, hs.h[:])
+	hs.n++
+	hs.mixHash(c)
+	return c
+}
+
+// decryptAndHash decrypts a payload encrypted with encryptAndHash
+func (hs *handshakeState) decryptAndHash(c []byte) ([]byte, error) {
+	b, err := hs.aead.Open(nil, frameNonce(hs.n), c, hs.h[:])
+	if err != nil {
+		return nil, ErrInvalidEncryptedHandshake
+	}
+	hs.n++
+	hs.mixHash(c)
+	return b, nil
+}
+
+// encryptPayload encrypts the static key and the feature bits of the node, mixing the ECDH of its static key and
+// of the ephemeral key of the peer in between
+func (hs *handshakeState) encryptPayload(key cipher.SecKey, features uint64, peerEphemeral cipher.PubKey) ([]byte, error) {
+	pubkey, err := cipher.PubKeyFromSecKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	b := hs.encryptAndHash(pubkey[:])
+	if err := hs.mixKey(peerEphemeral, key); err != nil {
+		return nil, err
+	}
+
+	var f [8]byte
+	binary.LittleEndian.PutUint64(f[:], features)
+	return append(b, hs.encryptAndHash(f[:])...), nil
+}
+
+// decryptPayload decrypts the payload of the peer encrypted with encryptPayload, and returns its static key and feature bits
+func (hs *handshakeState) decryptPayload(b []byte, ephemeral cipher.SecKey) (cipher.PubKey, uint64, error) {
+	n := len(cipher.PubKey{}) + encryptedHandshakeTagSize
+	k, err := hs.decryptAndHash(b[:n])
+	if err != nil {
+		return cipher.PubKey{}, 0, err
+	}
+
+	var peerKey cipher.PubKey
+	copy(peerKey[:], k)
+	if err := hs.mixKey(peerKey, ephemeral); err != nil {
+		return cipher.PubKey{}, 0, err
+	}
+
+	f, err := hs.decryptAndHash(b[n:])
+	if err != nil {
+		return cipher.PubKey{}, 0, err
+	}
+
+	return peerKey, binary.LittleEndian.Uint64(f), nil
+}
+
+// split derives the keys of the data sent by the initiator and by the responder once the handshake is complete
+func (hs *handshakeState) split() ([]byte, []byte) {
+	return hs.hkdf(nil)
+}
+
+// clientEncryptedHandshake performs the handshake of an outgoing connection with the static key and feature bits of
+// the node, and returns the encrypted connection. If peerKey is set, the peer must authenticate with it.
+// Returns ErrEncryptedTransportDeclined if the peer declines the encrypted transport
+func clientEncryptedHandshake(conn net.Conn, key cipher.SecKey, features uint64, peerKey cipher.PubKey) (net.Conn, error) {
+	if err := conn.SetDeadline(time.Now().Add(encryptedHandshakeTimeout)); err != nil {
+		return nil, err
+	}
+
+	hs := newHandshakeState()
+
+	pubkey, seckey := cipher.GenerateKeyPair()
+	h := encodeEncryptedHandshake(pubkey)
+	if _, err := conn.Write(h); err != nil {
+		return nil, err
+	}
+	hs.mixHash(h)
+
+	b := make([]byte, encryptedHandshakeSize)
+	if _, err := io.ReadFull(conn, b); err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(b[:len(encryptedTransportMagic)], encryptedTransportMagic[:]) {
+		return nil, ErrInvalidEncryptedHandshake
+	}
+
+	var peerEphemeral cipher.PubKey
+	copy(peerEphemeral[:], b[len(encryptedTransportMagic)+1:])
+	if peerEphemeral.Null() {
+		return nil, ErrEncryptedTransportDeclined
+	}
+
+	hs.mixHash(b)
+	if err := hs.mixKey(peerEphemeral, seckey); err != nil {
+		return nil, err
+	}
+
+	payload := make([]byte, encryptedHandshakePayloadSize)
+	if _, err := io.ReadFull(conn, payload); err != nil {
+		return nil, err
+	}
+
+	remoteKey, remoteFeatures, err := hs.decryptPayload(payload, seckey)
+	if err != nil {
+		return nil, err
+	}
+
+	if !peerKey.Null() && remoteKey != peerKey {
+		return nil, ErrEncryptedTransportPeerKey
+	}
+
+	payload, err = hs.encryptPayload(key, features, peerEphemeral)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := conn.Write(payload); err != nil {
+		return nil, err
+	}
+
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		return nil, err
+	}
+
+	initiatorKey, responderKey := hs.split()
+	c, err := newEncryptedConn(conn, initiatorKey, responderKey)
+	if err != nil {
+		return nil, err
+	}
+	c.peerKey = remoteKey
+	c.peerFeatures = remoteFeatures
+
+	return c, nil
+}
+
+// serverEncryptedHandshake performs the handshake of an incoming connection if it starts with one, with the static key
+// and feature bits of the node. Returns the encrypted connection, or the connection with its first bytes unread if it
+// is a plaintext connection. A handshake of an unknown version is declined, and the connection continues in plaintext
+func serverEncryptedHandshake(conn net.Conn, key cipher.SecKey, features uint64) (net.Conn, error) {
+	if err := conn.SetDeadline(time.Now().Add(encryptedHandshakeTimeout)); err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, encryptedHandshakeSize)
+	if _, err := io.ReadFull(conn, b[:len(encryptedTransportMagic)]); err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(b[:len(encryptedTransportMagic)], encryptedTransportMagic[:]) {
+		if err := conn.SetDeadline(time.Time{}); err != nil {
+			return nil, err
+		}
+
+		return &prefixConn{
+			Conn:   conn,
+			prefix: b[:len(encryptedTransportMagic)],
+		}, nil
+	}
+
+	if _, err := io.ReadFull(conn, b[len(encryptedTransportMagic):]); err != nil {
+		return nil, err
+	}
+
+	if b[len(encryptedTransportMagic)] != encryptedTransportVersion {
+		if _, err := conn.Write(encodeEncryptedHandshake(cipher.PubKey{})); err != nil {
+			return nil, err
+		}
+
+		if err := conn.SetDeadline(time.Time{}); err != nil {
+			return nil, err
+		}
+
+		return conn, nil
+	}
+
+	hs := newHandshakeState()
+	hs.mixHash(b)
+
+	var peerEphemeral cipher.PubKey
+	copy(peerEphemeral[:], b[len(encryptedTransportMagic)+1:])
+
+	pubkey, seckey := cipher.GenerateKeyPair()
+	h := encodeEncryptedHandshake(pubkey)
+	hs.mixHash(h)
+	if err := hs.mixKey(peerEphemeral, seckey); err != nil {
+		return nil, err
+	}
+
+	payload, err := hs.encryptPayload(key, features, peerEphemeral)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := conn.Write(append(h, payload...)); err != nil {
+		return nil, err
+	}
+
+	peerPayload := make([]byte, encryptedHandshakePayloadSize)
+	if _, err := io.ReadFull(conn, peerPayload); err != nil {
+		return nil, err
+	}
+
+	remoteKey, remoteFeatures, err := hs.decryptPayload(peerPayload, seckey)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		return nil, err
+	}
+
+	initiatorKey, responderKey := hs.split()
+	c, err := newEncryptedConn(conn, responderKey, initiatorKey)
+	if err != nil {
+		return nil, err
+	}
+	c.peerKey = remoteKey
+	c.peerFeatures = remoteFeatures
+
+	return c, nil
+}
@@ -0,0 +1,341 @@
+package gnet
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+type handshakeResult struct {
+	conn net.Conn
+	err  error
+}
+
+func serverHandshake(conn net.Conn, key cipher.SecKey, features uint64) <-chan handshakeResult {
+	c := make(chan handshakeResult, 1)
+	go func() {
+		conn, err := serverEncryptedHandshake(conn, key, features)
+		c <- handshakeResult{conn, err}
+	}()
+	return c
+}
+
+func TestEncryptedHandshake(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	clientPubkey, clientKey := cipher.GenerateKeyPair()
+	serverPubkey, serverKey := cipher.GenerateKeyPair()
+
+	sc := serverHandshake(server, serverKey, 0x21)
+
+	cc, err := clientEncryptedHandshake(client, clientKey, 0x12, serverPubkey)
+	require.NoError(t, err)
+	require.IsType(t, &encryptedConn{}, cc)
+
+	r := <-sc
+	require.NoError(t, r.err)
+	require.IsType(t, &encryptedConn{}, r.conn)
+
+	// Each peer learns the static key and the feature bits of the other
+	require.Equal(t, serverPubkey, cc.(*encryptedConn).peerKey)
+	require.Equal(t, uint64(0x21), cc.(*encryptedConn).peerFeatures)
+	require.Equal(t, clientPubkey, r.conn.(*encryptedConn).peerKey)
+	require.Equal(t, uint64(0x12), r.conn.(*encryptedConn).peerFeatures)
+
+	// Data larger than a frame is split in several frames
+	data := bytes.Repeat([]byte("skycoin"), maxEncryptedFrameSize/4)
+	go func() {
+		_, err := cc.Write(data)
+		require.NoError(t, err)
+	}()
+
+	b := make([]byte, len(data))
+	_, err = io.ReadFull(r.conn, b)
+	require.NoError(t, err)
+	require.Equal(t, data, b)
+
+	go func() {
+		_, err := r.conn.Write([]byte("pong"))
+		require.NoError(t, err)
+	}()
+
+	b = make([]byte, 4)
+	_, err = io.ReadFull(cc, b)
+	require.NoError(t, err)
+	require.Equal(t, []byte("pong"), b)
+}
+
+func TestEncryptedHandshakePlaintext(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	_, key := cipher.GenerateKeyPair()
+	sc := serverHandshake(server, key, 0)
+
+	// A plaintext connection is returned with its first bytes unread
+	go func() {
+		_, err := client.Write([]byte("plaintext message"))
+		require.NoError(t, err)
+	}()
+
+	r := <-sc
+	require.NoError(t, r.err)
+	require.IsType(t, &prefixConn{}, r.conn)
+
+	b := make([]byte, len("plaintext message"))
+	_, err := io.ReadFull(r.conn, b)
+	require.NoError(t, err)
+	require.Equal(t, []byte("plaintext message"), b)
+}
+
+func TestEncryptedHandshakeDeclined(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	_, key := cipher.GenerateKeyPair()
+	sc := serverHandshake(server, key, 0)
+
+	// A handshake of an unknown version is declined with an empty public key
+	go func() {
+		pubkey, _ := cipher.GenerateKeyPair()
+		h := encodeEncryptedHandshake(pubkey)
+		h[len(encryptedTransportMagic)] = encryptedTransportVersion + 1
+		_, err := client.Write(h)
+		require.NoError(t, err)
+	}()
+
+	b := make([]byte, encryptedHandshakeSize)
+	_, err := io.ReadFull(client, b)
+	require.NoError(t, err)
+	require.Equal(t, encodeEncryptedHandshake(cipher.PubKey{}), b)
+
+	r := <-sc
+	require.NoError(t, r.err)
+	require.Equal(t, server, r.conn)
+
+	// The client fails with ErrEncryptedTransportDeclined if the encrypted transport is declined
+	client2, server2 := net.Pipe()
+	defer client2.Close()
+	defer server2.Close()
+
+	go func() {
+		b := make([]byte, encryptedHandshakeSize)
+		_, err := io.ReadFull(server2, b)
+		require.NoError(t, err)
+		_, err = server2.Write(encodeEncryptedHandshake(cipher.PubKey{}))
+		require.NoError(t, err)
+	}()
+
+	cc, err := clientEncryptedHandshake(client2, key, 0, cipher.PubKey{})
+	require.Equal(t, ErrEncryptedTransportDeclined, err)
+	require.Nil(t, cc)
+}
+
+func TestEncryptedHandshakeInvalid(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	// The peer does not reply with a handshake
+	go func() {
+		b := make([]byte, encryptedHandshakeSize)
+		_, err := io.ReadFull(server, b)
+		require.NoError(t, err)
+		_, err = server.Write(make([]byte, encryptedHandshakeSize))
+		require.NoError(t, err)
+	}()
+
+	_, key := cipher.GenerateKeyPair()
+	_, err := clientEncryptedHandshake(client, key, 0, cipher.PubKey{})
+	require.Equal(t, ErrInvalidEncryptedHandshake, err)
+
+	// The peer closes the connection instead of replying
+	client2, server2 := net.Pipe()
+	defer client2.Close()
+
+	go func() {
+		b := make([]byte, encryptedHandshakeSize)
+		_, err := io.ReadFull(server2, b)
+		require.NoError(t, err)
+		require.NoError(t, server2.Close())
+	}()
+
+	_, err = clientEncryptedHandshake(client2, key, 0, cipher.PubKey{})
+	require.Equal(t, io.EOF, err)
+}
+
+func TestEncryptedHandshakePeerKey(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	_, clientKey := cipher.GenerateKeyPair()
+	_, serverKey := cipher.GenerateKeyPair()
+	otherPubkey, _ := cipher.GenerateKeyPair()
+
+	// The connection fails if the peer authenticates with another key than the expected key
+	sc := serverHandshake(server, serverKey, 0)
+
+	cc, err := clientEncryptedHandshake(client, clientKey, 0, otherPubkey)
+	require.Equal(t, ErrEncryptedTransportPeerKey, err)
+	require.Nil(t, cc)
+
+	client.Close()
+	r := <-sc
+	require.Error(t, r.err)
+}
+
+func TestEncryptedHandshakeTampered(t *testing.T) {
+	// A man in the middle relays the handshake and alters one byte of the responder's message
+	for _, i := range []int{
+		len(encryptedTransportMagic),     // version
+		encryptedHandshakeSize,           // static key
+		encryptedHandshakeSize + 60,      // feature bits
+		encryptedHandshakeSize + 49 + 23, // tag of the feature bits
+	} {
+		client, mitmClient := net.Pipe()
+		mitmServer, server := net.Pipe()
+
+		_, clientKey := cipher.GenerateKeyPair()
+		_, serverKey := cipher.GenerateKeyPair()
+
+		sc := serverHandshake(server, serverKey, 0x21)
+
+		go func() {
+			b := make([]byte, encryptedHandshakeSize)
+			if _, err := io.ReadFull(mitmClient, b); err != nil {
+				return
+			}
+			if _, err := mitmServer.Write(b); err != nil {
+				return
+			}
+
+			b = make([]byte, encryptedHandshakeSize+encryptedHandshakePayloadSize)
+			if _, err := io.ReadFull(mitmServer, b); err != nil {
+				return
+			}
+			b[i] ^= 1
+			mitmClient.Write(b) // nolint: errcheck
+		}()
+
+		_, err := clientEncryptedHandshake(client, clientKey, 0x12, cipher.PubKey{})
+		require.Error(t, err, "byte %d", i)
+
+		client.Close()
+		mitmClient.Close()
+		mitmServer.Close()
+		server.Close()
+
+		r := <-sc
+		require.Error(t, r.err)
+	}
+}
+
+func TestEncryptedConnTamperedFrame(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	key := bytes.Repeat([]byte{1}, 32)
+	cc, err := newEncryptedConn(client, key, key)
+	require.NoError(t, err)
+	sc, err := newEncryptedConn(server, key, key)
+	require.NoError(t, err)
+
+	// Intercept the frame written by the client and flip a bit of its data
+	tapClient, tapServer := net.Pipe()
+	defer tapClient.Close()
+	defer tapServer.Close()
+	cc.Conn = tapClient
+	go func() {
+		_, err := cc.Write([]byte("tampered"))
+		require.NoError(t, err)
+	}()
+
+	frame := make([]byte, encryptedFrameLengthSize+len("tampered")+cc.writeAEAD.Overhead())
+	_, err = io.ReadFull(tapServer, frame)
+	require.NoError(t, err)
+	frame[encryptedFrameLengthSize] ^= 1
+
+	go func() {
+		_, err := client.Write(frame)
+		require.NoError(t, err)
+	}()
+
+	_, err = sc.Read(make([]byte, 16))
+	require.Equal(t, ErrInvalidEncryptedFrame, err)
+}
+
+func TestEncryptedConnInvalidFrameLength(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	key := bytes.Repeat([]byte{1}, 32)
+	sc, err := newEncryptedConn(server, key, key)
+	require.NoError(t, err)
+
+	go func() {
+		_, err := client.Write([]byte{0xFF, 0xFF, 0xFF, 0xFF})
+		require.NoError(t, err)
+	}()
+
+	_, err = sc.Read(make([]byte, 16))
+	require.Equal(t, ErrInvalidEncryptedFrame, err)
+}
+
+func TestPoolEncryptedHandshake(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.EncryptedTransportFeatures = 0x12
+	p, err := NewConnectionPool(cfg, nil)
+	require.NoError(t, err)
+
+	// Incoming connections are plaintext if the encrypted transport is not enabled
+	conn := NewDummyConn(addr)
+	c, err := p.encryptedHandshake(conn, false, false, cipher.PubKey{})
+	require.NoError(t, err)
+	require.Equal(t, conn, c)
+
+	// Outgoing connections are plaintext unless encryption is requested
+	c, err = p.encryptedHandshake(conn, true, false, cipher.PubKey{})
+	require.NoError(t, err)
+	require.Equal(t, conn, c)
+
+	// The pools authenticate with their static keys, and send their features
+	cfg2 := newTestConfig()
+	cfg2.EncryptedTransport = true
+	cfg2.EncryptedTransportFeatures = 0x21
+	_, cfg2.EncryptedTransportKey = cipher.GenerateKeyPair()
+	p2, err := NewConnectionPool(cfg2, nil)
+	require.NoError(t, err)
+	require.Equal(t, cipher.MustPubKeyFromSecKey(cfg2.EncryptedTransportKey), p2.TransportPubKey())
+
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	sc := make(chan handshakeResult, 1)
+	go func() {
+		conn, err := p2.encryptedHandshake(server, false, false, cipher.PubKey{})
+		sc <- handshakeResult{conn, err}
+	}()
+
+	c, err = p.encryptedHandshake(client, true, true, p2.TransportPubKey())
+	require.NoError(t, err)
+	require.Equal(t, p2.TransportPubKey(), c.(*encryptedConn).peerKey)
+	require.Equal(t, uint64(0x21), c.(*encryptedConn).peerFeatures)
+
+	r := <-sc
+	require.NoError(t, r.err)
+	require.Equal(t, p.TransportPubKey(), r.conn.(*encryptedConn).peerKey)
+	require.Equal(t, uint64(0x12), r.conn.(*encryptedConn).peerFeatures)
+}
@@ -51,11 +51,18 @@ var MessageIDMap = make(map[reflect.Type]MessagePrefix)
 // MessageIDReverseMap maps message ids to their types
 var MessageIDReverseMap = make(map[MessagePrefix]reflect.Type)
 
+// MessageMaxLengthMap maps message ids to the maximum length of their messages, message id included.
+// The messages without a maximum length are only limited by the pool's MaxMessageLength
+var MessageMaxLengthMap = make(map[MessagePrefix]int)
+
 // RegisterMessage registers a message struct for recognition by the message handlers.
 func RegisterMessage(prefix MessagePrefix, msg interface{}) {
 	t := reflect.TypeOf(msg)
 	id := MessagePrefix{}
 	copy(id[:], prefix[:])
+	if id == compressedMessagePrefix {
+		logger.Panicf("Attempted to register the reserved message prefix %s", string(id[:]))
+	}
 	_, exists := MessageIDReverseMap[id]
 	if exists {
 		logger.Panicf("Attempted to register message prefix %s twice", string(id[:]))
@@ -68,6 +75,24 @@ func RegisterMessage(prefix MessagePrefix, msg interface{}) {
 	MessageIDReverseMap[id] = t
 }
 
+// RegisterMessageMaxLength sets the maximum length of the messages of a registered message id, message id included.
+// Longer messages are rejected before they are read entirely
+func RegisterMessageMaxLength(prefix MessagePrefix, maxLength int) {
+	if _, ok := MessageIDReverseMap[prefix]; !ok {
+		logger.Panicf("Attempted to set the maximum length of unregistered message prefix %s", string(prefix[:]))
+	}
+	if maxLength < messagePrefixLength {
+		logger.Panicf("Invalid maximum length %d for message prefix %s", maxLength, string(prefix[:]))
+	}
+	MessageMaxLengthMap[prefix] = maxLength
+}
+
+// checkMessageMaxLength returns false if a message of the id is longer than the maximum length of its type
+func checkMessageMaxLength(id MessagePrefix, length int) bool {
+	maxLength, ok := MessageMaxLengthMap[id]
+	return !ok || length <= maxLength
+}
+
 // VerifyMessages calls logger.Panic if message registration violates sanity checks
 func VerifyMessages() {
 	for t, k := range MessageIDMap {
@@ -113,4 +138,5 @@ func VerifyMessages() {
 func EraseMessages() {
 	MessageIDMap = make(map[reflect.Type]MessagePrefix)
 	MessageIDReverseMap = make(map[MessagePrefix]reflect.Type)
+	MessageMaxLengthMap = make(map[MessagePrefix]int)
 }
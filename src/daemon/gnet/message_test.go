@@ -37,6 +37,22 @@ func TestEraseMessages(t *testing.T) {
 	assert.Equal(t, len(MessageIDReverseMap), 0)
 }
 
+func TestRegisterMessageMaxLength(t *testing.T) {
+	EraseMessages()
+	RegisterMessage(BytePrefix, ByteMessage{})
+	assert.Panics(t, func() { RegisterMessageMaxLength(DummyPrefix, 8) })
+	assert.Panics(t, func() { RegisterMessageMaxLength(BytePrefix, 3) })
+
+	RegisterMessageMaxLength(BytePrefix, 5)
+	assert.Equal(t, 5, MessageMaxLengthMap[BytePrefix])
+	assert.True(t, checkMessageMaxLength(BytePrefix, 5))
+	assert.False(t, checkMessageMaxLength(BytePrefix, 6))
+	assert.True(t, checkMessageMaxLength(DummyPrefix, 1024))
+
+	EraseMessages()
+	assert.Equal(t, len(MessageMaxLengthMap), 0)
+}
+
 func TestVerifyMessages(t *testing.T) {
 	// VerifyMessages either no-ops or panics. Make sure it doesnt panic
 	EraseMessages()
@@ -170,6 +186,16 @@ func NewByteMessage(x byte) Message {
 	return &ByteMessage{X: x}
 }
 
+type StringMessage struct {
+	S string `enc:",maxlen=8"`
+}
+
+var StringPrefix = MessagePrefix{'S', 'T', 'R', 'G'}
+
+func (sm *StringMessage) Handle(c *MessageContext, x interface{}) error {
+	return nil
+}
+
 type PointerMessage struct {
 	Ptr *int
 }
@@ -10,17 +10,23 @@ import (
 	"fmt"
 	"net"
 	"reflect"
+	"sort"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"io"
 
 	"github.com/sirupsen/logrus"
 
+	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/cipher/encoder"
 	"github.com/skycoin/skycoin/src/daemon/strand"
 	"github.com/skycoin/skycoin/src/util/elapse"
 	"github.com/skycoin/skycoin/src/util/logging"
+	"github.com/skycoin/skycoin/src/util/ratelimit"
+	"github.com/skycoin/skycoin/src/util/socks5"
 )
 
 // DisconnectReason is passed to ConnectionPool's DisconnectCallback
@@ -48,6 +54,14 @@ var (
 	ErrDisconnectMessageDecodeUnderflow DisconnectReason = errors.New("Message data did not fully decode to a message object")
 	// ErrDisconnectTruncatedMessageID message data was too short to contain a message ID
 	ErrDisconnectTruncatedMessageID DisconnectReason = errors.New("Message data was too short to contain a message ID")
+	// ErrDisconnectInvalidCompressedMessage compressed message could not be decompressed or is too large
+	ErrDisconnectInvalidCompressedMessage DisconnectReason = errors.New("Invalid compressed message")
+	// ErrDisconnectMessageTooLong message is longer than the maximum length of its type
+	ErrDisconnectMessageTooLong DisconnectReason = errors.New("Message exceeds the maximum length of its type")
+	// ErrDisconnectMessageFieldTooLong a variable length field of a message is longer than its maximum length
+	ErrDisconnectMessageFieldTooLong DisconnectReason = errors.New("Message field exceeds its maximum length")
+	// ErrDisconnectEvicted incoming connection was evicted to free a slot for a new incoming connection
+	ErrDisconnectEvicted DisconnectReason = errors.New("Evicted for a new incoming connection")
 
 	// ErrConnectionPoolClosed error message indicates the connection pool is closed
 	ErrConnectionPoolClosed = errors.New("Connection pool is closed")
@@ -109,6 +123,20 @@ type Config struct {
 	// Timeout is the timeout for dialing new connections.  Use a
 	// timeout of 0 to ignore timeout.
 	DialTimeout time.Duration
+	// Make outgoing connections through this SOCKS5 proxy. Leave empty to connect directly
+	ProxyAddress string
+	// Don't listen on or connect to IPv4 addresses
+	DisableIPv4 bool
+	// Don't listen on or connect to IPv6 addresses
+	DisableIPv6 bool
+	// Maximum bytes per second sent to all connections. 0 for no limit
+	UploadRateLimit int
+	// Maximum bytes per second received from all connections. 0 for no limit
+	DownloadRateLimit int
+	// Maximum bytes per second sent to each connection. 0 for no limit
+	PeerUploadRateLimit int
+	// Maximum bytes per second received from each connection. 0 for no limit
+	PeerDownloadRateLimit int
 	// Timeout for reading from a connection. Set to 0 to default to the
 	// system's timeout
 	ReadTimeout time.Duration
@@ -126,12 +154,70 @@ type Config struct {
 	ConnectCallback ConnectCallback
 	// Triggered on client connect failure
 	ConnectFailureCallback ConnectFailureCallback
+	// Triggered when a new incoming connection arrives and the incoming connection slots are full.
+	// If not set, the new connection is rejected
+	EvictCallback EvictCallback
 	// Print debug logs
 	DebugPrint bool
 	// Default "trusted" peers
 	DefaultConnections []string
 	// Default connections map
 	defaultConnections map[string]struct{}
+	// IPs of whitelisted peers. Their incoming connections are accepted in reserved slots when
+	// the maximum incoming connections is reached, and their traffic is not rate limited
+	Whitelist []string
+	// Number of incoming connection slots reserved for whitelisted peers, in excess of MaxConnections
+	MaxWhitelistedConnections int
+	// Whitelisted IPs map
+	whitelist map[string]struct{}
+	// Accept the encrypted transport handshake on incoming connections
+	EncryptedTransport bool
+	// Static key that authenticates the node in the encrypted transport handshake. A random key is used if not set
+	EncryptedTransportKey cipher.SecKey
+	// Feature bits sent in the encrypted transport handshake, see Connection.HandshakeFeatures
+	EncryptedTransportFeatures uint64
+	// Minimum size of the messages compressed for the connections that enable compression
+	CompressionThreshold int
+	// Additional addresses to listen on, each with its own limit of incoming connections
+	Listeners []ListenerConfig
+	// Network the connections are made over. Leave nil to use TCP
+	Transport Transport
+}
+
+// Transport makes and accepts the connections of the pool. It is replaced by an in-memory
+// network to run several pools in the same process
+type Transport interface {
+	// Dial connects to an address of a network, giving up after timeout. A timeout of 0 means no timeout
+	Dial(network, address string, timeout time.Duration) (net.Conn, error)
+	// Listen listens for connections on an address of a network
+	Listen(network, address string) (net.Listener, error)
+}
+
+// tcpTransport is the default Transport, over TCP
+type tcpTransport struct{}
+
+func (tcpTransport) Dial(network, address string, timeout time.Duration) (net.Conn, error) {
+	return net.DialTimeout(network, address, timeout)
+}
+
+func (tcpTransport) Listen(network, address string) (net.Listener, error) {
+	return net.Listen(network, address)
+}
+
+// ListenerConfig is an additional address the pool listens on. The incoming connections accepted on it
+// are counted against its own MaxConnections instead of the pool's maximum incoming connections
+type ListenerConfig struct {
+	// Address to listen on. Leave empty to listen on all interfaces
+	Address string
+	// Port to listen on
+	Port uint16
+	// Maximum incoming connections accepted on this listener. 0 for no limit
+	MaxConnections int
+}
+
+// String returns the ip:port address of the listener
+func (l ListenerConfig) String() string {
+	return net.JoinHostPort(l.Address, strconv.Itoa(int(l.Port)))
 }
 
 // NewConfig returns a Config with defaults set
@@ -151,6 +237,9 @@ func NewConfig() Config {
 		ConnectCallback:                   nil,
 		DebugPrint:                        false,
 		defaultConnections:                make(map[string]struct{}),
+		MaxWhitelistedConnections:         8,
+		whitelist:                         make(map[string]struct{}),
+		CompressionThreshold:              1024,
 	}
 }
 
@@ -176,6 +265,22 @@ type Connection struct {
 	// Message send queue.
 	WriteQueue chan Message
 	Solicited  bool
+	// Whether the peer is whitelisted. Its traffic is not rate limited
+	Whitelisted bool
+	// Whether the connection uses the encrypted transport
+	Encrypted bool
+	// Static key the peer authenticated with in the encrypted transport handshake
+	PeerKey cipher.PubKey
+	// Feature bits the peer sent in the encrypted transport handshake. They must match the features of its introduction
+	HandshakeFeatures uint64
+	// Set to 1 once the messages sent to the connection are compressed. Accessed atomically
+	compression uint32
+	// Additional listener that accepted the connection, nil if the connection is outgoing
+	// or was accepted on the main listener
+	Listener *ListenerConfig
+	// Rate limits of the connection, nil if not limited
+	uploadLimiter   *ratelimit.Limiter
+	downloadLimiter *ratelimit.Limiter
 }
 
 // NewConnection creates a new Connection tied to a ConnectionPool
@@ -192,6 +297,12 @@ func NewConnection(pool *ConnectionPool, id uint64, conn net.Conn, writeQueueSiz
 	}
 }
 
+// newRateLimiter creates a limiter of bytes per second, which allows bursts of one second of traffic.
+// Returns nil if rate is 0
+func newRateLimiter(rate int) *ratelimit.Limiter {
+	return ratelimit.NewLimiter(float64(rate), rate)
+}
+
 // Addr returns remote address
 func (conn *Connection) Addr() string {
 	return conn.Conn.RemoteAddr().String()
@@ -202,6 +313,11 @@ func (conn *Connection) String() string {
 	return conn.Addr()
 }
 
+// Compression returns true if the messages sent to the connection are compressed
+func (conn *Connection) Compression() bool {
+	return atomic.LoadUint32(&conn.compression) == 1
+}
+
 // Close close the connection and write queue
 func (conn *Connection) Close() error {
 	err := conn.Conn.Close()
@@ -219,6 +335,12 @@ type ConnectCallback func(addr string, id uint64, solicited bool)
 // ConnectFailureCallback trigger on client connect failure
 type ConnectFailureCallback func(addr string, solicited bool, err error)
 
+// EvictCallback triggered on a new incoming connection from addr when the incoming connection slots are full.
+// candidates are the incoming connections that can be evicted. Returns the candidate to disconnect
+// to free a slot for addr, or false to reject addr. It is called in the pool's strand and must not
+// call back into the pool
+type EvictCallback func(addr string, candidates []string) (string, bool)
+
 // ConnectionPool connection pool
 type ConnectionPool struct {
 	// Configuration parameters
@@ -233,13 +355,26 @@ type ConnectionPool struct {
 	defaultOutgoingConnections map[string]struct{}
 	// connected outgoing connections
 	outgoingConnections map[string]struct{}
+	// incoming connections of whitelisted peers in the reserved slots
+	whitelistedConnections map[string]struct{}
+	// incoming connections accepted on the additional listeners, with the index of their listener
+	listenerConnections map[string]int
 	// User-defined state to be passed into message handlers
 	messageState interface{}
+	// Rate limits of all connections, nil if not limited
+	uploadLimiter   *ratelimit.Limiter
+	downloadLimiter *ratelimit.Limiter
+	// Sizes of the compressed messages
+	compressionStats compressionStats
+	// Static key of the encrypted transport handshake
+	transportKey cipher.SecKey
 	// Connection ID counter
 	connID uint64
 	// Listening connection
 	listener     net.Listener
 	listenerLock sync.Mutex
+	// Additional listeners, in the order of Config.Listeners
+	listeners []net.Listener
 	// operations channel
 	reqC chan strand.Request
 	// quit channel
@@ -257,18 +392,38 @@ func NewConnectionPool(c Config, state interface{}) (*ConnectionPool, error) {
 		c.defaultConnections[p] = struct{}{}
 	}
 
+	for _, ip := range c.Whitelist {
+		parsedIP := net.ParseIP(ip)
+		if parsedIP == nil {
+			return nil, fmt.Errorf("Invalid whitelisted IP %q", ip)
+		}
+		c.whitelist[parsedIP.String()] = struct{}{}
+	}
+
 	if c.MaxConnections < c.MaxOutgoingConnections+c.MaxDefaultPeerOutgoingConnections {
 		return nil, errors.New("MaxConnections must be >= MaxOutgoingConnections + MaxDefaultPeerOutgoingConnections")
 	}
 
+	transportKey := c.EncryptedTransportKey
+	if transportKey.Null() {
+		_, transportKey = cipher.GenerateKeyPair()
+	} else if err := transportKey.Verify(); err != nil {
+		return nil, fmt.Errorf("Invalid EncryptedTransportKey: %v", err)
+	}
+
 	return &ConnectionPool{
 		Config:                     c,
 		pool:                       make(map[uint64]*Connection),
 		addresses:                  make(map[string]*Connection),
 		defaultOutgoingConnections: make(map[string]struct{}),
 		outgoingConnections:        make(map[string]struct{}),
+		whitelistedConnections:     make(map[string]struct{}),
+		listenerConnections:        make(map[string]int),
 		SendResults:                make(chan SendResult, c.SendResultsSize),
 		messageState:               state,
+		transportKey:               transportKey,
+		uploadLimiter:              newRateLimiter(c.UploadRateLimit),
+		downloadLimiter:            newRateLimiter(c.DownloadRateLimit),
 		quit:                       make(chan struct{}),
 		done:                       make(chan struct{}),
 		strandDone:                 make(chan struct{}),
@@ -290,10 +445,11 @@ func (pool *ConnectionPool) Run() error {
 	}()
 
 	// start the connection accept loop
-	addr := fmt.Sprintf("%s:%v", pool.Config.Address, pool.Config.Port)
+	// With an empty address, the "tcp" network listens on both IPv4 and IPv6
+	addr := net.JoinHostPort(pool.Config.Address, strconv.Itoa(int(pool.Config.Port)))
 	logger.Infof("Listening for connections on %s...", addr)
 
-	ln, err := net.Listen("tcp", addr)
+	ln, err := pool.transport().Listen(pool.network(), addr)
 	if err != nil {
 		return err
 	}
@@ -302,7 +458,32 @@ func (pool *ConnectionPool) Run() error {
 	pool.listener = ln
 	pool.listenerLock.Unlock()
 
-loop:
+	for _, l := range pool.Config.Listeners {
+		logger.Infof("Listening for connections on %s...", l)
+
+		ln, err := pool.transport().Listen(pool.network(), l.String())
+		if err != nil {
+			return err
+		}
+
+		pool.listenerLock.Lock()
+		pool.listeners = append(pool.listeners, ln)
+		pool.listenerLock.Unlock()
+
+		pool.wg.Add(1)
+		go func() {
+			defer pool.wg.Done()
+			pool.acceptLoop(ln)
+		}()
+	}
+
+	pool.acceptLoop(ln)
+	pool.wg.Wait()
+	return nil
+}
+
+// acceptLoop accepts the incoming connections of a listener until the pool quits
+func (pool *ConnectionPool) acceptLoop(ln net.Listener) {
 	for {
 		conn, err := ln.Accept()
 		if err != nil {
@@ -310,7 +491,7 @@ loop:
 			// channel to see if we should continue or quit
 			select {
 			case <-pool.quit:
-				break loop
+				return
 			default:
 				// without the default case the select will block.
 				logger.Error(err.Error())
@@ -321,7 +502,7 @@ loop:
 		pool.wg.Add(1)
 		go func() {
 			defer pool.wg.Done()
-			if err := pool.handleConnection(conn, false); err != nil {
+			if err := pool.handleConnection(conn, false, false, cipher.PubKey{}); err != nil {
 				logger.WithFields(logrus.Fields{
 					"addr":     conn.RemoteAddr(),
 					"outgoing": false,
@@ -329,8 +510,6 @@ loop:
 			}
 		}()
 	}
-	pool.wg.Wait()
-	return nil
 }
 
 // RunOffline runs the pool in offline mode. No connections will be accepted,
@@ -375,6 +554,12 @@ func (pool *ConnectionPool) Shutdown() {
 		}
 	}
 	pool.listener = nil
+	for _, ln := range pool.listeners {
+		if err := ln.Close(); err != nil {
+			logger.WithError(err).Warning("pool.listeners ln.Close error")
+		}
+	}
+	pool.listeners = nil
 	pool.listenerLock.Unlock()
 
 	logger.Info("ConnectionPool.Shutdown disconnecting all connections")
@@ -411,7 +596,9 @@ func (pool *ConnectionPool) ListeningAddress() (net.Addr, error) {
 	return pool.listener.Addr(), nil
 }
 
-func (pool *ConnectionPool) canConnect(a string, solicited bool) error {
+// canConnect checks the limits of connections for a new connection. listener is the index of the additional
+// listener that accepted an incoming connection, or -1
+func (pool *ConnectionPool) canConnect(a string, solicited bool, listener int) error {
 	if pool.isConnExist(a) {
 		return ErrConnectionExists
 	}
@@ -422,22 +609,137 @@ func (pool *ConnectionPool) canConnect(a string, solicited bool) error {
 		} else if pool.isMaxOutgoingConnectionsReached() {
 			return ErrMaxOutgoingConnectionsReached
 		}
+	} else if listener != -1 {
+		if pool.isMaxListenerConnectionsReached(listener) {
+			return ErrMaxIncomingConnectionsReached
+		}
 	} else if pool.isMaxIncomingConnectionsReached() {
-		return ErrMaxIncomingConnectionsReached
+		if pool.IsWhitelisted(a) && !pool.isMaxWhitelistedConnectionsReached() {
+			return nil
+		}
+		if !pool.evictIncoming(a) {
+			return ErrMaxIncomingConnectionsReached
+		}
 	}
 
 	return nil
 }
 
+// evictIncoming asks the EvictCallback for an incoming connection to disconnect, to free a slot for
+// a new incoming connection from a. The connections in the whitelisted slots or accepted on an additional
+// listener are not candidates, since they don't use the incoming slots. Returns false if no connection was evicted
+func (pool *ConnectionPool) evictIncoming(a string) bool {
+	if pool.Config.EvictCallback == nil {
+		return false
+	}
+
+	var candidates []string
+	for addr, c := range pool.addresses {
+		if c.Solicited {
+			continue
+		}
+		if _, ok := pool.whitelistedConnections[addr]; ok {
+			continue
+		}
+		if _, ok := pool.listenerConnections[addr]; ok {
+			continue
+		}
+		candidates = append(candidates, addr)
+	}
+
+	if len(candidates) == 0 {
+		return false
+	}
+
+	sort.Strings(candidates)
+
+	evictAddr, ok := pool.Config.EvictCallback(a, candidates)
+	if !ok {
+		return false
+	}
+
+	found := false
+	for _, addr := range candidates {
+		if addr == evictAddr {
+			found = true
+			break
+		}
+	}
+	if !found {
+		logger.WithField("addr", evictAddr).Error("EvictCallback returned an address that is not a candidate")
+		return false
+	}
+
+	logger.WithFields(logrus.Fields{
+		"addr":    evictAddr,
+		"newAddr": a,
+	}).Info("Evicting incoming connection")
+
+	conn := pool.disconnect(evictAddr, ErrDisconnectEvicted)
+	if pool.Config.DisconnectCallback != nil {
+		pool.Config.DisconnectCallback(evictAddr, conn.ID, ErrDisconnectEvicted)
+	}
+
+	return true
+}
+
+// IsWhitelisted returns true if the IP of an ip:port address is whitelisted
+func (pool *ConnectionPool) IsWhitelisted(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	_, ok := pool.Config.whitelist[ip.String()]
+	return ok
+}
+
+// acceptingListener returns the index of the additional listener whose address is the local address
+// of an incoming connection, or -1 if the connection was accepted on the main listener
+func (pool *ConnectionPool) acceptingListener(localAddr net.Addr) int {
+	addr, ok := localAddr.(*net.TCPAddr)
+	if !ok {
+		return -1
+	}
+
+	pool.listenerLock.Lock()
+	defer pool.listenerLock.Unlock()
+
+	for i, ln := range pool.listeners {
+		lnAddr, ok := ln.Addr().(*net.TCPAddr)
+		if !ok || lnAddr.Port != addr.Port {
+			continue
+		}
+
+		if lnAddr.IP.IsUnspecified() || lnAddr.IP.Equal(addr.IP) {
+			return i
+		}
+	}
+
+	return -1
+}
+
 // newConnection creates a new Connection around a net.Conn. Trying to make a connection
 // to an address that is already connected will failed.
 func (pool *ConnectionPool) newConnection(conn net.Conn, solicited bool) (*Connection, error) {
 	a := conn.RemoteAddr().String()
 
-	if err := pool.canConnect(a, solicited); err != nil {
+	listener := -1
+	if !solicited && len(pool.Config.Listeners) != 0 {
+		listener = pool.acceptingListener(conn.LocalAddr())
+	}
+
+	if err := pool.canConnect(a, solicited, listener); err != nil {
 		return nil, err
 	}
 
+	whitelisted := pool.IsWhitelisted(a)
+
 	if solicited {
 		pool.outgoingConnections[a] = struct{}{}
 
@@ -446,6 +748,13 @@ func (pool *ConnectionPool) newConnection(conn net.Conn, solicited bool) (*Conne
 			l := len(pool.defaultOutgoingConnections)
 			logger.WithField("addr", a).Debugf("%d/%d outgoing default connections in use", l, pool.Config.MaxDefaultPeerOutgoingConnections)
 		}
+	} else if listener != -1 {
+		pool.listenerConnections[a] = listener
+		logger.WithField("addr", a).Debugf("Accepted connection on %s", pool.Config.Listeners[listener])
+	} else if whitelisted && pool.isMaxIncomingConnectionsReached() {
+		pool.whitelistedConnections[a] = struct{}{}
+		l := len(pool.whitelistedConnections)
+		logger.WithField("addr", a).Debugf("%d/%d reserved whitelisted connections in use", l, pool.Config.MaxWhitelistedConnections)
 	}
 
 	// ID must start at 1; in case connID overflows back to 0, force it to 1
@@ -455,6 +764,19 @@ func (pool *ConnectionPool) newConnection(conn net.Conn, solicited bool) (*Conne
 	}
 
 	nc := NewConnection(pool, pool.connID, conn, pool.Config.ConnectionWriteQueueSize, solicited)
+	nc.Whitelisted = whitelisted
+	if ec, ok := conn.(*encryptedConn); ok {
+		nc.Encrypted = true
+		nc.PeerKey = ec.peerKey
+		nc.HandshakeFeatures = ec.peerFeatures
+	}
+	if listener != -1 {
+		nc.Listener = &pool.Config.Listeners[listener]
+	}
+	if !whitelisted {
+		nc.uploadLimiter = newRateLimiter(pool.Config.PeerUploadRateLimit)
+		nc.downloadLimiter = newRateLimiter(pool.Config.PeerDownloadRateLimit)
+	}
 
 	pool.pool[nc.ID] = nc
 	pool.addresses[a] = nc
@@ -462,11 +784,44 @@ func (pool *ConnectionPool) newConnection(conn net.Conn, solicited bool) (*Conne
 	return nc, nil
 }
 
-// Creates a Connection and begins its read and write loop
-func (pool *ConnectionPool) handleConnection(conn net.Conn, solicited bool) error {
+// encryptedHandshake performs the encrypted transport handshake of an outgoing connection if encrypt is set,
+// or of an incoming connection that starts with one if Config.EncryptedTransport is set.
+// If peerKey is set, the peer of the outgoing connection must authenticate with it
+func (pool *ConnectionPool) encryptedHandshake(conn net.Conn, solicited, encrypt bool, peerKey cipher.PubKey) (net.Conn, error) {
+	switch {
+	case solicited && encrypt:
+		return clientEncryptedHandshake(conn, pool.transportKey, pool.Config.EncryptedTransportFeatures, peerKey)
+	case !solicited && pool.Config.EncryptedTransport:
+		return serverEncryptedHandshake(conn, pool.transportKey, pool.Config.EncryptedTransportFeatures)
+	default:
+		return conn, nil
+	}
+}
+
+// Creates a Connection and begins its read and write loop.
+// If encrypt is set, the encrypted transport is negotiated with the peer of an outgoing connection first,
+// and the peer must authenticate with peerKey if it is set
+func (pool *ConnectionPool) handleConnection(conn net.Conn, solicited, encrypt bool, peerKey cipher.PubKey) error {
 	defer logger.WithField("addr", conn.RemoteAddr()).Debug("Connection closed")
 	addr := conn.RemoteAddr().String()
 
+	hsConn, err := pool.encryptedHandshake(conn, solicited, encrypt, peerKey)
+	if err != nil {
+		err = HandshakeError{
+			Err: err,
+		}
+		logger.WithError(err).WithField("addr", addr).Debug("handleConnection: encryptedHandshake failed")
+		if closeErr := conn.Close(); closeErr != nil {
+			logger.WithError(closeErr).WithField("addr", addr).Error("handleConnection conn.Close")
+		}
+		// The daemon only tracks an incoming connection once it is created
+		if solicited && pool.Config.ConnectFailureCallback != nil {
+			pool.Config.ConnectFailureCallback(addr, solicited, err)
+		}
+		return err
+	}
+	conn = hsConn
+
 	c, err := func() (c *Connection, err error) {
 		// TODO -- when limits in newConnection() are reached, should we allow the peer
 		// to be added anyway, so that we can disconnect it normally and send a disconnect packet?
@@ -619,6 +974,11 @@ func (pool *ConnectionPool) readLoop(conn *Connection, msgChan chan []byte, qc c
 			continue
 		}
 
+		// Wait before reading more, so that the peer's sends are slowed down by TCP flow control
+		if !conn.Whitelisted && !pool.throttle(len(data), qc, pool.downloadLimiter, conn.downloadLimiter) {
+			return nil
+		}
+
 		// write data to buffer
 		if _, err := conn.Buffer.Write(data); err != nil {
 			return err
@@ -662,7 +1022,19 @@ func (pool *ConnectionPool) sendLoop(conn *Connection, timeout time.Duration, qc
 				continue
 			}
 
-			err := sendMessage(conn.Conn, m, timeout)
+			b := EncodeMessage(m)
+			if conn.Compression() && len(b) >= pool.Config.CompressionThreshold {
+				if cb, ok := compressMessage(b); ok {
+					pool.compressionStats.sent(len(b), len(cb))
+					b = cb
+				}
+			}
+
+			if !conn.Whitelisted && !pool.throttle(len(b), qc, pool.uploadLimiter, conn.uploadLimiter) {
+				return nil
+			}
+
+			err := sendByteMessage(conn.Conn, b, timeout)
 
 			// Update last sent before writing to SendResult,
 			// this allows a write to SendResult to be used as a sync marker,
@@ -689,6 +1061,34 @@ func (pool *ConnectionPool) sendLoop(conn *Connection, timeout time.Duration, qc
 	}
 }
 
+// throttle waits until n bytes can be transferred within the rate limits.
+// Returns false if the connection or the pool quit while waiting
+func (pool *ConnectionPool) throttle(n int, qc chan struct{}, limiters ...*ratelimit.Limiter) bool {
+	now := time.Now()
+	var delay time.Duration
+	for _, l := range limiters {
+		if d := l.Reserve(now, n); d > delay {
+			delay = d
+		}
+	}
+
+	if delay == 0 {
+		return true
+	}
+
+	t := time.NewTimer(delay)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return true
+	case <-qc:
+		return false
+	case <-pool.quit:
+		return false
+	}
+}
+
 func readData(reader io.Reader, buf []byte) ([]byte, error) {
 	c, err := reader.Read(buf)
 	if err != nil {
@@ -727,6 +1127,16 @@ func decodeData(buf *bytes.Buffer, maxMsgLength int) ([][]byte, error) {
 			return [][]byte{}, ErrDisconnectInvalidMessageLength
 		}
 
+		// Disconnect if the message is longer than the maximum length of its type,
+		// without waiting for the rest of the message
+		if buf.Len() >= messageLengthSize+messagePrefixLength {
+			var id MessagePrefix
+			copy(id[:], buf.Bytes()[messageLengthSize:])
+			if !checkMessageMaxLength(id, length) {
+				return [][]byte{}, ErrDisconnectMessageTooLong
+			}
+		}
+
 		if buf.Len()-messageLengthSize < length {
 			return [][]byte{}, nil
 		}
@@ -750,7 +1160,26 @@ func (pool *ConnectionPool) isConnExist(addr string) bool {
 }
 
 func (pool *ConnectionPool) isMaxIncomingConnectionsReached() bool {
-	return len(pool.pool) >= (pool.Config.MaxConnections - pool.Config.MaxOutgoingConnections - pool.Config.MaxDefaultPeerOutgoingConnections)
+	return len(pool.pool)-len(pool.whitelistedConnections)-len(pool.listenerConnections) >= (pool.Config.MaxConnections - pool.Config.MaxOutgoingConnections - pool.Config.MaxDefaultPeerOutgoingConnections)
+}
+
+func (pool *ConnectionPool) isMaxListenerConnectionsReached(listener int) bool {
+	max := pool.Config.Listeners[listener].MaxConnections
+	if max == 0 {
+		return false
+	}
+
+	n := 0
+	for _, l := range pool.listenerConnections {
+		if l == listener {
+			n++
+		}
+	}
+	return n >= max
+}
+
+func (pool *ConnectionPool) isMaxWhitelistedConnectionsReached() bool {
+	return len(pool.whitelistedConnections) >= pool.Config.MaxWhitelistedConnections
 }
 
 func (pool *ConnectionPool) isMaxOutgoingConnectionsReached() bool {
@@ -796,16 +1225,51 @@ func (pool *ConnectionPool) GetConnection(addr string) (*Connection, error) {
 	return conn, nil
 }
 
+// EnableCompression compresses the messages sent to a connection from now on.
+// The peer must be able to decompress them
+func (pool *ConnectionPool) EnableCompression(addr string) error {
+	return pool.strand("EnableCompression", func() error {
+		conn, ok := pool.addresses[addr]
+		if !ok {
+			return fmt.Errorf("Tried to enable compression for %s, but we are not connected", addr)
+		}
+
+		atomic.StoreUint32(&conn.compression, 1)
+		return nil
+	})
+}
+
+// TransportPubKey returns the static public key that authenticates the node in the encrypted transport handshake
+func (pool *ConnectionPool) TransportPubKey() cipher.PubKey {
+	return cipher.MustPubKeyFromSecKey(pool.transportKey)
+}
+
+// CompressionStats returns the sizes of the messages compressed and decompressed by the pool
+func (pool *ConnectionPool) CompressionStats() CompressionStats {
+	return pool.compressionStats.get()
+}
+
 // Connect to an address
 func (pool *ConnectionPool) Connect(address string) error {
+	return pool.connect(address, false, cipher.PubKey{})
+}
+
+// ConnectEncrypted connects to an address and negotiates the encrypted transport with the peer.
+// If peerKey is set, the connection fails with ErrEncryptedTransportPeerKey if the peer authenticates with another key.
+// The connection fails with ErrEncryptedTransportDeclined if the peer declines the encrypted transport
+func (pool *ConnectionPool) ConnectEncrypted(address string, peerKey cipher.PubKey) error {
+	return pool.connect(address, true, peerKey)
+}
+
+func (pool *ConnectionPool) connect(address string, encrypt bool, peerKey cipher.PubKey) error {
 	if err := pool.strand("canConnect", func() error {
-		return pool.canConnect(address, true)
+		return pool.canConnect(address, true, -1)
 	}); err != nil {
 		return err
 	}
 
 	logger.WithField("addr", address).Debugf("Making TCP connection")
-	conn, err := net.DialTimeout("tcp", address, pool.Config.DialTimeout)
+	conn, err := pool.dial(address)
 	if err != nil {
 		return err
 	}
@@ -813,7 +1277,7 @@ func (pool *ConnectionPool) Connect(address string) error {
 	pool.wg.Add(1)
 	go func() {
 		defer pool.wg.Done()
-		if err := pool.handleConnection(conn, true); err != nil {
+		if err := pool.handleConnection(conn, true, encrypt, peerKey); err != nil {
 			logger.WithFields(logrus.Fields{
 				"addr":     conn.RemoteAddr(),
 				"outgoing": true,
@@ -823,6 +1287,37 @@ func (pool *ConnectionPool) Connect(address string) error {
 	return nil
 }
 
+// dial makes a connection to the address, through the proxy if Config.ProxyAddress is set.
+// The hostname of the address is resolved by the proxy
+func (pool *ConnectionPool) dial(address string) (net.Conn, error) {
+	if pool.Config.ProxyAddress == "" {
+		return pool.transport().Dial(pool.network(), address, pool.Config.DialTimeout)
+	}
+
+	return socks5.NewDialer(pool.Config.ProxyAddress, pool.Config.DialTimeout).Dial("tcp", address)
+}
+
+// transport returns Config.Transport, or TCP if it is not set
+func (pool *ConnectionPool) transport() Transport {
+	if pool.Config.Transport == nil {
+		return tcpTransport{}
+	}
+	return pool.Config.Transport
+}
+
+// network returns the network to listen on and dial, restricted to one IP family
+// by Config.DisableIPv4 or Config.DisableIPv6
+func (pool *ConnectionPool) network() string {
+	switch {
+	case pool.Config.DisableIPv4:
+		return "tcp6"
+	case pool.Config.DisableIPv6:
+		return "tcp4"
+	default:
+		return "tcp"
+	}
+}
+
 // Disconnect removes a connection from the pool by address and invokes DisconnectCallback
 func (pool *ConnectionPool) Disconnect(addr string, r DisconnectReason) error {
 	return pool.strand("Disconnect", func() error {
@@ -873,6 +1368,8 @@ func (pool *ConnectionPool) disconnect(addr string, r DisconnectReason) *Connect
 	delete(pool.addresses, addr)
 	delete(pool.defaultOutgoingConnections, addr)
 	delete(pool.outgoingConnections, addr)
+	delete(pool.whitelistedConnections, addr)
+	delete(pool.listenerConnections, addr)
 	if err := conn.Close(); err != nil {
 		logger.WithError(err).WithFields(fields).Error("conn.Close")
 	}
@@ -993,6 +1490,15 @@ func (pool *ConnectionPool) BroadcastMessage(msg Message, addrs []string) (int,
 // first return value.  Otherwise, error will be nil and DisconnectReason will
 // be the value returned from the message handler.
 func (pool *ConnectionPool) receiveMessage(c *Connection, msg []byte) error {
+	if isCompressedMessage(msg) {
+		data, err := decompressMessage(msg, pool.Config.MaxMessageLength)
+		if err != nil {
+			return err
+		}
+		pool.compressionStats.received(messageLengthSize+len(data), messageLengthSize+len(msg))
+		msg = data
+	}
+
 	m, err := convertToMessage(c.ID, msg, pool.Config.DebugPrint)
 	if err != nil {
 		return err
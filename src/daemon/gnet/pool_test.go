@@ -6,6 +6,8 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net"
 	"strings"
 	"sync"
@@ -14,6 +16,7 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/util/logging"
 )
 
@@ -280,7 +283,7 @@ func TestHandleConnection(t *testing.T) {
 	done := make(chan struct{})
 	go func() {
 		defer close(done)
-		err = p.handleConnection(conn, true)
+		err = p.handleConnection(conn, true, false, cipher.PubKey{})
 		require.NotEqual(t, ErrConnectionExists, err)
 		require.NotEqual(t, ErrMaxIncomingConnectionsReached, err)
 		require.NotEqual(t, ErrMaxOutgoingConnectionsReached, err)
@@ -376,6 +379,303 @@ func TestConnectNoTimeout(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestConnectProxy(t *testing.T) {
+	// A SOCKS5 proxy that accepts any CONNECT request and keeps the connection open
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	reqC := make(chan []byte, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		buf := make([]byte, 512)
+		if _, err := io.ReadFull(conn, buf[:3]); err != nil {
+			return
+		}
+		if _, err := conn.Write([]byte{5, 0}); err != nil {
+			return
+		}
+
+		n, err := conn.Read(buf)
+		if err != nil {
+			return
+		}
+		reqC <- buf[:n]
+
+		if _, err := conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0}); err != nil {
+			return
+		}
+
+		io.Copy(ioutil.Discard, conn) // nolint: errcheck
+	}()
+
+	cfg := newTestConfig()
+	cfg.Port += 2
+	cfg.ProxyAddress = l.Addr().String()
+
+	p, err := NewConnectionPool(cfg, nil)
+	require.NoError(t, err)
+
+	connected := make(chan string, 1)
+	p.Config.ConnectCallback = func(addr string, id uint64, solicited bool) {
+		require.True(t, solicited)
+		connected <- addr
+	}
+
+	q := make(chan struct{})
+	go func() {
+		defer close(q)
+		err := p.Run()
+		require.NoError(t, err)
+	}()
+	wait()
+
+	// The hostname is sent to the proxy, and the connection is known by the dialed address
+	onion := "pg6mmjiyjmcrsslvykfwnntlaru7p5svn6y2ymmju6nubxndf4pscryd.onion"
+	err = p.Connect(onion + ":6000")
+	require.NoError(t, err)
+
+	req := <-reqC
+	require.Equal(t, append(append([]byte{5, 1, 0, 3, byte(len(onion))}, onion...), 0x17, 0x70), req)
+	require.Equal(t, onion+":6000", <-connected)
+
+	c, err := p.GetConnection(onion + ":6000")
+	require.NoError(t, err)
+	require.NotNil(t, c)
+
+	p.Shutdown()
+	<-q
+}
+
+func TestNewConnectionWhitelisted(t *testing.T) {
+	cfg := newTestConfig()
+	// No incoming connection slots
+	cfg.MaxConnections = 16
+	cfg.MaxWhitelistedConnections = 1
+	cfg.PeerUploadRateLimit = 1000
+	cfg.PeerDownloadRateLimit = 1000
+	cfg.Whitelist = []string{"11.22.33.44", "2001:0db8::1"}
+	p, err := NewConnectionPool(cfg, nil)
+	require.NoError(t, err)
+
+	require.True(t, p.IsWhitelisted("11.22.33.44:6000"))
+	require.True(t, p.IsWhitelisted("[2001:db8::1]:6000"))
+	require.False(t, p.IsWhitelisted("11.22.33.45:6000"))
+	require.False(t, p.IsWhitelisted("11.22.33.44"))
+
+	_, err = p.newConnection(NewDummyConn("11.22.33.45:6000"), false)
+	require.Equal(t, ErrMaxIncomingConnectionsReached, err)
+
+	// A whitelisted peer gets a reserved slot, and is not rate limited
+	c, err := p.newConnection(NewDummyConn("11.22.33.44:6000"), false)
+	require.NoError(t, err)
+	require.True(t, c.Whitelisted)
+	require.Nil(t, c.uploadLimiter)
+	require.Nil(t, c.downloadLimiter)
+	require.Len(t, p.whitelistedConnections, 1)
+
+	// The reserved slots are full
+	_, err = p.newConnection(NewDummyConn("[2001:db8::1]:6000"), false)
+	require.Equal(t, ErrMaxIncomingConnectionsReached, err)
+
+	// Outgoing connections are limited as usual
+	c, err = p.newConnection(NewDummyConn("[2001:db8::1]:6000"), true)
+	require.NoError(t, err)
+	require.True(t, c.Whitelisted)
+	require.Len(t, p.whitelistedConnections, 1)
+
+	// Disconnecting frees the reserved slot
+	require.NotNil(t, p.disconnect("11.22.33.44:6000", ErrDisconnectUnknownMessage))
+	require.Empty(t, p.whitelistedConnections)
+
+	cfg.Whitelist = []string{"11.22.33"}
+	_, err = NewConnectionPool(cfg, nil)
+	require.Equal(t, errors.New(`Invalid whitelisted IP "11.22.33"`), err)
+}
+
+// listenerConn is a connection accepted on a local address
+type listenerConn struct {
+	net.Conn
+	localAddr net.Addr
+}
+
+func (c listenerConn) LocalAddr() net.Addr {
+	return c.localAddr
+}
+
+func TestNewConnectionListeners(t *testing.T) {
+	cfg := newTestConfig()
+	// No incoming connection slots on the main listener
+	cfg.MaxConnections = 16
+	cfg.Listeners = []ListenerConfig{
+		{Address: "127.0.0.1", MaxConnections: 1},
+		{Address: "127.0.0.1"},
+	}
+	p, err := NewConnectionPool(cfg, nil)
+	require.NoError(t, err)
+
+	for _, l := range cfg.Listeners {
+		ln, err := net.Listen("tcp", l.String())
+		require.NoError(t, err)
+		defer ln.Close()
+		p.listeners = append(p.listeners, ln)
+	}
+
+	accepted := func(remoteAddr string, listener int) net.Conn {
+		return listenerConn{
+			Conn:      NewDummyConn(remoteAddr),
+			localAddr: p.listeners[listener].Addr(),
+		}
+	}
+
+	require.Equal(t, -1, p.acceptingListener(NewDummyConn(addr).LocalAddr()))
+	require.Equal(t, -1, p.acceptingListener(&net.TCPAddr{IP: net.ParseIP("127.0.0.2"), Port: p.listeners[0].Addr().(*net.TCPAddr).Port}))
+	require.Equal(t, 1, p.acceptingListener(p.listeners[1].Addr()))
+
+	_, err = p.newConnection(NewDummyConn("11.22.33.45:6000"), false)
+	require.Equal(t, ErrMaxIncomingConnectionsReached, err)
+
+	// Each listener has its own limit of incoming connections
+	c, err := p.newConnection(accepted("11.22.33.44:6000", 0), false)
+	require.NoError(t, err)
+	require.Equal(t, &p.Config.Listeners[0], c.Listener)
+
+	_, err = p.newConnection(accepted("11.22.33.45:6000", 0), false)
+	require.Equal(t, ErrMaxIncomingConnectionsReached, err)
+
+	for i := 0; i < 3; i++ {
+		c, err := p.newConnection(accepted(fmt.Sprintf("11.22.33.%d:6000", 50+i), 1), false)
+		require.NoError(t, err)
+		require.Equal(t, &p.Config.Listeners[1], c.Listener)
+	}
+	require.Len(t, p.listenerConnections, 4)
+
+	// Outgoing connections are not accepted on a listener
+	c, err = p.newConnection(NewDummyConn("11.22.33.46:6000"), true)
+	require.NoError(t, err)
+	require.Nil(t, c.Listener)
+
+	// Disconnecting frees the listener's slot
+	require.NotNil(t, p.disconnect("11.22.33.44:6000", ErrDisconnectUnknownMessage))
+	require.Len(t, p.listenerConnections, 3)
+
+	_, err = p.newConnection(accepted("11.22.33.45:6000", 0), false)
+	require.NoError(t, err)
+}
+
+func TestNewConnectionEvict(t *testing.T) {
+	cfg := newTestConfig()
+	// Two incoming connection slots
+	cfg.MaxConnections = 18
+	cfg.Whitelist = []string{"11.22.33.99"}
+
+	var disconnected []string
+	cfg.DisconnectCallback = func(addr string, id uint64, reason DisconnectReason) {
+		require.Equal(t, ErrDisconnectEvicted, reason)
+		disconnected = append(disconnected, addr)
+	}
+
+	p, err := NewConnectionPool(cfg, nil)
+	require.NoError(t, err)
+
+	for _, a := range []string{"11.22.33.44:6000", "11.22.33.45:6000"} {
+		_, err := p.newConnection(NewDummyConn(a), false)
+		require.NoError(t, err)
+	}
+
+	// Without an EvictCallback, the new connection is rejected
+	_, err = p.newConnection(NewDummyConn("11.22.33.46:6000"), false)
+	require.Equal(t, ErrMaxIncomingConnectionsReached, err)
+
+	// Outgoing and whitelisted connections are not candidates
+	_, err = p.newConnection(NewDummyConn("11.22.33.47:6000"), true)
+	require.NoError(t, err)
+	_, err = p.newConnection(NewDummyConn("11.22.33.99:6000"), false)
+	require.NoError(t, err)
+	require.Len(t, p.whitelistedConnections, 1)
+
+	var evictAddr string
+	p.Config.EvictCallback = func(addr string, candidates []string) (string, bool) {
+		require.Equal(t, "11.22.33.46:6000", addr)
+		require.Equal(t, []string{"11.22.33.44:6000", "11.22.33.45:6000"}, candidates)
+		return evictAddr, evictAddr != ""
+	}
+
+	// The callback refuses to evict
+	_, err = p.newConnection(NewDummyConn("11.22.33.46:6000"), false)
+	require.Equal(t, ErrMaxIncomingConnectionsReached, err)
+
+	// The callback returns an address that is not a candidate
+	evictAddr = "11.22.33.47:6000"
+	_, err = p.newConnection(NewDummyConn("11.22.33.46:6000"), false)
+	require.Equal(t, ErrMaxIncomingConnectionsReached, err)
+	require.Empty(t, disconnected)
+
+	// The chosen candidate is disconnected to free its slot
+	evictAddr = "11.22.33.45:6000"
+	_, err = p.newConnection(NewDummyConn("11.22.33.46:6000"), false)
+	require.NoError(t, err)
+	require.Equal(t, []string{"11.22.33.45:6000"}, disconnected)
+	require.False(t, p.isConnExist("11.22.33.45:6000"))
+	require.True(t, p.isMaxIncomingConnectionsReached())
+}
+
+func TestPoolThrottle(t *testing.T) {
+	cfg := newTestConfig()
+	p, err := NewConnectionPool(cfg, nil)
+	require.NoError(t, err)
+	require.Nil(t, p.uploadLimiter)
+	require.Nil(t, p.downloadLimiter)
+
+	cfg.UploadRateLimit = 1000
+	cfg.DownloadRateLimit = 2000
+	p, err = NewConnectionPool(cfg, nil)
+	require.NoError(t, err)
+	require.NotNil(t, p.uploadLimiter)
+	require.NotNil(t, p.downloadLimiter)
+
+	qc := make(chan struct{})
+
+	// Within the burst, and nil limiters don't limit
+	start := time.Now()
+	require.True(t, p.throttle(1000, qc, p.uploadLimiter, nil))
+	require.True(t, time.Since(start) < time.Millisecond*50)
+
+	// Over the limit, waits for the tokens
+	start = time.Now()
+	require.True(t, p.throttle(100, qc, p.uploadLimiter, nil))
+	require.True(t, time.Since(start) >= time.Millisecond*90)
+
+	// Waiting is interrupted when the connection quits
+	close(qc)
+	start = time.Now()
+	require.False(t, p.throttle(100000, qc, p.uploadLimiter))
+	require.True(t, time.Since(start) < time.Second)
+
+	// Waiting is interrupted when the pool quits
+	close(p.quit)
+	require.False(t, p.throttle(100000, make(chan struct{}), p.downloadLimiter))
+}
+
+func TestPoolNetwork(t *testing.T) {
+	cfg := newTestConfig()
+	p, err := NewConnectionPool(cfg, nil)
+	require.NoError(t, err)
+	require.Equal(t, "tcp", p.network())
+
+	p.Config.DisableIPv6 = true
+	require.Equal(t, "tcp4", p.network())
+
+	p.Config.DisableIPv6 = false
+	p.Config.DisableIPv4 = true
+	require.Equal(t, "tcp6", p.network())
+}
+
 func TestDisconnect(t *testing.T) {
 	cfg := newTestConfig()
 	p, err := NewConnectionPool(cfg, nil)
@@ -554,7 +854,7 @@ func TestConnectionReadLoopReadError(t *testing.T) {
 	// and throws an error on Read
 	reconn := NewReadErrorConn()
 	go func() {
-		err := p.handleConnection(reconn, false)
+		err := p.handleConnection(reconn, false, false, cipher.PubKey{})
 		require.Equal(t, readDataErr, err.Error())
 	}()
 
@@ -604,7 +904,7 @@ func TestConnectionReadLoopSetReadDeadlineFailed(t *testing.T) {
 
 	rdfconn := &ReadDeadlineFailedConn{}
 	go func() {
-		err := p.handleConnection(rdfconn, false)
+		err := p.handleConnection(rdfconn, false, false, cipher.PubKey{})
 		require.Equal(t, ErrDisconnectSetReadDeadlineFailed, err)
 	}()
 
@@ -649,7 +949,7 @@ func TestConnectionReadLoopInvalidMessageLength(t *testing.T) {
 
 	raconn := newReadAlwaysConn()
 	go func() {
-		err := p.handleConnection(raconn, false)
+		err := p.handleConnection(raconn, false, false, cipher.PubKey{})
 		require.Equal(t, ErrDisconnectInvalidMessageLength, err)
 	}()
 
@@ -695,7 +995,7 @@ func TestConnectionReadLoopTerminates(t *testing.T) {
 		require.Equal(t, readDataErr, reason.Error())
 	}
 	go func() {
-		err := p.handleConnection(rnconn, false)
+		err := p.handleConnection(rnconn, false, false, cipher.PubKey{})
 		require.Equal(t, readDataErr, err.Error())
 	}()
 
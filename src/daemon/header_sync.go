@@ -0,0 +1,452 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/daemon/gnet"
+	"github.com/skycoin/skycoin/src/visor"
+)
+
+const (
+	// maxSyncHeaders is the maximum number of headers requested from a peer at once
+	maxSyncHeaders = 512
+	// maxSyncBodies is the maximum number of block bodies requested from a peer at once
+	maxSyncBodies = 16
+	// maxSyncHeadersAhead is how far above the head block headers are downloaded
+	maxSyncHeadersAhead = 8192
+	// maxSyncBodiesAhead is how far above the head block bodies are downloaded, which limits the memory
+	// used by the bodies waiting for the bodies of previous blocks
+	maxSyncBodiesAhead = 512
+	// headerSyncRequestTimeout is how long a peer has to respond to a headers or bodies request.
+	// A peer that did not respond in time is not sent new requests for as long
+	headerSyncRequestTimeout = time.Second * 20
+	// maxBlockBodiesResponseSize is the maximum size of the transactions of the bodies sent in response
+	// to a GetBlockBodiesMessage, to keep the message below the maximum message length
+	maxBlockBodiesResponseSize = 128 * 1024
+)
+
+var (
+	// ErrSyncHeadersInvalid is returned when a peer sends headers that are not the requested ones
+	// or do not form a chain
+	ErrSyncHeadersInvalid = errors.New("Block headers are not the requested chain of headers")
+)
+
+// HashedBlockBody is the body of a block with the hash of the block header
+type HashedBlockBody struct {
+	BlockHash cipher.SHA256
+	Body      coin.BlockBody
+}
+
+// syncPeer is a peer that the header-first synchronization can request headers and bodies from
+type syncPeer struct {
+	addr   string
+	height uint64
+	pruned bool
+}
+
+// hasBody returns true if the peer should still have the body of the block seq
+func (p syncPeer) hasBody(seq uint64) bool {
+	return seq <= p.height && (!p.pruned || p.height < seq+visor.MinPruneDepth)
+}
+
+// syncRequest is a request to send to a peer
+type syncRequest struct {
+	addr string
+	msg  gnet.Message
+}
+
+type headersRequest struct {
+	start   uint64
+	count   uint64
+	created time.Time
+}
+
+type syncBlock struct {
+	header coin.SignedBlockHeader
+	body   *coin.BlockBody
+	// Peer the body is requested from. Empty if the body is not requested
+	addr string
+}
+
+// headerSync is the state of the header-first synchronization. The headers of the blocks above the head block
+// are downloaded and signature-verified first, in ranges requested from multiple peers in parallel.
+// Once linked to the head block, the bodies of the blocks are requested from the peers that have them,
+// in any order, and the complete blocks are returned by ready in order, to be executed.
+type headerSync struct {
+	sync.Mutex
+	pubkey cipher.PubKey
+	// Seq and hash of the last header of the chain of headers linked to the head block
+	tipSeq  uint64
+	tipHash cipher.SHA256
+	started bool
+	// Verified headers that are not linked to the chain yet, by seq
+	headers map[uint64]coin.SignedBlockHeader
+	// Blocks of the chain of headers above the head block, by seq, and their seq by hash
+	blocks map[uint64]*syncBlock
+	seqs   map[cipher.SHA256]uint64
+	// Pending requests by peer
+	headersRequests map[string]headersRequest
+	bodiesRequests  map[string]time.Time
+	// Peers that did not respond to a request in time, and when
+	stalled map[string]time.Time
+	// Peers that did not respond to a request in time since the last call to takeStalled
+	newlyStalled []string
+}
+
+func newHeaderSync(pubkey cipher.PubKey) *headerSync {
+	s := &headerSync{
+		pubkey: pubkey,
+	}
+	s.clear()
+	return s
+}
+
+func (s *headerSync) clear() {
+	s.started = false
+	s.tipSeq = 0
+	s.tipHash = cipher.SHA256{}
+	s.headers = make(map[uint64]coin.SignedBlockHeader)
+	s.blocks = make(map[uint64]*syncBlock)
+	s.seqs = make(map[cipher.SHA256]uint64)
+	s.headersRequests = make(map[string]headersRequest)
+	s.bodiesRequests = make(map[string]time.Time)
+	s.stalled = make(map[string]time.Time)
+	s.newlyStalled = nil
+}
+
+// reset forgets the downloaded headers and bodies and the pending requests
+func (s *headerSync) reset() {
+	s.Lock()
+	defer s.Unlock()
+	s.clear()
+}
+
+// setHead removes the headers and blocks up to the head block. If the chain of headers does not link
+// to the head block, the downloaded headers and bodies are forgotten
+func (s *headerSync) setHead(headSeq uint64, headHash cipher.SHA256) {
+	if b, ok := s.blocks[headSeq+1]; ok && b.header.Head.PrevHash != headHash {
+		s.headers = make(map[uint64]coin.SignedBlockHeader)
+		s.blocks = make(map[uint64]*syncBlock)
+		s.seqs = make(map[cipher.SHA256]uint64)
+		s.started = false
+	}
+
+	if !s.started || s.tipSeq <= headSeq {
+		s.tipSeq = headSeq
+		s.tipHash = headHash
+		s.started = true
+	}
+
+	for seq := range s.headers {
+		if seq <= headSeq {
+			delete(s.headers, seq)
+		}
+	}
+
+	for seq, b := range s.blocks {
+		if seq <= headSeq {
+			delete(s.seqs, b.header.Head.Hash())
+			delete(s.blocks, seq)
+		}
+	}
+}
+
+// expire removes the requests that timed out and marks their peers as stalled
+func (s *headerSync) expire(now time.Time) {
+	for addr, r := range s.headersRequests {
+		if now.Sub(r.created) >= headerSyncRequestTimeout {
+			delete(s.headersRequests, addr)
+			s.stall(addr, now)
+		}
+	}
+
+	for addr, created := range s.bodiesRequests {
+		if now.Sub(created) >= headerSyncRequestTimeout {
+			s.freeBodies(addr)
+			s.stall(addr, now)
+		}
+	}
+
+	for addr, t := range s.stalled {
+		if now.Sub(t) >= headerSyncRequestTimeout {
+			delete(s.stalled, addr)
+		}
+	}
+}
+
+// stall marks a peer as stalled
+func (s *headerSync) stall(addr string, now time.Time) {
+	if _, ok := s.stalled[addr]; !ok {
+		s.newlyStalled = append(s.newlyStalled, addr)
+	}
+	s.stalled[addr] = now
+}
+
+// takeStalled returns and forgets the peers that did not respond to a request in time
+// since the last call
+func (s *headerSync) takeStalled() []string {
+	s.Lock()
+	defer s.Unlock()
+
+	stalled := s.newlyStalled
+	s.newlyStalled = nil
+	return stalled
+}
+
+// freeBodies removes the bodies request to a peer, so that the bodies it did not send can be requested again
+func (s *headerSync) freeBodies(addr string) {
+	delete(s.bodiesRequests, addr)
+	for _, b := range s.blocks {
+		if b.addr == addr && b.body == nil {
+			b.addr = ""
+		}
+	}
+}
+
+// requested returns true if the header seq is downloaded or requested
+func (s *headerSync) requested(seq uint64) bool {
+	if _, ok := s.headers[seq]; ok {
+		return true
+	}
+
+	for _, r := range s.headersRequests {
+		if seq >= r.start && seq < r.start+r.count {
+			return true
+		}
+	}
+
+	return false
+}
+
+// requests returns the headers and bodies requests to send to the peers. Each peer has at most
+// one pending headers request and one pending bodies request. The headers are requested in ranges above
+// the last linked header that are not downloaded or requested yet, and the bodies of the linked blocks
+// from the lowest seq
+func (s *headerSync) requests(now time.Time, headSeq uint64, headHash cipher.SHA256, peers []syncPeer) []syncRequest {
+	s.Lock()
+	defer s.Unlock()
+
+	s.setHead(headSeq, headHash)
+	s.expire(now)
+
+	var reqs []syncRequest
+	for _, p := range peers {
+		if _, ok := s.stalled[p.addr]; ok {
+			continue
+		}
+
+		if _, ok := s.headersRequests[p.addr]; !ok {
+			if r, ok := s.headersRequest(headSeq, p); ok {
+				r.created = now
+				s.headersRequests[p.addr] = r
+				reqs = append(reqs, syncRequest{
+					addr: p.addr,
+					msg:  NewGetBlockHeadersMessage(r.start-1, r.count),
+				})
+			}
+		}
+
+		if _, ok := s.bodiesRequests[p.addr]; !ok {
+			if hashes := s.bodiesRequest(headSeq, p); len(hashes) != 0 {
+				s.bodiesRequests[p.addr] = now
+				reqs = append(reqs, syncRequest{
+					addr: p.addr,
+					msg:  NewGetBlockBodiesMessage(hashes),
+				})
+			}
+		}
+	}
+
+	return reqs
+}
+
+// headersRequest returns the lowest range of headers that is not downloaded or requested yet
+// and that the peer has
+func (s *headerSync) headersRequest(headSeq uint64, p syncPeer) (headersRequest, bool) {
+	limit := headSeq + maxSyncHeadersAhead
+	if p.height < limit {
+		limit = p.height
+	}
+
+	start := s.tipSeq + 1
+	for start <= limit && s.requested(start) {
+		start++
+	}
+	if start > limit {
+		return headersRequest{}, false
+	}
+
+	var count uint64
+	for start+count <= limit && count < maxSyncHeaders && !s.requested(start+count) {
+		count++
+	}
+
+	return headersRequest{
+		start: start,
+		count: count,
+	}, true
+}
+
+// bodiesRequest returns the hashes of the lowest blocks whose bodies are not downloaded or requested yet
+// and that the peer has, and marks them as requested from the peer
+func (s *headerSync) bodiesRequest(headSeq uint64, p syncPeer) []cipher.SHA256 {
+	limit := headSeq + maxSyncBodiesAhead
+	if s.tipSeq < limit {
+		limit = s.tipSeq
+	}
+
+	var hashes []cipher.SHA256
+	for seq := headSeq + 1; seq <= limit && len(hashes) < maxSyncBodies; seq++ {
+		b := s.blocks[seq]
+		if b == nil || b.body != nil || b.addr != "" || !p.hasBody(seq) {
+			continue
+		}
+
+		b.addr = p.addr
+		hashes = append(hashes, b.header.Head.Hash())
+	}
+
+	return hashes
+}
+
+// addHeaders adds the headers received from a peer in response to its headers request, and links them
+// to the chain of headers. Returns ErrSyncHeadersInvalid if the headers are not the requested chain of headers,
+// or an error if a signature is invalid. Headers that were not requested from the peer are ignored
+func (s *headerSync) addHeaders(addr string, headers []coin.SignedBlockHeader) error {
+	s.Lock()
+	defer s.Unlock()
+
+	r, ok := s.headersRequests[addr]
+	if !ok {
+		return nil
+	}
+	delete(s.headersRequests, addr)
+
+	if uint64(len(headers)) > r.count {
+		return ErrSyncHeadersInvalid
+	}
+
+	for i, h := range headers {
+		if h.Head.BkSeq != r.start+uint64(i) {
+			return ErrSyncHeadersInvalid
+		}
+		if i > 0 && h.Head.PrevHash != headers[i-1].Head.Hash() {
+			return ErrSyncHeadersInvalid
+		}
+		if err := h.VerifySignature(s.pubkey); err != nil {
+			return fmt.Errorf("Block header seq=%d signature is invalid: %v", h.Head.BkSeq, err)
+		}
+	}
+
+	for _, h := range headers {
+		if h.Head.BkSeq > s.tipSeq {
+			s.headers[h.Head.BkSeq] = h
+		}
+	}
+
+	s.link()
+
+	return nil
+}
+
+// link moves the headers that link to the last linked header to the chain of headers
+func (s *headerSync) link() {
+	for {
+		h, ok := s.headers[s.tipSeq+1]
+		if !ok {
+			return
+		}
+		delete(s.headers, s.tipSeq+1)
+
+		// The header is signed but does not link to the chain, it will be requested again
+		if h.Head.PrevHash != s.tipHash {
+			return
+		}
+
+		hash := h.Head.Hash()
+		s.blocks[h.Head.BkSeq] = &syncBlock{
+			header: h,
+		}
+		s.seqs[hash] = h.Head.BkSeq
+		s.tipSeq = h.Head.BkSeq
+		s.tipHash = hash
+	}
+}
+
+// addBodies adds the bodies received from a peer in response to its bodies request. The bodies requested
+// from the peer that it did not send can be requested from other peers. Returns an error if a body
+// does not match the body hash of its block header. Bodies that were not requested from the peer are ignored
+func (s *headerSync) addBodies(addr string, bodies []HashedBlockBody) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.bodiesRequests[addr]; !ok {
+		return nil
+	}
+	defer s.freeBodies(addr)
+
+	for _, hb := range bodies {
+		seq, ok := s.seqs[hb.BlockHash]
+		if !ok {
+			continue
+		}
+
+		b := s.blocks[seq]
+		if b.addr != addr || b.body != nil {
+			continue
+		}
+
+		if hb.Body.Hash() != b.header.Head.BodyHash {
+			return fmt.Errorf("Block seq=%d body does not match the body hash of its header", seq)
+		}
+
+		body := hb.Body
+		b.body = &body
+	}
+
+	return nil
+}
+
+// ready removes and returns the complete blocks that follow the head block, in order
+func (s *headerSync) ready(headSeq uint64, headHash cipher.SHA256) []coin.SignedBlock {
+	s.Lock()
+	defer s.Unlock()
+
+	s.setHead(headSeq, headHash)
+
+	var blocks []coin.SignedBlock
+	for seq := headSeq + 1; ; seq++ {
+		b, ok := s.blocks[seq]
+		if !ok || b.body == nil {
+			break
+		}
+
+		blocks = append(blocks, coin.SignedBlock{
+			Block: coin.Block{
+				Head: b.header.Head,
+				Body: *b.body,
+			},
+			Sig: b.header.Sig,
+		})
+
+		delete(s.seqs, b.header.Head.Hash())
+		delete(s.blocks, seq)
+	}
+
+	return blocks
+}
+
+// removePeer removes the pending requests to a disconnected peer
+func (s *headerSync) removePeer(addr string) {
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.headersRequests, addr)
+	s.freeBodies(addr)
+	delete(s.stalled, addr)
+}
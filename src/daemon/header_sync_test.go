@@ -0,0 +1,423 @@
+package daemon
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/daemon/gnet"
+	"github.com/skycoin/skycoin/src/visor"
+)
+
+// makeSyncTestChain creates a chain of n signed blocks after the block prev
+func makeSyncTestChain(t *testing.T, secKey cipher.SecKey, prev coin.SignedBlock, n int) []coin.SignedBlock {
+	blocks := make([]coin.SignedBlock, n)
+	for i := range blocks {
+		body := coin.BlockBody{
+			Transactions: coin.Transactions{makeCompactTestTxn(byte(prev.Head.BkSeq + 1))},
+		}
+
+		b := coin.Block{
+			Head: coin.BlockHeader{
+				Version:  1,
+				Time:     prev.Head.Time + 10,
+				BkSeq:    prev.Head.BkSeq + 1,
+				PrevHash: prev.HashHeader(),
+				BodyHash: body.Hash(),
+			},
+			Body: body,
+		}
+
+		blocks[i] = coin.SignedBlock{
+			Block: b,
+			Sig:   cipher.MustSignHash(b.HashHeader(), secKey),
+		}
+		prev = blocks[i]
+	}
+
+	return blocks
+}
+
+func syncTestHeaders(blocks []coin.SignedBlock) []coin.SignedBlockHeader {
+	headers := make([]coin.SignedBlockHeader, len(blocks))
+	for i, b := range blocks {
+		headers[i] = b.SignedHeader()
+	}
+	return headers
+}
+
+func syncTestBodies(blocks []coin.SignedBlock) []HashedBlockBody {
+	bodies := make([]HashedBlockBody, len(blocks))
+	for i, b := range blocks {
+		bodies[i] = HashedBlockBody{
+			BlockHash: b.HashHeader(),
+			Body:      b.Body,
+		}
+	}
+	return bodies
+}
+
+func TestHeaderSync(t *testing.T) {
+	pubkey, seckey := cipher.GenerateKeyPair()
+	head := makeSyncTestChain(t, seckey, coin.SignedBlock{}, 1)[0]
+	chain := makeSyncTestChain(t, seckey, head, maxSyncHeaders+10)
+	headSeq := head.Head.BkSeq
+	headHash := head.HashHeader()
+	now := time.Now()
+
+	s := newHeaderSync(pubkey)
+	peers := []syncPeer{
+		{addr: "1.1.1.1:6000", height: chain[len(chain)-1].Head.BkSeq},
+		{addr: "2.2.2.2:6000", height: chain[len(chain)-1].Head.BkSeq},
+		// Nothing to request from a peer that is not ahead
+		{addr: "3.3.3.3:6000", height: headSeq},
+	}
+
+	// The headers are requested in ranges from the peers in parallel
+	reqs := s.requests(now, headSeq, headHash, peers)
+	require.Equal(t, []syncRequest{
+		{addr: peers[0].addr, msg: NewGetBlockHeadersMessage(headSeq, maxSyncHeaders)},
+		{addr: peers[1].addr, msg: NewGetBlockHeadersMessage(headSeq+maxSyncHeaders, 10)},
+	}, reqs)
+
+	// Pending requests are not repeated
+	require.Empty(t, s.requests(now, headSeq, headHash, peers))
+
+	// Headers that were not requested from the peer are ignored
+	require.NoError(t, s.addHeaders(peers[2].addr, syncTestHeaders(chain[:10])))
+	require.Empty(t, s.headers)
+
+	// The second range is received first, it can't be linked yet
+	require.NoError(t, s.addHeaders(peers[1].addr, syncTestHeaders(chain[maxSyncHeaders:])))
+	require.Len(t, s.headers, 10)
+	require.Empty(t, s.blocks)
+	require.Equal(t, headSeq, s.tipSeq)
+
+	// The first range links both ranges to the head block
+	require.NoError(t, s.addHeaders(peers[0].addr, syncTestHeaders(chain[:maxSyncHeaders])))
+	require.Empty(t, s.headers)
+	require.Len(t, s.blocks, len(chain))
+	require.Equal(t, chain[len(chain)-1].Head.BkSeq, s.tipSeq)
+	require.Equal(t, chain[len(chain)-1].HashHeader(), s.tipHash)
+
+	// The bodies are requested from the lowest blocks, from different peers
+	reqs = s.requests(now, headSeq, headHash, peers)
+	require.Len(t, reqs, 2)
+	require.Equal(t, syncRequest{
+		addr: peers[0].addr,
+		msg:  NewGetBlockBodiesMessage(syncTestHashes(chain[:maxSyncBodies])),
+	}, reqs[0])
+	require.Equal(t, syncRequest{
+		addr: peers[1].addr,
+		msg:  NewGetBlockBodiesMessage(syncTestHashes(chain[maxSyncBodies : 2*maxSyncBodies])),
+	}, reqs[1])
+
+	// Nothing is ready until the bodies of the next blocks arrive
+	require.NoError(t, s.addBodies(peers[1].addr, syncTestBodies(chain[maxSyncBodies:2*maxSyncBodies])))
+	require.Empty(t, s.ready(headSeq, headHash))
+
+	// The first peer sends only some of the bodies, the others can be requested again
+	require.NoError(t, s.addBodies(peers[0].addr, syncTestBodies(chain[:4])))
+	blocks := s.ready(headSeq, headHash)
+	require.Equal(t, chain[:4], blocks)
+
+	headSeq = blocks[3].Head.BkSeq
+	headHash = blocks[3].HashHeader()
+	require.Empty(t, s.ready(headSeq, headHash))
+
+	reqs = s.requests(now, headSeq, headHash, peers[:1])
+	require.Equal(t, []syncRequest{{
+		addr: peers[0].addr,
+		msg:  NewGetBlockBodiesMessage(syncTestHashes(append(chain[4:maxSyncBodies:maxSyncBodies], chain[2*maxSyncBodies:2*maxSyncBodies+4]...))),
+	}}, reqs)
+
+	require.NoError(t, s.addBodies(peers[0].addr, syncTestBodies(chain[4:maxSyncBodies])))
+	blocks = s.ready(headSeq, headHash)
+	require.Equal(t, chain[4:2*maxSyncBodies], blocks)
+}
+
+func syncTestHashes(blocks []coin.SignedBlock) []cipher.SHA256 {
+	hashes := make([]cipher.SHA256, len(blocks))
+	for i, b := range blocks {
+		hashes[i] = b.HashHeader()
+	}
+	return hashes
+}
+
+func TestHeaderSyncInvalidResponses(t *testing.T) {
+	pubkey, seckey := cipher.GenerateKeyPair()
+	_, otherSeckey := cipher.GenerateKeyPair()
+	head := makeSyncTestChain(t, seckey, coin.SignedBlock{}, 1)[0]
+	chain := makeSyncTestChain(t, seckey, head, 4)
+	headSeq := head.Head.BkSeq
+	headHash := head.HashHeader()
+	peer := syncPeer{addr: "1.1.1.1:6000", height: chain[3].Head.BkSeq}
+	now := time.Now()
+
+	// The same headers, signed with another key
+	forged := makeSyncTestChain(t, otherSeckey, head, 4)
+
+	unlinked := syncTestHeaders(chain[:2])
+	unlinked[1].Head.PrevHash = cipher.SHA256{}
+
+	cases := []struct {
+		name    string
+		headers []coin.SignedBlockHeader
+		err     error
+	}{
+		{
+			name:    "too many headers",
+			headers: syncTestHeaders(append(chain[:4:4], chain[0])),
+			err:     ErrSyncHeadersInvalid,
+		},
+		{
+			name:    "not the requested headers",
+			headers: syncTestHeaders(chain[1:]),
+			err:     ErrSyncHeadersInvalid,
+		},
+		{
+			name:    "not a chain",
+			headers: unlinked,
+			err:     ErrSyncHeadersInvalid,
+		},
+		{
+			name:    "invalid signature",
+			headers: syncTestHeaders(forged),
+			err:     errors.New("Block header seq=2 signature is invalid: Recovered pubkey does not match pubkey"),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newHeaderSync(pubkey)
+			require.Len(t, s.requests(now, headSeq, headHash, []syncPeer{peer}), 1)
+
+			err := s.addHeaders(peer.addr, tc.headers)
+			require.Equal(t, tc.err, err)
+			require.Empty(t, s.headers)
+			require.Empty(t, s.blocks)
+
+			// The headers can be requested again
+			require.Len(t, s.requests(now, headSeq, headHash, []syncPeer{peer}), 1)
+		})
+	}
+
+	// A body that does not match its header
+	s := newHeaderSync(pubkey)
+	require.Len(t, s.requests(now, headSeq, headHash, []syncPeer{peer}), 1)
+	require.NoError(t, s.addHeaders(peer.addr, syncTestHeaders(chain)))
+	require.Len(t, s.requests(now, headSeq, headHash, []syncPeer{peer}), 1)
+
+	bodies := syncTestBodies(chain)
+	bodies[1].Body = chain[0].Body
+	err := s.addBodies(peer.addr, bodies)
+	require.Equal(t, errors.New("Block seq=3 body does not match the body hash of its header"), err)
+	require.Equal(t, chain[:1], s.ready(headSeq, headHash))
+
+	// The remaining bodies can be requested again
+	reqs := s.requests(now, chain[0].Head.BkSeq, chain[0].HashHeader(), []syncPeer{peer})
+	require.Equal(t, []syncRequest{{
+		addr: peer.addr,
+		msg:  NewGetBlockBodiesMessage(syncTestHashes(chain[1:])),
+	}}, reqs)
+}
+
+func TestHeaderSyncStalledPeers(t *testing.T) {
+	pubkey, seckey := cipher.GenerateKeyPair()
+	head := makeSyncTestChain(t, seckey, coin.SignedBlock{}, 1)[0]
+	chain := makeSyncTestChain(t, seckey, head, 4)
+	headSeq := head.Head.BkSeq
+	headHash := head.HashHeader()
+	slow := syncPeer{addr: "1.1.1.1:6000", height: chain[3].Head.BkSeq}
+	fast := syncPeer{addr: "2.2.2.2:6000", height: chain[3].Head.BkSeq}
+	now := time.Now()
+
+	s := newHeaderSync(pubkey)
+	require.Len(t, s.requests(now, headSeq, headHash, []syncPeer{slow}), 1)
+
+	// The request to the slow peer times out and is sent to another peer
+	now = now.Add(headerSyncRequestTimeout)
+	reqs := s.requests(now, headSeq, headHash, []syncPeer{slow, fast})
+	require.Equal(t, []syncRequest{{
+		addr: fast.addr,
+		msg:  NewGetBlockHeadersMessage(headSeq, 4),
+	}}, reqs)
+	require.Equal(t, []string{slow.addr}, s.takeStalled())
+	require.Empty(t, s.takeStalled())
+
+	// The late response of the slow peer is ignored
+	require.NoError(t, s.addHeaders(slow.addr, syncTestHeaders(chain)))
+	require.Empty(t, s.blocks)
+
+	require.NoError(t, s.addHeaders(fast.addr, syncTestHeaders(chain)))
+	require.Len(t, s.blocks, 4)
+
+	// The stalled peer can be sent requests again later
+	now = now.Add(headerSyncRequestTimeout)
+	reqs = s.requests(now, headSeq, headHash, []syncPeer{slow})
+	require.Equal(t, []syncRequest{{
+		addr: slow.addr,
+		msg:  NewGetBlockBodiesMessage(syncTestHashes(chain)),
+	}}, reqs)
+
+	// The bodies requested from a disconnected peer can be requested from other peers
+	s.removePeer(slow.addr)
+	reqs = s.requests(now, headSeq, headHash, []syncPeer{fast})
+	require.Equal(t, []syncRequest{{
+		addr: fast.addr,
+		msg:  NewGetBlockBodiesMessage(syncTestHashes(chain)),
+	}}, reqs)
+}
+
+func TestHeaderSyncHead(t *testing.T) {
+	pubkey, seckey := cipher.GenerateKeyPair()
+	head := makeSyncTestChain(t, seckey, coin.SignedBlock{}, 1)[0]
+	chain := makeSyncTestChain(t, seckey, head, 4)
+	peer := syncPeer{addr: "1.1.1.1:6000", height: chain[3].Head.BkSeq}
+	now := time.Now()
+
+	s := newHeaderSync(pubkey)
+	require.Len(t, s.requests(now, head.Head.BkSeq, head.HashHeader(), []syncPeer{peer}), 1)
+	require.NoError(t, s.addHeaders(peer.addr, syncTestHeaders(chain)))
+	require.Len(t, s.blocks, 4)
+
+	// Blocks executed by other means are removed
+	require.Empty(t, s.ready(chain[1].Head.BkSeq, chain[1].HashHeader()))
+	require.Len(t, s.blocks, 2)
+	require.Len(t, s.seqs, 2)
+
+	// The chain of headers is forgotten if it does not link to the head block
+	fork := chain[0]
+	fork.Head.Time++
+	other := makeSyncTestChain(t, seckey, fork, 2)
+	require.Empty(t, s.ready(other[1].Head.BkSeq-1, other[0].HashHeader()))
+	require.Empty(t, s.blocks)
+	require.Empty(t, s.seqs)
+	require.Equal(t, other[0].Head.BkSeq, s.tipSeq)
+	require.Equal(t, other[0].HashHeader(), s.tipHash)
+}
+
+func TestSyncPeerHasBody(t *testing.T) {
+	p := syncPeer{height: 1000}
+	require.True(t, p.hasBody(1000))
+	require.True(t, p.hasBody(1))
+	require.False(t, p.hasBody(1001))
+
+	p.pruned = true
+	require.True(t, p.hasBody(1000))
+	require.True(t, p.hasBody(1000-visor.MinPruneDepth+1))
+	require.False(t, p.hasBody(1000-visor.MinPruneDepth))
+}
+
+func TestGetBlockHeadersMessageProcess(t *testing.T) {
+	addr := "1.2.3.4:6000"
+	mc := &gnet.MessageContext{
+		Addr:   addr,
+		ConnID: 1,
+	}
+	_, seckey := cipher.GenerateKeyPair()
+	headers := syncTestHeaders(makeSyncTestChain(t, seckey, coin.SignedBlock{}, 3))
+
+	d := &mockDaemoner{}
+	d.On("daemonConfig").Return(DaemonConfig{})
+	d.On("getSignedBlockHeadersSince", uint64(10), uint64(maxSyncHeaders)).Return(headers, nil)
+	d.On("sendMessage", addr, mock.Anything).Return(nil)
+
+	// The number of headers is capped
+	m := NewGetBlockHeadersMessage(10, maxSyncHeaders*2)
+	m.c = mc
+	m.process(d)
+	d.AssertCalled(t, "sendMessage", addr, NewGiveBlockHeadersMessage(headers))
+}
+
+func TestGiveBlockHeadersMessageProcess(t *testing.T) {
+	addr := "1.2.3.4:6000"
+	mc := &gnet.MessageContext{
+		Addr:   addr,
+		ConnID: 1,
+	}
+	_, seckey := cipher.GenerateKeyPair()
+	headers := syncTestHeaders(makeSyncTestChain(t, seckey, coin.SignedBlock{}, 3))
+
+	for _, addErr := range []error{nil, ErrSyncHeadersInvalid} {
+		d := &mockDaemoner{}
+		d.On("daemonConfig").Return(DaemonConfig{})
+		d.On("addSyncHeaders", addr, headers).Return(addErr)
+		d.On("recordPeerMisbehavior", addr, PeerMisbehaviorInvalidMessage)
+		d.On("syncHeadersFirst").Return(nil)
+
+		m := NewGiveBlockHeadersMessage(headers)
+		m.c = mc
+		m.process(d)
+
+		d.AssertCalled(t, "addSyncHeaders", addr, headers)
+		d.AssertCalled(t, "syncHeadersFirst")
+		if addErr != nil {
+			d.AssertCalled(t, "recordPeerMisbehavior", addr, PeerMisbehaviorInvalidMessage)
+		} else {
+			d.AssertNotCalled(t, "recordPeerMisbehavior", mock.Anything, mock.Anything)
+		}
+	}
+}
+
+func TestGetBlockBodiesMessageProcess(t *testing.T) {
+	addr := "1.2.3.4:6000"
+	mc := &gnet.MessageContext{
+		Addr:   addr,
+		ConnID: 1,
+	}
+	_, seckey := cipher.GenerateKeyPair()
+	chain := makeSyncTestChain(t, seckey, coin.SignedBlock{}, 3)
+	unknown := cipher.SumSHA256([]byte("unknown"))
+	pruned := cipher.SumSHA256([]byte("pruned"))
+
+	d := &mockDaemoner{}
+	d.On("daemonConfig").Return(DaemonConfig{})
+	for i := range chain {
+		d.On("getSignedBlockByHash", chain[i].HashHeader()).Return(&chain[i], nil)
+	}
+	d.On("getSignedBlockByHash", pruned).Return(nil, errors.New("block is pruned"))
+	d.On("getSignedBlockByHash", unknown).Return(nil, nil)
+	d.On("sendMessage", addr, mock.Anything).Return(nil)
+
+	// The unknown and pruned blocks are left out
+	m := NewGetBlockBodiesMessage([]cipher.SHA256{chain[2].HashHeader(), unknown, pruned, chain[0].HashHeader()})
+	m.c = mc
+	m.process(d)
+	d.AssertCalled(t, "sendMessage", addr, NewGiveBlockBodiesMessage(syncTestBodies([]coin.SignedBlock{chain[2], chain[0]})))
+}
+
+func TestGiveBlockBodiesMessageProcess(t *testing.T) {
+	addr := "1.2.3.4:6000"
+	mc := &gnet.MessageContext{
+		Addr:   addr,
+		ConnID: 1,
+	}
+	_, seckey := cipher.GenerateKeyPair()
+	bodies := syncTestBodies(makeSyncTestChain(t, seckey, coin.SignedBlock{}, 3))
+
+	for _, addErr := range []error{nil, errors.New("invalid body")} {
+		d := &mockDaemoner{}
+		d.On("daemonConfig").Return(DaemonConfig{})
+		d.On("addSyncBodies", addr, bodies).Return(addErr)
+		d.On("recordPeerMisbehavior", addr, PeerMisbehaviorInvalidMessage)
+		d.On("syncHeadersFirst").Return(nil)
+
+		m := NewGiveBlockBodiesMessage(bodies)
+		m.c = mc
+		m.process(d)
+
+		d.AssertCalled(t, "addSyncBodies", addr, bodies)
+		d.AssertCalled(t, "syncHeadersFirst")
+		if addErr != nil {
+			d.AssertCalled(t, "recordPeerMisbehavior", addr, PeerMisbehaviorInvalidMessage)
+		} else {
+			d.AssertNotCalled(t, "recordPeerMisbehavior", mock.Anything, mock.Anything)
+		}
+	}
+}
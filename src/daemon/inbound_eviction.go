@@ -0,0 +1,192 @@
+package daemon
+
+import (
+	"net"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/skycoin/skycoin/src/daemon/gnet"
+	"github.com/skycoin/skycoin/src/daemon/pex"
+	"github.com/skycoin/skycoin/src/util/iputil"
+)
+
+const (
+	// evictionProtectLatency is the number of incoming peers with the lowest ping latency that are not evicted
+	evictionProtectLatency = 4
+	// evictionProtectUptime is the number of incoming peers connected for the longest time that are not evicted
+	evictionProtectUptime = 8
+)
+
+// evictionCandidate is an incoming connection that can be evicted to free a slot for a new incoming connection
+type evictionCandidate struct {
+	Addr        string
+	Netgroup    string
+	ConnectedAt time.Time
+	// Smoothed ping round trip time, 0 if not measured
+	Latency time.Duration
+	// Ban score of the IP of the peer
+	BanScore int
+}
+
+// selectEviction chooses the incoming connection to evict when the incoming slots are full.
+// The evictionProtectLatency peers with the lowest latency and the evictionProtectUptime peers connected
+// for the longest time are kept, since an attacker can't easily take these slots. Of the others, the peer
+// with the highest ban score is evicted. If none misbehaved, the most recently connected peer of the netgroup
+// with the most peers is evicted, so that an address range can't monopolize the incoming slots.
+// Returns false if all candidates are protected
+func selectEviction(candidates []evictionCandidate) (string, bool) {
+	cs := append([]evictionCandidate(nil), candidates...)
+	sort.Slice(cs, func(i, j int) bool {
+		return cs[i].Addr < cs[j].Addr
+	})
+
+	sort.SliceStable(cs, func(i, j int) bool {
+		a, b := cs[i].Latency, cs[j].Latency
+		if a == 0 || b == 0 {
+			return a != 0
+		}
+		return a < b
+	})
+	n := 0
+	for n < len(cs) && n < evictionProtectLatency && cs[n].Latency != 0 {
+		n++
+	}
+	cs = cs[n:]
+
+	sort.SliceStable(cs, func(i, j int) bool {
+		return cs[i].ConnectedAt.Before(cs[j].ConnectedAt)
+	})
+	if len(cs) <= evictionProtectUptime {
+		return "", false
+	}
+	cs = cs[evictionProtectUptime:]
+
+	// cs is sorted from the oldest to the youngest connection, so the last candidate wins ties
+	worst := -1
+	for i, c := range cs {
+		if c.BanScore > 0 && (worst == -1 || c.BanScore >= cs[worst].BanScore) {
+			worst = i
+		}
+	}
+	if worst != -1 {
+		return cs[worst].Addr, true
+	}
+
+	netgroups := make(map[string][]evictionCandidate)
+	for _, c := range cs {
+		netgroups[c.Netgroup] = append(netgroups[c.Netgroup], c)
+	}
+
+	var largest []evictionCandidate
+	for _, c := range cs {
+		g := netgroups[c.Netgroup]
+		if len(g) > len(largest) || (len(g) == len(largest) && g[len(g)-1].ConnectedAt.After(largest[len(largest)-1].ConnectedAt)) {
+			largest = g
+		}
+	}
+
+	return largest[len(largest)-1].Addr, true
+}
+
+// onGnetEvict chooses the incoming connection to evict for a new incoming connection from addr,
+// when the incoming slots are full. The new connection is rejected if its IP is banned, or if its IP
+// or its netgroup reached the incoming limits.
+// It is called in the gnet pool's strand, so it must not call into the pool
+func (dm *Daemon) onGnetEvict(addr string, candidates []string) (string, bool) {
+	if dm.Config.DisableIncomingEviction {
+		return "", false
+	}
+
+	if dm.pex.IsBanned(addr) || dm.incomingLimitReached(addr) != nil {
+		return "", false
+	}
+
+	now := time.Now().UTC()
+	cs := make([]evictionCandidate, 0, len(candidates))
+	for _, a := range candidates {
+		ip, _, err := iputil.SplitAddr(a)
+		if err != nil || dm.isWhitelistedIP(ip) {
+			continue
+		}
+
+		c := evictionCandidate{
+			Addr:        a,
+			Netgroup:    pex.Netgroup(a),
+			ConnectedAt: now,
+			Latency:     dm.blockFanout.latency(a),
+			BanScore:    dm.peerScores.score(ip, now, dm.Config.BanScoreWindow),
+		}
+
+		// The connection event of a connection accepted just now may not be processed yet
+		if conn := dm.connections.get(a); conn != nil && !conn.ConnectedAt.IsZero() {
+			c.ConnectedAt = conn.ConnectedAt
+		}
+
+		cs = append(cs, c)
+	}
+
+	evictAddr, ok := selectEviction(cs)
+	if !ok {
+		return "", false
+	}
+
+	logger.WithFields(logrus.Fields{
+		"addr":    addr,
+		"evicted": evictAddr,
+	}).Info("Evicting an incoming connection for a new incoming connection")
+
+	return evictAddr, true
+}
+
+// incomingLimitReached returns a disconnect reason if the IP of an incoming connection from addr already
+// has MaxIncomingPerIP other incoming connections, or its netgroup MaxIncomingPerNetgroup.
+// Whitelisted and loopback IPs are not limited, nor are the peers in LocalhostOnly mode
+func (dm *Daemon) incomingLimitReached(addr string) gnet.DisconnectReason {
+	if dm.Config.LocalhostOnly {
+		return nil
+	}
+
+	ip, _, err := iputil.SplitAddr(addr)
+	if err != nil {
+		return ErrDisconnectUnexpectedError
+	}
+
+	if dm.isWhitelistedIP(ip) {
+		return nil
+	}
+	if parsedIP := net.ParseIP(ip); parsedIP != nil && parsedIP.IsLoopback() {
+		return nil
+	}
+
+	netgroup := pex.Netgroup(addr)
+	ipCount := 0
+	netgroupCount := 0
+	for _, c := range dm.connections.all() {
+		if c.Outgoing || c.Addr == addr || dm.isWhitelistedAddr(c.Addr) {
+			continue
+		}
+
+		cIP, _, err := iputil.SplitAddr(c.Addr)
+		if err != nil {
+			continue
+		}
+		if cIP == ip {
+			ipCount++
+		}
+		if pex.Netgroup(c.Addr) == netgroup {
+			netgroupCount++
+		}
+	}
+
+	if dm.Config.MaxIncomingPerIP > 0 && ipCount >= dm.Config.MaxIncomingPerIP {
+		return ErrDisconnectIPLimitReached
+	}
+
+	if dm.Config.MaxIncomingPerNetgroup > 0 && netgroupCount >= dm.Config.MaxIncomingPerNetgroup {
+		return ErrDisconnectNetgroupLimitReached
+	}
+
+	return nil
+}
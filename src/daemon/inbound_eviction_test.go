@@ -0,0 +1,163 @@
+package daemon
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/daemon/pex"
+)
+
+func TestSelectEviction(t *testing.T) {
+	start := time.Now().UTC()
+
+	// The peers connected for the longest time are protected
+	var cs []evictionCandidate
+	for i := 0; i < evictionProtectUptime; i++ {
+		cs = append(cs, evictionCandidate{
+			Addr:        fmt.Sprintf("1.%d.1.1:6000", i),
+			Netgroup:    fmt.Sprintf("1.%d.0.0/16", i),
+			ConnectedAt: start.Add(time.Duration(i) * time.Second),
+		})
+	}
+	_, ok := selectEviction(cs)
+	require.False(t, ok)
+
+	// The peers with the lowest latency are protected
+	for i := 0; i < evictionProtectLatency; i++ {
+		cs = append(cs, evictionCandidate{
+			Addr:        fmt.Sprintf("2.%d.1.1:6000", i),
+			Netgroup:    fmt.Sprintf("2.%d.0.0/16", i),
+			ConnectedAt: start.Add(time.Hour),
+			Latency:     time.Millisecond * time.Duration(10+i),
+		})
+	}
+	_, ok = selectEviction(cs)
+	require.False(t, ok)
+
+	// The youngest peer of the netgroup with the most peers is evicted
+	cs = append(cs, []evictionCandidate{
+		{
+			Addr:        "3.3.1.1:6000",
+			Netgroup:    "3.3.0.0/16",
+			ConnectedAt: start.Add(time.Minute * 2),
+		},
+		{
+			Addr:        "3.3.1.2:6000",
+			Netgroup:    "3.3.0.0/16",
+			ConnectedAt: start.Add(time.Minute * 3),
+		},
+		{
+			Addr:        "3.3.1.3:6000",
+			Netgroup:    "3.3.0.0/16",
+			ConnectedAt: start.Add(time.Minute),
+		},
+		{
+			Addr:        "4.4.1.1:6000",
+			Netgroup:    "4.4.0.0/16",
+			ConnectedAt: start.Add(time.Minute * 4),
+			Latency:     time.Second,
+		},
+	}...)
+	addr, ok := selectEviction(cs)
+	require.True(t, ok)
+	require.Equal(t, "3.3.1.2:6000", addr)
+
+	// Between netgroups with as many peers, the one with the youngest peer is chosen
+	cs = append(cs, evictionCandidate{
+		Addr:        "4.4.1.2:6000",
+		Netgroup:    "4.4.0.0/16",
+		ConnectedAt: start.Add(time.Minute * 5),
+	}, evictionCandidate{
+		Addr:        "4.4.1.3:6000",
+		Netgroup:    "4.4.0.0/16",
+		ConnectedAt: start.Add(time.Minute),
+	})
+	addr, ok = selectEviction(cs)
+	require.True(t, ok)
+	require.Equal(t, "4.4.1.2:6000", addr)
+
+	// The peer with the highest ban score is evicted first, unless it is protected
+	cs[0].BanScore = 50
+	cs = append(cs, evictionCandidate{
+		Addr:        "5.5.1.1:6000",
+		Netgroup:    "5.5.0.0/16",
+		ConnectedAt: start.Add(time.Minute * 6),
+		BanScore:    10,
+	})
+	addr, ok = selectEviction(cs)
+	require.True(t, ok)
+	require.Equal(t, "5.5.1.1:6000", addr)
+
+	cs[len(cs)-2].BanScore = 25
+	addr, ok = selectEviction(cs)
+	require.True(t, ok)
+	require.Equal(t, "4.4.1.3:6000", addr)
+}
+
+func TestDaemonOnGnetEvict(t *testing.T) {
+	dir, err := ioutil.TempDir("", "daemon")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cfg := pex.NewConfig()
+	cfg.DataDirectory = dir
+	px, err := pex.New(cfg)
+	require.NoError(t, err)
+
+	dm := &Daemon{
+		Config:      NewDaemonConfig(),
+		pex:         px,
+		connections: NewConnections(),
+		peerScores:  newPeerScores(),
+	}
+	dm.Config.MaxIncomingPerIP = 1
+	dm.Config.MaxIncomingPerNetgroup = 2
+	dm.Config.Whitelist = []string{"9.9.9.9"}
+
+	var candidates []string
+	for i := 0; i < evictionProtectUptime+1; i++ {
+		addr := fmt.Sprintf("1.%d.1.1:6000", i)
+		_, err := dm.connections.connected(addr, uint64(i+1))
+		require.NoError(t, err)
+		candidates = append(candidates, addr)
+	}
+
+	// The candidates connected for the longest time are protected
+	addr, ok := dm.onGnetEvict("2.2.2.2:6000", candidates)
+	require.True(t, ok)
+	require.Equal(t, candidates[len(candidates)-1], addr)
+
+	// Whitelisted candidates are not evicted
+	_, ok = dm.onGnetEvict("2.2.2.2:6000", append(candidates[:evictionProtectUptime:evictionProtectUptime], "9.9.9.9:6000"))
+	require.False(t, ok)
+
+	// The IP and netgroup limits apply to the new connection
+	require.Nil(t, dm.incomingLimitReached("1.0.1.2:6000"))
+	require.Equal(t, ErrDisconnectIPLimitReached, dm.incomingLimitReached("1.0.1.1:6001"))
+	_, ok = dm.onGnetEvict("1.0.1.1:6001", candidates)
+	require.False(t, ok)
+
+	_, err = dm.connections.connected("1.0.1.2:6000", 100)
+	require.NoError(t, err)
+	require.Equal(t, ErrDisconnectNetgroupLimitReached, dm.incomingLimitReached("1.0.1.3:6000"))
+
+	// Whitelisted and loopback IPs are not limited
+	require.Nil(t, dm.incomingLimitReached("127.0.0.1:6000"))
+	_, err = dm.connections.connected("9.9.9.9:6000", 101)
+	require.NoError(t, err)
+	require.Nil(t, dm.incomingLimitReached("9.9.9.9:6001"))
+
+	// Banned IPs are not accepted
+	require.NoError(t, px.Ban("2.2.2.2", "test", time.Hour))
+	_, ok = dm.onGnetEvict("2.2.2.2:6000", candidates)
+	require.False(t, ok)
+
+	dm.Config.DisableIncomingEviction = true
+	_, ok = dm.onGnetEvict("3.3.3.3:6000", candidates)
+	require.False(t, ok)
+}
@@ -5,7 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
@@ -16,6 +18,7 @@ import (
 	"github.com/skycoin/skycoin/src/daemon/pex"
 	"github.com/skycoin/skycoin/src/util/iputil"
 	"github.com/skycoin/skycoin/src/util/useragent"
+	"github.com/skycoin/skycoin/src/visor/blockdb"
 )
 
 // Message represent a packet to be serialized over the network by
@@ -28,18 +31,38 @@ import (
 // When the message is retrieved from the messageEvent channel, its process()
 // method is called.
 
+// messageMaxLengths are the maximum lengths of the messages that are small by design, message id included.
+// A peer can't send larger ones, the other messages are only limited by the pool's MaxMessageLength
+var messageMaxLengths = map[string]int{
+	"INTR": 1024,
+	"GETP": 64,
+	"GIVP": 64 * 1024,
+	"PING": 64,
+	"PONG": 64,
+	"GETB": 64,
+	"ANNB": 64,
+	"ANNT": 4 + 4 + 256*len(cipher.SHA256{}),
+	"DISC": 256,
+	"GIV6": 64 * 1024,
+	"GHDR": 64,
+	"GBDY": 4 + 4 + 128*len(cipher.SHA256{}),
+}
+
 // MessageConfig config contains a gnet.Message's 4byte prefix and a
 // reference interface
 type MessageConfig struct {
 	Prefix  gnet.MessagePrefix
 	Message interface{}
+	// Maximum length of the message, message id included. 0 for the pool's MaxMessageLength
+	MaxLength int
 }
 
 // NewMessageConfig creates message config
 func NewMessageConfig(prefix string, m interface{}) MessageConfig {
 	return MessageConfig{
-		Message: m,
-		Prefix:  gnet.MessagePrefixFromString(prefix),
+		Message:   m,
+		Prefix:    gnet.MessagePrefixFromString(prefix),
+		MaxLength: messageMaxLengths[prefix],
 	}
 }
 
@@ -58,6 +81,14 @@ func getMessageConfigs() []MessageConfig {
 		NewMessageConfig("GIVT", GiveTxnsMessage{}),
 		NewMessageConfig("ANNT", AnnounceTxnsMessage{}),
 		NewMessageConfig("DISC", DisconnectMessage{}),
+		NewMessageConfig("GIV6", GiveIPv6PeersMessage{}),
+		NewMessageConfig("CMPB", CompactBlockMessage{}),
+		NewMessageConfig("GBTX", GetBlockTxnsMessage{}),
+		NewMessageConfig("BTXN", GiveBlockTxnsMessage{}),
+		NewMessageConfig("GHDR", GetBlockHeadersMessage{}),
+		NewMessageConfig("HDRS", GiveBlockHeadersMessage{}),
+		NewMessageConfig("GBDY", GetBlockBodiesMessage{}),
+		NewMessageConfig("BDYS", GiveBlockBodiesMessage{}),
 	}
 }
 
@@ -78,6 +109,9 @@ func NewMessagesConfig() MessagesConfig {
 func (msc *MessagesConfig) Register() {
 	for _, mc := range msc.Messages {
 		gnet.RegisterMessage(mc.Prefix, mc.Message)
+		if mc.MaxLength != 0 {
+			gnet.RegisterMessageMaxLength(mc.Prefix, mc.MaxLength)
+		}
 	}
 	gnet.VerifyMessages()
 }
@@ -127,6 +161,38 @@ func (ipa IPAddr) String() string {
 	return fmt.Sprintf("%s:%d", net.IP(ipb).String(), ipa.Port)
 }
 
+// IPv6Addr compact representation of [ip]:port for an IPv6 address
+type IPv6Addr struct {
+	IP   [net.IPv6len]byte
+	Port uint16
+}
+
+// NewIPv6Addr returns an IPv6Addr from an [ip]:port string.
+func NewIPv6Addr(addr string) (IPv6Addr, error) {
+	ips, port, err := iputil.SplitAddr(addr)
+	if err != nil {
+		return IPv6Addr{}, err
+	}
+
+	ip := net.ParseIP(ips)
+	if ip == nil {
+		return IPv6Addr{}, errors.New("Invalid IP")
+	}
+	if ip.To4() != nil {
+		return IPv6Addr{}, errors.New("Not an IPv6 address")
+	}
+
+	var ipaddr IPv6Addr
+	copy(ipaddr.IP[:], ip.To16())
+	ipaddr.Port = port
+	return ipaddr, nil
+}
+
+// String returns IPv6Addr as "[ip]:port"
+func (ipa IPv6Addr) String() string {
+	return net.JoinHostPort(net.IP(ipa.IP[:]).String(), strconv.Itoa(int(ipa.Port)))
+}
+
 // asyncMessage messages that perform an action when received must implement this interface.
 // process() is called after the message is pulled off of messageEvent channel.
 // Messages should place themselves on the messageEvent channel in their
@@ -172,6 +238,11 @@ type GivePeersMessage struct {
 func NewGivePeersMessage(peers []pex.Peer) *GivePeersMessage {
 	ipaddrs := make([]IPAddr, 0, len(peers))
 	for _, ps := range peers {
+		// IPv6 peers are sent in a GiveIPv6PeersMessage and .onion peers are not exchanged
+		if pex.AddrIPFamily(ps.Addr) == pex.IPFamilyIPv6 || isOnionAddr(ps.Addr) {
+			continue
+		}
+
 		ipaddr, err := NewIPAddr(ps.Addr)
 		if err != nil {
 			logger.WithError(err).WithField("addr", ps.Addr).Warning("GivePeersMessage skipping invalid address")
@@ -201,12 +272,68 @@ func (gpm *GivePeersMessage) Handle(mc *gnet.MessageContext, daemon interface{})
 
 // process Notifies the Pex instance that peers were received
 func (gpm *GivePeersMessage) process(d daemoner) {
+	processGivenPeers(d, gpm.c, gpm.GetPeers())
+}
+
+// isOnionAddr returns true if the host of an address is a .onion hostname
+func isOnionAddr(addr string) bool {
+	host, _, err := iputil.SplitAddr(addr)
+	return err == nil && pex.IsOnionHost(host)
+}
+
+// GiveIPv6PeersMessage sent in response to GetPeersMessage, with the IPv6 peers.
+// It is only sent to the peers that introduced themselves with a protocol version of at least
+// ipv6PeersProtocolVersion, older peers don't know the message and would disconnect
+type GiveIPv6PeersMessage struct {
+	Peers []IPv6Addr
+	c     *gnet.MessageContext `enc:"-"`
+}
+
+// NewGiveIPv6PeersMessage []*pex.Peer is converted to []IPv6Addr for binary transmission.
+// The peers that don't have an IPv6 address are skipped
+func NewGiveIPv6PeersMessage(peers []pex.Peer) *GiveIPv6PeersMessage {
+	ipaddrs := make([]IPv6Addr, 0, len(peers))
+	for _, ps := range peers {
+		if pex.AddrIPFamily(ps.Addr) != pex.IPFamilyIPv6 {
+			continue
+		}
+
+		ipaddr, err := NewIPv6Addr(ps.Addr)
+		if err != nil {
+			logger.WithError(err).WithField("addr", ps.Addr).Warning("GiveIPv6PeersMessage skipping invalid address")
+			continue
+		}
+		ipaddrs = append(ipaddrs, ipaddr)
+	}
+	return &GiveIPv6PeersMessage{Peers: ipaddrs}
+}
+
+// GetPeers returns the peers contained in the message as an array of "[ip]:port" strings
+func (gpm *GiveIPv6PeersMessage) GetPeers() []string {
+	peers := make([]string, len(gpm.Peers))
+	for i, ipaddr := range gpm.Peers {
+		peers[i] = ipaddr.String()
+	}
+	return peers
+}
+
+// Handle handle message
+func (gpm *GiveIPv6PeersMessage) Handle(mc *gnet.MessageContext, daemon interface{}) error {
+	gpm.c = mc
+	return daemon.(daemoner).recordMessageEvent(gpm, mc)
+}
+
+// process Notifies the Pex instance that peers were received
+func (gpm *GiveIPv6PeersMessage) process(d daemoner) {
+	processGivenPeers(d, gpm.c, gpm.GetPeers())
+}
+
+// processGivenPeers adds the peers received in a GivePeersMessage or GiveIPv6PeersMessage to the pex
+func processGivenPeers(d daemoner, c *gnet.MessageContext, peers []string) {
 	if d.pexConfig().Disabled {
 		return
 	}
 
-	peers := gpm.GetPeers()
-
 	if len(peers) == 0 {
 		return
 	}
@@ -222,13 +349,13 @@ func (gpm *GivePeersMessage) process(d daemoner) {
 	}
 
 	logger.WithFields(logrus.Fields{
-		"addr":   gpm.c.Addr,
-		"gnetID": gpm.c.ConnID,
+		"addr":   c.Addr,
+		"gnetID": c.ConnID,
 		"peers":  peersStr,
 		"count":  len(peers),
 	}).Debug("Received peers via PEX")
 
-	d.addPeers(peers)
+	d.addPeers(c.Addr, peers)
 }
 
 // IntroductionMessage is sent on first connect by both parties
@@ -237,6 +364,9 @@ type IntroductionMessage struct {
 	userAgent                     useragent.Data       `enc:"-"`
 	unconfirmedBurnFactor         uint32               `enc:"-"`
 	unconfirmedMaxTransactionSize uint32               `enc:"-"`
+	features                      PeerFeatures         `enc:"-"`
+	remoteFeatures                PeerFeatures         `enc:"-"`
+	timestamp                     int64                `enc:"-"`
 
 	// Mirror is a random value generated on client startup that is used to identify self-connections
 	Mirror uint32
@@ -248,26 +378,30 @@ type IntroductionMessage struct {
 	// Extra is extra bytes added to the struct to accommodate multiple versions of this packet.
 	// Currently it contains the blockchain pubkey and user agent but will accept a client that does not provide it.
 	// If any of this data is provided, it must include a valid blockchain pubkey and a valid user agent string (maxlen=256).
+	// The features are optional, a peer that does not provide them is assumed to have the features of its protocol version.
 	// Contents of extra:
 	// ExtraByte  uint32 // length prefix of []byte
 	// Pubkey     cipher.Pubkey // blockchain pubkey
 	// BurnFactor uint32 // burn factor for announced txns
 	// MaxTxnSize uint32 // max txn size for announced txns
 	// UserAgent  string `enc:",maxlen=256"`
+	// Features   uint64 // PeerFeatures bits
+	// Timestamp  int64 // unix time of the peer's clock, in seconds
 	Extra []byte `enc:",omitempty"`
 }
 
-// NewIntroductionMessage creates introduction message
-func NewIntroductionMessage(mirror uint32, version int32, port uint16, pubkey cipher.PubKey, userAgent string, unconfirmedBurnFactor, unconfirmedMaxTxnSize uint32) *IntroductionMessage {
+// NewIntroductionMessage creates introduction message. timestamp is the unix time of the local clock,
+// which the peer compares to its own clock
+func NewIntroductionMessage(mirror uint32, version int32, port uint16, pubkey cipher.PubKey, userAgent string, unconfirmedBurnFactor, unconfirmedMaxTxnSize uint32, features PeerFeatures, timestamp int64) *IntroductionMessage {
 	return &IntroductionMessage{
 		Mirror:          mirror,
 		ProtocolVersion: version,
 		ListenPort:      port,
-		Extra:           newIntroductionMessageExtra(pubkey, userAgent, unconfirmedBurnFactor, unconfirmedMaxTxnSize),
+		Extra:           newIntroductionMessageExtra(pubkey, userAgent, unconfirmedBurnFactor, unconfirmedMaxTxnSize, features, timestamp),
 	}
 }
 
-func newIntroductionMessageExtra(pubkey cipher.PubKey, userAgent string, unconfirmedBurnFactor, unconfirmedMaxTxnSize uint32) []byte {
+func newIntroductionMessageExtra(pubkey cipher.PubKey, userAgent string, unconfirmedBurnFactor, unconfirmedMaxTxnSize uint32, features PeerFeatures, timestamp int64) []byte {
 	if len(userAgent) > useragent.MaxLen {
 		logger.WithFields(logrus.Fields{
 			"userAgent": userAgent,
@@ -282,8 +416,10 @@ func newIntroductionMessageExtra(pubkey cipher.PubKey, userAgent string, unconfi
 	userAgentSerialized := encoder.SerializeString(userAgent)
 	burnFactorSerialized := encoder.SerializeAtomic(unconfirmedBurnFactor)
 	maxTxnSizeSerialized := encoder.SerializeAtomic(unconfirmedMaxTxnSize)
+	featuresSerialized := encoder.SerializeAtomic(uint64(features))
+	timestampSerialized := encoder.SerializeAtomic(timestamp)
 
-	extra := make([]byte, len(pubkey)+len(userAgentSerialized)+len(burnFactorSerialized)+len(maxTxnSizeSerialized))
+	extra := make([]byte, len(pubkey)+len(userAgentSerialized)+len(burnFactorSerialized)+len(maxTxnSizeSerialized)+len(featuresSerialized)+len(timestampSerialized))
 
 	copy(extra[:len(pubkey)], pubkey[:])
 	i := len(pubkey)
@@ -292,6 +428,10 @@ func newIntroductionMessageExtra(pubkey cipher.PubKey, userAgent string, unconfi
 	copy(extra[i:i+len(maxTxnSizeSerialized)], maxTxnSizeSerialized)
 	i += len(maxTxnSizeSerialized)
 	copy(extra[i:i+len(userAgentSerialized)], userAgentSerialized)
+	i += len(userAgentSerialized)
+	copy(extra[i:i+len(featuresSerialized)], featuresSerialized)
+	i += len(featuresSerialized)
+	copy(extra[i:i+len(timestampSerialized)], timestampSerialized)
 
 	return extra
 }
@@ -337,6 +477,10 @@ func (intro *IntroductionMessage) process(d daemoner) {
 			return
 		case ErrConnectionIPMirrorExists:
 			reason = ErrDisconnectConnectedTwice
+		case ErrDisconnectTransportFeaturesMismatch, ErrDisconnectTransportKeyMismatch:
+			reason = err
+		case pex.ErrBlacklistedAddress:
+			reason = ErrDisconnectIsBlacklisted
 		case pex.ErrPeerlistFull:
 			reason = ErrDisconnectPeerlistFull
 			// Send more peers before disconnecting
@@ -355,6 +499,11 @@ func (intro *IntroductionMessage) process(d daemoner) {
 		return
 	}
 
+	// The crawler does not synchronize the blockchain nor relay transactions
+	if d.daemonConfig().Crawler {
+		return
+	}
+
 	// Request blocks immediately after they're confirmed
 	if err := d.requestBlocksFromAddr(addr); err != nil {
 		logger.WithError(err).WithFields(fields).Warning("requestBlocksFromAddr")
@@ -399,6 +548,7 @@ func (intro *IntroductionMessage) verify(d daemoner) error {
 	// v25 sends blockchain pubkey and user agent
 	// v24 and v25 check the blockchain pubkey and user agent, would accept message with no Pubkey and user agent
 	// v26 would check the blockchain pubkey and reject if not matched or not provided, and parses a user agent
+	remoteFeatures := protocolVersionFeatures(intro.ProtocolVersion)
 	if len(intro.Extra) > 0 {
 		var bcPubKey cipher.PubKey
 		if len(intro.Extra) < len(bcPubKey) {
@@ -441,7 +591,7 @@ func (intro *IntroductionMessage) verify(d daemoner) error {
 		}
 
 		userAgentSerialized := intro.Extra[len(bcPubKey)+8:]
-		userAgent, _, err := encoder.DeserializeString(userAgentSerialized, useragent.MaxLen)
+		userAgent, n, err := encoder.DeserializeString(userAgentSerialized, useragent.MaxLen)
 		if err != nil {
 			logger.WithError(err).WithFields(fields).Warning("Extra data user agent string could not be deserialized")
 			return ErrDisconnectInvalidExtraData
@@ -452,8 +602,33 @@ func (intro *IntroductionMessage) verify(d daemoner) error {
 			logger.WithError(err).WithFields(fields).WithField("userAgent", userAgent).Warning("User agent is invalid")
 			return ErrDisconnectInvalidUserAgent
 		}
+
+		// The features were added after the user agent, older peers don't send them
+		featuresSerialized := userAgentSerialized[n:]
+		if len(featuresSerialized) >= 8 {
+			var features uint64
+			if _, err := encoder.DeserializeAtomic(featuresSerialized[:8], &features); err != nil {
+				// This should not occur due to the previous length check
+				logger.Critical().WithError(err).WithFields(fields).Warning("Features could not be deserialized")
+				return ErrDisconnectInvalidExtraData
+			}
+			remoteFeatures = PeerFeatures(features)
+
+			// The timestamp was added after the features, older peers don't send it
+			timestampSerialized := featuresSerialized[8:]
+			if len(timestampSerialized) >= 8 {
+				if _, err := encoder.DeserializeAtomic(timestampSerialized[:8], &intro.timestamp); err != nil {
+					// This should not occur due to the previous length check
+					logger.Critical().WithError(err).WithFields(fields).Warning("Timestamp could not be deserialized")
+					return ErrDisconnectInvalidExtraData
+				}
+			}
+		}
 	}
 
+	intro.remoteFeatures = remoteFeatures
+	intro.features = negotiateFeatures(dc.features(), remoteFeatures)
+
 	return nil
 }
 
@@ -483,15 +658,17 @@ func (ping *PingMessage) process(d daemoner) {
 	}
 }
 
-// PongMessage Sent in reply to a PingMessage.  No action is taken when this is received.
+// PongMessage Sent in reply to a PingMessage. It measures the latency of the peer for the block fan-out.
 type PongMessage struct {
 }
 
 // Handle handles message
 func (pong *PongMessage) Handle(mc *gnet.MessageContext, daemon interface{}) error {
-	// There is nothing to do; gnet updates Connection.LastMessage internally
-	// when this is received
-	if daemon.(*Daemon).Config.LogPings {
+	// gnet updates Connection.LastMessage internally when this is received
+	d := daemon.(*Daemon)
+	d.blockFanout.pong(mc.Addr, d.now())
+
+	if d.Config.LogPings {
 		logger.WithFields(logrus.Fields{
 			"addr":   mc.Addr,
 			"gnetID": mc.ConnID,
@@ -578,7 +755,13 @@ func (gbm *GetBlocksMessage) process(d daemoner) {
 	// Fetch and return signed blocks since LastBlock
 	blocks, err := d.getSignedBlocksSince(gbm.LastBlock, gbm.RequestedBlocks)
 	if err != nil {
-		logger.WithError(err).Error("Get signed blocks failed")
+		switch err.(type) {
+		case blockdb.ErrBlockPruned:
+			// This node is pruned and does not have the blocks, the peer has to get them from another peer
+			logger.WithFields(fields).WithError(err).Debug("Peer requested pruned blocks")
+		default:
+			logger.WithError(err).Error("Get signed blocks failed")
+		}
 		return
 	}
 
@@ -620,10 +803,19 @@ func (m *GiveBlocksMessage) process(d daemoner) {
 		return
 	}
 
+	if len(m.Blocks) != 0 {
+		d.recordBlocksReceived(m.c.ConnID)
+	}
+
 	// These DB queries are not performed in a transaction for performance reasons.
-	// It is not necessary that the blocks be executed together in a single transaction.
+	// The blocks are executed in batches of up to visor.Config.BlockApplyBatchSize blocks per transaction,
+	// so a batch can't be larger than the number of blocks of the message.
+
+	// The blocks may be copies of corrupted blocks of the database requested from peers
+	if _, err := d.repairBlocks(m.Blocks); err != nil {
+		logger.WithError(err).Error("d.repairBlocks failed")
+	}
 
-	processed := 0
 	maxSeq, ok, err := d.headBkSeq()
 	if err != nil {
 		logger.WithError(err).Error("d.headBkSeq failed")
@@ -634,9 +826,10 @@ func (m *GiveBlocksMessage) process(d daemoner) {
 		return
 	}
 
+	var blocks []coin.SignedBlock
 	for _, b := range m.Blocks {
 		// To minimize waste when receiving multiple responses from peers
-		// we only break out of the loop if the block itself is invalid.
+		// we only stop at a block if the block itself is invalid.
 		// E.g. if we request 20 blocks since 0 from 2 peers, and one peer
 		// replies with 15 and the other 20, if we did not do this check and
 		// the reply with 15 was received first, we would toss the one with 20
@@ -645,15 +838,25 @@ func (m *GiveBlocksMessage) process(d daemoner) {
 			continue
 		}
 
-		err := d.executeSignedBlock(b)
-		if err == nil {
+		blocks = append(blocks, b)
+	}
+
+	processed := 0
+	if len(blocks) > 0 {
+		// Blocks must be received in order, so if one fails its assumed
+		// the rest are failing
+		var err error
+		processed, err = d.executeSignedBlocks(blocks)
+		for _, b := range blocks[:processed] {
 			logger.Critical().WithField("seq", b.Block.Head.BkSeq).Info("Added new block")
-			processed++
-		} else {
-			logger.Critical().WithError(err).WithField("seq", b.Block.Head.BkSeq).Error("Failed to execute received block")
-			// Blocks must be received in order, so if one fails its assumed
-			// the rest are failing
-			break
+		}
+		if err != nil {
+			logger.Critical().WithError(err).WithField("seq", blocks[processed].Block.Head.BkSeq).Error("Failed to execute received block")
+
+			// A block with an invalid signature can't be a block of the blockchain
+			if err := blocks[processed].VerifySignature(d.daemonConfig().BlockchainPubkey); err != nil {
+				d.recordPeerMisbehavior(m.c.Addr, PeerMisbehaviorInvalidMessage)
+			}
 		}
 	}
 	if processed == 0 {
@@ -720,6 +923,9 @@ func (abm *AnnounceBlocksMessage) process(d daemoner) {
 		"gnetID": abm.c.ConnID,
 	}
 
+	// Record this as this peer's highest block
+	d.recordPeerHeight(abm.c.Addr, abm.c.ConnID, abm.MaxBkSeq)
+
 	headBkSeq, ok, err := d.headBkSeq()
 	if err != nil {
 		logger.WithError(err).Error("AnnounceBlocksMessage d.headBkSeq failed")
@@ -736,9 +942,8 @@ func (abm *AnnounceBlocksMessage) process(d daemoner) {
 
 	// TODO: Should this be block get request for current sequence?
 	// If client is not caught up, won't attempt to get block
-	m := NewGetBlocksMessage(headBkSeq, d.daemonConfig().BlocksResponseCount)
-	if err := d.sendMessage(abm.c.Addr, m); err != nil {
-		logger.WithError(err).WithFields(fields).Error("Send GetBlocksMessage")
+	if err := d.requestBlocksFromAddr(abm.c.Addr); err != nil {
+		logger.WithError(err).WithFields(fields).Error("requestBlocksFromAddr failed")
 	}
 }
 
@@ -773,7 +978,8 @@ func (atm *AnnounceTxnsMessage) Handle(mc *gnet.MessageContext, daemon interface
 
 // process process message
 func (atm *AnnounceTxnsMessage) process(d daemoner) {
-	if d.daemonConfig().DisableNetworking {
+	dc := d.daemonConfig()
+	if dc.DisableNetworking || dc.DisableTxnRelay {
 		return
 	}
 
@@ -788,6 +994,9 @@ func (atm *AnnounceTxnsMessage) process(d daemoner) {
 		return
 	}
 
+	// The transactions already requested from another peer are requested from this peer
+	// only if the other peer does not send them in time
+	unknown = d.scheduleTxnRequests(atm.c.Addr, unknown)
 	if len(unknown) == 0 {
 		return
 	}
@@ -875,9 +1084,19 @@ func (gtm *GiveTxnsMessage) process(d daemoner) {
 		return
 	}
 
+	received := make([]cipher.SHA256, len(gtm.Transactions))
+	for i, txn := range gtm.Transactions {
+		received[i] = txn.Hash()
+	}
+	d.txnsReceived(gtm.c.Addr, received)
+
 	hashes := make([]cipher.SHA256, 0, len(gtm.Transactions))
 	// Update unconfirmed pool with these transactions
 	for _, txn := range gtm.Transactions {
+		if d.checkRelayPolicy(txn) {
+			continue
+		}
+
 		// Only announce transactions that are new to us, so that peers can't spam relays
 		// It is not necessary to inject all of the transactions inside a database transaction,
 		// since each is independent
@@ -896,7 +1115,7 @@ func (gtm *GiveTxnsMessage) process(d daemoner) {
 		hashes = append(hashes, txn.Hash())
 	}
 
-	if len(hashes) == 0 {
+	if len(hashes) == 0 || d.daemonConfig().DisableTxnRelay {
 		return
 	}
 
@@ -908,3 +1127,433 @@ func (gtm *GiveTxnsMessage) process(d daemoner) {
 		logger.Debugf("Announced %d transactions to %d peers", len(hashes), n)
 	}
 }
+
+// CompactBlockMessage announces a new block by its header and the short IDs of its transactions.
+// The receiving peer reconstructs the block from its unconfirmed transactions and requests the missing
+// transactions with GetBlockTxnsMessage. It is only sent to peers of compactBlocksProtocolVersion or later
+type CompactBlockMessage struct {
+	Head     coin.BlockHeader
+	Sig      cipher.Sig
+	ShortIDs []ShortTxnID         `enc:",maxlen=65535"`
+	c        *gnet.MessageContext `enc:"-"`
+}
+
+// NewCompactBlockMessage creates a CompactBlockMessage of a block
+func NewCompactBlockMessage(sb coin.SignedBlock) *CompactBlockMessage {
+	blockHash := sb.HashHeader()
+	shortIDs := make([]ShortTxnID, len(sb.Body.Transactions))
+	for i, txn := range sb.Body.Transactions {
+		shortIDs[i] = NewShortTxnID(blockHash, txn.Hash())
+	}
+
+	return &CompactBlockMessage{
+		Head:     sb.Head,
+		Sig:      sb.Sig,
+		ShortIDs: shortIDs,
+	}
+}
+
+// Handle handles message
+func (m *CompactBlockMessage) Handle(mc *gnet.MessageContext, daemon interface{}) error {
+	m.c = mc
+	return daemon.(daemoner).recordMessageEvent(m, mc)
+}
+
+// process reconstructs the block and executes it, or requests its missing transactions
+func (m *CompactBlockMessage) process(d daemoner) {
+	if d.daemonConfig().DisableNetworking {
+		return
+	}
+
+	fields := logrus.Fields{
+		"addr":   m.c.Addr,
+		"gnetID": m.c.ConnID,
+		"seq":    m.Head.BkSeq,
+	}
+
+	d.recordPeerHeight(m.c.Addr, m.c.ConnID, m.Head.BkSeq)
+
+	headSeq, ok, err := d.headBkSeq()
+	if err != nil {
+		logger.WithError(err).Error("d.headBkSeq failed")
+		return
+	}
+	if !ok {
+		logger.Error("No HeadBkSeq found, cannot execute compact block")
+		return
+	}
+
+	if m.Head.BkSeq <= headSeq {
+		return
+	}
+
+	// The blocks before this one are missing, or compact blocks are disabled, request the full blocks instead
+	if m.Head.BkSeq != headSeq+1 || d.daemonConfig().DisableCompactBlocks {
+		if err := d.requestBlocksFromAddr(m.c.Addr); err != nil {
+			logger.WithFields(fields).WithError(err).Warning("requestBlocksFromAddr failed")
+		}
+		return
+	}
+
+	txns, err := d.getAllUnconfirmedTxns()
+	if err != nil {
+		logger.WithError(err).Error("d.getAllUnconfirmedTxns failed")
+		return
+	}
+
+	pb := newPartialBlock(m, txns, m.c.Addr, time.Now())
+	if !pb.complete() {
+		logger.WithFields(fields).Debugf("Compact block is missing %d of %d transactions", len(pb.missing), len(m.ShortIDs))
+		d.addPartialBlock(pb)
+
+		if err := d.sendMessage(m.c.Addr, NewGetBlockTxnsMessage(pb.hash(), pb.missing)); err != nil {
+			logger.WithFields(fields).WithError(err).Error("Send GetBlockTxnsMessage failed")
+		}
+		return
+	}
+
+	executeCompactBlock(d, m.c, pb)
+}
+
+// executeCompactBlock executes a reconstructed compact block and relays it to peers.
+// If the block can't be reconstructed, the full block is requested instead
+func executeCompactBlock(d daemoner, c *gnet.MessageContext, pb *partialBlock) {
+	fields := logrus.Fields{
+		"addr":   c.Addr,
+		"gnetID": c.ConnID,
+		"seq":    pb.block.Head.BkSeq,
+	}
+
+	sb, err := pb.signedBlock()
+	if err != nil {
+		logger.WithFields(fields).WithError(err).Info("Failed to reconstruct compact block, requesting the full block")
+		if err := d.requestBlocksFromAddr(c.Addr); err != nil {
+			logger.WithFields(fields).WithError(err).Warning("requestBlocksFromAddr failed")
+		}
+		return
+	}
+
+	d.recordBlocksReceived(c.ConnID)
+
+	if _, err := d.executeSignedBlocks([]coin.SignedBlock{sb}); err != nil {
+		logger.Critical().WithError(err).WithFields(fields).Error("Failed to execute received compact block")
+
+		// A block with an invalid signature can't be a block of the blockchain
+		if err := sb.VerifySignature(d.daemonConfig().BlockchainPubkey); err != nil {
+			d.recordPeerMisbehavior(c.Addr, PeerMisbehaviorInvalidMessage)
+		}
+		return
+	}
+
+	logger.Critical().WithField("seq", sb.Head.BkSeq).Info("Added new block")
+
+	if err := d.relayBlock(sb, c.Addr); err != nil {
+		logger.WithError(err).Warning("relayBlock failed")
+	}
+}
+
+// GetBlockTxnsMessage requests the transactions of a block by their index, in response to a CompactBlockMessage
+type GetBlockTxnsMessage struct {
+	BlockHash cipher.SHA256
+	Indexes   []uint16             `enc:",maxlen=65535"`
+	c         *gnet.MessageContext `enc:"-"`
+}
+
+// NewGetBlockTxnsMessage creates GetBlockTxnsMessage
+func NewGetBlockTxnsMessage(blockHash cipher.SHA256, indexes []uint16) *GetBlockTxnsMessage {
+	return &GetBlockTxnsMessage{
+		BlockHash: blockHash,
+		Indexes:   indexes,
+	}
+}
+
+// Handle handles message
+func (m *GetBlockTxnsMessage) Handle(mc *gnet.MessageContext, daemon interface{}) error {
+	m.c = mc
+	return daemon.(daemoner).recordMessageEvent(m, mc)
+}
+
+// process sends the requested transactions of the block
+func (m *GetBlockTxnsMessage) process(d daemoner) {
+	if d.daemonConfig().DisableNetworking {
+		return
+	}
+
+	fields := logrus.Fields{
+		"addr":      m.c.Addr,
+		"gnetID":    m.c.ConnID,
+		"blockHash": m.BlockHash.Hex(),
+	}
+
+	sb, err := d.getSignedBlockByHash(m.BlockHash)
+	if err != nil {
+		logger.WithFields(fields).WithError(err).Error("d.getSignedBlockByHash failed")
+		return
+	}
+	if sb == nil {
+		logger.WithFields(fields).Debug("Peer requested transactions of an unknown block")
+		return
+	}
+
+	txns := make(coin.Transactions, len(m.Indexes))
+	for i, idx := range m.Indexes {
+		if int(idx) >= len(sb.Body.Transactions) {
+			logger.WithFields(fields).WithField("index", idx).Info("Peer requested a transaction index out of range")
+			d.recordPeerMisbehavior(m.c.Addr, PeerMisbehaviorInvalidMessage)
+			return
+		}
+		txns[i] = sb.Body.Transactions[idx]
+	}
+
+	if err := d.sendMessage(m.c.Addr, NewGiveBlockTxnsMessage(m.BlockHash, txns)); err != nil {
+		logger.WithFields(fields).WithError(err).Error("Send GiveBlockTxnsMessage failed")
+	}
+}
+
+// GiveBlockTxnsMessage sends the transactions of a block requested by GetBlockTxnsMessage, in the order of the indexes
+type GiveBlockTxnsMessage struct {
+	BlockHash    cipher.SHA256
+	Transactions coin.Transactions    `enc:",maxlen=65535"`
+	c            *gnet.MessageContext `enc:"-"`
+}
+
+// NewGiveBlockTxnsMessage creates GiveBlockTxnsMessage
+func NewGiveBlockTxnsMessage(blockHash cipher.SHA256, txns coin.Transactions) *GiveBlockTxnsMessage {
+	return &GiveBlockTxnsMessage{
+		BlockHash:    blockHash,
+		Transactions: txns,
+	}
+}
+
+// Handle handles message
+func (m *GiveBlockTxnsMessage) Handle(mc *gnet.MessageContext, daemon interface{}) error {
+	m.c = mc
+	return daemon.(daemoner).recordMessageEvent(m, mc)
+}
+
+// process completes the compact block waiting for the transactions and executes it
+func (m *GiveBlockTxnsMessage) process(d daemoner) {
+	if d.daemonConfig().DisableNetworking {
+		return
+	}
+
+	fields := logrus.Fields{
+		"addr":      m.c.Addr,
+		"gnetID":    m.c.ConnID,
+		"blockHash": m.BlockHash.Hex(),
+	}
+
+	// The compact block may have expired, or was not requested from this peer
+	pb := d.removePartialBlock(m.c.Addr, m.BlockHash)
+	if pb == nil {
+		logger.WithFields(fields).Debug("No compact block waiting for these transactions")
+		return
+	}
+
+	if err := pb.fill(m.Transactions); err != nil {
+		logger.WithFields(fields).WithError(err).Info("Peer sent invalid compact block transactions")
+		d.recordPeerMisbehavior(m.c.Addr, PeerMisbehaviorInvalidMessage)
+		return
+	}
+
+	executeCompactBlock(d, m.c, pb)
+}
+
+// GetBlockHeadersMessage requests the signed headers of the blocks after LastBlock, for the header-first synchronization.
+// It is only sent to peers that negotiated FeatureHeadersFirst
+type GetBlockHeadersMessage struct {
+	LastBlock        uint64
+	RequestedHeaders uint64
+	c                *gnet.MessageContext `enc:"-"`
+}
+
+// NewGetBlockHeadersMessage creates GetBlockHeadersMessage
+func NewGetBlockHeadersMessage(lastBlock, requestedHeaders uint64) *GetBlockHeadersMessage {
+	return &GetBlockHeadersMessage{
+		LastBlock:        lastBlock,
+		RequestedHeaders: requestedHeaders,
+	}
+}
+
+// Handle handles message
+func (m *GetBlockHeadersMessage) Handle(mc *gnet.MessageContext, daemon interface{}) error {
+	m.c = mc
+	return daemon.(daemoner).recordMessageEvent(m, mc)
+}
+
+// process sends the requested block headers. The headers of pruned blocks are sent too
+func (m *GetBlockHeadersMessage) process(d daemoner) {
+	if d.daemonConfig().DisableNetworking {
+		return
+	}
+
+	fields := logrus.Fields{
+		"addr":   m.c.Addr,
+		"gnetID": m.c.ConnID,
+	}
+
+	// Unlike GetBlocksMessage, LastBlock is not the peer's highest block, since the headers
+	// are requested in ranges above it
+	count := m.RequestedHeaders
+	if count > maxSyncHeaders {
+		count = maxSyncHeaders
+	}
+
+	headers, err := d.getSignedBlockHeadersSince(m.LastBlock, count)
+	if err != nil {
+		logger.WithError(err).Error("Get signed block headers failed")
+		return
+	}
+
+	// The headers are sent even if there are none, so that the peer doesn't wait for them
+	if err := d.sendMessage(m.c.Addr, NewGiveBlockHeadersMessage(headers)); err != nil {
+		logger.WithFields(fields).WithError(err).Error("Send GiveBlockHeadersMessage failed")
+	}
+}
+
+// GiveBlockHeadersMessage sends the signed block headers requested by GetBlockHeadersMessage
+type GiveBlockHeadersMessage struct {
+	Headers []coin.SignedBlockHeader `enc:",maxlen=512"`
+	c       *gnet.MessageContext     `enc:"-"`
+}
+
+// NewGiveBlockHeadersMessage creates GiveBlockHeadersMessage
+func NewGiveBlockHeadersMessage(headers []coin.SignedBlockHeader) *GiveBlockHeadersMessage {
+	return &GiveBlockHeadersMessage{
+		Headers: headers,
+	}
+}
+
+// Handle handles message
+func (m *GiveBlockHeadersMessage) Handle(mc *gnet.MessageContext, daemon interface{}) error {
+	m.c = mc
+	return daemon.(daemoner).recordMessageEvent(m, mc)
+}
+
+// process adds the headers to the header-first synchronization and requests the next headers and bodies
+func (m *GiveBlockHeadersMessage) process(d daemoner) {
+	if d.daemonConfig().DisableNetworking {
+		return
+	}
+
+	fields := logrus.Fields{
+		"addr":   m.c.Addr,
+		"gnetID": m.c.ConnID,
+	}
+
+	if err := d.addSyncHeaders(m.c.Addr, m.Headers); err != nil {
+		logger.WithFields(fields).WithError(err).Info("Peer sent invalid block headers")
+		d.recordPeerMisbehavior(m.c.Addr, PeerMisbehaviorInvalidMessage)
+	}
+
+	if err := d.syncHeadersFirst(); err != nil {
+		logger.WithError(err).Warning("syncHeadersFirst failed")
+	}
+}
+
+// GetBlockBodiesMessage requests the bodies of blocks by their hash, for the header-first synchronization.
+// It is only sent to peers that negotiated FeatureHeadersFirst
+type GetBlockBodiesMessage struct {
+	Hashes []cipher.SHA256      `enc:",maxlen=128"`
+	c      *gnet.MessageContext `enc:"-"`
+}
+
+// NewGetBlockBodiesMessage creates GetBlockBodiesMessage
+func NewGetBlockBodiesMessage(hashes []cipher.SHA256) *GetBlockBodiesMessage {
+	return &GetBlockBodiesMessage{
+		Hashes: hashes,
+	}
+}
+
+// Handle handles message
+func (m *GetBlockBodiesMessage) Handle(mc *gnet.MessageContext, daemon interface{}) error {
+	m.c = mc
+	return daemon.(daemoner).recordMessageEvent(m, mc)
+}
+
+// process sends the bodies of the requested blocks that are known and not pruned, in the requested order.
+// The bodies that would make the response larger than maxBlockBodiesResponseSize are left out
+func (m *GetBlockBodiesMessage) process(d daemoner) {
+	if d.daemonConfig().DisableNetworking {
+		return
+	}
+
+	fields := logrus.Fields{
+		"addr":   m.c.Addr,
+		"gnetID": m.c.ConnID,
+	}
+
+	var bodies []HashedBlockBody
+	var size uint32
+	for _, h := range m.Hashes {
+		sb, err := d.getSignedBlockByHash(h)
+		if err != nil {
+			logger.WithFields(fields).WithError(err).WithField("blockHash", h.Hex()).Debug("d.getSignedBlockByHash failed")
+			continue
+		}
+		if sb == nil {
+			continue
+		}
+
+		bodySize, err := sb.Body.Size()
+		if err != nil {
+			logger.WithFields(fields).WithError(err).Error("Block body size could not be computed")
+			continue
+		}
+		if size+bodySize > maxBlockBodiesResponseSize && len(bodies) != 0 {
+			break
+		}
+		size += bodySize
+
+		bodies = append(bodies, HashedBlockBody{
+			BlockHash: h,
+			Body:      sb.Body,
+		})
+	}
+
+	// The bodies are sent even if there are none, so that the peer can request them from other peers
+	if err := d.sendMessage(m.c.Addr, NewGiveBlockBodiesMessage(bodies)); err != nil {
+		logger.WithFields(fields).WithError(err).Error("Send GiveBlockBodiesMessage failed")
+	}
+}
+
+// GiveBlockBodiesMessage sends the block bodies requested by GetBlockBodiesMessage
+type GiveBlockBodiesMessage struct {
+	Bodies []HashedBlockBody    `enc:",maxlen=128"`
+	c      *gnet.MessageContext `enc:"-"`
+}
+
+// NewGiveBlockBodiesMessage creates GiveBlockBodiesMessage
+func NewGiveBlockBodiesMessage(bodies []HashedBlockBody) *GiveBlockBodiesMessage {
+	return &GiveBlockBodiesMessage{
+		Bodies: bodies,
+	}
+}
+
+// Handle handles message
+func (m *GiveBlockBodiesMessage) Handle(mc *gnet.MessageContext, daemon interface{}) error {
+	m.c = mc
+	return daemon.(daemoner).recordMessageEvent(m, mc)
+}
+
+// process adds the bodies to the header-first synchronization, executes the blocks that are complete
+// and requests the next headers and bodies
+func (m *GiveBlockBodiesMessage) process(d daemoner) {
+	if d.daemonConfig().DisableNetworking {
+		return
+	}
+
+	fields := logrus.Fields{
+		"addr":   m.c.Addr,
+		"gnetID": m.c.ConnID,
+	}
+
+	if err := d.addSyncBodies(m.c.Addr, m.Bodies); err != nil {
+		logger.WithFields(fields).WithError(err).Info("Peer sent invalid block bodies")
+		d.recordPeerMisbehavior(m.c.Addr, PeerMisbehaviorInvalidMessage)
+	}
+
+	if err := d.syncHeadersFirst(); err != nil {
+		logger.WithError(err).Warning("syncHeadersFirst failed")
+	}
+}
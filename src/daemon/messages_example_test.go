@@ -419,7 +419,7 @@ func ExampleIntroductionMessage() {
 
 	pk := cipher.MustPubKeyFromHex("0328c576d3f420e7682058a981173a4b374c7cc5ff55bf394d3cf57059bbe6456a")
 
-	var message = NewIntroductionMessage(1234, 5, 7890, pk, "skycoin:0.24.1", 2, 32768)
+	var message = NewIntroductionMessage(1234, 5, 7890, pk, "skycoin:0.24.1", 2, 32768, FeatureCompactBlocks, 1523168686)
 	fmt.Println("IntroductionMessage:")
 	var mai = NewMessagesAnnotationsIterator(message)
 	w := bufio.NewWriter(os.Stdout)
@@ -429,12 +429,12 @@ func ExampleIntroductionMessage() {
 	}
 	// Output:
 	// IntroductionMessage:
-	// 0x0000 | 4d 00 00 00 ....................................... Length
+	// 0x0000 | 5d 00 00 00 ....................................... Length
 	// 0x0004 | 49 4e 54 52 ....................................... Prefix
 	// 0x0008 | d2 04 00 00 ....................................... Mirror
 	// 0x000c | d2 1e ............................................. ListenPort
 	// 0x000e | 05 00 00 00 ....................................... ProtocolVersion
-	// 0x0012 | 3b 00 00 00 ....................................... Extra length
+	// 0x0012 | 4b 00 00 00 ....................................... Extra length
 	// 0x0016 | 03 ................................................ Extra[0]
 	// 0x0017 | 28 ................................................ Extra[1]
 	// 0x0018 | c5 ................................................ Extra[2]
@@ -494,7 +494,23 @@ func ExampleIntroductionMessage() {
 	// 0x004e | 34 ................................................ Extra[56]
 	// 0x004f | 2e ................................................ Extra[57]
 	// 0x0050 | 31 ................................................ Extra[58]
-	// 0x0051 |
+	// 0x0051 | 01 ................................................ Extra[59]
+	// 0x0052 | 00 ................................................ Extra[60]
+	// 0x0053 | 00 ................................................ Extra[61]
+	// 0x0054 | 00 ................................................ Extra[62]
+	// 0x0055 | 00 ................................................ Extra[63]
+	// 0x0056 | 00 ................................................ Extra[64]
+	// 0x0057 | 00 ................................................ Extra[65]
+	// 0x0058 | 00 ................................................ Extra[66]
+	// 0x0059 | ae ................................................ Extra[67]
+	// 0x005a | b5 ................................................ Extra[68]
+	// 0x005b | c9 ................................................ Extra[69]
+	// 0x005c | 5a ................................................ Extra[70]
+	// 0x005d | 00 ................................................ Extra[71]
+	// 0x005e | 00 ................................................ Extra[72]
+	// 0x005f | 00 ................................................ Extra[73]
+	// 0x0060 | 00 ................................................ Extra[74]
+	// 0x0061 |
 }
 
 func ExampleGetPeersMessage() {
@@ -542,6 +558,33 @@ func ExampleGivePeersMessage() {
 	// 0x001e |
 }
 
+func ExampleGiveIPv6PeersMessage() {
+	defer gnet.EraseMessages()
+	setupMsgEncoding()
+	var peers = []pex.Peer{
+		*pex.NewPeer("[2001:db8::1]:6000"),
+		*pex.NewPeer("[2606:4700::1111]:6000"),
+	}
+	var message = NewGiveIPv6PeersMessage(peers)
+	fmt.Println("GiveIPv6PeersMessage:")
+	var mai = NewMessagesAnnotationsIterator(message)
+	w := bufio.NewWriter(os.Stdout)
+	err := NewFromIterator(gnet.EncodeMessage(message), &mai, w)
+	if err != nil {
+		fmt.Println(err)
+	}
+	// Output:
+	// GiveIPv6PeersMessage:
+	// 0x0000 | 2c 00 00 00 ....................................... Length
+	// 0x0004 | 47 49 56 36 ....................................... Prefix
+	// 0x0008 | 02 00 00 00 ....................................... Peers length
+	// 0x000c | 20 01 0d b8 00 00 00 00 00 00 00 00 00 00 00 01
+	// 0x001c | 70 17 ............................................. Peers[0]
+	// 0x001e | 26 06 47 00 00 00 00 00 00 00 00 00 00 00 11 11
+	// 0x002e | 70 17 ............................................. Peers[1]
+	// 0x0030 |
+}
+
 func ExampleGetBlocksMessage() {
 	defer gnet.EraseMessages()
 	setupMsgEncoding()
@@ -845,3 +888,248 @@ func ExampleDisconnectMessage() {
 	// 0x0035 |
 
 }
+
+func ExampleCompactBlockMessage() {
+	defer gnet.EraseMessages()
+	setupMsgEncoding()
+	var body = coin.BlockBody{
+		Transactions: make([]coin.Transaction, 0),
+	}
+	var sig, _ = cipher.SigFromHex(sig1hex)
+	var message = &CompactBlockMessage{
+		Head: coin.BlockHeader{
+			Version:  0x02,
+			Time:     100,
+			BkSeq:    7,
+			Fee:      10,
+			PrevHash: hashes[0],
+			BodyHash: body.Hash(),
+		},
+		Sig: sig,
+		ShortIDs: []ShortTxnID{
+			{0x01, 0x02, 0x03, 0x04, 0x05, 0x06},
+			{0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff},
+		},
+	}
+	fmt.Println("CompactBlockMessage:")
+	var mai = NewMessagesAnnotationsIterator(message)
+	w := bufio.NewWriter(os.Stdout)
+	err := NewFromIterator(gnet.EncodeMessage(message), &mai, w)
+	if err != nil {
+		fmt.Println(err)
+	}
+	// Output:
+	// CompactBlockMessage:
+	// 0x0000 | d1 00 00 00 ....................................... Length
+	// 0x0004 | 43 4d 50 42 ....................................... Prefix
+	// 0x0008 | 02 00 00 00 64 00 00 00 00 00 00 00 07 00 00 00
+	// 0x0018 | 00 00 00 00 0a 00 00 00 00 00 00 00 40 af f2 e9
+	// 0x0028 | d2 d8 92 2e 47 af d4 64 8e 69 67 49 71 58 78 5f
+	// 0x0038 | bd 1d a8 70 e7 11 02 66 bf 94 48 80 00 00 00 00
+	// 0x0048 | 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00
+	// 0x0058 | 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00
+	// 0x0068 | 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00
+	// 0x0078 | 00 00 00 00 00 00 00 00 00 00 00 00 ............... Head
+	// 0x0084 | 03 21 3f dd 6d df 86 0e 40 53 e1 a9 7e 42 76 d6
+	// 0x0094 | 34 54 f5 19 5a 83 21 35 70 04 d5 2c db bf d3 88
+	// 0x00a4 | 6f c7 ad 3f 3f 63 b6 5d 4a 87 9c e3 08 6d ae b3
+	// 0x00b4 | e5 4a 93 d3 c2 f9 6a 50 61 f9 bc 49 36 83 ca 8e
+	// 0x00c4 | 01 ................................................ Sig
+	// 0x00c5 | 02 00 00 00 ....................................... ShortIDs length
+	// 0x00c9 | 01 02 03 04 05 06 ................................. ShortIDs[0]
+	// 0x00cf | aa bb cc dd ee ff ................................. ShortIDs[1]
+	// 0x00d5 |
+}
+
+func ExampleGetBlockTxnsMessage() {
+	defer gnet.EraseMessages()
+	setupMsgEncoding()
+	var message = NewGetBlockTxnsMessage(hashes[1], []uint16{0, 3, 258})
+	fmt.Println("GetBlockTxnsMessage:")
+	var mai = NewMessagesAnnotationsIterator(message)
+	w := bufio.NewWriter(os.Stdout)
+	err := NewFromIterator(gnet.EncodeMessage(message), &mai, w)
+	if err != nil {
+		fmt.Println(err)
+	}
+	// Output:
+	// GetBlockTxnsMessage:
+	// 0x0000 | 2e 00 00 00 ....................................... Length
+	// 0x0004 | 47 42 54 58 ....................................... Prefix
+	// 0x0008 | 7b b4 62 c3 bd 37 1d d8 1c 06 ad 1d 2b 63 59 71
+	// 0x0018 | cb 56 eb 22 23 3d fc 9f eb e8 3e 44 c8 40 b8 d7 ... BlockHash
+	// 0x0028 | 03 00 00 00 ....................................... Indexes length
+	// 0x002c | 00 00 ............................................. Indexes[0]
+	// 0x002e | 03 00 ............................................. Indexes[1]
+	// 0x0030 | 02 01 ............................................. Indexes[2]
+	// 0x0032 |
+}
+
+func ExampleGiveBlockTxnsMessage() {
+	defer gnet.EraseMessages()
+	setupMsgEncoding()
+	var message = NewGiveBlockTxnsMessage(hashes[1], coin.Transactions{
+		{
+			Length:    100,
+			Type:      0,
+			InnerHash: hashes[2],
+			Sigs:      []cipher.Sig{},
+			In:        []cipher.SHA256{hashes[3]},
+			Out:       []coin.TransactionOutput{},
+		},
+	})
+	fmt.Println("GiveBlockTxnsMessage:")
+	var mai = NewMessagesAnnotationsIterator(message)
+	w := bufio.NewWriter(os.Stdout)
+	err := NewFromIterator(gnet.EncodeMessage(message), &mai, w)
+	if err != nil {
+		fmt.Println(err)
+	}
+	// Output:
+	// GiveBlockTxnsMessage:
+	// 0x0000 | 79 00 00 00 ....................................... Length
+	// 0x0004 | 42 54 58 4e ....................................... Prefix
+	// 0x0008 | 7b b4 62 c3 bd 37 1d d8 1c 06 ad 1d 2b 63 59 71
+	// 0x0018 | cb 56 eb 22 23 3d fc 9f eb e8 3e 44 c8 40 b8 d7 ... BlockHash
+	// 0x0028 | 01 00 00 00 ....................................... Transactions length
+	// 0x002c | 64 00 00 00 00 e7 5a c8 01 c1 3f 3d a9 c7 a1 24
+	// 0x003c | ca 31 3b e2 a3 73 f6 4a d9 7c 58 a1 b6 fe bc 0e
+	// 0x004c | 0c a5 c5 c8 73 00 00 00 00 01 00 00 00 f4 45 7d
+	// 0x005c | e9 f5 a5 94 2e 07 6a 7f 2b 28 e1 84 2a b6 1f 1b
+	// 0x006c | fc 39 e4 ca 55 75 36 60 0f d6 42 09 f6 00 00 00
+	// 0x007c | 00 ................................................ Transactions[0]
+	// 0x007d |
+}
+
+func ExampleGetBlockHeadersMessage() {
+	defer gnet.EraseMessages()
+	setupMsgEncoding()
+	var message = NewGetBlockHeadersMessage(1000, 512)
+	fmt.Println("GetBlockHeadersMessage:")
+	var mai = NewMessagesAnnotationsIterator(message)
+	w := bufio.NewWriter(os.Stdout)
+	err := NewFromIterator(gnet.EncodeMessage(message), &mai, w)
+	if err != nil {
+		fmt.Println(err)
+	}
+	// Output:
+	// GetBlockHeadersMessage:
+	// 0x0000 | 14 00 00 00 ....................................... Length
+	// 0x0004 | 47 48 44 52 ....................................... Prefix
+	// 0x0008 | e8 03 00 00 00 00 00 00 ........................... LastBlock
+	// 0x0010 | 00 02 00 00 00 00 00 00 ........................... RequestedHeaders
+	// 0x0018 |
+}
+
+func ExampleGiveBlockHeadersMessage() {
+	defer gnet.EraseMessages()
+	setupMsgEncoding()
+	var body = coin.BlockBody{
+		Transactions: make([]coin.Transaction, 0),
+	}
+	var sig, _ = cipher.SigFromHex(sig1hex)
+	var message = NewGiveBlockHeadersMessage([]coin.SignedBlockHeader{
+		{
+			Head: coin.BlockHeader{
+				Version:  0x02,
+				Time:     100,
+				BkSeq:    1001,
+				Fee:      10,
+				PrevHash: hashes[0],
+				BodyHash: body.Hash(),
+			},
+			Sig: sig,
+		},
+	})
+	fmt.Println("GiveBlockHeadersMessage:")
+	var mai = NewMessagesAnnotationsIterator(message)
+	w := bufio.NewWriter(os.Stdout)
+	err := NewFromIterator(gnet.EncodeMessage(message), &mai, w)
+	if err != nil {
+		fmt.Println(err)
+	}
+	// Output:
+	// GiveBlockHeadersMessage:
+	// 0x0000 | c5 00 00 00 ....................................... Length
+	// 0x0004 | 48 44 52 53 ....................................... Prefix
+	// 0x0008 | 01 00 00 00 ....................................... Headers length
+	// 0x000c | 02 00 00 00 64 00 00 00 00 00 00 00 e9 03 00 00
+	// 0x001c | 00 00 00 00 0a 00 00 00 00 00 00 00 40 af f2 e9
+	// 0x002c | d2 d8 92 2e 47 af d4 64 8e 69 67 49 71 58 78 5f
+	// 0x003c | bd 1d a8 70 e7 11 02 66 bf 94 48 80 00 00 00 00
+	// 0x004c | 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00
+	// 0x005c | 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00
+	// 0x006c | 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00
+	// 0x007c | 00 00 00 00 00 00 00 00 00 00 00 00 03 21 3f dd
+	// 0x008c | 6d df 86 0e 40 53 e1 a9 7e 42 76 d6 34 54 f5 19
+	// 0x009c | 5a 83 21 35 70 04 d5 2c db bf d3 88 6f c7 ad 3f
+	// 0x00ac | 3f 63 b6 5d 4a 87 9c e3 08 6d ae b3 e5 4a 93 d3
+	// 0x00bc | c2 f9 6a 50 61 f9 bc 49 36 83 ca 8e 01 ............ Headers[0]
+	// 0x00c9 |
+}
+
+func ExampleGetBlockBodiesMessage() {
+	defer gnet.EraseMessages()
+	setupMsgEncoding()
+	var message = NewGetBlockBodiesMessage([]cipher.SHA256{hashes[1], hashes[2]})
+	fmt.Println("GetBlockBodiesMessage:")
+	var mai = NewMessagesAnnotationsIterator(message)
+	w := bufio.NewWriter(os.Stdout)
+	err := NewFromIterator(gnet.EncodeMessage(message), &mai, w)
+	if err != nil {
+		fmt.Println(err)
+	}
+	// Output:
+	// GetBlockBodiesMessage:
+	// 0x0000 | 48 00 00 00 ....................................... Length
+	// 0x0004 | 47 42 44 59 ....................................... Prefix
+	// 0x0008 | 02 00 00 00 ....................................... Hashes length
+	// 0x000c | 7b b4 62 c3 bd 37 1d d8 1c 06 ad 1d 2b 63 59 71
+	// 0x001c | cb 56 eb 22 23 3d fc 9f eb e8 3e 44 c8 40 b8 d7 ... Hashes[0]
+	// 0x002c | e7 5a c8 01 c1 3f 3d a9 c7 a1 24 ca 31 3b e2 a3
+	// 0x003c | 73 f6 4a d9 7c 58 a1 b6 fe bc 0e 0c a5 c5 c8 73 ... Hashes[1]
+	// 0x004c |
+}
+
+func ExampleGiveBlockBodiesMessage() {
+	defer gnet.EraseMessages()
+	setupMsgEncoding()
+	var message = NewGiveBlockBodiesMessage([]HashedBlockBody{
+		{
+			BlockHash: hashes[1],
+			Body: coin.BlockBody{
+				Transactions: coin.Transactions{
+					{
+						Length:    100,
+						Type:      0,
+						InnerHash: hashes[2],
+						Sigs:      []cipher.Sig{},
+						In:        []cipher.SHA256{hashes[3]},
+						Out:       []coin.TransactionOutput{},
+					},
+				},
+			},
+		},
+	})
+	fmt.Println("GiveBlockBodiesMessage:")
+	var mai = NewMessagesAnnotationsIterator(message)
+	w := bufio.NewWriter(os.Stdout)
+	err := NewFromIterator(gnet.EncodeMessage(message), &mai, w)
+	if err != nil {
+		fmt.Println(err)
+	}
+	// Output:
+	// GiveBlockBodiesMessage:
+	// 0x0000 | 7d 00 00 00 ....................................... Length
+	// 0x0004 | 42 44 59 53 ....................................... Prefix
+	// 0x0008 | 01 00 00 00 ....................................... Bodies length
+	// 0x000c | 7b b4 62 c3 bd 37 1d d8 1c 06 ad 1d 2b 63 59 71
+	// 0x001c | cb 56 eb 22 23 3d fc 9f eb e8 3e 44 c8 40 b8 d7
+	// 0x002c | 01 00 00 00 64 00 00 00 00 e7 5a c8 01 c1 3f 3d
+	// 0x003c | a9 c7 a1 24 ca 31 3b e2 a3 73 f6 4a d9 7c 58 a1
+	// 0x004c | b6 fe bc 0e 0c a5 c5 c8 73 00 00 00 00 01 00 00
+	// 0x005c | 00 f4 45 7d e9 f5 a5 94 2e 07 6a 7f 2b 28 e1 84
+	// 0x006c | 2a b6 1f 1b fc 39 e4 ca 55 75 36 60 0f d6 42 09
+	// 0x007c | f6 00 00 00 00 .................................... Bodies[0]
+	// 0x0081 |
+}
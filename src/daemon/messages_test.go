@@ -48,6 +48,8 @@ func TestIntroductionMessage(t *testing.T) {
 		userAgent                     useragent.Data
 		unconfirmedBurnFactor         uint32
 		unconfirmedMaxTransactionSize uint32
+		features                      PeerFeatures
+		timestamp                     int64
 		intro                         *IntroductionMessage
 	}{
 		{
@@ -128,11 +130,81 @@ func TestIntroductionMessage(t *testing.T) {
 			},
 			unconfirmedBurnFactor:         4,
 			unconfirmedMaxTransactionSize: 32768,
+			features:                      FeatureCompactBlocks | FeaturePruned,
+			timestamp:                     1523168686,
 			intro: &IntroductionMessage{
 				Mirror:          10001,
 				ListenPort:      6000,
 				ProtocolVersion: 1,
-				Extra:           newIntroductionMessageExtra(pubkey, "skycoin:0.24.1", 4, 32768),
+				Extra:           newIntroductionMessageExtra(pubkey, "skycoin:0.24.1", 4, 32768, FeatureCompactBlocks|FeaturePruned, 1523168686),
+			},
+		},
+		{
+			name: "INTR message with all extra fields except features",
+			addr: "121.121.121.121:6000",
+			mockValue: daemonMockValue{
+				mirror:          10000,
+				protocolVersion: 4,
+				pubkey:          pubkey,
+				connectionIntroduced: &connection{
+					Addr: "121.121.121.121:6000",
+					ConnectionDetails: ConnectionDetails{
+						ListenPort: 6000,
+						UserAgent: useragent.Data{
+							Coin:    "skycoin",
+							Version: "0.24.1",
+						},
+						UnconfirmedBurnFactor:         4,
+						UnconfirmedMaxTransactionSize: 32768,
+					},
+				},
+			},
+			userAgent: useragent.Data{
+				Coin:    "skycoin",
+				Version: "0.24.1",
+			},
+			unconfirmedBurnFactor:         4,
+			unconfirmedMaxTransactionSize: 32768,
+			features:                      FeatureCompactBlocks,
+			intro: &IntroductionMessage{
+				Mirror:          10001,
+				ListenPort:      6000,
+				ProtocolVersion: 4,
+				Extra:           newIntroductionMessageExtra(pubkey, "skycoin:0.24.1", 4, 32768, 0, 0)[:len(pubkey)+8+18],
+			},
+		},
+		{
+			name: "INTR message with unknown features",
+			addr: "121.121.121.121:6000",
+			mockValue: daemonMockValue{
+				mirror:          10000,
+				protocolVersion: 4,
+				pubkey:          pubkey,
+				connectionIntroduced: &connection{
+					Addr: "121.121.121.121:6000",
+					ConnectionDetails: ConnectionDetails{
+						ListenPort: 6000,
+						UserAgent: useragent.Data{
+							Coin:    "skycoin",
+							Version: "0.24.1",
+						},
+						UnconfirmedBurnFactor:         4,
+						UnconfirmedMaxTransactionSize: 32768,
+					},
+				},
+			},
+			userAgent: useragent.Data{
+				Coin:    "skycoin",
+				Version: "0.24.1",
+			},
+			unconfirmedBurnFactor:         4,
+			unconfirmedMaxTransactionSize: 32768,
+			features:                      FeatureHeadersFirst | FeatureNoTxnRelay,
+			intro: &IntroductionMessage{
+				Mirror:          10001,
+				ListenPort:      6000,
+				ProtocolVersion: 4,
+				Extra:           newIntroductionMessageExtra(pubkey, "skycoin:0.24.1", 4, 32768, FeatureHeadersFirst|FeatureNoTxnRelay|1<<40, 0),
 			},
 		},
 		{
@@ -161,11 +233,13 @@ func TestIntroductionMessage(t *testing.T) {
 			},
 			unconfirmedBurnFactor:         4,
 			unconfirmedMaxTransactionSize: 32768,
+			features:                      FeatureCompactBlocks,
+			timestamp:                     1523168686,
 			intro: &IntroductionMessage{
 				Mirror:          10001,
 				ListenPort:      6000,
 				ProtocolVersion: 1,
-				Extra:           append(newIntroductionMessageExtra(pubkey, "skycoin:0.24.1", 4, 32768), []byte("additonal data")...),
+				Extra:           append(newIntroductionMessageExtra(pubkey, "skycoin:0.24.1", 4, 32768, FeatureCompactBlocks, 1523168686), []byte("additonal data")...),
 			},
 		},
 		{
@@ -181,7 +255,7 @@ func TestIntroductionMessage(t *testing.T) {
 				Mirror:          10001,
 				ListenPort:      6000,
 				ProtocolVersion: 1,
-				Extra:           newIntroductionMessageExtra(pubkey2, "skycoin:0.24.1", 4, 32768),
+				Extra:           newIntroductionMessageExtra(pubkey2, "skycoin:0.24.1", 4, 32768, 0, 0),
 			},
 		},
 		{
@@ -314,11 +388,12 @@ func TestIntroductionMessage(t *testing.T) {
 			},
 			unconfirmedBurnFactor:         4,
 			unconfirmedMaxTransactionSize: 32768,
+			features:                      FeatureCompactBlocks,
 			intro: &IntroductionMessage{
 				Mirror:          10001,
 				ProtocolVersion: 1,
 				ListenPort:      6000,
-				Extra:           newIntroductionMessageExtra(pubkey, "skycoin:0.24.1(foo)", uint32(4), uint32(32768)),
+				Extra:           newIntroductionMessageExtra(pubkey, "skycoin:0.24.1(foo)", uint32(4), uint32(32768), FeatureCompactBlocks, 0),
 			},
 		},
 		{
@@ -395,6 +470,12 @@ func TestIntroductionMessage(t *testing.T) {
 				if tc.unconfirmedMaxTransactionSize != m.unconfirmedMaxTransactionSize {
 					return false
 				}
+				if tc.features != m.features {
+					return false
+				}
+				if tc.timestamp != m.timestamp {
+					return false
+				}
 
 				return true
 			})).Return(tc.mockValue.connectionIntroduced, tc.mockValue.connectionIntroducedErr)
@@ -466,6 +547,22 @@ func TestMessageEncodeDecode(t *testing.T) {
 				},
 			},
 		},
+		{
+			goldenFile: "give-ipv6-peers-msg.golden",
+			obj:        &GiveIPv6PeersMessage{},
+			msg: &GiveIPv6PeersMessage{
+				Peers: []IPv6Addr{
+					{
+						IP:   [16]byte{0x20, 0x01, 0x0d, 0xb8, 15: 1},
+						Port: 1234,
+					},
+					{
+						IP:   [16]byte{0x26, 0x06, 0x47, 0x00, 14: 0x11, 15: 0x11},
+						Port: 4321,
+					},
+				},
+			},
+		},
 		{
 			goldenFile: "ping-msg.golden",
 			obj:        &PingMessage{},
@@ -713,6 +810,132 @@ func TestMessageEncodeDecode(t *testing.T) {
 				},
 			},
 		},
+		{
+			goldenFile: "compact-block-msg.golden",
+			obj:        &CompactBlockMessage{},
+			msg: &CompactBlockMessage{
+				Head: coin.BlockHeader{
+					Version:  1,
+					Time:     1538036613,
+					BkSeq:    9999999999,
+					Fee:      1234123412341234,
+					PrevHash: cipher.MustSHA256FromHex("59cb7d0e2ce8a03d1054afcc28a22fe864a8813460d241db38c59d10e7c29132"),
+					BodyHash: cipher.MustSHA256FromHex("6d421469409591f0c3112884c8cf10f8bca5d8ab87c9c30dea2ea73b6751bbf9"),
+					UxHash:   cipher.MustSHA256FromHex("6ea6a972cf06d25908b29953aeddb68c3b6f3a9903e8f964dc89b0abc0645dea"),
+				},
+				Sig: cipher.MustSigFromHex("8cf145e9ef4a4a5254bc57798a7a61dfed238768f94edc5635175c6b91bccd8ec1555da603c5e31b018e135b82b1525be8a92973c468a74b5b40b8da189cb465eb"),
+				ShortIDs: []ShortTxnID{
+					{0x3f, 0x91, 0x0a, 0xc2, 0x58, 0x7e},
+					{0xd4, 0x06, 0xbb, 0x13, 0xe9, 0x22},
+				},
+			},
+		},
+		{
+			goldenFile: "get-block-txns-msg.golden",
+			obj:        &GetBlockTxnsMessage{},
+			msg: &GetBlockTxnsMessage{
+				BlockHash: cipher.MustSHA256FromHex("23dc4b68c0fc790989bb82f04b9d5174baab6f0f6808ed35be9b93cb73c69108"),
+				Indexes:   []uint16{0, 7, 1024},
+			},
+		},
+		{
+			goldenFile: "give-block-txns-msg.golden",
+			obj:        &GiveBlockTxnsMessage{},
+			msg: &GiveBlockTxnsMessage{
+				BlockHash: cipher.MustSHA256FromHex("23dc4b68c0fc790989bb82f04b9d5174baab6f0f6808ed35be9b93cb73c69108"),
+				Transactions: coin.Transactions{
+					{
+						Length:    256,
+						Type:      0,
+						InnerHash: cipher.MustSHA256FromHex("1773d8901df96bba4c6d65499e11e6ec73a9978c611d1463898ffbc2b49773fc"),
+						Sigs: []cipher.Sig{
+							cipher.MustSigFromHex("a711880ae54d1b6b9adade2ef1e743d6d539a78b0cecf1af08107e467956de80ef1d49fb5e896c9d0870ef8bf8a4d328ca0ecf7c1956866867ec56064e68f8a374"),
+						},
+						In: []cipher.SHA256{
+							cipher.MustSHA256FromHex("703f84ee0702b44fc89ce573a239d5fbf185bf5d4e7fc8f4930262bcda1e8fb0"),
+						},
+						Out: []coin.TransactionOutput{
+							{
+								Address: cipher.MustDecodeBase58Address("29VEn56iRr2TpVVpPoPxUJPfFWuhbLSBRdU"),
+								Coins:   1111111111111111111,
+								Hours:   9999999999999999999,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			goldenFile: "get-block-headers-msg.golden",
+			obj:        &GetBlockHeadersMessage{},
+			msg: &GetBlockHeadersMessage{
+				LastBlock:        50000,
+				RequestedHeaders: 512,
+			},
+		},
+		{
+			goldenFile: "give-block-headers-msg.golden",
+			obj:        &GiveBlockHeadersMessage{},
+			msg: &GiveBlockHeadersMessage{
+				Headers: []coin.SignedBlockHeader{
+					{
+						Head: coin.BlockHeader{
+							Version:  1,
+							Time:     1538036613,
+							BkSeq:    50001,
+							Fee:      1234123412341234,
+							PrevHash: cipher.MustSHA256FromHex("59cb7d0e2ce8a03d1054afcc28a22fe864a8813460d241db38c59d10e7c29132"),
+							BodyHash: cipher.MustSHA256FromHex("6d421469409591f0c3112884c8cf10f8bca5d8ab87c9c30dea2ea73b6751bbf9"),
+							UxHash:   cipher.MustSHA256FromHex("6ea6a972cf06d25908b29953aeddb68c3b6f3a9903e8f964dc89b0abc0645dea"),
+						},
+						Sig: cipher.MustSigFromHex("8cf145e9ef4a4a5254bc57798a7a61dfed238768f94edc5635175c6b91bccd8ec1555da603c5e31b018e135b82b1525be8a92973c468a74b5b40b8da189cb465eb"),
+					},
+				},
+			},
+		},
+		{
+			goldenFile: "get-block-bodies-msg.golden",
+			obj:        &GetBlockBodiesMessage{},
+			msg: &GetBlockBodiesMessage{
+				Hashes: []cipher.SHA256{
+					cipher.MustSHA256FromHex("23dc4b68c0fc790989bb82f04b9d5174baab6f0f6808ed35be9b93cb73c69108"),
+					cipher.MustSHA256FromHex("59cb7d0e2ce8a03d1054afcc28a22fe864a8813460d241db38c59d10e7c29132"),
+				},
+			},
+		},
+		{
+			goldenFile: "give-block-bodies-msg.golden",
+			obj:        &GiveBlockBodiesMessage{},
+			msg: &GiveBlockBodiesMessage{
+				Bodies: []HashedBlockBody{
+					{
+						BlockHash: cipher.MustSHA256FromHex("23dc4b68c0fc790989bb82f04b9d5174baab6f0f6808ed35be9b93cb73c69108"),
+						Body: coin.BlockBody{
+							Transactions: coin.Transactions{
+								{
+									Length:    256,
+									Type:      0,
+									InnerHash: cipher.MustSHA256FromHex("1773d8901df96bba4c6d65499e11e6ec73a9978c611d1463898ffbc2b49773fc"),
+									Sigs: []cipher.Sig{
+										cipher.MustSigFromHex("a711880ae54d1b6b9adade2ef1e743d6d539a78b0cecf1af08107e467956de80ef1d49fb5e896c9d0870ef8bf8a4d328ca0ecf7c1956866867ec56064e68f8a374"),
+									},
+									In: []cipher.SHA256{
+										cipher.MustSHA256FromHex("703f84ee0702b44fc89ce573a239d5fbf185bf5d4e7fc8f4930262bcda1e8fb0"),
+									},
+									Out: []coin.TransactionOutput{
+										{
+											Address: cipher.MustDecodeBase58Address("29VEn56iRr2TpVVpPoPxUJPfFWuhbLSBRdU"),
+											Coins:   1111111111111111111,
+											Hours:   9999999999999999999,
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
 	}
 
 	if update {
@@ -752,3 +975,46 @@ func TestMessageEncodeDecode(t *testing.T) {
 		})
 	}
 }
+
+func TestGivePeersMessagesIPFamilies(t *testing.T) {
+	peers := []pex.Peer{
+		*pex.NewPeer("118.178.135.93:6000"),
+		*pex.NewPeer("[2001:db8::1]:6000"),
+		*pex.NewPeer("[::ffff:47.88.33.156]:6000"),
+		*pex.NewPeer("pg6mmjiyjmcrsslvykfwnntlaru7p5svn6y2ymmju6nubxndf4pscryd.onion:6000"),
+		*pex.NewPeer("[2606:4700::1111]:7000"),
+	}
+
+	// IPv4 peers are sent in a GivePeersMessage
+	m := NewGivePeersMessage(peers)
+	require.Equal(t, []string{"118.178.135.93:6000", "47.88.33.156:6000"}, m.GetPeers())
+
+	// IPv6 peers are sent in a GiveIPv6PeersMessage
+	m6 := NewGiveIPv6PeersMessage(peers)
+	require.Equal(t, []string{"[2001:db8::1]:6000", "[2606:4700::1111]:7000"}, m6.GetPeers())
+
+	// The messages survive encoding
+	var m6b GiveIPv6PeersMessage
+	err := encoder.DeserializeRaw(encoder.Serialize(*m6), &m6b)
+	require.NoError(t, err)
+	require.Equal(t, m6.Peers, m6b.Peers)
+}
+
+func TestNewIPv6Addr(t *testing.T) {
+	a, err := NewIPv6Addr("[2001:db8::1]:6000")
+	require.NoError(t, err)
+	require.Equal(t, IPv6Addr{
+		IP:   [16]byte{0x20, 0x01, 0x0d, 0xb8, 15: 1},
+		Port: 6000,
+	}, a)
+	require.Equal(t, "[2001:db8::1]:6000", a.String())
+
+	_, err = NewIPv6Addr("118.178.135.93:6000")
+	require.Error(t, err)
+
+	_, err = NewIPv6Addr("[::ffff:118.178.135.93]:6000")
+	require.Error(t, err)
+
+	_, err = NewIPv6Addr("2001:db8::1")
+	require.Error(t, err)
+}
@@ -14,13 +14,18 @@ type mockDaemoner struct {
 	mock.Mock
 }
 
-// addPeers provides a mock function with given fields: addrs
-func (_m *mockDaemoner) addPeers(addrs []string) int {
-	ret := _m.Called(addrs)
+// addPartialBlock provides a mock function with given fields: pb
+func (_m *mockDaemoner) addPartialBlock(pb *partialBlock) {
+	_m.Called(pb)
+}
+
+// addPeers provides a mock function with given fields: source, addrs
+func (_m *mockDaemoner) addPeers(source string, addrs []string) int {
+	ret := _m.Called(source, addrs)
 
 	var r0 int
-	if rf, ok := ret.Get(0).(func([]string) int); ok {
-		r0 = rf(addrs)
+	if rf, ok := ret.Get(0).(func(string, []string) int); ok {
+		r0 = rf(source, addrs)
 	} else {
 		r0 = ret.Get(0).(int)
 	}
@@ -28,6 +33,34 @@ func (_m *mockDaemoner) addPeers(addrs []string) int {
 	return r0
 }
 
+// addSyncBodies provides a mock function with given fields: addr, bodies
+func (_m *mockDaemoner) addSyncBodies(addr string, bodies []HashedBlockBody) error {
+	ret := _m.Called(addr, bodies)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, []HashedBlockBody) error); ok {
+		r0 = rf(addr, bodies)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// addSyncHeaders provides a mock function with given fields: addr, headers
+func (_m *mockDaemoner) addSyncHeaders(addr string, headers []coin.SignedBlockHeader) error {
+	ret := _m.Called(addr, headers)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, []coin.SignedBlockHeader) error); ok {
+		r0 = rf(addr, headers)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // announceAllTxns provides a mock function with given fields:
 func (_m *mockDaemoner) announceAllTxns() error {
 	ret := _m.Called()
@@ -63,6 +96,20 @@ func (_m *mockDaemoner) broadcastMessage(msg gnet.Message) (int, error) {
 	return r0, r1
 }
 
+// checkRelayPolicy provides a mock function with given fields: txn
+func (_m *mockDaemoner) checkRelayPolicy(txn coin.Transaction) bool {
+	ret := _m.Called(txn)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(coin.Transaction) bool); ok {
+		r0 = rf(txn)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
 // connectionIntroduced provides a mock function with given fields: addr, gnetID, m
 func (_m *mockDaemoner) connectionIntroduced(addr string, gnetID uint64, m *IntroductionMessage) (*connection, error) {
 	ret := _m.Called(addr, gnetID, m)
@@ -114,18 +161,25 @@ func (_m *mockDaemoner) disconnectNow(addr string, r gnet.DisconnectReason) erro
 	return r0
 }
 
-// executeSignedBlock provides a mock function with given fields: b
-func (_m *mockDaemoner) executeSignedBlock(b coin.SignedBlock) error {
-	ret := _m.Called(b)
+// executeSignedBlocks provides a mock function with given fields: blocks
+func (_m *mockDaemoner) executeSignedBlocks(blocks []coin.SignedBlock) (int, error) {
+	ret := _m.Called(blocks)
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(coin.SignedBlock) error); ok {
-		r0 = rf(b)
+	var r0 int
+	if rf, ok := ret.Get(0).(func([]coin.SignedBlock) int); ok {
+		r0 = rf(blocks)
 	} else {
-		r0 = ret.Error(0)
+		r0 = ret.Get(0).(int)
 	}
 
-	return r0
+	var r1 error
+	if rf, ok := ret.Get(1).(func([]coin.SignedBlock) error); ok {
+		r1 = rf(blocks)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
 // filterKnownUnconfirmed provides a mock function with given fields: txns
@@ -151,6 +205,29 @@ func (_m *mockDaemoner) filterKnownUnconfirmed(txns []cipher.SHA256) ([]cipher.S
 	return r0, r1
 }
 
+// getAllUnconfirmedTxns provides a mock function with given fields:
+func (_m *mockDaemoner) getAllUnconfirmedTxns() (coin.Transactions, error) {
+	ret := _m.Called()
+
+	var r0 coin.Transactions
+	if rf, ok := ret.Get(0).(func() coin.Transactions); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(coin.Transactions)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // getKnownUnconfirmed provides a mock function with given fields: txns
 func (_m *mockDaemoner) getKnownUnconfirmed(txns []cipher.SHA256) (coin.Transactions, error) {
 	ret := _m.Called(txns)
@@ -174,6 +251,52 @@ func (_m *mockDaemoner) getKnownUnconfirmed(txns []cipher.SHA256) (coin.Transact
 	return r0, r1
 }
 
+// getSignedBlockByHash provides a mock function with given fields: hash
+func (_m *mockDaemoner) getSignedBlockByHash(hash cipher.SHA256) (*coin.SignedBlock, error) {
+	ret := _m.Called(hash)
+
+	var r0 *coin.SignedBlock
+	if rf, ok := ret.Get(0).(func(cipher.SHA256) *coin.SignedBlock); ok {
+		r0 = rf(hash)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*coin.SignedBlock)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(cipher.SHA256) error); ok {
+		r1 = rf(hash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// getSignedBlockHeadersSince provides a mock function with given fields: seq, count
+func (_m *mockDaemoner) getSignedBlockHeadersSince(seq uint64, count uint64) ([]coin.SignedBlockHeader, error) {
+	ret := _m.Called(seq, count)
+
+	var r0 []coin.SignedBlockHeader
+	if rf, ok := ret.Get(0).(func(uint64, uint64) []coin.SignedBlockHeader); ok {
+		r0 = rf(seq, count)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]coin.SignedBlockHeader)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uint64, uint64) error); ok {
+		r1 = rf(seq, count)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // getSignedBlocksSince provides a mock function with given fields: seq, count
 func (_m *mockDaemoner) getSignedBlocksSince(seq uint64, count uint64) ([]coin.SignedBlock, error) {
 	ret := _m.Called(seq, count)
@@ -269,6 +392,11 @@ func (_m *mockDaemoner) pexConfig() pex.Config {
 	return r0
 }
 
+// recordBlocksReceived provides a mock function with given fields: gnetID
+func (_m *mockDaemoner) recordBlocksReceived(gnetID uint64) {
+	_m.Called(gnetID)
+}
+
 // recordMessageEvent provides a mock function with given fields: m, c
 func (_m *mockDaemoner) recordMessageEvent(m asyncMessage, c *gnet.MessageContext) error {
 	ret := _m.Called(m, c)
@@ -288,6 +416,62 @@ func (_m *mockDaemoner) recordPeerHeight(addr string, gnetID uint64, height uint
 	_m.Called(addr, gnetID, height)
 }
 
+// recordPeerMisbehavior provides a mock function with given fields: addr, m
+func (_m *mockDaemoner) recordPeerMisbehavior(addr string, m PeerMisbehavior) {
+	_m.Called(addr, m)
+}
+
+// relayBlock provides a mock function with given fields: sb, fromAddr
+func (_m *mockDaemoner) relayBlock(sb coin.SignedBlock, fromAddr string) error {
+	ret := _m.Called(sb, fromAddr)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(coin.SignedBlock, string) error); ok {
+		r0 = rf(sb, fromAddr)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// removePartialBlock provides a mock function with given fields: addr, hash
+func (_m *mockDaemoner) removePartialBlock(addr string, hash cipher.SHA256) *partialBlock {
+	ret := _m.Called(addr, hash)
+
+	var r0 *partialBlock
+	if rf, ok := ret.Get(0).(func(string, cipher.SHA256) *partialBlock); ok {
+		r0 = rf(addr, hash)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*partialBlock)
+		}
+	}
+
+	return r0
+}
+
+// repairBlocks provides a mock function with given fields: blocks
+func (_m *mockDaemoner) repairBlocks(blocks []coin.SignedBlock) (int, error) {
+	ret := _m.Called(blocks)
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func([]coin.SignedBlock) int); ok {
+		r0 = rf(blocks)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func([]coin.SignedBlock) error); ok {
+		r1 = rf(blocks)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // requestBlocksFromAddr provides a mock function with given fields: addr
 func (_m *mockDaemoner) requestBlocksFromAddr(addr string) error {
 	ret := _m.Called(addr)
@@ -302,6 +486,22 @@ func (_m *mockDaemoner) requestBlocksFromAddr(addr string) error {
 	return r0
 }
 
+// scheduleTxnRequests provides a mock function with given fields: addr, hashes
+func (_m *mockDaemoner) scheduleTxnRequests(addr string, hashes []cipher.SHA256) []cipher.SHA256 {
+	ret := _m.Called(addr, hashes)
+
+	var r0 []cipher.SHA256
+	if rf, ok := ret.Get(0).(func(string, []cipher.SHA256) []cipher.SHA256); ok {
+		r0 = rf(addr, hashes)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]cipher.SHA256)
+		}
+	}
+
+	return r0
+}
+
 // sendMessage provides a mock function with given fields: addr, msg
 func (_m *mockDaemoner) sendMessage(addr string, msg gnet.Message) error {
 	ret := _m.Called(addr, msg)
@@ -343,3 +543,22 @@ func (_m *mockDaemoner) Disconnect(addr string, r gnet.DisconnectReason) error {
 
 	return r0
 }
+
+// syncHeadersFirst provides a mock function with given fields:
+func (_m *mockDaemoner) syncHeadersFirst() error {
+	ret := _m.Called()
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func() error); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// txnsReceived provides a mock function with given fields: addr, hashes
+func (_m *mockDaemoner) txnsReceived(addr string, hashes []cipher.SHA256) {
+	_m.Called(addr, hashes)
+}
@@ -0,0 +1,133 @@
+package daemon
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// networkTimeMinSamples is the number of peer clock samples needed before the local clock is adjusted
+	// or reported as skewed
+	networkTimeMinSamples = 5
+	// networkTimeMaxSamples is the number of the most recent peer clock samples kept
+	networkTimeMaxSamples = 200
+)
+
+// ClockSkew is the offset of the local clock from the clocks of the peers
+type ClockSkew struct {
+	// Median of the clock offsets of the peers, their clock minus the local clock
+	Offset time.Duration
+	// Adjustment applied to the local clock for the network-adjusted time. It is the Offset, unless there are
+	// too few samples or the Offset exceeds the maximum adjustment, in which case the local clock is trusted
+	Adjustment time.Duration
+	// Number of peer clock samples
+	Samples int
+	// Skewed is true if the Offset exceeds the warning threshold
+	Skewed bool
+}
+
+// networkTime tracks the clock offsets of the peers, sampled from the timestamps of their introductions,
+// to detect a wrong local clock and compute the network-adjusted time, the local time plus the median offset.
+// A netgroup has a single sample, its most recent, so that an attacker connecting from an address range
+// can't move the median. A nil *networkTime uses the local clock
+type networkTime struct {
+	sync.Mutex
+	warnThreshold time.Duration
+	maxAdjustment time.Duration
+	offsets       map[string]time.Duration
+	// Netgroups in the order their offset was sampled, oldest first
+	order []string
+}
+
+// newNetworkTime creates a networkTime which reports a skewed clock when the median offset exceeds
+// warnThreshold, and adjusts the local clock by up to maxAdjustment
+func newNetworkTime(warnThreshold, maxAdjustment time.Duration) *networkTime {
+	return &networkTime{
+		warnThreshold: warnThreshold,
+		maxAdjustment: maxAdjustment,
+		offsets:       make(map[string]time.Duration),
+	}
+}
+
+// add records the clock offset of a peer of a netgroup and returns the updated ClockSkew
+func (nt *networkTime) add(netgroup string, offset time.Duration) ClockSkew {
+	if nt == nil {
+		return ClockSkew{}
+	}
+
+	nt.Lock()
+	defer nt.Unlock()
+
+	if _, ok := nt.offsets[netgroup]; ok {
+		for i, g := range nt.order {
+			if g == netgroup {
+				nt.order = append(nt.order[:i], nt.order[i+1:]...)
+				break
+			}
+		}
+	}
+
+	nt.offsets[netgroup] = offset
+	nt.order = append(nt.order, netgroup)
+
+	if len(nt.order) > networkTimeMaxSamples {
+		delete(nt.offsets, nt.order[0])
+		nt.order = nt.order[1:]
+	}
+
+	return nt.skew()
+}
+
+// clockSkew returns the ClockSkew of the local clock
+func (nt *networkTime) clockSkew() ClockSkew {
+	if nt == nil {
+		return ClockSkew{}
+	}
+
+	nt.Lock()
+	defer nt.Unlock()
+	return nt.skew()
+}
+
+func (nt *networkTime) skew() ClockSkew {
+	s := ClockSkew{
+		Samples: len(nt.offsets),
+	}
+
+	if s.Samples < networkTimeMinSamples {
+		return s
+	}
+
+	offsets := make([]time.Duration, 0, len(nt.offsets))
+	for _, o := range nt.offsets {
+		offsets = append(offsets, o)
+	}
+	sort.Slice(offsets, func(i, j int) bool {
+		return offsets[i] < offsets[j]
+	})
+
+	n := len(offsets)
+	if n%2 == 1 {
+		s.Offset = offsets[n/2]
+	} else {
+		s.Offset = (offsets[n/2-1] + offsets[n/2]) / 2
+	}
+
+	abs := s.Offset
+	if abs < 0 {
+		abs = -abs
+	}
+
+	s.Skewed = abs > nt.warnThreshold
+	if abs <= nt.maxAdjustment {
+		s.Adjustment = s.Offset
+	}
+
+	return s
+}
+
+// now returns the network-adjusted time of the local time t
+func (nt *networkTime) now(t time.Time) time.Time {
+	return t.Add(nt.clockSkew().Adjustment)
+}
@@ -0,0 +1,94 @@
+package daemon
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/coin"
+)
+
+func TestNetworkTime(t *testing.T) {
+	now := time.Now().UTC()
+
+	// A nil networkTime uses the local clock
+	var nt *networkTime
+	require.Equal(t, ClockSkew{}, nt.add("1.1.0.0/16", time.Hour))
+	require.Equal(t, now, nt.now(now))
+
+	nt = newNetworkTime(time.Minute*5, time.Minute*70)
+
+	// The local clock is not adjusted until there are enough samples
+	for i := 0; i < networkTimeMinSamples-1; i++ {
+		s := nt.add(fmt.Sprintf("1.%d.0.0/16", i), time.Minute*10)
+		require.Equal(t, ClockSkew{Samples: i + 1}, s)
+	}
+	require.Equal(t, now, nt.now(now))
+
+	// A netgroup has a single sample
+	require.Equal(t, networkTimeMinSamples-1, nt.add("1.0.0.0/16", time.Minute*10).Samples)
+
+	s := nt.add("2.2.0.0/16", time.Second)
+	require.Equal(t, ClockSkew{
+		Offset:     time.Minute * 10,
+		Adjustment: time.Minute * 10,
+		Samples:    networkTimeMinSamples,
+		Skewed:     true,
+	}, s)
+	require.Equal(t, now.Add(time.Minute*10), nt.now(now))
+
+	// The sample of a netgroup is replaced by its most recent sample
+	s = nt.add("1.0.0.0/16", -time.Second)
+	require.Equal(t, time.Minute*10, s.Offset)
+	require.Equal(t, networkTimeMinSamples, s.Samples)
+
+	// The median of an even number of samples is the mean of the middle samples
+	s = nt.add("3.3.0.0/16", -time.Second)
+	require.Equal(t, time.Minute*5+time.Second/2, s.Offset)
+
+	// An offset beyond the maximum adjustment is not applied
+	for i := 0; i < 8; i++ {
+		nt.add(fmt.Sprintf("4.%d.0.0/16", i), -time.Hour*2)
+	}
+	s = nt.clockSkew()
+	require.Equal(t, -time.Hour*2, s.Offset)
+	require.Equal(t, time.Duration(0), s.Adjustment)
+	require.True(t, s.Skewed)
+	require.Equal(t, now, nt.now(now))
+
+	// Only the most recent samples are kept
+	for i := 0; i < networkTimeMaxSamples; i++ {
+		nt.add(fmt.Sprintf("5.%d.%d.0/24", i/256, i%256), time.Second*2)
+	}
+	require.Equal(t, ClockSkew{
+		Offset:     time.Second * 2,
+		Adjustment: time.Second * 2,
+		Samples:    networkTimeMaxSamples,
+	}, nt.clockSkew())
+	require.Len(t, nt.order, networkTimeMaxSamples)
+}
+
+func TestExecuteSignedBlocksFutureTime(t *testing.T) {
+	dm := &Daemon{
+		Config:      NewDaemonConfig(),
+		networkTime: newNetworkTime(time.Minute*5, time.Minute*70),
+	}
+
+	future := uint64(time.Now().Add(dm.Config.MaxBlockTimeDrift + time.Minute).Unix())
+	blocks := []coin.SignedBlock{
+		{
+			Block: coin.Block{
+				Head: coin.BlockHeader{
+					BkSeq: 1,
+					Time:  future,
+				},
+			},
+		},
+	}
+
+	n, err := dm.executeSignedBlocks(blocks)
+	require.Equal(t, ErrBlockTooFarInFuture, err)
+	require.Equal(t, 0, n)
+}
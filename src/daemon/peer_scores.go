@@ -0,0 +1,309 @@
+package daemon
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/skycoin/skycoin/src/daemon/gnet"
+	"github.com/skycoin/skycoin/src/util/iputil"
+)
+
+// PeerMisbehavior is a kind of peer misbehavior, which adds to the ban score of the peer
+type PeerMisbehavior string
+
+const (
+	// PeerMisbehaviorInvalidMessage the peer sent a message that can't be decoded or is invalid,
+	// e.g. a block with an invalid signature
+	PeerMisbehaviorInvalidMessage PeerMisbehavior = "invalid_message"
+	// PeerMisbehaviorStalledBlocks the peer did not respond to a blocks request, although it reported
+	// a height greater than ours
+	PeerMisbehaviorStalledBlocks PeerMisbehavior = "stalled_blocks"
+	// PeerMisbehaviorProtocolViolation the peer did not follow the protocol, e.g. it did not send
+	// an introduction first
+	PeerMisbehaviorProtocolViolation PeerMisbehavior = "protocol_violation"
+	// PeerMisbehaviorStalledRequests the peer left several consecutive transactions or header-first
+	// synchronization requests unanswered
+	PeerMisbehaviorStalledRequests PeerMisbehavior = "stalled_requests"
+)
+
+// peerMisbehaviorScores is the ban score added by each kind of misbehavior
+var peerMisbehaviorScores = map[PeerMisbehavior]int{
+	PeerMisbehaviorInvalidMessage:    25,
+	PeerMisbehaviorStalledBlocks:     10,
+	PeerMisbehaviorProtocolViolation: 50,
+	PeerMisbehaviorStalledRequests:   20,
+}
+
+// disconnectReasonMisbehavior returns the misbehavior of a peer disconnected for a reason, if any
+func disconnectReasonMisbehavior(r gnet.DisconnectReason) (PeerMisbehavior, bool) {
+	switch r {
+	case gnet.ErrDisconnectInvalidMessageLength,
+		gnet.ErrDisconnectMalformedMessage,
+		gnet.ErrDisconnectUnknownMessage,
+		gnet.ErrDisconnectMessageDecodeUnderflow,
+		gnet.ErrDisconnectTruncatedMessageID,
+		gnet.ErrDisconnectInvalidCompressedMessage,
+		gnet.ErrDisconnectMessageTooLong,
+		gnet.ErrDisconnectMessageFieldTooLong,
+		ErrDisconnectInvalidExtraData,
+		ErrDisconnectInvalidUserAgent:
+		return PeerMisbehaviorInvalidMessage, true
+	case ErrDisconnectNoIntroduction,
+		ErrDisconnectTransportFeaturesMismatch:
+		return PeerMisbehaviorProtocolViolation, true
+	default:
+		return "", false
+	}
+}
+
+type peerPenalty struct {
+	score int
+	time  time.Time
+}
+
+type blockRequest struct {
+	// When the first blocks request not responded to yet was sent
+	time time.Time
+	// Whether the connection was penalized for not responding
+	penalized bool
+}
+
+// peerScores tracks the ban scores of peer IPs and the pending blocks requests of connections
+type peerScores struct {
+	sync.Mutex
+	// Penalties by IP, in the order they were added
+	penalties map[string][]peerPenalty
+	// Connections by gnet ID that were sent a blocks request they did not respond to yet
+	blockRequests map[uint64]blockRequest
+}
+
+func newPeerScores() *peerScores {
+	return &peerScores{
+		penalties:     make(map[string][]peerPenalty),
+		blockRequests: make(map[uint64]blockRequest),
+	}
+}
+
+// add adds a penalty to an IP and returns the ban score of the IP, the sum of its penalties
+// of the last window. Older penalties are removed
+func (s *peerScores) add(ip string, score int, now time.Time, window time.Duration) int {
+	s.Lock()
+	defer s.Unlock()
+
+	s.penalties[ip] = append(s.penalties[ip], peerPenalty{
+		score: score,
+		time:  now,
+	})
+
+	cutoff := now.Add(-window)
+	total := 0
+	for k, ps := range s.penalties {
+		n := 0
+		for _, p := range ps {
+			if p.time.After(cutoff) {
+				ps[n] = p
+				n++
+			}
+		}
+
+		if n == 0 {
+			delete(s.penalties, k)
+			continue
+		}
+
+		s.penalties[k] = ps[:n]
+
+		if k == ip {
+			for _, p := range ps[:n] {
+				total += p.score
+			}
+		}
+	}
+
+	return total
+}
+
+// score returns the ban score of an IP, the sum of its penalties of the last window
+func (s *peerScores) score(ip string, now time.Time, window time.Duration) int {
+	s.Lock()
+	defer s.Unlock()
+
+	cutoff := now.Add(-window)
+	total := 0
+	for _, p := range s.penalties[ip] {
+		if p.time.After(cutoff) {
+			total += p.score
+		}
+	}
+
+	return total
+}
+
+// remove removes the penalties of an IP
+func (s *peerScores) remove(ip string) {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.penalties, ip)
+}
+
+// requestBlocks records a blocks request to the connections and returns the connections that did not respond
+// to the previous request and were not penalized for it yet. The returned connections are marked as penalized.
+// The requests to other connections are forgotten
+func (s *peerScores) requestBlocks(gnetIDs []uint64, now time.Time) []uint64 {
+	s.Lock()
+	defer s.Unlock()
+
+	var stalled []uint64
+	requests := make(map[uint64]blockRequest, len(gnetIDs))
+	for _, id := range gnetIDs {
+		r, ok := s.blockRequests[id]
+		if !ok {
+			r.time = now
+		} else if !r.penalized {
+			stalled = append(stalled, id)
+			r.penalized = true
+		}
+		requests[id] = r
+	}
+
+	s.blockRequests = requests
+
+	return stalled
+}
+
+// blocksReceived records that a connection responded to the blocks requests.
+// Returns the latency of the response, if the connection was sent a blocks request
+func (s *peerScores) blocksReceived(gnetID uint64, now time.Time) (time.Duration, bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	r, ok := s.blockRequests[gnetID]
+	if !ok {
+		return 0, false
+	}
+
+	delete(s.blockRequests, gnetID)
+
+	return now.Sub(r.time), true
+}
+
+// recordPeerMisbehavior adds the score of a misbehavior to the ban score of the IP of a peer.
+// If the ban score reaches DaemonConfig.BanScoreThreshold, the IP is banned for DaemonConfig.BanDuration
+// and its connections are disconnected. Trusted, whitelisted and localhost peers are never banned automatically.
+func (dm *Daemon) recordPeerMisbehavior(addr string, m PeerMisbehavior) {
+	if dm.Config.BanScoreThreshold == 0 {
+		return
+	}
+
+	ip, _, err := iputil.SplitAddr(addr)
+	if err != nil {
+		logger.Critical().WithError(err).WithField("addr", addr).Error("recordPeerMisbehavior called with invalid addr")
+		return
+	}
+
+	score := dm.peerScores.add(ip, peerMisbehaviorScores[m], dm.now().UTC(), dm.Config.BanScoreWindow)
+
+	fields := logrus.Fields{
+		"addr":        addr,
+		"misbehavior": m,
+		"score":       score,
+	}
+	logger.WithFields(fields).Info("Peer misbehaved")
+
+	if score < dm.Config.BanScoreThreshold {
+		return
+	}
+
+	if iputil.IsLocalhost(ip) || dm.isTrustedIP(ip) || dm.isWhitelistedIP(ip) {
+		logger.WithFields(fields).Info("Ban score threshold reached by a trusted, whitelisted or localhost peer, not banning")
+		return
+	}
+
+	if err := dm.banIP(ip, string(m), dm.Config.BanDuration); err != nil {
+		logger.WithError(err).WithFields(fields).Error("banIP failed")
+	}
+}
+
+// banIP bans an IP for duration and disconnects its connections
+func (dm *Daemon) banIP(ip, reason string, duration time.Duration) error {
+	if err := dm.pex.Ban(ip, reason, duration); err != nil {
+		return err
+	}
+
+	dm.peerScores.remove(ip)
+
+	dm.connectionEvents.publish(ConnectionEvent{
+		Type:   ConnectionEventBanned,
+		Time:   dm.now().UTC(),
+		Addr:   ip,
+		Reason: reason,
+	})
+
+	logger.WithFields(logrus.Fields{
+		"ip":       ip,
+		"reason":   reason,
+		"duration": duration,
+	}).Warning("Banned peer")
+
+	for _, c := range dm.connections.all() {
+		if c.State == ConnectionStatePending {
+			continue
+		}
+
+		if connIP, _, err := iputil.SplitAddr(c.Addr); err == nil && connIP == ip {
+			if err := dm.Disconnect(c.Addr, ErrDisconnectIsBlacklisted); err != nil {
+				logger.WithError(err).WithField("addr", c.Addr).Error("Disconnect")
+			}
+		}
+	}
+
+	return nil
+}
+
+// recordBlocksReceived records that a connection sent blocks, in response to the blocks requests,
+// and the latency of the response in the peer's statistics
+func (dm *Daemon) recordBlocksReceived(gnetID uint64) {
+	latency, ok := dm.peerScores.blocksReceived(gnetID, dm.now().UTC())
+	if !ok {
+		return
+	}
+
+	if c := dm.connections.getByGnetID(gnetID); c != nil && c.ListenAddr() != "" {
+		dm.pex.RecordBlockLatency(c.ListenAddr(), latency)
+	}
+}
+
+// checkStalledBlockRequests records a blocks request to the introduced connections that reported a height greater
+// than headSeq, and a misbehavior for the connections that did not respond to the previous request.
+// Pruned peers that no longer have the requested blocks are not expected to respond, and the peers
+// synchronized header-first are not sent blocks requests.
+// A connection is penalized at most once until it sends blocks again.
+func (dm *Daemon) checkStalledBlockRequests(headSeq uint64) {
+	addrs := make(map[uint64]string)
+	var gnetIDs []uint64
+	for _, c := range dm.connections.all() {
+		if !c.HasIntroduced() || c.Height <= headSeq || c.Features.Has(FeatureHeadersFirst) || !canServeBlocks(c, headSeq) {
+			continue
+		}
+
+		addrs[c.gnetID] = c.Addr
+		gnetIDs = append(gnetIDs, c.gnetID)
+	}
+
+	for _, id := range dm.peerScores.requestBlocks(gnetIDs, dm.now().UTC()) {
+		dm.recordPeerMisbehavior(addrs[id], PeerMisbehaviorStalledBlocks)
+	}
+}
+
+// isTrustedIP returns true if a trusted peer has this IP
+func (dm *Daemon) isTrustedIP(ip string) bool {
+	for _, addr := range dm.pex.Trusted().ToAddrs() {
+		if peerIP, _, err := iputil.SplitAddr(addr); err == nil && peerIP == ip {
+			return true
+		}
+	}
+
+	return false
+}
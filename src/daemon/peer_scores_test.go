@@ -0,0 +1,149 @@
+package daemon
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/daemon/gnet"
+	"github.com/skycoin/skycoin/src/daemon/pex"
+)
+
+func TestPeerScoresAdd(t *testing.T) {
+	s := newPeerScores()
+	now := time.Now().UTC()
+
+	require.Equal(t, 10, s.add("1.2.3.4", 10, now, time.Hour))
+	require.Equal(t, 25, s.add("1.2.3.5", 25, now, time.Hour))
+	require.Equal(t, 30, s.add("1.2.3.4", 20, now.Add(time.Minute), time.Hour))
+
+	// The penalties older than the window are removed
+	require.Equal(t, 25, s.add("1.2.3.4", 5, now.Add(time.Hour+time.Second), time.Hour))
+	require.Len(t, s.penalties["1.2.3.4"], 2)
+	_, ok := s.penalties["1.2.3.5"]
+	require.False(t, ok)
+
+	require.Equal(t, 25, s.score("1.2.3.4", now.Add(time.Hour+time.Second), time.Hour))
+	require.Equal(t, 5, s.score("1.2.3.4", now.Add(time.Hour+time.Minute), time.Hour))
+	require.Equal(t, 0, s.score("1.2.3.5", now, time.Hour))
+
+	s.remove("1.2.3.4")
+	require.Empty(t, s.penalties)
+}
+
+func TestPeerScoresRequestBlocks(t *testing.T) {
+	s := newPeerScores()
+	now := time.Now()
+
+	require.Empty(t, s.requestBlocks([]uint64{1, 2, 3}, now))
+
+	// 1 responded, 2 and 3 stalled
+	latency, ok := s.blocksReceived(1, now.Add(time.Second))
+	require.True(t, ok)
+	require.Equal(t, time.Second, latency)
+	require.Equal(t, []uint64{2, 3}, s.requestBlocks([]uint64{1, 2, 3}, now.Add(time.Minute)))
+
+	// The stalled connections are only penalized once, and the connections not requested anymore are forgotten
+	_, ok = s.blocksReceived(1, now.Add(time.Minute*2))
+	require.True(t, ok)
+	require.Empty(t, s.requestBlocks([]uint64{1, 2}, now.Add(time.Minute*3)))
+	require.Equal(t, map[uint64]blockRequest{
+		1: {time: now.Add(time.Minute * 3)},
+		2: {time: now, penalized: true},
+	}, s.blockRequests)
+
+	// The latency is measured from the first request not responded to
+	latency, ok = s.blocksReceived(2, now.Add(time.Minute*4))
+	require.True(t, ok)
+	require.Equal(t, time.Minute*4, latency)
+
+	// Blocks that were not requested have no latency
+	_, ok = s.blocksReceived(2, now.Add(time.Minute*4))
+	require.False(t, ok)
+
+	// A connection that responds again can be penalized again
+	require.Empty(t, s.requestBlocks([]uint64{2}, now))
+	require.Equal(t, []uint64{2}, s.requestBlocks([]uint64{2}, now))
+}
+
+func TestDisconnectReasonMisbehavior(t *testing.T) {
+	m, ok := disconnectReasonMisbehavior(gnet.ErrDisconnectMalformedMessage)
+	require.True(t, ok)
+	require.Equal(t, PeerMisbehaviorInvalidMessage, m)
+
+	m, ok = disconnectReasonMisbehavior(ErrDisconnectNoIntroduction)
+	require.True(t, ok)
+	require.Equal(t, PeerMisbehaviorProtocolViolation, m)
+
+	_, ok = disconnectReasonMisbehavior(ErrDisconnectIdle)
+	require.False(t, ok)
+}
+
+func TestDaemonRecordPeerMisbehavior(t *testing.T) {
+	dir, err := ioutil.TempDir("", "daemon")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cfg := pex.NewConfig()
+	cfg.DataDirectory = dir
+	cfg.AllowLocalhost = true
+	cfg.DefaultConnections = []string{"112.32.32.20:6000"}
+	px, err := pex.New(cfg)
+	require.NoError(t, err)
+
+	dm := &Daemon{
+		Config:           NewDaemonConfig(),
+		pex:              px,
+		connections:      NewConnections(),
+		peerScores:       newPeerScores(),
+		connectionEvents: newConnectionEvents(),
+	}
+
+	events, unsubscribe := dm.connectionEvents.subscribe()
+	defer unsubscribe()
+
+	// The peer is banned once the threshold is reached
+	for i := 0; i < 3; i++ {
+		dm.recordPeerMisbehavior("112.32.32.14:6000", PeerMisbehaviorInvalidMessage)
+		require.False(t, px.IsBanned("112.32.32.14"))
+	}
+	dm.recordPeerMisbehavior("112.32.32.14:7000", PeerMisbehaviorInvalidMessage)
+	require.True(t, px.IsBanned("112.32.32.14"))
+
+	require.Len(t, events, 1)
+	e := <-events
+	require.Equal(t, ConnectionEventBanned, e.Type)
+	require.Equal(t, "112.32.32.14", e.Addr)
+	require.Equal(t, string(PeerMisbehaviorInvalidMessage), e.Reason)
+
+	bans := px.Bans()
+	require.Len(t, bans, 1)
+	require.Equal(t, string(PeerMisbehaviorInvalidMessage), bans[0].Reason)
+	require.Equal(t, bans[0].Created+int64(dm.Config.BanDuration/time.Second), bans[0].Expires)
+	require.Empty(t, dm.peerScores.penalties)
+
+	// Trusted and localhost peers are not banned
+	for i := 0; i < 2; i++ {
+		dm.recordPeerMisbehavior("112.32.32.20:6001", PeerMisbehaviorProtocolViolation)
+		dm.recordPeerMisbehavior("127.0.0.1:6000", PeerMisbehaviorProtocolViolation)
+	}
+	require.False(t, px.IsBanned("112.32.32.20"))
+	require.False(t, px.IsBanned("127.0.0.1"))
+
+	// Whitelisted peers are not banned
+	dm.Config.Whitelist = []string{"2001:db8::1"}
+	for i := 0; i < 2; i++ {
+		dm.recordPeerMisbehavior("[2001:db8::1]:6000", PeerMisbehaviorProtocolViolation)
+	}
+	require.False(t, px.IsBanned("2001:db8::1"))
+
+	// Automatic bans can be disabled
+	dm.Config.BanScoreThreshold = 0
+	for i := 0; i < 2; i++ {
+		dm.recordPeerMisbehavior("112.32.32.15:6000", PeerMisbehaviorProtocolViolation)
+	}
+	require.False(t, px.IsBanned("112.32.32.15"))
+}
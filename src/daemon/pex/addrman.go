@@ -0,0 +1,127 @@
+package pex
+
+import (
+	"encoding/binary"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+const (
+	// newBucketCount is the number of buckets of the table of peers that were never connected to
+	newBucketCount = 1024
+	// triedBucketCount is the number of buckets of the table of peers that were connected to
+	triedBucketCount = 256
+	// bucketSize is the maximum number of peers in a bucket
+	bucketSize = 64
+	// newBucketsPerSourceGroup is the number of new buckets the peers learned from a netgroup can be placed in
+	newBucketsPerSourceGroup = 64
+	// triedBucketsPerGroup is the number of tried buckets the peers of a netgroup can be placed in
+	triedBucketsPerGroup = 8
+	// localNetgroup is the netgroup of loopback addresses
+	localNetgroup = "local"
+)
+
+// Netgroup returns the network group of an ip:port address: the /16 of an IPv4 address, the /32 of an IPv6 address,
+// "local" for a loopback address, and the first character of the hostname of an onion service.
+// Any other host, or the host of a URL, is its own netgroup. An attacker controls the addresses of few netgroups,
+// so the peers are spread across netgroups to resist eclipse attacks
+func Netgroup(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if u, urlErr := url.Parse(addr); urlErr == nil && u.Host != "" {
+		host = u.Hostname()
+	} else if err != nil {
+		host = addr
+	}
+	host = strings.ToLower(host)
+
+	if IsOnionHost(host) {
+		return "onion/" + host[:1]
+	}
+
+	ip := net.ParseIP(host)
+	switch {
+	case ip == nil:
+		return host
+	case ip.IsLoopback():
+		return localNetgroup
+	case ip.To4() != nil:
+		return ip.Mask(net.CIDRMask(16, 32)).String() + "/16"
+	default:
+		return ip.Mask(net.CIDRMask(32, 128)).String() + "/32"
+	}
+}
+
+// addrman places the peers learned from other peers in buckets, so that the peers announced by a few netgroups
+// can't take over the peerlist. Peers that were never connected to are in the new table, in a bucket chosen
+// by their netgroup and the netgroup of their source, so that a source netgroup only reaches newBucketsPerSourceGroup
+// buckets. Peers that were connected to are moved to the tried table, in a bucket chosen by their netgroup.
+// The buckets are chosen with a secret key, so that an attacker can't pick addresses that fall in a given bucket
+type addrman struct {
+	key   cipher.SHA256
+	new   []map[string]struct{}
+	tried []map[string]struct{}
+}
+
+// newAddrman creates an addrman whose bucket key is derived from seed
+func newAddrman(seed []byte) *addrman {
+	return &addrman{
+		key:   cipher.SumSHA256(seed),
+		new:   make([]map[string]struct{}, newBucketCount),
+		tried: make([]map[string]struct{}, triedBucketCount),
+	}
+}
+
+// hash returns a keyed hash of the parts
+func (am *addrman) hash(parts ...string) uint64 {
+	b := append([]byte{}, am.key[:]...)
+	for _, p := range parts {
+		b = append(b, p...)
+		b = append(b, 0)
+	}
+
+	h := cipher.SumSHA256(b)
+	return binary.LittleEndian.Uint64(h[:8])
+}
+
+// bucket returns the bucket of a peer. The peer must have a source
+func (am *addrman) bucket(p Peer) map[string]struct{} {
+	table := am.new
+	var i uint64
+	if p.Tried {
+		n := am.hash("tried", p.Addr) % triedBucketsPerGroup
+		i = am.hash("tried", Netgroup(p.Addr), strconv.FormatUint(n, 10)) % triedBucketCount
+		table = am.tried
+	} else {
+		n := am.hash("new", Netgroup(p.Source), Netgroup(p.Addr)) % newBucketsPerSourceGroup
+		i = am.hash("new", Netgroup(p.Source), strconv.FormatUint(n, 10)) % newBucketCount
+	}
+
+	if table[i] == nil {
+		table[i] = make(map[string]struct{}, bucketSize)
+	}
+	return table[i]
+}
+
+// remove removes a peer from its bucket
+func (am *addrman) remove(p Peer) {
+	if p.Source != "" {
+		delete(am.bucket(p), p.Addr)
+	}
+}
+
+// len returns the number of peers in the new and tried tables
+func (am *addrman) len() (int, int) {
+	count := func(table []map[string]struct{}) int {
+		n := 0
+		for _, b := range table {
+			n += len(b)
+		}
+		return n
+	}
+
+	return count(am.new), count(am.tried)
+}
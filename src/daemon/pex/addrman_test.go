@@ -0,0 +1,176 @@
+package pex
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetgroup(t *testing.T) {
+	cases := []struct {
+		addr     string
+		netgroup string
+	}{
+		{"11.22.33.44:6000", "11.22.0.0/16"},
+		{"11.22.99.1:7000", "11.22.0.0/16"},
+		{"[::ffff:11.22.33.44]:6000", "11.22.0.0/16"},
+		{"[2001:db8:1:2::1]:6000", "2001:db8::/32"},
+		{"127.0.0.1:6000", "local"},
+		{"[::1]:6000", "local"},
+		{"pg6mmjiyjmcrsslvykfwnntlaru7p5svn6y2ymmju6nubxndf4pscryd.onion:6000", "onion/p"},
+		{"seed.skycoin.net", "seed.skycoin.net"},
+		{"https://downloads.skycoin.net/blockchain/peers.txt", "downloads.skycoin.net"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.addr, func(t *testing.T) {
+			require.Equal(t, tc.netgroup, Netgroup(tc.addr))
+		})
+	}
+}
+
+func TestAddrmanBucketSeed(t *testing.T) {
+	p := Peer{Addr: "11.22.33.44:6000", Source: "55.66.77.88:6000"}
+
+	bucketIndex := func(am *addrman) int {
+		am.bucket(p)[p.Addr] = struct{}{}
+		for i, b := range am.new {
+			if _, ok := b[p.Addr]; ok {
+				return i
+			}
+		}
+		return -1
+	}
+
+	// The same seed places the peers in the same buckets
+	require.Equal(t, bucketIndex(newAddrman([]byte("seed"))), bucketIndex(newAddrman([]byte("seed"))))
+	require.NotEqual(t, bucketIndex(newAddrman([]byte("seed"))), bucketIndex(newAddrman([]byte("other seed"))))
+}
+
+func TestPeerlistBuckets(t *testing.T) {
+	pl := newSeededPeerlist([]byte("test"))
+	source := "55.66.77.88:6000"
+
+	// The peers of a netgroup announced by a source netgroup share a bucket
+	for i := 0; i < bucketSize; i++ {
+		require.NoError(t, pl.addPeer(fmt.Sprintf("11.22.%d.%d:6000", i/200, i%200), source))
+	}
+
+	require.Equal(t, ErrBucketFull, pl.addPeer("11.22.99.1:6000", source))
+	require.Equal(t, ErrBucketFull, pl.addPeer("11.22.99.1:6000", "55.66.1.1:6000"))
+	require.False(t, pl.hasPeer("11.22.99.1:6000"))
+
+	// Another source netgroup places the peers in another bucket
+	require.NoError(t, pl.addPeer("11.22.99.1:6000", "99.88.77.66:6000"))
+
+	// Peers that are not learned from the network are not bucketed
+	require.NoError(t, pl.addPeer("11.22.99.2:6000", ""))
+
+	newLen, triedLen := pl.addrman.len()
+	require.Equal(t, bucketSize+1, newLen)
+	require.Equal(t, 0, triedLen)
+
+	// A stale peer is evicted from a full bucket
+	pl.peers["11.22.0.5:6000"].LastSeen = time.Now().UTC().Add(-staleBucketPeerAge * 2).Unix()
+	require.NoError(t, pl.addPeer("11.22.99.4:6000", source))
+	require.False(t, pl.hasPeer("11.22.0.5:6000"))
+	require.True(t, pl.hasPeer("11.22.99.4:6000"))
+
+	// Removing a peer frees its slot
+	pl.removePeer("11.22.0.6:6000")
+	require.NoError(t, pl.addPeer("11.22.99.5:6000", source))
+
+	// A peer that completed the introduction is moved to the tried table
+	pl.recordHandshake("11.22.0.7:6000", false)
+	require.False(t, pl.peers["11.22.0.7:6000"].Tried)
+	pl.recordHandshake("11.22.0.7:6000", true)
+	require.True(t, pl.peers["11.22.0.7:6000"].Tried)
+
+	newLen, triedLen = pl.addrman.len()
+	require.Equal(t, bucketSize, newLen)
+	require.Equal(t, 1, triedLen)
+
+	// Its slot in the new table is free
+	require.NoError(t, pl.addPeer("11.22.99.6:6000", source))
+	require.Equal(t, ErrBucketFull, pl.addPeer("11.22.99.7:6000", source))
+
+	pl.removePeer("11.22.0.7:6000")
+	newLen, triedLen = pl.addrman.len()
+	require.Equal(t, bucketSize+1, newLen)
+	require.Equal(t, 0, triedLen)
+
+	// Trusted peers are placed even in a full bucket
+	pl.setPeers([]Peer{{Addr: "11.22.99.3:6000", Source: source, Trusted: true}})
+	require.True(t, pl.hasPeer("11.22.99.3:6000"))
+
+	newLen, _ = pl.addrman.len()
+	require.Equal(t, bucketSize+2, newLen)
+}
+
+func TestPeerlistTriedBucketFull(t *testing.T) {
+	pl := newSeededPeerlist([]byte("test"))
+
+	// Fill the tried bucket of a peer with peers announced by different sources
+	p := Peer{Addr: "11.22.33.44:6000", Source: "55.66.77.88:6000", Tried: true}
+	b := pl.addrman.bucket(p)
+	for i := 0; len(b) < bucketSize; i++ {
+		addr := fmt.Sprintf("11.22.%d.%d:6000", i/200, i%200)
+		require.NoError(t, pl.addPeer(addr, fmt.Sprintf("%d.1.1.1:6000", 1+i%200)))
+		pl.peers[addr].LastSeen = int64(1000 + i)
+		pl.recordHandshake(addr, true)
+		if _, ok := b[addr]; !ok {
+			pl.removePeer(addr)
+		}
+	}
+
+	oldest := pl.oldestInBucket(b)
+	require.NotNil(t, oldest)
+
+	// The least recently seen peer of the full tried bucket is moved back to the new table
+	require.NoError(t, pl.addPeer(p.Addr, p.Source))
+	pl.recordHandshake(p.Addr, true)
+
+	_, ok := b[p.Addr]
+	require.True(t, ok)
+	require.Len(t, b, bucketSize)
+	require.False(t, pl.peers[oldest.Addr].Tried)
+	_, ok = pl.addrman.bucket(*pl.peers[oldest.Addr])[oldest.Addr]
+	require.True(t, ok)
+}
+
+func TestPexDiversePublic(t *testing.T) {
+	cfg := NewConfig()
+	cfg.AllowLocalhost = true
+	px := &Pex{
+		Config:   cfg,
+		peerlist: newPeerlist(),
+	}
+
+	px.peerlist.setPeers([]Peer{
+		{Addr: "11.22.1.1:6000"},
+		{Addr: "11.22.1.2:6000"},
+		{Addr: "33.44.1.1:6000"},
+		{Addr: "55.66.1.1:6000"},
+		{Addr: "127.0.0.1:6000"},
+		{Addr: "127.0.0.1:6001"},
+		{Addr: "77.88.1.1:6000", Private: true},
+	})
+
+	peers := px.DiversePublic(0, []string{"55.66.9.9:6000"})
+	require.Len(t, peers, 4)
+
+	groups := make(map[string]int)
+	for _, p := range peers {
+		groups[Netgroup(p.Addr)]++
+	}
+	require.Equal(t, map[string]int{
+		"11.22.0.0/16": 1,
+		"33.44.0.0/16": 1,
+		"local":        2,
+	}, groups)
+
+	require.Len(t, px.DiversePublic(2, nil), 2)
+	require.Len(t, px.DiversePublic(0, []string{"11.22.9.9:6000", "33.44.9.9:6000", "55.66.9.9:6000"}), 2)
+}
@@ -0,0 +1,230 @@
+package pex
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/skycoin/skycoin/src/util/file"
+	"github.com/skycoin/skycoin/src/util/iputil"
+)
+
+const (
+	// BansCacheFilename filename for disk-cached bans
+	BansCacheFilename = "bans.json"
+)
+
+var (
+	// ErrNotBanned is returned when unbanning an IP that is not banned
+	ErrNotBanned = errors.New("IP is not banned")
+)
+
+// Ban is a banned peer IP. Connections to and from a banned IP are refused until the ban expires.
+// Bans are by IP, since the port of an incoming connection is not the listening port of the peer
+type Ban struct {
+	IP     string
+	Reason string
+	// Unix timestamp when the ban was created
+	Created int64
+	// Unix timestamp when the ban expires
+	Expires int64
+}
+
+// expired returns true if the ban expired at time t
+func (b Ban) expired(t time.Time) bool {
+	return t.Unix() >= b.Expires
+}
+
+// banIP returns the IP of an ip:port address or an IP. The .onion peers are banned by hostname
+func banIP(addr string) (string, error) {
+	if ip, _, err := iputil.SplitAddr(addr); err == nil {
+		addr = ip
+	}
+
+	addr = whitespaceFilter.ReplaceAllString(addr, "")
+	if IsOnionHost(addr) {
+		return strings.ToLower(addr), nil
+	}
+
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return "", ErrInvalidAddress
+	}
+
+	return ip.String(), nil
+}
+
+// loadCachedBansFile loads bans from the cached bans.json file. Expired bans are skipped
+func loadCachedBansFile(path string) (map[string]Ban, error) {
+	var bansList []Ban
+	err := file.LoadJSON(path, &bansList)
+
+	if os.IsNotExist(err) {
+		logger.WithField("path", path).Info("File does not exist")
+		return nil, nil
+	} else if err == io.EOF {
+		logger.WithField("path", path).Error("Corrupt or empty file")
+		return nil, nil
+	}
+
+	if err != nil {
+		logger.WithField("path", path).WithError(err).Error("Failed to load bans file")
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	bans := make(map[string]Ban, len(bansList))
+	for _, b := range bansList {
+		ip, err := banIP(b.IP)
+		if err != nil {
+			logger.WithError(err).WithField("ip", b.IP).Error("Invalid IP in bans JSON file")
+			continue
+		}
+
+		if b.expired(now) {
+			continue
+		}
+
+		b.IP = ip
+		bans[ip] = b
+	}
+
+	return bans, nil
+}
+
+// loadBans loads the bans from disk
+func (px *Pex) loadBans() error {
+	px.Lock()
+	defer px.Unlock()
+
+	bans, err := loadCachedBansFile(filepath.Join(px.Config.DataDirectory, BansCacheFilename))
+	if err != nil {
+		return err
+	}
+
+	for ip, b := range bans {
+		px.bans[ip] = b
+	}
+
+	return nil
+}
+
+// saveBans saves the bans to disk. The caller must hold the lock
+func (px *Pex) saveBans() error {
+	if err := file.SaveJSON(filepath.Join(px.Config.DataDirectory, BansCacheFilename), px.bansList(), 0600); err != nil {
+		logger.WithError(err).Error("Save bans failed")
+		return err
+	}
+	return nil
+}
+
+// bansList returns the unexpired bans, sorted by IP. The caller must hold the lock
+func (px *Pex) bansList() []Ban {
+	now := time.Now().UTC()
+	bans := make([]Ban, 0, len(px.bans))
+	for _, b := range px.bans {
+		if !b.expired(now) {
+			bans = append(bans, b)
+		}
+	}
+
+	sort.Slice(bans, func(i, j int) bool {
+		return bans[i].IP < bans[j].IP
+	})
+
+	return bans
+}
+
+// Ban bans the IP of an address for the duration. An address of the form ip:port or an IP is accepted.
+// The untrusted, public peers with this IP are removed from the peer list.
+// Banning an IP that is already banned replaces the ban
+func (px *Pex) Ban(addr, reason string, duration time.Duration) error {
+	px.Lock()
+	defer px.Unlock()
+
+	ip, err := banIP(addr)
+	if err != nil {
+		logger.WithError(err).WithField("addr", addr).Error("Invalid address")
+		return ErrInvalidAddress
+	}
+
+	now := time.Now().UTC()
+	px.bans[ip] = Ban{
+		IP:      ip,
+		Reason:  reason,
+		Created: now.Unix(),
+		Expires: now.Add(duration).Unix(),
+	}
+
+	for a, p := range px.peerlist.peers {
+		if p.Trusted || p.Private {
+			continue
+		}
+		if peerIP, _, err := iputil.SplitAddr(a); err == nil && peerIP == ip {
+			px.peerlist.removePeer(a)
+		}
+	}
+
+	return px.saveBans()
+}
+
+// Unban removes the ban of the IP of an address. Returns ErrNotBanned if the IP is not banned
+func (px *Pex) Unban(addr string) error {
+	px.Lock()
+	defer px.Unlock()
+
+	ip, err := banIP(addr)
+	if err != nil {
+		return ErrInvalidAddress
+	}
+
+	if b, ok := px.bans[ip]; !ok || b.expired(time.Now().UTC()) {
+		return ErrNotBanned
+	}
+
+	delete(px.bans, ip)
+
+	return px.saveBans()
+}
+
+// IsBanned returns true if the IP of an address is banned
+func (px *Pex) IsBanned(addr string) bool {
+	px.RLock()
+	defer px.RUnlock()
+	return px.isBanned(addr)
+}
+
+func (px *Pex) isBanned(addr string) bool {
+	ip, err := banIP(addr)
+	if err != nil {
+		return false
+	}
+
+	b, ok := px.bans[ip]
+	return ok && !b.expired(time.Now().UTC())
+}
+
+// Bans returns the unexpired bans, sorted by IP
+func (px *Pex) Bans() []Ban {
+	px.RLock()
+	defer px.RUnlock()
+	return px.bansList()
+}
+
+// clearExpiredBans removes the expired bans
+func (px *Pex) clearExpiredBans() {
+	px.Lock()
+	defer px.Unlock()
+
+	now := time.Now().UTC()
+	for ip, b := range px.bans {
+		if b.expired(now) {
+			delete(px.bans, ip)
+		}
+	}
+}
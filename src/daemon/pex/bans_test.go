@@ -0,0 +1,139 @@
+package pex
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/util/file"
+)
+
+func TestPexBans(t *testing.T) {
+	dir, err := ioutil.TempDir("", "peerlist")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cfg := NewConfig()
+	cfg.DataDirectory = dir
+
+	px, err := New(cfg)
+	require.NoError(t, err)
+	require.Empty(t, px.Bans())
+
+	// Peers with the banned IP are removed, unless trusted or private
+	require.NoError(t, px.AddPeer("112.32.32.14:6000"))
+	require.NoError(t, px.AddPeer("112.32.32.14:7000"))
+	require.NoError(t, px.AddPeer("112.32.32.14:8000"))
+	require.NoError(t, px.AddPeer("112.32.32.15:6000"))
+	require.NoError(t, px.SetTrusted("112.32.32.14:7000"))
+	require.NoError(t, px.SetPrivate("112.32.32.14:8000", true))
+
+	require.Equal(t, ErrInvalidAddress, px.Ban("112.32.32", "test", time.Hour))
+
+	err = px.Ban("112.32.32.14:6000", "invalid_message", time.Hour)
+	require.NoError(t, err)
+
+	_, ok := px.GetPeer("112.32.32.14:6000")
+	require.False(t, ok)
+	_, ok = px.GetPeer("112.32.32.14:7000")
+	require.True(t, ok)
+	_, ok = px.GetPeer("112.32.32.14:8000")
+	require.True(t, ok)
+	_, ok = px.GetPeer("112.32.32.15:6000")
+	require.True(t, ok)
+
+	// The ban applies to every port of the IP
+	require.True(t, px.IsBanned("112.32.32.14"))
+	require.True(t, px.IsBanned("112.32.32.14:9000"))
+	require.False(t, px.IsBanned("112.32.32.15:6000"))
+	require.False(t, px.IsBanned("invalid"))
+
+	// Banned peers are not added to the peer list
+	require.Equal(t, ErrBlacklistedAddress, px.AddPeer("112.32.32.14:6000"))
+	require.Equal(t, 1, px.AddPeers([]string{"112.32.32.14:6000", "112.32.32.16:6000"}))
+	_, ok = px.GetPeer("112.32.32.16:6000")
+	require.True(t, ok)
+
+	err = px.Ban("112.32.32.17", "stalled_blocks", time.Hour)
+	require.NoError(t, err)
+
+	bans := px.Bans()
+	require.Len(t, bans, 2)
+	require.Equal(t, "112.32.32.14", bans[0].IP)
+	require.Equal(t, "invalid_message", bans[0].Reason)
+	require.Equal(t, bans[0].Created+int64(time.Hour/time.Second), bans[0].Expires)
+	require.Equal(t, "112.32.32.17", bans[1].IP)
+
+	// The bans are persisted
+	px2, err := New(cfg)
+	require.NoError(t, err)
+	require.Equal(t, bans, px2.Bans())
+
+	require.NoError(t, px2.Unban("112.32.32.17"))
+	require.Equal(t, ErrNotBanned, px2.Unban("112.32.32.17"))
+	require.Equal(t, ErrInvalidAddress, px2.Unban("invalid"))
+	require.False(t, px2.IsBanned("112.32.32.17"))
+
+	px3, err := New(cfg)
+	require.NoError(t, err)
+	require.Equal(t, bans[:1], px3.Bans())
+
+	// Expired bans are ignored, and skipped when loaded
+	px3.bans["112.32.32.18"] = Ban{
+		IP:      "112.32.32.18",
+		Created: time.Now().Add(-2 * time.Hour).Unix(),
+		Expires: time.Now().Add(-time.Hour).Unix(),
+	}
+	require.False(t, px3.IsBanned("112.32.32.18"))
+	require.Equal(t, bans[:1], px3.Bans())
+	require.Equal(t, ErrNotBanned, px3.Unban("112.32.32.18"))
+
+	err = file.SaveJSON(filepath.Join(dir, BansCacheFilename), []Ban{bans[0], px3.bans["112.32.32.18"]}, 0600)
+	require.NoError(t, err)
+
+	loaded, err := loadCachedBansFile(filepath.Join(dir, BansCacheFilename))
+	require.NoError(t, err)
+	require.Equal(t, map[string]Ban{
+		"112.32.32.14": bans[0],
+	}, loaded)
+
+	px3.clearExpiredBans()
+	require.Len(t, px3.bans, 1)
+}
+
+func TestPexBanOnion(t *testing.T) {
+	dir, err := ioutil.TempDir("", "peerlist")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cfg := NewConfig()
+	cfg.DataDirectory = dir
+	cfg.AllowOnion = true
+
+	px, err := New(cfg)
+	require.NoError(t, err)
+
+	onion := "pg6mmjiyjmcrsslvykfwnntlaru7p5svn6y2ymmju6nubxndf4pscryd.onion"
+	require.NoError(t, px.AddPeer(onion+":6000"))
+
+	// .onion peers are banned by hostname
+	err = px.Ban(strings.ToUpper(onion)+":6000", "invalid_message", time.Hour)
+	require.NoError(t, err)
+
+	require.True(t, px.IsBanned(onion+":7000"))
+	_, ok := px.GetPeer(onion + ":6000")
+	require.False(t, ok)
+	require.Equal(t, ErrBlacklistedAddress, px.AddPeer(onion+":6000"))
+
+	bans := px.Bans()
+	require.Len(t, bans, 1)
+	require.Equal(t, onion, bans[0].IP)
+
+	require.NoError(t, px.Unban(onion))
+	require.False(t, px.IsBanned(onion+":6000"))
+}
@@ -6,10 +6,12 @@ import (
 	"io"
 	"math/rand"
 	"os"
+	"sort"
 	"time"
 
 	"github.com/sirupsen/logrus"
 
+	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/util/file"
 	"github.com/skycoin/skycoin/src/util/useragent"
 )
@@ -29,11 +31,20 @@ func (ps Peers) ToAddrs() []string {
 // peerlist is a map of addresses to *PeerStates
 type peerlist struct {
 	peers map[string]*Peer
+	// Buckets of the peers that have a source
+	addrman *addrman
 }
 
+// newPeerlist creates a peerlist with a random bucket key
 func newPeerlist() peerlist {
+	return newSeededPeerlist(cipher.RandByte(32))
+}
+
+// newSeededPeerlist creates a peerlist whose bucket key is derived from seed
+func newSeededPeerlist(seed []byte) peerlist {
 	return peerlist{
-		peers: make(map[string]*Peer),
+		peers:   make(map[string]*Peer),
+		addrman: newAddrman(seed),
 	}
 }
 
@@ -65,7 +76,7 @@ func loadCachedPeersFile(path string) (map[string]*Peer, error) {
 			"path": path,
 		}
 
-		a, err := validateAddress(addr, true)
+		a, err := validateAddress(addr, true, true)
 
 		if err != nil {
 			logger.WithError(err).WithFields(fields).Error("Invalid address in peers JSON file")
@@ -93,29 +104,119 @@ func loadCachedPeersFile(path string) (map[string]*Peer, error) {
 func (pl *peerlist) setPeers(peers []Peer) {
 	for _, p := range peers {
 		np := p
+		if old, ok := pl.peers[p.Addr]; ok {
+			pl.addrman.remove(*old)
+		}
+		delete(pl.peers, p.Addr)
+
+		if err := pl.place(&np); err != nil {
+			logger.WithError(err).WithField("addr", p.Addr).Warning("Peer not added to the peerlist")
+			continue
+		}
 		pl.peers[p.Addr] = &np
 	}
 }
 
+// place puts a peer in its bucket. Peers without a source are not bucketed. If the bucket is full,
+// the least recently seen peer of the bucket is evicted if it is stale, otherwise ErrBucketFull is returned.
+// Trusted and private peers are placed even in a full bucket
+func (pl *peerlist) place(p *Peer) error {
+	if p.Source == "" {
+		return nil
+	}
+
+	b := pl.addrman.bucket(*p)
+	if len(b) >= bucketSize && !p.Trusted && !p.Private {
+		oldest := pl.oldestInBucket(b)
+		if oldest == nil || !oldest.isStale(time.Now().UTC()) {
+			return ErrBucketFull
+		}
+		pl.removePeer(oldest.Addr)
+	}
+
+	b[p.Addr] = struct{}{}
+	return nil
+}
+
+// oldestInBucket returns the least recently seen peer of a bucket that is neither trusted nor private
+func (pl *peerlist) oldestInBucket(b map[string]struct{}) *Peer {
+	var oldest *Peer
+	for addr := range b {
+		p := pl.peers[addr]
+		if p.Trusted || p.Private {
+			continue
+		}
+
+		if oldest == nil || p.LastSeen < oldest.LastSeen {
+			oldest = p
+		}
+	}
+	return oldest
+}
+
+// markTried moves a peer that was connected to from the new table to the tried table. If its tried bucket
+// is full, the least recently seen peer of the bucket is moved back to the new table
+func (pl *peerlist) markTried(p *Peer) {
+	if p.Tried {
+		return
+	}
+
+	if p.Source == "" {
+		p.Tried = true
+		return
+	}
+
+	pl.addrman.remove(*p)
+	p.Tried = true
+
+	b := pl.addrman.bucket(*p)
+	if len(b) >= bucketSize {
+		if oldest := pl.oldestInBucket(b); oldest != nil {
+			delete(b, oldest.Addr)
+			oldest.Tried = false
+			if err := pl.place(oldest); err != nil {
+				delete(pl.peers, oldest.Addr)
+			}
+		}
+	}
+
+	b[p.Addr] = struct{}{}
+}
+
 func (pl *peerlist) hasPeer(addr string) bool {
 	p, ok := pl.peers[addr]
 	return ok && p != nil
 }
 
-func (pl *peerlist) addPeer(addr string) {
+// addPeer adds a peer learned from source. Source is empty for the peers that were not learned
+// from the network, which are not bucketed
+func (pl *peerlist) addPeer(addr, source string) error {
 	if p, ok := pl.peers[addr]; ok && p != nil {
 		p.Seen()
-		return
+		return nil
 	}
 
 	peer := NewPeer(addr)
+	peer.Source = source
+	if err := pl.place(peer); err != nil {
+		return err
+	}
+
 	pl.peers[addr] = peer
+	return nil
 }
 
-func (pl *peerlist) addPeers(addrs []string) {
+// addPeers adds peers learned from source, and returns the number of peers added
+func (pl *peerlist) addPeers(addrs []string, source string) int {
+	n := 0
 	for _, addr := range addrs {
-		pl.addPeer(addr)
+		if err := pl.addPeer(addr, source); err != nil {
+			logger.WithError(err).WithField("addr", addr).Debug("Peer not added to the peerlist")
+			continue
+		}
+		n++
 	}
+	return n
 }
 
 func (pl *peerlist) seen(addr string) {
@@ -186,6 +287,9 @@ var isExchangeable = []Filter{hasIncomingPort, isPublic}
 
 // removePeer removes peer
 func (pl *peerlist) removePeer(addr string) {
+	if p, ok := pl.peers[addr]; ok {
+		pl.addrman.remove(*p)
+	}
 	delete(pl.peers, addr)
 }
 
@@ -258,7 +362,7 @@ func (pl *peerlist) clearOld(timeAgo time.Duration) {
 	for addr, peer := range pl.peers {
 		lastSeen := time.Unix(peer.LastSeen, 0)
 		if !peer.Private && !peer.Trusted && t.Sub(lastSeen) > timeAgo {
-			delete(pl.peers, addr)
+			pl.removePeer(addr)
 		}
 	}
 }
@@ -284,6 +388,26 @@ func (pl *peerlist) random(count int, flts []Filter) Peers {
 	return ps
 }
 
+// Returns n peers, or all of the peers, whichever is lower, ordered by score.
+// Peers with the same score are in random order.
+// If count is 0, all of the peers are returned.
+func (pl *peerlist) best(count int, flts []Filter) Peers {
+	ps := pl.getCanTryPeers(flts)
+	rand.Shuffle(len(ps), func(i, j int) {
+		ps[i], ps[j] = ps[j], ps[i]
+	})
+
+	sort.SliceStable(ps, func(i, j int) bool {
+		return ps[i].Score() > ps[j].Score()
+	})
+
+	if count != 0 && count < len(ps) {
+		ps = ps[:count]
+	}
+
+	return ps
+}
+
 // save saves known peers to disk as a newline delimited list of addresses to
 // <dir><PeerCacheFilename>
 func (pl *peerlist) save(fn string) error {
@@ -317,6 +441,52 @@ func (pl *peerlist) resetRetryTimes(addr string) {
 	}
 }
 
+// setProtocolVersion sets a peer's protocol version
+func (pl *peerlist) setProtocolVersion(addr string, version int32) {
+	if p, ok := pl.peers[addr]; ok {
+		p.ProtocolVersion = version
+	}
+}
+
+// setFeatures sets a peer's feature bits
+func (pl *peerlist) setFeatures(addr string, features uint64) {
+	if p, ok := pl.peers[addr]; ok {
+		p.Features = features
+	}
+}
+
+// setTransportKey sets a peer's encrypted transport key
+func (pl *peerlist) setTransportKey(addr string, key cipher.PubKey) {
+	if p, ok := pl.peers[addr]; ok {
+		p.TransportKey = key
+	}
+}
+
+// recordHandshake records an outgoing connection attempt to a peer. A peer that completed
+// the introduction is moved to the tried table
+func (pl *peerlist) recordHandshake(addr string, succeeded bool) {
+	if p, ok := pl.peers[addr]; ok {
+		p.RecordHandshake(succeeded)
+		if succeeded {
+			pl.markTried(p)
+		}
+	}
+}
+
+// recordUptime adds to the uptime of a peer
+func (pl *peerlist) recordUptime(addr string, d time.Duration) {
+	if p, ok := pl.peers[addr]; ok {
+		p.Uptime += d
+	}
+}
+
+// recordBlockLatency records the latency of a blocks response of a peer
+func (pl *peerlist) recordBlockLatency(addr string, latency time.Duration) {
+	if p, ok := pl.peers[addr]; ok {
+		p.RecordBlockLatency(latency)
+	}
+}
+
 // resetAllRetryTimes reset all peers' retry times
 func (pl *peerlist) resetAllRetryTimes() {
 	logger.Info("Reset all peer's retry times")
@@ -358,10 +528,29 @@ type PeerJSON struct {
 	HasIncomePort   *bool `json:"HasIncomePort,omitempty"` // Whether this peer has incoming port [DEPRECATED]
 	HasIncomingPort *bool // Whether this peer has incoming port
 	UserAgent       useragent.Data
+
+	// Peer statistics, used to prioritize the outgoing connections
+	ProtocolVersion    int32         `json:",omitempty"`
+	Uptime             time.Duration `json:",omitempty"`
+	HandshakeAttempts  int           `json:",omitempty"`
+	HandshakeSuccesses int           `json:",omitempty"`
+	BlockResponses     int           `json:",omitempty"`
+	BlockLatency       time.Duration `json:",omitempty"`
+	Features           uint64        `json:",omitempty"`
+	TransportKey       string        `json:",omitempty"`
+
+	// Address placement
+	Source string `json:",omitempty"`
+	Tried  bool   `json:",omitempty"`
 }
 
 // newPeerJSON returns a PeerJSON from a Peer
 func newPeerJSON(p Peer) PeerJSON {
+	var transportKey string
+	if !p.TransportKey.Null() {
+		transportKey = p.TransportKey.Hex()
+	}
+
 	return PeerJSON{
 		Addr:            p.Addr,
 		LastSeen:        p.LastSeen,
@@ -369,6 +558,18 @@ func newPeerJSON(p Peer) PeerJSON {
 		Trusted:         p.Trusted,
 		HasIncomingPort: &p.HasIncomingPort,
 		UserAgent:       p.UserAgent,
+
+		ProtocolVersion:    p.ProtocolVersion,
+		Uptime:             p.Uptime,
+		HandshakeAttempts:  p.HandshakeAttempts,
+		HandshakeSuccesses: p.HandshakeSuccesses,
+		BlockResponses:     p.BlockResponses,
+		BlockLatency:       p.BlockLatency,
+		Features:           p.Features,
+		TransportKey:       transportKey,
+
+		Source: p.Source,
+		Tried:  p.Tried,
 	}
 }
 
@@ -401,11 +602,19 @@ func newPeerFromJSON(p PeerJSON) (*Peer, error) {
 		return nil, fmt.Errorf("Invalid type %T for LastSeen field", p.LastSeen)
 	}
 
-	addr, err := validateAddress(p.Addr, true)
+	addr, err := validateAddress(p.Addr, true, true)
 	if err != nil {
 		return nil, err
 	}
 
+	var transportKey cipher.PubKey
+	if p.TransportKey != "" {
+		transportKey, err = cipher.PubKeyFromHex(p.TransportKey)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid TransportKey: %v", err)
+		}
+	}
+
 	return &Peer{
 		Addr:            addr,
 		LastSeen:        lastSeen,
@@ -413,5 +622,17 @@ func newPeerFromJSON(p PeerJSON) (*Peer, error) {
 		Trusted:         p.Trusted,
 		HasIncomingPort: hasIncomingPort,
 		UserAgent:       p.UserAgent,
+
+		ProtocolVersion:    p.ProtocolVersion,
+		Uptime:             p.Uptime,
+		HandshakeAttempts:  p.HandshakeAttempts,
+		HandshakeSuccesses: p.HandshakeSuccesses,
+		BlockResponses:     p.BlockResponses,
+		BlockLatency:       p.BlockLatency,
+		Features:           p.Features,
+		TransportKey:       transportKey,
+
+		Source: p.Source,
+		Tried:  p.Tried,
 	}, nil
 }
@@ -11,6 +11,7 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/util/file"
 )
 
@@ -206,7 +207,7 @@ func TestPeerlistAddPeer(t *testing.T) {
 			}
 
 			// add peer
-			pl.addPeer(tc.addPeer)
+			pl.addPeer(tc.addPeer, "")
 
 			require.Equal(t, len(tc.expectPeers), len(pl.peers))
 			for k, v := range tc.expectPeers {
@@ -267,7 +268,7 @@ func TestPeerlistAddPeers(t *testing.T) {
 			pl.setPeers(tc.initPeers)
 
 			// add peers
-			pl.addPeers(tc.addPeers)
+			pl.addPeers(tc.addPeers, "")
 
 			require.Equal(t, len(tc.expectPeers), len(pl.peers))
 			for k, v := range tc.expectPeers {
@@ -503,6 +504,35 @@ func TestPeerlistSave(t *testing.T) {
 				testPeers[1]: {Addr: testPeers[1]},
 			},
 		},
+		{
+			"save peer statistics",
+			[]Peer{
+				{
+					Addr:               testPeers[0],
+					ProtocolVersion:    4,
+					Uptime:             time.Hour,
+					HandshakeAttempts:  3,
+					HandshakeSuccesses: 2,
+					BlockResponses:     5,
+					BlockLatency:       time.Millisecond * 250,
+					Features:           3,
+					TransportKey:       testTransportKey,
+				},
+			},
+			map[string]Peer{
+				testPeers[0]: {
+					Addr:               testPeers[0],
+					ProtocolVersion:    4,
+					Uptime:             time.Hour,
+					HandshakeAttempts:  3,
+					HandshakeSuccesses: 2,
+					BlockResponses:     5,
+					BlockLatency:       time.Millisecond * 250,
+					Features:           3,
+					TransportKey:       testTransportKey,
+				},
+			},
+		},
 	}
 
 	for _, tc := range tt {
@@ -547,6 +577,56 @@ func TestPeerCanTry(t *testing.T) {
 	}
 }
 
+func TestPeerScore(t *testing.T) {
+	// A peer without statistics has a neutral score
+	require.Equal(t, 0.5, (&Peer{}).Score())
+
+	p := &Peer{}
+	p.RecordHandshake(true)
+	p.RecordHandshake(true)
+	p.RecordHandshake(false)
+	p.RecordHandshake(true)
+	require.Equal(t, 4, p.HandshakeAttempts)
+	require.Equal(t, 3, p.HandshakeSuccesses)
+
+	p.Uptime = maxScoredUptime / 2
+	require.Equal(t, 0.75*0.5+0.5*0.25+0.5*0.25, p.Score())
+
+	p.RecordBlockLatency(time.Second * 10)
+	require.Equal(t, time.Second*10, p.BlockLatency)
+	p.RecordBlockLatency(time.Second * 20)
+	require.Equal(t, time.Second*12, p.BlockLatency)
+	require.Equal(t, 2, p.BlockResponses)
+	require.InDelta(t, 0.75*0.5+0.5*0.25+0.6*0.25, p.Score(), 1e-9)
+
+	// The uptime and latency scores are capped
+	p.Uptime = maxScoredUptime * 2
+	p.BlockLatency = maxScoredBlockLatency * 2
+	require.Equal(t, 0.75*0.5+0.25, p.Score())
+
+	// Never connecting is worse than an unknown peer
+	p = &Peer{}
+	p.RecordHandshake(false)
+	require.True(t, p.Score() < 0.5)
+}
+
+func TestPeerlistBest(t *testing.T) {
+	pl := newPeerlist()
+	pl.setPeers([]Peer{
+		{Addr: testPeers[0], HandshakeAttempts: 4, HandshakeSuccesses: 1},
+		{Addr: testPeers[1]},
+		{Addr: testPeers[2], HandshakeAttempts: 2, HandshakeSuccesses: 2, Uptime: maxScoredUptime},
+		{Addr: testPeers[3], Private: true, HandshakeAttempts: 2, HandshakeSuccesses: 2, Uptime: maxScoredUptime},
+	})
+
+	require.Equal(t, []string{testPeers[2], testPeers[1], testPeers[0]}, pl.best(0, []Filter{isPublic}).ToAddrs())
+	require.Equal(t, []string{testPeers[2], testPeers[1]}, pl.best(2, []Filter{isPublic}).ToAddrs())
+	require.Equal(t, []string{testPeers[3]}, pl.best(2, []Filter{isPrivate}).ToAddrs())
+
+	pl = newPeerlist()
+	require.Empty(t, pl.best(2, nil))
+}
+
 func TestPeerJSONParsing(t *testing.T) {
 	// The serialized peer json format changed,
 	// this tests that the old format can still parse.
@@ -594,6 +674,8 @@ func TestPeerJSONParsing(t *testing.T) {
 	check(p)
 }
 
+var testTransportKey = cipher.MustPubKeyFromSecKey(cipher.MustSecKeyFromHex("a7e5ad6b1f4d7b5e1e4a4b3c2d1e0f9a8b7c6d5e4f3a2b1c0d9e8f7a6b5c4d3e"))
+
 func peersEqualWithSeenAllowedDiff(t *testing.T, expected Peer, actual Peer) {
 	require.WithinDuration(t, time.Unix(expected.LastSeen, 0), time.Unix(actual.LastSeen, 0), 1*time.Second)
 	expected.LastSeen = actual.LastSeen
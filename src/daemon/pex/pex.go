@@ -2,6 +2,7 @@
 package pex
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -12,6 +13,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -20,7 +22,9 @@ import (
 	"github.com/cenkalti/backoff"
 	"github.com/sirupsen/logrus"
 
+	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/util/logging"
+	"github.com/skycoin/skycoin/src/util/socks5"
 	"github.com/skycoin/skycoin/src/util/useragent"
 )
 
@@ -40,6 +44,20 @@ const (
 	oldPeerCacheFilename = "peers.txt"
 	// MaxPeerRetryTimes is the maximum number of times to retry a peer
 	MaxPeerRetryTimes = 10
+	// proxyDialTimeout is the timeout for connecting to the peers list host through the proxy
+	proxyDialTimeout = time.Second * 30
+	// dnsSeedTimeout is the timeout for resolving a DNS seed
+	dnsSeedTimeout = time.Second * 30
+	// signedPeerListSigSuffix is appended to the signed peers list URL to download its signature
+	signedPeerListSigSuffix = ".sig"
+	// blockLatencyWeight is the weight of a new sample in the moving average of the block latency
+	blockLatencyWeight = 0.2
+	// maxScoredUptime is the uptime from which a peer gets the full uptime score
+	maxScoredUptime = time.Hour * 24
+	// maxScoredBlockLatency is the block latency from which a peer gets no latency score
+	maxScoredBlockLatency = time.Second * 30
+	// staleBucketPeerAge is how long a peer must not have been seen to be evicted from a full bucket
+	staleBucketPeerAge = time.Hour * 24
 )
 
 var (
@@ -53,8 +71,14 @@ var (
 	ErrNotExternalIP = errors.New("IP is not a valid external IP")
 	// ErrPortTooLow is returned if a port is less than 1024
 	ErrPortTooLow = errors.New("Port must be >= 1024")
-	// ErrBlacklistedAddress returned when attempting to add a blacklisted peer
+	// ErrBlacklistedAddress returned when attempting to add a banned peer
 	ErrBlacklistedAddress = errors.New("Blacklisted address")
+	// ErrOnionNotAllowed is returned if .onion addresses are not allowed, which requires a proxy
+	ErrOnionNotAllowed = errors.New(".onion address is not allowed")
+	// ErrBucketFull is returned when the bucket of a peer is full of peers that were seen recently
+	ErrBucketFull = errors.New("Peer bucket full")
+	// ErrPeerNotFound is returned when removing a peer that is not in the peer list
+	ErrPeerNotFound = errors.New("Peer not found")
 
 	// Logging. See http://godoc.org/github.com/op/go-logging for
 	// instructions on how to include this log's output
@@ -63,28 +87,42 @@ var (
 	rnum = rand.New(rand.NewSource(time.Now().Unix()))
 	// For removing inadvertent whitespace from addresses
 	whitespaceFilter = regexp.MustCompile(`\s`)
+	// Tor version 3 onion service hostnames, 56 base32 characters
+	onionHostRegex = regexp.MustCompile(`^[a-z2-7]{56}\.onion$`)
+	// Resolves the A and AAAA records of a DNS seed
+	lookupIPAddr = net.DefaultResolver.LookupIPAddr
 )
 
-// validateAddress returns a sanitized address if valid, otherwise an error
-func validateAddress(ipPort string, allowLocalhost bool) (string, error) {
+// validateAddress returns a sanitized address if valid, otherwise an error.
+// The address is of the form ip:port for IPv4, [ip]:port for IPv6, or host:port for the hostname
+// of a Tor onion service if allowOnion is true. IPv6 addresses are returned in their canonical form
+func validateAddress(ipPort string, allowLocalhost, allowOnion bool) (string, error) {
 	ipPort = whitespaceFilter.ReplaceAllString(ipPort, "")
-	pts := strings.Split(ipPort, ":")
-	if len(pts) != 2 {
+	host, portStr, err := net.SplitHostPort(ipPort)
+	if err != nil {
 		return "", ErrInvalidAddress
 	}
 
-	ip := net.ParseIP(pts[0])
-	if ip == nil {
-		return "", ErrInvalidAddress
-	} else if ip.IsLoopback() {
-		if !allowLocalhost {
-			return "", ErrNoLocalhost
+	if IsOnionHost(host) {
+		if !allowOnion {
+			return "", ErrOnionNotAllowed
+		}
+		host = strings.ToLower(host)
+	} else {
+		ip := net.ParseIP(host)
+		if ip == nil {
+			return "", ErrInvalidAddress
+		} else if ip.IsLoopback() {
+			if !allowLocalhost {
+				return "", ErrNoLocalhost
+			}
+		} else if !ip.IsGlobalUnicast() {
+			return "", ErrNotExternalIP
 		}
-	} else if !ip.IsGlobalUnicast() {
-		return "", ErrNotExternalIP
+		host = ip.String()
 	}
 
-	port, err := strconv.ParseUint(pts[1], 10, 16)
+	port, err := strconv.ParseUint(portStr, 10, 16)
 	if err != nil {
 		return "", ErrInvalidAddress
 	}
@@ -93,18 +131,65 @@ func validateAddress(ipPort string, allowLocalhost bool) (string, error) {
 		return "", ErrPortTooLow
 	}
 
-	return ipPort, nil
+	return net.JoinHostPort(host, strconv.FormatUint(port, 10)), nil
+}
+
+// IsOnionHost returns true if host is the hostname of a Tor (version 3) onion service
+func IsOnionHost(host string) bool {
+	return onionHostRegex.MatchString(strings.ToLower(host))
+}
+
+// IPFamily is the IP address family of a peer address
+type IPFamily string
+
+const (
+	// IPFamilyIPv4 IPv4 addresses
+	IPFamilyIPv4 IPFamily = "ipv4"
+	// IPFamilyIPv6 IPv6 addresses
+	IPFamilyIPv6 IPFamily = "ipv6"
+)
+
+// AddrIPFamily returns the IP family of an ip:port or [ip]:port address.
+// Returns an empty IPFamily if the host is not an IP, e.g. a .onion hostname.
+// IPv4-mapped IPv6 addresses are IPv4 addresses.
+func AddrIPFamily(addr string) IPFamily {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return ""
+	}
+
+	ip := net.ParseIP(host)
+	switch {
+	case ip == nil:
+		return ""
+	case ip.To4() != nil:
+		return IPFamilyIPv4
+	default:
+		return IPFamilyIPv6
+	}
 }
 
 // Peer represents a known peer
 type Peer struct {
-	Addr            string         // An address of the form ip:port
+	Addr            string         // An address of the form ip:port, or [ip]:port for IPv6
 	LastSeen        int64          // Unix timestamp when this peer was last seen
 	Private         bool           // Whether it should omitted from public requests
 	Trusted         bool           // Whether this peer is trusted
 	HasIncomingPort bool           // Whether this peer has accessible public port
 	UserAgent       useragent.Data // Peer's last reported user agent
 	RetryTimes      int            `json:"-"` // records the retry times
+
+	ProtocolVersion    int32         // Peer's last reported protocol version
+	Uptime             time.Duration // Total time connected to this peer
+	HandshakeAttempts  int           // Number of outgoing connections attempted to this peer
+	HandshakeSuccesses int           // Number of outgoing connections to this peer that completed the introduction
+	BlockResponses     int           // Number of blocks responses received from this peer
+	BlockLatency       time.Duration // Moving average of the latency of the blocks responses of this peer
+	Features           uint64        // Feature bits last negotiated with this peer
+	TransportKey       cipher.PubKey `json:"-"` // Static key of the encrypted transport of this peer, learned on the first encrypted connection to it
+
+	Source string // Address of the peer or host this peer was learned from, empty if it was not learned from the network
+	Tried  bool   // Whether an outgoing connection to this peer completed the introduction
 }
 
 // NewPeer returns a *Peer initialized by an address string of the form ip:port
@@ -151,6 +236,54 @@ func (peer *Peer) CanTry() bool {
 	return now-peer.LastSeen > t
 }
 
+// isStale returns true if the peer can be evicted from a full bucket
+func (peer *Peer) isStale(now time.Time) bool {
+	if peer.Trusted || peer.Private {
+		return false
+	}
+
+	return peer.RetryTimes > MaxPeerRetryTimes || now.Sub(time.Unix(peer.LastSeen, 0)) > staleBucketPeerAge
+}
+
+// RecordHandshake records an outgoing connection attempt, and whether it completed the introduction
+func (peer *Peer) RecordHandshake(succeeded bool) {
+	peer.HandshakeAttempts++
+	if succeeded {
+		peer.HandshakeSuccesses++
+	}
+}
+
+// RecordBlockLatency adds the latency of a blocks response to the moving average of the block latency
+func (peer *Peer) RecordBlockLatency(latency time.Duration) {
+	if peer.BlockResponses == 0 {
+		peer.BlockLatency = latency
+	} else {
+		peer.BlockLatency += time.Duration(blockLatencyWeight * float64(latency-peer.BlockLatency))
+	}
+	peer.BlockResponses++
+}
+
+// Score returns the reputation of the peer, between 0 and 1, from its handshake success rate, uptime
+// and block latency. Peers without statistics have a neutral score of 0.5
+func (peer *Peer) Score() float64 {
+	handshakes := 0.5
+	if peer.HandshakeAttempts != 0 {
+		handshakes = float64(peer.HandshakeSuccesses) / float64(peer.HandshakeAttempts)
+	}
+
+	uptime := 0.5
+	if peer.HandshakeSuccesses != 0 {
+		uptime = math.Min(float64(peer.Uptime)/float64(maxScoredUptime), 1)
+	}
+
+	latency := 0.5
+	if peer.BlockResponses != 0 {
+		latency = 1 - math.Min(float64(peer.BlockLatency)/float64(maxScoredBlockLatency), 1)
+	}
+
+	return handshakes*0.5 + uptime*0.25 + latency*0.25
+}
+
 // String returns the peer address
 func (peer *Peer) String() string {
 	return peer.Addr
@@ -168,7 +301,7 @@ type Config struct {
 	CullRate time.Duration
 	// clear old peers on this interval
 	ClearOldRate time.Duration
-	// How often to clear expired blacklist entries
+	// How often to clear expired bans
 	UpdateBlacklistRate time.Duration
 	// How often to request peers via PEX
 	RequestRate time.Duration
@@ -176,6 +309,17 @@ type Config struct {
 	ReplyCount int
 	// Localhost peers are allowed in the peerlist
 	AllowLocalhost bool
+	// .onion peers are allowed in the peerlist. They can only be connected to through a Tor proxy,
+	// and are not exchanged with other peers, since the peers message only carries IPv4 addresses
+	AllowOnion bool
+	// Download the peers list through this SOCKS5 proxy, which also resolves the hostname of PeerListURL
+	ProxyAddress string
+	// Don't connect to IPv4 peers. They are still kept in the peerlist and exchanged
+	DisableIPv4 bool
+	// Don't connect to IPv6 peers. They are still kept in the peerlist and exchanged
+	DisableIPv6 bool
+	// Try the peers of this IP family first when picking random peers to connect to. Empty for no preference
+	PreferIPFamily IPFamily
 	// Disable exchanging of peers.  Peers are still loaded from disk
 	Disabled bool
 	// Whether the network is disabled
@@ -184,12 +328,25 @@ type Config struct {
 	DownloadPeerList bool
 	// Download peers list from this URL
 	PeerListURL string
+	// Hostnames whose A and AAAA records are the IPs of peers to bootstrap from.
+	// They are not resolved if ProxyAddress is set, so that DNS requests don't leak outside of the proxy
+	DNSSeeds []string
+	// Port of the peers returned by the DNS seeds
+	DNSSeedPort int
+	// Download a signed peers list from this HTTPS URL if the DNS seeds return no peers.
+	// The signature is downloaded from the same URL with a .sig suffix
+	SignedPeerListURL string
+	// Public key that signs the peers list of SignedPeerListURL
+	PeerListPubKey cipher.PubKey
 	// Set all peers as untrusted (even if loaded from DefaultConnections)
 	DisableTrustedPeers bool
 	// Load peers from this file on disk. NOTE: this is different from the peers file cache in the data directory
 	CustomPeersFile string
 	// Default "trusted" connections
 	DefaultConnections []string
+	// Seed of the key that places the peers in buckets. Leave empty for a random key.
+	// Only set it for tests, since an attacker who knows the key can pick addresses that fill a bucket
+	BucketSeed string
 }
 
 // NewConfig creates default pex config.
@@ -218,16 +375,24 @@ type Pex struct {
 	sync.RWMutex
 	// All known peers
 	peerlist peerlist
-	Config   Config
-	quit     chan struct{}
-	done     chan struct{}
+	// Banned IPs
+	bans   map[string]Ban
+	Config Config
+	quit   chan struct{}
+	done   chan struct{}
 }
 
 // New creates pex
 func New(cfg Config) (*Pex, error) {
+	pl := newPeerlist()
+	if cfg.BucketSeed != "" {
+		pl = newSeededPeerlist([]byte(cfg.BucketSeed))
+	}
+
 	pex := &Pex{
 		Config:   cfg,
-		peerlist: newPeerlist(),
+		peerlist: pl,
+		bans:     make(map[string]Ban),
 		quit:     make(chan struct{}),
 		done:     make(chan struct{}),
 	}
@@ -238,6 +403,12 @@ func New(cfg Config) (*Pex, error) {
 		return nil, err
 	}
 
+	// Load bans from disk
+	if err := pex.loadBans(); err != nil {
+		logger.Critical().WithError(err).Error("pex.loadBans failed")
+		return nil, err
+	}
+
 	// Load default hardcoded peers
 	for _, addr := range cfg.DefaultConnections {
 		// Default peers will mark as trusted peers.
@@ -278,6 +449,15 @@ func New(cfg Config) (*Pex, error) {
 		}()
 	}
 
+	// Resolve peers from the DNS seeds, falling back onto the signed peers list
+	if len(pex.Config.DNSSeeds) != 0 || pex.Config.SignedPeerListURL != "" {
+		go func() {
+			if err := pex.seedPeers(); err != nil {
+				logger.WithError(err).Error("Failed to bootstrap peers from the DNS seeds and the signed peers list")
+			}
+		}()
+	}
+
 	return pex, nil
 }
 
@@ -296,6 +476,9 @@ func (px *Pex) Run() error {
 	}()
 
 	clearOldTicker := time.NewTicker(px.Config.ClearOldRate)
+	defer clearOldTicker.Stop()
+	clearExpiredBansTicker := time.NewTicker(px.Config.UpdateBlacklistRate)
+	defer clearExpiredBansTicker.Stop()
 
 	for {
 		select {
@@ -308,6 +491,8 @@ func (px *Pex) Run() error {
 					px.peerlist.clearOld(px.Config.Expiration)
 				}()
 			}
+		case <-clearExpiredBansTicker.C:
+			px.clearExpiredBans()
 		case <-px.quit:
 			return nil
 		}
@@ -323,7 +508,7 @@ func (px *Pex) Shutdown() {
 }
 
 func (px *Pex) downloadPeers() error {
-	body, err := backoffDownloadText(px.Config.PeerListURL)
+	body, err := backoffDownloadText(px.httpClient(), px.Config.PeerListURL)
 	if err != nil {
 		logger.WithError(err).WithField("url", px.Config.PeerListURL).Error("Failed to download peers")
 		return err
@@ -332,12 +517,119 @@ func (px *Pex) downloadPeers() error {
 	peers := parseRemotePeerList(body)
 	logger.WithField("url", px.Config.PeerListURL).Infof("Downloaded peers list, got %d peers", len(peers))
 
-	n := px.AddPeers(peers)
+	n := px.AddPeersFrom(px.Config.PeerListURL, peers)
 	logger.WithField("url", px.Config.PeerListURL).Infof("Added %d/%d peers from downloaded peers list", n, len(peers))
 
 	return nil
 }
 
+// seedPeers adds the peers resolved from the DNS seeds. If they return no peers,
+// the signed peers list is downloaded instead
+func (px *Pex) seedPeers() error {
+	if n := px.resolveDNSSeeds(); n != 0 || px.Config.SignedPeerListURL == "" {
+		return nil
+	}
+
+	return px.downloadSignedPeers()
+}
+
+// resolveDNSSeeds adds the peers returned by the DNS seeds and returns the number of peers added
+func (px *Pex) resolveDNSSeeds() int {
+	if len(px.Config.DNSSeeds) == 0 {
+		return 0
+	}
+
+	if px.Config.ProxyAddress != "" {
+		logger.Info("Not resolving the DNS seeds, since a proxy is used")
+		return 0
+	}
+
+	var total int
+	for _, host := range px.Config.DNSSeeds {
+		peers, err := resolveDNSSeed(host, px.Config.DNSSeedPort)
+		if err != nil {
+			logger.WithError(err).WithField("host", host).Error("Failed to resolve DNS seed")
+			continue
+		}
+
+		n := px.AddPeersFrom(host, peers)
+		logger.WithField("host", host).Infof("Added %d/%d peers from DNS seed", n, len(peers))
+		total += n
+	}
+
+	return total
+}
+
+// resolveDNSSeed returns the addresses of the peers in the A and AAAA records of a DNS seed
+func resolveDNSSeed(host string, port int) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dnsSeedTimeout)
+	defer cancel()
+
+	ips, err := lookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var peers []string
+	for _, ip := range ips {
+		addr := net.JoinHostPort(ip.IP.String(), strconv.Itoa(port))
+
+		// Never allow localhost or .onion addresses from a DNS seed
+		a, err := validateAddress(addr, false, false)
+		if err != nil {
+			logger.WithError(err).WithField("host", host).Errorf("DNS seed returned invalid address %s", addr)
+			continue
+		}
+
+		peers = append(peers, a)
+	}
+
+	return peers, nil
+}
+
+// downloadSignedPeers downloads the signed peers list and its signature, and adds its peers
+// if the signature is valid
+func (px *Pex) downloadSignedPeers() error {
+	url := px.Config.SignedPeerListURL
+	c := px.httpClient()
+
+	body, err := backoffDownloadText(c, url)
+	if err != nil {
+		logger.WithError(err).WithField("url", url).Error("Failed to download signed peers list")
+		return err
+	}
+
+	sig, err := backoffDownloadText(c, url+signedPeerListSigSuffix)
+	if err != nil {
+		logger.WithError(err).WithField("url", url+signedPeerListSigSuffix).Error("Failed to download signed peers list signature")
+		return err
+	}
+
+	if err := verifyPeerListSignature(body, sig, px.Config.PeerListPubKey); err != nil {
+		logger.WithError(err).WithField("url", url).Error("Signed peers list is not signed by the peers list public key")
+		return err
+	}
+
+	peers := parseRemotePeerList(body)
+	logger.WithField("url", url).Infof("Downloaded signed peers list, got %d peers", len(peers))
+
+	n := px.AddPeersFrom(url, peers)
+	logger.WithField("url", url).Infof("Added %d/%d peers from signed peers list", n, len(peers))
+
+	return nil
+}
+
+// verifyPeerListSignature verifies that sigHex is the hex-encoded signature of the SHA256 hash
+// of the peers list body, by pubkey
+func verifyPeerListSignature(body, sigHex string, pubkey cipher.PubKey) error {
+	sig, err := cipher.SigFromHex(strings.TrimSpace(sigHex))
+	if err != nil {
+		return fmt.Errorf("Invalid peers list signature: %v", err)
+	}
+
+	return cipher.VerifyPubKeySignedHash(pubkey, sig, cipher.SumSHA256([]byte(body)))
+}
+
 func (px *Pex) loadCache() error {
 	px.Lock()
 	defer px.Unlock()
@@ -368,11 +660,16 @@ func (px *Pex) loadCache() error {
 	// remove invalid peers and limit the max number of peers to pex.Config.Max
 	var validPeers []Peer
 	for addr, p := range peers {
-		if _, err := validateAddress(addr, px.Config.AllowLocalhost); err != nil {
+		if _, err := validateAddress(addr, px.Config.AllowLocalhost, px.Config.AllowOnion); err != nil {
 			logger.WithError(err).Error("Invalid peer address")
 			continue
 		}
 
+		// Peers cached before they were bucketed are bucketed as if they announced their own address
+		if p.Source == "" && !p.Trusted && !p.Private {
+			p.Source = p.Addr
+		}
+
 		validPeers = append(validPeers, *p)
 		if px.Config.Max > 0 && len(validPeers) >= px.Config.Max {
 			break
@@ -399,14 +696,14 @@ func (px *Pex) loadCustom(fn string) error {
 		return err
 	}
 
-	peers, err := parseLocalPeerList(string(data), px.Config.AllowLocalhost)
+	peers, err := parseLocalPeerList(string(data), px.Config.AllowLocalhost, px.Config.AllowOnion)
 	if err != nil {
 		return err
 	}
 
 	logger.Infof("Loaded %d peers from %s", len(peers), fn)
 
-	px.peerlist.addPeers(peers)
+	px.peerlist.addPeers(peers, "")
 	return nil
 }
 
@@ -414,7 +711,10 @@ func (px *Pex) loadCustom(fn string) error {
 func (px *Pex) save() error {
 	px.Lock()
 	defer px.Unlock()
+	return px.savePeerlist()
+}
 
+func (px *Pex) savePeerlist() error {
 	fn := filepath.Join(px.Config.DataDirectory, PeerCacheFilename)
 	return px.peerlist.save(fn)
 }
@@ -423,15 +723,27 @@ func (px *Pex) save() error {
 // full, it will try to remove an old peer to make room.
 // If no room can be made, ErrPeerlistFull is returned
 func (px *Pex) AddPeer(addr string) error {
+	return px.AddPeerFrom("", addr)
+}
+
+// AddPeerFrom adds a peer learned from source, the address of the peer or host that announced it,
+// which is the peer itself for the listen address of an incoming connection. The peers learned from
+// the network are placed in buckets; if the peer's bucket is full, ErrBucketFull is returned.
+// Source is empty for the peers that are not learned from the network, which are not bucketed
+func (px *Pex) AddPeerFrom(source, addr string) error {
 	px.Lock()
 	defer px.Unlock()
 
-	cleanAddr, err := validateAddress(addr, px.Config.AllowLocalhost)
+	cleanAddr, err := validateAddress(addr, px.Config.AllowLocalhost, px.Config.AllowOnion)
 	if err != nil {
 		logger.WithError(err).WithField("addr", addr).Error("Invalid address")
 		return ErrInvalidAddress
 	}
 
+	if px.isBanned(cleanAddr) {
+		return ErrBlacklistedAddress
+	}
+
 	if px.peerlist.hasPeer(cleanAddr) {
 		px.peerlist.seen(cleanAddr)
 		return nil
@@ -454,14 +766,19 @@ func (px *Pex) AddPeer(addr string) error {
 		}
 	}
 
-	px.peerlist.addPeer(cleanAddr)
-	return nil
+	return px.peerlist.addPeer(cleanAddr, source)
 }
 
 // AddPeers add multiple peers at once. Any errors will be logged, but not returned
 // Returns the number of peers that were added without error. Note that
 // adding a duplicate peer will not cause an error.
 func (px *Pex) AddPeers(addrs []string) int {
+	return px.AddPeersFrom("", addrs)
+}
+
+// AddPeersFrom adds multiple peers learned from source at once, like AddPeers.
+// See AddPeerFrom for the source
+func (px *Pex) AddPeersFrom(source string, addrs []string) int {
 	px.Lock()
 	defer px.Unlock()
 
@@ -473,11 +790,15 @@ func (px *Pex) AddPeers(addrs []string) int {
 	// validate the addresses
 	var validAddrs []string
 	for _, addr := range addrs {
-		a, err := validateAddress(addr, px.Config.AllowLocalhost)
+		a, err := validateAddress(addr, px.Config.AllowLocalhost, px.Config.AllowOnion)
 		if err != nil {
 			logger.WithField("addr", addr).WithError(err).Info("Add peers sees an invalid address")
 			continue
 		}
+		if px.isBanned(a) {
+			logger.WithField("addr", addr).Debug("Add peers sees a banned address")
+			continue
+		}
 		validAddrs = append(validAddrs, a)
 	}
 	addrs = validAddrs
@@ -494,8 +815,73 @@ func (px *Pex) AddPeers(addrs []string) int {
 		}
 	}
 
-	px.peerlist.addPeers(addrs)
-	return len(addrs)
+	return px.peerlist.addPeers(addrs, source)
+}
+
+// ImportPeers adds peers given by the operator and persists the peerlist.
+// Unlike AddPeers, no peer is added if an address is invalid or banned,
+// or if the peer list does not have room for all the new peers
+func (px *Pex) ImportPeers(addrs []string) error {
+	px.Lock()
+	defer px.Unlock()
+
+	var cleanAddrs []string
+	for _, addr := range addrs {
+		a, err := validateAddress(addr, px.Config.AllowLocalhost, px.Config.AllowOnion)
+		if err != nil {
+			logger.WithError(err).WithField("addr", addr).Error("Invalid address")
+			return ErrInvalidAddress
+		}
+		if px.isBanned(a) {
+			return ErrBlacklistedAddress
+		}
+		cleanAddrs = append(cleanAddrs, a)
+	}
+
+	unknown := make(map[string]struct{})
+	for _, a := range cleanAddrs {
+		if !px.peerlist.hasPeer(a) {
+			unknown[a] = struct{}{}
+		}
+	}
+	if px.Config.Max > 0 && px.peerlist.len()+len(unknown) > px.Config.Max {
+		return ErrPeerlistFull
+	}
+
+	// Known peers are marked as seen
+	for _, a := range cleanAddrs {
+		if err := px.peerlist.addPeer(a, ""); err != nil {
+			return err
+		}
+	}
+
+	return px.savePeerlist()
+}
+
+// RemovePeers removes peers given by the operator and persists the peerlist.
+// No peer is removed if an address is invalid or not in the peer list
+func (px *Pex) RemovePeers(addrs []string) error {
+	px.Lock()
+	defer px.Unlock()
+
+	cleanAddrs := make([]string, len(addrs))
+	for i, addr := range addrs {
+		a, err := validateAddress(addr, px.Config.AllowLocalhost, px.Config.AllowOnion)
+		if err != nil {
+			logger.WithError(err).WithField("addr", addr).Error("Invalid address")
+			return ErrInvalidAddress
+		}
+		if !px.peerlist.hasPeer(a) {
+			return ErrPeerNotFound
+		}
+		cleanAddrs[i] = a
+	}
+
+	for _, a := range cleanAddrs {
+		px.peerlist.removePeer(a)
+	}
+
+	return px.savePeerlist()
 }
 
 // SetPrivate updates peer's private value
@@ -503,7 +889,7 @@ func (px *Pex) SetPrivate(addr string, private bool) error {
 	px.Lock()
 	defer px.Unlock()
 
-	cleanAddr, err := validateAddress(addr, px.Config.AllowLocalhost)
+	cleanAddr, err := validateAddress(addr, px.Config.AllowLocalhost, px.Config.AllowOnion)
 	if err != nil {
 		logger.WithError(err).WithField("addr", addr).Error("Invalid address")
 		return ErrInvalidAddress
@@ -517,7 +903,7 @@ func (px *Pex) SetTrusted(addr string) error {
 	px.Lock()
 	defer px.Unlock()
 
-	cleanAddr, err := validateAddress(addr, px.Config.AllowLocalhost)
+	cleanAddr, err := validateAddress(addr, px.Config.AllowLocalhost, px.Config.AllowOnion)
 	if err != nil {
 		logger.WithError(err).WithField("addr", addr).Error("Invalid address")
 		return ErrInvalidAddress
@@ -539,7 +925,7 @@ func (px *Pex) SetHasIncomingPort(addr string, hasPublicPort bool) error {
 	px.Lock()
 	defer px.Unlock()
 
-	cleanAddr, err := validateAddress(addr, px.Config.AllowLocalhost)
+	cleanAddr, err := validateAddress(addr, px.Config.AllowLocalhost, px.Config.AllowOnion)
 	if err != nil {
 		logger.WithError(err).WithField("addr", addr).Error("Invalid address")
 		return ErrInvalidAddress
@@ -559,7 +945,7 @@ func (px *Pex) SetUserAgent(addr string, userAgent useragent.Data) error {
 		}
 	}
 
-	cleanAddr, err := validateAddress(addr, px.Config.AllowLocalhost)
+	cleanAddr, err := validateAddress(addr, px.Config.AllowLocalhost, px.Config.AllowOnion)
 	if err != nil {
 		logger.WithError(err).WithField("addr", addr).Error("Invalid address")
 		return ErrInvalidAddress
@@ -582,6 +968,18 @@ func (px *Pex) GetPeer(addr string) (Peer, bool) {
 	return px.peerlist.getPeer(addr)
 }
 
+// Peers returns all the peers of the peer list, sorted by address
+func (px *Pex) Peers() Peers {
+	px.RLock()
+	defer px.RUnlock()
+
+	ps := px.peerlist.getPeers(nil)
+	sort.Slice(ps, func(i, j int) bool {
+		return ps[i].Addr < ps[j].Addr
+	})
+	return ps
+}
+
 // Trusted returns trusted peers
 func (px *Pex) Trusted() Peers {
 	px.RLock()
@@ -593,23 +991,91 @@ func (px *Pex) Trusted() Peers {
 func (px *Pex) Private() Peers {
 	px.RLock()
 	defer px.RUnlock()
-	return px.peerlist.getCanTryPeers([]Filter{isPrivate})
+	return px.peerlist.getCanTryPeers([]Filter{isPrivate, px.isIPFamilyEnabled})
 }
 
 // TrustedPublic returns trusted public peers
 func (px *Pex) TrustedPublic() Peers {
 	px.RLock()
 	defer px.RUnlock()
-	return px.peerlist.getCanTryPeers([]Filter{isPublic, isTrusted})
+	return px.peerlist.getCanTryPeers([]Filter{isPublic, isTrusted, px.isIPFamilyEnabled})
 }
 
-// RandomPublic returns N random public untrusted peers
+// RandomPublic returns N public untrusted peers for outgoing connections, the peers with the best score first.
+// Peers with the same score are returned in random order.
+// The peers of Config.PreferIPFamily are returned first, then the others
 func (px *Pex) RandomPublic(n int) Peers {
 	px.RLock()
 	defer px.RUnlock()
-	return px.peerlist.random(n, []Filter{func(p Peer) bool {
-		return !p.Private
-	}})
+	return px.randomPublic(n)
+}
+
+func (px *Pex) randomPublic(n int) Peers {
+	flts := []Filter{isPublic, px.isIPFamilyEnabled}
+	if px.Config.PreferIPFamily == "" {
+		return px.peerlist.best(n, flts)
+	}
+
+	isPreferred := func(p Peer) bool {
+		return AddrIPFamily(p.Addr) == px.Config.PreferIPFamily
+	}
+
+	peers := px.peerlist.best(n, append(flts, isPreferred))
+	if n != 0 && len(peers) >= n {
+		return peers
+	}
+
+	others := 0
+	if n != 0 {
+		others = n - len(peers)
+	}
+
+	return append(peers, px.peerlist.best(others, append(flts, func(p Peer) bool {
+		return !isPreferred(p)
+	}))...)
+}
+
+// DiversePublic returns N public untrusted peers for outgoing connections like RandomPublic, but with at most
+// one peer per netgroup, skipping the netgroups of the connected addresses, so that the outgoing connections
+// can't all be made to the peers of the few netgroups an attacker controls. Loopback peers are not limited
+func (px *Pex) DiversePublic(n int, connected []string) Peers {
+	px.RLock()
+	defer px.RUnlock()
+
+	groups := make(map[string]struct{}, len(connected))
+	for _, addr := range connected {
+		groups[Netgroup(addr)] = struct{}{}
+	}
+
+	peers := make(Peers, 0)
+	for _, p := range px.randomPublic(0) {
+		if n != 0 && len(peers) >= n {
+			break
+		}
+
+		if g := Netgroup(p.Addr); g != localNetgroup {
+			if _, ok := groups[g]; ok {
+				continue
+			}
+			groups[g] = struct{}{}
+		}
+
+		peers = append(peers, p)
+	}
+
+	return peers
+}
+
+// isIPFamilyEnabled returns false for the peers of an IP family disabled by Config.DisableIPv4 or Config.DisableIPv6
+func (px *Pex) isIPFamilyEnabled(p Peer) bool {
+	switch AddrIPFamily(p.Addr) {
+	case IPFamilyIPv4:
+		return !px.Config.DisableIPv4
+	case IPFamilyIPv6:
+		return !px.Config.DisableIPv6
+	default:
+		return true
+	}
 }
 
 // RandomExchangeable returns N random exchangeable peers
@@ -640,6 +1106,48 @@ func (px *Pex) ResetAllRetryTimes() {
 	px.peerlist.resetAllRetryTimes()
 }
 
+// SetProtocolVersion sets the peer's last reported protocol version
+func (px *Pex) SetProtocolVersion(addr string, version int32) {
+	px.Lock()
+	defer px.Unlock()
+	px.peerlist.setProtocolVersion(addr, version)
+}
+
+// SetFeatures sets the feature bits last negotiated with the peer
+func (px *Pex) SetFeatures(addr string, features uint64) {
+	px.Lock()
+	defer px.Unlock()
+	px.peerlist.setFeatures(addr, features)
+}
+
+// SetTransportKey sets the static key that the peer authenticated with in the encrypted transport handshake
+func (px *Pex) SetTransportKey(addr string, key cipher.PubKey) {
+	px.Lock()
+	defer px.Unlock()
+	px.peerlist.setTransportKey(addr, key)
+}
+
+// RecordHandshake records an outgoing connection attempt to a peer, and whether it completed the introduction
+func (px *Pex) RecordHandshake(addr string, succeeded bool) {
+	px.Lock()
+	defer px.Unlock()
+	px.peerlist.recordHandshake(addr, succeeded)
+}
+
+// RecordUptime adds the duration of a connection to the uptime of a peer
+func (px *Pex) RecordUptime(addr string, d time.Duration) {
+	px.Lock()
+	defer px.Unlock()
+	px.peerlist.recordUptime(addr, d)
+}
+
+// RecordBlockLatency records the latency of a blocks response of a peer
+func (px *Pex) RecordBlockLatency(addr string, latency time.Duration) {
+	px.Lock()
+	defer px.Unlock()
+	px.peerlist.recordBlockLatency(addr, latency)
+}
+
 // IsFull returns whether the peer list is full
 func (px *Pex) IsFull() bool {
 	px.RLock()
@@ -651,11 +1159,28 @@ func (px *Pex) isFull() bool {
 	return px.Config.Max > 0 && px.peerlist.len() >= px.Config.Max
 }
 
+// httpClient returns the client that downloads the peers list. If Config.ProxyAddress is set,
+// the requests go through the proxy, which also resolves the hostname
+func (px *Pex) httpClient() *http.Client {
+	if px.Config.ProxyAddress == "" {
+		return http.DefaultClient
+	}
+
+	d := socks5.NewDialer(px.Config.ProxyAddress, proxyDialTimeout)
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(_ context.Context, network, addr string) (net.Conn, error) {
+				return d.Dial(network, addr)
+			},
+		},
+	}
+}
+
 // downloadText downloads a text format file from url.
 // Returns the raw response body as a string.
 // TODO -- move to util, add backoff options
-func downloadText(url string) (string, error) {
-	resp, err := http.Get(url)
+func downloadText(c *http.Client, url string) (string, error) {
+	resp, err := c.Get(url)
 	if err != nil {
 		return "", err
 	}
@@ -669,7 +1194,7 @@ func downloadText(url string) (string, error) {
 	return string(body), nil
 }
 
-func backoffDownloadText(url string) (string, error) {
+func backoffDownloadText(c *http.Client, url string) (string, error) {
 	var body string
 
 	b := backoff.NewExponentialBackOff()
@@ -681,7 +1206,7 @@ func backoffDownloadText(url string) (string, error) {
 	operation := func() error {
 		logger.WithField("url", url).Info("Trying to download peers list")
 		var err error
-		body, err = downloadText(url)
+		body, err = downloadText(c, url)
 		return err
 	}
 
@@ -709,8 +1234,8 @@ func parseRemotePeerList(body string) []string {
 			continue
 		}
 
-		// Never allow localhost addresses from the remote peers list
-		a, err := validateAddress(addr, false)
+		// Never allow localhost or .onion addresses from the remote peers list
+		a, err := validateAddress(addr, false, false)
 		if err != nil {
 			err = fmt.Errorf("Peers list has invalid address %s: %v", addr, err)
 			logger.WithError(err).Error()
@@ -728,10 +1253,10 @@ func parseRemotePeerList(body string) []string {
 // Empty lines and lines that begin with # are treated as comment lines
 // Otherwise, the line is parsed as an ip:port
 // If the line fails to parse, an error is returned
-// Localhost addresses are allowed if allowLocalhost is true
+// Localhost addresses are allowed if allowLocalhost is true, .onion addresses if allowOnion is true
 // NOTE: this does not parse the cached peers.json file in the data directory, which is a JSON file
 // and is loaded by loadCachedPeersFile
-func parseLocalPeerList(body string, allowLocalhost bool) ([]string, error) {
+func parseLocalPeerList(body string, allowLocalhost, allowOnion bool) ([]string, error) {
 	var peers []string
 	for _, addr := range strings.Split(body, "\n") {
 		addr = whitespaceFilter.ReplaceAllString(addr, "")
@@ -743,7 +1268,7 @@ func parseLocalPeerList(body string, allowLocalhost bool) ([]string, error) {
 			continue
 		}
 
-		a, err := validateAddress(addr, allowLocalhost)
+		a, err := validateAddress(addr, allowLocalhost, allowOnion)
 		if err != nil {
 			err = fmt.Errorf("Peers list has invalid address %s: %v", addr, err)
 			logger.WithError(err).Error()
@@ -1,8 +1,13 @@
 package pex
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
@@ -10,6 +15,7 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/util/file"
 )
 
@@ -17,6 +23,7 @@ func TestValidateAddress(t *testing.T) {
 	cases := []struct {
 		addr           string
 		allowLocalhost bool
+		allowOnion     bool
 		err            error
 		cleanAddr      string
 	}{
@@ -140,12 +147,84 @@ func TestValidateAddress(t *testing.T) {
 			allowLocalhost: false,
 			cleanAddr:      "11.22.33.44:8080",
 		},
+		{
+			addr: "[2001:db8::1]:6000",
+		},
+		{
+			addr:      "[2001:0db8:0000::0001]:6000",
+			cleanAddr: "[2001:db8::1]:6000",
+		},
+		{
+			addr: "2001:db8::1:6000",
+			err:  ErrInvalidAddress,
+		},
+		{
+			addr: "[2001:db8::1]",
+			err:  ErrInvalidAddress,
+		},
+		{
+			addr: "[2001:db8::1]:100",
+			err:  ErrPortTooLow,
+		},
+		{
+			addr: "[fe80::1]:6000",
+			err:  ErrNotExternalIP,
+		},
+		{
+			addr: "[ff02::1]:6000",
+			err:  ErrNotExternalIP,
+		},
+		{
+			addr: "[::1]:6000",
+			err:  ErrNoLocalhost,
+		},
+		{
+			addr:           "[::1]:6000",
+			allowLocalhost: true,
+		},
+		{
+			addr:      "[::ffff:11.22.33.44]:8080",
+			cleanAddr: "11.22.33.44:8080",
+		},
+		{
+			addr:      "11.22.33.44:08080",
+			cleanAddr: "11.22.33.44:8080",
+		},
+		{
+			addr:       "pg6mmjiyjmcrsslvykfwnntlaru7p5svn6y2ymmju6nubxndf4pscryd.onion:6000",
+			allowOnion: true,
+		},
+		{
+			addr:       "pg6mmjiyjmcrsslvykfwnntlaru7p5svn6y2ymmju6nubxndf4pscryd.onion:6000",
+			allowOnion: false,
+			err:        ErrOnionNotAllowed,
+		},
+		{
+			addr:       "PG6MMJIYJMCRSSLVYKFWNNTLARU7P5SVN6Y2YMMJU6NUBXNDF4PSCRYD.ONION:6000",
+			allowOnion: true,
+			cleanAddr:  "pg6mmjiyjmcrsslvykfwnntlaru7p5svn6y2ymmju6nubxndf4pscryd.onion:6000",
+		},
+		{
+			addr:       "pg6mmjiyjmcrsslvykfwnntlaru7p5svn6y2ymmju6nubxndf4pscryd.onion:100",
+			allowOnion: true,
+			err:        ErrPortTooLow,
+		},
+		{
+			addr:       "expyuzz4wqqyqhjn.onion:6000",
+			allowOnion: true,
+			err:        ErrInvalidAddress,
+		},
+		{
+			addr:       "pg6mmjiyjmcrsslvykfwnntlaru7p5svn6y2ymmju6nubxndf4pscryd.onion",
+			allowOnion: true,
+			err:        ErrInvalidAddress,
+		},
 	}
 
 	for _, tc := range cases {
 		name := fmt.Sprintf("%+v", tc)
 		t.Run(name, func(t *testing.T) {
-			cleanAddr, err := validateAddress(tc.addr, tc.allowLocalhost)
+			cleanAddr, err := validateAddress(tc.addr, tc.allowLocalhost, tc.allowOnion)
 			require.Equal(t, tc.err, err)
 
 			if err == nil {
@@ -159,6 +238,15 @@ func TestValidateAddress(t *testing.T) {
 	}
 }
 
+func TestAddrIPFamily(t *testing.T) {
+	require.Equal(t, IPFamilyIPv4, AddrIPFamily("11.22.33.44:6000"))
+	require.Equal(t, IPFamilyIPv4, AddrIPFamily("[::ffff:11.22.33.44]:6000"))
+	require.Equal(t, IPFamilyIPv6, AddrIPFamily("[2001:db8::1]:6000"))
+	require.Equal(t, IPFamily(""), AddrIPFamily("pg6mmjiyjmcrsslvykfwnntlaru7p5svn6y2ymmju6nubxndf4pscryd.onion:6000"))
+	require.Equal(t, IPFamily(""), AddrIPFamily("2001:db8::1"))
+	require.Equal(t, IPFamily(""), AddrIPFamily(""))
+}
+
 func TestNewPex(t *testing.T) {
 	dir, err := ioutil.TempDir("", "peerlist")
 	require.NoError(t, err)
@@ -268,8 +356,8 @@ func TestPexLoadPeers(t *testing.T) {
 			max:     2,
 			expectN: 2,
 			expectPeers: []Peer{
-				Peer{Addr: testPeers[0]},
-				Peer{Addr: testPeers[1]},
+				Peer{Addr: testPeers[0], Source: testPeers[0]},
+				Peer{Addr: testPeers[1], Source: testPeers[1]},
 			},
 		},
 		{
@@ -283,9 +371,9 @@ func TestPexLoadPeers(t *testing.T) {
 			max:     2,
 			expectN: 2,
 			expectPeers: []Peer{
-				Peer{Addr: testPeers[0]},
-				Peer{Addr: testPeers[1]},
-				Peer{Addr: testPeers[2]},
+				Peer{Addr: testPeers[0], Source: testPeers[0]},
+				Peer{Addr: testPeers[1], Source: testPeers[1]},
+				Peer{Addr: testPeers[2], Source: testPeers[2]},
 			},
 		},
 		{
@@ -299,8 +387,8 @@ func TestPexLoadPeers(t *testing.T) {
 			max:     2,
 			expectN: 2,
 			expectPeers: []Peer{
-				Peer{Addr: testPeers[1]},
-				Peer{Addr: testPeers[2]},
+				Peer{Addr: testPeers[1], Source: testPeers[1]},
+				Peer{Addr: testPeers[2], Source: testPeers[2]},
 			},
 		},
 		{
@@ -313,8 +401,8 @@ func TestPexLoadPeers(t *testing.T) {
 			max:     2,
 			expectN: 2,
 			expectPeers: []Peer{
-				Peer{Addr: testPeers[0]},
-				Peer{Addr: testPeers[1]},
+				Peer{Addr: testPeers[0], Source: testPeers[0]},
+				Peer{Addr: testPeers[1], Source: testPeers[1]},
 			},
 		},
 		{
@@ -946,6 +1034,65 @@ func TestPexRandomPublic(t *testing.T) {
 	}
 }
 
+func TestPexRandomPublicIPFamily(t *testing.T) {
+	ipv4Peers := []string{"11.22.33.44:6000", "11.22.33.45:6000", "11.22.33.46:6000"}
+	ipv6Peers := []string{"[2001:db8::1]:6000", "[2001:db8::2]:6000"}
+	onionPeer := "pg6mmjiyjmcrsslvykfwnntlaru7p5svn6y2ymmju6nubxndf4pscryd.onion:6000"
+
+	newPex := func(cfg Config) *Pex {
+		px := &Pex{
+			Config:   cfg,
+			peerlist: newPeerlist(),
+		}
+		px.peerlist.addPeers(ipv4Peers, "")
+		px.peerlist.addPeers(ipv6Peers, "")
+		px.peerlist.addPeer(onionPeer, "")
+		return px
+	}
+
+	families := func(peers Peers) []IPFamily {
+		var fs []IPFamily
+		for _, p := range peers {
+			fs = append(fs, AddrIPFamily(p.Addr))
+		}
+		return fs
+	}
+
+	// No preference
+	px := newPex(Config{})
+	require.Len(t, px.RandomPublic(0), 6)
+	require.Len(t, px.RandomPublic(4), 4)
+
+	// The preferred family comes first
+	px = newPex(Config{PreferIPFamily: IPFamilyIPv6})
+	require.Equal(t, []IPFamily{IPFamilyIPv6, IPFamilyIPv6}, families(px.RandomPublic(2)))
+	require.Equal(t, []IPFamily{IPFamilyIPv6, IPFamilyIPv6}, families(px.RandomPublic(4))[:2])
+	require.Len(t, px.RandomPublic(4), 4)
+	require.Equal(t, []IPFamily{IPFamilyIPv6, IPFamilyIPv6}, families(px.RandomPublic(0))[:2])
+	require.Len(t, px.RandomPublic(0), 6)
+
+	px = newPex(Config{PreferIPFamily: IPFamilyIPv4})
+	require.Equal(t, []IPFamily{IPFamilyIPv4, IPFamilyIPv4, IPFamilyIPv4}, families(px.RandomPublic(3)))
+
+	// The peers of a disabled family are not returned
+	px = newPex(Config{DisableIPv4: true})
+	peers := px.RandomPublic(0)
+	require.Len(t, peers, 3)
+	for _, p := range peers {
+		require.NotEqual(t, IPFamilyIPv4, AddrIPFamily(p.Addr))
+	}
+
+	px = newPex(Config{DisableIPv6: true, PreferIPFamily: IPFamilyIPv4})
+	require.Equal(t, []IPFamily{IPFamilyIPv4, IPFamilyIPv4, IPFamilyIPv4, ""}, families(px.RandomPublic(0)))
+
+	// Disabled peers are still exchanged
+	require.Len(t, px.RandomExchangeable(0), 0)
+	for _, a := range append(ipv4Peers, ipv6Peers...) {
+		require.NoError(t, px.peerlist.setHasIncomingPort(a, true))
+	}
+	require.Len(t, px.RandomExchangeable(0), 5)
+}
+
 func TestPexTrusted(t *testing.T) {
 	tt := []struct {
 		name   string
@@ -1261,6 +1408,65 @@ func TestPexRemovePeer(t *testing.T) {
 	}
 }
 
+func TestPexImportPeers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pex")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cfg := NewConfig()
+	cfg.DataDirectory = dir
+	cfg.Max = 3
+	px, err := New(cfg)
+	require.NoError(t, err)
+
+	require.NoError(t, px.ImportPeers([]string{testPeers[0], testPeers[1]}))
+	require.Equal(t, []string{testPeers[0], testPeers[1]}, px.Peers().ToAddrs())
+
+	// The peer list is persisted
+	peers, err := loadCachedPeersFile(filepath.Join(dir, PeerCacheFilename))
+	require.NoError(t, err)
+	require.Len(t, peers, 2)
+
+	// No peer is added if an address is rejected
+	require.Equal(t, ErrInvalidAddress, px.ImportPeers([]string{testPeers[2], wrongPortPeer}))
+	require.NoError(t, px.Ban(testPeers[3], "test", time.Hour))
+	require.Equal(t, ErrBlacklistedAddress, px.ImportPeers([]string{testPeers[2], testPeers[3]}))
+	require.Len(t, px.Peers(), 2)
+
+	// Known peers don't count against the maximum
+	require.Equal(t, ErrPeerlistFull, px.ImportPeers([]string{testPeers[2], "112.32.32.18:7200"}))
+	require.NoError(t, px.ImportPeers([]string{testPeers[0], testPeers[2], testPeers[2]}))
+	require.Len(t, px.Peers(), 3)
+}
+
+func TestPexRemovePeers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pex")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cfg := NewConfig()
+	cfg.DataDirectory = dir
+	px, err := New(cfg)
+	require.NoError(t, err)
+
+	require.NoError(t, px.ImportPeers(testPeers))
+
+	// No peer is removed if an address is rejected
+	require.Equal(t, ErrInvalidAddress, px.RemovePeers([]string{testPeers[0], "112.32.32"}))
+	require.Equal(t, ErrPeerNotFound, px.RemovePeers([]string{testPeers[0], "112.32.32.18:7200"}))
+	require.Len(t, px.Peers(), len(testPeers))
+
+	require.NoError(t, px.RemovePeers([]string{testPeers[0], testPeers[2]}))
+	require.Equal(t, []string{testPeers[1], testPeers[3]}, px.Peers().ToAddrs())
+
+	// The peer list is persisted
+	peers, err := loadCachedPeersFile(filepath.Join(dir, PeerCacheFilename))
+	require.NoError(t, err)
+	require.Len(t, peers, 2)
+	require.Contains(t, peers, testPeers[1])
+	require.Contains(t, peers, testPeers[3])
+}
+
 func TestPexSetPrivate(t *testing.T) {
 	tt := []struct {
 		name     string
@@ -1486,6 +1692,7 @@ func TestParseRemotePeerList(t *testing.T) {
   54.54.32.32:7899
 11.33.11.33
 22.44.22.44:99
+pg6mmjiyjmcrsslvykfwnntlaru7p5svn6y2ymmju6nubxndf4pscryd.onion:6000
 `
 
 	peers := parseRemotePeerList(body)
@@ -1503,6 +1710,7 @@ func TestParseLocalPeerList(t *testing.T) {
 		body           string
 		peers          []string
 		allowLocalhost bool
+		allowOnion     bool
 		err            error
 	}{
 		{
@@ -1563,11 +1771,31 @@ func TestParseLocalPeerList(t *testing.T) {
 			err:            fmt.Errorf("Peers list has invalid address 54.54.32.32:99: %v", ErrPortTooLow),
 			allowLocalhost: false,
 		},
+
+		{
+			name: "valid, onion",
+			body: `11.22.33.44:5555
+pg6mmjiyjmcrsslvykfwnntlaru7p5svn6y2ymmju6nubxndf4pscryd.onion:6000
+`,
+			peers: []string{
+				"11.22.33.44:5555",
+				"pg6mmjiyjmcrsslvykfwnntlaru7p5svn6y2ymmju6nubxndf4pscryd.onion:6000",
+			},
+			allowOnion: true,
+		},
+
+		{
+			name: "invalid, contains onion but no onion allowed",
+			body: `11.22.33.44:5555
+pg6mmjiyjmcrsslvykfwnntlaru7p5svn6y2ymmju6nubxndf4pscryd.onion:6000
+`,
+			err: fmt.Errorf("Peers list has invalid address pg6mmjiyjmcrsslvykfwnntlaru7p5svn6y2ymmju6nubxndf4pscryd.onion:6000: %v", ErrOnionNotAllowed),
+		},
 	}
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
-			peers, err := parseLocalPeerList(tc.body, tc.allowLocalhost)
+			peers, err := parseLocalPeerList(tc.body, tc.allowLocalhost, tc.allowOnion)
 			if tc.err != nil {
 				require.Equal(t, tc.err, err)
 				return
@@ -1578,3 +1806,163 @@ func TestParseLocalPeerList(t *testing.T) {
 		})
 	}
 }
+
+func TestResolveDNSSeeds(t *testing.T) {
+	defer func(f func(context.Context, string) ([]net.IPAddr, error)) {
+		lookupIPAddr = f
+	}(lookupIPAddr)
+
+	lookupIPAddr = func(_ context.Context, host string) ([]net.IPAddr, error) {
+		switch host {
+		case "seed1.example.com":
+			return []net.IPAddr{
+				{IP: net.ParseIP("11.22.33.44")},
+				{IP: net.ParseIP("127.0.0.1")},
+				{IP: net.ParseIP("2001:db8::1")},
+			}, nil
+		case "seed2.example.com":
+			return []net.IPAddr{
+				{IP: net.ParseIP("11.22.33.44")},
+				{IP: net.ParseIP("55.66.77.88")},
+			}, nil
+		default:
+			return nil, errors.New("no such host")
+		}
+	}
+
+	pex := &Pex{
+		peerlist: newPeerlist(),
+		Config: Config{
+			DNSSeeds:    []string{"seed1.example.com", "unknown.example.com", "seed2.example.com"},
+			DNSSeedPort: 6000,
+		},
+	}
+
+	require.Equal(t, 4, pex.resolveDNSSeeds())
+	require.Equal(t, 3, pex.peerlist.len())
+	for _, addr := range []string{"11.22.33.44:6000", "[2001:db8::1]:6000", "55.66.77.88:6000"} {
+		_, ok := pex.GetPeer(addr)
+		require.True(t, ok, addr)
+	}
+
+	// The DNS seeds are not resolved through a proxy
+	pex = &Pex{
+		peerlist: newPeerlist(),
+		Config: Config{
+			DNSSeeds:     []string{"seed1.example.com"},
+			DNSSeedPort:  6000,
+			ProxyAddress: "127.0.0.1:9050",
+		},
+	}
+
+	require.Equal(t, 0, pex.resolveDNSSeeds())
+	require.Equal(t, 0, pex.peerlist.len())
+}
+
+func TestDownloadSignedPeers(t *testing.T) {
+	pubkey, seckey := cipher.GenerateKeyPair()
+	otherPubkey, _ := cipher.GenerateKeyPair()
+
+	body := `11.22.33.44:5555
+66.55.44.33:2020
+127.0.0.1:8080
+`
+	sig := cipher.MustSignHash(cipher.SumSHA256([]byte(body)), seckey)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/peers.txt":
+			fmt.Fprint(w, body)
+		case "/peers.txt.sig":
+			fmt.Fprintln(w, sig.Hex())
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	cases := []struct {
+		name   string
+		pubkey cipher.PubKey
+		peers  []string
+		err    error
+	}{
+		{
+			name:   "valid signature",
+			pubkey: pubkey,
+			peers:  []string{"11.22.33.44:5555", "66.55.44.33:2020"},
+		},
+		{
+			name:   "signed by another key",
+			pubkey: otherPubkey,
+			err:    errors.New("Recovered pubkey does not match pubkey"),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			pex := &Pex{
+				peerlist: newPeerlist(),
+				Config: Config{
+					SignedPeerListURL: srv.URL + "/peers.txt",
+					PeerListPubKey:    tc.pubkey,
+				},
+			}
+
+			err := pex.downloadSignedPeers()
+			require.Equal(t, tc.err, err)
+			require.Equal(t, len(tc.peers), pex.peerlist.len())
+			for _, addr := range tc.peers {
+				_, ok := pex.GetPeer(addr)
+				require.True(t, ok, addr)
+			}
+		})
+	}
+}
+
+func TestVerifyPeerListSignature(t *testing.T) {
+	pubkey, seckey := cipher.GenerateKeyPair()
+	body := "11.22.33.44:5555\n"
+	sig := cipher.MustSignHash(cipher.SumSHA256([]byte(body)), seckey)
+
+	require.NoError(t, verifyPeerListSignature(body, sig.Hex()+"\n", pubkey))
+	require.Error(t, verifyPeerListSignature(body+"55.66.77.88:6000\n", sig.Hex(), pubkey))
+	require.Error(t, verifyPeerListSignature(body, "not a signature", pubkey))
+}
+
+func TestPexRecordPeerStats(t *testing.T) {
+	pex := &Pex{
+		peerlist: newPeerlist(),
+	}
+	pex.peerlist.addPeers([]string{testPeers[0], testPeers[1]}, "")
+
+	pex.SetProtocolVersion(testPeers[0], 4)
+	pex.SetFeatures(testPeers[0], 3)
+	pex.SetTransportKey(testPeers[0], testTransportKey)
+	pex.RecordHandshake(testPeers[0], true)
+	pex.RecordHandshake(testPeers[0], false)
+	pex.RecordUptime(testPeers[0], time.Minute)
+	pex.RecordUptime(testPeers[0], time.Minute)
+	pex.RecordBlockLatency(testPeers[0], time.Second)
+
+	// Statistics of unknown peers are ignored
+	pex.RecordHandshake(testPeers[2], true)
+	_, ok := pex.GetPeer(testPeers[2])
+	require.False(t, ok)
+
+	p, ok := pex.GetPeer(testPeers[0])
+	require.True(t, ok)
+	require.Equal(t, int32(4), p.ProtocolVersion)
+	require.Equal(t, uint64(3), p.Features)
+	require.Equal(t, testTransportKey, p.TransportKey)
+	require.Equal(t, 2, p.HandshakeAttempts)
+	require.Equal(t, 1, p.HandshakeSuccesses)
+	require.Equal(t, time.Minute*2, p.Uptime)
+	require.Equal(t, 1, p.BlockResponses)
+	require.Equal(t, time.Second, p.BlockLatency)
+
+	// The peer with the best score is returned first
+	pex.RecordHandshake(testPeers[1], false)
+	require.Equal(t, []string{testPeers[0], testPeers[1]}, pex.RandomPublic(0).ToAddrs())
+	require.Equal(t, []string{testPeers[0]}, pex.RandomPublic(1).ToAddrs())
+}
@@ -1,8 +1,14 @@
 package daemon
 
 import (
+	"errors"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/skycoin/skycoin/src/util/iputil"
+
+	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/daemon/gnet"
 )
 
@@ -30,9 +36,32 @@ type PoolConfig struct {
 	MaxDefaultPeerOutgoingConnections int
 	// Default "trusted" peers
 	DefaultConnections []string
+	// Maximum bytes per second sent to all peers. 0 for no limit
+	UploadRateLimit int
+	// Maximum bytes per second received from all peers. 0 for no limit
+	DownloadRateLimit int
+	// Maximum bytes per second sent to each peer. 0 for no limit
+	PeerUploadRateLimit int
+	// Maximum bytes per second received from each peer. 0 for no limit
+	PeerDownloadRateLimit int
+	// Minimum size of the messages compressed for the peers that negotiated compression
+	CompressionThreshold int
 	// These should be assigned by the controlling daemon
-	address string
-	port    int
+	address      string
+	port         int
+	proxyAddress string
+	disableIPv4  bool
+	disableIPv6  bool
+	// IPs of whitelisted peers
+	whitelist                 []string
+	maxWhitelistedConnections int
+	encryptedTransport        bool
+	transportKey              cipher.SecKey
+	transportFeatures         PeerFeatures
+	// Additional listeners
+	listeners []gnet.ListenerConfig
+	// In-memory network of a Simulation, nil for TCP
+	transport gnet.Transport
 }
 
 // NewPoolConfig creates pool config
@@ -50,6 +79,7 @@ func NewPoolConfig() PoolConfig {
 		MaxConnections:                    128,
 		MaxOutgoingConnections:            8,
 		MaxDefaultPeerOutgoingConnections: 1,
+		CompressionThreshold:              1024,
 	}
 }
 
@@ -65,13 +95,29 @@ func NewPool(cfg PoolConfig, d *Daemon) (*Pool, error) {
 	gnetCfg.DialTimeout = cfg.DialTimeout
 	gnetCfg.Port = uint16(cfg.port)
 	gnetCfg.Address = cfg.address
+	gnetCfg.ProxyAddress = cfg.proxyAddress
+	gnetCfg.DisableIPv4 = cfg.disableIPv4
+	gnetCfg.DisableIPv6 = cfg.disableIPv6
 	gnetCfg.ConnectCallback = d.onGnetConnect
 	gnetCfg.DisconnectCallback = d.onGnetDisconnect
 	gnetCfg.ConnectFailureCallback = d.onGnetConnectFailure
+	gnetCfg.EvictCallback = d.onGnetEvict
 	gnetCfg.MaxConnections = cfg.MaxConnections
 	gnetCfg.MaxOutgoingConnections = cfg.MaxOutgoingConnections
 	gnetCfg.MaxDefaultPeerOutgoingConnections = cfg.MaxDefaultPeerOutgoingConnections
 	gnetCfg.DefaultConnections = cfg.DefaultConnections
+	gnetCfg.UploadRateLimit = cfg.UploadRateLimit
+	gnetCfg.DownloadRateLimit = cfg.DownloadRateLimit
+	gnetCfg.PeerUploadRateLimit = cfg.PeerUploadRateLimit
+	gnetCfg.PeerDownloadRateLimit = cfg.PeerDownloadRateLimit
+	gnetCfg.Whitelist = cfg.whitelist
+	gnetCfg.MaxWhitelistedConnections = cfg.maxWhitelistedConnections
+	gnetCfg.EncryptedTransport = cfg.encryptedTransport
+	gnetCfg.EncryptedTransportKey = cfg.transportKey
+	gnetCfg.EncryptedTransportFeatures = uint64(cfg.transportFeatures)
+	gnetCfg.CompressionThreshold = cfg.CompressionThreshold
+	gnetCfg.Listeners = cfg.listeners
+	gnetCfg.Transport = cfg.transport
 
 	pool, err := gnet.NewConnectionPool(gnetCfg, d)
 	if err != nil {
@@ -84,6 +130,33 @@ func NewPool(cfg PoolConfig, d *Daemon) (*Pool, error) {
 	}, nil
 }
 
+// parseListenAddress parses a listen address of the form "ip:port" or "ip:port/max"
+func parseListenAddress(s string) (gnet.ListenerConfig, error) {
+	var l gnet.ListenerConfig
+
+	addr := s
+	if i := strings.LastIndex(s, "/"); i != -1 {
+		max, err := strconv.Atoi(s[i+1:])
+		if err != nil || max < 0 {
+			return gnet.ListenerConfig{}, errors.New("Invalid max connections")
+		}
+		l.MaxConnections = max
+		addr = s[:i]
+	}
+
+	ip, port, err := iputil.SplitAddr(addr)
+	if err != nil {
+		return gnet.ListenerConfig{}, err
+	}
+	if port == 0 {
+		return gnet.ListenerConfig{}, errors.New("Port must not be 0")
+	}
+
+	l.Address = ip
+	l.Port = port
+	return l, nil
+}
+
 // Shutdown closes all connections and stops listening
 func (pool *Pool) Shutdown() {
 	if pool == nil {
@@ -0,0 +1,65 @@
+package daemon
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/daemon/gnet"
+	"github.com/skycoin/skycoin/src/util/iputil"
+)
+
+func TestParseListenAddress(t *testing.T) {
+	cases := []struct {
+		addr string
+		l    gnet.ListenerConfig
+		err  error
+	}{
+		{
+			addr: "127.0.0.1:6001",
+			l:    gnet.ListenerConfig{Address: "127.0.0.1", Port: 6001},
+		},
+		{
+			addr: "192.168.1.2:6000/16",
+			l:    gnet.ListenerConfig{Address: "192.168.1.2", Port: 6000, MaxConnections: 16},
+		},
+		{
+			addr: "[::1]:6001/0",
+			l:    gnet.ListenerConfig{Address: "::1", Port: 6001},
+		},
+		{
+			addr: "127.0.0.1:6001/-1",
+			err:  errors.New("Invalid max connections"),
+		},
+		{
+			addr: "127.0.0.1:6001/",
+			err:  errors.New("Invalid max connections"),
+		},
+		{
+			addr: ":6001",
+			err:  iputil.ErrMissingIP,
+		},
+		{
+			addr: "127.0.0.1:0",
+			err:  errors.New("Port must not be 0"),
+		},
+		{
+			addr: "127.0.0.1:66000",
+			err:  iputil.ErrInvalidPort,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.addr, func(t *testing.T) {
+			l, err := parseListenAddress(tc.addr)
+			if tc.err != nil {
+				require.Equal(t, tc.err, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.l, l)
+		})
+	}
+}
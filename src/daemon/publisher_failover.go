@@ -0,0 +1,103 @@
+package daemon
+
+import (
+	"sync"
+	"time"
+)
+
+// publisherFailover decides when a block publisher creates blocks, so that a standby publisher configured with
+// the same blockchain secret key takes over block creation when the active publisher stops, without both of them
+// publishing blocks. The publishers follow the same signing policy:
+//   - a publisher that receives a block from its peers, created by the other publisher, becomes passive
+//   - a passive publisher becomes active once no block was received for the failover timeout while
+//     valid transactions were waiting in the unconfirmed pool for as long
+//   - a publisher does not create a block while a peer reports a longer blockchain, which the block would fork
+//
+// A nil *publisherFailover always creates blocks.
+type publisherFailover struct {
+	sync.Mutex
+	timeout           time.Duration
+	active            bool
+	lastBlockReceived time.Time
+	pendingSince      time.Time
+}
+
+// newPublisherFailover creates a publisherFailover, active unless standby.
+// The silence of the other publisher is measured from now. Returns nil if timeout is 0
+func newPublisherFailover(standby bool, timeout time.Duration, now time.Time) *publisherFailover {
+	if timeout == 0 {
+		return nil
+	}
+
+	return &publisherFailover{
+		timeout:           timeout,
+		active:            !standby,
+		lastBlockReceived: now,
+	}
+}
+
+// blockReceived records that a block created by the other publisher was received, which makes this publisher passive
+func (f *publisherFailover) blockReceived(now time.Time) {
+	if f == nil {
+		return
+	}
+
+	f.Lock()
+	defer f.Unlock()
+
+	if f.active {
+		logger.Critical().Warning("Received a block from another block publisher, stepping down to standby")
+	}
+
+	f.active = false
+	f.lastBlockReceived = now
+	f.pendingSince = time.Time{}
+}
+
+// canPublish returns true if a block can be created now. hasPending is true if there are valid
+// unconfirmed transactions to publish, behind is true if a peer reports a longer blockchain
+func (f *publisherFailover) canPublish(now time.Time, hasPending, behind bool) bool {
+	if f == nil {
+		return true
+	}
+
+	f.Lock()
+	defer f.Unlock()
+
+	if behind {
+		return false
+	}
+
+	if f.active {
+		return true
+	}
+
+	if !hasPending {
+		f.pendingSince = time.Time{}
+		return false
+	}
+
+	if f.pendingSince.IsZero() {
+		f.pendingSince = now
+	}
+
+	if now.Sub(f.pendingSince) < f.timeout || now.Sub(f.lastBlockReceived) < f.timeout {
+		return false
+	}
+
+	logger.Critical().WithField("silence", now.Sub(f.lastBlockReceived)).Warning("The active block publisher is silent, taking over block creation")
+	f.active = true
+	return true
+}
+
+// isActive returns true if this publisher creates blocks
+func (f *publisherFailover) isActive() bool {
+	if f == nil {
+		return true
+	}
+
+	f.Lock()
+	defer f.Unlock()
+
+	return f.active
+}
@@ -0,0 +1,58 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublisherFailover(t *testing.T) {
+	start := time.Now()
+	timeout := time.Minute
+
+	// A nil publisherFailover always creates blocks
+	var f *publisherFailover
+	require.Nil(t, newPublisherFailover(true, 0, start))
+	require.True(t, f.canPublish(start, false, true))
+	require.True(t, f.isActive())
+	f.blockReceived(start)
+
+	// The primary publisher creates blocks until it receives a block from the standby
+	primary := newPublisherFailover(false, timeout, start)
+	require.True(t, primary.canPublish(start, true, false))
+	require.False(t, primary.canPublish(start, true, true))
+	primary.blockReceived(start.Add(time.Second))
+	require.False(t, primary.isActive())
+	require.False(t, primary.canPublish(start.Add(time.Second*2), true, false))
+
+	// The standby publisher waits for the active publisher to be silent
+	standby := newPublisherFailover(true, timeout, start)
+	require.False(t, standby.isActive())
+	require.False(t, standby.canPublish(start.Add(timeout*2), false, false))
+
+	// Transactions must be pending for the timeout too
+	now := start.Add(timeout * 3)
+	require.False(t, standby.canPublish(now, true, false))
+	require.False(t, standby.canPublish(now.Add(timeout/2), true, false))
+
+	// The pending transactions were published in a block by the active publisher
+	standby.blockReceived(now.Add(timeout / 2))
+	require.False(t, standby.canPublish(now.Add(timeout), true, false))
+	require.False(t, standby.canPublish(now.Add(timeout*3/2), true, false))
+
+	// The active publisher is silent for the timeout, while transactions were pending, but a peer has a longer blockchain
+	now = now.Add(timeout * 2)
+	require.False(t, standby.canPublish(now, true, true))
+	require.False(t, standby.isActive())
+
+	// The standby takes over
+	require.True(t, standby.canPublish(now, true, false))
+	require.True(t, standby.isActive())
+	require.True(t, standby.canPublish(now.Add(time.Second), false, false))
+
+	// It steps down when the other publisher publishes again
+	standby.blockReceived(now.Add(time.Second * 2))
+	require.False(t, standby.isActive())
+	require.False(t, standby.canPublish(now.Add(time.Second*3), true, false))
+}
@@ -0,0 +1,180 @@
+package daemon
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+// RebroadcastStats is the broadcast history of a transaction injected by this node
+type RebroadcastStats struct {
+	Txid cipher.SHA256
+	// Time the transaction was injected
+	Added time.Time
+	// Number of times the transaction was broadcast, including when it was injected
+	Broadcasts    int
+	LastBroadcast time.Time
+	NextBroadcast time.Time
+	// Time after which the transaction is no longer rebroadcast
+	Expires time.Time
+}
+
+// rebroadcaster schedules the rebroadcasts of the transactions injected by this node until they are confirmed,
+// since the peers they were broadcast to may have dropped them. The delay between the broadcasts of a transaction
+// starts at interval and doubles after each rebroadcast, up to maxInterval, so that a transaction which is slow
+// to confirm does not flood the network. A transaction is no longer rebroadcast after expiry.
+// A nil *rebroadcaster does not rebroadcast transactions.
+type rebroadcaster struct {
+	sync.Mutex
+	interval    time.Duration
+	maxInterval time.Duration
+	expiry      time.Duration
+	txns        map[cipher.SHA256]*RebroadcastStats
+}
+
+// newRebroadcaster creates a rebroadcaster. Returns nil if interval is 0
+func newRebroadcaster(interval, maxInterval, expiry time.Duration) *rebroadcaster {
+	if interval == 0 {
+		return nil
+	}
+
+	return &rebroadcaster{
+		interval:    interval,
+		maxInterval: maxInterval,
+		expiry:      expiry,
+		txns:        make(map[cipher.SHA256]*RebroadcastStats),
+	}
+}
+
+// add schedules the rebroadcasts of a transaction broadcast at now
+func (r *rebroadcaster) add(txid cipher.SHA256, now time.Time) {
+	if r == nil {
+		return
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.txns[txid]; ok {
+		return
+	}
+
+	r.txns[txid] = &RebroadcastStats{
+		Txid:          txid,
+		Added:         now,
+		Broadcasts:    1,
+		LastBroadcast: now,
+		NextBroadcast: now.Add(r.interval),
+		Expires:       now.Add(r.expiry),
+	}
+}
+
+// due returns the transactions to rebroadcast at now, sorted by txid, and stops rebroadcasting
+// the expired transactions, which are returned separately
+func (r *rebroadcaster) due(now time.Time) ([]cipher.SHA256, []cipher.SHA256) {
+	if r == nil {
+		return nil, nil
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	var due, expired []cipher.SHA256
+	for txid, s := range r.txns {
+		switch {
+		case !now.Before(s.Expires):
+			delete(r.txns, txid)
+			expired = append(expired, txid)
+		case !now.Before(s.NextBroadcast):
+			due = append(due, txid)
+		}
+	}
+
+	sortTxids(due)
+	sortTxids(expired)
+
+	return due, expired
+}
+
+// broadcast records a rebroadcast of a transaction at now and doubles its delay until the next rebroadcast
+func (r *rebroadcaster) broadcast(txid cipher.SHA256, now time.Time) {
+	if r == nil {
+		return
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	s, ok := r.txns[txid]
+	if !ok {
+		return
+	}
+
+	delay := s.NextBroadcast.Sub(s.LastBroadcast) * 2
+	if delay > r.maxInterval {
+		delay = r.maxInterval
+	}
+
+	s.Broadcasts++
+	s.LastBroadcast = now
+	s.NextBroadcast = now.Add(delay)
+}
+
+// remove stops rebroadcasting a transaction, once it is confirmed or no longer in the unconfirmed pool
+func (r *rebroadcaster) remove(txid cipher.SHA256) {
+	if r == nil {
+		return
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	delete(r.txns, txid)
+}
+
+// stats returns the RebroadcastStats of a transaction, false if it is not being rebroadcast
+func (r *rebroadcaster) stats(txid cipher.SHA256) (RebroadcastStats, bool) {
+	if r == nil {
+		return RebroadcastStats{}, false
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	s, ok := r.txns[txid]
+	if !ok {
+		return RebroadcastStats{}, false
+	}
+
+	return *s, true
+}
+
+// all returns the RebroadcastStats of the transactions being rebroadcast, sorted by txid
+func (r *rebroadcaster) all() []RebroadcastStats {
+	if r == nil {
+		return nil
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	stats := make([]RebroadcastStats, 0, len(r.txns))
+	for _, s := range r.txns {
+		stats = append(stats, *s)
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		return bytes.Compare(stats[i].Txid[:], stats[j].Txid[:]) < 0
+	})
+
+	return stats
+}
+
+func sortTxids(txids []cipher.SHA256) {
+	sort.Slice(txids, func(i, j int) bool {
+		return bytes.Compare(txids[i][:], txids[j][:]) < 0
+	})
+}
@@ -0,0 +1,80 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/testutil"
+)
+
+func TestRebroadcaster(t *testing.T) {
+	now := time.Now().UTC()
+	txid := testutil.RandSHA256(t)
+
+	// A nil rebroadcaster does not rebroadcast transactions
+	var r *rebroadcaster
+	r.add(txid, now)
+	due, expired := r.due(now.Add(time.Hour))
+	require.Empty(t, due)
+	require.Empty(t, expired)
+	_, ok := r.stats(txid)
+	require.False(t, ok)
+	require.Empty(t, r.all())
+
+	require.Nil(t, newRebroadcaster(0, time.Hour, time.Hour))
+
+	r = newRebroadcaster(time.Minute, time.Minute*5, time.Hour)
+	r.add(txid, now)
+
+	s, ok := r.stats(txid)
+	require.True(t, ok)
+	require.Equal(t, RebroadcastStats{
+		Txid:          txid,
+		Added:         now,
+		Broadcasts:    1,
+		LastBroadcast: now,
+		NextBroadcast: now.Add(time.Minute),
+		Expires:       now.Add(time.Hour),
+	}, s)
+
+	// Adding a transaction again does not reset its history
+	r.add(txid, now.Add(time.Second))
+	s, _ = r.stats(txid)
+	require.Equal(t, now, s.Added)
+
+	due, expired = r.due(now.Add(time.Second * 59))
+	require.Empty(t, due)
+	require.Empty(t, expired)
+
+	// The delay doubles after each rebroadcast, up to the max interval
+	at := now
+	for i, delay := range []time.Duration{time.Minute, time.Minute * 2, time.Minute * 4, time.Minute * 5, time.Minute * 5} {
+		at = at.Add(delay)
+		due, _ = r.due(at)
+		require.Equal(t, []cipher.SHA256{txid}, due)
+
+		r.broadcast(txid, at)
+		s, _ = r.stats(txid)
+		require.Equal(t, i+2, s.Broadcasts)
+		require.Equal(t, at, s.LastBroadcast)
+	}
+	s, _ = r.stats(txid)
+	require.Equal(t, at.Add(time.Minute*5), s.NextBroadcast)
+
+	txid2 := testutil.RandSHA256(t)
+	r.add(txid2, now.Add(time.Minute*30))
+	require.Len(t, r.all(), 2)
+
+	// A transaction is no longer rebroadcast after the expiry
+	due, expired = r.due(now.Add(time.Hour))
+	require.Equal(t, []cipher.SHA256{txid2}, due)
+	require.Equal(t, []cipher.SHA256{txid}, expired)
+	_, ok = r.stats(txid)
+	require.False(t, ok)
+
+	r.remove(txid2)
+	require.Empty(t, r.all())
+}
@@ -0,0 +1,133 @@
+package daemon
+
+import (
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/util/fee"
+)
+
+// RelayRejectReason is the reason a transaction received from a peer was rejected by the relay policy
+type RelayRejectReason string
+
+const (
+	// RelayRejectBlocksOnly the node runs in blocks-only mode and does not accept transactions from peers
+	RelayRejectBlocksOnly RelayRejectReason = "blocks_only"
+	// RelayRejectTooLarge the transaction is larger than RelayPolicy.MaxTransactionSize
+	RelayRejectTooLarge RelayRejectReason = "too_large"
+	// RelayRejectTooManyOutputs the transaction has more outputs than RelayPolicy.MaxOutputs
+	RelayRejectTooManyOutputs RelayRejectReason = "too_many_outputs"
+	// RelayRejectDust the transaction creates an output of fewer droplets than RelayPolicy.DustThreshold
+	RelayRejectDust RelayRejectReason = "dust"
+	// RelayRejectInsufficientFee the transaction does not burn enough coinhours for RelayPolicy.MinBurnFactor
+	RelayRejectInsufficientFee RelayRejectReason = "insufficient_fee"
+)
+
+// RelayPolicy is the policy applied to the transactions received from peers, before they are
+// injected into the unconfirmed pool and relayed. The zero value of a limit disables it
+type RelayPolicy struct {
+	// Reject all transactions received from peers
+	BlocksOnly bool
+	// Minimum coinhour burn factor. Applied in addition to DaemonConfig.UnconfirmedBurnFactor
+	MinBurnFactor uint32
+	// Maximum transaction size in bytes
+	MaxTransactionSize uint32
+	// Maximum number of outputs of a transaction
+	MaxOutputs int
+	// Minimum number of droplets of a transaction output
+	DustThreshold uint64
+}
+
+// check returns the reason a transaction is rejected by the policy, if any.
+// feeCalc is only called if a minimum burn factor is set.
+// If the fee can't be calculated, e.g. because the inputs are unknown, the fee is not checked,
+// the transaction is rejected by the unconfirmed pool instead
+func (p RelayPolicy) check(txn coin.Transaction, feeCalc coin.FeeCalculator) (RelayRejectReason, bool) {
+	if p.BlocksOnly {
+		return RelayRejectBlocksOnly, true
+	}
+
+	if p.MaxTransactionSize != 0 {
+		size, err := txn.Size()
+		if err != nil || size > p.MaxTransactionSize {
+			return RelayRejectTooLarge, true
+		}
+	}
+
+	if p.MaxOutputs != 0 && len(txn.Out) > p.MaxOutputs {
+		return RelayRejectTooManyOutputs, true
+	}
+
+	if p.DustThreshold != 0 {
+		for _, o := range txn.Out {
+			if o.Coins < p.DustThreshold {
+				return RelayRejectDust, true
+			}
+		}
+	}
+
+	if p.MinBurnFactor != 0 {
+		f, err := feeCalc(&txn)
+		if err != nil {
+			logger.WithError(err).WithField("txid", txn.Hash().Hex()).Debug("RelayPolicy: transaction fee can't be calculated")
+			return "", false
+		}
+
+		switch fee.VerifyTransactionFee(&txn, f, p.MinBurnFactor) {
+		case fee.ErrTxnNoFee, fee.ErrTxnInsufficientFee:
+			return RelayRejectInsufficientFee, true
+		}
+	}
+
+	return "", false
+}
+
+// relayRejections counts the transactions rejected by the relay policy, by reason
+type relayRejections struct {
+	sync.Mutex
+	counts map[RelayRejectReason]uint64
+}
+
+func newRelayRejections() *relayRejections {
+	return &relayRejections{
+		counts: make(map[RelayRejectReason]uint64),
+	}
+}
+
+func (r *relayRejections) add(reason RelayRejectReason) {
+	r.Lock()
+	defer r.Unlock()
+	r.counts[reason]++
+}
+
+// get returns a copy of the counts
+func (r *relayRejections) get() map[RelayRejectReason]uint64 {
+	r.Lock()
+	defer r.Unlock()
+
+	counts := make(map[RelayRejectReason]uint64, len(r.counts))
+	for k, v := range r.counts {
+		counts[k] = v
+	}
+	return counts
+}
+
+// checkRelayPolicy applies the relay policy to a transaction received from a peer.
+// Returns true if the transaction is rejected, and counts the rejection
+func (dm *Daemon) checkRelayPolicy(txn coin.Transaction) bool {
+	reason, rejected := dm.Config.relayPolicy().check(txn, dm.visor.TransactionFee)
+	if !rejected {
+		return false
+	}
+
+	dm.relayRejections.add(reason)
+
+	logger.WithFields(logrus.Fields{
+		"txid":   txn.Hash().Hex(),
+		"reason": reason,
+	}).Debug("Transaction rejected by the relay policy")
+
+	return true
+}
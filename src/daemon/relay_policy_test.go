@@ -0,0 +1,142 @@
+package daemon
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+)
+
+func TestRelayPolicyCheck(t *testing.T) {
+	var txn coin.Transaction
+	txn.PushOutput(cipher.Address{}, 1e6, 100)
+	txn.PushOutput(cipher.Address{}, 1e3, 100)
+
+	size, err := txn.Size()
+	require.NoError(t, err)
+
+	feeCalc := func(f uint64, err error) coin.FeeCalculator {
+		return func(*coin.Transaction) (uint64, error) {
+			return f, err
+		}
+	}
+
+	cases := []struct {
+		name     string
+		policy   RelayPolicy
+		feeCalc  coin.FeeCalculator
+		reason   RelayRejectReason
+		rejected bool
+	}{
+		{
+			name:    "no limits",
+			feeCalc: feeCalc(0, nil),
+		},
+		{
+			name: "blocks only",
+			policy: RelayPolicy{
+				BlocksOnly: true,
+			},
+			reason:   RelayRejectBlocksOnly,
+			rejected: true,
+		},
+		{
+			name: "too large",
+			policy: RelayPolicy{
+				MaxTransactionSize: size - 1,
+			},
+			reason:   RelayRejectTooLarge,
+			rejected: true,
+		},
+		{
+			name: "max size",
+			policy: RelayPolicy{
+				MaxTransactionSize: size,
+			},
+		},
+		{
+			name: "too many outputs",
+			policy: RelayPolicy{
+				MaxOutputs: 1,
+			},
+			reason:   RelayRejectTooManyOutputs,
+			rejected: true,
+		},
+		{
+			name: "dust",
+			policy: RelayPolicy{
+				DustThreshold: 1e3 + 1,
+			},
+			reason:   RelayRejectDust,
+			rejected: true,
+		},
+		{
+			name: "dust threshold",
+			policy: RelayPolicy{
+				DustThreshold: 1e3,
+			},
+		},
+		{
+			name: "insufficient fee",
+			policy: RelayPolicy{
+				MinBurnFactor: 4,
+			},
+			feeCalc:  feeCalc(50, nil),
+			reason:   RelayRejectInsufficientFee,
+			rejected: true,
+		},
+		{
+			name: "no fee",
+			policy: RelayPolicy{
+				MinBurnFactor: 4,
+			},
+			feeCalc:  feeCalc(0, nil),
+			reason:   RelayRejectInsufficientFee,
+			rejected: true,
+		},
+		{
+			name: "sufficient fee",
+			policy: RelayPolicy{
+				MinBurnFactor: 4,
+			},
+			feeCalc: feeCalc(100, nil),
+		},
+		{
+			name: "fee calculation failed",
+			policy: RelayPolicy{
+				MinBurnFactor: 4,
+			},
+			feeCalc: feeCalc(0, errors.New("unspent output does not exist")),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reason, rejected := tc.policy.check(txn, tc.feeCalc)
+			require.Equal(t, tc.rejected, rejected)
+			require.Equal(t, tc.reason, reason)
+		})
+	}
+}
+
+func TestRelayRejections(t *testing.T) {
+	r := newRelayRejections()
+	require.Empty(t, r.get())
+
+	r.add(RelayRejectDust)
+	r.add(RelayRejectDust)
+	r.add(RelayRejectTooLarge)
+
+	counts := r.get()
+	require.Equal(t, map[RelayRejectReason]uint64{
+		RelayRejectDust:     2,
+		RelayRejectTooLarge: 1,
+	}, counts)
+
+	// The counts returned are a copy
+	counts[RelayRejectDust] = 0
+	require.Equal(t, uint64(2), r.get()[RelayRejectDust])
+}
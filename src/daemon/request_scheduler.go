@@ -0,0 +1,229 @@
+package daemon
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+// txnRequest is a transaction requested from a peer and not received yet
+type txnRequest struct {
+	// Peer the transaction is requested from. Empty if the peer disconnected before responding
+	addr    string
+	created time.Time
+	// Number of times the transaction was requested from another peer after a request timed out
+	retries int
+	// Peers that announced the transaction and were not requested it yet, in the order they announced it
+	alternates []string
+}
+
+// requestScheduler tracks the transactions requested from peers. A transaction announced by several peers
+// is only requested from one peer at a time. If the peer does not send it in time, it is requested from
+// the next peer that announced it. Peers that consistently don't respond to requests are reported as stalled
+type requestScheduler struct {
+	sync.Mutex
+	timeout    time.Duration
+	maxRetries int
+	// Number of consecutive requests a peer must leave unanswered to be reported as stalled. 0 disables the reports
+	stallThreshold int
+	// Pending transaction requests by hash
+	txns map[cipher.SHA256]*txnRequest
+	// Number of consecutive requests each peer did not respond to
+	stalls map[string]int
+}
+
+func newRequestScheduler(timeout time.Duration, maxRetries, stallThreshold int) *requestScheduler {
+	return &requestScheduler{
+		timeout:        timeout,
+		maxRetries:     maxRetries,
+		stallThreshold: stallThreshold,
+		txns:           make(map[cipher.SHA256]*txnRequest),
+		stalls:         make(map[string]int),
+	}
+}
+
+// announced records the transactions announced by a peer and returns the ones to request from it.
+// The transactions already requested from another peer are not returned, the peer is an alternate
+// to request them from if the request times out
+func (s *requestScheduler) announced(addr string, hashes []cipher.SHA256, now time.Time) []cipher.SHA256 {
+	s.Lock()
+	defer s.Unlock()
+
+	var request []cipher.SHA256
+	for _, h := range hashes {
+		r, ok := s.txns[h]
+		if !ok {
+			s.txns[h] = &txnRequest{
+				addr:    addr,
+				created: now,
+			}
+			request = append(request, h)
+			continue
+		}
+
+		if r.addr == addr || inStringSlice(r.alternates, addr) {
+			continue
+		}
+
+		r.alternates = append(r.alternates, addr)
+	}
+
+	return request
+}
+
+// received records the transactions received from a peer. The pending requests of the transactions are removed,
+// whichever peer they were requested from. A peer that sends a transaction requested from it is not stalled anymore
+func (s *requestScheduler) received(addr string, hashes []cipher.SHA256) {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, h := range hashes {
+		r, ok := s.txns[h]
+		if !ok {
+			continue
+		}
+
+		if r.addr == addr {
+			delete(s.stalls, addr)
+		}
+
+		delete(s.txns, h)
+	}
+}
+
+// responded records that a peer responded to a request that is not tracked by the scheduler,
+// e.g. a header-first synchronization request, so that the peer is not stalled anymore
+func (s *requestScheduler) responded(addr string) {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.stalls, addr)
+}
+
+// stall records that a peer did not respond to a request in time. Returns true once the peer
+// left stallThreshold consecutive requests unanswered, after which its count starts over
+func (s *requestScheduler) stall(addr string) bool {
+	s.Lock()
+	defer s.Unlock()
+	return s.addStall(addr)
+}
+
+func (s *requestScheduler) addStall(addr string) bool {
+	if s.stallThreshold == 0 {
+		return false
+	}
+
+	s.stalls[addr]++
+	if s.stalls[addr] < s.stallThreshold {
+		return false
+	}
+
+	delete(s.stalls, addr)
+	return true
+}
+
+// retry returns the transactions to request again by peer, for the requests that timed out or whose peer
+// disconnected, and the peers reported as stalled. A transaction is requested again from the next peer that
+// announced it, at most maxRetries times. The transactions that can't be requested again are forgotten.
+// A peer that did not send several transactions in time counts as one unanswered request
+func (s *requestScheduler) retry(now time.Time) (map[string][]cipher.SHA256, []string) {
+	s.Lock()
+	defer s.Unlock()
+
+	timedOut := make(map[string]struct{})
+	retries := make(map[string][]cipher.SHA256)
+	for h, r := range s.txns {
+		if r.addr != "" && now.Sub(r.created) < s.timeout {
+			continue
+		}
+
+		if r.addr != "" {
+			timedOut[r.addr] = struct{}{}
+			r.retries++
+		}
+
+		if len(r.alternates) == 0 || r.retries > s.maxRetries {
+			delete(s.txns, h)
+			continue
+		}
+
+		r.addr = r.alternates[0]
+		r.alternates = r.alternates[1:]
+		r.created = now
+		retries[r.addr] = append(retries[r.addr], h)
+	}
+
+	var stalled []string
+	for addr := range timedOut {
+		if s.addStall(addr) {
+			stalled = append(stalled, addr)
+		}
+	}
+	sort.Strings(stalled)
+
+	return retries, stalled
+}
+
+// removePeer removes a disconnected peer from the alternates of the pending requests. The transactions
+// requested from the peer are requested from an alternate peer on the next retry, without counting as a retry
+func (s *requestScheduler) removePeer(addr string) {
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.stalls, addr)
+
+	for _, r := range s.txns {
+		if r.addr == addr {
+			r.addr = ""
+		}
+
+		for i, a := range r.alternates {
+			if a == addr {
+				r.alternates = append(r.alternates[:i], r.alternates[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+func inStringSlice(ss []string, s string) bool {
+	for _, x := range ss {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// scheduleTxnRequests records the unknown transactions announced by a peer and returns the ones to request from it
+func (dm *Daemon) scheduleTxnRequests(addr string, hashes []cipher.SHA256) []cipher.SHA256 {
+	return dm.requestScheduler.announced(addr, hashes, time.Now())
+}
+
+// txnsReceived records the transactions received from a peer, so that they are not requested again
+func (dm *Daemon) txnsReceived(addr string, hashes []cipher.SHA256) {
+	dm.requestScheduler.received(addr, hashes)
+}
+
+// retryRequests requests the transactions whose requests timed out from other peers that announced them,
+// and penalizes the peers that consistently leave transaction or header-first synchronization requests unanswered
+func (dm *Daemon) retryRequests() {
+	retries, stalled := dm.requestScheduler.retry(time.Now())
+
+	for addr, hashes := range retries {
+		if err := dm.sendMessage(addr, NewGetTxnsMessage(hashes)); err != nil {
+			logger.WithError(err).WithField("addr", addr).Warning("Send GetTxnsMessage retry failed")
+		}
+	}
+
+	for _, addr := range dm.headerSync.takeStalled() {
+		if dm.requestScheduler.stall(addr) {
+			stalled = append(stalled, addr)
+		}
+	}
+
+	for _, addr := range stalled {
+		dm.recordPeerMisbehavior(addr, PeerMisbehaviorStalledRequests)
+	}
+}
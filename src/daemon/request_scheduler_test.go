@@ -0,0 +1,115 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+func TestRequestSchedulerAnnounced(t *testing.T) {
+	s := newRequestScheduler(time.Second*10, 2, 3)
+	now := time.Now()
+	h1 := cipher.SumSHA256([]byte("1"))
+	h2 := cipher.SumSHA256([]byte("2"))
+
+	require.Equal(t, []cipher.SHA256{h1}, s.announced("1.1.1.1:6000", []cipher.SHA256{h1}, now))
+
+	// The transactions in flight are not requested again
+	require.Equal(t, []cipher.SHA256{h2}, s.announced("2.2.2.2:6000", []cipher.SHA256{h1, h2}, now))
+	require.Empty(t, s.announced("1.1.1.1:6000", []cipher.SHA256{h1}, now))
+	require.Empty(t, s.announced("3.3.3.3:6000", []cipher.SHA256{h1, h2}, now))
+	require.Empty(t, s.announced("3.3.3.3:6000", []cipher.SHA256{h1}, now))
+
+	require.Equal(t, "1.1.1.1:6000", s.txns[h1].addr)
+	require.Equal(t, []string{"2.2.2.2:6000", "3.3.3.3:6000"}, s.txns[h1].alternates)
+	require.Equal(t, []string{"3.3.3.3:6000"}, s.txns[h2].alternates)
+
+	// A received transaction is forgotten, whichever peer sent it
+	s.received("3.3.3.3:6000", []cipher.SHA256{h1})
+	require.Len(t, s.txns, 1)
+	require.Equal(t, []cipher.SHA256{h1}, s.announced("3.3.3.3:6000", []cipher.SHA256{h1}, now))
+}
+
+func TestRequestSchedulerRetry(t *testing.T) {
+	s := newRequestScheduler(time.Second*10, 2, 2)
+	now := time.Now()
+	h1 := cipher.SumSHA256([]byte("1"))
+	h2 := cipher.SumSHA256([]byte("2"))
+
+	s.announced("1.1.1.1:6000", []cipher.SHA256{h1, h2}, now)
+	s.announced("2.2.2.2:6000", []cipher.SHA256{h1}, now)
+	s.announced("3.3.3.3:6000", []cipher.SHA256{h1}, now)
+	s.announced("4.4.4.4:6000", []cipher.SHA256{h1}, now)
+
+	// Nothing to retry before the timeout
+	retries, stalled := s.retry(now.Add(time.Second * 9))
+	require.Empty(t, retries)
+	require.Empty(t, stalled)
+
+	// h1 is requested from the next peer that announced it, h2 has no alternate and is forgotten.
+	// The peer that did not send both transactions counts as one unanswered request
+	now = now.Add(time.Second * 10)
+	retries, stalled = s.retry(now)
+	require.Equal(t, map[string][]cipher.SHA256{
+		"2.2.2.2:6000": {h1},
+	}, retries)
+	require.Empty(t, stalled)
+	require.Equal(t, map[string]int{"1.1.1.1:6000": 1}, s.stalls)
+	require.Len(t, s.txns, 1)
+
+	// A disconnected peer is requested again immediately, without counting as a retry
+	s.removePeer("2.2.2.2:6000")
+	retries, stalled = s.retry(now)
+	require.Equal(t, map[string][]cipher.SHA256{
+		"3.3.3.3:6000": {h1},
+	}, retries)
+	require.Empty(t, stalled)
+
+	now = now.Add(time.Second * 10)
+	retries, stalled = s.retry(now)
+	require.Equal(t, map[string][]cipher.SHA256{
+		"4.4.4.4:6000": {h1},
+	}, retries)
+	require.Empty(t, stalled)
+
+	// The retries are exhausted
+	s.announced("5.5.5.5:6000", []cipher.SHA256{h1}, now)
+	retries, _ = s.retry(now.Add(time.Second * 10))
+	require.Empty(t, retries)
+	require.Empty(t, s.txns)
+}
+
+func TestRequestSchedulerStall(t *testing.T) {
+	s := newRequestScheduler(time.Second*10, 2, 2)
+	now := time.Now()
+	h1 := cipher.SumSHA256([]byte("1"))
+	h2 := cipher.SumSHA256([]byte("2"))
+	h3 := cipher.SumSHA256([]byte("3"))
+
+	// A peer that leaves consecutive requests unanswered is reported as stalled
+	s.announced("1.1.1.1:6000", []cipher.SHA256{h1}, now)
+	_, stalled := s.retry(now.Add(time.Second * 10))
+	require.Empty(t, stalled)
+
+	s.announced("1.1.1.1:6000", []cipher.SHA256{h2}, now)
+	_, stalled = s.retry(now.Add(time.Second * 10))
+	require.Equal(t, []string{"1.1.1.1:6000"}, stalled)
+	require.Empty(t, s.stalls)
+
+	// Responding resets the count
+	require.False(t, s.stall("1.1.1.1:6000"))
+	s.announced("1.1.1.1:6000", []cipher.SHA256{h3}, now)
+	s.received("1.1.1.1:6000", []cipher.SHA256{h3})
+	require.False(t, s.stall("1.1.1.1:6000"))
+	s.responded("1.1.1.1:6000")
+	require.False(t, s.stall("1.1.1.1:6000"))
+	require.True(t, s.stall("1.1.1.1:6000"))
+
+	// The stall reports can be disabled
+	s = newRequestScheduler(time.Second*10, 2, 0)
+	require.False(t, s.stall("1.1.1.1:6000"))
+	require.False(t, s.stall("1.1.1.1:6000"))
+}
@@ -0,0 +1,259 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrSimAddressInUse is returned by a SimNetwork listener if another node listens on the address already
+	ErrSimAddressInUse = errors.New("address already in use")
+	// ErrSimConnectionRefused is returned when dialing an address of a SimNetwork that no node listens on,
+	// or that is cut off from the dialing node
+	ErrSimConnectionRefused = errors.New("connection refused")
+	// ErrSimListenerClosed is returned by the Accept method of a closed SimNetwork listener
+	ErrSimListenerClosed = errors.New("listener closed")
+)
+
+// SimNetwork is an in-memory network that the nodes of a Simulation connect over, instead of TCP.
+// Each node has its own IP, and the connections are made of in-memory pipes.
+// The links between two IPs can be cut to partition the network
+type SimNetwork struct {
+	sync.Mutex
+	listeners map[string]*simListener
+	// Next ephemeral port of the outgoing connections of each IP
+	ports map[string]int
+	// Cut links, keyed by the lowest IP of the link
+	cut map[string]map[string]struct{}
+	// Open connections between two IPs, keyed by the lowest IP of the link
+	conns map[string]map[string][]net.Conn
+}
+
+// NewSimNetwork creates an empty SimNetwork
+func NewSimNetwork() *SimNetwork {
+	return &SimNetwork{
+		listeners: make(map[string]*simListener),
+		ports:     make(map[string]int),
+		cut:       make(map[string]map[string]struct{}),
+		conns:     make(map[string]map[string][]net.Conn),
+	}
+}
+
+// Transport returns the gnet.Transport of the node with the IP ip
+func (n *SimNetwork) Transport(ip string) *SimTransport {
+	return &SimTransport{
+		network: n,
+		ip:      ip,
+	}
+}
+
+// Cut cuts the link between two IPs and closes the connections between them.
+// New connections between them are refused until the link is restored
+func (n *SimNetwork) Cut(a, b string) {
+	n.Lock()
+	a, b = linkKey(a, b)
+	if n.cut[a] == nil {
+		n.cut[a] = make(map[string]struct{})
+	}
+	n.cut[a][b] = struct{}{}
+
+	conns := n.conns[a][b]
+	delete(n.conns[a], b)
+	n.Unlock()
+
+	for _, c := range conns {
+		c.Close() // nolint: errcheck
+	}
+}
+
+// Restore restores the link between two IPs
+func (n *SimNetwork) Restore(a, b string) {
+	n.Lock()
+	defer n.Unlock()
+
+	a, b = linkKey(a, b)
+	delete(n.cut[a], b)
+}
+
+func (n *SimNetwork) isCut(a, b string) bool {
+	a, b = linkKey(a, b)
+	_, ok := n.cut[a][b]
+	return ok
+}
+
+func (n *SimNetwork) dial(ip, address string, timeout time.Duration) (net.Conn, error) {
+	remote, err := net.ResolveTCPAddr("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	n.Lock()
+	ln, ok := n.listeners[remote.String()]
+	if !ok || n.isCut(ip, remote.IP.String()) {
+		n.Unlock()
+		return nil, &net.OpError{
+			Op:   "dial",
+			Net:  "tcp",
+			Addr: remote,
+			Err:  ErrSimConnectionRefused,
+		}
+	}
+
+	// Ephemeral ports start at 32768, like on linux
+	n.ports[ip]++
+	local := &net.TCPAddr{
+		IP:   net.ParseIP(ip),
+		Port: 32767 + n.ports[ip],
+	}
+
+	client, server := net.Pipe()
+	clientConn := &simConn{Conn: client, local: local, remote: remote}
+	serverConn := &simConn{Conn: server, local: remote, remote: local}
+
+	a, b := linkKey(ip, remote.IP.String())
+	if n.conns[a] == nil {
+		n.conns[a] = make(map[string][]net.Conn)
+	}
+	n.conns[a][b] = append(n.conns[a][b], clientConn, serverConn)
+	n.Unlock()
+
+	var timeoutC <-chan time.Time
+	if timeout != 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		timeoutC = timer.C
+	}
+
+	select {
+	case ln.conns <- serverConn:
+		return clientConn, nil
+	case <-ln.quit:
+	case <-timeoutC:
+	}
+
+	client.Close() // nolint: errcheck
+	server.Close() // nolint: errcheck
+	return nil, &net.OpError{
+		Op:   "dial",
+		Net:  "tcp",
+		Addr: remote,
+		Err:  ErrSimConnectionRefused,
+	}
+}
+
+func (n *SimNetwork) listen(ip, address string) (net.Listener, error) {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	// A node listens on its own IP only
+	if host != "" && host != ip {
+		return nil, fmt.Errorf("%s is not the IP of the node %s", host, ip)
+	}
+
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nil, err
+	}
+
+	addr := &net.TCPAddr{
+		IP:   net.ParseIP(ip),
+		Port: port,
+	}
+	if addr.IP == nil {
+		return nil, fmt.Errorf("invalid IP %q", ip)
+	}
+
+	n.Lock()
+	defer n.Unlock()
+
+	if _, ok := n.listeners[addr.String()]; ok {
+		return nil, ErrSimAddressInUse
+	}
+
+	ln := &simListener{
+		network: n,
+		addr:    addr,
+		conns:   make(chan net.Conn),
+		quit:    make(chan struct{}),
+	}
+	n.listeners[addr.String()] = ln
+
+	return ln, nil
+}
+
+// linkKey orders the IPs of a link
+func linkKey(a, b string) (string, string) {
+	if b < a {
+		return b, a
+	}
+	return a, b
+}
+
+// SimTransport is the gnet.Transport of a node of a SimNetwork
+type SimTransport struct {
+	network *SimNetwork
+	ip      string
+}
+
+// Dial connects to a node of the SimNetwork that listens on address
+func (t *SimTransport) Dial(network, address string, timeout time.Duration) (net.Conn, error) {
+	return t.network.dial(t.ip, address, timeout)
+}
+
+// Listen listens for the connections of the other nodes of the SimNetwork
+func (t *SimTransport) Listen(network, address string) (net.Listener, error) {
+	return t.network.listen(t.ip, address)
+}
+
+// simListener is a net.Listener of a SimNetwork
+type simListener struct {
+	network   *SimNetwork
+	addr      *net.TCPAddr
+	conns     chan net.Conn
+	quit      chan struct{}
+	closeOnce sync.Once
+}
+
+func (l *simListener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.conns:
+		return c, nil
+	case <-l.quit:
+		return nil, ErrSimListenerClosed
+	}
+}
+
+func (l *simListener) Close() error {
+	l.closeOnce.Do(func() {
+		l.network.Lock()
+		delete(l.network.listeners, l.addr.String())
+		l.network.Unlock()
+		close(l.quit)
+	})
+	return nil
+}
+
+func (l *simListener) Addr() net.Addr {
+	return l.addr
+}
+
+// simConn is an end of an in-memory pipe, with the TCP addresses of the nodes it connects
+type simConn struct {
+	net.Conn
+	local  *net.TCPAddr
+	remote *net.TCPAddr
+}
+
+func (c *simConn) LocalAddr() net.Addr {
+	return c.local
+}
+
+func (c *simConn) RemoteAddr() net.Addr {
+	return c.remote
+}
@@ -0,0 +1,401 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/daemon/gnet"
+	"github.com/skycoin/skycoin/src/daemon/pex"
+	"github.com/skycoin/skycoin/src/util/useragent"
+	"github.com/skycoin/skycoin/src/visor"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+var (
+	// ErrSimulationTimeout is returned when a Simulation does not reach a state in time
+	ErrSimulationTimeout = errors.New("simulation timed out")
+)
+
+// SimulationConfig configures a Simulation
+type SimulationConfig struct {
+	// Number of nodes. The first node is the block publisher
+	Nodes int
+	// Directory the databases and the peer lists of the nodes are created in
+	DataDirectory string
+	// Seed of the blockchain and genesis keys, so that the runs of a simulation create the same blocks
+	Seed string
+	// Initial time of the clock of the nodes, and timestamp of the genesis block
+	Start time.Time
+	// Coins of the genesis block, in droplets
+	GenesisCoinVolume uint64
+	// Port all the nodes listen on. Each node has its own IP
+	Port int
+	// Called with the index and the configuration of each node before it is created, e.g. to enable
+	// a protocol change on some of the nodes only
+	Configure func(i int, c *Config)
+}
+
+// NewSimulationConfig creates a SimulationConfig with defaults set
+func NewSimulationConfig() SimulationConfig {
+	return SimulationConfig{
+		Nodes:             4,
+		Seed:              "skycoin-sim",
+		Start:             time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC),
+		GenesisCoinVolume: 100e12,
+		Port:              6000,
+	}
+}
+
+// SimClock is the clock shared by the nodes of a Simulation. It only moves when it is advanced
+type SimClock struct {
+	sync.Mutex
+	t time.Time
+}
+
+// NewSimClock creates a SimClock set to t
+func NewSimClock(t time.Time) *SimClock {
+	return &SimClock{
+		t: t.UTC(),
+	}
+}
+
+// Now returns the time of the clock
+func (c *SimClock) Now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+	return c.t
+}
+
+// Advance moves the clock forward by d
+func (c *SimClock) Advance(d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+	c.t = c.t.Add(d)
+}
+
+// Simulation runs several daemons in the same process, connected over a SimNetwork and sharing a SimClock,
+// so that protocol changes can be validated deterministically without running separate nodes.
+// The first node is the block publisher, which only creates blocks when CreateBlock is called.
+// The tickers of the daemons still run on the system clock
+type Simulation struct {
+	Config  SimulationConfig
+	Network *SimNetwork
+	Clock   *SimClock
+	Nodes   []*Daemon
+
+	genesisSeckey  cipher.SecKey
+	genesisAddress cipher.Address
+	dbs            []*dbutil.DB
+	// Connections made by Connect, as [from, to] node indexes
+	links [][2]int
+	wg    sync.WaitGroup
+}
+
+// NewSimulation creates the nodes of a simulation. The nodes are not running until Start is called
+func NewSimulation(c SimulationConfig) (*Simulation, error) {
+	if c.Nodes < 1 || c.Nodes > 254 {
+		return nil, errors.New("Nodes must be between 1 and 254")
+	}
+
+	if c.DataDirectory == "" {
+		return nil, errors.New("DataDirectory is required")
+	}
+
+	bcPubkey, bcSeckey, err := cipher.GenerateDeterministicKeyPair([]byte(c.Seed + "-blockchain"))
+	if err != nil {
+		return nil, err
+	}
+
+	genesisPubkey, genesisSeckey, err := cipher.GenerateDeterministicKeyPair([]byte(c.Seed + "-genesis"))
+	if err != nil {
+		return nil, err
+	}
+	genesisAddress := cipher.AddressFromPubKey(genesisPubkey)
+
+	// The followers need the signature of the genesis block, which only the block publisher can create
+	gb, err := coin.NewGenesisBlock(genesisAddress, c.GenesisCoinVolume, uint64(c.Start.Unix()))
+	if err != nil {
+		return nil, err
+	}
+	genesisSignature := cipher.MustSignHash(gb.HashHeader(), bcSeckey)
+
+	s := &Simulation{
+		Config:         c,
+		Network:        NewSimNetwork(),
+		Clock:          NewSimClock(c.Start),
+		genesisSeckey:  genesisSeckey,
+		genesisAddress: genesisAddress,
+	}
+
+	for i := 0; i < c.Nodes; i++ {
+		dir := filepath.Join(c.DataDirectory, fmt.Sprintf("node%d", i))
+		if err := os.MkdirAll(dir, 0750); err != nil {
+			s.closeDBs()
+			return nil, err
+		}
+
+		cfg := NewConfig()
+		cfg.Daemon.Address = s.IP(i)
+		cfg.Daemon.Port = c.Port
+		cfg.Daemon.DataDirectory = dir
+		cfg.Daemon.BlockchainPubkey = bcPubkey
+		cfg.Daemon.UserAgent = useragent.Data{
+			Coin:    "skycoin-sim",
+			Version: "0.0.0",
+		}
+		// Blocks are only created by CreateBlock
+		cfg.Daemon.BlockCreationInterval = uint64(time.Hour * 24 * 365 / time.Second)
+		// The topology is only changed by Connect
+		cfg.Pex.Disabled = true
+		cfg.Pex.DataDirectory = dir
+		cfg.Pool.transport = s.Network.Transport(s.IP(i))
+
+		cfg.Visor.DBPath = filepath.Join(dir, "data.db")
+		cfg.Visor.BlockchainPubkey = bcPubkey
+		cfg.Visor.GenesisAddress = genesisAddress
+		cfg.Visor.GenesisSignature = genesisSignature
+		cfg.Visor.GenesisTimestamp = uint64(c.Start.Unix())
+		cfg.Visor.GenesisCoinVolume = c.GenesisCoinVolume
+		if i == 0 {
+			cfg.Visor.IsBlockPublisher = true
+			cfg.Visor.BlockchainSeckey = bcSeckey
+		}
+
+		if c.Configure != nil {
+			c.Configure(i, &cfg)
+		}
+
+		db, err := visor.OpenDB(cfg.Visor.DBPath, false)
+		if err != nil {
+			s.closeDBs()
+			return nil, err
+		}
+		s.dbs = append(s.dbs, db)
+
+		// The message types are registered globally by each daemon
+		gnet.EraseMessages()
+
+		d, err := NewDaemon(cfg, db)
+		if err != nil {
+			s.closeDBs()
+			return nil, err
+		}
+		d.clock = s.Clock.Now
+
+		s.Nodes = append(s.Nodes, d)
+	}
+
+	return s, nil
+}
+
+// IP returns the IP of a node. Each node is in its own netgroup
+func (s *Simulation) IP(i int) string {
+	return fmt.Sprintf("10.%d.0.1", i+1)
+}
+
+// Addr returns the address a node listens on
+func (s *Simulation) Addr(i int) string {
+	return net.JoinHostPort(s.IP(i), strconv.Itoa(s.Config.Port))
+}
+
+// Start initializes and runs the nodes
+func (s *Simulation) Start() error {
+	for _, d := range s.Nodes {
+		if err := d.Init(); err != nil {
+			return err
+		}
+	}
+
+	for i, d := range s.Nodes {
+		s.wg.Add(1)
+		go func(i int, d *Daemon) {
+			defer s.wg.Done()
+			if err := d.Run(); err != nil {
+				logger.WithError(err).WithField("node", i).Error("Simulation node stopped")
+			}
+		}(i, d)
+	}
+
+	return nil
+}
+
+// Stop shuts down the nodes and closes their databases
+func (s *Simulation) Stop() {
+	for _, d := range s.Nodes {
+		d.Shutdown()
+	}
+	s.wg.Wait()
+	s.closeDBs()
+}
+
+func (s *Simulation) closeDBs() {
+	for _, db := range s.dbs {
+		if err := db.Close(); err != nil {
+			logger.WithError(err).Error("Simulation db.Close failed")
+		}
+	}
+	s.dbs = nil
+}
+
+// Connect adds node j to the peers of node i and makes an outgoing connection from node i to node j
+func (s *Simulation) Connect(i, j int) error {
+	d := s.Nodes[i]
+	addr := s.Addr(j)
+	if err := d.pex.AddPeer(addr); err != nil {
+		return err
+	}
+
+	p, ok := d.pex.GetPeer(addr)
+	if !ok {
+		return pex.ErrPeerNotFound
+	}
+
+	if err := d.connectToPeer(p); err != nil {
+		return err
+	}
+
+	s.links = append(s.links, [2]int{i, j})
+	return nil
+}
+
+// Cut cuts the link between two nodes, disconnecting them
+func (s *Simulation) Cut(i, j int) {
+	s.Network.Cut(s.IP(i), s.IP(j))
+
+	links := s.links[:0]
+	for _, l := range s.links {
+		if (l[0] != i || l[1] != j) && (l[0] != j || l[1] != i) {
+			links = append(links, l)
+		}
+	}
+	s.links = links
+}
+
+// Restore restores the link between two nodes. They are not reconnected until Connect is called
+func (s *Simulation) Restore(i, j int) {
+	s.Network.Restore(s.IP(i), s.IP(j))
+}
+
+// WaitFor polls cond until it returns true, or returns ErrSimulationTimeout after timeout
+func (s *Simulation) WaitFor(timeout time.Duration, cond func() bool) error {
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			return ErrSimulationTimeout
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	return nil
+}
+
+// WaitForConnections waits until both sides of the connections made by Connect are introduced
+func (s *Simulation) WaitForConnections(timeout time.Duration) error {
+	return s.WaitFor(timeout, func() bool {
+		for _, l := range s.links {
+			if !s.introduced(l[0], l[1]) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// introduced returns true if node i made a connection to node j that both nodes introduced
+func (s *Simulation) introduced(i, j int) bool {
+	return s.hasIntroduced(i, j) && s.hasIntroduced(j, i)
+}
+
+// hasIntroduced returns true if node i has an introduced connection with node j
+func (s *Simulation) hasIntroduced(i, j int) bool {
+	for _, c := range s.Nodes[i].connections.all() {
+		if c.State == ConnectionStateIntroduced && (c.Addr == s.Addr(j) || c.ListenAddr() == s.Addr(j)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HeadSeq returns the sequence of the head block of a node
+func (s *Simulation) HeadSeq(i int) (uint64, error) {
+	seq, _, err := s.Nodes[i].visor.HeadBkSeq()
+	return seq, err
+}
+
+// WaitForHeight waits until the head block of every node is at least seq
+func (s *Simulation) WaitForHeight(seq uint64, timeout time.Duration) error {
+	return s.WaitFor(timeout, func() bool {
+		for i := range s.Nodes {
+			if headSeq, err := s.HeadSeq(i); err != nil || headSeq < seq {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// Spend creates a transaction sending coins from the genesis address to an address,
+// and injects and broadcasts it from node i. It spends all the confirmed outputs of the genesis address
+// that are not spent by an unconfirmed transaction yet, so it can only be called once per block
+func (s *Simulation) Spend(i int, to cipher.Address, coins uint64) (*coin.Transaction, error) {
+	summary, err := s.Nodes[0].visor.GetUnspentOutputsSummary([]visor.OutputsFilter{
+		visor.FbyAddresses([]string{s.genesisAddress.String()}),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	spent := make(map[cipher.SHA256]struct{}, len(summary.Outgoing))
+	for _, ux := range summary.Outgoing {
+		spent[ux.Hash()] = struct{}{}
+	}
+
+	var txn coin.Transaction
+	var totalCoins, totalHours uint64
+	for _, ux := range summary.Confirmed {
+		if _, ok := spent[ux.Hash()]; ok {
+			continue
+		}
+		txn.PushInput(ux.Hash())
+		totalCoins += ux.Body.Coins
+		totalHours += ux.Body.Hours
+	}
+
+	if coins == 0 || coins >= totalCoins {
+		return nil, fmt.Errorf("Genesis address has %d droplets available, can't spend %d", totalCoins, coins)
+	}
+
+	// Half of the coin hours are burned, which satisfies the default burn factor
+	txn.PushOutput(to, coins, totalHours/4)
+	txn.PushOutput(s.genesisAddress, totalCoins-coins, totalHours/4)
+
+	keys := make([]cipher.SecKey, len(txn.In))
+	for k := range keys {
+		keys[k] = s.genesisSeckey
+	}
+	txn.SignInputs(keys)
+	if err := txn.UpdateHeader(); err != nil {
+		return nil, err
+	}
+
+	if err := s.Nodes[i].Gateway.InjectBroadcastTransaction(txn); err != nil {
+		return nil, err
+	}
+
+	return &txn, nil
+}
+
+// CreateBlock advances the clock by interval, then creates a block from the unconfirmed transactions
+// of the block publisher and publishes it
+func (s *Simulation) CreateBlock(interval time.Duration) (*coin.SignedBlock, error) {
+	s.Clock.Advance(interval)
+	return s.Nodes[0].createAndPublishBlock()
+}
@@ -0,0 +1,155 @@
+package daemon
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/testutil"
+)
+
+func TestSimulation(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping the simulation in short mode")
+	}
+
+	dir, err := ioutil.TempDir("", "skycoin-sim")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	c := NewSimulationConfig()
+	c.Nodes = 3
+	c.DataDirectory = dir
+	s, err := NewSimulation(c)
+	require.NoError(t, err)
+	require.NoError(t, s.Start())
+	defer s.Stop()
+
+	// 0 <-> 1 <-> 2, so that the blocks of the publisher are relayed by node 1
+	require.NoError(t, s.Connect(1, 0))
+	require.NoError(t, s.Connect(2, 1))
+	require.NoError(t, s.WaitForConnections(time.Second*10))
+
+	to := testutil.MakeAddress()
+	txn, err := s.Spend(2, to, 1e6)
+	require.NoError(t, err)
+
+	// The transaction of node 2 reaches the publisher through node 1
+	err = s.WaitFor(time.Second*10, func() bool {
+		known, err := s.Nodes[0].visor.GetUnconfirmedTxn(txn.Hash())
+		return err == nil && known != nil
+	})
+	require.NoError(t, err)
+
+	sb, err := s.CreateBlock(time.Second * 10)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), sb.Head.BkSeq)
+	// The block is timestamped by the simulated clock
+	require.Equal(t, uint64(c.Start.Add(time.Second*10).Unix()), sb.Head.Time)
+
+	require.NoError(t, s.WaitForHeight(1, time.Second*10))
+	for i := range s.Nodes {
+		b, err := s.Nodes[i].visor.GetSignedBlockBySeq(1)
+		require.NoError(t, err)
+		require.Equal(t, sb.HashHeader(), b.HashHeader())
+	}
+
+	// A block published while node 2 is cut off reaches it once it reconnects
+	s.Cut(2, 1)
+	_, err = s.Spend(0, to, 1e6)
+	require.NoError(t, err)
+	sb, err = s.CreateBlock(time.Second * 10)
+	require.NoError(t, err)
+
+	err = s.WaitFor(time.Second*10, func() bool {
+		seq, err := s.HeadSeq(1)
+		return err == nil && seq == 2
+	})
+	require.NoError(t, err)
+	seq, err := s.HeadSeq(2)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), seq)
+
+	s.Restore(2, 1)
+	require.NoError(t, s.Connect(2, 1))
+	require.NoError(t, s.WaitForHeight(2, time.Second*10))
+}
+
+func TestSimulationEncryptedTransport(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping the simulation in short mode")
+	}
+
+	dir, err := ioutil.TempDir("", "skycoin-sim")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	c := NewSimulationConfig()
+	c.Nodes = 3
+	c.DataDirectory = dir
+	c.Configure = func(i int, c *Config) {
+		c.Daemon.EncryptedTransport = true
+	}
+	s, err := NewSimulation(c)
+	require.NoError(t, err)
+
+	// Each node has its own key, saved in its data directory
+	keys := make([]cipher.PubKey, len(s.Nodes))
+	for i, d := range s.Nodes {
+		keys[i] = d.pool.Pool.TransportPubKey()
+		sk, err := loadTransportKey(filepath.Join(dir, fmt.Sprintf("node%d", i), TransportKeyFilename))
+		require.NoError(t, err)
+		require.Equal(t, keys[i], cipher.MustPubKeyFromSecKey(sk))
+	}
+	require.NotEqual(t, keys[0], keys[1])
+
+	// Node 1 pins the key of node 0, node 2 pins another key for node 0
+	otherKey, _ := cipher.GenerateKeyPair()
+	s.Nodes[1].Config.PeerKeys = map[string]cipher.PubKey{s.Addr(0): keys[0]}
+	s.Nodes[2].Config.PeerKeys = map[string]cipher.PubKey{s.Addr(0): otherKey}
+
+	require.NoError(t, s.Start())
+	defer s.Stop()
+
+	// The connection to a peer with a pinned key is encrypted, and both peers are authenticated
+	require.NoError(t, s.Connect(1, 0))
+	require.NoError(t, s.WaitForConnections(time.Second*10))
+
+	gc, err := s.Nodes[1].pool.Pool.GetConnection(s.Addr(0))
+	require.NoError(t, err)
+	require.True(t, gc.Encrypted)
+	require.Equal(t, keys[0], gc.PeerKey)
+	require.Equal(t, uint64(s.Nodes[0].Config.features()), gc.HandshakeFeatures)
+
+	for _, c := range s.Nodes[0].connections.all() {
+		gc, err := s.Nodes[0].pool.Pool.GetConnection(c.Addr)
+		require.NoError(t, err)
+		require.True(t, gc.Encrypted)
+		require.Equal(t, keys[1], gc.PeerKey)
+	}
+
+	// The key of a peer that advertised the encrypted transport is learned on the first connection to it
+	require.NoError(t, s.Nodes[2].pex.AddPeer(s.Addr(1)))
+	s.Nodes[2].pex.SetFeatures(s.Addr(1), uint64(FeatureEncryptedTransport))
+	require.NoError(t, s.Connect(2, 1))
+	require.NoError(t, s.WaitForConnections(time.Second*10))
+
+	p, ok := s.Nodes[2].pex.GetPeer(s.Addr(1))
+	require.True(t, ok)
+	require.Equal(t, keys[1], p.TransportKey)
+	require.Equal(t, keys[1], s.Nodes[2].peerKey(s.Addr(1)))
+
+	// The connection fails if the peer does not authenticate with the pinned key
+	require.NoError(t, s.Connect(2, 0))
+	err = s.WaitFor(time.Second*10, func() bool {
+		return s.Nodes[2].connections.get(s.Addr(0)) == nil
+	})
+	require.NoError(t, err)
+	require.False(t, s.hasIntroduced(2, 0))
+}
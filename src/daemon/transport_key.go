@@ -0,0 +1,38 @@
+package daemon
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/util/file"
+)
+
+// TransportKeyFilename is the file in the data directory that holds the static key of the encrypted transport
+const TransportKeyFilename = "transport.key"
+
+// loadTransportKey loads the static key of the encrypted transport, hex encoded in a file.
+// The file is created with a new key if it does not exist
+func loadTransportKey(filename string) (cipher.SecKey, error) {
+	b, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		pk, sk := cipher.GenerateKeyPair()
+		if err := file.SaveBinaryAtomic(filename, []byte(sk.Hex()), 0600); err != nil {
+			return cipher.SecKey{}, err
+		}
+
+		logger.WithField("pubkey", pk.Hex()).Infof("Created the encrypted transport key %s", filename)
+		return sk, nil
+	} else if err != nil {
+		return cipher.SecKey{}, err
+	}
+
+	sk, err := cipher.SecKeyFromHex(strings.TrimSpace(string(b)))
+	if err != nil {
+		return cipher.SecKey{}, fmt.Errorf("Invalid encrypted transport key file %s: %v", filename, err)
+	}
+
+	return sk, nil
+}
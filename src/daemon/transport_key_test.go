@@ -0,0 +1,35 @@
+package daemon
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadTransportKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "daemon")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	fn := filepath.Join(dir, TransportKeyFilename)
+
+	// The key is created the first time, and loaded afterwards
+	sk, err := loadTransportKey(fn)
+	require.NoError(t, err)
+	require.NoError(t, sk.Verify())
+
+	sk2, err := loadTransportKey(fn)
+	require.NoError(t, err)
+	require.Equal(t, sk, sk2)
+
+	fi, err := os.Stat(fn)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+
+	require.NoError(t, ioutil.WriteFile(fn, []byte("not a key"), 0600))
+	_, err = loadTransportKey(fn)
+	require.Error(t, err)
+}
@@ -0,0 +1,158 @@
+package readable
+
+import (
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/util/droplet"
+	"github.com/skycoin/skycoin/src/visor"
+	"github.com/skycoin/skycoin/src/visor/historydb"
+)
+
+// AddressMeta summarizes the activity of an address
+type AddressMeta struct {
+	Address            string `json:"address"`
+	FirstSeenBlockSeq  uint64 `json:"first_seen_block_seq"`
+	FirstSeenTime      uint64 `json:"first_seen_time"`
+	LastActiveBlockSeq uint64 `json:"last_active_block_seq"`
+	LastActiveTime     uint64 `json:"last_active_time"`
+	TxnCount           uint64 `json:"txn_count"`
+	Received           string `json:"received"`
+	Sent               string `json:"sent"`
+}
+
+// NewAddressMeta creates a readable AddressMeta from historydb.AddressMeta
+func NewAddressMeta(addr cipher.Address, meta *historydb.AddressMeta) (*AddressMeta, error) {
+	received, err := droplet.ToString(meta.Received)
+	if err != nil {
+		return nil, err
+	}
+
+	sent, err := droplet.ToString(meta.Sent)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AddressMeta{
+		Address:            addr.String(),
+		FirstSeenBlockSeq:  meta.FirstSeenSeq,
+		FirstSeenTime:      meta.FirstSeenTime,
+		LastActiveBlockSeq: meta.LastActiveSeq,
+		LastActiveTime:     meta.LastActiveTime,
+		TxnCount:           meta.TxnCount,
+		Received:           received,
+		Sent:               sent,
+	}, nil
+}
+
+// AddressBalanceDelta is the change of the balance of an address in a block
+type AddressBalanceDelta struct {
+	BlockSeq  uint64 `json:"block_seq"`
+	BlockTime uint64 `json:"block_time"`
+	Received  string `json:"received"`
+	Sent      string `json:"sent"`
+	Balance   string `json:"balance"`
+}
+
+// AddressBalanceHistory is the balance history of an address over a range of blocks
+type AddressBalanceHistory struct {
+	Address      string                `json:"address"`
+	StartSeq     uint64                `json:"start_seq"`
+	EndSeq       uint64                `json:"end_seq"`
+	StartBalance string                `json:"start_balance"`
+	EndBalance   string                `json:"end_balance"`
+	Deltas       []AddressBalanceDelta `json:"deltas"`
+}
+
+// NewAddressBalanceHistory creates a readable AddressBalanceHistory from visor.AddressBalanceHistory
+func NewAddressBalanceHistory(h *visor.AddressBalanceHistory) (*AddressBalanceHistory, error) {
+	startBalance, err := droplet.ToString(h.StartBalance)
+	if err != nil {
+		return nil, err
+	}
+
+	endBalance, err := droplet.ToString(h.EndBalance)
+	if err != nil {
+		return nil, err
+	}
+
+	deltas := make([]AddressBalanceDelta, len(h.Deltas))
+	for i, d := range h.Deltas {
+		received, err := droplet.ToString(d.Received)
+		if err != nil {
+			return nil, err
+		}
+
+		sent, err := droplet.ToString(d.Sent)
+		if err != nil {
+			return nil, err
+		}
+
+		balance, err := droplet.ToString(d.Balance)
+		if err != nil {
+			return nil, err
+		}
+
+		deltas[i] = AddressBalanceDelta{
+			BlockSeq:  d.BlockSeq,
+			BlockTime: d.BlockTime,
+			Received:  received,
+			Sent:      sent,
+			Balance:   balance,
+		}
+	}
+
+	return &AddressBalanceHistory{
+		Address:      h.Address.String(),
+		StartSeq:     h.StartSeq,
+		EndSeq:       h.EndSeq,
+		StartBalance: startBalance,
+		EndBalance:   endBalance,
+		Deltas:       deltas,
+	}, nil
+}
+
+// AddressScanTransaction is a transaction of an address and the seq of its block
+type AddressScanTransaction struct {
+	BlockSeq uint64 `json:"block_seq"`
+	Transaction
+}
+
+// AddressScan is the confirmed state of an address: its balance, unspent outputs and most recent transactions
+type AddressScan struct {
+	Address            string                   `json:"address"`
+	Balance            Balance                  `json:"balance"`
+	Outputs            UnspentOutputs           `json:"outputs"`
+	RecentTransactions []AddressScanTransaction `json:"recent_transactions"`
+}
+
+// NewAddressScans creates readable AddressScans from visor.AddressScans
+func NewAddressScans(scans []visor.AddressScan) ([]AddressScan, error) {
+	rScans := make([]AddressScan, len(scans))
+	for i, s := range scans {
+		outputs, err := NewUnspentOutputs(s.Outputs)
+		if err != nil {
+			return nil, err
+		}
+
+		txns := make([]AddressScanTransaction, len(s.RecentTransactions))
+		for j, txn := range s.RecentTransactions {
+			rTxn, err := NewTransaction(txn.Txn, txn.BlockSeq == 0)
+			if err != nil {
+				return nil, err
+			}
+
+			txns[j] = AddressScanTransaction{
+				BlockSeq:    txn.BlockSeq,
+				Transaction: *rTxn,
+			}
+		}
+
+		rScans[i] = AddressScan{
+			Address:            s.Address.String(),
+			Balance:            NewBalance(s.Balance),
+			Outputs:            outputs,
+			RecentTransactions: txns,
+		}
+	}
+
+	return rScans, nil
+}
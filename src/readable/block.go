@@ -9,6 +9,7 @@ import (
 
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor"
 )
 
 // BlockHeader represents the readable block header
@@ -37,6 +38,26 @@ func NewBlockHeader(b coin.BlockHeader) BlockHeader {
 	}
 }
 
+// CommittedBlockHeader represents a readable block header with its unspent output set commitment
+type CommittedBlockHeader struct {
+	Header BlockHeader `json:"header"`
+	// Hash of the unspent output set after the block, the ux_hash of the next block
+	UnspentCommitment string `json:"unspent_commitment,omitempty"`
+}
+
+// NewCommittedBlockHeader creates a readable block header with its unspent output set commitment
+func NewCommittedBlockHeader(h visor.CommittedBlockHeader) CommittedBlockHeader {
+	var commitment string
+	if h.UnspentCommitment != nil {
+		commitment = h.UnspentCommitment.Hex()
+	}
+
+	return CommittedBlockHeader{
+		Header:            NewBlockHeader(h.Header),
+		UnspentCommitment: commitment,
+	}
+}
+
 // ToCoinBlockHeader converts BlockHeader back to coin.BlockHeader
 func (bh BlockHeader) ToCoinBlockHeader() (coin.BlockHeader, error) {
 	prevHash, err := cipher.SHA256FromHex(bh.PreviousHash)
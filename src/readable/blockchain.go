@@ -13,6 +13,9 @@ type BlockchainMetadata struct {
 	Unspents uint64 `json:"unspents"`
 	// Number of known unconfirmed txns
 	Unconfirmed uint64 `json:"unconfirmed"`
+	// Seq of the newest block whose body was pruned, 0 if no block is pruned.
+	// Pruned nodes only have the header of the blocks up to this seq, the genesis block is never pruned.
+	PrunedSeq uint64 `json:"pruned_seq"`
 }
 
 // NewBlockchainMetadata creates blockchain metadata
@@ -21,6 +24,7 @@ func NewBlockchainMetadata(bm visor.BlockchainMetadata) BlockchainMetadata {
 		Head:        NewBlockHeader(bm.HeadBlock.Head),
 		Unspents:    bm.Unspents,
 		Unconfirmed: bm.Unconfirmed,
+		PrunedSeq:   bm.PrunedSeq,
 	}
 }
 
@@ -32,6 +36,8 @@ type BlockchainProgress struct {
 	Highest uint64 `json:"highest"`
 	// Individual blockchain length reports from peers
 	Peers []PeerBlockchainHeight `json:"peers"`
+	// Networking is disabled, the blockchain is not synced
+	Offline bool `json:"offline"`
 }
 
 // PeerBlockchainHeight is a peer's IP address with their reported blockchain height
@@ -54,5 +60,6 @@ func NewBlockchainProgress(bp *daemon.BlockchainProgress) BlockchainProgress {
 		Current: bp.Current,
 		Highest: bp.Highest,
 		Peers:   peers,
+		Offline: bp.Offline,
 	}
 }
@@ -1,7 +1,11 @@
 package readable
 
 import (
+	"time"
+
 	"github.com/skycoin/skycoin/src/daemon"
+	"github.com/skycoin/skycoin/src/daemon/pex"
+	"github.com/skycoin/skycoin/src/util/droplet"
 	"github.com/skycoin/skycoin/src/util/useragent"
 )
 
@@ -21,6 +25,7 @@ type Connection struct {
 	IsTrustedPeer                 bool                   `json:"is_trusted_peer"`
 	UnconfirmedBurnFactor         uint32                 `json:"unconfirmed_burn_factor"`
 	UnconfirmedMaxTransactionSize uint32                 `json:"unconfirmed_max_transaction_size"`
+	Encrypted                     bool                   `json:"encrypted"`
 }
 
 // NewConnection copies daemon.Connection to a struct with json tags
@@ -54,5 +59,161 @@ func NewConnection(c *daemon.Connection) Connection {
 		IsTrustedPeer:                 c.Pex.Trusted,
 		UnconfirmedBurnFactor:         c.UnconfirmedBurnFactor,
 		UnconfirmedMaxTransactionSize: c.UnconfirmedMaxTransactionSize,
+		Encrypted:                     c.Gnet.Encrypted,
+	}
+}
+
+// Ban a banned peer IP
+type Ban struct {
+	IP      string `json:"ip"`
+	Reason  string `json:"reason"`
+	Created int64  `json:"created"`
+	Expires int64  `json:"expires"`
+}
+
+// NewBan copies pex.Ban to a struct with json tags
+func NewBan(b pex.Ban) Ban {
+	return Ban{
+		IP:      b.IP,
+		Reason:  b.Reason,
+		Created: b.Created,
+		Expires: b.Expires,
+	}
+}
+
+// Peer a peer of the peer list
+type Peer struct {
+	Addr               string         `json:"address"`
+	LastSeen           int64          `json:"last_seen"`
+	Private            bool           `json:"private"`
+	Trusted            bool           `json:"trusted"`
+	HasIncomingPort    bool           `json:"has_incoming_port"`
+	UserAgent          useragent.Data `json:"user_agent"`
+	ProtocolVersion    int32          `json:"protocol_version"`
+	Uptime             uint64         `json:"uptime"`
+	HandshakeAttempts  int            `json:"handshake_attempts"`
+	HandshakeSuccesses int            `json:"handshake_successes"`
+	BlockLatency       uint64         `json:"block_latency_ms"`
+	Source             string         `json:"source"`
+	Tried              bool           `json:"tried"`
+}
+
+// NewPeer copies pex.Peer to a struct with json tags
+func NewPeer(p pex.Peer) Peer {
+	return Peer{
+		Addr:               p.Addr,
+		LastSeen:           p.LastSeen,
+		Private:            p.Private,
+		Trusted:            p.Trusted,
+		HasIncomingPort:    p.HasIncomingPort,
+		UserAgent:          p.UserAgent,
+		ProtocolVersion:    p.ProtocolVersion,
+		Uptime:             uint64(p.Uptime / time.Second),
+		HandshakeAttempts:  p.HandshakeAttempts,
+		HandshakeSuccesses: p.HandshakeSuccesses,
+		BlockLatency:       uint64(p.BlockLatency / time.Millisecond),
+		Source:             p.Source,
+		Tried:              p.Tried,
+	}
+}
+
+// ConnectionEvent a step in the lifecycle of a peer connection
+type ConnectionEvent struct {
+	Type       daemon.ConnectionEventType `json:"type"`
+	Time       int64                      `json:"time"`
+	Addr       string                     `json:"address"`
+	GnetID     uint64                     `json:"id"`
+	Outgoing   bool                       `json:"outgoing"`
+	ListenPort uint16                     `json:"listen_port"`
+	UserAgent  useragent.Data             `json:"user_agent"`
+	Reason     string                     `json:"reason"`
+}
+
+// NewConnectionEvent copies daemon.ConnectionEvent to a struct with json tags
+func NewConnectionEvent(e daemon.ConnectionEvent) ConnectionEvent {
+	return ConnectionEvent{
+		Type:       e.Type,
+		Time:       e.Time.Unix(),
+		Addr:       e.Addr,
+		GnetID:     e.GnetID,
+		Outgoing:   e.Outgoing,
+		ListenPort: e.ListenPort,
+		UserAgent:  e.UserAgent,
+		Reason:     e.Reason,
+	}
+}
+
+// RelayPolicy the policy applied to the transactions received from peers, with the number
+// of transactions it rejected by reason
+type RelayPolicy struct {
+	BlocksOnly         bool              `json:"blocks_only"`
+	MinBurnFactor      uint32            `json:"min_burn_factor"`
+	MaxTransactionSize uint32            `json:"max_transaction_size"`
+	MaxOutputs         int               `json:"max_outputs"`
+	DustThreshold      string            `json:"dust_threshold"`
+	Rejected           map[string]uint64 `json:"rejected"`
+}
+
+// Compression whether compression is negotiated with peers, the minimum size of the messages compressed,
+// and the sizes of the messages compressed and decompressed
+type Compression struct {
+	Enabled   bool               `json:"enabled"`
+	Threshold int                `json:"threshold"`
+	Sent      CompressedMessages `json:"sent"`
+	Received  CompressedMessages `json:"received"`
+}
+
+// CompressedMessages the number of compressed messages, their size before compression and after compression,
+// and the compression ratio
+type CompressedMessages struct {
+	Messages       uint64  `json:"messages"`
+	Size           uint64  `json:"size"`
+	CompressedSize uint64  `json:"compressed_size"`
+	Ratio          float64 `json:"ratio"`
+}
+
+func newCompressedMessages(messages, size, compressedSize uint64) CompressedMessages {
+	var ratio float64
+	if compressedSize != 0 {
+		ratio = float64(size) / float64(compressedSize)
+	}
+
+	return CompressedMessages{
+		Messages:       messages,
+		Size:           size,
+		CompressedSize: compressedSize,
+		Ratio:          ratio,
 	}
 }
+
+// NewCompression copies daemon.CompressionStatus to a struct with json tags
+func NewCompression(s daemon.CompressionStatus) Compression {
+	return Compression{
+		Enabled:   s.Enabled,
+		Threshold: s.Threshold,
+		Sent:      newCompressedMessages(s.Stats.SentMessages, s.Stats.SentSize, s.Stats.SentCompressedSize),
+		Received:  newCompressedMessages(s.Stats.ReceivedMessages, s.Stats.ReceivedSize, s.Stats.ReceivedCompressedSize),
+	}
+}
+
+// NewRelayPolicy copies daemon.RelayPolicyStatus to a struct with json tags
+func NewRelayPolicy(s daemon.RelayPolicyStatus) (RelayPolicy, error) {
+	dust, err := droplet.ToString(s.Policy.DustThreshold)
+	if err != nil {
+		return RelayPolicy{}, err
+	}
+
+	rejected := make(map[string]uint64, len(s.Rejected))
+	for k, v := range s.Rejected {
+		rejected[string(k)] = v
+	}
+
+	return RelayPolicy{
+		BlocksOnly:         s.Policy.BlocksOnly,
+		MinBurnFactor:      s.Policy.MinBurnFactor,
+		MaxTransactionSize: s.Policy.MaxTransactionSize,
+		MaxOutputs:         s.Policy.MaxOutputs,
+		DustThreshold:      dust,
+		Rejected:           rejected,
+	}, nil
+}
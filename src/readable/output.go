@@ -272,3 +272,21 @@ func NewSpentOutputs(outs []historydb.UxOut) []SpentOutput {
 	}
 	return spents
 }
+
+// UxOutSpender is the transaction input which spent an output
+type UxOutSpender struct {
+	Uxid          string `json:"uxid"`
+	SpentTxnID    string `json:"spent_tx"`
+	SpentBlockSeq uint64 `json:"spent_block_seq"`
+	InputIndex    uint64 `json:"input_index"` // index of the output in the inputs of the spending transaction
+}
+
+// NewUxOutSpender creates a UxOutSpender from historydb.UxOutSpender
+func NewUxOutSpender(uxID cipher.SHA256, s *historydb.UxOutSpender) UxOutSpender {
+	return UxOutSpender{
+		Uxid:          uxID.Hex(),
+		SpentTxnID:    s.TxnID.Hex(),
+		SpentBlockSeq: s.BlockSeq,
+		InputIndex:    s.InputIndex,
+	}
+}
@@ -1,6 +1,10 @@
 package readable
 
-import "github.com/skycoin/skycoin/src/visor"
+import (
+	"github.com/skycoin/skycoin/src/util/droplet"
+	"github.com/skycoin/skycoin/src/visor"
+	"github.com/skycoin/skycoin/src/visor/historydb"
+)
 
 // RichlistBalance holds info an address balance holder
 type RichlistBalance struct {
@@ -21,3 +25,35 @@ func NewRichlistBalances(visorRichlist visor.Richlist) []RichlistBalance {
 	}
 	return richlist
 }
+
+// BalanceRange is the number of addresses with a balance in a range of the balance distribution,
+// and the total coins they hold
+type BalanceRange struct {
+	// MinCoins is the minimum balance of the range, the range ends at the min_coins of the next range
+	MinCoins     string `json:"min_coins"`
+	AddressCount uint64 `json:"address_count"`
+	Coins        string `json:"coins"`
+}
+
+// NewBalanceDistribution creates readable BalanceRanges from historydb.BalanceRanges
+func NewBalanceDistribution(ranges []historydb.BalanceRange) ([]BalanceRange, error) {
+	rRanges := make([]BalanceRange, len(ranges))
+	for i, r := range ranges {
+		minCoins, err := droplet.ToString(r.MinCoins)
+		if err != nil {
+			return nil, err
+		}
+
+		coins, err := droplet.ToString(r.Coins)
+		if err != nil {
+			return nil, err
+		}
+
+		rRanges[i] = BalanceRange{
+			MinCoins:     minCoins,
+			AddressCount: r.AddressCount,
+			Coins:        coins,
+		}
+	}
+	return rRanges, nil
+}
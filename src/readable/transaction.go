@@ -249,3 +249,69 @@ func NewTransactionWithStatusVerbose(txn *visor.Transaction, inputs []visor.Tran
 		Time:        txn.Time,
 	}, nil
 }
+
+// TransactionInclusionProof proves that a transaction is included in a block, see visor.TransactionInclusionProof
+type TransactionInclusionProof struct {
+	Hash     string `json:"txid"`
+	BlockSeq uint64 `json:"block_seq"`
+	// Index of the transaction in the block
+	TxnIndex uint64      `json:"txn_index"`
+	Header   BlockHeader `json:"header"`
+	// Signature of the block header hash
+	Sig string `json:"sig"`
+	// Sibling hashes on the path from the transaction hash to the body hash of the header
+	MerklePath []string `json:"merkle_path"`
+}
+
+// NewTransactionInclusionProof creates a readable TransactionInclusionProof
+func NewTransactionInclusionProof(p *visor.TransactionInclusionProof) TransactionInclusionProof {
+	path := make([]string, len(p.MerklePath))
+	for i, h := range p.MerklePath {
+		path[i] = h.Hex()
+	}
+
+	return TransactionInclusionProof{
+		Hash:       p.TxnHash.Hex(),
+		BlockSeq:   p.BlockSeq,
+		TxnIndex:   p.TxnIndex,
+		Header:     NewBlockHeader(p.Header),
+		Sig:        p.Sig.Hex(),
+		MerklePath: path,
+	}
+}
+
+// ToTransactionInclusionProof converts TransactionInclusionProof back to visor.TransactionInclusionProof,
+// which can then be verified
+func (p TransactionInclusionProof) ToTransactionInclusionProof() (*visor.TransactionInclusionProof, error) {
+	txnHash, err := cipher.SHA256FromHex(p.Hash)
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := p.Header.ToCoinBlockHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := cipher.SigFromHex(p.Sig)
+	if err != nil {
+		return nil, err
+	}
+
+	path := make([]cipher.SHA256, len(p.MerklePath))
+	for i, h := range p.MerklePath {
+		path[i], err = cipher.SHA256FromHex(h)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &visor.TransactionInclusionProof{
+		TxnHash:    txnHash,
+		BlockSeq:   p.BlockSeq,
+		TxnIndex:   p.TxnIndex,
+		Header:     header,
+		Sig:        sig,
+		MerklePath: path,
+	}, nil
+}
@@ -44,8 +44,49 @@ func NewAddressBalances(wab wallet.AddressBalances) AddressBalances {
 
 // WalletEntry the wallet entry struct
 type WalletEntry struct {
-	Address string `json:"address"`
-	Public  string `json:"public_key"`
+	Address    string      `json:"address"`
+	Public     string      `json:"public_key"`
+	Annotation *Annotation `json:"annotation,omitempty"`
+}
+
+// Annotation is the user annotation of an address or a transaction of a wallet
+type Annotation struct {
+	Label    string `json:"label,omitempty"`
+	Note     string `json:"note,omitempty"`
+	Category string `json:"category,omitempty"`
+}
+
+// NewAnnotation copies from wallet.Annotation
+func NewAnnotation(a wallet.Annotation) Annotation {
+	return Annotation{
+		Label:    a.Label,
+		Note:     a.Note,
+		Category: a.Category,
+	}
+}
+
+// WalletAnnotations are the annotations of a wallet's addresses and transactions
+type WalletAnnotations struct {
+	Addresses    map[string]Annotation `json:"addresses"`
+	Transactions map[string]Annotation `json:"transactions"`
+}
+
+// NewWalletAnnotations copies from wallet.Annotations
+func NewWalletAnnotations(as wallet.Annotations) WalletAnnotations {
+	wa := WalletAnnotations{
+		Addresses:    make(map[string]Annotation, len(as.Addresses)),
+		Transactions: make(map[string]Annotation, len(as.Transactions)),
+	}
+
+	for addr, a := range as.Addresses {
+		wa.Addresses[addr.String()] = NewAnnotation(a)
+	}
+
+	for txid, a := range as.Transactions {
+		wa.Transactions[txid.Hex()] = NewAnnotation(a)
+	}
+
+	return wa
 }
 
 // WalletMeta the wallet meta struct
@@ -1,13 +1,18 @@
 package skycoin
 
 import (
+	"bytes"
 	"errors"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"math"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
@@ -15,10 +20,15 @@ import (
 
 	"github.com/skycoin/skycoin/src/api"
 	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/encrypt"
+	"github.com/skycoin/skycoin/src/daemon/pex"
 	"github.com/skycoin/skycoin/src/params"
 	"github.com/skycoin/skycoin/src/readable"
 	"github.com/skycoin/skycoin/src/util/file"
+	"github.com/skycoin/skycoin/src/util/iputil"
 	"github.com/skycoin/skycoin/src/util/useragent"
+	"github.com/skycoin/skycoin/src/visor"
+	"github.com/skycoin/skycoin/src/visor/blockdb"
 	"github.com/skycoin/skycoin/src/wallet"
 )
 
@@ -43,12 +53,24 @@ type NodeConfig struct {
 	DownloadPeerList bool
 	// Download the peers list from this URL
 	PeerListURL string
+	// Comma separated list of DNS seed hostnames to bootstrap peers from
+	DNSSeeds string
+	// Download a signed peers list from this HTTPS URL if the DNS seeds return no peers
+	SignedPeerListURL string
+	// Hex-encoded public key that signs the peers list of SignedPeerListURL
+	PeerListPubkey string
 	// Don't make any outgoing connections
 	DisableOutgoingConnections bool
 	// Don't allowing incoming connections
 	DisableIncomingConnections bool
 	// Disables networking altogether
 	DisableNetworking bool
+	// Crawl the peer network instead of synchronizing the blockchain
+	Crawler bool
+	// File the crawl report is written to. Defaults to crawl.json in the data directory
+	CrawlerReportFile string
+	// How long a crawled peer has to send its height and its peers before it is disconnected
+	CrawlerVisitTimeout time.Duration
 	// Enable GUI
 	EnableGUI bool
 	// Disable CSRF check in the wallet API
@@ -82,10 +104,102 @@ type NodeConfig struct {
 	MaxOutgoingConnections int
 	// Maximum default outgoing connections
 	MaxDefaultPeerOutgoingConnections int
+	// Comma separated list of whitelisted peer IPs, which get a reserved incoming connection slot,
+	// are not rate limited and are never banned
+	PeerWhitelist string
+	peerWhitelist []string
+	// Number of incoming connection slots reserved for whitelisted peers, in excess of MaxConnections
+	MaxWhitelistedConnections int
+	// Comma separated list of additional addresses to listen on for peer connections, as ip:port or ip:port/max
+	ListenAddresses string
+	listenAddresses []string
+	// Maximum incoming connections from the same IP. 0 for no limit
+	MaxIncomingPerIP int
+	// Maximum incoming connections from the same netgroup, e.g. an IPv4 /16. 0 for no limit
+	MaxIncomingPerNetgroup int
+	// How long an incoming connection has to send its introduction
+	IncomingIntroductionWait time.Duration
+	// Reject new incoming connections when the incoming slots are full, instead of evicting the worst incoming peer
+	DisableIncomingEviction bool
 	// How often to make outgoing connections
 	OutgoingConnectionsRate time.Duration
 	// PeerlistSize represents the maximum number of peers that the pex would maintain
 	PeerlistSize int
+	// Ban score at which a misbehaving peer is banned. 0 disables automatic bans
+	BanScoreThreshold int
+	// How long the misbehavior of a peer counts toward its ban score
+	BanScoreWindow time.Duration
+	// How long misbehaving peers are banned for
+	BanDuration time.Duration
+	// Offset of the local clock from the median clock of the peers at which a warning is logged
+	ClockSkewWarnThreshold time.Duration
+	// Maximum adjustment of the local clock to the median clock of the peers
+	MaxNetworkTimeAdjustment time.Duration
+	// How far in the future of the network-adjusted time the blocks received from peers can be timestamped
+	MaxBlockTimeDrift time.Duration
+	// Delay before the first rebroadcast of an unconfirmed transaction injected by this node, doubled after each
+	// rebroadcast up to RebroadcastMaxInterval. 0 disables the rebroadcasts
+	RebroadcastInterval time.Duration
+	// Maximum delay between the rebroadcasts of a transaction
+	RebroadcastMaxInterval time.Duration
+	// How long after it is injected a transaction is rebroadcast
+	RebroadcastExpiry time.Duration
+	// How long a peer has to send the transactions requested from it, before they are requested from another peer
+	TxnRequestTimeout time.Duration
+	// How many times a transaction is requested from another peer after a request timed out
+	TxnRequestRetries int
+	// Number of consecutive requests a peer must leave unanswered to be penalized for stalling. 0 disables the penalty
+	RequestStallThreshold int
+	// Make outgoing connections through this SOCKS5 proxy, e.g. Tor. Leave empty to connect directly
+	Proxy string
+	// Don't listen on or connect to IPv4 addresses
+	DisableIPv4 bool
+	// Don't listen on or connect to IPv6 addresses
+	DisableIPv6 bool
+	// Connect to the peers of this IP family first, "ipv4" or "ipv6". Empty for no preference
+	PreferIPFamily string
+	// Maximum bytes per second sent to all peers. 0 for no limit
+	UploadRateLimit int
+	// Maximum bytes per second received from all peers. 0 for no limit
+	DownloadRateLimit int
+	// Maximum bytes per second sent to each peer. 0 for no limit
+	PeerUploadRateLimit int
+	// Maximum bytes per second received from each peer. 0 for no limit
+	PeerDownloadRateLimit int
+	// Maximum number of block and transaction announcements broadcast at once. 0 disables the throttling
+	AnnounceBurst int
+	// How often one throttled announcement can be broadcast once the burst is used up
+	AnnounceThrottleRate time.Duration
+	// Number of lowest latency peers a new block is sent to at once, the others are sent it in staggered waves.
+	// 0 sends the new blocks to all peers at once
+	BlockFanoutWaveSize int
+	// Delay between the waves of peers a new block is sent to
+	BlockFanoutStagger time.Duration
+	// Don't relay new blocks as compact blocks, nor accept them from peers
+	DisableCompactBlocks bool
+	// Don't relay the transactions of peers and ask peers not to relay transactions
+	DisableTxnRelay bool
+	// Minimum coinhour burn factor of the transactions accepted from peers. 0 disables the check
+	RelayMinBurnFactor uint32
+	relayMinBurnFactor uint64
+	// Maximum size of the transactions accepted from peers. 0 disables the check
+	RelayMaxTransactionSize uint32
+	relayMaxTransactionSize uint64
+	// Maximum number of outputs of the transactions accepted from peers. 0 disables the check
+	RelayMaxOutputs int
+	// Minimum number of droplets of the outputs of the transactions accepted from peers. 0 disables the check
+	RelayDustThreshold uint64
+	// Don't download blocks header-first from the peers that support it
+	DisableHeadersFirstSync bool
+	// Accept the encrypted transport on incoming connections, and use it with the peers that support it
+	EncryptedTransport bool
+	// Comma separated list of the encrypted transport keys of peers, as ip:port=pubkey
+	PeerKeys string
+	peerKeys map[string]cipher.PubKey
+	// Don't compress the messages sent to peers, nor ask peers to compress their messages
+	DisableCompression bool
+	// Minimum size of the messages compressed for the peers that negotiated compression
+	CompressionThreshold int
 	// Wallet Address Version
 	//AddressVersion string
 	// Remote web interface
@@ -140,6 +254,67 @@ type NodeConfig struct {
 	VerifyDB bool
 	// Reset the database if integrity checks fail, and continue running
 	ResetCorruptDB bool
+	// Check the database and report what ResetCorruptDB would do with it, then exit without modifying the database
+	DBCheckOnly bool
+	// How long to wait for peers to repair corrupted blocks before resetting the database, 0 resets it immediately
+	RepairCorruptDBTimeout time.Duration
+	// Verify the entire blockchain, ignoring the verification checkpoint
+	FullVerifyDB bool
+	// Verify the database in the background after the node has started, instead of blocking startup
+	VerifyDBInBackground bool
+	// Number of goroutines used for database verification, or "auto" to tune it automatically
+	VerifyDBWorkers string
+	verifyDBWorkers int
+	// Compact the database file during startup
+	CompactDB bool
+	// Directory where database backups made with /api/v1/db/backup are written
+	DBBackupDir string
+	// Maximum number of quarantined corrupted databases to keep, 0 keeps all
+	DBQuarantineMaxCopies int
+	// Gzip-compress quarantined corrupted databases
+	DBQuarantineCompress bool
+	// How often a random range of blocks is re-verified in the background, 0 disables the scrubbing
+	DBScrubInterval time.Duration
+	// Number of blocks re-verified by each background scrub
+	DBScrubBlocks uint64
+	// How often the read replica of the database serving the block and history queries of the API is refreshed, 0 disables the replica
+	DBReplicaInterval time.Duration
+	// Directory where the snapshots of the read replica are written
+	DBReplicaDir string
+	// File that the events of the parsed blocks are exported to as JSON lines, empty disables the export
+	ExportEventsFile string
+	// How often the new blocks are exported
+	ExportEventsInterval time.Duration
+	// How long to wait for the database file lock held by another process
+	DBLockTimeout time.Duration
+	// Number of times the database file lock is attempted again after DBLockTimeout
+	DBLockRetries int
+	// Delay before the first database file lock retry, doubled after each retry
+	DBLockRetryBackoff time.Duration
+	// Passphrase of the database encryption. The bucket values of the database are encrypted if set
+	DBEncryptionPassphrase string
+	// File whose content is the passphrase of the database encryption, instead of DBEncryptionPassphrase
+	DBEncryptionKeyFile    string
+	dbEncryptionPassphrase []byte
+	// Number of most recent blocks whose body is kept, the body of older blocks is removed. 0 disables pruning
+	PruneDepth uint64
+	// Compression of the stored blocks, "none" or "snappy"
+	BlockCompression string
+	blockCompression blockdb.BlockCompression
+	// Memory budget in bytes of the cache of recently read blocks, signatures and block tree entries. 0 disables the cache
+	BlockCacheSize int
+	// Maximum number of received blocks applied in one database transaction. 0 or 1 applies each block in its own transaction
+	BlockApplyBatchSize int
+	// Time after which a batch of received blocks is committed
+	BlockApplyBatchTime time.Duration
+	// Trusted block hashes at known heights, comma separated in the format seq:hash
+	Checkpoints string
+	checkpoints visor.Checkpoints
+	// Unspent output set snapshot file that an empty database is bootstrapped from, so that only the later blocks are synced
+	UnspentSnapshot string
+	// Trusted hash of the unspent output set of UnspentSnapshot
+	UnspentSnapshotCommitment string
+	unspentSnapshotCommitment *cipher.SHA256
 
 	// Maximum size of blocks in bytes to apply when creating blocks
 	MaxBlockSize uint32
@@ -149,6 +324,10 @@ type NodeConfig struct {
 	UnconfirmedBurnFactor uint32
 	// Coin hour burn factor to apply when creating blocks
 	CreateBlockBurnFactor uint32
+	// Maximum time a transaction stays in the unconfirmed pool before it is evicted. 0 disables the limit
+	UnconfirmedMaxAge time.Duration
+	// Maximum number of transactions in the unconfirmed pool. 0 disables the limit
+	UnconfirmedMaxPoolSize int
 
 	maxBlockSize                  uint64
 	maxUnconfirmedTransactionSize uint64
@@ -158,8 +337,17 @@ type NodeConfig struct {
 	// Wallets
 	// Defaults to ${DataDirectory}/wallets/
 	WalletDirectory string
+	// Comma separated list of additional directories to load wallets from
+	WalletDirectories string
+	walletDirectories []string
+	// Storage of the wallets, replaces WalletDirectory if set. It can't be set with a flag
+	WalletStorage wallet.WalletStorage
 	// Wallet crypto type
 	WalletCryptoType string
+	// Argon2id parameters of the argon2id-chacha20poly1305 wallet crypto type
+	WalletArgon2idTime    uint
+	WalletArgon2idMemory  uint // KiB
+	WalletArgon2idThreads uint
 
 	// Disable the hardcoded default peers
 	DisableDefaultPeers bool
@@ -167,6 +355,10 @@ type NodeConfig struct {
 	CustomPeersFile string
 
 	RunBlockPublisher bool
+	// Run as a standby block publisher, which takes over block creation when the active publisher is silent
+	BlockPublisherStandby bool
+	// How long the active block publisher must be silent before a standby publisher takes over. 0 disables the failover
+	BlockPublisherFailoverTimeout time.Duration
 
 	/* Developer options */
 
@@ -179,8 +371,13 @@ type NodeConfig struct {
 	// Expose HTTP profiling on this interface
 	HTTPProfHost string
 
-	DBPath      string
-	DBReadOnly  bool
+	DBPath     string
+	DBReadOnly bool
+	// Store the history db and the unconfirmed transaction pool in separate files next to the database file.
+	// An existing database is split when it is opened, and can't be merged back into a single file.
+	DBSplitFiles bool
+	// Bolt options of the database files, to tune the node for slow disks or huge chains
+	DBOptions   visor.DBOptions
 	Arbitrating bool
 	LogToFile   bool
 	Version     bool // show node version
@@ -198,6 +395,9 @@ type NodeConfig struct {
 
 	blockchainPubkey cipher.PubKey
 	blockchainSeckey cipher.SecKey
+
+	dnsSeeds       []string
+	peerListPubkey cipher.PubKey
 }
 
 // NewNodeConfig returns a new node config instance
@@ -219,6 +419,10 @@ func NewNodeConfig(mode string, node NodeParameters) NodeConfig {
 		DisableIncomingConnections: false,
 		// Disables networking altogether
 		DisableNetworking: false,
+		// Crawl the peer network instead of synchronizing the blockchain
+		Crawler:             false,
+		CrawlerReportFile:   "",
+		CrawlerVisitTimeout: time.Second * 15,
 		// Enable GUI
 		EnableGUI: false,
 		// Enable unversioned API
@@ -240,11 +444,37 @@ func NewNodeConfig(mode string, node NodeParameters) NodeConfig {
 		MaxOutgoingConnections: 8,
 		// MaxDefaultOutgoingConnections is the maximum default outgoing connections allowed
 		MaxDefaultPeerOutgoingConnections: 1,
+		MaxWhitelistedConnections:         8,
+		MaxIncomingPerIP:                  2,
+		MaxIncomingPerNetgroup:            8,
+		IncomingIntroductionWait:          time.Second * 10,
 		DownloadPeerList:                  true,
 		PeerListURL:                       node.PeerListURL,
+		DNSSeeds:                          strings.Join(node.DNSSeeds, ","),
+		SignedPeerListURL:                 node.SignedPeerListURL,
+		PeerListPubkey:                    node.PeerListPubkeyStr,
 		// How often to make outgoing connections, in seconds
 		OutgoingConnectionsRate: time.Second * 5,
 		PeerlistSize:            65535,
+		BanScoreThreshold:       100,
+		BanScoreWindow:          time.Hour,
+		BanDuration:             time.Hour * 24,
+		TxnRequestTimeout:       time.Second * 20,
+		TxnRequestRetries:       3,
+		RequestStallThreshold:   3,
+		AnnounceBurst:           32,
+		AnnounceThrottleRate:    time.Millisecond * 500,
+		BlockFanoutWaveSize:     4,
+		BlockFanoutStagger:      time.Millisecond * 250,
+		CompressionThreshold:    1024,
+		// Clock skew detection and network-adjusted time
+		ClockSkewWarnThreshold:   time.Minute * 5,
+		MaxNetworkTimeAdjustment: time.Minute * 70,
+		MaxBlockTimeDrift:        time.Hour * 2,
+		// Rebroadcasts of the injected transactions
+		RebroadcastInterval:    time.Minute * 5,
+		RebroadcastMaxInterval: time.Hour * 2,
+		RebroadcastExpiry:      time.Hour * 72,
 		// Wallet Address Version
 		//AddressVersion: "test",
 		// Remote web interface
@@ -273,16 +503,47 @@ func NewNodeConfig(mode string, node NodeParameters) NodeConfig {
 
 		VerifyDB:       false,
 		ResetCorruptDB: false,
+		FullVerifyDB:   false,
+
+		RepairCorruptDBTimeout: time.Minute * 10,
+
+		VerifyDBInBackground: false,
+		VerifyDBWorkers:      strconv.Itoa(visor.BlockchainVerifyTheadNum),
+		CompactDB:            false,
+
+		DBQuarantineMaxCopies: visor.DefaultQuarantineMaxCopies,
+		DBQuarantineCompress:  false,
+		PruneDepth:            0,
+		BlockCompression:      "none",
+		BlockCacheSize:        blockdb.DefaultBlockCacheSize,
+		BlockApplyBatchSize:   visor.DefaultBlockApplyBatchSize,
+		BlockApplyBatchTime:   visor.DefaultBlockApplyBatchTime,
+		Checkpoints:           strings.Join(node.Checkpoints, ","),
+
+		DBScrubInterval: time.Minute,
+		DBScrubBlocks:   100,
+
+		ExportEventsInterval: visor.DefaultEventExportInterval,
+
+		DBLockTimeout:      5 * time.Second,
+		DBLockRetries:      0,
+		DBLockRetryBackoff: time.Second,
+
+		DBOptions: visor.NewDBOptions(),
 
 		// Blockchain/transaction validation
 		UnconfirmedMaxTransactionSize: params.UserMaxTransactionSize,
 		MaxBlockSize:                  params.UserMaxTransactionSize,
 		UnconfirmedBurnFactor:         params.UserBurnFactor,
 		CreateBlockBurnFactor:         params.UserBurnFactor,
+		UnconfirmedMaxAge:             14 * 24 * time.Hour,
 
 		// Wallets
-		WalletDirectory:  "",
-		WalletCryptoType: string(wallet.CryptoTypeScryptChacha20poly1305),
+		WalletDirectory:       "",
+		WalletCryptoType:      string(wallet.CryptoTypeArgon2idChacha20poly1305),
+		WalletArgon2idTime:    encrypt.Argon2idTime,
+		WalletArgon2idMemory:  encrypt.Argon2idMemory,
+		WalletArgon2idThreads: encrypt.Argon2idThreads,
 
 		// Timeout settings for http.Server
 		// https://blog.cloudflare.com/the-complete-guide-to-golang-net-http-timeouts/
@@ -290,7 +551,9 @@ func NewNodeConfig(mode string, node NodeParameters) NodeConfig {
 		HTTPWriteTimeout: time.Second * 60,
 		HTTPIdleTimeout:  time.Second * 120,
 
-		RunBlockPublisher: false,
+		RunBlockPublisher:             false,
+		BlockPublisherStandby:         false,
+		BlockPublisherFailoverTimeout: 0,
 
 		// Enable cpu profiling
 		ProfileCPU: false,
@@ -360,12 +623,40 @@ func (c *Config) postProcess() error {
 		c.Node.WalletDirectory = replaceHome(c.Node.WalletDirectory, home)
 	}
 
+	if c.Node.WalletDirectories != "" {
+		for _, dir := range strings.Split(c.Node.WalletDirectories, ",") {
+			c.Node.walletDirectories = append(c.Node.walletDirectories, replaceHome(dir, home))
+		}
+	}
+
 	if c.Node.DBPath == "" {
 		c.Node.DBPath = filepath.Join(c.Node.DataDirectory, "data.db")
 	} else {
 		c.Node.DBPath = replaceHome(c.Node.DBPath, home)
 	}
 
+	if c.Node.DBBackupDir == "" {
+		c.Node.DBBackupDir = filepath.Join(c.Node.DataDirectory, "backups")
+	} else {
+		c.Node.DBBackupDir = replaceHome(c.Node.DBBackupDir, home)
+	}
+
+	if c.Node.DBReplicaDir == "" {
+		c.Node.DBReplicaDir = filepath.Join(c.Node.DataDirectory, "replica")
+	} else {
+		c.Node.DBReplicaDir = replaceHome(c.Node.DBReplicaDir, home)
+	}
+
+	if c.Node.ExportEventsFile != "" {
+		c.Node.ExportEventsFile = replaceHome(c.Node.ExportEventsFile, home)
+	}
+
+	if c.Node.CrawlerReportFile == "" {
+		c.Node.CrawlerReportFile = filepath.Join(c.Node.DataDirectory, "crawl.json")
+	} else {
+		c.Node.CrawlerReportFile = replaceHome(c.Node.CrawlerReportFile, home)
+	}
+
 	if c.Node.RunBlockPublisher {
 		// Run in arbitrating mode if the node is block publisher
 		c.Node.Arbitrating = true
@@ -420,6 +711,329 @@ func (c *Config) postProcess() error {
 		return errors.New("-max-outgoing-connections cannot be higher than -max-connections")
 	}
 
+	if c.Node.MaxWhitelistedConnections < 0 {
+		return errors.New("-max-whitelisted-connections cannot be negative")
+	}
+
+	if c.Node.MaxIncomingPerIP < 0 {
+		return errors.New("-max-incoming-per-ip cannot be negative")
+	}
+
+	if c.Node.MaxIncomingPerNetgroup < 0 {
+		return errors.New("-max-incoming-per-netgroup cannot be negative")
+	}
+
+	if c.Node.IncomingIntroductionWait <= 0 {
+		return errors.New("-incoming-introduction-wait must be > 0")
+	}
+
+	if c.Node.WalletArgon2idTime > math.MaxUint32 {
+		return errors.New("-wallet-argon2id-time is too large")
+	}
+
+	if c.Node.WalletArgon2idMemory > math.MaxUint32 {
+		return errors.New("-wallet-argon2id-memory is too large")
+	}
+
+	if c.Node.WalletArgon2idThreads > math.MaxUint8 {
+		return errors.New("-wallet-argon2id-threads must be <= 255")
+	}
+
+	c.Node.peerWhitelist = nil
+	for _, ip := range strings.Split(c.Node.PeerWhitelist, ",") {
+		if ip = strings.TrimSpace(ip); ip == "" {
+			continue
+		}
+		if net.ParseIP(ip) == nil {
+			return fmt.Errorf("-peer-whitelist has an invalid IP %q", ip)
+		}
+		c.Node.peerWhitelist = append(c.Node.peerWhitelist, ip)
+	}
+
+	c.Node.peerKeys = nil
+	for _, pk := range strings.Split(c.Node.PeerKeys, ",") {
+		if pk = strings.TrimSpace(pk); pk == "" {
+			continue
+		}
+
+		parts := strings.Split(pk, "=")
+		if len(parts) != 2 {
+			return fmt.Errorf("-peer-keys has an invalid entry %q, it must be ip:port=pubkey", pk)
+		}
+		if _, _, err := iputil.SplitAddr(parts[0]); err != nil {
+			return fmt.Errorf("-peer-keys has an invalid address %q: %v", parts[0], err)
+		}
+		key, err := cipher.PubKeyFromHex(parts[1])
+		if err != nil {
+			return fmt.Errorf("-peer-keys has an invalid key for %s: %v", parts[0], err)
+		}
+
+		if c.Node.peerKeys == nil {
+			c.Node.peerKeys = make(map[string]cipher.PubKey)
+		}
+		c.Node.peerKeys[parts[0]] = key
+	}
+
+	if len(c.Node.peerKeys) != 0 && !c.Node.EncryptedTransport {
+		return errors.New("-peer-keys requires -encrypted-transport")
+	}
+
+	c.Node.listenAddresses = nil
+	for _, a := range strings.Split(c.Node.ListenAddresses, ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			c.Node.listenAddresses = append(c.Node.listenAddresses, a)
+		}
+	}
+
+	if c.Node.Proxy != "" {
+		if c.Node.LocalhostOnly {
+			return errors.New("-proxy cannot be used with -localhost-only")
+		}
+
+		if _, _, err := iputil.SplitAddr(c.Node.Proxy); err != nil {
+			return fmt.Errorf("-proxy must be a host:port address: %v", err)
+		}
+	}
+
+	c.Node.dnsSeeds = nil
+	for _, host := range strings.Split(c.Node.DNSSeeds, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			c.Node.dnsSeeds = append(c.Node.dnsSeeds, host)
+		}
+	}
+
+	if c.Node.SignedPeerListURL != "" {
+		u, err := url.Parse(c.Node.SignedPeerListURL)
+		if err != nil {
+			return fmt.Errorf("-signed-peerlist-url is invalid: %v", err)
+		}
+		if u.Scheme != "https" {
+			return errors.New("-signed-peerlist-url must be an https URL")
+		}
+
+		c.Node.peerListPubkey, err = cipher.PubKeyFromHex(c.Node.PeerListPubkey)
+		if err != nil {
+			return fmt.Errorf("-peerlist-pubkey is invalid: %v", err)
+		}
+	}
+
+	if c.Node.DisableIPv4 && c.Node.DisableIPv6 {
+		return errors.New("-disable-ipv4 and -disable-ipv6 cannot both be set")
+	}
+
+	switch pex.IPFamily(c.Node.PreferIPFamily) {
+	case "":
+	case pex.IPFamilyIPv4:
+		if c.Node.DisableIPv4 {
+			return errors.New("-prefer-ip-family cannot be ipv4 when -disable-ipv4 is set")
+		}
+	case pex.IPFamilyIPv6:
+		if c.Node.DisableIPv6 {
+			return errors.New("-prefer-ip-family cannot be ipv6 when -disable-ipv6 is set")
+		}
+	default:
+		return errors.New("-prefer-ip-family must be ipv4 or ipv6")
+	}
+
+	if c.Node.BanScoreThreshold < 0 {
+		return errors.New("-ban-score-threshold must be >= 0")
+	}
+
+	if c.Node.BanScoreThreshold > 0 && (c.Node.BanScoreWindow <= 0 || c.Node.BanDuration <= 0) {
+		return errors.New("-ban-score-window and -ban-duration must be > 0 when -ban-score-threshold is enabled")
+	}
+
+	if c.Node.ClockSkewWarnThreshold <= 0 {
+		return errors.New("-clock-skew-warn-threshold must be > 0")
+	}
+
+	if c.Node.MaxNetworkTimeAdjustment < 0 {
+		return errors.New("-max-network-time-adjustment must be >= 0")
+	}
+
+	if c.Node.MaxBlockTimeDrift < 0 {
+		return errors.New("-max-block-time-drift must be >= 0")
+	}
+
+	if c.Node.RebroadcastInterval < 0 {
+		return errors.New("-rebroadcast-interval must be >= 0")
+	}
+
+	if c.Node.RebroadcastInterval > 0 {
+		if c.Node.RebroadcastMaxInterval < c.Node.RebroadcastInterval {
+			return errors.New("-rebroadcast-max-interval must be >= -rebroadcast-interval")
+		}
+		if c.Node.RebroadcastExpiry <= 0 {
+			return errors.New("-rebroadcast-expiry must be > 0")
+		}
+	}
+
+	if c.Node.CompressionThreshold < 0 {
+		return errors.New("-compression-threshold must be >= 0")
+	}
+
+	if c.Node.UploadRateLimit < 0 || c.Node.DownloadRateLimit < 0 || c.Node.PeerUploadRateLimit < 0 || c.Node.PeerDownloadRateLimit < 0 {
+		return errors.New("-upload-rate-limit, -download-rate-limit, -peer-upload-rate-limit and -peer-download-rate-limit must be >= 0")
+	}
+
+	if c.Node.AnnounceBurst < 0 {
+		return errors.New("-announce-burst must be >= 0")
+	}
+
+	if c.Node.AnnounceBurst > 0 && c.Node.AnnounceThrottleRate <= 0 {
+		return errors.New("-announce-throttle-rate must be > 0 when -announce-burst is enabled")
+	}
+
+	if c.Node.BlockFanoutWaveSize < 0 {
+		return errors.New("-block-fanout-wave-size must be >= 0")
+	}
+
+	if c.Node.BlockFanoutWaveSize > 0 && c.Node.BlockFanoutStagger <= 0 {
+		return errors.New("-block-fanout-stagger must be > 0 when -block-fanout-wave-size is enabled")
+	}
+
+	if c.Node.TxnRequestTimeout <= 0 {
+		return errors.New("-txn-request-timeout must be > 0")
+	}
+
+	if c.Node.TxnRequestRetries < 0 || c.Node.RequestStallThreshold < 0 {
+		return errors.New("-txn-request-retries and -request-stall-threshold must be >= 0")
+	}
+
+	if c.Node.RelayMaxOutputs < 0 {
+		return errors.New("-relay-max-outputs must be >= 0")
+	}
+
+	c.Node.verifyDBWorkers, err = visor.ParseVerifyWorkers(c.Node.VerifyDBWorkers)
+	if err != nil {
+		return fmt.Errorf("-verify-db-workers: %v", err)
+	}
+
+	if c.Node.DBQuarantineMaxCopies < 0 {
+		return errors.New("-db-quarantine-max-copies must be >= 0")
+	}
+
+	if c.Node.Crawler {
+		if c.Node.DisableNetworking || c.Node.DisableOutgoingConnections {
+			return errors.New("-crawler requires outgoing connections")
+		}
+		if c.Node.DisablePEX {
+			return errors.New("-crawler cannot be used with -disable-pex")
+		}
+		if c.Node.RunBlockPublisher {
+			return errors.New("-crawler cannot be used with -block-publisher")
+		}
+		if c.Node.CrawlerVisitTimeout <= 0 {
+			return errors.New("-crawler-visit-timeout must be positive")
+		}
+	}
+
+	if c.Node.BlockPublisherFailoverTimeout < 0 {
+		return errors.New("-block-publisher-failover-timeout must not be negative")
+	}
+
+	if c.Node.BlockPublisherStandby && (!c.Node.RunBlockPublisher || c.Node.BlockPublisherFailoverTimeout == 0) {
+		return errors.New("-block-publisher-standby requires -block-publisher and -block-publisher-failover-timeout")
+	}
+
+	if c.Node.RepairCorruptDBTimeout < 0 {
+		return errors.New("-repair-corrupt-db-timeout must not be negative")
+	}
+
+	if c.Node.DBScrubInterval < 0 {
+		return errors.New("-db-scrub-interval must not be negative")
+	}
+
+	if c.Node.DBScrubInterval != 0 && c.Node.DBScrubBlocks == 0 {
+		return errors.New("-db-scrub-blocks must be > 0 if -db-scrub-interval is set")
+	}
+
+	if c.Node.DBReplicaInterval < 0 {
+		return errors.New("-db-replica-interval must not be negative")
+	}
+
+	if c.Node.ExportEventsFile != "" && c.Node.ExportEventsInterval <= 0 {
+		return errors.New("-export-events-interval must be > 0")
+	}
+
+	if c.Node.DBLockTimeout <= 0 {
+		return errors.New("-db-lock-timeout must be > 0")
+	}
+
+	if c.Node.DBLockRetries < 0 {
+		return errors.New("-db-lock-retries must be >= 0")
+	}
+
+	if c.Node.DBLockRetryBackoff < 0 {
+		return errors.New("-db-lock-retry-backoff must not be negative")
+	}
+
+	if err := c.Node.DBOptions.Verify(); err != nil {
+		return fmt.Errorf("Invalid database options: %v", err)
+	}
+
+	if c.Node.DBCheckOnly && c.Node.CompactDB {
+		return errors.New("-db-check-only and -compact-db can't be combined")
+	}
+
+	if c.Node.DBEncryptionPassphrase != "" && c.Node.DBEncryptionKeyFile != "" {
+		return errors.New("-db-encryption-passphrase and -db-encryption-key-file can't be combined")
+	}
+
+	if c.Node.DBEncryptionKeyFile != "" {
+		key, err := ioutil.ReadFile(c.Node.DBEncryptionKeyFile)
+		if err != nil {
+			return fmt.Errorf("-db-encryption-key-file: %v", err)
+		}
+
+		// Allow a key file written with a trailing newline
+		key = bytes.TrimRight(key, "\r\n")
+		if len(key) == 0 {
+			return errors.New("-db-encryption-key-file is empty")
+		}
+		c.Node.dbEncryptionPassphrase = key
+	} else if c.Node.DBEncryptionPassphrase != "" {
+		c.Node.dbEncryptionPassphrase = []byte(c.Node.DBEncryptionPassphrase)
+	}
+
+	if c.Node.PruneDepth != 0 && c.Node.PruneDepth < visor.MinPruneDepth {
+		return fmt.Errorf("-prune-depth must be 0 or >= %d", visor.MinPruneDepth)
+	}
+
+	c.Node.blockCompression, err = blockdb.ParseBlockCompression(c.Node.BlockCompression)
+	if err != nil {
+		return fmt.Errorf("-block-compression: %v", err)
+	}
+
+	if c.Node.BlockCacheSize < 0 {
+		return errors.New("-block-cache-size must be >= 0")
+	}
+
+	if c.Node.BlockApplyBatchSize < 0 {
+		return errors.New("-block-apply-batch-size must be >= 0")
+	}
+
+	if c.Node.BlockApplyBatchTime < 0 {
+		return errors.New("-block-apply-batch-time must be >= 0")
+	}
+
+	c.Node.checkpoints, err = visor.ParseCheckpoints(strings.Split(c.Node.Checkpoints, ","))
+	if err != nil {
+		return fmt.Errorf("-checkpoints: %v", err)
+	}
+
+	if c.Node.UnspentSnapshotCommitment != "" {
+		if c.Node.UnspentSnapshot == "" {
+			return errors.New("-unspent-snapshot-commitment requires -unspent-snapshot")
+		}
+
+		h, err := cipher.SHA256FromHex(c.Node.UnspentSnapshotCommitment)
+		if err != nil {
+			return fmt.Errorf("-unspent-snapshot-commitment: %v", err)
+		}
+		c.Node.unspentSnapshotCommitment = &h
+	}
+
 	if c.Node.MaxBlockSize < 1024 {
 		return errors.New("-block-size must be >= 1024")
 	}
@@ -448,6 +1062,13 @@ func (c *Config) postProcess() error {
 		return fmt.Errorf("-create-block-burn-factor must be >= params.UserBurnFactor (%d)", params.UserBurnFactor)
 	}
 
+	if c.Node.UnconfirmedMaxAge < 0 {
+		return errors.New("-unconfirmed-max-age must be >= 0")
+	}
+	if c.Node.UnconfirmedMaxPoolSize < 0 {
+		return errors.New("-unconfirmed-max-pool-size must be >= 0")
+	}
+
 	if c.Node.maxBlockSize > math.MaxUint32 {
 		return errors.New("-block-size exceeds MaxUint32")
 	}
@@ -466,6 +1087,16 @@ func (c *Config) postProcess() error {
 	c.Node.UnconfirmedBurnFactor = uint32(c.Node.unconfirmedBurnFactor)
 	c.Node.CreateBlockBurnFactor = uint32(c.Node.createBlockBurnFactor)
 
+	if c.Node.relayMinBurnFactor > math.MaxUint32 {
+		return errors.New("-relay-min-burn-factor exceeds MaxUint32")
+	}
+	if c.Node.relayMaxTransactionSize > math.MaxUint32 {
+		return errors.New("-relay-max-txn-size exceeds MaxUint32")
+	}
+
+	c.Node.RelayMinBurnFactor = uint32(c.Node.relayMinBurnFactor)
+	c.Node.RelayMaxTransactionSize = uint32(c.Node.relayMaxTransactionSize)
+
 	return nil
 }
 
@@ -493,6 +1124,8 @@ func buildAPISets(c NodeConfig) (map[string]struct{}, error) {
 		api.EndpointsTransaction,
 		api.EndpointsPrometheus,
 		api.EndpointsNetCtrl,
+		api.EndpointsDBCtrl,
+		api.EndpointsWatch,
 		// Do not include insecure or deprecated API sets, they must always
 		// be explicitly enabled through -enable-api-sets
 	}
@@ -524,6 +1157,8 @@ func validateAPISets(opt string, apiSets []string) error {
 			api.EndpointsStatus,
 			api.EndpointsTransaction,
 			api.EndpointsWallet,
+			api.EndpointsDBCtrl,
+			api.EndpointsWatch,
 			api.EndpointsInsecureWalletSeed,
 			api.EndpointsDeprecatedWalletSpend:
 		case "":
@@ -541,9 +1176,15 @@ func (c *NodeConfig) RegisterFlags() {
 	flag.BoolVar(&c.DisablePEX, "disable-pex", c.DisablePEX, "disable PEX peer discovery")
 	flag.BoolVar(&c.DownloadPeerList, "download-peerlist", c.DownloadPeerList, "download a peers.txt from -peerlist-url")
 	flag.StringVar(&c.PeerListURL, "peerlist-url", c.PeerListURL, "with -download-peerlist=true, download a peers.txt file from this url")
+	flag.StringVar(&c.DNSSeeds, "dns-seeds", c.DNSSeeds, "comma separated list of DNS seed hostnames whose A and AAAA records are peers to bootstrap from. Not resolved when -proxy is set")
+	flag.StringVar(&c.SignedPeerListURL, "signed-peerlist-url", c.SignedPeerListURL, "download a peers.txt file from this https url if the DNS seeds return no peers. Its signature is downloaded from the url with a .sig suffix")
+	flag.StringVar(&c.PeerListPubkey, "peerlist-pubkey", c.PeerListPubkey, "hex-encoded public key that signs the peers list of -signed-peerlist-url")
 	flag.BoolVar(&c.DisableOutgoingConnections, "disable-outgoing", c.DisableOutgoingConnections, "Don't make outgoing connections")
 	flag.BoolVar(&c.DisableIncomingConnections, "disable-incoming", c.DisableIncomingConnections, "Don't allow incoming connections")
-	flag.BoolVar(&c.DisableNetworking, "disable-networking", c.DisableNetworking, "Disable all network activity")
+	flag.BoolVar(&c.DisableNetworking, "disable-networking", c.DisableNetworking, "Disable all network activity. The wallet, API and blockchain queries keep working with the local data, e.g. to sign transactions on an air-gapped machine or to explore a database snapshot")
+	flag.BoolVar(&c.Crawler, "crawler", c.Crawler, "Crawl the peer network instead of synchronizing the blockchain: connect to each known peer once, record its version, user agent, height and number of peers, and write them to -crawler-report. Use -max-outgoing-connections to visit more peers at once")
+	flag.StringVar(&c.CrawlerReportFile, "crawler-report", c.CrawlerReportFile, "with -crawler, JSON file the crawl report is written to (defaults to crawl.json in the data directory)")
+	flag.DurationVar(&c.CrawlerVisitTimeout, "crawler-visit-timeout", c.CrawlerVisitTimeout, "with -crawler, how long a peer has to send its height and its peers before it is disconnected")
 	flag.BoolVar(&c.EnableGUI, "enable-gui", c.EnableGUI, "Enable GUI")
 	flag.BoolVar(&c.EnableUnversionedAPI, "enable-unversioned-api", c.EnableUnversionedAPI, "Enable the deprecated unversioned API endpoints without /api/v1 prefix")
 	flag.BoolVar(&c.DisableCSRF, "disable-csrf", c.DisableCSRF, "disable CSRF check")
@@ -566,6 +1207,8 @@ func (c *NodeConfig) RegisterFlags() {
 		api.EndpointsTransaction,
 		api.EndpointsPrometheus,
 		api.EndpointsNetCtrl,
+		api.EndpointsDBCtrl,
+		api.EndpointsWatch,
 		api.EndpointsInsecureWalletSeed,
 		api.EndpointsDeprecatedWalletSpend,
 	}
@@ -584,6 +1227,17 @@ func (c *NodeConfig) RegisterFlags() {
 	flag.StringVar(&c.DataDirectory, "data-dir", c.DataDirectory, "directory to store app data (defaults to ~/.skycoin)")
 	flag.StringVar(&c.DBPath, "db-path", c.DBPath, "path of database file (defaults to ~/.skycoin/data.db)")
 	flag.BoolVar(&c.DBReadOnly, "db-read-only", c.DBReadOnly, "open bolt db read-only")
+	flag.BoolVar(&c.DBSplitFiles, "db-split-files", c.DBSplitFiles, "store the history db and the unconfirmed transaction pool in separate files next to the database file. An existing database is split and can't be merged back")
+	flag.IntVar(&c.DBOptions.InitialMmapSize, "db-initial-mmap-size", c.DBOptions.InitialMmapSize, "initial size of the database memory map in bytes. A map larger than the database keeps writes from waiting on reads when the database grows. 0 maps the file size")
+	flag.BoolVar(&c.DBOptions.MmapPopulate, "db-mmap-populate", c.DBOptions.MmapPopulate, "prefault the database memory map on startup (MAP_POPULATE), which speeds up reads on slow disks. Linux only")
+	flag.BoolVar(&c.DBOptions.NoSync, "db-no-sync", c.DBOptions.NoSync, "don't fsync the database after each commit. Faster on slow disks, but the database can be corrupted by a crash or power loss")
+	flag.BoolVar(&c.DBOptions.NoGrowSync, "db-no-grow-sync", c.DBOptions.NoGrowSync, "don't fsync the database file when it grows")
+	flag.IntVar(&c.DBOptions.AllocSize, "db-alloc-size", c.DBOptions.AllocSize, "number of bytes the database file grows by when it is full. 0 uses bolt's default of 16MB")
+	flag.DurationVar(&c.DBLockTimeout, "db-lock-timeout", c.DBLockTimeout, "how long to wait for the database file lock if another process holds it")
+	flag.IntVar(&c.DBLockRetries, "db-lock-retries", c.DBLockRetries, "number of times the database file lock is attempted again after -db-lock-timeout, e.g. while a previous instance shuts down")
+	flag.DurationVar(&c.DBLockRetryBackoff, "db-lock-retry-backoff", c.DBLockRetryBackoff, "delay before the first database file lock retry, doubled after each retry")
+	flag.StringVar(&c.DBEncryptionPassphrase, "db-encryption-passphrase", c.DBEncryptionPassphrase, "encrypt the values stored in the database with AES-GCM, with a key derived from this passphrase. The addresses in the keys of the address indexes are replaced by their HMAC, but the other keys, such as block hashes and txids, remain visible. An existing database is encrypted and can't be opened without the passphrase afterwards")
+	flag.StringVar(&c.DBEncryptionKeyFile, "db-encryption-key-file", c.DBEncryptionKeyFile, "like -db-encryption-passphrase, with the passphrase read from this file")
 	flag.BoolVar(&c.ProfileCPU, "profile-cpu", c.ProfileCPU, "enable cpu profiling")
 	flag.StringVar(&c.ProfileCPUFile, "profile-cpu-file", c.ProfileCPUFile, "where to write the cpu profile file")
 	flag.BoolVar(&c.HTTPProf, "http-prof", c.HTTPProf, "run the HTTP profiling interface")
@@ -596,6 +1250,29 @@ func (c *NodeConfig) RegisterFlags() {
 
 	flag.BoolVar(&c.VerifyDB, "verify-db", c.VerifyDB, "check the database for corruption")
 	flag.BoolVar(&c.ResetCorruptDB, "reset-corrupt-db", c.ResetCorruptDB, "reset the database if corrupted, and continue running instead of exiting")
+	flag.BoolVar(&c.DBCheckOnly, "db-check-only", c.DBCheckOnly, "check the database, report what -reset-corrupt-db would do with it (corrupted blocks, history rebuild or reset, quarantine path) and exit without modifying it. Implies -verify-db")
+	flag.DurationVar(&c.RepairCorruptDBTimeout, "repair-corrupt-db-timeout", c.RepairCorruptDBTimeout, "with -reset-corrupt-db, blocks with an invalid signature or body are repaired with copies requested from peers instead of resetting the database. The database is reset if they are not repaired within this duration. 0 disables the repair")
+	flag.BoolVar(&c.FullVerifyDB, "full-verify", c.FullVerifyDB, "verify the entire blockchain instead of only the blocks added since the last verification. Implies -verify-db")
+	flag.StringVar(&c.VerifyDBWorkers, "verify-db-workers", c.VerifyDBWorkers, "number of goroutines used for database verification, or \"auto\" to size it from the number of CPUs and the measured verification speed")
+	flag.BoolVar(&c.VerifyDBInBackground, "verify-db-background", c.VerifyDBInBackground, "when the database is verified, verify it in the background after the node has started instead of blocking startup. Check the status with /api/v1/db/verify")
+	flag.BoolVar(&c.CompactDB, "compact-db", c.CompactDB, "compact the database file during startup, to reclaim the space of deleted data")
+	flag.StringVar(&c.DBBackupDir, "db-backup-dir", c.DBBackupDir, "directory where database backups made with /api/v1/db/backup are written (defaults to ~/.skycoin/backups)")
+	flag.IntVar(&c.DBQuarantineMaxCopies, "db-quarantine-max-copies", c.DBQuarantineMaxCopies, "maximum number of quarantined corrupted databases to keep, the oldest are deleted first. 0 keeps all")
+	flag.BoolVar(&c.DBQuarantineCompress, "db-quarantine-compress", c.DBQuarantineCompress, "gzip-compress quarantined corrupted databases")
+	flag.DurationVar(&c.DBScrubInterval, "db-scrub-interval", c.DBScrubInterval, "how often a random range of blocks and their history db indexes are re-verified in the background. The corruption found is reported by /api/v1/db/health. 0 disables the scrubbing")
+	flag.Uint64Var(&c.DBScrubBlocks, "db-scrub-blocks", c.DBScrubBlocks, "number of blocks re-verified by each background scrub")
+	flag.DurationVar(&c.DBReplicaInterval, "db-replica-interval", c.DBReplicaInterval, "serve the block, transaction and address history queries of the API from a read-only copy of the database refreshed at this interval, so that heavy read traffic does not contend with the block application. The results lag behind the node by up to the interval. The copy takes as much disk space as the database, twice while it is refreshed. 0 disables the replica")
+	flag.StringVar(&c.DBReplicaDir, "db-replica-dir", c.DBReplicaDir, "directory where the read replica of the database is written (defaults to ~/.skycoin/replica)")
+	flag.StringVar(&c.ExportEventsFile, "export-events-file", c.ExportEventsFile, "file that the events of the parsed blocks (blocks, transactions, outputs created and spent, address deltas) are appended to as JSON lines, resuming after the last exported block on restart. The events of the last blocks can be written again after a crash")
+	flag.DurationVar(&c.ExportEventsInterval, "export-events-interval", c.ExportEventsInterval, "how often the new blocks are exported to -export-events-file")
+	flag.Uint64Var(&c.PruneDepth, "prune-depth", c.PruneDepth, "run as a pruned node, removing the transactions of blocks older than this number of blocks. Block headers and unspent outputs are kept. 0 disables pruning")
+	flag.StringVar(&c.BlockCompression, "block-compression", c.BlockCompression, "compression of the stored blocks, \"none\" or \"snappy\". The blocks already stored are rewritten with the compression on startup")
+	flag.IntVar(&c.BlockCacheSize, "block-cache-size", c.BlockCacheSize, "memory budget in bytes of the cache of recently read blocks, signatures and block tree entries, which speeds up random block lookups such as by the explorer. 0 disables the cache")
+	flag.IntVar(&c.BlockApplyBatchSize, "block-apply-batch-size", c.BlockApplyBatchSize, "maximum number of received blocks applied in one database transaction, which speeds up the initial sync. A batch can't be larger than the number of blocks received in one message. 0 or 1 applies each block in its own transaction")
+	flag.DurationVar(&c.BlockApplyBatchTime, "block-apply-batch-time", c.BlockApplyBatchTime, "time after which a batch of received blocks is committed, even if it has fewer than -block-apply-batch-size blocks. 0 disables the limit")
+	flag.StringVar(&c.Checkpoints, "checkpoints", c.Checkpoints, "comma separated list of trusted block hashes in the format seq:hash. Blocks that don't match them are rejected, and the database check does not verify the signatures of the blocks below the latest checkpoint")
+	flag.StringVar(&c.UnspentSnapshot, "unspent-snapshot", c.UnspentSnapshot, "unspent output set snapshot file made with skycoin-cli exportUnspentSnapshot. If the database has no blocks, it is bootstrapped from the snapshot and only the blocks after the snapshot block are synced")
+	flag.StringVar(&c.UnspentSnapshotCommitment, "unspent-snapshot-commitment", c.UnspentSnapshotCommitment, "trusted hash of the unspent output set of -unspent-snapshot. Without it, the unspent outputs are authenticated by the first block synced after the snapshot")
 
 	flag.BoolVar(&c.DisableDefaultPeers, "disable-default-peers", c.DisableDefaultPeers, "disable the hardcoded default peers")
 	flag.StringVar(&c.CustomPeersFile, "custom-peers-file", c.CustomPeersFile, "load custom peers from a newline separate list of ip:port in a file. Note that this is different from the peers.json file in the data directory")
@@ -606,8 +1283,12 @@ func (c *NodeConfig) RegisterFlags() {
 	flag.Uint64Var(&c.maxBlockSize, "block-size", uint64(c.MaxBlockSize), "maximum size of a block")
 	flag.Uint64Var(&c.unconfirmedBurnFactor, "burn-factor-unconfirmed", uint64(c.UnconfirmedBurnFactor), "coinhour burn factor applied to unconfirmed transactions")
 	flag.Uint64Var(&c.createBlockBurnFactor, "burn-factor-create-block", uint64(c.CreateBlockBurnFactor), "coinhour burn factor applied when creating blocks")
+	flag.DurationVar(&c.UnconfirmedMaxAge, "unconfirmed-max-age", c.UnconfirmedMaxAge, "time after which a transaction is evicted from the unconfirmed pool. 0 disables the limit")
+	flag.IntVar(&c.UnconfirmedMaxPoolSize, "unconfirmed-max-pool-size", c.UnconfirmedMaxPoolSize, "maximum number of transactions in the unconfirmed pool, the invalid and then the oldest transactions are evicted first. 0 disables the limit")
 
 	flag.BoolVar(&c.RunBlockPublisher, "block-publisher", c.RunBlockPublisher, "run the daemon as a block publisher")
+	flag.BoolVar(&c.BlockPublisherStandby, "block-publisher-standby", c.BlockPublisherStandby, "with -block-publisher, start as a standby block publisher, which only creates blocks once the active block publisher, running with the same blockchain secret key, was silent for -block-publisher-failover-timeout")
+	flag.DurationVar(&c.BlockPublisherFailoverTimeout, "block-publisher-failover-timeout", c.BlockPublisherFailoverTimeout, "with -block-publisher, how long the active block publisher must be silent while transactions are pending before a passive block publisher takes over. A publisher that receives a block from its peers becomes passive. 0 disables the failover")
 	flag.StringVar(&c.BlockchainPubkeyStr, "blockchain-public-key", c.BlockchainPubkeyStr, "public key of the blockchain")
 	flag.StringVar(&c.BlockchainSeckeyStr, "blockchain-secret-key", c.BlockchainSeckeyStr, "secret key of the blockchain")
 
@@ -616,14 +1297,60 @@ func (c *NodeConfig) RegisterFlags() {
 	flag.Uint64Var(&c.GenesisTimestamp, "genesis-timestamp", c.GenesisTimestamp, "genesis block timestamp")
 
 	flag.StringVar(&c.WalletDirectory, "wallet-dir", c.WalletDirectory, "location of the wallet files. Defaults to ~/.skycoin/wallet/")
+	flag.StringVar(&c.WalletDirectories, "wallet-dirs", c.WalletDirectories, "comma separated list of additional directories to load wallet files from. New wallets are created in -wallet-dir")
 	flag.IntVar(&c.MaxConnections, "max-connections", c.MaxConnections, "Maximum number of total connections allowed")
 	flag.IntVar(&c.MaxOutgoingConnections, "max-outgoing-connections", c.MaxOutgoingConnections, "Maximum number of outgoing connections allowed")
 	flag.IntVar(&c.MaxDefaultPeerOutgoingConnections, "max-default-peer-outgoing-connections", c.MaxDefaultPeerOutgoingConnections, "The maximum default peer outgoing connections allowed")
+	flag.StringVar(&c.PeerWhitelist, "peer-whitelist", c.PeerWhitelist, "comma separated list of whitelisted peer IPs. They get a reserved incoming connection slot when -max-connections is reached, are not rate limited and are never banned")
+	flag.IntVar(&c.MaxWhitelistedConnections, "max-whitelisted-connections", c.MaxWhitelistedConnections, "Number of incoming connection slots reserved for whitelisted peers, in excess of -max-connections")
+	flag.StringVar(&c.ListenAddresses, "listen-addresses", c.ListenAddresses, "comma separated list of additional addresses to listen on for peer connections, as ip:port or ip:port/max-connections. Without a max or with 0 the address accepts unlimited connections, which are not limited per IP")
+	flag.IntVar(&c.MaxIncomingPerIP, "max-incoming-per-ip", c.MaxIncomingPerIP, "Maximum number of incoming connections from the same IP. Whitelisted and loopback IPs are not limited. 0 for no limit")
+	flag.IntVar(&c.MaxIncomingPerNetgroup, "max-incoming-per-netgroup", c.MaxIncomingPerNetgroup, "Maximum number of incoming connections from the same address range, an IPv4 /16 or an IPv6 /32. 0 for no limit")
+	flag.DurationVar(&c.IncomingIntroductionWait, "incoming-introduction-wait", c.IncomingIntroductionWait, "How long an incoming connection has to send its introduction before it is disconnected")
+	flag.BoolVar(&c.DisableIncomingEviction, "disable-incoming-eviction", c.DisableIncomingEviction, "Reject new incoming connections when the incoming slots are full, instead of evicting the incoming peer with the worst ban score or from the most represented address range")
 	flag.IntVar(&c.PeerlistSize, "peerlist-size", c.PeerlistSize, "Max number of peers to track in peerlist")
 	flag.DurationVar(&c.OutgoingConnectionsRate, "connection-rate", c.OutgoingConnectionsRate, "How often to make an outgoing connection")
+	flag.IntVar(&c.BanScoreThreshold, "ban-score-threshold", c.BanScoreThreshold, "Ban score at which a misbehaving peer is banned. Invalid messages add 25, stalled block requests 10, consistently stalled requests 20 and protocol violations 50. 0 disables automatic bans")
+	flag.DurationVar(&c.BanScoreWindow, "ban-score-window", c.BanScoreWindow, "How long the misbehavior of a peer counts toward its ban score")
+	flag.DurationVar(&c.BanDuration, "ban-duration", c.BanDuration, "How long misbehaving peers are banned for")
+	flag.DurationVar(&c.ClockSkewWarnThreshold, "clock-skew-warn-threshold", c.ClockSkewWarnThreshold, "Warn when the local clock differs from the median clock of the peers by more than this")
+	flag.DurationVar(&c.MaxNetworkTimeAdjustment, "max-network-time-adjustment", c.MaxNetworkTimeAdjustment, "Maximum adjustment of the local clock to the median clock of the peers. A larger offset is not applied")
+	flag.DurationVar(&c.MaxBlockTimeDrift, "max-block-time-drift", c.MaxBlockTimeDrift, "How far in the future of the network-adjusted time the blocks received from peers can be timestamped. 0 disables the check")
+	flag.DurationVar(&c.RebroadcastInterval, "rebroadcast-interval", c.RebroadcastInterval, "Delay before an unconfirmed transaction injected by this node is rebroadcast, doubled after each rebroadcast. 0 disables the rebroadcasts")
+	flag.DurationVar(&c.RebroadcastMaxInterval, "rebroadcast-max-interval", c.RebroadcastMaxInterval, "Maximum delay between the rebroadcasts of an unconfirmed transaction")
+	flag.DurationVar(&c.RebroadcastExpiry, "rebroadcast-expiry", c.RebroadcastExpiry, "How long after it is injected an unconfirmed transaction is rebroadcast")
+	flag.DurationVar(&c.TxnRequestTimeout, "txn-request-timeout", c.TxnRequestTimeout, "How long a peer has to send the transactions requested from it, before they are requested from another peer that announced them")
+	flag.IntVar(&c.TxnRequestRetries, "txn-request-retries", c.TxnRequestRetries, "How many times a transaction is requested from another peer after a request timed out")
+	flag.IntVar(&c.RequestStallThreshold, "request-stall-threshold", c.RequestStallThreshold, "Number of consecutive transaction or block requests a peer must leave unanswered to add 20 to its ban score. 0 disables the penalty")
+	flag.StringVar(&c.Proxy, "proxy", c.Proxy, "Make outgoing connections through this SOCKS5 proxy, e.g. 127.0.0.1:9050 for Tor. Hostnames are resolved by the proxy and .onion peers are allowed")
+	flag.BoolVar(&c.DisableIPv4, "disable-ipv4", c.DisableIPv4, "Don't listen on or connect to IPv4 addresses")
+	flag.BoolVar(&c.DisableIPv6, "disable-ipv6", c.DisableIPv6, "Don't listen on or connect to IPv6 addresses")
+	flag.StringVar(&c.PreferIPFamily, "prefer-ip-family", c.PreferIPFamily, "Connect to the peers of this IP family first, ipv4 or ipv6")
+	flag.IntVar(&c.UploadRateLimit, "upload-rate-limit", c.UploadRateLimit, "Maximum bytes per second sent to all peers. 0 for no limit")
+	flag.IntVar(&c.DownloadRateLimit, "download-rate-limit", c.DownloadRateLimit, "Maximum bytes per second received from all peers. 0 for no limit")
+	flag.IntVar(&c.PeerUploadRateLimit, "peer-upload-rate-limit", c.PeerUploadRateLimit, "Maximum bytes per second sent to each peer. 0 for no limit")
+	flag.IntVar(&c.PeerDownloadRateLimit, "peer-download-rate-limit", c.PeerDownloadRateLimit, "Maximum bytes per second received from each peer. 0 for no limit")
+	flag.IntVar(&c.AnnounceBurst, "announce-burst", c.AnnounceBurst, "Maximum number of block and transaction announcements broadcast at once. Announcements over the burst are coalesced and throttled. 0 disables the throttling")
+	flag.DurationVar(&c.AnnounceThrottleRate, "announce-throttle-rate", c.AnnounceThrottleRate, "How often one throttled announcement can be broadcast once the announcement burst is used up")
+	flag.IntVar(&c.BlockFanoutWaveSize, "block-fanout-wave-size", c.BlockFanoutWaveSize, "Number of peers with the lowest ping latency a new block is sent to at once. The other peers are sent the block in waves of the same size, unless they reported it meanwhile. 0 sends the new blocks to all peers at once")
+	flag.DurationVar(&c.BlockFanoutStagger, "block-fanout-stagger", c.BlockFanoutStagger, "Delay between the waves of peers a new block is sent to")
+	flag.BoolVar(&c.DisableCompactBlocks, "disable-compact-blocks", c.DisableCompactBlocks, "Don't relay new blocks as compact blocks, nor accept them from peers")
+	flag.BoolVar(&c.DisableHeadersFirstSync, "disable-headers-first-sync", c.DisableHeadersFirstSync, "Don't download blocks header-first from the peers that support it, request them in sequence instead")
+	flag.BoolVar(&c.DisableTxnRelay, "disable-txn-relay", c.DisableTxnRelay, "Blocks-only mode. Don't accept or relay the transactions of peers and ask peers not to relay transactions. Transactions created by this node are still broadcast")
+	flag.Uint64Var(&c.relayMinBurnFactor, "relay-min-burn-factor", uint64(c.RelayMinBurnFactor), "Minimum coinhour burn factor of the transactions accepted from peers. 0 disables the check")
+	flag.Uint64Var(&c.relayMaxTransactionSize, "relay-max-txn-size", uint64(c.RelayMaxTransactionSize), "Maximum size in bytes of the transactions accepted from peers. 0 disables the check")
+	flag.IntVar(&c.RelayMaxOutputs, "relay-max-outputs", c.RelayMaxOutputs, "Maximum number of outputs of the transactions accepted from peers. 0 disables the check")
+	flag.Uint64Var(&c.RelayDustThreshold, "relay-dust-threshold", c.RelayDustThreshold, "Minimum number of droplets of the outputs of the transactions accepted from peers. 0 disables the check")
+	flag.BoolVar(&c.DisableCompression, "disable-compression", c.DisableCompression, "Don't compress the messages sent to peers, nor ask peers to compress their messages")
+	flag.IntVar(&c.CompressionThreshold, "compression-threshold", c.CompressionThreshold, "Minimum size in bytes of the messages compressed for the peers that negotiated compression")
+	flag.BoolVar(&c.EncryptedTransport, "encrypted-transport", c.EncryptedTransport, "Encrypt the connections to the peers that support it, and accept encrypted incoming connections. The peers authenticate with static keys: the key of the node is kept in transport.key in the data directory, and the key of a peer is learned on the first encrypted connection to it, or pinned with -peer-keys. The connections to a peer whose key is known are always encrypted, and are closed if the peer authenticates with another key")
+	flag.StringVar(&c.PeerKeys, "peer-keys", c.PeerKeys, "comma separated list of the encrypted transport keys of peers, as ip:port=pubkey. Requires -encrypted-transport")
 	flag.BoolVar(&c.LocalhostOnly, "localhost-only", c.LocalhostOnly, "Run on localhost and only connect to localhost peers")
 	flag.BoolVar(&c.Arbitrating, "arbitrating", c.Arbitrating, "Run node in arbitrating mode")
-	flag.StringVar(&c.WalletCryptoType, "wallet-crypto-type", c.WalletCryptoType, "wallet crypto type. Can be sha256-xor or scrypt-chacha20poly1305")
+	flag.StringVar(&c.WalletCryptoType, "wallet-crypto-type", c.WalletCryptoType, "wallet crypto type. Can be sha256-xor, scrypt-chacha20poly1305 or argon2id-chacha20poly1305. Wallets encrypted with an older crypto type are upgraded to argon2id-chacha20poly1305 when unlocked, if it is the crypto type")
+	flag.UintVar(&c.WalletArgon2idTime, "wallet-argon2id-time", c.WalletArgon2idTime, "number of passes of the argon2id key derivation of the argon2id-chacha20poly1305 wallet crypto type")
+	flag.UintVar(&c.WalletArgon2idMemory, "wallet-argon2id-memory", c.WalletArgon2idMemory, "memory in KiB of the argon2id key derivation of the argon2id-chacha20poly1305 wallet crypto type")
+	flag.UintVar(&c.WalletArgon2idThreads, "wallet-argon2id-threads", c.WalletArgon2idThreads, "number of threads of the argon2id key derivation of the argon2id-chacha20poly1305 wallet crypto type")
 	flag.BoolVar(&c.Version, "version", false, "show node version")
 }
 
@@ -35,8 +35,18 @@ type NodeParameters struct {
 	GenesisCoinVolume uint64 `mapstructure:"genesis_coin_volume"`
 	// DefaultConnections are the default "trusted" connections a node will try to connect to for bootstrapping
 	DefaultConnections []string `mapstructure:"default_connections"`
+	// Checkpoints are trusted block hashes at known heights, in the format seq:hash.
+	// Blocks that don't match them are rejected
+	Checkpoints []string `mapstructure:"checkpoints"`
 	// PeerlistURL is a URL pointing to a newline-separated list of ip:ports that are used for bootstrapping (but they are not "trusted")
 	PeerListURL string `mapstructure:"peer_list_url"`
+	// DNSSeeds are hostnames whose A and AAAA records are the IPs of peers used for bootstrapping (but they are not "trusted")
+	DNSSeeds []string `mapstructure:"dns_seeds"`
+	// SignedPeerListURL is an HTTPS URL pointing to a peers list signed by PeerListPubkeyStr, used for bootstrapping
+	// if the DNS seeds return no peers. The signature is downloaded from the same URL with a .sig suffix
+	SignedPeerListURL string `mapstructure:"signed_peer_list_url"`
+	// PeerListPubkeyStr is a hex-encoded public key used to verify the peers list of SignedPeerListURL
+	PeerListPubkeyStr string `mapstructure:"peer_list_pubkey_str"`
 	// UnconfirmedBurnFactor is the burn factor to apply when verifying unconfirmed transactions
 	UnconfirmedBurnFactor uint64 `mapstructure:"unconfirmed_burn_factor"`
 	// CreateBlockBurnFactor is the burn factor to apply to transactions when publishing blocks
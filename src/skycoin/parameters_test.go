@@ -27,6 +27,9 @@ func TestNewParameters(t *testing.T) {
 				"172.104.85.6:6000",
 				"139.162.7.132:6000",
 			},
+			Checkpoints: []string{
+				"0:0551a1e5af999fe8fff529f6f2ab341e1e33db95135eef1b2be44fe6981349f3",
+			},
 			Port:                          6000,
 			PeerListURL:                   "https://downloads.skycoin.net/blockchain/peers.txt",
 			WebInterfacePort:              6420,
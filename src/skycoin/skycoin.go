@@ -4,6 +4,7 @@ Package skycoin implements the main daemon cmd's configuration and setup
 package skycoin
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -20,8 +21,10 @@ import (
 
 	"github.com/skycoin/skycoin/src/api"
 	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/encrypt"
 	"github.com/skycoin/skycoin/src/coin"
 	"github.com/skycoin/skycoin/src/daemon"
+	"github.com/skycoin/skycoin/src/daemon/pex"
 	"github.com/skycoin/skycoin/src/params"
 	"github.com/skycoin/skycoin/src/readable"
 	"github.com/skycoin/skycoin/src/util/apputil"
@@ -52,6 +55,7 @@ func (c *Coin) Run() error {
 	var d *daemon.Daemon
 	var webInterface *api.Server
 	var retErr error
+	var fullVerifyDB bool
 	errC := make(chan error, 10)
 
 	if c.config.Node.Version {
@@ -117,8 +121,15 @@ func (c *Coin) Run() error {
 
 	quit := make(chan struct{})
 
+	// ctx is canceled with the quit channel, to stop the database operations on shutdown
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	// Catch SIGINT (CTRL-C) (closes the quit channel)
-	go apputil.CatchInterrupt(quit)
+	go func() {
+		apputil.CatchInterrupt(quit)
+		cancel()
+	}()
 
 	// Catch SIGUSR1 (prints runtime stack to stdout)
 	go apputil.CatchDebug()
@@ -135,9 +146,23 @@ func (c *Coin) Run() error {
 	// Open the database
 	dconf := c.ConfigureDaemon()
 	c.logger.Infof("Opening database %s", dconf.Visor.DBPath)
-	db, err = visor.OpenDB(dconf.Visor.DBPath, c.config.Node.DBReadOnly)
+	openCfg := visor.NewOpenDBConfig()
+	// The database is not modified when it is only checked
+	openCfg.ReadOnly = c.config.Node.DBReadOnly || c.config.Node.DBCheckOnly
+	openCfg.Split = c.config.Node.DBSplitFiles
+	openCfg.LockTimeout = c.config.Node.DBLockTimeout
+	openCfg.LockRetries = c.config.Node.DBLockRetries
+	openCfg.LockRetryBackoff = c.config.Node.DBLockRetryBackoff
+	openCfg.EncryptionPassphrase = c.config.Node.dbEncryptionPassphrase
+	openCfg.Options = c.config.Node.DBOptions
+	db, err = visor.OpenDBWithConfig(dconf.Visor.DBPath, openCfg)
 	if err != nil {
-		c.logger.Errorf("Database failed to open: %v. Is another skycoin instance running?", err)
+		switch err.(type) {
+		case visor.ErrDBLocked:
+			c.logger.Errorf("Database failed to open: %v. Use -db-lock-retries to wait for it to be released", err)
+		default:
+			c.logger.Errorf("Database failed to open: %v", err)
+		}
 		return err
 	}
 
@@ -155,6 +180,23 @@ func (c *Coin) Run() error {
 		c.logger.Infof("DB version: %s", dbVersion)
 	}
 
+	// Apply the schema migrations before anything reads the database.
+	// A database created by a newer node is refused, since its layout may not be understood.
+	if err := visor.MigrateDB(db); err != nil {
+		c.logger.WithError(err).Error("visor.MigrateDB failed")
+		retErr = err
+		goto earlyShutdown
+	}
+
+	// Bootstrap an empty database from an unspent output set snapshot, so that only the later blocks are synced
+	if c.config.Node.UnspentSnapshot != "" && !db.IsReadOnly() {
+		if err := c.importUnspentSnapshot(ctx, db); err != nil {
+			c.logger.WithError(err).Error("importUnspentSnapshot failed")
+			retErr = err
+			goto earlyShutdown
+		}
+	}
+
 	c.logger.Infof("DB verify checkpoint version: %s", DBVerifyCheckpointVersion)
 
 	// If the saved DB version is higher than the app version, abort.
@@ -166,23 +208,74 @@ func (c *Coin) Run() error {
 		goto earlyShutdown
 	}
 
-	// Verify the DB if the version detection says to, or if it was requested on the command line
-	if shouldVerifyDB(appVersion, dbVersion) || c.config.Node.VerifyDB {
-		if c.config.Node.ResetCorruptDB {
+	// Verify the DB if the version detection says to, or if it was requested on the command line.
+	// A version upgrade verification always walks the full chain, since the verification rules may have changed.
+	fullVerifyDB = shouldVerifyDB(appVersion, dbVersion) || c.config.Node.FullVerifyDB
+	if fullVerifyDB || c.config.Node.VerifyDB || c.config.Node.DBCheckOnly {
+		checkCfg := visor.CheckDatabaseConfig{
+			Pubkey:      c.config.Node.blockchainPubkey,
+			FullVerify:  fullVerifyDB,
+			Progress:    visor.NewVerifyProgressLogger(visor.VerifyProgressLogRate),
+			Workers:     c.config.Node.verifyDBWorkers,
+			Checkpoints: c.config.Node.checkpoints,
+		}
+
+		if c.config.Node.DBCheckOnly {
+			// Report what -reset-corrupt-db would do, then exit
+			c.logger.Info("Checking database, it will not be modified")
+			plan, err := visor.PlanResetCorruptDB(ctx, db, checkCfg, c.config.Node.DBReadOnly)
+			if err != nil {
+				if err != visor.ErrVerifyStopped {
+					c.logger.Errorf("visor.PlanResetCorruptDB failed: %v", err)
+					retErr = err
+				}
+				goto earlyShutdown
+			}
+
+			c.logRecoverCorruptDBPlan(plan)
+			if plan.Action != visor.RecoverActionNone {
+				retErr = fmt.Errorf("Database is corrupted: %s", plan.Error)
+			}
+			goto earlyShutdown
+		} else if c.config.Node.VerifyDBInBackground {
+			// The daemon verifies the database after it has started
+			c.logger.Info("Database will be checked in the background")
+			dconf.Visor.VerifyDBInBackground = true
+			dconf.Visor.FullVerifyDB = fullVerifyDB
+		} else if c.config.Node.ResetCorruptDB {
 			// Check the database integrity and recreate it if necessary
 			c.logger.Info("Checking database and resetting if corrupted")
-			if newDB, err := visor.ResetCorruptDB(db, c.config.Node.blockchainPubkey, quit); err != nil {
+			err := visor.CheckDatabase(ctx, db, checkCfg)
+
+			// Corrupted blocks are repaired with copies requested from peers once the daemon has started.
+			// If the repair fails, the daemon stops and the database is reset on shutdown.
+			if e, ok := err.(visor.ErrCorruptDB); ok && len(e.CorruptedBlocks) != 0 && c.config.Node.RepairCorruptDBTimeout != 0 && !db.IsReadOnly() {
+				c.logger.Errorf("Database has %d corrupted blocks, repairing them from peers: %v", len(e.CorruptedBlocks), err)
+				dconf.Visor.CorruptedBlocks = e.CorruptedBlocks
+				err = nil
+			}
+
+			if visor.IsCorruptDBError(err) {
+				if newDB, err := visor.RecoverCorruptDB(ctx, db, visor.RecoverCorruptDBConfig{
+					Pubkey:      checkCfg.Pubkey,
+					NodeVersion: c.config.Build.Version,
+				}, err); err != nil {
+					c.logger.Errorf("visor.RecoverCorruptDB failed: %v", err)
+					retErr = err
+					goto earlyShutdown
+				} else {
+					db = newDB
+				}
+			} else if err != nil {
 				if err != visor.ErrVerifyStopped {
-					c.logger.Errorf("visor.ResetCorruptDB failed: %v", err)
+					c.logger.Errorf("visor.CheckDatabase failed: %v", err)
 					retErr = err
 				}
 				goto earlyShutdown
-			} else {
-				db = newDB
 			}
 		} else {
 			c.logger.Info("Checking database")
-			if err := visor.CheckDatabase(db, c.config.Node.blockchainPubkey, quit); err != nil {
+			if err := visor.CheckDatabase(ctx, db, checkCfg); err != nil {
 				if err != visor.ErrVerifyStopped {
 					c.logger.Errorf("visor.CheckDatabase failed: %v", err)
 					retErr = err
@@ -192,6 +285,15 @@ func (c *Coin) Run() error {
 		}
 	}
 
+	// Apply the retention policy to the corrupted databases quarantined by this or earlier runs
+	if !db.IsReadOnly() {
+		for _, path := range visor.DBFiles(db) {
+			if err := visor.ApplyQuarantinePolicy(path, dconf.Visor.DBQuarantine); err != nil {
+				c.logger.WithError(err).Error("visor.ApplyQuarantinePolicy failed")
+			}
+		}
+	}
+
 	// Update the DB version
 	if !db.IsReadOnly() {
 		if err := visor.SetDBVersion(db, *appVersion); err != nil {
@@ -201,6 +303,29 @@ func (c *Coin) Run() error {
 		}
 	}
 
+	// Compact the DB before the daemon starts using it
+	if c.config.Node.CompactDB {
+		c.logger.Info("Compacting database")
+		if newDB, err := visor.CompactDB(ctx, db, visor.CompactDBConfig{
+			Check: visor.CheckDatabaseConfig{
+				Pubkey:      c.config.Node.blockchainPubkey,
+				FullVerify:  true,
+				Progress:    visor.NewVerifyProgressLogger(visor.VerifyProgressLogRate),
+				Workers:     c.config.Node.verifyDBWorkers,
+				Checkpoints: c.config.Node.checkpoints,
+			},
+			TxMaxSize: visor.DefaultCompactTxMaxSize,
+		}); err != nil {
+			if err != visor.ErrVerifyStopped {
+				c.logger.WithError(err).Error("visor.CompactDB failed")
+				retErr = err
+			}
+			goto earlyShutdown
+		} else {
+			db = newDB
+		}
+	}
+
 	c.logger.Infof("Coinhour burn factor for creating transactions is %d", params.UserBurnFactor)
 	c.logger.Infof("Max user transaction size is %d", params.UserMaxTransactionSize)
 
@@ -292,6 +417,26 @@ func (c *Coin) Run() error {
 	c.logger.Info("Waiting for goroutines to finish")
 	wg.Wait()
 
+	// The background database verification found a corrupted database.
+	// Now that the daemon has stopped using the database, it is safe to recover it.
+	if c.config.Node.ResetCorruptDB && visor.IsCorruptDBError(retErr) {
+		c.logger.Info("Recovering the corrupted database")
+		if newDB, err := visor.RecoverCorruptDB(ctx, db, visor.RecoverCorruptDBConfig{
+			Pubkey:      c.config.Node.blockchainPubkey,
+			NodeVersion: c.config.Build.Version,
+		}, retErr); err != nil {
+			c.logger.Errorf("visor.RecoverCorruptDB failed: %v", err)
+			db = nil
+		} else {
+			db = newDB
+			for _, path := range visor.DBFiles(db) {
+				if err := visor.ApplyQuarantinePolicy(path, dconf.Visor.DBQuarantine); err != nil {
+					c.logger.WithError(err).Error("visor.ApplyQuarantinePolicy failed")
+				}
+			}
+		}
+	}
+
 earlyShutdown:
 	if db != nil {
 		c.logger.Info("Closing database")
@@ -311,6 +456,44 @@ earlyShutdown:
 	return retErr
 }
 
+// logRecoverCorruptDBPlan logs what -reset-corrupt-db would do with the database
+func (c *Coin) logRecoverCorruptDBPlan(plan *visor.RecoverCorruptDBPlan) {
+	if plan.Action == visor.RecoverActionNone {
+		c.logger.Critical().Infof("Database %s is not corrupted, -reset-corrupt-db would not change it", plan.Path)
+		return
+	}
+
+	c.logger.Critical().Errorf("Database %s is corrupted: %s", plan.Path, plan.Error)
+	if len(plan.CorruptedBlocks) != 0 {
+		c.logger.Critical().Infof("Corrupted blocks: %v", plan.CorruptedBlocks)
+	}
+	if len(plan.CorruptedHistoryBlocks) != 0 {
+		c.logger.Critical().Infof("Blocks with corrupted history db indexes: %v", plan.CorruptedHistoryBlocks)
+	}
+	if len(plan.RepairableBlocks) != 0 && c.config.Node.RepairCorruptDBTimeout != 0 {
+		c.logger.Critical().Infof("-reset-corrupt-db would first request blocks %v from peers for up to %s, and recover the database only if the repair fails",
+			plan.RepairableBlocks, c.config.Node.RepairCorruptDBTimeout)
+	}
+
+	switch plan.Action {
+	case visor.RecoverActionRebuildHistoryBlocks:
+		c.logger.Critical().Info("-reset-corrupt-db would rebuild the history db indexes of the corrupted blocks, or the entire history db if that fails")
+	case visor.RecoverActionRebuildHistory:
+		c.logger.Critical().Info("-reset-corrupt-db would rebuild the entire history db")
+	case visor.RecoverActionResetDB:
+		c.logger.Critical().Info("-reset-corrupt-db would erase the database and sync the blockchain from the network again")
+	}
+
+	for _, path := range plan.QuarantinePaths {
+		if plan.Action == visor.RecoverActionResetDB {
+			c.logger.Critical().Infof("The corrupted database would be moved to %s", path)
+		} else {
+			c.logger.Critical().Infof("The corrupted database would be copied to %s", path)
+		}
+	}
+	c.logger.Critical().Infof("A forensic bundle would be written to %s", plan.ForensicsPath)
+}
+
 // NewCoin returns a new fiber coin instance
 func NewCoin(config Config, logger *logging.Logger) *Coin {
 	return &Coin{
@@ -319,6 +502,33 @@ func NewCoin(config Config, logger *logging.Logger) *Coin {
 	}
 }
 
+// importUnspentSnapshot bootstraps the database from the unspent output set snapshot, unless it already has blocks
+func (c *Coin) importUnspentSnapshot(ctx context.Context, db *dbutil.DB) error {
+	f, err := os.Open(c.config.Node.UnspentSnapshot)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	c.logger.Infof("Importing unspent output set snapshot %s", c.config.Node.UnspentSnapshot)
+
+	hdr, err := visor.ImportUnspentSnapshot(ctx, db, f, visor.ImportUnspentSnapshotConfig{
+		Pubkey:      c.config.Node.blockchainPubkey,
+		Checkpoints: c.config.Node.checkpoints,
+		Commitment:  c.config.Node.unspentSnapshotCommitment,
+	})
+	switch err {
+	case nil:
+		c.logger.Infof("Imported %d unspent outputs after block %d, syncing the later blocks", hdr.UxCount, hdr.Seq)
+		return nil
+	case visor.ErrSnapshotDBNotEmpty:
+		c.logger.Info("Database already has blocks, the unspent output set snapshot is not imported")
+		return nil
+	default:
+		return err
+	}
+}
+
 func (c *Coin) initLogFile() (*os.File, error) {
 	logDir := filepath.Join(c.config.Node.DataDirectory, "logs")
 	if err := createDirIfNotExist(logDir); err != nil {
@@ -348,12 +558,19 @@ func (c *Coin) ConfigureDaemon() daemon.Config {
 
 	dc.Pool.DefaultConnections = c.config.Node.DefaultConnections
 	dc.Pool.MaxDefaultPeerOutgoingConnections = c.config.Node.MaxDefaultPeerOutgoingConnections
+	dc.Pool.UploadRateLimit = c.config.Node.UploadRateLimit
+	dc.Pool.DownloadRateLimit = c.config.Node.DownloadRateLimit
+	dc.Pool.PeerUploadRateLimit = c.config.Node.PeerUploadRateLimit
+	dc.Pool.PeerDownloadRateLimit = c.config.Node.PeerDownloadRateLimit
 
 	dc.Pex.DataDirectory = c.config.Node.DataDirectory
 	dc.Pex.Disabled = c.config.Node.DisablePEX
 	dc.Pex.Max = c.config.Node.PeerlistSize
 	dc.Pex.DownloadPeerList = c.config.Node.DownloadPeerList
 	dc.Pex.PeerListURL = c.config.Node.PeerListURL
+	dc.Pex.DNSSeeds = c.config.Node.dnsSeeds
+	dc.Pex.SignedPeerListURL = c.config.Node.SignedPeerListURL
+	dc.Pex.PeerListPubKey = c.config.Node.peerListPubkey
 	dc.Pex.DisableTrustedPeers = c.config.Node.DisableDefaultPeers
 	dc.Pex.CustomPeersFile = c.config.Node.CustomPeersFile
 	dc.Pex.DefaultConnections = c.config.Node.DefaultConnections
@@ -362,23 +579,66 @@ func (c *Coin) ConfigureDaemon() daemon.Config {
 	dc.Daemon.DisableOutgoingConnections = c.config.Node.DisableOutgoingConnections
 	dc.Daemon.DisableIncomingConnections = c.config.Node.DisableIncomingConnections
 	dc.Daemon.DisableNetworking = c.config.Node.DisableNetworking
+	dc.Daemon.Crawler = c.config.Node.Crawler
+	dc.Daemon.CrawlerReportFile = c.config.Node.CrawlerReportFile
+	dc.Daemon.CrawlerVisitTimeout = c.config.Node.CrawlerVisitTimeout
 	dc.Daemon.Port = c.config.Node.Port
 	dc.Daemon.Address = c.config.Node.Address
 	dc.Daemon.LocalhostOnly = c.config.Node.LocalhostOnly
+	dc.Daemon.ProxyAddress = c.config.Node.Proxy
+	dc.Daemon.DisableIPv4 = c.config.Node.DisableIPv4
+	dc.Daemon.DisableIPv6 = c.config.Node.DisableIPv6
+	dc.Daemon.PreferIPFamily = pex.IPFamily(c.config.Node.PreferIPFamily)
 	dc.Daemon.MaxConnections = c.config.Node.MaxConnections
 	dc.Daemon.MaxOutgoingConnections = c.config.Node.MaxOutgoingConnections
+	dc.Daemon.Whitelist = c.config.Node.peerWhitelist
+	dc.Daemon.MaxWhitelistedConnections = c.config.Node.MaxWhitelistedConnections
+	dc.Daemon.ListenAddresses = c.config.Node.listenAddresses
+	dc.Daemon.MaxIncomingPerIP = c.config.Node.MaxIncomingPerIP
+	dc.Daemon.MaxIncomingPerNetgroup = c.config.Node.MaxIncomingPerNetgroup
+	dc.Daemon.IncomingIntroductionWait = c.config.Node.IncomingIntroductionWait
+	dc.Daemon.DisableIncomingEviction = c.config.Node.DisableIncomingEviction
 	dc.Daemon.DataDirectory = c.config.Node.DataDirectory
 	dc.Daemon.LogPings = !c.config.Node.DisablePingPong
 	dc.Daemon.BlockchainPubkey = c.config.Node.blockchainPubkey
 	dc.Daemon.UserAgent = c.config.Node.userAgent
 	dc.Daemon.UnconfirmedBurnFactor = c.config.Node.UnconfirmedBurnFactor
 	dc.Daemon.UnconfirmedMaxTransactionSize = c.config.Node.UnconfirmedMaxTransactionSize
+	dc.Daemon.BanScoreThreshold = c.config.Node.BanScoreThreshold
+	dc.Daemon.BanScoreWindow = c.config.Node.BanScoreWindow
+	dc.Daemon.BanDuration = c.config.Node.BanDuration
+	dc.Daemon.ClockSkewWarnThreshold = c.config.Node.ClockSkewWarnThreshold
+	dc.Daemon.MaxNetworkTimeAdjustment = c.config.Node.MaxNetworkTimeAdjustment
+	dc.Daemon.MaxBlockTimeDrift = c.config.Node.MaxBlockTimeDrift
+	dc.Daemon.RebroadcastInterval = c.config.Node.RebroadcastInterval
+	dc.Daemon.RebroadcastMaxInterval = c.config.Node.RebroadcastMaxInterval
+	dc.Daemon.RebroadcastExpiry = c.config.Node.RebroadcastExpiry
+	dc.Daemon.TxnRequestTimeout = c.config.Node.TxnRequestTimeout
+	dc.Daemon.TxnRequestRetries = c.config.Node.TxnRequestRetries
+	dc.Daemon.RequestStallThreshold = c.config.Node.RequestStallThreshold
+	dc.Daemon.AnnounceBurst = c.config.Node.AnnounceBurst
+	dc.Daemon.AnnounceThrottleRate = c.config.Node.AnnounceThrottleRate
+	dc.Daemon.BlockFanoutWaveSize = c.config.Node.BlockFanoutWaveSize
+	dc.Daemon.BlockFanoutStagger = c.config.Node.BlockFanoutStagger
+	dc.Daemon.DisableCompactBlocks = c.config.Node.DisableCompactBlocks
+	dc.Daemon.DisableTxnRelay = c.config.Node.DisableTxnRelay
+	dc.Daemon.RelayMinBurnFactor = c.config.Node.RelayMinBurnFactor
+	dc.Daemon.RelayMaxTransactionSize = c.config.Node.RelayMaxTransactionSize
+	dc.Daemon.RelayMaxOutputs = c.config.Node.RelayMaxOutputs
+	dc.Daemon.RelayDustThreshold = c.config.Node.RelayDustThreshold
+	dc.Daemon.DisableHeadersFirstSync = c.config.Node.DisableHeadersFirstSync
+	dc.Daemon.EncryptedTransport = c.config.Node.EncryptedTransport
+	dc.Daemon.PeerKeys = c.config.Node.peerKeys
+	dc.Daemon.DisableCompression = c.config.Node.DisableCompression
+	dc.Pool.CompressionThreshold = c.config.Node.CompressionThreshold
 
 	if c.config.Node.OutgoingConnectionsRate == 0 {
 		c.config.Node.OutgoingConnectionsRate = time.Millisecond
 	}
 	dc.Daemon.OutgoingRate = c.config.Node.OutgoingConnectionsRate
 	dc.Visor.IsBlockPublisher = c.config.Node.RunBlockPublisher
+	dc.Daemon.BlockPublisherStandby = c.config.Node.BlockPublisherStandby
+	dc.Daemon.BlockPublisherFailoverTimeout = c.config.Node.BlockPublisherFailoverTimeout
 
 	dc.Visor.BlockchainPubkey = c.config.Node.blockchainPubkey
 	dc.Visor.BlockchainSeckey = c.config.Node.blockchainSeckey
@@ -387,14 +647,40 @@ func (c *Coin) ConfigureDaemon() daemon.Config {
 	dc.Visor.UnconfirmedMaxTransactionSize = c.config.Node.UnconfirmedMaxTransactionSize
 	dc.Visor.UnconfirmedBurnFactor = c.config.Node.UnconfirmedBurnFactor
 	dc.Visor.CreateBlockBurnFactor = c.config.Node.CreateBlockBurnFactor
+	dc.Visor.UnconfirmedMaxAge = c.config.Node.UnconfirmedMaxAge
+	dc.Visor.UnconfirmedMaxPoolSize = c.config.Node.UnconfirmedMaxPoolSize
 
 	dc.Visor.GenesisAddress = c.config.Node.genesisAddress
 	dc.Visor.GenesisSignature = c.config.Node.genesisSignature
 	dc.Visor.GenesisTimestamp = c.config.Node.GenesisTimestamp
 	dc.Visor.GenesisCoinVolume = c.config.Node.GenesisCoinVolume
 	dc.Visor.DBPath = c.config.Node.DBPath
+	dc.Visor.DBBackupDir = c.config.Node.DBBackupDir
+	dc.Visor.VerifyDBWorkers = c.config.Node.verifyDBWorkers
+	dc.Visor.PruneDepth = c.config.Node.PruneDepth
+	dc.Visor.BlockCompression = c.config.Node.blockCompression
+	dc.Visor.BlockCacheSize = c.config.Node.BlockCacheSize
+	dc.Visor.BlockApplyBatchSize = c.config.Node.BlockApplyBatchSize
+	dc.Visor.BlockApplyBatchTime = c.config.Node.BlockApplyBatchTime
+	dc.Visor.DBScrubInterval = c.config.Node.DBScrubInterval
+	dc.Visor.DBScrubBlocks = c.config.Node.DBScrubBlocks
+	dc.Visor.DBReplicaInterval = c.config.Node.DBReplicaInterval
+	dc.Visor.DBReplicaDir = c.config.Node.DBReplicaDir
+	dc.Visor.EventExportFile = c.config.Node.ExportEventsFile
+	dc.Visor.EventExportInterval = c.config.Node.ExportEventsInterval
+	dc.Visor.Checkpoints = c.config.Node.checkpoints
+	// Corrupted blocks can't be repaired by peers when networking is disabled
+	if c.config.Node.ResetCorruptDB && !c.config.Node.DisableNetworking {
+		dc.Visor.BlockRepairTimeout = c.config.Node.RepairCorruptDBTimeout
+	}
+	dc.Visor.DBQuarantine = visor.QuarantineConfig{
+		MaxCopies: c.config.Node.DBQuarantineMaxCopies,
+		Compress:  c.config.Node.DBQuarantineCompress,
+	}
 	dc.Visor.Arbitrating = c.config.Node.Arbitrating
 	dc.Visor.WalletDirectory = c.config.Node.WalletDirectory
+	dc.Visor.WalletDirectories = c.config.Node.walletDirectories
+	dc.Visor.WalletStorage = c.config.Node.WalletStorage
 	_, dc.Visor.EnableWalletAPI = c.config.Node.enabledAPISets[api.EndpointsWallet]
 	_, dc.Visor.EnableSeedAPI = c.config.Node.enabledAPISets[api.EndpointsInsecureWalletSeed]
 
@@ -408,6 +694,12 @@ func (c *Coin) ConfigureDaemon() daemon.Config {
 	}
 
 	dc.Visor.WalletCryptoType = cryptoType
+	dc.Visor.WalletArgon2id = encrypt.Argon2idChacha20poly1305{
+		Time:    uint32(c.config.Node.WalletArgon2idTime),
+		Memory:  uint32(c.config.Node.WalletArgon2idMemory),
+		Threads: uint8(c.config.Node.WalletArgon2idThreads),
+		KeyLen:  encrypt.Argon2idKeyLen,
+	}
 
 	return dc
 }
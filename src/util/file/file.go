@@ -167,6 +167,63 @@ func SaveBinary(filename string, data []byte, mode os.FileMode) error {
 	return os.Remove(tmpname)
 }
 
+// SaveBinaryAtomic persists data into given file atomically: the data is written and synced to a temporary file,
+// which replaces the file by renaming it, so the file has either its previous or its new contents after a crash.
+// The directory is synced afterwards, to persist the rename.
+func SaveBinaryAtomic(filename string, data []byte, mode os.FileMode) error {
+	tmpname := filename + ".tmp"
+	f, err := os.OpenFile(tmpname, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+
+	removeTmp := func() {
+		if err := os.Remove(tmpname); err != nil && !os.IsNotExist(err) {
+			logger.WithError(err).Warningf("os.Remove(%s) failed", tmpname)
+		}
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		removeTmp()
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		removeTmp()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		removeTmp()
+		return err
+	}
+
+	if err := os.Rename(tmpname, filename); err != nil {
+		removeTmp()
+		return err
+	}
+
+	return syncDir(filepath.Dir(filename))
+}
+
+// syncDir syncs a directory, to persist the creation or the renaming of its files.
+// Directories can't be synced on windows, where renames are persisted by the filesystem.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
+
 //TODO: require file named after application and then hashcode, in static directory
 
 // ResolveResourceDirectory searches locations for a research directory and returns absolute path
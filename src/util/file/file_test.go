@@ -3,6 +3,7 @@ package file
 import (
 	"bytes"
 	"crypto/rand"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
@@ -212,3 +213,31 @@ func TestSaveBinary(t *testing.T) {
 	requireFileMode(t, fn, 0644)
 	// requireFileMode(t, fn+".bak", 0644)
 }
+
+func TestSaveBinaryAtomic(t *testing.T) {
+	fn := "test.bin"
+	defer cleanup(fn)
+	b := make([]byte, 128)
+	_, err := rand.Read(b)
+	require.NoError(t, err)
+	err = SaveBinaryAtomic(fn, b, 0600)
+	require.NoError(t, err)
+	testutil.RequireFileNotExists(t, fn+".tmp")
+	requireIsRegularFile(t, fn)
+	requireFileContentsBinary(t, fn, b)
+	requireFileMode(t, fn, 0600)
+
+	// A stale temporary file of an interrupted write is replaced
+	require.NoError(t, ioutil.WriteFile(fn+".tmp", []byte("stale"), 0600))
+
+	b2 := make([]byte, 64)
+	_, err = rand.Read(b2)
+	require.NoError(t, err)
+
+	err = SaveBinaryAtomic(fn, b2, 0600)
+	require.NoError(t, err)
+	requireIsRegularFile(t, fn)
+	testutil.RequireFileNotExists(t, fn+".tmp")
+	requireFileContentsBinary(t, fn, b2)
+	requireFileMode(t, fn, 0600)
+}
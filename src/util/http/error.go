@@ -42,6 +42,11 @@ func Error405(w http.ResponseWriter) {
 	ErrorXXX(w, http.StatusMethodNotAllowed, "")
 }
 
+// Error410 respond with a 410 error and include a message
+func Error410(w http.ResponseWriter, msg string) {
+	ErrorXXX(w, http.StatusGone, msg)
+}
+
 // Error415 respond with a 415 error
 func Error415(w http.ResponseWriter) {
 	ErrorXXX(w, http.StatusUnsupportedMediaType, "")
@@ -1,7 +1,10 @@
 package httphelper
 
 import (
+	"bufio"
 	"bytes"
+	"errors"
+	"net"
 	"net/http"
 	"strings"
 	"time"
@@ -51,3 +54,14 @@ func (lrw *wrappedResponseWriter) Write(buff []byte) (int, error) {
 	}
 	return retVal, err
 }
+
+// Hijack implements http.Hijacker, for the handlers that take over the connection, such as websockets
+func (lrw *wrappedResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := lrw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("http.Hijacker interface is not supported")
+	}
+
+	lrw.statusCode = http.StatusSwitchingProtocols
+	return hj.Hijack()
+}
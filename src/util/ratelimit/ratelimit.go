@@ -0,0 +1,87 @@
+/*
+Package ratelimit implements a token bucket rate limiter
+*/
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket. Tokens are added at a constant rate, up to the burst size, and are taken
+// for each unit of work, e.g. for each byte sent. A nil *Limiter does not limit.
+type Limiter struct {
+	sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter creates a Limiter which adds rate tokens per second, up to burst tokens. The bucket starts full.
+// Returns nil, which does not limit, if rate is 0
+func NewLimiter(rate float64, burst int) *Limiter {
+	if rate <= 0 {
+		return nil
+	}
+
+	return &Limiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+	}
+}
+
+// advance adds the tokens earned since the last update. The caller must hold the lock
+func (l *Limiter) advance(now time.Time) {
+	if !l.last.IsZero() && now.After(l.last) {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+
+	if now.After(l.last) {
+		l.last = now
+	}
+}
+
+// Allow takes n tokens and returns true if there are at least n tokens at time now.
+// Otherwise no tokens are taken. n greater than the burst size is never allowed
+func (l *Limiter) Allow(now time.Time, n int) bool {
+	if l == nil {
+		return true
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	l.advance(now)
+
+	if l.tokens < float64(n) {
+		return false
+	}
+
+	l.tokens -= float64(n)
+	return true
+}
+
+// Reserve takes n tokens at time now and returns how long to wait before acting on them.
+// The tokens may go into debt, so n may be greater than the burst size
+func (l *Limiter) Reserve(now time.Time, n int) time.Duration {
+	if l == nil {
+		return 0
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	l.advance(now)
+
+	l.tokens -= float64(n)
+	if l.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLimiterDisabled(t *testing.T) {
+	l := NewLimiter(0, 10)
+	require.Nil(t, l)
+
+	// A nil Limiter does not limit
+	now := time.Now()
+	require.True(t, l.Allow(now, 1000))
+	require.Equal(t, time.Duration(0), l.Reserve(now, 1000))
+}
+
+func TestLimiterAllow(t *testing.T) {
+	now := time.Now()
+	l := NewLimiter(2, 4)
+
+	// The bucket starts full
+	require.True(t, l.Allow(now, 3))
+	require.True(t, l.Allow(now, 1))
+	require.False(t, l.Allow(now, 1))
+
+	// Tokens are added at the rate
+	now = now.Add(time.Millisecond * 500)
+	require.True(t, l.Allow(now, 1))
+	require.False(t, l.Allow(now, 1))
+
+	// Tokens are capped at the burst size
+	now = now.Add(time.Hour)
+	require.False(t, l.Allow(now, 5))
+	require.True(t, l.Allow(now, 4))
+
+	// Time going backwards does not add tokens
+	require.False(t, l.Allow(now.Add(-time.Second), 1))
+}
+
+func TestLimiterReserve(t *testing.T) {
+	now := time.Now()
+	l := NewLimiter(100, 100)
+
+	require.Equal(t, time.Duration(0), l.Reserve(now, 60))
+	require.Equal(t, time.Duration(0), l.Reserve(now, 40))
+
+	// The tokens go into debt
+	require.Equal(t, time.Millisecond*500, l.Reserve(now, 50))
+	require.Equal(t, time.Second*2, l.Reserve(now, 150))
+
+	// The debt is paid at the rate
+	now = now.Add(time.Second)
+	require.Equal(t, time.Second, l.Reserve(now, 0))
+
+	now = now.Add(time.Second * 2)
+	require.Equal(t, time.Duration(0), l.Reserve(now, 100))
+	require.False(t, l.Allow(now, 1))
+}
@@ -0,0 +1,233 @@
+/*
+Package socks5 implements a SOCKS5 client (RFC 1928) for making TCP connections through a proxy, such as Tor
+*/
+package socks5
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+const (
+	socks5Version = 5
+
+	authNone         = 0
+	authNoAcceptable = 0xff
+
+	cmdConnect = 1
+
+	atypIPv4   = 1
+	atypDomain = 3
+	atypIPv6   = 4
+)
+
+var (
+	// ErrUnsupportedNetwork is returned when dialing a network other than tcp
+	ErrUnsupportedNetwork = errors.New("SOCKS5 proxy only supports tcp connections")
+	// ErrInvalidVersion is returned when the proxy does not reply with the SOCKS5 version
+	ErrInvalidVersion = errors.New("Proxy is not a SOCKS5 proxy")
+	// ErrAuthRequired is returned when the proxy requires authentication
+	ErrAuthRequired = errors.New("SOCKS5 proxy requires authentication, which is not supported")
+	// ErrHostnameTooLong is returned when the destination hostname is longer than 255 bytes
+	ErrHostnameTooLong = errors.New("Hostname is too long for SOCKS5")
+	// ErrInvalidAddressType is returned when the proxy replies with an unknown address type
+	ErrInvalidAddressType = errors.New("SOCKS5 proxy replied with an invalid address type")
+)
+
+// replyErrors are the errors for the reply codes of a connect request
+var replyErrors = map[byte]string{
+	1: "general SOCKS server failure",
+	2: "connection not allowed by ruleset",
+	3: "network unreachable",
+	4: "host unreachable",
+	5: "connection refused",
+	6: "TTL expired",
+	7: "command not supported",
+	8: "address type not supported",
+}
+
+// Dialer makes TCP connections through a SOCKS5 proxy.
+// Hostnames are sent to the proxy to be resolved, they are never resolved locally, so that DNS requests
+// do not leak outside of the proxy and the .onion addresses of Tor can be connected to.
+type Dialer struct {
+	// ProxyAddress is the host:port address of the proxy
+	ProxyAddress string
+	// Timeout for connecting to the proxy and for the proxy to connect to the destination. 0 disables the timeout
+	Timeout time.Duration
+}
+
+// NewDialer creates a Dialer
+func NewDialer(proxyAddress string, timeout time.Duration) *Dialer {
+	return &Dialer{
+		ProxyAddress: proxyAddress,
+		Timeout:      timeout,
+	}
+}
+
+// Dial connects to addr, a host:port address, through the proxy.
+// The RemoteAddr of the returned connection is addr, not the address of the proxy.
+func (d *Dialer) Dial(network, addr string) (net.Conn, error) {
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+	default:
+		return nil, ErrUnsupportedNetwork
+	}
+
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid port in address %s", addr)
+	}
+
+	conn, err := net.DialTimeout("tcp", d.ProxyAddress, d.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := d.handshake(conn, host, uint16(port)); err != nil {
+		conn.Close() // nolint: errcheck
+		return nil, err
+	}
+
+	return &proxiedConn{
+		Conn: conn,
+		remoteAddr: proxiedAddr{
+			network: network,
+			addr:    addr,
+		},
+	}, nil
+}
+
+// handshake sends a CONNECT request for host:port, within the timeout
+func (d *Dialer) handshake(conn net.Conn, host string, port uint16) error {
+	if d.Timeout == 0 {
+		return connect(conn, host, port)
+	}
+
+	if err := conn.SetDeadline(time.Now().Add(d.Timeout)); err != nil {
+		return err
+	}
+
+	if err := connect(conn, host, port); err != nil {
+		return err
+	}
+
+	return conn.SetDeadline(time.Time{})
+}
+
+// connect negotiates the authentication method and sends a CONNECT request for host:port
+func connect(conn net.Conn, host string, port uint16) error {
+	if _, err := conn.Write([]byte{socks5Version, 1, authNone}); err != nil {
+		return err
+	}
+
+	var methodReply [2]byte
+	if _, err := io.ReadFull(conn, methodReply[:]); err != nil {
+		return err
+	}
+
+	if methodReply[0] != socks5Version {
+		return ErrInvalidVersion
+	}
+
+	switch methodReply[1] {
+	case authNone:
+	case authNoAcceptable:
+		return ErrAuthRequired
+	default:
+		return fmt.Errorf("SOCKS5 proxy selected an unsupported authentication method %d", methodReply[1])
+	}
+
+	req := []byte{socks5Version, cmdConnect, 0}
+	if ip := net.ParseIP(host); ip == nil {
+		if len(host) > 255 {
+			return ErrHostnameTooLong
+		}
+		req = append(req, atypDomain, byte(len(host)))
+		req = append(req, host...)
+	} else if ip4 := ip.To4(); ip4 != nil {
+		req = append(req, atypIPv4)
+		req = append(req, ip4...)
+	} else {
+		req = append(req, atypIPv6)
+		req = append(req, ip.To16()...)
+	}
+	req = append(req, byte(port>>8), byte(port))
+
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	// The reply is VER, REP, RSV, ATYP, BND.ADDR, BND.PORT
+	var reply [4]byte
+	if _, err := io.ReadFull(conn, reply[:]); err != nil {
+		return err
+	}
+
+	if reply[0] != socks5Version {
+		return ErrInvalidVersion
+	}
+
+	if reply[1] != 0 {
+		msg, ok := replyErrors[reply[1]]
+		if !ok {
+			msg = fmt.Sprintf("unknown error %d", reply[1])
+		}
+		return fmt.Errorf("SOCKS5 proxy failed to connect to %s: %s", net.JoinHostPort(host, strconv.Itoa(int(port))), msg)
+	}
+
+	var bndAddrLen int
+	switch reply[3] {
+	case atypIPv4:
+		bndAddrLen = net.IPv4len
+	case atypIPv6:
+		bndAddrLen = net.IPv6len
+	case atypDomain:
+		var n [1]byte
+		if _, err := io.ReadFull(conn, n[:]); err != nil {
+			return err
+		}
+		bndAddrLen = int(n[0])
+	default:
+		return ErrInvalidAddressType
+	}
+
+	// The bound address and port are not used
+	_, err := io.ReadFull(conn, make([]byte, bndAddrLen+2))
+	return err
+}
+
+// proxiedConn is a connection through the proxy, which reports the destination as its remote address
+type proxiedConn struct {
+	net.Conn
+	remoteAddr proxiedAddr
+}
+
+// RemoteAddr returns the destination address
+func (c *proxiedConn) RemoteAddr() net.Addr {
+	return c.remoteAddr
+}
+
+// proxiedAddr is a destination address of the proxy, which may be a hostname
+type proxiedAddr struct {
+	network string
+	addr    string
+}
+
+// Network returns the network of the address
+func (a proxiedAddr) Network() string {
+	return a.network
+}
+
+// String returns the address as host:port
+func (a proxiedAddr) String() string {
+	return a.addr
+}
@@ -0,0 +1,167 @@
+package socks5
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// runProxy runs a fake SOCKS5 proxy which accepts one connection, records the CONNECT request
+// and replies with rep. If rep is 0, the connection is echoed back afterwards
+func runProxy(t *testing.T, method, rep byte) (string, <-chan []byte) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	reqC := make(chan []byte, 1)
+	go func() {
+		defer l.Close()
+
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		greeting := make([]byte, 3)
+		if _, err := io.ReadFull(conn, greeting); err != nil {
+			return
+		}
+
+		if _, err := conn.Write([]byte{socks5Version, method}); err != nil || method != authNone {
+			return
+		}
+
+		// VER, CMD, RSV, ATYP, then the address of the ATYP and the port
+		req := make([]byte, 4)
+		if _, err := io.ReadFull(conn, req); err != nil {
+			return
+		}
+
+		var addrLen int
+		switch req[3] {
+		case atypIPv4:
+			addrLen = net.IPv4len
+		case atypIPv6:
+			addrLen = net.IPv6len
+		case atypDomain:
+			n := make([]byte, 1)
+			if _, err := io.ReadFull(conn, n); err != nil {
+				return
+			}
+			req = append(req, n[0])
+			addrLen = int(n[0])
+		}
+
+		rest := make([]byte, addrLen+2)
+		if _, err := io.ReadFull(conn, rest); err != nil {
+			return
+		}
+		reqC <- append(req, rest...)
+
+		if _, err := conn.Write([]byte{socks5Version, rep, 0, atypIPv4, 127, 0, 0, 1, 0x1a, 0x0a}); err != nil || rep != 0 {
+			return
+		}
+
+		io.Copy(conn, conn) // nolint: errcheck
+	}()
+
+	return l.Addr().String(), reqC
+}
+
+func TestDialerDial(t *testing.T) {
+	onion := "expyuzz4wqqyqhjn.onion"
+
+	cases := []struct {
+		name   string
+		addr   string
+		method byte
+		rep    byte
+		req    []byte
+		err    string
+	}{
+		{
+			name: "ipv4",
+			addr: "1.2.3.4:6000",
+			req:  []byte{5, 1, 0, atypIPv4, 1, 2, 3, 4, 0x17, 0x70},
+		},
+		{
+			name: "ipv6",
+			addr: "[2001:db8::1]:6000",
+			req:  append(append([]byte{5, 1, 0, atypIPv6}, net.ParseIP("2001:db8::1")...), 0x17, 0x70),
+		},
+		{
+			name: "hostname is resolved by the proxy",
+			addr: onion + ":6000",
+			req:  append(append([]byte{5, 1, 0, atypDomain, byte(len(onion))}, onion...), 0x17, 0x70),
+		},
+		{
+			name: "connection refused",
+			addr: "1.2.3.4:6000",
+			rep:  5,
+			req:  []byte{5, 1, 0, atypIPv4, 1, 2, 3, 4, 0x17, 0x70},
+			err:  "SOCKS5 proxy failed to connect to 1.2.3.4:6000: connection refused",
+		},
+		{
+			name: "unknown reply error",
+			addr: "1.2.3.4:6000",
+			rep:  0x42,
+			req:  []byte{5, 1, 0, atypIPv4, 1, 2, 3, 4, 0x17, 0x70},
+			err:  "SOCKS5 proxy failed to connect to 1.2.3.4:6000: unknown error 66",
+		},
+		{
+			name:   "auth required",
+			addr:   "1.2.3.4:6000",
+			method: authNoAcceptable,
+			err:    ErrAuthRequired.Error(),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			proxyAddr, reqC := runProxy(t, tc.method, tc.rep)
+
+			d := NewDialer(proxyAddr, time.Second*5)
+			conn, err := d.Dial("tcp", tc.addr)
+
+			if tc.req != nil {
+				require.Equal(t, tc.req, <-reqC)
+			}
+
+			if tc.err != "" {
+				require.Error(t, err)
+				require.Equal(t, tc.err, err.Error())
+				return
+			}
+
+			require.NoError(t, err)
+			defer conn.Close()
+
+			require.Equal(t, tc.addr, conn.RemoteAddr().String())
+			require.Equal(t, "tcp", conn.RemoteAddr().Network())
+
+			// The connection is usable after the handshake
+			_, err = conn.Write([]byte("ping"))
+			require.NoError(t, err)
+			buf := make([]byte, 4)
+			_, err = io.ReadFull(conn, buf)
+			require.NoError(t, err)
+			require.Equal(t, "ping", string(buf))
+		})
+	}
+}
+
+func TestDialerDialInvalid(t *testing.T) {
+	d := NewDialer("127.0.0.1:1", time.Second)
+
+	_, err := d.Dial("udp", "1.2.3.4:6000")
+	require.Equal(t, ErrUnsupportedNetwork, err)
+
+	_, err = d.Dial("tcp", "1.2.3.4")
+	require.Error(t, err)
+
+	_, err = d.Dial("tcp", "1.2.3.4:70000")
+	require.Error(t, err)
+}
@@ -0,0 +1,271 @@
+/*
+Package websocket implements the server side of the websocket protocol (RFC 6455),
+for pushing text messages to clients.
+
+Messages sent by clients are discarded, except for the control frames: pings are answered,
+and a close frame closes the connection.
+*/
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1" // nolint: gosec
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// acceptGUID is appended to the key of the client to compute the Sec-WebSocket-Accept header
+	acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	// writeTimeout is the timeout for writing a frame
+	writeTimeout = time.Second * 10
+	// maxReadPayload is the maximum size of the frames sent by clients
+	maxReadPayload = 4096
+
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+var (
+	// ErrNotWebsocket is returned by Upgrade if the request is not a websocket handshake
+	ErrNotWebsocket = errors.New("Not a websocket handshake request")
+	// ErrClosed is returned when writing to a closed connection
+	ErrClosed = errors.New("Websocket connection closed")
+	// errInvalidFrame is returned when a client sends a malformed frame
+	errInvalidFrame = errors.New("Invalid websocket frame")
+)
+
+// Conn is a websocket connection upgraded from an HTTP request
+type Conn struct {
+	conn      net.Conn
+	r         *bufio.Reader
+	writeLock sync.Mutex
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// Upgrade completes the websocket handshake of an HTTP request, and takes over its connection.
+// ErrNotWebsocket is returned if the request is not a websocket handshake, in which case nothing
+// was written to w
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != http.MethodGet ||
+		!headerHasToken(r.Header, "Connection", "upgrade") ||
+		!headerHasToken(r.Header, "Upgrade", "websocket") ||
+		r.Header.Get("Sec-WebSocket-Version") != "13" ||
+		key == "" {
+		return nil, ErrNotWebsocket
+	}
+
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, errors.New("http.ResponseWriter does not support hijacking")
+	}
+
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	// Clear the deadlines set by the HTTP server for the request
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if err := conn.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if _, err := fmt.Fprintf(conn, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", acceptKey(key)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	c := &Conn{
+		conn: conn,
+		r:    rw.Reader,
+		done: make(chan struct{}),
+	}
+
+	go c.readLoop()
+
+	return c, nil
+}
+
+// headerHasToken returns true if a comma separated header contains token, case insensitively
+func headerHasToken(h http.Header, name, token string) bool {
+	for _, v := range h[http.CanonicalHeaderKey(name)] {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// acceptKey returns the Sec-WebSocket-Accept header for the Sec-WebSocket-Key of a client
+func acceptKey(key string) string {
+	h := sha1.Sum([]byte(key + acceptGUID)) // nolint: gosec
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// WriteText sends a text message
+func (c *Conn) WriteText(data []byte) error {
+	return c.writeFrame(opText, data)
+}
+
+// WriteJSON sends v encoded as JSON in a text message
+func (c *Conn) WriteJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return c.WriteText(data)
+}
+
+// writeFrame writes a single unmasked frame
+func (c *Conn) writeFrame(op byte, payload []byte) error {
+	select {
+	case <-c.done:
+		return ErrClosed
+	default:
+	}
+
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	header := make([]byte, 2, 10)
+	header[0] = 0x80 | op
+	switch n := len(payload); {
+	case n < 126:
+		header[1] = byte(n)
+	case n <= 0xFFFF:
+		header[1] = 126
+		header = header[:4]
+		binary.BigEndian.PutUint16(header[2:], uint16(n))
+	default:
+		header[1] = 127
+		header = header[:10]
+		binary.BigEndian.PutUint64(header[2:], uint64(n))
+	}
+
+	if err := c.conn.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+		return err
+	}
+
+	if _, err := c.conn.Write(append(header, payload...)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Done returns a channel that is closed when the connection is closed, by either side
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+// Close sends a close frame and closes the connection
+func (c *Conn) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		if werr := c.writeFrame(opClose, nil); werr != nil {
+			err = werr
+		}
+		close(c.done)
+		if cerr := c.conn.Close(); cerr != nil {
+			err = cerr
+		}
+	})
+	return err
+}
+
+// readLoop reads the frames sent by the client until the connection is closed
+func (c *Conn) readLoop() {
+	defer c.Close() // nolint: errcheck
+
+	for {
+		op, payload, err := c.readFrame()
+		if err != nil {
+			return
+		}
+
+		switch op {
+		case opClose:
+			return
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return
+			}
+		case opPong, opText, opBinary, opContinuation:
+		default:
+			return
+		}
+	}
+}
+
+// readFrame reads a frame sent by the client. Client frames must be masked
+func (c *Conn) readFrame() (byte, []byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.r, header[:]); err != nil {
+		return 0, nil, err
+	}
+
+	op := header[0] & 0x0F
+	if header[1]&0x80 == 0 {
+		return 0, nil, errInvalidFrame
+	}
+
+	n := uint64(header[1] & 0x7F)
+	switch n {
+	case 126:
+		var b [2]byte
+		if _, err := io.ReadFull(c.r, b[:]); err != nil {
+			return 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		if _, err := io.ReadFull(c.r, b[:]); err != nil {
+			return 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(b[:])
+	}
+
+	if n > maxReadPayload {
+		return 0, nil, errInvalidFrame
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.r, mask[:]); err != nil {
+		return 0, nil, err
+	}
+
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(c.r, payload); err != nil {
+		return 0, nil, err
+	}
+
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+
+	return op, payload, nil
+}
@@ -0,0 +1,126 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcceptKey(t *testing.T) {
+	// Example from RFC 6455
+	require.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", acceptKey("dGhlIHNhbXBsZSBub25jZQ=="))
+}
+
+// readTestFrame reads an unmasked frame sent by the server
+func readTestFrame(t *testing.T, r io.Reader) (byte, []byte) {
+	var header [2]byte
+	_, err := io.ReadFull(r, header[:])
+	require.NoError(t, err)
+	require.Equal(t, byte(0x80), header[0]&0x80)
+	require.Equal(t, byte(0), header[1]&0x80)
+
+	n := uint64(header[1])
+	switch n {
+	case 126:
+		var b [2]byte
+		_, err := io.ReadFull(r, b[:])
+		require.NoError(t, err)
+		n = uint64(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		_, err := io.ReadFull(r, b[:])
+		require.NoError(t, err)
+		n = binary.BigEndian.Uint64(b[:])
+	}
+
+	payload := make([]byte, n)
+	_, err = io.ReadFull(r, payload)
+	require.NoError(t, err)
+	return header[0] & 0x0F, payload
+}
+
+// writeTestFrame writes a masked frame, as a client does
+func writeTestFrame(t *testing.T, w io.Writer, op byte, payload []byte) {
+	require.True(t, len(payload) < 126)
+	mask := []byte{1, 2, 3, 4}
+	b := append([]byte{0x80 | op, 0x80 | byte(len(payload))}, mask...)
+	for i, c := range payload {
+		b = append(b, c^mask[i%4])
+	}
+	_, err := w.Write(b)
+	require.NoError(t, err)
+}
+
+func TestUpgrade(t *testing.T) {
+	conns := make(chan *Conn, 1)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := Upgrade(w, r)
+		if err == ErrNotWebsocket {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		require.NoError(t, err)
+		conns <- c
+	}))
+	defer s.Close()
+
+	// A plain HTTP request is not upgraded
+	resp, err := http.Get(s.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(s.URL, "http://"))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\nConnection: keep-alive, Upgrade\r\nUpgrade: websocket\r\n" +
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n"))
+	require.NoError(t, err)
+
+	r := bufio.NewReader(conn)
+	resp, err = http.ReadResponse(r, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	require.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", resp.Header.Get("Sec-WebSocket-Accept"))
+
+	c := <-conns
+
+	// Text messages of any size are sent in a single frame
+	require.NoError(t, c.WriteText([]byte("hello")))
+	op, payload := readTestFrame(t, r)
+	require.Equal(t, byte(opText), op)
+	require.Equal(t, []byte("hello"), payload)
+
+	require.NoError(t, c.WriteJSON(map[string]string{"data": strings.Repeat("a", 1000)}))
+	op, payload = readTestFrame(t, r)
+	require.Equal(t, byte(opText), op)
+	require.Equal(t, `{"data":"`+strings.Repeat("a", 1000)+`"}`, string(payload))
+
+	// Pings are answered
+	writeTestFrame(t, conn, opPing, []byte("ping"))
+	op, payload = readTestFrame(t, r)
+	require.Equal(t, byte(opPong), op)
+	require.Equal(t, []byte("ping"), payload)
+
+	// Closing the connection from the client closes it on the server
+	writeTestFrame(t, conn, opClose, nil)
+	select {
+	case <-c.Done():
+	case <-time.After(time.Second * 5):
+		t.Fatal("connection was not closed")
+	}
+
+	op, _ = readTestFrame(t, r)
+	require.Equal(t, byte(opClose), op)
+	require.Equal(t, ErrClosed, c.WriteText([]byte("closed")))
+	require.NoError(t, c.Close())
+}
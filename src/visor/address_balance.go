@@ -0,0 +1,72 @@
+package visor
+
+import (
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/historydb"
+)
+
+// AddressBalanceHistory is the coin balance history of an address over a range of blocks
+type AddressBalanceHistory struct {
+	Address  cipher.Address
+	StartSeq uint64
+	EndSeq   uint64
+	// StartBalance is the balance before the block of StartSeq, in droplets
+	StartBalance uint64
+	// EndBalance is the balance after the block of EndSeq, in droplets
+	EndBalance uint64
+	// Deltas are the balance changes in the blocks that the address received or spent outputs in
+	Deltas []historydb.AddressBalanceDelta
+}
+
+// GetAddressBalanceHistory returns the balance history of an address in the blocks of the seq range [start, end].
+// The range is capped at the head block. Returns ErrBlockNotExist if start is after the head block.
+// The balances of a database bootstrapped from an unspent output set snapshot are not known
+// between the genesis block and the snapshot block.
+func (vs *Visor) GetAddressBalanceHistory(addr cipher.Address, start, end uint64) (*AddressBalanceHistory, error) {
+	var h *AddressBalanceHistory
+
+	if err := vs.viewReplica("GetAddressBalanceHistory", func(tx *dbutil.Tx) error {
+		headSeq, ok, err := vs.Blockchain.HeadSeq(tx)
+		if err != nil {
+			return err
+		} else if !ok || start > headSeq {
+			return NewErrBlockNotExist(start)
+		}
+
+		if end > headSeq {
+			end = headSeq
+		}
+
+		h = &AddressBalanceHistory{
+			Address:  addr,
+			StartSeq: start,
+			EndSeq:   end,
+		}
+
+		if start > 0 {
+			d, err := vs.history.GetAddressBalanceAt(tx, addr, start-1)
+			if err != nil {
+				return err
+			}
+			if d != nil {
+				h.StartBalance = d.Balance
+			}
+		}
+
+		d, err := vs.history.GetAddressBalanceAt(tx, addr, end)
+		if err != nil {
+			return err
+		}
+		if d != nil {
+			h.EndBalance = d.Balance
+		}
+
+		h.Deltas, err = vs.history.GetAddressBalanceDeltas(tx, addr, start, end)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
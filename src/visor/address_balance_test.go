@@ -0,0 +1,129 @@
+package visor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/historydb"
+)
+
+func TestVisorGetAddressBalanceHistory(t *testing.T) {
+	addr := testutil.MakeAddress()
+
+	deltas := []historydb.AddressBalanceDelta{
+		{
+			BlockSeq: 3,
+			Received: 10e6,
+			Balance:  15e6,
+		},
+		{
+			BlockSeq: 5,
+			Sent:     2e6,
+			Balance:  13e6,
+		},
+	}
+
+	cases := []struct {
+		name       string
+		headSeq    uint64
+		hasHead    bool
+		start      uint64
+		end        uint64
+		rangeEnd   uint64
+		startDelta *historydb.AddressBalanceDelta
+		endDelta   *historydb.AddressBalanceDelta
+		deltas     []historydb.AddressBalanceDelta
+		expected   *AddressBalanceHistory
+		err        error
+	}{
+		{
+			name:    "no blocks",
+			hasHead: false,
+			err:     NewErrBlockNotExist(0),
+		},
+		{
+			name:    "start after head",
+			headSeq: 10,
+			hasHead: true,
+			start:   11,
+			end:     20,
+			err:     NewErrBlockNotExist(11),
+		},
+		{
+			name:     "address not used",
+			headSeq:  10,
+			hasHead:  true,
+			start:    0,
+			end:      10,
+			rangeEnd: 10,
+			expected: &AddressBalanceHistory{
+				Address:  addr,
+				StartSeq: 0,
+				EndSeq:   10,
+			},
+		},
+		{
+			name:       "end capped at head",
+			headSeq:    10,
+			hasHead:    true,
+			start:      2,
+			end:        20,
+			rangeEnd:   10,
+			startDelta: &historydb.AddressBalanceDelta{BlockSeq: 1, Balance: 5e6},
+			endDelta:   &deltas[1],
+			deltas:     deltas,
+			expected: &AddressBalanceHistory{
+				Address:      addr,
+				StartSeq:     2,
+				EndSeq:       10,
+				StartBalance: 5e6,
+				EndBalance:   13e6,
+				Deltas:       deltas,
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, shutdown := prepareDB(t)
+			defer shutdown()
+
+			matchTxn := mock.MatchedBy(func(tx *dbutil.Tx) bool {
+				return true
+			})
+
+			bc := &MockBlockchainer{}
+			bc.On("HeadSeq", matchTxn).Return(tc.headSeq, tc.hasHead, nil)
+
+			history := &MockHistoryer{}
+			if tc.err == nil {
+				if tc.start > 0 {
+					history.On("GetAddressBalanceAt", matchTxn, addr, tc.start-1).Return(tc.startDelta, nil)
+				}
+				history.On("GetAddressBalanceAt", matchTxn, addr, tc.rangeEnd).Return(tc.endDelta, nil)
+				history.On("GetAddressBalanceDeltas", matchTxn, addr, tc.start, tc.rangeEnd).Return(tc.deltas, nil)
+			}
+
+			v := &Visor{
+				DB:         db,
+				Blockchain: bc,
+				history:    history,
+			}
+
+			h, err := v.GetAddressBalanceHistory(addr, tc.start, tc.end)
+			if tc.err != nil {
+				require.Equal(t, tc.err, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, h)
+			bc.AssertExpectations(t)
+			history.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package visor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/historydb"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+const (
+	// MaxScanAddresses is the maximum number of addresses that ScanAddresses scans at once
+	MaxScanAddresses = 10000
+	// MaxScanRecentTxns is the maximum number of recent transactions that ScanAddresses returns per address
+	MaxScanRecentTxns = 100
+)
+
+var (
+	// ErrScanTooManyAddresses is returned by ScanAddresses if more than MaxScanAddresses addresses are scanned
+	ErrScanTooManyAddresses = fmt.Errorf("Can't scan more than %d addresses at once", MaxScanAddresses)
+	// ErrScanTooManyRecentTxns is returned by ScanAddresses if more than MaxScanRecentTxns recent transactions are requested
+	ErrScanTooManyRecentTxns = fmt.Errorf("Can't return more than %d recent transactions per address", MaxScanRecentTxns)
+	// ErrScanNoAddresses is returned by ScanAddresses if no addresses are scanned
+	ErrScanNoAddresses = errors.New("No addresses to scan")
+)
+
+// AddressScan is the confirmed state of an address: its balance, unspent outputs and most recent transactions
+type AddressScan struct {
+	Address cipher.Address
+	Balance wallet.Balance
+	// Outputs are the unspent outputs of the address, with their coin hours at the head block time
+	Outputs []UnspentOutput
+	// RecentTransactions are the most recent transactions of the address, most recent first
+	RecentTransactions []historydb.Transaction
+}
+
+// ScanAddresses returns the confirmed balances, unspent outputs and numTxns most recent transactions of up to
+// MaxScanAddresses addresses, read in a single database transaction so that all of them are from the same head block.
+// The addresses are looked up in key order, which walks each bucket once instead of seeking at random,
+// so a wallet sweep doesn't need a request per address. The scans are returned in the order of addrs.
+func (vs *Visor) ScanAddresses(addrs []cipher.Address, numTxns uint64) ([]AddressScan, error) {
+	if len(addrs) == 0 {
+		return nil, ErrScanNoAddresses
+	}
+
+	if len(addrs) > MaxScanAddresses {
+		return nil, ErrScanTooManyAddresses
+	}
+
+	if numTxns > MaxScanRecentTxns {
+		return nil, ErrScanTooManyRecentTxns
+	}
+
+	sorted := make([]cipher.Address, 0, len(addrs))
+	seen := make(map[cipher.Address]struct{}, len(addrs))
+	for _, addr := range addrs {
+		if _, ok := seen[addr]; ok {
+			continue
+		}
+		seen[addr] = struct{}{}
+		sorted = append(sorted, addr)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i].Bytes(), sorted[j].Bytes()) < 0
+	})
+
+	var head *coin.SignedBlock
+	var auxs coin.AddressUxOuts
+	var addrTxns map[cipher.Address][]historydb.Transaction
+
+	if err := vs.DB.View("ScanAddresses", func(tx *dbutil.Tx) error {
+		var err error
+		head, err = vs.Blockchain.Head(tx)
+		if err != nil {
+			return err
+		}
+
+		auxs, err = vs.Blockchain.Unspent().GetUnspentsOfAddrs(tx, sorted)
+		if err != nil {
+			return fmt.Errorf("GetUnspentsOfAddrs failed: %v", err)
+		}
+
+		addrTxns, err = vs.history.GetRecentTransactionsForAddrs(tx, sorted, numTxns)
+		if err != nil {
+			return fmt.Errorf("GetRecentTransactionsForAddrs failed: %v", err)
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	headTime := head.Time()
+	scans := make([]AddressScan, len(addrs))
+	for i, addr := range addrs {
+		uxs := auxs[addr]
+
+		coins, err := uxs.Coins()
+		if err != nil {
+			return nil, fmt.Errorf("uxs.Coins failed: %v", err)
+		}
+
+		hours, err := uxs.CoinHours(headTime)
+		if err != nil {
+			switch err {
+			case coin.ErrAddEarnedCoinHoursAdditionOverflow:
+				hours = 0
+			default:
+				return nil, fmt.Errorf("uxs.CoinHours failed: %v", err)
+			}
+		}
+
+		outputs, err := NewUnspentOutputs(uxs, headTime)
+		if err != nil {
+			return nil, err
+		}
+
+		scans[i] = AddressScan{
+			Address: addr,
+			Balance: wallet.Balance{
+				Coins: coins,
+				Hours: hours,
+			},
+			Outputs:            outputs,
+			RecentTransactions: addrTxns[addr],
+		}
+	}
+
+	return scans, nil
+}
@@ -0,0 +1,148 @@
+package visor
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/historydb"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+func TestVisorScanAddresses(t *testing.T) {
+	addrA := testutil.MakeAddress()
+	addrB := testutil.MakeAddress()
+	if bytes.Compare(addrA.Bytes(), addrB.Bytes()) > 0 {
+		addrA, addrB = addrB, addrA
+	}
+
+	head := coin.SignedBlock{
+		Block: coin.Block{
+			Head: coin.BlockHeader{
+				BkSeq: 10,
+				Time:  3600 * 1000,
+			},
+		},
+	}
+
+	uxsA := coin.UxArray{
+		{
+			Head: coin.UxHead{Time: 3600 * 1000},
+			Body: coin.UxBody{Address: addrA, Coins: 10e6, Hours: 5},
+		},
+		{
+			Head: coin.UxHead{Time: 0},
+			Body: coin.UxBody{Address: addrA, Coins: 2e6, Hours: 1},
+		},
+	}
+	hoursA, err := uxsA.CoinHours(head.Time())
+	require.NoError(t, err)
+	outputsA, err := NewUnspentOutputs(uxsA, head.Time())
+	require.NoError(t, err)
+
+	txnsA := []historydb.Transaction{
+		{BlockSeq: 9},
+		{BlockSeq: 3},
+	}
+
+	cases := []struct {
+		name     string
+		addrs    []cipher.Address
+		numTxns  uint64
+		sorted   []cipher.Address
+		expected []AddressScan
+		err      error
+	}{
+		{
+			name: "no addresses",
+			err:  ErrScanNoAddresses,
+		},
+		{
+			name:    "too many addresses",
+			addrs:   make([]cipher.Address, MaxScanAddresses+1),
+			numTxns: 5,
+			err:     ErrScanTooManyAddresses,
+		},
+		{
+			name:    "too many recent transactions",
+			addrs:   []cipher.Address{addrA},
+			numTxns: MaxScanRecentTxns + 1,
+			err:     ErrScanTooManyRecentTxns,
+		},
+		{
+			name:    "addresses are looked up sorted and unique, returned in request order",
+			addrs:   []cipher.Address{addrB, addrA, addrB},
+			numTxns: 5,
+			sorted:  []cipher.Address{addrA, addrB},
+			expected: []AddressScan{
+				{
+					Address: addrB,
+					Outputs: []UnspentOutput{},
+				},
+				{
+					Address: addrA,
+					Balance: wallet.Balance{
+						Coins: 12e6,
+						Hours: hoursA,
+					},
+					Outputs:            outputsA,
+					RecentTransactions: txnsA,
+				},
+				{
+					Address: addrB,
+					Outputs: []UnspentOutput{},
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, shutdown := prepareDB(t)
+			defer shutdown()
+
+			matchTxn := mock.MatchedBy(func(tx *dbutil.Tx) bool {
+				return true
+			})
+
+			bc := &MockBlockchainer{}
+			unspent := &MockUnspentPooler{}
+			history := &MockHistoryer{}
+
+			if tc.err == nil {
+				bc.On("Head", matchTxn).Return(&head, nil)
+				bc.On("Unspent").Return(unspent)
+				unspent.On("GetUnspentsOfAddrs", matchTxn, tc.sorted).Return(coin.AddressUxOuts{
+					addrA: uxsA,
+				}, nil)
+				history.On("GetRecentTransactionsForAddrs", matchTxn, tc.sorted, tc.numTxns).Return(map[cipher.Address][]historydb.Transaction{
+					addrA: txnsA,
+				}, nil)
+			}
+
+			v := &Visor{
+				DB:         db,
+				Blockchain: bc,
+				history:    history,
+			}
+
+			scans, err := v.ScanAddresses(tc.addrs, tc.numTxns)
+			if tc.err != nil {
+				require.Equal(t, tc.err, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, scans)
+			bc.AssertExpectations(t)
+			unspent.AssertExpectations(t)
+			history.AssertExpectations(t)
+		})
+	}
+}
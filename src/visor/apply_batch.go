@@ -0,0 +1,99 @@
+package visor
+
+import (
+	"time"
+
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+const (
+	// DefaultBlockApplyBatchSize is the default maximum number of received blocks applied in one db transaction
+	DefaultBlockApplyBatchSize = 20
+	// DefaultBlockApplyBatchTime is the default time after which a batch of received blocks is committed
+	DefaultBlockApplyBatchTime = time.Second
+)
+
+// ExecuteSignedBlocks adds a sequence of blocks to the blockchain, applying up to Config.BlockApplyBatchSize blocks,
+// or the blocks applied within Config.BlockApplyBatchTime, in each db transaction.
+// Committing each block in its own transaction is bound by the fsync of the db file during the initial sync,
+// so batching the blocks speeds up the sync, at the cost of redoing at most one batch if the node stops.
+// A batch is journaled as a whole, and is recovered on startup like the application of a single block.
+// Returns the number of blocks added. If a block is invalid, the blocks before it are added and its error is returned.
+// Blocks must be executed in sequence, and be signed by a block publisher node.
+func (vs *Visor) ExecuteSignedBlocks(blocks []coin.SignedBlock) (int, error) {
+	size := vs.Config.BlockApplyBatchSize
+	if size < 1 {
+		size = 1
+	}
+
+	var n int
+	for n < len(blocks) {
+		batch := blocks[n:]
+		if len(batch) > size {
+			batch = batch[:size]
+		}
+
+		applied, rolledBack, err := vs.executeSignedBlockBatch(batch, vs.Config.BlockApplyBatchTime)
+		if rolledBack {
+			// Apply the valid blocks before the invalid block again
+			if applied > 0 {
+				if _, _, err := vs.executeSignedBlockBatch(batch[:applied], 0); err != nil {
+					return n, err
+				}
+				n += applied
+			}
+			return n, err
+		} else if err != nil {
+			return n, err
+		}
+
+		n += applied
+	}
+
+	return n, nil
+}
+
+// executeSignedBlockBatch applies blocks in one db transaction, stopping after maxTime if maxTime is not 0.
+// At least one block is applied. Returns the number of blocks applied.
+// If a block is invalid the transaction is rolled back, and the number of blocks before it is returned
+// with its error and rolledBack set. Other errors, such as a failed commit, are returned with rolledBack unset.
+func (vs *Visor) executeSignedBlockBatch(blocks []coin.SignedBlock, maxTime time.Duration) (applied int, rolledBack bool, err error) {
+	start := time.Now()
+
+	var blockErr error
+	if err := vs.DB.Update("ExecuteSignedBlocks", func(tx *dbutil.Tx) error {
+		applied = 0
+		blockErr = nil
+
+		if len(blocks) > 1 {
+			if err := setApplyJournalFirstSeq(tx, blocks[0].Seq()); err != nil {
+				return err
+			}
+		}
+
+		for _, b := range blocks {
+			if applied > 0 && maxTime > 0 && time.Since(start) >= maxTime {
+				break
+			}
+
+			if err := vs.executeSignedBlock(tx, b); err != nil {
+				blockErr = err
+				return err
+			}
+
+			applied++
+		}
+
+		return nil
+	}); err != nil {
+		if blockErr != nil {
+			return applied, true, blockErr
+		}
+		return 0, false, err
+	}
+
+	vs.clearApplyJournal()
+
+	return applied, false, nil
+}
@@ -0,0 +1,185 @@
+package visor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/historydb"
+)
+
+func TestExecuteSignedBlocks(t *testing.T) {
+	newVisor := func(t *testing.T) (*Visor, coin.UxOut, func()) {
+		db, shutdown := prepareDB(t)
+
+		bc, err := NewBlockchain(db, BlockchainConfig{
+			Pubkey: genPublic,
+		})
+		require.NoError(t, err)
+
+		unconfirmed, err := NewUnconfirmedTransactionPool(db)
+		require.NoError(t, err)
+
+		cfg := NewConfig()
+		cfg.DBPath = db.Path()
+		cfg.IsBlockPublisher = true
+		cfg.BlockchainPubkey = genPublic
+		cfg.BlockchainSeckey = genSecret
+		cfg.GenesisAddress = genAddress
+
+		v := &Visor{
+			Config:      cfg,
+			Unconfirmed: unconfirmed,
+			Blockchain:  bc,
+			DB:          db,
+			history:     historydb.New(),
+			watchList:   newWatchList(),
+		}
+
+		gb := addGenesisBlockToVisor(t, v)
+		v.clearApplyJournal()
+		return v, coin.CreateUnspents(gb.Head, gb.Body.Transactions[0])[0], shutdown
+	}
+
+	// Create a chain of blocks with a publisher visor, each block spending the main output of the previous block
+	publisher, genUx, shutdown := newVisor(t)
+	defer shutdown()
+
+	var blocks []coin.SignedBlock
+	var txns []coin.Transaction
+	ux, seckey := genUx, genSecret
+	for i := 0; i < 5; i++ {
+		pubkey, sk := cipher.GenerateKeyPair()
+		txn := makeSpendTxWithFee(t, coin.UxArray{ux}, []cipher.SecKey{seckey}, cipher.AddressFromPubKey(pubkey), 100e6, 0)
+		_, _, err := publisher.InjectForeignTransaction(txn)
+		require.NoError(t, err)
+
+		var sb coin.SignedBlock
+		err = publisher.DB.Update("", func(tx *dbutil.Tx) error {
+			head, err := publisher.Blockchain.Head(tx)
+			require.NoError(t, err)
+
+			sb, err = publisher.createBlock(tx, head.Time()+3600)
+			require.NoError(t, err)
+
+			return publisher.executeSignedBlock(tx, sb)
+		})
+		require.NoError(t, err)
+		publisher.clearApplyJournal()
+
+		blocks = append(blocks, sb)
+		txns = append(txns, txn)
+		ux, seckey = coin.CreateUnspents(sb.Head, txn)[0], sk
+	}
+
+	invalid := blocks[2]
+	invalid.Sig = cipher.Sig{}
+
+	cases := []struct {
+		name      string
+		batchSize int
+		batchTime time.Duration
+		blocks    []coin.SignedBlock
+		applied   int
+		err       bool
+	}{
+		{
+			name:      "one block per transaction",
+			batchSize: 0,
+			blocks:    blocks,
+			applied:   5,
+		},
+		{
+			name:      "batches of 2 blocks",
+			batchSize: 2,
+			blocks:    blocks,
+			applied:   5,
+		},
+		{
+			name:      "one batch",
+			batchSize: 10,
+			blocks:    blocks,
+			applied:   5,
+		},
+		{
+			name:      "batch time elapsed after each block",
+			batchSize: 10,
+			batchTime: time.Nanosecond,
+			blocks:    blocks,
+			applied:   5,
+		},
+		{
+			name:      "invalid block in the middle of a batch",
+			batchSize: 10,
+			blocks:    []coin.SignedBlock{blocks[0], blocks[1], invalid, blocks[3]},
+			applied:   2,
+			err:       true,
+		},
+		{
+			name:      "invalid block at the start of a batch",
+			batchSize: 2,
+			blocks:    []coin.SignedBlock{blocks[0], blocks[1], invalid, blocks[3]},
+			applied:   2,
+			err:       true,
+		},
+		{
+			name:      "invalid first block",
+			batchSize: 10,
+			blocks:    []coin.SignedBlock{blocks[1], blocks[2]},
+			applied:   0,
+			err:       true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			v, _, shutdown := newVisor(t)
+			defer shutdown()
+
+			v.Config.BlockApplyBatchSize = tc.batchSize
+			v.Config.BlockApplyBatchTime = tc.batchTime
+
+			// The transactions of the applied blocks are removed from the unconfirmed pool
+			_, _, err := v.InjectForeignTransaction(txns[0])
+			require.NoError(t, err)
+
+			n, err := v.ExecuteSignedBlocks(tc.blocks)
+			if tc.err {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tc.applied, n)
+
+			err = v.DB.View("", func(tx *dbutil.Tx) error {
+				headSeq, ok, err := v.Blockchain.HeadSeq(tx)
+				require.NoError(t, err)
+				require.True(t, ok)
+				require.Equal(t, uint64(tc.applied), headSeq)
+
+				parsedSeq, ok, err := v.history.ParsedBlockSeq(tx)
+				require.NoError(t, err)
+				require.True(t, ok)
+				require.Equal(t, headSeq, parsedSeq)
+
+				entry, err := getApplyJournalEntry(tx)
+				require.NoError(t, err)
+				require.Nil(t, entry)
+
+				_, ok, err = getApplyJournalFirstSeq(tx)
+				require.NoError(t, err)
+				require.False(t, ok)
+
+				txn, err := v.Unconfirmed.Get(tx, txns[0].Hash())
+				require.NoError(t, err)
+				require.Equal(t, tc.applied == 0, txn != nil)
+				return nil
+			})
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package visor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/encoder"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/historydb"
+)
+
+var (
+	// ApplyJournalBkt records the block being applied, until it is applied to all the buckets it touches
+	ApplyJournalBkt = []byte("apply_journal")
+
+	applyJournalKey = []byte("entry")
+	// applyJournalFirstSeqKey records the seq of the first block of a batch of blocks applied in one transaction
+	applyJournalFirstSeqKey = []byte("first_seq")
+)
+
+// ApplyJournalEntry records a block being applied to the database.
+// The entry is written in the transaction that applies the block, and removed once that
+// transaction is committed. An entry found on startup means the node stopped in between,
+// and the database is recovered by recoverBlockApplication.
+type ApplyJournalEntry struct {
+	Seq  uint64
+	Hash cipher.SHA256
+}
+
+// getApplyJournalEntry returns the journaled block application, or nil if there is none
+func getApplyJournalEntry(tx *dbutil.Tx) (*ApplyJournalEntry, error) {
+	var entry ApplyJournalEntry
+	ok, err := dbutil.GetBucketObjectDecoded(tx, ApplyJournalBkt, applyJournalKey, &entry)
+	if err != nil {
+		switch err.(type) {
+		case dbutil.ErrBucketNotExist:
+			return nil, nil
+		default:
+			return nil, err
+		}
+	} else if !ok {
+		return nil, nil
+	}
+
+	return &entry, nil
+}
+
+// setApplyJournalEntry journals the application of a block
+func setApplyJournalEntry(tx *dbutil.Tx, entry ApplyJournalEntry) error {
+	if _, err := tx.CreateBucketIfNotExists(ApplyJournalBkt); err != nil {
+		return dbutil.NewErrCreateBucketFailed(ApplyJournalBkt, err)
+	}
+
+	return dbutil.PutBucketValue(tx, ApplyJournalBkt, applyJournalKey, encoder.Serialize(entry))
+}
+
+// getApplyJournalFirstSeq returns the seq of the first block of the journaled batch,
+// or false if a single block is journaled
+func getApplyJournalFirstSeq(tx *dbutil.Tx) (uint64, bool, error) {
+	if !dbutil.Exists(tx, ApplyJournalBkt) {
+		return 0, false, nil
+	}
+
+	v, err := dbutil.GetBucketValue(tx, ApplyJournalBkt, applyJournalFirstSeqKey)
+	if err != nil || v == nil {
+		return 0, false, err
+	}
+
+	return dbutil.Btoi(v), true, nil
+}
+
+// setApplyJournalFirstSeq journals the seq of the first block of a batch of blocks applied in one transaction.
+// The journal entry of each block of the batch replaces the previous one, so that the entry is the last block of the batch.
+func setApplyJournalFirstSeq(tx *dbutil.Tx, seq uint64) error {
+	if _, err := tx.CreateBucketIfNotExists(ApplyJournalBkt); err != nil {
+		return dbutil.NewErrCreateBucketFailed(ApplyJournalBkt, err)
+	}
+
+	return dbutil.PutBucketValue(tx, ApplyJournalBkt, applyJournalFirstSeqKey, dbutil.Itob(seq))
+}
+
+// deleteApplyJournalEntry removes the journaled block application
+func deleteApplyJournalEntry(tx *dbutil.Tx) error {
+	if !dbutil.Exists(tx, ApplyJournalBkt) {
+		return nil
+	}
+
+	if err := dbutil.Delete(tx, ApplyJournalBkt, applyJournalFirstSeqKey); err != nil {
+		return err
+	}
+
+	return dbutil.Delete(tx, ApplyJournalBkt, applyJournalKey)
+}
+
+// clearApplyJournal removes the journaled block application once the block is committed.
+// A failure is logged, the entry is then handled by recoverBlockApplication on the next startup.
+func (vs *Visor) clearApplyJournal() {
+	if err := vs.DB.Update("clearApplyJournal", deleteApplyJournalEntry); err != nil {
+		logger.WithError(err).Error("clearApplyJournal failed")
+	}
+}
+
+// recoverBlockApplication recovers the application of the journaled block, if the node stopped while applying it.
+// The apply journal is stored with the blocks, so the block was applied to the blockchain if the
+// journaled block is in the chain, and the history db and unconfirmed pool are rolled forward to include it.
+// Otherwise the block was not applied, and the history db is rolled back to the blockchain head.
+// Either way, the recovery depends only on the database content, and the journal entry is removed.
+// A batch of blocks applied in one transaction is journaled as its last block and the seq of its first block,
+// and is recovered as a whole, since the blocks of the batch are committed together.
+func recoverBlockApplication(tx *dbutil.Tx, bc *Blockchain, history *historydb.HistoryDB, unconfirmed *UnconfirmedTransactionPool) error {
+	entry, err := getApplyJournalEntry(tx)
+	if err != nil {
+		return err
+	} else if entry == nil {
+		return nil
+	}
+
+	firstSeq, isBatch, err := getApplyJournalFirstSeq(tx)
+	if err != nil {
+		return err
+	}
+	if !isBatch || firstSeq > entry.Seq {
+		firstSeq = entry.Seq
+	}
+
+	blocks := fmt.Sprintf("block %d", entry.Seq)
+	if firstSeq < entry.Seq {
+		blocks = fmt.Sprintf("blocks %d to %d", firstSeq, entry.Seq)
+	}
+
+	b, err := bc.GetSignedBlockBySeq(tx, entry.Seq)
+	if err != nil {
+		return err
+	}
+
+	if b != nil && b.HashHeader() == entry.Hash {
+		logger.Critical().Infof("The application of %s was not completed, rolling it forward", blocks)
+
+		if err := catchUpHistory(tx, bc, history); err != nil {
+			return err
+		}
+
+		for seq := firstSeq; seq <= entry.Seq; seq++ {
+			b, err := bc.GetSignedBlockBySeq(tx, seq)
+			if err != nil {
+				return err
+			}
+			if b == nil {
+				return fmt.Errorf("journaled block %d is not in the blockchain", seq)
+			}
+
+			txnHashes := make([]cipher.SHA256, 0, len(b.Body.Transactions))
+			for _, txn := range b.Body.Transactions {
+				txnHashes = append(txnHashes, txn.Hash())
+			}
+
+			if err := unconfirmed.RemoveTransactions(tx, txnHashes); err != nil {
+				return err
+			}
+		}
+
+		if err := addDBIntegrityAction(tx, "Rolled forward the interrupted application of %s", blocks); err != nil {
+			return err
+		}
+	} else {
+		logger.Critical().Infof("%s was not applied, rolling back its application", strings.Title(blocks))
+
+		if err := rollBackHistory(tx, bc, history); err != nil {
+			return err
+		}
+
+		if err := addDBIntegrityAction(tx, "Rolled back the interrupted application of %s", blocks); err != nil {
+			return err
+		}
+	}
+
+	return deleteApplyJournalEntry(tx)
+}
+
+// rollBackHistory rebuilds the history db if it has parsed blocks that are not in the blockchain
+func rollBackHistory(tx *dbutil.Tx, bc *Blockchain, history *historydb.HistoryDB) error {
+	parsedBlockSeq, ok, err := history.ParsedBlockSeq(tx)
+	if err != nil || !ok {
+		return err
+	}
+
+	headSeq, hasHead, err := bc.HeadSeq(tx)
+	if err != nil {
+		return err
+	}
+
+	if hasHead && parsedBlockSeq <= headSeq {
+		return nil
+	}
+
+	logger.Critical().Info("History db is ahead of the blockchain, rebuilding it")
+
+	if err := history.Erase(tx); err != nil {
+		return err
+	}
+
+	if !hasHead {
+		return nil
+	}
+
+	return parseHistoryTo(tx, history, bc, headSeq)
+}
@@ -0,0 +1,137 @@
+package visor
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/historydb"
+)
+
+func TestRecoverBlockApplication(t *testing.T) {
+	db, shutdown := copyTestDB(t, "./testdata/data.db.ok")
+	defer shutdown()
+
+	pubkey := mustParsePubkey(t)
+	bc, err := NewBlockchain(db, BlockchainConfig{Pubkey: pubkey})
+	require.NoError(t, err)
+
+	history := historydb.New()
+	utp, err := NewUnconfirmedTransactionPool(db)
+	require.NoError(t, err)
+
+	recoverAndCheck := func(action string) {
+		err := db.Update("", func(tx *dbutil.Tx) error {
+			return recoverBlockApplication(tx, bc, history, utp)
+		})
+		require.NoError(t, err)
+
+		err = db.View("", func(tx *dbutil.Tx) error {
+			entry, err := getApplyJournalEntry(tx)
+			require.NoError(t, err)
+			require.Nil(t, entry)
+
+			_, ok, err := getApplyJournalFirstSeq(tx)
+			require.NoError(t, err)
+			require.False(t, ok)
+			return nil
+		})
+		require.NoError(t, err)
+
+		report, err := GetDBIntegrityReport(db)
+		require.NoError(t, err)
+		if action == "" {
+			require.Nil(t, report)
+			return
+		}
+		require.Equal(t, action, report.Actions[len(report.Actions)-1].Description)
+
+		err = CheckDatabase(context.Background(), db, CheckDatabaseConfig{Pubkey: pubkey, FullVerify: true})
+		require.NoError(t, err)
+	}
+
+	// Nothing to recover without a journal entry
+	recoverAndCheck("")
+
+	// The history db missed the journaled head block, it is rolled forward
+	var headSeq uint64
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		head, err := bc.Head(tx)
+		require.NoError(t, err)
+		headSeq = head.Seq()
+
+		require.NoError(t, history.Erase(tx))
+		require.NoError(t, parseHistoryTo(tx, history, bc, headSeq-1))
+
+		return setApplyJournalEntry(tx, ApplyJournalEntry{
+			Seq:  headSeq,
+			Hash: head.HashHeader(),
+		})
+	})
+	require.NoError(t, err)
+
+	recoverAndCheck(fmt.Sprintf("Rolled forward the interrupted application of block %d", headSeq))
+
+	err = db.View("", func(tx *dbutil.Tx) error {
+		parsedSeq, ok, err := history.ParsedBlockSeq(tx)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, headSeq, parsedSeq)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// The history db missed the blocks of the journaled batch, they are rolled forward
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		head, err := bc.Head(tx)
+		require.NoError(t, err)
+
+		require.NoError(t, history.Erase(tx))
+		require.NoError(t, parseHistoryTo(tx, history, bc, headSeq-3))
+
+		require.NoError(t, setApplyJournalFirstSeq(tx, headSeq-2))
+		return setApplyJournalEntry(tx, ApplyJournalEntry{
+			Seq:  headSeq,
+			Hash: head.HashHeader(),
+		})
+	})
+	require.NoError(t, err)
+
+	recoverAndCheck(fmt.Sprintf("Rolled forward the interrupted application of blocks %d to %d", headSeq-2, headSeq))
+
+	err = db.View("", func(tx *dbutil.Tx) error {
+		parsedSeq, ok, err := history.ParsedBlockSeq(tx)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, headSeq, parsedSeq)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// The journaled block is not in the blockchain, its application is rolled back
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		return setApplyJournalEntry(tx, ApplyJournalEntry{
+			Seq:  headSeq + 1,
+			Hash: testutil.RandSHA256(t),
+		})
+	})
+	require.NoError(t, err)
+
+	recoverAndCheck(fmt.Sprintf("Rolled back the interrupted application of block %d", headSeq+1))
+
+	// The journaled batch is not in the blockchain, its application is rolled back
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		require.NoError(t, setApplyJournalFirstSeq(tx, headSeq+1))
+		return setApplyJournalEntry(tx, ApplyJournalEntry{
+			Seq:  headSeq + 3,
+			Hash: testutil.RandSHA256(t),
+		})
+	})
+	require.NoError(t, err)
+
+	recoverAndCheck(fmt.Sprintf("Rolled back the interrupted application of blocks %d to %d", headSeq+1, headSeq+3))
+}
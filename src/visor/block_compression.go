@@ -0,0 +1,42 @@
+package visor
+
+import (
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+// recompressBatchSize is the maximum number of blocks visited by recompressBlocks in a single database transaction
+const recompressBatchSize = 1000
+
+// recompressBlocks rewrites the stored blocks with the compression of vs.Config.BlockCompression,
+// such as when block compression is enabled on an existing database.
+// The blocks are rewritten in batches, so that a large database does not hold a single database transaction for too long.
+// Once every block was rewritten it does nothing, until the compression is changed.
+func (vs *Visor) recompressBlocks() error {
+	if vs.DB.IsReadOnly() {
+		return nil
+	}
+
+	var start []byte
+	var total uint64
+	for {
+		var n uint64
+		if err := vs.DB.Update("recompressBlocks", func(tx *dbutil.Tx) error {
+			var err error
+			n, start, err = vs.Blockchain.RecompressBlocks(tx, start, recompressBatchSize)
+			return err
+		}); err != nil {
+			return err
+		}
+
+		if n != 0 {
+			total += n
+			logger.Infof("Rewrote %d blocks with %s block compression", total, vs.Config.BlockCompression)
+		}
+
+		if start == nil {
+			break
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package visor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/blockdb"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/historydb"
+)
+
+func TestRecompressBlocks(t *testing.T) {
+	db, shutdown := copyTestDB(t, "./testdata/data.db.ok")
+	defer shutdown()
+
+	pubkey := mustParsePubkey(t)
+
+	readBlocks := func(bc *Blockchain) []coin.SignedBlock {
+		var blocks []coin.SignedBlock
+		err := db.View("", func(tx *dbutil.Tx) error {
+			headSeq, ok, err := bc.HeadSeq(tx)
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			for i := uint64(0); i <= headSeq; i++ {
+				b, err := bc.GetSignedBlockBySeq(tx, i)
+				require.NoError(t, err)
+				blocks = append(blocks, *b)
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		return blocks
+	}
+
+	newVisor := func(c blockdb.BlockCompression) *Visor {
+		bc, err := NewBlockchain(db, BlockchainConfig{
+			Pubkey:           pubkey,
+			BlockCompression: c,
+		})
+		require.NoError(t, err)
+
+		return &Visor{
+			Config: Config{
+				BlockchainPubkey: pubkey,
+				BlockCompression: c,
+			},
+			DB:         db,
+			Blockchain: bc,
+			history:    historydb.New(),
+		}
+	}
+
+	v := newVisor(blockdb.BlockCompressionNone)
+	originals := readBlocks(v.Blockchain.(*Blockchain))
+
+	// Compression is enabled on the existing database
+	v = newVisor(blockdb.BlockCompressionSnappy)
+	require.NoError(t, v.recompressBlocks())
+	require.Equal(t, originals, readBlocks(v.Blockchain.(*Blockchain)))
+
+	// Rewriting again does nothing
+	err := db.Update("", func(tx *dbutil.Tx) error {
+		n, next, err := v.Blockchain.RecompressBlocks(tx, nil, recompressBatchSize)
+		require.NoError(t, err)
+		require.Equal(t, uint64(0), n)
+		require.Nil(t, next)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// The compressed database is still valid
+	err = CheckDatabase(context.Background(), db, CheckDatabaseConfig{Pubkey: pubkey, FullVerify: true})
+	require.NoError(t, err)
+
+	// Compression is disabled again
+	v = newVisor(blockdb.BlockCompressionNone)
+	require.NoError(t, v.recompressBlocks())
+	require.Equal(t, originals, readBlocks(v.Blockchain.(*Blockchain)))
+}
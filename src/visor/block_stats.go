@@ -0,0 +1,87 @@
+package visor
+
+import (
+	"github.com/skycoin/skycoin/src/visor/blockdb"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+// GetBlockStatsPage returns a page of the stats of the blocks, in seq order.
+// The page starts from the block of seq start and covers at most limit blocks, up to the head block if limit is 0.
+// The stats of blocks pruned before the stats were added to the database are skipped.
+// The returned seq is the start of the next page, nil if there are no more blocks.
+func (vs *Visor) GetBlockStatsPage(start, limit uint64) ([]blockdb.BlockStats, *uint64, error) {
+	var stats []blockdb.BlockStats
+	var next *uint64
+
+	if err := vs.viewReplica("GetBlockStatsPage", func(tx *dbutil.Tx) error {
+		headSeq, ok, err := vs.Blockchain.HeadSeq(tx)
+		if err != nil {
+			return err
+		} else if !ok || start > headSeq {
+			return nil
+		}
+
+		end := headSeq
+		if limit != 0 && headSeq-start >= limit {
+			end = start + limit - 1
+		}
+
+		stats, err = vs.Blockchain.GetBlockStatsInRange(tx, start, end)
+		if err != nil {
+			return err
+		}
+
+		if end < headSeq {
+			n := end + 1
+			next = &n
+		}
+
+		return nil
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	return stats, next, nil
+}
+
+// GetLastBlockStats returns the stats of the num most recent blocks, in seq order
+func (vs *Visor) GetLastBlockStats(num uint64) ([]blockdb.BlockStats, error) {
+	var stats []blockdb.BlockStats
+
+	if err := vs.viewReplica("GetLastBlockStats", func(tx *dbutil.Tx) error {
+		headSeq, ok, err := vs.Blockchain.HeadSeq(tx)
+		if err != nil {
+			return err
+		} else if !ok || num == 0 {
+			return nil
+		}
+
+		var start uint64
+		if headSeq >= num {
+			start = headSeq - num + 1
+		}
+
+		stats, err = vs.Blockchain.GetBlockStatsInRange(tx, start, headSeq)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// RebuildBlockStats computes the stats of every unpruned block again and stores them.
+// Returns the number of blocks whose stats were computed.
+func (vs *Visor) RebuildBlockStats() (uint64, error) {
+	var n uint64
+
+	if err := vs.DB.Update("RebuildBlockStats", func(tx *dbutil.Tx) error {
+		var err error
+		n, err = vs.Blockchain.RebuildBlockStats(tx)
+		return err
+	}); err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
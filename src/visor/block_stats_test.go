@@ -0,0 +1,183 @@
+package visor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/visor/blockdb"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+func TestVisorGetBlockStatsPage(t *testing.T) {
+	uint64Ptr := func(v uint64) *uint64 {
+		return &v
+	}
+
+	cases := []struct {
+		name         string
+		headSeq      uint64
+		hasHead      bool
+		start        uint64
+		limit        uint64
+		rangeStart   uint64
+		rangeEnd     uint64
+		expectedNext *uint64
+	}{
+		{
+			name:    "no blocks",
+			hasHead: false,
+		},
+		{
+			name:    "start after head",
+			headSeq: 10,
+			hasHead: true,
+			start:   11,
+			limit:   5,
+		},
+		{
+			name:         "first page",
+			headSeq:      10,
+			hasHead:      true,
+			start:        0,
+			limit:        5,
+			rangeStart:   0,
+			rangeEnd:     4,
+			expectedNext: uint64Ptr(5),
+		},
+		{
+			name:       "last page",
+			headSeq:    10,
+			hasHead:    true,
+			start:      6,
+			limit:      5,
+			rangeStart: 6,
+			rangeEnd:   10,
+		},
+		{
+			name:       "page ends after head",
+			headSeq:    10,
+			hasHead:    true,
+			start:      8,
+			limit:      5,
+			rangeStart: 8,
+			rangeEnd:   10,
+		},
+		{
+			name:       "no limit",
+			headSeq:    10,
+			hasHead:    true,
+			start:      3,
+			limit:      0,
+			rangeStart: 3,
+			rangeEnd:   10,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, shutdown := prepareDB(t)
+			defer shutdown()
+
+			matchTxn := mock.MatchedBy(func(tx *dbutil.Tx) bool {
+				return true
+			})
+
+			var expected []blockdb.BlockStats
+			bc := &MockBlockchainer{}
+			bc.On("HeadSeq", matchTxn).Return(tc.headSeq, tc.hasHead, nil)
+			if tc.rangeEnd != 0 {
+				for seq := tc.rangeStart; seq <= tc.rangeEnd; seq++ {
+					expected = append(expected, blockdb.BlockStats{
+						Seq:      seq,
+						TxnCount: 1,
+					})
+				}
+				bc.On("GetBlockStatsInRange", matchTxn, tc.rangeStart, tc.rangeEnd).Return(expected, nil)
+			}
+
+			v := &Visor{
+				DB:         db,
+				Blockchain: bc,
+			}
+
+			stats, next, err := v.GetBlockStatsPage(tc.start, tc.limit)
+			require.NoError(t, err)
+			require.Equal(t, expected, stats)
+			require.Equal(t, tc.expectedNext, next)
+			bc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestVisorGetLastBlockStats(t *testing.T) {
+	cases := []struct {
+		name       string
+		headSeq    uint64
+		hasHead    bool
+		num        uint64
+		rangeStart uint64
+		call       bool
+	}{
+		{
+			name:    "no blocks",
+			hasHead: false,
+			num:     5,
+		},
+		{
+			name:    "num 0",
+			headSeq: 10,
+			hasHead: true,
+		},
+		{
+			name:       "fewer than num blocks",
+			headSeq:    3,
+			hasHead:    true,
+			num:        5,
+			rangeStart: 0,
+			call:       true,
+		},
+		{
+			name:       "num blocks",
+			headSeq:    10,
+			hasHead:    true,
+			num:        5,
+			rangeStart: 6,
+			call:       true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, shutdown := prepareDB(t)
+			defer shutdown()
+
+			matchTxn := mock.MatchedBy(func(tx *dbutil.Tx) bool {
+				return true
+			})
+
+			var expected []blockdb.BlockStats
+			bc := &MockBlockchainer{}
+			bc.On("HeadSeq", matchTxn).Return(tc.headSeq, tc.hasHead, nil)
+			if tc.call {
+				for seq := tc.rangeStart; seq <= tc.headSeq; seq++ {
+					expected = append(expected, blockdb.BlockStats{
+						Seq: seq,
+					})
+				}
+				bc.On("GetBlockStatsInRange", matchTxn, tc.rangeStart, tc.headSeq).Return(expected, nil)
+			}
+
+			v := &Visor{
+				DB:         db,
+				Blockchain: bc,
+			}
+
+			stats, err := v.GetLastBlockStats(tc.num)
+			require.NoError(t, err)
+			require.Equal(t, expected, stats)
+			bc.AssertExpectations(t)
+		})
+	}
+}
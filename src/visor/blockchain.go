@@ -2,9 +2,14 @@ package visor
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"runtime"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/coin"
@@ -22,8 +27,21 @@ const (
 )
 
 var (
-	// ErrVerifyStopped is returned when database verification is interrupted
-	ErrVerifyStopped = errors.New("database verification stopped")
+	// ErrVerifyStopped is returned when database verification is interrupted by canceling its context.
+	// It is context.Canceled, so that the errors of the context and of dbutil.DB.ViewContext compare equal to it.
+	// If the deadline of the context is exceeded, context.DeadlineExceeded is returned instead.
+	ErrVerifyStopped = context.Canceled
+
+	// walkChainProgressRate is how often the WalkChainProgress callback is called
+	walkChainProgressRate = time.Second
+)
+
+const (
+	// VerifyWorkersAuto makes CheckDatabase tune the number of verification goroutines automatically
+	VerifyWorkersAuto = -1
+
+	// autoTuneInterval is the number of blocks read between adjustments of the number of WalkChain workers
+	autoTuneInterval = 100
 )
 
 // ErrBlockNotExist may be returned if a block is not found
@@ -67,12 +85,28 @@ type chainStore interface {
 	Len(*dbutil.Tx) (uint64, error)
 	AddBlock(*dbutil.Tx, *coin.SignedBlock) error
 	GetBlockByHash(*dbutil.Tx, cipher.SHA256) (*coin.Block, error)
+	GetBlockBySeq(*dbutil.Tx, uint64) (*coin.Block, error)
 	GetSignedBlockByHash(*dbutil.Tx, cipher.SHA256) (*coin.SignedBlock, error)
 	GetSignedBlockBySeq(*dbutil.Tx, uint64) (*coin.SignedBlock, error)
 	UnspentPool() blockdb.UnspentPooler
 	GetGenesisBlock(*dbutil.Tx) (*coin.SignedBlock, error)
 	GetBlockSignature(*dbutil.Tx, *coin.Block) (cipher.Sig, bool, error)
-	ForEachBlock(*dbutil.Tx, func(*coin.Block) error) error
+	ForEachSignedBlock(*dbutil.Tx, func(*coin.Block, cipher.Sig, bool) error) error
+	PrunedSeq(*dbutil.Tx) (uint64, error)
+	IsPruned(*dbutil.Tx, uint64) (bool, error)
+	Prune(*dbutil.Tx, uint64, uint64) (uint64, error)
+	RecompressBlocks(*dbutil.Tx, []byte, uint64) (uint64, []byte, error)
+	RepairBlock(*dbutil.Tx, *coin.SignedBlock) error
+	RemoveHead(*dbutil.Tx) (*coin.SignedBlock, error)
+	GetUnspentCommitment(*dbutil.Tx, uint64) (cipher.SHA256, bool, error)
+	MaybeBuildUnspentCommitments(*dbutil.Tx) error
+	GetBlockStatsInRange(*dbutil.Tx, uint64, uint64) ([]blockdb.BlockStats, error)
+	MaybeBuildBlockStats(*dbutil.Tx) error
+	RebuildBlockStats(*dbutil.Tx) (uint64, error)
+	GetBlockSeqByTime(*dbutil.Tx, uint64) (uint64, bool, error)
+	MaybeBuildBlockTimes(*dbutil.Tx) error
+	ExportUnspentSnapshot(*dbutil.Tx, io.Writer, uint64, coin.UxArray) (*blockdb.UnspentSnapshotHeader, error)
+	ImportUnspentSnapshot(*dbutil.Tx, *blockdb.UnspentSnapshotHeader, io.Reader, func(*coin.SignedBlock) error) error
 }
 
 // DefaultWalker default blockchain walker
@@ -97,6 +131,12 @@ func CreateBuckets(db *dbutil.DB) error {
 		return dbutil.CreateBuckets(tx, [][]byte{
 			UnconfirmedTxnsBkt,
 			UnconfirmedUnspentsBkt,
+			UnconfirmedTxnsExpiryBkt,
+			UnconfirmedEvictedTxnsBkt,
+			WatchAddressesBkt,
+			WatchEventsBkt,
+			WalletEventsBkt,
+			WalletEventIDsBkt,
 		})
 	})
 }
@@ -108,6 +148,45 @@ type BlockchainConfig struct {
 	// node will throw the error and return.
 	Arbitrating bool
 	Pubkey      cipher.PubKey
+	// WalkChainProgress is called periodically by WalkChain and WalkChainFrom with the walk progress.
+	// It is called from a single goroutine and is optional.
+	WalkChainProgress func(VerifyProgress)
+	// VerifyWorkers is the number of goroutines used to verify blocks by CheckDatabase.
+	// If 0, BlockchainVerifyTheadNum is used. If VerifyWorkersAuto, the number is tuned automatically.
+	VerifyWorkers int
+	// BlockCompression is the compression of the blocks written to the database
+	BlockCompression blockdb.BlockCompression
+	// BlockCacheSize is the memory budget in bytes of the cache of recently read blocks. 0 disables the cache
+	BlockCacheSize int
+}
+
+// VerifyProgress reports the progress of a blockchain walk
+type VerifyProgress struct {
+	// Number of blocks processed so far
+	Verified uint64
+	// Number of blocks left to process
+	Remaining uint64
+	// Estimated time remaining, based on the rate of blocks processed so far
+	ETA time.Duration
+}
+
+// newVerifyProgress creates a VerifyProgress, estimating the ETA from the elapsed time
+func newVerifyProgress(verified, total uint64, elapsed time.Duration) VerifyProgress {
+	var remaining uint64
+	if total > verified {
+		remaining = total - verified
+	}
+
+	var eta time.Duration
+	if verified > 0 {
+		eta = time.Duration(float64(elapsed) / float64(verified) * float64(remaining))
+	}
+
+	return VerifyProgress{
+		Verified:  verified,
+		Remaining: remaining,
+		ETA:       eta,
+	}
 }
 
 // Blockchain maintains blockchain and provides apis for accessing the chain.
@@ -124,6 +203,14 @@ func NewBlockchain(db *dbutil.DB, cfg BlockchainConfig) (*Blockchain, error) {
 		return nil, err
 	}
 
+	if err := chainstore.SetBlockCompression(cfg.BlockCompression); err != nil {
+		return nil, err
+	}
+
+	if err := chainstore.SetCacheSize(cfg.BlockCacheSize); err != nil {
+		return nil, err
+	}
+
 	return &Blockchain{
 		cfg:   cfg,
 		db:    db,
@@ -146,6 +233,94 @@ func (bc *Blockchain) GetSignedBlockBySeq(tx *dbutil.Tx, seq uint64) (*coin.Sign
 	return bc.store.GetSignedBlockBySeq(tx, seq)
 }
 
+// PrunedSeq returns the seq of the newest block whose body was removed by Prune, 0 if no block is pruned
+func (bc *Blockchain) PrunedSeq(tx *dbutil.Tx) (uint64, error) {
+	return bc.store.PrunedSeq(tx)
+}
+
+// IsPruned returns true if the body of the block of seq was removed by Prune
+func (bc *Blockchain) IsPruned(tx *dbutil.Tx, seq uint64) (bool, error) {
+	return bc.store.IsPruned(tx, seq)
+}
+
+// Prune removes the body of the blocks that are more than depth blocks below the head block,
+// at most limit blocks per call. Returns the number of blocks pruned.
+func (bc *Blockchain) Prune(tx *dbutil.Tx, depth, limit uint64) (uint64, error) {
+	return bc.store.Prune(tx, depth, limit)
+}
+
+// RecompressBlocks rewrites the stored blocks that don't have the compression of cfg.BlockCompression,
+// visiting at most limit blocks from the key start. Returns the number of blocks rewritten
+// and the key to continue from, nil once every block was visited.
+func (bc *Blockchain) RecompressBlocks(tx *dbutil.Tx, start []byte, limit uint64) (uint64, []byte, error) {
+	return bc.store.RecompressBlocks(tx, start, limit)
+}
+
+// RepairBlock replaces the stored main chain block at the seq of b, and its signature, with b.
+// b must have a valid signature and the header hash of the main chain block at its seq.
+func (bc *Blockchain) RepairBlock(tx *dbutil.Tx, b *coin.SignedBlock) error {
+	if err := bc.VerifySignature(b); err != nil {
+		return err
+	}
+
+	return bc.store.RepairBlock(tx, b)
+}
+
+// RemoveHead removes the head block from the blockchain and reverts its changes to the unspent pool.
+// Only the blockdb.ReorgDepth most recent blocks can be removed. Returns the removed block.
+func (bc *Blockchain) RemoveHead(tx *dbutil.Tx) (*coin.SignedBlock, error) {
+	return bc.store.RemoveHead(tx)
+}
+
+// GetUnspentCommitment returns the hash of the unspent output set after the block of seq was executed,
+// false if the block does not exist or the database predates the commitments
+func (bc *Blockchain) GetUnspentCommitment(tx *dbutil.Tx, seq uint64) (cipher.SHA256, bool, error) {
+	return bc.store.GetUnspentCommitment(tx, seq)
+}
+
+// MaybeBuildUnspentCommitments stores the unspent output set hashes of the blocks
+// executed before the commitments were added to the database
+func (bc *Blockchain) MaybeBuildUnspentCommitments(tx *dbutil.Tx) error {
+	return bc.store.MaybeBuildUnspentCommitments(tx)
+}
+
+// GetBlockStatsInRange returns the stats of the blocks in the seq range [start, end], in seq order.
+// Blocks pruned before the stats were added to the database are skipped.
+func (bc *Blockchain) GetBlockStatsInRange(tx *dbutil.Tx, start, end uint64) ([]blockdb.BlockStats, error) {
+	return bc.store.GetBlockStatsInRange(tx, start, end)
+}
+
+// MaybeBuildBlockStats stores the stats of the blocks executed before the stats were added to the database
+func (bc *Blockchain) MaybeBuildBlockStats(tx *dbutil.Tx) error {
+	return bc.store.MaybeBuildBlockStats(tx)
+}
+
+// RebuildBlockStats computes the stats of every unpruned block again and stores them.
+// Returns the number of blocks whose stats were computed.
+func (bc *Blockchain) RebuildBlockStats(tx *dbutil.Tx) (uint64, error) {
+	return bc.store.RebuildBlockStats(tx)
+}
+
+// GetBlockSeqByTime returns the seq of the last block whose time is at or before t, false if there is no such block
+func (bc *Blockchain) GetBlockSeqByTime(tx *dbutil.Tx, t uint64) (uint64, bool, error) {
+	return bc.store.GetBlockSeqByTime(tx, t)
+}
+
+// MaybeBuildBlockTimes indexes the times of the blocks executed before the index was added to the database
+func (bc *Blockchain) MaybeBuildBlockTimes(tx *dbutil.Tx) error {
+	return bc.store.MaybeBuildBlockTimes(tx)
+}
+
+// ExportUnspentSnapshot writes uxs, the unspent output set after the block of seq, to w in the unspent snapshot file format
+func (bc *Blockchain) ExportUnspentSnapshot(tx *dbutil.Tx, w io.Writer, seq uint64, uxs coin.UxArray) (*blockdb.UnspentSnapshotHeader, error) {
+	return bc.store.ExportUnspentSnapshot(tx, w, seq, uxs)
+}
+
+// ImportUnspentSnapshot bootstraps an empty database from the rest of an unspent output set snapshot after its header hdr
+func (bc *Blockchain) ImportUnspentSnapshot(tx *dbutil.Tx, hdr *blockdb.UnspentSnapshotHeader, r io.Reader, verify func(*coin.SignedBlock) error) error {
+	return bc.store.ImportUnspentSnapshot(tx, hdr, r, verify)
+}
+
 // Head returns the most recent confirmed block
 func (bc Blockchain) Head(tx *dbutil.Tx) (*coin.SignedBlock, error) {
 	return bc.store.Head(tx)
@@ -696,13 +871,117 @@ func (bc *Blockchain) VerifySignature(block *coin.SignedBlock) error {
 	return err
 }
 
-// WalkChain walk through the blockchain concurrently
-// The quit channel is optional and if closed, this method still stop.
-func (bc *Blockchain) WalkChain(workers int, f func(*dbutil.Tx, *coin.SignedBlock) error, quit chan struct{}) error {
-	if quit == nil {
-		quit = make(chan struct{})
+// VerifyWorkers returns the number of goroutines to use for block verification
+func (bc *Blockchain) VerifyWorkers() int {
+	if bc.cfg.VerifyWorkers == 0 {
+		return BlockchainVerifyTheadNum
+	}
+	return bc.cfg.VerifyWorkers
+}
+
+// autoVerifyWorkers returns the number of workers needed to keep up with the goroutine reading the blocks,
+// given the average time to read a block and the average time to verify a block, up to maxWorkers
+func autoVerifyWorkers(readLatency, verifyLatency time.Duration, maxWorkers int) int {
+	if readLatency <= 0 {
+		return maxWorkers
+	}
+
+	n := int((verifyLatency + readLatency - 1) / readLatency)
+	if n > maxWorkers {
+		n = maxWorkers
+	}
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+// WalkChain walk through the blockchain concurrently.
+// The walk stops when ctx is done and returns the context error.
+// If workers is VerifyWorkersAuto, the walk starts with a single worker and adds workers,
+// up to runtime.NumCPU, based on the measured time to read and to process a block.
+func (bc *Blockchain) WalkChain(ctx context.Context, workers int, f func(*dbutil.Tx, *coin.SignedBlock) error) error {
+	var total uint64
+	if err := bc.db.ViewContext(ctx, "WalkChain total", func(tx *dbutil.Tx) error {
+		var err error
+		total, err = bc.store.Len(tx)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	forEachBlock := func(tx *dbutil.Tx, g func(*coin.SignedBlock) error) error {
+		return bc.store.ForEachSignedBlock(tx, func(b *coin.Block, sig cipher.Sig, ok bool) error {
+			if !ok {
+				return blockdb.NewErrMissingSignature(b)
+			}
+
+			return g(&coin.SignedBlock{
+				Block: *b,
+				Sig:   sig,
+			})
+		})
+	}
+
+	return bc.walkChain(ctx, workers, total, forEachBlock, f)
+}
+
+// WalkChainFrom is like WalkChain, but only walks the blocks of the main chain
+// starting from startSeq up to the head block.
+func (bc *Blockchain) WalkChainFrom(ctx context.Context, startSeq uint64, workers int, f func(*dbutil.Tx, *coin.SignedBlock) error) error {
+	forEachBlock := func(tx *dbutil.Tx, g func(*coin.SignedBlock) error) error {
+		headSeq, ok, err := bc.store.HeadSeq(tx)
+		if err != nil {
+			return err
+		} else if !ok {
+			return nil
+		}
+
+		for seq := startSeq; seq <= headSeq; seq++ {
+			b, err := bc.store.GetSignedBlockBySeq(tx, seq)
+			if err != nil {
+				return err
+			}
+			if b == nil {
+				return fmt.Errorf("block of seq %d does not exist", seq)
+			}
+
+			if err := g(b); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	var total uint64
+	if err := bc.db.ViewContext(ctx, "WalkChainFrom total", func(tx *dbutil.Tx) error {
+		headSeq, ok, err := bc.store.HeadSeq(tx)
+		if err != nil {
+			return err
+		}
+		if ok && headSeq >= startSeq {
+			total = headSeq - startSeq + 1
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return bc.walkChain(ctx, workers, total, forEachBlock, f)
+}
+
+func (bc *Blockchain) walkChain(ctx context.Context, workers int, total uint64, forEachBlock func(*dbutil.Tx, func(*coin.SignedBlock) error) error, f func(*dbutil.Tx, *coin.SignedBlock) error) error {
+	autoTune := workers == VerifyWorkersAuto
+	if autoTune {
+		workers = 1
 	}
 
+	// Number of blocks processed by f, for progress reporting
+	var walked uint64
+	// Total time spent in f, in nanoseconds, for auto-tuning the number of workers
+	var verifyNanos uint64
+
 	signedBlockC := make(chan *coin.SignedBlock, 100)
 	errC := make(chan error, 100)
 	interrupt := make(chan struct{})
@@ -710,12 +989,13 @@ func (bc *Blockchain) WalkChain(workers int, f func(*dbutil.Tx, *coin.SignedBloc
 
 	// Verify block signatures in a worker pool
 	var workerWg sync.WaitGroup
-	workerWg.Add(workers)
-	for i := 0; i < workers; i++ {
+	startWorker := func() {
+		workerWg.Add(1)
 		go func() {
 			defer workerWg.Done()
-			if err := bc.db.View("WalkChain verify blocks", func(tx *dbutil.Tx) error {
+			if err := bc.db.ViewContext(ctx, "WalkChain verify blocks", func(tx *dbutil.Tx) error {
 				for b := range signedBlockC {
+					t := time.Now()
 					if err := f(tx, b); err != nil {
 						// if err := cipher.VerifyPubKeySignedHash(bc.cfg.Pubkey, sh.sig, sh.hash); err != nil {
 						// logger.Errorf("Signature verification failed: %v", err)
@@ -724,14 +1004,44 @@ func (bc *Blockchain) WalkChain(workers int, f func(*dbutil.Tx, *coin.SignedBloc
 						default:
 						}
 					}
+					atomic.AddUint64(&verifyNanos, uint64(time.Since(t)))
+					atomic.AddUint64(&walked, 1)
 				}
 				return nil
-			}); err != nil {
+			}); err != nil && err != ctx.Err() {
 				logger.WithError(err).Error("WalkChain verify blocks db transaction failed")
 			}
 		}()
 	}
 
+	for i := 0; i < workers; i++ {
+		startWorker()
+	}
+
+	// tuneWorkers adds workers if the workers can't keep up with the blocks read.
+	// It is only called by the goroutine reading the blocks, before signedBlockC is closed,
+	// so the workers started earlier are still running when workerWg.Add is called.
+	maxWorkers := runtime.NumCPU()
+	tuneWorkers := func(read uint64, readTime time.Duration) {
+		verified := atomic.LoadUint64(&walked)
+		if verified == 0 || read == 0 {
+			return
+		}
+
+		readLatency := readTime / time.Duration(read)
+		verifyLatency := time.Duration(atomic.LoadUint64(&verifyNanos) / verified)
+
+		n := autoVerifyWorkers(readLatency, verifyLatency, maxWorkers)
+		if n <= workers {
+			return
+		}
+
+		logger.Debugf("WalkChain: read %s/block, verify %s/block, increasing workers from %d to %d", readLatency, verifyLatency, workers, n)
+		for ; workers < n; workers++ {
+			startWorker()
+		}
+	}
+
 	// Wait for verification worker goroutines to finish
 	var wg sync.WaitGroup
 	wg.Add(1)
@@ -741,49 +1051,59 @@ func (bc *Blockchain) WalkChain(workers int, f func(*dbutil.Tx, *coin.SignedBloc
 		close(verifyDone)
 	}()
 
+	// Report the progress periodically
+	progressDone := make(chan struct{})
+	if bc.cfg.WalkChainProgress != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			bc.reportWalkChainProgress(total, &walked, progressDone)
+		}()
+	}
+
 	// Iterate all blocks stored in the "blocks" bucket
 	// * Detect if a corresponding signature is missing from the signatures bucket
 	// * Verify the signature for the block
 	wg.Add(1)
 	go func() {
-		if err := bc.db.View("WalkChain get blocks", func(tx *dbutil.Tx) error {
+		defer wg.Done()
+		defer close(signedBlockC)
+
+		if err := bc.db.ViewContext(ctx, "WalkChain get blocks", func(tx *dbutil.Tx) error {
 			if length, err := bc.Len(tx); err != nil {
 				return err
 			} else if length == 0 {
 				return nil
 			}
-			defer wg.Done()
-			defer close(signedBlockC)
 
 			errInterrupted := errors.New("goroutine was stopped")
 
-			if err := bc.store.ForEachBlock(tx, func(block *coin.Block) error {
-				sig, ok, err := bc.store.GetBlockSignature(tx, block)
-				if err != nil {
-					return err
-				}
-				if !ok {
-					return blockdb.NewErrMissingSignature(block)
-				}
+			// Time spent reading blocks, excluding the time waiting for the workers, for auto-tuning
+			var read uint64
+			var readTime time.Duration
+			readStart := time.Now()
 
-				signedBlock := &coin.SignedBlock{
-					Sig:   sig,
-					Block: *block,
+			if err := forEachBlock(tx, func(signedBlock *coin.SignedBlock) error {
+				readTime += time.Since(readStart)
+				read++
+				if autoTune && read%autoTuneInterval == 0 {
+					tuneWorkers(read, readTime)
 				}
 
 				select {
 				case signedBlockC <- signedBlock:
+					readStart = time.Now()
 					return nil
-				case <-quit:
+				case <-ctx.Done():
 					return errInterrupted
 				case <-interrupt:
 					return errInterrupted
 				}
-			}); err != nil && err != errInterrupted {
+			}); err != nil && err != errInterrupted && err != ctx.Err() {
 				switch err.(type) {
 				case blockdb.ErrMissingSignature:
 				default:
-					logger.Errorf("bc.store.ForEachBlock failed: %v", err)
+					logger.Errorf("WalkChain forEachBlock failed: %v", err)
 				}
 				select {
 				case errC <- err:
@@ -791,7 +1111,7 @@ func (bc *Blockchain) WalkChain(workers int, f func(*dbutil.Tx, *coin.SignedBloc
 				}
 			}
 			return nil
-		}); err != nil {
+		}); err != nil && err != ctx.Err() {
 			logger.WithError(err).Error("WalkChain get blocks db transaction failed")
 		}
 	}()
@@ -802,18 +1122,41 @@ func (bc *Blockchain) WalkChain(workers int, f func(*dbutil.Tx, *coin.SignedBloc
 		if err != nil {
 			break
 		}
-	case <-quit:
-		err = ErrVerifyStopped
+	case <-ctx.Done():
+		err = ctx.Err()
 		break
 	case <-verifyDone:
 		break
 	}
 
+	close(progressDone)
 	close(interrupt)
 	wg.Wait()
 	return err
 }
 
+// reportWalkChainProgress calls the WalkChainProgress callback every walkChainProgressRate,
+// and once more when done is closed
+func (bc *Blockchain) reportWalkChainProgress(total uint64, walked *uint64, done <-chan struct{}) {
+	start := time.Now()
+	ticker := time.NewTicker(walkChainProgressRate)
+	defer ticker.Stop()
+
+	report := func() {
+		bc.cfg.WalkChainProgress(newVerifyProgress(atomic.LoadUint64(walked), total, time.Since(start)))
+	}
+
+	for {
+		select {
+		case <-ticker.C:
+			report()
+		case <-done:
+			report()
+			return
+		}
+	}
+}
+
 // VerifyBlockHeader Returns error if the BlockHeader is not valid
 func (bc Blockchain) verifyBlockHeader(tx *dbutil.Tx, b coin.Block) error {
 	head, err := bc.Head(tx)
@@ -2,7 +2,9 @@ package visor
 
 import (
 	"errors"
+	"io"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -111,6 +113,15 @@ func (fcs *fakeChainStore) GetBlockByHash(tx *dbutil.Tx, hash cipher.SHA256) (*c
 	return nil, nil
 }
 
+func (fcs *fakeChainStore) GetBlockBySeq(tx *dbutil.Tx, seq uint64) (*coin.Block, error) {
+	l := len(fcs.blocks)
+	if seq >= uint64(l) {
+		return nil, nil
+	}
+
+	return &fcs.blocks[seq].Block, nil
+}
+
 func (fcs *fakeChainStore) GetSignedBlockByHash(tx *dbutil.Tx, hash cipher.SHA256) (*coin.SignedBlock, error) {
 	return nil, nil
 }
@@ -135,7 +146,67 @@ func (fcs *fakeChainStore) GetGenesisBlock(tx *dbutil.Tx) (*coin.SignedBlock, er
 	return nil, nil
 }
 
-func (fcs *fakeChainStore) ForEachBlock(tx *dbutil.Tx, f func(*coin.Block) error) error {
+func (fcs *fakeChainStore) ForEachSignedBlock(tx *dbutil.Tx, f func(*coin.Block, cipher.Sig, bool) error) error {
+	return nil
+}
+
+func (fcs *fakeChainStore) PrunedSeq(tx *dbutil.Tx) (uint64, error) {
+	return 0, nil
+}
+
+func (fcs *fakeChainStore) IsPruned(tx *dbutil.Tx, seq uint64) (bool, error) {
+	return false, nil
+}
+
+func (fcs *fakeChainStore) Prune(tx *dbutil.Tx, depth, limit uint64) (uint64, error) {
+	return 0, nil
+}
+
+func (fcs *fakeChainStore) RecompressBlocks(tx *dbutil.Tx, start []byte, limit uint64) (uint64, []byte, error) {
+	return 0, nil, nil
+}
+
+func (fcs *fakeChainStore) RepairBlock(tx *dbutil.Tx, b *coin.SignedBlock) error {
+	return nil
+}
+
+func (fcs *fakeChainStore) RemoveHead(tx *dbutil.Tx) (*coin.SignedBlock, error) {
+	return nil, nil
+}
+
+func (fcs *fakeChainStore) GetUnspentCommitment(tx *dbutil.Tx, seq uint64) (cipher.SHA256, bool, error) {
+	return cipher.SHA256{}, false, nil
+}
+
+func (fcs *fakeChainStore) MaybeBuildUnspentCommitments(tx *dbutil.Tx) error {
+	return nil
+}
+
+func (fcs *fakeChainStore) GetBlockStatsInRange(tx *dbutil.Tx, start, end uint64) ([]blockdb.BlockStats, error) {
+	return nil, nil
+}
+
+func (fcs *fakeChainStore) MaybeBuildBlockStats(tx *dbutil.Tx) error {
+	return nil
+}
+
+func (fcs *fakeChainStore) RebuildBlockStats(tx *dbutil.Tx) (uint64, error) {
+	return 0, nil
+}
+
+func (fcs *fakeChainStore) GetBlockSeqByTime(tx *dbutil.Tx, t uint64) (uint64, bool, error) {
+	return 0, false, nil
+}
+
+func (fcs *fakeChainStore) MaybeBuildBlockTimes(tx *dbutil.Tx) error {
+	return nil
+}
+
+func (fcs *fakeChainStore) ExportUnspentSnapshot(tx *dbutil.Tx, w io.Writer, seq uint64, uxs coin.UxArray) (*blockdb.UnspentSnapshotHeader, error) {
+	return nil, nil
+}
+
+func (fcs *fakeChainStore) ImportUnspentSnapshot(tx *dbutil.Tx, hdr *blockdb.UnspentSnapshotHeader, r io.Reader, verify func(*coin.SignedBlock) error) error {
 	return nil
 }
 
@@ -859,3 +930,83 @@ func TestExecuteBlock(t *testing.T) {
 	})
 	require.NoError(t, err)
 }
+
+func TestNewVerifyProgress(t *testing.T) {
+	tt := []struct {
+		name     string
+		verified uint64
+		total    uint64
+		elapsed  time.Duration
+		progress VerifyProgress
+	}{
+		{
+			name:     "not started",
+			verified: 0,
+			total:    100,
+			elapsed:  time.Second,
+			progress: VerifyProgress{
+				Remaining: 100,
+			},
+		},
+		{
+			name:     "in progress",
+			verified: 25,
+			total:    100,
+			elapsed:  time.Second * 10,
+			progress: VerifyProgress{
+				Verified:  25,
+				Remaining: 75,
+				ETA:       time.Second * 30,
+			},
+		},
+		{
+			name:     "complete",
+			verified: 100,
+			total:    100,
+			elapsed:  time.Second * 10,
+			progress: VerifyProgress{
+				Verified: 100,
+			},
+		},
+		{
+			name:     "verified exceeds total",
+			verified: 101,
+			total:    100,
+			elapsed:  time.Second * 10,
+			progress: VerifyProgress{
+				Verified: 101,
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			p := newVerifyProgress(tc.verified, tc.total, tc.elapsed)
+			require.Equal(t, tc.progress, p)
+		})
+	}
+}
+
+func TestAutoVerifyWorkers(t *testing.T) {
+	tt := []struct {
+		name          string
+		readLatency   time.Duration
+		verifyLatency time.Duration
+		maxWorkers    int
+		workers       int
+	}{
+		{"verify faster than read", time.Millisecond, time.Microsecond, 8, 1},
+		{"verify as fast as read", time.Millisecond, time.Millisecond, 8, 1},
+		{"verify slower than read", time.Millisecond, 3 * time.Millisecond, 8, 3},
+		{"round up", time.Millisecond, 2*time.Millisecond + 1, 8, 3},
+		{"capped by max workers", time.Microsecond, time.Millisecond, 8, 8},
+		{"no read time", 0, time.Millisecond, 4, 4},
+		{"no verify time", time.Millisecond, 0, 4, 1},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.workers, autoVerifyWorkers(tc.readLatency, tc.verifyLatency, tc.maxWorkers))
+		})
+	}
+}
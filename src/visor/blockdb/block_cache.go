@@ -0,0 +1,189 @@
+package blockdb
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+const (
+	// DefaultBlockCacheSize is the default memory budget of the block cache, in bytes
+	DefaultBlockCacheSize = 32 * 1024 * 1024
+
+	// blockCacheEntryOverhead approximates the memory used by an entry besides its key and value
+	blockCacheEntryOverhead = 96
+)
+
+// BlockCacheStats are the size and hit counts of the block cache
+type BlockCacheStats struct {
+	// MaxSize is the memory budget of the cache in bytes, 0 if the cache is disabled
+	MaxSize int
+	// Size is the approximate memory used by the cached values in bytes
+	Size      int
+	Entries   int
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+}
+
+// blockCache is a concurrent-safe LRU read-through cache of the values of BlocksBkt, BlockSigsBkt and TreeBkt.
+// Random access to blocks, such as by the explorer, otherwise reads the same bolt pages over and over.
+//
+// A value is only cached by a read-only transaction that began after the last transaction that wrote
+// to the cached buckets, so a transaction that reads an older snapshot can't cache a stale value,
+// and a value written by a transaction that is rolled back is never cached.
+// Writes remove the values they replace from the cache.
+//
+// The cached values are shared by the callers and must not be modified.
+// A nil *blockCache reads every value from the db.
+type blockCache struct {
+	sync.Mutex
+	maxSize int
+	size    int
+	lru     *list.List
+	entries map[string]*list.Element
+	// writeTxID is the ID of the most recent writable transaction that removed a value
+	writeTxID int
+	counts    BlockCacheStats
+}
+
+type blockCacheEntry struct {
+	key   string
+	value []byte
+}
+
+func (e *blockCacheEntry) size() int {
+	return len(e.key) + len(e.value) + blockCacheEntryOverhead
+}
+
+// newBlockCache creates a blockCache using at most maxSize bytes
+func newBlockCache(maxSize int) *blockCache {
+	return &blockCache{
+		maxSize: maxSize,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+func blockCacheKey(bktName, key []byte) string {
+	k := make([]byte, 0, len(bktName)+1+len(key))
+	k = append(k, bktName...)
+	k = append(k, 0)
+	return string(append(k, key...))
+}
+
+// get returns a value from a bucket, from the cache if it is cached.
+// Returns nil if the value does not exist.
+func (c *blockCache) get(tx *dbutil.Tx, bktName, key []byte) ([]byte, error) {
+	if c == nil {
+		return dbutil.GetBucketValue(tx, bktName, key)
+	}
+
+	k := blockCacheKey(bktName, key)
+
+	c.Lock()
+	if e, ok := c.entries[k]; ok {
+		c.lru.MoveToFront(e)
+		c.counts.Hits++
+		v := e.Value.(*blockCacheEntry).value
+		c.Unlock()
+		return v, nil
+	}
+	c.counts.Misses++
+	c.Unlock()
+
+	v, err := dbutil.GetBucketValue(tx, bktName, key)
+	if err != nil || v == nil {
+		return v, err
+	}
+
+	if !tx.Writable() {
+		c.add(tx.ID(), k, v)
+	}
+
+	return v, nil
+}
+
+// add caches a value read by the read-only transaction txID, evicting the least recently used values
+// to stay within the memory budget
+func (c *blockCache) add(txID int, k string, v []byte) {
+	c.Lock()
+	defer c.Unlock()
+
+	if txID < c.writeTxID {
+		return
+	}
+
+	if _, ok := c.entries[k]; ok {
+		return
+	}
+
+	entry := &blockCacheEntry{
+		key:   k,
+		value: v,
+	}
+	if entry.size() > c.maxSize {
+		return
+	}
+
+	c.entries[k] = c.lru.PushFront(entry)
+	c.size += entry.size()
+
+	for c.size > c.maxSize {
+		c.removeElement(c.lru.Back())
+		c.counts.Evictions++
+	}
+}
+
+// remove removes a value written by the writable transaction tx from the cache
+func (c *blockCache) remove(tx *dbutil.Tx, bktName, key []byte) {
+	if c == nil {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if id := tx.ID(); id > c.writeTxID {
+		c.writeTxID = id
+	}
+
+	if e, ok := c.entries[blockCacheKey(bktName, key)]; ok {
+		c.removeElement(e)
+	}
+}
+
+func (c *blockCache) removeElement(e *list.Element) {
+	entry := c.lru.Remove(e).(*blockCacheEntry)
+	delete(c.entries, entry.key)
+	c.size -= entry.size()
+}
+
+// putBucketValue writes a value to a bucket and removes the value it replaces from the cache
+func (c *blockCache) putBucketValue(tx *dbutil.Tx, bktName, key, value []byte) error {
+	c.remove(tx, bktName, key)
+	return dbutil.PutBucketValue(tx, bktName, key, value)
+}
+
+// delete deletes a value from a bucket and from the cache
+func (c *blockCache) delete(tx *dbutil.Tx, bktName, key []byte) error {
+	c.remove(tx, bktName, key)
+	return dbutil.Delete(tx, bktName, key)
+}
+
+// stats returns the size and hit counts of the cache
+func (c *blockCache) stats() BlockCacheStats {
+	if c == nil {
+		return BlockCacheStats{}
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	s := c.counts
+	s.MaxSize = c.maxSize
+	s.Size = c.size
+	s.Entries = len(c.entries)
+	return s
+}
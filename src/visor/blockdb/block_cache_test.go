@@ -0,0 +1,218 @@
+package blockdb
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+func TestBlockchainCache(t *testing.T) {
+	db, shutdown := setupNoUnspentAddrIndexDB(t)
+	defer shutdown()
+
+	bc, err := NewBlockchain(db, func(tx *dbutil.Tx, hps []coin.HashPair) (cipher.SHA256, bool) {
+		return hps[0].Hash, true
+	})
+	require.NoError(t, err)
+
+	// The cache is disabled by default
+	blocks := readBlockchain180(t, bc, db)
+	require.Equal(t, BlockCacheStats{}, bc.CacheStats())
+
+	require.Error(t, bc.SetCacheSize(-1))
+	require.NoError(t, bc.SetCacheSize(DefaultBlockCacheSize))
+
+	// Each block reads its tree entry, block record and signature
+	nReads := uint64(len(blocks) * 3)
+
+	require.Equal(t, blocks, readBlockchain180(t, bc, db))
+	stats := bc.CacheStats()
+	require.Equal(t, uint64(0), stats.Hits)
+	require.Equal(t, nReads, stats.Misses)
+	require.Equal(t, int(nReads), stats.Entries)
+	require.Equal(t, uint64(0), stats.Evictions)
+	require.Equal(t, DefaultBlockCacheSize, stats.MaxSize)
+	require.True(t, stats.Size > 0)
+
+	require.Equal(t, blocks, readBlockchain180(t, bc, db))
+	stats = bc.CacheStats()
+	require.Equal(t, nReads, stats.Hits)
+	require.Equal(t, nReads, stats.Misses)
+
+	// Writes remove the values they replace, so the pruned blocks are read from the db
+	headSeq := uint64(len(blocks) - 1)
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		n, err := bc.Prune(tx, headSeq-10, 0)
+		require.Equal(t, uint64(10), n)
+		return err
+	})
+	require.NoError(t, err)
+	require.Equal(t, int(nReads)-10, bc.CacheStats().Entries)
+
+	pruned := readBlockchain180(t, bc, db)
+	for i := range blocks {
+		if i >= 1 && i <= 10 {
+			require.Empty(t, pruned[i].Body.Transactions)
+			require.Equal(t, blocks[i].Head, pruned[i].Head)
+			require.Equal(t, blocks[i].Sig, pruned[i].Sig)
+		} else {
+			require.Equal(t, blocks[i], pruned[i])
+		}
+	}
+
+	// Values read by a writable transaction are not cached, so a rolled back write can't be cached
+	require.NoError(t, bc.SetCacheSize(DefaultBlockCacheSize))
+	errRollback := errors.New("rollback")
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		_, err := bc.Prune(tx, 10, 0)
+		require.NoError(t, err)
+
+		b, err := bc.GetSignedBlockBySeq(tx, 100)
+		require.NoError(t, err)
+		require.Empty(t, b.Body.Transactions)
+		return errRollback
+	})
+	require.Equal(t, errRollback, err)
+	require.Equal(t, 0, bc.CacheStats().Entries)
+	require.Equal(t, pruned, readBlockchain180(t, bc, db))
+
+	// The least recently used values are evicted to stay within the memory budget
+	maxSize := 10 * 1024
+	require.NoError(t, bc.SetCacheSize(maxSize))
+	require.Equal(t, pruned, readBlockchain180(t, bc, db))
+	stats = bc.CacheStats()
+	require.True(t, stats.Size <= maxSize)
+	require.True(t, stats.Evictions > 0)
+	require.Equal(t, nReads, stats.Misses)
+
+	// The most recently read block is cached
+	err = db.View("", func(tx *dbutil.Tx) error {
+		b, err := bc.GetSignedBlockBySeq(tx, headSeq)
+		require.NoError(t, err)
+		require.Equal(t, blocks[headSeq], *b)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, stats.Hits+3, bc.CacheStats().Hits)
+
+	// Disabling the cache reads every value from the db
+	require.NoError(t, bc.SetCacheSize(0))
+	require.Equal(t, pruned, readBlockchain180(t, bc, db))
+	require.Equal(t, BlockCacheStats{}, bc.CacheStats())
+}
+
+func TestBlockCacheStaleSnapshot(t *testing.T) {
+	db, shutdown := prepareDB(t)
+	defer shutdown()
+
+	c := newBlockCache(DefaultBlockCacheSize)
+	key := []byte("key")
+
+	var readTxID int
+	err := db.View("", func(tx *dbutil.Tx) error {
+		readTxID = tx.ID()
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		require.True(t, tx.ID() > readTxID)
+		return c.putBucketValue(tx, BlocksBkt, key, []byte("new"))
+	})
+	require.NoError(t, err)
+
+	// A transaction that began before the write reads an older snapshot, so its values are not cached
+	c.add(readTxID, blockCacheKey(BlocksBkt, key), []byte("old"))
+	require.Equal(t, 0, c.stats().Entries)
+
+	// A transaction that began after the write caches the values it reads
+	err = db.View("", func(tx *dbutil.Tx) error {
+		v, err := c.get(tx, BlocksBkt, key)
+		require.NoError(t, err)
+		require.Equal(t, []byte("new"), v)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, c.stats().Entries)
+
+	// Missing values are not cached
+	err = db.View("", func(tx *dbutil.Tx) error {
+		v, err := c.get(tx, BlocksBkt, []byte("missing"))
+		require.NoError(t, err)
+		require.Nil(t, v)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, c.stats().Entries)
+
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		return c.delete(tx, BlocksBkt, key)
+	})
+	require.NoError(t, err)
+	require.Equal(t, 0, c.stats().Entries)
+
+	err = db.View("", func(tx *dbutil.Tx) error {
+		v, err := c.get(tx, BlocksBkt, key)
+		require.NoError(t, err)
+		require.Nil(t, v)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+// BenchmarkBlockchainCache reads random blocks of the test database, like the explorer does,
+// with and without the block cache
+func BenchmarkBlockchainCache(b *testing.B) {
+	var t testing.T
+	db, shutdown := setupNoUnspentAddrIndexDB(&t)
+	defer shutdown()
+
+	bc, err := NewBlockchain(db, func(tx *dbutil.Tx, hps []coin.HashPair) (cipher.SHA256, bool) {
+		return hps[0].Hash, true
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	var n uint64
+	if err := db.View("", func(tx *dbutil.Tx) error {
+		var err error
+		n, err = bc.Len(tx)
+		return err
+	}); err != nil {
+		b.Fatal(err)
+	}
+
+	for _, size := range []int{0, DefaultBlockCacheSize} {
+		name := "disabled"
+		if size > 0 {
+			name = "enabled"
+		}
+
+		b.Run(name, func(b *testing.B) {
+			if err := bc.SetCacheSize(size); err != nil {
+				b.Fatal(err)
+			}
+
+			r := rand.New(rand.NewSource(1))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := db.View("", func(tx *dbutil.Tx) error {
+					_, err := bc.GetSignedBlockBySeq(tx, uint64(r.Int63n(int64(n))))
+					return err
+				}); err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			stats := bc.CacheStats()
+			b.Logf("%d hits, %d misses", stats.Hits, stats.Misses)
+		})
+	}
+}
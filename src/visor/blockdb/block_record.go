@@ -0,0 +1,264 @@
+package blockdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/golang/snappy"
+
+	"github.com/skycoin/skycoin/src/cipher/encoder"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+// BlockCompression is the compression of the block records written to BlocksBkt
+type BlockCompression byte
+
+const (
+	// BlockCompressionNone stores blocks as encoded bytes
+	BlockCompressionNone BlockCompression = 0
+	// BlockCompressionSnappy compresses blocks with snappy
+	BlockCompressionSnappy BlockCompression = 1
+)
+
+// blockRecordMarker prefixes the block records that have a format flag.
+// Records without it are encoded blocks, as written before block compression was added.
+// An encoded block starts with its uint32 version, which is 0 for every block of the chain,
+// a block whose encoding starts with the marker is stored with a format flag, see encodeBlockRecord.
+var blockRecordMarker = []byte{0xFF, 0xFF, 0xFF, 0xFF}
+
+// ErrUnknownBlockCompression is returned when decoding a block record with an unknown format flag
+var ErrUnknownBlockCompression = errors.New("Unknown block compression")
+
+// ParseBlockCompression parses the name of a block compression, "none" or "snappy"
+func ParseBlockCompression(s string) (BlockCompression, error) {
+	switch strings.ToLower(s) {
+	case "", "none":
+		return BlockCompressionNone, nil
+	case "snappy":
+		return BlockCompressionSnappy, nil
+	default:
+		return 0, fmt.Errorf("Invalid block compression %q, must be \"none\" or \"snappy\"", s)
+	}
+}
+
+func (c BlockCompression) String() string {
+	switch c {
+	case BlockCompressionNone:
+		return "none"
+	case BlockCompressionSnappy:
+		return "snappy"
+	default:
+		return fmt.Sprintf("unknown(%d)", byte(c))
+	}
+}
+
+// encodeBlockRecord encodes a block for BlocksBkt with compression c.
+// The compressed block is prefixed by blockRecordMarker and the format flag.
+// If compressing does not make the block smaller, the block is stored uncompressed.
+func encodeBlockRecord(b *coin.Block, c BlockCompression) ([]byte, error) {
+	data := encoder.Serialize(b)
+
+	switch c {
+	case BlockCompressionNone:
+	case BlockCompressionSnappy:
+		compressed := snappy.Encode(nil, data)
+		if len(compressed)+len(blockRecordMarker)+1 < len(data) {
+			return flagBlockRecord(BlockCompressionSnappy, compressed), nil
+		}
+	default:
+		return nil, ErrUnknownBlockCompression
+	}
+
+	if bytes.HasPrefix(data, blockRecordMarker) {
+		return flagBlockRecord(BlockCompressionNone, data), nil
+	}
+
+	return data, nil
+}
+
+func flagBlockRecord(c BlockCompression, data []byte) []byte {
+	v := make([]byte, 0, len(blockRecordMarker)+1+len(data))
+	v = append(v, blockRecordMarker...)
+	v = append(v, byte(c))
+	return append(v, data...)
+}
+
+// blockRecordCompression returns the compression of a block record
+func blockRecordCompression(v []byte) (BlockCompression, error) {
+	if !bytes.HasPrefix(v, blockRecordMarker) {
+		return BlockCompressionNone, nil
+	}
+
+	if len(v) == len(blockRecordMarker) {
+		return 0, errors.New("Block record is missing its format flag")
+	}
+
+	c := BlockCompression(v[len(blockRecordMarker)])
+	switch c {
+	case BlockCompressionNone, BlockCompressionSnappy:
+		return c, nil
+	default:
+		return 0, ErrUnknownBlockCompression
+	}
+}
+
+// decodeBlockRecord decodes a block record of BlocksBkt written by encodeBlockRecord
+func decodeBlockRecord(v []byte, b *coin.Block) error {
+	c, err := blockRecordCompression(v)
+	if err != nil {
+		return err
+	}
+
+	data := v
+	if bytes.HasPrefix(v, blockRecordMarker) {
+		data = v[len(blockRecordMarker)+1:]
+	}
+
+	if c == BlockCompressionSnappy {
+		data, err = snappy.Decode(nil, data)
+		if err != nil {
+			return fmt.Errorf("Decompress block record failed: %v", err)
+		}
+	}
+
+	return encoder.DeserializeRaw(data, b)
+}
+
+// putBlockRecord writes a block to BlocksBkt with compression c, removing the record it replaces from the cache
+func putBlockRecord(tx *dbutil.Tx, cache *blockCache, b *coin.Block, c BlockCompression) error {
+	v, err := encodeBlockRecord(b, c)
+	if err != nil {
+		return err
+	}
+
+	hash := b.HashHeader()
+	return cache.putBucketValue(tx, BlocksBkt, hash[:], v)
+}
+
+// BlockCompressionStats counts the block records of each compression and their size in BlocksBkt
+type BlockCompressionStats struct {
+	Records map[BlockCompression]uint64
+	Bytes   map[BlockCompression]uint64
+}
+
+// BlockCompressionStats returns the number and size of the block records of each compression
+func (bc *Blockchain) BlockCompressionStats(tx *dbutil.Tx) (*BlockCompressionStats, error) {
+	stats := &BlockCompressionStats{
+		Records: make(map[BlockCompression]uint64),
+		Bytes:   make(map[BlockCompression]uint64),
+	}
+
+	if err := dbutil.ForEach(tx, BlocksBkt, func(_, v []byte) error {
+		c, err := blockRecordCompression(v)
+		if err != nil {
+			return err
+		}
+
+		stats.Records[c]++
+		stats.Bytes[c] += uint64(len(v))
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// SetBlockCompression sets the compression of the block records written by the blockchain.
+// Block records written with any compression can be read.
+func (bc *Blockchain) SetBlockCompression(c BlockCompression) error {
+	switch c {
+	case BlockCompressionNone, BlockCompressionSnappy:
+	default:
+		return ErrUnknownBlockCompression
+	}
+
+	bc.compression = c
+	bc.tree = &blockTree{
+		compression: c,
+		cache:       bc.cache,
+	}
+	return nil
+}
+
+// RecompressBlocks rewrites the block records that are not stored with the compression of the blockchain.
+// The records are visited in key order starting from the key start, at most limit records per call.
+// Returns the number of records rewritten and the key to continue from, which is nil once every record was visited.
+// A block that doesn't get smaller when compressed is left uncompressed.
+// Once every record was visited the compression is recorded, and later calls with a nil start do nothing
+// until the compression of the blockchain changes.
+func (bc *Blockchain) RecompressBlocks(tx *dbutil.Tx, start []byte, limit uint64) (uint64, []byte, error) {
+	if limit == 0 {
+		return 0, nil, errors.New("RecompressBlocks limit must be > 0")
+	}
+
+	if start == nil {
+		v, err := dbutil.GetBucketValue(tx, BlockchainMetaBkt, blockCompressionKey)
+		if err != nil {
+			return 0, nil, err
+		}
+		if len(v) == 1 && BlockCompression(v[0]) == bc.compression {
+			return 0, nil, nil
+		}
+	}
+
+	var blocks []coin.Block
+	var visited uint64
+	var next []byte
+
+	if err := dbutil.ForEachPrefix(tx, BlocksBkt, nil, start, func(k, v []byte) (bool, error) {
+		if visited == limit {
+			next = append([]byte{}, k...)
+			return false, nil
+		}
+		visited++
+
+		c, err := blockRecordCompression(v)
+		if err != nil {
+			return false, err
+		}
+
+		if c == bc.compression {
+			return true, nil
+		}
+
+		var b coin.Block
+		if err := decodeBlockRecord(v, &b); err != nil {
+			return false, err
+		}
+
+		// Skip the blocks that are stored uncompressed because compressing them does not make them smaller
+		enc, err := encodeBlockRecord(&b, bc.compression)
+		if err != nil {
+			return false, err
+		}
+		if encC, err := blockRecordCompression(enc); err != nil {
+			return false, err
+		} else if encC == c {
+			return true, nil
+		}
+
+		blocks = append(blocks, b)
+		return true, nil
+	}); err != nil {
+		return 0, nil, err
+	}
+
+	// The bucket must not be modified while a cursor iterates it, so the records are written afterwards
+	for i := range blocks {
+		if err := putBlockRecord(tx, bc.cache, &blocks[i], bc.compression); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	if next == nil {
+		if err := dbutil.PutBucketValue(tx, BlockchainMetaBkt, blockCompressionKey, []byte{byte(bc.compression)}); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	return uint64(len(blocks)), next, nil
+}
@@ -0,0 +1,276 @@
+package blockdb
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/encoder"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+func makeBlockRecordTestBlock(t *testing.T, version uint32, ntxns int) coin.Block {
+	b := coin.Block{
+		Head: coin.BlockHeader{
+			Version:  version,
+			BkSeq:    3,
+			Time:     1000,
+			PrevHash: testutil.RandSHA256(t),
+			UxHash:   testutil.RandSHA256(t),
+		},
+	}
+
+	addr := testutil.MakeAddress()
+	for i := 0; i < ntxns; i++ {
+		txn := coin.Transaction{
+			In: []cipher.SHA256{testutil.RandSHA256(t)},
+		}
+		// Outputs to the same address compress well, like the change outputs of real transactions
+		for j := 0; j < 10; j++ {
+			txn.Out = append(txn.Out, coin.TransactionOutput{
+				Address: addr,
+				Coins:   1e6,
+				Hours:   100,
+			})
+		}
+		b.Body.Transactions = append(b.Body.Transactions, txn)
+	}
+
+	b.Head.BodyHash = b.HashBody()
+	return b
+}
+
+func TestEncodeDecodeBlockRecord(t *testing.T) {
+	legacy := makeBlockRecordTestBlock(t, 0, 5)
+	markerVersion := makeBlockRecordTestBlock(t, math.MaxUint32, 5)
+	header := makeBlockRecordTestBlock(t, 0, 0)
+
+	tt := []struct {
+		name        string
+		block       coin.Block
+		compression BlockCompression
+		stored      BlockCompression
+		legacy      bool
+	}{
+		{
+			name:        "none",
+			block:       legacy,
+			compression: BlockCompressionNone,
+			stored:      BlockCompressionNone,
+			legacy:      true,
+		},
+		{
+			name:        "snappy",
+			block:       legacy,
+			compression: BlockCompressionSnappy,
+			stored:      BlockCompressionSnappy,
+		},
+		{
+			name:        "snappy, header only",
+			block:       header,
+			compression: BlockCompressionSnappy,
+			stored:      BlockCompressionSnappy,
+		},
+		{
+			name:        "none, encoding starts with the record marker",
+			block:       markerVersion,
+			compression: BlockCompressionNone,
+			stored:      BlockCompressionNone,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			v, err := encodeBlockRecord(&tc.block, tc.compression)
+			require.NoError(t, err)
+
+			c, err := blockRecordCompression(v)
+			require.NoError(t, err)
+			require.Equal(t, tc.stored, c)
+
+			// Uncompressed records are stored like the blocks written before block compression was added
+			if tc.legacy {
+				require.Equal(t, encoder.Serialize(tc.block), v)
+			}
+
+			var b coin.Block
+			err = decodeBlockRecord(v, &b)
+			require.NoError(t, err)
+			require.Equal(t, tc.block, b)
+		})
+	}
+
+	_, err := encodeBlockRecord(&legacy, BlockCompression(9))
+	require.Equal(t, ErrUnknownBlockCompression, err)
+
+	var b coin.Block
+	err = decodeBlockRecord(append(append([]byte{}, blockRecordMarker...), 9), &b)
+	require.Equal(t, ErrUnknownBlockCompression, err)
+}
+
+func TestParseBlockCompression(t *testing.T) {
+	for s, c := range map[string]BlockCompression{
+		"":       BlockCompressionNone,
+		"none":   BlockCompressionNone,
+		"snappy": BlockCompressionSnappy,
+		"Snappy": BlockCompressionSnappy,
+	} {
+		v, err := ParseBlockCompression(s)
+		require.NoError(t, err)
+		require.Equal(t, c, v)
+
+		v, err = ParseBlockCompression(c.String())
+		require.NoError(t, err)
+		require.Equal(t, c, v)
+	}
+
+	_, err := ParseBlockCompression("zstd")
+	require.Error(t, err)
+}
+
+// readBlockchain180 returns the signed blocks of the test database
+func readBlockchain180(t *testing.T, bc *Blockchain, db *dbutil.DB) []coin.SignedBlock {
+	var blocks []coin.SignedBlock
+	err := db.View("", func(tx *dbutil.Tx) error {
+		headSeq, ok, err := bc.HeadSeq(tx)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		for seq := uint64(0); seq <= headSeq; seq++ {
+			b, err := bc.GetSignedBlockBySeq(tx, seq)
+			require.NoError(t, err)
+			require.NotNil(t, b)
+			blocks = append(blocks, *b)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	return blocks
+}
+
+func TestRecompressBlocks(t *testing.T) {
+	db, shutdown := setupNoUnspentAddrIndexDB(t)
+	defer shutdown()
+
+	bc, err := NewBlockchain(db, func(tx *dbutil.Tx, hps []coin.HashPair) (cipher.SHA256, bool) {
+		return hps[0].Hash, true
+	})
+	require.NoError(t, err)
+
+	blocks := readBlockchain180(t, bc, db)
+
+	blockCompressionStats := func() *BlockCompressionStats {
+		var stats *BlockCompressionStats
+		err := db.View("", func(tx *dbutil.Tx) error {
+			var err error
+			stats, err = bc.BlockCompressionStats(tx)
+			return err
+		})
+		require.NoError(t, err)
+		return stats
+	}
+
+	// recompress rewrites the block records in batches and returns the number of records rewritten
+	recompress := func(limit uint64) uint64 {
+		var start []byte
+		var total uint64
+		for {
+			err := db.Update("", func(tx *dbutil.Tx) error {
+				n, next, err := bc.RecompressBlocks(tx, start, limit)
+				total += n
+				start = next
+				return err
+			})
+			require.NoError(t, err)
+
+			if start == nil {
+				return total
+			}
+		}
+	}
+
+	before := blockCompressionStats()
+	require.Equal(t, uint64(len(blocks)), before.Records[BlockCompressionNone])
+	require.Equal(t, uint64(0), before.Records[BlockCompressionSnappy])
+
+	require.NoError(t, bc.SetBlockCompression(BlockCompressionSnappy))
+	n := recompress(7)
+	require.NotEqual(t, uint64(0), n)
+
+	after := blockCompressionStats()
+	require.Equal(t, n, after.Records[BlockCompressionSnappy])
+	require.Equal(t, uint64(len(blocks))-n, after.Records[BlockCompressionNone])
+	require.True(t, after.Bytes[BlockCompressionNone]+after.Bytes[BlockCompressionSnappy] < before.Bytes[BlockCompressionNone])
+
+	// The compressed blocks are read transparently
+	require.Equal(t, blocks, readBlockchain180(t, bc, db))
+
+	// Once every record was rewritten, the records are not visited again
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		n, next, err := bc.RecompressBlocks(tx, nil, 1)
+		require.NoError(t, err)
+		require.Equal(t, uint64(0), n)
+		require.Nil(t, next)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// Disabling compression restores the original records
+	require.NoError(t, bc.SetBlockCompression(BlockCompressionNone))
+	require.Equal(t, n, recompress(1000))
+	require.Equal(t, before, blockCompressionStats())
+	require.Equal(t, blocks, readBlockchain180(t, bc, db))
+
+	require.Equal(t, ErrUnknownBlockCompression, bc.SetBlockCompression(BlockCompression(9)))
+}
+
+// BenchmarkBlockCompression compresses the blocks of the test database, which are the first blocks of the mainnet
+// blockchain, and logs the disk space saved
+func BenchmarkBlockCompression(b *testing.B) {
+	var t testing.T
+	db, shutdown := setupNoUnspentAddrIndexDB(&t)
+	defer shutdown()
+
+	var records [][]byte
+	if err := db.View("", func(tx *dbutil.Tx) error {
+		return dbutil.ForEach(tx, BlocksBkt, func(_, v []byte) error {
+			records = append(records, append([]byte{}, v...))
+			return nil
+		})
+	}); err != nil {
+		b.Fatal(err)
+	}
+
+	var rawSize int64
+	for _, v := range records {
+		rawSize += int64(len(v))
+	}
+
+	for _, c := range []BlockCompression{BlockCompressionNone, BlockCompressionSnappy} {
+		b.Run(c.String(), func(b *testing.B) {
+			var size int64
+			b.SetBytes(rawSize)
+			for i := 0; i < b.N; i++ {
+				size = 0
+				for _, v := range records {
+					var block coin.Block
+					if err := decodeBlockRecord(v, &block); err != nil {
+						b.Fatal(err)
+					}
+
+					enc, err := encodeBlockRecord(&block, c)
+					if err != nil {
+						b.Fatal(err)
+					}
+					size += int64(len(enc))
+				}
+			}
+
+			b.Logf("%d blocks: %d bytes raw, %d bytes %s, %.1f%% saved", len(records), rawSize, size, c, 100*float64(rawSize-size)/float64(rawSize))
+		})
+	}
+}
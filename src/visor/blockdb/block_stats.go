@@ -0,0 +1,194 @@
+package blockdb
+
+import (
+	"fmt"
+
+	"github.com/skycoin/skycoin/src/cipher/encoder"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+// BlockStatsBkt maps block seqs to the aggregate stats of the block
+var BlockStatsBkt = []byte("block_stats")
+
+// BlockStats are the aggregate stats of a block
+type BlockStats struct {
+	Seq  uint64
+	Time uint64
+	// Size of the block transactions, in bytes
+	Size uint32
+	// Fee is the coinhour fee total of the block transactions
+	Fee      uint64
+	TxnCount uint32
+	// OutputVolume is the total coins of the outputs created by the block, in droplets
+	OutputVolume uint64
+}
+
+// NewBlockStats computes the aggregate stats of a block
+func NewBlockStats(b *coin.Block) (*BlockStats, error) {
+	size, err := b.Size()
+	if err != nil {
+		return nil, err
+	}
+
+	var volume uint64
+	for _, txn := range b.Body.Transactions {
+		for _, o := range txn.Out {
+			volume, err = coin.AddUint64(volume, o.Coins)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return &BlockStats{
+		Seq:          b.Seq(),
+		Time:         b.Time(),
+		Size:         size,
+		Fee:          b.Head.Fee,
+		TxnCount:     uint32(len(b.Body.Transactions)),
+		OutputVolume: volume,
+	}, nil
+}
+
+// blockStats bucket stores the aggregate stats of each block,
+// block seq as key, BlockStats as value.
+// The stats of blocks pruned before the stats were added to the database are not stored.
+type blockStats struct{}
+
+// put saves the stats of the block
+func (bs *blockStats) put(tx *dbutil.Tx, b *coin.Block) error {
+	s, err := NewBlockStats(b)
+	if err != nil {
+		return err
+	}
+
+	return dbutil.PutBucketValue(tx, BlockStatsBkt, dbutil.Itob(s.Seq), encoder.Serialize(*s))
+}
+
+// get returns the stats of the block of seq, nil if they are not stored
+func (bs *blockStats) get(tx *dbutil.Tx, seq uint64) (*BlockStats, error) {
+	// A database opened read-only can predate the bucket
+	if !dbutil.Exists(tx, BlockStatsBkt) {
+		return nil, nil
+	}
+
+	var s BlockStats
+	if ok, err := dbutil.GetBucketObjectDecoded(tx, BlockStatsBkt, dbutil.Itob(seq), &s); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, nil
+	}
+
+	return &s, nil
+}
+
+// delete removes the stats of the block of seq
+func (bs *blockStats) delete(tx *dbutil.Tx, seq uint64) error {
+	return dbutil.Delete(tx, BlockStatsBkt, dbutil.Itob(seq))
+}
+
+// isEmpty checks if the block stats bucket is empty
+func (bs *blockStats) isEmpty(tx *dbutil.Tx) (bool, error) {
+	return dbutil.IsEmpty(tx, BlockStatsBkt)
+}
+
+// GetBlockStats returns the stats of the block of seq, nil if the block does not exist
+// or was pruned before the stats were added to the database
+func (bc *Blockchain) GetBlockStats(tx *dbutil.Tx, seq uint64) (*BlockStats, error) {
+	return bc.stats.get(tx, seq)
+}
+
+// GetBlockStatsInRange returns the stats of the blocks in the seq range [start, end], in seq order.
+// Blocks whose stats are not stored are skipped.
+func (bc *Blockchain) GetBlockStatsInRange(tx *dbutil.Tx, start, end uint64) ([]BlockStats, error) {
+	if start > end || !dbutil.Exists(tx, BlockStatsBkt) {
+		return nil, nil
+	}
+
+	var stats []BlockStats
+	if err := dbutil.ForEachPrefix(tx, BlockStatsBkt, nil, dbutil.Itob(start), func(k, v []byte) (bool, error) {
+		if dbutil.Btoi(k) > end {
+			return false, nil
+		}
+
+		var s BlockStats
+		if err := encoder.DeserializeRaw(v, &s); err != nil {
+			return false, err
+		}
+
+		stats = append(stats, s)
+		return true, nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// MaybeBuildBlockStats stores the stats of the blocks executed before the stats were added to the database
+func (bc *Blockchain) MaybeBuildBlockStats(tx *dbutil.Tx) error {
+	if _, ok, err := bc.meta.GetHeadSeq(tx); err != nil {
+		return err
+	} else if !ok {
+		return nil
+	}
+
+	empty, err := bc.stats.isEmpty(tx)
+	if err != nil {
+		return err
+	} else if !empty {
+		return nil
+	}
+
+	_, err = bc.RebuildBlockStats(tx)
+	return err
+}
+
+// RebuildBlockStats computes the stats of every block again and stores them.
+// The bodies of pruned blocks were removed, so their stored stats are kept.
+// Returns the number of blocks whose stats were computed.
+func (bc *Blockchain) RebuildBlockStats(tx *dbutil.Tx) (uint64, error) {
+	headSeq, ok, err := bc.meta.GetHeadSeq(tx)
+	if err != nil {
+		return 0, err
+	} else if !ok {
+		return 0, nil
+	}
+
+	prunedSeq, err := bc.meta.GetPrunedSeq(tx)
+	if err != nil {
+		return 0, err
+	}
+
+	logger.Infof("Building block stats of %d blocks", headSeq-prunedSeq+1)
+
+	// The genesis block is never pruned
+	seqs := []uint64{0}
+	for seq := prunedSeq + 1; seq <= headSeq; seq++ {
+		seqs = append(seqs, seq)
+	}
+
+	var n uint64
+	for _, seq := range seqs {
+		if err := tx.Context().Err(); err != nil {
+			return n, err
+		}
+
+		b, err := bc.tree.GetBlockInDepth(tx, seq, bc.walker)
+		if err != nil {
+			return n, err
+		}
+		if b == nil {
+			return n, fmt.Errorf("block of seq %d does not exist", seq)
+		}
+
+		if err := bc.stats.put(tx, b); err != nil {
+			return n, err
+		}
+
+		n++
+	}
+
+	return n, nil
+}
@@ -0,0 +1,194 @@
+package blockdb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+func TestNewBlockStats(t *testing.T) {
+	b := coin.Block{
+		Head: coin.BlockHeader{
+			BkSeq: 3,
+			Time:  1000,
+			Fee:   25,
+		},
+		Body: coin.BlockBody{
+			Transactions: coin.Transactions{
+				{
+					Out: []coin.TransactionOutput{{Coins: 1e6, Hours: 10}, {Coins: 2e6, Hours: 5}},
+				},
+				{
+					Out: []coin.TransactionOutput{{Coins: 3e6}},
+				},
+			},
+		},
+	}
+
+	size, err := b.Size()
+	require.NoError(t, err)
+
+	s, err := NewBlockStats(&b)
+	require.NoError(t, err)
+	require.Equal(t, BlockStats{
+		Seq:          3,
+		Time:         1000,
+		Size:         size,
+		Fee:          25,
+		TxnCount:     2,
+		OutputVolume: 6e6,
+	}, *s)
+
+	b.Body.Transactions[1].Out[0].Coins = ^uint64(0)
+	_, err = NewBlockStats(&b)
+	require.Equal(t, coin.ErrUint64AddOverflow, err)
+}
+
+func TestBlockchainBlockStats(t *testing.T) {
+	srcDB, shutdownSrc := setupNoUnspentAddrIndexDB(t)
+	defer shutdownSrc()
+
+	walker := func(tx *dbutil.Tx, hps []coin.HashPair) (cipher.SHA256, bool) {
+		return hps[0].Hash, true
+	}
+
+	srcBC, err := NewBlockchain(srcDB, walker)
+	require.NoError(t, err)
+	blocks := readBlockchain180(t, srcBC, srcDB)
+
+	db, shutdown := prepareDB(t)
+	defer shutdown()
+
+	bc, err := NewBlockchain(db, walker)
+	require.NoError(t, err)
+
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		for i := range blocks {
+			require.NoError(t, bc.AddBlock(tx, &blocks[i]))
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	headSeq := uint64(len(blocks) - 1)
+
+	expected := make([]BlockStats, len(blocks))
+	for i := range blocks {
+		s, err := NewBlockStats(&blocks[i].Block)
+		require.NoError(t, err)
+		expected[i] = *s
+	}
+
+	err = db.View("", func(tx *dbutil.Tx) error {
+		for i := range blocks {
+			s, err := bc.GetBlockStats(tx, uint64(i))
+			require.NoError(t, err)
+			require.Equal(t, &expected[i], s)
+		}
+
+		s, err := bc.GetBlockStats(tx, headSeq+1)
+		require.NoError(t, err)
+		require.Nil(t, s)
+
+		stats, err := bc.GetBlockStatsInRange(tx, 10, 19)
+		require.NoError(t, err)
+		require.Equal(t, expected[10:20], stats)
+
+		stats, err = bc.GetBlockStatsInRange(tx, headSeq-1, headSeq+10)
+		require.NoError(t, err)
+		require.Equal(t, expected[headSeq-1:], stats)
+
+		stats, err = bc.GetBlockStatsInRange(tx, 20, 10)
+		require.NoError(t, err)
+		require.Empty(t, stats)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// Removing the head block removes its stats
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		_, err := bc.RemoveHead(tx)
+		require.NoError(t, err)
+
+		s, err := bc.GetBlockStats(tx, headSeq)
+		require.NoError(t, err)
+		require.Nil(t, s)
+
+		require.NoError(t, bc.AddBlock(tx, &blocks[headSeq]))
+		return nil
+	})
+	require.NoError(t, err)
+
+	// The stats of pruned blocks are kept when rebuilding the stats
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		n, err := bc.Prune(tx, 100, 0)
+		require.NoError(t, err)
+		require.Equal(t, headSeq-100, n)
+
+		require.NoError(t, dbutil.Reset(tx, BlockStatsBkt))
+		require.NoError(t, bc.stats.put(tx, &blocks[1].Block))
+
+		n, err = bc.RebuildBlockStats(tx)
+		require.NoError(t, err)
+		require.Equal(t, uint64(101), n)
+
+		stats, err := bc.GetBlockStatsInRange(tx, 0, headSeq)
+		require.NoError(t, err)
+		require.Equal(t, append(expected[:2:2], expected[headSeq-99:]...), stats)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestBlockchainMaybeBuildBlockStats(t *testing.T) {
+	db, shutdown := setupNoUnspentAddrIndexDB(t)
+	defer shutdown()
+
+	walker := func(tx *dbutil.Tx, hps []coin.HashPair) (cipher.SHA256, bool) {
+		return hps[0].Hash, true
+	}
+
+	bc, err := NewBlockchain(db, walker)
+	require.NoError(t, err)
+	blocks := readBlockchain180(t, bc, db)
+
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		if !dbutil.Exists(tx, BlockStatsBkt) {
+			require.NoError(t, dbutil.CreateBuckets(tx, [][]byte{BlockStatsBkt}))
+		}
+
+		require.NoError(t, bc.MaybeBuildBlockStats(tx))
+
+		stats, err := bc.GetBlockStatsInRange(tx, 0, uint64(len(blocks)))
+		require.NoError(t, err)
+		require.Len(t, stats, len(blocks))
+		for i, s := range stats {
+			expected, err := NewBlockStats(&blocks[i].Block)
+			require.NoError(t, err)
+			require.Equal(t, *expected, s)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	// The stats are not built again once stored
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		require.NoError(t, bc.stats.delete(tx, 5))
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		require.NoError(t, bc.MaybeBuildBlockStats(tx))
+
+		s, err := bc.GetBlockStats(tx, 5)
+		require.NoError(t, err)
+		require.Nil(t, s)
+		return nil
+	})
+	require.NoError(t, err)
+}
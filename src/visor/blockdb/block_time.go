@@ -0,0 +1,88 @@
+package blockdb
+
+import (
+	"fmt"
+
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+// BlockTimesBkt maps block times to block seqs
+var BlockTimesBkt = []byte("block_times")
+
+// blockTimes bucket indexes the blocks by time, block time as key, block seq as value.
+// Block times are strictly increasing with the block seq, so the keys are ordered like the blocks.
+type blockTimes struct{}
+
+// put saves the seq of the block of time t
+func (bt *blockTimes) put(tx *dbutil.Tx, t, seq uint64) error {
+	return dbutil.PutBucketValue(tx, BlockTimesBkt, dbutil.Itob(t), dbutil.Itob(seq))
+}
+
+// delete removes the block of time t
+func (bt *blockTimes) delete(tx *dbutil.Tx, t uint64) error {
+	return dbutil.Delete(tx, BlockTimesBkt, dbutil.Itob(t))
+}
+
+// getAtOrBefore returns the seq of the last block whose time is <= t, false if there is none
+func (bt *blockTimes) getAtOrBefore(tx *dbutil.Tx, t uint64) (uint64, bool, error) {
+	// A database opened read-only can predate the bucket
+	if !dbutil.Exists(tx, BlockTimesBkt) {
+		return 0, false, nil
+	}
+
+	k, v, err := dbutil.SeekPrev(tx, BlockTimesBkt, dbutil.Itob(t))
+	if err != nil {
+		return 0, false, err
+	} else if k == nil {
+		return 0, false, nil
+	}
+
+	return dbutil.Btoi(v), true, nil
+}
+
+// isEmpty checks if the block times bucket is empty
+func (bt *blockTimes) isEmpty(tx *dbutil.Tx) (bool, error) {
+	return dbutil.IsEmpty(tx, BlockTimesBkt)
+}
+
+// GetBlockSeqByTime returns the seq of the last block whose time is at or before t,
+// false if there is no such block or the database predates the block time index
+func (bc *Blockchain) GetBlockSeqByTime(tx *dbutil.Tx, t uint64) (uint64, bool, error) {
+	return bc.times.getAtOrBefore(tx, t)
+}
+
+// MaybeBuildBlockTimes indexes the times of the blocks executed before the index was added to the database.
+// The headers of pruned blocks are kept, so every block is indexed.
+func (bc *Blockchain) MaybeBuildBlockTimes(tx *dbutil.Tx) error {
+	headSeq, ok, err := bc.meta.GetHeadSeq(tx)
+	if err != nil {
+		return err
+	} else if !ok {
+		return nil
+	}
+
+	empty, err := bc.times.isEmpty(tx)
+	if err != nil {
+		return err
+	} else if !empty {
+		return nil
+	}
+
+	logger.Infof("Building block time index of %d blocks", headSeq+1)
+
+	for seq := uint64(0); seq <= headSeq; seq++ {
+		b, err := bc.tree.GetBlockInDepth(tx, seq, bc.walker)
+		if err != nil {
+			return err
+		}
+		if b == nil {
+			return fmt.Errorf("block of seq %d does not exist", seq)
+		}
+
+		if err := bc.times.put(tx, b.Time(), seq); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
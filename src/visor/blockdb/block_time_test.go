@@ -0,0 +1,107 @@
+package blockdb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+func TestBlockchainGetBlockSeqByTime(t *testing.T) {
+	srcDB, shutdownSrc := setupNoUnspentAddrIndexDB(t)
+	defer shutdownSrc()
+
+	walker := func(tx *dbutil.Tx, hps []coin.HashPair) (cipher.SHA256, bool) {
+		return hps[0].Hash, true
+	}
+
+	srcBC, err := NewBlockchain(srcDB, walker)
+	require.NoError(t, err)
+	blocks := readBlockchain180(t, srcBC, srcDB)
+	headSeq := uint64(len(blocks) - 1)
+
+	db, shutdown := prepareDB(t)
+	defer shutdown()
+
+	bc, err := NewBlockchain(db, walker)
+	require.NoError(t, err)
+
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		// No blocks
+		_, ok, err := bc.GetBlockSeqByTime(tx, blocks[0].Time())
+		require.NoError(t, err)
+		require.False(t, ok)
+
+		for i := range blocks {
+			require.NoError(t, bc.AddBlock(tx, &blocks[i]))
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	requireSeqByTime := func(tx *dbutil.Tx, time uint64, seq uint64, found bool) {
+		s, ok, err := bc.GetBlockSeqByTime(tx, time)
+		require.NoError(t, err)
+		require.Equal(t, found, ok, "time %d", time)
+		require.Equal(t, seq, s, "time %d", time)
+	}
+
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		// Before the genesis block
+		requireSeqByTime(tx, blocks[0].Time()-1, 0, false)
+
+		for i, b := range blocks {
+			requireSeqByTime(tx, b.Time(), b.Seq(), true)
+			if i < len(blocks)-1 {
+				requireSeqByTime(tx, blocks[i+1].Time()-1, b.Seq(), true)
+			}
+		}
+
+		// After the head block
+		requireSeqByTime(tx, blocks[headSeq].Time()+1000, headSeq, true)
+
+		// Removing the head block removes it from the index
+		_, err := bc.RemoveHead(tx)
+		require.NoError(t, err)
+		requireSeqByTime(tx, blocks[headSeq].Time(), headSeq-1, true)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestBlockchainMaybeBuildBlockTimes(t *testing.T) {
+	db, shutdown := setupNoUnspentAddrIndexDB(t)
+	defer shutdown()
+
+	walker := func(tx *dbutil.Tx, hps []coin.HashPair) (cipher.SHA256, bool) {
+		return hps[0].Hash, true
+	}
+
+	bc, err := NewBlockchain(db, walker)
+	require.NoError(t, err)
+	blocks := readBlockchain180(t, bc, db)
+
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		if !dbutil.Exists(tx, BlockTimesBkt) {
+			require.NoError(t, dbutil.CreateBuckets(tx, [][]byte{BlockTimesBkt}))
+		}
+
+		// The headers of pruned blocks are indexed too
+		_, err := bc.Prune(tx, 100, 0)
+		require.NoError(t, err)
+
+		require.NoError(t, bc.MaybeBuildBlockTimes(tx))
+
+		for _, b := range blocks {
+			seq, ok, err := bc.GetBlockSeqByTime(tx, b.Time())
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, b.Seq(), seq)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+}
@@ -27,7 +27,12 @@ var (
 type Walker func(*dbutil.Tx, []coin.HashPair) (cipher.SHA256, bool)
 
 // blockTree use the blockdb store all blocks and maintains the block tree struct.
-type blockTree struct{}
+type blockTree struct {
+	// compression of the block records written by AddBlock
+	compression BlockCompression
+	// cache of the block records and tree entries, nil if disabled
+	cache *blockCache
+}
 
 // AddBlock adds block with *dbutil.Tx
 func (bt *blockTree) AddBlock(tx *dbutil.Tx, b *coin.Block) error {
@@ -45,14 +50,14 @@ func (bt *blockTree) AddBlock(tx *dbutil.Tx, b *coin.Block) error {
 	}
 
 	// write block into blocks bucket.
-	if err := dbutil.PutBucketValue(tx, BlocksBkt, hash[:], encoder.Serialize(b)); err != nil {
+	if err := putBlockRecord(tx, bt.cache, b, bt.compression); err != nil {
 		return err
 	}
 
 	// the pre hash must be in depth - 1.
 	if b.Seq() > 0 {
 		preHash := b.PreHashHeader()
-		parentHashPair, err := getHashPairInDepth(tx, b.Seq()-1, func(hp coin.HashPair) bool {
+		parentHashPair, err := getHashPairInDepth(tx, bt.cache, b.Seq()-1, func(hp coin.HashPair) bool {
 			return hp.Hash == preHash
 		})
 		if err != nil {
@@ -69,7 +74,7 @@ func (bt *blockTree) AddBlock(tx *dbutil.Tx, b *coin.Block) error {
 	}
 
 	// get block pairs in the depth
-	hashPairs, err := getHashPairInDepth(tx, b.Seq(), allPairs)
+	hashPairs, err := getHashPairInDepth(tx, bt.cache, b.Seq(), allPairs)
 	if err != nil {
 		return err
 	}
@@ -77,7 +82,7 @@ func (bt *blockTree) AddBlock(tx *dbutil.Tx, b *coin.Block) error {
 	if len(hashPairs) == 0 {
 		// no hash pair exist in the depth.
 		// write the hash pair into tree.
-		return setHashPairInDepth(tx, b.Seq(), []coin.HashPair{hp})
+		return setHashPairInDepth(tx, bt.cache, b.Seq(), []coin.HashPair{hp})
 	}
 
 	// check dup block
@@ -86,7 +91,7 @@ func (bt *blockTree) AddBlock(tx *dbutil.Tx, b *coin.Block) error {
 	}
 
 	hashPairs = append(hashPairs, hp)
-	return setHashPairInDepth(tx, b.Seq(), hashPairs)
+	return setHashPairInDepth(tx, bt.cache, b.Seq(), hashPairs)
 }
 
 // RemoveBlock remove block from blocks bucket and tree bucket.
@@ -94,19 +99,19 @@ func (bt *blockTree) AddBlock(tx *dbutil.Tx, b *coin.Block) error {
 func (bt *blockTree) RemoveBlock(tx *dbutil.Tx, b *coin.Block) error {
 	// delete block in blocks bucket.
 	hash := b.HashHeader()
-	if err := dbutil.Delete(tx, BlocksBkt, hash[:]); err != nil {
+	if err := bt.cache.delete(tx, BlocksBkt, hash[:]); err != nil {
 		return err
 	}
 
 	// check if this block has children
-	if has, err := hasChild(tx, *b); err != nil {
+	if has, err := hasChild(tx, bt.cache, *b); err != nil {
 		return err
 	} else if has {
 		return errHasChild
 	}
 
 	// get block hash pairs in depth
-	hashPairs, err := getHashPairInDepth(tx, b.Seq(), allPairs)
+	hashPairs, err := getHashPairInDepth(tx, bt.cache, b.Seq(), allPairs)
 	if err != nil {
 		return err
 	}
@@ -118,23 +123,27 @@ func (bt *blockTree) RemoveBlock(tx *dbutil.Tx, b *coin.Block) error {
 	})
 
 	if len(ps) == 0 {
-		return dbutil.Delete(tx, TreeBkt, dbutil.Itob(b.Seq()))
+		return bt.cache.delete(tx, TreeBkt, dbutil.Itob(b.Seq()))
 	}
 
 	// update the hash pairs in tree.
-	return setHashPairInDepth(tx, b.Seq(), ps)
+	return setHashPairInDepth(tx, bt.cache, b.Seq(), ps)
 }
 
 // GetBlock get block by hash, return nil on not found
 func (bt *blockTree) GetBlock(tx *dbutil.Tx, hash cipher.SHA256) (*coin.Block, error) {
-	var b coin.Block
-
-	if ok, err := dbutil.GetBucketObjectDecoded(tx, BlocksBkt, hash[:], &b); err != nil {
+	v, err := bt.cache.get(tx, BlocksBkt, hash[:])
+	if err != nil {
 		return nil, err
-	} else if !ok {
+	} else if v == nil {
 		return nil, nil
 	}
 
+	var b coin.Block
+	if err := decodeBlockRecord(v, &b); err != nil {
+		return nil, fmt.Errorf("decode block %s failed: %v", hash.Hex(), err)
+	}
+
 	if hash != b.HashHeader() {
 		return nil, fmt.Errorf("DB key %s does not match block hash header %s", hash, b.HashHeader())
 	}
@@ -159,7 +168,7 @@ func (bt *blockTree) GetBlockInDepth(tx *dbutil.Tx, depth uint64, filter Walker)
 func (bt *blockTree) ForEachBlock(tx *dbutil.Tx, f func(b *coin.Block) error) error {
 	return dbutil.ForEach(tx, BlocksBkt, func(_, v []byte) error {
 		var b coin.Block
-		if err := encoder.DeserializeRaw(v, &b); err != nil {
+		if err := decodeBlockRecord(v, &b); err != nil {
 			return err
 		}
 
@@ -168,8 +177,8 @@ func (bt *blockTree) ForEachBlock(tx *dbutil.Tx, f func(b *coin.Block) error) er
 }
 
 func (bt *blockTree) getHashInDepth(tx *dbutil.Tx, depth uint64, filter Walker) (cipher.SHA256, bool, error) {
-	var pairs []coin.HashPair
-	if ok, err := dbutil.GetBucketObjectDecoded(tx, TreeBkt, dbutil.Itob(depth), &pairs); err != nil {
+	pairs, ok, err := getHashPairs(tx, bt.cache, depth)
+	if err != nil {
 		return cipher.SHA256{}, false, err
 	} else if !ok {
 		return cipher.SHA256{}, false, nil
@@ -203,9 +212,26 @@ func removePairs(hps []coin.HashPair, pair coin.HashPair) []coin.HashPair {
 	return pairs
 }
 
-func getHashPairInDepth(tx *dbutil.Tx, dep uint64, fn func(hp coin.HashPair) bool) ([]coin.HashPair, error) {
+// getHashPairs returns the hash pairs of the blocks in a depth of the tree, read through the cache
+func getHashPairs(tx *dbutil.Tx, cache *blockCache, dep uint64) ([]coin.HashPair, bool, error) {
+	v, err := cache.get(tx, TreeBkt, dbutil.Itob(dep))
+	if err != nil {
+		return nil, false, err
+	} else if v == nil {
+		return nil, false, nil
+	}
+
 	var hps []coin.HashPair
-	if ok, err := dbutil.GetBucketObjectDecoded(tx, TreeBkt, dbutil.Itob(dep), &hps); err != nil {
+	if err := encoder.DeserializeRaw(v, &hps); err != nil {
+		return nil, false, fmt.Errorf("encoder.DeserializeRaw failed: %v", err)
+	}
+
+	return hps, true, nil
+}
+
+func getHashPairInDepth(tx *dbutil.Tx, cache *blockCache, dep uint64, fn func(hp coin.HashPair) bool) ([]coin.HashPair, error) {
+	hps, ok, err := getHashPairs(tx, cache, dep)
+	if err != nil {
 		return nil, err
 	} else if !ok {
 		return nil, nil
@@ -221,9 +247,9 @@ func getHashPairInDepth(tx *dbutil.Tx, dep uint64, fn func(hp coin.HashPair) boo
 }
 
 // check if this block has children
-func hasChild(tx *dbutil.Tx, b coin.Block) (bool, error) {
+func hasChild(tx *dbutil.Tx, cache *blockCache, b coin.Block) (bool, error) {
 	// get the child block hash pair, whose pre hash point to current block.
-	childHashPair, err := getHashPairInDepth(tx, b.Head.BkSeq+1, func(hp coin.HashPair) bool {
+	childHashPair, err := getHashPairInDepth(tx, cache, b.Head.BkSeq+1, func(hp coin.HashPair) bool {
 		return hp.PreHash == b.HashHeader()
 	})
 
@@ -234,8 +260,8 @@ func hasChild(tx *dbutil.Tx, b coin.Block) (bool, error) {
 	return len(childHashPair) > 0, nil
 }
 
-func setHashPairInDepth(tx *dbutil.Tx, dep uint64, hps []coin.HashPair) error {
-	return dbutil.PutBucketValue(tx, TreeBkt, dbutil.Itob(dep), encoder.Serialize(hps))
+func setHashPairInDepth(tx *dbutil.Tx, cache *blockCache, dep uint64, hps []coin.HashPair) error {
+	return cache.putBucketValue(tx, TreeBkt, dbutil.Itob(dep), encoder.Serialize(hps))
 }
 
 func allPairs(hp coin.HashPair) bool {
@@ -8,6 +8,7 @@ import (
 	"fmt"
 
 	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/encoder"
 	"github.com/skycoin/skycoin/src/coin"
 	"github.com/skycoin/skycoin/src/util/logging"
 	"github.com/skycoin/skycoin/src/visor/dbutil"
@@ -36,6 +37,11 @@ func (e ErrMissingSignature) Error() string {
 	return fmt.Sprintf("Signature not found for block seq=%d hash=%s", e.b.Head.BkSeq, e.b.HashHeader().Hex())
 }
 
+// Seq returns the seq of the block whose signature is missing
+func (e ErrMissingSignature) Seq() uint64 {
+	return e.b.Head.BkSeq
+}
+
 // CreateBuckets creates bolt.DB buckets used by the blockdb
 func CreateBuckets(tx *dbutil.Tx) error {
 	return dbutil.CreateBuckets(tx, [][]byte{
@@ -46,6 +52,10 @@ func CreateBuckets(tx *dbutil.Tx) error {
 		UnspentPoolBkt,
 		UnspentPoolAddrIndexBkt,
 		UnspentMetaBkt,
+		UnspentCommitmentsBkt,
+		BlockStatsBkt,
+		BlockTimesBkt,
+		UnspentReversalsBkt,
 	})
 }
 
@@ -55,6 +65,7 @@ type BlockTree interface {
 	GetBlock(*dbutil.Tx, cipher.SHA256) (*coin.Block, error)
 	GetBlockInDepth(*dbutil.Tx, uint64, Walker) (*coin.Block, error)
 	ForEachBlock(*dbutil.Tx, func(*coin.Block) error) error
+	RemoveBlock(*dbutil.Tx, *coin.Block) error
 }
 
 // BlockSigs block signature storage
@@ -62,6 +73,7 @@ type BlockSigs interface {
 	Add(*dbutil.Tx, cipher.SHA256, cipher.Sig) error
 	Get(*dbutil.Tx, cipher.SHA256) (cipher.Sig, bool, error)
 	ForEach(*dbutil.Tx, func(cipher.SHA256, cipher.Sig) error) error
+	Delete(*dbutil.Tx, cipher.SHA256) error
 }
 
 //go:generate go install
@@ -78,6 +90,8 @@ type UnspentPooler interface {
 	GetUxHash(*dbutil.Tx) (cipher.SHA256, error)
 	GetUnspentsOfAddrs(*dbutil.Tx, []cipher.Address) (coin.AddressUxOuts, error)
 	ProcessBlock(*dbutil.Tx, *coin.SignedBlock) error
+	RevertBlock(*dbutil.Tx, *coin.SignedBlock, coin.UxArray) error
+	LoadSnapshot(*dbutil.Tx, uint64, coin.UxArray) (cipher.SHA256, error)
 	AddressCount(*dbutil.Tx) (uint64, error)
 }
 
@@ -85,16 +99,26 @@ type UnspentPooler interface {
 type ChainMeta interface {
 	GetHeadSeq(*dbutil.Tx) (uint64, bool, error)
 	SetHeadSeq(*dbutil.Tx, uint64) error
+	GetPrunedSeq(*dbutil.Tx) (uint64, error)
+	SetPrunedSeq(*dbutil.Tx, uint64) error
 }
 
 // Blockchain maintain the buckets for blockchain
 type Blockchain struct {
-	db      *dbutil.DB
-	meta    ChainMeta
-	unspent UnspentPooler
-	tree    BlockTree
-	sigs    BlockSigs
-	walker  Walker
+	db          *dbutil.DB
+	meta        ChainMeta
+	unspent     UnspentPooler
+	commitments *unspentCommitments
+	reversals   *unspentReversals
+	stats       *blockStats
+	times       *blockTimes
+	tree        BlockTree
+	sigs        BlockSigs
+	walker      Walker
+	// compression of the block records written by the blockchain
+	compression BlockCompression
+	// cache of the block records, signatures and tree entries, nil if disabled
+	cache *blockCache
 }
 
 // NewBlockchain creates a new blockchain instance
@@ -108,15 +132,47 @@ func NewBlockchain(db *dbutil.DB, walker Walker) (*Blockchain, error) {
 	}
 
 	return &Blockchain{
-		db:      db,
-		unspent: NewUnspentPool(),
-		meta:    &chainMeta{},
-		tree:    &blockTree{},
-		sigs:    &blockSigs{},
-		walker:  walker,
+		db:          db,
+		unspent:     NewUnspentPool(),
+		commitments: &unspentCommitments{},
+		reversals:   &unspentReversals{},
+		stats:       &blockStats{},
+		times:       &blockTimes{},
+		meta:        &chainMeta{},
+		tree:        &blockTree{},
+		sigs:        &blockSigs{},
+		walker:      walker,
 	}, nil
 }
 
+// SetCacheSize sets the memory budget in bytes of the LRU cache of recently read blocks, signatures and
+// block tree entries, which is shared by the transactions of the db. The cache is emptied. 0 disables the cache.
+func (bc *Blockchain) SetCacheSize(size int) error {
+	if size < 0 {
+		return errors.New("block cache size must be >= 0")
+	}
+
+	var cache *blockCache
+	if size > 0 {
+		cache = newBlockCache(size)
+	}
+
+	bc.cache = cache
+	bc.tree = &blockTree{
+		compression: bc.compression,
+		cache:       cache,
+	}
+	bc.sigs = &blockSigs{
+		cache: cache,
+	}
+	return nil
+}
+
+// CacheStats returns the size and hit counts of the block cache
+func (bc *Blockchain) CacheStats() BlockCacheStats {
+	return bc.cache.stats()
+}
+
 // UnspentPool returns the unspent pool
 func (bc *Blockchain) UnspentPool() UnspentPooler {
 	return bc.unspent
@@ -142,13 +198,60 @@ func (bc *Blockchain) AddBlock(tx *dbutil.Tx, sb *coin.SignedBlock) error {
 
 // processBlock processes a block and updates the db
 func (bc *Blockchain) processBlock(tx *dbutil.Tx, b *coin.SignedBlock) error {
+	if b.Seq() > 0 {
+		if err := bc.putUnspentReversal(tx, b); err != nil {
+			return err
+		}
+	}
+
 	if err := bc.unspent.ProcessBlock(tx, b); err != nil {
 		return err
 	}
 
+	uxHash, err := bc.unspent.GetUxHash(tx)
+	if err != nil {
+		return err
+	}
+
+	if err := bc.commitments.put(tx, b.Seq(), uxHash); err != nil {
+		return err
+	}
+
+	if err := bc.stats.put(tx, &b.Block); err != nil {
+		return err
+	}
+
+	if err := bc.times.put(tx, b.Time(), b.Seq()); err != nil {
+		return err
+	}
+
 	return bc.meta.SetHeadSeq(tx, b.Seq())
 }
 
+// putUnspentReversal saves the unspent outputs spent by a block before it is processed,
+// and deletes the unspent reversal of the block that is now more than ReorgDepth blocks below it
+func (bc *Blockchain) putUnspentReversal(tx *dbutil.Tx, b *coin.SignedBlock) error {
+	var inputs []cipher.SHA256
+	for _, txn := range b.Body.Transactions {
+		inputs = append(inputs, txn.In...)
+	}
+
+	spent, err := bc.unspent.GetArray(tx, inputs)
+	if err != nil {
+		return err
+	}
+
+	if err := bc.reversals.put(tx, b.Seq(), spent); err != nil {
+		return err
+	}
+
+	if b.Seq() <= ReorgDepth {
+		return nil
+	}
+
+	return bc.reversals.delete(tx, b.Seq()-ReorgDepth)
+}
+
 // Head returns head block, returns error if no head block exists
 func (bc *Blockchain) Head(tx *dbutil.Tx) (*coin.SignedBlock, error) {
 	seq, ok, err := bc.HeadSeq(tx)
@@ -202,6 +305,11 @@ func (bc *Blockchain) GetBlockByHash(tx *dbutil.Tx, hash cipher.SHA256) (*coin.B
 	return b, nil
 }
 
+// GetBlockBySeq returns the block of given seq, without looking up its signature
+func (bc *Blockchain) GetBlockBySeq(tx *dbutil.Tx, seq uint64) (*coin.Block, error) {
+	return bc.tree.GetBlockInDepth(tx, seq, bc.walker)
+}
+
 // GetSignedBlockByHash returns signed block of given hash
 func (bc *Blockchain) GetSignedBlockByHash(tx *dbutil.Tx, hash cipher.SHA256) (*coin.SignedBlock, error) {
 	b, err := bc.tree.GetBlock(tx, hash)
@@ -262,3 +370,26 @@ func (bc *Blockchain) GetGenesisBlock(tx *dbutil.Tx) (*coin.SignedBlock, error)
 func (bc *Blockchain) ForEachBlock(tx *dbutil.Tx, f func(b *coin.Block) error) error {
 	return bc.tree.ForEachBlock(tx, f)
 }
+
+// ForEachSignedBlock iterates all blocks with their signature in one pass and calls f on them.
+// ok is false if the signature of the block is missing.
+// The iteration stops when the context of tx is done.
+func (bc *Blockchain) ForEachSignedBlock(tx *dbutil.Tx, f func(b *coin.Block, sig cipher.Sig, ok bool) error) error {
+	return dbutil.ForEachJoined(tx, BlocksBkt, [][]byte{BlockSigsBkt}, func(_, v []byte, joined [][]byte) error {
+		var b coin.Block
+		if err := decodeBlockRecord(v, &b); err != nil {
+			return err
+		}
+
+		var sig cipher.Sig
+		if joined[0] == nil {
+			return f(&b, sig, false)
+		}
+
+		if err := encoder.DeserializeRaw(joined[0], &sig); err != nil {
+			return err
+		}
+
+		return f(&b, sig, true)
+	})
+}
@@ -1,6 +1,7 @@
 package blockdb
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"testing"
@@ -108,6 +109,11 @@ func (bt *fakeBlockTree) ForEachBlock(tx *dbutil.Tx, f func(*coin.Block) error)
 	return nil
 }
 
+func (bt *fakeBlockTree) RemoveBlock(tx *dbutil.Tx, b *coin.Block) error {
+	delete(bt.blocks, b.HashHeader().Hex())
+	return nil
+}
+
 type fakeSignatureStore struct {
 	sigs       map[string]cipher.Sig
 	saveFailed bool
@@ -154,6 +160,11 @@ func (ss *fakeSignatureStore) ForEach(tx *dbutil.Tx, f func(cipher.SHA256, ciphe
 	return nil
 }
 
+func (ss *fakeSignatureStore) Delete(tx *dbutil.Tx, hash cipher.SHA256) error {
+	delete(ss.sigs, hash.Hex())
+	return nil
+}
+
 type fakeUnspentPool struct {
 	outs       map[cipher.SHA256]coin.UxOut
 	uxHash     cipher.SHA256
@@ -238,6 +249,14 @@ func (fup *fakeUnspentPool) ProcessBlock(tx *dbutil.Tx, b *coin.SignedBlock) err
 	return nil
 }
 
+func (fup *fakeUnspentPool) RevertBlock(tx *dbutil.Tx, b *coin.SignedBlock, spent coin.UxArray) error {
+	return nil
+}
+
+func (fup *fakeUnspentPool) LoadSnapshot(tx *dbutil.Tx, seq uint64, uxs coin.UxArray) (cipher.SHA256, error) {
+	return cipher.SHA256{}, nil
+}
+
 func (fup *fakeUnspentPool) Contains(tx *dbutil.Tx, h cipher.SHA256) (bool, error) {
 	_, ok := fup.outs[h]
 	return ok, nil
@@ -255,6 +274,7 @@ func (fup *fakeUnspentPool) AddressCount(tx *dbutil.Tx) (uint64, error) {
 type fakeChainMeta struct {
 	headSeq   uint64
 	didSetSeq bool
+	prunedSeq uint64
 }
 
 func newFakeChainMeta() *fakeChainMeta {
@@ -275,6 +295,15 @@ func (fcm *fakeChainMeta) SetHeadSeq(tx *dbutil.Tx, seq uint64) error {
 	return nil
 }
 
+func (fcm *fakeChainMeta) GetPrunedSeq(tx *dbutil.Tx) (uint64, error) {
+	return fcm.prunedSeq, nil
+}
+
+func (fcm *fakeChainMeta) SetPrunedSeq(tx *dbutil.Tx, seq uint64) error {
+	fcm.prunedSeq = seq
+	return nil
+}
+
 func DefaultWalker(tx *dbutil.Tx, hps []coin.HashPair) (cipher.SHA256, bool) {
 	return hps[0].Hash, true
 }
@@ -370,14 +399,16 @@ func TestBlockchainAddBlockWithTx(t *testing.T) {
 			tc.fakeStorage.tree.saveFailed = tc.failedSaves.tree
 			tc.fakeStorage.sigs.saveFailed = tc.failedSaves.sigs
 			tc.fakeStorage.unspent.saveFailed = tc.failedSaves.unspent
+			tc.fakeStorage.unspent.uxHash = testutil.RandSHA256(t)
 
 			bc := &Blockchain{
-				db:      db,
-				unspent: tc.fakeStorage.unspent,
-				meta:    tc.fakeStorage.chainMeta,
-				tree:    tc.fakeStorage.tree,
-				sigs:    tc.fakeStorage.sigs,
-				walker:  DefaultWalker,
+				db:          db,
+				unspent:     tc.fakeStorage.unspent,
+				commitments: &unspentCommitments{},
+				meta:        tc.fakeStorage.chainMeta,
+				tree:        tc.fakeStorage.tree,
+				sigs:        tc.fakeStorage.sigs,
+				walker:      DefaultWalker,
 			}
 
 			gb := makeGenesisBlock(t)
@@ -411,6 +442,14 @@ func TestBlockchainAddBlockWithTx(t *testing.T) {
 					require.False(t, ok)
 				}
 
+				// check unspent commitment
+				commitment, ok, err := bc.GetUnspentCommitment(tx, 0)
+				require.NoError(t, err)
+				require.Equal(t, tc.expect.err == nil, ok)
+				if ok {
+					require.Equal(t, tc.fakeStorage.unspent.uxHash, commitment)
+				}
+
 				// check len
 				length, err := bc.Len(tx)
 				require.NoError(t, err)
@@ -600,3 +639,60 @@ func TestBlockchainGetBlockByHash(t *testing.T) {
 		})
 	}
 }
+
+func TestBlockchainForEachSignedBlock(t *testing.T) {
+	db, shutdown := setupNoUnspentAddrIndexDB(t)
+	defer shutdown()
+
+	bc, err := NewBlockchain(db, DefaultWalker)
+	require.NoError(t, err)
+
+	blocks := readBlockchain180(t, bc, db)
+	expected := make(map[cipher.SHA256]coin.SignedBlock, len(blocks))
+	for _, b := range blocks {
+		expected[b.HashHeader()] = b
+	}
+
+	// Remove the signature of a block
+	missing := blocks[3].HashHeader()
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		return dbutil.Delete(tx, BlockSigsBkt, missing[:])
+	})
+	require.NoError(t, err)
+
+	seen := make(map[cipher.SHA256]struct{}, len(blocks))
+	err = db.View("", func(tx *dbutil.Tx) error {
+		return bc.ForEachSignedBlock(tx, func(b *coin.Block, sig cipher.Sig, ok bool) error {
+			hash := b.HashHeader()
+			exp, found := expected[hash]
+			require.True(t, found)
+			require.Equal(t, exp.Block, *b)
+
+			if hash == missing {
+				require.False(t, ok)
+				require.Equal(t, cipher.Sig{}, sig)
+			} else {
+				require.True(t, ok)
+				require.Equal(t, exp.Sig, sig)
+			}
+
+			seen[hash] = struct{}{}
+			return nil
+		})
+	})
+	require.NoError(t, err)
+	require.Len(t, seen, len(blocks))
+
+	// The iteration stops when the context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	n := 0
+	err = db.ViewContext(ctx, "", func(tx *dbutil.Tx) error {
+		return bc.ForEachSignedBlock(tx, func(b *coin.Block, sig cipher.Sig, ok bool) error {
+			n++
+			cancel()
+			return nil
+		})
+	})
+	require.Equal(t, context.Canceled, err)
+	require.Equal(t, 1, n)
+}
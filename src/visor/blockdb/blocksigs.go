@@ -1,6 +1,8 @@
 package blockdb
 
 import (
+	"fmt"
+
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/cipher/encoder"
 	"github.com/skycoin/skycoin/src/visor/dbutil"
@@ -21,24 +23,36 @@ var (
 // blockSigs per BkSeq, or use hashes as keys. For now, this is not a
 // problem assuming the signed blocks created by a block publisher are valid blocks,
 // because we can check the signature independently of the blockchain.
-type blockSigs struct{}
+type blockSigs struct {
+	// cache of the signatures, nil if disabled
+	cache *blockCache
+}
 
 // Get returns the signature of a specific block
 func (bs *blockSigs) Get(tx *dbutil.Tx, hash cipher.SHA256) (cipher.Sig, bool, error) {
-	var sig cipher.Sig
-
-	if ok, err := dbutil.GetBucketObjectDecoded(tx, BlockSigsBkt, hash[:], &sig); err != nil {
+	v, err := bs.cache.get(tx, BlockSigsBkt, hash[:])
+	if err != nil {
 		return cipher.Sig{}, false, err
-	} else if !ok {
+	} else if v == nil {
 		return cipher.Sig{}, false, nil
 	}
 
+	var sig cipher.Sig
+	if err := encoder.DeserializeRaw(v, &sig); err != nil {
+		return cipher.Sig{}, false, fmt.Errorf("encoder.DeserializeRaw failed: %v", err)
+	}
+
 	return sig, true, nil
 }
 
 // Add adds a signed block to the db
 func (bs *blockSigs) Add(tx *dbutil.Tx, hash cipher.SHA256, sig cipher.Sig) error {
-	return dbutil.PutBucketValue(tx, BlockSigsBkt, hash[:], encoder.Serialize(sig))
+	return bs.cache.putBucketValue(tx, BlockSigsBkt, hash[:], encoder.Serialize(sig))
+}
+
+// Delete removes the signature of a block
+func (bs *blockSigs) Delete(tx *dbutil.Tx, hash cipher.SHA256) error {
+	return bs.cache.delete(tx, BlockSigsBkt, hash[:])
 }
 
 // ForEach iterates all signatures and calls f on them
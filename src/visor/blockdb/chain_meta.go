@@ -9,6 +9,10 @@ var (
 	BlockchainMetaBkt = []byte("blockchain_meta")
 	// blockchain head sequence number
 	headSeqKey = []byte("head_seq")
+	// seq of the newest pruned block, blocks 1 to prunedSeq are pruned
+	prunedSeqKey = []byte("pruned_seq")
+	// compression of every block record, set once RecompressBlocks has rewritten all of them
+	blockCompressionKey = []byte("block_compression")
 )
 
 type chainMeta struct{}
@@ -27,3 +31,18 @@ func (m chainMeta) GetHeadSeq(tx *dbutil.Tx) (uint64, bool, error) {
 
 	return dbutil.Btoi(v), true, nil
 }
+
+func (m chainMeta) SetPrunedSeq(tx *dbutil.Tx, seq uint64) error {
+	return dbutil.PutBucketValue(tx, BlockchainMetaBkt, prunedSeqKey, dbutil.Itob(seq))
+}
+
+func (m chainMeta) GetPrunedSeq(tx *dbutil.Tx) (uint64, error) {
+	v, err := dbutil.GetBucketValue(tx, BlockchainMetaBkt, prunedSeqKey)
+	if err != nil {
+		return 0, err
+	} else if v == nil {
+		return 0, nil
+	}
+
+	return dbutil.Btoi(v), nil
+}
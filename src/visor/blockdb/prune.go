@@ -0,0 +1,102 @@
+package blockdb
+
+import (
+	"fmt"
+
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+// ErrBlockPruned is returned if the body of a block was removed by Prune
+type ErrBlockPruned struct {
+	Seq uint64
+}
+
+// NewErrBlockPruned creates ErrBlockPruned
+func NewErrBlockPruned(seq uint64) error {
+	return ErrBlockPruned{
+		Seq: seq,
+	}
+}
+
+func (e ErrBlockPruned) Error() string {
+	return fmt.Sprintf("Block %d is pruned", e.Seq)
+}
+
+// PrunedSeq returns the seq of the newest pruned block, 0 if no block is pruned.
+// The genesis block is never pruned.
+func (bc *Blockchain) PrunedSeq(tx *dbutil.Tx) (uint64, error) {
+	return bc.meta.GetPrunedSeq(tx)
+}
+
+// IsPruned returns true if the body of the block of seq was removed by Prune
+func (bc *Blockchain) IsPruned(tx *dbutil.Tx, seq uint64) (bool, error) {
+	if seq == 0 {
+		return false, nil
+	}
+
+	prunedSeq, err := bc.meta.GetPrunedSeq(tx)
+	if err != nil {
+		return false, err
+	}
+
+	return seq <= prunedSeq, nil
+}
+
+// Prune removes the body of the blocks that are more than depth blocks below the head block,
+// at most limit blocks per call. If limit is 0 there is no limit.
+// The block headers and signatures are kept, so the chain of headers can still be verified,
+// and the unspent pool is not changed. Returns the number of blocks pruned.
+func (bc *Blockchain) Prune(tx *dbutil.Tx, depth, limit uint64) (uint64, error) {
+	if depth == 0 {
+		return 0, nil
+	}
+
+	headSeq, ok, err := bc.meta.GetHeadSeq(tx)
+	if err != nil {
+		return 0, err
+	} else if !ok || headSeq <= depth {
+		return 0, nil
+	}
+
+	prunedSeq, err := bc.meta.GetPrunedSeq(tx)
+	if err != nil {
+		return 0, err
+	}
+
+	end := headSeq - depth
+	if limit != 0 && end-prunedSeq > limit {
+		end = prunedSeq + limit
+	}
+
+	var n uint64
+	for seq := prunedSeq + 1; seq <= end; seq++ {
+		b, err := bc.tree.GetBlockInDepth(tx, seq, bc.walker)
+		if err != nil {
+			return n, err
+		}
+		if b == nil {
+			return n, fmt.Errorf("block of seq %d does not exist", seq)
+		}
+
+		// The header hash does not depend on the body, so the block keeps its hash and signature
+		header := coin.Block{
+			Head: b.Head,
+		}
+		if err := putBlockRecord(tx, bc.cache, &header, bc.compression); err != nil {
+			return n, err
+		}
+
+		n++
+	}
+
+	if n == 0 {
+		return 0, nil
+	}
+
+	if err := bc.meta.SetPrunedSeq(tx, end); err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
@@ -0,0 +1,52 @@
+package blockdb
+
+import (
+	"fmt"
+
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+// RepairBlock replaces the stored block and signature of the main chain block at the seq of b with b.
+// It is used to repair a corrupted block with a copy received from a peer, so b must have the header hash
+// of the main chain block at its seq, and its body must match the body hash of its header.
+// The signature is not verified. The block tree and the unspent pool are not changed.
+// If the block is pruned, only its header is stored.
+func (bc *Blockchain) RepairBlock(tx *dbutil.Tx, b *coin.SignedBlock) error {
+	seq := b.Seq()
+	pairs, err := getHashPairInDepth(tx, bc.cache, seq, allPairs)
+	if err != nil {
+		return err
+	}
+
+	hash, ok := bc.walker(tx, pairs)
+	if !ok {
+		return fmt.Errorf("block of seq %d does not exist", seq)
+	}
+
+	if h := b.HashHeader(); h != hash {
+		return fmt.Errorf("block %d hash %s does not match the stored block hash %s", seq, h.Hex(), hash.Hex())
+	}
+
+	if b.HashBody() != b.Head.BodyHash {
+		return fmt.Errorf("block %d body does not match the body hash of its header", seq)
+	}
+
+	pruned, err := bc.IsPruned(tx, seq)
+	if err != nil {
+		return err
+	}
+
+	block := b.Block
+	if pruned {
+		block = coin.Block{
+			Head: b.Head,
+		}
+	}
+
+	if err := putBlockRecord(tx, bc.cache, &block, bc.compression); err != nil {
+		return err
+	}
+
+	return bc.sigs.Add(tx, hash, b.Sig)
+}
@@ -17,7 +17,9 @@ var (
 
 	// UnspentPoolBkt holds unspent outputs, indexed by unspent output hash
 	UnspentPoolBkt = []byte("unspent_pool")
-	// UnspentPoolAddrIndexBkt maps addresses to their unspent outputs
+	// UnspentPoolAddrIndexBkt maps addresses to their unspent outputs.
+	// In an encrypted database, the addresses are hidden with dbutil.Tx.KeyMAC,
+	// the index is rebuilt by MaybeBuildIndexes when the database is encrypted.
 	UnspentPoolAddrIndexBkt = []byte("unspent_pool_addr_index")
 	// UnspentMetaBkt holds unspent output metadata
 	UnspentMetaBkt = []byte("unspent_meta")
@@ -116,7 +118,7 @@ type poolAddrIndex struct{}
 func (p poolAddrIndex) get(tx *dbutil.Tx, addr cipher.Address) ([]cipher.SHA256, error) {
 	var hashes []cipher.SHA256
 
-	if ok, err := dbutil.GetBucketObjectDecoded(tx, UnspentPoolAddrIndexBkt, addr.Bytes(), &hashes); err != nil {
+	if ok, err := dbutil.GetBucketObjectDecoded(tx, UnspentPoolAddrIndexBkt, tx.KeyMAC(addr.Bytes()), &hashes); err != nil {
 		return nil, err
 	} else if !ok {
 		return nil, nil
@@ -140,7 +142,22 @@ func (p poolAddrIndex) put(tx *dbutil.Tx, addr cipher.Address, hashes []cipher.S
 	}
 
 	encodedHashes := encoder.Serialize(hashes)
-	return dbutil.PutBucketValue(tx, UnspentPoolAddrIndexBkt, addr.Bytes(), encodedHashes)
+	return dbutil.PutBucketValue(tx, UnspentPoolAddrIndexBkt, tx.KeyMAC(addr.Bytes()), encodedHashes)
+}
+
+// keysHidden returns false if the addresses of the index are not hidden with tx.KeyMAC,
+// because it was built before the database was encrypted
+func (p poolAddrIndex) keysHidden(tx *dbutil.Tx) (bool, error) {
+	keyLen := len(tx.KeyMAC(cipher.Address{}.Bytes()))
+	hidden := true
+	if err := dbutil.ForEachPrefix(tx, UnspentPoolAddrIndexBkt, nil, nil, func(k, _ []byte) (bool, error) {
+		hidden = len(k) == keyLen
+		return false, nil
+	}); err != nil {
+		return false, err
+	}
+
+	return hidden, nil
 }
 
 // adjust adds and removes hashes from an address -> hashes index
@@ -203,7 +220,7 @@ func (p poolAddrIndex) adjust(tx *dbutil.Tx, addr cipher.Address, addHashes, rmH
 	// Delete the row if hashes is empty, so that the length of the bucket can
 	// be used to determine the number of addresses with unspents
 	if len(newHashes) == 0 {
-		return dbutil.Delete(tx, UnspentPoolAddrIndexBkt, addr.Bytes())
+		return dbutil.Delete(tx, UnspentPoolAddrIndexBkt, tx.KeyMAC(addr.Bytes()))
 	}
 
 	return p.put(tx, addr, newHashes)
@@ -237,7 +254,17 @@ func (up *Unspents) MaybeBuildIndexes(tx *dbutil.Tx, headSeq uint64) error {
 	}
 
 	if ok && addrIndexHeight == headSeq {
-		return nil
+		hidden, err := up.poolAddrIndex.keysHidden(tx)
+		if err != nil {
+			return err
+		}
+
+		if hidden {
+			return nil
+		}
+
+		logger.Info("Rebuilding unspent_pool_addr_index to hide its addresses")
+		return up.buildAddrIndex(tx)
 	}
 
 	if addrIndexHeight > headSeq {
@@ -408,6 +435,153 @@ func (up *Unspents) ProcessBlock(tx *dbutil.Tx, b *coin.SignedBlock) error {
 	return up.meta.setAddrIndexHeight(tx, b.Block.Head.BkSeq)
 }
 
+// RevertBlock undoes ProcessBlock for the most recently processed block b, removing the unspents created by b
+// from the unspent pool and restoring the unspents it spent. spent are the unspents spent by b, in the order of its inputs.
+func (up *Unspents) RevertBlock(tx *dbutil.Tx, b *coin.SignedBlock, spent coin.UxArray) error {
+	if b.Block.Head.BkSeq == 0 {
+		return errors.New("unspent pool can't revert the genesis block")
+	}
+
+	addrIndexHeight, ok, err := up.meta.getAddrIndexHeight(tx)
+	if err != nil {
+		return err
+	}
+
+	if !ok || addrIndexHeight != b.Block.Head.BkSeq {
+		return fmt.Errorf("unspent pool can't revert block %d, it is not the last processed block", b.Block.Head.BkSeq)
+	}
+
+	// Gather all transaction inputs
+	var inputs []cipher.SHA256
+	var txnUxs coin.UxArray
+	for _, txn := range b.Body.Transactions {
+		inputs = append(inputs, txn.In...)
+		txnUxs = append(txnUxs, coin.CreateUnspents(b.Head, txn)...)
+	}
+
+	if len(spent) != len(inputs) {
+		return fmt.Errorf("block %d has %d inputs but %d spent unspents were given", b.Block.Head.BkSeq, len(inputs), len(spent))
+	}
+
+	for i, ux := range spent {
+		if ux.Hash() != inputs[i] {
+			return fmt.Errorf("spent uxout %s does not match input %s of block %d", ux.Hash().Hex(), inputs[i].Hex(), b.Block.Head.BkSeq)
+		}
+	}
+
+	xorHash, err := up.meta.getXorHash(tx)
+	if err != nil {
+		return err
+	}
+
+	// Remove the outputs created by the block
+	rmAddrHashes := make(map[cipher.Address][]cipher.SHA256)
+	for _, ux := range txnUxs {
+		h := ux.Hash()
+
+		if hasKey, err := up.Contains(tx, h); err != nil {
+			return err
+		} else if !hasKey {
+			return NewErrUnspentNotExist(h.Hex())
+		}
+
+		if err := up.pool.delete(tx, h); err != nil {
+			return err
+		}
+
+		xorHash = xorHash.Xor(ux.SnapshotHash())
+		rmAddrHashes[ux.Body.Address] = append(rmAddrHashes[ux.Body.Address], h)
+	}
+
+	// Restore the outputs spent by the block
+	addAddrHashes := make(map[cipher.Address][]cipher.SHA256)
+	for _, ux := range spent {
+		h := ux.Hash()
+
+		if hasKey, err := up.Contains(tx, h); err != nil {
+			return err
+		} else if hasKey {
+			return fmt.Errorf("attempted to insert uxout:%v twice into the unspent pool", h.Hex())
+		}
+
+		if err := up.pool.put(tx, h, ux); err != nil {
+			return err
+		}
+
+		xorHash = xorHash.Xor(ux.SnapshotHash())
+		addAddrHashes[ux.Body.Address] = append(addAddrHashes[ux.Body.Address], h)
+	}
+
+	if err := up.meta.setXorHash(tx, xorHash); err != nil {
+		return err
+	}
+
+	// Update indexes
+	for addr, rmHashes := range rmAddrHashes {
+		addHashes := addAddrHashes[addr]
+
+		if err := up.poolAddrIndex.adjust(tx, addr, addHashes, rmHashes); err != nil {
+			return err
+		}
+
+		delete(addAddrHashes, addr)
+	}
+
+	for addr, addHashes := range addAddrHashes {
+		if err := up.poolAddrIndex.adjust(tx, addr, addHashes, nil); err != nil {
+			return err
+		}
+	}
+
+	return up.meta.setAddrIndexHeight(tx, b.Block.Head.BkSeq-1)
+}
+
+// LoadSnapshot fills an empty unspent pool with the unspent outputs after the block of seq,
+// for a database bootstrapped from an unspent output set snapshot instead of executing the blocks.
+// Returns the hash of the unspent output set.
+func (up *Unspents) LoadSnapshot(tx *dbutil.Tx, seq uint64, uxs coin.UxArray) (cipher.SHA256, error) {
+	if empty, err := dbutil.IsEmpty(tx, UnspentPoolBkt); err != nil {
+		return cipher.SHA256{}, err
+	} else if !empty {
+		return cipher.SHA256{}, errors.New("unspent pool is not empty")
+	}
+
+	var xorHash cipher.SHA256
+	for _, ux := range uxs {
+		if ux.Head.BkSeq > seq {
+			return cipher.SHA256{}, fmt.Errorf("uxout %s was created by block %d, after block %d", ux.Hash().Hex(), ux.Head.BkSeq, seq)
+		}
+
+		h := ux.Hash()
+		if hasKey, err := up.Contains(tx, h); err != nil {
+			return cipher.SHA256{}, err
+		} else if hasKey {
+			return cipher.SHA256{}, fmt.Errorf("attempted to insert uxout:%v twice into the unspent pool", h.Hex())
+		}
+
+		if err := up.pool.put(tx, h, ux); err != nil {
+			return cipher.SHA256{}, err
+		}
+
+		xorHash = xorHash.Xor(ux.SnapshotHash())
+	}
+
+	if err := up.meta.setXorHash(tx, xorHash); err != nil {
+		return cipher.SHA256{}, err
+	}
+
+	if err := up.buildAddrIndex(tx); err != nil {
+		return cipher.SHA256{}, err
+	}
+
+	// The address index is up to date with the block of seq, even if none of the outputs were created by it
+	if err := up.meta.setAddrIndexHeight(tx, seq); err != nil {
+		return cipher.SHA256{}, err
+	}
+
+	return xorHash, nil
+}
+
 // GetArray returns UxOut for a set of hashes, will return error if any of the hashes do not exist in the pool.
 func (up *Unspents) GetArray(tx *dbutil.Tx, hashes []cipher.SHA256) (coin.UxArray, error) {
 	var uxa coin.UxArray
@@ -0,0 +1,103 @@
+package blockdb
+
+import (
+	"fmt"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+// UnspentCommitmentsBkt maps block seqs to the hash of the unspent output set after the block was executed
+var UnspentCommitmentsBkt = []byte("unspent_commitments")
+
+// unspentCommitments bucket stores the unspent output set hash after each block,
+// block seq as key, hash as value.
+// The hash is the XOR of the snapshot hashes of the unspent outputs, which the unspent pool updates
+// for each block and which the header of the next block commits to as its UxHash.
+type unspentCommitments struct{}
+
+// put saves the unspent output set hash after the block of seq
+func (uc *unspentCommitments) put(tx *dbutil.Tx, seq uint64, hash cipher.SHA256) error {
+	return dbutil.PutBucketValue(tx, UnspentCommitmentsBkt, dbutil.Itob(seq), hash[:])
+}
+
+// get returns the unspent output set hash after the block of seq, false if it is not stored
+func (uc *unspentCommitments) get(tx *dbutil.Tx, seq uint64) (cipher.SHA256, bool, error) {
+	// A database opened read-only can predate the bucket
+	if !dbutil.Exists(tx, UnspentCommitmentsBkt) {
+		return cipher.SHA256{}, false, nil
+	}
+
+	v, err := dbutil.GetBucketValue(tx, UnspentCommitmentsBkt, dbutil.Itob(seq))
+	if err != nil {
+		return cipher.SHA256{}, false, err
+	} else if v == nil {
+		return cipher.SHA256{}, false, nil
+	}
+
+	hash, err := cipher.SHA256FromBytes(v)
+	if err != nil {
+		return cipher.SHA256{}, false, err
+	}
+
+	return hash, true, nil
+}
+
+// delete removes the unspent output set hash after the block of seq
+func (uc *unspentCommitments) delete(tx *dbutil.Tx, seq uint64) error {
+	return dbutil.Delete(tx, UnspentCommitmentsBkt, dbutil.Itob(seq))
+}
+
+// isEmpty checks if the unspent commitments bucket is empty
+func (uc *unspentCommitments) isEmpty(tx *dbutil.Tx) (bool, error) {
+	return dbutil.IsEmpty(tx, UnspentCommitmentsBkt)
+}
+
+// GetUnspentCommitment returns the hash of the unspent output set after the block of seq was executed,
+// false if the block does not exist or the database predates the commitments
+func (bc *Blockchain) GetUnspentCommitment(tx *dbutil.Tx, seq uint64) (cipher.SHA256, bool, error) {
+	return bc.commitments.get(tx, seq)
+}
+
+// MaybeBuildUnspentCommitments stores the unspent output set hashes of the blocks
+// executed before the commitments were added to the database.
+// The hash after a block is the UxHash of the header of the next block,
+// and the hash after the head block is the current hash of the unspent pool.
+func (bc *Blockchain) MaybeBuildUnspentCommitments(tx *dbutil.Tx) error {
+	headSeq, ok, err := bc.meta.GetHeadSeq(tx)
+	if err != nil {
+		return err
+	} else if !ok {
+		return nil
+	}
+
+	empty, err := bc.commitments.isEmpty(tx)
+	if err != nil {
+		return err
+	} else if !empty {
+		return nil
+	}
+
+	logger.Infof("Building unspent commitments of %d blocks", headSeq+1)
+
+	for seq := uint64(1); seq <= headSeq; seq++ {
+		b, err := bc.tree.GetBlockInDepth(tx, seq, bc.walker)
+		if err != nil {
+			return err
+		}
+		if b == nil {
+			return fmt.Errorf("block of seq %d does not exist", seq)
+		}
+
+		if err := bc.commitments.put(tx, seq-1, b.Head.UxHash); err != nil {
+			return err
+		}
+	}
+
+	hash, err := bc.unspent.GetUxHash(tx)
+	if err != nil {
+		return err
+	}
+
+	return bc.commitments.put(tx, headSeq, hash)
+}
@@ -0,0 +1,130 @@
+package blockdb
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/skycoin/skycoin/src/cipher/encoder"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+// ReorgDepth is the number of most recent blocks that can be removed from the head of the blockchain.
+// The unspent reversals of older blocks are deleted.
+const ReorgDepth = 100
+
+// UnspentReversalsBkt maps block seqs to the unspent outputs spent by the block
+var UnspentReversalsBkt = []byte("unspent_reversals")
+
+// ErrNoUnspentReversal is returned when removing a block whose unspent reversal is not stored,
+// because the block is more than ReorgDepth blocks below the head block, is the genesis block,
+// or was executed before the reversals were added to the database
+type ErrNoUnspentReversal struct {
+	Seq uint64
+}
+
+// NewErrNoUnspentReversal creates ErrNoUnspentReversal
+func NewErrNoUnspentReversal(seq uint64) error {
+	return ErrNoUnspentReversal{
+		Seq: seq,
+	}
+}
+
+func (e ErrNoUnspentReversal) Error() string {
+	return fmt.Sprintf("Block %d cannot be removed, its unspent reversal is not stored", e.Seq)
+}
+
+// unspentReversals bucket stores the unspent outputs spent by the most recent blocks,
+// so that the unspent pool can be restored when a block is removed from the head of the blockchain.
+// block seq as key, coin.UxArray as value.
+type unspentReversals struct{}
+
+// put saves the unspent outputs spent by the block of seq
+func (ur *unspentReversals) put(tx *dbutil.Tx, seq uint64, uxs coin.UxArray) error {
+	return dbutil.PutBucketValue(tx, UnspentReversalsBkt, dbutil.Itob(seq), encoder.Serialize(uxs))
+}
+
+// get returns the unspent outputs spent by the block of seq, false if they are not stored
+func (ur *unspentReversals) get(tx *dbutil.Tx, seq uint64) (coin.UxArray, bool, error) {
+	// A database opened read-only can predate the bucket
+	if !dbutil.Exists(tx, UnspentReversalsBkt) {
+		return nil, false, nil
+	}
+
+	var uxs coin.UxArray
+	if ok, err := dbutil.GetBucketObjectDecoded(tx, UnspentReversalsBkt, dbutil.Itob(seq), &uxs); err != nil {
+		return nil, false, err
+	} else if !ok {
+		return nil, false, nil
+	}
+
+	return uxs, true, nil
+}
+
+// delete removes the unspent reversal of the block of seq
+func (ur *unspentReversals) delete(tx *dbutil.Tx, seq uint64) error {
+	return dbutil.Delete(tx, UnspentReversalsBkt, dbutil.Itob(seq))
+}
+
+// RemoveHead removes the head block from the blockchain and reverts its changes to the unspent pool.
+// The signature, unspent commitment, unspent reversal, stats and time index entry of the block are deleted,
+// and the previous block becomes the head block. Only the ReorgDepth most recent blocks can be removed,
+// and the genesis block can't be removed. Returns the removed block.
+func (bc *Blockchain) RemoveHead(tx *dbutil.Tx) (*coin.SignedBlock, error) {
+	b, err := bc.Head(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	seq := b.Seq()
+	if seq == 0 {
+		return nil, errors.New("The genesis block can't be removed")
+	}
+
+	if pruned, err := bc.IsPruned(tx, seq); err != nil {
+		return nil, err
+	} else if pruned {
+		return nil, NewErrBlockPruned(seq)
+	}
+
+	spent, ok, err := bc.reversals.get(tx, seq)
+	if err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, NewErrNoUnspentReversal(seq)
+	}
+
+	if err := bc.unspent.RevertBlock(tx, b, spent); err != nil {
+		return nil, err
+	}
+
+	if err := bc.reversals.delete(tx, seq); err != nil {
+		return nil, err
+	}
+
+	if err := bc.commitments.delete(tx, seq); err != nil {
+		return nil, err
+	}
+
+	if err := bc.stats.delete(tx, seq); err != nil {
+		return nil, err
+	}
+
+	if err := bc.times.delete(tx, b.Time()); err != nil {
+		return nil, err
+	}
+
+	if err := bc.tree.RemoveBlock(tx, &b.Block); err != nil {
+		return nil, err
+	}
+
+	if err := bc.sigs.Delete(tx, b.HashHeader()); err != nil {
+		return nil, err
+	}
+
+	if err := bc.meta.SetHeadSeq(tx, seq-1); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
@@ -0,0 +1,191 @@
+package blockdb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/encoder"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+// unspentState is the content of the unspent pool and the blockchain meta after a block
+type unspentState struct {
+	headSeq         uint64
+	uxHash          cipher.SHA256
+	uxs             map[cipher.SHA256]coin.UxOut
+	addrIndex       map[cipher.Address]map[cipher.SHA256]struct{}
+	addrIndexHeight uint64
+}
+
+func readUnspentState(t *testing.T, db *dbutil.DB, bc *Blockchain) unspentState {
+	var s unspentState
+	err := db.View("", func(tx *dbutil.Tx) error {
+		var ok bool
+		var err error
+		s.headSeq, ok, err = bc.HeadSeq(tx)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		s.uxHash, err = bc.unspent.GetUxHash(tx)
+		require.NoError(t, err)
+
+		commitment, ok, err := bc.GetUnspentCommitment(tx, s.headSeq)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, s.uxHash, commitment)
+
+		uxs, err := bc.unspent.GetAll(tx)
+		require.NoError(t, err)
+		s.uxs = make(map[cipher.SHA256]coin.UxOut, len(uxs))
+		for _, ux := range uxs {
+			s.uxs[ux.Hash()] = ux
+		}
+
+		// The order of the hashes of an address depends on the order they were indexed in
+		s.addrIndex = make(map[cipher.Address]map[cipher.SHA256]struct{})
+		err = dbutil.ForEach(tx, UnspentPoolAddrIndexBkt, func(k, v []byte) error {
+			addr, err := cipher.AddressFromBytes(k)
+			require.NoError(t, err)
+
+			var hashes []cipher.SHA256
+			require.NoError(t, encoder.DeserializeRaw(v, &hashes))
+			s.addrIndex[addr] = make(map[cipher.SHA256]struct{}, len(hashes))
+			for _, h := range hashes {
+				s.addrIndex[addr][h] = struct{}{}
+			}
+			return nil
+		})
+		require.NoError(t, err)
+
+		s.addrIndexHeight, ok, err = (&unspentMeta{}).getAddrIndexHeight(tx)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		return nil
+	})
+	require.NoError(t, err)
+	return s
+}
+
+func TestBlockchainRemoveHead(t *testing.T) {
+	srcDB, shutdownSrc := setupNoUnspentAddrIndexDB(t)
+	defer shutdownSrc()
+
+	walker := func(tx *dbutil.Tx, hps []coin.HashPair) (cipher.SHA256, bool) {
+		return hps[0].Hash, true
+	}
+
+	srcBC, err := NewBlockchain(srcDB, walker)
+	require.NoError(t, err)
+	blocks := readBlockchain180(t, srcBC, srcDB)
+	require.True(t, len(blocks) > ReorgDepth+10)
+
+	db, shutdown := prepareDB(t)
+	defer shutdown()
+
+	bc, err := NewBlockchain(db, walker)
+	require.NoError(t, err)
+
+	addBlocks := func(blocks []coin.SignedBlock) {
+		err := db.Update("", func(tx *dbutil.Tx) error {
+			for i := range blocks {
+				require.NoError(t, bc.AddBlock(tx, &blocks[i]))
+			}
+			return nil
+		})
+		require.NoError(t, err)
+	}
+
+	headSeq := uint64(len(blocks) - 1)
+	addBlocks(blocks[:headSeq-9])
+	expected := readUnspentState(t, db, bc)
+	addBlocks(blocks[headSeq-9:])
+
+	// Only the unspent reversals of the ReorgDepth most recent blocks are kept
+	err = db.View("", func(tx *dbutil.Tx) error {
+		n, err := dbutil.Len(tx, UnspentReversalsBkt)
+		require.NoError(t, err)
+		require.Equal(t, uint64(ReorgDepth), n)
+
+		_, ok, err := bc.reversals.get(tx, headSeq-ReorgDepth)
+		require.NoError(t, err)
+		require.False(t, ok)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// Remove the 10 most recent blocks
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		for i := 0; i < 10; i++ {
+			b, err := bc.RemoveHead(tx)
+			require.NoError(t, err)
+			require.Equal(t, blocks[headSeq-uint64(i)], *b)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, expected, readUnspentState(t, db, bc))
+
+	err = db.View("", func(tx *dbutil.Tx) error {
+		b, err := bc.GetSignedBlockBySeq(tx, headSeq)
+		require.NoError(t, err)
+		require.Nil(t, b)
+
+		b, err = bc.GetSignedBlockByHash(tx, blocks[headSeq].HashHeader())
+		require.NoError(t, err)
+		require.Nil(t, b)
+
+		_, ok, err := bc.GetBlockSignature(tx, &blocks[headSeq].Block)
+		require.NoError(t, err)
+		require.False(t, ok)
+
+		_, ok, err = bc.GetUnspentCommitment(tx, headSeq)
+		require.NoError(t, err)
+		require.False(t, ok)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// The removed blocks can be executed again
+	addBlocks(blocks[headSeq-9:])
+	final := readUnspentState(t, db, bc)
+	require.Equal(t, headSeq, final.headSeq)
+
+	// Blocks can be removed until the first block whose unspent reversal was deleted
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		for seq := headSeq; seq > headSeq-ReorgDepth; seq-- {
+			_, err := bc.RemoveHead(tx)
+			require.NoError(t, err)
+		}
+
+		_, err := bc.RemoveHead(tx)
+		require.Equal(t, NewErrNoUnspentReversal(headSeq-ReorgDepth), err)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestBlockchainRemoveHeadGenesis(t *testing.T) {
+	db, shutdown := prepareDB(t)
+	defer shutdown()
+
+	bc, err := NewBlockchain(db, DefaultWalker)
+	require.NoError(t, err)
+
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		_, err := bc.RemoveHead(tx)
+		require.Equal(t, ErrNoHeadBlock, err)
+
+		gb := makeGenesisBlock(t)
+		require.NoError(t, bc.AddBlock(tx, &gb))
+
+		_, err = bc.RemoveHead(tx)
+		require.Error(t, err)
+		return nil
+	})
+	require.NoError(t, err)
+}
@@ -0,0 +1,371 @@
+package blockdb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/encoder"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+/*
+Unspent output set snapshot file format, all integers are little endian:
+
+	magic       [8]byte   "SKYUTXO\x00"
+	version     uint32    UnspentSnapshotVersion
+	seq         uint64    seq of the block after which the unspent output set is taken
+	block hash  [32]byte  header hash of the block of seq
+	commitment  [32]byte  hash of the unspent output set, see unspentCommitments
+	count       uint64    number of unspent outputs
+
+followed by seq+1 block records, ordered by block seq starting from the genesis block.
+The genesis block is stored with its body, the other blocks only with their header:
+
+	length      uint32    length of the encoded block
+	block       []byte    encoder.Serialize(coin.SignedBlock)
+
+followed by count unspent output records, ordered by uxout hash:
+
+	length      uint32    length of the encoded uxout
+	uxout       []byte    encoder.Serialize(coin.UxOut)
+*/
+
+const (
+	// UnspentSnapshotVersion is the version of the unspent output set snapshot file format
+	UnspentSnapshotVersion = 1
+
+	// maxUnspentSnapshotBlockSize limits the size of a block record read from a snapshot,
+	// to avoid allocating huge buffers for a corrupted snapshot
+	maxUnspentSnapshotBlockSize = 32 * 1024 * 1024
+
+	// maxUnspentSnapshotUxOutSize limits the size of an uxout record read from a snapshot
+	maxUnspentSnapshotUxOutSize = 1024
+)
+
+var (
+	unspentSnapshotMagic = [8]byte{'S', 'K', 'Y', 'U', 'T', 'X', 'O', 0}
+
+	// ErrUnspentSnapshotInvalid is returned if a file is not an unspent output set snapshot
+	ErrUnspentSnapshotInvalid = errors.New("Not an unspent output set snapshot file")
+	// ErrUnspentSnapshotCommitment is returned if the unspent outputs of a snapshot don't match its commitment
+	ErrUnspentSnapshotCommitment = errors.New("Unspent outputs do not match the unspent output set commitment")
+	// ErrUnspentSnapshotDBNotEmpty is returned by ImportUnspentSnapshot if the database already has blocks
+	ErrUnspentSnapshotDBNotEmpty = errors.New("Can't import an unspent output set snapshot into a database that already has blocks")
+)
+
+// ErrUnspentSnapshotVersion is returned if the unspent output set snapshot format version is not supported
+type ErrUnspentSnapshotVersion struct {
+	Version uint32
+}
+
+// NewErrUnspentSnapshotVersion creates an ErrUnspentSnapshotVersion
+func NewErrUnspentSnapshotVersion(version uint32) error {
+	return ErrUnspentSnapshotVersion{
+		Version: version,
+	}
+}
+
+func (e ErrUnspentSnapshotVersion) Error() string {
+	return fmt.Sprintf("Unsupported unspent output set snapshot version %d, the latest supported version is %d", e.Version, UnspentSnapshotVersion)
+}
+
+// UnspentSnapshotHeader describes the unspent output set of an unspent output set snapshot
+type UnspentSnapshotHeader struct {
+	// Seq of the block after which the unspent output set is taken
+	Seq uint64
+	// Header hash of the block of Seq
+	BlockHash cipher.SHA256
+	// Hash of the unspent output set, the UxHash of the header of the block after Seq
+	Commitment cipher.SHA256
+	// Number of unspent outputs
+	UxCount uint64
+}
+
+type unspentSnapshotFileHeader struct {
+	Magic      [8]byte
+	Version    uint32
+	Seq        uint64
+	BlockHash  cipher.SHA256
+	Commitment cipher.SHA256
+	UxCount    uint64
+}
+
+// ExportUnspentSnapshot writes uxs, the unspent output set after the block of seq, to w in the unspent snapshot
+// file format, with the headers of the blocks up to seq. The unspent pool only holds the unspent output set
+// after the head block, the unspent output set after an earlier block must be rebuilt by the caller.
+// uxs must match the unspent commitment stored for the block, otherwise ErrUnspentSnapshotCommitment is returned.
+func (bc *Blockchain) ExportUnspentSnapshot(tx *dbutil.Tx, w io.Writer, seq uint64, uxs coin.UxArray) (*UnspentSnapshotHeader, error) {
+	headSeq, ok, err := bc.meta.GetHeadSeq(tx)
+	if err != nil {
+		return nil, err
+	} else if !ok || seq > headSeq {
+		return nil, fmt.Errorf("block of seq %d does not exist", seq)
+	}
+
+	commitment, ok, err := bc.commitments.get(tx, seq)
+	if err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, fmt.Errorf("no unspent commitment is stored for block %d", seq)
+	}
+
+	var xorHash cipher.SHA256
+	for _, ux := range uxs {
+		if ux.Head.BkSeq > seq {
+			return nil, fmt.Errorf("uxout %s was created by block %d, after block %d", ux.Hash().Hex(), ux.Head.BkSeq, seq)
+		}
+		xorHash = xorHash.Xor(ux.SnapshotHash())
+	}
+
+	if xorHash != commitment {
+		return nil, ErrUnspentSnapshotCommitment
+	}
+
+	b, err := bc.tree.GetBlockInDepth(tx, seq, bc.walker)
+	if err != nil {
+		return nil, err
+	} else if b == nil {
+		return nil, fmt.Errorf("block of seq %d does not exist", seq)
+	}
+
+	hdr := &UnspentSnapshotHeader{
+		Seq:        seq,
+		BlockHash:  b.HashHeader(),
+		Commitment: commitment,
+		UxCount:    uint64(len(uxs)),
+	}
+
+	bw := bufio.NewWriter(w)
+
+	if err := binary.Write(bw, binary.LittleEndian, unspentSnapshotFileHeader{
+		Magic:      unspentSnapshotMagic,
+		Version:    UnspentSnapshotVersion,
+		Seq:        hdr.Seq,
+		BlockHash:  hdr.BlockHash,
+		Commitment: hdr.Commitment,
+		UxCount:    hdr.UxCount,
+	}); err != nil {
+		return nil, err
+	}
+
+	for i := uint64(0); i <= seq; i++ {
+		b, err := bc.GetSignedBlockBySeq(tx, i)
+		if err != nil {
+			return nil, err
+		} else if b == nil {
+			return nil, fmt.Errorf("block of seq %d does not exist", i)
+		}
+
+		if i > 0 {
+			b.Block = coin.Block{
+				Head: b.Head,
+			}
+		}
+
+		if err := writeUnspentSnapshotRecord(bw, encoder.Serialize(*b)); err != nil {
+			return nil, err
+		}
+	}
+
+	// The outputs are sorted so that the snapshot of a block is always the same file
+	sorted := make(coin.UxArray, len(uxs))
+	copy(sorted, uxs)
+	sorted.Sort()
+
+	for _, ux := range sorted {
+		if err := writeUnspentSnapshotRecord(bw, encoder.Serialize(ux)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := bw.Flush(); err != nil {
+		return nil, err
+	}
+
+	return hdr, nil
+}
+
+// ReadUnspentSnapshotHeader reads the header of an unspent output set snapshot from r.
+// The rest of the snapshot is read by ImportUnspentSnapshot.
+func ReadUnspentSnapshotHeader(r io.Reader) (*UnspentSnapshotHeader, error) {
+	var fh unspentSnapshotFileHeader
+	if err := binary.Read(r, binary.LittleEndian, &fh); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrUnspentSnapshotInvalid
+		}
+		return nil, err
+	}
+
+	if !bytes.Equal(fh.Magic[:], unspentSnapshotMagic[:]) {
+		return nil, ErrUnspentSnapshotInvalid
+	}
+
+	if fh.Version != UnspentSnapshotVersion {
+		return nil, NewErrUnspentSnapshotVersion(fh.Version)
+	}
+
+	return &UnspentSnapshotHeader{
+		Seq:        fh.Seq,
+		BlockHash:  fh.BlockHash,
+		Commitment: fh.Commitment,
+		UxCount:    fh.UxCount,
+	}, nil
+}
+
+// ImportUnspentSnapshot bootstraps an empty database from the rest of an unspent output set snapshot,
+// after its header hdr was read from r by ReadUnspentSnapshotHeader. r should be buffered.
+// verify is called with each block before it is stored, to check its signature.
+// The blocks are stored as if the blocks after the genesis block were pruned, and the unspent pool
+// is filled with the unspent outputs of the snapshot instead of executing the blocks.
+// Each block header commits to the unspent output set after the previous block with its UxHash,
+// so the outputs are authenticated when the header of the block after hdr.Seq is verified,
+// either the first block executed after the import or the head block of a snapshot exported below it.
+// The unspent outputs must match hdr.Commitment, otherwise ErrUnspentSnapshotCommitment is returned.
+func (bc *Blockchain) ImportUnspentSnapshot(tx *dbutil.Tx, hdr *UnspentSnapshotHeader, r io.Reader, verify func(*coin.SignedBlock) error) error {
+	if _, ok, err := bc.meta.GetHeadSeq(tx); err != nil {
+		return err
+	} else if ok {
+		return ErrUnspentSnapshotDBNotEmpty
+	}
+
+	var head *coin.SignedBlock
+	for seq := uint64(0); seq <= hdr.Seq; seq++ {
+		if err := tx.Context().Err(); err != nil {
+			return err
+		}
+
+		data, err := readUnspentSnapshotRecord(r, maxUnspentSnapshotBlockSize)
+		if err != nil {
+			return fmt.Errorf("Read snapshot block %d failed: %v", seq, err)
+		}
+
+		var b coin.SignedBlock
+		if err := encoder.DeserializeRaw(data, &b); err != nil {
+			return fmt.Errorf("Decode snapshot block %d failed: %v", seq, err)
+		}
+
+		if b.Seq() != seq {
+			return fmt.Errorf("Snapshot block seq is %d, expected %d", b.Seq(), seq)
+		}
+
+		if seq == 0 {
+			if b.HashBody() != b.Head.BodyHash {
+				return errors.New("Snapshot genesis block body does not match the body hash of its header")
+			}
+		} else {
+			b.Block = coin.Block{
+				Head: b.Head,
+			}
+		}
+
+		if err := verify(&b); err != nil {
+			return fmt.Errorf("Snapshot block %d: %v", seq, err)
+		}
+
+		if err := bc.sigs.Add(tx, b.HashHeader(), b.Sig); err != nil {
+			return fmt.Errorf("save signature failed: %v", err)
+		}
+
+		if err := bc.tree.AddBlock(tx, &b.Block); err != nil {
+			return fmt.Errorf("Snapshot block %d: save block failed: %v", seq, err)
+		}
+
+		if seq > 0 {
+			if err := bc.commitments.put(tx, seq-1, b.Head.UxHash); err != nil {
+				return err
+			}
+		} else if err := bc.stats.put(tx, &b.Block); err != nil {
+			return err
+		}
+
+		if err := bc.times.put(tx, b.Time(), seq); err != nil {
+			return err
+		}
+
+		head = &b
+	}
+
+	if head.HashHeader() != hdr.BlockHash {
+		return fmt.Errorf("Snapshot block %d hash does not match the snapshot block hash", hdr.Seq)
+	}
+
+	uxs := make(coin.UxArray, 0, hdr.UxCount)
+	for i := uint64(0); i < hdr.UxCount; i++ {
+		if err := tx.Context().Err(); err != nil {
+			return err
+		}
+
+		data, err := readUnspentSnapshotRecord(r, maxUnspentSnapshotUxOutSize)
+		if err != nil {
+			return fmt.Errorf("Read snapshot uxout failed: %v", err)
+		}
+
+		var ux coin.UxOut
+		if err := encoder.DeserializeRaw(data, &ux); err != nil {
+			return fmt.Errorf("Decode snapshot uxout failed: %v", err)
+		}
+
+		uxs = append(uxs, ux)
+	}
+
+	var b [1]byte
+	if _, err := io.ReadFull(r, b[:]); err != io.EOF {
+		return ErrUnspentSnapshotInvalid
+	}
+
+	xorHash, err := bc.unspent.LoadSnapshot(tx, hdr.Seq, uxs)
+	if err != nil {
+		return err
+	}
+
+	if xorHash != hdr.Commitment {
+		return ErrUnspentSnapshotCommitment
+	}
+
+	if err := bc.commitments.put(tx, hdr.Seq, xorHash); err != nil {
+		return err
+	}
+
+	if hdr.Seq > 0 {
+		if err := bc.meta.SetPrunedSeq(tx, hdr.Seq); err != nil {
+			return err
+		}
+	}
+
+	return bc.meta.SetHeadSeq(tx, hdr.Seq)
+}
+
+func writeUnspentSnapshotRecord(w io.Writer, data []byte) error {
+	if err := binary.Write(w, binary.LittleEndian, uint32(len(data))); err != nil {
+		return err
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+func readUnspentSnapshotRecord(r io.Reader, maxSize uint32) ([]byte, error) {
+	var length uint32
+	if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	if length > maxSize {
+		return nil, fmt.Errorf("record size %d exceeds the maximum of %d", length, maxSize)
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
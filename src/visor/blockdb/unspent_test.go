@@ -633,6 +633,72 @@ func TestUnspentProcessBlock(t *testing.T) {
 	}
 }
 
+func TestUnspentPoolLoadSnapshot(t *testing.T) {
+	db, teardown := prepareDB(t)
+	defer teardown()
+
+	up := NewUnspentPool()
+
+	var uxs coin.UxArray
+	var xorHash cipher.SHA256
+	for i := 0; i < 5; i++ {
+		ux := makeUxOut(t)
+		uxs = append(uxs, ux)
+		xorHash = xorHash.Xor(ux.SnapshotHash())
+	}
+
+	// The uxouts must have been created by the snapshot block or before
+	err := db.Update("", func(tx *dbutil.Tx) error {
+		_, err := up.LoadSnapshot(tx, 1, uxs)
+		return err
+	})
+	require.Error(t, err)
+
+	// An uxout can't be loaded twice
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		_, err := up.LoadSnapshot(tx, 3, append(uxs, uxs[0]))
+		return err
+	})
+	require.Equal(t, fmt.Errorf("attempted to insert uxout:%v twice into the unspent pool", uxs[0].Hash().Hex()), err)
+
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		h, err := up.LoadSnapshot(tx, 3, uxs)
+		require.NoError(t, err)
+		require.Equal(t, xorHash, h)
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = db.View("", func(tx *dbutil.Tx) error {
+		h, err := up.GetUxHash(tx)
+		require.NoError(t, err)
+		require.Equal(t, xorHash, h)
+
+		all, err := up.GetAll(tx)
+		require.NoError(t, err)
+		require.Equal(t, len(uxs), len(all))
+
+		addrUxs, err := up.GetUnspentsOfAddrs(tx, []cipher.Address{uxs[0].Body.Address})
+		require.NoError(t, err)
+		require.Equal(t, coin.UxArray{uxs[0]}, addrUxs[uxs[0].Body.Address])
+
+		// The address index is up to date with the snapshot block, not the newest uxout
+		height, ok, err := up.meta.getAddrIndexHeight(tx)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, uint64(3), height)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// A pool that is not empty can't be loaded
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		_, err := up.LoadSnapshot(tx, 3, nil)
+		return err
+	})
+	require.Equal(t, errors.New("unspent pool is not empty"), err)
+}
+
 func TestUnspentPoolAddrIndex(t *testing.T) {
 	addrs := make([]cipher.Address, 10)
 	for i := range addrs {
@@ -0,0 +1,214 @@
+package visor
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+// Checkpoint is the trusted hash of the block at a known height
+type Checkpoint struct {
+	Seq  uint64
+	Hash cipher.SHA256
+}
+
+// Checkpoints is a list of checkpoints ordered by seq
+type Checkpoints []Checkpoint
+
+// ErrCheckpointMismatch is returned if a block does not match the checkpoint at its height
+type ErrCheckpointMismatch struct {
+	Seq      uint64
+	Expected cipher.SHA256
+	Hash     cipher.SHA256
+}
+
+// NewErrCheckpointMismatch creates an ErrCheckpointMismatch
+func NewErrCheckpointMismatch(seq uint64, expected, hash cipher.SHA256) error {
+	return ErrCheckpointMismatch{
+		Seq:      seq,
+		Expected: expected,
+		Hash:     hash,
+	}
+}
+
+func (e ErrCheckpointMismatch) Error() string {
+	return fmt.Sprintf("Block %d hash %s does not match the checkpoint hash %s", e.Seq, e.Hash.Hex(), e.Expected.Hex())
+}
+
+// ParseCheckpoint parses a checkpoint in the format "seq:hash"
+func ParseCheckpoint(s string) (Checkpoint, error) {
+	pts := strings.Split(strings.TrimSpace(s), ":")
+	if len(pts) != 2 {
+		return Checkpoint{}, fmt.Errorf("Invalid checkpoint %q, must be in the format seq:hash", s)
+	}
+
+	seq, err := strconv.ParseUint(pts[0], 10, 64)
+	if err != nil {
+		return Checkpoint{}, fmt.Errorf("Invalid checkpoint %q seq: %v", s, err)
+	}
+
+	hash, err := cipher.SHA256FromHex(pts[1])
+	if err != nil {
+		return Checkpoint{}, fmt.Errorf("Invalid checkpoint %q hash: %v", s, err)
+	}
+
+	return Checkpoint{
+		Seq:  seq,
+		Hash: hash,
+	}, nil
+}
+
+// ParseCheckpoints parses a list of checkpoints in the format "seq:hash".
+// The checkpoints are sorted by seq, and there must be at most one checkpoint per seq.
+func ParseCheckpoints(s []string) (Checkpoints, error) {
+	cps := make(Checkpoints, 0, len(s))
+	for _, x := range s {
+		if strings.TrimSpace(x) == "" {
+			continue
+		}
+
+		cp, err := ParseCheckpoint(x)
+		if err != nil {
+			return nil, err
+		}
+		cps = append(cps, cp)
+	}
+
+	sort.Slice(cps, func(i, j int) bool {
+		return cps[i].Seq < cps[j].Seq
+	})
+
+	for i := 1; i < len(cps); i++ {
+		if cps[i].Seq == cps[i-1].Seq {
+			return nil, fmt.Errorf("Duplicate checkpoint for seq %d", cps[i].Seq)
+		}
+	}
+
+	return cps, nil
+}
+
+// Get returns the checkpoint at seq
+func (cps Checkpoints) Get(seq uint64) (Checkpoint, bool) {
+	i := sort.Search(len(cps), func(i int) bool {
+		return cps[i].Seq >= seq
+	})
+
+	if i < len(cps) && cps[i].Seq == seq {
+		return cps[i], true
+	}
+
+	return Checkpoint{}, false
+}
+
+// Latest returns the checkpoint with the highest seq that is not above maxSeq
+func (cps Checkpoints) Latest(maxSeq uint64) (Checkpoint, bool) {
+	i := sort.Search(len(cps), func(i int) bool {
+		return cps[i].Seq > maxSeq
+	})
+
+	if i == 0 {
+		return Checkpoint{}, false
+	}
+
+	return cps[i-1], true
+}
+
+// VerifyBlock returns ErrCheckpointMismatch if there is a checkpoint at the height of the block
+// and the block hash does not match it
+func (cps Checkpoints) VerifyBlock(b *coin.Block) error {
+	cp, ok := cps.Get(b.Seq())
+	if !ok {
+		return nil
+	}
+
+	if hash := b.HashHeader(); hash != cp.Hash {
+		return NewErrCheckpointMismatch(cp.Seq, cp.Hash, hash)
+	}
+
+	return nil
+}
+
+// Strings returns the checkpoints in the format "seq:hash"
+func (cps Checkpoints) Strings() []string {
+	s := make([]string, len(cps))
+	for i, cp := range cps {
+		s[i] = fmt.Sprintf("%d:%s", cp.Seq, cp.Hash.Hex())
+	}
+	return s
+}
+
+// verifyChainCheckpoints verifies that the blocks of the main chain match the checkpoints up to the head block
+func verifyChainCheckpoints(tx *dbutil.Tx, bc *Blockchain, cps Checkpoints) error {
+	headSeq, ok, err := bc.HeadSeq(tx)
+	if err != nil {
+		return err
+	} else if !ok {
+		return nil
+	}
+
+	for _, cp := range cps {
+		if cp.Seq > headSeq {
+			break
+		}
+
+		b, err := bc.GetSignedBlockBySeq(tx, cp.Seq)
+		if err != nil {
+			return err
+		}
+		if b == nil {
+			return NewErrBlockNotExist(cp.Seq)
+		}
+
+		if err := cps.VerifyBlock(&b.Block); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkpointedBlocks returns the hashes of the blocks that are authenticated by the latest checkpoint reached
+// by the main chain: the checkpoint block and the blocks below it, following the previous block hashes.
+// The header hash of a block covers the hash of the previous block, so these blocks can't be altered
+// without changing the hash of the checkpoint block, and their signatures don't need to be verified.
+// Call verifyChainCheckpoints first to verify that the main chain matches the checkpoints.
+func checkpointedBlocks(tx *dbutil.Tx, bc *Blockchain, cps Checkpoints) (map[cipher.SHA256]struct{}, error) {
+	headSeq, ok, err := bc.HeadSeq(tx)
+	if err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, nil
+	}
+
+	cp, ok := cps.Latest(headSeq)
+	if !ok {
+		return nil, nil
+	}
+
+	hashes := make(map[cipher.SHA256]struct{}, cp.Seq+1)
+	hash := cp.Hash
+	for seq := cp.Seq; ; seq-- {
+		b, err := bc.store.GetBlockByHash(tx, hash)
+		if err != nil {
+			return nil, err
+		}
+
+		// Stop at a missing or misplaced block, the blocks below are verified with their signatures
+		if b == nil || b.Seq() != seq {
+			return hashes, nil
+		}
+
+		hashes[hash] = struct{}{}
+
+		if seq == 0 {
+			return hashes, nil
+		}
+
+		hash = b.Head.PrevHash
+	}
+}
@@ -0,0 +1,334 @@
+package visor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+// testChainHashes returns the header hashes of the blocks of a testdata db file, indexed by seq
+func testChainHashes(t *testing.T, dbFile string) []cipher.SHA256 {
+	db, err := OpenDB(dbFile, true)
+	require.NoError(t, err)
+	defer db.Close()
+
+	bc, err := NewBlockchain(db, BlockchainConfig{})
+	require.NoError(t, err)
+
+	var hashes []cipher.SHA256
+	err = db.View("", func(tx *dbutil.Tx) error {
+		headSeq, ok, err := bc.HeadSeq(tx)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		for i := uint64(0); i <= headSeq; i++ {
+			b, err := bc.GetSignedBlockBySeq(tx, i)
+			require.NoError(t, err)
+			hashes = append(hashes, b.HashHeader())
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	return hashes
+}
+
+func TestParseCheckpoints(t *testing.T) {
+	h1 := testutil.RandSHA256(t)
+	h2 := testutil.RandSHA256(t)
+
+	tt := []struct {
+		name string
+		s    []string
+		cps  Checkpoints
+		err  error
+	}{
+		{
+			name: "empty",
+			s:    []string{""},
+			cps:  Checkpoints{},
+		},
+		{
+			name: "sorted by seq",
+			s:    []string{fmt.Sprintf("10:%s", h2.Hex()), fmt.Sprintf(" 0:%s ", h1.Hex())},
+			cps: Checkpoints{
+				{Seq: 0, Hash: h1},
+				{Seq: 10, Hash: h2},
+			},
+		},
+		{
+			name: "missing hash",
+			s:    []string{"10"},
+			err:  errors.New(`Invalid checkpoint "10", must be in the format seq:hash`),
+		},
+		{
+			name: "invalid seq",
+			s:    []string{fmt.Sprintf("x:%s", h1.Hex())},
+			err:  fmt.Errorf(`Invalid checkpoint "x:%s" seq: strconv.ParseUint: parsing "x": invalid syntax`, h1.Hex()),
+		},
+		{
+			name: "invalid hash",
+			s:    []string{"10:abcd"},
+			err:  errors.New(`Invalid checkpoint "10:abcd" hash: Invalid hex length`),
+		},
+		{
+			name: "duplicate seq",
+			s:    []string{fmt.Sprintf("10:%s", h1.Hex()), fmt.Sprintf("10:%s", h2.Hex())},
+			err:  errors.New("Duplicate checkpoint for seq 10"),
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			cps, err := ParseCheckpoints(tc.s)
+			if tc.err != nil {
+				require.Equal(t, tc.err, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.cps, cps)
+
+			parsed, err := ParseCheckpoints(cps.Strings())
+			require.NoError(t, err)
+			require.Equal(t, cps, parsed)
+		})
+	}
+}
+
+func TestCheckpointsGetLatest(t *testing.T) {
+	cps := Checkpoints{
+		{Seq: 0, Hash: testutil.RandSHA256(t)},
+		{Seq: 5, Hash: testutil.RandSHA256(t)},
+		{Seq: 10, Hash: testutil.RandSHA256(t)},
+	}
+
+	cp, ok := cps.Get(5)
+	require.True(t, ok)
+	require.Equal(t, cps[1], cp)
+
+	_, ok = cps.Get(6)
+	require.False(t, ok)
+
+	cp, ok = cps.Latest(9)
+	require.True(t, ok)
+	require.Equal(t, cps[1], cp)
+
+	cp, ok = cps.Latest(100)
+	require.True(t, ok)
+	require.Equal(t, cps[2], cp)
+
+	_, ok = cps[1:].Latest(4)
+	require.False(t, ok)
+
+	_, ok = Checkpoints(nil).Latest(100)
+	require.False(t, ok)
+}
+
+func TestCheckpointsVerifyBlock(t *testing.T) {
+	b := coin.Block{
+		Head: coin.BlockHeader{
+			BkSeq: 5,
+		},
+	}
+	hash := b.HashHeader()
+	otherHash := testutil.RandSHA256(t)
+
+	// No checkpoint at the height of the block
+	require.NoError(t, Checkpoints{{Seq: 4, Hash: otherHash}}.VerifyBlock(&b))
+
+	require.NoError(t, Checkpoints{{Seq: 5, Hash: hash}}.VerifyBlock(&b))
+
+	err := Checkpoints{{Seq: 5, Hash: otherHash}}.VerifyBlock(&b)
+	require.Equal(t, NewErrCheckpointMismatch(5, otherHash, hash), err)
+}
+
+func TestCheckDatabaseCheckpoints(t *testing.T) {
+	hashes := testChainHashes(t, "./testdata/data.db.ok")
+	headSeq := uint64(len(hashes) - 1)
+	pubkey := mustParsePubkey(t)
+
+	// The signatures don't match this pubkey, so only the blocks authenticated by a checkpoint pass verification
+	otherPubkey, _ := cipher.GenerateKeyPair()
+
+	tt := []struct {
+		name        string
+		pubkey      cipher.PubKey
+		checkpoints Checkpoints
+		err         error
+	}{
+		{
+			name:   "matching checkpoints",
+			pubkey: pubkey,
+			checkpoints: Checkpoints{
+				{Seq: 0, Hash: hashes[0]},
+				{Seq: 5, Hash: hashes[5]},
+			},
+		},
+		{
+			name:   "checkpoint above the head block",
+			pubkey: pubkey,
+			checkpoints: Checkpoints{
+				{Seq: headSeq + 100, Hash: hashes[0]},
+			},
+		},
+		{
+			name:   "checkpoint mismatch",
+			pubkey: pubkey,
+			checkpoints: Checkpoints{
+				{Seq: 5, Hash: hashes[6]},
+			},
+			err: NewErrCheckpointMismatch(5, hashes[6], hashes[5]),
+		},
+		{
+			name:   "head checkpoint skips all signatures",
+			pubkey: otherPubkey,
+			checkpoints: Checkpoints{
+				{Seq: headSeq, Hash: hashes[headSeq]},
+			},
+		},
+		{
+			name:   "signatures above the checkpoint are verified",
+			pubkey: otherPubkey,
+			checkpoints: Checkpoints{
+				{Seq: 5, Hash: hashes[5]},
+			},
+			err: errors.New("Recovered pubkey does not match pubkey"),
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			db, shutdown := copyTestDB(t, "./testdata/data.db.ok")
+			defer shutdown()
+
+			err := CheckDatabase(context.Background(), db, CheckDatabaseConfig{
+				Pubkey:      tc.pubkey,
+				FullVerify:  true,
+				Checkpoints: tc.checkpoints,
+			})
+
+			if tc.err == nil {
+				require.NoError(t, err)
+				return
+			}
+
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.err.Error())
+			if _, ok := tc.err.(ErrCheckpointMismatch); ok {
+				require.Equal(t, tc.err, err)
+				require.True(t, IsCorruptDBError(err))
+			}
+		})
+	}
+}
+
+func TestImportSnapshotCheckpoints(t *testing.T) {
+	data, count, _ := exportTestSnapshot(t, "./testdata/data.db.ok")
+	hashes := testChainHashes(t, "./testdata/data.db.ok")
+	headSeq := count - 1
+	pubkey := mustParsePubkey(t)
+	otherPubkey, _ := cipher.GenerateKeyPair()
+
+	tt := []struct {
+		name string
+		cfg  ImportSnapshotConfig
+		err  error
+	}{
+		{
+			name: "matching checkpoints",
+			cfg: ImportSnapshotConfig{
+				Pubkey: pubkey,
+				Checkpoints: Checkpoints{
+					{Seq: 0, Hash: hashes[0]},
+					{Seq: headSeq, Hash: hashes[headSeq]},
+				},
+			},
+		},
+		{
+			name: "checkpoint mismatch",
+			cfg: ImportSnapshotConfig{
+				Pubkey: pubkey,
+				Checkpoints: Checkpoints{
+					{Seq: 3, Hash: hashes[4]},
+				},
+			},
+			err: NewErrCheckpointMismatch(3, hashes[4], hashes[3]),
+		},
+		{
+			name: "signatures are verified unless skipped",
+			cfg: ImportSnapshotConfig{
+				Pubkey: otherPubkey,
+				Checkpoints: Checkpoints{
+					{Seq: headSeq, Hash: hashes[headSeq]},
+				},
+			},
+			err: errors.New("Snapshot block 0: Recovered pubkey does not match pubkey"),
+		},
+		{
+			name: "skip signatures up to the head checkpoint",
+			cfg: ImportSnapshotConfig{
+				Pubkey: otherPubkey,
+				Checkpoints: Checkpoints{
+					{Seq: headSeq, Hash: hashes[headSeq]},
+				},
+				SkipCheckpointedSignatures: true,
+			},
+		},
+		{
+			name: "signatures above the checkpoint are verified",
+			cfg: ImportSnapshotConfig{
+				Pubkey: otherPubkey,
+				Checkpoints: Checkpoints{
+					{Seq: 5, Hash: hashes[5]},
+				},
+				SkipCheckpointedSignatures: true,
+			},
+			err: errors.New("Snapshot block 6: Recovered pubkey does not match pubkey"),
+		},
+		{
+			name: "skipping signatures requires a checkpoint in the snapshot",
+			cfg: ImportSnapshotConfig{
+				Pubkey: otherPubkey,
+				Checkpoints: Checkpoints{
+					{Seq: headSeq + 1, Hash: hashes[0]},
+				},
+				SkipCheckpointedSignatures: true,
+			},
+			err: errors.New("Snapshot block 0: Recovered pubkey does not match pubkey"),
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			db, shutdown := testutil.PrepareDB(t)
+			defer shutdown()
+
+			n, err := ImportSnapshot(context.Background(), db, bytes.NewReader(data), tc.cfg)
+			if tc.err != nil {
+				require.Equal(t, tc.err, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, count, n)
+
+			// The imported blockchain matches the checkpoints
+			err = CheckDatabase(context.Background(), db, CheckDatabaseConfig{
+				Pubkey:      pubkey,
+				FullVerify:  true,
+				Checkpoints: tc.cfg.Checkpoints,
+			})
+			require.NoError(t, err)
+		})
+	}
+}
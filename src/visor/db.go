@@ -1,6 +1,7 @@
 package visor
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/base64"
 	"errors"
@@ -8,11 +9,11 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"sync"
 	"time"
 
-	"github.com/boltdb/bolt"
-
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/coin"
 	"github.com/skycoin/skycoin/src/util/elapse"
@@ -24,22 +25,57 @@ import (
 var (
 	// BlockchainVerifyTheadNum number of goroutines to use for signature and historydb verification
 	BlockchainVerifyTheadNum = 4
+
+	// VerifyProgressLogRate is the minimum interval between database verification progress log messages
+	VerifyProgressLogRate = time.Second * 10
 )
 
 // ErrCorruptDB is returned if the database is corrupted
 // The original corruption error is embedded
 type ErrCorruptDB struct {
 	error
+	// CorruptedBlocks are the seqs of the blocks whose signature or body failed verification, in ascending order.
+	// These blocks can be repaired with copies received from peers, see Visor.RepairBlocks.
+	CorruptedBlocks []uint64
+}
+
+// NewErrCorruptDB creates ErrCorruptDB
+func NewErrCorruptDB(err error, corruptedBlocks []uint64) error {
+	return ErrCorruptDB{
+		error:           err,
+		CorruptedBlocks: corruptedBlocks,
+	}
 }
 
-// CheckDatabase checks the database for corruption, rebuild history if corrupted
-func CheckDatabase(db *dbutil.DB, pubkey cipher.PubKey, quit chan struct{}) error {
+// CheckDatabaseConfig configures CheckDatabase
+type CheckDatabaseConfig struct {
+	// Public key of the blockchain, used to verify the block signatures
+	Pubkey cipher.PubKey
+	// Verify the entire blockchain, ignoring the verification checkpoint
+	FullVerify bool
+	// Progress is called periodically with the verification progress, optional
+	Progress func(VerifyProgress)
+	// Workers is the number of goroutines used to verify blocks.
+	// If 0, BlockchainVerifyTheadNum is used. If VerifyWorkersAuto, the number is tuned automatically.
+	Workers int
+	// Checkpoints are trusted block hashes that the blockchain must match.
+	// The signatures of the blocks below the latest checkpoint reached by the blockchain are not verified.
+	Checkpoints Checkpoints
+}
+
+// CheckDatabase checks the database for corruption, rebuild history if corrupted.
+// Only the blocks added since the last successful verification are checked,
+// unless cfg.FullVerify is true or no valid verification checkpoint exists.
+// If the database is writable, the verification checkpoint is updated on success,
+// and a DBIntegrityReport of the verification is saved.
+// The verification stops when ctx is done and returns the context error.
+func CheckDatabase(ctx context.Context, db *dbutil.DB, cfg CheckDatabaseConfig) error {
 	elapser := elapse.NewElapser(time.Second*30, logger)
 	elapser.Register("CheckDatabase")
 	defer elapser.CheckForDone()
 
 	var blocksBktExist bool
-	if err := db.View("CheckDatabase", func(tx *dbutil.Tx) error {
+	if err := db.ViewContext(ctx, "CheckDatabase", func(tx *dbutil.Tx) error {
 		blocksBktExist = dbutil.Exists(tx, blockdb.BlocksBkt)
 		return nil
 	}); err != nil {
@@ -51,53 +87,319 @@ func CheckDatabase(db *dbutil.DB, pubkey cipher.PubKey, quit chan struct{}) erro
 		return nil
 	}
 
-	bc, err := NewBlockchain(db, BlockchainConfig{Pubkey: pubkey})
+	var report DBIntegrityReport
+	err := checkDatabase(ctx, db, cfg, &report)
+
+	// A stopped verification is not reported
+	if db.IsReadOnly() || (err != nil && err == ctx.Err()) {
+		return err
+	}
+
+	report.Time = uint64(time.Now().UTC().Unix())
 	if err != nil {
+		report.Error = err.Error()
+	}
+
+	if saveErr := saveDBIntegrityReport(db, report); saveErr != nil {
+		logger.WithError(saveErr).Error("saveDBIntegrityReport failed")
+		if err == nil {
+			return saveErr
+		}
+	}
+
+	return err
+}
+
+// checkDatabase verifies the database for CheckDatabase, recording the outcome in report
+func checkDatabase(ctx context.Context, db *dbutil.DB, cfg CheckDatabaseConfig, report *DBIntegrityReport) error {
+	bc, err := NewBlockchain(db, BlockchainConfig{
+		Pubkey:            cfg.Pubkey,
+		WalkChainProgress: cfg.Progress,
+		VerifyWorkers:     cfg.Workers,
+	})
+	if err != nil {
+		return err
+	}
+
+	var startSeq uint64
+	if !cfg.FullVerify {
+		startSeq, err = verifyStartSeq(db, bc)
+		if err != nil {
+			return err
+		}
+	}
+
+	report.FullVerify = startSeq == 0
+	if startSeq == 0 {
+		logger.Info("CheckDatabase: verifying the full blockchain")
+	} else {
+		logger.Infof("CheckDatabase: verifying blocks from seq %d", startSeq)
+	}
+
+	// Blocks authenticated by a checkpoint don't need their signatures verified
+	var checkpointed map[cipher.SHA256]struct{}
+	if len(cfg.Checkpoints) != 0 {
+		if err := db.ViewContext(ctx, "CheckDatabase checkpoints", func(tx *dbutil.Tx) error {
+			if err := verifyChainCheckpoints(tx, bc, cfg.Checkpoints); err != nil {
+				return err
+			}
+
+			var err error
+			checkpointed, err = checkpointedBlocks(tx, bc, cfg.Checkpoints)
+			return err
+		}); err != nil {
+			return err
+		}
+
+		if len(checkpointed) != 0 {
+			logger.Infof("CheckDatabase: %d blocks are authenticated by checkpoints, skipping their signatures", len(checkpointed))
+		}
+	}
+
+	// The body of pruned blocks is removed, so it can't be verified
+	var prunedSeq uint64
+	if err := db.ViewContext(ctx, "CheckDatabase pruned seq", func(tx *dbutil.Tx) error {
+		var err error
+		report.HeadSeq, _, err = bc.HeadSeq(tx)
+		if err != nil {
+			return err
+		}
+
+		prunedSeq, err = bc.PrunedSeq(tx)
+		return err
+	}); err != nil {
 		return err
 	}
 
 	history := historydb.New()
 	indexesMap := historydb.NewIndexesMap()
 
+	// The address indexes are emptied when the database is encrypted, and the history is parsed again
+	// when the node starts, so it is not verified
+	var historyEmptied bool
+	if err := db.ViewContext(ctx, "CheckDatabase history", func(tx *dbutil.Tx) error {
+		var err error
+		historyEmptied, err = history.AddressIndexesEmptied(tx)
+		return err
+	}); err != nil {
+		return err
+	}
+
 	var historyVerifyErr error
+	var corruptErr historydb.ErrHistoryDBCorrupted
+	var corruptedBlocks []uint64
+
+	// Blocks with an invalid signature or body, and the error of the lowest of them
+	var blockErr error
+	var badBlocks []uint64
+	var badSigs, verifiedSigs int
+
 	var lock sync.Mutex
+	addBadBlock := func(seq uint64, err error) {
+		// Report the error of the lowest corrupted block, the order blocks are walked in is not deterministic
+		if len(badBlocks) == 0 || seq < badBlocks[0] {
+			blockErr = err
+			badBlocks = append([]uint64{seq}, badBlocks...)
+		} else {
+			badBlocks = append(badBlocks, seq)
+		}
+	}
+
 	verifyFunc := func(tx *dbutil.Tx, b *coin.SignedBlock) error {
 		// Verify signature
-		if err := bc.VerifySignature(b); err != nil {
-			return err
+		var sigErr error
+		_, sigSkipped := checkpointed[b.HashHeader()]
+		if !sigSkipped {
+			sigErr = bc.VerifySignature(b)
+		}
+
+		// Verify that the body matches the header
+		var bodyErr error
+		if (b.Seq() == 0 || b.Seq() > prunedSeq) && b.HashBody() != b.Head.BodyHash {
+			bodyErr = fmt.Errorf("Block %d body does not match the body hash of its header", b.Seq())
 		}
 
 		// Verify historydb, we don't return the error of history.Verify here,
 		// as we have to check all signature, if we return error early here, the
 		// potential bad signature won't be detected.
+		// All blocks with corrupted indexes are recorded, so that only those need to be rebuilt.
+		// Likewise, all blocks with an invalid signature or body are recorded, so that only those need to be repaired.
 		lock.Lock()
 		defer lock.Unlock()
-		if historyVerifyErr == nil {
-			historyVerifyErr = history.Verify(tx, b, indexesMap)
+		report.BlocksVerified++
+
+		switch {
+		case sigErr != nil:
+			badSigs++
+			addBadBlock(b.Seq(), sigErr)
+			return nil
+		case bodyErr != nil:
+			addBadBlock(b.Seq(), bodyErr)
+			return nil
+		case !sigSkipped:
+			verifiedSigs++
+		}
+
+		if historyVerifyErr != nil || historyEmptied {
+			return nil
+		}
+
+		if err := history.Verify(tx, b, indexesMap); err != nil {
+			switch e := err.(type) {
+			case historydb.ErrHistoryDBCorrupted:
+				// Report the error of the lowest corrupted block, the order blocks are walked in is not deterministic
+				if len(corruptedBlocks) == 0 || b.Seq() < corruptedBlocks[0] {
+					corruptErr = e
+					corruptedBlocks = append([]uint64{b.Seq()}, corruptedBlocks...)
+				} else {
+					corruptedBlocks = append(corruptedBlocks, b.Seq())
+				}
+			default:
+				historyVerifyErr = err
+			}
 		}
 		return nil
 	}
 
-	err = bc.WalkChain(BlockchainVerifyTheadNum, verifyFunc, quit)
-	switch err.(type) {
-	case nil:
-		lock.Lock()
-		err = historyVerifyErr
-		lock.Unlock()
-		return err
-	default:
+	if startSeq == 0 {
+		err = bc.WalkChain(ctx, bc.VerifyWorkers(), verifyFunc)
+	} else {
+		err = bc.WalkChainFrom(ctx, startSeq, bc.VerifyWorkers(), verifyFunc)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	// The walk stops at the first block with a missing signature
+	if e, ok := err.(blockdb.ErrMissingSignature); ok {
+		addBadBlock(e.Seq(), err)
+	} else if err != nil {
 		return err
 	}
+
+	if historyVerifyErr != nil {
+		return historyVerifyErr
+	}
+
+	sort.Slice(corruptedBlocks, func(i, j int) bool {
+		return corruptedBlocks[i] < corruptedBlocks[j]
+	})
+	report.CorruptedHistoryBlocks = corruptedBlocks
+
+	if len(badBlocks) != 0 {
+		// If no signature could be verified, the blockchain pubkey is more likely wrong than the blocks corrupted
+		if verifiedSigs == 0 && badSigs == len(badBlocks) {
+			return blockErr
+		}
+
+		sort.Slice(badBlocks, func(i, j int) bool {
+			return badBlocks[i] < badBlocks[j]
+		})
+		report.CorruptedBlocks = badBlocks
+		return NewErrCorruptDB(blockErr, badBlocks)
+	}
+
+	if len(corruptedBlocks) != 0 {
+		corruptErr.CorruptedBlocks = corruptedBlocks
+		return corruptErr
+	}
+
+	if db.IsReadOnly() {
+		return nil
+	}
+
+	return saveVerifyCheckpoint(db, bc)
 }
 
-// backup the corrypted db first, then rebuild the history DB.
-func rebuildHistoryDB(db *dbutil.DB, history *historydb.HistoryDB, bc *Blockchain, quit chan struct{}) (*dbutil.DB, error) { // nolint: unused,megacheck
-	db, err := backupDB(db)
-	if err != nil {
-		return nil, err
+// ParseVerifyWorkers parses the number of database verification goroutines,
+// which is either a positive integer or "auto" for VerifyWorkersAuto
+func ParseVerifyWorkers(s string) (int, error) {
+	if s == "auto" {
+		return VerifyWorkersAuto, nil
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("Invalid number of verification workers %q, must be a positive integer or \"auto\"", s)
+	}
+
+	return n, nil
+}
+
+// NewVerifyProgressLogger returns a CheckDatabaseConfig.Progress callback that logs
+// the verification progress, at most once per interval and when the verification completes
+func NewVerifyProgressLogger(interval time.Duration) func(VerifyProgress) {
+	var lastLog time.Time
+	return func(p VerifyProgress) {
+		if p.Remaining != 0 && time.Since(lastLog) < interval {
+			return
+		}
+		lastLog = time.Now()
+
+		total := p.Verified + p.Remaining
+		var pct float64
+		if total > 0 {
+			pct = float64(p.Verified) / float64(total) * 100
+		}
+
+		logger.Infof("Verified %d/%d blocks (%.1f%%), ETA %s", p.Verified, total, pct, p.ETA.Round(time.Second))
+	}
+}
+
+// verifyStartSeq returns the seq of the first block that has not been verified yet.
+// If the checkpoint is missing, or the checkpoint block is no longer in the main chain, returns 0.
+func verifyStartSeq(db *dbutil.DB, bc *Blockchain) (uint64, error) {
+	var startSeq uint64
+	if err := db.View("verifyStartSeq", func(tx *dbutil.Tx) error {
+		cp, err := dbutil.GetVerifyCheckpoint(tx)
+		if err != nil {
+			return err
+		} else if cp == nil {
+			return nil
+		}
+
+		b, err := bc.GetSignedBlockBySeq(tx, cp.Seq)
+		if err != nil {
+			return err
+		}
+
+		if b == nil || b.HashHeader() != cp.BlockHash {
+			logger.Warningf("Verification checkpoint at seq %d does not match the blockchain, ignoring it", cp.Seq)
+			return nil
+		}
+
+		startSeq = cp.Seq + 1
+		return nil
+	}); err != nil {
+		return 0, err
 	}
 
-	if err := db.Update("Rebuild history db", func(tx *dbutil.Tx) error {
+	return startSeq, nil
+}
+
+// saveVerifyCheckpoint records the current head block as verified
+func saveVerifyCheckpoint(db *dbutil.DB, bc *Blockchain) error {
+	return db.Update("saveVerifyCheckpoint", func(tx *dbutil.Tx) error {
+		head, err := bc.Head(tx)
+		if err != nil {
+			return err
+		}
+
+		return dbutil.SetVerifyCheckpoint(tx, dbutil.VerifyCheckpoint{
+			Seq:       head.Seq(),
+			BlockHash: head.HashHeader(),
+		})
+	})
+}
+
+// rebuildHistoryDB erases the history DB and parses every block again.
+// If ctx is done before the rebuild completes, no changes are saved.
+func rebuildHistoryDB(ctx context.Context, db *dbutil.DB, history *historydb.HistoryDB, bc *Blockchain) error {
+	return db.UpdateContext(ctx, "Rebuild history db", func(tx *dbutil.Tx) error {
+		if err := dbutil.ResetVerifyCheckpoint(tx); err != nil {
+			return err
+		}
+
 		if err := history.Erase(tx); err != nil {
 			return err
 		}
@@ -112,111 +414,461 @@ func rebuildHistoryDB(db *dbutil.DB, history *historydb.HistoryDB, bc *Blockchai
 		}
 
 		for i := uint64(0); i <= headSeq; i++ {
-			select {
-			case <-quit:
-				return nil
-			default:
+			if err := tx.Context().Err(); err != nil {
+				return err
+			}
+
+			b, err := bc.GetSignedBlockBySeq(tx, i)
+			if err != nil {
+				return err
+			}
+
+			if err := history.ParseBlock(tx, b.Block); err != nil {
+				return err
+			}
+
+			if i%1000 == 0 {
+				logger.Critical().Infof("Parse block: %d", i)
+			}
+		}
+		return nil
+	})
+}
+
+// blockRange is an inclusive range of block seqs
+type blockRange struct {
+	start uint64
+	end   uint64
+}
+
+// groupBlockRanges groups sorted block seqs into ranges of consecutive seqs
+func groupBlockRanges(seqs []uint64) []blockRange {
+	var ranges []blockRange
+	for _, seq := range seqs {
+		if n := len(ranges); n != 0 && ranges[n-1].end+1 == seq {
+			ranges[n-1].end = seq
+			continue
+		}
+		ranges = append(ranges, blockRange{
+			start: seq,
+			end:   seq,
+		})
+	}
+	return ranges
+}
+
+// errRepairIncomplete is returned by rebuildHistoryDBBlocks if the history DB is still corrupted after repairing the blocks
+var errRepairIncomplete = errors.New("history db is still corrupted after repairing the corrupted blocks")
+
+// rebuildHistoryDBBlocks repairs the history DB indexes of the corrupted blocks only,
+// then verifies them again. corruptedBlocks must be sorted in ascending order.
+// The broken index entries found by historydb.VerifyDiff are patched, and the indexes of a block are rebuilt
+// if its diff can't be patched on its own.
+// Returns errRepairIncomplete if the repaired blocks fail verification, in which case no changes are saved.
+// If ctx is done before the rebuild completes, no changes are saved.
+func rebuildHistoryDBBlocks(ctx context.Context, db *dbutil.DB, history *historydb.HistoryDB, bc *Blockchain, corruptedBlocks []uint64) error {
+	return db.UpdateContext(ctx, "Rebuild history db blocks", func(tx *dbutil.Tx) error {
+		if err := dbutil.ResetVerifyCheckpoint(tx); err != nil {
+			return err
+		}
+
+		// The diffs of all blocks are collected before patching any of them,
+		// so that they are all relative to the corrupted indexes
+		blocks := make([]*coin.SignedBlock, 0, len(corruptedBlocks))
+		diffs := make([]*historydb.BlockIndexDiff, 0, len(corruptedBlocks))
+		indexesMap := historydb.NewIndexesMap()
+		for _, r := range groupBlockRanges(corruptedBlocks) {
+			logger.Critical().Infof("Repairing history db for blocks %d-%d", r.start, r.end)
+
+			for i := r.start; i <= r.end; i++ {
+				if err := tx.Context().Err(); err != nil {
+					return err
+				}
+
 				b, err := bc.GetSignedBlockBySeq(tx, i)
 				if err != nil {
 					return err
 				}
+				if b == nil {
+					return NewErrBlockNotExist(i)
+				}
 
-				if err := history.ParseBlock(tx, b.Block); err != nil {
+				diff, err := history.VerifyDiff(tx, b, indexesMap)
+				if err != nil {
 					return err
 				}
 
-				if i%1000 == 0 {
-					logger.Critical().Infof("Parse block: %d", i)
+				blocks = append(blocks, b)
+				diffs = append(diffs, diff)
+			}
+		}
+
+		for i, b := range blocks {
+			if err := tx.Context().Err(); err != nil {
+				return err
+			}
+
+			switch err := history.PatchBlock(tx, b.Block, diffs[i]); err {
+			case nil:
+				logger.Critical().Infof("Patched %d history db index entries of block %d", len(diffs[i].Entries), b.Seq())
+			case historydb.ErrIndexDiffNotPatchable:
+				logger.Critical().Infof("Rebuilding history db for block %d", b.Seq())
+				if err := history.RepairBlock(tx, b.Block); err != nil {
+					return err
+				}
+			default:
+				return err
+			}
+		}
+
+		indexesMap = historydb.NewIndexesMap()
+		for _, b := range blocks {
+			if err := history.Verify(tx, b, indexesMap); err != nil {
+				switch err.(type) {
+				case historydb.ErrHistoryDBCorrupted:
+					logger.Critical().WithError(err).Errorf("Block %d is still corrupted after repairing", b.Seq())
+					return errRepairIncomplete
+				default:
+					return err
 				}
 			}
 		}
+
 		return nil
-	}); err != nil {
-		return nil, err
-	}
-	return db, nil
+	})
 }
 
 // backupDB makes a backup copy of the DB
-func backupDB(db *dbutil.DB) (*dbutil.DB, error) { // nolint: unused,megacheck
+func backupDB(db *dbutil.DB) (*dbutil.DB, error) {
 	// backup the corrupted database
 	dbReadOnly := db.IsReadOnly()
 
 	dbPath := db.Path()
+	dbFiles := DBFiles(db)
+	encryption := db.Encryption()
+	options := dbOptions(db)
 
 	if err := db.Close(); err != nil {
 		return nil, fmt.Errorf("Failed to close db: %v", err)
 	}
 
-	corruptDBPath, err := copyCorruptDB(dbPath)
-	if err != nil {
-		return nil, fmt.Errorf("Failed to copy corrupted db: %v", err)
-	}
+	for _, path := range dbFiles {
+		corruptDBPath, err := copyCorruptDB(path)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to copy corrupted db: %v", err)
+		}
 
-	logger.Critical().Infof("Copy corrupted db to %s", corruptDBPath)
+		logger.Critical().Infof("Copy corrupted db to %s", corruptDBPath)
+	}
 
 	// Open the database again
-	return OpenDB(dbPath, dbReadOnly)
+	cfg := NewOpenDBConfig()
+	cfg.ReadOnly = dbReadOnly
+	cfg.Encryption = encryption
+	cfg.Options = options
+	return OpenDBWithConfig(dbPath, cfg)
 }
 
 // ResetCorruptDB checks the database for corruption and if corrupted and
 // is ErrMissingSignature, then then it erases the db and starts over.
-// If it's ErrHistoryDBCorrupted, then rebuild historydb from scratch.
+// If it's ErrHistoryDBCorrupted, then the historydb indexes of the corrupted blocks are rebuilt.
 // A copy of the corrupted database is saved.
-func ResetCorruptDB(db *dbutil.DB, pubkey cipher.PubKey, quit chan struct{}) (*dbutil.DB, error) {
-	err := CheckDatabase(db, pubkey, quit)
-	switch err.(type) {
-	case nil:
+func ResetCorruptDB(ctx context.Context, db *dbutil.DB, cfg CheckDatabaseConfig) (*dbutil.DB, error) {
+	err := CheckDatabase(ctx, db, cfg)
+	if err == nil {
 		return db, nil
-	case blockdb.ErrMissingSignature,
-		historydb.ErrHistoryDBCorrupted:
-		logger.Critical().Errorf("Database is corrupted, recreating db: %v", err)
-		return resetCorruptDB(db)
-	default:
+	}
+
+	if !IsCorruptDBError(err) {
 		return nil, err
 	}
+
+	return RecoverCorruptDB(ctx, db, RecoverCorruptDBConfig{Pubkey: cfg.Pubkey}, err)
+}
+
+// IsCorruptDBError returns true if the error returned by CheckDatabase indicates a corrupted database
+func IsCorruptDBError(err error) bool {
+	switch err.(type) {
+	case ErrCorruptDB,
+		blockdb.ErrMissingSignature,
+		historydb.ErrHistoryDBCorrupted,
+		ErrCheckpointMismatch:
+		return true
+	default:
+		return false
+	}
 }
 
-func rebuildCorruptDB(db *dbutil.DB, pubkey cipher.PubKey, quit chan struct{}) (*dbutil.DB, error) { //nolint: deadcode,unused,megacheck
+// RecoverCorruptDBConfig configures RecoverCorruptDB
+type RecoverCorruptDBConfig struct {
+	// Public key of the blockchain
+	Pubkey cipher.PubKey
+	// Version of the node, recorded in the forensic bundle
+	NodeVersion string
+}
+
+// RecoverCorruptDB recovers the db after CheckDatabase returned a corruption error.
+// If the historydb is corrupted, only the indexes of the corrupted blocks are rebuilt,
+// otherwise the db is recreated.
+// The db must not be in use by a running visor.
+// A copy of the corrupted database is saved, with a forensic bundle of the corruption, see DBForensics.
+// PlanRecoverCorruptDB reports the recovery without performing it.
+func RecoverCorruptDB(ctx context.Context, db *dbutil.DB, cfg RecoverCorruptDBConfig, err error) (*dbutil.DB, error) {
+	if !IsCorruptDBError(err) {
+		return nil, fmt.Errorf("RecoverCorruptDB: not a database corruption error: %v", err)
+	}
+
+	writeCorruptDBForensics(ctx, db, cfg, err)
+
+	switch recoverCorruptDBAction(err, db.IsReadOnly()) {
+	case RecoverActionRebuildHistory, RecoverActionRebuildHistoryBlocks:
+		logger.Critical().Errorf("History database is corrupted, rebuilding history db: %v", err)
+		return rebuildCorruptDB(ctx, db, cfg.Pubkey, err.(historydb.ErrHistoryDBCorrupted).CorruptedBlocks)
+	default:
+		logger.Critical().Errorf("Database is corrupted, recreating db: %v", err)
+		return resetCorruptDB(db, err)
+	}
+}
+
+// rebuildCorruptDB makes a backup copy of the db, then rebuilds the historydb.
+// Only the broken index entries of the corrupted blocks are repaired, unless no corrupted blocks are given,
+// or the historydb is still corrupted after repairing them.
+func rebuildCorruptDB(ctx context.Context, db *dbutil.DB, pubkey cipher.PubKey, corruptedBlocks []uint64) (*dbutil.DB, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	db, err := backupDB(db)
+	if err != nil {
+		return nil, err
+	}
+
 	history := historydb.New()
 	bc, err := NewBlockchain(db, BlockchainConfig{Pubkey: pubkey})
 	if err != nil {
 		return nil, err
 	}
 
-	return rebuildHistoryDB(db, history, bc, quit)
+	if len(corruptedBlocks) != 0 {
+		logger.Critical().Infof("Repairing history db for %d corrupted blocks", len(corruptedBlocks))
+		switch err := rebuildHistoryDBBlocks(ctx, db, history, bc, corruptedBlocks); err {
+		case nil:
+			recordDBIntegrityAction(db, "Repaired the history db indexes of corrupted blocks %v", corruptedBlocks)
+			return db, nil
+		case errRepairIncomplete:
+			logger.Critical().Info("Repairing the corrupted blocks failed, rebuilding the entire history db")
+		default:
+			return nil, err
+		}
+	}
+
+	if err := rebuildHistoryDB(ctx, db, history, bc); err != nil {
+		return nil, err
+	}
+
+	recordDBIntegrityAction(db, "Rebuilt the history db")
+
+	return db, nil
 }
 
-// resetCorruptDB recreates the DB, making a backup copy marked as corrupted
-func resetCorruptDB(db *dbutil.DB) (*dbutil.DB, error) {
+// resetCorruptDB recreates the DB, making a backup copy marked as corrupted.
+// corruptErr is the corruption error recorded in the DBIntegrityReport of the new DB.
+func resetCorruptDB(db *dbutil.DB, corruptErr error) (*dbutil.DB, error) {
 	dbReadOnly := db.IsReadOnly()
 	dbPath := db.Path()
+	dbFiles := DBFiles(db)
+	split := db.IsSplit()
+	encryption := db.Encryption()
+	options := dbOptions(db)
 
 	if err := db.Close(); err != nil {
 		return nil, fmt.Errorf("Failed to close db: %v", err)
 	}
 
-	corruptDBPath, err := moveCorruptDB(dbPath)
+	// The part files of a split database are moved too, the primary file's path is recorded in the report
+	var corruptDBPath string
+	for _, path := range dbFiles {
+		movedPath, err := moveCorruptDB(path)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to copy corrupted db: %v", err)
+		}
+
+		logger.Critical().Infof("Moved corrupted db to %s", movedPath)
+
+		if corruptDBPath == "" {
+			corruptDBPath = movedPath
+		}
+	}
+
+	// The recreated database is encrypted with the same key
+	cfg := NewOpenDBConfig()
+	cfg.ReadOnly = dbReadOnly
+	cfg.Split = split
+	cfg.Encryption = encryption
+	cfg.Options = options
+	newDB, err := OpenDBWithConfig(dbPath, cfg)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to copy corrupted db: %v", err)
+		return nil, err
 	}
 
-	logger.Critical().Infof("Moved corrupted db to %s", corruptDBPath)
+	// The recreated database is empty, so it has the latest schema version
+	if err := MigrateDB(newDB); err != nil {
+		newDB.Close()
+		return nil, err
+	}
+
+	recordDBIntegrityAction(newDB, "Reset the corrupted database, moved it to %s: %v", corruptDBPath, corruptErr)
+
+	return newDB, nil
+}
 
-	return OpenDB(dbPath, dbReadOnly)
+// OpenDBConfig configures how the database files are opened
+type OpenDBConfig struct {
+	// Open the database read-only
+	ReadOnly bool
+	// Store the history db and the unconfirmed transaction pool in separate files, see OpenSplitDB
+	Split bool
+	// How long to wait for the file lock held by another process on each attempt
+	LockTimeout time.Duration
+	// Number of times the file lock is attempted again after LockTimeout
+	LockRetries int
+	// Delay before the first retry, doubled after each retry
+	LockRetryBackoff time.Duration
+	// Passphrase of an encrypted database, or the content of its key file, see dbutil.EnableEncryption.
+	// If set, a database that is not encrypted yet is encrypted.
+	EncryptionPassphrase []byte
+	// Encryption of a database that was opened already, used instead of EncryptionPassphrase
+	// when the database is reopened
+	Encryption *dbutil.Encryption
+	// Bolt options of the database files
+	Options DBOptions
 }
 
-// OpenDB opens the blockdb
+// NewOpenDBConfig creates the default OpenDBConfig
+func NewOpenDBConfig() OpenDBConfig {
+	return OpenDBConfig{
+		LockTimeout:      5000 * time.Millisecond,
+		LockRetryBackoff: time.Second,
+		Options:          NewDBOptions(),
+	}
+}
+
+// Verify checks that the config is valid
+func (c OpenDBConfig) Verify() error {
+	if c.LockTimeout <= 0 {
+		return fmt.Errorf("LockTimeout must be > 0")
+	}
+
+	if c.LockRetries < 0 {
+		return fmt.Errorf("LockRetries must be >= 0")
+	}
+
+	if c.LockRetries > 0 && c.LockRetryBackoff < 0 {
+		return fmt.Errorf("LockRetryBackoff must be >= 0")
+	}
+
+	return c.Options.Verify()
+}
+
+// OpenDB opens the blockdb. A database split with OpenSplitDB is opened with its part files.
 func OpenDB(dbFile string, readOnly bool) (*dbutil.DB, error) {
-	db, err := bolt.Open(dbFile, 0600, &bolt.Options{
-		Timeout:  5000 * time.Millisecond,
-		ReadOnly: readOnly,
-	})
+	cfg := NewOpenDBConfig()
+	cfg.ReadOnly = readOnly
+	return OpenDBWithConfig(dbFile, cfg)
+}
+
+// OpenDBWithConfig opens the blockdb with the given config.
+// If cfg.Split is true, or the database was split already, it is opened with its part files, see OpenSplitDB.
+// ErrDBLocked is returned if another process holds the lock of a database file.
+func OpenDBWithConfig(dbFile string, cfg OpenDBConfig) (*dbutil.DB, error) {
+	if !cfg.Split {
+		split, err := isSplitDB(dbFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Split = split
+	}
+
+	var db *dbutil.DB
+	if cfg.Split {
+		var err error
+		db, err = openSplitDB(dbFile, cfg)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		boltDB, err := OpenBoltDB(dbFile, cfg)
+		if err != nil {
+			return nil, err
+		}
+		db = dbutil.WrapDB(boltDB)
+	}
+
+	if err := openEncryption(db, cfg); err != nil {
+		if closeErr := db.Close(); closeErr != nil {
+			logger.WithError(closeErr).Error("Failed to close db")
+		}
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// keyMACBuckets are the buckets whose keys hold addresses or wallet IDs,
+// which are replaced by their HMAC in an encrypted database, see dbutil.Tx.KeyMAC
+var keyMACBuckets = append(append([][]byte{}, historydb.KeyMACBuckets...), WalletEventIDsBkt, WatchAddressesBkt)
+
+// openEncryption enables the encryption of the database with cfg.Encryption or cfg.EncryptionPassphrase.
+// dbutil.ErrDBEncrypted is returned if the database is encrypted and neither is set.
+func openEncryption(db *dbutil.DB, cfg OpenDBConfig) error {
+	switch {
+	case cfg.Encryption != nil:
+		return keepWatchAddresses(db, func() error {
+			return dbutil.SetEncryption(db, cfg.Encryption, keyMACBuckets)
+		})
+	case len(cfg.EncryptionPassphrase) != 0:
+		return keepWatchAddresses(db, func() error {
+			return dbutil.EnableEncryption(db, cfg.EncryptionPassphrase, keyMACBuckets)
+		})
+	}
+
+	encrypted, err := dbutil.IsEncrypted(db)
 	if err != nil {
-		return nil, fmt.Errorf("Open boltdb failed, %v", err)
+		return err
 	}
 
-	return dbutil.WrapDB(db), nil
+	if encrypted {
+		return dbutil.ErrDBEncrypted
+	}
+
+	return nil
+}
+
+// keepWatchAddresses calls enableEncryption and adds back the watched addresses that it removed
+// when it hid the keys of the database. The other keyMACBuckets are rebuilt from the blockchain and the
+// wallet events, but the watch list is only stored in its bucket.
+func keepWatchAddresses(db *dbutil.DB, enableEncryption func() error) error {
+	if db.IsReadOnly() {
+		return enableEncryption()
+	}
+
+	addrs, err := unhiddenWatchAddresses(db)
+	if err != nil {
+		return err
+	}
+
+	if err := enableEncryption(); err != nil {
+		return err
+	}
+
+	if len(addrs) == 0 {
+		return nil
+	}
+
+	return db.Update("keepWatchAddresses", func(tx *dbutil.Tx) error {
+		_, err := newWatchList().add(tx, addrs)
+		return err
+	})
 }
 
 // moveCorruptDB moves a file to makeCorruptDBPath(dbPath)
@@ -235,7 +887,7 @@ func moveCorruptDB(dbPath string) (string, error) {
 }
 
 // copyCorruptDB copy a file to makeCorruptDBPath(dbPath)
-func copyCorruptDB(dbPath string) (string, error) { // nolint: unused,megacheck
+func copyCorruptDB(dbPath string) (string, error) {
 	newDBPath, err := makeCorruptDBPath(dbPath)
 	if err != nil {
 		return "", err
@@ -252,9 +904,8 @@ func copyCorruptDB(dbPath string) (string, error) { // nolint: unused,megacheck
 		return "", err
 	}
 	defer out.Close()
-	logger.Critical().Info(out.Name())
 
-	_, err = io.Copy(in, out)
+	_, err = io.Copy(out, in)
 	if err != nil {
 		return "", err
 	}
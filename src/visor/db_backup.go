@@ -0,0 +1,121 @@
+package visor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/boltdb/bolt"
+
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+var (
+	// ErrDBBackupDisabled is returned by Visor.BackupDB if no backup directory is configured
+	ErrDBBackupDisabled = errors.New("Database backup directory is not configured")
+
+	// backupLock prevents concurrent backups from writing the same file
+	backupLock sync.Mutex
+)
+
+// DBBackup describes a database backup file
+type DBBackup struct {
+	// Path of the backup file
+	Path string
+	// Seq of the head block in the backup
+	HeadSeq uint64
+	// Size of the backup files in bytes
+	Size int64
+	// Paths of the backup files of the part files of a split database, named like Path
+	PartPaths []string
+}
+
+// BackupDB writes a consistent snapshot of the database to a file in dir, while the database remains in use.
+// The name of the backup file includes the head block seq of the snapshot,
+// e.g. data.db.1234.bak for a database file named data.db with head block seq 1234.
+// An existing backup with the same name is replaced.
+// The part files of a split database are backed up from the same transaction, e.g. to data.db.history.1234.bak.
+func BackupDB(db *dbutil.DB, bc Blockchainer, dir string) (*DBBackup, error) {
+	backupLock.Lock()
+	defer backupLock.Unlock()
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	var backup *DBBackup
+	if err := db.View("BackupDB", func(tx *dbutil.Tx) error {
+		headSeq, ok, err := bc.HeadSeq(tx)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("Can't back up a database without blocks")
+		}
+
+		path := filepath.Join(dir, fmt.Sprintf("%s.%d.bak", filepath.Base(db.Path()), headSeq))
+		size, err := writeDBSnapshot(tx.Tx, path)
+		if err != nil {
+			return err
+		}
+
+		backup = &DBBackup{
+			Path:    path,
+			HeadSeq: headSeq,
+			Size:    size,
+		}
+
+		for _, p := range db.Parts() {
+			partPath := filepath.Join(dir, fmt.Sprintf("%s.%d.bak", filepath.Base(p.DB.Path()), headSeq))
+			size, err := writeDBSnapshot(tx.PartTx(p.Name), partPath)
+			if err != nil {
+				return err
+			}
+
+			backup.PartPaths = append(backup.PartPaths, partPath)
+			backup.Size += size
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	logger.Infof("Backed up database at block %d to %s", backup.HeadSeq, backup.Path)
+
+	return backup, nil
+}
+
+// writeDBSnapshot writes the database as seen by tx to a temporary file, then moves it to path,
+// so that an incomplete backup never replaces a previous backup
+func writeDBSnapshot(tx *bolt.Tx, path string) (int64, error) {
+	tmpPath := path + ".tmp"
+
+	f, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return 0, err
+	}
+
+	size, err := tx.WriteTo(f)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+
+	if err != nil {
+		if rmErr := os.Remove(tmpPath); rmErr != nil && !os.IsNotExist(rmErr) {
+			logger.WithError(rmErr).Errorf("Failed to remove %s", tmpPath)
+		}
+		return 0, err
+	}
+
+	return size, nil
+}
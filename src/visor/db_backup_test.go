@@ -0,0 +1,82 @@
+package visor
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+func TestBackupDB(t *testing.T) {
+	db, shutdown := copyTestDB(t, "./testdata/data.db.ok")
+	defer shutdown()
+
+	pubkey := mustParsePubkey(t)
+	bc, err := NewBlockchain(db, BlockchainConfig{Pubkey: pubkey})
+	require.NoError(t, err)
+
+	var headSeq uint64
+	err = db.View("", func(tx *dbutil.Tx) error {
+		var ok bool
+		headSeq, ok, err = bc.HeadSeq(tx)
+		require.NoError(t, err)
+		require.True(t, ok)
+		return nil
+	})
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "dbbackup")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// The backup directory is created if it does not exist
+	backupDir := filepath.Join(dir, "backups")
+
+	backup, err := BackupDB(db, bc, backupDir)
+	require.NoError(t, err)
+	require.Equal(t, headSeq, backup.HeadSeq)
+	require.Equal(t, filepath.Join(backupDir, "data.db.10.bak"), backup.Path)
+
+	fi, err := os.Stat(backup.Path)
+	require.NoError(t, err)
+	require.Equal(t, fi.Size(), backup.Size)
+
+	// No temporary file is left behind
+	_, err = os.Stat(backup.Path + ".tmp")
+	require.True(t, os.IsNotExist(err))
+
+	// The database is still usable after the backup
+	err = db.View("", func(tx *dbutil.Tx) error {
+		_, err := bc.Head(tx)
+		return err
+	})
+	require.NoError(t, err)
+
+	// The backup is a valid database
+	backupDB, err := OpenDB(backup.Path, true)
+	require.NoError(t, err)
+	defer backupDB.Close()
+
+	err = CheckDatabase(context.Background(), backupDB, CheckDatabaseConfig{Pubkey: pubkey, FullVerify: true})
+	require.NoError(t, err)
+
+	// Backing up again at the same head block replaces the backup
+	backup2, err := BackupDB(db, bc, backupDir)
+	require.NoError(t, err)
+	require.Equal(t, backup.Path, backup2.Path)
+
+	files, err := ioutil.ReadDir(backupDir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+}
+
+func TestVisorBackupDBDisabled(t *testing.T) {
+	v := &Visor{}
+	_, err := v.BackupDB()
+	require.Equal(t, ErrDBBackupDisabled, err)
+}
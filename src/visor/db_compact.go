@@ -0,0 +1,318 @@
+package visor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/boltdb/bolt"
+
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+const (
+	// DefaultCompactTxMaxSize is the default maximum size of the keys and values copied in a single transaction
+	DefaultCompactTxMaxSize = 64 * 1024 * 1024
+
+	// compactProgressRate is how often the CompactDBConfig.Progress callback is called
+	compactProgressRate = time.Second
+)
+
+var (
+	// ErrCompactReadOnly is returned by CompactDB if the database is opened read-only
+	ErrCompactReadOnly = errors.New("Can't compact a read-only database")
+
+	// ErrCompactSplitDB is returned by CompactDB if the database is split into several files
+	ErrCompactSplitDB = errors.New("Can't compact a database split into several files")
+)
+
+// CompactDBConfig configures CompactDB
+type CompactDBConfig struct {
+	// Configures the verification of the compacted database
+	Check CheckDatabaseConfig
+	// Maximum size of the keys and values copied in a single transaction, to limit memory usage
+	TxMaxSize int
+	// Progress is called periodically with the copy progress, optional
+	Progress func(CompactProgress)
+}
+
+// CompactProgress reports the progress of a database compaction
+type CompactProgress struct {
+	// Number of keys copied so far
+	Copied uint64
+	// Total number of keys to copy
+	Total uint64
+}
+
+// CompactDB copies all buckets of the database into a new file, verifies the copy with CheckDatabase,
+// then replaces the database file with the copy. The database must not be in use by a running visor.
+// The original database is closed, and the compacted database is returned opened.
+// If the compaction fails or ctx is done, the original database file is left unchanged.
+func CompactDB(ctx context.Context, db *dbutil.DB, cfg CompactDBConfig) (*dbutil.DB, error) {
+	if db.IsReadOnly() {
+		return nil, ErrCompactReadOnly
+	}
+
+	if db.IsSplit() {
+		return nil, ErrCompactSplitDB
+	}
+
+	dbPath := db.Path()
+	compactPath := dbPath + ".compact"
+
+	// Remove a leftover file from an interrupted compaction
+	if err := os.Remove(compactPath); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	oldSize, err := fileSize(dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Infof("Compacting database %s to %s", dbPath, compactPath)
+
+	if err := compactDBFile(ctx, db, compactPath, cfg); err != nil {
+		if rmErr := os.Remove(compactPath); rmErr != nil && !os.IsNotExist(rmErr) {
+			logger.WithError(rmErr).Errorf("Failed to remove %s", compactPath)
+		}
+		return nil, err
+	}
+
+	if err := db.Close(); err != nil {
+		return nil, fmt.Errorf("Failed to close db: %v", err)
+	}
+
+	if err := os.Rename(compactPath, dbPath); err != nil {
+		return nil, err
+	}
+
+	newSize, err := fileSize(dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Infof("Compacted database from %d bytes to %d bytes", oldSize, newSize)
+
+	return reopenDB(db, dbPath, db.Encryption())
+}
+
+// compactDBFile copies the database to compactPath and verifies the copy
+func compactDBFile(ctx context.Context, db *dbutil.DB, compactPath string, cfg CompactDBConfig) error {
+	// The encryption params are copied with the buckets, the copy is not encrypted again
+	dst, err := reopenDB(db, compactPath, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := dst.Close(); err != nil {
+			logger.WithError(err).Error("Failed to close compacted db")
+		}
+	}()
+
+	if err := db.ViewContext(ctx, "CompactDB", func(tx *dbutil.Tx) error {
+		c := &dbCompactor{
+			ctx:       tx.Context(),
+			dst:       dst,
+			txMaxSize: cfg.TxMaxSize,
+			progress:  cfg.Progress,
+		}
+		return c.copy(tx.Tx)
+	}); err != nil {
+		return err
+	}
+
+	if enc := db.Encryption(); enc != nil {
+		if err := dbutil.SetEncryption(dst, enc, keyMACBuckets); err != nil {
+			return err
+		}
+	}
+
+	logger.Info("Verifying the compacted database")
+	return CheckDatabase(ctx, dst, cfg.Check)
+}
+
+// dbCompactor copies the buckets of a database into another database,
+// committing the copy whenever the size of the pending transaction exceeds txMaxSize
+type dbCompactor struct {
+	ctx       context.Context
+	dst       *dbutil.DB
+	txMaxSize int
+	progress  func(CompactProgress)
+
+	tx           *bolt.Tx
+	txSize       int
+	copied       uint64
+	total        uint64
+	lastProgress time.Time
+}
+
+func (c *dbCompactor) copy(src *bolt.Tx) error {
+	if err := src.ForEach(func(name []byte, b *bolt.Bucket) error {
+		c.total += uint64(b.Stats().KeyN)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	tx, err := c.dst.Begin(true)
+	if err != nil {
+		return err
+	}
+	c.tx = tx
+
+	defer func() {
+		if c.tx != nil {
+			if err := c.tx.Rollback(); err != nil {
+				logger.WithError(err).Error("CompactDB: rollback failed")
+			}
+		}
+	}()
+
+	if err := src.ForEach(func(name []byte, b *bolt.Bucket) error {
+		if err := c.put(nil, name, nil, b.Sequence()); err != nil {
+			return err
+		}
+		return c.copyBucket(b, [][]byte{name})
+	}); err != nil {
+		return err
+	}
+
+	err = c.tx.Commit()
+	c.tx = nil
+	if err != nil {
+		return err
+	}
+
+	c.reportProgress(true)
+	return nil
+}
+
+// copyBucket recursively copies the keys and nested buckets of b, which is located at path
+func (c *dbCompactor) copyBucket(b *bolt.Bucket, path [][]byte) error {
+	return b.ForEach(func(k, v []byte) error {
+		if err := c.ctx.Err(); err != nil {
+			return err
+		}
+
+		// A nil value is a nested bucket
+		if v == nil {
+			nb := b.Bucket(k)
+			if err := c.put(path, k, nil, nb.Sequence()); err != nil {
+				return err
+			}
+
+			nestedPath := make([][]byte, len(path)+1)
+			copy(nestedPath, path)
+			nestedPath[len(path)] = k
+			return c.copyBucket(nb, nestedPath)
+		}
+
+		if err := c.put(path, k, v, 0); err != nil {
+			return err
+		}
+
+		c.copied++
+		c.reportProgress(false)
+		return nil
+	})
+}
+
+// put writes a key, or creates a bucket with the given sequence if v is nil, in the bucket located at path
+func (c *dbCompactor) put(path [][]byte, k, v []byte, seq uint64) error {
+	size := len(k) + len(v)
+	if c.txMaxSize > 0 && c.txSize+size > c.txMaxSize {
+		if err := c.tx.Commit(); err != nil {
+			c.tx = nil
+			return err
+		}
+
+		tx, err := c.dst.Begin(true)
+		if err != nil {
+			c.tx = nil
+			return err
+		}
+		c.tx = tx
+		c.txSize = 0
+	}
+	c.txSize += size
+
+	if len(path) == 0 {
+		b, err := c.tx.CreateBucket(k)
+		if err != nil {
+			return dbutil.NewErrCreateBucketFailed(k, err)
+		}
+		return b.SetSequence(seq)
+	}
+
+	b := c.tx.Bucket(path[0])
+	for _, name := range path[1:] {
+		if b == nil {
+			break
+		}
+		b = b.Bucket(name)
+	}
+	if b == nil {
+		return dbutil.NewErrBucketNotExist(path[len(path)-1])
+	}
+
+	// Fill the pages completely, since keys are inserted in order
+	b.FillPercent = 1.0
+
+	if v == nil {
+		nb, err := b.CreateBucket(k)
+		if err != nil {
+			return dbutil.NewErrCreateBucketFailed(k, err)
+		}
+		return nb.SetSequence(seq)
+	}
+
+	return b.Put(k, v)
+}
+
+func (c *dbCompactor) reportProgress(done bool) {
+	if c.progress == nil {
+		return
+	}
+
+	if !done && time.Since(c.lastProgress) < compactProgressRate {
+		return
+	}
+	c.lastProgress = time.Now()
+
+	c.progress(CompactProgress{
+		Copied: c.copied,
+		Total:  c.total,
+	})
+}
+
+// reopenDB opens dbPath for writing with the given encryption, with the same bolt options and logging settings as db
+func reopenDB(db *dbutil.DB, dbPath string, encryption *dbutil.Encryption) (*dbutil.DB, error) {
+	cfg := NewOpenDBConfig()
+	cfg.Encryption = encryption
+	cfg.Options = dbOptions(db)
+	newDB, err := OpenDBWithConfig(dbPath, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	newDB.ViewLog = db.ViewLog
+	newDB.ViewTrace = db.ViewTrace
+	newDB.UpdateLog = db.UpdateLog
+	newDB.UpdateTrace = db.UpdateTrace
+	newDB.DurationLog = db.DurationLog
+	newDB.DurationReportingThreshold = db.DurationReportingThreshold
+
+	return newDB, nil
+}
+
+func fileSize(path string) (int64, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	return fi.Size(), nil
+}
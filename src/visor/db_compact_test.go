@@ -0,0 +1,123 @@
+package visor
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+// dumpDB returns all keys and values of the database, keyed by bucket path and key
+func dumpDB(t *testing.T, db *dbutil.DB) map[string]string {
+	values := make(map[string]string)
+
+	var dumpBucket func(prefix string, b *bolt.Bucket) error
+	dumpBucket = func(prefix string, b *bolt.Bucket) error {
+		return b.ForEach(func(k, v []byte) error {
+			if v == nil {
+				return dumpBucket(prefix+"/"+string(k), b.Bucket(k))
+			}
+			values[prefix+"/"+string(k)] = string(v)
+			return nil
+		})
+	}
+
+	err := db.View("", func(tx *dbutil.Tx) error {
+		return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			return dumpBucket(string(name), b)
+		})
+	})
+	require.NoError(t, err)
+
+	return values
+}
+
+func TestCompactDB(t *testing.T) {
+	db, shutdown := copyTestDB(t, "./testdata/data.db.ok")
+	defer shutdown()
+
+	pubkey := mustParsePubkey(t)
+	dbPath := db.Path()
+	values := dumpDB(t, db)
+	require.NotEmpty(t, values)
+
+	var progress []CompactProgress
+	newDB, err := CompactDB(context.Background(), db, CompactDBConfig{
+		Check: CheckDatabaseConfig{
+			Pubkey:     pubkey,
+			FullVerify: true,
+		},
+		// Use a small transaction size to exercise committing in the middle of a bucket
+		TxMaxSize: 4096,
+		Progress: func(p CompactProgress) {
+			progress = append(progress, p)
+		},
+	})
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, newDB.Close())
+	}()
+
+	require.Equal(t, dbPath, newDB.Path())
+
+	// The temporary file was moved into place
+	_, err = os.Stat(dbPath + ".compact")
+	require.True(t, os.IsNotExist(err))
+
+	// The final progress report covers all keys
+	require.NotEmpty(t, progress)
+	last := progress[len(progress)-1]
+	require.Equal(t, uint64(len(values)), last.Copied)
+	require.Equal(t, last.Total, last.Copied)
+
+	// All keys are copied, except for the verification checkpoint which is updated by verifying the copy
+	compacted := dumpDB(t, newDB)
+	for k, v := range values {
+		if k == string(dbutil.VerifyCheckpointBkt)+"/checkpoint" {
+			continue
+		}
+		require.Equal(t, v, compacted[k], k)
+	}
+
+	err = CheckDatabase(context.Background(), newDB, CheckDatabaseConfig{Pubkey: pubkey, FullVerify: true})
+	require.NoError(t, err)
+}
+
+func TestCompactDBCorrupted(t *testing.T) {
+	db, shutdown := copyTestDB(t, "./testdata/data.db.notxn")
+	defer shutdown()
+
+	dbPath := db.Path()
+
+	// The copy fails verification, the original database is not replaced
+	_, err := CompactDB(context.Background(), db, CompactDBConfig{
+		Check: CheckDatabaseConfig{
+			Pubkey:     mustParsePubkey(t),
+			FullVerify: true,
+		},
+	})
+	require.Error(t, err)
+	require.True(t, IsCorruptDBError(err))
+
+	_, err = os.Stat(dbPath + ".compact")
+	require.True(t, os.IsNotExist(err))
+
+	// The original database is still open
+	err = db.View("", func(tx *dbutil.Tx) error {
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestCompactDBReadOnly(t *testing.T) {
+	db, err := OpenDB("./testdata/data.db.ok", true)
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = CompactDB(context.Background(), db, CompactDBConfig{})
+	require.Equal(t, ErrCompactReadOnly, err)
+}
@@ -0,0 +1,197 @@
+package visor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/encoder"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+// DBFingerprint is a canonical hash of the consensus data of a database at a block height,
+// which is equal for two databases that store the same blocks, signatures and unspent outputs.
+// It does not depend on how the data is stored, such as the block compression or a split database,
+// nor on the data that is derived from the blocks, such as the history db indexes.
+type DBFingerprint struct {
+	// Seq of the block the fingerprint was computed at
+	Seq uint64 `json:"seq"`
+	// Hash of the block
+	BlockHash string `json:"block_hash"`
+	// PrunedSeq is the seq of the newest pruned block. The body of a pruned block is not stored,
+	// so a pruned and an unpruned database have different blocks hashes
+	PrunedSeq uint64 `json:"pruned_seq"`
+	// BlocksHash is the hash of the blocks up to Seq, without their signatures
+	BlocksHash string `json:"blocks_hash"`
+	// SignaturesHash is the hash of the signatures of the blocks up to Seq
+	SignaturesHash string `json:"signatures_hash"`
+	// UnspentHash is the hash of the unspent output set after the block of Seq was executed,
+	// in the format of the UxHash of the block headers
+	UnspentHash string `json:"unspent_hash"`
+	// Fingerprint is the hash of Seq and the hashes above
+	Fingerprint string `json:"fingerprint"`
+}
+
+// ComputeDBFingerprint computes the fingerprint of the database at the block of seq, or at the head block if seq is nil.
+// Every block up to seq is read in a single database transaction, so the node can keep running.
+//
+// At the head block the unspent hash is computed from the unspent outputs stored in the unspent pool.
+// The unspent pool only stores the unspent outputs after the head block, so below the head block
+// the unspent hash is the stored commitment of the unspent outputs after the block, which is the UxHash
+// of the header of the next block.
+//
+// The computation stops when ctx is done and returns the context error.
+func ComputeDBFingerprint(ctx context.Context, db *dbutil.DB, seq *uint64) (*DBFingerprint, error) {
+	bc, err := NewBlockchain(db, BlockchainConfig{})
+	if err != nil {
+		return nil, err
+	}
+
+	var fp *DBFingerprint
+	if err := db.ViewContext(ctx, "ComputeDBFingerprint", func(tx *dbutil.Tx) error {
+		headSeq, ok, err := bc.HeadSeq(tx)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("Can't fingerprint a blockchain without blocks")
+		}
+
+		atSeq := headSeq
+		if seq != nil {
+			if *seq > headSeq {
+				return fmt.Errorf("block %d is above the head block %d", *seq, headSeq)
+			}
+			atSeq = *seq
+		}
+
+		prunedSeq, err := bc.PrunedSeq(tx)
+		if err != nil {
+			return err
+		}
+
+		blocksHash, signaturesHash, blockHash, err := hashBlocks(tx, bc, atSeq)
+		if err != nil {
+			return err
+		}
+
+		var unspentHash cipher.SHA256
+		if atSeq == headSeq {
+			unspentHash, err = hashUnspentPool(tx, bc)
+		} else {
+			unspentHash, err = getUnspentCommitment(tx, bc, atSeq)
+		}
+		if err != nil {
+			return err
+		}
+
+		fp = &DBFingerprint{
+			Seq:            atSeq,
+			BlockHash:      blockHash.Hex(),
+			PrunedSeq:      prunedSeq,
+			BlocksHash:     blocksHash.Hex(),
+			SignaturesHash: signaturesHash.Hex(),
+			UnspentHash:    unspentHash.Hex(),
+			Fingerprint:    fingerprintHash(atSeq, blocksHash, signaturesHash, unspentHash).Hex(),
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return fp, nil
+}
+
+// hashBlocks hashes the encoded blocks and the signatures of the blocks up to seq, in seq order.
+// Returns the blocks hash, the signatures hash and the hash of the block of seq.
+func hashBlocks(tx *dbutil.Tx, bc *Blockchain, seq uint64) (cipher.SHA256, cipher.SHA256, cipher.SHA256, error) {
+	blocksHasher := sha256.New()
+	sigsHasher := sha256.New()
+
+	var blockHash cipher.SHA256
+	for i := uint64(0); i <= seq; i++ {
+		if err := tx.Context().Err(); err != nil {
+			return cipher.SHA256{}, cipher.SHA256{}, cipher.SHA256{}, err
+		}
+
+		b, err := bc.GetSignedBlockBySeq(tx, i)
+		if err != nil {
+			return cipher.SHA256{}, cipher.SHA256{}, cipher.SHA256{}, err
+		}
+		if b == nil {
+			return cipher.SHA256{}, cipher.SHA256{}, cipher.SHA256{}, NewErrBlockNotExist(i)
+		}
+
+		// The encoded blocks have different lengths, so they are length prefixed to keep the hash unambiguous
+		writeHashRecord(blocksHasher, encoder.Serialize(b.Block))
+		writeHashRecord(sigsHasher, b.Sig[:])
+
+		blockHash = b.HashHeader()
+	}
+
+	return sumHash(blocksHasher), sumHash(sigsHasher), blockHash, nil
+}
+
+// hashUnspentPool returns the XOR of the snapshot hashes of the unspent outputs in the unspent pool
+func hashUnspentPool(tx *dbutil.Tx, bc *Blockchain) (cipher.SHA256, error) {
+	uxs, err := bc.Unspent().GetAll(tx)
+	if err != nil {
+		return cipher.SHA256{}, err
+	}
+
+	var h cipher.SHA256
+	for _, ux := range uxs {
+		h = h.Xor(ux.SnapshotHash())
+	}
+
+	return h, nil
+}
+
+// getUnspentCommitment returns the hash of the unspent output set after the block of seq, which is below the head block.
+// If the database predates the commitments, such as an old database opened read-only, the UxHash of the next block is used.
+func getUnspentCommitment(tx *dbutil.Tx, bc *Blockchain, seq uint64) (cipher.SHA256, error) {
+	h, ok, err := bc.GetUnspentCommitment(tx, seq)
+	if err != nil {
+		return cipher.SHA256{}, err
+	}
+	if ok {
+		return h, nil
+	}
+
+	b, err := bc.GetSignedBlockBySeq(tx, seq+1)
+	if err != nil {
+		return cipher.SHA256{}, err
+	}
+	if b == nil {
+		return cipher.SHA256{}, NewErrBlockNotExist(seq + 1)
+	}
+
+	return b.Head.UxHash, nil
+}
+
+func writeHashRecord(h hash.Hash, data []byte) {
+	var n [4]byte
+	binary.LittleEndian.PutUint32(n[:], uint32(len(data)))
+	h.Write(n[:])
+	h.Write(data)
+}
+
+func sumHash(h hash.Hash) cipher.SHA256 {
+	var sum cipher.SHA256
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+// fingerprintHash hashes the seq and the hashes of a DBFingerprint
+func fingerprintHash(seq uint64, blocksHash, signaturesHash, unspentHash cipher.SHA256) cipher.SHA256 {
+	data := make([]byte, 0, 8+3*len(cipher.SHA256{}))
+	data = append(data, dbutil.Itob(seq)...)
+	data = append(data, blocksHash[:]...)
+	data = append(data, signaturesHash[:]...)
+	data = append(data, unspentHash[:]...)
+	return cipher.SumSHA256(data)
+}
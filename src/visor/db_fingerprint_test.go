@@ -0,0 +1,118 @@
+package visor
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor/blockdb"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+func TestComputeDBFingerprint(t *testing.T) {
+	data, count, head := exportTestSnapshot(t, "./testdata/data.db.ok")
+	headSeq := count - 1
+
+	db, err := OpenDB("./testdata/data.db.ok", true)
+	require.NoError(t, err)
+	defer db.Close()
+
+	fp, err := ComputeDBFingerprint(context.Background(), db, nil)
+	require.NoError(t, err)
+	require.Equal(t, headSeq, fp.Seq)
+	require.Equal(t, head.Hex(), fp.BlockHash)
+	require.Equal(t, uint64(0), fp.PrunedSeq)
+
+	bc, err := NewBlockchain(db, BlockchainConfig{})
+	require.NoError(t, err)
+
+	err = db.View("", func(tx *dbutil.Tx) error {
+		uxHash, err := bc.Unspent().GetUxHash(tx)
+		require.NoError(t, err)
+		require.Equal(t, uxHash.Hex(), fp.UnspentHash)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// The fingerprint of the head block seq is the fingerprint of the head block
+	fpHead, err := ComputeDBFingerprint(context.Background(), db, &headSeq)
+	require.NoError(t, err)
+	require.Equal(t, fp, fpHead)
+
+	// Below the head block, the unspent hash is the UxHash of the next block
+	seq := headSeq / 2
+	fpSeq, err := ComputeDBFingerprint(context.Background(), db, &seq)
+	require.NoError(t, err)
+	require.Equal(t, seq, fpSeq.Seq)
+	require.NotEqual(t, fp.Fingerprint, fpSeq.Fingerprint)
+	require.NotEqual(t, fp.BlocksHash, fpSeq.BlocksHash)
+	require.NotEqual(t, fp.SignaturesHash, fpSeq.SignaturesHash)
+
+	err = db.View("", func(tx *dbutil.Tx) error {
+		b, err := bc.GetSignedBlockBySeq(tx, seq)
+		require.NoError(t, err)
+		require.Equal(t, b.HashHeader().Hex(), fpSeq.BlockHash)
+
+		next, err := bc.GetSignedBlockBySeq(tx, seq+1)
+		require.NoError(t, err)
+		require.Equal(t, next.Head.UxHash.Hex(), fpSeq.UnspentHash)
+		return nil
+	})
+	require.NoError(t, err)
+
+	above := headSeq + 1
+	_, err = ComputeDBFingerprint(context.Background(), db, &above)
+	require.Error(t, err)
+
+	// A database with the same blocks has the same fingerprint, whatever the block compression
+	importDB, shutdown := testutil.PrepareDB(t)
+	defer shutdown()
+
+	_, err = ImportSnapshot(context.Background(), importDB, bytes.NewReader(data), ImportSnapshotConfig{Pubkey: mustParsePubkey(t)})
+	require.NoError(t, err)
+
+	importFp, err := ComputeDBFingerprint(context.Background(), importDB, nil)
+	require.NoError(t, err)
+	require.Equal(t, fp, importFp)
+
+	importSeqFp, err := ComputeDBFingerprint(context.Background(), importDB, &seq)
+	require.NoError(t, err)
+	require.Equal(t, fpSeq, importSeqFp)
+
+	importBc, err := NewBlockchain(importDB, BlockchainConfig{BlockCompression: blockdb.BlockCompressionSnappy})
+	require.NoError(t, err)
+	err = importDB.Update("", func(tx *dbutil.Tx) error {
+		n, next, err := importBc.RecompressBlocks(tx, nil, count)
+		require.NotEqual(t, uint64(0), n)
+		require.Nil(t, next)
+		return err
+	})
+	require.NoError(t, err)
+
+	importFp, err = ComputeDBFingerprint(context.Background(), importDB, nil)
+	require.NoError(t, err)
+	require.Equal(t, fp, importFp)
+
+	// Pruning the database changes the blocks hash, but not the signatures and unspent hashes
+	err = importDB.Update("", func(tx *dbutil.Tx) error {
+		_, err := importBc.Prune(tx, 5, 0)
+		return err
+	})
+	require.NoError(t, err)
+
+	prunedFp, err := ComputeDBFingerprint(context.Background(), importDB, nil)
+	require.NoError(t, err)
+	require.Equal(t, headSeq-5, prunedFp.PrunedSeq)
+	require.NotEqual(t, fp.BlocksHash, prunedFp.BlocksHash)
+	require.NotEqual(t, fp.Fingerprint, prunedFp.Fingerprint)
+	require.Equal(t, fp.SignaturesHash, prunedFp.SignaturesHash)
+	require.Equal(t, fp.UnspentHash, prunedFp.UnspentHash)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = ComputeDBFingerprint(ctx, db, nil)
+	require.Equal(t, context.Canceled, err)
+}
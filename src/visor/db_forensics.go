@@ -0,0 +1,402 @@
+package visor
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/boltdb/bolt"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/encoder"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/blockdb"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/historydb"
+)
+
+// DefaultDBForensicsMaxBlockDumps is the default maximum number of corrupted blocks dumped in a DBForensics bundle
+const DefaultDBForensicsMaxBlockDumps = 10
+
+// DBForensicsConfig configures NewDBForensics
+type DBForensicsConfig struct {
+	// Public key of the blockchain, used to verify the database if Err is nil
+	Pubkey cipher.PubKey
+	// Version of the node or CLI creating the bundle
+	NodeVersion string
+	// Corruption error returned by CheckDatabase, the corrupted blocks are taken from it.
+	// If nil, the database is verified like VerifyDBFile to find the corrupted blocks.
+	Err error
+	// Maximum number of corrupted blocks dumped, the others are only listed. If 0, DefaultDBForensicsMaxBlockDumps is used
+	MaxBlockDumps int
+	// Progress is called periodically with the progress of the verification, optional
+	Progress func(VerifyProgress)
+}
+
+// DBForensics is a forensic bundle of a corrupted database, to attach to corruption bug reports.
+// It is written next to the quarantined .corrupt copy when a corrupted database is recovered,
+// and can be created on demand with the dbforensics CLI command.
+type DBForensics struct {
+	// Unix time the bundle was created
+	CreatedAt uint64 `json:"created_at"`
+	// Version of the node or CLI that created the bundle
+	NodeVersion string `json:"node_version"`
+	// Version of the node that last opened the database
+	DBVersion string `json:"db_version"`
+	// Path of the database file
+	Path string `json:"path"`
+	// Corruption error
+	Error string `json:"error,omitempty"`
+	// Head block, if the blockchain has one
+	HeadSeq  uint64 `json:"head_seq"`
+	HeadHash string `json:"head_hash,omitempty"`
+	// Seq of the newest pruned block
+	PrunedSeq uint64 `json:"pruned_seq"`
+	// Corrupted blocks, ordered by seq
+	CorruptedBlocks []DBForensicsBlock `json:"corrupted_blocks"`
+	// Statistics of the top level buckets, ordered by name
+	Buckets []DBForensicsBucket `json:"buckets"`
+	// Page statistics of the database files, the primary file first
+	Files []DBForensicsFile `json:"files"`
+}
+
+// DBForensicsBlock is a dump of a corrupted block
+type DBForensicsBlock struct {
+	Seq          uint64 `json:"seq"`
+	Hash         string `json:"hash,omitempty"`
+	Error        string `json:"error,omitempty"`
+	Version      uint32 `json:"version,omitempty"`
+	Time         uint64 `json:"time,omitempty"`
+	Fee          uint64 `json:"fee,omitempty"`
+	PrevHash     string `json:"prev_hash,omitempty"`
+	BodyHash     string `json:"body_hash,omitempty"`
+	UxHash       string `json:"ux_hash,omitempty"`
+	Signature    string `json:"signature,omitempty"`
+	Transactions int    `json:"transactions"`
+	// Hex-encoded serialized block, as stored in the database
+	Raw string `json:"raw,omitempty"`
+	// Why the block could not be dumped
+	DumpError string `json:"dump_error,omitempty"`
+}
+
+// DBForensicsBucket are the bolt statistics of a bucket
+type DBForensicsBucket struct {
+	Name           string `json:"name"`
+	Keys           int    `json:"keys"`
+	Depth          int    `json:"depth"`
+	BranchPages    int    `json:"branch_pages"`
+	BranchOverflow int    `json:"branch_overflow"`
+	LeafPages      int    `json:"leaf_pages"`
+	LeafOverflow   int    `json:"leaf_overflow"`
+	LeafInuse      int    `json:"leaf_inuse"`
+	LeafAlloc      int    `json:"leaf_alloc"`
+	NestedBuckets  int    `json:"nested_buckets"`
+}
+
+// DBForensicsFile are the bolt page statistics of a database file
+type DBForensicsFile struct {
+	Path          string `json:"path"`
+	Size          int64  `json:"size"`
+	PageSize      int    `json:"page_size"`
+	Pages         int64  `json:"pages"`
+	FreePages     int    `json:"free_pages"`
+	PendingPages  int    `json:"pending_pages"`
+	FreeAlloc     int    `json:"free_alloc"`
+	FreelistInuse int    `json:"freelist_inuse"`
+}
+
+// dbForensicsSuffix is appended to the path of a quarantined database copy to name its forensic bundle
+const dbForensicsSuffix = ".forensics.json"
+
+// DBForensicsPath returns the path of the forensic bundle written next to a quarantined database copy
+func DBForensicsPath(corruptDBPath string) string {
+	return corruptDBPath + dbForensicsSuffix
+}
+
+// NewDBForensics creates a forensic bundle of the database.
+// The database is only read, so a read-only database or a quarantined copy can be inspected.
+func NewDBForensics(ctx context.Context, db *dbutil.DB, cfg DBForensicsConfig) (*DBForensics, error) {
+	maxBlockDumps := cfg.MaxBlockDumps
+	if maxBlockDumps == 0 {
+		maxBlockDumps = DefaultDBForensicsMaxBlockDumps
+	}
+
+	f := &DBForensics{
+		CreatedAt:       uint64(time.Now().UTC().Unix()),
+		NodeVersion:     cfg.NodeVersion,
+		Path:            db.Path(),
+		CorruptedBlocks: []DBForensicsBlock{},
+		Buckets:         []DBForensicsBucket{},
+	}
+
+	corrupted := corruptedBlocksFromErr(cfg.Err)
+	if cfg.Err != nil {
+		f.Error = cfg.Err.Error()
+	} else {
+		report, err := verifyDB(ctx, db, VerifyDBFileConfig{
+			Pubkey:   cfg.Pubkey,
+			Progress: cfg.Progress,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		corrupted = corruptedBlocksFromReport(report)
+		if report.Corrupted {
+			f.Error = fmt.Sprintf("%d blocks with signature errors, %d blocks with history db index mismatches",
+				len(report.SignatureErrors), len(report.IndexMismatches))
+		}
+	}
+
+	bc, err := NewBlockchain(db, BlockchainConfig{
+		Pubkey: cfg.Pubkey,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.ViewContext(ctx, "NewDBForensics", func(tx *dbutil.Tx) error {
+		dbVersion, err := getDBVersion(tx)
+		if err != nil {
+			return err
+		}
+		if dbVersion != nil {
+			f.DBVersion = dbVersion.String()
+		}
+
+		if dbutil.Exists(tx, blockdb.BlocksBkt) {
+			if err := f.addBlocks(tx, bc, corrupted, maxBlockDumps); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			f.Buckets = append(f.Buckets, newDBForensicsBucket(name, b.Stats()))
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		sort.Slice(f.Buckets, func(i, j int) bool {
+			return f.Buckets[i].Name < f.Buckets[j].Name
+		})
+
+		f.Files = append(f.Files, newDBForensicsFile(db.DB, tx.Tx))
+		for _, p := range db.Parts() {
+			f.Files = append(f.Files, newDBForensicsFile(p.DB, tx.PartTx(p.Name)))
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// addBlocks adds the head block and the dumps of the corrupted blocks
+func (f *DBForensics) addBlocks(tx *dbutil.Tx, bc *Blockchain, corrupted []DBBlockError, maxBlockDumps int) error {
+	headSeq, ok, err := bc.HeadSeq(tx)
+	if err != nil {
+		return err
+	}
+
+	if ok {
+		f.HeadSeq = headSeq
+		if b, err := bc.store.GetBlockBySeq(tx, headSeq); err == nil && b != nil {
+			f.HeadHash = b.HashHeader().Hex()
+		}
+	}
+
+	f.PrunedSeq, err = bc.PrunedSeq(tx)
+	if err != nil {
+		return err
+	}
+
+	for i, e := range corrupted {
+		block := DBForensicsBlock{
+			Seq:   e.Seq,
+			Hash:  e.Hash,
+			Error: e.Error,
+		}
+
+		if i < maxBlockDumps {
+			dumpForensicsBlock(tx, bc, &block)
+		}
+
+		f.CorruptedBlocks = append(f.CorruptedBlocks, block)
+	}
+
+	return nil
+}
+
+// dumpForensicsBlock adds the stored content of a block to its dump.
+// A block that can't be read is not an error, since the database is corrupted.
+func dumpForensicsBlock(tx *dbutil.Tx, bc *Blockchain, block *DBForensicsBlock) {
+	var b *coin.Block
+	var err error
+	if block.Hash != "" {
+		var hash cipher.SHA256
+		hash, err = cipher.SHA256FromHex(block.Hash)
+		if err == nil {
+			b, err = bc.store.GetBlockByHash(tx, hash)
+		}
+	} else {
+		b, err = bc.store.GetBlockBySeq(tx, block.Seq)
+	}
+
+	if err != nil {
+		block.DumpError = err.Error()
+		return
+	} else if b == nil {
+		block.DumpError = "Block not found"
+		return
+	}
+
+	block.Hash = b.HashHeader().Hex()
+	block.Version = b.Head.Version
+	block.Time = b.Head.Time
+	block.Fee = b.Head.Fee
+	block.PrevHash = b.Head.PrevHash.Hex()
+	block.BodyHash = b.Head.BodyHash.Hex()
+	block.UxHash = b.Head.UxHash.Hex()
+	block.Transactions = len(b.Body.Transactions)
+	block.Raw = hex.EncodeToString(encoder.Serialize(*b))
+
+	sig, ok, err := bc.store.GetBlockSignature(tx, b)
+	switch {
+	case err != nil:
+		block.DumpError = err.Error()
+	case ok:
+		block.Signature = sig.Hex()
+	}
+}
+
+// corruptedBlocksFromErr returns the corrupted blocks of a corruption error returned by CheckDatabase
+func corruptedBlocksFromErr(err error) []DBBlockError {
+	var seqs []uint64
+	switch e := err.(type) {
+	case ErrCorruptDB:
+		seqs = e.CorruptedBlocks
+	case historydb.ErrHistoryDBCorrupted:
+		seqs = e.CorruptedBlocks
+	case ErrCheckpointMismatch:
+		return []DBBlockError{
+			{
+				Seq:   e.Seq,
+				Hash:  e.Hash.Hex(),
+				Error: e.Error(),
+			},
+		}
+	}
+
+	blocks := make([]DBBlockError, 0, len(seqs))
+	for _, seq := range seqs {
+		blocks = append(blocks, DBBlockError{
+			Seq: seq,
+		})
+	}
+
+	return blocks
+}
+
+// corruptedBlocksFromReport returns the corrupted blocks of a DBVerifyReport, ordered by seq.
+// The errors of a block that has both a signature error and an index mismatch are joined.
+func corruptedBlocksFromReport(report *DBVerifyReport) []DBBlockError {
+	bySeq := make(map[uint64]DBBlockError)
+	for _, e := range append(append([]DBBlockError{}, report.SignatureErrors...), report.IndexMismatches...) {
+		if prev, ok := bySeq[e.Seq]; ok {
+			e.Error = strings.Join([]string{prev.Error, e.Error}, "; ")
+		}
+		bySeq[e.Seq] = e
+	}
+
+	blocks := make([]DBBlockError, 0, len(bySeq))
+	for _, e := range bySeq {
+		blocks = append(blocks, e)
+	}
+
+	sortBlockErrors(blocks)
+	return blocks
+}
+
+func newDBForensicsBucket(name []byte, s bolt.BucketStats) DBForensicsBucket {
+	return DBForensicsBucket{
+		Name:           string(name),
+		Keys:           s.KeyN,
+		Depth:          s.Depth,
+		BranchPages:    s.BranchPageN,
+		BranchOverflow: s.BranchOverflowN,
+		LeafPages:      s.LeafPageN,
+		LeafOverflow:   s.LeafOverflowN,
+		LeafInuse:      s.LeafInuse,
+		LeafAlloc:      s.LeafAlloc,
+		NestedBuckets:  s.BucketN - 1,
+	}
+}
+
+func newDBForensicsFile(db *bolt.DB, tx *bolt.Tx) DBForensicsFile {
+	stats := db.Stats()
+	f := DBForensicsFile{
+		Path:          db.Path(),
+		PageSize:      db.Info().PageSize,
+		FreePages:     stats.FreePageN,
+		PendingPages:  stats.PendingPageN,
+		FreeAlloc:     stats.FreeAlloc,
+		FreelistInuse: stats.FreelistInuse,
+	}
+
+	if fi, err := os.Stat(db.Path()); err == nil {
+		f.Size = fi.Size()
+	}
+
+	if tx != nil && f.PageSize != 0 {
+		f.Pages = tx.Size() / int64(f.PageSize)
+	}
+
+	return f
+}
+
+// WriteDBForensics writes a forensic bundle to path as JSON
+func WriteDBForensics(path string, f *DBForensics) error {
+	b, err := json.MarshalIndent(f, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, b, 0600)
+}
+
+// writeCorruptDBForensics writes the forensic bundle of a corrupted database next to its quarantined copy.
+// The bundle is only a diagnostic aid, so a failure is logged and does not stop the recovery.
+func writeCorruptDBForensics(ctx context.Context, db *dbutil.DB, cfg RecoverCorruptDBConfig, corruptErr error) {
+	corruptDBPath, err := makeCorruptDBPath(db.Path())
+	if err != nil {
+		logger.WithError(err).Error("writeCorruptDBForensics: makeCorruptDBPath failed")
+		return
+	}
+
+	f, err := NewDBForensics(ctx, db, DBForensicsConfig{
+		Pubkey:      cfg.Pubkey,
+		NodeVersion: cfg.NodeVersion,
+		Err:         corruptErr,
+	})
+	if err != nil {
+		logger.WithError(err).Error("writeCorruptDBForensics: NewDBForensics failed")
+		return
+	}
+
+	path := DBForensicsPath(corruptDBPath)
+	if err := WriteDBForensics(path, f); err != nil {
+		logger.WithError(err).Error("writeCorruptDBForensics: WriteDBForensics failed")
+		return
+	}
+
+	logger.Critical().Infof("Wrote the forensic bundle of the corrupted db to %s", path)
+}
@@ -0,0 +1,95 @@
+package visor
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/encoder"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor/blockdb"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+func TestNewDBForensics(t *testing.T) {
+	db, shutdown := copyTestDB(t, "./testdata/data.db.ok")
+	defer shutdown()
+
+	pubkey := mustParsePubkey(t)
+	cfg := DBForensicsConfig{
+		Pubkey:      pubkey,
+		NodeVersion: "0.25.0",
+	}
+
+	// A valid database has no corrupted blocks
+	f, err := NewDBForensics(context.Background(), db, cfg)
+	require.NoError(t, err)
+	require.Equal(t, "0.25.0", f.NodeVersion)
+	require.Equal(t, db.Path(), f.Path)
+	require.Empty(t, f.Error)
+	require.Empty(t, f.CorruptedBlocks)
+	require.NotZero(t, f.HeadSeq)
+	require.NotEmpty(t, f.HeadHash)
+	require.NotEmpty(t, f.Buckets)
+	require.Len(t, f.Files, 1)
+	require.Equal(t, db.Path(), f.Files[0].Path)
+	require.NotZero(t, f.Files[0].Pages)
+
+	// Corrupt the signature of block 3
+	bc, err := NewBlockchain(db, BlockchainConfig{Pubkey: pubkey})
+	require.NoError(t, err)
+
+	_, seckey := cipher.GenerateKeyPair()
+	var hash cipher.SHA256
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		b, err := bc.GetSignedBlockBySeq(tx, 3)
+		require.NoError(t, err)
+		hash = b.HashHeader()
+		badSig := cipher.MustSignHash(testutil.RandSHA256(t), seckey)
+		return dbutil.PutBucketValue(tx, blockdb.BlockSigsBkt, hash[:], encoder.Serialize(badSig))
+	})
+	require.NoError(t, err)
+
+	f, err = NewDBForensics(context.Background(), db, cfg)
+	require.NoError(t, err)
+	require.NotEmpty(t, f.Error)
+	require.Len(t, f.CorruptedBlocks, 1)
+
+	block := f.CorruptedBlocks[0]
+	require.Equal(t, uint64(3), block.Seq)
+	require.Equal(t, hash.Hex(), block.Hash)
+	require.NotEmpty(t, block.Error)
+	require.NotEmpty(t, block.Raw)
+	require.NotEmpty(t, block.Signature)
+	require.Empty(t, block.DumpError)
+}
+
+func TestRecoverCorruptDBWritesForensics(t *testing.T) {
+	db, shutdown := copyTestDB(t, "./testdata/data.db.notxn")
+	defer shutdown()
+
+	dbPath := db.Path()
+
+	newDB, err := ResetCorruptDB(context.Background(), db, CheckDatabaseConfig{
+		Pubkey: mustParsePubkey(t),
+	})
+	require.NoError(t, err)
+	db = newDB
+
+	// The bundle is written next to the quarantined copy, and is not listed as a quarantined database
+	dbs, err := ListQuarantinedDBs(dbPath)
+	require.NoError(t, err)
+	require.Len(t, dbs, 1)
+
+	forensicsPath := DBForensicsPath(dbs[0].Path)
+	_, err = os.Stat(forensicsPath)
+	require.NoError(t, err)
+
+	// Deleting the quarantined database deletes its bundle
+	require.NoError(t, DeleteQuarantinedDB(dbPath, dbs[0].Name))
+	_, err = os.Stat(forensicsPath)
+	require.True(t, os.IsNotExist(err))
+}
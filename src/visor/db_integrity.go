@@ -0,0 +1,146 @@
+package visor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher/encoder"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+var (
+	// DBIntegrityReportBkt stores the report of the last database verification
+	DBIntegrityReportBkt = []byte("db_integrity_report")
+
+	dbIntegrityReportKey = []byte("report")
+)
+
+// maxDBIntegrityActions is the number of recovery actions kept in the DBIntegrityReport, the oldest are dropped first
+const maxDBIntegrityActions = 100
+
+// DBIntegrityAction is a recovery action taken on the database
+type DBIntegrityAction struct {
+	// Unix time the action was taken
+	Time        uint64
+	Description string
+}
+
+// DBIntegrityReport is the outcome of the last database verification done by CheckDatabase,
+// and the recovery actions taken on the database. It is saved in the database,
+// so that operators can audit what the node did after a crash.
+type DBIntegrityReport struct {
+	// Unix time the verification finished, 0 if the database was not verified since the report was created
+	Time uint64
+	// Head block seq at the time of the verification
+	HeadSeq uint64
+	// Whether the entire blockchain was verified, instead of the blocks added since the last verification
+	FullVerify bool
+	// Number of blocks verified
+	BlocksVerified uint64
+	// Verification error, empty if the database passed verification
+	Error string
+	// Seqs of the blocks with an invalid signature or body
+	CorruptedBlocks []uint64
+	// Seqs of the blocks with corrupted historydb indexes
+	CorruptedHistoryBlocks []uint64
+	// Unix time of the last background scrub since the verification, see DBScrubber.
+	// The corruption found by the scrubs is added to Error, CorruptedBlocks and CorruptedHistoryBlocks.
+	ScrubTime uint64
+	// Number of blocks re-verified by the background scrubs since the verification
+	BlocksScrubbed uint64
+	// Most recent recovery actions taken on the database, oldest first.
+	// They are kept across verifications, up to maxDBIntegrityActions.
+	Actions []DBIntegrityAction
+}
+
+// GetDBIntegrityReport returns the saved DBIntegrityReport, or nil if there is none
+func GetDBIntegrityReport(db *dbutil.DB) (*DBIntegrityReport, error) {
+	var report *DBIntegrityReport
+	if err := db.View("GetDBIntegrityReport", func(tx *dbutil.Tx) error {
+		var err error
+		report, err = getDBIntegrityReport(tx)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+func getDBIntegrityReport(tx *dbutil.Tx) (*DBIntegrityReport, error) {
+	var report DBIntegrityReport
+	ok, err := dbutil.GetBucketObjectDecoded(tx, DBIntegrityReportBkt, dbIntegrityReportKey, &report)
+	if err != nil {
+		switch err.(type) {
+		case dbutil.ErrBucketNotExist:
+			return nil, nil
+		default:
+			return nil, err
+		}
+	} else if !ok {
+		return nil, nil
+	}
+
+	return &report, nil
+}
+
+func setDBIntegrityReport(tx *dbutil.Tx, report DBIntegrityReport) error {
+	if _, err := tx.CreateBucketIfNotExists(DBIntegrityReportBkt); err != nil {
+		return dbutil.NewErrCreateBucketFailed(DBIntegrityReportBkt, err)
+	}
+
+	return dbutil.PutBucketValue(tx, DBIntegrityReportBkt, dbIntegrityReportKey, encoder.Serialize(report))
+}
+
+// saveDBIntegrityReport saves the report of a verification, keeping the recovery actions of the previous report
+func saveDBIntegrityReport(db *dbutil.DB, report DBIntegrityReport) error {
+	return db.Update("saveDBIntegrityReport", func(tx *dbutil.Tx) error {
+		prev, err := getDBIntegrityReport(tx)
+		if err != nil {
+			return err
+		}
+
+		if prev != nil {
+			report.Actions = prev.Actions
+		}
+
+		return setDBIntegrityReport(tx, report)
+	})
+}
+
+// addDBIntegrityAction records a recovery action in the DBIntegrityReport
+func addDBIntegrityAction(tx *dbutil.Tx, format string, args ...interface{}) error {
+	report, err := getDBIntegrityReport(tx)
+	if err != nil {
+		return err
+	}
+
+	if report == nil {
+		report = &DBIntegrityReport{}
+	}
+
+	report.Actions = append(report.Actions, DBIntegrityAction{
+		Time:        uint64(time.Now().UTC().Unix()),
+		Description: fmt.Sprintf(format, args...),
+	})
+
+	if n := len(report.Actions); n > maxDBIntegrityActions {
+		report.Actions = report.Actions[n-maxDBIntegrityActions:]
+	}
+
+	return setDBIntegrityReport(tx, *report)
+}
+
+// recordDBIntegrityAction records a recovery action in the DBIntegrityReport of a writable database.
+// Failing to record the action is logged, since the action itself was successful.
+func recordDBIntegrityAction(db *dbutil.DB, format string, args ...interface{}) {
+	if db.IsReadOnly() {
+		return
+	}
+
+	if err := db.Update("recordDBIntegrityAction", func(tx *dbutil.Tx) error {
+		return addDBIntegrityAction(tx, format, args...)
+	}); err != nil {
+		logger.WithError(err).Error("recordDBIntegrityAction failed")
+	}
+}
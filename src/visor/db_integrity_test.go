@@ -0,0 +1,69 @@
+package visor
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+func TestCheckDatabaseIntegrityReport(t *testing.T) {
+	db, shutdown := copyTestDB(t, "./testdata/data.db.ok")
+	defer shutdown()
+
+	pubkey := mustParsePubkey(t)
+
+	report, err := GetDBIntegrityReport(db)
+	require.NoError(t, err)
+	require.Nil(t, report)
+
+	err = CheckDatabase(context.Background(), db, CheckDatabaseConfig{Pubkey: pubkey, FullVerify: true})
+	require.NoError(t, err)
+
+	report, err = GetDBIntegrityReport(db)
+	require.NoError(t, err)
+	require.NotNil(t, report)
+	require.NotEqual(t, uint64(0), report.Time)
+	require.Equal(t, uint64(10), report.HeadSeq)
+	require.True(t, report.FullVerify)
+	require.Equal(t, uint64(11), report.BlocksVerified)
+	require.Empty(t, report.Error)
+	require.Empty(t, report.CorruptedBlocks)
+	require.Empty(t, report.CorruptedHistoryBlocks)
+
+	// Recovery actions are kept across verifications, up to maxDBIntegrityActions
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		for i := 0; i < maxDBIntegrityActions+2; i++ {
+			require.NoError(t, addDBIntegrityAction(tx, "action %d", i))
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	// Only the blocks added since the last verification are verified
+	err = CheckDatabase(context.Background(), db, CheckDatabaseConfig{Pubkey: pubkey})
+	require.NoError(t, err)
+
+	report, err = GetDBIntegrityReport(db)
+	require.NoError(t, err)
+	require.False(t, report.FullVerify)
+	require.Equal(t, uint64(0), report.BlocksVerified)
+	require.Len(t, report.Actions, maxDBIntegrityActions)
+	require.Equal(t, "action 2", report.Actions[0].Description)
+	require.Equal(t, fmt.Sprintf("action %d", maxDBIntegrityActions+1), report.Actions[maxDBIntegrityActions-1].Description)
+
+	// A failed verification is reported
+	checkErr := CheckDatabase(context.Background(), db, CheckDatabaseConfig{
+		Pubkey:      pubkey,
+		FullVerify:  true,
+		Checkpoints: Checkpoints{{Seq: 0, Hash: testChainHashes(t, "./testdata/data.db.ok")[1]}},
+	})
+	require.Error(t, checkErr)
+
+	report, err = GetDBIntegrityReport(db)
+	require.NoError(t, err)
+	require.Equal(t, checkErr.Error(), report.Error)
+}
@@ -0,0 +1,68 @@
+package visor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// ErrDBLocked is returned when the database file is locked by another process, usually another skycoin node
+type ErrDBLocked struct {
+	// Path of the locked database file
+	Path string
+	// PID of the process holding the lock, 0 if it could not be detected
+	PID int
+}
+
+// NewErrDBLocked creates ErrDBLocked
+func NewErrDBLocked(path string, pid int) error {
+	return ErrDBLocked{
+		Path: path,
+		PID:  pid,
+	}
+}
+
+func (e ErrDBLocked) Error() string {
+	if e.PID == 0 {
+		return fmt.Sprintf("Database %s is locked by another process. Is another skycoin instance running? Stop it, or use a different -data-dir", e.Path)
+	}
+
+	return fmt.Sprintf("Database %s is locked by another process (pid %d). Is another skycoin instance running? Stop it, or use a different -data-dir", e.Path, e.PID)
+}
+
+// OpenBoltDB opens a bolt database file, waiting for the file lock to be released by another process.
+// The lock is attempted cfg.LockRetries more times, with an exponential backoff, before ErrDBLocked is returned.
+// cfg.Split is ignored, a single file is opened with cfg.Options.
+func OpenBoltDB(dbFile string, cfg OpenDBConfig) (*bolt.DB, error) {
+	if err := cfg.Verify(); err != nil {
+		return nil, err
+	}
+
+	backoff := cfg.LockRetryBackoff
+	for attempt := 0; ; attempt++ {
+		opts := cfg.Options.boltOptions()
+		opts.Timeout = cfg.LockTimeout
+		opts.ReadOnly = cfg.ReadOnly
+
+		db, err := bolt.Open(dbFile, 0600, &opts)
+		if err == nil {
+			cfg.Options.apply(db)
+			return db, nil
+		}
+
+		if err != bolt.ErrTimeout {
+			return nil, fmt.Errorf("Open boltdb failed, %v", err)
+		}
+
+		pid := dbLockHolder(dbFile)
+		if attempt >= cfg.LockRetries {
+			return nil, NewErrDBLocked(dbFile, pid)
+		}
+
+		logger.Warningf("Database %s is locked by process %d, retrying in %s (%d/%d)", dbFile, pid, backoff, attempt+1, cfg.LockRetries)
+
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
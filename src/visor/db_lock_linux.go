@@ -0,0 +1,57 @@
+// +build linux
+
+package visor
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// dbLockHolder returns the PID of the process holding the flock of the file, 0 if it can't be detected.
+// The file locks are listed in /proc/locks, e.g.
+// 1: FLOCK  ADVISORY  WRITE 1234 08:01:5678 0 EOF
+// where 08:01 is the device of the file, in hex, and 5678 is its inode.
+func dbLockHolder(path string) int {
+	var st syscall.Stat_t
+	if err := syscall.Stat(path, &st); err != nil {
+		return 0
+	}
+
+	dev := uint64(st.Dev)
+	major := ((dev >> 8) & 0xfff) | ((dev >> 32) & ^uint64(0xfff))
+	minor := (dev & 0xff) | ((dev >> 12) & ^uint64(0xff))
+	fileID := fmt.Sprintf("%02x:%02x:%d", major, minor, st.Ino)
+
+	f, err := os.Open("/proc/locks")
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	return parseProcLocks(bufio.NewScanner(f), fileID)
+}
+
+// parseProcLocks returns the PID of the process holding the FLOCK of the file identified by fileID
+func parseProcLocks(s *bufio.Scanner, fileID string) int {
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+
+		// Processes waiting for a lock are listed with "->" after the lock number
+		if len(fields) < 6 || fields[1] != "FLOCK" || fields[5] != fileID {
+			continue
+		}
+
+		pid, err := strconv.Atoi(fields[4])
+		if err != nil {
+			continue
+		}
+
+		return pid
+	}
+
+	return 0
+}
@@ -0,0 +1,8 @@
+// +build !linux
+
+package visor
+
+// dbLockHolder returns 0, the process holding the flock of a file is only detected on linux
+func dbLockHolder(path string) int {
+	return 0
+}
@@ -0,0 +1,58 @@
+package visor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenBoltDBLocked(t *testing.T) {
+	dir, err := ioutil.TempDir("", "testdb")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	dbPath := filepath.Join(dir, "data.db")
+
+	db, err := OpenBoltDB(dbPath, NewOpenDBConfig())
+	require.NoError(t, err)
+
+	cfg := NewOpenDBConfig()
+	cfg.LockTimeout = 50 * time.Millisecond
+	cfg.LockRetries = 2
+	cfg.LockRetryBackoff = 10 * time.Millisecond
+
+	// The lock is held by this process
+	start := time.Now()
+	_, err = OpenBoltDB(dbPath, cfg)
+	require.Error(t, err)
+	require.True(t, time.Since(start) >= 3*cfg.LockTimeout+3*cfg.LockRetryBackoff)
+
+	lockedErr, ok := err.(ErrDBLocked)
+	require.True(t, ok)
+	require.Equal(t, dbPath, lockedErr.Path)
+	if runtime.GOOS == "linux" {
+		require.Equal(t, os.Getpid(), lockedErr.PID)
+	}
+
+	_, err = OpenDBWithConfig(dbPath, cfg)
+	require.IsType(t, ErrDBLocked{}, err)
+
+	// The database is opened once the lock is released
+	go func() {
+		time.Sleep(60 * time.Millisecond)
+		db.Close()
+	}()
+
+	db, err = OpenBoltDB(dbPath, cfg)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	cfg.LockTimeout = 0
+	_, err = OpenBoltDB(dbPath, cfg)
+	require.EqualError(t, err, "LockTimeout must be > 0")
+}
@@ -0,0 +1,89 @@
+package visor
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+
+	"github.com/boltdb/bolt"
+
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+// DBOptions are the bolt options used to open the database files.
+// The defaults are bolt's defaults, which suit most nodes.
+type DBOptions struct {
+	// Initial size of the memory map in bytes. A write transaction has to wait for the read transactions
+	// to finish when the database outgrows its memory map, so a map larger than the database avoids
+	// stalling writes on huge chains. If 0, the map is sized to the file.
+	InitialMmapSize int
+	// Prefault the memory map when the database is opened (MAP_POPULATE), which speeds up the first reads
+	// on slow disks at the cost of a slower startup. Only supported on linux.
+	MmapPopulate bool
+	// Don't fsync the database file after each commit. Writes are much faster on slow disks, but the database
+	// can be corrupted if the machine crashes or loses power. Ignored by bolt on openbsd.
+	NoSync bool
+	// Don't fsync the database file when it grows. Only useful on filesystems without sparse file support
+	NoGrowSync bool
+	// Number of bytes the database file grows by when it is full. If 0, bolt's default of 16MB is used
+	AllocSize int
+}
+
+// NewDBOptions creates the default DBOptions
+func NewDBOptions() DBOptions {
+	return DBOptions{}
+}
+
+// Verify checks that the options are valid on this platform
+func (o DBOptions) Verify() error {
+	if o.InitialMmapSize < 0 {
+		return errors.New("InitialMmapSize must be >= 0")
+	}
+
+	if o.AllocSize < 0 {
+		return errors.New("AllocSize must be >= 0")
+	}
+
+	if o.MmapPopulate && mmapPopulateFlag == 0 {
+		return fmt.Errorf("MmapPopulate is not supported on %s", runtime.GOOS)
+	}
+
+	if o.NoSync && bolt.IgnoreNoSync {
+		return fmt.Errorf("NoSync is not supported on %s", runtime.GOOS)
+	}
+
+	return nil
+}
+
+// boltOptions returns the bolt.Options set by the options
+func (o DBOptions) boltOptions() bolt.Options {
+	opts := bolt.Options{
+		NoGrowSync:      o.NoGrowSync,
+		InitialMmapSize: o.InitialMmapSize,
+	}
+
+	if o.MmapPopulate {
+		opts.MmapFlags = mmapPopulateFlag
+	}
+
+	return opts
+}
+
+// apply sets the options that bolt reads from the DB after it is opened
+func (o DBOptions) apply(db *bolt.DB) {
+	db.NoSync = o.NoSync
+	if o.AllocSize > 0 {
+		db.AllocSize = o.AllocSize
+	}
+}
+
+// dbOptions returns the options an open database was opened with, to reopen it with the same options.
+// The memory map of a reopened database is sized to its file, so InitialMmapSize is not kept.
+func dbOptions(db *dbutil.DB) DBOptions {
+	return DBOptions{
+		MmapPopulate: mmapPopulateFlag != 0 && db.DB.MmapFlags&mmapPopulateFlag != 0,
+		NoSync:       db.DB.NoSync,
+		NoGrowSync:   db.DB.NoGrowSync,
+		AllocSize:    db.DB.AllocSize,
+	}
+}
@@ -0,0 +1,8 @@
+// +build linux
+
+package visor
+
+import "syscall"
+
+// mmapPopulateFlag is the mmap flag set by DBOptions.MmapPopulate
+const mmapPopulateFlag = syscall.MAP_POPULATE
@@ -0,0 +1,6 @@
+// +build !linux
+
+package visor
+
+// mmapPopulateFlag is 0, DBOptions.MmapPopulate is only supported on linux
+const mmapPopulateFlag = 0
@@ -0,0 +1,54 @@
+package visor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDBOptionsVerify(t *testing.T) {
+	require.NoError(t, NewDBOptions().Verify())
+	require.Error(t, DBOptions{InitialMmapSize: -1}.Verify())
+	require.Error(t, DBOptions{AllocSize: -1}.Verify())
+
+	err := DBOptions{MmapPopulate: true}.Verify()
+	if mmapPopulateFlag == 0 {
+		require.Error(t, err)
+	} else {
+		require.NoError(t, err)
+	}
+}
+
+func TestOpenDBWithOptions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dboptions")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cfg := NewOpenDBConfig()
+	cfg.Options = DBOptions{
+		InitialMmapSize: 1 << 24,
+		NoSync:          true,
+		NoGrowSync:      true,
+		AllocSize:       1 << 20,
+	}
+
+	db, err := OpenDBWithConfig(filepath.Join(dir, "data.db"), cfg)
+	require.NoError(t, err)
+	defer db.Close()
+
+	require.True(t, db.DB.NoSync)
+	require.True(t, db.DB.NoGrowSync)
+	require.Equal(t, 1<<20, db.DB.AllocSize)
+
+	// The options are kept when the database is reopened, except the initial mmap size
+	expected := cfg.Options
+	expected.InitialMmapSize = 0
+	require.Equal(t, expected, dbOptions(db))
+
+	cfg.Options.AllocSize = -1
+	_, err = OpenDBWithConfig(filepath.Join(dir, "other.db"), cfg)
+	require.Error(t, err)
+}
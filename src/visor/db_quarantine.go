@@ -0,0 +1,217 @@
+package visor
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultQuarantineMaxCopies is the default number of quarantined databases that are kept
+const DefaultQuarantineMaxCopies = 3
+
+// QuarantineConfig configures the retention of quarantined databases,
+// the $FILE.corrupt.$HASH copies made when a corrupted database is recovered
+type QuarantineConfig struct {
+	// MaxCopies is the maximum number of quarantined databases that are kept, the oldest are deleted first.
+	// If 0, all quarantined databases are kept.
+	MaxCopies int
+	// Compress gzip-compresses quarantined databases
+	Compress bool
+}
+
+// QuarantinedDB describes a quarantined database file
+type QuarantinedDB struct {
+	// Name of the file, in the directory of the database
+	Name string
+	// Path of the file
+	Path string
+	// Size of the file in bytes
+	Size int64
+	// Time the file was last modified
+	Time time.Time
+	// Compressed is true if the file is gzip-compressed
+	Compressed bool
+}
+
+// ErrQuarantinedDBNotExist is returned if a quarantined database does not exist
+type ErrQuarantinedDBNotExist struct {
+	Name string
+}
+
+// NewErrQuarantinedDBNotExist creates an ErrQuarantinedDBNotExist
+func NewErrQuarantinedDBNotExist(name string) error {
+	return ErrQuarantinedDBNotExist{
+		Name: name,
+	}
+}
+
+func (e ErrQuarantinedDBNotExist) Error() string {
+	return fmt.Sprintf("Quarantined database %q does not exist", e.Name)
+}
+
+// ListQuarantinedDBs returns the quarantined copies of the database at dbPath, newest first
+func ListQuarantinedDBs(dbPath string) ([]QuarantinedDB, error) {
+	dir, file := filepath.Split(dbPath)
+	if dir == "" {
+		dir = "."
+	}
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := file + ".corrupt."
+
+	var dbs []QuarantinedDB
+	for _, fi := range entries {
+		name := fi.Name()
+		if fi.IsDir() || !strings.HasPrefix(name, prefix) || strings.HasSuffix(name, ".tmp") || strings.HasSuffix(name, dbForensicsSuffix) {
+			continue
+		}
+
+		dbs = append(dbs, QuarantinedDB{
+			Name:       name,
+			Path:       filepath.Join(dir, name),
+			Size:       fi.Size(),
+			Time:       fi.ModTime(),
+			Compressed: strings.HasSuffix(name, ".gz"),
+		})
+	}
+
+	sort.Slice(dbs, func(i, j int) bool {
+		if dbs[i].Time.Equal(dbs[j].Time) {
+			return dbs[i].Name > dbs[j].Name
+		}
+		return dbs[i].Time.After(dbs[j].Time)
+	})
+
+	return dbs, nil
+}
+
+// DeleteQuarantinedDB deletes a quarantined copy of the database at dbPath.
+// name must be the name of a file returned by ListQuarantinedDBs.
+func DeleteQuarantinedDB(dbPath, name string) error {
+	dbs, err := ListQuarantinedDBs(dbPath)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range dbs {
+		if d.Name == name {
+			return removeQuarantinedDB(d)
+		}
+	}
+
+	return NewErrQuarantinedDBNotExist(name)
+}
+
+// ApplyQuarantinePolicy compresses and deletes quarantined copies of the database at dbPath,
+// according to the retention policy in cfg
+func ApplyQuarantinePolicy(dbPath string, cfg QuarantineConfig) error {
+	dbs, err := ListQuarantinedDBs(dbPath)
+	if err != nil {
+		return err
+	}
+
+	if cfg.MaxCopies > 0 && len(dbs) > cfg.MaxCopies {
+		for _, d := range dbs[cfg.MaxCopies:] {
+			logger.Infof("Deleting quarantined database %s", d.Path)
+			if err := removeQuarantinedDB(d); err != nil {
+				return err
+			}
+		}
+		dbs = dbs[:cfg.MaxCopies]
+	}
+
+	if !cfg.Compress {
+		return nil
+	}
+
+	for _, d := range dbs {
+		if d.Compressed {
+			continue
+		}
+
+		logger.Infof("Compressing quarantined database %s", d.Path)
+		if err := gzipFile(d.Path); err != nil {
+			return fmt.Errorf("Compress quarantined database %s failed: %v", d.Path, err)
+		}
+	}
+
+	return nil
+}
+
+// removeQuarantinedDB deletes a quarantined database and its forensic bundle, if any
+func removeQuarantinedDB(d QuarantinedDB) error {
+	if err := os.Remove(d.Path); err != nil {
+		return err
+	}
+
+	forensicsPath := DBForensicsPath(strings.TrimSuffix(d.Path, ".gz"))
+	if err := os.Remove(forensicsPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// gzipFile replaces a file with a gzip-compressed copy named $FILE.gz,
+// preserving the modification time so that the age of the file is not lost
+func gzipFile(path string) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	gzPath := path + ".gz"
+	tmpPath := gzPath + ".tmp"
+
+	out, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	if err := func() error {
+		defer out.Close()
+
+		zw := gzip.NewWriter(out)
+		if _, err := io.Copy(zw, in); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		if err := out.Sync(); err != nil {
+			return err
+		}
+		return out.Close()
+	}(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Chtimes(tmpPath, fi.ModTime(), fi.ModTime()); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, gzPath); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return os.Remove(path)
+}
@@ -0,0 +1,149 @@
+package visor
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// makeQuarantineDir creates a database file and n quarantined copies of it,
+// data.db.corrupt.0 being the oldest
+func makeQuarantineDir(t *testing.T, n int) (string, string) {
+	dir, err := ioutil.TempDir("", "quarantine")
+	require.NoError(t, err)
+
+	dbPath := filepath.Join(dir, "data.db")
+	require.NoError(t, ioutil.WriteFile(dbPath, []byte("db"), 0600))
+
+	// Files that are not quarantined copies of data.db
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "other.db.corrupt.0"), []byte("other"), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "data.db.corrupt.x.gz.tmp"), []byte("tmp"), 0600))
+
+	now := time.Now()
+	for i := 0; i < n; i++ {
+		p := filepath.Join(dir, fmt.Sprintf("data.db.corrupt.%d", i))
+		require.NoError(t, ioutil.WriteFile(p, bytes.Repeat([]byte{byte(i)}, 100), 0600))
+		mtime := now.Add(time.Duration(i-n) * time.Hour)
+		require.NoError(t, os.Chtimes(p, mtime, mtime))
+	}
+
+	return dir, dbPath
+}
+
+func quarantinedNames(t *testing.T, dbPath string) []string {
+	dbs, err := ListQuarantinedDBs(dbPath)
+	require.NoError(t, err)
+
+	var names []string
+	for _, d := range dbs {
+		names = append(names, d.Name)
+	}
+	return names
+}
+
+func TestListQuarantinedDBs(t *testing.T) {
+	dir, dbPath := makeQuarantineDir(t, 3)
+	defer os.RemoveAll(dir)
+
+	dbs, err := ListQuarantinedDBs(dbPath)
+	require.NoError(t, err)
+	require.Len(t, dbs, 3)
+
+	require.Equal(t, "data.db.corrupt.2", dbs[0].Name)
+	require.Equal(t, filepath.Join(dir, "data.db.corrupt.2"), dbs[0].Path)
+	require.Equal(t, int64(100), dbs[0].Size)
+	require.False(t, dbs[0].Compressed)
+	require.Equal(t, []string{"data.db.corrupt.2", "data.db.corrupt.1", "data.db.corrupt.0"}, quarantinedNames(t, dbPath))
+}
+
+func TestDeleteQuarantinedDB(t *testing.T) {
+	dir, dbPath := makeQuarantineDir(t, 2)
+	defer os.RemoveAll(dir)
+
+	err := DeleteQuarantinedDB(dbPath, "data.db.corrupt.0")
+	require.NoError(t, err)
+	require.Equal(t, []string{"data.db.corrupt.1"}, quarantinedNames(t, dbPath))
+
+	// Only quarantined databases can be deleted
+	for _, name := range []string{"data.db.corrupt.0", "data.db", "other.db.corrupt.0", "../data.db.corrupt.1"} {
+		err = DeleteQuarantinedDB(dbPath, name)
+		require.Equal(t, NewErrQuarantinedDBNotExist(name), err)
+	}
+
+	_, err = os.Stat(dbPath)
+	require.NoError(t, err)
+	_, err = os.Stat(filepath.Join(dir, "other.db.corrupt.0"))
+	require.NoError(t, err)
+}
+
+func TestApplyQuarantinePolicy(t *testing.T) {
+	tt := []struct {
+		name  string
+		cfg   QuarantineConfig
+		names []string
+	}{
+		{
+			name:  "keep all",
+			cfg:   QuarantineConfig{},
+			names: []string{"data.db.corrupt.3", "data.db.corrupt.2", "data.db.corrupt.1", "data.db.corrupt.0"},
+		},
+		{
+			name:  "max copies",
+			cfg:   QuarantineConfig{MaxCopies: 2},
+			names: []string{"data.db.corrupt.3", "data.db.corrupt.2"},
+		},
+		{
+			name:  "max copies more than quarantined",
+			cfg:   QuarantineConfig{MaxCopies: 10},
+			names: []string{"data.db.corrupt.3", "data.db.corrupt.2", "data.db.corrupt.1", "data.db.corrupt.0"},
+		},
+		{
+			name:  "compress",
+			cfg:   QuarantineConfig{MaxCopies: 3, Compress: true},
+			names: []string{"data.db.corrupt.3.gz", "data.db.corrupt.2.gz", "data.db.corrupt.1.gz"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			dir, dbPath := makeQuarantineDir(t, 4)
+			defer os.RemoveAll(dir)
+
+			err := ApplyQuarantinePolicy(dbPath, tc.cfg)
+			require.NoError(t, err)
+			require.Equal(t, tc.names, quarantinedNames(t, dbPath))
+
+			// Applying the policy again changes nothing
+			err = ApplyQuarantinePolicy(dbPath, tc.cfg)
+			require.NoError(t, err)
+			require.Equal(t, tc.names, quarantinedNames(t, dbPath))
+
+			if !tc.cfg.Compress {
+				return
+			}
+
+			dbs, err := ListQuarantinedDBs(dbPath)
+			require.NoError(t, err)
+			for i, d := range dbs {
+				require.True(t, d.Compressed)
+
+				f, err := os.Open(d.Path)
+				require.NoError(t, err)
+				zr, err := gzip.NewReader(f)
+				require.NoError(t, err)
+				data, err := ioutil.ReadAll(zr)
+				require.NoError(t, err)
+				f.Close()
+
+				require.Equal(t, bytes.Repeat([]byte{byte(3 - i)}, 100), data)
+			}
+		})
+	}
+}
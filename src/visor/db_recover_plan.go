@@ -0,0 +1,118 @@
+package visor
+
+import (
+	"context"
+
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/historydb"
+)
+
+// RecoverCorruptDBAction is the recovery performed by RecoverCorruptDB
+type RecoverCorruptDBAction string
+
+const (
+	// RecoverActionNone is planned if the database is not corrupted
+	RecoverActionNone RecoverCorruptDBAction = "none"
+	// RecoverActionRebuildHistoryBlocks copies the database to quarantine,
+	// then patches the broken history db index entries of the corrupted blocks, see VerifyDBFile for a dry run.
+	// The entire history db is rebuilt if the corrupted blocks are still corrupted afterwards.
+	RecoverActionRebuildHistoryBlocks RecoverCorruptDBAction = "rebuild_history_blocks"
+	// RecoverActionRebuildHistory copies the database to quarantine, then rebuilds the entire history db
+	RecoverActionRebuildHistory RecoverCorruptDBAction = "rebuild_history"
+	// RecoverActionResetDB moves the database to quarantine and creates an empty database,
+	// which is then synced from the network
+	RecoverActionResetDB RecoverCorruptDBAction = "reset_db"
+)
+
+// RecoverCorruptDBPlan describes what ResetCorruptDB would do with a database, without doing it
+type RecoverCorruptDBPlan struct {
+	// Path of the database file
+	Path string `json:"path"`
+	// Corruption error, empty if the database is not corrupted
+	Error string `json:"error,omitempty"`
+	// Blocks with a corrupted signature or hash, or which don't match a checkpoint
+	CorruptedBlocks []uint64 `json:"corrupted_blocks"`
+	// Blocks with corrupted history db indexes
+	CorruptedHistoryBlocks []uint64 `json:"corrupted_history_blocks"`
+	// Corrupted blocks that a node with block repair enabled requests from peers first.
+	// The recovery is only performed if the repair fails.
+	RepairableBlocks []uint64 `json:"repairable_blocks"`
+	// Recovery that would be performed
+	Action RecoverCorruptDBAction `json:"action"`
+	// Paths the database files would be copied or moved to. The paths end with a hash of the file contents
+	// at the time of the plan, which changes once the database is written to.
+	// The part files of a split database are quarantined too.
+	QuarantinePaths []string `json:"quarantine_paths"`
+	// Path of the forensic bundle that would be written next to the quarantined database
+	ForensicsPath string `json:"forensics_path,omitempty"`
+}
+
+// recoverCorruptDBAction returns the recovery RecoverCorruptDB performs for a corruption error
+func recoverCorruptDBAction(err error, readOnly bool) RecoverCorruptDBAction {
+	if e, ok := err.(historydb.ErrHistoryDBCorrupted); ok && !readOnly {
+		if len(e.CorruptedBlocks) == 0 {
+			return RecoverActionRebuildHistory
+		}
+		return RecoverActionRebuildHistoryBlocks
+	}
+
+	return RecoverActionResetDB
+}
+
+// PlanResetCorruptDB checks the database for corruption like ResetCorruptDB, and reports the recovery
+// ResetCorruptDB would perform, without modifying or quarantining the database.
+// The database can be opened read-only for the check, readOnly is whether it would be opened read-only
+// when it is recovered.
+func PlanResetCorruptDB(ctx context.Context, db *dbutil.DB, cfg CheckDatabaseConfig, readOnly bool) (*RecoverCorruptDBPlan, error) {
+	err := CheckDatabase(ctx, db, cfg)
+	if err != nil && !IsCorruptDBError(err) {
+		return nil, err
+	}
+
+	return PlanRecoverCorruptDB(db, err, readOnly)
+}
+
+// PlanRecoverCorruptDB reports the recovery RecoverCorruptDB would perform for a corruption error returned by CheckDatabase.
+// If err is nil, RecoverActionNone is planned.
+func PlanRecoverCorruptDB(db *dbutil.DB, err error, readOnly bool) (*RecoverCorruptDBPlan, error) {
+	plan := &RecoverCorruptDBPlan{
+		Path:                   db.Path(),
+		CorruptedBlocks:        []uint64{},
+		CorruptedHistoryBlocks: []uint64{},
+		RepairableBlocks:       []uint64{},
+		Action:                 RecoverActionNone,
+		QuarantinePaths:        []string{},
+	}
+
+	if err == nil {
+		return plan, nil
+	}
+
+	plan.Error = err.Error()
+
+	for _, b := range corruptedBlocksFromErr(err) {
+		if _, ok := err.(historydb.ErrHistoryDBCorrupted); ok {
+			plan.CorruptedHistoryBlocks = append(plan.CorruptedHistoryBlocks, b.Seq)
+		} else {
+			plan.CorruptedBlocks = append(plan.CorruptedBlocks, b.Seq)
+		}
+	}
+
+	if e, ok := err.(ErrCorruptDB); ok && !readOnly {
+		plan.RepairableBlocks = append(plan.RepairableBlocks, e.CorruptedBlocks...)
+	}
+
+	plan.Action = recoverCorruptDBAction(err, readOnly)
+
+	for _, path := range DBFiles(db) {
+		corruptDBPath, err := makeCorruptDBPath(path)
+		if err != nil {
+			return nil, err
+		}
+		plan.QuarantinePaths = append(plan.QuarantinePaths, corruptDBPath)
+	}
+
+	plan.ForensicsPath = DBForensicsPath(plan.QuarantinePaths[0])
+
+	return plan, nil
+}
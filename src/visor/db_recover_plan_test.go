@@ -0,0 +1,54 @@
+package visor
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanResetCorruptDB(t *testing.T) {
+	cfg := CheckDatabaseConfig{
+		Pubkey: mustParsePubkey(t),
+	}
+
+	t.Run("not corrupted", func(t *testing.T) {
+		db, shutdown := copyTestDB(t, "./testdata/data.db.ok")
+		defer shutdown()
+
+		plan, err := PlanResetCorruptDB(context.Background(), db, cfg, false)
+		require.NoError(t, err)
+		require.Equal(t, RecoverActionNone, plan.Action)
+		require.Empty(t, plan.Error)
+		require.Empty(t, plan.QuarantinePaths)
+	})
+
+	t.Run("corrupted history db", func(t *testing.T) {
+		db, shutdown := copyTestDB(t, "./testdata/data.db.notxn")
+		defer shutdown()
+
+		dbPath := db.Path()
+
+		// A read-only database is reset, since its history db can't be rebuilt
+		plan, err := PlanResetCorruptDB(context.Background(), db, cfg, true)
+		require.NoError(t, err)
+		require.Equal(t, RecoverActionResetDB, plan.Action)
+
+		plan, err = PlanResetCorruptDB(context.Background(), db, cfg, false)
+		require.NoError(t, err)
+		require.Equal(t, RecoverActionRebuildHistoryBlocks, plan.Action)
+		require.NotEmpty(t, plan.Error)
+		require.NotEmpty(t, plan.CorruptedHistoryBlocks)
+		require.Empty(t, plan.CorruptedBlocks)
+		require.Empty(t, plan.RepairableBlocks)
+		require.Len(t, plan.QuarantinePaths, 1)
+		require.True(t, strings.HasPrefix(plan.QuarantinePaths[0], dbPath+".corrupt."))
+		require.Equal(t, DBForensicsPath(plan.QuarantinePaths[0]), plan.ForensicsPath)
+
+		// Nothing is quarantined by the plan
+		dbs, err := ListQuarantinedDBs(dbPath)
+		require.NoError(t, err)
+		require.Empty(t, dbs)
+	})
+}
@@ -0,0 +1,204 @@
+package visor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+// DBReplicaStatus describes the snapshot served by a DBReplica
+type DBReplicaStatus struct {
+	// Seq of the head block of the snapshot
+	HeadSeq uint64
+	// Time the snapshot was taken, zero if no snapshot was taken yet
+	RefreshedAt time.Time
+}
+
+// DBReplica serves read-only queries from a snapshot copy of the database, opened read-only and refreshed periodically,
+// so that heavy read traffic, such as from the explorer, never holds transactions open on the database
+// written by the block application.
+// The snapshot is written to one of two files in dir, alternately, so that the previous snapshot can be read
+// while the next one is written. The part files of a split database are copied with it.
+// The queries run on the database until the first snapshot is taken.
+type DBReplica struct {
+	db       *dbutil.DB
+	bc       Blockchainer
+	dir      string
+	interval time.Duration
+
+	// refreshLock serializes Refresh and Close
+	refreshLock sync.Mutex
+	// replicaLock is held by the queries, and by Refresh and Close while they replace the replica
+	replicaLock sync.RWMutex
+	replica     *dbutil.DB
+	slot        int
+	status      DBReplicaStatus
+}
+
+// NewDBReplica creates a DBReplica writing the snapshots of db to dir every interval
+func NewDBReplica(db *dbutil.DB, bc Blockchainer, dir string, interval time.Duration) *DBReplica {
+	return &DBReplica{
+		db:       db,
+		bc:       bc,
+		dir:      dir,
+		interval: interval,
+	}
+}
+
+// replicaPath returns the path of the snapshot file of a slot, e.g. data.db.replica1
+func (r *DBReplica) replicaPath(slot int) string {
+	return filepath.Join(r.dir, fmt.Sprintf("%s.replica%d", filepath.Base(r.db.Path()), slot))
+}
+
+// View runs a read-only transaction on the latest snapshot, or on the database if no snapshot was taken yet
+func (r *DBReplica) View(name string, f func(*dbutil.Tx) error) error {
+	r.replicaLock.RLock()
+	defer r.replicaLock.RUnlock()
+
+	if r.replica == nil {
+		return r.db.View(name, f)
+	}
+
+	return r.replica.View(name, f)
+}
+
+// Status returns the status of the latest snapshot
+func (r *DBReplica) Status() DBReplicaStatus {
+	r.replicaLock.RLock()
+	defer r.replicaLock.RUnlock()
+	return r.status
+}
+
+// Refresh takes a new snapshot of the database and replaces the snapshot served by View with it,
+// once the queries running on the previous snapshot are done
+func (r *DBReplica) Refresh() error {
+	r.refreshLock.Lock()
+	defer r.refreshLock.Unlock()
+
+	if err := os.MkdirAll(r.dir, 0700); err != nil {
+		return err
+	}
+
+	slot := 1 - r.slot
+	path := r.replicaPath(slot)
+
+	var headSeq uint64
+	if err := r.db.View("DBReplica.Refresh", func(tx *dbutil.Tx) error {
+		var err error
+		headSeq, _, err = r.bc.HeadSeq(tx)
+		if err != nil {
+			return err
+		}
+
+		if _, err := writeDBSnapshot(tx.Tx, path); err != nil {
+			return err
+		}
+
+		for _, p := range r.db.Parts() {
+			if _, err := writeDBSnapshot(tx.PartTx(p.Name), SplitDBPartPath(path, p.Name)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	cfg := NewOpenDBConfig()
+	cfg.ReadOnly = true
+	cfg.Split = r.db.IsSplit()
+	cfg.Encryption = r.db.Encryption()
+	replica, err := OpenDBWithConfig(path, cfg)
+	if err != nil {
+		return err
+	}
+
+	r.replicaLock.Lock()
+	prev := r.replica
+	r.replica = replica
+	r.slot = slot
+	r.status = DBReplicaStatus{
+		HeadSeq:     headSeq,
+		RefreshedAt: time.Now().UTC(),
+	}
+	r.replicaLock.Unlock()
+
+	if prev != nil {
+		if err := prev.Close(); err != nil {
+			logger.WithError(err).Error("Failed to close the previous database replica")
+		}
+	}
+
+	logger.Debugf("Refreshed the database replica at block %d", headSeq)
+
+	return nil
+}
+
+// Run refreshes the snapshot every interval until ctx is done, then closes the replica and removes its files.
+// Refresh errors are logged, the previous snapshot keeps being served.
+func (r *DBReplica) Run(ctx context.Context) {
+	logger.Infof("Serving the API queries from a database replica in %s refreshed every %s", r.dir, r.interval)
+
+	defer func() {
+		if err := r.Close(); err != nil {
+			logger.WithError(err).Error("DBReplica.Close failed")
+		}
+	}()
+
+	if err := r.Refresh(); err != nil {
+		logger.WithError(err).Error("DBReplica.Refresh failed")
+	}
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Refresh(); err != nil {
+				logger.WithError(err).Error("DBReplica.Refresh failed")
+			}
+		}
+	}
+}
+
+// Close closes the replica and removes the snapshot files. The queries run on the database afterwards.
+func (r *DBReplica) Close() error {
+	r.refreshLock.Lock()
+	defer r.refreshLock.Unlock()
+
+	r.replicaLock.Lock()
+	replica := r.replica
+	r.replica = nil
+	r.status = DBReplicaStatus{}
+	r.replicaLock.Unlock()
+
+	var err error
+	if replica != nil {
+		err = replica.Close()
+	}
+
+	for slot := 0; slot < 2; slot++ {
+		path := r.replicaPath(slot)
+		paths := []string{path}
+		for _, p := range r.db.Parts() {
+			paths = append(paths, SplitDBPartPath(path, p.Name))
+		}
+
+		for _, p := range paths {
+			if rmErr := os.Remove(p); rmErr != nil && !os.IsNotExist(rmErr) && err == nil {
+				err = rmErr
+			}
+		}
+	}
+
+	return err
+}
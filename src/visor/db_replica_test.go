@@ -0,0 +1,137 @@
+package visor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/historydb"
+)
+
+func TestDBReplica(t *testing.T) {
+	for _, split := range []bool{false, true} {
+		name := "single file"
+		if split {
+			name = "split"
+		}
+
+		t.Run(name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "dbreplica")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			dbPath := filepath.Join(dir, "data.db")
+			err = ioutil.WriteFile(dbPath, readAll(t, "./testdata/data.db.ok"), 0600)
+			require.NoError(t, err)
+
+			cfg := NewOpenDBConfig()
+			cfg.Split = split
+			db, err := OpenDBWithConfig(dbPath, cfg)
+			require.NoError(t, err)
+			defer db.Close()
+
+			pubkey := mustParsePubkey(t)
+			bc, err := NewBlockchain(db, BlockchainConfig{Pubkey: pubkey})
+			require.NoError(t, err)
+
+			testBkt := []byte("replica_test")
+			history := historydb.New()
+
+			// readTestValue reads a value written to the database and the parsed block seq of the history,
+			// stored in a part file of a split database
+			readTestValue := func(r *DBReplica) ([]byte, uint64) {
+				var v []byte
+				var parsedSeq uint64
+				err := r.View("", func(tx *dbutil.Tx) error {
+					var err error
+					if dbutil.Exists(tx, testBkt) {
+						v, err = dbutil.GetBucketValue(tx, testBkt, []byte("key"))
+						if err != nil {
+							return err
+						}
+					}
+
+					parsedSeq, _, err = history.ParsedBlockSeq(tx)
+					return err
+				})
+				require.NoError(t, err)
+				return v, parsedSeq
+			}
+
+			writeTestValue := func(v string) {
+				err := db.Update("", func(tx *dbutil.Tx) error {
+					if _, err := tx.CreateBucketIfNotExists(testBkt); err != nil {
+						return err
+					}
+					return dbutil.PutBucketValue(tx, testBkt, []byte("key"), []byte(v))
+				})
+				require.NoError(t, err)
+			}
+
+			replicaDir := filepath.Join(dir, "replica")
+			r := NewDBReplica(db, bc, replicaDir, 0)
+
+			// The queries run on the database until the first snapshot is taken
+			writeTestValue("a")
+			v, _ := readTestValue(r)
+			require.Equal(t, []byte("a"), v)
+			require.True(t, r.Status().RefreshedAt.IsZero())
+
+			err = r.Refresh()
+			require.NoError(t, err)
+			require.Equal(t, uint64(10), r.Status().HeadSeq)
+			require.False(t, r.Status().RefreshedAt.IsZero())
+
+			path := filepath.Join(replicaDir, "data.db.replica1")
+			require.Equal(t, path, r.replicaPath(r.slot))
+			_, err = os.Stat(path)
+			require.NoError(t, err)
+			if split {
+				_, err = os.Stat(SplitDBPartPath(path, "history"))
+				require.NoError(t, err)
+			}
+
+			// The snapshot does not see the later writes until it is refreshed
+			writeTestValue("b")
+			v, parsedSeq := readTestValue(r)
+			require.Equal(t, []byte("a"), v)
+			require.Equal(t, uint64(10), parsedSeq)
+
+			err = db.Update("", func(tx *dbutil.Tx) error {
+				return history.Erase(tx)
+			})
+			require.NoError(t, err)
+
+			err = r.Refresh()
+			require.NoError(t, err)
+			require.Equal(t, filepath.Join(replicaDir, "data.db.replica0"), r.replicaPath(r.slot))
+
+			v, parsedSeq = readTestValue(r)
+			require.Equal(t, []byte("b"), v)
+			require.Equal(t, uint64(0), parsedSeq)
+
+			// The snapshot is read-only
+			err = r.View("", func(tx *dbutil.Tx) error {
+				return tx.DeleteBucket(testBkt)
+			})
+			require.Error(t, err)
+
+			// The snapshot files are removed when the replica is closed, and the queries run on the database again
+			err = r.Close()
+			require.NoError(t, err)
+
+			files, err := ioutil.ReadDir(replicaDir)
+			require.NoError(t, err)
+			require.Empty(t, files)
+
+			writeTestValue("c")
+			v, _ = readTestValue(r)
+			require.Equal(t, []byte("c"), v)
+			require.True(t, r.Status().RefreshedAt.IsZero())
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package visor
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/blockdb"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/historydb"
+)
+
+// DBScrubber re-verifies random ranges of blocks and their historydb indexes while the node is running,
+// a few blocks at a time so that it does not slow down the sync. Corruption is detected before the
+// database is verified on the next startup, and is reported in the DBIntegrityReport.
+type DBScrubber struct {
+	db       *dbutil.DB
+	bc       *Blockchain
+	history  *historydb.HistoryDB
+	interval time.Duration
+	blocks   uint64
+}
+
+// NewDBScrubber creates a DBScrubber verifying the given number of blocks every interval
+func NewDBScrubber(db *dbutil.DB, bc *Blockchain, history *historydb.HistoryDB, interval time.Duration, blocks uint64) *DBScrubber {
+	return &DBScrubber{
+		db:       db,
+		bc:       bc,
+		history:  history,
+		interval: interval,
+		blocks:   blocks,
+	}
+}
+
+// dbScrubResult is the outcome of scrubbing a range of blocks
+type dbScrubResult struct {
+	// Number of blocks verified
+	BlocksScrubbed uint64
+	// Seqs of the blocks with an invalid signature or body
+	CorruptedBlocks []uint64
+	// Seqs of the blocks with corrupted historydb indexes
+	CorruptedHistoryBlocks []uint64
+	// Error of the lowest corrupted block
+	Err error
+}
+
+// Run scrubs a random range of blocks every interval, until ctx is done.
+// Scrub errors are logged, they don't stop the scrubber.
+func (s *DBScrubber) Run(ctx context.Context) {
+	logger.Infof("Scrubbing %d blocks every %s in the background", s.blocks, s.interval)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.scrub(ctx); err != nil && err != ctx.Err() {
+				logger.WithError(err).Error("DBScrubber.scrub failed")
+			}
+		}
+	}
+}
+
+// scrub verifies a random range of blocks and records the result in the DBIntegrityReport
+func (s *DBScrubber) scrub(ctx context.Context) error {
+	var headSeq uint64
+	var ok bool
+	if err := s.db.ViewContext(ctx, "DBScrubber.scrub", func(tx *dbutil.Tx) error {
+		var err error
+		headSeq, ok, err = s.bc.HeadSeq(tx)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	if !ok {
+		return nil
+	}
+
+	start := uint64(rand.Int63n(int64(headSeq) + 1))
+	end := start + s.blocks - 1
+	if end > headSeq || end < start {
+		end = headSeq
+	}
+
+	result, err := s.scrubRange(ctx, start, end)
+	if err != nil {
+		return err
+	}
+
+	if result.Err != nil {
+		logger.Critical().WithError(result.Err).Errorf("Scrubbing blocks %d-%d found corrupted blocks %v and corrupted history db indexes of blocks %v",
+			start, end, result.CorruptedBlocks, result.CorruptedHistoryBlocks)
+	}
+
+	if s.db.IsReadOnly() {
+		return nil
+	}
+
+	return s.db.Update("DBScrubber.scrub", func(tx *dbutil.Tx) error {
+		return recordDBScrubResult(tx, result)
+	})
+}
+
+// scrubRange verifies the signature and body of the blocks from start to end and their historydb indexes
+func (s *DBScrubber) scrubRange(ctx context.Context, start, end uint64) (*dbScrubResult, error) {
+	var result dbScrubResult
+	if err := s.db.ViewContext(ctx, "DBScrubber.scrubRange", func(tx *dbutil.Tx) error {
+		// The body of pruned blocks is removed, so it can't be verified
+		prunedSeq, err := s.bc.PrunedSeq(tx)
+		if err != nil {
+			return err
+		}
+
+		indexesMap := historydb.NewIndexesMap()
+		addCorrupted := func(seqs *[]uint64, seq uint64, err error) {
+			if result.Err == nil {
+				result.Err = err
+			}
+			*seqs = append(*seqs, seq)
+		}
+
+		for seq := start; seq <= end; seq++ {
+			if err := tx.Context().Err(); err != nil {
+				return err
+			}
+
+			result.BlocksScrubbed++
+
+			b, err := s.bc.GetSignedBlockBySeq(tx, seq)
+			if err != nil {
+				if _, ok := err.(blockdb.ErrMissingSignature); ok {
+					addCorrupted(&result.CorruptedBlocks, seq, err)
+					continue
+				}
+				return err
+			}
+
+			if b == nil {
+				return NewErrBlockNotExist(seq)
+			}
+
+			if err := s.verifyBlock(b, prunedSeq); err != nil {
+				addCorrupted(&result.CorruptedBlocks, seq, err)
+				continue
+			}
+
+			if err := s.history.Verify(tx, b, indexesMap); err != nil {
+				switch err.(type) {
+				case historydb.ErrHistoryDBCorrupted:
+					addCorrupted(&result.CorruptedHistoryBlocks, seq, err)
+				default:
+					return err
+				}
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// verifyBlock verifies the signature of a block and that its body matches its header
+func (s *DBScrubber) verifyBlock(b *coin.SignedBlock, prunedSeq uint64) error {
+	if err := s.bc.VerifySignature(b); err != nil {
+		return err
+	}
+
+	if (b.Seq() == 0 || b.Seq() > prunedSeq) && b.HashBody() != b.Head.BodyHash {
+		return fmt.Errorf("Block %d body does not match the body hash of its header", b.Seq())
+	}
+
+	return nil
+}
+
+// recordDBScrubResult adds the result of a scrub to the DBIntegrityReport.
+// If corruption was found, the verification checkpoint is reset,
+// so that the entire database is verified, and recovered, on the next startup.
+func recordDBScrubResult(tx *dbutil.Tx, result *dbScrubResult) error {
+	report, err := getDBIntegrityReport(tx)
+	if err != nil {
+		return err
+	}
+
+	if report == nil {
+		report = &DBIntegrityReport{}
+	}
+
+	report.ScrubTime = uint64(time.Now().UTC().Unix())
+	report.BlocksScrubbed += result.BlocksScrubbed
+
+	if result.Err != nil {
+		if report.Error == "" {
+			report.Error = fmt.Sprintf("Background scrub found corruption: %v", result.Err)
+		}
+
+		report.CorruptedBlocks = mergeSeqs(report.CorruptedBlocks, result.CorruptedBlocks)
+		report.CorruptedHistoryBlocks = mergeSeqs(report.CorruptedHistoryBlocks, result.CorruptedHistoryBlocks)
+
+		if err := dbutil.ResetVerifyCheckpoint(tx); err != nil {
+			return err
+		}
+	}
+
+	return setDBIntegrityReport(tx, *report)
+}
+
+// mergeSeqs returns the union of two sets of block seqs, in ascending order
+func mergeSeqs(a, b []uint64) []uint64 {
+	seen := make(map[uint64]struct{}, len(a)+len(b))
+	var seqs []uint64
+	for _, seq := range append(append([]uint64{}, a...), b...) {
+		if _, ok := seen[seq]; ok {
+			continue
+		}
+		seen[seq] = struct{}{}
+		seqs = append(seqs, seq)
+	}
+
+	sort.Slice(seqs, func(i, j int) bool {
+		return seqs[i] < seqs[j]
+	})
+	return seqs
+}
@@ -0,0 +1,119 @@
+package visor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/encoder"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor/blockdb"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/historydb"
+)
+
+func TestDBScrubber(t *testing.T) {
+	db, shutdown := copyTestDB(t, "./testdata/data.db.ok")
+	defer shutdown()
+
+	pubkey := mustParsePubkey(t)
+	bc, err := NewBlockchain(db, BlockchainConfig{Pubkey: pubkey})
+	require.NoError(t, err)
+
+	err = CheckDatabase(context.Background(), db, CheckDatabaseConfig{Pubkey: pubkey, FullVerify: true})
+	require.NoError(t, err)
+
+	s := NewDBScrubber(db, bc, historydb.New(), 0, 10)
+
+	var headSeq uint64
+	err = db.View("", func(tx *dbutil.Tx) error {
+		var err error
+		headSeq, _, err = bc.HeadSeq(tx)
+		return err
+	})
+	require.NoError(t, err)
+
+	// A valid database has no corruption
+	result, err := s.scrubRange(context.Background(), 0, headSeq)
+	require.NoError(t, err)
+	require.Equal(t, headSeq+1, result.BlocksScrubbed)
+	require.NoError(t, result.Err)
+	require.Empty(t, result.CorruptedBlocks)
+	require.Empty(t, result.CorruptedHistoryBlocks)
+
+	require.NoError(t, s.scrub(context.Background()))
+
+	report, err := GetDBIntegrityReport(db)
+	require.NoError(t, err)
+	require.Empty(t, report.Error)
+	require.NotZero(t, report.ScrubTime)
+	require.NotZero(t, report.BlocksScrubbed)
+	scrubbed := report.BlocksScrubbed
+
+	// Corrupt the signature of block 3 and the history of the first block with transactions
+	var historySeq uint64
+	_, seckey := cipher.GenerateKeyPair()
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		b, err := bc.GetSignedBlockBySeq(tx, 3)
+		require.NoError(t, err)
+		hash := b.HashHeader()
+		badSig := cipher.MustSignHash(testutil.RandSHA256(t), seckey)
+		require.NoError(t, dbutil.PutBucketValue(tx, blockdb.BlockSigsBkt, hash[:], encoder.Serialize(badSig)))
+
+		for seq := uint64(1); seq <= headSeq; seq++ {
+			b, err := bc.GetSignedBlockBySeq(tx, seq)
+			require.NoError(t, err)
+			if seq != 3 && len(b.Body.Transactions) != 0 {
+				historySeq = seq
+				txnHash := b.Body.Transactions[0].Hash()
+				return tx.Bucket(historydb.TransactionsBkt).Delete(txnHash[:])
+			}
+		}
+
+		t.Fatal("no block with transactions")
+		return nil
+	})
+	require.NoError(t, err)
+
+	result, err = s.scrubRange(context.Background(), 0, headSeq)
+	require.NoError(t, err)
+	require.Error(t, result.Err)
+	require.Equal(t, []uint64{3}, result.CorruptedBlocks)
+	require.Equal(t, []uint64{historySeq}, result.CorruptedHistoryBlocks)
+
+	// The corruption is added to the report, and the verification checkpoint is reset
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		return recordDBScrubResult(tx, result)
+	})
+	require.NoError(t, err)
+
+	report, err = GetDBIntegrityReport(db)
+	require.NoError(t, err)
+	require.Contains(t, report.Error, "Background scrub found corruption")
+	require.Equal(t, []uint64{3}, report.CorruptedBlocks)
+	require.Equal(t, []uint64{historySeq}, report.CorruptedHistoryBlocks)
+	require.Equal(t, scrubbed+result.BlocksScrubbed, report.BlocksScrubbed)
+
+	err = db.View("", func(tx *dbutil.Tx) error {
+		cp, err := dbutil.GetVerifyCheckpoint(tx)
+		require.NoError(t, err)
+		require.Nil(t, cp)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// The next verification finds the corruption
+	err = CheckDatabase(context.Background(), db, CheckDatabaseConfig{Pubkey: pubkey})
+	require.Error(t, err)
+	require.True(t, IsCorruptDBError(err))
+	corruptErr, ok := err.(ErrCorruptDB)
+	require.True(t, ok)
+	require.Equal(t, []uint64{3}, corruptErr.CorruptedBlocks)
+}
+
+func TestMergeSeqs(t *testing.T) {
+	require.Empty(t, mergeSeqs(nil, nil))
+	require.Equal(t, []uint64{1, 2, 5, 7}, mergeSeqs([]uint64{5, 1}, []uint64{7, 1, 2}))
+}
@@ -0,0 +1,217 @@
+package visor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/boltdb/bolt"
+
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/historydb"
+)
+
+var (
+	// ErrSplitDBIncomplete is returned by OpenDB if a split database is opened read-only
+	// before its buckets were moved to the part files
+	ErrSplitDBIncomplete = errors.New("The database was not completely split into separate files, open it without -db-read-only to finish the split")
+)
+
+// splitDBParts are the part files of a split database and the buckets they store.
+// The part files only store data that the visor can rebuild from the blocks in the primary file:
+// the history db is reindexed from the blocks if it is behind the blockchain,
+// and unconfirmed transactions that were included in a block are removed from the pool on startup.
+var splitDBParts = []struct {
+	name    string
+	buckets [][]byte
+}{
+	{
+		name: "history",
+		buckets: [][]byte{
+			historydb.AddressTxnsBkt,
+			historydb.AddressUxBkt,
+			historydb.AddressTxnSeqsBkt,
+			historydb.AddressMetaBkt,
+			historydb.HistoryMetaBkt,
+			historydb.TransactionsBkt,
+			historydb.TxnBlocksBkt,
+			historydb.UxOutSpendersBkt,
+			historydb.UxOutsBkt,
+		},
+	},
+	{
+		name: "unconfirmed",
+		buckets: [][]byte{
+			UnconfirmedTxnsBkt,
+			UnconfirmedUnspentsBkt,
+			UnconfirmedTxnsExpiryBkt,
+			UnconfirmedEvictedTxnsBkt,
+		},
+	},
+}
+
+// SplitDBPartPath returns the path of a part file of a split database, e.g. data.db.history
+func SplitDBPartPath(dbFile, part string) string {
+	return fmt.Sprintf("%s.%s", dbFile, part)
+}
+
+// DBFiles returns the paths of the files of the database, the primary file first
+func DBFiles(db *dbutil.DB) []string {
+	paths := []string{db.Path()}
+	for _, p := range db.Parts() {
+		paths = append(paths, p.DB.Path())
+	}
+	return paths
+}
+
+// isSplitDB returns true if a part file of a split database exists for dbFile
+func isSplitDB(dbFile string) (bool, error) {
+	for _, p := range splitDBParts {
+		if _, err := os.Stat(SplitDBPartPath(dbFile, p.name)); err == nil {
+			return true, nil
+		} else if !os.IsNotExist(err) {
+			return false, err
+		}
+	}
+
+	return false, nil
+}
+
+// OpenSplitDB opens the blockdb with the history db and the unconfirmed transaction pool
+// stored in separate files next to dbFile, see dbutil.WrapSplitDB.
+// The history db can then be rebuilt without writing to the block storage,
+// and it is rebuilt from the blocks when its file is deleted.
+// If dbFile is not split yet, the buckets are moved from dbFile to the part files.
+// A split database can't be merged back into a single file.
+func OpenSplitDB(dbFile string, readOnly bool) (*dbutil.DB, error) {
+	cfg := NewOpenDBConfig()
+	cfg.ReadOnly = readOnly
+	cfg.Split = true
+	return OpenDBWithConfig(dbFile, cfg)
+}
+
+// openSplitDB opens a split database for OpenDBWithConfig
+func openSplitDB(dbFile string, cfg OpenDBConfig) (*dbutil.DB, error) {
+	readOnly := cfg.ReadOnly
+	db, err := OpenBoltDB(dbFile, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var parts []dbutil.DBPart
+	closeAll := func() {
+		for _, p := range parts {
+			if err := p.DB.Close(); err != nil {
+				logger.WithError(err).Errorf("Failed to close the %s db part", p.Name)
+			}
+		}
+		if err := db.Close(); err != nil {
+			logger.WithError(err).Error("Failed to close db")
+		}
+	}
+
+	for _, p := range splitDBParts {
+		partPath := SplitDBPartPath(dbFile, p.name)
+
+		// bolt creates a missing file even if it is opened read-only
+		if readOnly {
+			if _, err := os.Stat(partPath); os.IsNotExist(err) {
+				closeAll()
+				return nil, ErrSplitDBIncomplete
+			}
+		}
+
+		partDB, err := OpenBoltDB(partPath, cfg)
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+
+		part := dbutil.DBPart{
+			Name:    p.name,
+			Buckets: p.buckets,
+			DB:      partDB,
+		}
+		parts = append(parts, part)
+
+		if err := moveBucketsToPart(db, part); err != nil {
+			closeAll()
+			return nil, err
+		}
+	}
+
+	splitDB, err := dbutil.WrapSplitDB(db, parts)
+	if err != nil {
+		closeAll()
+		return nil, err
+	}
+
+	return splitDB, nil
+}
+
+// moveBucketsToPart moves the buckets of a part file from the primary file to the part file.
+// A bucket is only deleted from the primary file after its copy is committed to the part file,
+// so an interrupted move is finished the next time the database is opened.
+func moveBucketsToPart(db *bolt.DB, part dbutil.DBPart) error {
+	for _, name := range part.Buckets {
+		var exists bool
+		if err := db.View(func(tx *bolt.Tx) error {
+			exists = tx.Bucket(name) != nil
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		if !exists {
+			continue
+		}
+
+		if db.IsReadOnly() {
+			return ErrSplitDBIncomplete
+		}
+
+		logger.Infof("Moving bucket %s to the %s db part %s", name, part.Name, part.DB.Path())
+
+		if err := db.View(func(tx *bolt.Tx) error {
+			return part.DB.Update(func(partTx *bolt.Tx) error {
+				if partTx.Bucket(name) != nil {
+					if err := partTx.DeleteBucket(name); err != nil {
+						return err
+					}
+				}
+
+				dst, err := partTx.CreateBucket(name)
+				if err != nil {
+					return dbutil.NewErrCreateBucketFailed(name, err)
+				}
+
+				return copyBucket(dst, tx.Bucket(name))
+			})
+		}); err != nil {
+			return err
+		}
+
+		if err := db.Update(func(tx *bolt.Tx) error {
+			return tx.DeleteBucket(name)
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// copyBucket copies the keys and nested buckets of src to dst
+func copyBucket(dst, src *bolt.Bucket) error {
+	return src.ForEach(func(k, v []byte) error {
+		if b := src.Bucket(k); b != nil {
+			nested, err := dst.CreateBucket(k)
+			if err != nil {
+				return dbutil.NewErrCreateBucketFailed(k, err)
+			}
+			return copyBucket(nested, b)
+		}
+
+		return dst.Put(k, v)
+	})
+}
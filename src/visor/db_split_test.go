@@ -0,0 +1,87 @@
+package visor
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/visor/blockdb"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/historydb"
+)
+
+func TestOpenSplitDB(t *testing.T) {
+	dir, err := ioutil.TempDir("", "testdb")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	dbPath := filepath.Join(dir, "data.db")
+	err = ioutil.WriteFile(dbPath, readAll(t, "./testdata/data.db.ok"), 0600)
+	require.NoError(t, err)
+
+	// A single file database can't be split read-only
+	_, err = OpenSplitDB(dbPath, true)
+	require.Equal(t, ErrSplitDBIncomplete, err)
+	split, err := isSplitDB(dbPath)
+	require.NoError(t, err)
+	require.False(t, split)
+
+	db, err := OpenSplitDB(dbPath, false)
+	require.NoError(t, err)
+	require.True(t, db.IsSplit())
+	require.Equal(t, []string{
+		dbPath,
+		SplitDBPartPath(dbPath, "history"),
+		SplitDBPartPath(dbPath, "unconfirmed"),
+	}, DBFiles(db))
+
+	// The history buckets were moved to the history file
+	err = db.View("", func(tx *dbutil.Tx) error {
+		history := tx.PartTx("history")
+		require.NotNil(t, tx.Tx.Bucket(blockdb.BlocksBkt))
+		require.Nil(t, history.Bucket(blockdb.BlocksBkt))
+		require.Nil(t, tx.Tx.Bucket(historydb.TransactionsBkt))
+		require.NotNil(t, history.Bucket(historydb.TransactionsBkt))
+		return nil
+	})
+	require.NoError(t, err)
+
+	pubkey := mustParsePubkey(t)
+	err = CheckDatabase(context.Background(), db, CheckDatabaseConfig{Pubkey: pubkey, FullVerify: true})
+	require.NoError(t, err)
+
+	require.NoError(t, db.Close())
+
+	// OpenDB opens the part files of a split database
+	db, err = OpenDB(dbPath, true)
+	require.NoError(t, err)
+	require.True(t, db.IsSplit())
+
+	err = CheckDatabase(context.Background(), db, CheckDatabaseConfig{Pubkey: pubkey, FullVerify: true})
+	require.NoError(t, err)
+
+	require.NoError(t, db.Close())
+
+	// The history is rebuilt from the blocks if its file is deleted
+	require.NoError(t, os.Remove(SplitDBPartPath(dbPath, "history")))
+
+	db, err = OpenDB(dbPath, false)
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, CreateBuckets(db))
+
+	bc, err := NewBlockchain(db, BlockchainConfig{Pubkey: pubkey})
+	require.NoError(t, err)
+
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		return initHistory(tx, bc, historydb.New())
+	})
+	require.NoError(t, err)
+
+	err = CheckDatabase(context.Background(), db, CheckDatabaseConfig{Pubkey: pubkey, FullVerify: true})
+	require.NoError(t, err)
+}
@@ -0,0 +1,576 @@
+package visor
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor/blockdb"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/historydb"
+)
+
+// copyTestDB copies a testdata db file to a temporary directory and opens it
+func copyTestDB(t *testing.T, dbFile string) (*dbutil.DB, func()) {
+	dir, err := ioutil.TempDir("", "testdb")
+	require.NoError(t, err)
+
+	dbPath := filepath.Join(dir, "data.db")
+	err = ioutil.WriteFile(dbPath, readAll(t, dbFile), 0600)
+	require.NoError(t, err)
+
+	db, err := OpenDB(dbPath, false)
+	require.NoError(t, err)
+
+	return db, func() {
+		if err := db.Close(); err != nil {
+			t.Logf("Failed to close database: %v", err)
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			t.Logf("Failed to remove temp dir %s: %v", dir, err)
+		}
+	}
+}
+
+func TestCheckDatabaseVerifyCheckpoint(t *testing.T) {
+	db, shutdown := copyTestDB(t, "./testdata/data.db.ok")
+	defer shutdown()
+
+	pubkey := mustParsePubkey(t)
+
+	bc, err := NewBlockchain(db, BlockchainConfig{Pubkey: pubkey})
+	require.NoError(t, err)
+
+	var head cipher.SHA256
+	var headSeq uint64
+	err = db.View("", func(tx *dbutil.Tx) error {
+		b, err := bc.Head(tx)
+		require.NoError(t, err)
+		head = b.HashHeader()
+		headSeq = b.Seq()
+
+		cp, err := dbutil.GetVerifyCheckpoint(tx)
+		require.NoError(t, err)
+		require.Nil(t, cp)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// No checkpoint, verification starts from the genesis block
+	startSeq, err := verifyStartSeq(db, bc)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), startSeq)
+
+	// A successful verification saves the head block as the checkpoint
+	err = CheckDatabase(context.Background(), db, CheckDatabaseConfig{Pubkey: pubkey})
+	require.NoError(t, err)
+
+	err = db.View("", func(tx *dbutil.Tx) error {
+		cp, err := dbutil.GetVerifyCheckpoint(tx)
+		require.NoError(t, err)
+		require.NotNil(t, cp)
+		require.Equal(t, headSeq, cp.Seq)
+		require.Equal(t, head, cp.BlockHash)
+		return nil
+	})
+	require.NoError(t, err)
+
+	startSeq, err = verifyStartSeq(db, bc)
+	require.NoError(t, err)
+	require.Equal(t, headSeq+1, startSeq)
+
+	// Incremental verification with no new blocks succeeds
+	err = CheckDatabase(context.Background(), db, CheckDatabaseConfig{Pubkey: pubkey})
+	require.NoError(t, err)
+
+	// Full verification ignores the checkpoint
+	err = CheckDatabase(context.Background(), db, CheckDatabaseConfig{Pubkey: pubkey, FullVerify: true})
+	require.NoError(t, err)
+
+	// A checkpoint that does not match the chain is ignored
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		return dbutil.SetVerifyCheckpoint(tx, dbutil.VerifyCheckpoint{
+			Seq:       headSeq - 1,
+			BlockHash: head,
+		})
+	})
+	require.NoError(t, err)
+
+	startSeq, err = verifyStartSeq(db, bc)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), startSeq)
+
+	// Resetting the checkpoint removes it
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		return dbutil.ResetVerifyCheckpoint(tx)
+	})
+	require.NoError(t, err)
+
+	err = db.View("", func(tx *dbutil.Tx) error {
+		cp, err := dbutil.GetVerifyCheckpoint(tx)
+		require.NoError(t, err)
+		require.Nil(t, cp)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestCheckDatabaseIncrementalDetectsCorruption(t *testing.T) {
+	db, shutdown := copyTestDB(t, "./testdata/data.db.notxn")
+	defer shutdown()
+
+	pubkey := mustParsePubkey(t)
+
+	// A checkpoint at the genesis block still verifies the corrupted blocks after it
+	err := db.Update("", func(tx *dbutil.Tx) error {
+		bc, err := NewBlockchain(db, BlockchainConfig{Pubkey: pubkey})
+		require.NoError(t, err)
+		b, err := bc.GetSignedBlockBySeq(tx, 0)
+		require.NoError(t, err)
+		return dbutil.SetVerifyCheckpoint(tx, dbutil.VerifyCheckpoint{
+			Seq:       0,
+			BlockHash: b.HashHeader(),
+		})
+	})
+	require.NoError(t, err)
+
+	err = CheckDatabase(context.Background(), db, CheckDatabaseConfig{Pubkey: pubkey})
+	testutil.RequireError(t, err, "HistoryDB.Verify: transaction 98db7eb30e13853d3dd93d5d8b4061596d5d288b6f8b92c4d43c46c6599f67fb does not exist in historydb")
+}
+
+func TestCheckDatabaseProgress(t *testing.T) {
+	db, shutdown := copyTestDB(t, "./testdata/data.db.ok")
+	defer shutdown()
+
+	pubkey := mustParsePubkey(t)
+
+	var headSeq uint64
+	err := db.View("", func(tx *dbutil.Tx) error {
+		bc, err := NewBlockchain(db, BlockchainConfig{Pubkey: pubkey})
+		require.NoError(t, err)
+		b, err := bc.Head(tx)
+		require.NoError(t, err)
+		headSeq = b.Seq()
+		return nil
+	})
+	require.NoError(t, err)
+
+	var progress []VerifyProgress
+	err = CheckDatabase(context.Background(), db, CheckDatabaseConfig{
+		Pubkey:     pubkey,
+		FullVerify: true,
+		Progress: func(p VerifyProgress) {
+			progress = append(progress, p)
+		},
+	})
+	require.NoError(t, err)
+
+	// The final report covers the entire chain
+	require.NotEmpty(t, progress)
+	last := progress[len(progress)-1]
+	require.Equal(t, headSeq+1, last.Verified)
+	require.Equal(t, uint64(0), last.Remaining)
+	require.Equal(t, time.Duration(0), last.ETA)
+
+	for i := 1; i < len(progress); i++ {
+		require.True(t, progress[i].Verified >= progress[i-1].Verified)
+	}
+}
+
+func TestCheckDatabaseWorkers(t *testing.T) {
+	pubkey := mustParsePubkey(t)
+
+	for _, workers := range []int{0, 1, 8, VerifyWorkersAuto} {
+		t.Run(fmt.Sprintf("workers=%d", workers), func(t *testing.T) {
+			db, shutdown := copyTestDB(t, "./testdata/data.db.ok")
+			defer shutdown()
+
+			err := CheckDatabase(context.Background(), db, CheckDatabaseConfig{
+				Pubkey:     pubkey,
+				FullVerify: true,
+				Workers:    workers,
+			})
+			require.NoError(t, err)
+
+			// data.db.nosig has enough blocks for the number of workers to be tuned
+			db, err = OpenDB("./testdata/data.db.nosig", true)
+			require.NoError(t, err)
+			defer db.Close()
+
+			err = CheckDatabase(context.Background(), db, CheckDatabaseConfig{
+				Pubkey:     pubkey,
+				FullVerify: true,
+				Workers:    workers,
+			})
+			require.Error(t, err)
+			require.IsType(t, ErrCorruptDB{}, err)
+			require.IsType(t, blockdb.ErrMissingSignature{}, err.(ErrCorruptDB).error)
+			require.Len(t, err.(ErrCorruptDB).CorruptedBlocks, 1)
+		})
+	}
+}
+
+func TestParseVerifyWorkers(t *testing.T) {
+	tt := []struct {
+		s       string
+		workers int
+		err     bool
+	}{
+		{"auto", VerifyWorkersAuto, false},
+		{"1", 1, false},
+		{"16", 16, false},
+		{"0", 0, true},
+		{"-1", 0, true},
+		{"", 0, true},
+		{"many", 0, true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.s, func(t *testing.T) {
+			workers, err := ParseVerifyWorkers(tc.s)
+			if tc.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.workers, workers)
+		})
+	}
+}
+
+func TestDBVerifier(t *testing.T) {
+	pubkey := mustParsePubkey(t)
+
+	t.Run("valid db", func(t *testing.T) {
+		db, shutdown := copyTestDB(t, "./testdata/data.db.ok")
+		defer shutdown()
+
+		dv := NewDBVerifier(db, CheckDatabaseConfig{Pubkey: pubkey, FullVerify: true})
+		status := dv.Status()
+		require.Equal(t, DBVerifyStatePending, status.State)
+		require.True(t, status.FullVerify)
+		require.True(t, status.StartedAt.IsZero())
+
+		err := dv.Run(context.Background())
+		require.NoError(t, err)
+
+		status = dv.Status()
+		require.Equal(t, DBVerifyStateComplete, status.State)
+		require.NoError(t, status.Err)
+		require.NotEqual(t, uint64(0), status.Progress.Verified)
+		require.Equal(t, uint64(0), status.Progress.Remaining)
+		require.False(t, status.StartedAt.IsZero())
+		require.False(t, status.FinishedAt.Before(status.StartedAt))
+	})
+
+	t.Run("corrupted db", func(t *testing.T) {
+		db, shutdown := copyTestDB(t, "./testdata/data.db.notxn")
+		defer shutdown()
+
+		dv := NewDBVerifier(db, CheckDatabaseConfig{Pubkey: pubkey})
+		err := dv.Run(context.Background())
+		require.Error(t, err)
+		require.True(t, IsCorruptDBError(err))
+
+		status := dv.Status()
+		require.Equal(t, DBVerifyStateFailed, status.State)
+		require.Equal(t, err, status.Err)
+	})
+}
+
+func TestGroupBlockRanges(t *testing.T) {
+	tt := []struct {
+		name   string
+		seqs   []uint64
+		ranges []blockRange
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:   "single",
+			seqs:   []uint64{5},
+			ranges: []blockRange{{start: 5, end: 5}},
+		},
+		{
+			name: "consecutive and gaps",
+			seqs: []uint64{0, 1, 2, 5, 7, 8},
+			ranges: []blockRange{
+				{start: 0, end: 2},
+				{start: 5, end: 5},
+				{start: 7, end: 8},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.ranges, groupBlockRanges(tc.seqs))
+		})
+	}
+}
+
+func TestResetCorruptDBRebuildsCorruptedBlocks(t *testing.T) {
+	tt := []struct {
+		name   string
+		dbPath string
+	}{
+		{
+			name:   "missing transaction",
+			dbPath: "./testdata/data.db.notxn",
+		},
+		{
+			name:   "missing uxout",
+			dbPath: "./testdata/data.db.nouxout",
+		},
+		{
+			name:   "missing addr transaction index",
+			dbPath: "./testdata/data.db.no-addr-txn-index",
+		},
+		{
+			name:   "missing addr uxout index",
+			dbPath: "./testdata/data.db.no-addr-uxout-index",
+		},
+	}
+
+	pubkey := mustParsePubkey(t)
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			db, shutdown := copyTestDB(t, tc.dbPath)
+			defer shutdown()
+
+			cfg := CheckDatabaseConfig{
+				Pubkey:     pubkey,
+				FullVerify: true,
+			}
+
+			err := CheckDatabase(context.Background(), db, cfg)
+			require.Error(t, err)
+			require.IsType(t, historydb.ErrHistoryDBCorrupted{}, err)
+			corruptedBlocks := err.(historydb.ErrHistoryDBCorrupted).CorruptedBlocks
+			require.NotEmpty(t, corruptedBlocks)
+
+			var headSeq uint64
+			err = db.View("", func(tx *dbutil.Tx) error {
+				bc, err := NewBlockchain(db, BlockchainConfig{Pubkey: pubkey})
+				require.NoError(t, err)
+				b, err := bc.Head(tx)
+				require.NoError(t, err)
+				headSeq = b.Seq()
+				return nil
+			})
+			require.NoError(t, err)
+			require.True(t, uint64(len(corruptedBlocks)) < headSeq+1)
+
+			// Only the corrupted blocks are rebuilt
+			bc, err := NewBlockchain(db, BlockchainConfig{Pubkey: pubkey})
+			require.NoError(t, err)
+			err = rebuildHistoryDBBlocks(context.Background(), db, historydb.New(), bc, corruptedBlocks)
+			require.NoError(t, err)
+
+			err = CheckDatabase(context.Background(), db, cfg)
+			require.NoError(t, err)
+		})
+	}
+
+	t.Run("ResetCorruptDB keeps the blockchain", func(t *testing.T) {
+		db, shutdown := copyTestDB(t, "./testdata/data.db.notxn")
+		defer shutdown()
+
+		cfg := CheckDatabaseConfig{
+			Pubkey: pubkey,
+		}
+
+		newDB, err := ResetCorruptDB(context.Background(), db, cfg)
+		require.NoError(t, err)
+		db = newDB
+
+		err = db.View("", func(tx *dbutil.Tx) error {
+			require.True(t, dbutil.Exists(tx, blockdb.BlocksBkt))
+			return nil
+		})
+		require.NoError(t, err)
+
+		err = CheckDatabase(context.Background(), db, cfg)
+		require.NoError(t, err)
+	})
+}
+
+func TestCheckDatabaseContext(t *testing.T) {
+	db, shutdown := copyTestDB(t, "./testdata/data.db.ok")
+	defer shutdown()
+
+	pubkey := mustParsePubkey(t)
+	cfg := CheckDatabaseConfig{
+		Pubkey:     pubkey,
+		FullVerify: true,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := CheckDatabase(ctx, db, cfg)
+	require.Equal(t, ErrVerifyStopped, err)
+
+	ctx, cancel = context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	err = CheckDatabase(ctx, db, cfg)
+	require.Equal(t, context.DeadlineExceeded, err)
+
+	bc, err := NewBlockchain(db, BlockchainConfig{Pubkey: pubkey})
+	require.NoError(t, err)
+
+	// The walk stops when the context is canceled by the walk function
+	ctx, cancel = context.WithCancel(context.Background())
+	err = bc.WalkChain(ctx, 1, func(*dbutil.Tx, *coin.SignedBlock) error {
+		cancel()
+		return nil
+	})
+	require.Equal(t, ErrVerifyStopped, err)
+
+	err = CheckDatabase(context.Background(), db, cfg)
+	require.NoError(t, err)
+}
+
+// cancelAfterContext is a context whose Err method returns context.Canceled after n calls
+type cancelAfterContext struct {
+	context.Context
+	n int
+}
+
+func (c *cancelAfterContext) Err() error {
+	if c.n == 0 {
+		return context.Canceled
+	}
+	c.n--
+	return nil
+}
+
+func TestRebuildHistoryDBCanceled(t *testing.T) {
+	db, shutdown := copyTestDB(t, "./testdata/data.db.ok")
+	defer shutdown()
+
+	pubkey := mustParsePubkey(t)
+	bc, err := NewBlockchain(db, BlockchainConfig{Pubkey: pubkey})
+	require.NoError(t, err)
+
+	// Cancel the rebuild after the history db was erased and a few blocks were parsed again
+	ctx := &cancelAfterContext{
+		Context: context.Background(),
+		n:       4,
+	}
+	err = rebuildHistoryDB(ctx, db, historydb.New(), bc)
+	require.Equal(t, context.Canceled, err)
+
+	// The transaction was rolled back, the history db was not erased
+	err = CheckDatabase(context.Background(), db, CheckDatabaseConfig{
+		Pubkey:     pubkey,
+		FullVerify: true,
+	})
+	require.NoError(t, err)
+}
+
+func TestOpenDBEncrypted(t *testing.T) {
+	db, shutdown := copyTestDB(t, "./testdata/data.db.ok")
+	defer shutdown()
+
+	pubkey := mustParsePubkey(t)
+	dbPath := db.Path()
+	require.NoError(t, db.Close())
+
+	cfg := NewOpenDBConfig()
+	cfg.EncryptionPassphrase = []byte("secret")
+
+	// The existing database is encrypted
+	db, err := OpenDBWithConfig(dbPath, cfg)
+	require.NoError(t, err)
+	require.NotNil(t, db.Encryption())
+
+	// The indexes keyed by addresses are emptied, the history is not verified until it is parsed again
+	err = db.View("", func(tx *dbutil.Tx) error {
+		for _, b := range historydb.KeyMACBuckets {
+			if !dbutil.Exists(tx, b) {
+				continue
+			}
+
+			empty, err := dbutil.IsEmpty(tx, b)
+			require.NoError(t, err)
+			require.True(t, empty, string(b))
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = CheckDatabase(context.Background(), db, CheckDatabaseConfig{Pubkey: pubkey, FullVerify: true})
+	require.NoError(t, err)
+
+	// The indexes are rebuilt with the addresses hidden in their keys
+	require.NoError(t, CreateBuckets(db))
+	bc, err := NewBlockchain(db, BlockchainConfig{Pubkey: pubkey})
+	require.NoError(t, err)
+
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		headSeq, _, err := bc.HeadSeq(tx)
+		require.NoError(t, err)
+		require.NoError(t, bc.Unspent().MaybeBuildIndexes(tx, headSeq))
+		return initHistory(tx, bc, historydb.New())
+	})
+	require.NoError(t, err)
+
+	err = db.View("", func(tx *dbutil.Tx) error {
+		for _, b := range [][]byte{blockdb.UnspentPoolAddrIndexBkt, historydb.AddressTxnsBkt, historydb.AddressMetaBkt} {
+			empty, err := dbutil.IsEmpty(tx, b)
+			require.NoError(t, err)
+			require.False(t, empty, string(b))
+
+			require.NoError(t, tx.Bucket(b).ForEach(func(k, _ []byte) error {
+				require.Len(t, k, 32, string(b))
+				return nil
+			}))
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = CheckDatabase(context.Background(), db, CheckDatabaseConfig{Pubkey: pubkey, FullVerify: true})
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	// The passphrase is required to open it
+	_, err = OpenDB(dbPath, true)
+	require.Equal(t, dbutil.ErrDBEncrypted, err)
+
+	wrongCfg := NewOpenDBConfig()
+	wrongCfg.EncryptionPassphrase = []byte("wrong")
+	_, err = OpenDBWithConfig(dbPath, wrongCfg)
+	require.Equal(t, dbutil.ErrWrongDBPassphrase, err)
+
+	db, err = OpenDBWithConfig(dbPath, cfg)
+	require.NoError(t, err)
+
+	// The compacted database is encrypted with the same key
+	newDB, err := CompactDB(context.Background(), db, CompactDBConfig{
+		Check: CheckDatabaseConfig{
+			Pubkey:     pubkey,
+			FullVerify: true,
+		},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, newDB.Encryption())
+	require.NoError(t, newDB.Close())
+
+	db, err = OpenDBWithConfig(dbPath, cfg)
+	require.NoError(t, err)
+	err = CheckDatabase(context.Background(), db, CheckDatabaseConfig{Pubkey: pubkey, FullVerify: true})
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+}
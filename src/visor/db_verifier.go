@@ -0,0 +1,110 @@
+package visor
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+// DBVerifyState is the state of a background database verification
+type DBVerifyState string
+
+const (
+	// DBVerifyStateDisabled background verification is not enabled
+	DBVerifyStateDisabled DBVerifyState = "disabled"
+	// DBVerifyStatePending background verification has not started yet
+	DBVerifyStatePending DBVerifyState = "pending"
+	// DBVerifyStateRunning background verification is in progress
+	DBVerifyStateRunning DBVerifyState = "running"
+	// DBVerifyStateComplete background verification finished and the database is valid
+	DBVerifyStateComplete DBVerifyState = "complete"
+	// DBVerifyStateFailed background verification finished with an error
+	DBVerifyStateFailed DBVerifyState = "failed"
+)
+
+// DBVerifyStatus is the status of a background database verification
+type DBVerifyStatus struct {
+	State      DBVerifyState
+	FullVerify bool
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Progress   VerifyProgress
+	Err        error
+}
+
+// DBVerifier runs CheckDatabase while the node is running and records its status
+type DBVerifier struct {
+	db  *dbutil.DB
+	cfg CheckDatabaseConfig
+
+	status DBVerifyStatus
+	lock   sync.RWMutex
+}
+
+// NewDBVerifier creates a DBVerifier in the pending state.
+// The verification progress is recorded in the status, in addition to calling cfg.Progress.
+func NewDBVerifier(db *dbutil.DB, cfg CheckDatabaseConfig) *DBVerifier {
+	dv := &DBVerifier{
+		db: db,
+		status: DBVerifyStatus{
+			State:      DBVerifyStatePending,
+			FullVerify: cfg.FullVerify,
+		},
+	}
+
+	progress := cfg.Progress
+	cfg.Progress = func(p VerifyProgress) {
+		dv.lock.Lock()
+		dv.status.Progress = p
+		dv.lock.Unlock()
+
+		if progress != nil {
+			progress(p)
+		}
+	}
+	dv.cfg = cfg
+
+	return dv
+}
+
+// Run verifies the database and returns the verification error.
+// If the database is corrupted, the error can be passed to RecoverCorruptDB once the database is no longer in use.
+// The verification stops when ctx is done.
+func (dv *DBVerifier) Run(ctx context.Context) error {
+	dv.lock.Lock()
+	dv.status.State = DBVerifyStateRunning
+	dv.status.StartedAt = time.Now().UTC()
+	dv.lock.Unlock()
+
+	logger.Info("Verifying database in the background")
+
+	err := CheckDatabase(ctx, dv.db, dv.cfg)
+
+	dv.lock.Lock()
+	defer dv.lock.Unlock()
+
+	dv.status.FinishedAt = time.Now().UTC()
+	switch err {
+	case nil:
+		logger.Info("Background database verification complete")
+		dv.status.State = DBVerifyStateComplete
+	case ErrVerifyStopped:
+		// The node is shutting down, the verification will be done on the next run
+		dv.status.State = DBVerifyStatePending
+	default:
+		logger.WithError(err).Error("Background database verification failed")
+		dv.status.State = DBVerifyStateFailed
+		dv.status.Err = err
+	}
+
+	return err
+}
+
+// Status returns the verification status
+func (dv *DBVerifier) Status() DBVerifyStatus {
+	dv.lock.RLock()
+	defer dv.lock.RUnlock()
+	return dv.status
+}
@@ -0,0 +1,198 @@
+package visor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/blockdb"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/historydb"
+)
+
+// VerifyDBFileConfig configures VerifyDBFile
+type VerifyDBFileConfig struct {
+	// Public key of the blockchain, used to verify the block signatures
+	Pubkey cipher.PubKey
+	// Progress is called periodically with the verification progress, optional
+	Progress func(VerifyProgress)
+}
+
+// DBVerifyReport is the result of the verification of a database file by VerifyDBFile
+type DBVerifyReport struct {
+	// Path of the database file
+	Path string `json:"path"`
+	// Number of blocks verified
+	Blocks uint64 `json:"blocks"`
+	// Corrupted is true if any block failed verification
+	Corrupted bool `json:"corrupted"`
+	// Blocks with a missing or invalid signature, ordered by seq
+	SignatureErrors []DBBlockError `json:"signature_errors"`
+	// Blocks whose historydb indexes do not match the block, ordered by seq.
+	// Their IndexDiff lists the broken index entries that a recovery would patch.
+	IndexMismatches []DBBlockError `json:"index_mismatches"`
+}
+
+// DBBlockError is a block that failed verification
+type DBBlockError struct {
+	Seq   uint64 `json:"seq"`
+	Hash  string `json:"hash"`
+	Error string `json:"error"`
+	// IndexDiff are the broken historydb index entries of the block, for the blocks in IndexMismatches
+	IndexDiff []DBIndexDiffEntry `json:"index_diff,omitempty"`
+}
+
+// DBIndexDiffEntry is a broken historydb index entry of a block, see historydb.IndexDiffEntry
+type DBIndexDiffEntry struct {
+	Kind    historydb.IndexDiffKind `json:"kind"`
+	TxnID   string                  `json:"txid"`
+	UxID    string                  `json:"uxid,omitempty"`
+	Address string                  `json:"address,omitempty"`
+	Error   string                  `json:"error"`
+}
+
+// newDBIndexDiffEntries converts the entries of a historydb.BlockIndexDiff
+func newDBIndexDiffEntries(diff *historydb.BlockIndexDiff) []DBIndexDiffEntry {
+	entries := make([]DBIndexDiffEntry, len(diff.Entries))
+	for i, e := range diff.Entries {
+		entries[i] = DBIndexDiffEntry{
+			Kind:  e.Kind,
+			TxnID: e.TxnHash.Hex(),
+			Error: e.Description,
+		}
+		if e.UxID != (cipher.SHA256{}) {
+			entries[i].UxID = e.UxID.Hex()
+		}
+		if !e.Address.Null() {
+			entries[i].Address = e.Address.String()
+		}
+	}
+	return entries
+}
+
+// VerifyDBFile verifies the signatures and historydb indexes of every block of the database file at dbPath.
+// The database is opened read-only and is never modified or quarantined, so any database file can be inspected,
+// including the database of another node or a quarantined copy.
+// Unlike CheckDatabase, the verification does not stop at the first corrupted block,
+// all corrupted blocks are listed in the report. The broken historydb index entries of each block are listed too,
+// which is a dry run of the entries RecoverCorruptDB patches. The verification stops when ctx is done and returns the context error.
+func VerifyDBFile(ctx context.Context, dbPath string, cfg VerifyDBFileConfig) (*DBVerifyReport, error) {
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil, err
+	}
+
+	db, err := OpenDB(dbPath, true)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	report, err := verifyDB(ctx, db, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	report.Path = dbPath
+	return report, nil
+}
+
+func verifyDB(ctx context.Context, db *dbutil.DB, cfg VerifyDBFileConfig) (*DBVerifyReport, error) {
+	bc, err := NewBlockchain(db, BlockchainConfig{
+		Pubkey: cfg.Pubkey,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	history := historydb.New()
+	indexesMap := historydb.NewIndexesMap()
+
+	report := &DBVerifyReport{
+		SignatureErrors: []DBBlockError{},
+		IndexMismatches: []DBBlockError{},
+	}
+
+	if err := db.ViewContext(ctx, "VerifyDBFile", func(tx *dbutil.Tx) error {
+		if !dbutil.Exists(tx, blockdb.BlocksBkt) {
+			return errors.New("Database has no blocks bucket")
+		}
+
+		total, err := bc.Len(tx)
+		if err != nil {
+			return err
+		}
+
+		start := time.Now()
+		var lastProgress time.Time
+		progress := func() {
+			if cfg.Progress != nil {
+				cfg.Progress(newVerifyProgress(report.Blocks, total, time.Since(start)))
+			}
+		}
+
+		// Blocks are stored by hash, so they are not visited in seq order
+		if err := bc.store.ForEachSignedBlock(tx, func(b *coin.Block, sig cipher.Sig, ok bool) error {
+			blockErr := func(err error) DBBlockError {
+				return DBBlockError{
+					Seq:   b.Seq(),
+					Hash:  b.HashHeader().Hex(),
+					Error: err.Error(),
+				}
+			}
+
+			sb := &coin.SignedBlock{
+				Block: *b,
+				Sig:   sig,
+			}
+
+			if !ok {
+				report.SignatureErrors = append(report.SignatureErrors, blockErr(blockdb.NewErrMissingSignature(b)))
+			} else if err := sb.VerifySignature(cfg.Pubkey); err != nil {
+				report.SignatureErrors = append(report.SignatureErrors, blockErr(err))
+			}
+
+			diff, err := history.VerifyDiff(tx, sb, indexesMap)
+			if err != nil {
+				return fmt.Errorf("Verify historydb of block %d failed: %v", b.Seq(), err)
+			}
+			if !diff.Empty() {
+				e := blockErr(historydb.NewErrHistoryDBCorrupted(errors.New(diff.Entries[0].Description)))
+				e.IndexDiff = newDBIndexDiffEntries(diff)
+				report.IndexMismatches = append(report.IndexMismatches, e)
+			}
+
+			report.Blocks++
+
+			if time.Since(lastProgress) >= walkChainProgressRate {
+				lastProgress = time.Now()
+				progress()
+			}
+
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		progress()
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	sortBlockErrors(report.SignatureErrors)
+	sortBlockErrors(report.IndexMismatches)
+	report.Corrupted = len(report.SignatureErrors) != 0 || len(report.IndexMismatches) != 0
+
+	return report, nil
+}
+
+func sortBlockErrors(errs []DBBlockError) {
+	sort.Slice(errs, func(i, j int) bool {
+		return errs[i].Seq < errs[j].Seq
+	})
+}
@@ -0,0 +1,127 @@
+package visor
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/visor/historydb"
+)
+
+func TestVerifyDBFile(t *testing.T) {
+	tt := []struct {
+		name            string
+		dbFile          string
+		signatureErrors []uint64
+		indexMismatches []uint64
+		indexDiffKinds  map[uint64][]historydb.IndexDiffKind
+		err             string
+	}{
+		{
+			name:   "ok",
+			dbFile: "./testdata/data.db.ok",
+		},
+		{
+			name:            "missing signature",
+			dbFile:          "./testdata/data.db.nosig",
+			signatureErrors: []uint64{1000},
+		},
+		{
+			name:            "missing transaction",
+			dbFile:          "./testdata/data.db.notxn",
+			indexMismatches: []uint64{10},
+			indexDiffKinds: map[uint64][]historydb.IndexDiffKind{
+				10: {historydb.IndexDiffMissingTxn},
+			},
+		},
+		{
+			name:            "missing uxout",
+			dbFile:          "./testdata/data.db.nouxout",
+			indexMismatches: []uint64{9, 10},
+			indexDiffKinds: map[uint64][]historydb.IndexDiffKind{
+				9:  {historydb.IndexDiffMissingUxOut},
+				10: {historydb.IndexDiffOrphanedUxOut},
+			},
+		},
+		{
+			name:            "missing address transaction index",
+			dbFile:          "./testdata/data.db.no-addr-txn-index",
+			indexMismatches: []uint64{10},
+			indexDiffKinds: map[uint64][]historydb.IndexDiffKind{
+				10: {historydb.IndexDiffAddressTxn, historydb.IndexDiffAddressTxn},
+			},
+		},
+		{
+			name:   "not a database",
+			dbFile: "./testdata/data.db.garbage",
+			err:    "Open boltdb failed, invalid database",
+		},
+		{
+			name:   "does not exist",
+			dbFile: "./testdata/data.db.missing",
+			err:    "stat ./testdata/data.db.missing: no such file or directory",
+		},
+	}
+
+	seqs := func(errs []DBBlockError) []uint64 {
+		var s []uint64
+		for _, e := range errs {
+			s = append(s, e.Seq)
+		}
+		return s
+	}
+
+	diffKinds := func(errs []DBBlockError) map[uint64][]historydb.IndexDiffKind {
+		if len(errs) == 0 {
+			return nil
+		}
+		kinds := make(map[uint64][]historydb.IndexDiffKind, len(errs))
+		for _, e := range errs {
+			for _, d := range e.IndexDiff {
+				kinds[e.Seq] = append(kinds[e.Seq], d.Kind)
+			}
+		}
+		return kinds
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var before []byte
+			if _, err := os.Stat(tc.dbFile); err == nil {
+				before, err = ioutil.ReadFile(tc.dbFile)
+				require.NoError(t, err)
+			}
+
+			var progressCalled bool
+			report, err := VerifyDBFile(context.Background(), tc.dbFile, VerifyDBFileConfig{
+				Pubkey: mustParsePubkey(t),
+				Progress: func(p VerifyProgress) {
+					progressCalled = true
+				},
+			})
+
+			if tc.err != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.dbFile, report.Path)
+			require.NotZero(t, report.Blocks)
+			require.True(t, progressCalled)
+			require.Equal(t, tc.signatureErrors, seqs(report.SignatureErrors))
+			require.Equal(t, tc.indexMismatches, seqs(report.IndexMismatches))
+			require.Equal(t, tc.indexDiffKinds, diffKinds(report.IndexMismatches))
+			require.Equal(t, len(tc.signatureErrors) != 0 || len(tc.indexMismatches) != 0, report.Corrupted)
+
+			// The database file is not modified
+			after, err := ioutil.ReadFile(tc.dbFile)
+			require.NoError(t, err)
+			require.Equal(t, before, after)
+		})
+	}
+}
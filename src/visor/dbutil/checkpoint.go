@@ -0,0 +1,56 @@
+package dbutil
+
+import (
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/encoder"
+)
+
+var (
+	// VerifyCheckpointBkt stores the head block of the last successful database verification
+	VerifyCheckpointBkt = []byte("db_verify_checkpoint")
+
+	verifyCheckpointKey = []byte("checkpoint")
+)
+
+// VerifyCheckpoint records the head block at the time of the last successful database verification.
+// Blocks up to and including Seq do not need to be verified again, unless a full verification is requested.
+type VerifyCheckpoint struct {
+	Seq       uint64
+	BlockHash cipher.SHA256
+}
+
+// GetVerifyCheckpoint returns the saved verification checkpoint, or nil if there is none
+func GetVerifyCheckpoint(tx *Tx) (*VerifyCheckpoint, error) {
+	var cp VerifyCheckpoint
+	ok, err := GetBucketObjectDecoded(tx, VerifyCheckpointBkt, verifyCheckpointKey, &cp)
+	if err != nil {
+		switch err.(type) {
+		case ErrBucketNotExist:
+			return nil, nil
+		default:
+			return nil, err
+		}
+	} else if !ok {
+		return nil, nil
+	}
+
+	return &cp, nil
+}
+
+// SetVerifyCheckpoint saves the verification checkpoint, creating the bucket if necessary
+func SetVerifyCheckpoint(tx *Tx, cp VerifyCheckpoint) error {
+	if _, err := tx.CreateBucketIfNotExists(VerifyCheckpointBkt); err != nil {
+		return NewErrCreateBucketFailed(VerifyCheckpointBkt, err)
+	}
+
+	return PutBucketValue(tx, VerifyCheckpointBkt, verifyCheckpointKey, encoder.Serialize(cp))
+}
+
+// ResetVerifyCheckpoint removes the verification checkpoint, forcing the next verification to walk the full chain
+func ResetVerifyCheckpoint(tx *Tx) error {
+	if !Exists(tx, VerifyCheckpointBkt) {
+		return nil
+	}
+
+	return Delete(tx, VerifyCheckpointBkt, verifyCheckpointKey)
+}
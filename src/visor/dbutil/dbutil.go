@@ -4,6 +4,8 @@ Package dbutil provides boltdb utility methods
 package dbutil
 
 import (
+	"bytes"
+	"context"
 	"encoding/binary"
 	"encoding/json"
 	"errors"
@@ -31,6 +33,25 @@ var (
 // Tx wraps a Tx
 type Tx struct {
 	*bolt.Tx
+	ctx context.Context
+
+	// db is the split DB of the transaction, nil if the DB is not split
+	db *DB
+	// parts are the transactions of the part files of a split DB, in the order of DB.Parts
+	parts []*bolt.Tx
+	// encryption encrypts the bucket values, nil if the DB is not encrypted
+	encryption *Encryption
+	// keyMAC is true if the keys of the KeyMAC buckets are hidden, see Tx.KeyMAC
+	keyMAC bool
+}
+
+// Context returns the context of the transaction, given to DB.ViewContext or DB.UpdateContext.
+// Long running transactions should stop when the context is done.
+func (tx *Tx) Context() context.Context {
+	if tx.ctx == nil {
+		return context.Background()
+	}
+	return tx.ctx
 }
 
 // String is implemented to prevent a panic when mocking methods with *Tx arguments.
@@ -58,6 +79,16 @@ type DB struct {
 	// https://github.com/coreos/bbolt/pull/91
 	// When coreos has this feature, we can switch to coreos's bbolt and remove this lock
 	shutdownLock sync.RWMutex
+
+	// parts are the part files of a split DB, see WrapSplitDB
+	parts []DBPart
+	// partIndex maps the buckets stored in part files to their index in parts
+	partIndex map[string]int
+
+	// encryption encrypts the bucket values, see EnableEncryption
+	encryption *Encryption
+	// keyMAC is true if the keys of the KeyMAC buckets are hidden, see Tx.KeyMAC
+	keyMAC bool
 }
 
 // WrapDB returns WrapDB
@@ -75,6 +106,16 @@ func WrapDB(db *bolt.DB) *DB {
 
 // View wraps *bolt.DB.View to add logging
 func (db *DB) View(name string, f func(*Tx) error) error {
+	return db.ViewContext(context.Background(), name, f)
+}
+
+// ViewContext is like View, with a context that f can get from Tx.Context.
+// If the context is done before the transaction starts, the context error is returned.
+func (db *DB) ViewContext(ctx context.Context, name string, f func(*Tx) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	db.shutdownLock.RLock()
 	defer db.shutdownLock.RUnlock()
 
@@ -88,9 +129,7 @@ func (db *DB) View(name string, f func(*Tx) error) error {
 
 	t0 := time.Now()
 
-	err := db.DB.View(func(tx *bolt.Tx) error {
-		return f(&Tx{tx})
-	})
+	err := db.view(ctx, f)
 
 	t1 := time.Now()
 	delta := t1.Sub(t0)
@@ -103,6 +142,17 @@ func (db *DB) View(name string, f func(*Tx) error) error {
 
 // Update wraps *bolt.DB.Update to add logging
 func (db *DB) Update(name string, f func(*Tx) error) error {
+	return db.UpdateContext(context.Background(), name, f)
+}
+
+// UpdateContext is like Update, with a context that f can get from Tx.Context.
+// If the context is done before the transaction starts, the context error is returned.
+// To roll back the transaction when the context is done, f must return an error.
+func (db *DB) UpdateContext(ctx context.Context, name string, f func(*Tx) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	db.shutdownLock.RLock()
 	defer db.shutdownLock.RUnlock()
 
@@ -116,9 +166,7 @@ func (db *DB) Update(name string, f func(*Tx) error) error {
 
 	t0 := time.Now()
 
-	err := db.DB.Update(func(tx *bolt.Tx) error {
-		return f(&Tx{tx})
-	})
+	err := db.update(ctx, f)
 
 	t1 := time.Now()
 	delta := t1.Sub(t0)
@@ -129,12 +177,19 @@ func (db *DB) Update(name string, f func(*Tx) error) error {
 	return err
 }
 
-// Close closes the underlying *bolt.DB
+// Close closes the underlying *bolt.DB, and the part files of a split DB
 func (db *DB) Close() error {
 	db.shutdownLock.Lock()
 	defer db.shutdownLock.Unlock()
 
-	return db.DB.Close()
+	err := db.DB.Close()
+	for _, p := range db.parts {
+		if partErr := p.DB.Close(); partErr != nil && err == nil {
+			err = partErr
+		}
+	}
+
+	return err
 }
 
 // ErrCreateBucketFailed is returned if creating a bolt.DB bucket fails
@@ -251,7 +306,7 @@ func getBucketValue(tx *Tx, bktName, key []byte) ([]byte, error) {
 		return nil, NewErrBucketNotExist(bktName)
 	}
 
-	return bkt.Get(key), nil
+	return tx.open(bktName, key, bkt.Get(key))
 }
 
 // PutBucketValue puts a value into a bucket under key.
@@ -261,7 +316,7 @@ func PutBucketValue(tx *Tx, bktName, key, val []byte) error {
 		return NewErrBucketNotExist(bktName)
 	}
 
-	return bkt.Put(key, val)
+	return bkt.Put(key, tx.seal(bktName, key, val))
 }
 
 // BucketHasKey returns true if a bucket has a non-nil value for a key
@@ -292,7 +347,126 @@ func ForEach(tx *Tx, bktName []byte, f func(k, v []byte) error) error {
 		return NewErrBucketNotExist(bktName)
 	}
 
-	return bkt.ForEach(f)
+	if tx.encryption == nil {
+		return bkt.ForEach(f)
+	}
+
+	return bkt.ForEach(func(k, v []byte) error {
+		v, err := tx.open(bktName, k, v)
+		if err != nil {
+			return err
+		}
+		return f(k, v)
+	})
+}
+
+// ForEachPrefix calls f on the keys of the bucket that start with prefix, in key order,
+// starting from the first key >= start. start must be prefixed by prefix.
+// The iteration stops when f returns false or an error.
+func ForEachPrefix(tx *Tx, bktName, prefix, start []byte, f func(k, v []byte) (bool, error)) error {
+	bkt := tx.Bucket(bktName)
+	if bkt == nil {
+		return NewErrBucketNotExist(bktName)
+	}
+
+	if !bytes.HasPrefix(start, prefix) {
+		return errors.New("ForEachPrefix start key is not prefixed by prefix")
+	}
+
+	c := bkt.Cursor()
+	for k, v := c.Seek(start); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+		v, err := tx.open(bktName, k, v)
+		if err != nil {
+			return err
+		}
+
+		if ok, err := f(k, v); err != nil {
+			return err
+		} else if !ok {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// ForEachPrefixReverse calls f on the keys of the bucket that start with prefix, in reverse key order.
+// The iteration stops when f returns false or an error.
+func ForEachPrefixReverse(tx *Tx, bktName, prefix []byte, f func(k, v []byte) (bool, error)) error {
+	bkt := tx.Bucket(bktName)
+	if bkt == nil {
+		return NewErrBucketNotExist(bktName)
+	}
+
+	c := bkt.Cursor()
+
+	// Position the cursor on the last key with the prefix, which precedes the first key
+	// greater than every key with the prefix, if there is one
+	var k, v []byte
+	if end := prefixEnd(prefix); end == nil {
+		k, v = c.Last()
+	} else if k, v = c.Seek(end); k == nil {
+		k, v = c.Last()
+	} else {
+		k, v = c.Prev()
+	}
+
+	for ; k != nil && bytes.HasPrefix(k, prefix); k, v = c.Prev() {
+		v, err := tx.open(bktName, k, v)
+		if err != nil {
+			return err
+		}
+
+		if ok, err := f(k, v); err != nil {
+			return err
+		} else if !ok {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// prefixEnd returns the smallest key that is greater than every key with the prefix,
+// nil if there is none because the prefix is empty or only has 0xff bytes
+func prefixEnd(prefix []byte) []byte {
+	end := make([]byte, len(prefix))
+	copy(end, prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return nil
+}
+
+// SeekPrev returns the greatest key of the bucket that is <= key and its value, nil if there is no such key.
+// bolt seeks keys by searching its B+tree, so this is a binary search on the sorted keys of the bucket.
+func SeekPrev(tx *Tx, bktName, key []byte) ([]byte, []byte, error) {
+	bkt := tx.Bucket(bktName)
+	if bkt == nil {
+		return nil, nil, NewErrBucketNotExist(bktName)
+	}
+
+	c := bkt.Cursor()
+	k, v := c.Seek(key)
+	if k == nil {
+		k, v = c.Last()
+	} else if !bytes.Equal(k, key) {
+		k, v = c.Prev()
+	}
+
+	if k == nil {
+		return nil, nil, nil
+	}
+
+	v, err := tx.open(bktName, k, v)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return k, v, nil
 }
 
 // Delete deletes from a bucket
@@ -345,6 +519,14 @@ func Reset(tx *Tx, bktName []byte) error {
 	return err
 }
 
+// ResetBucket empties a bucket, creating it if it does not exist, in a db that predates it
+func ResetBucket(tx *Tx, bktName []byte) error {
+	if !Exists(tx, bktName) {
+		return CreateBuckets(tx, [][]byte{bktName})
+	}
+	return Reset(tx, bktName)
+}
+
 // Itob converts uint64 to bytes
 func Itob(v uint64) []byte {
 	b := make([]byte, 8)
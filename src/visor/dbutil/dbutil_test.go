@@ -0,0 +1,287 @@
+package dbutil
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestViewUpdateContext(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dbutil")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	db := openTestDB(t, filepath.Join(dir, "data.db"), false)
+	defer db.Close()
+
+	bkt := []byte("test")
+
+	// The transactions of View and Update have a background context
+	err = db.Update("", func(tx *Tx) error {
+		require.Equal(t, context.Background(), tx.Context())
+		return CreateBuckets(tx, [][]byte{bkt})
+	})
+	require.NoError(t, err)
+
+	err = db.View("", func(tx *Tx) error {
+		require.Equal(t, context.Background(), tx.Context())
+		return nil
+	})
+	require.NoError(t, err)
+
+	// The context is passed to the transaction
+	ctx, cancel := context.WithCancel(context.Background())
+
+	err = db.ViewContext(ctx, "", func(tx *Tx) error {
+		require.Equal(t, ctx, tx.Context())
+		return nil
+	})
+	require.NoError(t, err)
+
+	// Canceling the context during the transaction rolls back the transaction if f returns the context error
+	err = db.UpdateContext(ctx, "", func(tx *Tx) error {
+		require.NoError(t, PutBucketValue(tx, bkt, []byte("a"), []byte("1")))
+		cancel()
+		return tx.Context().Err()
+	})
+	require.Equal(t, context.Canceled, err)
+
+	err = db.View("", func(tx *Tx) error {
+		ok, err := BucketHasKey(tx, bkt, []byte("a"))
+		require.NoError(t, err)
+		require.False(t, ok)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// A transaction is not started if the context is done
+	err = db.ViewContext(ctx, "", func(tx *Tx) error {
+		t.Fatal("transaction should not start")
+		return nil
+	})
+	require.Equal(t, context.Canceled, err)
+
+	err = db.UpdateContext(ctx, "", func(tx *Tx) error {
+		t.Fatal("transaction should not start")
+		return nil
+	})
+	require.Equal(t, context.Canceled, err)
+}
+
+func TestForEachPrefix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dbutil")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	db := openTestDB(t, filepath.Join(dir, "data.db"), false)
+	defer db.Close()
+
+	bkt := []byte("test")
+
+	err = db.Update("", func(tx *Tx) error {
+		if err := CreateBuckets(tx, [][]byte{bkt}); err != nil {
+			return err
+		}
+		for _, k := range []string{"a1", "b1", "b2", "b3", "c1"} {
+			if err := PutBucketValue(tx, bkt, []byte(k), []byte("v"+k)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	collect := func(prefix, start string, max int) ([]string, error) {
+		var keys []string
+		err := db.View("", func(tx *Tx) error {
+			return ForEachPrefix(tx, bkt, []byte(prefix), []byte(start), func(k, v []byte) (bool, error) {
+				require.Equal(t, "v"+string(k), string(v))
+				keys = append(keys, string(k))
+				return len(keys) < max, nil
+			})
+		})
+		return keys, err
+	}
+
+	keys, err := collect("b", "b", 10)
+	require.NoError(t, err)
+	require.Equal(t, []string{"b1", "b2", "b3"}, keys)
+
+	keys, err = collect("b", "b2", 10)
+	require.NoError(t, err)
+	require.Equal(t, []string{"b2", "b3"}, keys)
+
+	// Iteration stops when f returns false
+	keys, err = collect("b", "b", 2)
+	require.NoError(t, err)
+	require.Equal(t, []string{"b1", "b2"}, keys)
+
+	keys, err = collect("d", "d", 10)
+	require.NoError(t, err)
+	require.Empty(t, keys)
+
+	_, err = collect("b", "a", 10)
+	require.Error(t, err)
+
+	err = db.View("", func(tx *Tx) error {
+		return ForEachPrefix(tx, []byte("missing"), nil, nil, func(k, v []byte) (bool, error) {
+			return true, nil
+		})
+	})
+	require.Equal(t, NewErrBucketNotExist([]byte("missing")), err)
+}
+
+func TestForEachPrefixReverse(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dbutil")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	db := openTestDB(t, filepath.Join(dir, "data.db"), false)
+	defer db.Close()
+
+	bkt := []byte("test")
+
+	err = db.Update("", func(tx *Tx) error {
+		if err := CreateBuckets(tx, [][]byte{bkt}); err != nil {
+			return err
+		}
+		for _, k := range []string{"a1", "b1", "b2", "b\xff", "c1", "\xff1", "\xff\xff"} {
+			if err := PutBucketValue(tx, bkt, []byte(k), []byte("v"+k)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	collect := func(prefix string, max int) []string {
+		var keys []string
+		err := db.View("", func(tx *Tx) error {
+			return ForEachPrefixReverse(tx, bkt, []byte(prefix), func(k, v []byte) (bool, error) {
+				require.Equal(t, "v"+string(k), string(v))
+				keys = append(keys, string(k))
+				return len(keys) < max, nil
+			})
+		})
+		require.NoError(t, err)
+		return keys
+	}
+
+	require.Equal(t, []string{"b\xff", "b2", "b1"}, collect("b", 10))
+	require.Equal(t, []string{"c1"}, collect("c", 10))
+	require.Equal(t, []string{"\xff\xff", "\xff1"}, collect("\xff", 10))
+	require.Empty(t, collect("d", 10))
+	require.Empty(t, collect("0", 10))
+
+	// Iteration stops when f returns false
+	require.Equal(t, []string{"b\xff", "b2"}, collect("b", 2))
+
+	// An empty prefix matches every key
+	require.Equal(t, []string{"\xff\xff", "\xff1", "c1"}, collect("", 3))
+
+	err = db.View("", func(tx *Tx) error {
+		return ForEachPrefixReverse(tx, []byte("missing"), nil, func(k, v []byte) (bool, error) {
+			return true, nil
+		})
+	})
+	require.Equal(t, NewErrBucketNotExist([]byte("missing")), err)
+}
+
+func TestSeekPrev(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dbutil")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	db := openTestDB(t, filepath.Join(dir, "data.db"), false)
+	defer db.Close()
+
+	bkt := []byte("test")
+
+	err = db.Update("", func(tx *Tx) error {
+		return CreateBuckets(tx, [][]byte{bkt})
+	})
+	require.NoError(t, err)
+
+	seek := func(key string) (string, string) {
+		var k, v []byte
+		err := db.View("", func(tx *Tx) error {
+			var err error
+			k, v, err = SeekPrev(tx, bkt, []byte(key))
+			return err
+		})
+		require.NoError(t, err)
+		return string(k), string(v)
+	}
+
+	// Empty bucket
+	k, _ := seek("b")
+	require.Empty(t, k)
+
+	err = db.Update("", func(tx *Tx) error {
+		for _, k := range []string{"b", "d", "f"} {
+			if err := PutBucketValue(tx, bkt, []byte(k), []byte("v"+k)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	cases := []struct {
+		key      string
+		expected string
+	}{
+		{"a", ""},
+		{"b", "b"},
+		{"c", "b"},
+		{"d", "d"},
+		{"e", "d"},
+		{"f", "f"},
+		{"g", "f"},
+	}
+
+	for _, tc := range cases {
+		k, v := seek(tc.key)
+		require.Equal(t, tc.expected, k, tc.key)
+		if tc.expected != "" {
+			require.Equal(t, "v"+tc.expected, v)
+		}
+	}
+
+	err = db.View("", func(tx *Tx) error {
+		_, _, err := SeekPrev(tx, []byte("missing"), nil)
+		return err
+	})
+	require.Equal(t, NewErrBucketNotExist([]byte("missing")), err)
+}
+
+func TestResetBucket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dbutil")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	db := openTestDB(t, filepath.Join(dir, "data.db"), false)
+	defer db.Close()
+
+	bkt := []byte("test")
+
+	err = db.Update("", func(tx *Tx) error {
+		// A missing bucket is created
+		require.NoError(t, ResetBucket(tx, bkt))
+		require.True(t, Exists(tx, bkt))
+
+		// An existing bucket is emptied
+		require.NoError(t, PutBucketValue(tx, bkt, []byte("k"), []byte("v")))
+		require.NoError(t, ResetBucket(tx, bkt))
+
+		empty, err := IsEmpty(tx, bkt)
+		require.NoError(t, err)
+		require.True(t, empty)
+		return nil
+	})
+	require.NoError(t, err)
+}
@@ -0,0 +1,422 @@
+package dbutil
+
+import (
+	"bytes"
+	"crypto/aes"
+	gcipher "crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/boltdb/bolt"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/scrypt"
+)
+
+var (
+	// EncryptionBkt stores the key derivation parameters of an encrypted database.
+	// Its values are not encrypted.
+	EncryptionBkt = []byte("db_encryption")
+
+	encryptionParamsKey = []byte("params")
+
+	// encryptionCheckPlaintext is encrypted in the params to detect a wrong passphrase
+	encryptionCheckPlaintext = []byte("skycoin db encryption")
+
+	// ErrDBEncrypted is returned if an encrypted database is opened without a passphrase
+	ErrDBEncrypted = errors.New("The database is encrypted, a passphrase or key file is required to open it")
+	// ErrWrongDBPassphrase is returned if the passphrase of an encrypted database is wrong
+	ErrWrongDBPassphrase = errors.New("The database passphrase is wrong")
+	// ErrDBNotEncrypted is returned if encryption is enabled on a read-only database that is not encrypted yet
+	ErrDBNotEncrypted = errors.New("The database is not encrypted, it can't be encrypted when it is opened read-only")
+)
+
+const (
+	// Scrypt parameters of the key derivation, stored in the database so that they can be changed later.
+	// The key is derived once when the database is opened, so the cost is kept low enough for a quick startup.
+	encryptionScryptN      = 1 << 15
+	encryptionScryptR      = 8
+	encryptionScryptP      = 1
+	encryptionKeyLen       = 32
+	encryptionSaltSize     = 32
+	encryptionBatchSize    = 1000
+	encryptionAdditionalID = "skycoin-db-v1"
+	encryptionKeyMACID     = "skycoin-db-v1 key mac"
+)
+
+// ErrDecryptFailed is returned if a bucket value can't be decrypted
+type ErrDecryptFailed struct {
+	Bucket string
+	Key    []byte
+	Err    error
+}
+
+// NewErrDecryptFailed creates ErrDecryptFailed
+func NewErrDecryptFailed(bktName, key []byte, err error) error {
+	return ErrDecryptFailed{
+		Bucket: string(bktName),
+		Key:    key,
+		Err:    err,
+	}
+}
+
+func (e ErrDecryptFailed) Error() string {
+	return fmt.Sprintf("Decrypt value %x of bucket %s failed: %v", e.Key, e.Bucket, e.Err)
+}
+
+// encryptionParams are the key derivation parameters of an encrypted database
+type encryptionParams struct {
+	N    int    `json:"n"`
+	R    int    `json:"r"`
+	P    int    `json:"p"`
+	Salt []byte `json:"salt"`
+	// Check is encryptionCheckPlaintext encrypted with the key
+	Check []byte `json:"check"`
+	// KeyMAC is true if the keys of the KeyMAC buckets are hidden with Tx.KeyMAC.
+	// It is false in databases encrypted before the keys were hidden.
+	KeyMAC bool `json:"key_mac,omitempty"`
+}
+
+// Encryption encrypts the values of the buckets with AES-256-GCM.
+// Each value is sealed with a random nonce, and authenticated with its bucket name and key,
+// so a value moved to another key fails to decrypt. Bucket names and keys are not encrypted,
+// the callers hide the keys that would reveal addresses with Tx.KeyMAC.
+type Encryption struct {
+	aead   gcipher.AEAD
+	macKey []byte
+	params encryptionParams
+}
+
+// newEncryption derives the key from the passphrase with the scrypt parameters
+func newEncryption(passphrase []byte, params encryptionParams) (*Encryption, error) {
+	if len(passphrase) == 0 {
+		return nil, errors.New("missing passphrase")
+	}
+
+	key, err := scrypt.Key(passphrase, params.Salt, params.N, params.R, params.P, encryptionKeyLen)
+	if err != nil {
+		return nil, fmt.Errorf("scrypt.Key failed: %v", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := gcipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// The key of the key MAC is derived from the database key, so that it is not the encryption key
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(encryptionKeyMACID)) // nolint: errcheck
+
+	return &Encryption{
+		aead:   aead,
+		macKey: mac.Sum(nil),
+		params: params,
+	}, nil
+}
+
+// additionalData binds a value to its bucket and key
+func additionalData(bktName, key []byte) []byte {
+	ad := make([]byte, 0, len(encryptionAdditionalID)+4+len(bktName)+len(key))
+	ad = append(ad, encryptionAdditionalID...)
+	var n [4]byte
+	binary.BigEndian.PutUint32(n[:], uint32(len(bktName)))
+	ad = append(ad, n[:]...)
+	ad = append(ad, bktName...)
+	return append(ad, key...)
+}
+
+// Seal encrypts the value of key in a bucket, as nonce|ciphertext
+func (e *Encryption) Seal(bktName, key, val []byte) []byte {
+	nonce := cipher.RandByte(e.aead.NonceSize())
+	return e.aead.Seal(nonce, nonce, val, additionalData(bktName, key))
+}
+
+// Open decrypts the value of key in a bucket, sealed with Seal
+func (e *Encryption) Open(bktName, key, sealed []byte) ([]byte, error) {
+	if len(sealed) < e.aead.NonceSize() {
+		return nil, NewErrDecryptFailed(bktName, key, errors.New("value is too short"))
+	}
+
+	nonce := sealed[:e.aead.NonceSize()]
+	val, err := e.aead.Open(nil, nonce, sealed[e.aead.NonceSize():], additionalData(bktName, key))
+	if err != nil {
+		return nil, NewErrDecryptFailed(bktName, key, err)
+	}
+
+	// Distinguish an empty value from a missing one
+	if val == nil {
+		val = []byte{}
+	}
+
+	return val, nil
+}
+
+// KeyMAC returns the HMAC-SHA256 of a bucket key
+func (e *Encryption) KeyMAC(key []byte) []byte {
+	mac := hmac.New(sha256.New, e.macKey)
+	mac.Write(key) // nolint: errcheck
+	return mac.Sum(nil)
+}
+
+// Encryption returns the encryption of the database, nil if it is not encrypted
+func (db *DB) Encryption() *Encryption {
+	return db.encryption
+}
+
+// IsEncrypted returns true if the database was encrypted with EnableEncryption
+func IsEncrypted(db *DB) (bool, error) {
+	var encrypted bool
+	err := db.View("IsEncrypted", func(tx *Tx) error {
+		encrypted = tx.Bucket(EncryptionBkt) != nil
+		return nil
+	})
+	return encrypted, err
+}
+
+// getEncryptionParams returns the key derivation parameters of an encrypted database, nil if it is not encrypted
+func getEncryptionParams(tx *Tx) (*encryptionParams, error) {
+	bkt := tx.Bucket(EncryptionBkt)
+	if bkt == nil {
+		return nil, nil
+	}
+
+	v := bkt.Get(encryptionParamsKey)
+	if v == nil {
+		return nil, nil
+	}
+
+	var params encryptionParams
+	if err := json.Unmarshal(v, &params); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal encryption params failed: %v", err)
+	}
+
+	return &params, nil
+}
+
+// EnableEncryption encrypts the bucket values of the database with a key derived from the passphrase,
+// which can be the content of a key file.
+// If the database is encrypted already, the passphrase is checked and ErrWrongDBPassphrase is returned if it is wrong.
+// Otherwise the existing values are encrypted in a single transaction, and the database can't be opened
+// without the passphrase afterwards.
+//
+// macBkts are the buckets whose keys are hidden with Tx.KeyMAC. Their existing keys are not hidden,
+// so they are emptied when the database is encrypted, and their owners must rebuild them.
+func EnableEncryption(db *DB, passphrase []byte, macBkts [][]byte) error {
+	var params *encryptionParams
+	if err := db.View("EnableEncryption", func(tx *Tx) error {
+		var err error
+		params, err = getEncryptionParams(tx)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	if params == nil {
+		params = &encryptionParams{
+			N:    encryptionScryptN,
+			R:    encryptionScryptR,
+			P:    encryptionScryptP,
+			Salt: cipher.RandByte(encryptionSaltSize),
+		}
+	}
+
+	enc, err := newEncryption(passphrase, *params)
+	if err != nil {
+		return err
+	}
+
+	return SetEncryption(db, enc, macBkts)
+}
+
+// SetEncryption encrypts the database with the encryption of another database,
+// for example when a database is recreated or reopened. It is otherwise like EnableEncryption.
+func SetEncryption(db *DB, enc *Encryption, macBkts [][]byte) error {
+	var params *encryptionParams
+	if err := db.View("SetEncryption", func(tx *Tx) error {
+		var err error
+		params, err = getEncryptionParams(tx)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	if params != nil {
+		check, err := enc.Open(EncryptionBkt, encryptionParamsKey, params.Check)
+		if err != nil || !bytes.Equal(check, encryptionCheckPlaintext) {
+			return ErrWrongDBPassphrase
+		}
+
+		if !params.KeyMAC {
+			if db.IsReadOnly() {
+				logger.Warning("The keys of the encrypted database are not hidden, they are hidden when it is opened writable")
+				db.encryption = enc
+				return nil
+			}
+
+			logger.Info("Hiding the keys of the encrypted database")
+
+			if err := db.Update("SetEncryption", func(tx *Tx) error {
+				if err := emptyBuckets(tx, macBkts); err != nil {
+					return err
+				}
+
+				params.KeyMAC = true
+				return putEncryptionParams(tx, *params)
+			}); err != nil {
+				return err
+			}
+		}
+
+		db.encryption = enc
+		db.keyMAC = true
+		return nil
+	}
+
+	if db.IsReadOnly() {
+		return ErrDBNotEncrypted
+	}
+
+	logger.Info("Encrypting the database")
+
+	if err := db.Update("SetEncryption", func(tx *Tx) error {
+		return encryptValues(tx, enc, macBkts)
+	}); err != nil {
+		return err
+	}
+
+	db.encryption = enc
+	db.keyMAC = true
+	return nil
+}
+
+// emptyBuckets empties the buckets that exist
+func emptyBuckets(tx *Tx, bkts [][]byte) error {
+	for _, b := range bkts {
+		if !Exists(tx, b) {
+			continue
+		}
+
+		if err := Reset(tx, b); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encryptValues encrypts the values of all buckets of an unencrypted database and saves the encryption params.
+// The buckets of macBkts are emptied.
+func encryptValues(tx *Tx, enc *Encryption, macBkts [][]byte) error {
+	if err := emptyBuckets(tx, macBkts); err != nil {
+		return err
+	}
+
+	if err := tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+		if bytes.Equal(name, EncryptionBkt) {
+			return nil
+		}
+		return encryptBucket(name, b, enc)
+	}); err != nil {
+		return err
+	}
+
+	params := enc.params
+	params.Check = enc.Seal(EncryptionBkt, encryptionParamsKey, encryptionCheckPlaintext)
+	params.KeyMAC = true
+
+	return putEncryptionParams(tx, params)
+}
+
+// putEncryptionParams saves the key derivation parameters of an encrypted database
+func putEncryptionParams(tx *Tx, params encryptionParams) error {
+	v, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	bkt, err := tx.CreateBucketIfNotExists(EncryptionBkt)
+	if err != nil {
+		return NewErrCreateBucketFailed(EncryptionBkt, err)
+	}
+
+	return bkt.Put(encryptionParamsKey, v)
+}
+
+// encryptBucket encrypts the values of a bucket. The values of nested buckets are not encrypted.
+// The values are read in batches, since a bucket can't be modified while iterating it.
+func encryptBucket(name []byte, b *bolt.Bucket, enc *Encryption) error {
+	type kv struct {
+		k, v []byte
+	}
+
+	var last []byte
+	for {
+		batch := make([]kv, 0, encryptionBatchSize)
+
+		c := b.Cursor()
+		k, v := c.First()
+		if last != nil {
+			k, v = c.Seek(last)
+			if bytes.Equal(k, last) {
+				k, v = c.Next()
+			}
+		}
+
+		for ; k != nil && len(batch) < encryptionBatchSize; k, v = c.Next() {
+			last = append([]byte{}, k...)
+			if v == nil {
+				continue
+			}
+
+			batch = append(batch, kv{
+				k: last,
+				v: enc.Seal(name, k, v),
+			})
+		}
+
+		for _, x := range batch {
+			if err := b.Put(x.k, x.v); err != nil {
+				return err
+			}
+		}
+
+		if k == nil {
+			return nil
+		}
+	}
+}
+
+// seal encrypts a value of a bucket if the database is encrypted
+func (tx *Tx) seal(bktName, key, val []byte) []byte {
+	if tx.encryption == nil {
+		return val
+	}
+	return tx.encryption.Seal(bktName, key, val)
+}
+
+// KeyMAC returns the keyed MAC of a bucket key if the database is encrypted, and the key itself otherwise.
+// It hides the keys that would reveal the addresses indexed by the node, see EnableEncryption.
+// The MAC is 32 bytes long, so the keys made of a hidden key and a suffix can still be iterated by prefix.
+func (tx *Tx) KeyMAC(key []byte) []byte {
+	if tx.encryption == nil || !tx.keyMAC {
+		return key
+	}
+	return tx.encryption.KeyMAC(key)
+}
+
+// open decrypts a value of a bucket if the database is encrypted
+func (tx *Tx) open(bktName, key, val []byte) ([]byte, error) {
+	if tx.encryption == nil || val == nil {
+		return val, nil
+	}
+	return tx.encryption.Open(bktName, key, val)
+}
@@ -0,0 +1,212 @@
+package dbutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryption(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dbutil")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "data.db")
+	db := openTestDB(t, path, false)
+
+	bkt := []byte("values")
+	nestedBkt := []byte("nested")
+	n := encryptionBatchSize + 10
+
+	// More values than a batch, and a nested bucket, are encrypted
+	err = db.Update("", func(tx *Tx) error {
+		require.NoError(t, CreateBuckets(tx, [][]byte{bkt}))
+		for i := 0; i < n; i++ {
+			require.NoError(t, PutBucketValue(tx, bkt, Itob(uint64(i)), Itob(uint64(i*2))))
+		}
+		_, err := tx.Bucket(bkt).CreateBucket(nestedBkt)
+		return err
+	})
+	require.NoError(t, err)
+
+	encrypted, err := IsEncrypted(db)
+	require.NoError(t, err)
+	require.False(t, encrypted)
+
+	require.NoError(t, EnableEncryption(db, []byte("secret"), nil))
+	require.NotNil(t, db.Encryption())
+
+	encrypted, err = IsEncrypted(db)
+	require.NoError(t, err)
+	require.True(t, encrypted)
+
+	err = db.Update("", func(tx *Tx) error {
+		require.NoError(t, PutBucketValue(tx, bkt, []byte("new"), []byte("value")))
+
+		// The values are encrypted in the file
+		raw := tx.Bucket(bkt).Get(Itob(1))
+		require.NotEqual(t, Itob(2), raw)
+		require.NotEqual(t, []byte("value"), tx.Bucket(bkt).Get([]byte("new")))
+
+		v, err := GetBucketValue(tx, bkt, Itob(1))
+		require.NoError(t, err)
+		require.Equal(t, Itob(2), v)
+
+		v, err = GetBucketValue(tx, bkt, []byte("new"))
+		require.NoError(t, err)
+		require.Equal(t, []byte("value"), v)
+
+		var count int
+		require.NoError(t, ForEach(tx, bkt, func(k, v []byte) error {
+			if v != nil && len(k) == 8 {
+				require.Equal(t, Itob(Btoi(k)*2), v)
+			}
+			count++
+			return nil
+		}))
+		require.Equal(t, n+2, count)
+
+		// A value moved to another key fails to decrypt
+		require.NoError(t, tx.Bucket(bkt).Put(Itob(3), raw))
+		_, err = GetBucketValue(tx, bkt, Itob(3))
+		require.IsType(t, ErrDecryptFailed{}, err)
+		return nil
+	})
+	require.NoError(t, err)
+
+	enc := db.Encryption()
+	require.NoError(t, db.Close())
+
+	// The passphrase is checked when the database is opened again
+	db = openTestDB(t, path, false)
+	require.Equal(t, ErrWrongDBPassphrase, EnableEncryption(db, []byte("wrong"), nil))
+	require.Nil(t, db.Encryption())
+	require.NoError(t, EnableEncryption(db, []byte("secret"), nil))
+
+	err = db.View("", func(tx *Tx) error {
+		v, err := GetBucketValue(tx, bkt, Itob(10))
+		require.NoError(t, err)
+		require.Equal(t, Itob(20), v)
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	// A new database is encrypted with the encryption of another database
+	newPath := filepath.Join(dir, "new.db")
+	db = openTestDB(t, newPath, false)
+	require.NoError(t, SetEncryption(db, enc, nil))
+	require.NoError(t, db.Close())
+
+	db = openTestDB(t, newPath, true)
+	defer db.Close()
+	require.NoError(t, EnableEncryption(db, []byte("secret"), nil))
+
+	// A read-only database can't be encrypted
+	unencrypted := openTestDB(t, filepath.Join(dir, "unencrypted.db"), false)
+	require.NoError(t, unencrypted.Close())
+	unencrypted = openTestDB(t, filepath.Join(dir, "unencrypted.db"), true)
+	defer unencrypted.Close()
+	require.Equal(t, ErrDBNotEncrypted, EnableEncryption(unencrypted, []byte("secret"), nil))
+}
+
+func TestEncryptionKeyMAC(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dbutil")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "data.db")
+	db := openTestDB(t, path, false)
+
+	addrBkt := []byte("address_index")
+	otherBkt := []byte("other")
+	addr := []byte("address")
+
+	err = db.Update("", func(tx *Tx) error {
+		// The keys are not hidden in an unencrypted database
+		require.Equal(t, addr, tx.KeyMAC(addr))
+
+		require.NoError(t, CreateBuckets(tx, [][]byte{addrBkt, otherBkt}))
+		require.NoError(t, PutBucketValue(tx, addrBkt, addr, []byte("value")))
+		return PutBucketValue(tx, otherBkt, []byte("key"), []byte("value"))
+	})
+	require.NoError(t, err)
+
+	// The buckets with hidden keys are emptied by the encryption, the others are kept
+	require.NoError(t, EnableEncryption(db, []byte("secret"), [][]byte{addrBkt, []byte("missing")}))
+
+	var mac []byte
+	err = db.Update("", func(tx *Tx) error {
+		empty, err := IsEmpty(tx, addrBkt)
+		require.NoError(t, err)
+		require.True(t, empty)
+
+		v, err := GetBucketValue(tx, otherBkt, []byte("key"))
+		require.NoError(t, err)
+		require.Equal(t, []byte("value"), v)
+
+		mac = tx.KeyMAC(addr)
+		require.Len(t, mac, 32)
+		require.NotEqual(t, addr, mac)
+		require.NotEqual(t, mac, tx.KeyMAC([]byte("other address")))
+
+		require.NoError(t, PutBucketValue(tx, addrBkt, mac, []byte("value")))
+		require.Nil(t, tx.Bucket(addrBkt).Get(addr))
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	// The MAC is derived from the passphrase, so it is the same when the database is opened again
+	db = openTestDB(t, path, false)
+	require.NoError(t, EnableEncryption(db, []byte("secret"), [][]byte{addrBkt}))
+	err = db.View("", func(tx *Tx) error {
+		require.Equal(t, mac, tx.KeyMAC(addr))
+
+		v, err := GetBucketValue(tx, addrBkt, tx.KeyMAC(addr))
+		require.NoError(t, err)
+		require.Equal(t, []byte("value"), v)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// A database encrypted before the keys were hidden is upgraded when it is opened writable
+	err = db.Update("", func(tx *Tx) error {
+		params, err := getEncryptionParams(tx)
+		require.NoError(t, err)
+		params.KeyMAC = false
+		require.NoError(t, putEncryptionParams(tx, *params))
+		return PutBucketValue(tx, addrBkt, addr, []byte("value"))
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	db = openTestDB(t, path, true)
+	require.NoError(t, EnableEncryption(db, []byte("secret"), [][]byte{addrBkt}))
+	err = db.View("", func(tx *Tx) error {
+		require.Equal(t, addr, tx.KeyMAC(addr))
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	db = openTestDB(t, path, false)
+	defer db.Close()
+	require.NoError(t, EnableEncryption(db, []byte("secret"), [][]byte{addrBkt}))
+	err = db.View("", func(tx *Tx) error {
+		require.Equal(t, mac, tx.KeyMAC(addr))
+
+		empty, err := IsEmpty(tx, addrBkt)
+		require.NoError(t, err)
+		require.True(t, empty)
+
+		params, err := getEncryptionParams(tx)
+		require.NoError(t, err)
+		require.True(t, params.KeyMAC)
+		return nil
+	})
+	require.NoError(t, err)
+}
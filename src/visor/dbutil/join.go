@@ -0,0 +1,82 @@
+package dbutil
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/boltdb/bolt"
+)
+
+// ForEachJoined iterates the keys of a bucket in key order and calls f with each key, its value and the values
+// of the same key in the joined buckets, in the order of joined. The value of a joined bucket is nil if the bucket
+// doesn't have the key.
+// It is used instead of looking up the keys of a ForEach in the other buckets, e.g. to iterate the blocks
+// with their signatures in one pass.
+// The iteration stops when f returns an error or when the context of tx is done, and returns the error.
+// The values are only valid until f returns.
+func ForEachJoined(tx *Tx, bktName []byte, joined [][]byte, f func(k, v []byte, joinedVals [][]byte) error) error {
+	return forEachJoined(tx, bktName, joined, nil, nil, func(k, v []byte, joinedVals [][]byte) (bool, error) {
+		return true, f(k, v, joinedVals)
+	})
+}
+
+// ForEachPrefixJoined is like ForEachJoined, but only iterates the keys that start with prefix,
+// starting from the first key >= start, like ForEachPrefix. start must be prefixed by prefix.
+// The iteration also stops when f returns false.
+func ForEachPrefixJoined(tx *Tx, bktName, prefix, start []byte, joined [][]byte, f func(k, v []byte, joinedVals [][]byte) (bool, error)) error {
+	if !bytes.HasPrefix(start, prefix) {
+		return errors.New("ForEachPrefixJoined start key is not prefixed by prefix")
+	}
+
+	return forEachJoined(tx, bktName, joined, prefix, start, f)
+}
+
+func forEachJoined(tx *Tx, bktName []byte, joined [][]byte, prefix, start []byte, f func(k, v []byte, joinedVals [][]byte) (bool, error)) error {
+	bkt := tx.Bucket(bktName)
+	if bkt == nil {
+		return NewErrBucketNotExist(bktName)
+	}
+
+	joinedBkts := make([]*bolt.Bucket, len(joined))
+	for i, name := range joined {
+		joinedBkts[i] = tx.Bucket(name)
+		if joinedBkts[i] == nil {
+			return NewErrBucketNotExist(name)
+		}
+	}
+
+	ctx := tx.Context()
+	joinedVals := make([][]byte, len(joined))
+
+	c := bkt.Cursor()
+	k, v := c.First()
+	if start != nil {
+		k, v = c.Seek(start)
+	}
+
+	for ; k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		v, err := tx.open(bktName, k, v)
+		if err != nil {
+			return err
+		}
+
+		for i, jb := range joinedBkts {
+			joinedVals[i], err = tx.open(joined[i], k, jb.Get(k))
+			if err != nil {
+				return err
+			}
+		}
+
+		if ok, err := f(k, v, joinedVals); err != nil {
+			return err
+		} else if !ok {
+			return nil
+		}
+	}
+
+	return nil
+}
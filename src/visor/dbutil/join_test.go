@@ -0,0 +1,159 @@
+package dbutil
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestForEachJoined(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dbutil")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	db := openTestDB(t, filepath.Join(dir, "data.db"), false)
+	defer db.Close()
+
+	bkt := []byte("test")
+	joinedA := []byte("joined_a")
+	joinedB := []byte("joined_b")
+
+	err = db.Update("", func(tx *Tx) error {
+		if err := CreateBuckets(tx, [][]byte{bkt, joinedA, joinedB}); err != nil {
+			return err
+		}
+		for _, k := range []string{"a1", "b1", "b2", "b3", "c1"} {
+			if err := PutBucketValue(tx, bkt, []byte(k), []byte("v"+k)); err != nil {
+				return err
+			}
+			if err := PutBucketValue(tx, joinedA, []byte(k), []byte("a"+k)); err != nil {
+				return err
+			}
+		}
+		// joinedB is missing some of the keys, and has a key missing from bkt
+		for _, k := range []string{"a1", "b2", "d1"} {
+			if err := PutBucketValue(tx, joinedB, []byte(k), []byte("b"+k)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	type row struct {
+		k, v, a, b string
+	}
+
+	toRow := func(k, v []byte, joinedVals [][]byte) row {
+		r := row{
+			k: string(k),
+			v: string(v),
+			a: string(joinedVals[0]),
+		}
+		if joinedVals[1] != nil {
+			r.b = string(joinedVals[1])
+		}
+		return r
+	}
+
+	var rows []row
+	err = db.View("", func(tx *Tx) error {
+		return ForEachJoined(tx, bkt, [][]byte{joinedA, joinedB}, func(k, v []byte, joinedVals [][]byte) error {
+			require.Len(t, joinedVals, 2)
+			if string(k) != "a1" && string(k) != "b2" {
+				require.Nil(t, joinedVals[1])
+			}
+			rows = append(rows, toRow(k, v, joinedVals))
+			return nil
+		})
+	})
+	require.NoError(t, err)
+	require.Equal(t, []row{
+		{"a1", "va1", "aa1", "ba1"},
+		{"b1", "vb1", "ab1", ""},
+		{"b2", "vb2", "ab2", "bb2"},
+		{"b3", "vb3", "ab3", ""},
+		{"c1", "vc1", "ac1", ""},
+	}, rows)
+
+	// The iteration stops on the first error of f
+	errStop := errors.New("stop")
+	n := 0
+	err = db.View("", func(tx *Tx) error {
+		return ForEachJoined(tx, bkt, [][]byte{joinedA}, func(k, v []byte, joinedVals [][]byte) error {
+			n++
+			if n == 2 {
+				return errStop
+			}
+			return nil
+		})
+	})
+	require.Equal(t, errStop, err)
+	require.Equal(t, 2, n)
+
+	// Iterates the prefixed keys from start and stops when f returns false
+	collect := func(prefix, start string, max int) ([]row, error) {
+		var rows []row
+		err := db.View("", func(tx *Tx) error {
+			return ForEachPrefixJoined(tx, bkt, []byte(prefix), []byte(start), [][]byte{joinedA, joinedB}, func(k, v []byte, joinedVals [][]byte) (bool, error) {
+				rows = append(rows, toRow(k, v, joinedVals))
+				return len(rows) < max, nil
+			})
+		})
+		return rows, err
+	}
+
+	rows, err = collect("b", "b", 10)
+	require.NoError(t, err)
+	require.Equal(t, []row{
+		{"b1", "vb1", "ab1", ""},
+		{"b2", "vb2", "ab2", "bb2"},
+		{"b3", "vb3", "ab3", ""},
+	}, rows)
+
+	rows, err = collect("b", "b2", 1)
+	require.NoError(t, err)
+	require.Equal(t, []row{
+		{"b2", "vb2", "ab2", "bb2"},
+	}, rows)
+
+	rows, err = collect("d", "d", 10)
+	require.NoError(t, err)
+	require.Empty(t, rows)
+
+	_, err = collect("b", "a", 10)
+	require.Error(t, err)
+
+	// The main and joined buckets must exist
+	err = db.View("", func(tx *Tx) error {
+		return ForEachJoined(tx, []byte("missing"), [][]byte{joinedA}, func(k, v []byte, joinedVals [][]byte) error {
+			return nil
+		})
+	})
+	require.Equal(t, NewErrBucketNotExist([]byte("missing")), err)
+
+	err = db.View("", func(tx *Tx) error {
+		return ForEachJoined(tx, bkt, [][]byte{joinedA, []byte("missing")}, func(k, v []byte, joinedVals [][]byte) error {
+			return nil
+		})
+	})
+	require.Equal(t, NewErrBucketNotExist([]byte("missing")), err)
+
+	// The iteration stops when the context of the transaction is done
+	ctx, cancel := context.WithCancel(context.Background())
+	n = 0
+	err = db.ViewContext(ctx, "", func(tx *Tx) error {
+		return ForEachJoined(tx, bkt, [][]byte{joinedA}, func(k, v []byte, joinedVals [][]byte) error {
+			n++
+			cancel()
+			return nil
+		})
+	})
+	require.Equal(t, context.Canceled, err)
+	require.Equal(t, 1, n)
+}
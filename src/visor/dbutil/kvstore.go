@@ -0,0 +1,209 @@
+package dbutil
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// Backend is a key-value store implementation
+type Backend string
+
+const (
+	// BackendBolt is the boltdb backend
+	BackendBolt Backend = "bolt"
+	// BackendBadger is the experimental BadgerDB backend
+	BackendBadger Backend = "badger"
+)
+
+// openBadgerKVStore opens a BadgerDB KVStore. It is set by kvstore_badger.go, which is only built with the "badger" build tag
+var openBadgerKVStore func(path string, readOnly bool) (KVStore, error)
+
+// ErrBackendNotAvailable is returned by OpenKVStore if the backend is not available in this build
+type ErrBackendNotAvailable struct {
+	Backend Backend
+}
+
+func (e ErrBackendNotAvailable) Error() string {
+	return fmt.Sprintf("Database backend %q is not available in this build", e.Backend)
+}
+
+// NewErrBackendNotAvailable returns an ErrBackendNotAvailable
+func NewErrBackendNotAvailable(backend Backend) error {
+	return ErrBackendNotAvailable{
+		Backend: backend,
+	}
+}
+
+// KVStore is a key-value store organized in buckets, with read-only and read-write transactions
+type KVStore interface {
+	// View runs f in a read-only transaction
+	View(name string, f func(KVTx) error) error
+	// Update runs f in a read-write transaction, which is committed if f returns nil
+	Update(name string, f func(KVTx) error) error
+	// Backend returns the backend of the store
+	Backend() Backend
+	// Path returns the path of the store on disk
+	Path() string
+	// IsReadOnly returns true if the store was opened read-only
+	IsReadOnly() bool
+	// Close closes the store
+	Close() error
+}
+
+// KVTx is a KVStore transaction
+type KVTx interface {
+	// Bucket returns the bucket with the given name, or nil if it does not exist
+	Bucket(name []byte) KVBucket
+	// CreateBucketIfNotExists creates a bucket if it does not exist and returns it
+	CreateBucketIfNotExists(name []byte) (KVBucket, error)
+	// DeleteBucket deletes a bucket and all of its keys
+	DeleteBucket(name []byte) error
+	// ForEachBucket calls f with the name of every bucket
+	ForEachBucket(f func(name []byte) error) error
+}
+
+// KVBucket is a collection of keys and values in a KVStore.
+// Keys are sorted in byte order.
+// Values returned by a bucket are only valid for the life of the transaction.
+type KVBucket interface {
+	// Get returns the value of a key, or nil if the key does not exist
+	Get(key []byte) []byte
+	// Put sets the value of a key. The value must remain valid for the life of the transaction
+	Put(key, value []byte) error
+	// Delete removes a key
+	Delete(key []byte) error
+	// NextSequence returns an autoincrementing integer for the bucket
+	NextSequence() (uint64, error)
+	// ForEach calls f for every key and value in the bucket, in key order
+	ForEach(f func(k, v []byte) error) error
+	// Cursor returns a cursor for iterating over the bucket
+	Cursor() KVCursor
+}
+
+// KVCursor iterates over the keys of a KVBucket in key order.
+// A nil key is returned when the cursor moves past the first or last key.
+type KVCursor interface {
+	First() (key, value []byte)
+	Last() (key, value []byte)
+	Next() (key, value []byte)
+	Prev() (key, value []byte)
+	Seek(seek []byte) (key, value []byte)
+}
+
+// CheckBackend returns an error if the backend can't be opened by OpenKVStore
+func CheckBackend(backend Backend) error {
+	switch backend {
+	case BackendBolt:
+		return nil
+	case BackendBadger:
+		if openBadgerKVStore == nil {
+			return NewErrBackendNotAvailable(backend)
+		}
+		return nil
+	default:
+		return fmt.Errorf("Unknown database backend %q", backend)
+	}
+}
+
+// OpenKVStore opens a KVStore with the given backend.
+// The bolt backend opens a bolt DB file, the badger backend opens a BadgerDB directory.
+func OpenKVStore(backend Backend, path string, readOnly bool) (KVStore, error) {
+	if err := CheckBackend(backend); err != nil {
+		return nil, err
+	}
+
+	if backend == BackendBadger {
+		return openBadgerKVStore(path, readOnly)
+	}
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{
+		Timeout:  5000 * time.Millisecond,
+		ReadOnly: readOnly,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Open boltdb failed, %v", err)
+	}
+
+	return NewKVStore(WrapDB(db)), nil
+}
+
+// NewKVStore returns a KVStore backed by a bolt DB
+func NewKVStore(db *DB) KVStore {
+	return boltStore{
+		db: db,
+	}
+}
+
+// boltStore implements KVStore with boltdb
+type boltStore struct {
+	db *DB
+}
+
+func (s boltStore) View(name string, f func(KVTx) error) error {
+	return s.db.View(name, func(tx *Tx) error {
+		return f(boltTx{tx.Tx})
+	})
+}
+
+func (s boltStore) Update(name string, f func(KVTx) error) error {
+	return s.db.Update(name, func(tx *Tx) error {
+		return f(boltTx{tx.Tx})
+	})
+}
+
+func (s boltStore) Backend() Backend {
+	return BackendBolt
+}
+
+func (s boltStore) Path() string {
+	return s.db.Path()
+}
+
+func (s boltStore) IsReadOnly() bool {
+	return s.db.IsReadOnly()
+}
+
+func (s boltStore) Close() error {
+	return s.db.Close()
+}
+
+type boltTx struct {
+	tx *bolt.Tx
+}
+
+func (t boltTx) Bucket(name []byte) KVBucket {
+	b := t.tx.Bucket(name)
+	if b == nil {
+		// Return an untyped nil, so that callers can compare the result to nil
+		return nil
+	}
+	return boltBucket{b}
+}
+
+func (t boltTx) CreateBucketIfNotExists(name []byte) (KVBucket, error) {
+	b, err := t.tx.CreateBucketIfNotExists(name)
+	if err != nil {
+		return nil, NewErrCreateBucketFailed(name, err)
+	}
+	return boltBucket{b}, nil
+}
+
+func (t boltTx) DeleteBucket(name []byte) error {
+	return t.tx.DeleteBucket(name)
+}
+
+func (t boltTx) ForEachBucket(f func(name []byte) error) error {
+	return t.tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+		return f(name)
+	})
+}
+
+type boltBucket struct {
+	*bolt.Bucket
+}
+
+func (b boltBucket) Cursor() KVCursor {
+	return b.Bucket.Cursor()
+}
@@ -0,0 +1,375 @@
+// +build badger
+
+package dbutil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+
+	"github.com/boltdb/bolt"
+	badger "github.com/dgraph-io/badger/v4"
+)
+
+/*
+
+The experimental BadgerDB backend is only built with the "badger" build tag:
+
+	go get github.com/dgraph-io/badger/v4
+	go build -tags badger ./...
+
+BadgerDB has a single sorted keyspace, so buckets are emulated with key prefixes:
+
+	0x00 | bucket name                              bucket registry
+	0x01 | bucket name                              bucket sequence, for NextSequence
+	0x02 | len(bucket name) uint32 | bucket name | key   bucket keys
+
+The length of the bucket name keeps the keys of bucket "a" apart from the keys of bucket "ab".
+
+BadgerDB limits the size of a transaction, so a large Update fails with badger.ErrTxnTooBig
+where bolt would succeed.
+
+*/
+
+const (
+	badgerBucketPrefix   byte = 0x00
+	badgerSequencePrefix byte = 0x01
+	badgerKeyPrefix      byte = 0x02
+)
+
+var errBadgerBucketNameRequired = errors.New("bucket name required")
+
+func init() {
+	openBadgerKVStore = openBadgerStore
+}
+
+// badgerStore implements KVStore with BadgerDB
+type badgerStore struct {
+	db       *badger.DB
+	path     string
+	readOnly bool
+}
+
+func openBadgerStore(path string, readOnly bool) (KVStore, error) {
+	opts := badger.DefaultOptions(path).WithReadOnly(readOnly).WithLogger(logger)
+	db, err := badger.Open(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return badgerStore{
+		db:       db,
+		path:     path,
+		readOnly: readOnly,
+	}, nil
+}
+
+func (s badgerStore) View(name string, f func(KVTx) error) error {
+	return s.db.View(func(txn *badger.Txn) error {
+		return f(badgerTx{txn})
+	})
+}
+
+func (s badgerStore) Update(name string, f func(KVTx) error) error {
+	return s.db.Update(func(txn *badger.Txn) error {
+		return f(badgerTx{txn})
+	})
+}
+
+func (s badgerStore) Backend() Backend {
+	return BackendBadger
+}
+
+func (s badgerStore) Path() string {
+	return s.path
+}
+
+func (s badgerStore) IsReadOnly() bool {
+	return s.readOnly
+}
+
+func (s badgerStore) Close() error {
+	return s.db.Close()
+}
+
+func badgerBucketKey(name []byte) []byte {
+	return append([]byte{badgerBucketPrefix}, name...)
+}
+
+func badgerSequenceKey(name []byte) []byte {
+	return append([]byte{badgerSequencePrefix}, name...)
+}
+
+func badgerKeysPrefix(name []byte) []byte {
+	prefix := make([]byte, 5, 5+len(name))
+	prefix[0] = badgerKeyPrefix
+	binary.BigEndian.PutUint32(prefix[1:], uint32(len(name)))
+	return append(prefix, name...)
+}
+
+// badgerPrefixEnd returns the smallest key that is greater than all the keys with the prefix
+func badgerPrefixEnd(prefix []byte) []byte {
+	end := append([]byte{}, prefix...)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] != 0xFF {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return nil
+}
+
+type badgerTx struct {
+	txn *badger.Txn
+}
+
+func (t badgerTx) has(key []byte) (bool, error) {
+	_, err := t.txn.Get(key)
+	switch err {
+	case nil:
+		return true, nil
+	case badger.ErrKeyNotFound:
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+func (t badgerTx) Bucket(name []byte) KVBucket {
+	if ok, err := t.has(badgerBucketKey(name)); err != nil || !ok {
+		// Return an untyped nil, so that callers can compare the result to nil
+		return nil
+	}
+	return t.bucket(name)
+}
+
+func (t badgerTx) bucket(name []byte) badgerBucket {
+	return badgerBucket{
+		txn:    t.txn,
+		name:   append([]byte{}, name...),
+		prefix: badgerKeysPrefix(name),
+	}
+}
+
+func (t badgerTx) CreateBucketIfNotExists(name []byte) (KVBucket, error) {
+	if len(name) == 0 {
+		return nil, NewErrCreateBucketFailed(name, errBadgerBucketNameRequired)
+	}
+
+	ok, err := t.has(badgerBucketKey(name))
+	if err != nil {
+		return nil, NewErrCreateBucketFailed(name, err)
+	}
+
+	if !ok {
+		if err := t.txn.Set(badgerBucketKey(name), []byte{}); err != nil {
+			return nil, NewErrCreateBucketFailed(name, err)
+		}
+	}
+
+	return t.bucket(name), nil
+}
+
+func (t badgerTx) DeleteBucket(name []byte) error {
+	ok, err := t.has(badgerBucketKey(name))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return bolt.ErrBucketNotFound
+	}
+
+	// Collect the keys first, a read-write transaction can't delete keys while an iterator is open
+	var keys [][]byte
+	b := t.bucket(name)
+	if err := b.forEachItem(func(item *badger.Item) error {
+		keys = append(keys, item.KeyCopy(nil))
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	keys = append(keys, badgerSequenceKey(name), badgerBucketKey(name))
+	for _, k := range keys {
+		if err := t.txn.Delete(k); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t badgerTx) ForEachBucket(f func(name []byte) error) error {
+	opts := badger.DefaultIteratorOptions
+	opts.PrefetchValues = false
+	opts.Prefix = []byte{badgerBucketPrefix}
+	it := t.txn.NewIterator(opts)
+	defer it.Close()
+
+	for it.Rewind(); it.Valid(); it.Next() {
+		if err := f(it.Item().KeyCopy(nil)[1:]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type badgerBucket struct {
+	txn    *badger.Txn
+	name   []byte
+	prefix []byte
+}
+
+func (b badgerBucket) key(k []byte) []byte {
+	key := make([]byte, len(b.prefix), len(b.prefix)+len(k))
+	copy(key, b.prefix)
+	return append(key, k...)
+}
+
+func (b badgerBucket) Get(key []byte) []byte {
+	item, err := b.txn.Get(b.key(key))
+	if err != nil {
+		return nil
+	}
+
+	v, err := item.ValueCopy(nil)
+	if err != nil {
+		return nil
+	}
+	return v
+}
+
+func (b badgerBucket) Put(key, value []byte) error {
+	if len(key) == 0 {
+		return bolt.ErrKeyRequired
+	}
+
+	return b.txn.Set(b.key(key), value)
+}
+
+func (b badgerBucket) Delete(key []byte) error {
+	return b.txn.Delete(b.key(key))
+}
+
+func (b badgerBucket) NextSequence() (uint64, error) {
+	key := badgerSequenceKey(b.name)
+
+	var seq uint64
+	item, err := b.txn.Get(key)
+	switch err {
+	case nil:
+		if err := item.Value(func(v []byte) error {
+			seq = binary.BigEndian.Uint64(v)
+			return nil
+		}); err != nil {
+			return 0, err
+		}
+	case badger.ErrKeyNotFound:
+	default:
+		return 0, err
+	}
+
+	seq++
+	v := make([]byte, 8)
+	binary.BigEndian.PutUint64(v, seq)
+	if err := b.txn.Set(key, v); err != nil {
+		return 0, err
+	}
+
+	return seq, nil
+}
+
+func (b badgerBucket) forEachItem(f func(item *badger.Item) error) error {
+	opts := badger.DefaultIteratorOptions
+	opts.Prefix = b.prefix
+	it := b.txn.NewIterator(opts)
+	defer it.Close()
+
+	for it.Rewind(); it.Valid(); it.Next() {
+		if err := f(it.Item()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b badgerBucket) ForEach(f func(k, v []byte) error) error {
+	return b.forEachItem(func(item *badger.Item) error {
+		v, err := item.ValueCopy(nil)
+		if err != nil {
+			return err
+		}
+		return f(item.KeyCopy(nil)[len(b.prefix):], v)
+	})
+}
+
+func (b badgerBucket) Cursor() KVCursor {
+	return &badgerCursor{
+		bucket: b,
+	}
+}
+
+// badgerCursor implements KVCursor with a short-lived iterator for each move,
+// because a read-write transaction can only have one iterator open at a time
+type badgerCursor struct {
+	bucket badgerBucket
+	// key is the bucket key at the cursor, nil if the cursor moved past the first or last key
+	key []byte
+}
+
+// seek moves the cursor to the first key >= seek, or to the last key <= seek if reverse is true.
+// If skip is true, a key equal to seek is skipped.
+func (c *badgerCursor) seek(seek []byte, reverse, skip bool) ([]byte, []byte) {
+	opts := badger.DefaultIteratorOptions
+	opts.Reverse = reverse
+	it := c.bucket.txn.NewIterator(opts)
+	defer it.Close()
+
+	it.Seek(seek)
+	if it.Valid() && skip && bytes.Equal(it.Item().Key(), seek) {
+		it.Next()
+	}
+
+	if !it.Valid() || !bytes.HasPrefix(it.Item().Key(), c.bucket.prefix) {
+		c.key = nil
+		return nil, nil
+	}
+
+	item := it.Item()
+	v, err := item.ValueCopy(nil)
+	if err != nil {
+		c.key = nil
+		return nil, nil
+	}
+
+	c.key = item.KeyCopy(nil)[len(c.bucket.prefix):]
+	return c.key, v
+}
+
+func (c *badgerCursor) First() ([]byte, []byte) {
+	return c.seek(c.bucket.prefix, false, false)
+}
+
+func (c *badgerCursor) Last() ([]byte, []byte) {
+	return c.seek(badgerPrefixEnd(c.bucket.prefix), true, true)
+}
+
+func (c *badgerCursor) Next() ([]byte, []byte) {
+	if c.key == nil {
+		return nil, nil
+	}
+	return c.seek(c.bucket.key(c.key), false, true)
+}
+
+func (c *badgerCursor) Prev() ([]byte, []byte) {
+	if c.key == nil {
+		return nil, nil
+	}
+	return c.seek(c.bucket.key(c.key), true, true)
+}
+
+func (c *badgerCursor) Seek(seek []byte) ([]byte, []byte) {
+	return c.seek(c.bucket.key(seek), false, false)
+}
@@ -0,0 +1,53 @@
+// +build badger
+
+package dbutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBadgerKVStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kvstore")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "data")
+	s, err := OpenKVStore(BackendBadger, path, false)
+	require.NoError(t, err)
+
+	require.Equal(t, BackendBadger, s.Backend())
+	require.Equal(t, path, s.Path())
+	require.False(t, s.IsReadOnly())
+
+	testKVStore(t, s)
+	require.NoError(t, s.Close())
+
+	// The data is persisted
+	s, err = OpenKVStore(BackendBadger, path, true)
+	require.NoError(t, err)
+	defer s.Close()
+
+	require.True(t, s.IsReadOnly())
+	err = s.View("", func(tx KVTx) error {
+		b := tx.Bucket([]byte("bkt2"))
+		require.NotNil(t, b)
+		require.Equal(t, []byte("vz"), b.Get([]byte("z")))
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestBadgerPrefixEnd(t *testing.T) {
+	require.Equal(t, []byte{0x02, 0x00, 0x01}, badgerPrefixEnd([]byte{0x02, 0x00, 0x00}))
+	require.Equal(t, []byte{0x02, 0x01}, badgerPrefixEnd([]byte{0x02, 0x00, 0xFF}))
+	require.Nil(t, badgerPrefixEnd([]byte{0xFF, 0xFF}))
+}
+
+func BenchmarkBadgerKVStorePut(b *testing.B) {
+	benchmarkKVStorePut(b, BackendBadger, "data")
+}
@@ -0,0 +1,191 @@
+package dbutil
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenKVStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kvstore")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "data.db")
+
+	if openBadgerKVStore == nil {
+		_, err = OpenKVStore(BackendBadger, path, false)
+		require.Equal(t, NewErrBackendNotAvailable(BackendBadger), err)
+	}
+
+	_, err = OpenKVStore(Backend("foo"), path, false)
+	require.Equal(t, errors.New(`Unknown database backend "foo"`), err)
+
+	s, err := OpenKVStore(BackendBolt, path, false)
+	require.NoError(t, err)
+	defer s.Close()
+
+	require.Equal(t, BackendBolt, s.Backend())
+	require.Equal(t, path, s.Path())
+	require.False(t, s.IsReadOnly())
+}
+
+func TestBoltKVStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kvstore")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	s, err := OpenKVStore(BackendBolt, filepath.Join(dir, "data.db"), false)
+	require.NoError(t, err)
+	defer s.Close()
+
+	testKVStore(t, s)
+}
+
+func BenchmarkBoltKVStorePut(b *testing.B) {
+	benchmarkKVStorePut(b, BackendBolt, "data.db")
+}
+
+// testKVStore checks the behavior that all the KVStore backends share
+func testKVStore(t *testing.T, s KVStore) {
+	bktName := []byte("bkt")
+	// A bucket whose name has bktName as prefix, its keys must not be mixed with the keys of bktName
+	otherName := []byte("bkt2")
+
+	err := s.View("", func(tx KVTx) error {
+		require.Nil(t, tx.Bucket(bktName))
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = s.Update("", func(tx KVTx) error {
+		b, err := tx.CreateBucketIfNotExists(bktName)
+		require.NoError(t, err)
+
+		for _, k := range []string{"b", "a", "c"} {
+			require.NoError(t, b.Put([]byte(k), []byte("v"+k)))
+		}
+
+		other, err := tx.CreateBucketIfNotExists(otherName)
+		require.NoError(t, err)
+		require.NoError(t, other.Put([]byte("0"), []byte("v0")))
+		require.NoError(t, other.Put([]byte("z"), []byte("vz")))
+
+		seq, err := b.NextSequence()
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), seq)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// A failed update is rolled back
+	err = s.Update("", func(tx KVTx) error {
+		require.NoError(t, tx.Bucket(bktName).Put([]byte("d"), []byte("vd")))
+		return errors.New("rollback")
+	})
+	require.Equal(t, errors.New("rollback"), err)
+
+	err = s.View("", func(tx KVTx) error {
+		var names []string
+		err := tx.ForEachBucket(func(name []byte) error {
+			names = append(names, string(name))
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"bkt", "bkt2"}, names)
+
+		b := tx.Bucket(bktName)
+		require.NotNil(t, b)
+		require.Equal(t, []byte("va"), b.Get([]byte("a")))
+		require.Nil(t, b.Get([]byte("d")))
+
+		// Keys are iterated in order
+		var keys []string
+		err = b.ForEach(func(k, v []byte) error {
+			keys = append(keys, string(k))
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"a", "b", "c"}, keys)
+
+		c := b.Cursor()
+		k, v := c.Last()
+		require.Equal(t, []byte("c"), k)
+		require.Equal(t, []byte("vc"), v)
+		k, _ = c.Prev()
+		require.Equal(t, []byte("b"), k)
+		k, _ = c.Seek([]byte("bb"))
+		require.Equal(t, []byte("c"), k)
+		k, _ = c.Next()
+		require.Nil(t, k)
+		k, v = c.First()
+		require.Equal(t, []byte("a"), k)
+		require.Equal(t, []byte("va"), v)
+		k, _ = c.Prev()
+		require.Nil(t, k)
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = s.Update("", func(tx KVTx) error {
+		require.NoError(t, tx.Bucket(bktName).Delete([]byte("a")))
+		require.Nil(t, tx.Bucket(bktName).Get([]byte("a")))
+		require.NoError(t, tx.DeleteBucket(bktName))
+		require.Nil(t, tx.Bucket(bktName))
+		require.Error(t, tx.DeleteBucket(bktName))
+
+		// The other bucket is not deleted
+		other := tx.Bucket(otherName)
+		require.NotNil(t, other)
+		require.Equal(t, []byte("v0"), other.Get([]byte("0")))
+
+		// The sequence of a deleted bucket starts over
+		b, err := tx.CreateBucketIfNotExists(bktName)
+		require.NoError(t, err)
+		require.Nil(t, b.Get([]byte("b")))
+		seq, err := b.NextSequence()
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), seq)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func benchmarkKVStorePut(b *testing.B, backend Backend, name string) {
+	dir, err := ioutil.TempDir("", "kvstore")
+	require.NoError(b, err)
+	defer os.RemoveAll(dir)
+
+	s, err := OpenKVStore(backend, filepath.Join(dir, name), false)
+	require.NoError(b, err)
+	defer s.Close()
+
+	v := make([]byte, 256)
+	b.ResetTimer()
+
+	// Write 100 keys per transaction, like a block with its transactions and outputs
+	for i := 0; i < b.N; i++ {
+		err := s.Update("", func(tx KVTx) error {
+			bkt, err := tx.CreateBucketIfNotExists([]byte("bkt"))
+			if err != nil {
+				return err
+			}
+
+			for j := 0; j < 100; j++ {
+				seq, err := bkt.NextSequence()
+				if err != nil {
+					return err
+				}
+				if err := bkt.Put(Itob(seq), v); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		require.NoError(b, err)
+	}
+}
@@ -0,0 +1,189 @@
+package dbutil
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher/encoder"
+)
+
+var (
+	// SchemaBkt stores the schema version of the database
+	SchemaBkt = []byte("db_schema")
+	// SchemaMigrationsBkt records the schema migrations applied to the database, keyed by version
+	SchemaMigrationsBkt = []byte("db_schema_migrations")
+
+	schemaVersionKey = []byte("version")
+
+	// ErrMigrationReadOnly is returned by Migrate if migrations need to be applied to a read-only database
+	ErrMigrationReadOnly = errors.New("Database schema needs to be migrated, but the database is opened read-only")
+)
+
+// Migration changes the database from schema version Version-1 to Version
+type Migration struct {
+	// Version is the schema version after the migration is applied
+	Version uint64
+	// Name describes the migration
+	Name string
+	// Migrate applies the migration. It is called in the same transaction that records the new schema version.
+	// If nil, the migration only records the schema version.
+	Migrate func(*Tx) error
+}
+
+// AppliedMigration records a migration applied to the database
+type AppliedMigration struct {
+	Version uint64
+	Name    string
+	// AppliedAt is the unix time the migration was applied
+	AppliedAt int64
+}
+
+// ErrSchemaTooNew is returned by Migrate if the database schema version is newer than the latest migration,
+// which means that the database was written by a newer version of the node
+type ErrSchemaTooNew struct {
+	Version uint64
+	Latest  uint64
+}
+
+// NewErrSchemaTooNew creates an ErrSchemaTooNew
+func NewErrSchemaTooNew(version, latest uint64) error {
+	return ErrSchemaTooNew{
+		Version: version,
+		Latest:  latest,
+	}
+}
+
+func (e ErrSchemaTooNew) Error() string {
+	return fmt.Sprintf("Database schema version %d is newer than the latest supported version %d. The database was created by a newer version of the node", e.Version, e.Latest)
+}
+
+// GetSchemaVersion returns the schema version of the database, 0 if it was never migrated
+func GetSchemaVersion(tx *Tx) (uint64, error) {
+	v, err := GetBucketValue(tx, SchemaBkt, schemaVersionKey)
+	if err != nil {
+		switch err.(type) {
+		case ErrBucketNotExist:
+			return 0, nil
+		default:
+			return 0, err
+		}
+	} else if v == nil {
+		return 0, nil
+	}
+
+	return Btoi(v), nil
+}
+
+// GetAppliedMigrations returns the migrations applied to the database, in order
+func GetAppliedMigrations(tx *Tx) ([]AppliedMigration, error) {
+	var applied []AppliedMigration
+	if err := ForEach(tx, SchemaMigrationsBkt, func(_, v []byte) error {
+		var m AppliedMigration
+		if err := encoder.DeserializeRaw(v, &m); err != nil {
+			return err
+		}
+		applied = append(applied, m)
+		return nil
+	}); err != nil {
+		switch err.(type) {
+		case ErrBucketNotExist:
+			return nil, nil
+		default:
+			return nil, err
+		}
+	}
+
+	return applied, nil
+}
+
+// Migrate applies the migrations newer than the schema version of the database, in order.
+// Each migration is applied in its own transaction, together with the update of the schema version,
+// so an interrupted migration run can be resumed.
+// migrations must be ordered by version, starting from version 1 with no gaps.
+// Returns the migrations that were applied.
+func Migrate(db *DB, migrations []Migration) ([]AppliedMigration, error) {
+	for i, m := range migrations {
+		if m.Version != uint64(i+1) {
+			return nil, fmt.Errorf("Migration %q has version %d, expected %d", m.Name, m.Version, i+1)
+		}
+	}
+
+	latest := uint64(len(migrations))
+
+	var version uint64
+	if err := db.View("Migrate", func(tx *Tx) error {
+		var err error
+		version, err = GetSchemaVersion(tx)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	if version > latest {
+		return nil, NewErrSchemaTooNew(version, latest)
+	}
+
+	if version == latest {
+		return nil, nil
+	}
+
+	if db.IsReadOnly() {
+		// Migrations that don't change any data can be skipped in a read-only database,
+		// the database is readable without them
+		for _, m := range migrations[version:] {
+			if m.Migrate != nil {
+				return nil, ErrMigrationReadOnly
+			}
+		}
+		return nil, nil
+	}
+
+	var applied []AppliedMigration
+	for _, m := range migrations[version:] {
+		logger.Infof("Applying database migration %d: %s", m.Version, m.Name)
+
+		a := AppliedMigration{
+			Version:   m.Version,
+			Name:      m.Name,
+			AppliedAt: time.Now().UTC().Unix(),
+		}
+
+		if err := db.Update("Migrate", func(tx *Tx) error {
+			return applyMigration(tx, m, a)
+		}); err != nil {
+			return applied, fmt.Errorf("Database migration %d %q failed: %v", m.Version, m.Name, err)
+		}
+
+		applied = append(applied, a)
+	}
+
+	return applied, nil
+}
+
+func applyMigration(tx *Tx, m Migration, a AppliedMigration) error {
+	if err := CreateBuckets(tx, [][]byte{SchemaBkt, SchemaMigrationsBkt}); err != nil {
+		return err
+	}
+
+	// Check the version again, in case another migration run got here first
+	version, err := GetSchemaVersion(tx)
+	if err != nil {
+		return err
+	}
+	if version != m.Version-1 {
+		return fmt.Errorf("Database schema version is %d, expected %d", version, m.Version-1)
+	}
+
+	if m.Migrate != nil {
+		if err := m.Migrate(tx); err != nil {
+			return err
+		}
+	}
+
+	if err := PutBucketValue(tx, SchemaMigrationsBkt, Itob(m.Version), encoder.Serialize(a)); err != nil {
+		return err
+	}
+
+	return PutBucketValue(tx, SchemaBkt, schemaVersionKey, Itob(m.Version))
+}
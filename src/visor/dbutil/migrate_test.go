@@ -0,0 +1,172 @@
+package dbutil
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/stretchr/testify/require"
+)
+
+func openTestDB(t *testing.T, path string, readOnly bool) *DB {
+	db, err := bolt.Open(path, 0600, &bolt.Options{
+		Timeout:  time.Second,
+		ReadOnly: readOnly,
+	})
+	require.NoError(t, err)
+	return WrapDB(db)
+}
+
+func schemaVersion(t *testing.T, db *DB) uint64 {
+	var v uint64
+	err := db.View("", func(tx *Tx) error {
+		var err error
+		v, err = GetSchemaVersion(tx)
+		return err
+	})
+	require.NoError(t, err)
+	return v
+}
+
+func TestMigrate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "migrate")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "data.db")
+	db := openTestDB(t, path, false)
+	defer db.Close()
+
+	require.Equal(t, uint64(0), schemaVersion(t, db))
+
+	bkt := []byte("bkt")
+	var calls []uint64
+	migrations := []Migration{
+		{
+			Version: 1,
+			Name:    "create bucket",
+			Migrate: func(tx *Tx) error {
+				calls = append(calls, 1)
+				return CreateBuckets(tx, [][]byte{bkt})
+			},
+		},
+		{
+			Version: 2,
+			Name:    "no-op",
+		},
+	}
+
+	applied, err := Migrate(db, migrations)
+	require.NoError(t, err)
+	require.Len(t, applied, 2)
+	require.Equal(t, uint64(1), applied[0].Version)
+	require.Equal(t, "create bucket", applied[0].Name)
+	require.Equal(t, uint64(2), applied[1].Version)
+	require.Equal(t, []uint64{1}, calls)
+	require.Equal(t, uint64(2), schemaVersion(t, db))
+
+	err = db.View("", func(tx *Tx) error {
+		require.True(t, Exists(tx, bkt))
+
+		records, err := GetAppliedMigrations(tx)
+		require.NoError(t, err)
+		require.Equal(t, applied, records)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// Applied migrations are not applied again
+	applied, err = Migrate(db, migrations)
+	require.NoError(t, err)
+	require.Empty(t, applied)
+	require.Equal(t, []uint64{1}, calls)
+
+	// A failed migration is rolled back, and the migrations before it are kept
+	migrations = append(migrations, Migration{
+		Version: 3,
+		Name:    "add key",
+		Migrate: func(tx *Tx) error {
+			return PutBucketValue(tx, bkt, []byte("k"), []byte("v"))
+		},
+	}, Migration{
+		Version: 4,
+		Name:    "fail",
+		Migrate: func(tx *Tx) error {
+			if err := Delete(tx, bkt, []byte("k")); err != nil {
+				return err
+			}
+			return errors.New("failed")
+		},
+	})
+
+	applied, err = Migrate(db, migrations)
+	require.Equal(t, errors.New(`Database migration 4 "fail" failed: failed`), err)
+	require.Len(t, applied, 1)
+	require.Equal(t, uint64(3), applied[0].Version)
+	require.Equal(t, uint64(3), schemaVersion(t, db))
+
+	err = db.View("", func(tx *Tx) error {
+		v, err := GetBucketValue(tx, bkt, []byte("k"))
+		require.NoError(t, err)
+		require.Equal(t, []byte("v"), v)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// A database with a newer schema is refused
+	_, err = Migrate(db, migrations[:2])
+	require.Equal(t, NewErrSchemaTooNew(3, 2), err)
+}
+
+func TestMigrateInvalidVersions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "migrate")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	db := openTestDB(t, filepath.Join(dir, "data.db"), false)
+	defer db.Close()
+
+	_, err = Migrate(db, []Migration{
+		{Version: 1, Name: "a"},
+		{Version: 3, Name: "b"},
+	})
+	require.Equal(t, errors.New(`Migration "b" has version 3, expected 2`), err)
+	require.Equal(t, uint64(0), schemaVersion(t, db))
+}
+
+func TestMigrateReadOnly(t *testing.T) {
+	dir, err := ioutil.TempDir("", "migrate")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "data.db")
+	db := openTestDB(t, path, false)
+	require.NoError(t, db.Close())
+
+	db = openTestDB(t, path, true)
+	defer db.Close()
+
+	// Migrations that don't change data are skipped
+	applied, err := Migrate(db, []Migration{
+		{Version: 1, Name: "no-op"},
+	})
+	require.NoError(t, err)
+	require.Empty(t, applied)
+	require.Equal(t, uint64(0), schemaVersion(t, db))
+
+	_, err = Migrate(db, []Migration{
+		{Version: 1, Name: "no-op"},
+		{
+			Version: 2,
+			Name:    "change",
+			Migrate: func(tx *Tx) error {
+				return nil
+			},
+		},
+	})
+	require.Equal(t, ErrMigrationReadOnly, err)
+}
@@ -0,0 +1,252 @@
+package dbutil
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/boltdb/bolt"
+)
+
+// DBPart is a bolt database file storing a subset of the buckets of a split DB
+type DBPart struct {
+	// Name of the part, used in logs and errors
+	Name string
+	// Buckets stored in the part file instead of the primary file
+	Buckets [][]byte
+	DB      *bolt.DB
+}
+
+// ErrPartialCommit is returned by DB.Update on a split DB if the primary file was committed
+// but the commit of a part file failed
+type ErrPartialCommit struct {
+	Part string
+	Err  error
+}
+
+func (e ErrPartialCommit) Error() string {
+	return fmt.Sprintf("Commit of the %s db part failed after the primary db was committed: %v", e.Part, e.Err)
+}
+
+// NewErrPartialCommit returns an ErrPartialCommit
+func NewErrPartialCommit(part string, err error) error {
+	return ErrPartialCommit{
+		Part: part,
+		Err:  err,
+	}
+}
+
+// WrapSplitDB returns a DB whose buckets are stored in several bolt files.
+// The buckets of each part are stored in the part file, the other buckets in db, the primary file.
+//
+// A View or Update transaction spans all files, and is committed in two phases: f runs with a write
+// transaction open on every file, and the transactions are only committed if f succeeds on all of them.
+// The primary file is committed first, then the part files. bolt can't prepare a transaction without
+// committing it, so if the process stops between the commits, the part files miss the changes of the
+// last transaction. The part files must only store data that can be rebuilt from the primary file.
+func WrapSplitDB(db *bolt.DB, parts []DBPart) (*DB, error) {
+	partIndex := make(map[string]int)
+	for i, p := range parts {
+		for _, b := range p.Buckets {
+			if _, ok := partIndex[string(b)]; ok {
+				return nil, fmt.Errorf("Bucket \"%s\" is in more than one db part", b)
+			}
+			partIndex[string(b)] = i
+		}
+	}
+
+	wdb := WrapDB(db)
+	wdb.parts = parts
+	wdb.partIndex = partIndex
+	return wdb, nil
+}
+
+// IsSplit returns true if the DB is stored in several files, see WrapSplitDB
+func (db *DB) IsSplit() bool {
+	return len(db.parts) != 0
+}
+
+// Parts returns the part files of a split DB
+func (db *DB) Parts() []DBPart {
+	return db.parts
+}
+
+func (db *DB) view(ctx context.Context, f func(*Tx) error) error {
+	if !db.IsSplit() {
+		return db.DB.View(func(tx *bolt.Tx) error {
+			return f(&Tx{
+				Tx:         tx,
+				ctx:        ctx,
+				encryption: db.encryption,
+				keyMAC:     db.keyMAC,
+			})
+		})
+	}
+
+	tx, err := db.begin(ctx, false)
+	if err != nil {
+		return err
+	}
+	defer tx.rollback()
+
+	return f(tx)
+}
+
+func (db *DB) update(ctx context.Context, f func(*Tx) error) error {
+	if !db.IsSplit() {
+		return db.DB.Update(func(tx *bolt.Tx) error {
+			return f(&Tx{
+				Tx:         tx,
+				ctx:        ctx,
+				encryption: db.encryption,
+				keyMAC:     db.keyMAC,
+			})
+		})
+	}
+
+	tx, err := db.begin(ctx, true)
+	if err != nil {
+		return err
+	}
+	defer tx.rollback()
+
+	if err := f(tx); err != nil {
+		return err
+	}
+
+	return tx.commit()
+}
+
+// begin starts a transaction on every file of a split DB.
+// The files are always locked in the same order, so that concurrent write transactions can't deadlock.
+func (db *DB) begin(ctx context.Context, writable bool) (*Tx, error) {
+	btx, err := db.DB.Begin(writable)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := &Tx{
+		Tx:         btx,
+		ctx:        ctx,
+		db:         db,
+		parts:      make([]*bolt.Tx, 0, len(db.parts)),
+		encryption: db.encryption,
+		keyMAC:     db.keyMAC,
+	}
+
+	for _, p := range db.parts {
+		ptx, err := p.DB.Begin(writable)
+		if err != nil {
+			tx.rollback()
+			return nil, fmt.Errorf("Begin transaction on the %s db part failed: %v", p.Name, err)
+		}
+		tx.parts = append(tx.parts, ptx)
+	}
+
+	return tx, nil
+}
+
+// rollback rolls back the transactions of a split DB that were not committed
+func (tx *Tx) rollback() {
+	for _, btx := range append([]*bolt.Tx{tx.Tx}, tx.parts...) {
+		if err := btx.Rollback(); err != nil && err != bolt.ErrTxClosed {
+			logger.WithError(err).Error("Rollback failed")
+		}
+	}
+}
+
+// commit commits the primary file of a split DB, then the part files.
+// The commit of the primary file is the commit point of the transaction.
+func (tx *Tx) commit() error {
+	if err := tx.Tx.Commit(); err != nil {
+		return err
+	}
+
+	var partErr error
+	for i, ptx := range tx.parts {
+		if err := ptx.Commit(); err != nil {
+			name := tx.db.parts[i].Name
+			logger.WithError(err).Errorf("Commit of the %s db part failed", name)
+			if partErr == nil {
+				partErr = NewErrPartialCommit(name, err)
+			}
+		}
+	}
+
+	return partErr
+}
+
+// boltTx returns the transaction of the file storing a bucket
+func (tx *Tx) boltTx(name []byte) *bolt.Tx {
+	if i, ok := tx.partIndex()[string(name)]; ok {
+		return tx.parts[i]
+	}
+	return tx.Tx
+}
+
+func (tx *Tx) partIndex() map[string]int {
+	if tx.db == nil {
+		return nil
+	}
+	return tx.db.partIndex
+}
+
+// PartTx returns the transaction of a part file of a split DB, or nil if there is no such part
+func (tx *Tx) PartTx(name string) *bolt.Tx {
+	if tx.db == nil {
+		return nil
+	}
+
+	for i, p := range tx.db.parts {
+		if p.Name == name {
+			return tx.parts[i]
+		}
+	}
+
+	return nil
+}
+
+// Bucket wraps bolt.Tx.Bucket, using the file storing the bucket
+func (tx *Tx) Bucket(name []byte) *bolt.Bucket {
+	return tx.boltTx(name).Bucket(name)
+}
+
+// CreateBucket wraps bolt.Tx.CreateBucket, using the file storing the bucket
+func (tx *Tx) CreateBucket(name []byte) (*bolt.Bucket, error) {
+	return tx.boltTx(name).CreateBucket(name)
+}
+
+// CreateBucketIfNotExists wraps bolt.Tx.CreateBucketIfNotExists, using the file storing the bucket
+func (tx *Tx) CreateBucketIfNotExists(name []byte) (*bolt.Bucket, error) {
+	return tx.boltTx(name).CreateBucketIfNotExists(name)
+}
+
+// DeleteBucket wraps bolt.Tx.DeleteBucket, using the file storing the bucket
+func (tx *Tx) DeleteBucket(name []byte) error {
+	return tx.boltTx(name).DeleteBucket(name)
+}
+
+// ForEach wraps bolt.Tx.ForEach, iterating over the buckets of all files of a split DB
+func (tx *Tx) ForEach(f func([]byte, *bolt.Bucket) error) error {
+	partIndex := tx.partIndex()
+	if err := tx.Tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+		if _, ok := partIndex[string(name)]; ok {
+			return nil
+		}
+		return f(name, b)
+	}); err != nil {
+		return err
+	}
+
+	for i, ptx := range tx.parts {
+		if err := ptx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			if j, ok := partIndex[string(name)]; !ok || j != i {
+				return nil
+			}
+			return f(name, b)
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package dbutil
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitDB(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dbutil")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	primary := openTestDB(t, filepath.Join(dir, "data.db"), false)
+	part := openTestDB(t, filepath.Join(dir, "data.db.part"), false)
+
+	primaryBkt := []byte("primary")
+	partBkt := []byte("part")
+
+	db, err := WrapSplitDB(primary.DB, []DBPart{
+		{
+			Name:    "part",
+			Buckets: [][]byte{partBkt},
+			DB:      part.DB,
+		},
+	})
+	require.NoError(t, err)
+	defer db.Close()
+	require.True(t, db.IsSplit())
+
+	// The buckets are created in the file storing them
+	err = db.Update("", func(tx *Tx) error {
+		require.NoError(t, CreateBuckets(tx, [][]byte{primaryBkt, partBkt}))
+		require.NoError(t, PutBucketValue(tx, primaryBkt, []byte("a"), []byte("1")))
+		return PutBucketValue(tx, partBkt, []byte("b"), []byte("2"))
+	})
+	require.NoError(t, err)
+
+	err = db.View("", func(tx *Tx) error {
+		require.NotNil(t, tx.Tx.Bucket(primaryBkt))
+		require.Nil(t, tx.Tx.Bucket(partBkt))
+		require.NotNil(t, tx.PartTx("part").Bucket(partBkt))
+		require.Nil(t, tx.PartTx("part").Bucket(primaryBkt))
+		require.Nil(t, tx.PartTx("other"))
+
+		var names []string
+		require.NoError(t, tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+			names = append(names, string(name))
+			return nil
+		}))
+		require.Equal(t, []string{"primary", "part"}, names)
+
+		v, err := GetBucketValue(tx, partBkt, []byte("b"))
+		require.NoError(t, err)
+		require.Equal(t, []byte("2"), v)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// A failed transaction is rolled back in all files
+	err = db.Update("", func(tx *Tx) error {
+		require.NoError(t, PutBucketValue(tx, primaryBkt, []byte("c"), []byte("3")))
+		require.NoError(t, PutBucketValue(tx, partBkt, []byte("c"), []byte("3")))
+		return errors.New("failed")
+	})
+	require.EqualError(t, err, "failed")
+
+	err = db.View("", func(tx *Tx) error {
+		for _, bkt := range [][]byte{primaryBkt, partBkt} {
+			ok, err := BucketHasKey(tx, bkt, []byte("c"))
+			require.NoError(t, err)
+			require.False(t, ok)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	// A bucket can't be in several parts
+	_, err = WrapSplitDB(primary.DB, []DBPart{
+		{Name: "a", Buckets: [][]byte{partBkt}},
+		{Name: "b", Buckets: [][]byte{partBkt}},
+	})
+	require.EqualError(t, err, `Bucket "part" is in more than one db part`)
+}
@@ -0,0 +1,108 @@
+package dbutil
+
+import (
+	"sort"
+
+	"github.com/boltdb/bolt"
+)
+
+// BucketStats are the key count, size and page utilization of a top level bucket, see bolt.BucketStats
+type BucketStats struct {
+	Name string
+	// Part is the name of the part file storing the bucket of a split DB, empty for the primary file
+	Part string
+	bolt.BucketStats
+}
+
+// AllocBytes returns the number of bytes allocated for the pages of the bucket
+func (s BucketStats) AllocBytes() int {
+	return s.BranchAlloc + s.LeafAlloc
+}
+
+// InuseBytes returns the number of bytes used by the data of the bucket
+func (s BucketStats) InuseBytes() int {
+	return s.BranchInuse + s.LeafInuse
+}
+
+// Utilization returns the fraction of the allocated bytes in use, 0 if no bytes are allocated.
+// Inline buckets don't allocate pages.
+func (s BucketStats) Utilization() float64 {
+	if s.AllocBytes() == 0 {
+		return 0
+	}
+	return float64(s.InuseBytes()) / float64(s.AllocBytes())
+}
+
+// FileStats are the size and free space of a file of the DB
+type FileStats struct {
+	// Part is the name of the part file of a split DB, empty for the primary file
+	Part     string
+	Size     int64
+	PageSize int
+	// FreePages are the pages that are free to be reused
+	FreePages int
+	// FreeBytes are the bytes of the free pages, which compaction reclaims
+	FreeBytes int
+}
+
+// Stats are the per bucket statistics of a DB
+type Stats struct {
+	Files []FileStats
+	// Buckets are sorted by allocated bytes, largest first, then by name
+	Buckets []BucketStats
+}
+
+// GetStats collects the statistics of the files and top level buckets of the DB.
+// Every page of the DB is visited, so this takes a while on a large DB.
+func GetStats(tx *Tx) (*Stats, error) {
+	stats := &Stats{
+		Files: []FileStats{
+			newFileStats("", tx.Tx),
+		},
+	}
+
+	var partNames []string
+	if tx.db != nil {
+		for i, p := range tx.db.parts {
+			partNames = append(partNames, p.Name)
+			stats.Files = append(stats.Files, newFileStats(p.Name, tx.parts[i]))
+		}
+	}
+
+	partIndex := tx.partIndex()
+	if err := tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+		s := BucketStats{
+			Name:        string(name),
+			BucketStats: b.Stats(),
+		}
+		if i, ok := partIndex[string(name)]; ok {
+			s.Part = partNames[i]
+		}
+
+		stats.Buckets = append(stats.Buckets, s)
+		return tx.Context().Err()
+	}); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(stats.Buckets, func(i, j int) bool {
+		a, b := stats.Buckets[i], stats.Buckets[j]
+		if a.AllocBytes() != b.AllocBytes() {
+			return a.AllocBytes() > b.AllocBytes()
+		}
+		return a.Name < b.Name
+	})
+
+	return stats, nil
+}
+
+func newFileStats(part string, tx *bolt.Tx) FileStats {
+	dbStats := tx.DB().Stats()
+	return FileStats{
+		Part:      part,
+		Size:      tx.Size(),
+		PageSize:  tx.DB().Info().PageSize,
+		FreePages: dbStats.FreePageN,
+		FreeBytes: dbStats.FreeAlloc,
+	}
+}
@@ -0,0 +1,83 @@
+package dbutil
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetStats(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dbutil")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	primary := openTestDB(t, filepath.Join(dir, "data.db"), false)
+	part := openTestDB(t, filepath.Join(dir, "data.db.part"), false)
+
+	smallBkt := []byte("small")
+	largeBkt := []byte("large")
+	emptyBkt := []byte("empty")
+	partBkt := []byte("part")
+
+	db, err := WrapSplitDB(primary.DB, []DBPart{
+		{
+			Name:    "part",
+			Buckets: [][]byte{partBkt},
+			DB:      part.DB,
+		},
+	})
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Update("", func(tx *Tx) error {
+		require.NoError(t, CreateBuckets(tx, [][]byte{smallBkt, largeBkt, emptyBkt, partBkt}))
+		require.NoError(t, PutBucketValue(tx, smallBkt, []byte("a"), []byte("1")))
+		require.NoError(t, PutBucketValue(tx, partBkt, []byte("b"), []byte("2")))
+		for i := 0; i < 1000; i++ {
+			require.NoError(t, PutBucketValue(tx, largeBkt, []byte(fmt.Sprintf("%04d", i)), make([]byte, 100)))
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = db.View("", func(tx *Tx) error {
+		stats, err := GetStats(tx)
+		require.NoError(t, err)
+
+		require.Len(t, stats.Files, 2)
+		require.Equal(t, "", stats.Files[0].Part)
+		require.Equal(t, "part", stats.Files[1].Part)
+		for _, f := range stats.Files {
+			require.NotZero(t, f.Size)
+			require.NotZero(t, f.PageSize)
+		}
+
+		var names, parts []string
+		for _, b := range stats.Buckets {
+			names = append(names, b.Name)
+			parts = append(parts, b.Part)
+		}
+
+		// The large bucket allocates pages, the small buckets are inlined in their parent page
+		require.Equal(t, []string{"large", "empty", "part", "small"}, names)
+		require.Equal(t, []string{"", "", "part", ""}, parts)
+
+		large := stats.Buckets[0]
+		require.Equal(t, 1000, large.KeyN)
+		require.NotZero(t, large.AllocBytes())
+		require.True(t, large.InuseBytes() <= large.AllocBytes())
+		require.True(t, large.Utilization() > 0 && large.Utilization() <= 1)
+
+		small := stats.Buckets[3]
+		require.Equal(t, 1, small.KeyN)
+		require.Equal(t, 1, small.InlineBucketN)
+		require.Zero(t, small.AllocBytes())
+		require.Zero(t, small.Utilization())
+		return nil
+	})
+	require.NoError(t, err)
+}
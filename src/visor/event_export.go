@@ -0,0 +1,315 @@
+package visor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/blockdb"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/export"
+)
+
+// EventExportBlocksBkt is the export cursor of the event export sinks, the recent blocks exported to each sink.
+// Sink name and block seq as key, block hash as value.
+// The blocks within MaxReorgDepth of the last exported block are kept, to detect the exported blocks that
+// were removed from the blockchain.
+var EventExportBlocksBkt = []byte("event_export_blocks")
+
+const (
+	// DefaultEventExportInterval is the default interval between the exports of the new blocks
+	DefaultEventExportInterval = 10 * time.Second
+
+	// eventExportBatchSize is the maximum number of blocks read in one database transaction by the event export
+	eventExportBatchSize = 100
+)
+
+// exportedBlock is a block recorded in the export cursor
+type exportedBlock struct {
+	seq  uint64
+	hash cipher.SHA256
+}
+
+// EventExporter streams the events of the blocks parsed into the history db to an export.Sink,
+// resuming after the last block exported to the sink, see EventExportBlocksBkt.
+// The blocks are exported once they are parsed, so the export is behind the blockchain while the history
+// db is rebuilt. The exported blocks removed from the blockchain are reported with export.EventBlockReverted.
+type EventExporter struct {
+	db       *dbutil.DB
+	bc       Blockchainer
+	history  Historyer
+	sink     export.Sink
+	interval time.Duration
+}
+
+// NewEventExporter creates an EventExporter exporting the new blocks to sink every interval
+func NewEventExporter(db *dbutil.DB, bc Blockchainer, history Historyer, sink export.Sink, interval time.Duration) *EventExporter {
+	return &EventExporter{
+		db:       db,
+		bc:       bc,
+		history:  history,
+		sink:     sink,
+		interval: interval,
+	}
+}
+
+// Run exports the new blocks every interval until ctx is done, then closes the sink.
+// Export errors are logged, the export is attempted again at the next interval.
+func (e *EventExporter) Run(ctx context.Context) {
+	logger.Infof("Exporting the block events to %s every %s", e.sink.Name(), e.interval)
+
+	defer func() {
+		if err := e.sink.Close(); err != nil {
+			logger.WithError(err).Errorf("Failed to close the event export sink %s", e.sink.Name())
+		}
+	}()
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := e.Export(ctx); err != nil && err != ctx.Err() {
+			logger.WithError(err).Error("EventExporter.Export failed")
+		} else if n != 0 {
+			logger.Debugf("Exported the events of %d blocks to %s", n, e.sink.Name())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Export exports the events of the parsed blocks that were not exported yet, until ctx is done.
+// Returns the number of blocks exported.
+func (e *EventExporter) Export(ctx context.Context) (int, error) {
+	var total int
+	for {
+		n, err := e.exportBatch(ctx)
+		total += n
+		if err != nil || n == 0 {
+			return total, err
+		}
+	}
+}
+
+// eventExportKey returns the key of a block exported to a sink in EventExportBlocksBkt
+func eventExportKey(name string, seq uint64) []byte {
+	return append(eventExportPrefix(name), dbutil.Itob(seq)...)
+}
+
+// eventExportPrefix returns the key prefix of the blocks exported to a sink in EventExportBlocksBkt
+func eventExportPrefix(name string) []byte {
+	return append([]byte(name), 0)
+}
+
+// exportedBlocks returns the blocks recorded in the export cursor of the sink, in seq order
+func (e *EventExporter) exportedBlocks(tx *dbutil.Tx) ([]exportedBlock, error) {
+	if !dbutil.Exists(tx, EventExportBlocksBkt) {
+		return nil, nil
+	}
+
+	prefix := eventExportPrefix(e.sink.Name())
+
+	var blocks []exportedBlock
+	if err := dbutil.ForEachPrefix(tx, EventExportBlocksBkt, prefix, prefix, func(k, v []byte) (bool, error) {
+		hash, err := cipher.SHA256FromBytes(v)
+		if err != nil {
+			return false, err
+		}
+
+		blocks = append(blocks, exportedBlock{
+			seq:  dbutil.Btoi(k[len(prefix):]),
+			hash: hash,
+		})
+		return true, nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return blocks, nil
+}
+
+// exportBatch reports the exported blocks removed from the blockchain, then exports the events of up to
+// eventExportBatchSize parsed blocks. Returns the number of blocks exported.
+func (e *EventExporter) exportBatch(ctx context.Context) (int, error) {
+	var reverted []exportedBlock
+	var blocks []exportedBlock
+	var events [][]export.Event
+
+	if err := e.db.ViewContext(ctx, "EventExporter.exportBatch", func(tx *dbutil.Tx) error {
+		exported, err := e.exportedBlocks(tx)
+		if err != nil {
+			return err
+		}
+
+		// The exported blocks that are not in the blockchain anymore were removed by a reorganization
+		for i := len(exported) - 1; i >= 0; i-- {
+			b, err := e.bc.GetSignedBlockBySeq(tx, exported[i].seq)
+			if err != nil {
+				return err
+			}
+			if b != nil && b.HashHeader() == exported[i].hash {
+				break
+			}
+			reverted = append(reverted, exported[i])
+		}
+
+		if len(exported) != 0 && len(reverted) == len(exported) {
+			return fmt.Errorf("None of the last %d blocks exported to %s is in the blockchain", len(exported), e.sink.Name())
+		}
+
+		var next uint64
+		if n := len(exported) - len(reverted); n != 0 {
+			next = exported[n-1].seq + 1
+		}
+
+		parsedSeq, ok, err := e.history.ParsedBlockSeq(tx)
+		if err != nil || !ok {
+			return err
+		}
+
+		for seq := next; seq <= parsedSeq && len(blocks) < eventExportBatchSize; seq++ {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			pruned, err := e.bc.IsPruned(tx, seq)
+			if err != nil {
+				return err
+			}
+			if pruned {
+				return blockdb.NewErrBlockPruned(seq)
+			}
+
+			b, err := e.bc.GetSignedBlockBySeq(tx, seq)
+			if err != nil {
+				return err
+			}
+			if b == nil {
+				return NewErrBlockNotExist(seq)
+			}
+
+			inputs := make([]coin.UxArray, len(b.Body.Transactions))
+			for i, txn := range b.Body.Transactions {
+				uxs, err := e.history.GetUxOuts(tx, txn.In)
+				if err != nil {
+					return err
+				}
+
+				inputs[i] = make(coin.UxArray, len(uxs))
+				for j, ux := range uxs {
+					inputs[i][j] = ux.Out
+				}
+			}
+
+			blocks = append(blocks, exportedBlock{
+				seq:  seq,
+				hash: b.HashHeader(),
+			})
+			events = append(events, export.BlockEvents(b.Block, inputs))
+		}
+
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	// The sink is written outside of the database transaction, since it can be slow
+	if len(reverted) != 0 {
+		revertedEvents := make([]export.Event, len(reverted))
+		for i, b := range reverted {
+			revertedEvents[i] = export.BlockRevertedEvent(b.seq, b.hash)
+		}
+
+		if err := e.sink.Write(revertedEvents); err != nil {
+			return 0, err
+		}
+
+		if err := e.db.Update("EventExporter.exportBatch reverted", func(tx *dbutil.Tx) error {
+			for _, b := range reverted {
+				if err := dbutil.Delete(tx, EventExportBlocksBkt, eventExportKey(e.sink.Name(), b.seq)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}); err != nil {
+			return 0, err
+		}
+	}
+
+	var written int
+	var writeErr error
+	for i := range blocks {
+		if writeErr = e.sink.Write(events[i]); writeErr != nil {
+			break
+		}
+		written++
+	}
+
+	if written == 0 {
+		return 0, writeErr
+	}
+
+	if err := e.db.Update("EventExporter.exportBatch", func(tx *dbutil.Tx) error {
+		return e.recordExportedBlocks(tx, blocks[:written])
+	}); err != nil {
+		return 0, err
+	}
+
+	return written, writeErr
+}
+
+// recordExportedBlocks adds blocks to the export cursor of the sink, removing the blocks older than
+// MaxReorgDepth blocks before the last block
+func (e *EventExporter) recordExportedBlocks(tx *dbutil.Tx, blocks []exportedBlock) error {
+	if _, err := tx.CreateBucketIfNotExists(EventExportBlocksBkt); err != nil {
+		return dbutil.NewErrCreateBucketFailed(EventExportBlocksBkt, err)
+	}
+
+	name := e.sink.Name()
+	for _, b := range blocks {
+		if err := dbutil.PutBucketValue(tx, EventExportBlocksBkt, eventExportKey(name, b.seq), b.hash[:]); err != nil {
+			return err
+		}
+	}
+
+	lastSeq := blocks[len(blocks)-1].seq
+	if lastSeq <= MaxReorgDepth {
+		return nil
+	}
+
+	prefix := eventExportPrefix(name)
+	var old [][]byte
+	if err := dbutil.ForEachPrefix(tx, EventExportBlocksBkt, prefix, prefix, func(k, _ []byte) (bool, error) {
+		if dbutil.Btoi(k[len(k)-8:]) >= lastSeq-MaxReorgDepth {
+			return false, nil
+		}
+		old = append(old, k)
+		return true, nil
+	}); err != nil {
+		return err
+	}
+
+	for _, k := range old {
+		if err := dbutil.Delete(tx, EventExportBlocksBkt, k); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RunEventExport exports the block events to Config.EventExportSink every Config.EventExportInterval if the export
+// is enabled, blocking until ctx is done
+func (vs *Visor) RunEventExport(ctx context.Context) {
+	if vs.eventExporter == nil {
+		return
+	}
+
+	vs.eventExporter.Run(ctx)
+}
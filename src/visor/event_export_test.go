@@ -0,0 +1,232 @@
+package visor
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/export"
+	"github.com/skycoin/skycoin/src/visor/historydb"
+)
+
+// memorySink records the events written to it, failing the writes after failAfter writes if failAfter is not 0
+type memorySink struct {
+	blocks    [][]export.Event
+	failAfter int
+}
+
+func (s *memorySink) Name() string {
+	return "memory"
+}
+
+func (s *memorySink) Write(events []export.Event) error {
+	if s.failAfter != 0 && len(s.blocks) >= s.failAfter {
+		return errors.New("sink write failed")
+	}
+	s.blocks = append(s.blocks, events)
+	return nil
+}
+
+func (s *memorySink) Close() error {
+	return nil
+}
+
+// writtenSeqs returns the block seqs and types of the block and block_reverted events written
+func (s *memorySink) writtenSeqs() ([]uint64, []export.EventType) {
+	var seqs []uint64
+	var types []export.EventType
+	for _, events := range s.blocks {
+		for _, e := range events {
+			if e.Type == export.EventBlock || e.Type == export.EventBlockReverted {
+				seqs = append(seqs, e.BlockSeq)
+				types = append(types, e.Type)
+			}
+		}
+	}
+	return seqs, types
+}
+
+func seqRange(start, end uint64) []uint64 {
+	var seqs []uint64
+	for i := start; i <= end; i++ {
+		seqs = append(seqs, i)
+	}
+	return seqs
+}
+
+func TestEventExporter(t *testing.T) {
+	db, shutdown := copyTestDB(t, "./testdata/data.db.ok")
+	defer shutdown()
+
+	bc, err := NewBlockchain(db, BlockchainConfig{Pubkey: mustParsePubkey(t)})
+	require.NoError(t, err)
+
+	history := historydb.New()
+
+	// The write of block 4 fails, the export stops after block 3
+	sink := &memorySink{failAfter: 4}
+	e := NewEventExporter(db, bc, history, sink, DefaultEventExportInterval)
+
+	n, err := e.Export(context.Background())
+	require.Error(t, err)
+	require.Equal(t, 4, n)
+
+	seqs, _ := sink.writtenSeqs()
+	require.Equal(t, seqRange(0, 3), seqs)
+
+	// The export resumes after the last exported block, up to the head block
+	sink.failAfter = 0
+	n, err = e.Export(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 7, n)
+
+	seqs, _ = sink.writtenSeqs()
+	require.Equal(t, seqRange(0, 10), seqs)
+
+	// The events of each block are written in one call
+	for i, events := range sink.blocks {
+		require.Equal(t, export.EventBlock, events[0].Type)
+		for _, e := range events {
+			require.Equal(t, uint64(i), e.BlockSeq)
+		}
+	}
+
+	// The outputs spent by the transactions are reported
+	var spent int
+	for _, events := range sink.blocks[1:] {
+		for _, e := range events {
+			if e.Type == export.EventOutputSpent {
+				require.NotEmpty(t, e.Address)
+				require.NotZero(t, e.Coins)
+				spent++
+			}
+		}
+	}
+	require.NotZero(t, spent)
+
+	// Nothing is exported when there is no new block
+	n, err = e.Export(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+	require.Len(t, sink.blocks, 11)
+
+	// The export cursor is kept in the database
+	e = NewEventExporter(db, bc, history, sink, DefaultEventExportInterval)
+	n, err = e.Export(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+
+	// The exported blocks that are not in the blockchain anymore are reverted, then exported again
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		for _, seq := range []uint64{9, 10} {
+			h := testutil.RandSHA256(t)
+			if err := dbutil.PutBucketValue(tx, EventExportBlocksBkt, eventExportKey(sink.Name(), seq), h[:]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	sink.blocks = nil
+	n, err = e.Export(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	seqs, types := sink.writtenSeqs()
+	require.Equal(t, []uint64{10, 9, 9, 10}, seqs)
+	require.Equal(t, []export.EventType{
+		export.EventBlockReverted,
+		export.EventBlockReverted,
+		export.EventBlock,
+		export.EventBlock,
+	}, types)
+
+	// The export stops if none of the exported blocks is in the blockchain
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		var keys [][]byte
+		if err := dbutil.ForEach(tx, EventExportBlocksBkt, func(k, _ []byte) error {
+			keys = append(keys, append([]byte{}, k...))
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		for _, k := range keys {
+			h := testutil.RandSHA256(t)
+			if err := dbutil.PutBucketValue(tx, EventExportBlocksBkt, k, h[:]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	_, err = e.Export(context.Background())
+	require.Error(t, err)
+
+	// Each sink has its own export cursor
+	other := &namedMemorySink{name: "other"}
+	e = NewEventExporter(db, bc, history, other, DefaultEventExportInterval)
+	n, err = e.Export(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 11, n)
+}
+
+type namedMemorySink struct {
+	memorySink
+	name string
+}
+
+func (s *namedMemorySink) Name() string {
+	return s.name
+}
+
+func TestEventExporterRecordExportedBlocks(t *testing.T) {
+	db, shutdown := prepareDB(t)
+	defer shutdown()
+
+	e := NewEventExporter(db, nil, nil, &memorySink{}, DefaultEventExportInterval)
+
+	record := func(start, end uint64) {
+		var blocks []exportedBlock
+		for seq := start; seq <= end; seq++ {
+			blocks = append(blocks, exportedBlock{
+				seq:  seq,
+				hash: testutil.RandSHA256(t),
+			})
+		}
+
+		err := db.Update("", func(tx *dbutil.Tx) error {
+			return e.recordExportedBlocks(tx, blocks)
+		})
+		require.NoError(t, err)
+	}
+
+	exported := func() []uint64 {
+		var seqs []uint64
+		err := db.View("", func(tx *dbutil.Tx) error {
+			blocks, err := e.exportedBlocks(tx)
+			for _, b := range blocks {
+				seqs = append(seqs, b.seq)
+			}
+			return err
+		})
+		require.NoError(t, err)
+		return seqs
+	}
+
+	record(0, 50)
+	require.Equal(t, seqRange(0, 50), exported())
+
+	// The blocks older than MaxReorgDepth blocks before the last exported block are removed
+	record(51, 150)
+	require.Equal(t, seqRange(150-MaxReorgDepth, 150), exported())
+
+	record(151, 151)
+	require.Equal(t, seqRange(151-MaxReorgDepth, 151), exported())
+}
@@ -0,0 +1,143 @@
+/*
+Package export streams the events of the parsed blocks to external analytic stores.
+
+The events of each block are written to a Sink in one call, in block order. A JSON lines file sink and
+a database/sql sink for PostgreSQL are provided, other stores such as Kafka are supported by implementing Sink.
+*/
+package export
+
+import (
+	"sort"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+)
+
+// EventType is the type of an exported event
+type EventType string
+
+const (
+	// EventBlock is a block added to the blockchain
+	EventBlock EventType = "block"
+	// EventBlockReverted is a block exported before that was removed from the blockchain by a reorganization.
+	// The events of the block must be discarded by the consumers.
+	EventBlockReverted EventType = "block_reverted"
+	// EventTransaction is a transaction of a block
+	EventTransaction EventType = "transaction"
+	// EventOutputCreated is an output created by a transaction
+	EventOutputCreated EventType = "output_created"
+	// EventOutputSpent is an output spent by a transaction
+	EventOutputSpent EventType = "output_spent"
+	// EventAddressDelta is the coins received and sent by an address in a block
+	EventAddressDelta EventType = "address_delta"
+)
+
+// Event is an exported event. The fields that don't apply to the event type are empty.
+type Event struct {
+	Type      EventType `json:"type"`
+	BlockSeq  uint64    `json:"block_seq"`
+	BlockTime uint64    `json:"block_time"`
+	BlockHash string    `json:"block_hash"`
+	// Txid is the transaction of a transaction, output_created or output_spent event
+	Txid string `json:"txid,omitempty"`
+	// UxID is the output of an output_created or output_spent event
+	UxID string `json:"uxid,omitempty"`
+	// Address is the address of an output_created, output_spent or address_delta event
+	Address string `json:"address,omitempty"`
+	// Coins and Hours are the droplets and the coin hours of the output of an output_created or output_spent event,
+	// the hours being the hours of the output when it was created
+	Coins uint64 `json:"coins,omitempty"`
+	Hours uint64 `json:"hours,omitempty"`
+	// Received and Sent are the droplets received and sent by the address of an address_delta event in the block
+	Received uint64 `json:"received,omitempty"`
+	Sent     uint64 `json:"sent,omitempty"`
+}
+
+// BlockEvents returns the events of a block, inputs being the outputs spent by each transaction of the block.
+// The block event comes first, then the events of each transaction in block order, then the address deltas
+// in address order.
+func BlockEvents(b coin.Block, inputs []coin.UxArray) []Event {
+	blockHash := b.HashHeader().Hex()
+	newEvent := func(t EventType) Event {
+		return Event{
+			Type:      t,
+			BlockSeq:  b.Seq(),
+			BlockTime: b.Time(),
+			BlockHash: blockHash,
+		}
+	}
+
+	events := []Event{newEvent(EventBlock)}
+
+	type delta struct {
+		received uint64
+		sent     uint64
+	}
+	deltas := make(map[cipher.Address]*delta)
+	addrDelta := func(addr cipher.Address) *delta {
+		d, ok := deltas[addr]
+		if !ok {
+			d = &delta{}
+			deltas[addr] = d
+		}
+		return d
+	}
+
+	for i, txn := range b.Body.Transactions {
+		txid := txn.Hash().Hex()
+
+		e := newEvent(EventTransaction)
+		e.Txid = txid
+		events = append(events, e)
+
+		if i < len(inputs) {
+			for _, ux := range inputs[i] {
+				e := newEvent(EventOutputSpent)
+				e.Txid = txid
+				e.UxID = ux.Hash().Hex()
+				e.Address = ux.Body.Address.String()
+				e.Coins = ux.Body.Coins
+				e.Hours = ux.Body.Hours
+				events = append(events, e)
+
+				addrDelta(ux.Body.Address).sent += ux.Body.Coins
+			}
+		}
+
+		for _, ux := range coin.CreateUnspents(b.Head, txn) {
+			e := newEvent(EventOutputCreated)
+			e.Txid = txid
+			e.UxID = ux.Hash().Hex()
+			e.Address = ux.Body.Address.String()
+			e.Coins = ux.Body.Coins
+			e.Hours = ux.Body.Hours
+			events = append(events, e)
+
+			addrDelta(ux.Body.Address).received += ux.Body.Coins
+		}
+	}
+
+	addrDeltas := make([]Event, 0, len(deltas))
+	for addr, d := range deltas {
+		e := newEvent(EventAddressDelta)
+		e.Address = addr.String()
+		e.Received = d.received
+		e.Sent = d.sent
+		addrDeltas = append(addrDeltas, e)
+	}
+
+	sort.Slice(addrDeltas, func(i, j int) bool {
+		return addrDeltas[i].Address < addrDeltas[j].Address
+	})
+
+	return append(events, addrDeltas...)
+}
+
+// BlockRevertedEvent returns the event of an exported block removed from the blockchain
+func BlockRevertedEvent(seq uint64, hash cipher.SHA256) Event {
+	return Event{
+		Type:      EventBlockReverted,
+		BlockSeq:  seq,
+		BlockHash: hash.Hex(),
+	}
+}
@@ -0,0 +1,117 @@
+package export
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/testutil"
+)
+
+func TestBlockEvents(t *testing.T) {
+	a := testutil.MakeAddress()
+	b := testutil.MakeAddress()
+
+	input := coin.UxOut{
+		Head: coin.UxHead{
+			Time:  10,
+			BkSeq: 1,
+		},
+		Body: coin.UxBody{
+			SrcTransaction: testutil.RandSHA256(t),
+			Address:        a,
+			Coins:          10e6,
+			Hours:          100,
+		},
+	}
+
+	var txn coin.Transaction
+	txn.PushInput(input.Hash())
+	txn.PushOutput(b, 6e6, 40)
+	txn.PushOutput(a, 4e6, 10)
+
+	block := coin.Block{
+		Head: coin.BlockHeader{
+			BkSeq: 5,
+			Time:  1000,
+		},
+		Body: coin.BlockBody{
+			Transactions: coin.Transactions{txn},
+		},
+	}
+
+	events := BlockEvents(block, []coin.UxArray{{input}})
+
+	blockHash := block.HashHeader().Hex()
+	txid := txn.Hash().Hex()
+	uxs := coin.CreateUnspents(block.Head, txn)
+
+	expectedDeltas := []Event{
+		{
+			Type:     EventAddressDelta,
+			Address:  a.String(),
+			Received: 4e6,
+			Sent:     10e6,
+		},
+		{
+			Type:     EventAddressDelta,
+			Address:  b.String(),
+			Received: 6e6,
+		},
+	}
+	if b.String() < a.String() {
+		expectedDeltas[0], expectedDeltas[1] = expectedDeltas[1], expectedDeltas[0]
+	}
+
+	expected := append([]Event{
+		{
+			Type: EventBlock,
+		},
+		{
+			Type: EventTransaction,
+			Txid: txid,
+		},
+		{
+			Type:    EventOutputSpent,
+			Txid:    txid,
+			UxID:    input.Hash().Hex(),
+			Address: a.String(),
+			Coins:   10e6,
+			Hours:   100,
+		},
+		{
+			Type:    EventOutputCreated,
+			Txid:    txid,
+			UxID:    uxs[0].Hash().Hex(),
+			Address: b.String(),
+			Coins:   6e6,
+			Hours:   40,
+		},
+		{
+			Type:    EventOutputCreated,
+			Txid:    txid,
+			UxID:    uxs[1].Hash().Hex(),
+			Address: a.String(),
+			Coins:   4e6,
+			Hours:   10,
+		},
+	}, expectedDeltas...)
+
+	for i := range expected {
+		expected[i].BlockSeq = 5
+		expected[i].BlockTime = 1000
+		expected[i].BlockHash = blockHash
+	}
+
+	require.Equal(t, expected, events)
+}
+
+func TestBlockRevertedEvent(t *testing.T) {
+	hash := testutil.RandSHA256(t)
+	require.Equal(t, Event{
+		Type:      EventBlockReverted,
+		BlockSeq:  3,
+		BlockHash: hash.Hex(),
+	}, BlockRevertedEvent(3, hash))
+}
@@ -0,0 +1,73 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// Sink receives the exported events.
+// The events are delivered at least once: the events of the blocks written after the last export cursor update
+// are written again if the node stops before the cursor is updated, so consumers should deduplicate them
+// by block hash.
+type Sink interface {
+	// Name identifies the sink, each sink has its own export cursor
+	Name() string
+	// Write writes the events of a block. The block is not considered exported if an error is returned,
+	// and is written again later.
+	Write(events []Event) error
+	// Close closes the sink
+	Close() error
+}
+
+// JSONFileSink appends the events to a file, one JSON object per line
+type JSONFileSink struct {
+	path string
+	f    *os.File
+}
+
+// NewJSONFileSink opens a JSONFileSink appending to path, creating the file if it does not exist
+func NewJSONFileSink(path string) (*JSONFileSink, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return &JSONFileSink{
+		path: path,
+		f:    f,
+	}, nil
+}
+
+// Name returns the name of the sink, which includes the path of the file
+func (s *JSONFileSink) Name() string {
+	return "json:" + s.path
+}
+
+// Write appends the events to the file and syncs it
+func (s *JSONFileSink) Write(events []Event) error {
+	w := bufio.NewWriter(s.f)
+	enc := json.NewEncoder(w)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	return s.f.Sync()
+}
+
+// Close closes the file
+func (s *JSONFileSink) Close() error {
+	return s.f.Close()
+}
@@ -0,0 +1,196 @@
+package export
+
+import (
+	"bufio"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONFileSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "export")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "events.json")
+
+	first := []Event{
+		{Type: EventBlock, BlockSeq: 1, BlockHash: "a"},
+		{Type: EventTransaction, BlockSeq: 1, BlockHash: "a", Txid: "b"},
+	}
+	second := []Event{
+		{Type: EventAddressDelta, BlockSeq: 2, BlockHash: "c", Address: "d", Received: 5},
+	}
+
+	s, err := NewJSONFileSink(path)
+	require.NoError(t, err)
+	require.Equal(t, "json:"+path, s.Name())
+
+	err = s.Write(first)
+	require.NoError(t, err)
+	err = s.Close()
+	require.NoError(t, err)
+
+	// The events are appended to the existing file
+	s, err = NewJSONFileSink(path)
+	require.NoError(t, err)
+	err = s.Write(second)
+	require.NoError(t, err)
+	err = s.Close()
+	require.NoError(t, err)
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Event
+		err := json.Unmarshal(scanner.Bytes(), &e)
+		require.NoError(t, err)
+		events = append(events, e)
+	}
+	require.NoError(t, scanner.Err())
+
+	require.Equal(t, append(first, second...), events)
+}
+
+// fakeDriver is a database/sql driver recording the statements executed
+type fakeDriver struct {
+	execs     []fakeExec
+	commits   int
+	rollbacks int
+	failExec  bool
+}
+
+type fakeExec struct {
+	query string
+	args  []driver.Value
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) {
+	return &fakeConn{d: d}, nil
+}
+
+type fakeConn struct {
+	d *fakeDriver
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{d: c.d, query: query}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return &fakeTx{d: c.d}, nil
+}
+
+type fakeStmt struct {
+	d     *fakeDriver
+	query string
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if s.d.failExec && strings.HasPrefix(s.query, "INSERT") {
+		return nil, errors.New("exec failed")
+	}
+	s.d.execs = append(s.d.execs, fakeExec{
+		query: s.query,
+		args:  args,
+	})
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+type fakeTx struct {
+	d *fakeDriver
+}
+
+func (tx *fakeTx) Commit() error {
+	tx.d.commits++
+	return nil
+}
+
+func (tx *fakeTx) Rollback() error {
+	tx.d.rollbacks++
+	return nil
+}
+
+var testFakeDriver = &fakeDriver{}
+
+func init() {
+	sql.Register("export_fake", testFakeDriver)
+}
+
+func TestSQLSink(t *testing.T) {
+	db, err := sql.Open("export_fake", "")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = NewSQLSink(db, "events; DROP TABLE x")
+	require.Error(t, err)
+	require.Empty(t, testFakeDriver.execs)
+
+	s, err := NewSQLSink(db, "skycoin_events")
+	require.NoError(t, err)
+	require.Equal(t, "sql:skycoin_events", s.Name())
+
+	require.Len(t, testFakeDriver.execs, 1)
+	require.True(t, strings.HasPrefix(testFakeDriver.execs[0].query, "CREATE TABLE IF NOT EXISTS skycoin_events ("))
+
+	err = s.Write([]Event{
+		{Type: EventBlock, BlockSeq: 1, BlockTime: 100, BlockHash: "a"},
+		{Type: EventOutputCreated, BlockSeq: 1, BlockTime: 100, BlockHash: "a", Txid: "b", UxID: "c", Address: "d", Coins: 1<<64 - 1, Hours: 7},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, testFakeDriver.commits)
+
+	require.Len(t, testFakeDriver.execs, 3)
+	insert := testFakeDriver.execs[2]
+	require.True(t, strings.HasPrefix(insert.query, "INSERT INTO skycoin_events"))
+	require.Equal(t, []driver.Value{
+		"output_created",
+		int64(1),
+		int64(100),
+		"a",
+		"b",
+		"c",
+		"d",
+		"18446744073709551615",
+		"7",
+		"0",
+		"0",
+	}, insert.args)
+
+	// The transaction is rolled back if an insert fails
+	testFakeDriver.failExec = true
+	err = s.Write([]Event{
+		{Type: EventBlock, BlockSeq: 2, BlockTime: 200, BlockHash: "e"},
+	})
+	require.Error(t, err)
+	require.Equal(t, 1, testFakeDriver.commits)
+	require.Equal(t, 1, testFakeDriver.rollbacks)
+}
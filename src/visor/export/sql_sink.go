@@ -0,0 +1,106 @@
+package export
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+var sqlTableNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// SQLSink inserts the events in a PostgreSQL table through database/sql.
+// The driver is registered by the program using the sink, e.g. by importing github.com/lib/pq.
+// The events written again after a restart are ignored, so that each event is stored once.
+type SQLSink struct {
+	db    *sql.DB
+	table string
+}
+
+// NewSQLSink creates a SQLSink inserting the events in table, creating the table if it does not exist.
+// The amounts are stored as NUMERIC, since they don't always fit in a BIGINT.
+func NewSQLSink(db *sql.DB, table string) (*SQLSink, error) {
+	if !sqlTableNameRe.MatchString(table) {
+		return nil, fmt.Errorf("Invalid table name %q", table)
+	}
+
+	if _, err := db.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	type TEXT NOT NULL,
+	block_seq BIGINT NOT NULL,
+	block_time BIGINT NOT NULL,
+	block_hash TEXT NOT NULL,
+	txid TEXT NOT NULL,
+	uxid TEXT NOT NULL,
+	address TEXT NOT NULL,
+	coins NUMERIC(20) NOT NULL,
+	hours NUMERIC(20) NOT NULL,
+	received NUMERIC(20) NOT NULL,
+	sent NUMERIC(20) NOT NULL,
+	PRIMARY KEY (block_hash, type, txid, uxid, address)
+)`, table)); err != nil {
+		return nil, err
+	}
+
+	return &SQLSink{
+		db:    db,
+		table: table,
+	}, nil
+}
+
+// Name returns the name of the sink, which includes the table name
+func (s *SQLSink) Name() string {
+	return "sql:" + s.table
+}
+
+// Write inserts the events in one database transaction
+func (s *SQLSink) Write(events []Event) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if err := s.insert(tx, events); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("%v, and rollback failed: %v", err, rbErr)
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *SQLSink) insert(tx *sql.Tx, events []Event) error {
+	stmt, err := tx.Prepare(fmt.Sprintf(`INSERT INTO %s
+	(type, block_seq, block_time, block_hash, txid, uxid, address, coins, hours, received, sent)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	ON CONFLICT DO NOTHING`, s.table))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, e := range events {
+		if _, err := stmt.Exec(
+			string(e.Type),
+			int64(e.BlockSeq),
+			int64(e.BlockTime),
+			e.BlockHash,
+			e.Txid,
+			e.UxID,
+			e.Address,
+			strconv.FormatUint(e.Coins, 10),
+			strconv.FormatUint(e.Hours, 10),
+			strconv.FormatUint(e.Received, 10),
+			strconv.FormatUint(e.Sent, 10),
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close does nothing, the database is closed by its owner
+func (s *SQLSink) Close() error {
+	return nil
+}
@@ -0,0 +1,274 @@
+package historydb
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/encoder"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+// AddressBalanceDeltasBkt maps addresses and block seqs to the change of the coin balance of the address in the block
+var AddressBalanceDeltasBkt = []byte("address_balance_deltas")
+
+// AddressBalanceDelta is the change of the coin balance of an address in a block
+type AddressBalanceDelta struct {
+	BlockSeq  uint64
+	BlockTime uint64
+	// Total coins of the outputs received and spent by the address in the block, in droplets.
+	// Change returned to the address is counted in both.
+	Received uint64
+	Sent     uint64
+	// Balance is the coin balance of the address after the block, in droplets
+	Balance uint64
+}
+
+// addressBalanceDeltaKey returns the key of the balance delta of an address in a block,
+// the keys of an address sort in block seq order
+func addressBalanceDeltaKey(tx *dbutil.Tx, addr cipher.Address, seq uint64) []byte {
+	prefix := addressKey(tx, addr)
+	key := make([]byte, 0, len(prefix)+8)
+	key = append(key, prefix...)
+	return append(key, dbutil.Itob(seq)...)
+}
+
+// addressBalanceDeltas bucket is a journal of the balance changes of the addresses,
+// address and block seq as key, AddressBalanceDelta as value.
+// A delta is appended for each block that an address received or spent outputs in.
+type addressBalanceDeltas struct{}
+
+// put saves the balance delta of an address in a block
+func (abd *addressBalanceDeltas) put(tx *dbutil.Tx, addr cipher.Address, d AddressBalanceDelta) error {
+	return dbutil.PutBucketValue(tx, AddressBalanceDeltasBkt, addressBalanceDeltaKey(tx, addr, d.BlockSeq), encoder.Serialize(d))
+}
+
+// delete removes the balance delta of an address in the block of seq
+func (abd *addressBalanceDeltas) delete(tx *dbutil.Tx, addr cipher.Address, seq uint64) error {
+	return dbutil.Delete(tx, AddressBalanceDeltasBkt, addressBalanceDeltaKey(tx, addr, seq))
+}
+
+// last returns the last balance delta of an address at or before the block of seq, nil if there is none
+func (abd *addressBalanceDeltas) last(tx *dbutil.Tx, addr cipher.Address, seq uint64) (*AddressBalanceDelta, error) {
+	k, v, err := dbutil.SeekPrev(tx, AddressBalanceDeltasBkt, addressBalanceDeltaKey(tx, addr, seq))
+	if err != nil {
+		return nil, err
+	}
+
+	if k == nil || !bytes.HasPrefix(k, addressKey(tx, addr)) {
+		return nil, nil
+	}
+
+	var d AddressBalanceDelta
+	if err := encoder.DeserializeRaw(v, &d); err != nil {
+		return nil, err
+	}
+
+	return &d, nil
+}
+
+// inRange returns the balance deltas of an address in the blocks of the seq range [start, end], in block seq order
+func (abd *addressBalanceDeltas) inRange(tx *dbutil.Tx, addr cipher.Address, start, end uint64) ([]AddressBalanceDelta, error) {
+	var deltas []AddressBalanceDelta
+	if err := dbutil.ForEachPrefix(tx, AddressBalanceDeltasBkt, addressKey(tx, addr), addressBalanceDeltaKey(tx, addr, start), func(k, v []byte) (bool, error) {
+		var d AddressBalanceDelta
+		if err := encoder.DeserializeRaw(v, &d); err != nil {
+			return false, err
+		}
+
+		if d.BlockSeq > end {
+			return false, nil
+		}
+
+		deltas = append(deltas, d)
+		return true, nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return deltas, nil
+}
+
+// isEmpty checks if the address balance deltas bucket is empty
+func (abd *addressBalanceDeltas) isEmpty(tx *dbutil.Tx) (bool, error) {
+	return dbutil.IsEmpty(tx, AddressBalanceDeltasBkt)
+}
+
+// reset empties the bucket, see dbutil.ResetBucket
+func (abd *addressBalanceDeltas) reset(tx *dbutil.Tx) error {
+	return dbutil.ResetBucket(tx, AddressBalanceDeltasBkt)
+}
+
+// balanceChange is the change of the balance of an address by a block
+type balanceChange struct {
+	addr cipher.Address
+	old  uint64
+	new  uint64
+}
+
+// addressSet is a set of addresses that keeps the order the addresses were added in
+type addressSet struct {
+	addrs []cipher.Address
+	set   map[cipher.Address]struct{}
+}
+
+func newAddressSet() *addressSet {
+	return &addressSet{
+		set: make(map[cipher.Address]struct{}),
+	}
+}
+
+// add adds an address to the set if it is not in it yet
+func (as *addressSet) add(addr cipher.Address) {
+	if _, ok := as.set[addr]; ok {
+		return
+	}
+	as.set[addr] = struct{}{}
+	as.addrs = append(as.addrs, addr)
+}
+
+// apply appends the balance deltas of the addresses that received or spent outputs in a block,
+// and returns the balance changes of the addresses
+func (abd *addressBalanceDeltas) apply(tx *dbutil.Tx, a *addressActivities, seq, time uint64) ([]balanceChange, error) {
+	changes := make([]balanceChange, 0, len(a.addrs))
+	for _, addr := range a.addrs {
+		act := a.activities[addr]
+
+		var prevBalance uint64
+		if prev, err := abd.last(tx, addr, seq); err != nil {
+			return nil, err
+		} else if prev != nil {
+			prevBalance = prev.Balance
+		}
+
+		balance, err := coin.AddUint64(prevBalance, act.received)
+		if err != nil {
+			return nil, err
+		}
+
+		if act.sent > balance {
+			return nil, fmt.Errorf("address %s spent %d droplets in block %d, more than its balance %d", addr, act.sent, seq, balance)
+		}
+		balance -= act.sent
+
+		if err := abd.put(tx, addr, AddressBalanceDelta{
+			BlockSeq:  seq,
+			BlockTime: time,
+			Received:  act.received,
+			Sent:      act.sent,
+			Balance:   balance,
+		}); err != nil {
+			return nil, err
+		}
+
+		changes = append(changes, balanceChange{
+			addr: addr,
+			old:  prevBalance,
+			new:  balance,
+		})
+	}
+
+	return changes, nil
+}
+
+// unapply removes the balance deltas of the addresses in the block of seq, undoing apply,
+// and returns the balance changes of the addresses
+func (abd *addressBalanceDeltas) unapply(tx *dbutil.Tx, addrs []cipher.Address, seq uint64) ([]balanceChange, error) {
+	changes := make([]balanceChange, 0, len(addrs))
+	for _, addr := range addrs {
+		d, err := abd.last(tx, addr, seq)
+		if err != nil {
+			return nil, err
+		} else if d == nil || d.BlockSeq != seq {
+			return nil, fmt.Errorf("balance delta of address %s in block %d is not stored", addr, seq)
+		}
+
+		if err := abd.delete(tx, addr, seq); err != nil {
+			return nil, err
+		}
+
+		var prevBalance uint64
+		if prev, err := abd.last(tx, addr, seq); err != nil {
+			return nil, err
+		} else if prev != nil {
+			prevBalance = prev.Balance
+		}
+
+		changes = append(changes, balanceChange{
+			addr: addr,
+			old:  d.Balance,
+			new:  prevBalance,
+		})
+	}
+
+	return changes, nil
+}
+
+// applySnapshot sets the balances of the addresses to their balances in the unspent output set snapshot
+// that the history starts from after the block of seq and time. The transactions of the blocks up to seq are not known,
+// so the deltas only record the difference to the balances after the genesis block.
+// Returns the balance changes of the addresses.
+func (abd *addressBalanceDeltas) applySnapshot(tx *dbutil.Tx, genesis coin.Block, seq, time uint64, uxs coin.UxArray) ([]balanceChange, error) {
+	balances := make(map[cipher.Address]uint64)
+	var addrs []cipher.Address
+	addAddr := func(addr cipher.Address) {
+		if _, ok := balances[addr]; !ok {
+			balances[addr] = 0
+			addrs = append(addrs, addr)
+		}
+	}
+
+	for _, txn := range genesis.Body.Transactions {
+		for _, o := range txn.Out {
+			addAddr(o.Address)
+		}
+	}
+
+	for _, ux := range uxs {
+		addAddr(ux.Body.Address)
+		b, err := coin.AddUint64(balances[ux.Body.Address], ux.Body.Coins)
+		if err != nil {
+			return nil, err
+		}
+		balances[ux.Body.Address] = b
+	}
+
+	var changes []balanceChange
+	for _, addr := range addrs {
+		var prevBalance uint64
+		if prev, err := abd.last(tx, addr, seq); err != nil {
+			return nil, err
+		} else if prev != nil {
+			prevBalance = prev.Balance
+		}
+
+		balance := balances[addr]
+		if balance == prevBalance {
+			continue
+		}
+
+		d := AddressBalanceDelta{
+			BlockSeq:  seq,
+			BlockTime: time,
+			Balance:   balance,
+		}
+		if balance > prevBalance {
+			d.Received = balance - prevBalance
+		} else {
+			d.Sent = prevBalance - balance
+		}
+
+		if err := abd.put(tx, addr, d); err != nil {
+			return nil, err
+		}
+
+		changes = append(changes, balanceChange{
+			addr: addr,
+			old:  prevBalance,
+			new:  balance,
+		})
+	}
+
+	return changes, nil
+}
@@ -0,0 +1,225 @@
+package historydb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+func TestAddressBalanceDeltas(t *testing.T) {
+	db, teardown := prepareDB(t)
+	defer teardown()
+	bc := newBlockchain()
+	gb := bc.CreateGenesisBlock(genAddress, genCoins, genTime)
+	hisDB := New()
+
+	err := db.Update("", func(tx *dbutil.Tx) error {
+		return hisDB.ParseBlock(tx, gb)
+	})
+	require.NoError(t, err)
+
+	addrA := cipher.MustDecodeBase58Address("2RxP5N26GhDqHrP6SK45ZzEMSmSpeUeWxsS")
+	addrB := cipher.MustDecodeBase58Address("222uMeCeL1PbkJGZJDgAz5sib2uisv9hYUm")
+
+	tds := newProcessBlockTestData(gb)
+	var blocks []coin.Block
+	for i, td := range tds {
+		b, txn, err := addBlock(bc, td, incTime*(uint64(i)+1))
+		require.NoError(t, err)
+
+		if i+1 < len(tds) {
+			tds[i+1].Vin.TxID = txn.Hash()
+			tds[i+1].PreBlockHash = b.HashHeader()
+		}
+
+		err = db.Update("", func(tx *dbutil.Tx) error {
+			return hisDB.ParseBlock(tx, *b)
+		})
+		require.NoError(t, err)
+		blocks = append(blocks, *b)
+	}
+
+	genDeltas := []AddressBalanceDelta{
+		{
+			BlockSeq:  0,
+			BlockTime: gb.Time(),
+			Received:  genCoins,
+			Balance:   genCoins,
+		},
+		{
+			BlockSeq:  1,
+			BlockTime: blocks[0].Time(),
+			Sent:      genCoins,
+			Balance:   0,
+		},
+	}
+
+	aDeltas := []AddressBalanceDelta{
+		{
+			BlockSeq:  1,
+			BlockTime: blocks[0].Time(),
+			Received:  10e6,
+			Balance:   10e6,
+		},
+		{
+			BlockSeq:  2,
+			BlockTime: blocks[1].Time(),
+			Received:  10e6,
+			Balance:   20e6,
+		},
+	}
+
+	// addrB spends its output of block 1 and receives the change in block 2
+	bDeltas := []AddressBalanceDelta{
+		{
+			BlockSeq:  1,
+			BlockTime: blocks[0].Time(),
+			Received:  genCoins - 10e6,
+			Balance:   genCoins - 10e6,
+		},
+		{
+			BlockSeq:  2,
+			BlockTime: blocks[1].Time(),
+			Received:  genCoins - 20e6,
+			Sent:      genCoins - 10e6,
+			Balance:   genCoins - 20e6,
+		},
+	}
+
+	err = db.View("", func(tx *dbutil.Tx) error {
+		deltas, err := hisDB.GetAddressBalanceDeltas(tx, genAddress, 0, 10)
+		require.NoError(t, err)
+		require.Equal(t, genDeltas, deltas)
+
+		deltas, err = hisDB.GetAddressBalanceDeltas(tx, addrA, 0, 10)
+		require.NoError(t, err)
+		require.Equal(t, aDeltas, deltas)
+
+		deltas, err = hisDB.GetAddressBalanceDeltas(tx, addrB, 2, 2)
+		require.NoError(t, err)
+		require.Equal(t, bDeltas[1:], deltas)
+
+		deltas, err = hisDB.GetAddressBalanceDeltas(tx, addrB, 3, 10)
+		require.NoError(t, err)
+		require.Empty(t, deltas)
+
+		d, err := hisDB.GetAddressBalanceAt(tx, addrA, 0)
+		require.NoError(t, err)
+		require.Nil(t, d)
+
+		d, err = hisDB.GetAddressBalanceAt(tx, addrA, 1)
+		require.NoError(t, err)
+		require.Equal(t, &aDeltas[0], d)
+
+		d, err = hisDB.GetAddressBalanceAt(tx, addrA, 10)
+		require.NoError(t, err)
+		require.Equal(t, &aDeltas[1], d)
+
+		d, err = hisDB.GetAddressBalanceAt(tx, genAddress, 10)
+		require.NoError(t, err)
+		require.Equal(t, &genDeltas[1], d)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// Unparsing a block removes its deltas
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		require.NoError(t, hisDB.UnparseBlock(tx, blocks[1]))
+
+		deltas, err := hisDB.GetAddressBalanceDeltas(tx, addrB, 0, 10)
+		require.NoError(t, err)
+		require.Equal(t, bDeltas[:1], deltas)
+
+		d, err := hisDB.GetAddressBalanceAt(tx, addrA, 10)
+		require.NoError(t, err)
+		require.Equal(t, &aDeltas[0], d)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestAddressBalanceDeltasApplySnapshot(t *testing.T) {
+	db, teardown := prepareDB(t)
+	defer teardown()
+	bc := newBlockchain()
+	gb := bc.CreateGenesisBlock(genAddress, genCoins, genTime)
+	hisDB := New()
+
+	addrA := cipher.MustDecodeBase58Address("2RxP5N26GhDqHrP6SK45ZzEMSmSpeUeWxsS")
+	addrB := cipher.MustDecodeBase58Address("222uMeCeL1PbkJGZJDgAz5sib2uisv9hYUm")
+
+	uxs := coin.UxArray{
+		{Body: coin.UxBody{Address: addrA, Coins: 10e6}},
+		{Body: coin.UxBody{Address: addrB, Coins: 5e6}},
+		{Body: coin.UxBody{Address: addrA, Coins: 20e6}},
+	}
+
+	err := db.Update("", func(tx *dbutil.Tx) error {
+		return hisDB.ParseUnspentSnapshot(tx, gb, 10, 5000, uxs)
+	})
+	require.NoError(t, err)
+
+	err = db.View("", func(tx *dbutil.Tx) error {
+		// The genesis address spent its coins before the snapshot block
+		deltas, err := hisDB.GetAddressBalanceDeltas(tx, genAddress, 0, 10)
+		require.NoError(t, err)
+		require.Equal(t, []AddressBalanceDelta{
+			{
+				BlockSeq:  0,
+				BlockTime: gb.Time(),
+				Received:  genCoins,
+				Balance:   genCoins,
+			},
+			{
+				BlockSeq:  10,
+				BlockTime: 5000,
+				Sent:      genCoins,
+			},
+		}, deltas)
+
+		d, err := hisDB.GetAddressBalanceAt(tx, addrA, 10)
+		require.NoError(t, err)
+		require.Equal(t, &AddressBalanceDelta{
+			BlockSeq:  10,
+			BlockTime: 5000,
+			Received:  30e6,
+			Balance:   30e6,
+		}, d)
+
+		d, err = hisDB.GetAddressBalanceAt(tx, addrB, 9)
+		require.NoError(t, err)
+		require.Nil(t, d)
+
+		d, err = hisDB.GetAddressBalanceAt(tx, addrB, 10)
+		require.NoError(t, err)
+		require.Equal(t, uint64(5e6), d.Balance)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestAddressBalanceDeltasApplyOverspend(t *testing.T) {
+	db, teardown := prepareDB(t)
+	defer teardown()
+
+	abd := &addressBalanceDeltas{}
+	addr := testutil.MakeAddress()
+
+	err := db.Update("", func(tx *dbutil.Tx) error {
+		a := newAddressActivities()
+		a.addReceived(addr, 10)
+		_, err := abd.apply(tx, a, 1, 100)
+		require.NoError(t, err)
+
+		a = newAddressActivities()
+		a.addSent(addr, 11)
+		_, err = abd.apply(tx, a, 2, 200)
+		return err
+	})
+	require.Error(t, err)
+}
@@ -0,0 +1,128 @@
+package historydb
+
+import (
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/encoder"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+// AddressMetaBkt maps addresses to their AddressMeta
+var AddressMetaBkt = []byte("address_meta")
+
+// AddressMeta summarizes the activity of an address in the blockchain
+type AddressMeta struct {
+	// Block in which the address first received an output
+	FirstSeenSeq  uint64
+	FirstSeenTime uint64
+	// Last block in which the address received or spent an output
+	LastActiveSeq  uint64
+	LastActiveTime uint64
+	// Number of transactions that the address received or spent outputs in
+	TxnCount uint64
+	// Total coins of the outputs received and spent by the address, in droplets.
+	// Change returned to the address is counted in both.
+	Received uint64
+	Sent     uint64
+}
+
+// addressMetas bucket stores the AddressMeta of the addresses,
+// address as key, AddressMeta as value
+type addressMetas struct{}
+
+// get returns the meta of an address, nil if the address was never used
+func (am *addressMetas) get(tx *dbutil.Tx, address cipher.Address) (*AddressMeta, error) {
+	var meta AddressMeta
+	if ok, err := dbutil.GetBucketObjectDecoded(tx, AddressMetaBkt, addressKey(tx, address), &meta); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, nil
+	}
+
+	return &meta, nil
+}
+
+// put saves the meta of an address
+func (am *addressMetas) put(tx *dbutil.Tx, address cipher.Address, meta AddressMeta) error {
+	return dbutil.PutBucketValue(tx, AddressMetaBkt, addressKey(tx, address), encoder.Serialize(meta))
+}
+
+// isEmpty checks if address meta bucket is empty
+func (am *addressMetas) isEmpty(tx *dbutil.Tx) (bool, error) {
+	return dbutil.IsEmpty(tx, AddressMetaBkt)
+}
+
+// reset empties the bucket, see dbutil.ResetBucket
+func (am *addressMetas) reset(tx *dbutil.Tx) error {
+	return dbutil.ResetBucket(tx, AddressMetaBkt)
+}
+
+// addressActivity is the activity of an address in a transaction
+type addressActivity struct {
+	received uint64
+	sent     uint64
+}
+
+// addressActivities collects the activity of the addresses of a transaction, in order of appearance
+type addressActivities struct {
+	addrs      []cipher.Address
+	activities map[cipher.Address]*addressActivity
+}
+
+func newAddressActivities() *addressActivities {
+	return &addressActivities{
+		activities: make(map[cipher.Address]*addressActivity),
+	}
+}
+
+func (a *addressActivities) activity(addr cipher.Address) *addressActivity {
+	act, ok := a.activities[addr]
+	if !ok {
+		act = &addressActivity{}
+		a.activities[addr] = act
+		a.addrs = append(a.addrs, addr)
+	}
+	return act
+}
+
+// addSent records coins spent by an address
+func (a *addressActivities) addSent(addr cipher.Address, coins uint64) {
+	a.activity(addr).sent += coins
+}
+
+// addReceived records coins received by an address
+func (a *addressActivities) addReceived(addr cipher.Address, coins uint64) {
+	a.activity(addr).received += coins
+}
+
+// apply adds the activities of a transaction of a block to the address metas,
+// recording the metas of the addresses before the block changed them in undo
+func (am *addressMetas) apply(tx *dbutil.Tx, a *addressActivities, seq, time uint64, undo *blockAddressMetaUndo) error {
+	for _, addr := range a.addrs {
+		meta, err := am.get(tx, addr)
+		if err != nil {
+			return err
+		}
+
+		undo.record(addr, meta)
+
+		if meta == nil {
+			meta = &AddressMeta{
+				FirstSeenSeq:  seq,
+				FirstSeenTime: time,
+			}
+		}
+
+		act := a.activities[addr]
+		meta.LastActiveSeq = seq
+		meta.LastActiveTime = time
+		meta.TxnCount++
+		meta.Received += act.received
+		meta.Sent += act.sent
+
+		if err := am.put(tx, addr, *meta); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,190 @@
+package historydb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+func TestAddressMeta(t *testing.T) {
+	db, teardown := prepareDB(t)
+	defer teardown()
+	bc := newBlockchain()
+	gb := bc.CreateGenesisBlock(genAddress, genCoins, genTime)
+
+	hisDB := New()
+
+	err := db.Update("", func(tx *dbutil.Tx) error {
+		return hisDB.ParseBlock(tx, gb)
+	})
+	require.NoError(t, err)
+
+	addrA := cipher.MustDecodeBase58Address("2RxP5N26GhDqHrP6SK45ZzEMSmSpeUeWxsS")
+	addrB := cipher.MustDecodeBase58Address("222uMeCeL1PbkJGZJDgAz5sib2uisv9hYUm")
+
+	testData := []testData{
+		{
+			PreBlockHash: gb.HashHeader(),
+			Vin: txIn{
+				SigKey:   genSecret.Hex(),
+				Addr:     genAddress.String(),
+				TxID:     gb.Body.Transactions[0].Hash(),
+				BlockSeq: 0,
+			},
+			Vouts: []txOut{
+				{
+					ToAddr: addrA.String(),
+					Coins:  10e6,
+					Hours:  100,
+				},
+				{
+					ToAddr: addrB.String(),
+					Coins:  genCoins - 10e6,
+					Hours:  400,
+				},
+			},
+			AddrInNum: map[string]int{
+				addrA.String(): 1,
+				addrB.String(): 1,
+			},
+		},
+		{
+			Vin: txIn{
+				Addr:     addrB.String(),
+				SigKey:   "62f4d675d991c41a2819d908a4fcf4ba44ff0c31564039e80508c9d68197f90c",
+				BlockSeq: 1,
+			},
+			Vouts: []txOut{
+				{
+					ToAddr: addrA.String(),
+					Coins:  10e6,
+					Hours:  100,
+				},
+				{
+					ToAddr: addrB.String(),
+					Coins:  genCoins - 20e6,
+					Hours:  100,
+				},
+			},
+			AddrInNum: map[string]int{
+				addrA.String(): 2,
+				addrB.String(): 2,
+			},
+		},
+	}
+
+	testEngine(t, testData, bc, hisDB, db)
+
+	tt := []struct {
+		name string
+		addr cipher.Address
+		meta *AddressMeta
+	}{
+		{
+			name: "genesis address",
+			addr: genAddress,
+			meta: &AddressMeta{
+				FirstSeenSeq:   0,
+				FirstSeenTime:  genTime,
+				LastActiveSeq:  1,
+				LastActiveTime: incTime,
+				TxnCount:       2,
+				Received:       genCoins,
+				Sent:           genCoins,
+			},
+		},
+		{
+			name: "received only",
+			addr: addrA,
+			meta: &AddressMeta{
+				FirstSeenSeq:   1,
+				FirstSeenTime:  incTime,
+				LastActiveSeq:  2,
+				LastActiveTime: incTime * 2,
+				TxnCount:       2,
+				Received:       20e6,
+				Sent:           0,
+			},
+		},
+		{
+			name: "change counted as received and sent",
+			addr: addrB,
+			meta: &AddressMeta{
+				FirstSeenSeq:   1,
+				FirstSeenTime:  incTime,
+				LastActiveSeq:  2,
+				LastActiveTime: incTime * 2,
+				TxnCount:       2,
+				Received:       genCoins - 10e6 + genCoins - 20e6,
+				Sent:           genCoins - 10e6,
+			},
+		},
+		{
+			name: "never used",
+			addr: makeAddress(),
+			meta: nil,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := db.View("", func(tx *dbutil.Tx) error {
+				meta, err := hisDB.GetAddressMeta(tx, tc.addr)
+				require.NoError(t, err)
+				require.Equal(t, tc.meta, meta)
+				return nil
+			})
+			require.NoError(t, err)
+		})
+	}
+
+	// The metas match the parsed blocks
+	err = db.View("", func(tx *dbutil.Tx) error {
+		indexesMap := NewIndexesMap()
+		for _, b := range bc.blocks {
+			require.NoError(t, hisDB.Verify(tx, &coin.SignedBlock{Block: b}, indexesMap))
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	// A meta with a wrong transaction count is detected as corrupted
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		meta, err := hisDB.GetAddressMeta(tx, addrA)
+		require.NoError(t, err)
+		meta.TxnCount = 1
+		return hisDB.addrMeta.put(tx, addrA, *meta)
+	})
+	require.NoError(t, err)
+
+	err = db.View("", func(tx *dbutil.Tx) error {
+		indexesMap := NewIndexesMap()
+		for _, b := range bc.blocks {
+			if err := hisDB.Verify(tx, &coin.SignedBlock{Block: b}, indexesMap); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.Error(t, err)
+	_, ok := err.(ErrHistoryDBCorrupted)
+	require.True(t, ok)
+
+	// Erase removes the metas
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		require.NoError(t, hisDB.Erase(tx))
+		meta, err := hisDB.GetAddressMeta(tx, genAddress)
+		require.NoError(t, err)
+		require.Nil(t, meta)
+
+		needsReset, err := hisDB.NeedsReset(tx)
+		require.NoError(t, err)
+		require.True(t, needsReset)
+		return nil
+	})
+	require.NoError(t, err)
+}
@@ -0,0 +1,106 @@
+package historydb
+
+import (
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/encoder"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+// UnparseDepth is the number of most recent parsed blocks that can be unparsed.
+// The address meta undos of older blocks are deleted.
+const UnparseDepth = 100
+
+// AddressMetaUndosBkt maps block seqs to the address metas changed by the block, as they were before the block
+var AddressMetaUndosBkt = []byte("address_meta_undos")
+
+// addressMetaUndo is the meta of an address before a block changed it
+type addressMetaUndo struct {
+	Address cipher.Address
+	// Meta is not set if the address was first seen in the block
+	Meta    AddressMeta
+	HasMeta bool
+}
+
+// blockAddressMetaUndo collects the metas of the addresses changed by a block, before the block changed them
+type blockAddressMetaUndo struct {
+	undos []addressMetaUndo
+	seen  map[cipher.Address]struct{}
+}
+
+func newBlockAddressMetaUndo() *blockAddressMetaUndo {
+	return &blockAddressMetaUndo{
+		seen: make(map[cipher.Address]struct{}),
+	}
+}
+
+// record saves the meta of an address, nil if the address was never used, unless it was already recorded for the block
+func (u *blockAddressMetaUndo) record(addr cipher.Address, meta *AddressMeta) {
+	if _, ok := u.seen[addr]; ok {
+		return
+	}
+	u.seen[addr] = struct{}{}
+
+	undo := addressMetaUndo{
+		Address: addr,
+	}
+	if meta != nil {
+		undo.Meta = *meta
+		undo.HasMeta = true
+	}
+
+	u.undos = append(u.undos, undo)
+}
+
+// addressMetaUndos bucket stores the address metas changed by the most recent parsed blocks,
+// as they were before the block, so that they can be restored when the block is unparsed.
+// block seq as key, []addressMetaUndo as value.
+type addressMetaUndos struct{}
+
+// put saves the address metas changed by the block of seq
+func (amu *addressMetaUndos) put(tx *dbutil.Tx, seq uint64, undos []addressMetaUndo) error {
+	return dbutil.PutBucketValue(tx, AddressMetaUndosBkt, dbutil.Itob(seq), encoder.Serialize(undos))
+}
+
+// get returns the address metas changed by the block of seq, false if they are not stored
+func (amu *addressMetaUndos) get(tx *dbutil.Tx, seq uint64) ([]addressMetaUndo, bool, error) {
+	if !dbutil.Exists(tx, AddressMetaUndosBkt) {
+		return nil, false, nil
+	}
+
+	var undos []addressMetaUndo
+	if ok, err := dbutil.GetBucketObjectDecoded(tx, AddressMetaUndosBkt, dbutil.Itob(seq), &undos); err != nil {
+		return nil, false, err
+	} else if !ok {
+		return nil, false, nil
+	}
+
+	return undos, true, nil
+}
+
+// delete removes the address metas changed by the block of seq
+func (amu *addressMetaUndos) delete(tx *dbutil.Tx, seq uint64) error {
+	return dbutil.Delete(tx, AddressMetaUndosBkt, dbutil.Itob(seq))
+}
+
+// reset empties the bucket, see dbutil.ResetBucket
+func (amu *addressMetaUndos) reset(tx *dbutil.Tx) error {
+	return dbutil.ResetBucket(tx, AddressMetaUndosBkt)
+}
+
+// undo restores the metas of the addresses changed by a block
+func (am *addressMetas) undo(tx *dbutil.Tx, undos []addressMetaUndo) error {
+	for _, u := range undos {
+		if !u.HasMeta {
+			if err := dbutil.Delete(tx, AddressMetaBkt, addressKey(tx, u.Address)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := am.put(tx, u.Address, u.Meta); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -16,7 +16,7 @@ type addressTxns struct{}
 // get returns the transaction hashes of given address
 func (atx *addressTxns) get(tx *dbutil.Tx, address cipher.Address) ([]cipher.SHA256, error) {
 	var txHashes []cipher.SHA256
-	if ok, err := dbutil.GetBucketObjectDecoded(tx, AddressTxnsBkt, address.Bytes(), &txHashes); err != nil {
+	if ok, err := dbutil.GetBucketObjectDecoded(tx, AddressTxnsBkt, addressKey(tx, address), &txHashes); err != nil {
 		return nil, err
 	} else if !ok {
 		return nil, nil
@@ -40,7 +40,33 @@ func (atx *addressTxns) add(tx *dbutil.Tx, addr cipher.Address, hash cipher.SHA2
 	}
 
 	hashes = append(hashes, hash)
-	return dbutil.PutBucketValue(tx, AddressTxnsBkt, addr.Bytes(), encoder.Serialize(hashes))
+	return dbutil.PutBucketValue(tx, AddressTxnsBkt, addressKey(tx, addr), encoder.Serialize(hashes))
+}
+
+// remove removes a hash from an address's hash list
+func (atx *addressTxns) remove(tx *dbutil.Tx, addr cipher.Address, hash cipher.SHA256) error {
+	hashes, err := atx.get(tx, addr)
+	if err != nil {
+		return err
+	}
+
+	hashes = removeHash(hashes, hash)
+	if len(hashes) == 0 {
+		return dbutil.Delete(tx, AddressTxnsBkt, addressKey(tx, addr))
+	}
+
+	return dbutil.PutBucketValue(tx, AddressTxnsBkt, addressKey(tx, addr), encoder.Serialize(hashes))
+}
+
+// removeHash returns hashes without hash
+func removeHash(hashes []cipher.SHA256, hash cipher.SHA256) []cipher.SHA256 {
+	kept := hashes[:0]
+	for _, h := range hashes {
+		if h != hash {
+			kept = append(kept, h)
+		}
+	}
+	return kept
 }
 
 // isEmpty checks if address transactions bucket is empty
@@ -52,3 +78,113 @@ func (atx *addressTxns) isEmpty(tx *dbutil.Tx) (bool, error) {
 func (atx *addressTxns) reset(tx *dbutil.Tx) error {
 	return dbutil.Reset(tx, AddressTxnsBkt)
 }
+
+// AddressTxnSeqsBkt maps addresses and the block seq and index in the block of their transactions
+// to the transaction hashes, to page through the transactions of an address without loading all of them
+var AddressTxnSeqsBkt = []byte("address_txn_seqs")
+
+// AddressTxnsCursor is the position of a transaction in the transactions of an address,
+// which are ordered by block seq and index of the transaction in the block
+type AddressTxnsCursor struct {
+	BlockSeq uint64
+	TxnIndex uint64
+}
+
+// addressTxnSeqKey returns the key of a transaction of an address in the address_txn_seqs bucket,
+// the keys of an address sort in block seq and transaction index order
+func addressTxnSeqKey(tx *dbutil.Tx, addr cipher.Address, c AddressTxnsCursor) []byte {
+	prefix := addressKey(tx, addr)
+	key := make([]byte, 0, len(prefix)+16)
+	key = append(key, prefix...)
+	key = append(key, dbutil.Itob(c.BlockSeq)...)
+	return append(key, dbutil.Itob(c.TxnIndex)...)
+}
+
+// addressTxnSeqs bucket indexes the transactions of addresses by block seq and transaction index,
+// address, block seq and transaction index as key, transaction hash as value
+type addressTxnSeqs struct{}
+
+// add adds a transaction to the index of an address
+func (ats *addressTxnSeqs) add(tx *dbutil.Tx, addr cipher.Address, c AddressTxnsCursor, hash cipher.SHA256) error {
+	return dbutil.PutBucketValue(tx, AddressTxnSeqsBkt, addressTxnSeqKey(tx, addr, c), hash[:])
+}
+
+// delete removes a transaction from the index of an address
+func (ats *addressTxnSeqs) delete(tx *dbutil.Tx, addr cipher.Address, c AddressTxnsCursor) error {
+	return dbutil.Delete(tx, AddressTxnSeqsBkt, addressTxnSeqKey(tx, addr, c))
+}
+
+// has checks if a transaction is in the index of an address
+func (ats *addressTxnSeqs) has(tx *dbutil.Tx, addr cipher.Address, c AddressTxnsCursor) (bool, error) {
+	return dbutil.BucketHasKey(tx, AddressTxnSeqsBkt, addressTxnSeqKey(tx, addr, c))
+}
+
+// page returns the hashes of the transactions of an address, starting from the cursor, or from the first transaction
+// if the cursor is nil. The first offset transactions are skipped, and at most limit hashes are returned,
+// all of them if limit is 0. The cursor of the next transaction is returned if there are more transactions.
+func (ats *addressTxnSeqs) page(tx *dbutil.Tx, addr cipher.Address, cursor *AddressTxnsCursor, offset, limit uint64) ([]cipher.SHA256, *AddressTxnsCursor, error) {
+	prefix := addressKey(tx, addr)
+	start := prefix
+	if cursor != nil {
+		start = addressTxnSeqKey(tx, addr, *cursor)
+	}
+
+	var hashes []cipher.SHA256
+	var next *AddressTxnsCursor
+	if err := dbutil.ForEachPrefix(tx, AddressTxnSeqsBkt, prefix, start, func(k, v []byte) (bool, error) {
+		if offset > 0 {
+			offset--
+			return true, nil
+		}
+
+		if limit != 0 && uint64(len(hashes)) == limit {
+			next = &AddressTxnsCursor{
+				BlockSeq: dbutil.Btoi(k[len(prefix) : len(prefix)+8]),
+				TxnIndex: dbutil.Btoi(k[len(prefix)+8:]),
+			}
+			return false, nil
+		}
+
+		hash, err := cipher.SHA256FromBytes(v)
+		if err != nil {
+			return false, err
+		}
+		hashes = append(hashes, hash)
+		return true, nil
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	return hashes, next, nil
+}
+
+// last returns the hashes of the n most recent transactions of an address, most recent first
+func (ats *addressTxnSeqs) last(tx *dbutil.Tx, addr cipher.Address, n uint64) ([]cipher.SHA256, error) {
+	var hashes []cipher.SHA256
+	if n == 0 {
+		return hashes, nil
+	}
+
+	if err := dbutil.ForEachPrefixReverse(tx, AddressTxnSeqsBkt, addressKey(tx, addr), func(k, v []byte) (bool, error) {
+		hash, err := cipher.SHA256FromBytes(v)
+		if err != nil {
+			return false, err
+		}
+		hashes = append(hashes, hash)
+		return uint64(len(hashes)) < n, nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return hashes, nil
+}
+
+// isEmpty checks if address transaction seqs bucket is empty
+func (ats *addressTxnSeqs) isEmpty(tx *dbutil.Tx) (bool, error) {
+	return dbutil.IsEmpty(tx, AddressTxnSeqsBkt)
+}
+
+// reset empties the bucket, see dbutil.ResetBucket
+func (ats *addressTxnSeqs) reset(tx *dbutil.Tx) error {
+	return dbutil.ResetBucket(tx, AddressTxnSeqsBkt)
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
 	"github.com/skycoin/skycoin/src/visor/dbutil"
 )
 
@@ -252,3 +253,214 @@ func TestGetAddressTxns(t *testing.T) {
 		})
 	}
 }
+
+func TestAddressTxnSeqsPage(t *testing.T) {
+	db, td := prepareDB(t)
+	defer td()
+
+	addr := makeAddress()
+	otherAddr := makeAddress()
+
+	cursors := []AddressTxnsCursor{
+		{BlockSeq: 1, TxnIndex: 0},
+		{BlockSeq: 1, TxnIndex: 2},
+		{BlockSeq: 3, TxnIndex: 1},
+		{BlockSeq: 256, TxnIndex: 0},
+		{BlockSeq: 300, TxnIndex: 5},
+	}
+
+	var hashes []cipher.SHA256
+	for i := range cursors {
+		hashes = append(hashes, cipher.SumSHA256([]byte(fmt.Sprintf("tx%d", i))))
+	}
+
+	addrTxnSeqs := &addressTxnSeqs{}
+
+	err := db.Update("", func(tx *dbutil.Tx) error {
+		// Add in reverse order, the transactions are returned in block seq and index order
+		for i := len(cursors) - 1; i >= 0; i-- {
+			require.NoError(t, addrTxnSeqs.add(tx, addr, cursors[i], hashes[i]))
+		}
+		return addrTxnSeqs.add(tx, otherAddr, cursors[0], cipher.SumSHA256([]byte("other")))
+	})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name   string
+		addr   cipher.Address
+		cursor *AddressTxnsCursor
+		offset uint64
+		limit  uint64
+		hashes []cipher.SHA256
+		next   *AddressTxnsCursor
+	}{
+		{
+			name:   "all",
+			addr:   addr,
+			hashes: hashes,
+		},
+		{
+			name:   "limit",
+			addr:   addr,
+			limit:  2,
+			hashes: hashes[:2],
+			next:   &cursors[2],
+		},
+		{
+			name:   "limit equal to count",
+			addr:   addr,
+			limit:  5,
+			hashes: hashes,
+		},
+		{
+			name:   "offset and limit",
+			addr:   addr,
+			offset: 1,
+			limit:  3,
+			hashes: hashes[1:4],
+			next:   &cursors[4],
+		},
+		{
+			name:   "cursor",
+			addr:   addr,
+			cursor: &cursors[2],
+			limit:  2,
+			hashes: hashes[2:4],
+			next:   &cursors[4],
+		},
+		{
+			name:   "cursor between transactions",
+			addr:   addr,
+			cursor: &AddressTxnsCursor{BlockSeq: 2},
+			hashes: hashes[2:],
+		},
+		{
+			name:   "offset past the end",
+			addr:   addr,
+			offset: 5,
+		},
+		{
+			name: "unknown address",
+			addr: makeAddress(),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := db.View("", func(tx *dbutil.Tx) error {
+				hashes, next, err := addrTxnSeqs.page(tx, tc.addr, tc.cursor, tc.offset, tc.limit)
+				require.NoError(t, err)
+				require.Equal(t, tc.hashes, hashes)
+				require.Equal(t, tc.next, next)
+				return nil
+			})
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestAddressTxnSeqsLast(t *testing.T) {
+	db, td := prepareDB(t)
+	defer td()
+
+	addr := makeAddress()
+	otherAddr := makeAddress()
+
+	cursors := []AddressTxnsCursor{
+		{BlockSeq: 1, TxnIndex: 0},
+		{BlockSeq: 1, TxnIndex: 2},
+		{BlockSeq: 256, TxnIndex: 0},
+	}
+
+	var hashes []cipher.SHA256
+	for i := range cursors {
+		hashes = append(hashes, cipher.SumSHA256([]byte(fmt.Sprintf("tx%d", i))))
+	}
+
+	addrTxnSeqs := &addressTxnSeqs{}
+
+	err := db.Update("", func(tx *dbutil.Tx) error {
+		for i := range cursors {
+			require.NoError(t, addrTxnSeqs.add(tx, addr, cursors[i], hashes[i]))
+		}
+		return addrTxnSeqs.add(tx, otherAddr, AddressTxnsCursor{BlockSeq: 500}, cipher.SumSHA256([]byte("other")))
+	})
+	require.NoError(t, err)
+
+	err = db.View("", func(tx *dbutil.Tx) error {
+		last, err := addrTxnSeqs.last(tx, addr, 2)
+		require.NoError(t, err)
+		require.Equal(t, []cipher.SHA256{hashes[2], hashes[1]}, last)
+
+		last, err = addrTxnSeqs.last(tx, addr, 10)
+		require.NoError(t, err)
+		require.Equal(t, []cipher.SHA256{hashes[2], hashes[1], hashes[0]}, last)
+
+		last, err = addrTxnSeqs.last(tx, addr, 0)
+		require.NoError(t, err)
+		require.Empty(t, last)
+
+		last, err = addrTxnSeqs.last(tx, makeAddress(), 10)
+		require.NoError(t, err)
+		require.Empty(t, last)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestGetRecentTransactionsForAddrs(t *testing.T) {
+	db, teardown := prepareDB(t)
+	defer teardown()
+	bc := newBlockchain()
+	gb := bc.CreateGenesisBlock(genAddress, genCoins, genTime)
+	hisDB := New()
+
+	err := db.Update("", func(tx *dbutil.Tx) error {
+		return hisDB.ParseBlock(tx, gb)
+	})
+	require.NoError(t, err)
+
+	tds := newProcessBlockTestData(gb)
+	var txns []coin.Transaction
+	for i, td := range tds {
+		b, txn, err := addBlock(bc, td, incTime*(uint64(i)+1))
+		require.NoError(t, err)
+
+		if i+1 < len(tds) {
+			tds[i+1].Vin.TxID = txn.Hash()
+			tds[i+1].PreBlockHash = b.HashHeader()
+		}
+
+		err = db.Update("", func(tx *dbutil.Tx) error {
+			return hisDB.ParseBlock(tx, *b)
+		})
+		require.NoError(t, err)
+		txns = append(txns, *txn)
+	}
+
+	addrA := cipher.MustDecodeBase58Address("2RxP5N26GhDqHrP6SK45ZzEMSmSpeUeWxsS")
+	unused := makeAddress()
+
+	err = db.View("", func(tx *dbutil.Tx) error {
+		addrTxns, err := hisDB.GetRecentTransactionsForAddrs(tx, []cipher.Address{addrA, genAddress, unused, addrA}, 1)
+		require.NoError(t, err)
+		require.Len(t, addrTxns, 3)
+
+		require.Len(t, addrTxns[addrA], 1)
+		require.Equal(t, txns[1], addrTxns[addrA][0].Txn)
+		require.Equal(t, uint64(2), addrTxns[addrA][0].BlockSeq)
+
+		require.Len(t, addrTxns[genAddress], 1)
+		require.Equal(t, txns[0], addrTxns[genAddress][0].Txn)
+
+		require.Empty(t, addrTxns[unused])
+
+		addrTxns, err = hisDB.GetRecentTransactionsForAddrs(tx, []cipher.Address{addrA}, 10)
+		require.NoError(t, err)
+		require.Len(t, addrTxns[addrA], 2)
+		require.Equal(t, txns[1], addrTxns[addrA][0].Txn)
+		require.Equal(t, txns[0], addrTxns[addrA][1].Txn)
+		return nil
+	})
+	require.NoError(t, err)
+}
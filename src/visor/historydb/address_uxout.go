@@ -16,7 +16,7 @@ type addressUx struct{}
 func (au *addressUx) get(tx *dbutil.Tx, address cipher.Address) ([]cipher.SHA256, error) {
 	var uxHashes []cipher.SHA256
 
-	if ok, err := dbutil.GetBucketObjectDecoded(tx, AddressUxBkt, address.Bytes(), &uxHashes); err != nil {
+	if ok, err := dbutil.GetBucketObjectDecoded(tx, AddressUxBkt, addressKey(tx, address), &uxHashes); err != nil {
 		return nil, err
 	} else if !ok {
 		return nil, nil
@@ -40,7 +40,22 @@ func (au *addressUx) add(tx *dbutil.Tx, address cipher.Address, uxHash cipher.SH
 	}
 
 	hashes = append(hashes, uxHash)
-	return dbutil.PutBucketValue(tx, AddressUxBkt, address.Bytes(), encoder.Serialize(hashes))
+	return dbutil.PutBucketValue(tx, AddressUxBkt, addressKey(tx, address), encoder.Serialize(hashes))
+}
+
+// remove removes a hash from an address's hash list
+func (au *addressUx) remove(tx *dbutil.Tx, address cipher.Address, uxHash cipher.SHA256) error {
+	hashes, err := au.get(tx, address)
+	if err != nil {
+		return err
+	}
+
+	hashes = removeHash(hashes, uxHash)
+	if len(hashes) == 0 {
+		return dbutil.Delete(tx, AddressUxBkt, addressKey(tx, address))
+	}
+
+	return dbutil.PutBucketValue(tx, AddressUxBkt, addressKey(tx, address), encoder.Serialize(hashes))
 }
 
 // isEmpty checks if the addressUx bucket is empty
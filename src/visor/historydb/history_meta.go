@@ -8,6 +8,8 @@ var (
 	// HistoryMetaBkt holds history metadata
 	HistoryMetaBkt  = []byte("history_meta")
 	parsedHeightKey = []byte("parsed_height")
+	// seq of the block after which the history of a database bootstrapped from an unspent output set snapshot starts
+	bootstrapSeqKey = []byte("bootstrap_seq")
 )
 
 // historyMeta bucket for storing block history meta info
@@ -30,6 +32,24 @@ func (hm *historyMeta) setParsedBlockSeq(tx *dbutil.Tx, h uint64) error {
 	return dbutil.PutBucketValue(tx, HistoryMetaBkt, parsedHeightKey, dbutil.Itob(h))
 }
 
+// bootstrapSeq returns the seq of the unspent output set snapshot the history starts from,
+// false if the history starts from the genesis block
+func (hm *historyMeta) bootstrapSeq(tx *dbutil.Tx) (uint64, bool, error) {
+	v, err := dbutil.GetBucketValue(tx, HistoryMetaBkt, bootstrapSeqKey)
+	if err != nil {
+		return 0, false, err
+	} else if v == nil {
+		return 0, false, nil
+	}
+
+	return dbutil.Btoi(v), true, nil
+}
+
+// setBootstrapSeq updates the seq of the unspent output set snapshot the history starts from
+func (hm *historyMeta) setBootstrapSeq(tx *dbutil.Tx, seq uint64) error {
+	return dbutil.PutBucketValue(tx, HistoryMetaBkt, bootstrapSeqKey, dbutil.Itob(seq))
+}
+
 // reset resets the bucket
 func (hm *historyMeta) reset(tx *dbutil.Tx) error {
 	return dbutil.Reset(tx, HistoryMetaBkt)
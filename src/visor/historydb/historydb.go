@@ -4,8 +4,10 @@ Package historydb stores historical blockchain data.
 package historydb
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/skycoin/skycoin/src/cipher"
@@ -20,38 +22,97 @@ var logger = logging.MustGetLogger("historydb")
 func CreateBuckets(tx *dbutil.Tx) error {
 	return dbutil.CreateBuckets(tx, [][]byte{
 		AddressTxnsBkt,
+		AddressTxnSeqsBkt,
 		AddressUxBkt,
+		AddressMetaBkt,
 		HistoryMetaBkt,
 		UxOutsBkt,
 		TransactionsBkt,
+		TxnBlocksBkt,
+		UxOutSpendersBkt,
+		AddressMetaUndosBkt,
+		AddressBalanceDeltasBkt,
+		RichlistBkt,
+		BalanceDistributionBkt,
+		OutputValuesBkt,
 	})
 }
 
+// KeyMACBuckets are the buckets keyed by addresses. In an encrypted database, the addresses
+// of their keys are hidden with dbutil.Tx.KeyMAC, see addressKey. They are emptied when the database
+// is encrypted, and the history is parsed again.
+var KeyMACBuckets = [][]byte{
+	AddressTxnsBkt,
+	AddressTxnSeqsBkt,
+	AddressUxBkt,
+	AddressMetaBkt,
+	AddressBalanceDeltasBkt,
+	RichlistBkt,
+}
+
+// addressKey returns the key of an address in the KeyMACBuckets
+func addressKey(tx *dbutil.Tx, addr cipher.Address) []byte {
+	return tx.KeyMAC(addr.Bytes())
+}
+
 // HistoryDB provides APIs for blockchain explorer
 type HistoryDB struct {
-	outputs  *uxOuts       // outputs bucket
-	txns     *transactions // transactions bucket
-	addrUx   *addressUx    // bucket which stores all UxOuts that address received
-	addrTxns *addressTxns  // address related transaction bucket
-	meta     *historyMeta  // stores history meta info
+	outputs      *uxOuts               // outputs bucket
+	spenders     *uxOutSpenders        // bucket which stores the transactions that spent the outputs
+	txns         *transactions         // transactions bucket
+	txnBlocks    *txnBlocks            // bucket which stores the position of the transactions in the blockchain
+	addrUx       *addressUx            // bucket which stores all UxOuts that address received
+	addrTxns     *addressTxns          // address related transaction bucket
+	addrTxnSeqs  *addressTxnSeqs       // address related transaction bucket, ordered by block seq
+	addrMeta     *addressMetas         // address activity summary bucket
+	addrUndos    *addressMetaUndos     // bucket which stores the address metas before the most recent blocks changed them
+	addrBalances *addressBalanceDeltas // journal of the balance changes of the addresses in each block
+	richlist     *richlist             // index of the addresses by balance and of the balance distribution
+	outputValues *outputValues         // index of the outputs by coins and creation block seq
+	meta         *historyMeta          // stores history meta info
 }
 
 // New create HistoryDB instance
 func New() *HistoryDB {
 	return &HistoryDB{
-		outputs:  &uxOuts{},
-		txns:     &transactions{},
-		addrUx:   &addressUx{},
-		addrTxns: &addressTxns{},
-		meta:     &historyMeta{},
+		outputs:      &uxOuts{},
+		spenders:     &uxOutSpenders{},
+		txns:         &transactions{},
+		txnBlocks:    &txnBlocks{},
+		addrUx:       &addressUx{},
+		addrTxns:     &addressTxns{},
+		addrTxnSeqs:  &addressTxnSeqs{},
+		addrMeta:     &addressMetas{},
+		addrUndos:    &addressMetaUndos{},
+		addrBalances: &addressBalanceDeltas{},
+		richlist:     &richlist{},
+		outputValues: &outputValues{},
+		meta:         &historyMeta{},
 	}
 }
 
+// AddressIndexesEmptied returns true if blocks were parsed but the KeyMACBuckets are empty,
+// because they were emptied when the database was encrypted. NeedsReset is true too,
+// and the history is parsed again.
+func (hd *HistoryDB) AddressIndexesEmptied(tx *dbutil.Tx) (bool, error) {
+	if !dbutil.Exists(tx, HistoryMetaBkt) {
+		return false, nil
+	}
+
+	if _, ok, err := hd.meta.parsedBlockSeq(tx); err != nil {
+		return false, err
+	} else if !ok {
+		return false, nil
+	}
+
+	return isEmptyOrMissing(tx, AddressTxnsBkt)
+}
+
 // NeedsReset checks if need to reset the parsed block history,
 // If we have a new added bucket, we need to reset to parse
 // blockchain again to get the new bucket filled.
 func (hd *HistoryDB) NeedsReset(tx *dbutil.Tx) (bool, error) {
-	_, ok, err := hd.meta.parsedBlockSeq(tx)
+	parsedSeq, ok, err := hd.meta.parsedBlockSeq(tx)
 	if err != nil {
 		return false, err
 	} else if !ok {
@@ -79,7 +140,53 @@ func (hd *HistoryDB) NeedsReset(tx *dbutil.Tx) (bool, error) {
 		return false, err
 	}
 
-	if addrTxnsEmpty || addrUxEmpty || txnsEmpty || outputsEmpty {
+	addrMetaEmpty, err := hd.addrMeta.isEmpty(tx)
+	if err != nil {
+		return false, err
+	}
+
+	addrTxnSeqsEmpty, err := hd.addrTxnSeqs.isEmpty(tx)
+	if err != nil {
+		return false, err
+	}
+
+	txnBlocksEmpty, err := hd.txnBlocks.isEmpty(tx)
+	if err != nil {
+		return false, err
+	}
+
+	addrBalancesEmpty, err := hd.addrBalances.isEmpty(tx)
+	if err != nil {
+		return false, err
+	}
+
+	richlistEmpty, err := hd.richlist.isEmpty(tx)
+	if err != nil {
+		return false, err
+	}
+
+	outputValuesEmpty, err := hd.outputValues.isEmpty(tx)
+	if err != nil {
+		return false, err
+	}
+
+	if addrTxnsEmpty || addrUxEmpty || txnsEmpty || outputsEmpty || addrMetaEmpty || addrTxnSeqsEmpty || txnBlocksEmpty || addrBalancesEmpty || richlistEmpty || outputValuesEmpty {
+		return true, nil
+	}
+
+	// the genesis block doesn't spend any outputs, every later block does.
+	// The history of a database bootstrapped from an unspent output set snapshot starts after the snapshot block.
+	bootstrapSeq, _, err := hd.meta.bootstrapSeq(tx)
+	if err != nil {
+		return false, err
+	}
+
+	spendersEmpty, err := hd.spenders.isEmpty(tx)
+	if err != nil {
+		return false, err
+	}
+
+	if spendersEmpty && parsedSeq > bootstrapSeq {
 		return true, nil
 	}
 
@@ -97,14 +204,46 @@ func (hd *HistoryDB) Erase(tx *dbutil.Tx) error {
 		return err
 	}
 
+	if err := hd.addrTxnSeqs.reset(tx); err != nil {
+		return err
+	}
+
+	if err := hd.addrMeta.reset(tx); err != nil {
+		return err
+	}
+
+	if err := hd.addrUndos.reset(tx); err != nil {
+		return err
+	}
+
+	if err := hd.addrBalances.reset(tx); err != nil {
+		return err
+	}
+
+	if err := hd.richlist.reset(tx); err != nil {
+		return err
+	}
+
+	if err := hd.outputValues.reset(tx); err != nil {
+		return err
+	}
+
 	if err := hd.outputs.reset(tx); err != nil {
 		return err
 	}
 
+	if err := hd.spenders.reset(tx); err != nil {
+		return err
+	}
+
 	if err := hd.meta.reset(tx); err != nil {
 		return err
 	}
 
+	if err := hd.txnBlocks.reset(tx); err != nil {
+		return err
+	}
+
 	return hd.txns.reset(tx)
 }
 
@@ -118,6 +257,65 @@ func (hd *HistoryDB) SetParsedBlockSeq(tx *dbutil.Tx, seq uint64) error {
 	return hd.meta.setParsedBlockSeq(tx, seq)
 }
 
+// BootstrapSeq returns the seq of the block after which the history of a database bootstrapped
+// from an unspent output set snapshot starts, false if the history starts from the genesis block
+func (hd *HistoryDB) BootstrapSeq(tx *dbutil.Tx) (uint64, bool, error) {
+	return hd.meta.bootstrapSeq(tx)
+}
+
+// ParseUnspentSnapshot starts the history of a database bootstrapped from an unspent output set snapshot
+// after the block of seq and time. The genesis block is parsed, and the unspent outputs uxs are indexed as outputs of their
+// addresses, so that the blocks after seq can spend them. The transactions of the blocks between the genesis block
+// and seq are not known, and the genesis block outputs spent by them are not marked as spent.
+// The address balance deltas of the block of seq set the balances of the addresses to their balances in uxs.
+func (hd *HistoryDB) ParseUnspentSnapshot(tx *dbutil.Tx, genesis coin.Block, seq, time uint64, uxs coin.UxArray) error {
+	if err := hd.parseBlock(tx, genesis, false); err != nil {
+		return err
+	}
+
+	if seq > 0 {
+		changes, err := hd.addrBalances.applySnapshot(tx, genesis, seq, time, uxs)
+		if err != nil {
+			return err
+		}
+
+		if err := hd.richlist.apply(tx, changes); err != nil {
+			return err
+		}
+	}
+
+	for _, ux := range uxs {
+		h := ux.Hash()
+
+		// The unspent outputs of the genesis block were added by parsing it
+		if o, err := hd.outputs.get(tx, h); err != nil {
+			return err
+		} else if o != nil {
+			continue
+		}
+
+		if err := hd.outputs.put(tx, UxOut{
+			Out: ux,
+		}); err != nil {
+			return err
+		}
+
+		if err := hd.addrUx.add(tx, ux.Body.Address, h); err != nil {
+			return err
+		}
+
+		if err := hd.outputValues.add(tx, ux); err != nil {
+			return err
+		}
+	}
+
+	if err := hd.meta.setBootstrapSeq(tx, seq); err != nil {
+		return err
+	}
+
+	return hd.SetParsedBlockSeq(tx, seq)
+}
+
 // GetUxOuts get UxOut of specific uxIDs.
 func (hd *HistoryDB) GetUxOuts(tx *dbutil.Tx, uxIDs []cipher.SHA256) ([]UxOut, error) {
 	return hd.outputs.getArray(tx, uxIDs)
@@ -125,7 +323,64 @@ func (hd *HistoryDB) GetUxOuts(tx *dbutil.Tx, uxIDs []cipher.SHA256) ([]UxOut, e
 
 // ParseBlock builds indexes out of the block data
 func (hd *HistoryDB) ParseBlock(tx *dbutil.Tx, b coin.Block) error {
-	for _, t := range b.Body.Transactions {
+	if err := hd.parseBlock(tx, b, false); err != nil {
+		return err
+	}
+
+	return hd.SetParsedBlockSeq(tx, b.Seq())
+}
+
+// RepairBlock rebuilds the indexes of a block that was already parsed.
+// Unlike ParseBlock, the spend data of the block's outputs is preserved if they were spent by a later block,
+// the address uxout indexes of the block's inputs are restored, and the parsed block seq is not changed.
+// The address metas are not changed, since they summarize the later blocks too. A corrupted address meta
+// is only fixed by erasing and parsing the history db again.
+// The address transaction seqs, transaction blocks and uxout spenders are only repaired if the history db was parsed with them.
+// Blocks must be repaired in ascending seq order.
+func (hd *HistoryDB) RepairBlock(tx *dbutil.Tx, b coin.Block) error {
+	return hd.parseBlock(tx, b, true)
+}
+
+func (hd *HistoryDB) parseBlock(tx *dbutil.Tx, b coin.Block, repair bool) error {
+	// A repair doesn't start filling the indexes of a history db parsed before they were added
+	addTxnSeqs, addTxnBlocks, addSpenders, addOutputValues := true, true, true, true
+	if repair {
+		addrTxnSeqsEmpty, err := isEmptyOrMissing(tx, AddressTxnSeqsBkt)
+		if err != nil {
+			return err
+		}
+		addTxnSeqs = !addrTxnSeqsEmpty
+
+		txnBlocksEmpty, err := isEmptyOrMissing(tx, TxnBlocksBkt)
+		if err != nil {
+			return err
+		}
+		addTxnBlocks = !txnBlocksEmpty
+
+		spendersEmpty, err := isEmptyOrMissing(tx, UxOutSpendersBkt)
+		if err != nil {
+			return err
+		}
+		addSpenders = !spendersEmpty
+
+		outputValuesEmpty, err := isEmptyOrMissing(tx, OutputValuesBkt)
+		if err != nil {
+			return err
+		}
+		addOutputValues = !outputValuesEmpty
+	}
+
+	blockHash := b.HashHeader()
+	undo := newBlockAddressMetaUndo()
+	balances := newAddressActivities()
+
+	for i, t := range b.Body.Transactions {
+		activities := newAddressActivities()
+		txnCursor := AddressTxnsCursor{
+			BlockSeq: b.Seq(),
+			TxnIndex: uint64(i),
+		}
+
 		txn := Transaction{
 			Txn:      t,
 			BlockSeq: b.Seq(),
@@ -135,7 +390,17 @@ func (hd *HistoryDB) ParseBlock(tx *dbutil.Tx, b coin.Block) error {
 			return err
 		}
 
-		for _, in := range t.In {
+		if addTxnBlocks {
+			if err := hd.txnBlocks.put(tx, t.Hash(), TxnBlock{
+				BlockSeq:  b.Seq(),
+				TxnIndex:  uint64(i),
+				BlockHash: blockHash,
+			}); err != nil {
+				return err
+			}
+		}
+
+		for j, in := range t.In {
 			o, err := hd.outputs.get(tx, in)
 			if err != nil {
 				return err
@@ -145,6 +410,16 @@ func (hd *HistoryDB) ParseBlock(tx *dbutil.Tx, b coin.Block) error {
 				return errors.New("HistoryDB.ParseBlock: transaction input not found in outputs bucket")
 			}
 
+			if addSpenders {
+				if err := hd.spenders.put(tx, in, UxOutSpender{
+					TxnID:      t.Hash(),
+					BlockSeq:   b.Seq(),
+					InputIndex: uint64(j),
+				}); err != nil {
+					return err
+				}
+			}
+
 			// update the output's spent block seq and txid
 			o.SpentBlockSeq = b.Seq()
 			o.SpentTxnID = t.Hash()
@@ -156,14 +431,45 @@ func (hd *HistoryDB) ParseBlock(tx *dbutil.Tx, b coin.Block) error {
 			if err := hd.addrTxns.add(tx, o.Out.Body.Address, t.Hash()); err != nil {
 				return err
 			}
+
+			if addTxnSeqs {
+				if err := hd.addrTxnSeqs.add(tx, o.Out.Body.Address, txnCursor, t.Hash()); err != nil {
+					return err
+				}
+			}
+
+			activities.addSent(o.Out.Body.Address, o.Out.Body.Coins)
+			balances.addSent(o.Out.Body.Address, o.Out.Body.Coins)
+
+			// the uxout index of the input's address is normally added by the block that created it,
+			// but a missing index is only detected when verifying the block that spends it
+			if repair {
+				if err := hd.addrUx.add(tx, o.Out.Body.Address, in); err != nil {
+					return err
+				}
+			}
 		}
 
 		// handle the tx out
 		uxArray := coin.CreateUnspents(b.Head, t)
 		for _, ux := range uxArray {
-			if err := hd.outputs.put(tx, UxOut{
+			out := UxOut{
 				Out: ux,
-			}); err != nil {
+			}
+
+			if repair {
+				o, err := hd.outputs.get(tx, ux.Hash())
+				if err != nil {
+					return err
+				}
+
+				if o != nil {
+					out.SpentBlockSeq = o.SpentBlockSeq
+					out.SpentTxnID = o.SpentTxnID
+				}
+			}
+
+			if err := hd.outputs.put(tx, out); err != nil {
 				return err
 			}
 
@@ -171,13 +477,203 @@ func (hd *HistoryDB) ParseBlock(tx *dbutil.Tx, b coin.Block) error {
 				return err
 			}
 
+			if addOutputValues {
+				if err := hd.outputValues.add(tx, ux); err != nil {
+					return err
+				}
+			}
+
 			if err := hd.addrTxns.add(tx, ux.Body.Address, t.Hash()); err != nil {
 				return err
 			}
+
+			if addTxnSeqs {
+				if err := hd.addrTxnSeqs.add(tx, ux.Body.Address, txnCursor, t.Hash()); err != nil {
+					return err
+				}
+			}
+
+			activities.addReceived(ux.Body.Address, ux.Body.Coins)
+			balances.addReceived(ux.Body.Address, ux.Body.Coins)
+		}
+
+		if !repair {
+			if err := hd.addrMeta.apply(tx, activities, b.Seq(), b.Time(), undo); err != nil {
+				return err
+			}
 		}
 	}
 
-	return hd.SetParsedBlockSeq(tx, b.Seq())
+	if repair {
+		return nil
+	}
+
+	changes, err := hd.addrBalances.apply(tx, balances, b.Seq(), b.Time())
+	if err != nil {
+		return err
+	}
+
+	if err := hd.richlist.apply(tx, changes); err != nil {
+		return err
+	}
+
+	if err := hd.addrUndos.put(tx, b.Seq(), undo.undos); err != nil {
+		return err
+	}
+
+	if b.Seq() < UnparseDepth {
+		return nil
+	}
+
+	return hd.addrUndos.delete(tx, b.Seq()-UnparseDepth)
+}
+
+// UnparseBlock removes the indexes of the most recently parsed block b, undoing ParseBlock.
+// The outputs spent by b are marked as unspent again, and the address metas are restored from the
+// address meta undo of b, so only the UnparseDepth most recent parsed blocks can be unparsed.
+// The parsed block seq is set to the previous block.
+func (hd *HistoryDB) UnparseBlock(tx *dbutil.Tx, b coin.Block) error {
+	seq := b.Seq()
+	parsedSeq, ok, err := hd.meta.parsedBlockSeq(tx)
+	if err != nil {
+		return err
+	} else if !ok || parsedSeq != seq {
+		return fmt.Errorf("HistoryDB.UnparseBlock: block %d is not the last parsed block", seq)
+	}
+
+	if seq == 0 {
+		return errors.New("HistoryDB.UnparseBlock: the genesis block can't be unparsed")
+	}
+
+	if bootstrapSeq, ok, err := hd.meta.bootstrapSeq(tx); err != nil {
+		return err
+	} else if ok && seq <= bootstrapSeq {
+		return fmt.Errorf("HistoryDB.UnparseBlock: block %d is part of the unspent output set snapshot the history starts from", seq)
+	}
+
+	undos, ok, err := hd.addrUndos.get(tx, seq)
+	if err != nil {
+		return err
+	} else if !ok {
+		return fmt.Errorf("HistoryDB.UnparseBlock: address meta undo of block %d is not stored", seq)
+	}
+
+	balanceAddrs := newAddressSet()
+	for i := len(b.Body.Transactions) - 1; i >= 0; i-- {
+		t := b.Body.Transactions[i]
+		txnHash := t.Hash()
+		txnCursor := AddressTxnsCursor{
+			BlockSeq: seq,
+			TxnIndex: uint64(i),
+		}
+
+		for _, ux := range coin.CreateUnspents(b.Head, t) {
+			h := ux.Hash()
+
+			if err := hd.outputs.delete(tx, h); err != nil {
+				return err
+			}
+
+			if err := hd.addrUx.remove(tx, ux.Body.Address, h); err != nil {
+				return err
+			}
+
+			if err := hd.outputValues.delete(tx, ux); err != nil {
+				return err
+			}
+
+			if err := hd.addrTxns.remove(tx, ux.Body.Address, txnHash); err != nil {
+				return err
+			}
+
+			if err := hd.addrTxnSeqs.delete(tx, ux.Body.Address, txnCursor); err != nil {
+				return err
+			}
+
+			balanceAddrs.add(ux.Body.Address)
+		}
+
+		for _, in := range t.In {
+			o, err := hd.outputs.get(tx, in)
+			if err != nil {
+				return err
+			}
+
+			if o == nil {
+				return errors.New("HistoryDB.UnparseBlock: transaction input not found in outputs bucket")
+			}
+
+			if err := hd.spenders.delete(tx, in); err != nil {
+				return err
+			}
+
+			o.SpentBlockSeq = 0
+			o.SpentTxnID = cipher.SHA256{}
+			if err := hd.outputs.put(tx, *o); err != nil {
+				return err
+			}
+
+			if err := hd.addrTxns.remove(tx, o.Out.Body.Address, txnHash); err != nil {
+				return err
+			}
+
+			if err := hd.addrTxnSeqs.delete(tx, o.Out.Body.Address, txnCursor); err != nil {
+				return err
+			}
+
+			balanceAddrs.add(o.Out.Body.Address)
+		}
+
+		if err := hd.txnBlocks.delete(tx, txnHash); err != nil {
+			return err
+		}
+
+		if err := hd.txns.delete(tx, txnHash); err != nil {
+			return err
+		}
+	}
+
+	changes, err := hd.addrBalances.unapply(tx, balanceAddrs.addrs, seq)
+	if err != nil {
+		return err
+	}
+
+	if err := hd.richlist.apply(tx, changes); err != nil {
+		return err
+	}
+
+	if err := hd.addrMeta.undo(tx, undos); err != nil {
+		return err
+	}
+
+	if err := hd.addrUndos.delete(tx, seq); err != nil {
+		return err
+	}
+
+	return hd.SetParsedBlockSeq(tx, seq-1)
+}
+
+// GetOutputsByValuePage returns a page of the outputs with coins in a range created by the blocks in a range,
+// spent or not, in coins, block seq and output hash order. The page starts from the cursor, or from the first
+// selected output if the cursor is nil. At most limit outputs are returned, all of them if limit is 0.
+// The returned cursor is the start of the next page, nil if there are no more outputs.
+func (hd HistoryDB) GetOutputsByValuePage(tx *dbutil.Tx, q OutputValuesQuery, cursor *OutputValuesCursor, limit uint64) ([]UxOut, *OutputValuesCursor, error) {
+	hashes, next, err := hd.outputValues.page(tx, q, cursor, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	outs, err := hd.outputs.getArray(tx, hashes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return outs, next, nil
+}
+
+// GetUxOutSpender returns the transaction input which spent an output, nil if the output is unspent or not found
+func (hd HistoryDB) GetUxOutSpender(tx *dbutil.Tx, uxID cipher.SHA256) (*UxOutSpender, error) {
+	return hd.spenders.get(tx, uxID)
 }
 
 // GetTransaction get transaction by hash.
@@ -185,6 +681,11 @@ func (hd HistoryDB) GetTransaction(tx *dbutil.Tx, hash cipher.SHA256) (*Transact
 	return hd.txns.get(tx, hash)
 }
 
+// GetTxnBlock returns the position of a transaction in the blockchain, nil if the transaction is not found
+func (hd HistoryDB) GetTxnBlock(tx *dbutil.Tx, hash cipher.SHA256) (*TxnBlock, error) {
+	return hd.txnBlocks.get(tx, hash)
+}
+
 // GetOutputsForAddress get all uxout that the address affected.
 func (hd HistoryDB) GetOutputsForAddress(tx *dbutil.Tx, address cipher.Address) ([]UxOut, error) {
 	hashes, err := hd.addrUx.get(tx, address)
@@ -205,11 +706,122 @@ func (hd HistoryDB) GetTransactionsForAddress(tx *dbutil.Tx, address cipher.Addr
 	return hd.txns.getArray(tx, hashes)
 }
 
+// GetTransactionsForAddressPage returns a page of the address related transactions, in block seq order.
+// The page starts from the cursor, or from the first transaction if the cursor is nil, skipping the first offset transactions.
+// At most limit transactions are returned, all of them if limit is 0.
+// The returned cursor is the start of the next page, nil if there are no more transactions.
+func (hd HistoryDB) GetTransactionsForAddressPage(tx *dbutil.Tx, address cipher.Address, cursor *AddressTxnsCursor, offset, limit uint64) ([]Transaction, *AddressTxnsCursor, error) {
+	hashes, next, err := hd.addrTxnSeqs.page(tx, address, cursor, offset, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	txns, err := hd.txns.getArray(tx, hashes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return txns, next, nil
+}
+
+// GetRecentTransactionsForAddrs returns the n most recent transactions of each of the addresses,
+// most recent first. The addresses and the transactions are looked up in key order,
+// so that scanning many addresses walks each bucket once instead of seeking at random.
+func (hd HistoryDB) GetRecentTransactionsForAddrs(tx *dbutil.Tx, addrs []cipher.Address, n uint64) (map[cipher.Address][]Transaction, error) {
+	sorted := make([]cipher.Address, len(addrs))
+	copy(sorted, addrs)
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i].Bytes(), sorted[j].Bytes()) < 0
+	})
+
+	addrHashes := make(map[cipher.Address][]cipher.SHA256, len(sorted))
+	hashSet := make(map[cipher.SHA256]struct{})
+	for _, addr := range sorted {
+		if _, ok := addrHashes[addr]; ok {
+			continue
+		}
+
+		hashes, err := hd.addrTxnSeqs.last(tx, addr, n)
+		if err != nil {
+			return nil, err
+		}
+
+		addrHashes[addr] = hashes
+		for _, h := range hashes {
+			hashSet[h] = struct{}{}
+		}
+	}
+
+	hashes := make([]cipher.SHA256, 0, len(hashSet))
+	for h := range hashSet {
+		hashes = append(hashes, h)
+	}
+	sort.Slice(hashes, func(i, j int) bool {
+		return bytes.Compare(hashes[i][:], hashes[j][:]) < 0
+	})
+
+	txns, err := hd.txns.getArray(tx, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	txnsMap := make(map[cipher.SHA256]Transaction, len(txns))
+	for i, h := range hashes {
+		txnsMap[h] = txns[i]
+	}
+
+	addrTxns := make(map[cipher.Address][]Transaction, len(addrHashes))
+	for addr, hashes := range addrHashes {
+		txns := make([]Transaction, len(hashes))
+		for i, h := range hashes {
+			txns[i] = txnsMap[h]
+		}
+		addrTxns[addr] = txns
+	}
+
+	return addrTxns, nil
+}
+
+// GetAddressBalanceAt returns the last balance delta of an address at or before the block of seq,
+// whose Balance is the coin balance of the address after the block of seq. Returns nil if the address
+// received no outputs up to the block of seq.
+func (hd HistoryDB) GetAddressBalanceAt(tx *dbutil.Tx, address cipher.Address, seq uint64) (*AddressBalanceDelta, error) {
+	return hd.addrBalances.last(tx, address, seq)
+}
+
+// GetAddressBalanceDeltas returns the balance deltas of an address in the blocks of the seq range [start, end],
+// in block seq order
+func (hd HistoryDB) GetAddressBalanceDeltas(tx *dbutil.Tx, address cipher.Address, start, end uint64) ([]AddressBalanceDelta, error) {
+	return hd.addrBalances.inRange(tx, address, start, end)
+}
+
+// ForEachRichlistEntry calls f on the addresses with a balance, in descending balance order and ties in address
+// byte order. The iteration stops when f returns false or an error.
+func (hd HistoryDB) ForEachRichlistEntry(tx *dbutil.Tx, f func(RichlistEntry) (bool, error)) error {
+	return hd.richlist.forEach(tx, f)
+}
+
+// GetBalanceDistribution returns the number of addresses and the coins they hold in each range of
+// BalanceDistributionThresholds, in ascending order of balance
+func (hd HistoryDB) GetBalanceDistribution(tx *dbutil.Tx) ([]BalanceRange, error) {
+	return hd.richlist.distribution(tx)
+}
+
+// GetAddressMeta returns the activity summary of an address, nil if the address was never used
+func (hd HistoryDB) GetAddressMeta(tx *dbutil.Tx, address cipher.Address) (*AddressMeta, error) {
+	return hd.addrMeta.get(tx, address)
+}
+
 // ForEachTxn traverses the transactions bucket
 func (hd HistoryDB) ForEachTxn(tx *dbutil.Tx, f func(cipher.SHA256, *Transaction) error) error {
 	return hd.txns.forEach(tx, f)
 }
 
+// ForEachUxOut traverses the outputs bucket
+func (hd HistoryDB) ForEachUxOut(tx *dbutil.Tx, f func(*UxOut) error) error {
+	return hd.outputs.forEach(tx, f)
+}
+
 // IndexesMap is a goroutine safe address indexes map
 type IndexesMap struct {
 	value map[cipher.Address]AddressIndexes
@@ -244,145 +856,33 @@ type AddressIndexes struct {
 	UxHashes  map[cipher.SHA256]struct{}
 }
 
-// Verify checks if the historydb is corrupted
+// Verify checks if the historydb is corrupted. The ErrHistoryDBCorrupted returned describes the first broken
+// index entry of the block, VerifyDiff lists all of them.
 func (hd HistoryDB) Verify(tx *dbutil.Tx, b *coin.SignedBlock, indexesMap *IndexesMap) error {
-	for _, t := range b.Body.Transactions {
-		txnHash := t.Hash()
-		txn, err := hd.txns.get(tx, txnHash)
-		if err != nil {
-			return err
-		}
-
-		if txn == nil {
-			err := fmt.Errorf("HistoryDB.Verify: transaction %v does not exist in historydb", txnHash.Hex())
-			return ErrHistoryDBCorrupted{err}
-		}
-
-		for _, in := range t.In {
-			// Checks the existence of transaction input
-			o, err := hd.outputs.get(tx, in)
-			if err != nil {
-				return err
-			}
-
-			if o == nil {
-				err := fmt.Errorf("HistoryDB.Verify: transaction input %v does not exist in historydb", in.Hex())
-				return ErrHistoryDBCorrupted{err}
-			}
-
-			// Checks the output's spend block seq
-			if o.SpentBlockSeq != b.Seq() {
-				err := fmt.Errorf("HistoryDB.Verify: spend block seq of transaction input %v is wrong, should be: %v, but is %v",
-					in.Hex(), b.Seq(), o.SpentBlockSeq)
-				return ErrHistoryDBCorrupted{err}
-			}
-
-			addr := o.Out.Body.Address
-			txnHashesMap := map[cipher.SHA256]struct{}{}
-			uxHashesMap := map[cipher.SHA256]struct{}{}
-
-			// Checks if the address indexes already loaded into memory
-			indexes, ok := indexesMap.Load(addr)
-			if ok {
-				txnHashesMap = indexes.TxnHashes
-				uxHashesMap = indexes.UxHashes
-			} else {
-				txnHashes, err := hd.addrTxns.get(tx, addr)
-				if err != nil {
-					return err
-				}
-				for _, hash := range txnHashes {
-					txnHashesMap[hash] = struct{}{}
-				}
-
-				uxHashes, err := hd.addrUx.get(tx, addr)
-				if err != nil {
-					return err
-				}
-				for _, hash := range uxHashes {
-					uxHashesMap[hash] = struct{}{}
-				}
-
-				indexesMap.Store(addr, AddressIndexes{
-					TxnHashes: txnHashesMap,
-					UxHashes:  uxHashesMap,
-				})
-			}
-
-			if _, ok := txnHashesMap[txnHash]; !ok {
-				err := fmt.Errorf("HistoryDB.Verify: index of address transaction [%s:%s] does not exist in historydb",
-					addr, txnHash.Hex())
-				return ErrHistoryDBCorrupted{err}
-			}
-
-			if _, ok := uxHashesMap[in]; !ok {
-				err := fmt.Errorf("HistoryDB.Verify: index of address uxout [%s:%s] does not exist in historydb",
-					addr, in.Hex())
-				return ErrHistoryDBCorrupted{err}
-			}
-		}
-
-		// Checks the transaction outs
-		uxArray := coin.CreateUnspents(b.Head, t)
-		for _, ux := range uxArray {
-			uxHash := ux.Hash()
-			out, err := hd.outputs.get(tx, uxHash)
-			if err != nil {
-				return err
-			}
-
-			if out == nil {
-				err := fmt.Errorf("HistoryDB.Verify: transaction output %s does not exist in historydb", uxHash.Hex())
-				return ErrHistoryDBCorrupted{err}
-			}
-
-			addr := ux.Body.Address
-			txnHashesMap := map[cipher.SHA256]struct{}{}
-			indexes, ok := indexesMap.Load(addr)
-			if ok {
-				txnHashesMap = indexes.TxnHashes
-			} else {
-				txnHashes, err := hd.addrTxns.get(tx, addr)
-				if err != nil {
-					return err
-				}
-				for _, hash := range txnHashes {
-					txnHashesMap[hash] = struct{}{}
-				}
-
-				uxHashes, err := hd.addrUx.get(tx, addr)
-				if err != nil {
-					return err
-				}
-
-				uxHashesMap := make(map[cipher.SHA256]struct{}, len(uxHashes))
-				for _, hash := range uxHashes {
-					uxHashesMap[hash] = struct{}{}
-				}
-
-				indexesMap.Store(addr, AddressIndexes{
-					TxnHashes: txnHashesMap,
-					UxHashes:  uxHashesMap,
-				})
-			}
+	diff, err := hd.VerifyDiff(tx, b, indexesMap)
+	if err != nil {
+		return err
+	}
 
-			if _, ok := txnHashesMap[txnHash]; !ok {
-				err := fmt.Errorf("HistoryDB.Verify: index of address transaction [%s:%s] does not exist in historydb",
-					addr, txnHash.Hex())
-				return ErrHistoryDBCorrupted{err}
-			}
-		}
+	if !diff.Empty() {
+		return NewErrHistoryDBCorrupted(errors.New(diff.Entries[0].Description))
 	}
+
 	return nil
 }
 
 // ErrHistoryDBCorrupted is returned when found the historydb is corrupted
 type ErrHistoryDBCorrupted struct {
 	error
+	// CorruptedBlocks are the seqs of the blocks whose indexes failed verification, in ascending order.
+	// It is set by CheckDatabase, and is empty for the errors returned by Verify.
+	CorruptedBlocks []uint64
 }
 
 // NewErrHistoryDBCorrupted is for user to be able to create ErrHistoryDBCorrupted instance
 // outside of the package
 func NewErrHistoryDBCorrupted(err error) ErrHistoryDBCorrupted {
-	return ErrHistoryDBCorrupted{err}
+	return ErrHistoryDBCorrupted{
+		error: err,
+	}
 }
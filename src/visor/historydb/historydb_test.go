@@ -276,7 +276,12 @@ func TestProcessBlock(t *testing.T) {
 	                   |-222uMeCeL1PbkJGZJDgAz5sib2uisv9hYUm ==>|
 	                                                            |-222uMeCeL1PbkJGZJDgAz5sib2uisv9hYUm
 	*/
-	testData := []testData{
+	testEngine(t, newProcessBlockTestData(gb), bc, hisDB, db)
+}
+
+// newProcessBlockTestData returns the test data of two blocks after the genesis block gb
+func newProcessBlockTestData(gb coin.Block) []testData {
+	return []testData{
 		{
 			PreBlockHash: gb.HashHeader(),
 			Vin: txIn{
@@ -332,8 +337,117 @@ func TestProcessBlock(t *testing.T) {
 			},
 		},
 	}
+}
+
+// dumpHistoryDB returns the content of the history db buckets, except the address meta undos
+func dumpHistoryDB(t *testing.T, db *dbutil.DB) map[string]map[string]string {
+	buckets := [][]byte{
+		AddressTxnsBkt,
+		AddressTxnSeqsBkt,
+		AddressUxBkt,
+		AddressMetaBkt,
+		HistoryMetaBkt,
+		UxOutsBkt,
+		TransactionsBkt,
+		TxnBlocksBkt,
+		UxOutSpendersBkt,
+		AddressBalanceDeltasBkt,
+		RichlistBkt,
+		BalanceDistributionBkt,
+		OutputValuesBkt,
+	}
+
+	dump := make(map[string]map[string]string, len(buckets))
+	err := db.View("", func(tx *dbutil.Tx) error {
+		for _, bkt := range buckets {
+			values := make(map[string]string)
+			if err := dbutil.ForEach(tx, bkt, func(k, v []byte) error {
+				values[string(k)] = string(v)
+				return nil
+			}); err != nil {
+				return err
+			}
+			dump[string(bkt)] = values
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	return dump
+}
+
+func TestUnparseBlock(t *testing.T) {
+	db, teardown := prepareDB(t)
+	defer teardown()
+	bc := newBlockchain()
+	gb := bc.CreateGenesisBlock(genAddress, genCoins, genTime)
+	hisDB := New()
+
+	parseBlock := func(b coin.Block) {
+		err := db.Update("", func(tx *dbutil.Tx) error {
+			return hisDB.ParseBlock(tx, b)
+		})
+		require.NoError(t, err)
+	}
+
+	unparseBlock := func(b coin.Block) error {
+		return db.Update("", func(tx *dbutil.Tx) error {
+			return hisDB.UnparseBlock(tx, b)
+		})
+	}
+
+	parseBlock(gb)
+	dumps := []map[string]map[string]string{dumpHistoryDB(t, db)}
+
+	tds := newProcessBlockTestData(gb)
+	var blocks []coin.Block
+	for i, td := range tds {
+		b, txn, err := addBlock(bc, td, incTime*(uint64(i)+1))
+		require.NoError(t, err)
+
+		if i+1 < len(tds) {
+			tds[i+1].Vin.TxID = txn.Hash()
+			tds[i+1].PreBlockHash = b.HashHeader()
+		}
+
+		parseBlock(*b)
+		blocks = append(blocks, *b)
+		dumps = append(dumps, dumpHistoryDB(t, db))
+	}
+
+	// Only the last parsed block can be unparsed
+	require.Error(t, unparseBlock(blocks[0]))
+
+	// Unparsing the blocks restores the history db as it was before they were parsed
+	for i := len(blocks) - 1; i >= 0; i-- {
+		require.NoError(t, unparseBlock(blocks[i]))
+		require.Equal(t, dumps[i], dumpHistoryDB(t, db))
+	}
+
+	require.Error(t, unparseBlock(gb))
+
+	// The blocks can be parsed again
+	for i, b := range blocks {
+		parseBlock(b)
+		require.Equal(t, dumps[i+1], dumpHistoryDB(t, db))
+	}
+
+	// An address meta undo is stored for each parsed block
+	err := db.View("", func(tx *dbutil.Tx) error {
+		n, err := dbutil.Len(tx, AddressMetaUndosBkt)
+		require.NoError(t, err)
+		require.Equal(t, uint64(len(blocks)+1), n)
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		require.NoError(t, hisDB.addrUndos.delete(tx, blocks[1].Seq()))
+		return nil
+	})
+	require.NoError(t, err)
 
-	testEngine(t, testData, bc, hisDB, db)
+	// A block whose address meta undo is not stored can't be unparsed
+	require.Error(t, unparseBlock(blocks[1]))
 }
 
 func testEngine(t *testing.T, tds []testData, bc *fakeBlockchain, hdb *HistoryDB, db *dbutil.DB) {
@@ -0,0 +1,162 @@
+package historydb
+
+import (
+	"fmt"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+// OrphanedUxOutReason is the broken reference of an orphaned output
+type OrphanedUxOutReason string
+
+const (
+	// OrphanedUxOutMissingTxn is an output whose source transaction does not exist
+	OrphanedUxOutMissingTxn OrphanedUxOutReason = "missing_txn"
+	// OrphanedUxOutTxnMismatch is an output that its source transaction does not create in the block of the output
+	OrphanedUxOutTxnMismatch OrphanedUxOutReason = "txn_mismatch"
+	// OrphanedUxOutUnparsedBlock is an output created by a block after the parsed block seq
+	OrphanedUxOutUnparsedBlock OrphanedUxOutReason = "unparsed_block"
+	// OrphanedUxOutStaleSpender is an output spent by a transaction that does not exist
+	// or by a block after the parsed block seq. The output itself is valid and only its spent fields are stale.
+	OrphanedUxOutStaleSpender OrphanedUxOutReason = "stale_spender"
+)
+
+// OrphanedUxOut is an output of the outputs bucket referencing a transaction or block that doesn't exist,
+// left behind by a partial history rebuild
+type OrphanedUxOut struct {
+	UxOut  UxOut
+	Reason OrphanedUxOutReason
+}
+
+// FindOrphanedUxOuts scans the outputs bucket and calls f for each orphaned output, in output hash order.
+// Returns the number of outputs scanned.
+// The outputs created by the genesis block, or by the blocks up to the bootstrap seq of a database bootstrapped
+// from an unspent output set snapshot, have no source transaction in the history and are not orphaned.
+func (hd HistoryDB) FindOrphanedUxOuts(tx *dbutil.Tx, f func(OrphanedUxOut) error) (uint64, error) {
+	parsedSeq, parsed, err := hd.meta.parsedBlockSeq(tx)
+	if err != nil {
+		return 0, err
+	}
+
+	bootstrapSeq, bootstrapped, err := hd.meta.bootstrapSeq(tx)
+	if err != nil {
+		return 0, err
+	}
+
+	var n uint64
+	if err := hd.outputs.forEach(tx, func(o *UxOut) error {
+		n++
+
+		// Any output is orphaned if nothing is parsed
+		reason := OrphanedUxOutUnparsedBlock
+		if parsed {
+			var err error
+			reason, err = hd.orphanedUxOutReason(tx, o, parsedSeq, bootstrapped, bootstrapSeq)
+			if err != nil {
+				return err
+			}
+		}
+
+		if reason == "" {
+			return nil
+		}
+
+		return f(OrphanedUxOut{
+			UxOut:  *o,
+			Reason: reason,
+		})
+	}); err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// orphanedUxOutReason returns the broken reference of an output, or an empty reason if the output is not orphaned
+func (hd HistoryDB) orphanedUxOutReason(tx *dbutil.Tx, o *UxOut, parsedSeq uint64, bootstrapped bool, bootstrapSeq uint64) (OrphanedUxOutReason, error) {
+	seq := o.Out.Head.BkSeq
+	if seq > parsedSeq {
+		return OrphanedUxOutUnparsedBlock, nil
+	}
+
+	if seq != 0 && (!bootstrapped || seq > bootstrapSeq) {
+		txn, err := hd.txns.get(tx, o.Out.Body.SrcTransaction)
+		if err != nil {
+			return "", err
+		}
+
+		if txn == nil {
+			return OrphanedUxOutMissingTxn, nil
+		}
+
+		if txn.BlockSeq != seq || !createsUxOut(txn, o.Hash()) {
+			return OrphanedUxOutTxnMismatch, nil
+		}
+	}
+
+	if o.SpentTxnID != (cipher.SHA256{}) {
+		if o.SpentBlockSeq > parsedSeq {
+			return OrphanedUxOutStaleSpender, nil
+		}
+
+		txn, err := hd.txns.get(tx, o.SpentTxnID)
+		if err != nil {
+			return "", err
+		}
+
+		if txn == nil || txn.BlockSeq != o.SpentBlockSeq {
+			return OrphanedUxOutStaleSpender, nil
+		}
+	}
+
+	return "", nil
+}
+
+// createsUxOut checks if a transaction creates the output of uxID
+func createsUxOut(txn *Transaction, uxID cipher.SHA256) bool {
+	for _, ux := range coin.CreateUnspents(coin.BlockHeader{BkSeq: txn.BlockSeq}, txn.Txn) {
+		if ux.Hash() == uxID {
+			return true
+		}
+	}
+	return false
+}
+
+// DeleteOrphanedUxOut removes an orphaned output found by FindOrphanedUxOuts from the outputs bucket and from the
+// output indexes. An output with a stale spender is kept and marked as unspent instead.
+// The indexes missing from a database opened without the node creating them are skipped.
+func (hd *HistoryDB) DeleteOrphanedUxOut(tx *dbutil.Tx, o OrphanedUxOut) error {
+	uxID := o.UxOut.Hash()
+
+	if dbutil.Exists(tx, UxOutSpendersBkt) {
+		if err := hd.spenders.delete(tx, uxID); err != nil {
+			return err
+		}
+	}
+
+	switch o.Reason {
+	case OrphanedUxOutStaleSpender:
+		out := o.UxOut
+		out.SpentBlockSeq = 0
+		out.SpentTxnID = cipher.SHA256{}
+		return hd.outputs.put(tx, out)
+
+	case OrphanedUxOutMissingTxn, OrphanedUxOutTxnMismatch, OrphanedUxOutUnparsedBlock:
+		if err := hd.addrUx.remove(tx, o.UxOut.Out.Body.Address, uxID); err != nil {
+			return err
+		}
+
+		if dbutil.Exists(tx, OutputValuesBkt) {
+			if err := hd.outputValues.delete(tx, o.UxOut.Out); err != nil {
+				return err
+			}
+		}
+
+		return hd.outputs.delete(tx, uxID)
+
+	default:
+		return fmt.Errorf("HistoryDB.DeleteOrphanedUxOut: unknown reason %q", o.Reason)
+	}
+}
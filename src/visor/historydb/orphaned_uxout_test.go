@@ -0,0 +1,168 @@
+package historydb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+func TestOrphanedUxOuts(t *testing.T) {
+	db, teardown := prepareDB(t)
+	defer teardown()
+	bc := newBlockchain()
+	gb := bc.CreateGenesisBlock(genAddress, genCoins, genTime)
+
+	hisDB := New()
+
+	err := db.Update("", func(tx *dbutil.Tx) error {
+		return hisDB.ParseBlock(tx, gb)
+	})
+	require.NoError(t, err)
+
+	outAddr := cipher.MustDecodeBase58Address("2RxP5N26GhDqHrP6SK45ZzEMSmSpeUeWxsS")
+	b, _, err := addBlock(bc, testData{
+		PreBlockHash: gb.HashHeader(),
+		Vin: txIn{
+			SigKey:   genSecret.Hex(),
+			Addr:     genAddress.String(),
+			TxID:     gb.Body.Transactions[0].Hash(),
+			BlockSeq: 0,
+		},
+		Vouts: []txOut{
+			{
+				ToAddr: outAddr.String(),
+				Coins:  genCoins,
+				Hours:  100,
+			},
+		},
+	}, incTime)
+	require.NoError(t, err)
+
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		return hisDB.ParseBlock(tx, *b)
+	})
+	require.NoError(t, err)
+
+	find := func() (uint64, []OrphanedUxOut) {
+		var n uint64
+		var orphans []OrphanedUxOut
+		err := db.View("", func(tx *dbutil.Tx) error {
+			var err error
+			n, err = hisDB.FindOrphanedUxOuts(tx, func(o OrphanedUxOut) error {
+				orphans = append(orphans, o)
+				return nil
+			})
+			return err
+		})
+		require.NoError(t, err)
+		return n, orphans
+	}
+
+	// A consistent history has no orphaned outputs
+	n, orphans := find()
+	require.Equal(t, uint64(2), n)
+	require.Empty(t, orphans)
+
+	txn := b.Body.Transactions[0]
+	ux := coin.CreateUnspents(b.Head, txn)[0]
+
+	makeOrphan := func(seq uint64, srcTxn cipher.SHA256, coins uint64) UxOut {
+		o := UxOut{
+			Out: ux,
+		}
+		o.Out.Head.BkSeq = seq
+		o.Out.Body.SrcTransaction = srcTxn
+		o.Out.Body.Coins = coins
+		return o
+	}
+
+	missingTxn := makeOrphan(1, testutil.RandSHA256(t), 1e6)
+	txnMismatch := makeOrphan(1, txn.Hash(), 2e6)
+	unparsed := makeOrphan(2, testutil.RandSHA256(t), 3e6)
+
+	expected := map[cipher.SHA256]OrphanedUxOutReason{
+		missingTxn.Hash():  OrphanedUxOutMissingTxn,
+		txnMismatch.Hash(): OrphanedUxOutTxnMismatch,
+		unparsed.Hash():    OrphanedUxOutUnparsedBlock,
+		ux.Hash():          OrphanedUxOutStaleSpender,
+	}
+
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		for _, o := range []UxOut{missingTxn, txnMismatch, unparsed} {
+			if err := hisDB.outputs.put(tx, o); err != nil {
+				return err
+			}
+			if err := hisDB.addrUx.add(tx, o.Out.Body.Address, o.Hash()); err != nil {
+				return err
+			}
+			if err := hisDB.outputValues.add(tx, o.Out); err != nil {
+				return err
+			}
+		}
+
+		// The output of the block is spent by a transaction that doesn't exist
+		o, err := hisDB.outputs.get(tx, ux.Hash())
+		if err != nil {
+			return err
+		}
+		o.SpentBlockSeq = 1
+		o.SpentTxnID = testutil.RandSHA256(t)
+		if err := hisDB.outputs.put(tx, *o); err != nil {
+			return err
+		}
+		return hisDB.spenders.put(tx, ux.Hash(), UxOutSpender{
+			TxnID:    o.SpentTxnID,
+			BlockSeq: 1,
+		})
+	})
+	require.NoError(t, err)
+
+	n, orphans = find()
+	require.Equal(t, uint64(5), n)
+	require.Len(t, orphans, len(expected))
+	for _, o := range orphans {
+		require.Equal(t, expected[o.UxOut.Hash()], o.Reason, o.UxOut.Hash().Hex())
+	}
+
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		for _, o := range orphans {
+			if err := hisDB.DeleteOrphanedUxOut(tx, o); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	// The orphaned outputs are deleted from the outputs and their indexes, the stale spender is cleared
+	n, orphans = find()
+	require.Equal(t, uint64(2), n)
+	require.Empty(t, orphans)
+
+	err = db.View("", func(tx *dbutil.Tx) error {
+		hashes, err := hisDB.addrUx.get(tx, outAddr)
+		require.NoError(t, err)
+		require.Equal(t, []cipher.SHA256{ux.Hash()}, hashes)
+
+		for _, o := range []UxOut{missingTxn, txnMismatch, unparsed} {
+			ok, err := hisDB.outputValues.has(tx, o.Out)
+			require.NoError(t, err)
+			require.False(t, ok)
+		}
+
+		o, err := hisDB.outputs.get(tx, ux.Hash())
+		require.NoError(t, err)
+		require.Equal(t, UxOut{Out: ux}, *o)
+
+		s, err := hisDB.spenders.get(tx, ux.Hash())
+		require.NoError(t, err)
+		require.Nil(t, s)
+		return nil
+	})
+	require.NoError(t, err)
+}
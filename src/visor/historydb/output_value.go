@@ -0,0 +1,127 @@
+package historydb
+
+import (
+	"math"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+// OutputValuesBkt indexes the outputs by coins and by the seq of the block that created them,
+// coins, block seq and output hash as key, empty value
+var OutputValuesBkt = []byte("output_values")
+
+// OutputValuesCursor is the position of an output in the output value index,
+// which is ordered by coins, block seq and output hash
+type OutputValuesCursor struct {
+	Coins    uint64
+	BlockSeq uint64
+	UxID     cipher.SHA256
+}
+
+// OutputValuesQuery selects the outputs with coins in [MinCoins, MaxCoins] created by the blocks in [StartSeq, EndSeq]
+type OutputValuesQuery struct {
+	MinCoins uint64
+	MaxCoins uint64
+	StartSeq uint64
+	EndSeq   uint64
+}
+
+// outputValueKey returns the key of an output in the output_values bucket
+func outputValueKey(coins, seq uint64, uxID cipher.SHA256) []byte {
+	key := make([]byte, 0, 16+len(uxID))
+	key = append(key, dbutil.Itob(coins)...)
+	key = append(key, dbutil.Itob(seq)...)
+	return append(key, uxID[:]...)
+}
+
+// outputValues bucket indexes the outputs by coins and creation block seq
+type outputValues struct{}
+
+// add adds an output to the index
+func (ov *outputValues) add(tx *dbutil.Tx, ux coin.UxOut) error {
+	return dbutil.PutBucketValue(tx, OutputValuesBkt, outputValueKey(ux.Body.Coins, ux.Head.BkSeq, ux.Hash()), []byte{})
+}
+
+// delete removes an output from the index
+func (ov *outputValues) delete(tx *dbutil.Tx, ux coin.UxOut) error {
+	return dbutil.Delete(tx, OutputValuesBkt, outputValueKey(ux.Body.Coins, ux.Head.BkSeq, ux.Hash()))
+}
+
+// has checks if an output is in the index
+func (ov *outputValues) has(tx *dbutil.Tx, ux coin.UxOut) (bool, error) {
+	return dbutil.BucketHasKey(tx, OutputValuesBkt, outputValueKey(ux.Body.Coins, ux.Head.BkSeq, ux.Hash()))
+}
+
+// page returns the hashes of the outputs selected by q, in coins, block seq and hash order, starting from the cursor,
+// or from the first selected output if the cursor is nil. At most limit hashes are returned, all of them if limit is 0.
+// The cursor of the next selected output is returned if there are more outputs.
+// The outputs of a coins value created outside of the block range are skipped by seeking past them,
+// so that a narrow block range doesn't scan every output of the coins range.
+func (ov *outputValues) page(tx *dbutil.Tx, q OutputValuesQuery, cursor *OutputValuesCursor, limit uint64) ([]cipher.SHA256, *OutputValuesCursor, error) {
+	var hashes []cipher.SHA256
+	var next *OutputValuesCursor
+	if q.MinCoins > q.MaxCoins || q.StartSeq > q.EndSeq {
+		return hashes, nil, nil
+	}
+
+	start := outputValueKey(q.MinCoins, q.StartSeq, cipher.SHA256{})
+	if cursor != nil {
+		start = outputValueKey(cursor.Coins, cursor.BlockSeq, cursor.UxID)
+	}
+
+	for start != nil {
+		seek := start
+		start = nil
+
+		if err := dbutil.ForEachPrefix(tx, OutputValuesBkt, nil, seek, func(k, _ []byte) (bool, error) {
+			coins := dbutil.Btoi(k[:8])
+			seq := dbutil.Btoi(k[8:16])
+
+			switch {
+			case coins > q.MaxCoins:
+				return false, nil
+			case seq < q.StartSeq:
+				start = outputValueKey(coins, q.StartSeq, cipher.SHA256{})
+				return false, nil
+			case seq > q.EndSeq:
+				if coins < math.MaxUint64 {
+					start = outputValueKey(coins+1, q.StartSeq, cipher.SHA256{})
+				}
+				return false, nil
+			}
+
+			uxID, err := cipher.SHA256FromBytes(k[16:])
+			if err != nil {
+				return false, err
+			}
+
+			if limit != 0 && uint64(len(hashes)) == limit {
+				next = &OutputValuesCursor{
+					Coins:    coins,
+					BlockSeq: seq,
+					UxID:     uxID,
+				}
+				return false, nil
+			}
+
+			hashes = append(hashes, uxID)
+			return true, nil
+		}); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return hashes, next, nil
+}
+
+// isEmpty checks if the output value index is empty
+func (ov *outputValues) isEmpty(tx *dbutil.Tx) (bool, error) {
+	return dbutil.IsEmpty(tx, OutputValuesBkt)
+}
+
+// reset empties the bucket, see dbutil.ResetBucket
+func (ov *outputValues) reset(tx *dbutil.Tx) error {
+	return dbutil.ResetBucket(tx, OutputValuesBkt)
+}
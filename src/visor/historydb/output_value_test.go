@@ -0,0 +1,152 @@
+package historydb
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+func TestOutputValuesPage(t *testing.T) {
+	db, teardown := prepareDB(t)
+	defer teardown()
+
+	makeUx := func(coins, seq uint64) coin.UxOut {
+		return coin.UxOut{
+			Head: coin.UxHead{
+				BkSeq: seq,
+			},
+			Body: coin.UxBody{
+				SrcTransaction: testutil.RandSHA256(t),
+				Address:        testutil.MakeAddress(),
+				Coins:          coins,
+			},
+		}
+	}
+
+	// Sorted by coins, block seq and output hash
+	uxs := []coin.UxOut{
+		makeUx(1e6, 1),
+		makeUx(1e6, 5),
+		makeUx(2e6, 2),
+		makeUx(2e6, 3),
+		makeUx(2e6, 9),
+		makeUx(3e6, 0),
+		makeUx(3e6, 4),
+		makeUx(math.MaxUint64, 4),
+	}
+
+	ov := &outputValues{}
+	err := db.Update("", func(tx *dbutil.Tx) error {
+		for _, ux := range uxs {
+			if err := ov.add(tx, ux); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	hashes := func(uxs ...coin.UxOut) []cipher.SHA256 {
+		var h []cipher.SHA256
+		for _, ux := range uxs {
+			h = append(h, ux.Hash())
+		}
+		return h
+	}
+
+	cursor := func(ux coin.UxOut) *OutputValuesCursor {
+		return &OutputValuesCursor{
+			Coins:    ux.Body.Coins,
+			BlockSeq: ux.Head.BkSeq,
+			UxID:     ux.Hash(),
+		}
+	}
+
+	cases := []struct {
+		name   string
+		q      OutputValuesQuery
+		cursor *OutputValuesCursor
+		limit  uint64
+		hashes []cipher.SHA256
+		next   *OutputValuesCursor
+	}{
+		{
+			name:   "all",
+			q:      OutputValuesQuery{MaxCoins: math.MaxUint64, EndSeq: math.MaxUint64},
+			hashes: hashes(uxs...),
+		},
+		{
+			name:   "coins range",
+			q:      OutputValuesQuery{MinCoins: 2e6, MaxCoins: 3e6, EndSeq: math.MaxUint64},
+			hashes: hashes(uxs[2:7]...),
+		},
+		{
+			name:   "block range skips the outputs of each coins value outside of it",
+			q:      OutputValuesQuery{MaxCoins: math.MaxUint64, StartSeq: 2, EndSeq: 4},
+			hashes: hashes(uxs[2], uxs[3], uxs[6], uxs[7]),
+		},
+		{
+			name:   "coins and block range",
+			q:      OutputValuesQuery{MinCoins: 1e6, MaxCoins: 2e6, StartSeq: 3, EndSeq: 9},
+			hashes: hashes(uxs[1], uxs[3], uxs[4]),
+		},
+		{
+			name:   "limit",
+			q:      OutputValuesQuery{MaxCoins: math.MaxUint64, StartSeq: 2, EndSeq: 4},
+			limit:  2,
+			hashes: hashes(uxs[2], uxs[3]),
+			next:   cursor(uxs[6]),
+		},
+		{
+			name:   "cursor",
+			q:      OutputValuesQuery{MaxCoins: math.MaxUint64, StartSeq: 2, EndSeq: 4},
+			cursor: cursor(uxs[6]),
+			limit:  2,
+			hashes: hashes(uxs[6], uxs[7]),
+		},
+		{
+			name: "empty range",
+			q:    OutputValuesQuery{MinCoins: 3e6, MaxCoins: 2e6, EndSeq: math.MaxUint64},
+		},
+		{
+			name: "no match",
+			q:    OutputValuesQuery{MinCoins: 1e6, MaxCoins: 3e6, StartSeq: 6, EndSeq: 8},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := db.View("", func(tx *dbutil.Tx) error {
+				h, next, err := ov.page(tx, tc.q, tc.cursor, tc.limit)
+				require.NoError(t, err)
+				require.Equal(t, tc.hashes, h)
+				require.Equal(t, tc.next, next)
+				return nil
+			})
+			require.NoError(t, err)
+		})
+	}
+
+	// Deleted outputs are not returned
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		if err := ov.delete(tx, uxs[3]); err != nil {
+			return err
+		}
+
+		ok, err := ov.has(tx, uxs[3])
+		require.NoError(t, err)
+		require.False(t, ok)
+
+		h, _, err := ov.page(tx, OutputValuesQuery{MinCoins: 2e6, MaxCoins: 2e6, EndSeq: math.MaxUint64}, nil, 0)
+		require.NoError(t, err)
+		require.Equal(t, hashes(uxs[2], uxs[4]), h)
+		return nil
+	})
+	require.NoError(t, err)
+}
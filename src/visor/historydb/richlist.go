@@ -0,0 +1,186 @@
+package historydb
+
+import (
+	"fmt"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/encoder"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+var (
+	// RichlistBkt indexes the addresses with a balance by balance, the inverted balance and address as key,
+	// and the address as value
+	RichlistBkt = []byte("richlist")
+
+	// BalanceDistributionBkt maps the minimum balances of the balance ranges to the BalanceRange
+	BalanceDistributionBkt = []byte("balance_distribution")
+)
+
+// BalanceDistributionThresholds are the minimum balances of the ranges of the balance distribution, in droplets.
+// The first range holds the addresses with a balance of less than 1 coin.
+var BalanceDistributionThresholds = []uint64{
+	1,
+	1e6,
+	10e6,
+	100e6,
+	1000e6,
+	10000e6,
+	100000e6,
+	1000000e6,
+	10000000e6,
+}
+
+// BalanceRange is the number of addresses with a balance in a range of the balance distribution,
+// and the total coins they hold
+type BalanceRange struct {
+	// MinCoins is the minimum balance of the range, in droplets.
+	// The range ends at the MinCoins of the next range.
+	MinCoins     uint64
+	AddressCount uint64
+	Coins        uint64
+}
+
+// RichlistEntry is the balance of an address in the richlist
+type RichlistEntry struct {
+	Address cipher.Address
+	Coins   uint64
+}
+
+// balanceThreshold returns the minimum balance of the range of the balance distribution that holds coins
+func balanceThreshold(coins uint64) uint64 {
+	t := BalanceDistributionThresholds[0]
+	for _, min := range BalanceDistributionThresholds[1:] {
+		if coins < min {
+			break
+		}
+		t = min
+	}
+	return t
+}
+
+// richlistKey returns the key of an address in the richlist bucket. The balance is inverted,
+// so that the keys sort by descending balance and then by address, or by the MAC of the address
+// in an encrypted database
+func richlistKey(tx *dbutil.Tx, addr cipher.Address, coins uint64) []byte {
+	k := addressKey(tx, addr)
+	key := make([]byte, 0, 8+len(k))
+	key = append(key, dbutil.Itob(^coins)...)
+	return append(key, k...)
+}
+
+// richlist maintains the richlist and balance distribution buckets from the balance changes of the addresses
+type richlist struct{}
+
+// apply updates the richlist and the balance distribution with balance changes
+func (rl *richlist) apply(tx *dbutil.Tx, changes []balanceChange) error {
+	for _, c := range changes {
+		if c.old == c.new {
+			continue
+		}
+
+		if c.old > 0 {
+			if err := dbutil.Delete(tx, RichlistBkt, richlistKey(tx, c.addr, c.old)); err != nil {
+				return err
+			}
+
+			if err := rl.updateRange(tx, c.old, false); err != nil {
+				return err
+			}
+		}
+
+		if c.new > 0 {
+			if err := dbutil.PutBucketValue(tx, RichlistBkt, richlistKey(tx, c.addr, c.new), c.addr.Bytes()); err != nil {
+				return err
+			}
+
+			if err := rl.updateRange(tx, c.new, true); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// updateRange adds an address with a balance of coins to its range of the balance distribution, or removes it
+func (rl *richlist) updateRange(tx *dbutil.Tx, coins uint64, add bool) error {
+	min := balanceThreshold(coins)
+	key := dbutil.Itob(min)
+
+	r := BalanceRange{
+		MinCoins: min,
+	}
+	if _, err := dbutil.GetBucketObjectDecoded(tx, BalanceDistributionBkt, key, &r); err != nil {
+		return err
+	}
+
+	if add {
+		r.AddressCount++
+		r.Coins += coins
+	} else {
+		if r.AddressCount == 0 || r.Coins < coins {
+			return fmt.Errorf("balance distribution range %d does not hold an address with %d droplets", min, coins)
+		}
+		r.AddressCount--
+		r.Coins -= coins
+
+		if r.AddressCount == 0 {
+			return dbutil.Delete(tx, BalanceDistributionBkt, key)
+		}
+	}
+
+	return dbutil.PutBucketValue(tx, BalanceDistributionBkt, key, encoder.Serialize(r))
+}
+
+// forEach calls f on the addresses of the richlist in descending balance order, ties in address order,
+// or in the order of the MACs of the addresses in an encrypted database.
+// The iteration stops when f returns false or an error.
+func (rl *richlist) forEach(tx *dbutil.Tx, f func(RichlistEntry) (bool, error)) error {
+	return dbutil.ForEachPrefix(tx, RichlistBkt, nil, nil, func(k, v []byte) (bool, error) {
+		// The address is not in the value of the richlists built before it was stored there
+		b := v
+		if len(b) == 0 {
+			b = k[8:]
+		}
+
+		addr, err := cipher.AddressFromBytes(b)
+		if err != nil {
+			return false, err
+		}
+
+		return f(RichlistEntry{
+			Address: addr,
+			Coins:   ^dbutil.Btoi(k[:8]),
+		})
+	})
+}
+
+// distribution returns the ranges of the balance distribution, in ascending order of balance
+func (rl *richlist) distribution(tx *dbutil.Tx) ([]BalanceRange, error) {
+	ranges := make([]BalanceRange, len(BalanceDistributionThresholds))
+	for i, min := range BalanceDistributionThresholds {
+		ranges[i].MinCoins = min
+		if _, err := dbutil.GetBucketObjectDecoded(tx, BalanceDistributionBkt, dbutil.Itob(min), &ranges[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return ranges, nil
+}
+
+// isEmpty checks if the richlist bucket is empty
+func (rl *richlist) isEmpty(tx *dbutil.Tx) (bool, error) {
+	return dbutil.IsEmpty(tx, RichlistBkt)
+}
+
+// reset empties the richlist and balance distribution buckets, see dbutil.ResetBucket
+func (rl *richlist) reset(tx *dbutil.Tx) error {
+	for _, bkt := range [][]byte{RichlistBkt, BalanceDistributionBkt} {
+		if err := dbutil.ResetBucket(tx, bkt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,200 @@
+package historydb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+func TestBalanceThreshold(t *testing.T) {
+	cases := []struct {
+		coins uint64
+		min   uint64
+	}{
+		{1, 1},
+		{999999, 1},
+		{1e6, 1e6},
+		{9999999, 1e6},
+		{10e6, 10e6},
+		{123456e6, 100000e6},
+		{10000000e6, 10000000e6},
+		{100000000e6, 10000000e6},
+	}
+
+	for _, tc := range cases {
+		require.Equal(t, tc.min, balanceThreshold(tc.coins), "coins=%d", tc.coins)
+	}
+}
+
+func TestRichlist(t *testing.T) {
+	db, teardown := prepareDB(t)
+	defer teardown()
+
+	rl := &richlist{}
+	addrs := make([]cipher.Address, 4)
+	for i := range addrs {
+		addrs[i] = makeAddress()
+	}
+
+	richlistEntries := func() []RichlistEntry {
+		var entries []RichlistEntry
+		err := db.View("", func(tx *dbutil.Tx) error {
+			return rl.forEach(tx, func(e RichlistEntry) (bool, error) {
+				entries = append(entries, e)
+				return true, nil
+			})
+		})
+		require.NoError(t, err)
+		return entries
+	}
+
+	distribution := func() []BalanceRange {
+		var ranges []BalanceRange
+		err := db.View("", func(tx *dbutil.Tx) error {
+			var err error
+			ranges, err = rl.distribution(tx)
+			return err
+		})
+		require.NoError(t, err)
+		return ranges
+	}
+
+	apply := func(changes ...balanceChange) {
+		err := db.Update("", func(tx *dbutil.Tx) error {
+			return rl.apply(tx, changes)
+		})
+		require.NoError(t, err)
+	}
+
+	require.Empty(t, richlistEntries())
+	for _, r := range distribution() {
+		require.Equal(t, uint64(0), r.AddressCount)
+	}
+
+	apply(
+		balanceChange{addr: addrs[0], new: 5e6},
+		balanceChange{addr: addrs[1], new: 20e6},
+		balanceChange{addr: addrs[2], new: 500},
+		balanceChange{addr: addrs[3], new: 7e6},
+	)
+
+	require.Equal(t, []RichlistEntry{
+		{Address: addrs[1], Coins: 20e6},
+		{Address: addrs[3], Coins: 7e6},
+		{Address: addrs[0], Coins: 5e6},
+		{Address: addrs[2], Coins: 500},
+	}, richlistEntries())
+
+	ranges := distribution()
+	require.Len(t, ranges, len(BalanceDistributionThresholds))
+	require.Equal(t, BalanceRange{MinCoins: 1, AddressCount: 1, Coins: 500}, ranges[0])
+	require.Equal(t, BalanceRange{MinCoins: 1e6, AddressCount: 2, Coins: 12e6}, ranges[1])
+	require.Equal(t, BalanceRange{MinCoins: 10e6, AddressCount: 1, Coins: 20e6}, ranges[2])
+
+	// Balances move addresses in the richlist and between ranges, and addresses without a balance are removed
+	apply(
+		balanceChange{addr: addrs[0], old: 5e6, new: 30e6},
+		balanceChange{addr: addrs[1], old: 20e6, new: 0},
+		balanceChange{addr: addrs[3], old: 7e6, new: 7e6},
+	)
+
+	require.Equal(t, []RichlistEntry{
+		{Address: addrs[0], Coins: 30e6},
+		{Address: addrs[3], Coins: 7e6},
+		{Address: addrs[2], Coins: 500},
+	}, richlistEntries())
+
+	ranges = distribution()
+	require.Equal(t, BalanceRange{MinCoins: 1, AddressCount: 1, Coins: 500}, ranges[0])
+	require.Equal(t, BalanceRange{MinCoins: 1e6, AddressCount: 1, Coins: 7e6}, ranges[1])
+	require.Equal(t, BalanceRange{MinCoins: 10e6, AddressCount: 1, Coins: 30e6}, ranges[2])
+
+	// Removing a balance that is not in the distribution fails
+	err := db.Update("", func(tx *dbutil.Tx) error {
+		return rl.apply(tx, []balanceChange{{addr: addrs[1], old: 500e6}})
+	})
+	require.Error(t, err)
+}
+
+func TestHistoryDBRichlist(t *testing.T) {
+	db, teardown := prepareDB(t)
+	defer teardown()
+	bc := newBlockchain()
+	gb := bc.CreateGenesisBlock(genAddress, genCoins, genTime)
+	hisDB := New()
+
+	parseBlock := func(b coin.Block) {
+		err := db.Update("", func(tx *dbutil.Tx) error {
+			return hisDB.ParseBlock(tx, b)
+		})
+		require.NoError(t, err)
+	}
+
+	richlistEntries := func() []RichlistEntry {
+		var entries []RichlistEntry
+		err := db.View("", func(tx *dbutil.Tx) error {
+			return hisDB.ForEachRichlistEntry(tx, func(e RichlistEntry) (bool, error) {
+				entries = append(entries, e)
+				return true, nil
+			})
+		})
+		require.NoError(t, err)
+		return entries
+	}
+
+	parseBlock(gb)
+	require.Equal(t, []RichlistEntry{{Address: genAddress, Coins: genCoins}}, richlistEntries())
+
+	addrA := cipher.MustDecodeBase58Address("2RxP5N26GhDqHrP6SK45ZzEMSmSpeUeWxsS")
+	addrB := cipher.MustDecodeBase58Address("222uMeCeL1PbkJGZJDgAz5sib2uisv9hYUm")
+
+	tds := newProcessBlockTestData(gb)
+	var blocks []coin.Block
+	for i, td := range tds {
+		b, txn, err := addBlock(bc, td, incTime*(uint64(i)+1))
+		require.NoError(t, err)
+
+		if i+1 < len(tds) {
+			tds[i+1].Vin.TxID = txn.Hash()
+			tds[i+1].PreBlockHash = b.HashHeader()
+		}
+
+		parseBlock(*b)
+		blocks = append(blocks, *b)
+	}
+
+	require.Equal(t, []RichlistEntry{
+		{Address: addrB, Coins: genCoins - 20e6},
+		{Address: addrA, Coins: 20e6},
+	}, richlistEntries())
+
+	err := db.View("", func(tx *dbutil.Tx) error {
+		ranges, err := hisDB.GetBalanceDistribution(tx)
+		require.NoError(t, err)
+
+		var count, coins uint64
+		for _, r := range ranges {
+			count += r.AddressCount
+			coins += r.Coins
+		}
+		require.Equal(t, uint64(2), count)
+		require.Equal(t, genCoins, coins)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// Unparsing a block restores the richlist
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		return hisDB.UnparseBlock(tx, blocks[1])
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, []RichlistEntry{
+		{Address: addrB, Coins: genCoins - 10e6},
+		{Address: addrA, Coins: 10e6},
+	}, richlistEntries())
+}
@@ -0,0 +1,54 @@
+package historydb
+
+import (
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/encoder"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+// TxnBlocksBkt maps transaction hashes to the position of the transactions in the blockchain
+var TxnBlocksBkt = []byte("txn_blocks")
+
+// TxnBlock is the position of a transaction in the blockchain
+type TxnBlock struct {
+	BlockSeq uint64
+	// Index of the transaction in the block
+	TxnIndex  uint64
+	BlockHash cipher.SHA256
+}
+
+// txnBlocks bucket stores the position of the transactions in the blockchain,
+// transaction hash as key, TxnBlock as value
+type txnBlocks struct{}
+
+// put saves the position of a transaction
+func (tb *txnBlocks) put(tx *dbutil.Tx, hash cipher.SHA256, b TxnBlock) error {
+	return dbutil.PutBucketValue(tx, TxnBlocksBkt, hash[:], encoder.Serialize(b))
+}
+
+// get returns the position of a transaction, nil if the transaction is not found
+func (tb *txnBlocks) get(tx *dbutil.Tx, hash cipher.SHA256) (*TxnBlock, error) {
+	var b TxnBlock
+	if ok, err := dbutil.GetBucketObjectDecoded(tx, TxnBlocksBkt, hash[:], &b); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, nil
+	}
+
+	return &b, nil
+}
+
+// delete removes the position of a transaction
+func (tb *txnBlocks) delete(tx *dbutil.Tx, hash cipher.SHA256) error {
+	return dbutil.Delete(tx, TxnBlocksBkt, hash[:])
+}
+
+// isEmpty checks if transaction blocks bucket is empty
+func (tb *txnBlocks) isEmpty(tx *dbutil.Tx) (bool, error) {
+	return dbutil.IsEmpty(tx, TxnBlocksBkt)
+}
+
+// reset empties the bucket, see dbutil.ResetBucket
+func (tb *txnBlocks) reset(tx *dbutil.Tx) error {
+	return dbutil.ResetBucket(tx, TxnBlocksBkt)
+}
@@ -0,0 +1,76 @@
+package historydb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+func TestTxnBlock(t *testing.T) {
+	db, teardown := prepareDB(t)
+	defer teardown()
+	bc := newBlockchain()
+	gb := bc.CreateGenesisBlock(genAddress, genCoins, genTime)
+
+	hisDB := New()
+
+	err := db.Update("", func(tx *dbutil.Tx) error {
+		return hisDB.ParseBlock(tx, gb)
+	})
+	require.NoError(t, err)
+
+	txnHash := gb.Body.Transactions[0].Hash()
+	expected := TxnBlock{
+		BlockSeq:  0,
+		TxnIndex:  0,
+		BlockHash: gb.HashHeader(),
+	}
+
+	verify := func() error {
+		return db.View("", func(tx *dbutil.Tx) error {
+			return hisDB.Verify(tx, &coin.SignedBlock{Block: gb}, NewIndexesMap())
+		})
+	}
+
+	err = db.View("", func(tx *dbutil.Tx) error {
+		b, err := hisDB.GetTxnBlock(tx, txnHash)
+		require.NoError(t, err)
+		require.Equal(t, &expected, b)
+
+		b, err = hisDB.GetTxnBlock(tx, testutil.RandSHA256(t))
+		require.NoError(t, err)
+		require.Nil(t, b)
+		return nil
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, verify())
+
+	// A transaction block with a wrong block hash is detected as corrupted
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		b := expected
+		b.BlockHash = cipher.SHA256{}
+		return hisDB.txnBlocks.put(tx, txnHash, b)
+	})
+	require.NoError(t, err)
+
+	err = verify()
+	require.Error(t, err)
+	_, ok := err.(ErrHistoryDBCorrupted)
+	require.True(t, ok)
+
+	// Erase removes the transaction blocks
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		require.NoError(t, hisDB.Erase(tx))
+		b, err := hisDB.GetTxnBlock(tx, txnHash)
+		require.NoError(t, err)
+		require.Nil(t, b)
+		return nil
+	})
+	require.NoError(t, err)
+}
@@ -0,0 +1,54 @@
+package historydb
+
+import (
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/encoder"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+// UxOutSpendersBkt maps spent outputs to the transaction that spent them
+var UxOutSpendersBkt = []byte("uxout_spenders")
+
+// UxOutSpender is the transaction input which spent an output
+type UxOutSpender struct {
+	TxnID    cipher.SHA256
+	BlockSeq uint64
+	// Index of the output in the inputs of the transaction
+	InputIndex uint64
+}
+
+// uxOutSpenders bucket stores the spenders of the outputs,
+// UxOut hash as key, UxOutSpender as value
+type uxOutSpenders struct{}
+
+// put saves the spender of an output
+func (us *uxOutSpenders) put(tx *dbutil.Tx, uxID cipher.SHA256, s UxOutSpender) error {
+	return dbutil.PutBucketValue(tx, UxOutSpendersBkt, uxID[:], encoder.Serialize(s))
+}
+
+// get returns the spender of an output, nil if the output is unspent or not found
+func (us *uxOutSpenders) get(tx *dbutil.Tx, uxID cipher.SHA256) (*UxOutSpender, error) {
+	var s UxOutSpender
+	if ok, err := dbutil.GetBucketObjectDecoded(tx, UxOutSpendersBkt, uxID[:], &s); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, nil
+	}
+
+	return &s, nil
+}
+
+// delete removes the spender of an output
+func (us *uxOutSpenders) delete(tx *dbutil.Tx, uxID cipher.SHA256) error {
+	return dbutil.Delete(tx, UxOutSpendersBkt, uxID[:])
+}
+
+// isEmpty checks if uxout spenders bucket is empty
+func (us *uxOutSpenders) isEmpty(tx *dbutil.Tx) (bool, error) {
+	return dbutil.IsEmpty(tx, UxOutSpendersBkt)
+}
+
+// reset empties the bucket, see dbutil.ResetBucket
+func (us *uxOutSpenders) reset(tx *dbutil.Tx) error {
+	return dbutil.ResetBucket(tx, UxOutSpendersBkt)
+}
@@ -0,0 +1,128 @@
+package historydb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+func TestUxOutSpender(t *testing.T) {
+	db, teardown := prepareDB(t)
+	defer teardown()
+	bc := newBlockchain()
+	gb := bc.CreateGenesisBlock(genAddress, genCoins, genTime)
+
+	hisDB := New()
+
+	err := db.Update("", func(tx *dbutil.Tx) error {
+		return hisDB.ParseBlock(tx, gb)
+	})
+	require.NoError(t, err)
+
+	// The genesis block doesn't spend any outputs, so an empty index doesn't need a reset
+	err = db.View("", func(tx *dbutil.Tx) error {
+		needsReset, err := hisDB.NeedsReset(tx)
+		require.NoError(t, err)
+		require.False(t, needsReset)
+		return nil
+	})
+	require.NoError(t, err)
+
+	genUxID := coin.CreateUnspents(gb.Head, gb.Body.Transactions[0])[0].Hash()
+
+	b, txn, err := addBlock(bc, testData{
+		PreBlockHash: gb.HashHeader(),
+		Vin: txIn{
+			SigKey:   genSecret.Hex(),
+			Addr:     genAddress.String(),
+			TxID:     gb.Body.Transactions[0].Hash(),
+			BlockSeq: 0,
+		},
+		Vouts: []txOut{
+			{
+				ToAddr: "2RxP5N26GhDqHrP6SK45ZzEMSmSpeUeWxsS",
+				Coins:  genCoins,
+				Hours:  100,
+			},
+		},
+	}, incTime)
+	require.NoError(t, err)
+
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		return hisDB.ParseBlock(tx, *b)
+	})
+	require.NoError(t, err)
+
+	expected := UxOutSpender{
+		TxnID:      txn.Hash(),
+		BlockSeq:   1,
+		InputIndex: 0,
+	}
+
+	verify := func() error {
+		return db.View("", func(tx *dbutil.Tx) error {
+			return hisDB.Verify(tx, &coin.SignedBlock{Block: *b}, NewIndexesMap())
+		})
+	}
+
+	err = db.View("", func(tx *dbutil.Tx) error {
+		s, err := hisDB.GetUxOutSpender(tx, genUxID)
+		require.NoError(t, err)
+		require.Equal(t, &expected, s)
+
+		// The spender matches the spend data of the output
+		outs, err := hisDB.GetUxOuts(tx, []cipher.SHA256{genUxID})
+		require.NoError(t, err)
+		require.Equal(t, outs[0].SpentTxnID, s.TxnID)
+		require.Equal(t, outs[0].SpentBlockSeq, s.BlockSeq)
+
+		// The outputs of the spending transaction are unspent
+		uxID := coin.CreateUnspents(b.Head, *txn)[0].Hash()
+		s, err = hisDB.GetUxOutSpender(tx, uxID)
+		require.NoError(t, err)
+		require.Nil(t, s)
+
+		s, err = hisDB.GetUxOutSpender(tx, testutil.RandSHA256(t))
+		require.NoError(t, err)
+		require.Nil(t, s)
+		return nil
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, verify())
+
+	// A spender with a wrong input index is detected as corrupted
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		s := expected
+		s.InputIndex = 1
+		return hisDB.spenders.put(tx, genUxID, s)
+	})
+	require.NoError(t, err)
+
+	err = verify()
+	require.Error(t, err)
+	_, ok := err.(ErrHistoryDBCorrupted)
+	require.True(t, ok)
+
+	// Repairing the block fixes the spender
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		return hisDB.RepairBlock(tx, *b)
+	})
+	require.NoError(t, err)
+	require.NoError(t, verify())
+
+	// An empty index is filled by parsing the history db again once blocks were parsed
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		require.NoError(t, hisDB.spenders.reset(tx))
+		needsReset, err := hisDB.NeedsReset(tx)
+		require.NoError(t, err)
+		require.True(t, needsReset)
+		return nil
+	})
+	require.NoError(t, err)
+}
@@ -0,0 +1,484 @@
+package historydb
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+// IndexDiffKind is the kind of a broken history db index entry
+type IndexDiffKind string
+
+const (
+	// IndexDiffMissingTxn is a transaction of the block missing from the transactions bucket
+	IndexDiffMissingTxn IndexDiffKind = "missing_txn"
+	// IndexDiffTxnBlock is a missing or wrong position of a transaction in the blockchain
+	IndexDiffTxnBlock IndexDiffKind = "txn_block"
+	// IndexDiffMissingUxOut is an output created by the block missing from the outputs bucket
+	IndexDiffMissingUxOut IndexDiffKind = "missing_uxout"
+	// IndexDiffOrphanedUxOut is an output spent by the block that is missing from the outputs bucket,
+	// or is not marked as spent by the block
+	IndexDiffOrphanedUxOut IndexDiffKind = "orphaned_uxout"
+	// IndexDiffUxOutSpender is a missing or wrong transaction input that spent an output
+	IndexDiffUxOutSpender IndexDiffKind = "uxout_spender"
+	// IndexDiffAddressTxn is a transaction missing from the transaction index of an address
+	IndexDiffAddressTxn IndexDiffKind = "address_txn"
+	// IndexDiffAddressUxOut is an output missing from the output index of an address
+	IndexDiffAddressUxOut IndexDiffKind = "address_uxout"
+	// IndexDiffAddressTxnSeq is a transaction missing from the block seq index of an address
+	IndexDiffAddressTxnSeq IndexDiffKind = "address_txn_seq"
+	// IndexDiffAddressMeta is a missing or mismatched address meta
+	IndexDiffAddressMeta IndexDiffKind = "address_meta"
+	// IndexDiffOutputValue is an output created by the block missing from the output value index
+	IndexDiffOutputValue IndexDiffKind = "output_value"
+)
+
+// ErrIndexDiffNotPatchable is returned by PatchBlock if an entry of the diff can't be patched on its own,
+// the indexes of the block have to be rebuilt with RepairBlock instead
+var ErrIndexDiffNotPatchable = errors.New("history db index diff can't be patched")
+
+// IndexDiffEntry is a broken history db index entry of a block.
+// The fields that don't apply to the kind of the entry are empty.
+type IndexDiffEntry struct {
+	Kind IndexDiffKind
+	// TxnIndex is the position of the transaction in the block
+	TxnIndex uint64
+	TxnHash  cipher.SHA256
+	// InputIndex is the position of the input in the transaction, for IndexDiffOrphanedUxOut and IndexDiffUxOutSpender
+	InputIndex uint64
+	UxID       cipher.SHA256
+	Address    cipher.Address
+	// Description is the error Verify returns for the entry
+	Description string
+}
+
+// BlockIndexDiff lists the broken history db index entries of a block, in the order they were verified
+type BlockIndexDiff struct {
+	Seq     uint64
+	Entries []IndexDiffEntry
+}
+
+// Empty returns true if no broken entry was found
+func (d *BlockIndexDiff) Empty() bool {
+	return len(d.Entries) == 0
+}
+
+func (d *BlockIndexDiff) add(e IndexDiffEntry, format string, args ...interface{}) {
+	e.Description = "HistoryDB.Verify: " + fmt.Sprintf(format, args...)
+	d.Entries = append(d.Entries, e)
+}
+
+// VerifyDiff checks the history db indexes of a block like Verify, but doesn't stop at the first broken entry.
+// All broken entries are returned in a BlockIndexDiff, which PatchBlock consumes. The database is not modified.
+func (hd HistoryDB) VerifyDiff(tx *dbutil.Tx, b *coin.SignedBlock, indexesMap *IndexesMap) (*BlockIndexDiff, error) {
+	addrMetaEmpty, err := isEmptyOrMissing(tx, AddressMetaBkt)
+	if err != nil {
+		return nil, err
+	}
+
+	addrTxnSeqsEmpty, err := isEmptyOrMissing(tx, AddressTxnSeqsBkt)
+	if err != nil {
+		return nil, err
+	}
+
+	txnBlocksEmpty, err := isEmptyOrMissing(tx, TxnBlocksBkt)
+	if err != nil {
+		return nil, err
+	}
+
+	spendersEmpty, err := isEmptyOrMissing(tx, UxOutSpendersBkt)
+	if err != nil {
+		return nil, err
+	}
+
+	outputValuesEmpty, err := isEmptyOrMissing(tx, OutputValuesBkt)
+	if err != nil {
+		return nil, err
+	}
+
+	var blockHash cipher.SHA256
+	if !txnBlocksEmpty {
+		blockHash = b.HashHeader()
+	}
+
+	diff := &BlockIndexDiff{
+		Seq: b.Seq(),
+	}
+
+	for i, t := range b.Body.Transactions {
+		txnHash := t.Hash()
+		txnCursor := AddressTxnsCursor{
+			BlockSeq: b.Seq(),
+			TxnIndex: uint64(i),
+		}
+		txnEntry := IndexDiffEntry{
+			TxnIndex: uint64(i),
+			TxnHash:  txnHash,
+		}
+
+		txn, err := hd.txns.get(tx, txnHash)
+		if err != nil {
+			return nil, err
+		}
+
+		if txn == nil {
+			e := txnEntry
+			e.Kind = IndexDiffMissingTxn
+			diff.add(e, "transaction %v does not exist in historydb", txnHash.Hex())
+		}
+
+		if !txnBlocksEmpty {
+			if err := hd.verifyTxnBlock(tx, diff, txnEntry, TxnBlock{
+				BlockSeq:  b.Seq(),
+				TxnIndex:  uint64(i),
+				BlockHash: blockHash,
+			}); err != nil {
+				return nil, err
+			}
+		}
+
+		for j, in := range t.In {
+			inEntry := txnEntry
+			inEntry.InputIndex = uint64(j)
+			inEntry.UxID = in
+
+			// Checks the existence of transaction input
+			o, err := hd.outputs.get(tx, in)
+			if err != nil {
+				return nil, err
+			}
+
+			if o == nil {
+				// The address indexes of the input can't be checked without the output
+				e := inEntry
+				e.Kind = IndexDiffOrphanedUxOut
+				diff.add(e, "transaction input %v does not exist in historydb", in.Hex())
+				continue
+			}
+
+			// Checks the output's spend block seq
+			if o.SpentBlockSeq != b.Seq() {
+				e := inEntry
+				e.Kind = IndexDiffOrphanedUxOut
+				diff.add(e, "spend block seq of transaction input %v is wrong, should be: %v, but is %v",
+					in.Hex(), b.Seq(), o.SpentBlockSeq)
+			}
+
+			if !spendersEmpty {
+				if err := hd.verifyUxOutSpender(tx, diff, inEntry, UxOutSpender{
+					TxnID:      txnHash,
+					BlockSeq:   b.Seq(),
+					InputIndex: uint64(j),
+				}); err != nil {
+					return nil, err
+				}
+			}
+
+			addr := o.Out.Body.Address
+			addrEntry := inEntry
+			addrEntry.Address = addr
+
+			indexes, err := hd.loadAddressIndexes(tx, addr, indexesMap)
+			if err != nil {
+				return nil, err
+			}
+
+			if _, ok := indexes.TxnHashes[txnHash]; !ok {
+				e := addrEntry
+				e.Kind = IndexDiffAddressTxn
+				diff.add(e, "index of address transaction [%s:%s] does not exist in historydb", addr, txnHash.Hex())
+			}
+
+			if _, ok := indexes.UxHashes[in]; !ok {
+				e := addrEntry
+				e.Kind = IndexDiffAddressUxOut
+				diff.add(e, "index of address uxout [%s:%s] does not exist in historydb", addr, in.Hex())
+			}
+
+			if !addrTxnSeqsEmpty {
+				if err := hd.verifyAddressTxnSeq(tx, diff, addrEntry, txnCursor); err != nil {
+					return nil, err
+				}
+			}
+
+			if !addrMetaEmpty {
+				if err := hd.verifyAddressMeta(tx, diff, addrEntry, b.Seq(), len(indexes.TxnHashes)); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		// Checks the transaction outs
+		uxArray := coin.CreateUnspents(b.Head, t)
+		for _, ux := range uxArray {
+			uxHash := ux.Hash()
+			outEntry := txnEntry
+			outEntry.UxID = uxHash
+
+			out, err := hd.outputs.get(tx, uxHash)
+			if err != nil {
+				return nil, err
+			}
+
+			if out == nil {
+				e := outEntry
+				e.Kind = IndexDiffMissingUxOut
+				diff.add(e, "transaction output %s does not exist in historydb", uxHash.Hex())
+			}
+
+			if !outputValuesEmpty {
+				if ok, err := hd.outputValues.has(tx, ux); err != nil {
+					return nil, err
+				} else if !ok {
+					e := outEntry
+					e.Kind = IndexDiffOutputValue
+					diff.add(e, "index of output value [%d:%s] does not exist in historydb", ux.Body.Coins, uxHash.Hex())
+				}
+			}
+
+			addr := ux.Body.Address
+			addrEntry := outEntry
+			addrEntry.Address = addr
+
+			indexes, err := hd.loadAddressIndexes(tx, addr, indexesMap)
+			if err != nil {
+				return nil, err
+			}
+
+			if _, ok := indexes.TxnHashes[txnHash]; !ok {
+				e := addrEntry
+				e.Kind = IndexDiffAddressTxn
+				diff.add(e, "index of address transaction [%s:%s] does not exist in historydb", addr, txnHash.Hex())
+			}
+
+			if !addrTxnSeqsEmpty {
+				if err := hd.verifyAddressTxnSeq(tx, diff, addrEntry, txnCursor); err != nil {
+					return nil, err
+				}
+			}
+
+			if !addrMetaEmpty {
+				if err := hd.verifyAddressMeta(tx, diff, addrEntry, b.Seq(), len(indexes.TxnHashes)); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return diff, nil
+}
+
+// loadAddressIndexes returns the transaction and output indexes of an address, loading them into indexesMap
+// if they are not loaded yet
+func (hd HistoryDB) loadAddressIndexes(tx *dbutil.Tx, addr cipher.Address, indexesMap *IndexesMap) (AddressIndexes, error) {
+	if indexes, ok := indexesMap.Load(addr); ok {
+		return indexes, nil
+	}
+
+	txnHashes, err := hd.addrTxns.get(tx, addr)
+	if err != nil {
+		return AddressIndexes{}, err
+	}
+
+	uxHashes, err := hd.addrUx.get(tx, addr)
+	if err != nil {
+		return AddressIndexes{}, err
+	}
+
+	indexes := AddressIndexes{
+		TxnHashes: make(map[cipher.SHA256]struct{}, len(txnHashes)),
+		UxHashes:  make(map[cipher.SHA256]struct{}, len(uxHashes)),
+	}
+	for _, hash := range txnHashes {
+		indexes.TxnHashes[hash] = struct{}{}
+	}
+	for _, hash := range uxHashes {
+		indexes.UxHashes[hash] = struct{}{}
+	}
+
+	indexesMap.Store(addr, indexes)
+	return indexes, nil
+}
+
+// isEmptyOrMissing checks if a bucket is empty or doesn't exist. The buckets added to a history db
+// parsed before they existed are filled when it is parsed again on startup, until then they are not verified.
+func isEmptyOrMissing(tx *dbutil.Tx, bkt []byte) (bool, error) {
+	if !dbutil.Exists(tx, bkt) {
+		return true, nil
+	}
+	return dbutil.IsEmpty(tx, bkt)
+}
+
+// verifyTxnBlock checks the position of a transaction in the blockchain
+func (hd HistoryDB) verifyTxnBlock(tx *dbutil.Tx, diff *BlockIndexDiff, e IndexDiffEntry, expected TxnBlock) error {
+	b, err := hd.txnBlocks.get(tx, e.TxnHash)
+	if err != nil {
+		return err
+	}
+
+	e.Kind = IndexDiffTxnBlock
+	if b == nil {
+		diff.add(e, "block of transaction %s does not exist in historydb", e.TxnHash.Hex())
+	} else if *b != expected {
+		diff.add(e, "block of transaction %s is wrong, should be: %+v, but is %+v", e.TxnHash.Hex(), expected, *b)
+	}
+
+	return nil
+}
+
+// verifyUxOutSpender checks the transaction input which spent an output
+func (hd HistoryDB) verifyUxOutSpender(tx *dbutil.Tx, diff *BlockIndexDiff, e IndexDiffEntry, expected UxOutSpender) error {
+	s, err := hd.spenders.get(tx, e.UxID)
+	if err != nil {
+		return err
+	}
+
+	e.Kind = IndexDiffUxOutSpender
+	if s == nil {
+		diff.add(e, "spender of transaction input %s does not exist in historydb", e.UxID.Hex())
+	} else if *s != expected {
+		diff.add(e, "spender of transaction input %s is wrong, should be: %+v, but is %+v", e.UxID.Hex(), expected, *s)
+	}
+
+	return nil
+}
+
+// verifyAddressTxnSeq checks that a transaction is indexed by block seq for an address
+func (hd HistoryDB) verifyAddressTxnSeq(tx *dbutil.Tx, diff *BlockIndexDiff, e IndexDiffEntry, c AddressTxnsCursor) error {
+	ok, err := hd.addrTxnSeqs.has(tx, e.Address, c)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		e.Kind = IndexDiffAddressTxnSeq
+		diff.add(e, "seq index of address transaction [%s:%s] does not exist in historydb", e.Address, e.TxnHash.Hex())
+	}
+
+	return nil
+}
+
+// verifyAddressMeta checks that the meta of an address used in block seq covers the block,
+// and counts the transactions indexed for the address
+func (hd HistoryDB) verifyAddressMeta(tx *dbutil.Tx, diff *BlockIndexDiff, e IndexDiffEntry, seq uint64, txnCount int) error {
+	meta, err := hd.addrMeta.get(tx, e.Address)
+	if err != nil {
+		return err
+	}
+
+	e.Kind = IndexDiffAddressMeta
+	switch {
+	case meta == nil:
+		diff.add(e, "meta of address %s does not exist in historydb", e.Address)
+	case seq < meta.FirstSeenSeq || seq > meta.LastActiveSeq:
+		diff.add(e, "address %s is used in block %d, outside of its first seen block %d and last active block %d",
+			e.Address, seq, meta.FirstSeenSeq, meta.LastActiveSeq)
+	case meta.TxnCount != uint64(txnCount):
+		diff.add(e, "transaction count of address %s is wrong, should be: %d, but is %d",
+			e.Address, txnCount, meta.TxnCount)
+	}
+
+	return nil
+}
+
+// PatchBlock writes the broken index entries of a block found by VerifyDiff, leaving the other entries untouched.
+// The diffs of several blocks must be patched in ascending block seq order, so that the outputs created by a block
+// exist when the block that spends them is patched.
+// Returns ErrIndexDiffNotPatchable if an entry can't be patched on its own, the entries before it are patched.
+func (hd *HistoryDB) PatchBlock(tx *dbutil.Tx, b coin.Block, diff *BlockIndexDiff) error {
+	if diff.Seq != b.Seq() {
+		return fmt.Errorf("HistoryDB.PatchBlock: diff of block %d can't patch block %d", diff.Seq, b.Seq())
+	}
+
+	for _, e := range diff.Entries {
+		if e.TxnIndex >= uint64(len(b.Body.Transactions)) {
+			return fmt.Errorf("HistoryDB.PatchBlock: block %d has no transaction %d", b.Seq(), e.TxnIndex)
+		}
+
+		t := b.Body.Transactions[e.TxnIndex]
+		if t.Hash() != e.TxnHash {
+			return fmt.Errorf("HistoryDB.PatchBlock: transaction %d of block %d is not %s", e.TxnIndex, b.Seq(), e.TxnHash.Hex())
+		}
+
+		if err := hd.patchEntry(tx, b, t, e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (hd *HistoryDB) patchEntry(tx *dbutil.Tx, b coin.Block, t coin.Transaction, e IndexDiffEntry) error {
+	switch e.Kind {
+	case IndexDiffMissingTxn:
+		return hd.txns.put(tx, &Transaction{
+			Txn:      t,
+			BlockSeq: b.Seq(),
+		})
+
+	case IndexDiffTxnBlock:
+		return hd.txnBlocks.put(tx, e.TxnHash, TxnBlock{
+			BlockSeq:  b.Seq(),
+			TxnIndex:  e.TxnIndex,
+			BlockHash: b.HashHeader(),
+		})
+
+	case IndexDiffMissingUxOut:
+		for _, ux := range coin.CreateUnspents(b.Head, t) {
+			if ux.Hash() == e.UxID {
+				return hd.outputs.put(tx, UxOut{
+					Out: ux,
+				})
+			}
+		}
+		return fmt.Errorf("HistoryDB.PatchBlock: transaction %s has no output %s", e.TxnHash.Hex(), e.UxID.Hex())
+
+	case IndexDiffOutputValue:
+		for _, ux := range coin.CreateUnspents(b.Head, t) {
+			if ux.Hash() == e.UxID {
+				return hd.outputValues.add(tx, ux)
+			}
+		}
+		return fmt.Errorf("HistoryDB.PatchBlock: transaction %s has no output %s", e.TxnHash.Hex(), e.UxID.Hex())
+
+	case IndexDiffOrphanedUxOut:
+		// An output missing from the outputs bucket is restored by patching the block that created it
+		o, err := hd.outputs.get(tx, e.UxID)
+		if err != nil {
+			return err
+		}
+		if o == nil {
+			return ErrIndexDiffNotPatchable
+		}
+
+		o.SpentBlockSeq = b.Seq()
+		o.SpentTxnID = e.TxnHash
+		return hd.outputs.put(tx, *o)
+
+	case IndexDiffUxOutSpender:
+		return hd.spenders.put(tx, e.UxID, UxOutSpender{
+			TxnID:      e.TxnHash,
+			BlockSeq:   b.Seq(),
+			InputIndex: e.InputIndex,
+		})
+
+	case IndexDiffAddressTxn:
+		return hd.addrTxns.add(tx, e.Address, e.TxnHash)
+
+	case IndexDiffAddressUxOut:
+		return hd.addrUx.add(tx, e.Address, e.UxID)
+
+	case IndexDiffAddressTxnSeq:
+		return hd.addrTxnSeqs.add(tx, e.Address, AddressTxnsCursor{
+			BlockSeq: b.Seq(),
+			TxnIndex: e.TxnIndex,
+		}, e.TxnHash)
+
+	default:
+		// The address meta accumulates the activity of all blocks, it can only be rebuilt by parsing them all
+		return ErrIndexDiffNotPatchable
+	}
+}
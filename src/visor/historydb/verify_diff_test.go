@@ -0,0 +1,206 @@
+package historydb
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+func TestVerifyDiffPatchBlock(t *testing.T) {
+	outAddr := cipher.MustDecodeBase58Address("2RxP5N26GhDqHrP6SK45ZzEMSmSpeUeWxsS")
+
+	cases := []struct {
+		name     string
+		corrupt  func(tx *dbutil.Tx, hd *HistoryDB, gb, b coin.Block) error
+		kinds    []IndexDiffKind
+		patchErr error
+	}{
+		{
+			name: "not corrupted",
+			corrupt: func(tx *dbutil.Tx, hd *HistoryDB, gb, b coin.Block) error {
+				return nil
+			},
+		},
+		{
+			name: "broken entries are patched",
+			corrupt: func(tx *dbutil.Tx, hd *HistoryDB, gb, b coin.Block) error {
+				txnHash := b.Body.Transactions[0].Hash()
+				genUxID := coin.CreateUnspents(gb.Head, gb.Body.Transactions[0])[0].Hash()
+				uxID := coin.CreateUnspents(b.Head, b.Body.Transactions[0])[0].Hash()
+
+				if err := hd.txns.delete(tx, txnHash); err != nil {
+					return err
+				}
+				if err := hd.txnBlocks.delete(tx, txnHash); err != nil {
+					return err
+				}
+
+				o, err := hd.outputs.get(tx, genUxID)
+				if err != nil {
+					return err
+				}
+				o.SpentBlockSeq = 0
+				o.SpentTxnID = cipher.SHA256{}
+				if err := hd.outputs.put(tx, *o); err != nil {
+					return err
+				}
+
+				// The spenders bucket is not verified if it is empty
+				if err := hd.spenders.put(tx, genUxID, UxOutSpender{
+					TxnID:      txnHash,
+					BlockSeq:   1,
+					InputIndex: 1,
+				}); err != nil {
+					return err
+				}
+				if err := hd.addrUx.remove(tx, genAddress, genUxID); err != nil {
+					return err
+				}
+				if err := hd.outputs.delete(tx, uxID); err != nil {
+					return err
+				}
+				return hd.addrTxnSeqs.delete(tx, outAddr, AddressTxnsCursor{
+					BlockSeq: 1,
+				})
+			},
+			kinds: []IndexDiffKind{
+				IndexDiffMissingTxn,
+				IndexDiffTxnBlock,
+				IndexDiffOrphanedUxOut,
+				IndexDiffUxOutSpender,
+				IndexDiffAddressUxOut,
+				IndexDiffMissingUxOut,
+				IndexDiffAddressTxnSeq,
+			},
+		},
+		{
+			name: "output value index is patched",
+			corrupt: func(tx *dbutil.Tx, hd *HistoryDB, gb, b coin.Block) error {
+				return hd.outputValues.delete(tx, coin.CreateUnspents(b.Head, b.Body.Transactions[0])[0])
+			},
+			kinds: []IndexDiffKind{
+				IndexDiffOutputValue,
+			},
+		},
+		{
+			name: "missing input is not patchable",
+			corrupt: func(tx *dbutil.Tx, hd *HistoryDB, gb, b coin.Block) error {
+				genUxID := coin.CreateUnspents(gb.Head, gb.Body.Transactions[0])[0].Hash()
+				return hd.outputs.delete(tx, genUxID)
+			},
+			kinds: []IndexDiffKind{
+				IndexDiffOrphanedUxOut,
+			},
+			patchErr: ErrIndexDiffNotPatchable,
+		},
+		{
+			name: "address meta is not patchable",
+			corrupt: func(tx *dbutil.Tx, hd *HistoryDB, gb, b coin.Block) error {
+				return hd.addrTxns.remove(tx, genAddress, b.Body.Transactions[0].Hash())
+			},
+			kinds: []IndexDiffKind{
+				IndexDiffAddressTxn,
+				IndexDiffAddressMeta,
+			},
+			patchErr: ErrIndexDiffNotPatchable,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, teardown := prepareDB(t)
+			defer teardown()
+			bc := newBlockchain()
+			gb := bc.CreateGenesisBlock(genAddress, genCoins, genTime)
+
+			hisDB := New()
+
+			err := db.Update("", func(tx *dbutil.Tx) error {
+				return hisDB.ParseBlock(tx, gb)
+			})
+			require.NoError(t, err)
+
+			b, _, err := addBlock(bc, testData{
+				PreBlockHash: gb.HashHeader(),
+				Vin: txIn{
+					SigKey:   genSecret.Hex(),
+					Addr:     genAddress.String(),
+					TxID:     gb.Body.Transactions[0].Hash(),
+					BlockSeq: 0,
+				},
+				Vouts: []txOut{
+					{
+						ToAddr: outAddr.String(),
+						Coins:  genCoins,
+						Hours:  100,
+					},
+				},
+			}, incTime)
+			require.NoError(t, err)
+
+			err = db.Update("", func(tx *dbutil.Tx) error {
+				if err := hisDB.ParseBlock(tx, *b); err != nil {
+					return err
+				}
+				return tc.corrupt(tx, hisDB, gb, *b)
+			})
+			require.NoError(t, err)
+
+			sb := &coin.SignedBlock{Block: *b}
+
+			var diff *BlockIndexDiff
+			err = db.View("", func(tx *dbutil.Tx) error {
+				var err error
+				diff, err = hisDB.VerifyDiff(tx, sb, NewIndexesMap())
+				return err
+			})
+			require.NoError(t, err)
+			require.Equal(t, b.Seq(), diff.Seq)
+
+			kinds := make([]IndexDiffKind, len(diff.Entries))
+			for i, e := range diff.Entries {
+				kinds[i] = e.Kind
+				require.Equal(t, b.Body.Transactions[0].Hash(), e.TxnHash)
+			}
+			require.Equal(t, len(tc.kinds), len(kinds))
+			if len(tc.kinds) != 0 {
+				require.Equal(t, tc.kinds, kinds)
+			}
+
+			// Verify reports the first broken entry
+			err = db.View("", func(tx *dbutil.Tx) error {
+				return hisDB.Verify(tx, sb, NewIndexesMap())
+			})
+			if diff.Empty() {
+				require.NoError(t, err)
+			} else {
+				require.Equal(t, NewErrHistoryDBCorrupted(errors.New(diff.Entries[0].Description)), err)
+			}
+
+			err = db.Update("", func(tx *dbutil.Tx) error {
+				return hisDB.PatchBlock(tx, *b, diff)
+			})
+			if tc.patchErr != nil {
+				require.Equal(t, tc.patchErr, err)
+				return
+			}
+			require.NoError(t, err)
+
+			err = db.View("", func(tx *dbutil.Tx) error {
+				return hisDB.Verify(tx, sb, NewIndexesMap())
+			})
+			require.NoError(t, err)
+
+			// The diff of another block is rejected
+			err = db.Update("", func(tx *dbutil.Tx) error {
+				return hisDB.PatchBlock(tx, gb, diff)
+			})
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package visor
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+var (
+	// ErrInclusionProofBlockSeq is returned by TransactionInclusionProof.Verify if the block seq of the proof is not the seq of its header
+	ErrInclusionProofBlockSeq = errors.New("inclusion proof block seq does not match the block header")
+	// ErrInclusionProofMerklePath is returned by TransactionInclusionProof.Verify if the merkle path
+	// does not lead from the transaction hash to the body hash of the block header
+	ErrInclusionProofMerklePath = errors.New("inclusion proof merkle path does not match the block body hash")
+)
+
+// TransactionInclusionProof proves that a transaction is included in a block, without the other transactions of the block.
+// It lets light clients check the confirmation of a transaction with the blockchain pubkey only.
+type TransactionInclusionProof struct {
+	TxnHash  cipher.SHA256
+	BlockSeq uint64
+	// Index of the transaction in the block
+	TxnIndex uint64
+	// Header of the block, whose BodyHash is the merkle root of the hashes of the block's transactions
+	Header coin.BlockHeader
+	// Signature of the block header hash
+	Sig cipher.Sig
+	// Sibling hashes on the path from the transaction hash to the BodyHash of the header, see cipher.MerkleProof
+	MerklePath []cipher.SHA256
+}
+
+// BlockHash returns the hash of the block of the transaction
+func (p TransactionInclusionProof) BlockHash() cipher.SHA256 {
+	return p.Header.Hash()
+}
+
+// Verify checks that the merkle path leads from the transaction hash to the body hash of the block header,
+// and that the block header is signed by the blockchain pubkey
+func (p TransactionInclusionProof) Verify(pubkey cipher.PubKey) error {
+	if p.Header.BkSeq != p.BlockSeq {
+		return ErrInclusionProofBlockSeq
+	}
+
+	if cipher.MerkleRootFromProof(p.TxnHash, p.TxnIndex, p.MerklePath) != p.Header.BodyHash {
+		return ErrInclusionProofMerklePath
+	}
+
+	return cipher.VerifyPubKeySignedHash(pubkey, p.Sig, p.BlockHash())
+}
+
+// GetTransactionInclusionProof returns a proof that a confirmed transaction is included in its block,
+// nil if the transaction is not confirmed
+func (vs *Visor) GetTransactionInclusionProof(txnHash cipher.SHA256) (*TransactionInclusionProof, error) {
+	var proof *TransactionInclusionProof
+
+	if err := vs.viewReplica("GetTransactionInclusionProof", func(tx *dbutil.Tx) error {
+		var err error
+		proof, err = vs.getTransactionInclusionProof(tx, txnHash)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return proof, nil
+}
+
+func (vs *Visor) getTransactionInclusionProof(tx *dbutil.Tx, txnHash cipher.SHA256) (*TransactionInclusionProof, error) {
+	tb, err := vs.history.GetTxnBlock(tx, txnHash)
+	if err != nil {
+		return nil, err
+	}
+
+	if tb == nil {
+		return nil, nil
+	}
+
+	b, err := vs.Blockchain.GetSignedBlockBySeq(tx, tb.BlockSeq)
+	if err != nil {
+		return nil, err
+	}
+
+	if b == nil {
+		return nil, fmt.Errorf("block seq=%d of transaction %s doesn't exist", tb.BlockSeq, txnHash.Hex())
+	}
+
+	if b.HashHeader() != tb.BlockHash {
+		return nil, fmt.Errorf("block seq=%d of transaction %s has hash %s, history db has %s",
+			tb.BlockSeq, txnHash.Hex(), b.HashHeader().Hex(), tb.BlockHash.Hex())
+	}
+
+	hashes := make([]cipher.SHA256, len(b.Body.Transactions))
+	for i := range b.Body.Transactions {
+		hashes[i] = b.Body.Transactions[i].Hash()
+	}
+
+	if tb.TxnIndex >= uint64(len(hashes)) || hashes[tb.TxnIndex] != txnHash {
+		return nil, fmt.Errorf("transaction %s is not at index %d of block seq=%d", txnHash.Hex(), tb.TxnIndex, tb.BlockSeq)
+	}
+
+	path, err := cipher.MerkleProof(hashes, tb.TxnIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TransactionInclusionProof{
+		TxnHash:    txnHash,
+		BlockSeq:   tb.BlockSeq,
+		TxnIndex:   tb.TxnIndex,
+		Header:     b.Head,
+		Sig:        b.Sig,
+		MerklePath: path,
+	}, nil
+}
@@ -0,0 +1,95 @@
+package visor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/historydb"
+)
+
+func TestGetTransactionInclusionProof(t *testing.T) {
+	db, shutdown := copyTestDB(t, "./testdata/data.db.ok")
+	defer shutdown()
+
+	pubkey := mustParsePubkey(t)
+	bc, err := NewBlockchain(db, BlockchainConfig{Pubkey: pubkey})
+	require.NoError(t, err)
+
+	// The test db predates the transaction blocks index, parse the history db again
+	history := historydb.New()
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		if err := historydb.CreateBuckets(tx); err != nil {
+			return err
+		}
+		if err := history.Erase(tx); err != nil {
+			return err
+		}
+		headSeq, _, err := bc.HeadSeq(tx)
+		if err != nil {
+			return err
+		}
+		return parseHistoryTo(tx, history, bc, headSeq)
+	})
+	require.NoError(t, err)
+
+	v := &Visor{
+		DB:         db,
+		Blockchain: bc,
+		history:    history,
+	}
+
+	var headSeq uint64
+	err = db.View("", func(tx *dbutil.Tx) error {
+		var err error
+		headSeq, _, err = bc.HeadSeq(tx)
+		return err
+	})
+	require.NoError(t, err)
+
+	for seq := uint64(0); seq <= headSeq; seq += 5 {
+		var b *coin.SignedBlock
+		err := db.View("", func(tx *dbutil.Tx) error {
+			var err error
+			b, err = bc.GetSignedBlockBySeq(tx, seq)
+			return err
+		})
+		require.NoError(t, err)
+
+		for i, txn := range b.Body.Transactions {
+			proof, err := v.GetTransactionInclusionProof(txn.Hash())
+			require.NoError(t, err)
+			require.NotNil(t, proof)
+			require.Equal(t, seq, proof.BlockSeq)
+			require.Equal(t, uint64(i), proof.TxnIndex)
+			require.Equal(t, b.HashHeader(), proof.BlockHash())
+			require.NoError(t, proof.Verify(pubkey))
+
+			// A proof for another transaction does not verify
+			bad := *proof
+			bad.TxnHash = testutil.RandSHA256(t)
+			require.Equal(t, ErrInclusionProofMerklePath, bad.Verify(pubkey))
+
+			bad = *proof
+			bad.BlockSeq++
+			require.Equal(t, ErrInclusionProofBlockSeq, bad.Verify(pubkey))
+
+			// A proof with a forged header does not verify
+			bad = *proof
+			bad.Header.Time++
+			require.Error(t, bad.Verify(pubkey))
+
+			otherPubkey, _ := cipher.GenerateKeyPair()
+			require.Error(t, proof.Verify(otherPubkey))
+		}
+	}
+
+	// Unknown transactions have no proof
+	proof, err := v.GetTransactionInclusionProof(testutil.RandSHA256(t))
+	require.NoError(t, err)
+	require.Nil(t, proof)
+}
@@ -0,0 +1,49 @@
+package visor
+
+import (
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+// dbMigrations are the schema migrations of the database, in order.
+// Append a migration whenever the layout of a bucket changes.
+// Never remove or reorder migrations, databases record the versions applied to them.
+var dbMigrations = []dbutil.Migration{
+	{
+		// Databases created before schema versioning have no schema version,
+		// this migration marks them with the version of the layout they already have
+		Version: 1,
+		Name:    "Initial schema",
+	},
+}
+
+// DBSchemaVersion is the latest database schema version known by this node
+var DBSchemaVersion = uint64(len(dbMigrations))
+
+// MigrateDB applies the pending schema migrations to the database.
+// Returns dbutil.ErrSchemaTooNew if the database was created by a newer node.
+func MigrateDB(db *dbutil.DB) error {
+	applied, err := dbutil.Migrate(db, dbMigrations)
+	if err != nil {
+		return err
+	}
+
+	if len(applied) != 0 {
+		logger.Infof("Applied %d database migrations, schema version is %d", len(applied), DBSchemaVersion)
+	}
+
+	return nil
+}
+
+// GetDBSchemaVersion returns the schema version of the database, 0 if it was never migrated
+func GetDBSchemaVersion(db *dbutil.DB) (uint64, error) {
+	var version uint64
+	if err := db.View("GetDBSchemaVersion", func(tx *dbutil.Tx) error {
+		var err error
+		version, err = dbutil.GetSchemaVersion(tx)
+		return err
+	}); err != nil {
+		return 0, err
+	}
+
+	return version, nil
+}
@@ -0,0 +1,39 @@
+package visor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+func TestMigrateDB(t *testing.T) {
+	db, shutdown := testutil.PrepareDB(t)
+	defer shutdown()
+
+	v, err := GetDBSchemaVersion(db)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), v)
+
+	err = MigrateDB(db)
+	require.NoError(t, err)
+
+	v, err = GetDBSchemaVersion(db)
+	require.NoError(t, err)
+	require.Equal(t, DBSchemaVersion, v)
+
+	// Migrating again does nothing
+	err = MigrateDB(db)
+	require.NoError(t, err)
+
+	// A database created by a newer node is refused
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		return dbutil.PutBucketValue(tx, dbutil.SchemaBkt, []byte("version"), dbutil.Itob(DBSchemaVersion+1))
+	})
+	require.NoError(t, err)
+
+	err = MigrateDB(db)
+	require.Equal(t, dbutil.NewErrSchemaTooNew(DBSchemaVersion+1, DBSchemaVersion), err)
+}
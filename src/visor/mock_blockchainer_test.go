@@ -27,6 +27,57 @@ func (_m *MockBlockchainer) ExecuteBlock(tx *dbutil.Tx, sb *coin.SignedBlock) er
 	return r0
 }
 
+// GetBlockSeqByTime provides a mock function with given fields: tx, t
+func (_m *MockBlockchainer) GetBlockSeqByTime(tx *dbutil.Tx, t uint64) (uint64, bool, error) {
+	ret := _m.Called(tx, t)
+
+	var r0 uint64
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, uint64) uint64); ok {
+		r0 = rf(tx, t)
+	} else {
+		r0 = ret.Get(0).(uint64)
+	}
+
+	var r1 bool
+	if rf, ok := ret.Get(1).(func(*dbutil.Tx, uint64) bool); ok {
+		r1 = rf(tx, t)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(*dbutil.Tx, uint64) error); ok {
+		r2 = rf(tx, t)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// GetBlockStatsInRange provides a mock function with given fields: tx, start, end
+func (_m *MockBlockchainer) GetBlockStatsInRange(tx *dbutil.Tx, start uint64, end uint64) ([]blockdb.BlockStats, error) {
+	ret := _m.Called(tx, start, end)
+
+	var r0 []blockdb.BlockStats
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, uint64, uint64) []blockdb.BlockStats); ok {
+		r0 = rf(tx, start, end)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]blockdb.BlockStats)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*dbutil.Tx, uint64, uint64) error); ok {
+		r1 = rf(tx, start, end)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetBlocks provides a mock function with given fields: tx, seqs
 func (_m *MockBlockchainer) GetBlocks(tx *dbutil.Tx, seqs []uint64) ([]coin.SignedBlock, error) {
 	ret := _m.Called(tx, seqs)
@@ -165,6 +216,36 @@ func (_m *MockBlockchainer) GetSignedBlockBySeq(tx *dbutil.Tx, seq uint64) (*coi
 	return r0, r1
 }
 
+// GetUnspentCommitment provides a mock function with given fields: tx, seq
+func (_m *MockBlockchainer) GetUnspentCommitment(tx *dbutil.Tx, seq uint64) (cipher.SHA256, bool, error) {
+	ret := _m.Called(tx, seq)
+
+	var r0 cipher.SHA256
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, uint64) cipher.SHA256); ok {
+		r0 = rf(tx, seq)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(cipher.SHA256)
+		}
+	}
+
+	var r1 bool
+	if rf, ok := ret.Get(1).(func(*dbutil.Tx, uint64) bool); ok {
+		r1 = rf(tx, seq)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(*dbutil.Tx, uint64) error); ok {
+		r2 = rf(tx, seq)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 // Head provides a mock function with given fields: tx
 func (_m *MockBlockchainer) Head(tx *dbutil.Tx) (*coin.SignedBlock, error) {
 	ret := _m.Called(tx)
@@ -216,6 +297,27 @@ func (_m *MockBlockchainer) HeadSeq(tx *dbutil.Tx) (uint64, bool, error) {
 	return r0, r1, r2
 }
 
+// IsPruned provides a mock function with given fields: tx, seq
+func (_m *MockBlockchainer) IsPruned(tx *dbutil.Tx, seq uint64) (bool, error) {
+	ret := _m.Called(tx, seq)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, uint64) bool); ok {
+		r0 = rf(tx, seq)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*dbutil.Tx, uint64) error); ok {
+		r1 = rf(tx, seq)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Len provides a mock function with given fields: tx
 func (_m *MockBlockchainer) Len(tx *dbutil.Tx) (uint64, error) {
 	ret := _m.Called(tx)
@@ -260,6 +362,136 @@ func (_m *MockBlockchainer) NewBlock(tx *dbutil.Tx, txns coin.Transactions, curr
 	return r0, r1
 }
 
+// Prune provides a mock function with given fields: tx, depth, limit
+func (_m *MockBlockchainer) Prune(tx *dbutil.Tx, depth uint64, limit uint64) (uint64, error) {
+	ret := _m.Called(tx, depth, limit)
+
+	var r0 uint64
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, uint64, uint64) uint64); ok {
+		r0 = rf(tx, depth, limit)
+	} else {
+		r0 = ret.Get(0).(uint64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*dbutil.Tx, uint64, uint64) error); ok {
+		r1 = rf(tx, depth, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PrunedSeq provides a mock function with given fields: tx
+func (_m *MockBlockchainer) PrunedSeq(tx *dbutil.Tx) (uint64, error) {
+	ret := _m.Called(tx)
+
+	var r0 uint64
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx) uint64); ok {
+		r0 = rf(tx)
+	} else {
+		r0 = ret.Get(0).(uint64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*dbutil.Tx) error); ok {
+		r1 = rf(tx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RebuildBlockStats provides a mock function with given fields: tx
+func (_m *MockBlockchainer) RebuildBlockStats(tx *dbutil.Tx) (uint64, error) {
+	ret := _m.Called(tx)
+
+	var r0 uint64
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx) uint64); ok {
+		r0 = rf(tx)
+	} else {
+		r0 = ret.Get(0).(uint64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*dbutil.Tx) error); ok {
+		r1 = rf(tx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RecompressBlocks provides a mock function with given fields: tx, start, limit
+func (_m *MockBlockchainer) RecompressBlocks(tx *dbutil.Tx, start []byte, limit uint64) (uint64, []byte, error) {
+	ret := _m.Called(tx, start, limit)
+
+	var r0 uint64
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, []byte, uint64) uint64); ok {
+		r0 = rf(tx, start, limit)
+	} else {
+		r0 = ret.Get(0).(uint64)
+	}
+
+	var r1 []byte
+	if rf, ok := ret.Get(1).(func(*dbutil.Tx, []byte, uint64) []byte); ok {
+		r1 = rf(tx, start, limit)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).([]byte)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(*dbutil.Tx, []byte, uint64) error); ok {
+		r2 = rf(tx, start, limit)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// RemoveHead provides a mock function with given fields: tx
+func (_m *MockBlockchainer) RemoveHead(tx *dbutil.Tx) (*coin.SignedBlock, error) {
+	ret := _m.Called(tx)
+
+	var r0 *coin.SignedBlock
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx) *coin.SignedBlock); ok {
+		r0 = rf(tx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*coin.SignedBlock)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*dbutil.Tx) error); ok {
+		r1 = rf(tx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RepairBlock provides a mock function with given fields: tx, b
+func (_m *MockBlockchainer) RepairBlock(tx *dbutil.Tx, b *coin.SignedBlock) error {
+	ret := _m.Called(tx, b)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, *coin.SignedBlock) error); ok {
+		r0 = rf(tx, b)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Time provides a mock function with given fields: tx
 func (_m *MockBlockchainer) Time(tx *dbutil.Tx) (uint64, error) {
 	ret := _m.Called(tx)
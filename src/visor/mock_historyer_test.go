@@ -27,6 +27,20 @@ func (_m *MockHistoryer) Erase(tx *dbutil.Tx) error {
 	return r0
 }
 
+// ForEachRichlistEntry provides a mock function with given fields: tx, f
+func (_m *MockHistoryer) ForEachRichlistEntry(tx *dbutil.Tx, f func(historydb.RichlistEntry) (bool, error)) error {
+	ret := _m.Called(tx, f)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, func(historydb.RichlistEntry) (bool, error)) error); ok {
+		r0 = rf(tx, f)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // ForEachTxn provides a mock function with given fields: tx, f
 func (_m *MockHistoryer) ForEachTxn(tx *dbutil.Tx, f func(cipher.SHA256, *historydb.Transaction) error) error {
 	ret := _m.Called(tx, f)
@@ -41,6 +55,61 @@ func (_m *MockHistoryer) ForEachTxn(tx *dbutil.Tx, f func(cipher.SHA256, *histor
 	return r0
 }
 
+// GetBalanceDistribution provides a mock function with given fields: tx
+func (_m *MockHistoryer) GetBalanceDistribution(tx *dbutil.Tx) ([]historydb.BalanceRange, error) {
+	ret := _m.Called(tx)
+
+	var r0 []historydb.BalanceRange
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx) []historydb.BalanceRange); ok {
+		r0 = rf(tx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]historydb.BalanceRange)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*dbutil.Tx) error); ok {
+		r1 = rf(tx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetOutputsByValuePage provides a mock function with given fields: tx, q, cursor, limit
+func (_m *MockHistoryer) GetOutputsByValuePage(tx *dbutil.Tx, q historydb.OutputValuesQuery, cursor *historydb.OutputValuesCursor, limit uint64) ([]historydb.UxOut, *historydb.OutputValuesCursor, error) {
+	ret := _m.Called(tx, q, cursor, limit)
+
+	var r0 []historydb.UxOut
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, historydb.OutputValuesQuery, *historydb.OutputValuesCursor, uint64) []historydb.UxOut); ok {
+		r0 = rf(tx, q, cursor, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]historydb.UxOut)
+		}
+	}
+
+	var r1 *historydb.OutputValuesCursor
+	if rf, ok := ret.Get(1).(func(*dbutil.Tx, historydb.OutputValuesQuery, *historydb.OutputValuesCursor, uint64) *historydb.OutputValuesCursor); ok {
+		r1 = rf(tx, q, cursor, limit)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*historydb.OutputValuesCursor)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(*dbutil.Tx, historydb.OutputValuesQuery, *historydb.OutputValuesCursor, uint64) error); ok {
+		r2 = rf(tx, q, cursor, limit)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 // GetOutputsForAddress provides a mock function with given fields: tx, address
 func (_m *MockHistoryer) GetOutputsForAddress(tx *dbutil.Tx, address cipher.Address) ([]historydb.UxOut, error) {
 	ret := _m.Called(tx, address)
@@ -64,6 +133,29 @@ func (_m *MockHistoryer) GetOutputsForAddress(tx *dbutil.Tx, address cipher.Addr
 	return r0, r1
 }
 
+// GetRecentTransactionsForAddrs provides a mock function with given fields: tx, addrs, n
+func (_m *MockHistoryer) GetRecentTransactionsForAddrs(tx *dbutil.Tx, addrs []cipher.Address, n uint64) (map[cipher.Address][]historydb.Transaction, error) {
+	ret := _m.Called(tx, addrs, n)
+
+	var r0 map[cipher.Address][]historydb.Transaction
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, []cipher.Address, uint64) map[cipher.Address][]historydb.Transaction); ok {
+		r0 = rf(tx, addrs, n)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[cipher.Address][]historydb.Transaction)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*dbutil.Tx, []cipher.Address, uint64) error); ok {
+		r1 = rf(tx, addrs, n)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetTransaction provides a mock function with given fields: tx, hash
 func (_m *MockHistoryer) GetTransaction(tx *dbutil.Tx, hash cipher.SHA256) (*historydb.Transaction, error) {
 	ret := _m.Called(tx, hash)
@@ -87,6 +179,75 @@ func (_m *MockHistoryer) GetTransaction(tx *dbutil.Tx, hash cipher.SHA256) (*his
 	return r0, r1
 }
 
+// GetAddressBalanceAt provides a mock function with given fields: tx, address, seq
+func (_m *MockHistoryer) GetAddressBalanceAt(tx *dbutil.Tx, address cipher.Address, seq uint64) (*historydb.AddressBalanceDelta, error) {
+	ret := _m.Called(tx, address, seq)
+
+	var r0 *historydb.AddressBalanceDelta
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, cipher.Address, uint64) *historydb.AddressBalanceDelta); ok {
+		r0 = rf(tx, address, seq)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*historydb.AddressBalanceDelta)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*dbutil.Tx, cipher.Address, uint64) error); ok {
+		r1 = rf(tx, address, seq)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetAddressBalanceDeltas provides a mock function with given fields: tx, address, start, end
+func (_m *MockHistoryer) GetAddressBalanceDeltas(tx *dbutil.Tx, address cipher.Address, start uint64, end uint64) ([]historydb.AddressBalanceDelta, error) {
+	ret := _m.Called(tx, address, start, end)
+
+	var r0 []historydb.AddressBalanceDelta
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, cipher.Address, uint64, uint64) []historydb.AddressBalanceDelta); ok {
+		r0 = rf(tx, address, start, end)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]historydb.AddressBalanceDelta)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*dbutil.Tx, cipher.Address, uint64, uint64) error); ok {
+		r1 = rf(tx, address, start, end)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetAddressMeta provides a mock function with given fields: tx, address
+func (_m *MockHistoryer) GetAddressMeta(tx *dbutil.Tx, address cipher.Address) (*historydb.AddressMeta, error) {
+	ret := _m.Called(tx, address)
+
+	var r0 *historydb.AddressMeta
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, cipher.Address) *historydb.AddressMeta); ok {
+		r0 = rf(tx, address)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*historydb.AddressMeta)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*dbutil.Tx, cipher.Address) error); ok {
+		r1 = rf(tx, address)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetTransactionsForAddress provides a mock function with given fields: tx, address
 func (_m *MockHistoryer) GetTransactionsForAddress(tx *dbutil.Tx, address cipher.Address) ([]historydb.Transaction, error) {
 	ret := _m.Called(tx, address)
@@ -110,6 +271,84 @@ func (_m *MockHistoryer) GetTransactionsForAddress(tx *dbutil.Tx, address cipher
 	return r0, r1
 }
 
+// GetTransactionsForAddressPage provides a mock function with given fields: tx, address, cursor, offset, limit
+func (_m *MockHistoryer) GetTransactionsForAddressPage(tx *dbutil.Tx, address cipher.Address, cursor *historydb.AddressTxnsCursor, offset uint64, limit uint64) ([]historydb.Transaction, *historydb.AddressTxnsCursor, error) {
+	ret := _m.Called(tx, address, cursor, offset, limit)
+
+	var r0 []historydb.Transaction
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, cipher.Address, *historydb.AddressTxnsCursor, uint64, uint64) []historydb.Transaction); ok {
+		r0 = rf(tx, address, cursor, offset, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]historydb.Transaction)
+		}
+	}
+
+	var r1 *historydb.AddressTxnsCursor
+	if rf, ok := ret.Get(1).(func(*dbutil.Tx, cipher.Address, *historydb.AddressTxnsCursor, uint64, uint64) *historydb.AddressTxnsCursor); ok {
+		r1 = rf(tx, address, cursor, offset, limit)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*historydb.AddressTxnsCursor)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(*dbutil.Tx, cipher.Address, *historydb.AddressTxnsCursor, uint64, uint64) error); ok {
+		r2 = rf(tx, address, cursor, offset, limit)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// GetTxnBlock provides a mock function with given fields: tx, hash
+func (_m *MockHistoryer) GetTxnBlock(tx *dbutil.Tx, hash cipher.SHA256) (*historydb.TxnBlock, error) {
+	ret := _m.Called(tx, hash)
+
+	var r0 *historydb.TxnBlock
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, cipher.SHA256) *historydb.TxnBlock); ok {
+		r0 = rf(tx, hash)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*historydb.TxnBlock)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*dbutil.Tx, cipher.SHA256) error); ok {
+		r1 = rf(tx, hash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUxOutSpender provides a mock function with given fields: tx, uxID
+func (_m *MockHistoryer) GetUxOutSpender(tx *dbutil.Tx, uxID cipher.SHA256) (*historydb.UxOutSpender, error) {
+	ret := _m.Called(tx, uxID)
+
+	var r0 *historydb.UxOutSpender
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, cipher.SHA256) *historydb.UxOutSpender); ok {
+		r0 = rf(tx, uxID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*historydb.UxOutSpender)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*dbutil.Tx, cipher.SHA256) error); ok {
+		r1 = rf(tx, uxID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetUxOuts provides a mock function with given fields: tx, uxids
 func (_m *MockHistoryer) GetUxOuts(tx *dbutil.Tx, uxids []cipher.SHA256) ([]historydb.UxOut, error) {
 	ret := _m.Called(tx, uxids)
@@ -195,3 +434,17 @@ func (_m *MockHistoryer) ParsedBlockSeq(tx *dbutil.Tx) (uint64, bool, error) {
 
 	return r0, r1, r2
 }
+
+// UnparseBlock provides a mock function with given fields: tx, b
+func (_m *MockHistoryer) UnparseBlock(tx *dbutil.Tx, b coin.Block) error {
+	ret := _m.Called(tx, b)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, coin.Block) error); ok {
+		r0 = rf(tx, b)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
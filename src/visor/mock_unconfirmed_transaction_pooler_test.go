@@ -6,6 +6,7 @@ import cipher "github.com/skycoin/skycoin/src/cipher"
 import coin "github.com/skycoin/skycoin/src/coin"
 import dbutil "github.com/skycoin/skycoin/src/visor/dbutil"
 import mock "github.com/stretchr/testify/mock"
+import time "time"
 
 // MockUnconfirmedTransactionPooler is an autogenerated mock type for the UnconfirmedTransactionPooler type
 type MockUnconfirmedTransactionPooler struct {
@@ -35,6 +36,29 @@ func (_m *MockUnconfirmedTransactionPooler) AllRawTransactions(tx *dbutil.Tx) (c
 	return r0, r1
 }
 
+// Evict provides a mock function with given fields: tx, maxAge, maxSize, now
+func (_m *MockUnconfirmedTransactionPooler) Evict(tx *dbutil.Tx, maxAge time.Duration, maxSize int, now time.Time) ([]EvictedUnconfirmedTxn, error) {
+	ret := _m.Called(tx, maxAge, maxSize, now)
+
+	var r0 []EvictedUnconfirmedTxn
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, time.Duration, int, time.Time) []EvictedUnconfirmedTxn); ok {
+		r0 = rf(tx, maxAge, maxSize, now)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]EvictedUnconfirmedTxn)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*dbutil.Tx, time.Duration, int, time.Time) error); ok {
+		r1 = rf(tx, maxAge, maxSize, now)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // FilterKnown provides a mock function with given fields: tx, txns
 func (_m *MockUnconfirmedTransactionPooler) FilterKnown(tx *dbutil.Tx, txns []cipher.SHA256) ([]cipher.SHA256, error) {
 	ret := _m.Called(tx, txns)
@@ -95,6 +119,29 @@ func (_m *MockUnconfirmedTransactionPooler) Get(tx *dbutil.Tx, hash cipher.SHA25
 	return r0, r1
 }
 
+// GetEvicted provides a mock function with given fields: tx, after, limit
+func (_m *MockUnconfirmedTransactionPooler) GetEvicted(tx *dbutil.Tx, after uint64, limit uint64) ([]EvictedUnconfirmedTxn, error) {
+	ret := _m.Called(tx, after, limit)
+
+	var r0 []EvictedUnconfirmedTxn
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, uint64, uint64) []EvictedUnconfirmedTxn); ok {
+		r0 = rf(tx, after, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]EvictedUnconfirmedTxn)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*dbutil.Tx, uint64, uint64) error); ok {
+		r1 = rf(tx, after, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetFiltered provides a mock function with given fields: tx, filter
 func (_m *MockUnconfirmedTransactionPooler) GetFiltered(tx *dbutil.Tx, filter func(UnconfirmedTransaction) bool) ([]UnconfirmedTransaction, error) {
 	ret := _m.Called(tx, filter)
@@ -190,6 +190,29 @@ func (_m *MockUnspentPooler) Len(_a0 *dbutil.Tx) (uint64, error) {
 	return r0, r1
 }
 
+// LoadSnapshot provides a mock function with given fields: _a0, _a1, _a2
+func (_m *MockUnspentPooler) LoadSnapshot(_a0 *dbutil.Tx, _a1 uint64, _a2 coin.UxArray) (cipher.SHA256, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 cipher.SHA256
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, uint64, coin.UxArray) cipher.SHA256); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(cipher.SHA256)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*dbutil.Tx, uint64, coin.UxArray) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // MaybeBuildIndexes provides a mock function with given fields: _a0, _a1
 func (_m *MockUnspentPooler) MaybeBuildIndexes(_a0 *dbutil.Tx, _a1 uint64) error {
 	ret := _m.Called(_a0, _a1)
@@ -217,3 +240,17 @@ func (_m *MockUnspentPooler) ProcessBlock(_a0 *dbutil.Tx, _a1 *coin.SignedBlock)
 
 	return r0
 }
+
+// RevertBlock provides a mock function with given fields: _a0, _a1, _a2
+func (_m *MockUnspentPooler) RevertBlock(_a0 *dbutil.Tx, _a1 *coin.SignedBlock, _a2 coin.UxArray) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, *coin.SignedBlock, coin.UxArray) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
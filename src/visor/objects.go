@@ -77,14 +77,17 @@ type BlockchainMetadata struct {
 	Unspents uint64
 	// Number of known unconfirmed txns
 	Unconfirmed uint64
+	// Seq of the newest block whose body was pruned, 0 if no block is pruned
+	PrunedSeq uint64
 }
 
 // NewBlockchainMetadata creates blockchain meta data
-func NewBlockchainMetadata(head coin.SignedBlock, unconfirmedLen, unspentsLen uint64) (*BlockchainMetadata, error) {
+func NewBlockchainMetadata(head coin.SignedBlock, unconfirmedLen, unspentsLen, prunedSeq uint64) (*BlockchainMetadata, error) {
 	return &BlockchainMetadata{
 		HeadBlock:   head,
 		Unspents:    unspentsLen,
 		Unconfirmed: unconfirmedLen,
+		PrunedSeq:   prunedSeq,
 	}, nil
 }
 
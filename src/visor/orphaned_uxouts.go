@@ -0,0 +1,98 @@
+package visor
+
+import (
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/historydb"
+)
+
+// MaxOrphanedUxOutsListed is the maximum number of orphaned outputs listed in an OrphanedUxOutsReport,
+// the others are only counted
+const MaxOrphanedUxOutsListed = 1000
+
+// OrphanedUxOutsReport is the result of a scan of the historydb outputs for orphaned outputs
+type OrphanedUxOutsReport struct {
+	// ParsedSeq is the seq of the last block parsed into the historydb
+	ParsedSeq uint64
+	// Scanned is the number of outputs scanned
+	Scanned uint64
+	// Counts is the number of orphaned outputs of each reason
+	Counts map[historydb.OrphanedUxOutReason]uint64
+	// Orphans are the first MaxOrphanedUxOutsListed orphaned outputs, in output hash order
+	Orphans []historydb.OrphanedUxOut
+	// Deleted is true if the orphaned outputs were deleted
+	Deleted bool
+}
+
+// Total returns the number of orphaned outputs
+func (r OrphanedUxOutsReport) Total() uint64 {
+	var n uint64
+	for _, c := range r.Counts {
+		n += c
+	}
+	return n
+}
+
+// CollectOrphanedUxOuts scans the historydb for the outputs referencing transactions or blocks that no longer exist,
+// left behind by partial history rebuilds. If del is true, the orphaned outputs are deleted in the same transaction,
+// the outputs with a stale spender are marked as unspent, and the deletion is recorded in the DBIntegrityReport.
+func CollectOrphanedUxOuts(db *dbutil.DB, del bool) (*OrphanedUxOutsReport, error) {
+	history := historydb.New()
+	report := &OrphanedUxOutsReport{
+		Counts: make(map[historydb.OrphanedUxOutReason]uint64),
+	}
+
+	collect := func(tx *dbutil.Tx) error {
+		parsedSeq, _, err := history.ParsedBlockSeq(tx)
+		if err != nil {
+			return err
+		}
+		report.ParsedSeq = parsedSeq
+
+		var orphans []historydb.OrphanedUxOut
+		report.Scanned, err = history.FindOrphanedUxOuts(tx, func(o historydb.OrphanedUxOut) error {
+			report.Counts[o.Reason]++
+			if len(report.Orphans) < MaxOrphanedUxOutsListed {
+				report.Orphans = append(report.Orphans, o)
+			}
+			if del {
+				orphans = append(orphans, o)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if !del || len(orphans) == 0 {
+			return nil
+		}
+
+		// The outputs are deleted once the scan is done, since bolt cursors can't be used while their bucket changes
+		for _, o := range orphans {
+			if err := history.DeleteOrphanedUxOut(tx, o); err != nil {
+				return err
+			}
+		}
+		report.Deleted = true
+
+		return addDBIntegrityAction(tx, "Deleted %d orphaned outputs from the history", len(orphans))
+	}
+
+	var err error
+	if del {
+		err = db.Update("CollectOrphanedUxOuts", collect)
+	} else {
+		err = db.View("CollectOrphanedUxOuts", collect)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// CollectOrphanedUxOuts scans the historydb for orphaned outputs and deletes them if del is true.
+// See CollectOrphanedUxOuts.
+func (vs *Visor) CollectOrphanedUxOuts(del bool) (*OrphanedUxOutsReport, error) {
+	return CollectOrphanedUxOuts(vs.DB, del)
+}
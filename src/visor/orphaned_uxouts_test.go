@@ -0,0 +1,102 @@
+package visor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher/encoder"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/historydb"
+)
+
+func TestCollectOrphanedUxOuts(t *testing.T) {
+	db, shutdown := prepareDB(t)
+	defer shutdown()
+
+	bc, err := NewBlockchain(db, BlockchainConfig{
+		Pubkey: genPublic,
+	})
+	require.NoError(t, err)
+
+	unconfirmed, err := NewUnconfirmedTransactionPool(db)
+	require.NoError(t, err)
+
+	cfg := NewConfig()
+	cfg.DBPath = db.Path()
+	cfg.BlockchainPubkey = genPublic
+	cfg.GenesisAddress = genAddress
+
+	v := &Visor{
+		Config:      cfg,
+		Unconfirmed: unconfirmed,
+		Blockchain:  bc,
+		DB:          db,
+		history:     historydb.New(),
+		watchList:   newWatchList(),
+	}
+
+	addGenesisBlockToVisor(t, v)
+
+	report, err := v.CollectOrphanedUxOuts(true)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), report.ParsedSeq)
+	require.Equal(t, uint64(1), report.Scanned)
+	require.Equal(t, uint64(0), report.Total())
+	require.Empty(t, report.Orphans)
+	require.False(t, report.Deleted)
+
+	// An output of a block that is not parsed, left behind by a partial rebuild
+	orphan := historydb.UxOut{
+		Out: coin.UxOut{
+			Head: coin.UxHead{
+				BkSeq: 1,
+			},
+			Body: coin.UxBody{
+				SrcTransaction: testutil.RandSHA256(t),
+				Address:        testutil.MakeAddress(),
+				Coins:          1e6,
+			},
+		},
+	}
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		uxID := orphan.Hash()
+		return dbutil.PutBucketValue(tx, historydb.UxOutsBkt, uxID[:], encoder.Serialize(orphan))
+	})
+	require.NoError(t, err)
+
+	// The orphaned outputs are only reported
+	for i := 0; i < 2; i++ {
+		report, err = v.CollectOrphanedUxOuts(false)
+		require.NoError(t, err)
+		require.Equal(t, uint64(2), report.Scanned)
+		require.Equal(t, uint64(1), report.Total())
+		require.Equal(t, map[historydb.OrphanedUxOutReason]uint64{
+			historydb.OrphanedUxOutUnparsedBlock: 1,
+		}, report.Counts)
+		require.Equal(t, []historydb.OrphanedUxOut{
+			{
+				UxOut:  orphan,
+				Reason: historydb.OrphanedUxOutUnparsedBlock,
+			},
+		}, report.Orphans)
+		require.False(t, report.Deleted)
+	}
+
+	// The orphaned outputs are deleted and the deletion is recorded
+	report, err = v.CollectOrphanedUxOuts(true)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), report.Total())
+	require.True(t, report.Deleted)
+
+	integrity, err := GetDBIntegrityReport(db)
+	require.NoError(t, err)
+	require.Equal(t, "Deleted 1 orphaned outputs from the history", integrity.Actions[len(integrity.Actions)-1].Description)
+
+	report, err = v.CollectOrphanedUxOuts(false)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), report.Scanned)
+	require.Equal(t, uint64(0), report.Total())
+}
@@ -0,0 +1,92 @@
+package visor
+
+import (
+	"errors"
+
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/historydb"
+)
+
+// OutputsByValueQuery selects the outputs with coins in a range, created by the blocks in a range
+type OutputsByValueQuery struct {
+	// MinCoins and MaxCoins are the inclusive coins range of the outputs, in droplets
+	MinCoins uint64
+	MaxCoins uint64
+	// LastBlocks selects the outputs created by the LastBlocks most recent blocks.
+	// If 0, the outputs created by the blocks in [StartSeq, EndSeq] are selected.
+	LastBlocks uint64
+	StartSeq   uint64
+	EndSeq     uint64
+}
+
+// OutputsByValuePage is a page of the outputs selected by an OutputsByValueQuery
+type OutputsByValuePage struct {
+	// StartSeq and EndSeq are the block range the outputs were selected from
+	StartSeq uint64
+	EndSeq   uint64
+	Outputs  []historydb.UxOut
+	// NextCursor is the start of the next page, nil if there are no more outputs
+	NextCursor *historydb.OutputValuesCursor
+}
+
+// GetOutputsByValue returns a page of the outputs selected by q, spent or not, in coins, block seq and output hash order.
+// The block range is capped at the head block. Returns ErrBlockNotExist if the range starts after the head block.
+// The page starts from the cursor, or from the first selected output if the cursor is nil.
+// At most limit outputs are returned, all of them if limit is 0.
+// The range of q.LastBlocks moves when blocks are added, so the next pages should be read with the block range of the first page.
+func (vs *Visor) GetOutputsByValue(q OutputsByValueQuery, cursor *historydb.OutputValuesCursor, limit uint64) (*OutputsByValuePage, error) {
+	if q.MinCoins > q.MaxCoins {
+		return nil, errors.New("MinCoins must not be greater than MaxCoins")
+	}
+
+	var page *OutputsByValuePage
+	if err := vs.viewReplica("GetOutputsByValue", func(tx *dbutil.Tx) error {
+		headSeq, ok, err := vs.Blockchain.HeadSeq(tx)
+		if err != nil {
+			return err
+		} else if !ok {
+			return NewErrBlockNotExist(0)
+		}
+
+		start, end := q.StartSeq, q.EndSeq
+		if q.LastBlocks > 0 {
+			end = headSeq
+			start = 0
+			if q.LastBlocks <= headSeq {
+				start = headSeq - q.LastBlocks + 1
+			}
+		}
+
+		if start > end {
+			return errors.New("StartSeq must not be greater than EndSeq")
+		}
+		if start > headSeq {
+			return NewErrBlockNotExist(start)
+		}
+		if end > headSeq {
+			end = headSeq
+		}
+
+		outs, next, err := vs.history.GetOutputsByValuePage(tx, historydb.OutputValuesQuery{
+			MinCoins: q.MinCoins,
+			MaxCoins: q.MaxCoins,
+			StartSeq: start,
+			EndSeq:   end,
+		}, cursor, limit)
+		if err != nil {
+			return err
+		}
+
+		page = &OutputsByValuePage{
+			StartSeq:   start,
+			EndSeq:     end,
+			Outputs:    outs,
+			NextCursor: next,
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
@@ -0,0 +1,156 @@
+package visor
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/historydb"
+)
+
+func TestGetOutputsByValue(t *testing.T) {
+	db, shutdown := prepareDB(t)
+	defer shutdown()
+
+	bc, err := NewBlockchain(db, BlockchainConfig{
+		Pubkey: genPublic,
+	})
+	require.NoError(t, err)
+
+	unconfirmed, err := NewUnconfirmedTransactionPool(db)
+	require.NoError(t, err)
+
+	cfg := NewConfig()
+	cfg.DBPath = db.Path()
+	cfg.IsBlockPublisher = true
+	cfg.BlockchainPubkey = genPublic
+	cfg.BlockchainSeckey = genSecret
+	cfg.GenesisAddress = genAddress
+
+	v := &Visor{
+		Config:      cfg,
+		Unconfirmed: unconfirmed,
+		Blockchain:  bc,
+		DB:          db,
+		history:     historydb.New(),
+		watchList:   newWatchList(),
+	}
+
+	gb := addGenesisBlockToVisor(t, v)
+	genUx := coin.CreateUnspents(gb.Head, gb.Body.Transactions[0])[0]
+
+	// executeTxn executes a block with a transaction
+	executeTxn := func(txn coin.Transaction) coin.UxArray {
+		_, _, err := v.InjectForeignTransaction(txn)
+		require.NoError(t, err)
+
+		var sb coin.SignedBlock
+		err = db.Update("", func(tx *dbutil.Tx) error {
+			head, err := bc.Head(tx)
+			require.NoError(t, err)
+
+			sb, err = v.createBlock(tx, head.Time()+3600)
+			require.NoError(t, err)
+
+			return v.executeSignedBlock(tx, sb)
+		})
+		require.NoError(t, err)
+		v.clearApplyJournal()
+		return coin.CreateUnspents(sb.Head, txn)
+	}
+
+	toPubkey, toSeckey := cipher.GenerateKeyPair()
+	toAddr := cipher.AddressFromPubKey(toPubkey)
+	uxs1 := executeTxn(makeSpendTxWithFee(t, coin.UxArray{genUx}, []cipher.SecKey{genSecret}, toAddr, 3e6, 0))
+	uxs2 := executeTxn(makeSpendTxWithFee(t, coin.UxArray{uxs1[0]}, []cipher.SecKey{toSeckey}, testutil.MakeAddress(), 2e6, 0))
+
+	hashes := func(outs []historydb.UxOut) []cipher.SHA256 {
+		h := make([]cipher.SHA256, len(outs))
+		for i, o := range outs {
+			h[i] = o.Hash()
+		}
+		return h
+	}
+
+	// The range is capped at the head block
+	page, err := v.GetOutputsByValue(OutputsByValueQuery{
+		MinCoins: 1e6,
+		MaxCoins: 5e6,
+		EndSeq:   math.MaxUint64,
+	}, nil, 0)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), page.StartSeq)
+	require.Equal(t, uint64(2), page.EndSeq)
+	require.Equal(t, []cipher.SHA256{uxs2[1].Hash(), uxs2[0].Hash(), uxs1[0].Hash()}, hashes(page.Outputs))
+	require.Nil(t, page.NextCursor)
+
+	// Spent outputs are returned
+	page, err = v.GetOutputsByValue(OutputsByValueQuery{
+		MinCoins: 3e6,
+		MaxCoins: 3e6,
+		EndSeq:   math.MaxUint64,
+	}, nil, 0)
+	require.NoError(t, err)
+	require.Len(t, page.Outputs, 1)
+	require.Equal(t, uxs1[0].Hash(), page.Outputs[0].Hash())
+	require.Equal(t, uint64(2), page.Outputs[0].SpentBlockSeq)
+
+	// LastBlocks selects the most recent blocks
+	page, err = v.GetOutputsByValue(OutputsByValueQuery{
+		MinCoins:   1e6,
+		MaxCoins:   5e6,
+		LastBlocks: 1,
+	}, nil, 0)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), page.StartSeq)
+	require.Equal(t, uint64(2), page.EndSeq)
+	require.Equal(t, []cipher.SHA256{uxs2[1].Hash(), uxs2[0].Hash()}, hashes(page.Outputs))
+
+	page, err = v.GetOutputsByValue(OutputsByValueQuery{
+		MaxCoins:   math.MaxUint64,
+		LastBlocks: 10,
+	}, nil, 0)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), page.StartSeq)
+	require.Len(t, page.Outputs, 5)
+
+	// Pages are read with the cursor
+	q := OutputsByValueQuery{
+		MinCoins: 1e6,
+		MaxCoins: 5e6,
+		StartSeq: 1,
+		EndSeq:   2,
+	}
+	page, err = v.GetOutputsByValue(q, nil, 1)
+	require.NoError(t, err)
+	require.Equal(t, []cipher.SHA256{uxs2[1].Hash()}, hashes(page.Outputs))
+	require.Equal(t, &historydb.OutputValuesCursor{
+		Coins:    2e6,
+		BlockSeq: 2,
+		UxID:     uxs2[0].Hash(),
+	}, page.NextCursor)
+
+	page, err = v.GetOutputsByValue(q, page.NextCursor, 2)
+	require.NoError(t, err)
+	require.Equal(t, []cipher.SHA256{uxs2[0].Hash(), uxs1[0].Hash()}, hashes(page.Outputs))
+	require.Nil(t, page.NextCursor)
+
+	// A range starting after the head block doesn't exist
+	_, err = v.GetOutputsByValue(OutputsByValueQuery{
+		MaxCoins: math.MaxUint64,
+		StartSeq: 3,
+		EndSeq:   math.MaxUint64,
+	}, nil, 0)
+	require.Equal(t, NewErrBlockNotExist(3), err)
+
+	_, err = v.GetOutputsByValue(OutputsByValueQuery{
+		MinCoins: 2,
+		MaxCoins: 1,
+	}, nil, 0)
+	require.EqualError(t, err, "MinCoins must not be greater than MaxCoins")
+}
@@ -0,0 +1,90 @@
+package visor
+
+import (
+	"fmt"
+
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/blockdb"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+// MinPruneDepth is the minimum number of recent blocks whose body is kept by a pruned node,
+// so that peers that are a few blocks behind can still sync from it
+const MinPruneDepth = 100
+
+// pruneBatchSize is the maximum number of blocks pruned in a single database transaction
+const pruneBatchSize = 1000
+
+// pruneBlocks removes the body of the blocks that are more than vs.Config.PruneDepth blocks below the head block.
+// The blocks are pruned in batches, so that a large backlog of blocks, such as when pruning is enabled on an
+// existing database, does not hold a single database transaction for too long.
+func (vs *Visor) pruneBlocks() error {
+	if vs.Config.PruneDepth == 0 || vs.DB.IsReadOnly() {
+		return nil
+	}
+
+	var total uint64
+	for {
+		var n uint64
+		if err := vs.DB.Update("pruneBlocks", func(tx *dbutil.Tx) error {
+			var err error
+			n, err = vs.Blockchain.Prune(tx, vs.Config.PruneDepth, pruneBatchSize)
+			return err
+		}); err != nil {
+			return err
+		}
+
+		if n == 0 {
+			break
+		}
+
+		total += n
+		logger.Infof("Pruned %d blocks", total)
+	}
+
+	return nil
+}
+
+// checkBlocksPruned returns blockdb.ErrBlockPruned if the body of any of the blocks was pruned.
+// The headers of pruned blocks are still stored, so pruned blocks can be read for their header,
+// but must not be returned to callers that expect the transactions of the block.
+func checkBlocksPruned(tx *dbutil.Tx, bc Blockchainer, blocks ...coin.SignedBlock) error {
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	prunedSeq, err := bc.PrunedSeq(tx)
+	if err != nil {
+		return err
+	}
+
+	for _, b := range blocks {
+		if b.Seq() != 0 && b.Seq() <= prunedSeq {
+			return blockdb.NewErrBlockPruned(b.Seq())
+		}
+	}
+
+	return nil
+}
+
+// checkBlockPruned is like checkBlocksPruned, for a single block, which can be nil
+func checkBlockPruned(tx *dbutil.Tx, bc Blockchainer, b *coin.SignedBlock) error {
+	if b == nil {
+		return nil
+	}
+	return checkBlocksPruned(tx, bc, *b)
+}
+
+// checkHistoryNotPruned returns an error if the historydb would have to be rebuilt from pruned blocks
+func checkHistoryNotPruned(tx *dbutil.Tx, bc *Blockchain, fromSeq uint64) error {
+	prunedSeq, err := bc.PrunedSeq(tx)
+	if err != nil {
+		return err
+	}
+
+	if fromSeq != 0 && fromSeq <= prunedSeq {
+		return fmt.Errorf("Cannot parse the history of pruned blocks: %v", blockdb.NewErrBlockPruned(fromSeq))
+	}
+
+	return nil
+}
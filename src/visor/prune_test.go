@@ -0,0 +1,180 @@
+package visor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/blockdb"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/historydb"
+)
+
+func TestPruneBlocks(t *testing.T) {
+	db, shutdown := copyTestDB(t, "./testdata/data.db.ok")
+	defer shutdown()
+
+	pubkey := mustParsePubkey(t)
+	bc, err := NewBlockchain(db, BlockchainConfig{Pubkey: pubkey})
+	require.NoError(t, err)
+
+	utp, err := NewUnconfirmedTransactionPool(db)
+	require.NoError(t, err)
+
+	v := &Visor{
+		Config: Config{
+			BlockchainPubkey: pubkey,
+			PruneDepth:       4,
+		},
+		DB:          db,
+		Blockchain:  bc,
+		Unconfirmed: utp,
+		history:     historydb.New(),
+	}
+
+	var headSeq uint64
+	originals := make(map[uint64]coin.SignedBlock)
+	err = db.View("", func(tx *dbutil.Tx) error {
+		var ok bool
+		headSeq, ok, err = bc.HeadSeq(tx)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		for i := uint64(0); i <= headSeq; i++ {
+			b, err := bc.GetSignedBlockBySeq(tx, i)
+			require.NoError(t, err)
+			originals[i] = *b
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint64(10), headSeq)
+
+	prunedSeq := headSeq - v.Config.PruneDepth
+
+	// The blocks are pruned in batches limited in size
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		n, err := bc.Prune(tx, v.Config.PruneDepth, 2)
+		require.NoError(t, err)
+		require.Equal(t, uint64(2), n)
+
+		seq, err := bc.PrunedSeq(tx)
+		require.NoError(t, err)
+		require.Equal(t, uint64(2), seq)
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = v.pruneBlocks()
+	require.NoError(t, err)
+
+	// Pruning again does nothing
+	err = v.pruneBlocks()
+	require.NoError(t, err)
+
+	err = db.View("", func(tx *dbutil.Tx) error {
+		seq, err := bc.PrunedSeq(tx)
+		require.NoError(t, err)
+		require.Equal(t, prunedSeq, seq)
+
+		// The headers and signatures of the pruned blocks are kept
+		for i := uint64(0); i <= headSeq; i++ {
+			b, err := bc.GetSignedBlockBySeq(tx, i)
+			require.NoError(t, err)
+			require.Equal(t, originals[i].HashHeader(), b.HashHeader())
+			require.Equal(t, originals[i].Sig, b.Sig)
+			require.NoError(t, b.VerifySignature(pubkey))
+
+			pruned, err := bc.IsPruned(tx, i)
+			require.NoError(t, err)
+
+			if i != 0 && i <= prunedSeq {
+				require.True(t, pruned)
+				require.Empty(t, b.Body.Transactions)
+			} else {
+				require.False(t, pruned)
+				require.Equal(t, originals[i].Body, b.Body)
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	// Pruned blocks are not returned to callers that expect the block transactions
+	_, err = v.GetSignedBlockBySeq(prunedSeq)
+	require.Equal(t, blockdb.NewErrBlockPruned(prunedSeq), err)
+
+	_, err = v.GetSignedBlockByHash(originals[1].HashHeader())
+	require.Equal(t, blockdb.NewErrBlockPruned(1), err)
+
+	_, _, err = v.GetSignedBlockBySeqVerbose(3)
+	require.Equal(t, blockdb.NewErrBlockPruned(3), err)
+
+	_, err = v.GetBlocksInRange(prunedSeq, headSeq)
+	require.Equal(t, blockdb.NewErrBlockPruned(prunedSeq), err)
+
+	_, err = v.GetSignedBlocksSince(0, 3)
+	require.Equal(t, blockdb.NewErrBlockPruned(1), err)
+
+	// The headers of pruned blocks are still returned
+	headers, err := v.GetSignedBlockHeadersSince(0, headSeq+10)
+	require.NoError(t, err)
+	require.Len(t, headers, int(headSeq))
+	for i, h := range headers {
+		require.Equal(t, originals[uint64(i)+1].SignedHeader(), h)
+	}
+
+	headers, err = v.GetSignedBlockHeadersSince(headSeq, 10)
+	require.NoError(t, err)
+	require.Empty(t, headers)
+
+	head, ok, err := v.GetHeadBlockHeader()
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, originals[headSeq].SignedHeader(), head)
+
+	b, err := v.GetSignedBlockBySeq(0)
+	require.NoError(t, err)
+	require.Equal(t, originals[0], *b)
+
+	blocks, err := v.GetBlocksInRange(prunedSeq+1, headSeq)
+	require.NoError(t, err)
+	require.Len(t, blocks, int(v.Config.PruneDepth))
+
+	blocks, err = v.GetSignedBlocksSince(prunedSeq, 10)
+	require.NoError(t, err)
+	require.Len(t, blocks, int(v.Config.PruneDepth))
+
+	metadata, err := v.GetBlockchainMetadata()
+	require.NoError(t, err)
+	require.Equal(t, prunedSeq, metadata.PrunedSeq)
+
+	// The pruned database still passes verification
+	err = CheckDatabase(context.Background(), db, CheckDatabaseConfig{Pubkey: pubkey, FullVerify: true})
+	require.NoError(t, err)
+
+	// The historydb can't be rebuilt from pruned blocks
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		require.NoError(t, v.history.Erase(tx))
+		err := parseHistoryTo(tx, v.history.(*historydb.HistoryDB), bc, headSeq)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), blockdb.NewErrBlockPruned(1).Error())
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestConfigVerifyPruneDepth(t *testing.T) {
+	c := NewConfig()
+
+	c.PruneDepth = MinPruneDepth - 1
+	require.EqualError(t, c.Verify(), "PruneDepth must be 0 or >= 100")
+
+	c.PruneDepth = MinPruneDepth
+	require.NoError(t, c.Verify())
+
+	c.PruneDepth = 0
+	require.NoError(t, c.Verify())
+}
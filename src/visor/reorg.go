@@ -0,0 +1,116 @@
+package visor
+
+import (
+	"fmt"
+
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/blockdb"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+// MaxReorgDepth is the maximum number of blocks that ReorgTo can remove from the head of the blockchain.
+// It must not be larger than historydb.UnparseDepth.
+const MaxReorgDepth = blockdb.ReorgDepth
+
+// ReorgTo removes the blocks after the block of seq from the blockchain, so that the node can recover from a divergent
+// chain published by the block publisher, by syncing the blocks after seq again from its peers.
+// The blocks are removed from the blockchain, the unspent pool and the history db in a single database transaction.
+// The transactions of the removed blocks are returned to the unconfirmed pool, unless they spend outputs created by
+// a removed block or violate hard constraints. At most MaxReorgDepth blocks can be removed.
+// Returns the removed blocks, most recent first.
+func (vs *Visor) ReorgTo(seq uint64) ([]coin.SignedBlock, error) {
+	var removed []coin.SignedBlock
+	if err := vs.DB.Update("ReorgTo", func(tx *dbutil.Tx) error {
+		var err error
+		removed, err = vs.reorgTo(tx, seq)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	if len(removed) != 0 {
+		logger.Critical().Infof("Reorganized the blockchain to block %d, removed %d blocks", seq, len(removed))
+	}
+
+	return removed, nil
+}
+
+func (vs *Visor) reorgTo(tx *dbutil.Tx, seq uint64) ([]coin.SignedBlock, error) {
+	headSeq, ok, err := vs.Blockchain.HeadSeq(tx)
+	if err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, blockdb.ErrNoHeadBlock
+	}
+
+	if seq > headSeq {
+		return nil, NewErrBlockNotExist(seq)
+	}
+
+	if seq == headSeq {
+		return nil, nil
+	}
+
+	if headSeq-seq > MaxReorgDepth {
+		return nil, fmt.Errorf("Cannot remove %d blocks, at most %d blocks can be removed", headSeq-seq, MaxReorgDepth)
+	}
+
+	parsedSeq, ok, err := vs.history.ParsedBlockSeq(tx)
+	if err != nil {
+		return nil, err
+	} else if !ok || parsedSeq != headSeq {
+		return nil, fmt.Errorf("History db is not parsed up to the head block %d", headSeq)
+	}
+
+	removed := make([]coin.SignedBlock, 0, headSeq-seq)
+	for i := headSeq; i > seq; i-- {
+		b, err := vs.Blockchain.RemoveHead(tx)
+		if err != nil {
+			return nil, fmt.Errorf("Remove block %d failed: %v", i, err)
+		}
+
+		if err := vs.history.UnparseBlock(tx, b.Block); err != nil {
+			return nil, fmt.Errorf("Unparse block %d failed: %v", i, err)
+		}
+
+		removed = append(removed, *b)
+	}
+
+	// The transactions that spend outputs of the removed blocks are no longer valid
+	if _, err := vs.Unconfirmed.RemoveInvalid(tx, vs.Blockchain); err != nil {
+		return nil, err
+	}
+
+	// Return the transactions of the removed blocks to the unconfirmed pool, oldest first.
+	// The transactions that spend outputs created by a removed block can't be injected, since those outputs don't exist.
+	for i := len(removed) - 1; i >= 0; i-- {
+		for _, txn := range removed[i].Body.Transactions {
+			if _, _, err := vs.Unconfirmed.InjectTransaction(tx, vs.Blockchain, txn, vs.Config.UnconfirmedMaxTransactionSize, vs.Config.UnconfirmedBurnFactor); err != nil {
+				switch err.(type) {
+				case ErrTxnViolatesHardConstraint:
+					logger.WithError(err).Infof("Transaction %s of removed block %d not returned to the unconfirmed pool", txn.TxIDHex(), removed[i].Seq())
+				default:
+					return nil, err
+				}
+			}
+		}
+	}
+
+	// The verification checkpoint must not point to a removed block
+	cp, err := dbutil.GetVerifyCheckpoint(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if cp != nil && cp.Seq > seq {
+		if err := dbutil.ResetVerifyCheckpoint(tx); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := addDBIntegrityAction(tx, "Reorganized the blockchain to block %d, removed %d blocks", seq, len(removed)); err != nil {
+		return nil, err
+	}
+
+	return removed, nil
+}
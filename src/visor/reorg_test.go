@@ -0,0 +1,171 @@
+package visor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/params"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/historydb"
+)
+
+func TestReorgTo(t *testing.T) {
+	db, shutdown := prepareDB(t)
+	defer shutdown()
+
+	bc, err := NewBlockchain(db, BlockchainConfig{
+		Pubkey: genPublic,
+	})
+	require.NoError(t, err)
+
+	unconfirmed, err := NewUnconfirmedTransactionPool(db)
+	require.NoError(t, err)
+
+	cfg := NewConfig()
+	cfg.DBPath = db.Path()
+	cfg.IsBlockPublisher = true
+	cfg.BlockchainPubkey = genPublic
+	cfg.BlockchainSeckey = genSecret
+	cfg.GenesisAddress = genAddress
+
+	v := &Visor{
+		Config:      cfg,
+		Unconfirmed: unconfirmed,
+		Blockchain:  bc,
+		DB:          db,
+		history:     historydb.New(),
+	}
+
+	gb := addGenesisBlockToVisor(t, v)
+
+	// executeBlock injects txns and executes a block with them
+	executeBlock := func(txns ...coin.Transaction) coin.SignedBlock {
+		var sb coin.SignedBlock
+		err := db.Update("", func(tx *dbutil.Tx) error {
+			head, err := bc.Head(tx)
+			require.NoError(t, err)
+
+			for _, txn := range txns {
+				_, softErr, err := unconfirmed.InjectTransaction(tx, bc, txn, v.Config.UnconfirmedMaxTransactionSize, v.Config.UnconfirmedBurnFactor)
+				require.NoError(t, err)
+				require.Nil(t, softErr)
+			}
+
+			sb, err = v.createBlock(tx, head.Time()+3600)
+			require.NoError(t, err)
+			require.Equal(t, len(txns), len(sb.Body.Transactions))
+
+			return v.executeSignedBlock(tx, sb)
+		})
+		require.NoError(t, err)
+		v.clearApplyJournal()
+		return sb
+	}
+
+	uxHash := func() cipher.SHA256 {
+		var h cipher.SHA256
+		err := db.View("", func(tx *dbutil.Tx) error {
+			var err error
+			h, err = bc.Unspent().GetUxHash(tx)
+			return err
+		})
+		require.NoError(t, err)
+		return h
+	}
+
+	toPubkey, toSeckey := cipher.GenerateKeyPair()
+	toAddr := cipher.AddressFromPubKey(toPubkey)
+
+	// Block 1 splits the genesis output
+	txn1 := makeUnspentsTx(t, coin.CreateUnspents(gb.Head, gb.Body.Transactions[0]), []cipher.SecKey{genSecret}, genAddress, 10, params.MaxDropletDivisor())
+	b1 := executeBlock(txn1)
+	uxs1 := coin.CreateUnspents(b1.Head, txn1)
+	uxHash1 := uxHash()
+
+	// Block 2 sends coins to toAddr
+	txn2 := makeSpendTxWithFee(t, coin.UxArray{uxs1[0]}, []cipher.SecKey{genSecret}, toAddr, 1e6, 0)
+	b2 := executeBlock(txn2)
+	uxs2 := coin.CreateUnspents(b2.Head, txn2)
+
+	// Block 3 spends an output created by block 2, and an output created by block 1
+	txn3 := makeSpendTxWithFee(t, coin.UxArray{uxs2[0]}, []cipher.SecKey{toSeckey}, genAddress, 1e6, 0)
+	txn4 := makeSpendTxWithFee(t, coin.UxArray{uxs1[1]}, []cipher.SecKey{genSecret}, toAddr, 1e6, 0)
+	b3 := executeBlock(txn3, txn4)
+
+	// Can't reorganize to a block after the head block
+	_, err = v.ReorgTo(4)
+	require.Equal(t, NewErrBlockNotExist(4), err)
+
+	// Reorganizing to the head block does nothing
+	removed, err := v.ReorgTo(3)
+	require.NoError(t, err)
+	require.Empty(t, removed)
+
+	removed, err = v.ReorgTo(1)
+	require.NoError(t, err)
+	require.Equal(t, []coin.SignedBlock{b3, b2}, removed)
+
+	require.Equal(t, uxHash1, uxHash())
+
+	err = db.View("", func(tx *dbutil.Tx) error {
+		headSeq, ok, err := bc.HeadSeq(tx)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, uint64(1), headSeq)
+
+		parsedSeq, ok, err := v.history.ParsedBlockSeq(tx)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, uint64(1), parsedSeq)
+
+		// The history of the removed blocks is removed
+		for _, txn := range []coin.Transaction{txn2, txn3, txn4} {
+			historyTxn, err := v.history.GetTransaction(tx, txn.Hash())
+			require.NoError(t, err)
+			require.Nil(t, historyTxn)
+		}
+
+		meta, err := v.history.GetAddressMeta(tx, toAddr)
+		require.NoError(t, err)
+		require.Nil(t, meta)
+
+		uxOuts, err := v.history.GetUxOuts(tx, []cipher.SHA256{uxs1[0].Hash()})
+		require.NoError(t, err)
+		require.Equal(t, uint64(0), uxOuts[0].SpentBlockSeq)
+
+		// The transactions of the removed blocks are returned to the unconfirmed pool,
+		// except txn3, which spends an output created by a removed block
+		for _, txn := range []coin.Transaction{txn2, txn4} {
+			utxn, err := unconfirmed.Get(tx, txn.Hash())
+			require.NoError(t, err)
+			require.NotNil(t, utxn)
+		}
+
+		utxn, err := unconfirmed.Get(tx, txn3.Hash())
+		require.NoError(t, err)
+		require.Nil(t, utxn)
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = CheckDatabase(context.Background(), db, CheckDatabaseConfig{Pubkey: genPublic, FullVerify: true})
+	require.NoError(t, err)
+
+	// The chain can be extended again
+	b2 = executeBlock(txn2, txn4)
+	require.Equal(t, uint64(2), b2.Seq())
+	require.Len(t, b2.Body.Transactions, 2)
+
+	// Blocks can be removed down to the genesis block
+	removed, err = v.ReorgTo(0)
+	require.NoError(t, err)
+	require.Len(t, removed, 2)
+
+	err = CheckDatabase(context.Background(), db, CheckDatabaseConfig{Pubkey: genPublic, FullVerify: true})
+	require.NoError(t, err)
+}
@@ -0,0 +1,120 @@
+package visor
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+// blockRepairer tracks the corrupted blocks that are waiting to be repaired with copies received from peers
+type blockRepairer struct {
+	sync.Mutex
+	pending map[uint64]struct{}
+}
+
+func newBlockRepairer(seqs []uint64) *blockRepairer {
+	r := &blockRepairer{
+		pending: make(map[uint64]struct{}, len(seqs)),
+	}
+	r.add(seqs)
+	return r
+}
+
+func (r *blockRepairer) add(seqs []uint64) {
+	r.Lock()
+	defer r.Unlock()
+	for _, seq := range seqs {
+		r.pending[seq] = struct{}{}
+	}
+}
+
+func (r *blockRepairer) has(seq uint64) bool {
+	r.Lock()
+	defer r.Unlock()
+	_, ok := r.pending[seq]
+	return ok
+}
+
+func (r *blockRepairer) remove(seq uint64) {
+	r.Lock()
+	defer r.Unlock()
+	delete(r.pending, seq)
+}
+
+func (r *blockRepairer) seqs() []uint64 {
+	r.Lock()
+	defer r.Unlock()
+
+	seqs := make([]uint64, 0, len(r.pending))
+	for seq := range r.pending {
+		seqs = append(seqs, seq)
+	}
+
+	sort.Slice(seqs, func(i, j int) bool {
+		return seqs[i] < seqs[j]
+	})
+	return seqs
+}
+
+// StartBlockRepair adds the blocks of an ErrCorruptDB to the blocks waiting to be repaired with copies
+// received from peers. Returns false if the repair is disabled, the error has no corrupted blocks,
+// or the database is read-only, in which case the database can only be recovered with RecoverCorruptDB.
+func (vs *Visor) StartBlockRepair(err ErrCorruptDB) bool {
+	if vs.Config.BlockRepairTimeout == 0 || len(err.CorruptedBlocks) == 0 || vs.DB.IsReadOnly() {
+		return false
+	}
+
+	logger.Critical().WithError(err).Errorf("Database has %d corrupted blocks, waiting for peers to repair them", len(err.CorruptedBlocks))
+	vs.blockRepairer.add(err.CorruptedBlocks)
+	return true
+}
+
+// PendingBlockRepairs returns the seqs of the corrupted blocks that are waiting to be repaired, in ascending order
+func (vs *Visor) PendingBlockRepairs() []uint64 {
+	return vs.blockRepairer.seqs()
+}
+
+// RepairBlocks repairs the corrupted blocks that are waiting to be repaired with the blocks received from a peer.
+// Blocks that are not waiting to be repaired are ignored. A block is only accepted if it has a valid signature,
+// the header hash of the stored main chain block at its seq, and a body matching its header, so a peer can't
+// change the blockchain by sending a different block. Returns the number of blocks repaired.
+func (vs *Visor) RepairBlocks(blocks []coin.SignedBlock) (int, error) {
+	var candidates []*coin.SignedBlock
+	for i := range blocks {
+		if vs.blockRepairer.has(blocks[i].Seq()) {
+			candidates = append(candidates, &blocks[i])
+		}
+	}
+
+	if len(candidates) == 0 {
+		return 0, nil
+	}
+
+	var repaired []uint64
+	if err := vs.DB.Update("RepairBlocks", func(tx *dbutil.Tx) error {
+		for _, b := range candidates {
+			if err := vs.Blockchain.RepairBlock(tx, b); err != nil {
+				logger.WithError(err).WithField("seq", b.Seq()).Warning("Received block can't repair the corrupted block")
+				continue
+			}
+
+			if err := addDBIntegrityAction(tx, "Repaired corrupted block %d with a copy received from a peer", b.Seq()); err != nil {
+				return err
+			}
+
+			repaired = append(repaired, b.Seq())
+		}
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	for _, seq := range repaired {
+		logger.Critical().WithField("seq", seq).Info("Repaired corrupted block")
+		vs.blockRepairer.remove(seq)
+	}
+
+	return len(repaired), nil
+}
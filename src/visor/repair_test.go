@@ -0,0 +1,128 @@
+package visor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/encoder"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor/blockdb"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+func TestRepairBlocks(t *testing.T) {
+	db, shutdown := copyTestDB(t, "./testdata/data.db.ok")
+	defer shutdown()
+
+	pubkey := mustParsePubkey(t)
+	bc, err := NewBlockchain(db, BlockchainConfig{Pubkey: pubkey})
+	require.NoError(t, err)
+
+	originals := make(map[uint64]coin.SignedBlock)
+	err = db.View("", func(tx *dbutil.Tx) error {
+		for _, seq := range []uint64{3, 4, 5} {
+			b, err := bc.GetSignedBlockBySeq(tx, seq)
+			require.NoError(t, err)
+			originals[seq] = *b
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	// Corrupt the signature of block 3 and the body of block 5
+	_, seckey := cipher.GenerateKeyPair()
+	badSig := cipher.MustSignHash(testutil.RandSHA256(t), seckey)
+	badBody := originals[5].Block
+	badBody.Body = coin.BlockBody{}
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		hash := originals[3].HashHeader()
+		require.NoError(t, dbutil.PutBucketValue(tx, blockdb.BlockSigsBkt, hash[:], encoder.Serialize(badSig)))
+
+		hash = originals[5].HashHeader()
+		require.NoError(t, dbutil.PutBucketValue(tx, blockdb.BlocksBkt, hash[:], encoder.Serialize(badBody)))
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = CheckDatabase(context.Background(), db, CheckDatabaseConfig{Pubkey: pubkey, FullVerify: true})
+	require.IsType(t, ErrCorruptDB{}, err)
+	require.True(t, IsCorruptDBError(err))
+	corruptErr := err.(ErrCorruptDB)
+	require.Equal(t, []uint64{3, 5}, corruptErr.CorruptedBlocks)
+
+	report, err := GetDBIntegrityReport(db)
+	require.NoError(t, err)
+	require.Equal(t, corruptErr.Error(), report.Error)
+	require.Equal(t, []uint64{3, 5}, report.CorruptedBlocks)
+
+	v := &Visor{
+		Config: Config{
+			BlockchainPubkey: pubkey,
+		},
+		DB:            db,
+		Blockchain:    bc,
+		blockRepairer: newBlockRepairer(nil),
+	}
+
+	// The repair is disabled without a timeout
+	require.False(t, v.StartBlockRepair(corruptErr))
+	require.Empty(t, v.PendingBlockRepairs())
+
+	v.Config.BlockRepairTimeout = time.Minute
+	require.True(t, v.StartBlockRepair(corruptErr))
+	require.Equal(t, []uint64{3, 5}, v.PendingBlockRepairs())
+
+	// Blocks with an invalid signature or body are rejected
+	wrongSig := originals[3]
+	wrongSig.Sig = badSig
+	wrongBody := originals[5]
+	wrongBody.Block = badBody
+
+	n, err := v.RepairBlocks([]coin.SignedBlock{wrongSig, wrongBody})
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+	require.Equal(t, []uint64{3, 5}, v.PendingBlockRepairs())
+
+	// Valid copies repair the corrupted blocks, blocks that are not corrupted are ignored
+	n, err = v.RepairBlocks([]coin.SignedBlock{originals[3], originals[4], originals[5]})
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.Empty(t, v.PendingBlockRepairs())
+
+	err = CheckDatabase(context.Background(), db, CheckDatabaseConfig{Pubkey: pubkey, FullVerify: true})
+	require.NoError(t, err)
+
+	// The repairs are recorded in the integrity report
+	report, err = GetDBIntegrityReport(db)
+	require.NoError(t, err)
+	require.Empty(t, report.Error)
+	require.Len(t, report.Actions, 2)
+	require.Equal(t, "Repaired corrupted block 3 with a copy received from a peer", report.Actions[0].Description)
+	require.Equal(t, "Repaired corrupted block 5 with a copy received from a peer", report.Actions[1].Description)
+
+	err = db.View("", func(tx *dbutil.Tx) error {
+		for seq, b := range originals {
+			stored, err := bc.GetSignedBlockBySeq(tx, seq)
+			require.NoError(t, err)
+			require.Equal(t, b, *stored)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestCheckDatabaseWrongPubkey(t *testing.T) {
+	db, shutdown := copyTestDB(t, "./testdata/data.db.ok")
+	defer shutdown()
+
+	// If no signature can be verified, the pubkey is wrong and the blocks are not reported as corrupted
+	pubkey, _ := cipher.GenerateKeyPair()
+	err := CheckDatabase(context.Background(), db, CheckDatabaseConfig{Pubkey: pubkey, FullVerify: true})
+	require.Error(t, err)
+	require.False(t, IsCorruptDBError(err))
+}
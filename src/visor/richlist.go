@@ -4,7 +4,10 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/skycoin/skycoin/src/params"
 	"github.com/skycoin/skycoin/src/util/droplet"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/historydb"
 )
 
 // RichlistBalance holds info an address balance holder
@@ -69,3 +72,73 @@ func (r Richlist) FilterAddresses(addrs map[string]struct{}) Richlist {
 	}
 	return s
 }
+
+// GetRichlist returns the n addresses with the highest confirmed balances, in descending balance order.
+// If n is 0, all addresses with a balance are returned. The distribution addresses are excluded
+// unless includeDistribution is true.
+func (vs *Visor) GetRichlist(n uint64, includeDistribution bool) (Richlist, error) {
+	lockedAddrs := params.GetLockedDistributionAddresses()
+	lockedMap := make(map[string]struct{}, len(lockedAddrs))
+	for _, a := range lockedAddrs {
+		lockedMap[a] = struct{}{}
+	}
+
+	excludedMap := make(map[string]struct{})
+	if !includeDistribution {
+		for _, a := range lockedAddrs {
+			excludedMap[a] = struct{}{}
+		}
+		for _, a := range params.GetUnlockedDistributionAddresses() {
+			excludedMap[a] = struct{}{}
+		}
+	}
+
+	// Addresses with the same balance as the nth address are collected too,
+	// so that NewRichlist decides which of them are included
+	allAccounts := make(map[string]uint64)
+	var lastCoins uint64
+	if err := vs.viewReplica("GetRichlist", func(tx *dbutil.Tx) error {
+		return vs.history.ForEachRichlistEntry(tx, func(e historydb.RichlistEntry) (bool, error) {
+			if n > 0 && uint64(len(allAccounts)) >= n && e.Coins != lastCoins {
+				return false, nil
+			}
+
+			addr := e.Address.String()
+			if _, ok := excludedMap[addr]; ok {
+				return true, nil
+			}
+
+			allAccounts[addr] = e.Coins
+			lastCoins = e.Coins
+			return true, nil
+		})
+	}); err != nil {
+		return nil, err
+	}
+
+	richlist, err := NewRichlist(allAccounts, lockedMap)
+	if err != nil {
+		return nil, err
+	}
+
+	if n > 0 && uint64(len(richlist)) > n {
+		richlist = richlist[:n]
+	}
+
+	return richlist, nil
+}
+
+// GetBalanceDistribution returns the number of addresses and the coins they hold in each range of
+// historydb.BalanceDistributionThresholds, in ascending order of balance
+func (vs *Visor) GetBalanceDistribution() ([]historydb.BalanceRange, error) {
+	var ranges []historydb.BalanceRange
+	if err := vs.viewReplica("GetBalanceDistribution", func(tx *dbutil.Tx) error {
+		var err error
+		ranges, err = vs.history.GetBalanceDistribution(tx)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return ranges, nil
+}
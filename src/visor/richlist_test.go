@@ -1,9 +1,19 @@
 package visor
 
 import (
+	"bytes"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/params"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/historydb"
 )
 
 func getLockedMap() map[string]struct{} {
@@ -96,3 +106,114 @@ func TestRichlist(t *testing.T) {
 		})
 	}
 }
+
+func TestVisorGetRichlist(t *testing.T) {
+	distAddr := cipher.MustDecodeBase58Address(params.GetLockedDistributionAddresses()[0])
+	addrA := testutil.MakeAddress()
+	addrB := testutil.MakeAddress()
+	if bytes.Compare(addrA.Bytes(), addrB.Bytes()) > 0 {
+		addrA, addrB = addrB, addrA
+	}
+	addrC := testutil.MakeAddress()
+
+	// Index order: descending balance, ties in address byte order
+	entries := []historydb.RichlistEntry{
+		{Address: distAddr, Coins: 1000e6},
+		{Address: addrA, Coins: 500e6},
+		{Address: addrB, Coins: 500e6},
+		{Address: addrC, Coins: 100e6},
+	}
+
+	dist := RichlistBalance{Address: distAddr.String(), Coins: "1000.000000", Locked: true, coins: 1000e6}
+	balA := RichlistBalance{Address: addrA.String(), Coins: "500.000000", coins: 500e6}
+	balB := RichlistBalance{Address: addrB.String(), Coins: "500.000000", coins: 500e6}
+	balC := RichlistBalance{Address: addrC.String(), Coins: "100.000000", coins: 100e6}
+
+	// Richlist ties are ordered by address string
+	if strings.Compare(balA.Address, balB.Address) > 0 {
+		balA, balB = balB, balA
+	}
+
+	cases := []struct {
+		name                string
+		n                   uint64
+		includeDistribution bool
+		visited             int
+		expected            Richlist
+	}{
+		{
+			name:                "all, with distribution",
+			includeDistribution: true,
+			visited:             4,
+			expected:            Richlist{dist, balA, balB, balC},
+		},
+		{
+			name:     "all, without distribution",
+			visited:  4,
+			expected: Richlist{balA, balB, balC},
+		},
+		{
+			name:                "top 1, with distribution",
+			n:                   1,
+			includeDistribution: true,
+			visited:             2,
+			expected:            Richlist{dist},
+		},
+		{
+			name:     "top 1, ties are collected before truncating",
+			n:        1,
+			visited:  4,
+			expected: Richlist{balA},
+		},
+		{
+			name:     "top 2",
+			n:        2,
+			visited:  4,
+			expected: Richlist{balA, balB},
+		},
+		{
+			name:     "more than the number of addresses",
+			n:        10,
+			visited:  4,
+			expected: Richlist{balA, balB, balC},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, shutdown := prepareDB(t)
+			defer shutdown()
+
+			matchTxn := mock.MatchedBy(func(tx *dbutil.Tx) bool {
+				return true
+			})
+
+			visited := 0
+			history := &MockHistoryer{}
+			history.On("ForEachRichlistEntry", matchTxn, mock.Anything).Return(func(tx *dbutil.Tx, f func(historydb.RichlistEntry) (bool, error)) error {
+				for _, e := range entries {
+					visited++
+					cont, err := f(e)
+					if err != nil {
+						return err
+					}
+					if !cont {
+						return nil
+					}
+				}
+				return nil
+			})
+
+			v := &Visor{
+				DB:      db,
+				history: history,
+			}
+
+			richlist, err := v.GetRichlist(tc.n, tc.includeDistribution)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, richlist)
+			require.Equal(t, tc.visited, visited)
+			history.AssertExpectations(t)
+		})
+	}
+}
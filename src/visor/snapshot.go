@@ -0,0 +1,315 @@
+package visor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/encoder"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/historydb"
+)
+
+/*
+Blockchain snapshot file format, all integers are little endian:
+
+	magic      [8]byte  "SKYSNAP\x00"
+	version    uint32   SnapshotVersion
+	count      uint64   number of blocks
+
+followed by count block records, ordered by block seq starting from the genesis block:
+
+	length     uint32   length of the encoded block
+	block      []byte   encoder.Serialize(coin.SignedBlock)
+*/
+
+const (
+	// SnapshotVersion is the version of the snapshot file format written by ExportSnapshot
+	SnapshotVersion = 1
+
+	// maxSnapshotBlockSize limits the size of a block record read from a snapshot,
+	// to avoid allocating huge buffers for a corrupted snapshot
+	maxSnapshotBlockSize = 32 * 1024 * 1024
+
+	// snapshotImportBatchSize is the number of blocks imported in a single database transaction
+	snapshotImportBatchSize = 1000
+)
+
+var (
+	snapshotMagic = [8]byte{'S', 'K', 'Y', 'S', 'N', 'A', 'P', 0}
+
+	// ErrSnapshotInvalid is returned if the snapshot file is not a blockchain snapshot
+	ErrSnapshotInvalid = errors.New("Not a blockchain snapshot file")
+	// ErrSnapshotDBNotEmpty is returned by ImportSnapshot if the database already has blocks
+	ErrSnapshotDBNotEmpty = errors.New("Can't import a snapshot into a database that already has blocks")
+)
+
+// ErrSnapshotVersion is returned if the snapshot file format version is not supported
+type ErrSnapshotVersion struct {
+	Version uint32
+}
+
+// NewErrSnapshotVersion creates an ErrSnapshotVersion
+func NewErrSnapshotVersion(version uint32) error {
+	return ErrSnapshotVersion{
+		Version: version,
+	}
+}
+
+func (e ErrSnapshotVersion) Error() string {
+	return fmt.Sprintf("Unsupported snapshot version %d, the latest supported version is %d", e.Version, SnapshotVersion)
+}
+
+type snapshotHeader struct {
+	Magic   [8]byte
+	Version uint32
+	Count   uint64
+}
+
+// ExportSnapshot writes all blocks and their signatures to w in the snapshot file format.
+// The blocks are read in a single database transaction, so the node can keep running.
+// The export stops when ctx is done and returns the context error.
+// Returns the number of blocks written.
+func ExportSnapshot(ctx context.Context, db *dbutil.DB, w io.Writer) (uint64, error) {
+	bc, err := NewBlockchain(db, BlockchainConfig{})
+	if err != nil {
+		return 0, err
+	}
+
+	bw := bufio.NewWriter(w)
+
+	var count uint64
+	if err := db.ViewContext(ctx, "ExportSnapshot", func(tx *dbutil.Tx) error {
+		headSeq, ok, err := bc.HeadSeq(tx)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("Can't export a blockchain without blocks")
+		}
+
+		if err := binary.Write(bw, binary.LittleEndian, snapshotHeader{
+			Magic:   snapshotMagic,
+			Version: SnapshotVersion,
+			Count:   headSeq + 1,
+		}); err != nil {
+			return err
+		}
+
+		for seq := uint64(0); seq <= headSeq; seq++ {
+			if err := tx.Context().Err(); err != nil {
+				return err
+			}
+
+			b, err := bc.GetSignedBlockBySeq(tx, seq)
+			if err != nil {
+				return err
+			}
+			if b == nil {
+				return NewErrBlockNotExist(seq)
+			}
+
+			data := encoder.Serialize(*b)
+			if err := binary.Write(bw, binary.LittleEndian, uint32(len(data))); err != nil {
+				return err
+			}
+			if _, err := bw.Write(data); err != nil {
+				return err
+			}
+
+			count++
+		}
+
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	if err := bw.Flush(); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// ImportSnapshotConfig configures ImportSnapshot
+type ImportSnapshotConfig struct {
+	// Public key of the blockchain, used to verify the block signatures
+	Pubkey cipher.PubKey
+	// Checkpoints are trusted block hashes that the snapshot blocks must match
+	Checkpoints Checkpoints
+	// SkipCheckpointedSignatures skips the signature verification of the blocks up to the latest checkpoint
+	// included in the snapshot. These blocks are authenticated by the checkpoint hash instead, once it is reached.
+	SkipCheckpointedSignatures bool
+}
+
+// ImportSnapshot bootstraps an empty database from a snapshot written by ExportSnapshot.
+// Every block is verified as if it was received from the network: the signature is checked against cfg.Pubkey,
+// the block must follow the previous block, match the checkpoint at its height and its transactions must be valid.
+// Blocks are committed in batches, if the import fails the database contains the blocks imported before the failure.
+// If cfg.SkipCheckpointedSignatures is true and the import fails before reaching the latest checkpoint,
+// the imported blocks were not authenticated and the database must be discarded.
+// Returns the number of blocks imported.
+func ImportSnapshot(ctx context.Context, db *dbutil.DB, r io.Reader, cfg ImportSnapshotConfig) (uint64, error) {
+	br := bufio.NewReader(r)
+
+	var hdr snapshotHeader
+	if err := binary.Read(br, binary.LittleEndian, &hdr); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return 0, ErrSnapshotInvalid
+		}
+		return 0, err
+	}
+
+	if !bytes.Equal(hdr.Magic[:], snapshotMagic[:]) {
+		return 0, ErrSnapshotInvalid
+	}
+
+	if hdr.Version != SnapshotVersion {
+		return 0, NewErrSnapshotVersion(hdr.Version)
+	}
+
+	if err := CreateBuckets(db); err != nil {
+		return 0, err
+	}
+
+	bc, err := NewBlockchain(db, BlockchainConfig{Pubkey: cfg.Pubkey})
+	if err != nil {
+		return 0, err
+	}
+
+	history := historydb.New()
+
+	// The blocks up to the latest checkpoint in the snapshot are authenticated by the checkpoint hash,
+	// since each block header includes the hash of the previous block
+	var trustedSeq uint64
+	var skipSignatures bool
+	if cfg.SkipCheckpointedSignatures && hdr.Count > 0 {
+		if cp, ok := cfg.Checkpoints.Latest(hdr.Count - 1); ok {
+			trustedSeq = cp.Seq
+			skipSignatures = true
+			logger.Infof("Skipping the signature verification of snapshot blocks up to checkpoint %d", cp.Seq)
+		}
+	}
+
+	if err := db.ViewContext(ctx, "ImportSnapshot", func(tx *dbutil.Tx) error {
+		length, err := bc.Len(tx)
+		if err != nil {
+			return err
+		}
+		if length != 0 {
+			return ErrSnapshotDBNotEmpty
+		}
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	var imported uint64
+	for imported < hdr.Count {
+		n := hdr.Count - imported
+		if n > snapshotImportBatchSize {
+			n = snapshotImportBatchSize
+		}
+
+		if err := db.UpdateContext(ctx, "ImportSnapshot", func(tx *dbutil.Tx) error {
+			for i := uint64(0); i < n; i++ {
+				if err := tx.Context().Err(); err != nil {
+					return err
+				}
+
+				b, err := readSnapshotBlock(br)
+				if err != nil {
+					return err
+				}
+
+				seq := imported + i
+				verifySignature := !skipSignatures || seq > trustedSeq
+				if err := importSnapshotBlock(tx, bc, history, cfg, seq, b, verifySignature); err != nil {
+					return err
+				}
+			}
+			return nil
+		}); err != nil {
+			return imported, err
+		}
+
+		imported += n
+		logger.Infof("Imported %d/%d blocks from snapshot", imported, hdr.Count)
+	}
+
+	// Every block was verified while importing
+	if err := saveVerifyCheckpoint(db, bc); err != nil {
+		return imported, err
+	}
+
+	return imported, nil
+}
+
+func readSnapshotBlock(r io.Reader) (*coin.SignedBlock, error) {
+	var length uint32
+	if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, fmt.Errorf("Read snapshot block failed: %v", err)
+	}
+
+	if length > maxSnapshotBlockSize {
+		return nil, fmt.Errorf("Snapshot block size %d exceeds the maximum of %d", length, maxSnapshotBlockSize)
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, fmt.Errorf("Read snapshot block failed: %v", err)
+	}
+
+	var b coin.SignedBlock
+	if err := encoder.DeserializeRaw(data, &b); err != nil {
+		return nil, fmt.Errorf("Decode snapshot block failed: %v", err)
+	}
+
+	return &b, nil
+}
+
+// importSnapshotBlock verifies a snapshot block and adds it to the blockchain and historydb
+func importSnapshotBlock(tx *dbutil.Tx, bc *Blockchain, history *historydb.HistoryDB, cfg ImportSnapshotConfig, seq uint64, b *coin.SignedBlock, verifySignature bool) error {
+	if b.Seq() != seq {
+		return fmt.Errorf("Snapshot block seq is %d, expected %d", b.Seq(), seq)
+	}
+
+	if err := cfg.Checkpoints.VerifyBlock(&b.Block); err != nil {
+		return err
+	}
+
+	if verifySignature {
+		if err := b.VerifySignature(cfg.Pubkey); err != nil {
+			return fmt.Errorf("Snapshot block %d: %v", seq, err)
+		}
+	}
+
+	// ExecuteBlock overwrites PrevHash with the hash of the head block,
+	// so check it first to reject blocks that don't belong to this chain
+	if seq > 0 {
+		head, err := bc.Head(tx)
+		if err != nil {
+			return err
+		}
+
+		if b.Head.PrevHash != head.HashHeader() {
+			return fmt.Errorf("Snapshot block %d does not follow block %d", seq, head.Seq())
+		}
+	}
+
+	if err := bc.ExecuteBlock(tx, b); err != nil {
+		return fmt.Errorf("Snapshot block %d: %v", seq, err)
+	}
+
+	return history.ParseBlock(tx, b.Block)
+}
@@ -0,0 +1,159 @@
+package visor
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+// exportTestSnapshot exports the blockchain of a testdata db file
+func exportTestSnapshot(t *testing.T, dbFile string) ([]byte, uint64, cipher.SHA256) {
+	db, err := OpenDB(dbFile, true)
+	require.NoError(t, err)
+	defer db.Close()
+
+	var buf bytes.Buffer
+	n, err := ExportSnapshot(context.Background(), db, &buf)
+	require.NoError(t, err)
+
+	bc, err := NewBlockchain(db, BlockchainConfig{})
+	require.NoError(t, err)
+
+	var head cipher.SHA256
+	err = db.View("", func(tx *dbutil.Tx) error {
+		b, err := bc.Head(tx)
+		require.NoError(t, err)
+		require.Equal(t, b.Seq()+1, n)
+		head = b.HashHeader()
+		return nil
+	})
+	require.NoError(t, err)
+
+	return buf.Bytes(), n, head
+}
+
+func TestExportImportSnapshot(t *testing.T) {
+	data, count, head := exportTestSnapshot(t, "./testdata/data.db.ok")
+	pubkey := mustParsePubkey(t)
+
+	db, shutdown := testutil.PrepareDB(t)
+	defer shutdown()
+
+	n, err := ImportSnapshot(context.Background(), db, bytes.NewReader(data), ImportSnapshotConfig{Pubkey: pubkey})
+	require.NoError(t, err)
+	require.Equal(t, count, n)
+
+	bc, err := NewBlockchain(db, BlockchainConfig{Pubkey: pubkey})
+	require.NoError(t, err)
+
+	err = db.View("", func(tx *dbutil.Tx) error {
+		b, err := bc.Head(tx)
+		require.NoError(t, err)
+		require.Equal(t, head, b.HashHeader())
+
+		// The imported blocks were verified, so the verification checkpoint is saved
+		cp, err := dbutil.GetVerifyCheckpoint(tx)
+		require.NoError(t, err)
+		require.NotNil(t, cp)
+		require.Equal(t, head, cp.BlockHash)
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = CheckDatabase(context.Background(), db, CheckDatabaseConfig{Pubkey: pubkey, FullVerify: true})
+	require.NoError(t, err)
+
+	// The exported snapshot of the imported database is identical
+	var buf bytes.Buffer
+	_, err = ExportSnapshot(context.Background(), db, &buf)
+	require.NoError(t, err)
+	require.Equal(t, data, buf.Bytes())
+
+	// A database that already has blocks can't be imported into
+	_, err = ImportSnapshot(context.Background(), db, bytes.NewReader(data), ImportSnapshotConfig{Pubkey: pubkey})
+	require.Equal(t, ErrSnapshotDBNotEmpty, err)
+}
+
+func TestImportSnapshotInvalid(t *testing.T) {
+	data, _, _ := exportTestSnapshot(t, "./testdata/data.db.ok")
+	pubkey := mustParsePubkey(t)
+	otherPubkey, _ := cipher.GenerateKeyPair()
+
+	// Offset of the first block record, after the header and the record length
+	firstBlock := binary.Size(snapshotHeader{}) + 4
+
+	withVersion := func(v uint32) []byte {
+		d := append([]byte{}, data...)
+		binary.LittleEndian.PutUint32(d[8:12], v)
+		return d
+	}
+
+	// The signature is at the end of the first block record
+	badSig := append([]byte{}, data...)
+	firstBlockLen := int(binary.LittleEndian.Uint32(data[firstBlock-4 : firstBlock]))
+	badSig[firstBlock+firstBlockLen-1] ^= 0xFF
+
+	tt := []struct {
+		name   string
+		data   []byte
+		pubkey cipher.PubKey
+		err    error
+	}{
+		{
+			name:   "empty",
+			data:   nil,
+			pubkey: pubkey,
+			err:    ErrSnapshotInvalid,
+		},
+		{
+			name:   "bad magic",
+			data:   append([]byte("NOTASNAP"), data[8:]...),
+			pubkey: pubkey,
+			err:    ErrSnapshotInvalid,
+		},
+		{
+			name:   "unsupported version",
+			data:   withVersion(SnapshotVersion + 1),
+			pubkey: pubkey,
+			err:    NewErrSnapshotVersion(SnapshotVersion + 1),
+		},
+		{
+			name:   "truncated",
+			data:   data[:len(data)-10],
+			pubkey: pubkey,
+			err:    errors.New("Read snapshot block failed: unexpected EOF"),
+		},
+		{
+			name:   "wrong pubkey",
+			data:   data,
+			pubkey: otherPubkey,
+			err:    errors.New("Snapshot block 0: Recovered pubkey does not match pubkey"),
+		},
+		{
+			name:   "bad signature",
+			data:   badSig,
+			pubkey: pubkey,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			db, shutdown := testutil.PrepareDB(t)
+			defer shutdown()
+
+			_, err := ImportSnapshot(context.Background(), db, bytes.NewReader(tc.data), ImportSnapshotConfig{Pubkey: tc.pubkey})
+			require.Error(t, err)
+			if tc.err != nil {
+				require.Equal(t, tc.err.Error(), err.Error())
+			}
+		})
+	}
+}
@@ -142,6 +142,8 @@ type UnconfirmedTransactionPool struct {
 	// our future balance and avoid double spending our own coins
 	// Maps from Transaction.Hash() to UxArray.
 	unspent *txnUnspents
+	// Time the txns were first added to the pool, see UnconfirmedTxnsExpiryBkt
+	expiry *unconfirmedTxnsExpiry
 }
 
 // NewUnconfirmedTransactionPool creates an UnconfirmedTransactionPool instance
@@ -162,6 +164,7 @@ func NewUnconfirmedTransactionPool(db *dbutil.DB) (*UnconfirmedTransactionPool,
 		db:      db,
 		txns:    &unconfirmedTxns{},
 		unspent: &txnUnspents{},
+		expiry:  &unconfirmedTxnsExpiry{},
 	}, nil
 }
 
@@ -249,6 +252,11 @@ func (utp *UnconfirmedTransactionPool) InjectTransaction(tx *dbutil.Tx, bc Block
 		return false, nil, err
 	}
 
+	if err := utp.expiry.put(tx, hash, utx.Received); err != nil {
+		logger.Errorf("InjectTransaction put new unconfirmed txn expiry failed: %v", err)
+		return false, nil, err
+	}
+
 	head, err := bc.Head(tx)
 	if err != nil {
 		logger.Errorf("InjectTransaction bc.Head() failed: %v", err)
@@ -284,6 +292,10 @@ func (utp *UnconfirmedTransactionPool) removeTransaction(tx *dbutil.Tx, txHash c
 		return err
 	}
 
+	if err := utp.expiry.delete(tx, txHash); err != nil {
+		return err
+	}
+
 	return utp.unspent.delete(tx, txHash)
 }
 
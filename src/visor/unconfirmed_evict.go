@@ -0,0 +1,232 @@
+package visor
+
+import (
+	"sort"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/encoder"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+var (
+	// UnconfirmedTxnsExpiryBkt holds the time each unconfirmed transaction was first added to the pool,
+	// from which its expiry is computed. Unlike UnconfirmedTransaction.Received, it is not reset when the
+	// transaction is received again, so transactions rebroadcast by peers still expire.
+	UnconfirmedTxnsExpiryBkt = []byte("unconfirmed_txns_expiry")
+
+	// UnconfirmedEvictedTxnsBkt holds the log of the transactions evicted from the unconfirmed pool, by eviction ID
+	UnconfirmedEvictedTxnsBkt = []byte("unconfirmed_evicted_txns")
+)
+
+// MaxEvictedUnconfirmedTxns is the maximum number of evictions stored, the oldest evictions are deleted first
+const MaxEvictedUnconfirmedTxns = 10000
+
+// EvictReason is the reason a transaction was evicted from the unconfirmed pool
+type EvictReason string
+
+const (
+	// EvictReasonExpired is recorded for the transactions that stayed in the pool longer than the max age
+	EvictReasonExpired EvictReason = "expired"
+	// EvictReasonPoolFull is recorded for the transactions evicted to bring the pool down to the max size.
+	// The invalid transactions are evicted first, then the oldest transactions.
+	EvictReasonPoolFull EvictReason = "pool_full"
+)
+
+// EvictedUnconfirmedTxn records a transaction evicted from the unconfirmed pool
+type EvictedUnconfirmedTxn struct {
+	// ID of the eviction. IDs increase in the order the transactions were evicted
+	ID    uint64
+	TxnID cipher.SHA256
+	// Time the txn was first added to the pool
+	Added int64
+	// Time the txn was evicted
+	Evicted int64
+	Reason  EvictReason
+	// If the txn was valid when it was evicted
+	IsValid int8
+}
+
+// unconfirmedTxnsExpiry holds the time the unconfirmed transactions were first added to the pool.
+// The bucket is missing from the databases that were not opened by this version yet, in which case no txn
+// has expiry metadata.
+type unconfirmedTxnsExpiry struct{}
+
+func (e *unconfirmedTxnsExpiry) get(tx *dbutil.Tx, hash cipher.SHA256) (int64, bool, error) {
+	if !dbutil.Exists(tx, UnconfirmedTxnsExpiryBkt) {
+		return 0, false, nil
+	}
+
+	v, err := dbutil.GetBucketValue(tx, UnconfirmedTxnsExpiryBkt, []byte(hash.Hex()))
+	if err != nil || v == nil {
+		return 0, false, err
+	}
+
+	return int64(dbutil.Btoi(v)), true, nil
+}
+
+func (e *unconfirmedTxnsExpiry) put(tx *dbutil.Tx, hash cipher.SHA256, added int64) error {
+	return dbutil.PutBucketValue(tx, UnconfirmedTxnsExpiryBkt, []byte(hash.Hex()), dbutil.Itob(uint64(added)))
+}
+
+func (e *unconfirmedTxnsExpiry) delete(tx *dbutil.Tx, hash cipher.SHA256) error {
+	if !dbutil.Exists(tx, UnconfirmedTxnsExpiryBkt) {
+		return nil
+	}
+
+	return dbutil.Delete(tx, UnconfirmedTxnsExpiryBkt, []byte(hash.Hex()))
+}
+
+// Evict removes the transactions added to the pool more than maxAge before now, then the transactions in excess
+// of maxSize transactions, invalid transactions first, then oldest first. A maxAge or maxSize of 0 disables
+// the limit. The evictions are recorded in UnconfirmedEvictedTxnsBkt and returned.
+func (utp *UnconfirmedTransactionPool) Evict(tx *dbutil.Tx, maxAge time.Duration, maxSize int, now time.Time) ([]EvictedUnconfirmedTxn, error) {
+	var kept []EvictedUnconfirmedTxn
+	if err := utp.txns.forEach(tx, func(hash cipher.SHA256, utxn UnconfirmedTransaction) error {
+		added, ok, err := utp.expiry.get(tx, hash)
+		if err != nil {
+			return err
+		}
+		// The transactions added before the expiry was recorded expire from the time they were last received
+		if !ok {
+			added = utxn.Received
+		}
+
+		kept = append(kept, EvictedUnconfirmedTxn{
+			TxnID:   hash,
+			Added:   added,
+			IsValid: utxn.IsValid,
+		})
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	var evicted []EvictedUnconfirmedTxn
+
+	if maxAge > 0 {
+		cutoff := now.Add(-maxAge).UnixNano()
+		n := 0
+		for _, t := range kept {
+			if t.Added < cutoff {
+				t.Reason = EvictReasonExpired
+				evicted = append(evicted, t)
+			} else {
+				kept[n] = t
+				n++
+			}
+		}
+		kept = kept[:n]
+	}
+
+	if maxSize > 0 && len(kept) > maxSize {
+		sort.Slice(kept, func(i, j int) bool {
+			if kept[i].IsValid != kept[j].IsValid {
+				return kept[i].IsValid < kept[j].IsValid
+			}
+			if kept[i].Added != kept[j].Added {
+				return kept[i].Added < kept[j].Added
+			}
+			return kept[i].TxnID.Hex() < kept[j].TxnID.Hex()
+		})
+
+		for _, t := range kept[:len(kept)-maxSize] {
+			t.Reason = EvictReasonPoolFull
+			evicted = append(evicted, t)
+		}
+	}
+
+	for i := range evicted {
+		if err := utp.removeTransaction(tx, evicted[i].TxnID); err != nil {
+			return nil, err
+		}
+
+		evicted[i].Evicted = now.UnixNano()
+		if err := putEvictedUnconfirmedTxn(tx, &evicted[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return evicted, nil
+}
+
+// putEvictedUnconfirmedTxn stores an eviction with the next eviction ID and deletes the evictions older than
+// the MaxEvictedUnconfirmedTxns most recent evictions
+func putEvictedUnconfirmedTxn(tx *dbutil.Tx, e *EvictedUnconfirmedTxn) error {
+	id, err := dbutil.NextSequence(tx, UnconfirmedEvictedTxnsBkt)
+	if err != nil {
+		return err
+	}
+
+	e.ID = id
+	if err := dbutil.PutBucketValue(tx, UnconfirmedEvictedTxnsBkt, dbutil.Itob(id), encoder.Serialize(*e)); err != nil {
+		return err
+	}
+
+	if id <= MaxEvictedUnconfirmedTxns {
+		return nil
+	}
+
+	// IDs are sequential, so at most one eviction is older than the retained evictions
+	return dbutil.Delete(tx, UnconfirmedEvictedTxnsBkt, dbutil.Itob(id-MaxEvictedUnconfirmedTxns))
+}
+
+// GetEvicted returns at most limit evictions with an ID greater than after, in ID order
+func (utp *UnconfirmedTransactionPool) GetEvicted(tx *dbutil.Tx, after, limit uint64) ([]EvictedUnconfirmedTxn, error) {
+	var evicted []EvictedUnconfirmedTxn
+	if limit == 0 {
+		return evicted, nil
+	}
+
+	if err := dbutil.ForEachPrefix(tx, UnconfirmedEvictedTxnsBkt, nil, dbutil.Itob(after+1), func(_, v []byte) (bool, error) {
+		var e EvictedUnconfirmedTxn
+		if err := encoder.DeserializeRaw(v, &e); err != nil {
+			return false, err
+		}
+
+		evicted = append(evicted, e)
+		return uint64(len(evicted)) < limit, nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return evicted, nil
+}
+
+// EvictUnconfirmed removes the transactions that exceed Config.UnconfirmedMaxAge or Config.UnconfirmedMaxPoolSize
+// from the pool. Returns the evictions.
+func (vs *Visor) EvictUnconfirmed() ([]EvictedUnconfirmedTxn, error) {
+	if vs.Config.UnconfirmedMaxAge == 0 && vs.Config.UnconfirmedMaxPoolSize == 0 {
+		return nil, nil
+	}
+
+	var evicted []EvictedUnconfirmedTxn
+	if err := vs.DB.Update("EvictUnconfirmed", func(tx *dbutil.Tx) error {
+		var err error
+		evicted, err = vs.Unconfirmed.Evict(tx, vs.Config.UnconfirmedMaxAge, vs.Config.UnconfirmedMaxPoolSize, time.Now().UTC())
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	for _, e := range evicted {
+		logger.Infof("Evicted unconfirmed txn %s: %s", e.TxnID.Hex(), e.Reason)
+	}
+
+	return evicted, nil
+}
+
+// GetEvictedUnconfirmedTxns returns at most limit evictions from the unconfirmed pool with an ID greater than after,
+// in ID order. The most recent MaxEvictedUnconfirmedTxns evictions are stored.
+func (vs *Visor) GetEvictedUnconfirmedTxns(after, limit uint64) ([]EvictedUnconfirmedTxn, error) {
+	var evicted []EvictedUnconfirmedTxn
+	if err := vs.DB.View("GetEvictedUnconfirmedTxns", func(tx *dbutil.Tx) error {
+		var err error
+		evicted, err = vs.Unconfirmed.GetEvicted(tx, after, limit)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return evicted, nil
+}
@@ -0,0 +1,214 @@
+package visor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+func TestUnconfirmedTransactionPoolEvict(t *testing.T) {
+	db, shutdown := prepareDB(t)
+	defer shutdown()
+
+	err := CreateBuckets(db)
+	require.NoError(t, err)
+
+	utp, err := NewUnconfirmedTransactionPool(db)
+	require.NoError(t, err)
+
+	now := time.Now().UTC()
+
+	// putTxn adds a txn to the pool, first added at added. A zero added time adds a txn without expiry metadata,
+	// like the txns added before the expiry was recorded
+	putTxn := func(received, added time.Time, isValid int8) UnconfirmedTransaction {
+		var txn coin.Transaction
+		txn.PushInput(testutil.RandSHA256(t))
+		txn.PushOutput(testutil.MakeAddress(), 1e6, 1)
+
+		utxn := UnconfirmedTransaction{
+			Transaction: txn,
+			Received:    received.UnixNano(),
+			IsValid:     isValid,
+		}
+
+		err := db.Update("", func(tx *dbutil.Tx) error {
+			if err := utp.txns.put(tx, &utxn); err != nil {
+				return err
+			}
+			if err := utp.unspent.put(tx, utxn.Hash(), nil); err != nil {
+				return err
+			}
+			if added.IsZero() {
+				return nil
+			}
+			return utp.expiry.put(tx, utxn.Hash(), added.UnixNano())
+		})
+		require.NoError(t, err)
+		return utxn
+	}
+
+	day := 24 * time.Hour
+	expired := putTxn(now, now.Add(-20*day), 1)
+	legacy := putTxn(now.Add(-30*day), time.Time{}, 1)
+	invalid := putTxn(now, now.Add(-1*day), 0)
+	older := putTxn(now, now.Add(-2*day), 1)
+	newer := putTxn(now, now.Add(-3*time.Hour), 1)
+
+	evict := func(maxAge time.Duration, maxSize int) []EvictedUnconfirmedTxn {
+		var evicted []EvictedUnconfirmedTxn
+		err := db.Update("", func(tx *dbutil.Tx) error {
+			var err error
+			evicted, err = utp.Evict(tx, maxAge, maxSize, now)
+			return err
+		})
+		require.NoError(t, err)
+		return evicted
+	}
+
+	requirePool := func(txns ...UnconfirmedTransaction) {
+		err := db.View("", func(tx *dbutil.Tx) error {
+			n, err := utp.Len(tx)
+			require.NoError(t, err)
+			require.Equal(t, uint64(len(txns)), n)
+
+			for _, txn := range txns {
+				utxn, err := utp.Get(tx, txn.Hash())
+				require.NoError(t, err)
+				require.NotNil(t, utxn)
+			}
+			return nil
+		})
+		require.NoError(t, err)
+	}
+
+	// No limits
+	require.Empty(t, evict(0, 0))
+	requirePool(expired, legacy, invalid, older, newer)
+
+	// The expired txns are evicted, then the invalid txns in excess of the max size
+	evicted := evict(14*day, 2)
+	require.Len(t, evicted, 3)
+
+	expiredEvictions := map[string]EvictedUnconfirmedTxn{
+		evicted[0].TxnID.Hex(): evicted[0],
+		evicted[1].TxnID.Hex(): evicted[1],
+	}
+	require.Equal(t, EvictedUnconfirmedTxn{
+		ID:      expiredEvictions[expired.Hash().Hex()].ID,
+		TxnID:   expired.Hash(),
+		Added:   now.Add(-20 * day).UnixNano(),
+		Evicted: now.UnixNano(),
+		Reason:  EvictReasonExpired,
+		IsValid: 1,
+	}, expiredEvictions[expired.Hash().Hex()])
+	require.Equal(t, EvictedUnconfirmedTxn{
+		ID:      expiredEvictions[legacy.Hash().Hex()].ID,
+		TxnID:   legacy.Hash(),
+		Added:   legacy.Received,
+		Evicted: now.UnixNano(),
+		Reason:  EvictReasonExpired,
+		IsValid: 1,
+	}, expiredEvictions[legacy.Hash().Hex()])
+
+	require.Equal(t, EvictedUnconfirmedTxn{
+		ID:      3,
+		TxnID:   invalid.Hash(),
+		Added:   now.Add(-1 * day).UnixNano(),
+		Evicted: now.UnixNano(),
+		Reason:  EvictReasonPoolFull,
+		IsValid: 0,
+	}, evicted[2])
+
+	requirePool(older, newer)
+
+	// The oldest txns are evicted when all the txns are valid
+	evicted = evict(14*day, 1)
+	require.Len(t, evicted, 1)
+	require.Equal(t, older.Hash(), evicted[0].TxnID)
+	require.Equal(t, EvictReasonPoolFull, evicted[0].Reason)
+	require.Equal(t, uint64(4), evicted[0].ID)
+
+	requirePool(newer)
+
+	// The expiry metadata and the unspents of the evicted txns are removed
+	err = db.View("", func(tx *dbutil.Tx) error {
+		_, ok, err := utp.expiry.get(tx, older.Hash())
+		require.NoError(t, err)
+		require.False(t, ok)
+
+		_, ok, err = utp.expiry.get(tx, newer.Hash())
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		n, err := dbutil.Len(tx, UnconfirmedUnspentsBkt)
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), n)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// The evictions are logged in order
+	getEvicted := func(after, limit uint64) []uint64 {
+		var ids []uint64
+		err := db.View("", func(tx *dbutil.Tx) error {
+			evicted, err := utp.GetEvicted(tx, after, limit)
+			for _, e := range evicted {
+				ids = append(ids, e.ID)
+			}
+			return err
+		})
+		require.NoError(t, err)
+		return ids
+	}
+
+	require.Equal(t, []uint64{1, 2, 3, 4}, getEvicted(0, 100))
+	require.Equal(t, []uint64{3, 4}, getEvicted(2, 100))
+	require.Equal(t, []uint64{2}, getEvicted(1, 1))
+	require.Empty(t, getEvicted(4, 100))
+	require.Empty(t, getEvicted(0, 0))
+}
+
+func TestVisorEvictUnconfirmed(t *testing.T) {
+	db, shutdown := prepareDB(t)
+	defer shutdown()
+
+	err := CreateBuckets(db)
+	require.NoError(t, err)
+
+	utp, err := NewUnconfirmedTransactionPool(db)
+	require.NoError(t, err)
+
+	v := &Visor{
+		DB:          db,
+		Unconfirmed: utp,
+	}
+
+	var txn coin.Transaction
+	txn.PushInput(testutil.RandSHA256(t))
+	utxn := NewUnconfirmedTransaction(txn)
+	utxn.Received = time.Now().Add(-time.Hour).UnixNano()
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		return utp.txns.put(tx, &utxn)
+	})
+	require.NoError(t, err)
+
+	// Nothing is evicted when the limits are disabled
+	evicted, err := v.EvictUnconfirmed()
+	require.NoError(t, err)
+	require.Empty(t, evicted)
+
+	v.Config.UnconfirmedMaxAge = time.Minute
+	evicted, err = v.EvictUnconfirmed()
+	require.NoError(t, err)
+	require.Len(t, evicted, 1)
+	require.Equal(t, txn.Hash(), evicted[0].TxnID)
+
+	logged, err := v.GetEvictedUnconfirmedTxns(0, 10)
+	require.NoError(t, err)
+	require.Equal(t, evicted, logged)
+}
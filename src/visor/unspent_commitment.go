@@ -0,0 +1,76 @@
+package visor
+
+import (
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+// CommittedBlockHeader is a block header with the commitment to the unspent output set after the block
+type CommittedBlockHeader struct {
+	Header coin.BlockHeader
+	// Hash of the unspent output set after the block was executed, nil if the database predates the commitments.
+	// It equals the UxHash of the header of the next block.
+	UnspentCommitment *cipher.SHA256
+}
+
+// GetBlockHeaderByHash returns the header of the block of hash and its unspent output set commitment,
+// nil if the block does not exist. The header of a pruned block is returned too.
+func (vs *Visor) GetBlockHeaderByHash(hash cipher.SHA256) (*CommittedBlockHeader, error) {
+	var h *CommittedBlockHeader
+
+	if err := vs.viewReplica("GetBlockHeaderByHash", func(tx *dbutil.Tx) error {
+		b, err := vs.Blockchain.GetSignedBlockByHash(tx, hash)
+		if err != nil {
+			return err
+		}
+
+		h, err = vs.getCommittedBlockHeader(tx, b)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+// GetBlockHeaderBySeq returns the header of the block of seq and its unspent output set commitment,
+// nil if the block does not exist. The header of a pruned block is returned too.
+func (vs *Visor) GetBlockHeaderBySeq(seq uint64) (*CommittedBlockHeader, error) {
+	var h *CommittedBlockHeader
+
+	if err := vs.viewReplica("GetBlockHeaderBySeq", func(tx *dbutil.Tx) error {
+		b, err := vs.Blockchain.GetSignedBlockBySeq(tx, seq)
+		if err != nil {
+			return err
+		}
+
+		h, err = vs.getCommittedBlockHeader(tx, b)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+func (vs *Visor) getCommittedBlockHeader(tx *dbutil.Tx, b *coin.SignedBlock) (*CommittedBlockHeader, error) {
+	if b == nil {
+		return nil, nil
+	}
+
+	h := &CommittedBlockHeader{
+		Header: b.Head,
+	}
+
+	commitment, ok, err := vs.Blockchain.GetUnspentCommitment(tx, b.Seq())
+	if err != nil {
+		return nil, err
+	}
+
+	if ok {
+		h.UnspentCommitment = &commitment
+	}
+
+	return h, nil
+}
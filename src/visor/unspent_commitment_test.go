@@ -0,0 +1,85 @@
+package visor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+func TestGetBlockHeaderUnspentCommitment(t *testing.T) {
+	db, shutdown := copyTestDB(t, "./testdata/data.db.ok")
+	defer shutdown()
+
+	bc, err := NewBlockchain(db, BlockchainConfig{Pubkey: mustParsePubkey(t)})
+	require.NoError(t, err)
+
+	v := &Visor{
+		DB:         db,
+		Blockchain: bc,
+	}
+
+	var headSeq uint64
+	err = db.View("", func(tx *dbutil.Tx) error {
+		var err error
+		headSeq, _, err = bc.HeadSeq(tx)
+		return err
+	})
+	require.NoError(t, err)
+
+	// The test db predates the commitments
+	h, err := v.GetBlockHeaderBySeq(headSeq)
+	require.NoError(t, err)
+	require.NotNil(t, h)
+	require.Nil(t, h.UnspentCommitment)
+
+	require.NoError(t, CreateBuckets(db))
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		return bc.MaybeBuildUnspentCommitments(tx)
+	})
+	require.NoError(t, err)
+
+	// Building the commitments again doesn't change them
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		return bc.MaybeBuildUnspentCommitments(tx)
+	})
+	require.NoError(t, err)
+
+	// The commitment of a block is the ux hash of the next block
+	var prev *CommittedBlockHeader
+	for seq := uint64(0); seq <= headSeq; seq++ {
+		h, err := v.GetBlockHeaderBySeq(seq)
+		require.NoError(t, err)
+		require.NotNil(t, h)
+		require.Equal(t, seq, h.Header.BkSeq)
+		require.NotNil(t, h.UnspentCommitment)
+
+		if prev != nil {
+			require.Equal(t, *prev.UnspentCommitment, h.Header.UxHash)
+		}
+		prev = h
+	}
+
+	// The commitment of the head block is the hash of the unspent pool
+	err = db.View("", func(tx *dbutil.Tx) error {
+		uxHash, err := bc.Unspent().GetUxHash(tx)
+		require.NoError(t, err)
+		require.Equal(t, uxHash, *prev.UnspentCommitment)
+		return nil
+	})
+	require.NoError(t, err)
+
+	h, err = v.GetBlockHeaderByHash(prev.Header.Hash())
+	require.NoError(t, err)
+	require.Equal(t, prev, h)
+
+	h, err = v.GetBlockHeaderBySeq(headSeq + 1)
+	require.NoError(t, err)
+	require.Nil(t, h)
+
+	h, err = v.GetBlockHeaderByHash(testutil.RandSHA256(t))
+	require.NoError(t, err)
+	require.Nil(t, h)
+}
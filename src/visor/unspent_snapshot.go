@@ -0,0 +1,215 @@
+package visor
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/blockdb"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/historydb"
+)
+
+// ExportUnspentSnapshot writes the unspent output set after the block of seq, or after the head block if seq is nil,
+// to w in the unspent snapshot file format, see blockdb.ExportUnspentSnapshot.
+// The unspent output set after an earlier block than the head block is rebuilt from the historydb,
+// and must match the unspent commitment stored for the block.
+// The snapshot is read in a single database transaction, so the node can keep running.
+func ExportUnspentSnapshot(ctx context.Context, db *dbutil.DB, w io.Writer, seq *uint64) (*blockdb.UnspentSnapshotHeader, error) {
+	bc, err := NewBlockchain(db, BlockchainConfig{})
+	if err != nil {
+		return nil, err
+	}
+
+	history := historydb.New()
+
+	var hdr *blockdb.UnspentSnapshotHeader
+	if err := db.ViewContext(ctx, "ExportUnspentSnapshot", func(tx *dbutil.Tx) error {
+		headSeq, ok, err := bc.HeadSeq(tx)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("Can't export a blockchain without blocks")
+		}
+
+		snapshotSeq := headSeq
+		if seq != nil {
+			if *seq > headSeq {
+				return NewErrBlockNotExist(*seq)
+			}
+			snapshotSeq = *seq
+		}
+
+		var uxs coin.UxArray
+		if snapshotSeq == headSeq {
+			uxs, err = bc.Unspent().GetAll(tx)
+		} else {
+			uxs, err = historyUnspentsAt(tx, history, headSeq, snapshotSeq)
+		}
+		if err != nil {
+			return err
+		}
+
+		hdr, err = bc.ExportUnspentSnapshot(tx, w, snapshotSeq, uxs)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return hdr, nil
+}
+
+// historyUnspentsAt returns the unspent outputs after the block of seq from the outputs of the historydb,
+// which records the block that spent each output
+func historyUnspentsAt(tx *dbutil.Tx, history *historydb.HistoryDB, headSeq, seq uint64) (coin.UxArray, error) {
+	parsedSeq, ok, err := history.ParsedBlockSeq(tx)
+	if err != nil {
+		return nil, err
+	}
+	if !ok || parsedSeq != headSeq {
+		return nil, errors.New("The history db is not parsed up to the head block")
+	}
+
+	if _, ok, err := history.BootstrapSeq(tx); err != nil {
+		return nil, err
+	} else if ok {
+		return nil, errors.New("The history db of a database bootstrapped from an unspent output set snapshot is incomplete")
+	}
+
+	var uxs coin.UxArray
+	if err := history.ForEachUxOut(tx, func(o *historydb.UxOut) error {
+		if err := tx.Context().Err(); err != nil {
+			return err
+		}
+
+		// The genesis block doesn't spend any outputs, so a spent block seq of 0 means unspent
+		if o.Out.Head.BkSeq <= seq && (o.SpentBlockSeq == 0 || o.SpentBlockSeq > seq) {
+			uxs = append(uxs, o.Out)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return uxs, nil
+}
+
+// ImportUnspentSnapshotConfig configures ImportUnspentSnapshot
+type ImportUnspentSnapshotConfig struct {
+	// Public key of the blockchain, used to verify the block signatures
+	Pubkey cipher.PubKey
+	// Checkpoints are trusted block hashes that the snapshot blocks must match
+	Checkpoints Checkpoints
+	// SkipCheckpointedSignatures skips the signature verification of the blocks up to the latest checkpoint
+	// included in the snapshot. These blocks are authenticated by the checkpoint hash instead.
+	SkipCheckpointedSignatures bool
+	// Commitment is a trusted hash of the unspent output set of the snapshot, which the snapshot must match
+	Commitment *cipher.SHA256
+}
+
+// ImportUnspentSnapshot bootstraps an empty database from an unspent output set snapshot written by ExportUnspentSnapshot,
+// so that the node only syncs the blocks after the snapshot block instead of executing the whole blockchain.
+// The headers of the blocks up to the snapshot block are verified like the blocks of ImportSnapshot, their bodies are
+// not stored, as if they were pruned. The unspent outputs must match the commitment of the snapshot, and cfg.Commitment if set.
+// Without cfg.Commitment, the unspent outputs are only authenticated by the UxHash of the header of the block after the
+// snapshot block, which is verified when the block is executed: if they were forged the node rejects every later block,
+// and the database must be deleted.
+// The history db only starts after the snapshot block, see historydb.ParseUnspentSnapshot.
+// The snapshot is imported in a single database transaction, if the import fails the database is left empty.
+func ImportUnspentSnapshot(ctx context.Context, db *dbutil.DB, r io.Reader, cfg ImportUnspentSnapshotConfig) (*blockdb.UnspentSnapshotHeader, error) {
+	br := bufio.NewReader(r)
+
+	hdr, err := blockdb.ReadUnspentSnapshotHeader(br)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Commitment != nil && *cfg.Commitment != hdr.Commitment {
+		return nil, blockdb.ErrUnspentSnapshotCommitment
+	}
+
+	if err := CreateBuckets(db); err != nil {
+		return nil, err
+	}
+
+	bc, err := NewBlockchain(db, BlockchainConfig{Pubkey: cfg.Pubkey})
+	if err != nil {
+		return nil, err
+	}
+
+	history := historydb.New()
+
+	// The blocks up to the latest checkpoint in the snapshot are authenticated by the checkpoint hash,
+	// since each block header includes the hash of the previous block
+	var trustedSeq uint64
+	var skipSignatures bool
+	if cfg.SkipCheckpointedSignatures {
+		if cp, ok := cfg.Checkpoints.Latest(hdr.Seq); ok {
+			trustedSeq = cp.Seq
+			skipSignatures = true
+			logger.Infof("Skipping the signature verification of snapshot blocks up to checkpoint %d", cp.Seq)
+		}
+	}
+
+	if cfg.Commitment == nil {
+		logger.Warningf("No trusted unspent commitment, the unspent outputs of the snapshot are authenticated by block %d once it is synced", hdr.Seq+1)
+	}
+
+	verify := func(b *coin.SignedBlock) error {
+		if err := cfg.Checkpoints.VerifyBlock(&b.Block); err != nil {
+			return err
+		}
+
+		if skipSignatures && b.Seq() <= trustedSeq {
+			return nil
+		}
+
+		return b.VerifySignature(cfg.Pubkey)
+	}
+
+	if err := db.UpdateContext(ctx, "ImportUnspentSnapshot", func(tx *dbutil.Tx) error {
+		length, err := bc.Len(tx)
+		if err != nil {
+			return err
+		}
+		if length != 0 {
+			return ErrSnapshotDBNotEmpty
+		}
+
+		if err := bc.ImportUnspentSnapshot(tx, hdr, br, verify); err != nil {
+			return err
+		}
+
+		genesis, err := bc.GetGenesisBlock(tx)
+		if err != nil {
+			return err
+		}
+
+		head, err := bc.Head(tx)
+		if err != nil {
+			return err
+		}
+
+		uxs, err := bc.Unspent().GetAll(tx)
+		if err != nil {
+			return err
+		}
+
+		return history.ParseUnspentSnapshot(tx, genesis.Block, head.Seq(), head.Time(), uxs)
+	}); err != nil {
+		return nil, err
+	}
+
+	logger.Infof("Imported %d unspent outputs after block %d from snapshot", hdr.UxCount, hdr.Seq)
+
+	// Every block header was verified while importing
+	if err := saveVerifyCheckpoint(db, bc); err != nil {
+		return nil, err
+	}
+
+	return hdr, nil
+}
@@ -0,0 +1,312 @@
+package visor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor/blockdb"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/historydb"
+)
+
+// prepareUnspentSnapshotDB creates a database with the blockchain of a testdata db file,
+// executed again so that the unspent commitments of every block are stored
+func prepareUnspentSnapshotDB(t *testing.T, dbFile string) (*dbutil.DB, func()) {
+	data, _, _ := exportTestSnapshot(t, dbFile)
+
+	db, shutdown := testutil.PrepareDB(t)
+	_, err := ImportSnapshot(context.Background(), db, bytes.NewReader(data), ImportSnapshotConfig{Pubkey: mustParsePubkey(t)})
+	require.NoError(t, err)
+
+	return db, shutdown
+}
+
+func exportTestUnspentSnapshot(t *testing.T, db *dbutil.DB, seq *uint64) ([]byte, *blockdb.UnspentSnapshotHeader) {
+	var buf bytes.Buffer
+	hdr, err := ExportUnspentSnapshot(context.Background(), db, &buf, seq)
+	require.NoError(t, err)
+	return buf.Bytes(), hdr
+}
+
+func TestExportImportUnspentSnapshot(t *testing.T) {
+	srcDB, shutdown := prepareUnspentSnapshotDB(t, "./testdata/data.db.ok")
+	defer shutdown()
+
+	pubkey := mustParsePubkey(t)
+
+	srcBc, err := NewBlockchain(srcDB, BlockchainConfig{Pubkey: pubkey})
+	require.NoError(t, err)
+
+	var headSeq uint64
+	var uxHash cipher.SHA256
+	var uxLen uint64
+	err = srcDB.View("", func(tx *dbutil.Tx) error {
+		var err error
+		headSeq, _, err = srcBc.HeadSeq(tx)
+		require.NoError(t, err)
+		uxHash, err = srcBc.Unspent().GetUxHash(tx)
+		require.NoError(t, err)
+		uxLen, err = srcBc.Unspent().Len(tx)
+		require.NoError(t, err)
+		return nil
+	})
+	require.NoError(t, err)
+	require.True(t, headSeq > 2)
+
+	// Export the unspent output set of the head block
+	data, hdr := exportTestUnspentSnapshot(t, srcDB, nil)
+	require.Equal(t, headSeq, hdr.Seq)
+	require.Equal(t, uxHash, hdr.Commitment)
+	require.Equal(t, uxLen, hdr.UxCount)
+
+	db, shutdown2 := testutil.PrepareDB(t)
+	defer shutdown2()
+
+	importHdr, err := ImportUnspentSnapshot(context.Background(), db, bytes.NewReader(data), ImportUnspentSnapshotConfig{
+		Pubkey:     pubkey,
+		Commitment: &uxHash,
+	})
+	require.NoError(t, err)
+	require.Equal(t, hdr, importHdr)
+
+	bc, err := NewBlockchain(db, BlockchainConfig{Pubkey: pubkey})
+	require.NoError(t, err)
+
+	err = db.View("", func(tx *dbutil.Tx) error {
+		b, err := bc.Head(tx)
+		require.NoError(t, err)
+		require.Equal(t, hdr.BlockHash, b.HashHeader())
+
+		h, err := bc.Unspent().GetUxHash(tx)
+		require.NoError(t, err)
+		require.Equal(t, uxHash, h)
+
+		n, err := bc.Unspent().Len(tx)
+		require.NoError(t, err)
+		require.Equal(t, uxLen, n)
+
+		// The blocks below the snapshot block are stored like pruned blocks
+		prunedSeq, err := bc.PrunedSeq(tx)
+		require.NoError(t, err)
+		require.Equal(t, headSeq, prunedSeq)
+
+		commitment, ok, err := bc.GetUnspentCommitment(tx, headSeq)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, uxHash, commitment)
+
+		// The history starts after the snapshot block
+		history := historydb.New()
+		parsedSeq, ok, err := history.ParsedBlockSeq(tx)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, headSeq, parsedSeq)
+
+		bootstrapSeq, ok, err := history.BootstrapSeq(tx)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, headSeq, bootstrapSeq)
+
+		needsReset, err := history.NeedsReset(tx)
+		require.NoError(t, err)
+		require.False(t, needsReset)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// The node starts on the imported database without rebuilding its indexes or history
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		require.NoError(t, bc.Unspent().MaybeBuildIndexes(tx, headSeq))
+		require.NoError(t, bc.MaybeBuildUnspentCommitments(tx))
+		return initHistory(tx, bc, historydb.New())
+	})
+	require.NoError(t, err)
+
+	err = CheckDatabase(context.Background(), db, CheckDatabaseConfig{Pubkey: pubkey, FullVerify: true})
+	require.NoError(t, err)
+
+	// The snapshot exported from the imported database is identical
+	data2, _ := exportTestUnspentSnapshot(t, db, nil)
+	require.Equal(t, data, data2)
+
+	// The history of the imported database can't rebuild an earlier unspent output set
+	earlier := headSeq - 1
+	_, err = ExportUnspentSnapshot(context.Background(), db, &bytes.Buffer{}, &earlier)
+	require.Equal(t, errors.New("The history db of a database bootstrapped from an unspent output set snapshot is incomplete"), err)
+
+	// A database that already has blocks can't be imported into
+	_, err = ImportUnspentSnapshot(context.Background(), db, bytes.NewReader(data), ImportUnspentSnapshotConfig{Pubkey: pubkey})
+	require.Equal(t, ErrSnapshotDBNotEmpty, err)
+}
+
+func TestImportUnspentSnapshotSyncLaterBlocks(t *testing.T) {
+	srcDB, shutdown := prepareUnspentSnapshotDB(t, "./testdata/data.db.ok")
+	defer shutdown()
+
+	pubkey := mustParsePubkey(t)
+
+	srcBc, err := NewBlockchain(srcDB, BlockchainConfig{Pubkey: pubkey})
+	require.NoError(t, err)
+
+	var headSeq uint64
+	var uxHash cipher.SHA256
+	err = srcDB.View("", func(tx *dbutil.Tx) error {
+		var err error
+		headSeq, _, err = srcBc.HeadSeq(tx)
+		require.NoError(t, err)
+		uxHash, err = srcBc.Unspent().GetUxHash(tx)
+		require.NoError(t, err)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// Export the unspent output set of an earlier block, rebuilt from the history db
+	seq := headSeq - 2
+	data, hdr := exportTestUnspentSnapshot(t, srcDB, &seq)
+	require.Equal(t, seq, hdr.Seq)
+
+	err = srcDB.View("", func(tx *dbutil.Tx) error {
+		b, err := srcBc.GetSignedBlockBySeq(tx, seq+1)
+		require.NoError(t, err)
+		require.Equal(t, b.Head.UxHash, hdr.Commitment)
+		return nil
+	})
+	require.NoError(t, err)
+
+	db, shutdown2 := testutil.PrepareDB(t)
+	defer shutdown2()
+
+	_, err = ImportUnspentSnapshot(context.Background(), db, bytes.NewReader(data), ImportUnspentSnapshotConfig{Pubkey: pubkey})
+	require.NoError(t, err)
+
+	bc, err := NewBlockchain(db, BlockchainConfig{Pubkey: pubkey})
+	require.NoError(t, err)
+
+	history := historydb.New()
+
+	// The blocks after the snapshot block are executed on top of the imported unspent output set
+	for s := seq + 1; s <= headSeq; s++ {
+		err = srcDB.View("", func(tx *dbutil.Tx) error {
+			b, err := srcBc.GetSignedBlockBySeq(tx, s)
+			require.NoError(t, err)
+
+			return db.Update("", func(tx *dbutil.Tx) error {
+				if err := bc.ExecuteBlock(tx, b); err != nil {
+					return err
+				}
+				return history.ParseBlock(tx, b.Block)
+			})
+		})
+		require.NoError(t, err)
+	}
+
+	err = db.View("", func(tx *dbutil.Tx) error {
+		h, err := bc.Unspent().GetUxHash(tx)
+		require.NoError(t, err)
+		require.Equal(t, uxHash, h)
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = CheckDatabase(context.Background(), db, CheckDatabaseConfig{Pubkey: pubkey, FullVerify: true})
+	require.NoError(t, err)
+}
+
+func TestImportUnspentSnapshotInvalid(t *testing.T) {
+	srcDB, shutdown := prepareUnspentSnapshotDB(t, "./testdata/data.db.ok")
+	defer shutdown()
+
+	data, hdr := exportTestUnspentSnapshot(t, srcDB, nil)
+	pubkey := mustParsePubkey(t)
+	otherPubkey, _ := cipher.GenerateKeyPair()
+	otherCommitment := testutil.RandSHA256(t)
+
+	// The last byte of the snapshot belongs to the last uxout
+	badUxOut := append([]byte{}, data...)
+	badUxOut[len(badUxOut)-1] ^= 0xFF
+
+	tt := []struct {
+		name       string
+		data       []byte
+		pubkey     cipher.PubKey
+		commitment *cipher.SHA256
+		err        error
+	}{
+		{
+			name:   "empty",
+			data:   nil,
+			pubkey: pubkey,
+			err:    blockdb.ErrUnspentSnapshotInvalid,
+		},
+		{
+			name:   "bad magic",
+			data:   append([]byte("NOTASNAP"), data[8:]...),
+			pubkey: pubkey,
+			err:    blockdb.ErrUnspentSnapshotInvalid,
+		},
+		{
+			name:   "trailing data",
+			data:   append(append([]byte{}, data...), 0),
+			pubkey: pubkey,
+			err:    blockdb.ErrUnspentSnapshotInvalid,
+		},
+		{
+			name:   "truncated",
+			data:   data[:len(data)-10],
+			pubkey: pubkey,
+			err:    errors.New("Read snapshot uxout failed: unexpected EOF"),
+		},
+		{
+			name:   "wrong pubkey",
+			data:   data,
+			pubkey: otherPubkey,
+			err:    errors.New("Snapshot block 0: Recovered pubkey does not match pubkey"),
+		},
+		{
+			name:   "forged uxout",
+			data:   badUxOut,
+			pubkey: pubkey,
+			err:    blockdb.ErrUnspentSnapshotCommitment,
+		},
+		{
+			name:       "untrusted commitment",
+			data:       data,
+			pubkey:     pubkey,
+			commitment: &otherCommitment,
+			err:        blockdb.ErrUnspentSnapshotCommitment,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			db, shutdown := testutil.PrepareDB(t)
+			defer shutdown()
+
+			_, err := ImportUnspentSnapshot(context.Background(), db, bytes.NewReader(tc.data), ImportUnspentSnapshotConfig{
+				Pubkey:     tc.pubkey,
+				Commitment: tc.commitment,
+			})
+			require.Equal(t, tc.err, err)
+
+			// A failed import leaves the database empty
+			require.NoError(t, CreateBuckets(db))
+			bc, err := NewBlockchain(db, BlockchainConfig{Pubkey: pubkey})
+			require.NoError(t, err)
+			err = db.View("", func(tx *dbutil.Tx) error {
+				n, err := bc.Len(tx)
+				require.NoError(t, err)
+				require.Equal(t, uint64(0), n)
+				return nil
+			})
+			require.NoError(t, err)
+		})
+	}
+
+	require.NotEqual(t, otherCommitment, hdr.Commitment)
+}
@@ -194,6 +194,17 @@ func verifyTxnSoftConstraints(txn coin.Transaction, headTime uint64, uxIn coin.U
 //      * That the transaction input and output hours do not overflow uint64
 // NOTE: Double spends are checked against the unspent output pool when querying for uxIn
 func VerifySingleTxnHardConstraints(txn coin.Transaction, head coin.BlockHeader, uxIn coin.UxArray) error {
+	return verifySingleTxnHardConstraints(txn, head, uxIn, true)
+}
+
+// VerifySingleTxnHardConstraintsUnsigned is VerifySingleTxnHardConstraints for a transaction
+// that is not fully signed yet, such as a transaction created for a watch-only wallet.
+// The inputs with a null signature are not checked; the transaction must be signed before it can be injected
+func VerifySingleTxnHardConstraintsUnsigned(txn coin.Transaction, head coin.BlockHeader, uxIn coin.UxArray) error {
+	return verifySingleTxnHardConstraints(txn, head, uxIn, false)
+}
+
+func verifySingleTxnHardConstraints(txn coin.Transaction, head coin.BlockHeader, uxIn coin.UxArray, signed bool) error {
 	// Check for output hours overflow
 	// When verifying a single transaction, this is considered a hard constraint.
 	// For transactions inside of a block, it is a soft constraint.
@@ -211,7 +222,7 @@ func VerifySingleTxnHardConstraints(txn coin.Transaction, head coin.BlockHeader,
 		}
 	}
 
-	if err := verifyTxnHardConstraints(txn, head, uxIn); err != nil {
+	if err := verifyTxnHardConstraints(txn, head, uxIn, signed); err != nil {
 		return NewErrTxnViolatesHardConstraint(err)
 	}
 
@@ -234,14 +245,14 @@ func VerifySingleTxnHardConstraints(txn coin.Transaction, head coin.BlockHeader,
 // NOTE: output hours overflow is treated as a soft constraint for transactions inside of a block, due to a bug
 //       which allowed some blocks to be published with overflowing output hours.
 func VerifyBlockTxnConstraints(txn coin.Transaction, head coin.BlockHeader, uxIn coin.UxArray) error {
-	if err := verifyTxnHardConstraints(txn, head, uxIn); err != nil {
+	if err := verifyTxnHardConstraints(txn, head, uxIn, true); err != nil {
 		return NewErrTxnViolatesHardConstraint(err)
 	}
 
 	return nil
 }
 
-func verifyTxnHardConstraints(txn coin.Transaction, head coin.BlockHeader, uxIn coin.UxArray) error {
+func verifyTxnHardConstraints(txn coin.Transaction, head coin.BlockHeader, uxIn coin.UxArray, signed bool) error {
 	//CHECKLIST: DONE: check for duplicate ux inputs/double spending
 	//     NOTE: Double spends are checked against the unspent output pool when querying for uxIn
 
@@ -263,13 +274,18 @@ func verifyTxnHardConstraints(txn coin.Transaction, head coin.BlockHeader, uxIn
 	// Check for zero coin outputs
 	// Check valid looking signatures
 
-	if err := txn.Verify(); err != nil {
+	verify, verifyInput := txn.Verify, txn.VerifyInput
+	if !signed {
+		verify, verifyInput = txn.VerifyUnsigned, txn.VerifyInputUnsigned
+	}
+
+	if err := verify(); err != nil {
 		return err
 	}
 
 	// Checks whether ux inputs exist,
 	// Check that signatures are allowed to spend inputs
-	if err := txn.VerifyInput(uxIn); err != nil {
+	if err := verifyInput(uxIn); err != nil {
 		return err
 	}
 
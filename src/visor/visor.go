@@ -11,6 +11,7 @@ Wallets are conceptually a second database.
 package visor
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sort"
@@ -20,12 +21,14 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/encrypt"
 	"github.com/skycoin/skycoin/src/coin"
 	"github.com/skycoin/skycoin/src/params"
 	"github.com/skycoin/skycoin/src/util/logging"
 	"github.com/skycoin/skycoin/src/util/timeutil"
 	"github.com/skycoin/skycoin/src/visor/blockdb"
 	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/export"
 	"github.com/skycoin/skycoin/src/visor/historydb"
 	"github.com/skycoin/skycoin/src/wallet"
 )
@@ -54,6 +57,10 @@ type Config struct {
 	UnconfirmedBurnFactor uint32
 	// Burn factor to apply when creating blocks
 	CreateBlockBurnFactor uint32
+	// Maximum time a txn stays in the unconfirmed pool before it is evicted. 0 disables the limit
+	UnconfirmedMaxAge time.Duration
+	// Maximum number of txns in the unconfirmed pool, the invalid and then the oldest txns are evicted first. 0 disables the limit
+	UnconfirmedMaxPoolSize int
 
 	// Where the blockchain is saved
 	BlockchainFile string
@@ -74,12 +81,58 @@ type Config struct {
 	Arbitrating bool
 	// wallet directory
 	WalletDirectory string
+	// additional directories to load wallets from
+	WalletDirectories []string
+	// wallet storage, replaces the wallet directory if set
+	WalletStorage wallet.WalletStorage
 	// enables wallet API
 	EnableWalletAPI bool
 	// enables seed API
 	EnableSeedAPI bool
 	// wallet crypto type
 	WalletCryptoType wallet.CryptoType
+	// argon2id parameters of the argon2id-chacha20poly1305 wallet crypto type
+	WalletArgon2id encrypt.Argon2idChacha20poly1305
+	// verify the database in the background while the node is running
+	VerifyDBInBackground bool
+	// verify the entire blockchain in the background verification, ignoring the verification checkpoint
+	FullVerifyDB bool
+	// directory where database backups are written
+	DBBackupDir string
+	// retention policy of quarantined corrupted databases
+	DBQuarantine QuarantineConfig
+	// number of goroutines used for database verification, 0 for the default, VerifyWorkersAuto to tune automatically
+	VerifyDBWorkers int
+	// number of most recent blocks whose body is kept, the body of older blocks is removed. 0 disables pruning
+	PruneDepth uint64
+	// compression of the stored blocks. The blocks stored with another compression are rewritten on startup
+	BlockCompression blockdb.BlockCompression
+	// memory budget in bytes of the cache of recently read blocks, signatures and block tree entries. 0 disables the cache
+	BlockCacheSize int
+	// trusted block hashes, blocks that don't match them are rejected
+	Checkpoints Checkpoints
+	// maximum number of received blocks applied in one db transaction. 0 or 1 applies each block in its own transaction
+	BlockApplyBatchSize int
+	// time after which a batch of received blocks is committed, even if it has fewer than BlockApplyBatchSize blocks. 0 disables the limit
+	BlockApplyBatchTime time.Duration
+	// seqs of the corrupted blocks found by CheckDatabase, to be repaired with copies received from peers
+	CorruptedBlocks []uint64
+	// how long to wait for peers to repair the corrupted blocks before giving up. 0 disables the repair
+	BlockRepairTimeout time.Duration
+	// how often a random range of blocks is re-verified in the background. 0 disables the scrubbing
+	DBScrubInterval time.Duration
+	// number of blocks re-verified by each background scrub
+	DBScrubBlocks uint64
+	// how often the read replica of the database, which serves the queries of the API, is refreshed. 0 disables the replica
+	DBReplicaInterval time.Duration
+	// directory where the snapshots of the read replica are written
+	DBReplicaDir string
+	// sink that the events of the parsed blocks are exported to. nil disables the export, unless EventExportFile is set
+	EventExportSink export.Sink
+	// file that the events of the parsed blocks are exported to as JSON lines, if EventExportSink is not set
+	EventExportFile string
+	// how often the new blocks are exported
+	EventExportInterval time.Duration
 }
 
 // NewConfig creates Config
@@ -112,6 +165,50 @@ func (c Config) Verify() error {
 		}
 	}
 
+	if c.DBScrubInterval != 0 && c.DBScrubBlocks == 0 {
+		return errors.New("DBScrubBlocks must be > 0 if DBScrubInterval is set")
+	}
+
+	if c.DBReplicaInterval < 0 {
+		return errors.New("DBReplicaInterval must be >= 0")
+	}
+
+	if c.DBReplicaInterval != 0 && c.DBReplicaDir == "" {
+		return errors.New("DBReplicaDir must be set if DBReplicaInterval is set")
+	}
+
+	if c.EventExportSink != nil && c.EventExportFile != "" {
+		return errors.New("EventExportSink and EventExportFile can't both be set")
+	}
+
+	if (c.EventExportSink != nil || c.EventExportFile != "") && c.EventExportInterval <= 0 {
+		return errors.New("EventExportInterval must be > 0 if the event export is enabled")
+	}
+
+	if c.PruneDepth != 0 && c.PruneDepth < MinPruneDepth {
+		return fmt.Errorf("PruneDepth must be 0 or >= %d", MinPruneDepth)
+	}
+
+	if c.BlockCacheSize < 0 {
+		return errors.New("BlockCacheSize must be >= 0")
+	}
+
+	if c.BlockApplyBatchSize < 0 {
+		return errors.New("BlockApplyBatchSize must be >= 0")
+	}
+
+	if c.BlockApplyBatchTime < 0 {
+		return errors.New("BlockApplyBatchTime must be >= 0")
+	}
+
+	if c.UnconfirmedMaxAge < 0 {
+		return errors.New("UnconfirmedMaxAge must be >= 0")
+	}
+
+	if c.UnconfirmedMaxPoolSize < 0 {
+		return errors.New("UnconfirmedMaxPoolSize must be >= 0")
+	}
+
 	if c.UnconfirmedBurnFactor < params.UserBurnFactor {
 		return fmt.Errorf("UnconfirmedBurnFactor must be >= params.UserBurnFactor (%d)", params.UserBurnFactor)
 	}
@@ -143,9 +240,20 @@ func (c Config) Verify() error {
 type Historyer interface {
 	GetUxOuts(tx *dbutil.Tx, uxids []cipher.SHA256) ([]historydb.UxOut, error)
 	ParseBlock(tx *dbutil.Tx, b coin.Block) error
+	UnparseBlock(tx *dbutil.Tx, b coin.Block) error
 	GetTransaction(tx *dbutil.Tx, hash cipher.SHA256) (*historydb.Transaction, error)
+	GetTxnBlock(tx *dbutil.Tx, hash cipher.SHA256) (*historydb.TxnBlock, error)
+	GetUxOutSpender(tx *dbutil.Tx, uxID cipher.SHA256) (*historydb.UxOutSpender, error)
 	GetOutputsForAddress(tx *dbutil.Tx, address cipher.Address) ([]historydb.UxOut, error)
 	GetTransactionsForAddress(tx *dbutil.Tx, address cipher.Address) ([]historydb.Transaction, error)
+	GetTransactionsForAddressPage(tx *dbutil.Tx, address cipher.Address, cursor *historydb.AddressTxnsCursor, offset, limit uint64) ([]historydb.Transaction, *historydb.AddressTxnsCursor, error)
+	GetOutputsByValuePage(tx *dbutil.Tx, q historydb.OutputValuesQuery, cursor *historydb.OutputValuesCursor, limit uint64) ([]historydb.UxOut, *historydb.OutputValuesCursor, error)
+	GetAddressMeta(tx *dbutil.Tx, address cipher.Address) (*historydb.AddressMeta, error)
+	GetAddressBalanceAt(tx *dbutil.Tx, address cipher.Address, seq uint64) (*historydb.AddressBalanceDelta, error)
+	GetAddressBalanceDeltas(tx *dbutil.Tx, address cipher.Address, start, end uint64) ([]historydb.AddressBalanceDelta, error)
+	GetRecentTransactionsForAddrs(tx *dbutil.Tx, addrs []cipher.Address, n uint64) (map[cipher.Address][]historydb.Transaction, error)
+	ForEachRichlistEntry(tx *dbutil.Tx, f func(historydb.RichlistEntry) (bool, error)) error
+	GetBalanceDistribution(tx *dbutil.Tx) ([]historydb.BalanceRange, error)
 	NeedsReset(tx *dbutil.Tx) (bool, error)
 	Erase(tx *dbutil.Tx) error
 	ParsedBlockSeq(tx *dbutil.Tx) (uint64, bool, error)
@@ -160,6 +268,9 @@ type Blockchainer interface {
 	GetLastBlocks(tx *dbutil.Tx, n uint64) ([]coin.SignedBlock, error)
 	GetSignedBlockByHash(tx *dbutil.Tx, hash cipher.SHA256) (*coin.SignedBlock, error)
 	GetSignedBlockBySeq(tx *dbutil.Tx, seq uint64) (*coin.SignedBlock, error)
+	GetUnspentCommitment(tx *dbutil.Tx, seq uint64) (cipher.SHA256, bool, error)
+	GetBlockStatsInRange(tx *dbutil.Tx, start, end uint64) ([]blockdb.BlockStats, error)
+	GetBlockSeqByTime(tx *dbutil.Tx, t uint64) (uint64, bool, error)
 	Unspent() blockdb.UnspentPooler
 	Len(tx *dbutil.Tx) (uint64, error)
 	Head(tx *dbutil.Tx) (*coin.SignedBlock, error)
@@ -171,6 +282,13 @@ type Blockchainer interface {
 	VerifySingleTxnHardConstraints(tx *dbutil.Tx, txn coin.Transaction) error
 	VerifySingleTxnSoftHardConstraints(tx *dbutil.Tx, txn coin.Transaction, maxSize, burnFactor uint32) error
 	TransactionFee(tx *dbutil.Tx, hours uint64) coin.FeeCalculator
+	PrunedSeq(tx *dbutil.Tx) (uint64, error)
+	IsPruned(tx *dbutil.Tx, seq uint64) (bool, error)
+	Prune(tx *dbutil.Tx, depth, limit uint64) (uint64, error)
+	RecompressBlocks(tx *dbutil.Tx, start []byte, limit uint64) (uint64, []byte, error)
+	RepairBlock(tx *dbutil.Tx, b *coin.SignedBlock) error
+	RemoveHead(tx *dbutil.Tx) (*coin.SignedBlock, error)
+	RebuildBlockStats(tx *dbutil.Tx) (uint64, error)
 }
 
 // UnconfirmedTransactionPooler is the interface that provides methods for
@@ -192,6 +310,8 @@ type UnconfirmedTransactionPooler interface {
 	ForEach(tx *dbutil.Tx, f func(cipher.SHA256, UnconfirmedTransaction) error) error
 	GetUnspentsOfAddr(tx *dbutil.Tx, addr cipher.Address) (coin.UxArray, error)
 	Len(tx *dbutil.Tx) (uint64, error)
+	Evict(tx *dbutil.Tx, maxAge time.Duration, maxSize int, now time.Time) ([]EvictedUnconfirmedTxn, error)
+	GetEvicted(tx *dbutil.Tx, after, limit uint64) ([]EvictedUnconfirmedTxn, error)
 }
 
 // Visor manages the blockchain
@@ -203,7 +323,14 @@ type Visor struct {
 	Wallets     *wallet.Service
 	StartedAt   time.Time
 
-	history Historyer
+	history       Historyer
+	dbVerifier    *DBVerifier
+	dbScrubber    *DBScrubber
+	dbReplica     *DBReplica
+	eventExporter *EventExporter
+	blockRepairer *blockRepairer
+	watchList     *watchList
+	walletEvents  *walletEvents
 }
 
 // NewVisor creates a Visor for managing the blockchain database
@@ -225,7 +352,10 @@ func NewVisor(c Config, db *dbutil.DB) (*Visor, error) {
 	// Loads wallet
 	wltServConfig := wallet.Config{
 		WalletDir:       c.WalletDirectory,
+		WalletDirs:      c.WalletDirectories,
+		Storage:         c.WalletStorage,
 		CryptoType:      c.WalletCryptoType,
+		Argon2id:        c.WalletArgon2id,
 		EnableWalletAPI: c.EnableWalletAPI,
 		EnableSeedAPI:   c.EnableSeedAPI,
 	}
@@ -243,8 +373,11 @@ func NewVisor(c Config, db *dbutil.DB) (*Visor, error) {
 	}
 
 	bc, err := NewBlockchain(db, BlockchainConfig{
-		Pubkey:      c.BlockchainPubkey,
-		Arbitrating: c.Arbitrating,
+		Pubkey:           c.BlockchainPubkey,
+		Arbitrating:      c.Arbitrating,
+		VerifyWorkers:    c.VerifyDBWorkers,
+		BlockCompression: c.BlockCompression,
+		BlockCacheSize:   c.BlockCacheSize,
 	})
 	if err != nil {
 		return nil, err
@@ -252,6 +385,11 @@ func NewVisor(c Config, db *dbutil.DB) (*Visor, error) {
 
 	history := historydb.New()
 
+	utp, err := NewUnconfirmedTransactionPool(db)
+	if err != nil {
+		return nil, err
+	}
+
 	if !db.IsReadOnly() {
 		if err := db.Update("build unspent indexes and init history", func(tx *dbutil.Tx) error {
 			headSeq, _, err := bc.HeadSeq(tx)
@@ -263,17 +401,36 @@ func NewVisor(c Config, db *dbutil.DB) (*Visor, error) {
 				return err
 			}
 
+			if err := bc.MaybeBuildUnspentCommitments(tx); err != nil {
+				return err
+			}
+
+			if err := bc.MaybeBuildBlockStats(tx); err != nil {
+				return err
+			}
+
+			if err := bc.MaybeBuildBlockTimes(tx); err != nil {
+				return err
+			}
+
+			if err := maybeBuildWalletEventsIndex(tx); err != nil {
+				return err
+			}
+
+			if err := maybeMigrateWatchAddresses(tx); err != nil {
+				return err
+			}
+
+			if err := recoverBlockApplication(tx, bc, history, utp); err != nil {
+				return err
+			}
+
 			return initHistory(tx, bc, history)
 		}); err != nil {
 			return nil, err
 		}
 	}
 
-	utp, err := NewUnconfirmedTransactionPool(db)
-	if err != nil {
-		return nil, err
-	}
-
 	v := &Visor{
 		Config:      c,
 		DB:          db,
@@ -282,11 +439,148 @@ func NewVisor(c Config, db *dbutil.DB) (*Visor, error) {
 		history:     history,
 		Wallets:     wltServ,
 		StartedAt:   time.Now(),
+
+		blockRepairer: newBlockRepairer(c.CorruptedBlocks),
+		watchList:     newWatchList(),
+		walletEvents:  newWalletEvents(),
+	}
+
+	if c.DBScrubInterval != 0 {
+		v.dbScrubber = NewDBScrubber(db, bc, history, c.DBScrubInterval, c.DBScrubBlocks)
+	}
+
+	if c.DBReplicaInterval != 0 {
+		v.dbReplica = NewDBReplica(db, bc, c.DBReplicaDir, c.DBReplicaInterval)
+	}
+
+	eventExportSink := c.EventExportSink
+	if (eventExportSink != nil || c.EventExportFile != "") && db.IsReadOnly() {
+		return nil, errors.New("The event export records its cursor in the database, it can't run on a read-only database")
+	}
+	if c.EventExportFile != "" {
+		eventExportSink, err = export.NewJSONFileSink(c.EventExportFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if eventExportSink != nil {
+		v.eventExporter = NewEventExporter(db, bc, history, eventExportSink, c.EventExportInterval)
+	}
+
+	if c.VerifyDBInBackground {
+		v.dbVerifier = NewDBVerifier(db, CheckDatabaseConfig{
+			Pubkey:      c.BlockchainPubkey,
+			FullVerify:  c.FullVerifyDB,
+			Progress:    NewVerifyProgressLogger(VerifyProgressLogRate),
+			Workers:     c.VerifyDBWorkers,
+			Checkpoints: c.Checkpoints,
+		})
 	}
 
 	return v, nil
 }
 
+// VerifyDB verifies the database if background verification is enabled,
+// blocking until the verification is done. It returns the verification error, if any.
+func (vs *Visor) VerifyDB(ctx context.Context) error {
+	if vs.dbVerifier == nil {
+		return nil
+	}
+
+	return vs.dbVerifier.Run(ctx)
+}
+
+// ScrubDB re-verifies random ranges of blocks in the background if scrubbing is enabled,
+// blocking until ctx is done
+func (vs *Visor) ScrubDB(ctx context.Context) {
+	if vs.dbScrubber == nil {
+		return
+	}
+
+	vs.dbScrubber.Run(ctx)
+}
+
+// RunDBReplica refreshes the read replica of the database periodically if the replica is enabled,
+// blocking until ctx is done
+func (vs *Visor) RunDBReplica(ctx context.Context) {
+	if vs.dbReplica == nil {
+		return
+	}
+
+	vs.dbReplica.Run(ctx)
+}
+
+// GetDBReplicaStatus returns the status of the read replica of the database, nil if the replica is disabled
+func (vs *Visor) GetDBReplicaStatus() *DBReplicaStatus {
+	if vs.dbReplica == nil {
+		return nil
+	}
+
+	s := vs.dbReplica.Status()
+	return &s
+}
+
+// viewReplica runs a read-only transaction on the read replica of the database if it is enabled, otherwise on the database.
+// It is used by the queries of the blocks and of the history served to the API,
+// whose results may lag behind the node by up to Config.DBReplicaInterval.
+func (vs *Visor) viewReplica(name string, f func(*dbutil.Tx) error) error {
+	if vs.dbReplica == nil {
+		return vs.DB.View(name, f)
+	}
+
+	return vs.dbReplica.View(name, f)
+}
+
+// BackupDB writes a snapshot of the database to the backup directory while the node keeps running
+func (vs *Visor) BackupDB() (*DBBackup, error) {
+	if vs.Config.DBBackupDir == "" {
+		return nil, ErrDBBackupDisabled
+	}
+
+	return BackupDB(vs.DB, vs.Blockchain, vs.Config.DBBackupDir)
+}
+
+// QuarantinedDBs returns the quarantined copies of corrupted databases, newest first
+func (vs *Visor) QuarantinedDBs() ([]QuarantinedDB, error) {
+	return ListQuarantinedDBs(vs.DB.Path())
+}
+
+// DeleteQuarantinedDB deletes a quarantined copy of a corrupted database
+func (vs *Visor) DeleteQuarantinedDB(name string) error {
+	return DeleteQuarantinedDB(vs.DB.Path(), name)
+}
+
+// GetDBVerifyStatus returns the status of the background database verification
+func (vs *Visor) GetDBVerifyStatus() DBVerifyStatus {
+	if vs.dbVerifier == nil {
+		return DBVerifyStatus{
+			State: DBVerifyStateDisabled,
+		}
+	}
+
+	return vs.dbVerifier.Status()
+}
+
+// GetDBIntegrityReport returns the report of the last database verification and the recovery actions
+// taken on the database, or nil if there is none
+func (vs *Visor) GetDBIntegrityReport() (*DBIntegrityReport, error) {
+	return GetDBIntegrityReport(vs.DB)
+}
+
+// GetDBStats returns the size and page utilization of the database files and buckets
+func (vs *Visor) GetDBStats() (*dbutil.Stats, error) {
+	var stats *dbutil.Stats
+	if err := vs.DB.View("GetDBStats", func(tx *dbutil.Tx) error {
+		var err error
+		stats, err = dbutil.GetStats(tx)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
 // Init initializes starts the visor
 func (vs *Visor) Init() error {
 	logger.Info("Visor init")
@@ -295,7 +589,7 @@ func (vs *Visor) Init() error {
 		return nil
 	}
 
-	return vs.DB.Update("visor init", func(tx *dbutil.Tx) error {
+	if err := vs.DB.Update("visor init", func(tx *dbutil.Tx) error {
 		if err := vs.maybeCreateGenesisBlock(tx); err != nil {
 			return err
 		}
@@ -307,7 +601,18 @@ func (vs *Visor) Init() error {
 		logger.Infof("Removed %d invalid txns from pool", len(removed))
 
 		return nil
-	})
+	}); err != nil {
+		return err
+	}
+
+	// The genesis block may have been applied
+	vs.clearApplyJournal()
+
+	if err := vs.pruneBlocks(); err != nil {
+		return err
+	}
+
+	return vs.recompressBlocks()
 }
 
 func initHistory(tx *dbutil.Tx, bc *Blockchain, history *historydb.HistoryDB) error {
@@ -319,7 +624,7 @@ func initHistory(tx *dbutil.Tx, bc *Blockchain, history *historydb.HistoryDB) er
 	}
 
 	if !shouldReset {
-		return nil
+		return catchUpHistory(tx, bc, history)
 	}
 
 	logger.Info("Resetting historyDB")
@@ -329,11 +634,15 @@ func initHistory(tx *dbutil.Tx, bc *Blockchain, history *historydb.HistoryDB) er
 	}
 
 	// Reparse the history up to the blockchain head
-	headSeq, _, err := bc.HeadSeq(tx)
+	headSeq, ok, err := bc.HeadSeq(tx)
 	if err != nil {
 		return err
 	}
 
+	if !ok {
+		return nil
+	}
+
 	if err := parseHistoryTo(tx, history, bc, headSeq); err != nil {
 		logger.WithError(err).Error("parseHistoryTo failed")
 		return err
@@ -342,22 +651,57 @@ func initHistory(tx *dbutil.Tx, bc *Blockchain, history *historydb.HistoryDB) er
 	return nil
 }
 
+// catchUpHistory parses the blocks that are missing from the history db.
+// The history file of a split database misses the last blocks if the process stopped
+// after the block was committed to the primary file, see dbutil.WrapSplitDB.
+func catchUpHistory(tx *dbutil.Tx, bc *Blockchain, history *historydb.HistoryDB) error {
+	parsedBlockSeq, _, err := history.ParsedBlockSeq(tx)
+	if err != nil {
+		return err
+	}
+
+	headSeq, ok, err := bc.HeadSeq(tx)
+	if err != nil {
+		return err
+	}
+
+	if !ok || headSeq <= parsedBlockSeq {
+		return nil
+	}
+
+	logger.Infof("History db is behind the blockchain, parsing blocks %d to %d", parsedBlockSeq+1, headSeq)
+
+	return parseHistoryTo(tx, history, bc, headSeq)
+}
+
 func parseHistoryTo(tx *dbutil.Tx, history *historydb.HistoryDB, bc *Blockchain, height uint64) error {
 	logger.Info("Visor parseHistoryTo")
 
-	parsedBlockSeq, _, err := history.ParsedBlockSeq(tx)
+	parsedBlockSeq, ok, err := history.ParsedBlockSeq(tx)
 	if err != nil {
 		return err
 	}
 
-	for i := uint64(0); i < height-parsedBlockSeq; i++ {
-		b, err := bc.GetSignedBlockBySeq(tx, parsedBlockSeq+i+1)
+	if height > parsedBlockSeq {
+		if err := checkHistoryNotPruned(tx, bc, parsedBlockSeq+1); err != nil {
+			return err
+		}
+	}
+
+	// An empty history db starts with the genesis block
+	start := parsedBlockSeq + 1
+	if !ok {
+		start = 0
+	}
+
+	for seq := start; seq <= height; seq++ {
+		b, err := bc.GetSignedBlockBySeq(tx, seq)
 		if err != nil {
 			return err
 		}
 
 		if b == nil {
-			return fmt.Errorf("no block exists in depth: %d", parsedBlockSeq+i+1)
+			return fmt.Errorf("no block exists in depth: %d", seq)
 		}
 
 		if err := history.ParseBlock(tx, b.Block); err != nil {
@@ -522,36 +866,64 @@ func (vs *Visor) createBlock(tx *dbutil.Tx, when uint64) (coin.SignedBlock, erro
 
 // CreateAndExecuteBlock creates a SignedBlock from pending transactions and executes it
 func (vs *Visor) CreateAndExecuteBlock() (coin.SignedBlock, error) {
+	return vs.CreateAndExecuteBlockAt(time.Now())
+}
+
+// CreateAndExecuteBlockAt creates a SignedBlock timestamped at when from pending transactions and executes it
+func (vs *Visor) CreateAndExecuteBlockAt(when time.Time) (coin.SignedBlock, error) {
 	var sb coin.SignedBlock
 
 	err := vs.DB.Update("CreateAndExecuteBlock", func(tx *dbutil.Tx) error {
 		var err error
-		sb, err = vs.createBlock(tx, uint64(time.Now().UTC().Unix()))
+		sb, err = vs.createBlock(tx, uint64(when.UTC().Unix()))
 		if err != nil {
 			return err
 		}
 
 		return vs.executeSignedBlock(tx, sb)
 	})
+	if err != nil {
+		return sb, err
+	}
 
-	return sb, err
+	vs.clearApplyJournal()
+
+	return sb, nil
 }
 
 // ExecuteSignedBlock adds a block to the blockchain, or returns error.
 // Blocks must be executed in sequence, and be signed by a block publisher node
 func (vs *Visor) ExecuteSignedBlock(b coin.SignedBlock) error {
-	return vs.DB.Update("ExecuteSignedBlock", func(tx *dbutil.Tx) error {
+	if err := vs.DB.Update("ExecuteSignedBlock", func(tx *dbutil.Tx) error {
 		return vs.executeSignedBlock(tx, b)
-	})
+	}); err != nil {
+		return err
+	}
+
+	vs.clearApplyJournal()
+
+	return nil
 }
 
 // executeSignedBlock adds a block to the blockchain, or returns error.
-// Blocks must be executed in sequence, and be signed by a block publisher node
+// Blocks must be executed in sequence, and be signed by a block publisher node.
+// The block application is journaled, the caller must call clearApplyJournal once tx is committed.
 func (vs *Visor) executeSignedBlock(tx *dbutil.Tx, b coin.SignedBlock) error {
+	if err := vs.Config.Checkpoints.VerifyBlock(&b.Block); err != nil {
+		return err
+	}
+
 	if err := b.VerifySignature(vs.Config.BlockchainPubkey); err != nil {
 		return err
 	}
 
+	if err := setApplyJournalEntry(tx, ApplyJournalEntry{
+		Seq:  b.Seq(),
+		Hash: b.HashHeader(),
+	}); err != nil {
+		return err
+	}
+
 	if err := vs.Blockchain.ExecuteBlock(tx, &b); err != nil {
 		return err
 	}
@@ -567,7 +939,25 @@ func (vs *Visor) executeSignedBlock(tx *dbutil.Tx, b coin.SignedBlock) error {
 	}
 
 	// Update the HistoryDB
-	return vs.history.ParseBlock(tx, b.Block)
+	if err := vs.history.ParseBlock(tx, b.Block); err != nil {
+		return err
+	}
+
+	if err := vs.recordBlockWatchEvents(tx, &b.Block); err != nil {
+		return err
+	}
+
+	if err := vs.recordBlockWalletEvents(tx, &b.Block); err != nil {
+		return err
+	}
+
+	if vs.Config.PruneDepth > 0 {
+		if _, err := vs.Blockchain.Prune(tx, vs.Config.PruneDepth, pruneBatchSize); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 // signBlock signs a block for a block publisher node. Will panic if anything is invalid
@@ -703,7 +1093,8 @@ func (vs *Visor) GetSignedBlocksSince(seq, ct uint64) ([]coin.SignedBlock, error
 			blocks = append(blocks, *b)
 		}
 
-		return nil
+		// Peers must not be sent pruned blocks, they would be rejected as invalid
+		return checkBlocksPruned(tx, vs.Blockchain, blocks...)
 	}); err != nil {
 		return nil, err
 	}
@@ -711,6 +1102,45 @@ func (vs *Visor) GetSignedBlocksSince(seq, ct uint64) ([]coin.SignedBlock, error
 	return blocks, nil
 }
 
+// GetSignedBlockHeadersSince returns the signed headers of N blocks more recent than Seq. Does not return nil.
+// Unlike GetSignedBlocksSince, the headers of pruned blocks are returned, since pruned blocks keep their header
+func (vs *Visor) GetSignedBlockHeadersSince(seq, ct uint64) ([]coin.SignedBlockHeader, error) {
+	headers := []coin.SignedBlockHeader{}
+
+	if err := vs.DB.View("GetSignedBlockHeadersSince", func(tx *dbutil.Tx) error {
+		head, err := vs.Blockchain.Head(tx)
+		if err != nil {
+			return err
+		}
+
+		headSeq := head.Seq()
+		if headSeq <= seq {
+			return nil
+		}
+		if avail := headSeq - seq; avail < ct {
+			ct = avail
+		}
+
+		for i := seq + 1; i <= seq+ct; i++ {
+			b, err := vs.Blockchain.GetSignedBlockBySeq(tx, i)
+			if err != nil {
+				return err
+			}
+			if b == nil {
+				return fmt.Errorf("GetSignedBlockHeadersSince block seq=%d not found", i)
+			}
+
+			headers = append(headers, b.SignedHeader())
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return headers, nil
+}
+
 // HeadBkSeq returns the highest BkSeq we know, returns false in the 2nd return value
 // if the blockchain is empty
 func (vs *Visor) HeadBkSeq() (uint64, bool, error) {
@@ -728,10 +1158,37 @@ func (vs *Visor) HeadBkSeq() (uint64, bool, error) {
 	return headSeq, ok, nil
 }
 
+// GetHeadBlockHeader returns the signed header of the head block, returns false in the 2nd return value
+// if the blockchain is empty
+func (vs *Visor) GetHeadBlockHeader() (coin.SignedBlockHeader, bool, error) {
+	var header coin.SignedBlockHeader
+	var ok bool
+
+	if err := vs.DB.View("GetHeadBlockHeader", func(tx *dbutil.Tx) error {
+		var err error
+		_, ok, err = vs.Blockchain.HeadSeq(tx)
+		if err != nil || !ok {
+			return err
+		}
+
+		head, err := vs.Blockchain.Head(tx)
+		if err != nil {
+			return err
+		}
+
+		header = head.SignedHeader()
+		return nil
+	}); err != nil {
+		return coin.SignedBlockHeader{}, false, err
+	}
+
+	return header, ok, nil
+}
+
 // GetBlockchainMetadata returns descriptive Blockchain information
 func (vs *Visor) GetBlockchainMetadata() (*BlockchainMetadata, error) {
 	var head *coin.SignedBlock
-	var unconfirmedLen, unspentsLen uint64
+	var unconfirmedLen, unspentsLen, prunedSeq uint64
 
 	if err := vs.DB.View("GetBlockchainMetadata", func(tx *dbutil.Tx) error {
 		var err error
@@ -746,19 +1203,24 @@ func (vs *Visor) GetBlockchainMetadata() (*BlockchainMetadata, error) {
 		}
 
 		unspentsLen, err = vs.Blockchain.Unspent().Len(tx)
+		if err != nil {
+			return err
+		}
+
+		prunedSeq, err = vs.Blockchain.PrunedSeq(tx)
 		return err
 	}); err != nil {
 		return nil, err
 	}
 
-	return NewBlockchainMetadata(*head, unconfirmedLen, unspentsLen)
+	return NewBlockchainMetadata(*head, unconfirmedLen, unspentsLen, prunedSeq)
 }
 
 // GetBlock returns a copy of the block at seq. Returns error if seq out of range
 func (vs *Visor) GetBlock(seq uint64) (*coin.SignedBlock, error) {
 	var b *coin.SignedBlock
 
-	if err := vs.DB.View("GetBlock", func(tx *dbutil.Tx) error {
+	if err := vs.viewReplica("GetBlock", func(tx *dbutil.Tx) error {
 		headSeq, ok, err := vs.Blockchain.HeadSeq(tx)
 		if err != nil {
 			return err
@@ -769,7 +1231,11 @@ func (vs *Visor) GetBlock(seq uint64) (*coin.SignedBlock, error) {
 		}
 
 		b, err = vs.Blockchain.GetSignedBlockBySeq(tx, seq)
-		return err
+		if err != nil {
+			return err
+		}
+
+		return checkBlockPruned(tx, vs.Blockchain, b)
 	}); err != nil {
 		return nil, err
 	}
@@ -781,10 +1247,14 @@ func (vs *Visor) GetBlock(seq uint64) (*coin.SignedBlock, error) {
 func (vs *Visor) GetBlocks(seqs []uint64) ([]coin.SignedBlock, error) {
 	var blocks []coin.SignedBlock
 
-	if err := vs.DB.View("GetBlocks", func(tx *dbutil.Tx) error {
+	if err := vs.viewReplica("GetBlocks", func(tx *dbutil.Tx) error {
 		var err error
 		blocks, err = vs.Blockchain.GetBlocks(tx, seqs)
-		return err
+		if err != nil {
+			return err
+		}
+
+		return checkBlocksPruned(tx, vs.Blockchain, blocks...)
 	}); err != nil {
 		return nil, err
 	}
@@ -797,7 +1267,7 @@ func (vs *Visor) GetBlocksVerbose(seqs []uint64) ([]coin.SignedBlock, [][][]Tran
 	var blocks []coin.SignedBlock
 	var inputs [][][]TransactionInput
 
-	if err := vs.DB.View("GetBlocksVerbose", func(tx *dbutil.Tx) error {
+	if err := vs.viewReplica("GetBlocksVerbose", func(tx *dbutil.Tx) error {
 		var err error
 		blocks, inputs, err = vs.getBlocksVerbose(tx, func(tx *dbutil.Tx) ([]coin.SignedBlock, error) {
 			return vs.Blockchain.GetBlocks(tx, seqs)
@@ -815,10 +1285,14 @@ func (vs *Visor) GetBlocksVerbose(seqs []uint64) ([]coin.SignedBlock, [][][]Tran
 func (vs *Visor) GetBlocksInRange(start, end uint64) ([]coin.SignedBlock, error) {
 	var blocks []coin.SignedBlock
 
-	if err := vs.DB.View("GetBlocksInRange", func(tx *dbutil.Tx) error {
+	if err := vs.viewReplica("GetBlocksInRange", func(tx *dbutil.Tx) error {
 		var err error
 		blocks, err = vs.Blockchain.GetBlocksInRange(tx, start, end)
-		return err
+		if err != nil {
+			return err
+		}
+
+		return checkBlocksPruned(tx, vs.Blockchain, blocks...)
 	}); err != nil {
 		return nil, err
 	}
@@ -833,7 +1307,7 @@ func (vs *Visor) GetBlocksInRangeVerbose(start, end uint64) ([]coin.SignedBlock,
 	var blocks []coin.SignedBlock
 	var inputs [][][]TransactionInput
 
-	if err := vs.DB.View("GetBlocksInRangeVerbose", func(tx *dbutil.Tx) error {
+	if err := vs.viewReplica("GetBlocksInRangeVerbose", func(tx *dbutil.Tx) error {
 		var err error
 		blocks, inputs, err = vs.getBlocksVerbose(tx, func(tx *dbutil.Tx) ([]coin.SignedBlock, error) {
 			return vs.Blockchain.GetBlocksInRange(tx, start, end)
@@ -850,10 +1324,14 @@ func (vs *Visor) GetBlocksInRangeVerbose(start, end uint64) ([]coin.SignedBlock,
 func (vs *Visor) GetLastBlocks(num uint64) ([]coin.SignedBlock, error) {
 	var blocks []coin.SignedBlock
 
-	if err := vs.DB.View("GetLastBlocks", func(tx *dbutil.Tx) error {
+	if err := vs.viewReplica("GetLastBlocks", func(tx *dbutil.Tx) error {
 		var err error
 		blocks, err = vs.Blockchain.GetLastBlocks(tx, num)
-		return err
+		if err != nil {
+			return err
+		}
+
+		return checkBlocksPruned(tx, vs.Blockchain, blocks...)
 	}); err != nil {
 		return nil, err
 	}
@@ -866,7 +1344,7 @@ func (vs *Visor) GetLastBlocksVerbose(num uint64) ([]coin.SignedBlock, [][][]Tra
 	var blocks []coin.SignedBlock
 	var inputs [][][]TransactionInput
 
-	if err := vs.DB.View("GetLastBlocksVerbose", func(tx *dbutil.Tx) error {
+	if err := vs.viewReplica("GetLastBlocksVerbose", func(tx *dbutil.Tx) error {
 		var err error
 		blocks, inputs, err = vs.getBlocksVerbose(tx, func(tx *dbutil.Tx) ([]coin.SignedBlock, error) {
 			return vs.Blockchain.GetLastBlocks(tx, num)
@@ -885,6 +1363,10 @@ func (vs *Visor) getBlocksVerbose(tx *dbutil.Tx, getBlocks func(*dbutil.Tx) ([]c
 		return nil, nil, err
 	}
 
+	if err := checkBlocksPruned(tx, vs.Blockchain, blocks...); err != nil {
+		return nil, nil, err
+	}
+
 	if len(blocks) == 0 {
 		return nil, nil, nil
 	}
@@ -914,7 +1396,15 @@ func (vs *Visor) InjectForeignTransaction(txn coin.Transaction) (bool, *ErrTxnVi
 	if err := vs.DB.Update("InjectForeignTransaction", func(tx *dbutil.Tx) error {
 		var err error
 		known, softErr, err = vs.Unconfirmed.InjectTransaction(tx, vs.Blockchain, txn, vs.Config.UnconfirmedMaxTransactionSize, vs.Config.UnconfirmedBurnFactor)
-		return err
+		if err != nil || known {
+			return err
+		}
+
+		if err := vs.recordUnconfirmedWatchEvents(tx, &txn); err != nil {
+			return err
+		}
+
+		return vs.recordUnconfirmedWalletEvents(tx, &txn)
 	}); err != nil {
 		return false, nil, err
 	}
@@ -958,8 +1448,38 @@ func (vs *Visor) InjectUserTransactionTx(tx *dbutil.Tx, txn coin.Transaction) (b
 	if softErr != nil {
 		logger.WithError(softErr).Warning("InjectUserTransaction vs.Unconfirmed.InjectTransaction returned a softErr unexpectedly")
 	}
+	if err != nil || known {
+		return known, err
+	}
+
+	if err := vs.recordUnconfirmedWatchEvents(tx, &txn); err != nil {
+		return false, err
+	}
+
+	if err := vs.recordUnconfirmedWalletEvents(tx, &txn); err != nil {
+		return false, err
+	}
+
+	return known, nil
+}
+
+// TransactionFee calculates the current coinhour fee of a transaction.
+// Returns an error if the inputs of the transaction are not unspent outputs
+func (vs *Visor) TransactionFee(txn *coin.Transaction) (uint64, error) {
+	var f uint64
+	if err := vs.DB.View("TransactionFee", func(tx *dbutil.Tx) error {
+		head, err := vs.Blockchain.Head(tx)
+		if err != nil {
+			return err
+		}
+
+		f, err = vs.Blockchain.TransactionFee(tx, head.Time())(txn)
+		return err
+	}); err != nil {
+		return 0, err
+	}
 
-	return known, err
+	return f, nil
 }
 
 // GetTransactionsForAddress returns the Transactions whose unspents give coins to a cipher.Address.
@@ -982,7 +1502,7 @@ func (vs *Visor) GetTransactionsForAddress(a cipher.Address) ([]Transaction, err
 func (vs *Visor) GetTransaction(txnHash cipher.SHA256) (*Transaction, error) {
 	var txn *Transaction
 
-	if err := vs.DB.View("GetTransaction", func(tx *dbutil.Tx) error {
+	if err := vs.viewReplica("GetTransaction", func(tx *dbutil.Tx) error {
 		var err error
 		txn, err = vs.getTransaction(tx, txnHash)
 		return err
@@ -998,7 +1518,7 @@ func (vs *Visor) GetTransactionWithInputs(txnHash cipher.SHA256) (*Transaction,
 	var txn *Transaction
 	var inputs []TransactionInput
 
-	if err := vs.DB.View("GetTransactionWithInputs", func(tx *dbutil.Tx) error {
+	if err := vs.viewReplica("GetTransactionWithInputs", func(tx *dbutil.Tx) error {
 		var err error
 		txn, err = vs.getTransaction(tx, txnHash)
 		if err != nil {
@@ -1119,7 +1639,7 @@ func NewConfirmedTxFilter(isConfirmed bool) TxFilter {
 func (vs *Visor) GetTransactions(flts []TxFilter) ([]Transaction, error) {
 	var txns []Transaction
 
-	if err := vs.DB.View("GetTransactions", func(tx *dbutil.Tx) error {
+	if err := vs.viewReplica("GetTransactions", func(tx *dbutil.Tx) error {
 		var err error
 		txns, err = vs.getTransactions(tx, flts)
 		return err
@@ -1135,7 +1655,7 @@ func (vs *Visor) GetTransactionsWithInputs(flts []TxFilter) ([]Transaction, [][]
 	var txns []Transaction
 	var inputs [][]TransactionInput
 
-	if err := vs.DB.View("GetTransactionsWithInputs", func(tx *dbutil.Tx) error {
+	if err := vs.viewReplica("GetTransactionsWithInputs", func(tx *dbutil.Tx) error {
 		var err error
 		txns, err = vs.getTransactions(tx, flts)
 		if err != nil {
@@ -1268,30 +1788,10 @@ func (vs *Visor) getTransactionsForAddresses(tx *dbutil.Tx, addrs []cipher.Addre
 
 		txns := make([]Transaction, len(addrTxns), len(addrTxns)+4)
 		for i, txn := range addrTxns {
-			if headBkSeq < txn.BlockSeq {
-				err := errors.New("Transaction block sequence is greater than the head block sequence")
-				logger.Critical().WithError(err).WithFields(logrus.Fields{
-					"headBkSeq":  headBkSeq,
-					"txBlockSeq": txn.BlockSeq,
-				}).Error()
-				return nil, err
-			}
-			h := headBkSeq - txn.BlockSeq + 1
-
-			bk, err := vs.Blockchain.GetSignedBlockBySeq(tx, txn.BlockSeq)
+			txns[i], err = vs.newConfirmedTransaction(tx, headBkSeq, txn)
 			if err != nil {
 				return nil, err
 			}
-
-			if bk == nil {
-				return nil, fmt.Errorf("block seq=%d doesn't exist", txn.BlockSeq)
-			}
-
-			txns[i] = Transaction{
-				Transaction: txn.Txn,
-				Status:      NewConfirmedTransactionStatus(h, txn.BlockSeq),
-				Time:        bk.Time(),
-			}
 		}
 
 		// Look in the unconfirmed pool
@@ -1324,6 +1824,34 @@ func (vs *Visor) getTransactionsForAddresses(tx *dbutil.Tx, addrs []cipher.Addre
 	return ret, nil
 }
 
+// newConfirmedTransaction creates a Transaction from a transaction of the history db
+func (vs *Visor) newConfirmedTransaction(tx *dbutil.Tx, headBkSeq uint64, txn historydb.Transaction) (Transaction, error) {
+	if headBkSeq < txn.BlockSeq {
+		err := errors.New("Transaction block sequence is greater than the head block sequence")
+		logger.Critical().WithError(err).WithFields(logrus.Fields{
+			"headBkSeq":  headBkSeq,
+			"txBlockSeq": txn.BlockSeq,
+		}).Error()
+		return Transaction{}, err
+	}
+	h := headBkSeq - txn.BlockSeq + 1
+
+	bk, err := vs.Blockchain.GetSignedBlockBySeq(tx, txn.BlockSeq)
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	if bk == nil {
+		return Transaction{}, fmt.Errorf("block seq=%d doesn't exist", txn.BlockSeq)
+	}
+
+	return Transaction{
+		Transaction: txn.Txn,
+		Status:      NewConfirmedTransactionStatus(h, txn.BlockSeq),
+		Time:        bk.Time(),
+	}, nil
+}
+
 // traverseTxns traverses transactions in historydb and unconfirmed tx pool in db,
 // returns transactions that can pass the filters.
 func (vs *Visor) traverseTxns(tx *dbutil.Tx, flts []TxFilter) ([]Transaction, error) {
@@ -1632,10 +2160,14 @@ func (vs *Visor) GetAllValidUnconfirmedTxHashes() ([]cipher.SHA256, error) {
 func (vs *Visor) GetSignedBlockByHash(hash cipher.SHA256) (*coin.SignedBlock, error) {
 	var sb *coin.SignedBlock
 
-	if err := vs.DB.View("GetSignedBlockByHash", func(tx *dbutil.Tx) error {
+	if err := vs.viewReplica("GetSignedBlockByHash", func(tx *dbutil.Tx) error {
 		var err error
 		sb, err = vs.Blockchain.GetSignedBlockByHash(tx, hash)
-		return err
+		if err != nil {
+			return err
+		}
+
+		return checkBlockPruned(tx, vs.Blockchain, sb)
 	}); err != nil {
 		return nil, err
 	}
@@ -1647,10 +2179,14 @@ func (vs *Visor) GetSignedBlockByHash(hash cipher.SHA256) (*coin.SignedBlock, er
 func (vs *Visor) GetSignedBlockBySeq(seq uint64) (*coin.SignedBlock, error) {
 	var b *coin.SignedBlock
 
-	if err := vs.DB.View("GetSignedBlockBySeq", func(tx *dbutil.Tx) error {
+	if err := vs.viewReplica("GetSignedBlockBySeq", func(tx *dbutil.Tx) error {
 		var err error
 		b, err = vs.Blockchain.GetSignedBlockBySeq(tx, seq)
-		return err
+		if err != nil {
+			return err
+		}
+
+		return checkBlockPruned(tx, vs.Blockchain, b)
 	}); err != nil {
 		return nil, err
 	}
@@ -1663,7 +2199,7 @@ func (vs *Visor) GetSignedBlockByHashVerbose(hash cipher.SHA256) (*coin.SignedBl
 	var b *coin.SignedBlock
 	var inputs [][]TransactionInput
 
-	if err := vs.DB.View("GetSignedBlockByHashVerbose", func(tx *dbutil.Tx) error {
+	if err := vs.viewReplica("GetSignedBlockByHashVerbose", func(tx *dbutil.Tx) error {
 		var err error
 		b, inputs, err = vs.getBlockVerbose(tx, func(tx *dbutil.Tx) (*coin.SignedBlock, error) {
 			return vs.Blockchain.GetSignedBlockByHash(tx, hash)
@@ -1681,7 +2217,7 @@ func (vs *Visor) GetSignedBlockBySeqVerbose(seq uint64) (*coin.SignedBlock, [][]
 	var b *coin.SignedBlock
 	var inputs [][]TransactionInput
 
-	if err := vs.DB.View("GetSignedBlockBySeqVerbose", func(tx *dbutil.Tx) error {
+	if err := vs.viewReplica("GetSignedBlockBySeqVerbose", func(tx *dbutil.Tx) error {
 		var err error
 		b, inputs, err = vs.getBlockVerbose(tx, func(tx *dbutil.Tx) (*coin.SignedBlock, error) {
 			return vs.Blockchain.GetSignedBlockBySeq(tx, seq)
@@ -1694,12 +2230,65 @@ func (vs *Visor) GetSignedBlockBySeqVerbose(seq uint64) (*coin.SignedBlock, [][]
 	return b, inputs, nil
 }
 
+// GetSignedBlockByTime returns the last block whose time is at or before t, nil if there is no such block
+func (vs *Visor) GetSignedBlockByTime(t uint64) (*coin.SignedBlock, error) {
+	var b *coin.SignedBlock
+
+	if err := vs.viewReplica("GetSignedBlockByTime", func(tx *dbutil.Tx) error {
+		var err error
+		b, err = vs.getSignedBlockByTime(tx, t)
+		if err != nil {
+			return err
+		}
+
+		return checkBlockPruned(tx, vs.Blockchain, b)
+	}); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// GetSignedBlockByTimeVerbose returns the last block whose time is at or before t and its transactions' input data,
+// nil if there is no such block
+func (vs *Visor) GetSignedBlockByTimeVerbose(t uint64) (*coin.SignedBlock, [][]TransactionInput, error) {
+	var b *coin.SignedBlock
+	var inputs [][]TransactionInput
+
+	if err := vs.viewReplica("GetSignedBlockByTimeVerbose", func(tx *dbutil.Tx) error {
+		var err error
+		b, inputs, err = vs.getBlockVerbose(tx, func(tx *dbutil.Tx) (*coin.SignedBlock, error) {
+			return vs.getSignedBlockByTime(tx, t)
+		})
+		return err
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	return b, inputs, nil
+}
+
+func (vs *Visor) getSignedBlockByTime(tx *dbutil.Tx, t uint64) (*coin.SignedBlock, error) {
+	seq, ok, err := vs.Blockchain.GetBlockSeqByTime(tx, t)
+	if err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, nil
+	}
+
+	return vs.Blockchain.GetSignedBlockBySeq(tx, seq)
+}
+
 func (vs *Visor) getBlockVerbose(tx *dbutil.Tx, getBlock func(*dbutil.Tx) (*coin.SignedBlock, error)) (*coin.SignedBlock, [][]TransactionInput, error) {
 	b, err := getBlock(tx)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	if err := checkBlockPruned(tx, vs.Blockchain, b); err != nil {
+		return nil, nil, err
+	}
+
 	if b == nil {
 		return nil, nil, nil
 	}
@@ -1817,11 +2406,25 @@ func (vs Visor) GetHeadBlockTime() (uint64, error) {
 	return t, nil
 }
 
+// GetAddressMeta returns the activity summary of an address, nil if the address was never used
+func (vs Visor) GetAddressMeta(addr cipher.Address) (*historydb.AddressMeta, error) {
+	var meta *historydb.AddressMeta
+	if err := vs.viewReplica("GetAddressMeta", func(tx *dbutil.Tx) error {
+		var err error
+		meta, err = vs.history.GetAddressMeta(tx, addr)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return meta, nil
+}
+
 // GetUxOutByID gets UxOut by hash id.
 func (vs Visor) GetUxOutByID(id cipher.SHA256) (*historydb.UxOut, error) {
 	var outs []historydb.UxOut
 
-	if err := vs.DB.View("GetUxOutByID", func(tx *dbutil.Tx) error {
+	if err := vs.viewReplica("GetUxOutByID", func(tx *dbutil.Tx) error {
 		var err error
 		outs, err = vs.history.GetUxOuts(tx, []cipher.SHA256{id})
 		return err
@@ -1836,6 +2439,21 @@ func (vs Visor) GetUxOutByID(id cipher.SHA256) (*historydb.UxOut, error) {
 	return &outs[0], nil
 }
 
+// GetUxOutSpender returns the transaction input which spent an output, nil if the output is unspent or not found
+func (vs Visor) GetUxOutSpender(id cipher.SHA256) (*historydb.UxOutSpender, error) {
+	var spender *historydb.UxOutSpender
+
+	if err := vs.viewReplica("GetUxOutSpender", func(tx *dbutil.Tx) error {
+		var err error
+		spender, err = vs.history.GetUxOutSpender(tx, id)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return spender, nil
+}
+
 // GetSpentOutputsForAddresses gets all the spent outputs of a set of addresses
 func (vs Visor) GetSpentOutputsForAddresses(addresses []cipher.Address) ([][]historydb.UxOut, error) {
 	out := make([][]historydb.UxOut, len(addresses))
@@ -2241,7 +2859,7 @@ func (vs *Visor) VerifyTxnVerbose(txn *coin.Transaction) ([]wallet.UxBalance, bo
 // AddressCount returns the total number of addresses with unspents
 func (vs *Visor) AddressCount() (uint64, error) {
 	var count uint64
-	if err := vs.DB.View("AddressCount", func(tx *dbutil.Tx) error {
+	if err := vs.viewReplica("AddressCount", func(tx *dbutil.Tx) error {
 		var err error
 		count, err = vs.Blockchain.Unspent().AddressCount(tx)
 		return err
@@ -2373,7 +2991,7 @@ func (vs *Visor) GetVerboseTransactionsForAddress(a cipher.Address) ([]Transacti
 	var txns []Transaction
 	var inputs [][]TransactionInput
 
-	if err := vs.DB.View("GetVerboseTransactionsForAddress", func(tx *dbutil.Tx) error {
+	if err := vs.viewReplica("GetVerboseTransactionsForAddress", func(tx *dbutil.Tx) error {
 		addrTxns, err := vs.getTransactionsForAddresses(tx, []cipher.Address{a})
 		if err != nil {
 			logger.Errorf("GetVerboseTransactionsForAddress: vs.GetTransactionsForAddress failed: %v", err)
@@ -2385,63 +3003,116 @@ func (vs *Visor) GetVerboseTransactionsForAddress(a cipher.Address) ([]Transacti
 			return nil
 		}
 
-		head, err := vs.Blockchain.Head(tx)
+		inputs, err = vs.getVerboseTransactionInputs(tx, txns)
+		return err
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	return txns, inputs, nil
+}
+
+// GetVerboseTransactionsForAddressPage returns a page of the confirmed transactions of an address
+// with verbose transaction input data, in block seq order.
+// The page starts from the cursor, or from the first transaction if the cursor is nil, skipping the first offset transactions.
+// At most limit transactions are returned, all of them if limit is 0.
+// The returned cursor is the start of the next page, nil if there are no more transactions.
+func (vs *Visor) GetVerboseTransactionsForAddressPage(a cipher.Address, cursor *historydb.AddressTxnsCursor, offset, limit uint64) ([]Transaction, [][]TransactionInput, *historydb.AddressTxnsCursor, error) {
+	var txns []Transaction
+	var inputs [][]TransactionInput
+	var next *historydb.AddressTxnsCursor
+
+	if err := vs.viewReplica("GetVerboseTransactionsForAddressPage", func(tx *dbutil.Tx) error {
+		headBkSeq, ok, err := vs.Blockchain.HeadSeq(tx)
 		if err != nil {
-			logger.Errorf("GetVerboseTransactionsForAddress: vs.Blockchain.Head failed: %v", err)
 			return err
 		}
+		if !ok {
+			return errors.New("No head block seq")
+		}
 
-		inputs = make([][]TransactionInput, len(txns))
+		var addrTxns []historydb.Transaction
+		addrTxns, next, err = vs.history.GetTransactionsForAddressPage(tx, a, cursor, offset, limit)
+		if err != nil {
+			logger.Errorf("GetVerboseTransactionsForAddressPage: vs.history.GetTransactionsForAddressPage failed: %v", err)
+			return err
+		}
 
-		for i, txn := range txns {
-			// If the txn is confirmed, use the time of the block previous
-			// to the block in which the transaction was executed,
-			// else use the head time for unconfirmed blocks.
-			t := head.Time()
-			if txn.Status.Confirmed && txn.Status.BlockSeq > 0 {
-				prevBlock, err := vs.Blockchain.GetSignedBlockBySeq(tx, txn.Status.BlockSeq-1)
-				if err != nil {
-					return err
-				}
+		txns = make([]Transaction, len(addrTxns))
+		for i, txn := range addrTxns {
+			txns[i], err = vs.newConfirmedTransaction(tx, headBkSeq, txn)
+			if err != nil {
+				return err
+			}
+		}
 
-				if prevBlock == nil {
-					return fmt.Errorf("GetVerboseTransactionsForAddress prevBlock seq=%d missing", txn.Status.BlockSeq-1)
-				}
+		inputs, err = vs.getVerboseTransactionInputs(tx, txns)
+		return err
+	}); err != nil {
+		return nil, nil, nil, err
+	}
+
+	return txns, inputs, next, nil
+}
+
+// getVerboseTransactionInputs returns the verbose input data of transactions
+func (vs *Visor) getVerboseTransactionInputs(tx *dbutil.Tx, txns []Transaction) ([][]TransactionInput, error) {
+	if len(txns) == 0 {
+		return nil, nil
+	}
+
+	head, err := vs.Blockchain.Head(tx)
+	if err != nil {
+		logger.Errorf("getVerboseTransactionInputs: vs.Blockchain.Head failed: %v", err)
+		return nil, err
+	}
+
+	inputs := make([][]TransactionInput, len(txns))
 
-				t = prevBlock.Block.Head.Time
+	for i, txn := range txns {
+		// If the txn is confirmed, use the time of the block previous
+		// to the block in which the transaction was executed,
+		// else use the head time for unconfirmed blocks.
+		t := head.Time()
+		if txn.Status.Confirmed && txn.Status.BlockSeq > 0 {
+			prevBlock, err := vs.Blockchain.GetSignedBlockBySeq(tx, txn.Status.BlockSeq-1)
+			if err != nil {
+				return nil, err
 			}
 
-			txnInputs := make([]TransactionInput, len(txn.Transaction.In))
-			for j, inputID := range txn.Transaction.In {
-				uxOuts, err := vs.history.GetUxOuts(tx, []cipher.SHA256{inputID})
-				if err != nil {
-					logger.Errorf("GetVerboseTransactionsForAddress: vs.history.GetUxOuts failed: %v", err)
-					return err
-				}
-				if len(uxOuts) == 0 {
-					err := fmt.Errorf("uxout of %v does not exist in history db", inputID.Hex())
-					logger.Critical().Error(err)
-					return err
-				}
+			if prevBlock == nil {
+				return nil, fmt.Errorf("getVerboseTransactionInputs prevBlock seq=%d missing", txn.Status.BlockSeq-1)
+			}
 
-				input, err := NewTransactionInput(uxOuts[0].Out, t)
-				if err != nil {
-					logger.Errorf("GetVerboseTransactionsForAddress: NewTransactionInput failed: %v", err)
-					return err
-				}
+			t = prevBlock.Block.Head.Time
+		}
 
-				txnInputs[j] = input
+		txnInputs := make([]TransactionInput, len(txn.Transaction.In))
+		for j, inputID := range txn.Transaction.In {
+			uxOuts, err := vs.history.GetUxOuts(tx, []cipher.SHA256{inputID})
+			if err != nil {
+				logger.Errorf("getVerboseTransactionInputs: vs.history.GetUxOuts failed: %v", err)
+				return nil, err
+			}
+			if len(uxOuts) == 0 {
+				err := fmt.Errorf("uxout of %v does not exist in history db", inputID.Hex())
+				logger.Critical().Error(err)
+				return nil, err
 			}
 
-			inputs[i] = txnInputs
+			input, err := NewTransactionInput(uxOuts[0].Out, t)
+			if err != nil {
+				logger.Errorf("getVerboseTransactionInputs: NewTransactionInput failed: %v", err)
+				return nil, err
+			}
+
+			txnInputs[j] = input
 		}
 
-		return nil
-	}); err != nil {
-		return nil, nil, err
+		inputs[i] = txnInputs
 	}
 
-	return txns, inputs, nil
+	return inputs, nil
 }
 
 // OutputsFilter used as optional arguments in GetUnspentOutputs method
@@ -2,6 +2,7 @@ package visor
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -150,7 +151,7 @@ func TestErrMissingSignatureRecreateDB(t *testing.T) {
 			return bc.VerifySignature(b)
 		}
 
-		err = bc.WalkChain(BlockchainVerifyTheadNum, f, nil)
+		err = bc.WalkChain(context.Background(), BlockchainVerifyTheadNum, f)
 
 		require.Error(t, err)
 		require.IsType(t, blockdb.ErrMissingSignature{}, err)
@@ -164,17 +165,24 @@ func TestErrMissingSignatureRecreateDB(t *testing.T) {
 	require.NotEmpty(t, badDB.Path())
 	t.Logf("badDB.Path() == %s", badDB.Path())
 
-	db, err := ResetCorruptDB(badDB, pubkey, nil)
+	db, err := ResetCorruptDB(context.Background(), badDB, CheckDatabaseConfig{Pubkey: pubkey, FullVerify: true})
 	require.NoError(t, err)
 
+	// The reset is recorded in the integrity report of the new db
+	report, err := GetDBIntegrityReport(db)
+	require.NoError(t, err)
+	require.Len(t, report.Actions, 1)
+	require.Contains(t, report.Actions[0].Description, "Reset the corrupted database")
+
 	err = db.Close()
 	require.NoError(t, err)
 
 	require.NotNil(t, db)
 
-	// A corrupted database file should exist
+	// A corrupted database file and its forensic bundle should exist
 	corruptFiles = findCorruptDBFiles(t, badDBFile)
-	require.Len(t, corruptFiles, 1)
+	require.Len(t, corruptFiles, 2)
+	require.Equal(t, DBForensicsPath(corruptFiles[0]), corruptFiles[1])
 
 	// A new db should be written in place of the old bad db, and not be corrupted
 	t.Logf("Checking that the new db file is valid")
@@ -246,7 +254,7 @@ func TestHistorydbVerifier(t *testing.T) {
 				return history.Verify(tx, b, indexesMap)
 			}
 
-			err = bc.WalkChain(2, f, nil)
+			err = bc.WalkChain(context.Background(), 2, f)
 			if tc.expectErr == nil {
 				require.Nil(t, err)
 				return
@@ -3183,3 +3191,77 @@ func TestFbyHashes(t *testing.T) {
 		require.Equal(t, outs, tt.want)
 	}
 }
+
+func TestGetSignedBlockByTime(t *testing.T) {
+	b := &coin.SignedBlock{
+		Block: coin.Block{
+			Head: coin.BlockHeader{
+				BkSeq: 3,
+				Time:  1000,
+			},
+		},
+	}
+
+	cases := []struct {
+		name      string
+		time      uint64
+		seq       uint64
+		found     bool
+		pruned    bool
+		expect    *coin.SignedBlock
+		expectErr error
+	}{
+		{
+			name: "no block at or before time",
+			time: 10,
+		},
+		{
+			name:   "block at or before time",
+			time:   1500,
+			seq:    3,
+			found:  true,
+			expect: b,
+		},
+		{
+			name:      "block is pruned",
+			time:      1500,
+			seq:       3,
+			found:     true,
+			pruned:    true,
+			expectErr: blockdb.NewErrBlockPruned(3),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, shutdown := prepareDB(t)
+			defer shutdown()
+
+			matchTxn := mock.MatchedBy(func(tx *dbutil.Tx) bool {
+				return true
+			})
+
+			bc := &MockBlockchainer{}
+			bc.On("GetBlockSeqByTime", matchTxn, tc.time).Return(tc.seq, tc.found, nil)
+			if tc.found {
+				bc.On("GetSignedBlockBySeq", matchTxn, tc.seq).Return(b, nil)
+				prunedSeq := uint64(0)
+				if tc.pruned {
+					prunedSeq = tc.seq
+				}
+				bc.On("PrunedSeq", matchTxn).Return(prunedSeq, nil)
+			}
+
+			v := &Visor{
+				DB:         db,
+				Blockchain: bc,
+			}
+
+			sb, err := v.GetSignedBlockByTime(tc.time)
+			require.Equal(t, tc.expectErr, err)
+			if err == nil {
+				require.Equal(t, tc.expect, sb)
+			}
+		})
+	}
+}
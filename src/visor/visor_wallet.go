@@ -3,6 +3,8 @@ package visor
 // This file contains Visor method that require wallet access
 
 import (
+	"time"
+
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/coin"
 	"github.com/skycoin/skycoin/src/params"
@@ -12,6 +14,21 @@ import (
 
 // GetWalletBalance returns balance pairs of specific wallet
 func (vs *Visor) GetWalletBalance(wltID string) (wallet.BalancePair, wallet.AddressBalances, error) {
+	return vs.getWalletBalance(wltID, func(w *wallet.Wallet) ([]cipher.Address, error) {
+		return w.GetSkycoinAddresses()
+	})
+}
+
+// GetWalletAccountBalance returns balance pairs of the addresses of an account of a wallet.
+// An empty account name is the default account.
+func (vs *Visor) GetWalletAccountBalance(wltID, account string) (wallet.BalancePair, wallet.AddressBalances, error) {
+	return vs.getWalletBalance(wltID, func(w *wallet.Wallet) ([]cipher.Address, error) {
+		return w.AccountAddresses(account)
+	})
+}
+
+// getWalletBalance returns balance pairs of the addresses of a wallet returned by getAddrs
+func (vs *Visor) getWalletBalance(wltID string, getAddrs func(*wallet.Wallet) ([]cipher.Address, error)) (wallet.BalancePair, wallet.AddressBalances, error) {
 	var addressBalances wallet.AddressBalances
 	var walletBalance wallet.BalancePair
 	var addrsBalanceList []wallet.BalancePair
@@ -19,7 +36,7 @@ func (vs *Visor) GetWalletBalance(wltID string) (wallet.BalancePair, wallet.Addr
 
 	if err := vs.Wallets.View(wltID, func(w *wallet.Wallet) error {
 		var err error
-		addrs, err = w.GetSkycoinAddresses()
+		addrs, err = getAddrs(w)
 		if err != nil {
 			return err
 		}
@@ -102,18 +119,35 @@ func (vs *Visor) GetWalletUnconfirmedTransactionsVerbose(wltID string) ([]Unconf
 	return txns, inputs, nil
 }
 
-// CreateTransaction creates a transaction based upon the parameters in wallet.CreateTransactionParams
+// CreateTransaction creates a transaction based upon the parameters in wallet.CreateTransactionParams.
+// If p.Unsigned is set, the transaction is not signed and the wallet's secrets are not needed.
+// The transaction must comply with the spending policy of the wallet, see wallet.Service.EnforceSpendingPolicy
 func (vs *Visor) CreateTransaction(p wallet.CreateTransactionParams) (*coin.Transaction, []wallet.UxBalance, error) {
 	if err := p.Validate(); err != nil {
 		return nil, nil, err
 	}
 
+	if p.Signer != nil {
+		return vs.createSignerTransaction(p)
+	}
+
+	if err := vs.prepareChangeAddress(p); err != nil {
+		return nil, nil, err
+	}
+
 	var txn *coin.Transaction
 	var inputs []wallet.UxBalance
 
-	if err := vs.Wallets.ViewSecrets(p.Wallet.ID, p.Wallet.Password, func(w *wallet.Wallet) error {
-		// Get all addresses from the wallet for checking p against
-		allAddrs, err := w.GetSkycoinAddresses()
+	view := func(f func(*wallet.Wallet) error) error {
+		if p.Unsigned {
+			return vs.Wallets.View(p.Wallet.ID, f)
+		}
+		return vs.Wallets.ViewSecrets(p.Wallet.ID, p.Wallet.Password, f)
+	}
+
+	if err := view(func(w *wallet.Wallet) error {
+		// Get all addresses that p can spend from for checking p against
+		allAddrs, err := spendableAddresses(w, p)
 		if err != nil {
 			return err
 		}
@@ -137,21 +171,178 @@ func (vs *Visor) CreateTransaction(p wallet.CreateTransactionParams) (*coin.Tran
 				return err
 			}
 
-			// The wallet can create transactions that would not pass all validation, such as the decimal restriction,
-			// because the wallet is not aware of visor-level constraints.
-			// Check that the transaction is valid before returning it to the caller.
-			if err := VerifySingleTxnUserConstraints(*txn); err != nil {
-				logger.WithError(err).Error("Created transaction violates transaction constraints")
+			return vs.verifyCreatedTxn(tx, *txn, head, p.Unsigned)
+		})
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	if err := vs.enforceSpendingPolicy(p, []coin.Transaction{*txn}); err != nil {
+		return nil, nil, err
+	}
+
+	return txn, inputs, nil
+}
+
+// enforceSpendingPolicy checks that transactions created with p comply with the spending policy of the wallet.
+// The spends of signed transactions are recorded. The spends of unsigned transactions are recorded
+// when they are signed by the wallet, see wallet.Service.SignPartialTransaction.
+func (vs *Visor) enforceSpendingPolicy(p wallet.CreateTransactionParams, txns []coin.Transaction) error {
+	if p.Unsigned {
+		return vs.Wallets.CheckSpendingPolicy(p.Wallet.ID, txns, time.Now())
+	}
+	return vs.Wallets.EnforceSpendingPolicy(p.Wallet.ID, txns, time.Now())
+}
+
+// spendableAddresses returns the addresses of the wallet that p can spend from,
+// which are the addresses of p.Wallet.Account if it is set
+func spendableAddresses(w *wallet.Wallet, p wallet.CreateTransactionParams) ([]cipher.Address, error) {
+	if p.Wallet.Account == "" {
+		return w.GetSkycoinAddresses()
+	}
+	return w.AccountAddresses(p.Wallet.Account)
+}
+
+// prepareChangeAddress refreshes the address pool of the wallet if the change goes to a new address,
+// so that the addresses seen on the blockchain are not used for the change.
+// If the transaction is signed and the account spent from has no unused address left, an address is added for the change.
+func (vs *Visor) prepareChangeAddress(p wallet.CreateTransactionParams) error {
+	if p.ChangePolicy != wallet.ChangePolicyNewAddress {
+		return nil
+	}
+
+	if err := vs.RefreshAddressPool(p.Wallet.ID); err != nil {
+		return err
+	}
+
+	if p.Unsigned {
+		return nil
+	}
+
+	_, err := vs.Wallets.FillAddressPool(p.Wallet.ID, p.Wallet.Account, p.Wallet.Password, 1)
+	return err
+}
+
+// CreatePayoutBatch creates the transactions that pay the outputs of p.To, which can be more than fit in a transaction,
+// in transactions of at most maxOutputs payouts (unlimited if 0). See wallet.Wallet.CreatePayoutBatch.
+// If p.Unsigned is set, the transactions are not signed and the wallet's secrets are not needed.
+// The transactions must comply with the spending policy of the wallet, see wallet.Service.EnforceSpendingPolicy
+func (vs *Visor) CreatePayoutBatch(p wallet.CreateTransactionParams, maxOutputs int) (*wallet.PayoutBatch, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := vs.prepareChangeAddress(p); err != nil {
+		return nil, err
+	}
+
+	var batch *wallet.PayoutBatch
+
+	view := func(f func(*wallet.Wallet) error) error {
+		if p.Unsigned {
+			return vs.Wallets.View(p.Wallet.ID, f)
+		}
+		return vs.Wallets.ViewSecrets(p.Wallet.ID, p.Wallet.Password, f)
+	}
+
+	if err := view(func(w *wallet.Wallet) error {
+		allAddrs, err := spendableAddresses(w, p)
+		if err != nil {
+			return err
+		}
+
+		return vs.DB.View("CreatePayoutBatch", func(tx *dbutil.Tx) error {
+			head, err := vs.Blockchain.Head(tx)
+			if err != nil {
+				logger.WithError(err).Error("Blockchain.Head failed")
 				return err
 			}
 
-			if err := vs.Blockchain.VerifySingleTxnSoftHardConstraints(tx, *txn, params.UserMaxTransactionSize, params.UserBurnFactor); err != nil {
-				logger.WithError(err).Error("Created transaction violates transaction constraints")
+			auxs, err := vs.getCreateTransactionAuxs(tx, p, allAddrs)
+			if err != nil {
+				return err
+			}
+
+			batch, err = w.CreatePayoutBatch(p, maxOutputs, auxs, head.Time())
+			if err != nil {
+				logger.WithError(err).Error("CreatePayoutBatch failed")
 				return err
 			}
 
+			for _, bt := range batch.Transactions {
+				if err := vs.verifyCreatedTxn(tx, *bt.Transaction, head, p.Unsigned); err != nil {
+					return err
+				}
+			}
+
 			return nil
 		})
+	}); err != nil {
+		return nil, err
+	}
+
+	txns := make([]coin.Transaction, len(batch.Transactions))
+	for i, bt := range batch.Transactions {
+		txns[i] = *bt.Transaction
+	}
+
+	if err := vs.enforceSpendingPolicy(p, txns); err != nil {
+		return nil, err
+	}
+
+	return batch, nil
+}
+
+// CreateHoursRedistribution creates a transaction that redistributes the coin hours of a wallet's addresses
+// between them, without moving coins. See wallet.Wallet.CreateHoursRedistribution.
+// The transaction only sends coins back to the addresses spent from, so it is not subject to the spending policy of the wallet.
+func (vs *Visor) CreateHoursRedistribution(p wallet.HoursRedistributionParams) (*coin.Transaction, []wallet.UxBalance, error) {
+	if err := p.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	// The unspent outputs are selected like for a transaction created from the same wallet parameters
+	cp := wallet.CreateTransactionParams{
+		Wallet:            p.Wallet,
+		IgnoreUnconfirmed: p.IgnoreUnconfirmed,
+	}
+
+	var txn *coin.Transaction
+	var inputs []wallet.UxBalance
+
+	view := func(f func(*wallet.Wallet) error) error {
+		if p.Unsigned {
+			return vs.Wallets.View(p.Wallet.ID, f)
+		}
+		return vs.Wallets.ViewSecrets(p.Wallet.ID, p.Wallet.Password, f)
+	}
+
+	if err := view(func(w *wallet.Wallet) error {
+		allAddrs, err := spendableAddresses(w, cp)
+		if err != nil {
+			return err
+		}
+
+		return vs.DB.View("CreateHoursRedistribution", func(tx *dbutil.Tx) error {
+			head, err := vs.Blockchain.Head(tx)
+			if err != nil {
+				logger.WithError(err).Error("Blockchain.Head failed")
+				return err
+			}
+
+			auxs, err := vs.getCreateTransactionAuxs(tx, cp, allAddrs)
+			if err != nil {
+				return err
+			}
+
+			txn, inputs, err = w.CreateHoursRedistribution(p, auxs, head.Time())
+			if err != nil {
+				logger.WithError(err).Error("CreateHoursRedistribution failed")
+				return err
+			}
+
+			return vs.verifyCreatedTxn(tx, *txn, head, p.Unsigned)
+		})
 	}); err != nil {
 		return nil, nil, err
 	}
@@ -159,6 +350,88 @@ func (vs *Visor) CreateTransaction(p wallet.CreateTransactionParams) (*coin.Tran
 	return txn, inputs, nil
 }
 
+// verifyCreatedTxn checks that a transaction created by a wallet is valid.
+// The wallet can create transactions that would not pass all validation, such as the decimal restriction,
+// because the wallet is not aware of visor-level constraints.
+func (vs *Visor) verifyCreatedTxn(tx *dbutil.Tx, txn coin.Transaction, head *coin.SignedBlock, unsigned bool) error {
+	if err := VerifySingleTxnUserConstraints(txn); err != nil {
+		logger.WithError(err).Error("Created transaction violates transaction constraints")
+		return err
+	}
+
+	if unsigned {
+		return vs.verifyUnsignedTxn(tx, txn, head)
+	}
+
+	if err := vs.Blockchain.VerifySingleTxnSoftHardConstraints(tx, txn, params.UserMaxTransactionSize, params.UserBurnFactor); err != nil {
+		logger.WithError(err).Error("Created transaction violates transaction constraints")
+		return err
+	}
+
+	return nil
+}
+
+// createSignerTransaction creates an unsigned transaction and signs it with p.Signer.
+// The signer can be slow, such as a hardware device waiting for the user's confirmation,
+// so the transaction is signed outside of the database transaction.
+func (vs *Visor) createSignerTransaction(p wallet.CreateTransactionParams) (*coin.Transaction, []wallet.UxBalance, error) {
+	signer := p.Signer
+	p.Signer = nil
+	p.Unsigned = true
+
+	txn, inputs, err := vs.CreateTransaction(p)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ptx, err := wallet.NewPartialTransaction(*txn, inputs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if _, err := signer.SignPartialTransaction(ptx); err != nil {
+		return nil, nil, err
+	}
+
+	if !ptx.IsFullySigned() {
+		return nil, nil, wallet.ErrSignerNotFullySigned
+	}
+
+	if err := vs.DB.View("CreateTransaction", func(tx *dbutil.Tx) error {
+		if err := vs.Blockchain.VerifySingleTxnSoftHardConstraints(tx, ptx.Transaction, params.UserMaxTransactionSize, params.UserBurnFactor); err != nil {
+			logger.WithError(err).Error("Signed transaction violates transaction constraints")
+			return err
+		}
+
+		return nil
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	return &ptx.Transaction, inputs, nil
+}
+
+// verifyUnsignedTxn checks that an unsigned transaction does not violate hard or soft constraints,
+// apart from its missing signatures
+func (vs *Visor) verifyUnsignedTxn(tx *dbutil.Tx, txn coin.Transaction, head *coin.SignedBlock) error {
+	uxIn, err := vs.Blockchain.Unspent().GetArray(tx, txn.In)
+	if err != nil {
+		return NewErrTxnViolatesHardConstraint(err)
+	}
+
+	if err := VerifySingleTxnHardConstraintsUnsigned(txn, head.Head, uxIn); err != nil {
+		logger.WithError(err).Error("Created transaction violates transaction constraints")
+		return err
+	}
+
+	if err := VerifySingleTxnSoftConstraints(txn, head.Time(), uxIn, params.UserMaxTransactionSize, params.UserBurnFactor); err != nil {
+		logger.WithError(err).Error("Created transaction violates transaction constraints")
+		return err
+	}
+
+	return nil
+}
+
 // CreateTransactionDeprecated creates a transaction using an entire wallet,
 // specifying only coins and one destination.
 func (vs *Visor) CreateTransactionDeprecated(wltID string, password []byte, coins uint64, dest cipher.Address) (*coin.Transaction, error) {
@@ -213,5 +486,84 @@ func (vs *Visor) CreateTransactionDeprecated(wltID string, password []byte, coin
 		return nil, err
 	}
 
+	if err := vs.Wallets.EnforceSpendingPolicy(wltID, []coin.Transaction{*txn}, time.Now()); err != nil {
+		return nil, err
+	}
+
 	return txn, nil
 }
+
+// SweepPrivateKeys creates the transactions that send all the coins and coin hours of the private keys
+// to dest. If wltID is not empty, dest must be an address of the wallet, and defaults to its first address.
+// The unspent outputs that are being spent by unconfirmed transactions are not swept.
+func (vs *Visor) SweepPrivateKeys(keys []cipher.SecKey, wltID string, dest cipher.Address) (*wallet.Sweep, error) {
+	sk, err := wallet.NewSweepKeys(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	if wltID != "" {
+		if err := vs.Wallets.View(wltID, func(w *wallet.Wallet) error {
+			addrs, err := w.GetSkycoinAddresses()
+			if err != nil {
+				return err
+			}
+
+			if len(addrs) == 0 {
+				return wallet.ErrUnknownAddress
+			}
+
+			if dest.Null() {
+				dest = addrs[0]
+				return nil
+			}
+
+			for _, a := range addrs {
+				if a == dest {
+					return nil
+				}
+			}
+
+			return wallet.ErrUnknownAddress
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	var sweep *wallet.Sweep
+	if err := vs.DB.View("SweepPrivateKeys", func(tx *dbutil.Tx) error {
+		head, err := vs.Blockchain.Head(tx)
+		if err != nil {
+			logger.WithError(err).Error("Blockchain.Head failed")
+			return err
+		}
+
+		auxs, err := vs.getUnspentsForSpending(tx, sk.Addresses(), true)
+		if err != nil {
+			logger.WithError(err).Error("getUnspentsForSpending failed")
+			return err
+		}
+
+		uxb, err := wallet.NewUxBalances(head.Time(), auxs.Flatten())
+		if err != nil {
+			return err
+		}
+
+		sweep, err = sk.CreateSweep(uxb, dest, params.UserMaxTransactionSize)
+		if err != nil {
+			return err
+		}
+
+		for _, st := range sweep.Transactions {
+			if err := vs.verifyCreatedTxn(tx, *st.Transaction, head, false); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return sweep, nil
+}
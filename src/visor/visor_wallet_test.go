@@ -0,0 +1,115 @@
+package visor
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+func TestVisorEnforceSpendingPolicyUnsigned(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spending-policy")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	wallets, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      wallet.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	w, err := wallets.CreateWallet("t.wlt", wallet.Options{
+		Seed:      "seed",
+		GenerateN: 1,
+	}, nil)
+	require.NoError(t, err)
+
+	_, err = wallets.SetSpendingPolicy("t.wlt", nil, wallet.SpendingPolicy{
+		DailyLimitCoins: 3e6,
+	})
+	require.NoError(t, err)
+
+	v := &Visor{
+		Wallets: wallets,
+	}
+
+	// makePartialTransaction creates an unsigned transaction sending coins from the wallet to another address
+	makePartialTransaction := func(coins uint64) *wallet.PartialTransaction {
+		ux := coin.UxOut{
+			Head: coin.UxHead{
+				Time: uint64(time.Now().Unix()),
+			},
+			Body: coin.UxBody{
+				SrcTransaction: testutil.RandSHA256(t),
+				Address:        w.Entries[0].SkycoinAddress(),
+				Coins:          coins,
+				Hours:          10,
+			},
+		}
+
+		var txn coin.Transaction
+		txn.PushInput(ux.Hash())
+		txn.PushOutput(testutil.MakeAddress(), coins, 1)
+		txn.Sigs = make([]cipher.Sig, len(txn.In))
+		require.NoError(t, txn.UpdateHeader())
+
+		inputs, err := wallet.NewUxBalances(ux.Head.Time, coin.UxArray{ux})
+		require.NoError(t, err)
+		ptx, err := wallet.NewPartialTransaction(txn, inputs)
+		require.NoError(t, err)
+		return ptx
+	}
+
+	unsigned := wallet.CreateTransactionParams{
+		Unsigned: true,
+		Wallet: wallet.CreateTransactionWalletParams{
+			ID: "t.wlt",
+		},
+	}
+
+	spends := func() []wallet.Spend {
+		w, err := wallets.GetWallet("t.wlt")
+		require.NoError(t, err)
+		return w.Spends
+	}
+
+	// The spend of a transaction created unsigned is not recorded
+	ptx := makePartialTransaction(2e6)
+	require.NoError(t, v.enforceSpendingPolicy(unsigned, []coin.Transaction{ptx.Transaction}))
+	require.Empty(t, spends())
+
+	// The policy is still checked
+	err = v.enforceSpendingPolicy(unsigned, []coin.Transaction{makePartialTransaction(3e6 + 1).Transaction})
+	require.Equal(t, wallet.ErrSpendingPolicyDailyLimit, err)
+
+	// The spend is recorded when the wallet signs the transaction, and signing it again does not record it twice
+	signed, err := wallets.SignPartialTransaction("t.wlt", nil, ptx)
+	require.NoError(t, err)
+	require.True(t, signed.IsFullySigned())
+	_, err = wallets.SignPartialTransaction("t.wlt", nil, ptx)
+	require.NoError(t, err)
+	require.Len(t, spends(), 1)
+	require.Equal(t, ptx.Transaction.HashInner(), spends()[0].InnerHash)
+
+	// The signed transaction is the same spend
+	p := unsigned
+	p.Unsigned = false
+	require.NoError(t, v.enforceSpendingPolicy(p, []coin.Transaction{signed.Transaction}))
+	require.Len(t, spends(), 1)
+
+	// Another transaction exceeds the daily limit, whether it is created unsigned or signed
+	ptx2 := makePartialTransaction(2e6)
+	err = v.enforceSpendingPolicy(unsigned, []coin.Transaction{ptx2.Transaction})
+	require.Equal(t, wallet.ErrSpendingPolicyDailyLimit, err)
+	_, err = wallets.SignPartialTransaction("t.wlt", nil, ptx2)
+	require.Equal(t, wallet.ErrSpendingPolicyDailyLimit, err)
+	require.Len(t, spends(), 1)
+}
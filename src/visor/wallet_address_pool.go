@@ -0,0 +1,116 @@
+package visor
+
+import (
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+// RefreshAddressPool marks the addresses of a wallet that have been seen on the blockchain as used
+// in its address pool, according to the historydb, so that they are not handed out
+func (vs *Visor) RefreshAddressPool(wltID string) error {
+	var addrs []cipher.Address
+	if err := vs.Wallets.View(wltID, func(w *wallet.Wallet) error {
+		wltAddrs, err := w.GetSkycoinAddresses()
+		if err != nil {
+			return err
+		}
+
+		for _, a := range wltAddrs {
+			if _, ok := w.AddressPool.Used[a]; !ok {
+				addrs = append(addrs, a)
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if len(addrs) == 0 {
+		return nil
+	}
+
+	active, err := vs.GetAddressesActivity(addrs)
+	if err != nil {
+		return err
+	}
+
+	var used []cipher.Address
+	for i, a := range addrs {
+		if active[i] {
+			used = append(used, a)
+		}
+	}
+
+	if len(used) == 0 {
+		return nil
+	}
+
+	_, err = vs.Wallets.MarkAddressesUsed(wltID, used)
+	return err
+}
+
+// WalletAddressPoolStatus refreshes the address pool of a wallet and returns the status of the pool of an account.
+// An empty account name is the default account.
+func (vs *Visor) WalletAddressPoolStatus(wltID, account string) (wallet.AddressPoolStatus, error) {
+	if err := vs.RefreshAddressPool(wltID); err != nil {
+		return wallet.AddressPoolStatus{}, err
+	}
+
+	return vs.walletAddressPoolStatus(wltID, account)
+}
+
+// FillWalletAddressPool refreshes the address pool of a wallet and pre-generates addresses in an account
+// until it has size unused addresses, see wallet.Wallet.FillAddressPool.
+// The password is required to fill the pool of an encrypted wallet.
+// Returns the added addresses and the status of the pool.
+func (vs *Visor) FillWalletAddressPool(wltID, account string, password []byte, size uint64) ([]cipher.Address, wallet.AddressPoolStatus, error) {
+	if err := vs.RefreshAddressPool(wltID); err != nil {
+		return nil, wallet.AddressPoolStatus{}, err
+	}
+
+	addrs, err := vs.Wallets.FillAddressPool(wltID, account, password, size)
+	if err != nil {
+		return nil, wallet.AddressPoolStatus{}, err
+	}
+
+	status, err := vs.walletAddressPoolStatus(wltID, account)
+	if err != nil {
+		return nil, wallet.AddressPoolStatus{}, err
+	}
+
+	return addrs, status, nil
+}
+
+// TakeWalletPoolAddresses refreshes the address pool of a wallet and hands out n unused addresses of an account,
+// see wallet.Wallet.TakePoolAddresses. The password is not needed.
+// Returns the addresses and the status of the pool.
+func (vs *Visor) TakeWalletPoolAddresses(wltID, account string, n uint64) ([]cipher.Address, wallet.AddressPoolStatus, error) {
+	if err := vs.RefreshAddressPool(wltID); err != nil {
+		return nil, wallet.AddressPoolStatus{}, err
+	}
+
+	addrs, err := vs.Wallets.TakePoolAddresses(wltID, account, n)
+	if err != nil {
+		return nil, wallet.AddressPoolStatus{}, err
+	}
+
+	status, err := vs.walletAddressPoolStatus(wltID, account)
+	if err != nil {
+		return nil, wallet.AddressPoolStatus{}, err
+	}
+
+	return addrs, status, nil
+}
+
+func (vs *Visor) walletAddressPoolStatus(wltID, account string) (wallet.AddressPoolStatus, error) {
+	var status wallet.AddressPoolStatus
+	if err := vs.Wallets.View(wltID, func(w *wallet.Wallet) error {
+		var err error
+		status, err = w.AddressPoolStatus(account)
+		return err
+	}); err != nil {
+		return wallet.AddressPoolStatus{}, err
+	}
+
+	return status, nil
+}
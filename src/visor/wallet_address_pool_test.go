@@ -0,0 +1,171 @@
+package visor
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/historydb"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+func TestVisorTakeWalletPoolAddresses(t *testing.T) {
+	db, shutdown := prepareDB(t)
+	defer shutdown()
+
+	dir, err := ioutil.TempDir("", "address-pool")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	wallets, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      wallet.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	w, err := wallets.CreateWallet("t.wlt", wallet.Options{
+		Seed:      "seed",
+		GenerateN: 3,
+	}, nil)
+	require.NoError(t, err)
+	addrs, err := w.GetSkycoinAddresses()
+	require.NoError(t, err)
+
+	matchTxn := mock.MatchedBy(func(tx *dbutil.Tx) bool {
+		return true
+	})
+
+	// The first address has been used on the blockchain
+	history := &MockHistoryer{}
+	history.On("GetAddressMeta", matchTxn, addrs[0]).Return(&historydb.AddressMeta{
+		TxnCount: 1,
+	}, nil)
+	history.On("GetAddressMeta", matchTxn, mock.Anything).Return(nil, nil)
+
+	v := &Visor{
+		DB:      db,
+		history: history,
+		Wallets: wallets,
+	}
+
+	status, err := v.WalletAddressPoolStatus("t.wlt", "")
+	require.NoError(t, err)
+	require.Equal(t, wallet.AddressPoolStatus{
+		Generated: 3,
+		Used:      1,
+		Unused:    2,
+	}, status)
+
+	taken, status, err := v.TakeWalletPoolAddresses("t.wlt", "", 2)
+	require.NoError(t, err)
+	require.Equal(t, addrs[1:], taken)
+	require.Equal(t, wallet.AddressPoolStatus{
+		Generated: 3,
+		Used:      1,
+		Issued:    2,
+	}, status)
+
+	_, _, err = v.TakeWalletPoolAddresses("t.wlt", "", 1)
+	require.Equal(t, wallet.ErrAddressPoolExhausted, err)
+
+	added, status, err := v.FillWalletAddressPool("t.wlt", "", nil, 2)
+	require.NoError(t, err)
+	require.Len(t, added, 2)
+	require.Equal(t, wallet.AddressPoolStatus{
+		Generated: 5,
+		Used:      1,
+		Issued:    2,
+		Unused:    2,
+	}, status)
+}
+
+func TestVisorPrepareChangeAddress(t *testing.T) {
+	db, shutdown := prepareDB(t)
+	defer shutdown()
+
+	dir, err := ioutil.TempDir("", "change-address")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	wallets, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      wallet.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	w, err := wallets.CreateWallet("t.wlt", wallet.Options{
+		Seed: "seed",
+	}, nil)
+	require.NoError(t, err)
+	addrs, err := w.GetSkycoinAddresses()
+	require.NoError(t, err)
+
+	matchTxn := mock.MatchedBy(func(tx *dbutil.Tx) bool {
+		return true
+	})
+
+	// The only address has been used on the blockchain
+	history := &MockHistoryer{}
+	history.On("GetAddressMeta", matchTxn, addrs[0]).Return(&historydb.AddressMeta{
+		TxnCount: 1,
+	}, nil)
+	history.On("GetAddressMeta", matchTxn, mock.Anything).Return(nil, nil)
+
+	v := &Visor{
+		DB:      db,
+		history: history,
+		Wallets: wallets,
+	}
+
+	p := wallet.CreateTransactionParams{
+		Wallet: wallet.CreateTransactionWalletParams{
+			ID: "t.wlt",
+		},
+	}
+
+	// The address pool is not touched by the other change policies
+	require.NoError(t, v.prepareChangeAddress(p))
+	status, err := v.walletAddressPoolStatus("t.wlt", "")
+	require.NoError(t, err)
+	require.Equal(t, wallet.AddressPoolStatus{
+		Generated: 1,
+		Unused:    1,
+	}, status)
+
+	// An unsigned transaction can't add an address for the change
+	p.ChangePolicy = wallet.ChangePolicyNewAddress
+	p.Unsigned = true
+	require.NoError(t, v.prepareChangeAddress(p))
+	status, err = v.walletAddressPoolStatus("t.wlt", "")
+	require.NoError(t, err)
+	require.Equal(t, wallet.AddressPoolStatus{
+		Generated: 1,
+		Used:      1,
+	}, status)
+
+	p.Unsigned = false
+	require.NoError(t, v.prepareChangeAddress(p))
+	status, err = v.walletAddressPoolStatus("t.wlt", "")
+	require.NoError(t, err)
+	require.Equal(t, wallet.AddressPoolStatus{
+		Generated: 2,
+		Used:      1,
+		Unused:    1,
+	}, status)
+
+	// The unused address is reused until it is seen on the blockchain
+	require.NoError(t, v.prepareChangeAddress(p))
+	status, err = v.walletAddressPoolStatus("t.wlt", "")
+	require.NoError(t, err)
+	require.Equal(t, wallet.AddressPoolStatus{
+		Generated: 2,
+		Used:      1,
+		Unused:    1,
+	}, status)
+}
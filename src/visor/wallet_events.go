@@ -0,0 +1,430 @@
+package visor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/encoder"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+var (
+	// WalletEventsBkt holds the incoming funds events of the wallets, by event ID
+	WalletEventsBkt = []byte("wallet_events")
+	// WalletEventIDsBkt indexes the events of each wallet, by the hash of the wallet ID then the event ID
+	WalletEventIDsBkt = []byte("wallet_event_ids")
+)
+
+const (
+	// MaxWalletEvents is the maximum number of wallet events stored, the oldest events are deleted first
+	MaxWalletEvents = 100000
+
+	// walletEventsBufferSize is the number of events buffered for a subscriber.
+	// The events are dropped for a subscriber that does not keep up
+	walletEventsBufferSize = 256
+)
+
+// WalletEventType is the type of a WalletEvent
+type WalletEventType string
+
+const (
+	// WalletEventUnconfirmed is recorded when a transaction paying a wallet is added to the unconfirmed pool
+	WalletEventUnconfirmed WalletEventType = "unconfirmed"
+	// WalletEventConfirmed is recorded when a transaction paying a wallet is executed in a block
+	WalletEventConfirmed WalletEventType = "confirmed"
+)
+
+// WalletEvent records that a transaction sent coins to the addresses of a wallet.
+// The transactions that spend the outputs of the wallet are not incoming funds, their outputs to the wallet are change
+type WalletEvent struct {
+	// ID of the event. IDs increase in the order the events were recorded, for all the wallets
+	ID       uint64
+	Type     WalletEventType
+	WalletID string
+	TxnID    cipher.SHA256
+	// BlockSeq is the seq of the block that executed the transaction, for WalletEventConfirmed events
+	BlockSeq uint64
+	// Time is the time of the block, or the time the transaction was received for WalletEventUnconfirmed events
+	Time uint64
+	// Amount is the number of droplets sent to the addresses of the wallet by the transaction
+	Amount uint64
+	// Addresses are the addresses of the wallet paid by the transaction
+	Addresses []cipher.Address
+	// Confirmations is the number of blocks that confirmed the transaction when the event was read,
+	// 0 for WalletEventUnconfirmed events. It is not stored
+	Confirmations uint64 `enc:"-"`
+}
+
+// walletEvents records the transactions that pay the wallets and publishes them to their subscribers
+type walletEvents struct {
+	sync.Mutex
+	// notifyC is closed and replaced when events are committed
+	notifyC chan struct{}
+	// subscribers are the channels of the subscribers, with the ID of the wallet they subscribed to
+	subscribers map[chan WalletEvent]string
+}
+
+func newWalletEvents() *walletEvents {
+	return &walletEvents{
+		notifyC:     make(chan struct{}),
+		subscribers: make(map[chan WalletEvent]string),
+	}
+}
+
+// recordTxn records an event for each wallet that a transaction pays.
+// addrWallets are the IDs of the wallets of the addresses of the transaction outputs,
+// spendingWallets are the IDs of the wallets of the outputs spent by the transaction.
+func (we *walletEvents) recordTxn(tx *dbutil.Tx, typ WalletEventType, txn *coin.Transaction, addrWallets map[cipher.Address][]string, spendingWallets map[string]struct{}, seq, t uint64) error {
+	txnID := txn.Hash()
+
+	// Sum the coins sent to each wallet, in the order the wallets appear in the outputs
+	var wltIDs []string
+	events := make(map[string]*WalletEvent)
+	for _, o := range txn.Out {
+		for _, id := range addrWallets[o.Address] {
+			if _, ok := spendingWallets[id]; ok {
+				continue
+			}
+
+			e, ok := events[id]
+			if !ok {
+				e = &WalletEvent{
+					Type:     typ,
+					WalletID: id,
+					TxnID:    txnID,
+					BlockSeq: seq,
+					Time:     t,
+				}
+				if typ == WalletEventConfirmed {
+					e.Confirmations = 1
+				}
+				events[id] = e
+				wltIDs = append(wltIDs, id)
+			}
+
+			e.Amount += o.Coins
+			if !containsAddress(e.Addresses, o.Address) {
+				e.Addresses = append(e.Addresses, o.Address)
+			}
+		}
+	}
+
+	if len(wltIDs) == 0 {
+		return nil
+	}
+
+	recorded := make([]WalletEvent, len(wltIDs))
+	for i, id := range wltIDs {
+		if err := we.putEvent(tx, events[id]); err != nil {
+			return err
+		}
+		recorded[i] = *events[id]
+	}
+
+	// The events are published once the transaction is committed
+	tx.OnCommit(func() {
+		we.publish(recorded)
+	})
+
+	return nil
+}
+
+func containsAddress(addrs []cipher.Address, a cipher.Address) bool {
+	for _, b := range addrs {
+		if a == b {
+			return true
+		}
+	}
+	return false
+}
+
+// walletEventsPrefix returns the prefix of the keys of a wallet's events in WalletEventIDsBkt.
+// The wallet ID is hashed so that the prefixes have the same length, and the hash is replaced
+// by its HMAC in an encrypted database, see dbutil.Tx.KeyMAC
+func walletEventsPrefix(tx *dbutil.Tx, wltID string) []byte {
+	h := cipher.SumSHA256([]byte(wltID))
+	return tx.KeyMAC(h[:])
+}
+
+// walletEventKey returns the key of an event in WalletEventIDsBkt
+func walletEventKey(tx *dbutil.Tx, wltID string, id uint64) []byte {
+	prefix := walletEventsPrefix(tx, wltID)
+	k := make([]byte, 0, len(prefix)+8)
+	k = append(k, prefix...)
+	return append(k, dbutil.Itob(id)...)
+}
+
+// putEvent stores an event with the next event ID and deletes the events older than the MaxWalletEvents most recent events
+func (we *walletEvents) putEvent(tx *dbutil.Tx, e *WalletEvent) error {
+	id, err := dbutil.NextSequence(tx, WalletEventsBkt)
+	if err != nil {
+		return err
+	}
+
+	e.ID = id
+	if err := dbutil.PutBucketValue(tx, WalletEventsBkt, dbutil.Itob(id), encoder.Serialize(*e)); err != nil {
+		return err
+	}
+
+	if err := dbutil.PutBucketValue(tx, WalletEventIDsBkt, walletEventKey(tx, e.WalletID, id), []byte{}); err != nil {
+		return err
+	}
+
+	if id <= MaxWalletEvents {
+		return nil
+	}
+
+	// IDs are sequential, so at most one event is older than the retained events
+	var old WalletEvent
+	if ok, err := dbutil.GetBucketObjectDecoded(tx, WalletEventsBkt, dbutil.Itob(id-MaxWalletEvents), &old); err != nil {
+		return err
+	} else if !ok {
+		return nil
+	}
+
+	if err := dbutil.Delete(tx, WalletEventIDsBkt, walletEventKey(tx, old.WalletID, old.ID)); err != nil {
+		return err
+	}
+
+	return dbutil.Delete(tx, WalletEventsBkt, dbutil.Itob(old.ID))
+}
+
+// maybeBuildWalletEventsIndex indexes the events of WalletEventsBkt in WalletEventIDsBkt if the index is empty,
+// for a database that predates the index or whose index was emptied when the database was encrypted
+func maybeBuildWalletEventsIndex(tx *dbutil.Tx) error {
+	if empty, err := dbutil.IsEmpty(tx, WalletEventIDsBkt); err != nil || !empty {
+		return err
+	}
+
+	if empty, err := dbutil.IsEmpty(tx, WalletEventsBkt); err != nil || empty {
+		return err
+	}
+
+	logger.Info("Building the wallet events index")
+
+	return dbutil.ForEach(tx, WalletEventsBkt, func(_, v []byte) error {
+		var e WalletEvent
+		if err := encoder.DeserializeRaw(v, &e); err != nil {
+			return err
+		}
+
+		return dbutil.PutBucketValue(tx, WalletEventIDsBkt, walletEventKey(tx, e.WalletID, e.ID), []byte{})
+	})
+}
+
+// events returns at most limit events of a wallet with an ID greater than after, in ID order.
+// Only the events of the wallet are read, through WalletEventIDsBkt.
+// headSeq is the seq of the head block, to count the confirmations of the transactions.
+// A database opened read-only can predate the wallet events and their index.
+func (we *walletEvents) events(tx *dbutil.Tx, wltID string, after, limit, headSeq uint64) ([]WalletEvent, error) {
+	var events []WalletEvent
+	if limit == 0 || tx.Bucket(WalletEventsBkt) == nil || tx.Bucket(WalletEventIDsBkt) == nil {
+		return events, nil
+	}
+
+	prefix := walletEventsPrefix(tx, wltID)
+	start := walletEventKey(tx, wltID, after+1)
+	if err := dbutil.ForEachPrefix(tx, WalletEventIDsBkt, prefix, start, func(k, _ []byte) (bool, error) {
+		var e WalletEvent
+		if ok, err := dbutil.GetBucketObjectDecoded(tx, WalletEventsBkt, k[len(prefix):], &e); err != nil {
+			return false, err
+		} else if !ok {
+			return false, fmt.Errorf("wallet event %d of the index does not exist", dbutil.Btoi(k[len(prefix):]))
+		}
+
+		if e.Type == WalletEventConfirmed && headSeq >= e.BlockSeq {
+			e.Confirmations = headSeq - e.BlockSeq + 1
+		}
+
+		events = append(events, e)
+		return uint64(len(events)) < limit, nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// wait returns a channel that is closed when events are committed
+func (we *walletEvents) wait() <-chan struct{} {
+	we.Lock()
+	defer we.Unlock()
+	return we.notifyC
+}
+
+// subscribe returns a channel that receives the events of a wallet published from now on,
+// and a function that unsubscribes and closes the channel
+func (we *walletEvents) subscribe(wltID string) (<-chan WalletEvent, func()) {
+	c := make(chan WalletEvent, walletEventsBufferSize)
+
+	we.Lock()
+	defer we.Unlock()
+	we.subscribers[c] = wltID
+
+	var once sync.Once
+	return c, func() {
+		once.Do(func() {
+			we.Lock()
+			defer we.Unlock()
+			delete(we.subscribers, c)
+			close(c)
+		})
+	}
+}
+
+// publish sends committed events to the subscribers of their wallets without blocking,
+// and wakes the callers waiting for events
+func (we *walletEvents) publish(events []WalletEvent) {
+	we.Lock()
+	defer we.Unlock()
+
+	for c, wltID := range we.subscribers {
+		for _, e := range events {
+			if e.WalletID != wltID {
+				continue
+			}
+
+			select {
+			case c <- e:
+			default:
+				logger.WithField("wallet", wltID).Debug("Wallet event subscriber is full, dropping event")
+			}
+		}
+	}
+
+	close(we.notifyC)
+	we.notifyC = make(chan struct{})
+}
+
+// recordWalletEvents records the events of a transaction for the wallets it pays.
+// getInputs returns the outputs spent by the transaction, it is only called if the transaction pays a wallet.
+func (vs *Visor) recordWalletEvents(tx *dbutil.Tx, typ WalletEventType, txn *coin.Transaction, getInputs func() (coin.UxArray, error), seq, t uint64) error {
+	if vs.walletEvents == nil || vs.Wallets == nil {
+		return nil
+	}
+
+	addrs := make([]cipher.Address, len(txn.Out))
+	for i, o := range txn.Out {
+		addrs[i] = o.Address
+	}
+
+	addrWallets := vs.Wallets.AddressWallets(addrs)
+	if len(addrWallets) == 0 {
+		return nil
+	}
+
+	inputs, err := getInputs()
+	if err != nil {
+		return err
+	}
+
+	inputAddrs := make([]cipher.Address, len(inputs))
+	for i, ux := range inputs {
+		inputAddrs[i] = ux.Body.Address
+	}
+
+	spendingWallets := make(map[string]struct{})
+	for _, ids := range vs.Wallets.AddressWallets(inputAddrs) {
+		for _, id := range ids {
+			spendingWallets[id] = struct{}{}
+		}
+	}
+
+	return vs.walletEvents.recordTxn(tx, typ, txn, addrWallets, spendingWallets, seq, t)
+}
+
+// recordBlockWalletEvents records the wallet events of the transactions of a block, after the history db parsed the block
+func (vs *Visor) recordBlockWalletEvents(tx *dbutil.Tx, b *coin.Block) error {
+	for i := range b.Body.Transactions {
+		txn := &b.Body.Transactions[i]
+
+		getInputs := func() (coin.UxArray, error) {
+			uxs, err := vs.history.GetUxOuts(tx, txn.In)
+			if err != nil {
+				return nil, err
+			}
+
+			inputs := make(coin.UxArray, len(uxs))
+			for j, ux := range uxs {
+				inputs[j] = ux.Out
+			}
+			return inputs, nil
+		}
+
+		if err := vs.recordWalletEvents(tx, WalletEventConfirmed, txn, getInputs, b.Seq(), b.Time()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// recordUnconfirmedWalletEvents records the wallet events of a transaction added to the unconfirmed pool
+func (vs *Visor) recordUnconfirmedWalletEvents(tx *dbutil.Tx, txn *coin.Transaction) error {
+	getInputs := func() (coin.UxArray, error) {
+		return vs.Blockchain.Unspent().GetArray(tx, txn.In)
+	}
+
+	return vs.recordWalletEvents(tx, WalletEventUnconfirmed, txn, getInputs, 0, uint64(time.Now().UTC().Unix()))
+}
+
+// GetWalletEvents returns at most limit incoming funds events of a wallet with an ID greater than after, in ID order.
+// The most recent MaxWalletEvents events of all the wallets are stored.
+func (vs *Visor) GetWalletEvents(wltID string, after, limit uint64) ([]WalletEvent, error) {
+	if _, err := vs.Wallets.GetSkycoinAddresses(wltID); err != nil {
+		return nil, err
+	}
+
+	var events []WalletEvent
+	if err := vs.DB.View("GetWalletEvents", func(tx *dbutil.Tx) error {
+		headSeq, _, err := vs.Blockchain.HeadSeq(tx)
+		if err != nil {
+			return err
+		}
+
+		events, err = vs.walletEvents.events(tx, wltID, after, limit, headSeq)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// WaitWalletEvents is like GetWalletEvents, but if the wallet has no events with an ID greater than after,
+// it waits until such events are recorded or ctx is done. Returns no events if ctx is done first.
+func (vs *Visor) WaitWalletEvents(ctx context.Context, wltID string, after, limit uint64) ([]WalletEvent, error) {
+	for {
+		// Get the channel before reading the events, so that events committed after the read wake the wait
+		c := vs.walletEvents.wait()
+
+		events, err := vs.GetWalletEvents(wltID, after, limit)
+		if err != nil {
+			return nil, err
+		}
+		if len(events) != 0 {
+			return events, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return events, nil
+		case <-c:
+		}
+	}
+}
+
+// SubscribeWalletEvents returns a channel that receives the incoming funds events of a wallet from now on,
+// and a function that unsubscribes. The events are dropped for a subscriber that does not keep up
+func (vs *Visor) SubscribeWalletEvents(wltID string) (<-chan WalletEvent, func(), error) {
+	if _, err := vs.Wallets.GetSkycoinAddresses(wltID); err != nil {
+		return nil, nil, err
+	}
+
+	c, unsubscribe := vs.walletEvents.subscribe(wltID)
+	return c, unsubscribe, nil
+}
@@ -0,0 +1,333 @@
+package visor
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/params"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/historydb"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+func TestWalletEvents(t *testing.T) {
+	db, shutdown := prepareDB(t)
+	defer shutdown()
+
+	bc, err := NewBlockchain(db, BlockchainConfig{
+		Pubkey: genPublic,
+	})
+	require.NoError(t, err)
+
+	unconfirmed, err := NewUnconfirmedTransactionPool(db)
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "wallet-events")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	wallets, err := wallet.NewService(wallet.Config{
+		WalletDir:       dir,
+		CryptoType:      wallet.CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	wa, err := wallets.CreateWallet("a.wlt", wallet.Options{
+		Seed: "seeda",
+	}, nil)
+	require.NoError(t, err)
+	aAddr := wa.Entries[0].SkycoinAddress()
+
+	wb, err := wallets.CreateWallet("b.wlt", wallet.Options{
+		Seed: "seedb",
+	}, nil)
+	require.NoError(t, err)
+	bAddr := wb.Entries[0].SkycoinAddress()
+
+	cfg := NewConfig()
+	cfg.DBPath = db.Path()
+	cfg.IsBlockPublisher = true
+	cfg.BlockchainPubkey = genPublic
+	cfg.BlockchainSeckey = genSecret
+	cfg.GenesisAddress = genAddress
+
+	v := &Visor{
+		Config:       cfg,
+		Unconfirmed:  unconfirmed,
+		Blockchain:   bc,
+		DB:           db,
+		Wallets:      wallets,
+		history:      historydb.New(),
+		walletEvents: newWalletEvents(),
+	}
+
+	gb := addGenesisBlockToVisor(t, v)
+
+	// executeBlock executes a block with the unconfirmed txns
+	executeBlock := func() coin.SignedBlock {
+		var sb coin.SignedBlock
+		err := db.Update("", func(tx *dbutil.Tx) error {
+			head, err := bc.Head(tx)
+			require.NoError(t, err)
+
+			sb, err = v.createBlock(tx, head.Time()+3600)
+			require.NoError(t, err)
+
+			return v.executeSignedBlock(tx, sb)
+		})
+		require.NoError(t, err)
+		v.clearApplyJournal()
+		return sb
+	}
+
+	// Nothing is recorded for the transactions that don't pay a wallet
+	txn1 := makeUnspentsTx(t, coin.CreateUnspents(gb.Head, gb.Body.Transactions[0]), []cipher.SecKey{genSecret}, genAddress, 10, params.MaxDropletDivisor())
+	_, _, err = v.InjectForeignTransaction(txn1)
+	require.NoError(t, err)
+	b1 := executeBlock()
+	uxs1 := coin.CreateUnspents(b1.Head, txn1)
+
+	events, err := v.GetWalletEvents("a.wlt", 0, 100)
+	require.NoError(t, err)
+	require.Empty(t, events)
+
+	// The events of unknown wallets can't be read
+	_, err = v.GetWalletEvents("foo.wlt", 0, 100)
+	require.Equal(t, wallet.ErrWalletNotExist, err)
+	_, _, err = v.SubscribeWalletEvents("foo.wlt")
+	require.Equal(t, wallet.ErrWalletNotExist, err)
+
+	// A transaction paying a wallet is recorded when it's injected and when it's executed
+	subA, unsubscribeA, err := v.SubscribeWalletEvents("a.wlt")
+	require.NoError(t, err)
+	defer unsubscribeA()
+	subB, unsubscribeB, err := v.SubscribeWalletEvents("b.wlt")
+	require.NoError(t, err)
+	defer unsubscribeB()
+
+	txn2 := makeSpendTxWithFee(t, coin.UxArray{uxs1[0]}, []cipher.SecKey{genSecret}, aAddr, 1e6, 0)
+
+	c := v.walletEvents.wait()
+	known, softErr, err := v.InjectForeignTransaction(txn2)
+	require.NoError(t, err)
+	require.Nil(t, softErr)
+	require.False(t, known)
+
+	select {
+	case <-c:
+	default:
+		t.Fatal("waiters were not notified")
+	}
+
+	events, err = v.GetWalletEvents("a.wlt", 0, 100)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, uint64(1), events[0].ID)
+	require.Equal(t, WalletEventUnconfirmed, events[0].Type)
+	require.Equal(t, "a.wlt", events[0].WalletID)
+	require.Equal(t, txn2.Hash(), events[0].TxnID)
+	require.Equal(t, uint64(1e6), events[0].Amount)
+	require.Equal(t, []cipher.Address{aAddr}, events[0].Addresses)
+	require.Equal(t, uint64(0), events[0].Confirmations)
+	require.NotEqual(t, uint64(0), events[0].Time)
+
+	select {
+	case e := <-subA:
+		require.Equal(t, events[0], e)
+	default:
+		t.Fatal("subscriber of a.wlt did not receive the event")
+	}
+
+	b2 := executeBlock()
+	uxs2 := coin.CreateUnspents(b2.Head, txn2)
+
+	confirmed := WalletEvent{
+		ID:            2,
+		Type:          WalletEventConfirmed,
+		WalletID:      "a.wlt",
+		TxnID:         txn2.Hash(),
+		BlockSeq:      b2.Seq(),
+		Time:          b2.Time(),
+		Amount:        1e6,
+		Addresses:     []cipher.Address{aAddr},
+		Confirmations: 1,
+	}
+
+	events, err = v.GetWalletEvents("a.wlt", 1, 100)
+	require.NoError(t, err)
+	require.Equal(t, []WalletEvent{confirmed}, events)
+
+	select {
+	case e := <-subA:
+		require.Equal(t, confirmed, e)
+	default:
+		t.Fatal("subscriber of a.wlt did not receive the event")
+	}
+
+	// A transaction spending the outputs of a wallet is not incoming funds for it, its outputs to the wallet are change
+	txn3 := makeSpendTxWithFee(t, coin.UxArray{uxs2[0]}, []cipher.SecKey{wa.Entries[0].Secret}, bAddr, 4e5, 0)
+	_, err = v.InjectUserTransaction(txn3)
+	require.NoError(t, err)
+
+	events, err = v.GetWalletEvents("a.wlt", 2, 100)
+	require.NoError(t, err)
+	require.Empty(t, events)
+
+	events, err = v.GetWalletEvents("b.wlt", 0, 100)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, uint64(3), events[0].ID)
+	require.Equal(t, "b.wlt", events[0].WalletID)
+	require.Equal(t, txn3.Hash(), events[0].TxnID)
+	require.Equal(t, uint64(4e5), events[0].Amount)
+	require.Equal(t, []cipher.Address{bAddr}, events[0].Addresses)
+
+	select {
+	case e := <-subB:
+		require.Equal(t, events[0], e)
+	default:
+		t.Fatal("subscriber of b.wlt did not receive the event")
+	}
+
+	select {
+	case e := <-subA:
+		t.Fatalf("subscriber of a.wlt received an event of another wallet: %+v", e)
+	default:
+	}
+
+	// The confirmations are counted when the events are read
+	executeBlock()
+
+	events, err = v.GetWalletEvents("a.wlt", 0, 100)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, uint64(2), events[1].Confirmations)
+
+	// limit bounds the events returned
+	events, err = v.GetWalletEvents("a.wlt", 0, 1)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, uint64(1), events[0].ID)
+
+	// WaitWalletEvents returns no events when the context is done first
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	events, err = v.WaitWalletEvents(ctx, "a.wlt", 2, 100)
+	require.NoError(t, err)
+	require.Empty(t, events)
+
+	// WaitWalletEvents returns the events once they're recorded, ignoring the events of other wallets
+	done := make(chan []WalletEvent)
+	go func() {
+		events, err := v.WaitWalletEvents(context.Background(), "b.wlt", 4, 100)
+		require.NoError(t, err)
+		done <- events
+	}()
+
+	txn4 := makeSpendTxWithFee(t, coin.UxArray{uxs1[1]}, []cipher.SecKey{genSecret}, aAddr, 1e6, 0)
+	_, _, err = v.InjectForeignTransaction(txn4)
+	require.NoError(t, err)
+
+	txn5 := makeSpendTxWithFee(t, coin.UxArray{uxs1[2]}, []cipher.SecKey{genSecret}, bAddr, 2e6, 0)
+	_, _, err = v.InjectForeignTransaction(txn5)
+	require.NoError(t, err)
+
+	select {
+	case events = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("WaitWalletEvents did not return")
+	}
+
+	require.Len(t, events, 1)
+	require.Equal(t, txn5.Hash(), events[0].TxnID)
+	require.Equal(t, uint64(2e6), events[0].Amount)
+}
+
+func TestWalletEventsIndex(t *testing.T) {
+	db, shutdown := prepareDB(t)
+	defer shutdown()
+
+	we := newWalletEvents()
+
+	// The events of a wallet are read through the index, "a.wlt" is a prefix of "a.wlt2"
+	wltIDs := []string{"a.wlt", "a.wlt2", "a.wlt", "b.wlt", "a.wlt"}
+	err := db.Update("", func(tx *dbutil.Tx) error {
+		for _, id := range wltIDs {
+			require.NoError(t, we.putEvent(tx, &WalletEvent{
+				Type:     WalletEventUnconfirmed,
+				WalletID: id,
+				TxnID:    testutil.RandSHA256(t),
+			}))
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	eventIDs := func(wltID string, after uint64) []uint64 {
+		var ids []uint64
+		err := db.View("", func(tx *dbutil.Tx) error {
+			events, err := we.events(tx, wltID, after, 100, 0)
+			require.NoError(t, err)
+			for _, e := range events {
+				require.Equal(t, wltID, e.WalletID)
+				ids = append(ids, e.ID)
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		return ids
+	}
+
+	require.Equal(t, []uint64{1, 3, 5}, eventIDs("a.wlt", 0))
+	require.Equal(t, []uint64{5}, eventIDs("a.wlt", 3))
+	require.Equal(t, []uint64{2}, eventIDs("a.wlt2", 0))
+	require.Equal(t, []uint64{4}, eventIDs("b.wlt", 0))
+	require.Empty(t, eventIDs("c.wlt", 0))
+
+	// The index is built for the events of a database that predates it
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		require.NoError(t, dbutil.ResetBucket(tx, WalletEventIDsBkt))
+		return maybeBuildWalletEventsIndex(tx)
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, []uint64{1, 3, 5}, eventIDs("a.wlt", 0))
+	require.Equal(t, []uint64{2}, eventIDs("a.wlt2", 0))
+	require.Equal(t, []uint64{4}, eventIDs("b.wlt", 0))
+
+	// The oldest events are deleted from the index with the events
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		for i := len(wltIDs); i < MaxWalletEvents+2; i++ {
+			require.NoError(t, we.putEvent(tx, &WalletEvent{
+				Type:     WalletEventUnconfirmed,
+				WalletID: "c.wlt",
+			}))
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, []uint64{3, 5}, eventIDs("a.wlt", 0))
+	require.Empty(t, eventIDs("a.wlt2", 0))
+
+	err = db.View("", func(tx *dbutil.Tx) error {
+		n, err := dbutil.Len(tx, WalletEventsBkt)
+		require.NoError(t, err)
+		require.Equal(t, uint64(MaxWalletEvents), n)
+
+		n, err = dbutil.Len(tx, WalletEventIDsBkt)
+		require.NoError(t, err)
+		require.Equal(t, uint64(MaxWalletEvents), n)
+		return nil
+	})
+	require.NoError(t, err)
+}
@@ -0,0 +1,59 @@
+package visor
+
+import (
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/wallet"
+	"github.com/skycoin/skycoin/src/wallet/history"
+)
+
+// GetWalletHistory returns the history of the confirmed transactions of a wallet, with their direction,
+// counterparties, fee and the running balance of the wallet, see history.NewEntries.
+// If account is not empty, the history is the history of the addresses of that account of the wallet,
+// and the transfers between the account and the other accounts of the wallet are not internal.
+func (vs *Visor) GetWalletHistory(wltID, account string) ([]history.Entry, error) {
+	var addrs []cipher.Address
+	if err := vs.Wallets.View(wltID, func(w *wallet.Wallet) error {
+		var err error
+		if account == "" {
+			addrs, err = w.GetSkycoinAddresses()
+		} else {
+			addrs, err = w.AccountAddresses(account)
+		}
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	if len(addrs) == 0 {
+		return []history.Entry{}, nil
+	}
+
+	txns, inputs, err := vs.GetTransactionsWithInputs([]TxFilter{
+		NewAddrsFilter(addrs),
+		NewConfirmedTxFilter(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	htxns := make([]history.Transaction, len(txns))
+	for i, txn := range txns {
+		hInputs := make([]history.Input, len(inputs[i]))
+		for j, in := range inputs[i] {
+			hInputs[j] = history.Input{
+				Address: in.UxOut.Body.Address,
+				Coins:   in.UxOut.Body.Coins,
+				Hours:   in.CalculatedHours,
+			}
+		}
+
+		htxns[i] = history.Transaction{
+			Transaction: txn.Transaction,
+			BlockSeq:    txn.Status.BlockSeq,
+			Time:        txn.Time,
+			Inputs:      hInputs,
+		}
+	}
+
+	return history.NewEntries(addrs, htxns)
+}
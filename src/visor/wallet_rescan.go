@@ -0,0 +1,129 @@
+package visor
+
+import (
+	"fmt"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+const (
+	// MaxRescanGapLimit is the maximum gap limit of RescanWallet
+	MaxRescanGapLimit = 1000
+)
+
+var (
+	// ErrRescanGapLimitTooLarge is returned by RescanWallet if the gap limit is larger than MaxRescanGapLimit
+	ErrRescanGapLimitTooLarge = fmt.Errorf("gap limit can't be larger than %d", MaxRescanGapLimit)
+	// ErrRescanTooManyAddresses is returned by RescanWallet if the wallet has more than MaxScanAddresses addresses
+	ErrRescanTooManyAddresses = fmt.Errorf("Can't rescan a wallet with more than %d addresses", MaxScanAddresses)
+)
+
+// WalletRescan is the result of RescanWallet
+type WalletRescan struct {
+	// NewAddresses are the addresses added to the wallet by the gap limit extension
+	NewAddresses []cipher.Address
+	// Discovered are the new addresses that have been used in the blockchain
+	Discovered []cipher.Address
+	// Balance is the confirmed balance of the wallet
+	Balance wallet.Balance
+	// Addresses are the scans of the addresses of the wallet, in the order of the wallet
+	Addresses []AddressScan
+}
+
+// GetAddressesActivity reports whether each address has ever received or spent coins in a confirmed transaction,
+// according to the historydb
+func (vs Visor) GetAddressesActivity(addrs []cipher.Address) ([]bool, error) {
+	active := make([]bool, len(addrs))
+	if err := vs.DB.View("GetAddressesActivity", func(tx *dbutil.Tx) error {
+		for i, addr := range addrs {
+			meta, err := vs.history.GetAddressMeta(tx, addr)
+			if err != nil {
+				return err
+			}
+
+			active[i] = meta != nil && meta.TxnCount > 0
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return active, nil
+}
+
+// RescanWallet scans the historydb for the activity of the addresses of a wallet.
+// If gapLimit is not 0, a deterministic wallet is first extended with the used addresses that follow its last address,
+// until gapLimit consecutive addresses were never used, see wallet.Wallet.ExtendActiveAddresses.
+// The password is required to extend an encrypted wallet. Watch-only wallets are not extended.
+// Returns the added addresses, the confirmed balances and the numTxns most recent transactions of all the addresses
+// of the wallet, which replace what a client has cached about the wallet.
+func (vs *Visor) RescanWallet(wltID string, password []byte, gapLimit, numTxns uint64) (*WalletRescan, error) {
+	if gapLimit > MaxRescanGapLimit {
+		return nil, ErrRescanGapLimitTooLarge
+	}
+
+	if numTxns > MaxScanRecentTxns {
+		return nil, ErrScanTooManyRecentTxns
+	}
+
+	newAddrs, err := vs.Wallets.ExtendActiveAddresses(wltID, password, gapLimit, vs)
+	if err != nil {
+		return nil, err
+	}
+
+	var addrs []cipher.Address
+	if err := vs.Wallets.View(wltID, func(w *wallet.Wallet) error {
+		var err error
+		addrs, err = w.GetSkycoinAddresses()
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	if len(addrs) > MaxScanAddresses {
+		return nil, ErrRescanTooManyAddresses
+	}
+
+	rescan := &WalletRescan{
+		NewAddresses: newAddrs,
+	}
+
+	if len(addrs) == 0 {
+		return rescan, nil
+	}
+
+	rescan.Addresses, err = vs.ScanAddresses(addrs, numTxns)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(newAddrs) != 0 {
+		active, err := vs.GetAddressesActivity(newAddrs)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, a := range newAddrs {
+			if active[i] {
+				rescan.Discovered = append(rescan.Discovered, a)
+			}
+		}
+	}
+
+	for _, s := range rescan.Addresses {
+		rescan.Balance.Coins, err = coin.AddUint64(rescan.Balance.Coins, s.Balance.Coins)
+		if err != nil {
+			return nil, err
+		}
+
+		rescan.Balance.Hours, err = coin.AddUint64(rescan.Balance.Hours, s.Balance.Hours)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return rescan, nil
+}
@@ -0,0 +1,52 @@
+package visor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/historydb"
+)
+
+func TestVisorGetAddressesActivity(t *testing.T) {
+	db, shutdown := prepareDB(t)
+	defer shutdown()
+
+	matchTxn := mock.MatchedBy(func(tx *dbutil.Tx) bool {
+		return true
+	})
+
+	used := testutil.MakeAddress()
+	unused := testutil.MakeAddress()
+
+	history := &MockHistoryer{}
+	history.On("GetAddressMeta", matchTxn, used).Return(&historydb.AddressMeta{
+		FirstSeenSeq:  3,
+		LastActiveSeq: 5,
+		TxnCount:      2,
+	}, nil)
+	history.On("GetAddressMeta", matchTxn, unused).Return(nil, nil)
+
+	v := &Visor{
+		DB:      db,
+		history: history,
+	}
+
+	active, err := v.GetAddressesActivity([]cipher.Address{unused, used, unused})
+	require.NoError(t, err)
+	require.Equal(t, []bool{false, true, false}, active)
+}
+
+func TestVisorRescanWalletLimits(t *testing.T) {
+	v := &Visor{}
+
+	_, err := v.RescanWallet("t.wlt", nil, MaxRescanGapLimit+1, 0)
+	require.Equal(t, ErrRescanGapLimitTooLarge, err)
+
+	_, err = v.RescanWallet("t.wlt", nil, 20, MaxScanRecentTxns+1)
+	require.Equal(t, ErrScanTooManyRecentTxns, err)
+}
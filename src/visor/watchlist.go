@@ -0,0 +1,464 @@
+package visor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/encoder"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+var (
+	// WatchAddressesBkt holds the addresses of the watch list, by their key from watchAddressKey
+	WatchAddressesBkt = []byte("watch_addresses")
+
+	// WatchEventsBkt holds the events of the watched addresses, by event ID
+	WatchEventsBkt = []byte("watch_events")
+)
+
+// MaxWatchEvents is the maximum number of watch events stored, the oldest events are deleted first
+const MaxWatchEvents = 100000
+
+// WatchEventType is the type of a WatchEvent
+type WatchEventType string
+
+const (
+	// WatchEventUnconfirmed is recorded when a transaction touching a watched address is added to the unconfirmed pool
+	WatchEventUnconfirmed WatchEventType = "unconfirmed"
+	// WatchEventConfirmed is recorded when a transaction touching a watched address is executed in a block
+	WatchEventConfirmed WatchEventType = "confirmed"
+)
+
+// WatchEvent records that a transaction touched a watched address, by spending its outputs or sending coins to it
+type WatchEvent struct {
+	// ID of the event. IDs increase in the order the events were recorded
+	ID      uint64
+	Type    WatchEventType
+	Address cipher.Address
+	TxnID   cipher.SHA256
+	// BlockSeq is the seq of the block that executed the transaction, for WatchEventConfirmed events
+	BlockSeq uint64
+	// Time is the time of the block, or the time the transaction was received for WatchEventUnconfirmed events
+	Time uint64
+	// Received is the number of droplets sent to the address by the transaction
+	Received uint64
+	// Sent is the number of droplets of the outputs of the address spent by the transaction
+	Sent uint64
+}
+
+// watchList records events for the transactions that touch the watched addresses.
+// Only the addresses of the transactions are looked up in the watch list, so the cost of a block
+// does not depend on the number of watched addresses.
+type watchList struct {
+	sync.Mutex
+	// notifyC is closed and replaced when events are committed
+	notifyC chan struct{}
+}
+
+func newWatchList() *watchList {
+	return &watchList{
+		notifyC: make(chan struct{}),
+	}
+}
+
+// watchAddressKey returns the key of an address in WatchAddressesBkt.
+// The address is replaced by its HMAC in an encrypted database, see dbutil.Tx.KeyMAC,
+// so the address is also stored in the value.
+func watchAddressKey(tx *dbutil.Tx, a cipher.Address) []byte {
+	return tx.KeyMAC(a.Bytes())
+}
+
+// isWatched returns true if the address is in the watch list
+func isWatched(tx *dbutil.Tx, a cipher.Address) (bool, error) {
+	return dbutil.BucketHasKey(tx, WatchAddressesBkt, watchAddressKey(tx, a))
+}
+
+// add adds addresses to the watch list, returns the number of addresses that were not watched already
+func (wl *watchList) add(tx *dbutil.Tx, addrs []cipher.Address) (uint64, error) {
+	var n uint64
+	for _, a := range addrs {
+		if ok, err := isWatched(tx, a); err != nil {
+			return 0, err
+		} else if ok {
+			continue
+		}
+
+		if err := dbutil.PutBucketValue(tx, WatchAddressesBkt, watchAddressKey(tx, a), a.Bytes()); err != nil {
+			return 0, err
+		}
+		n++
+	}
+
+	return n, nil
+}
+
+// remove removes addresses from the watch list, returns the number of addresses that were watched.
+// The events of the addresses are kept.
+func (wl *watchList) remove(tx *dbutil.Tx, addrs []cipher.Address) (uint64, error) {
+	var n uint64
+	for _, a := range addrs {
+		if ok, err := isWatched(tx, a); err != nil {
+			return 0, err
+		} else if !ok {
+			continue
+		}
+
+		if err := dbutil.Delete(tx, WatchAddressesBkt, watchAddressKey(tx, a)); err != nil {
+			return 0, err
+		}
+		n++
+	}
+
+	return n, nil
+}
+
+// addresses returns the watched addresses
+func (wl *watchList) addresses(tx *dbutil.Tx) ([]cipher.Address, error) {
+	var addrs []cipher.Address
+	if err := dbutil.ForEach(tx, WatchAddressesBkt, func(k, v []byte) error {
+		// The key of an address that is not migrated yet is the address, see maybeMigrateWatchAddresses
+		if len(v) == 0 {
+			v = k
+		}
+
+		a, err := cipher.AddressFromBytes(v)
+		if err != nil {
+			return err
+		}
+
+		addrs = append(addrs, a)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return addrs, nil
+}
+
+// maybeMigrateWatchAddresses rekeys the addresses that a database stored by their raw bytes with an empty value,
+// before the addresses were hidden in an encrypted database
+func maybeMigrateWatchAddresses(tx *dbutil.Tx) error {
+	var addrs []cipher.Address
+	if err := dbutil.ForEach(tx, WatchAddressesBkt, func(k, v []byte) error {
+		if len(v) != 0 {
+			return nil
+		}
+
+		a, err := cipher.AddressFromBytes(k)
+		if err != nil {
+			return err
+		}
+
+		addrs = append(addrs, a)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if len(addrs) == 0 {
+		return nil
+	}
+
+	logger.Infof("Migrating %d watched addresses", len(addrs))
+
+	for _, a := range addrs {
+		if err := dbutil.Delete(tx, WatchAddressesBkt, a.Bytes()); err != nil {
+			return err
+		}
+
+		if err := dbutil.PutBucketValue(tx, WatchAddressesBkt, watchAddressKey(tx, a), a.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// unhiddenWatchAddresses returns the watched addresses whose keys are not hidden yet,
+// which are the addresses of a database that is not encrypted or whose keys are not hidden.
+// It only reads the keys, so it can read an encrypted database before its encryption is set.
+func unhiddenWatchAddresses(db *dbutil.DB) ([]cipher.Address, error) {
+	var addrs []cipher.Address
+	if err := db.View("unhiddenWatchAddresses", func(tx *dbutil.Tx) error {
+		bkt := tx.Bucket(WatchAddressesBkt)
+		if bkt == nil {
+			return nil
+		}
+
+		return bkt.ForEach(func(k, _ []byte) error {
+			// A hidden key is a 32 byte HMAC, longer than an address
+			a, err := cipher.AddressFromBytes(k)
+			if err != nil {
+				return nil
+			}
+
+			addrs = append(addrs, a)
+			return nil
+		})
+	}); err != nil {
+		return nil, err
+	}
+
+	return addrs, nil
+}
+
+// isEmpty returns true if no address is watched.
+// A database opened read-only can predate the watch list.
+func (wl *watchList) isEmpty(tx *dbutil.Tx) bool {
+	if wl == nil {
+		return true
+	}
+
+	bkt := tx.Bucket(WatchAddressesBkt)
+	if bkt == nil {
+		return true
+	}
+
+	k, _ := bkt.Cursor().First()
+	return k == nil
+}
+
+// recordTxn records an event for each watched address that a transaction touches.
+// inputs are the outputs spent by the transaction.
+func (wl *watchList) recordTxn(tx *dbutil.Tx, typ WatchEventType, txn *coin.Transaction, inputs coin.UxArray, seq, t uint64) error {
+	if len(inputs) != len(txn.In) {
+		return fmt.Errorf("transaction %s has %d inputs, got %d spent outputs", txn.Hash().Hex(), len(txn.In), len(inputs))
+	}
+
+	txnID := txn.Hash()
+
+	// Index the coins sent and received by the addresses of the transaction, in the order they appear
+	var addrs []cipher.Address
+	events := make(map[cipher.Address]*WatchEvent)
+	touch := func(a cipher.Address) *WatchEvent {
+		e, ok := events[a]
+		if !ok {
+			e = &WatchEvent{
+				Type:     typ,
+				Address:  a,
+				TxnID:    txnID,
+				BlockSeq: seq,
+				Time:     t,
+			}
+			events[a] = e
+			addrs = append(addrs, a)
+		}
+		return e
+	}
+
+	for _, ux := range inputs {
+		e := touch(ux.Body.Address)
+		e.Sent += ux.Body.Coins
+	}
+
+	for _, o := range txn.Out {
+		e := touch(o.Address)
+		e.Received += o.Coins
+	}
+
+	var recorded bool
+	for _, a := range addrs {
+		if ok, err := isWatched(tx, a); err != nil {
+			return err
+		} else if !ok {
+			continue
+		}
+
+		if err := wl.putEvent(tx, events[a]); err != nil {
+			return err
+		}
+		recorded = true
+	}
+
+	if recorded {
+		// The events become visible to the readers once the transaction is committed
+		tx.OnCommit(wl.notify)
+	}
+
+	return nil
+}
+
+// putEvent stores an event with the next event ID and deletes the events older than the MaxWatchEvents most recent events
+func (wl *watchList) putEvent(tx *dbutil.Tx, e *WatchEvent) error {
+	id, err := dbutil.NextSequence(tx, WatchEventsBkt)
+	if err != nil {
+		return err
+	}
+
+	e.ID = id
+	if err := dbutil.PutBucketValue(tx, WatchEventsBkt, dbutil.Itob(id), encoder.Serialize(*e)); err != nil {
+		return err
+	}
+
+	if id <= MaxWatchEvents {
+		return nil
+	}
+
+	// IDs are sequential, so at most one event is older than the retained events
+	return dbutil.Delete(tx, WatchEventsBkt, dbutil.Itob(id-MaxWatchEvents))
+}
+
+// events returns at most limit events with an ID greater than after, in ID order
+func (wl *watchList) events(tx *dbutil.Tx, after, limit uint64) ([]WatchEvent, error) {
+	var events []WatchEvent
+	if limit == 0 {
+		return events, nil
+	}
+
+	if err := dbutil.ForEachPrefix(tx, WatchEventsBkt, nil, dbutil.Itob(after+1), func(_, v []byte) (bool, error) {
+		var e WatchEvent
+		if err := encoder.DeserializeRaw(v, &e); err != nil {
+			return false, err
+		}
+
+		events = append(events, e)
+		return uint64(len(events)) < limit, nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// wait returns a channel that is closed when events are committed
+func (wl *watchList) wait() <-chan struct{} {
+	wl.Lock()
+	defer wl.Unlock()
+	return wl.notifyC
+}
+
+// notify wakes the callers waiting for events
+func (wl *watchList) notify() {
+	wl.Lock()
+	defer wl.Unlock()
+
+	close(wl.notifyC)
+	wl.notifyC = make(chan struct{})
+}
+
+// recordBlockWatchEvents records the events of the transactions of a block, after the history db parsed the block
+func (vs *Visor) recordBlockWatchEvents(tx *dbutil.Tx, b *coin.Block) error {
+	if vs.watchList.isEmpty(tx) {
+		return nil
+	}
+
+	for i := range b.Body.Transactions {
+		txn := &b.Body.Transactions[i]
+
+		uxs, err := vs.history.GetUxOuts(tx, txn.In)
+		if err != nil {
+			return err
+		}
+
+		inputs := make(coin.UxArray, len(uxs))
+		for j, ux := range uxs {
+			inputs[j] = ux.Out
+		}
+
+		if err := vs.watchList.recordTxn(tx, WatchEventConfirmed, txn, inputs, b.Seq(), b.Time()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// recordUnconfirmedWatchEvents records the events of a transaction added to the unconfirmed pool
+func (vs *Visor) recordUnconfirmedWatchEvents(tx *dbutil.Tx, txn *coin.Transaction) error {
+	if vs.watchList.isEmpty(tx) {
+		return nil
+	}
+
+	inputs, err := vs.Blockchain.Unspent().GetArray(tx, txn.In)
+	if err != nil {
+		return err
+	}
+
+	return vs.watchList.recordTxn(tx, WatchEventUnconfirmed, txn, inputs, 0, uint64(time.Now().UTC().Unix()))
+}
+
+// AddWatchAddresses adds addresses to the watch list. Events are recorded for the transactions that touch them
+// from now on. Returns the number of addresses that were not watched already.
+func (vs *Visor) AddWatchAddresses(addrs []cipher.Address) (uint64, error) {
+	var n uint64
+	if err := vs.DB.Update("AddWatchAddresses", func(tx *dbutil.Tx) error {
+		var err error
+		n, err = vs.watchList.add(tx, addrs)
+		return err
+	}); err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// RemoveWatchAddresses removes addresses from the watch list, keeping their recorded events.
+// Returns the number of addresses that were watched.
+func (vs *Visor) RemoveWatchAddresses(addrs []cipher.Address) (uint64, error) {
+	var n uint64
+	if err := vs.DB.Update("RemoveWatchAddresses", func(tx *dbutil.Tx) error {
+		var err error
+		n, err = vs.watchList.remove(tx, addrs)
+		return err
+	}); err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// GetWatchAddresses returns the watched addresses
+func (vs *Visor) GetWatchAddresses() ([]cipher.Address, error) {
+	var addrs []cipher.Address
+	if err := vs.DB.View("GetWatchAddresses", func(tx *dbutil.Tx) error {
+		var err error
+		addrs, err = vs.watchList.addresses(tx)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return addrs, nil
+}
+
+// GetWatchEvents returns at most limit watch events with an ID greater than after, in ID order.
+// The most recent MaxWatchEvents events are stored.
+func (vs *Visor) GetWatchEvents(after, limit uint64) ([]WatchEvent, error) {
+	var events []WatchEvent
+	if err := vs.DB.View("GetWatchEvents", func(tx *dbutil.Tx) error {
+		var err error
+		events, err = vs.watchList.events(tx, after, limit)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// WaitWatchEvents is like GetWatchEvents, but if there are no events with an ID greater than after,
+// it waits until such events are recorded or ctx is done. Returns no events if ctx is done first.
+func (vs *Visor) WaitWatchEvents(ctx context.Context, after, limit uint64) ([]WatchEvent, error) {
+	for {
+		// Get the channel before reading the events, so that events committed after the read wake the wait
+		c := vs.watchList.wait()
+
+		events, err := vs.GetWatchEvents(after, limit)
+		if err != nil {
+			return nil, err
+		}
+		if len(events) != 0 {
+			return events, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return events, nil
+		case <-c:
+		}
+	}
+}
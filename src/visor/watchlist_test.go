@@ -0,0 +1,353 @@
+package visor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/params"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/historydb"
+)
+
+func TestWatchList(t *testing.T) {
+	db, shutdown := prepareDB(t)
+	defer shutdown()
+
+	bc, err := NewBlockchain(db, BlockchainConfig{
+		Pubkey: genPublic,
+	})
+	require.NoError(t, err)
+
+	unconfirmed, err := NewUnconfirmedTransactionPool(db)
+	require.NoError(t, err)
+
+	cfg := NewConfig()
+	cfg.DBPath = db.Path()
+	cfg.IsBlockPublisher = true
+	cfg.BlockchainPubkey = genPublic
+	cfg.BlockchainSeckey = genSecret
+	cfg.GenesisAddress = genAddress
+
+	v := &Visor{
+		Config:      cfg,
+		Unconfirmed: unconfirmed,
+		Blockchain:  bc,
+		DB:          db,
+		history:     historydb.New(),
+		watchList:   newWatchList(),
+	}
+
+	gb := addGenesisBlockToVisor(t, v)
+
+	// executeBlock executes a block with the unconfirmed txns
+	executeBlock := func() coin.SignedBlock {
+		var sb coin.SignedBlock
+		err := db.Update("", func(tx *dbutil.Tx) error {
+			head, err := bc.Head(tx)
+			require.NoError(t, err)
+
+			sb, err = v.createBlock(tx, head.Time()+3600)
+			require.NoError(t, err)
+
+			return v.executeSignedBlock(tx, sb)
+		})
+		require.NoError(t, err)
+		v.clearApplyJournal()
+		return sb
+	}
+
+	toPubkey, toSeckey := cipher.GenerateKeyPair()
+	toAddr := cipher.AddressFromPubKey(toPubkey)
+	otherAddr := testutil.MakeAddress()
+
+	// Nothing is recorded while no address is watched
+	txn1 := makeUnspentsTx(t, coin.CreateUnspents(gb.Head, gb.Body.Transactions[0]), []cipher.SecKey{genSecret}, genAddress, 10, params.MaxDropletDivisor())
+	known, softErr, err := v.InjectForeignTransaction(txn1)
+	require.NoError(t, err)
+	require.Nil(t, softErr)
+	require.False(t, known)
+	b1 := executeBlock()
+	uxs1 := coin.CreateUnspents(b1.Head, txn1)
+
+	events, err := v.GetWatchEvents(0, 100)
+	require.NoError(t, err)
+	require.Empty(t, events)
+
+	n, err := v.AddWatchAddresses([]cipher.Address{toAddr, otherAddr, toAddr})
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), n)
+
+	n, err = v.AddWatchAddresses([]cipher.Address{toAddr})
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), n)
+
+	addrs, err := v.GetWatchAddresses()
+	require.NoError(t, err)
+	require.Len(t, addrs, 2)
+	require.Contains(t, addrs, toAddr)
+	require.Contains(t, addrs, otherAddr)
+
+	// A transaction sending coins to a watched address is recorded when it's injected and when it's executed
+	txn2 := makeSpendTxWithFee(t, coin.UxArray{uxs1[0]}, []cipher.SecKey{genSecret}, toAddr, 1e6, 0)
+
+	c := v.watchList.wait()
+	known, softErr, err = v.InjectForeignTransaction(txn2)
+	require.NoError(t, err)
+	require.Nil(t, softErr)
+	require.False(t, known)
+
+	select {
+	case <-c:
+	default:
+		t.Fatal("watchers were not notified")
+	}
+
+	// Injecting a known transaction records nothing
+	known, _, err = v.InjectForeignTransaction(txn2)
+	require.NoError(t, err)
+	require.True(t, known)
+
+	events, err = v.GetWatchEvents(0, 100)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, uint64(1), events[0].ID)
+	require.Equal(t, WatchEventUnconfirmed, events[0].Type)
+	require.Equal(t, toAddr, events[0].Address)
+	require.Equal(t, txn2.Hash(), events[0].TxnID)
+	require.Equal(t, uint64(0), events[0].BlockSeq)
+	require.NotEqual(t, uint64(0), events[0].Time)
+	require.Equal(t, uint64(1e6), events[0].Received)
+	require.Equal(t, uint64(0), events[0].Sent)
+
+	b2 := executeBlock()
+	uxs2 := coin.CreateUnspents(b2.Head, txn2)
+
+	events, err = v.GetWatchEvents(1, 100)
+	require.NoError(t, err)
+	require.Equal(t, []WatchEvent{
+		{
+			ID:       2,
+			Type:     WatchEventConfirmed,
+			Address:  toAddr,
+			TxnID:    txn2.Hash(),
+			BlockSeq: b2.Seq(),
+			Time:     b2.Time(),
+			Received: 1e6,
+		},
+	}, events)
+
+	// A transaction spending the outputs of a watched address is recorded, with the coins sent back as change
+	txn3 := makeSpendTxWithFee(t, coin.UxArray{uxs2[0]}, []cipher.SecKey{toSeckey}, genAddress, 4e5, 0)
+	_, err = v.InjectUserTransaction(txn3)
+	require.NoError(t, err)
+
+	events, err = v.GetWatchEvents(2, 100)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, WatchEventUnconfirmed, events[0].Type)
+	require.Equal(t, toAddr, events[0].Address)
+	require.Equal(t, txn3.Hash(), events[0].TxnID)
+	require.Equal(t, uint64(6e5), events[0].Received)
+	require.Equal(t, uint64(1e6), events[0].Sent)
+
+	// Removed addresses are not recorded, but their events are kept
+	n, err = v.RemoveWatchAddresses([]cipher.Address{toAddr, genAddress})
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), n)
+
+	executeBlock()
+
+	events, err = v.GetWatchEvents(0, 100)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+
+	// limit bounds the events returned
+	events, err = v.GetWatchEvents(0, 2)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, uint64(2), events[1].ID)
+
+	events, err = v.GetWatchEvents(0, 0)
+	require.NoError(t, err)
+	require.Empty(t, events)
+
+	// WaitWatchEvents returns the events that are already recorded
+	events, err = v.WaitWatchEvents(context.Background(), 1, 100)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+
+	// WaitWatchEvents returns no events when the context is done first
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	events, err = v.WaitWatchEvents(ctx, 3, 100)
+	require.NoError(t, err)
+	require.Empty(t, events)
+
+	// WaitWatchEvents returns the events once they're recorded
+	n, err = v.AddWatchAddresses([]cipher.Address{genAddress})
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), n)
+
+	done := make(chan []WatchEvent)
+	go func() {
+		events, err := v.WaitWatchEvents(context.Background(), 3, 100)
+		require.NoError(t, err)
+		done <- events
+	}()
+
+	txn4 := makeSpendTxWithFee(t, coin.UxArray{uxs1[1]}, []cipher.SecKey{genSecret}, otherAddr, 1e6, 0)
+	_, _, err = v.InjectForeignTransaction(txn4)
+	require.NoError(t, err)
+
+	select {
+	case events = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("WaitWatchEvents did not return")
+	}
+
+	// txn4 spends an output of genAddress and sends coins to otherAddr, in that order
+	require.Len(t, events, 2)
+	require.Equal(t, genAddress, events[0].Address)
+	require.Equal(t, uxs1[1].Body.Coins, events[0].Sent)
+	require.Equal(t, uxs1[1].Body.Coins-1e6, events[0].Received)
+	require.Equal(t, otherAddr, events[1].Address)
+	require.Equal(t, uint64(1e6), events[1].Received)
+	require.Equal(t, uint64(0), events[1].Sent)
+}
+
+func TestWatchListRetention(t *testing.T) {
+	db, shutdown := prepareDB(t)
+	defer shutdown()
+
+	wl := newWatchList()
+
+	// The oldest events are deleted once MaxWatchEvents events are stored
+	err := db.Update("", func(tx *dbutil.Tx) error {
+		for i := uint64(1); i <= 3; i++ {
+			require.NoError(t, dbutil.PutBucketValue(tx, WatchEventsBkt, dbutil.Itob(i), []byte{}))
+		}
+		require.NoError(t, tx.Bucket(WatchEventsBkt).SetSequence(MaxWatchEvents-1))
+
+		for i := 0; i < 3; i++ {
+			require.NoError(t, wl.putEvent(tx, &WatchEvent{Type: WatchEventConfirmed}))
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = db.View("", func(tx *dbutil.Tx) error {
+		var ids []uint64
+		require.NoError(t, dbutil.ForEach(tx, WatchEventsBkt, func(k, _ []byte) error {
+			ids = append(ids, dbutil.Btoi(k))
+			return nil
+		}))
+		require.Equal(t, []uint64{3, MaxWatchEvents, MaxWatchEvents + 1, MaxWatchEvents + 2}, ids)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// A database without the watch list buckets has no watched addresses
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		_, err := wl.add(tx, []cipher.Address{testutil.MakeAddress()})
+		require.NoError(t, err)
+		require.False(t, wl.isEmpty(tx))
+
+		require.NoError(t, tx.DeleteBucket(WatchAddressesBkt))
+		require.True(t, wl.isEmpty(tx))
+		return nil
+	})
+	require.NoError(t, err)
+
+	var nilWatchList *watchList
+	err = db.View("", func(tx *dbutil.Tx) error {
+		require.True(t, nilWatchList.isEmpty(tx))
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestWatchListEncrypted(t *testing.T) {
+	db, shutdown := prepareDB(t)
+	defer shutdown()
+
+	wl := newWatchList()
+	addr := testutil.MakeAddress()
+	legacyAddr := testutil.MakeAddress()
+
+	// A database that predates the migration stores the address in the key, with an empty value
+	err := db.Update("", func(tx *dbutil.Tx) error {
+		_, err := wl.add(tx, []cipher.Address{addr})
+		require.NoError(t, err)
+		return dbutil.PutBucketValue(tx, WatchAddressesBkt, legacyAddr.Bytes(), []byte{})
+	})
+	require.NoError(t, err)
+
+	err = db.View("", func(tx *dbutil.Tx) error {
+		addrs, err := wl.addresses(tx)
+		require.NoError(t, err)
+		require.Len(t, addrs, 2)
+		require.Contains(t, addrs, addr)
+		require.Contains(t, addrs, legacyAddr)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// The watched addresses are kept when the database is encrypted, and their keys are hidden
+	dbPath := db.Path()
+	require.NoError(t, db.Close())
+
+	cfg := NewOpenDBConfig()
+	cfg.EncryptionPassphrase = []byte("secret")
+	db, err = OpenDBWithConfig(dbPath, cfg)
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		require.NoError(t, maybeMigrateWatchAddresses(tx))
+
+		n, err := dbutil.Len(tx, WatchAddressesBkt)
+		require.NoError(t, err)
+		require.Equal(t, uint64(2), n)
+
+		require.NoError(t, tx.Bucket(WatchAddressesBkt).ForEach(func(k, _ []byte) error {
+			require.Len(t, k, 32)
+			return nil
+		}))
+
+		addrs, err := wl.addresses(tx)
+		require.NoError(t, err)
+		require.Len(t, addrs, 2)
+		require.Contains(t, addrs, addr)
+		require.Contains(t, addrs, legacyAddr)
+
+		ok, err := isWatched(tx, legacyAddr)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		// A legacy row of a database whose keys were hidden before the watch list is rekeyed
+		otherAddr := testutil.MakeAddress()
+		require.NoError(t, dbutil.PutBucketValue(tx, WatchAddressesBkt, otherAddr.Bytes(), []byte{}))
+		require.NoError(t, maybeMigrateWatchAddresses(tx))
+
+		ok, err = dbutil.BucketHasKey(tx, WatchAddressesBkt, otherAddr.Bytes())
+		require.NoError(t, err)
+		require.False(t, ok)
+
+		ok, err = isWatched(tx, otherAddr)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		n, err = wl.remove(tx, []cipher.Address{addr, legacyAddr, otherAddr})
+		require.NoError(t, err)
+		require.Equal(t, uint64(3), n)
+		require.True(t, wl.isEmpty(tx))
+		return nil
+	})
+	require.NoError(t, err)
+}
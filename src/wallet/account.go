@@ -0,0 +1,268 @@
+package wallet
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+const (
+	// DefaultAccount is the name of the account of the addresses generated from the wallet seed itself.
+	// Every wallet has it, including the wallets created before accounts existed.
+	DefaultAccount = "default"
+	// MaxAccountNameLength is the maximum length of the name of an account
+	MaxAccountNameLength = 64
+)
+
+var (
+	// ErrAccountNotExist is returned if an account is not found in a wallet
+	ErrAccountNotExist = NewError(errors.New("account does not exist"))
+	// ErrAccountExists is returned when creating an account with the name of an existing account
+	ErrAccountExists = NewError(errors.New("account already exists"))
+	// ErrInvalidAccountName is returned if an account name is empty, too long or padded with whitespace
+	ErrInvalidAccountName = NewError(fmt.Errorf("account name must be 1 to %d characters, without leading or trailing whitespace", MaxAccountNameLength))
+)
+
+// Account is a named branch of the key derivation of a wallet seed. The addresses of an account
+// are generated from their own branch seed, so accounts have independent balances, address pools
+// and transaction histories while sharing the seed, password and backups of the wallet.
+type Account struct {
+	// Index is the derivation branch of the account, 0 for the default account
+	Index uint32
+	// Name is the unique name of the account in the wallet
+	Name string
+}
+
+// validateAccountName checks that name can be the name of a new account
+func validateAccountName(name string) error {
+	if name == "" || len(name) > MaxAccountNameLength || strings.TrimSpace(name) != name {
+		return ErrInvalidAccountName
+	}
+	return nil
+}
+
+// ListAccounts returns the accounts of the wallet, starting with the default account
+func (w *Wallet) ListAccounts() []Account {
+	accounts := make([]Account, 0, len(w.Accounts)+1)
+	accounts = append(accounts, Account{
+		Index: 0,
+		Name:  DefaultAccount,
+	})
+	return append(accounts, w.Accounts...)
+}
+
+// GetAccount returns the account with the given name. An empty name is the default account.
+// Returns ErrAccountNotExist if the wallet has no such account.
+func (w *Wallet) GetAccount(name string) (Account, error) {
+	for _, a := range w.ListAccounts() {
+		if a.Name == name || (name == "" && a.Index == 0) {
+			return a, nil
+		}
+	}
+	return Account{}, ErrAccountNotExist
+}
+
+// CreateAccount adds a named account to the wallet, with the next derivation branch,
+// and generates the first address of the account
+func (w *Wallet) CreateAccount(name string) (Account, error) {
+	if err := validateAccountName(name); err != nil {
+		return Account{}, err
+	}
+
+	if w.IsWatchOnly() {
+		return Account{}, ErrWalletWatchOnly
+	}
+
+	if w.Type() == WalletTypeCollection {
+		return Account{}, ErrWalletNotDeterministic
+	}
+
+	if w.IsEncrypted() {
+		return Account{}, ErrWalletEncrypted
+	}
+
+	if _, err := w.GetAccount(name); err == nil {
+		return Account{}, ErrAccountExists
+	}
+
+	a := Account{
+		Index: uint32(len(w.Accounts) + 1),
+		Name:  name,
+	}
+	w.Accounts = append(w.Accounts, a)
+
+	if _, err := w.GenerateAccountAddresses(name, 1); err != nil {
+		w.Accounts = w.Accounts[:len(w.Accounts)-1]
+		return Account{}, err
+	}
+
+	return a, nil
+}
+
+// AccountAddresses returns the Skycoin addresses of an account, in the order of the wallet.
+// An empty name is the default account.
+func (w *Wallet) AccountAddresses(name string) ([]cipher.Address, error) {
+	a, err := w.GetAccount(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if w.coin() != CoinTypeSkycoin {
+		return nil, errors.New("Wallet coin type is not Skycoin")
+	}
+
+	var addrs []cipher.Address
+	for _, e := range w.Entries {
+		if e.Account == a.Index {
+			addrs = append(addrs, e.SkycoinAddress())
+		}
+	}
+
+	return addrs, nil
+}
+
+// GenerateAccountAddresses generates Skycoin addresses in an account. An empty name is the default account,
+// whose addresses are generated like GenerateSkycoinAddresses does.
+func (w *Wallet) GenerateAccountAddresses(name string, num uint64) ([]cipher.Address, error) {
+	a, err := w.GetAccount(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.Index == 0 {
+		return w.GenerateSkycoinAddresses(num)
+	}
+
+	if w.coin() != CoinTypeSkycoin {
+		return nil, errors.New("GenerateAccountAddresses called for non-skycoin wallet")
+	}
+
+	if num == 0 {
+		return nil, nil
+	}
+
+	if w.IsEncrypted() {
+		return nil, ErrWalletEncrypted
+	}
+
+	if w.IsWatchOnly() {
+		return nil, ErrWalletWatchOnly
+	}
+
+	if w.Type() == WalletTypeCollection {
+		return nil, ErrWalletNotDeterministic
+	}
+
+	// The keys of an account are not chained from a stored last seed like the default account's,
+	// they are regenerated from the branch seed of the account each time
+	n := w.accountSize(a.Index)
+	seckeys := cipher.MustGenerateDeterministicKeyPairs(w.accountSeed(a.Index), int(n+num))[n:]
+
+	addrs := make([]cipher.Address, len(seckeys))
+	for i, s := range seckeys {
+		p := cipher.MustPubKeyFromSecKey(s)
+		addrs[i] = cipher.AddressFromPubKey(p)
+		w.Entries = append(w.Entries, Entry{
+			Address: addrs[i],
+			Public:  p,
+			Secret:  s,
+			Account: a.Index,
+		})
+	}
+
+	return addrs, nil
+}
+
+// accountSeed returns the seed of the derivation branch of a named account,
+// the SHA256 of the key seed of the wallet and the index of the account
+func (w *Wallet) accountSeed(index uint32) []byte {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], index)
+
+	data := append([]byte("account"), b[:]...)
+	data = append(data, w.keySeed()...)
+	seed := cipher.SumSHA256(data)
+	return seed[:]
+}
+
+// accountSize returns the number of addresses of the account with the given index
+func (w *Wallet) accountSize(index uint32) uint64 {
+	var n uint64
+	for _, e := range w.Entries {
+		if e.Account == index {
+			n++
+		}
+	}
+	return n
+}
+
+// validateAccounts checks that the named accounts have unique names and consecutive indexes,
+// and that every entry belongs to an account of the wallet
+func (w *Wallet) validateAccounts() error {
+	if len(w.Accounts) != 0 && w.Type() != WalletTypeDeterministic {
+		return fmt.Errorf("%s wallet has accounts", w.Type())
+	}
+
+	names := make(map[string]struct{}, len(w.Accounts))
+	for i, a := range w.Accounts {
+		if a.Index != uint32(i+1) {
+			return fmt.Errorf("account %q has index %d, expected %d", a.Name, a.Index, i+1)
+		}
+
+		if a.Name == DefaultAccount {
+			return fmt.Errorf("account %d can't be named %q", a.Index, DefaultAccount)
+		}
+
+		if err := validateAccountName(a.Name); err != nil {
+			return fmt.Errorf("account %d: %v", a.Index, err)
+		}
+
+		if _, ok := names[a.Name]; ok {
+			return fmt.Errorf("duplicate account name %q", a.Name)
+		}
+		names[a.Name] = struct{}{}
+	}
+
+	for _, e := range w.Entries {
+		if e.Account > uint32(len(w.Accounts)) {
+			return fmt.Errorf("address %s belongs to unknown account %d", e.Address, e.Account)
+		}
+	}
+
+	return nil
+}
+
+// ReadableAccount is the JSON representation of a named Account, in the wallet file
+type ReadableAccount struct {
+	Index uint32 `json:"index"`
+	Name  string `json:"name"`
+}
+
+// newReadableAccounts creates the ReadableAccounts of the named accounts of a wallet
+func newReadableAccounts(accounts []Account) []ReadableAccount {
+	if len(accounts) == 0 {
+		return nil
+	}
+
+	ra := make([]ReadableAccount, len(accounts))
+	for i, a := range accounts {
+		ra[i] = ReadableAccount(a)
+	}
+	return ra
+}
+
+// toAccounts converts ReadableAccounts to Accounts
+func toAccounts(ra []ReadableAccount) []Account {
+	if len(ra) == 0 {
+		return nil
+	}
+
+	accounts := make([]Account, len(ra))
+	for i, a := range ra {
+		accounts[i] = Account(a)
+	}
+	return accounts
+}
@@ -0,0 +1,256 @@
+package wallet
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/testutil"
+)
+
+func TestWalletAccounts(t *testing.T) {
+	w := makeWallet(t, Options{
+		Seed: "seed",
+	}, 2)
+	defaultAddrs, err := w.GetSkycoinAddresses()
+	require.NoError(t, err)
+
+	require.Equal(t, []Account{{Index: 0, Name: DefaultAccount}}, w.ListAccounts())
+
+	_, err = w.GetAccount("savings")
+	require.Equal(t, ErrAccountNotExist, err)
+
+	for _, name := range []string{"", " savings", strings.Repeat("a", MaxAccountNameLength+1)} {
+		_, err = w.CreateAccount(name)
+		require.Equal(t, ErrInvalidAccountName, err)
+	}
+	_, err = w.CreateAccount(DefaultAccount)
+	require.Equal(t, ErrAccountExists, err)
+
+	savings, err := w.CreateAccount("savings")
+	require.NoError(t, err)
+	require.Equal(t, Account{Index: 1, Name: "savings"}, savings)
+	_, err = w.CreateAccount("savings")
+	require.Equal(t, ErrAccountExists, err)
+
+	trading, err := w.CreateAccount("trading")
+	require.NoError(t, err)
+	require.Equal(t, Account{Index: 2, Name: "trading"}, trading)
+	require.Equal(t, []Account{{Index: 0, Name: DefaultAccount}, savings, trading}, w.ListAccounts())
+
+	// The accounts are derived from their own branches, which don't overlap with the default account
+	savingsAddrs, err := w.GenerateAccountAddresses("savings", 2)
+	require.NoError(t, err)
+	require.Len(t, savingsAddrs, 2)
+
+	addrs, err := w.AccountAddresses("savings")
+	require.NoError(t, err)
+	require.Len(t, addrs, 3)
+	require.Equal(t, savingsAddrs, addrs[1:])
+
+	tradingAddrs, err := w.AccountAddresses("trading")
+	require.NoError(t, err)
+	require.Len(t, tradingAddrs, 1)
+	require.NotContains(t, addrs, tradingAddrs[0])
+	require.NotContains(t, defaultAddrs, tradingAddrs[0])
+	for _, a := range addrs {
+		require.NotContains(t, defaultAddrs, a)
+	}
+
+	// The default account is unchanged, and keeps generating its addresses from the wallet seed
+	addrs, err = w.AccountAddresses("")
+	require.NoError(t, err)
+	require.Equal(t, defaultAddrs, addrs)
+
+	newAddrs, err := w.GenerateAccountAddresses(DefaultAccount, 1)
+	require.NoError(t, err)
+	seedWlt := makeWallet(t, Options{
+		Seed: "seed",
+	}, 3)
+	require.Equal(t, seedWlt.Entries[2].SkycoinAddress(), newAddrs[0])
+
+	// The addresses of an account are regenerated from the seed in the same order
+	w2 := makeWallet(t, Options{
+		Seed: "seed",
+	}, 1)
+	_, err = w2.CreateAccount("savings")
+	require.NoError(t, err)
+	_, err = w2.GenerateAccountAddresses("savings", 2)
+	require.NoError(t, err)
+	addrs, err = w.AccountAddresses("savings")
+	require.NoError(t, err)
+	addrs2, err := w2.AccountAddresses("savings")
+	require.NoError(t, err)
+	require.Equal(t, addrs, addrs2)
+
+	// The address pools of the accounts are independent
+	status, err := w.AddressPoolStatus("savings")
+	require.NoError(t, err)
+	require.Equal(t, AddressPoolStatus{Generated: 3, Unused: 3}, status)
+
+	taken, err := w.TakePoolAddresses("trading", 1)
+	require.NoError(t, err)
+	require.Equal(t, tradingAddrs, taken)
+	_, err = w.TakePoolAddresses("trading", 1)
+	require.Equal(t, ErrAddressPoolExhausted, err)
+
+	filled, err := w.FillAddressPool("trading", 2)
+	require.NoError(t, err)
+	require.Len(t, filled, 2)
+	status, err = w.AddressPoolStatus("trading")
+	require.NoError(t, err)
+	require.Equal(t, AddressPoolStatus{Generated: 3, Issued: 1, Unused: 2}, status)
+
+	status, err = w.AddressPoolStatus("")
+	require.NoError(t, err)
+	require.Equal(t, AddressPoolStatus{Generated: 3, Unused: 3}, status)
+
+	// The wallet file keeps the accounts of the addresses
+	rw := NewReadableWallet(w)
+	require.Equal(t, []ReadableAccount{{Index: 1, Name: "savings"}, {Index: 2, Name: "trading"}}, rw.Accounts)
+	w3, err := rw.ToWallet()
+	require.NoError(t, err)
+	require.Equal(t, w.Accounts, w3.Accounts)
+	require.Equal(t, w.Entries, w3.Entries)
+
+	rw.Accounts = rw.Accounts[:1]
+	_, err = rw.ToWallet()
+	require.Error(t, err)
+
+	rw.Accounts = []ReadableAccount{{Index: 1, Name: "savings"}, {Index: 2, Name: "savings"}}
+	_, err = rw.ToWallet()
+	require.Error(t, err)
+
+	// The seed is needed to create an account
+	require.NoError(t, w.Lock([]byte("pwd"), CryptoTypeSha256Xor))
+	_, err = w.CreateAccount("spending")
+	require.Equal(t, ErrWalletEncrypted, err)
+
+	wo := &Wallet{
+		Meta: map[string]string{
+			metaType: WalletTypeWatchOnly,
+			metaCoin: string(CoinTypeSkycoin),
+		},
+	}
+	_, err = wo.CreateAccount("savings")
+	require.Equal(t, ErrWalletWatchOnly, err)
+}
+
+func TestWalletAccountSpend(t *testing.T) {
+	headTime := uint64(time.Now().UTC().Unix())
+	w := makeWallet(t, Options{
+		Seed: "seed",
+	}, 1)
+	_, err := w.CreateAccount("savings")
+	require.NoError(t, err)
+	_, err = w.GenerateAccountAddresses("savings", 2)
+	require.NoError(t, err)
+
+	savingsEntries := w.Entries[1:]
+	auxs := coin.AddressUxOuts{
+		savingsEntries[0].SkycoinAddress(): []coin.UxOut{makeUxOut(t, savingsEntries[0].Secret, 3e6, 100)},
+	}
+
+	p := CreateTransactionParams{
+		HoursSelection: HoursSelection{
+			Type: HoursSelectionTypeManual,
+		},
+		Wallet: CreateTransactionWalletParams{
+			ID:      "t.wlt",
+			Account: "savings",
+		},
+		ChangePolicy: ChangePolicyNewAddress,
+		To: []coin.TransactionOutput{
+			{
+				Address: testutil.MakeAddress(),
+				Coins:   1e6,
+				Hours:   10,
+			},
+		},
+	}
+
+	// The change goes to an unused address of the account spent from
+	txn, _, err := w.CreateAndSignTransactionAdvanced(p, auxs, headTime)
+	require.NoError(t, err)
+	require.Len(t, txn.Out, 2)
+	require.Equal(t, savingsEntries[1].SkycoinAddress(), txn.Out[1].Address)
+
+	// The outputs of the other accounts can't be spent
+	auxs[w.Entries[0].SkycoinAddress()] = []coin.UxOut{makeUxOut(t, w.Entries[0].Secret, 3e6, 100)}
+	_, _, err = w.CreateAndSignTransactionAdvanced(p, auxs, headTime)
+	require.Equal(t, ErrUnknownAddress, err)
+
+	p.Wallet.Account = "foo"
+	_, _, err = w.CreateAndSignTransactionAdvanced(p, auxs, headTime)
+	require.Equal(t, ErrAccountNotExist, err)
+}
+
+func TestServiceCreateAccount(t *testing.T) {
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	s, err := NewService(Config{
+		WalletDir:       dir,
+		CryptoType:      CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	_, err = s.CreateWallet("t.wlt", Options{
+		Seed:     "seed",
+		Encrypt:  true,
+		Password: []byte("pwd"),
+	}, nil)
+	require.NoError(t, err)
+
+	_, err = s.CreateAccount("t.wlt", "savings", nil)
+	require.Equal(t, ErrMissingPassword, err)
+	_, err = s.CreateAccount("foo.wlt", "savings", []byte("pwd"))
+	require.Equal(t, ErrWalletNotExist, err)
+
+	a, err := s.CreateAccount("t.wlt", "savings", []byte("pwd"))
+	require.NoError(t, err)
+	require.Equal(t, Account{Index: 1, Name: "savings"}, a)
+
+	_, err = s.CreateAccount("t.wlt", "savings", []byte("pwd"))
+	require.Equal(t, ErrAccountExists, err)
+
+	// The address pool of the account is filled, and the wallet is saved encrypted with the account
+	addrs, err := s.FillAddressPool("t.wlt", "savings", []byte("pwd"), 3)
+	require.NoError(t, err)
+	require.Len(t, addrs, 2)
+
+	s, err = NewService(Config{
+		WalletDir:       dir,
+		CryptoType:      CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+	w, err := s.GetWallet("t.wlt")
+	require.NoError(t, err)
+	require.True(t, w.IsEncrypted())
+	checkNoSensitiveData(t, w)
+	require.Equal(t, []Account{a}, w.Accounts)
+
+	accountAddrs, err := w.AccountAddresses("savings")
+	require.NoError(t, err)
+	require.Len(t, accountAddrs, 3)
+	require.Equal(t, addrs, accountAddrs[1:])
+
+	// The account's keys are kept encrypted with the other keys of the wallet
+	require.NoError(t, s.ViewSecrets("t.wlt", []byte("pwd"), func(w *Wallet) error {
+		for _, e := range w.Entries {
+			require.NoError(t, e.Verify())
+		}
+		return nil
+	}))
+
+	taken, err := s.TakePoolAddresses("t.wlt", "savings", 1)
+	require.NoError(t, err)
+	require.Equal(t, []cipher.Address{accountAddrs[0]}, taken)
+}
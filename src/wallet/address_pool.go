@@ -0,0 +1,311 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+const (
+	// MaxAddressPoolSize is the maximum number of unused addresses that FillAddressPool pre-generates
+	MaxAddressPoolSize = 1000
+)
+
+var (
+	// ErrAddressPoolSizeTooLarge is returned if the size of an address pool is larger than MaxAddressPoolSize
+	ErrAddressPoolSizeTooLarge = NewError(fmt.Errorf("address pool size can't be larger than %d", MaxAddressPoolSize))
+	// ErrAddressPoolExhausted is returned if the address pool doesn't have enough unused addresses to hand out
+	ErrAddressPoolExhausted = NewError(errors.New("address pool doesn't have enough unused addresses"))
+)
+
+// AddressPool tracks which addresses of a wallet have been used on the blockchain or handed out,
+// so that the unused addresses pre-generated by FillAddressPool can be handed out as deposit addresses
+// without the seed. It is not secret, and is stored unencrypted in the wallet file.
+type AddressPool struct {
+	// Used are the addresses that have been seen on the blockchain
+	Used map[cipher.Address]struct{}
+	// Issued are the addresses handed out by TakePoolAddresses that have not been used yet
+	Issued map[cipher.Address]struct{}
+}
+
+func (p AddressPool) clone() AddressPool {
+	var c AddressPool
+
+	if len(p.Used) != 0 {
+		c.Used = make(map[cipher.Address]struct{}, len(p.Used))
+		for k := range p.Used {
+			c.Used[k] = struct{}{}
+		}
+	}
+
+	if len(p.Issued) != 0 {
+		c.Issued = make(map[cipher.Address]struct{}, len(p.Issued))
+		for k := range p.Issued {
+			c.Issued[k] = struct{}{}
+		}
+	}
+
+	return c
+}
+
+// AddressPoolStatus counts the addresses of a wallet by their state in the address pool
+type AddressPoolStatus struct {
+	// Generated is the number of addresses of the wallet
+	Generated uint64
+	// Used is the number of addresses that have been seen on the blockchain
+	Used uint64
+	// Issued is the number of addresses handed out that have not been used yet
+	Issued uint64
+	// Unused is the number of addresses that can be handed out
+	Unused uint64
+}
+
+// validateAddressPool checks that the addresses of the pool are in the wallet, and are not both used and issued
+func (w *Wallet) validateAddressPool() error {
+	if len(w.AddressPool.Used) == 0 && len(w.AddressPool.Issued) == 0 {
+		return nil
+	}
+
+	addrs, err := w.skycoinAddressSet()
+	if err != nil {
+		return err
+	}
+
+	check := func(m map[cipher.Address]struct{}) error {
+		for a := range m {
+			if _, ok := addrs[a]; !ok {
+				return fmt.Errorf("address pool address %s is not in the wallet", a)
+			}
+		}
+		return nil
+	}
+
+	if err := check(w.AddressPool.Used); err != nil {
+		return err
+	}
+
+	if err := check(w.AddressPool.Issued); err != nil {
+		return err
+	}
+
+	for a := range w.AddressPool.Issued {
+		if _, ok := w.AddressPool.Used[a]; ok {
+			return fmt.Errorf("address pool address %s is both used and issued", a)
+		}
+	}
+
+	return nil
+}
+
+// skycoinAddressSet returns the set of the addresses of the wallet. The wallet's coin type must be Skycoin.
+func (w *Wallet) skycoinAddressSet() (map[cipher.Address]struct{}, error) {
+	addrs, err := w.GetSkycoinAddresses()
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[cipher.Address]struct{}, len(addrs))
+	for _, a := range addrs {
+		m[a] = struct{}{}
+	}
+
+	return m, nil
+}
+
+// AddressPoolStatus returns the status of the address pool of an account of the wallet.
+// An empty account name is the default account.
+func (w *Wallet) AddressPoolStatus(account string) (AddressPoolStatus, error) {
+	addrs, err := w.AccountAddresses(account)
+	if err != nil {
+		return AddressPoolStatus{}, err
+	}
+
+	status := AddressPoolStatus{
+		Generated: uint64(len(addrs)),
+	}
+	for _, a := range addrs {
+		if _, ok := w.AddressPool.Used[a]; ok {
+			status.Used++
+		} else if _, ok := w.AddressPool.Issued[a]; ok {
+			status.Issued++
+		} else {
+			status.Unused++
+		}
+	}
+
+	return status, nil
+}
+
+// UnusedAddresses returns the addresses of an account of the wallet that are neither used nor issued,
+// in the order of the wallet. An empty account name is the default account.
+func (w *Wallet) UnusedAddresses(account string) ([]cipher.Address, error) {
+	addrs, err := w.AccountAddresses(account)
+	if err != nil {
+		return nil, err
+	}
+
+	var unused []cipher.Address
+	for _, a := range addrs {
+		if _, ok := w.AddressPool.Used[a]; ok {
+			continue
+		}
+		if _, ok := w.AddressPool.Issued[a]; ok {
+			continue
+		}
+		unused = append(unused, a)
+	}
+
+	return unused, nil
+}
+
+// FillAddressPool generates addresses in an account until it has size unused addresses. Returns the added addresses.
+// An empty account name is the default account.
+func (w *Wallet) FillAddressPool(account string, size uint64) ([]cipher.Address, error) {
+	if size > MaxAddressPoolSize {
+		return nil, ErrAddressPoolSizeTooLarge
+	}
+
+	status, err := w.AddressPoolStatus(account)
+	if err != nil {
+		return nil, err
+	}
+
+	if status.Unused >= size {
+		return nil, nil
+	}
+
+	return w.GenerateAccountAddresses(account, size-status.Unused)
+}
+
+// MarkAddressesUsed marks addresses of the wallet as seen on the blockchain, which are not handed out anymore.
+// Returns the addresses that were not marked yet, or ErrUnknownAddress if an address is not in the wallet.
+func (w *Wallet) MarkAddressesUsed(addrs []cipher.Address) ([]cipher.Address, error) {
+	wltAddrs, err := w.skycoinAddressSet()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, a := range addrs {
+		if _, ok := wltAddrs[a]; !ok {
+			return nil, ErrUnknownAddress
+		}
+	}
+
+	var marked []cipher.Address
+	for _, a := range addrs {
+		if _, ok := w.AddressPool.Used[a]; ok {
+			continue
+		}
+
+		if w.AddressPool.Used == nil {
+			w.AddressPool.Used = make(map[cipher.Address]struct{})
+		}
+		w.AddressPool.Used[a] = struct{}{}
+
+		delete(w.AddressPool.Issued, a)
+		if len(w.AddressPool.Issued) == 0 {
+			w.AddressPool.Issued = nil
+		}
+
+		marked = append(marked, a)
+	}
+
+	return marked, nil
+}
+
+// TakePoolAddresses hands out the first n unused addresses of an account of the wallet, which are marked as issued
+// so that they are not handed out again. The seed is not needed. An empty account name is the default account.
+// Returns ErrAddressPoolExhausted if the account doesn't have n unused addresses.
+func (w *Wallet) TakePoolAddresses(account string, n uint64) ([]cipher.Address, error) {
+	if n > MaxAddressPoolSize {
+		return nil, ErrAddressPoolSizeTooLarge
+	}
+
+	unused, err := w.UnusedAddresses(account)
+	if err != nil {
+		return nil, err
+	}
+
+	if uint64(len(unused)) < n {
+		return nil, ErrAddressPoolExhausted
+	}
+
+	taken := unused[:n]
+	for _, a := range taken {
+		if w.AddressPool.Issued == nil {
+			w.AddressPool.Issued = make(map[cipher.Address]struct{})
+		}
+		w.AddressPool.Issued[a] = struct{}{}
+	}
+
+	return taken, nil
+}
+
+// ReadableAddressPool is the JSON representation of an AddressPool, in the wallet file
+type ReadableAddressPool struct {
+	Used   []string `json:"used,omitempty"`
+	Issued []string `json:"issued,omitempty"`
+}
+
+// newReadableAddressPool creates a ReadableAddressPool with the addresses in the order of the wallet,
+// returns nil if the pool is empty
+func newReadableAddressPool(w *Wallet) *ReadableAddressPool {
+	if len(w.AddressPool.Used) == 0 && len(w.AddressPool.Issued) == 0 {
+		return nil
+	}
+
+	var rp ReadableAddressPool
+	for _, e := range w.Entries {
+		a, ok := e.Address.(cipher.Address)
+		if !ok {
+			continue
+		}
+
+		if _, ok := w.AddressPool.Used[a]; ok {
+			rp.Used = append(rp.Used, a.String())
+		}
+		if _, ok := w.AddressPool.Issued[a]; ok {
+			rp.Issued = append(rp.Issued, a.String())
+		}
+	}
+
+	return &rp
+}
+
+// toAddressPool converts a ReadableAddressPool to an AddressPool
+func (rp *ReadableAddressPool) toAddressPool() (AddressPool, error) {
+	var p AddressPool
+	if rp == nil {
+		return p, nil
+	}
+
+	decode := func(addrs []string) (map[cipher.Address]struct{}, error) {
+		if len(addrs) == 0 {
+			return nil, nil
+		}
+
+		m := make(map[cipher.Address]struct{}, len(addrs))
+		for _, s := range addrs {
+			a, err := cipher.DecodeBase58Address(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid address pool address %q: %v", s, err)
+			}
+			m[a] = struct{}{}
+		}
+		return m, nil
+	}
+
+	var err error
+	p.Used, err = decode(rp.Used)
+	if err != nil {
+		return AddressPool{}, err
+	}
+
+	p.Issued, err = decode(rp.Issued)
+	if err != nil {
+		return AddressPool{}, err
+	}
+
+	return p, nil
+}
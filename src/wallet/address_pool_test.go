@@ -0,0 +1,177 @@
+package wallet
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/testutil"
+)
+
+func TestWalletAddressPool(t *testing.T) {
+	w := makeWallet(t, Options{
+		Seed: "seed",
+	}, 1)
+	seedWlt := makeWallet(t, Options{
+		Seed: "seed",
+	}, 6)
+	seedAddr := func(i int) cipher.Address {
+		return seedWlt.Entries[i].SkycoinAddress()
+	}
+	status := func() AddressPoolStatus {
+		s, err := w.AddressPoolStatus("")
+		require.NoError(t, err)
+		return s
+	}
+
+	require.Equal(t, AddressPoolStatus{
+		Generated: 1,
+		Unused:    1,
+	}, status())
+
+	_, err := w.FillAddressPool("", MaxAddressPoolSize+1)
+	require.Equal(t, ErrAddressPoolSizeTooLarge, err)
+
+	addrs, err := w.FillAddressPool("", 4)
+	require.NoError(t, err)
+	require.Equal(t, []cipher.Address{seedAddr(1), seedAddr(2), seedAddr(3)}, addrs)
+
+	// The pool is already filled
+	addrs, err = w.FillAddressPool("", 4)
+	require.NoError(t, err)
+	require.Empty(t, addrs)
+
+	// The first unused addresses are handed out, and not handed out again
+	addrs, err = w.TakePoolAddresses("", 2)
+	require.NoError(t, err)
+	require.Equal(t, []cipher.Address{seedAddr(0), seedAddr(1)}, addrs)
+
+	addrs, err = w.TakePoolAddresses("", 1)
+	require.NoError(t, err)
+	require.Equal(t, []cipher.Address{seedAddr(2)}, addrs)
+
+	_, err = w.TakePoolAddresses("", 2)
+	require.Equal(t, ErrAddressPoolExhausted, err)
+
+	// Used addresses are not issued anymore
+	_, err = w.MarkAddressesUsed([]cipher.Address{testutil.MakeAddress()})
+	require.Equal(t, ErrUnknownAddress, err)
+
+	marked, err := w.MarkAddressesUsed([]cipher.Address{seedAddr(1), seedAddr(3)})
+	require.NoError(t, err)
+	require.Equal(t, []cipher.Address{seedAddr(1), seedAddr(3)}, marked)
+
+	marked, err = w.MarkAddressesUsed([]cipher.Address{seedAddr(1)})
+	require.NoError(t, err)
+	require.Empty(t, marked)
+
+	require.Equal(t, AddressPoolStatus{
+		Generated: 4,
+		Used:      2,
+		Issued:    2,
+		Unused:    0,
+	}, status())
+
+	addrs, err = w.FillAddressPool("", 2)
+	require.NoError(t, err)
+	require.Equal(t, []cipher.Address{seedAddr(4), seedAddr(5)}, addrs)
+
+	unused, err := w.UnusedAddresses("")
+	require.NoError(t, err)
+	require.Equal(t, []cipher.Address{seedAddr(4), seedAddr(5)}, unused)
+
+	// The wallet file keeps the address pool
+	rw := NewReadableWallet(w)
+	require.Equal(t, &ReadableAddressPool{
+		Used:   []string{seedAddr(1).String(), seedAddr(3).String()},
+		Issued: []string{seedAddr(0).String(), seedAddr(2).String()},
+	}, rw.AddressPool)
+
+	w2, err := rw.ToWallet()
+	require.NoError(t, err)
+	require.Equal(t, w.AddressPool, w2.AddressPool)
+
+	// The addresses of the pool must be in the wallet
+	rw.AddressPool.Issued = append(rw.AddressPool.Issued, testutil.MakeAddress().String())
+	_, err = rw.ToWallet()
+	require.Error(t, err)
+
+	rw.AddressPool.Issued = []string{seedAddr(1).String()}
+	_, err = rw.ToWallet()
+	require.Error(t, err)
+}
+
+func TestServiceAddressPool(t *testing.T) {
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	s, err := NewService(Config{
+		WalletDir:       dir,
+		CryptoType:      CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	_, err = s.CreateWallet("t.wlt", Options{
+		Seed:     "seed",
+		Encrypt:  true,
+		Password: []byte("pwd"),
+	}, nil)
+	require.NoError(t, err)
+
+	// The seed is needed to fill the pool
+	_, err = s.FillAddressPool("t.wlt", "", nil, 3)
+	require.Equal(t, ErrMissingPassword, err)
+	_, err = s.FillAddressPool("t.wlt", "", []byte("wrong"), 3)
+	require.Equal(t, ErrInvalidPassword, err)
+	_, err = s.FillAddressPool("foo.wlt", "", []byte("pwd"), 3)
+	require.Equal(t, ErrWalletNotExist, err)
+
+	addrs, err := s.FillAddressPool("t.wlt", "", []byte("pwd"), 3)
+	require.NoError(t, err)
+	require.Len(t, addrs, 2)
+
+	// But not to hand out addresses
+	taken, err := s.TakePoolAddresses("t.wlt", "", 2)
+	require.NoError(t, err)
+	require.Len(t, taken, 2)
+
+	marked, err := s.MarkAddressesUsed("t.wlt", taken[:1])
+	require.NoError(t, err)
+	require.Equal(t, taken[:1], marked)
+
+	// The wallet is saved encrypted with the address pool
+	s, err = NewService(Config{
+		WalletDir:       dir,
+		CryptoType:      CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+	w, err := s.GetWallet("t.wlt")
+	require.NoError(t, err)
+	require.True(t, w.IsEncrypted())
+	checkNoSensitiveData(t, w)
+	poolStatus, err := w.AddressPoolStatus("")
+	require.NoError(t, err)
+	require.Equal(t, AddressPoolStatus{
+		Generated: 3,
+		Used:      1,
+		Issued:    1,
+		Unused:    1,
+	}, poolStatus)
+
+	// Disabled wallet API
+	s, err = NewService(Config{
+		WalletDir:  dir,
+		CryptoType: CryptoTypeSha256Xor,
+	})
+	require.NoError(t, err)
+	_, err = s.FillAddressPool("t.wlt", "", []byte("pwd"), 3)
+	require.Equal(t, ErrWalletAPIDisabled, err)
+	_, err = s.TakePoolAddresses("t.wlt", "", 1)
+	require.Equal(t, ErrWalletAPIDisabled, err)
+	_, err = s.MarkAddressesUsed("t.wlt", taken)
+	require.Equal(t, ErrWalletAPIDisabled, err)
+}
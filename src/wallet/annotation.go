@@ -0,0 +1,212 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+const (
+	// MaxAnnotationLabelLength is the maximum length of the label and the category of an Annotation
+	MaxAnnotationLabelLength = 100
+	// MaxAnnotationNoteLength is the maximum length of the note of an Annotation
+	MaxAnnotationNoteLength = 1000
+)
+
+var (
+	// ErrAnnotationLabelTooLong is returned if the label or the category of an annotation is too long
+	ErrAnnotationLabelTooLong = NewError(fmt.Errorf("annotation label and category must not be longer than %d characters", MaxAnnotationLabelLength))
+	// ErrAnnotationNoteTooLong is returned if the note of an annotation is too long
+	ErrAnnotationNoteTooLong = NewError(fmt.Errorf("annotation note must not be longer than %d characters", MaxAnnotationNoteLength))
+	// ErrNullTransactionID is returned when annotating a transaction with a null txid
+	ErrNullTransactionID = NewError(errors.New("transaction id is null"))
+)
+
+// Annotation is user metadata of an address or a transaction of a wallet, to annotate payments
+type Annotation struct {
+	Label    string
+	Note     string
+	Category string
+}
+
+// Empty returns true if the annotation has no label, note or category
+func (a Annotation) Empty() bool {
+	return a == Annotation{}
+}
+
+// Validate checks the length of the fields of the annotation
+func (a Annotation) Validate() error {
+	if len([]rune(a.Label)) > MaxAnnotationLabelLength || len([]rune(a.Category)) > MaxAnnotationLabelLength {
+		return ErrAnnotationLabelTooLong
+	}
+
+	if len([]rune(a.Note)) > MaxAnnotationNoteLength {
+		return ErrAnnotationNoteTooLong
+	}
+
+	return nil
+}
+
+// Annotations are the annotations of the addresses and the transactions of a wallet.
+// They are not secret, and are stored unencrypted in the wallet file.
+type Annotations struct {
+	Addresses    map[cipher.Address]Annotation
+	Transactions map[cipher.SHA256]Annotation
+}
+
+func (as Annotations) clone() Annotations {
+	var c Annotations
+
+	if len(as.Addresses) != 0 {
+		c.Addresses = make(map[cipher.Address]Annotation, len(as.Addresses))
+		for k, v := range as.Addresses {
+			c.Addresses[k] = v
+		}
+	}
+
+	if len(as.Transactions) != 0 {
+		c.Transactions = make(map[cipher.SHA256]Annotation, len(as.Transactions))
+		for k, v := range as.Transactions {
+			c.Transactions[k] = v
+		}
+	}
+
+	return c
+}
+
+// AddressAnnotation returns the annotation of an address
+func (w *Wallet) AddressAnnotation(addr cipher.Address) (Annotation, bool) {
+	a, ok := w.Annotations.Addresses[addr]
+	return a, ok
+}
+
+// TransactionAnnotation returns the annotation of a transaction
+func (w *Wallet) TransactionAnnotation(txid cipher.SHA256) (Annotation, bool) {
+	a, ok := w.Annotations.Transactions[txid]
+	return a, ok
+}
+
+// SetAddressAnnotation annotates an address of the wallet. An empty annotation removes the annotation of the address.
+// Returns ErrUnknownAddress if the address is not in the wallet.
+func (w *Wallet) SetAddressAnnotation(addr cipher.Address, a Annotation) error {
+	if err := a.Validate(); err != nil {
+		return err
+	}
+
+	if _, ok := w.GetEntry(addr); !ok {
+		return ErrUnknownAddress
+	}
+
+	if a.Empty() {
+		delete(w.Annotations.Addresses, addr)
+		if len(w.Annotations.Addresses) == 0 {
+			w.Annotations.Addresses = nil
+		}
+		return nil
+	}
+
+	if w.Annotations.Addresses == nil {
+		w.Annotations.Addresses = make(map[cipher.Address]Annotation)
+	}
+	w.Annotations.Addresses[addr] = a
+
+	return nil
+}
+
+// SetTransactionAnnotation annotates a transaction. An empty annotation removes the annotation of the transaction.
+// The wallet does not know its transactions, so any transaction can be annotated.
+func (w *Wallet) SetTransactionAnnotation(txid cipher.SHA256, a Annotation) error {
+	if err := a.Validate(); err != nil {
+		return err
+	}
+
+	if txid.Null() {
+		return ErrNullTransactionID
+	}
+
+	if a.Empty() {
+		delete(w.Annotations.Transactions, txid)
+		if len(w.Annotations.Transactions) == 0 {
+			w.Annotations.Transactions = nil
+		}
+		return nil
+	}
+
+	if w.Annotations.Transactions == nil {
+		w.Annotations.Transactions = make(map[cipher.SHA256]Annotation)
+	}
+	w.Annotations.Transactions[txid] = a
+
+	return nil
+}
+
+// ReadableAnnotation is the JSON representation of an Annotation
+type ReadableAnnotation struct {
+	Label    string `json:"label,omitempty"`
+	Note     string `json:"note,omitempty"`
+	Category string `json:"category,omitempty"`
+}
+
+// ReadableAnnotations is the JSON representation of Annotations, in the wallet file
+type ReadableAnnotations struct {
+	Addresses    map[string]ReadableAnnotation `json:"addresses,omitempty"`
+	Transactions map[string]ReadableAnnotation `json:"transactions,omitempty"`
+}
+
+// newReadableAnnotations creates ReadableAnnotations, returns nil if there are no annotations
+func newReadableAnnotations(as Annotations) *ReadableAnnotations {
+	if len(as.Addresses) == 0 && len(as.Transactions) == 0 {
+		return nil
+	}
+
+	var ra ReadableAnnotations
+
+	if len(as.Addresses) != 0 {
+		ra.Addresses = make(map[string]ReadableAnnotation, len(as.Addresses))
+		for addr, a := range as.Addresses {
+			ra.Addresses[addr.String()] = ReadableAnnotation(a)
+		}
+	}
+
+	if len(as.Transactions) != 0 {
+		ra.Transactions = make(map[string]ReadableAnnotation, len(as.Transactions))
+		for txid, a := range as.Transactions {
+			ra.Transactions[txid.Hex()] = ReadableAnnotation(a)
+		}
+	}
+
+	return &ra
+}
+
+// toAnnotations converts ReadableAnnotations to Annotations
+func (ra *ReadableAnnotations) toAnnotations() (Annotations, error) {
+	var as Annotations
+	if ra == nil {
+		return as, nil
+	}
+
+	if len(ra.Addresses) != 0 {
+		as.Addresses = make(map[cipher.Address]Annotation, len(ra.Addresses))
+		for s, a := range ra.Addresses {
+			addr, err := cipher.DecodeBase58Address(s)
+			if err != nil {
+				return Annotations{}, fmt.Errorf("invalid annotated address %q: %v", s, err)
+			}
+			as.Addresses[addr] = Annotation(a)
+		}
+	}
+
+	if len(ra.Transactions) != 0 {
+		as.Transactions = make(map[cipher.SHA256]Annotation, len(ra.Transactions))
+		for s, a := range ra.Transactions {
+			txid, err := cipher.SHA256FromHex(s)
+			if err != nil {
+				return Annotations{}, fmt.Errorf("invalid annotated transaction %q: %v", s, err)
+			}
+			as.Transactions[txid] = Annotation(a)
+		}
+	}
+
+	return as, nil
+}
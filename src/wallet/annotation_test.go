@@ -0,0 +1,120 @@
+package wallet
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/testutil"
+)
+
+func TestAnnotationValidate(t *testing.T) {
+	cases := []struct {
+		name string
+		a    Annotation
+		err  error
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name: "max length",
+			a: Annotation{
+				Label:    strings.Repeat("é", MaxAnnotationLabelLength),
+				Note:     strings.Repeat("n", MaxAnnotationNoteLength),
+				Category: strings.Repeat("c", MaxAnnotationLabelLength),
+			},
+		},
+		{
+			name: "label too long",
+			a: Annotation{
+				Label: strings.Repeat("l", MaxAnnotationLabelLength+1),
+			},
+			err: ErrAnnotationLabelTooLong,
+		},
+		{
+			name: "category too long",
+			a: Annotation{
+				Category: strings.Repeat("c", MaxAnnotationLabelLength+1),
+			},
+			err: ErrAnnotationLabelTooLong,
+		},
+		{
+			name: "note too long",
+			a: Annotation{
+				Note: strings.Repeat("n", MaxAnnotationNoteLength+1),
+			},
+			err: ErrAnnotationNoteTooLong,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.err, tc.a.Validate())
+		})
+	}
+}
+
+func TestWalletAnnotations(t *testing.T) {
+	w, err := NewWallet("t.wlt", Options{
+		Seed: "seed",
+	})
+	require.NoError(t, err)
+	addr := w.Entries[0].SkycoinAddress()
+	txid := testutil.RandSHA256(t)
+
+	a := Annotation{
+		Label:    "rent",
+		Note:     "march",
+		Category: "housing",
+	}
+
+	err = w.SetAddressAnnotation(testutil.MakeAddress(), a)
+	require.Equal(t, ErrUnknownAddress, err)
+
+	err = w.SetTransactionAnnotation(cipher.SHA256{}, a)
+	require.Equal(t, ErrNullTransactionID, err)
+
+	err = w.SetAddressAnnotation(addr, Annotation{Note: strings.Repeat("n", MaxAnnotationNoteLength+1)})
+	require.Equal(t, ErrAnnotationNoteTooLong, err)
+
+	require.NoError(t, w.SetAddressAnnotation(addr, a))
+	require.NoError(t, w.SetTransactionAnnotation(txid, Annotation{Label: "paid"}))
+
+	got, ok := w.AddressAnnotation(addr)
+	require.True(t, ok)
+	require.Equal(t, a, got)
+
+	got, ok = w.TransactionAnnotation(txid)
+	require.True(t, ok)
+	require.Equal(t, Annotation{Label: "paid"}, got)
+
+	// Clones do not share the annotations
+	c := w.clone()
+	require.Equal(t, w.Annotations, c.Annotations)
+	require.NoError(t, c.SetAddressAnnotation(addr, Annotation{Label: "other"}))
+	got, _ = w.AddressAnnotation(addr)
+	require.Equal(t, a, got)
+
+	// Annotations are saved in the wallet file
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+	require.NoError(t, w.Save(dir))
+	lw, err := Load(filepath.Join(dir, "t.wlt"))
+	require.NoError(t, err)
+	require.Equal(t, w.Annotations, lw.Annotations)
+
+	// Empty annotations remove the annotation
+	require.NoError(t, w.SetAddressAnnotation(addr, Annotation{}))
+	require.NoError(t, w.SetTransactionAnnotation(txid, Annotation{}))
+	_, ok = w.AddressAnnotation(addr)
+	require.False(t, ok)
+	require.Equal(t, Annotations{}, w.Annotations)
+
+	// Wallets without annotations have no annotations in the wallet file
+	require.Nil(t, NewReadableWallet(w).Annotations)
+}
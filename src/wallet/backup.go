@@ -0,0 +1,410 @@
+package wallet
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BackupVersion is the version of the backup archive format
+const BackupVersion = "1"
+
+var (
+	// ErrMissingBackupPassphrase is returned when creating or opening a backup archive without a passphrase
+	ErrMissingBackupPassphrase = NewError(errors.New("missing backup passphrase"))
+	// ErrInvalidBackupPassphrase is returned when a backup archive can't be decrypted with the passphrase
+	ErrInvalidBackupPassphrase = NewError(errors.New("invalid backup passphrase or corrupted backup archive"))
+	// ErrUnsupportedBackupVersion is returned when opening a backup archive of an unknown version
+	ErrUnsupportedBackupVersion = NewError(errors.New("unsupported backup archive version"))
+)
+
+// BackupArchive is a backup of wallets, encrypted with a passphrase.
+// The wallets are kept as in their wallet files, with their metadata, labels and annotations.
+// The secrets of the encrypted wallets stay encrypted with the password of the wallet.
+type BackupArchive struct {
+	Version string `json:"version"`
+	// Created is the time the archive was created, in unix seconds
+	Created    int64      `json:"created"`
+	CryptoType CryptoType `json:"crypto_type"`
+	// Data are the encrypted wallets
+	Data string `json:"data"`
+}
+
+// backupContents are the contents of a backup archive, once decrypted
+type backupContents struct {
+	Wallets []*ReadableWallet `json:"wallets"`
+}
+
+// NewBackupArchive creates a backup archive of wallets, encrypted with the passphrase and the crypto type
+func NewBackupArchive(wlts []*Wallet, passphrase []byte, cryptoType CryptoType) (*BackupArchive, error) {
+	crypto, err := getCrypto(cryptoType)
+	if err != nil {
+		return nil, err
+	}
+
+	return newBackupArchive(wlts, passphrase, cryptoType, crypto)
+}
+
+func newBackupArchive(wlts []*Wallet, passphrase []byte, cryptoType CryptoType, crypto cryptor) (*BackupArchive, error) {
+	if len(passphrase) == 0 {
+		return nil, ErrMissingBackupPassphrase
+	}
+
+	contents := backupContents{
+		Wallets: make([]*ReadableWallet, len(wlts)),
+	}
+	for i, w := range wlts {
+		contents.Wallets[i] = NewReadableWallet(w)
+	}
+
+	b, err := json.Marshal(contents)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := crypto.Encrypt(b, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BackupArchive{
+		Version:    BackupVersion,
+		Created:    time.Now().UTC().Unix(),
+		CryptoType: cryptoType,
+		Data:       string(data),
+	}, nil
+}
+
+// Open decrypts the archive with the passphrase and returns its wallets
+func (a BackupArchive) Open(passphrase []byte) ([]*Wallet, error) {
+	if a.Version != BackupVersion {
+		return nil, ErrUnsupportedBackupVersion
+	}
+
+	if len(passphrase) == 0 {
+		return nil, ErrMissingBackupPassphrase
+	}
+
+	crypto, err := getCrypto(a.CryptoType)
+	if err != nil {
+		return nil, NewError(err)
+	}
+
+	b, err := crypto.Decrypt([]byte(a.Data), passphrase)
+	if err != nil {
+		return nil, ErrInvalidBackupPassphrase
+	}
+
+	var contents backupContents
+	if err := json.Unmarshal(b, &contents); err != nil {
+		return nil, NewError(fmt.Errorf("invalid backup archive: %v", err))
+	}
+
+	wlts := make([]*Wallet, len(contents.Wallets))
+	for i, rw := range contents.Wallets {
+		w, err := rw.ToWallet()
+		if err != nil {
+			return nil, NewError(err)
+		}
+
+		if err := validateBackupWalletID(w.Filename()); err != nil {
+			return nil, err
+		}
+
+		wlts[i] = w
+	}
+
+	return wlts, nil
+}
+
+// validateBackupWalletID checks that the ID of a wallet of a backup is the name of a wallet file,
+// so that a wallet can't be restored out of the wallet directory
+func validateBackupWalletID(id string) error {
+	if filepath.Base(id) != id || !strings.HasSuffix(id, "."+WalletExt) {
+		return NewError(fmt.Errorf("invalid wallet id %q in backup archive", id))
+	}
+	return nil
+}
+
+// RestoreConflictReason is the reason why a wallet of a backup conflicts with an existing wallet
+type RestoreConflictReason string
+
+const (
+	// RestoreConflictWalletExists is the conflict of a wallet with the ID of an existing wallet
+	RestoreConflictWalletExists RestoreConflictReason = "wallet_exists"
+	// RestoreConflictSeedUsed is the conflict of a wallet with the seed of an existing wallet
+	RestoreConflictSeedUsed RestoreConflictReason = "seed_used"
+)
+
+// RestoreConflict is a wallet of a backup that can't be restored without overwriting or duplicating an existing wallet
+type RestoreConflict struct {
+	ID     string
+	Reason RestoreConflictReason
+	// ExistingID is the ID of the existing wallet
+	ExistingID string
+}
+
+// RestoreResult is the result of restoring a backup archive
+type RestoreResult struct {
+	// Restored are the IDs of the restored wallets
+	Restored []string
+	// Skipped are the wallets that were not restored because they conflict with existing wallets
+	Skipped []RestoreConflict
+}
+
+// restoreConflicts returns the wallets of a backup that conflict with the existing wallets:
+// the wallets with the ID of an existing wallet file, and the deterministic wallets with the
+// same first address, hence the same seed, as an existing deterministic wallet.
+// ids are the IDs of the existing wallet files and firstAddrs maps the first addresses of
+// the existing deterministic wallets to their IDs.
+func restoreConflicts(wlts []*Wallet, ids map[string]struct{}, firstAddrs map[string]string) []RestoreConflict {
+	var conflicts []RestoreConflict
+	for _, w := range wlts {
+		if _, ok := ids[w.Filename()]; ok {
+			conflicts = append(conflicts, RestoreConflict{
+				ID:         w.Filename(),
+				Reason:     RestoreConflictWalletExists,
+				ExistingID: w.Filename(),
+			})
+			continue
+		}
+
+		if w.IsWatchOnly() || len(w.Entries) == 0 {
+			continue
+		}
+
+		if id, ok := firstAddrs[w.Entries[0].Address.String()]; ok {
+			conflicts = append(conflicts, RestoreConflict{
+				ID:         w.Filename(),
+				Reason:     RestoreConflictSeedUsed,
+				ExistingID: id,
+			})
+		}
+	}
+
+	return conflicts
+}
+
+// restorableWallets returns the wallets of a backup to restore.
+// If some wallets conflict with the existing wallets, an error listing the conflicts is returned,
+// unless skipConflicts is true, in which case the conflicting wallets are skipped.
+func restorableWallets(wlts []*Wallet, conflicts []RestoreConflict, skipConflicts bool) ([]*Wallet, error) {
+	if len(conflicts) == 0 {
+		return wlts, nil
+	}
+
+	if !skipConflicts {
+		strs := make([]string, len(conflicts))
+		for i, c := range conflicts {
+			switch c.Reason {
+			case RestoreConflictSeedUsed:
+				strs[i] = fmt.Sprintf("%s has the seed of %s", c.ID, c.ExistingID)
+			default:
+				strs[i] = fmt.Sprintf("%s already exists", c.ID)
+			}
+		}
+		return nil, NewError(fmt.Errorf("backup conflicts with existing wallets: %s", strings.Join(strs, ", ")))
+	}
+
+	skipped := make(map[string]struct{}, len(conflicts))
+	for _, c := range conflicts {
+		skipped[c.ID] = struct{}{}
+	}
+
+	var restore []*Wallet
+	for _, w := range wlts {
+		if _, ok := skipped[w.Filename()]; !ok {
+			restore = append(restore, w)
+		}
+	}
+
+	return restore, nil
+}
+
+// walletsFirstAddrs maps the first addresses of the deterministic wallets to their IDs
+func walletsFirstAddrs(wlts Wallets) map[string]string {
+	firstAddrs := make(map[string]string, len(wlts))
+	for id, w := range wlts {
+		if w.IsWatchOnly() || len(w.Entries) == 0 {
+			continue
+		}
+		firstAddrs[w.Entries[0].Address.String()] = id
+	}
+	return firstAddrs
+}
+
+// RestoreBackupToDir restores the wallets of a backup archive as wallet files of a directory.
+// The wallets that conflict with the wallet files of the directory are not restored, see Service.RestoreBackup.
+func RestoreBackupToDir(dir string, a BackupArchive, passphrase []byte, skipConflicts bool) (*RestoreResult, error) {
+	wlts, err := a.Open(passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := LoadWallets(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]struct{}, len(existing))
+	for id := range existing {
+		ids[id] = struct{}{}
+	}
+
+	conflicts := restoreConflicts(wlts, ids, walletsFirstAddrs(existing))
+	restore, err := restorableWallets(wlts, conflicts, skipConflicts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RestoreResult{
+		Restored: []string{},
+		Skipped:  conflicts,
+	}
+	for _, w := range restore {
+		w.upgradeVersion()
+		if err := w.Save(dir); err != nil {
+			return nil, err
+		}
+		result.Restored = append(result.Restored, w.Filename())
+	}
+
+	return result, nil
+}
+
+// Backup creates a backup archive of wallets, encrypted with the passphrase and the crypto type of the service.
+// If ids is empty, all the wallets are backed up.
+// The seeds of unencrypted wallets are only exported if the seed API is enabled.
+func (serv *Service) Backup(ids []string, passphrase []byte) (*BackupArchive, error) {
+	if !serv.enableWalletAPI {
+		return nil, ErrWalletAPIDisabled
+	}
+
+	// The wallets are encrypted from their snapshots, after the registry is unlocked
+	serv.RLock()
+	if len(ids) == 0 {
+		for id := range serv.wallets {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+	}
+
+	wlts := make([]*Wallet, len(ids))
+	for i, id := range ids {
+		w, ok := serv.wallets.get(id)
+		if !ok {
+			serv.RUnlock()
+			return nil, ErrWalletNotExist
+		}
+		wlts[i] = w
+	}
+	serv.RUnlock()
+
+	for i, w := range wlts {
+		if !w.IsWatchOnly() && !w.IsEncrypted() && !serv.enableSeedAPI {
+			return nil, ErrSeedAPIDisabled
+		}
+
+		wlts[i] = w.clone()
+	}
+
+	crypto, err := getCryptoWithArgon2id(serv.cryptoType, serv.argon2id)
+	if err != nil {
+		return nil, err
+	}
+
+	return newBackupArchive(wlts, passphrase, serv.cryptoType, crypto)
+}
+
+// RestoreBackup restores the wallets of a backup archive, saving them to the storage of new wallets.
+//
+// A wallet conflicts with the existing wallets if a wallet file of a storage already has its ID,
+// or if it was created from the seed of an existing wallet. If some wallets conflict, nothing is restored
+// and an error listing the conflicts is returned, unless skipConflicts is true, in which case the
+// conflicting wallets are skipped and the other wallets are restored.
+func (serv *Service) RestoreBackup(a BackupArchive, passphrase []byte, skipConflicts bool) (*RestoreResult, error) {
+	if !serv.enableWalletAPI {
+		return nil, ErrWalletAPIDisabled
+	}
+
+	wlts, err := a.Open(passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lock the restored wallets in the order of their IDs, so that concurrent restores can't deadlock
+	lockIDs := make(map[string]struct{}, len(wlts))
+	for _, w := range wlts {
+		lockIDs[w.Filename()] = struct{}{}
+	}
+	sortedIDs := make([]string, 0, len(lockIDs))
+	for id := range lockIDs {
+		sortedIDs = append(sortedIDs, id)
+	}
+	sort.Strings(sortedIDs)
+	for _, id := range sortedIDs {
+		unlock := serv.lockWallet(id)
+		defer unlock()
+	}
+
+	restore, conflicts, err := serv.registerRestoredWallets(wlts, skipConflicts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RestoreResult{
+		Restored: []string{},
+		Skipped:  conflicts,
+	}
+	for i, w := range restore {
+		if err := serv.saveWallet(w); err != nil {
+			// If save fails, remove the wallets that were not saved
+			for _, w := range restore[i:] {
+				serv.unregisterWallet(w)
+			}
+			return nil, err
+		}
+
+		result.Restored = append(result.Restored, w.Filename())
+	}
+
+	return result, nil
+}
+
+// registerRestoredWallets adds the wallets of a backup that don't conflict with the existing wallets to the registry,
+// before they are saved. The conflicts are checked and the wallets are added while the registry is locked,
+// so that the wallets created meanwhile can't conflict with them.
+func (serv *Service) registerRestoredWallets(wlts []*Wallet, skipConflicts bool) ([]*Wallet, []RestoreConflict, error) {
+	serv.Lock()
+	defer serv.Unlock()
+
+	ids, err := serv.walletFileIDsLocked()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conflicts := restoreConflicts(wlts, ids, serv.firstAddrIDMap)
+	restore, err := restorableWallets(wlts, conflicts, skipConflicts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i, w := range restore {
+		if err := serv.registerWalletLocked(w); err != nil {
+			// Remove the wallets of the backup that were added
+			for _, w := range restore[:i] {
+				serv.wallets.remove(w.Filename())
+				if !w.IsWatchOnly() && len(w.Entries) != 0 {
+					delete(serv.firstAddrIDMap, w.Entries[0].Address.String())
+				}
+			}
+			return nil, nil, err
+		}
+	}
+
+	return restore, conflicts, nil
+}
@@ -0,0 +1,243 @@
+package wallet
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/testutil"
+)
+
+func makeBackupWallets(t *testing.T) []*Wallet {
+	w1, err := NewWallet("a.wlt", Options{
+		Seed:  "seed-a",
+		Label: "savings",
+	})
+	require.NoError(t, err)
+	require.NoError(t, w1.SetAddressAnnotation(w1.Entries[0].SkycoinAddress(), Annotation{
+		Label: "cold storage",
+	}))
+
+	w2, err := NewWallet("b.wlt", Options{
+		Seed:       "seed-b",
+		Encrypt:    true,
+		Password:   []byte("pwd"),
+		CryptoType: CryptoTypeSha256Xor,
+	})
+	require.NoError(t, err)
+
+	w3, err := NewWatchOnlyWallet("c.wlt", "watched", []cipher.Address{testutil.MakeAddress(), testutil.MakeAddress()})
+	require.NoError(t, err)
+
+	return []*Wallet{w1, w2, w3}
+}
+
+func TestBackupArchive(t *testing.T) {
+	wlts := makeBackupWallets(t)
+
+	_, err := NewBackupArchive(wlts, nil, CryptoTypeSha256Xor)
+	require.Equal(t, ErrMissingBackupPassphrase, err)
+
+	a, err := NewBackupArchive(wlts, []byte("passphrase"), CryptoTypeSha256Xor)
+	require.NoError(t, err)
+	require.Equal(t, BackupVersion, a.Version)
+	require.Equal(t, CryptoTypeSha256Xor, a.CryptoType)
+	require.NotZero(t, a.Created)
+	require.NotContains(t, a.Data, "seed-a")
+
+	_, err = a.Open(nil)
+	require.Equal(t, ErrMissingBackupPassphrase, err)
+
+	_, err = a.Open([]byte("wrong"))
+	require.Equal(t, ErrInvalidBackupPassphrase, err)
+
+	opened, err := a.Open([]byte("passphrase"))
+	require.NoError(t, err)
+	require.Len(t, opened, len(wlts))
+	for i, w := range opened {
+		require.Equal(t, wlts[i].Meta, w.Meta)
+		require.Equal(t, wlts[i].Entries, w.Entries)
+		require.Equal(t, wlts[i].Annotations, w.Annotations)
+	}
+
+	// The encrypted wallets stay encrypted with their own password
+	require.True(t, opened[1].IsEncrypted())
+	checkNoSensitiveData(t, opened[1])
+	_, err = opened[1].Unlock([]byte("pwd"))
+	require.NoError(t, err)
+
+	v := *a
+	v.Version = "2"
+	_, err = v.Open([]byte("passphrase"))
+	require.Equal(t, ErrUnsupportedBackupVersion, err)
+
+	// The wallets can't be restored out of the wallet directory
+	wlts[0].setFilename("../a.wlt")
+	a, err = NewBackupArchive(wlts[:1], []byte("passphrase"), CryptoTypeSha256Xor)
+	require.NoError(t, err)
+	_, err = a.Open([]byte("passphrase"))
+	require.Error(t, err)
+}
+
+func TestRestoreBackupToDir(t *testing.T) {
+	wlts := makeBackupWallets(t)
+	a, err := NewBackupArchive(wlts, []byte("passphrase"), CryptoTypeSha256Xor)
+	require.NoError(t, err)
+
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	// a.wlt exists and d.wlt has the seed of b.wlt
+	w, err := NewWallet("d.wlt", Options{
+		Seed: "seed-b",
+	})
+	require.NoError(t, err)
+	require.NoError(t, w.Save(dir))
+	w, err = NewWallet("a.wlt", Options{
+		Seed: "seed-x",
+	})
+	require.NoError(t, err)
+	require.NoError(t, w.Save(dir))
+
+	_, err = RestoreBackupToDir(dir, *a, []byte("passphrase"), false)
+	require.Error(t, err)
+	require.Equal(t, "backup conflicts with existing wallets: a.wlt already exists, b.wlt has the seed of d.wlt", err.Error())
+	testutil.RequireFileNotExists(t, filepath.Join(dir, "c.wlt"))
+
+	result, err := RestoreBackupToDir(dir, *a, []byte("passphrase"), true)
+	require.NoError(t, err)
+	require.Equal(t, &RestoreResult{
+		Restored: []string{"c.wlt"},
+		Skipped: []RestoreConflict{
+			{
+				ID:         "a.wlt",
+				Reason:     RestoreConflictWalletExists,
+				ExistingID: "a.wlt",
+			},
+			{
+				ID:         "b.wlt",
+				Reason:     RestoreConflictSeedUsed,
+				ExistingID: "d.wlt",
+			},
+		},
+	}, result)
+
+	restored, err := Load(filepath.Join(dir, "c.wlt"))
+	require.NoError(t, err)
+	require.Equal(t, "watched", restored.Label())
+	require.Equal(t, wlts[2].Entries, restored.Entries)
+}
+
+func TestServiceBackupRestore(t *testing.T) {
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	s, err := NewService(Config{
+		WalletDir:       dir,
+		CryptoType:      CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	_, err = s.CreateWallet("a.wlt", Options{
+		Seed:  "seed-a",
+		Label: "savings",
+	}, nil)
+	require.NoError(t, err)
+	_, err = s.CreateWallet("b.wlt", Options{
+		Seed:     "seed-b",
+		Encrypt:  true,
+		Password: []byte("pwd"),
+	}, nil)
+	require.NoError(t, err)
+
+	_, err = s.Backup(nil, []byte("passphrase"))
+	require.Equal(t, ErrSeedAPIDisabled, err)
+
+	// Encrypted wallets don't expose their seeds
+	a, err := s.Backup([]string{"b.wlt"}, []byte("passphrase"))
+	require.NoError(t, err)
+	wlts, err := a.Open([]byte("passphrase"))
+	require.NoError(t, err)
+	require.Len(t, wlts, 1)
+	require.Equal(t, "b.wlt", wlts[0].Filename())
+
+	_, err = s.Backup([]string{"x.wlt"}, []byte("passphrase"))
+	require.Equal(t, ErrWalletNotExist, err)
+
+	s.enableSeedAPI = true
+	a, err = s.Backup(nil, []byte("passphrase"))
+	require.NoError(t, err)
+	wlts, err = a.Open([]byte("passphrase"))
+	require.NoError(t, err)
+	require.Len(t, wlts, 2)
+	require.Equal(t, "a.wlt", wlts[0].Filename())
+	require.Equal(t, "savings", wlts[0].Label())
+	require.Equal(t, "b.wlt", wlts[1].Filename())
+
+	// Restore into another node, which has a wallet with the seed of a.wlt
+	dir2 := prepareWltDir()
+	defer os.RemoveAll(dir2)
+
+	s2, err := NewService(Config{
+		WalletDir:       dir2,
+		CryptoType:      CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+	_, err = s2.CreateWallet("x.wlt", Options{
+		Seed: "seed-a",
+	}, nil)
+	require.NoError(t, err)
+
+	_, err = s2.RestoreBackup(*a, []byte("wrong"), false)
+	require.Equal(t, ErrInvalidBackupPassphrase, err)
+
+	_, err = s2.RestoreBackup(*a, []byte("passphrase"), false)
+	require.Error(t, err)
+	_, err = s2.GetWallet("b.wlt")
+	require.Equal(t, ErrWalletNotExist, err)
+
+	result, err := s2.RestoreBackup(*a, []byte("passphrase"), true)
+	require.NoError(t, err)
+	require.Equal(t, &RestoreResult{
+		Restored: []string{"b.wlt"},
+		Skipped: []RestoreConflict{
+			{
+				ID:         "a.wlt",
+				Reason:     RestoreConflictSeedUsed,
+				ExistingID: "x.wlt",
+			},
+		},
+	}, result)
+
+	// The restored wallets are saved, and conflict with the next restores
+	s2, err = NewService(Config{
+		WalletDir:       dir2,
+		CryptoType:      CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+	w, err := s2.GetWallet("b.wlt")
+	require.NoError(t, err)
+	require.True(t, w.IsEncrypted())
+	require.Equal(t, wlts[1].Entries, w.Entries)
+
+	result, err = s2.RestoreBackup(*a, []byte("passphrase"), true)
+	require.NoError(t, err)
+	require.Empty(t, result.Restored)
+	require.Len(t, result.Skipped, 2)
+	require.Equal(t, RestoreConflictWalletExists, result.Skipped[1].Reason)
+
+	s2, err = NewService(Config{
+		WalletDir: dir2,
+	})
+	require.NoError(t, err)
+	_, err = s2.RestoreBackup(*a, []byte("passphrase"), false)
+	require.Equal(t, ErrWalletAPIDisabled, err)
+	_, err = s2.Backup(nil, []byte("passphrase"))
+	require.Equal(t, ErrWalletAPIDisabled, err)
+}
@@ -0,0 +1,154 @@
+package wallet
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+)
+
+const (
+	// ChangePolicyFixed sends the change to CreateTransactionParams.ChangeAddress.
+	// It is the policy used when ChangeAddress is set and ChangePolicy is not
+	ChangePolicyFixed = "fixed"
+	// ChangePolicyNewAddress sends the change to the first unused address of the address pool of the account
+	// spent from that is neither spent from nor sent to by the transaction, so that the change is not linked
+	// to the addresses spent from. See Wallet.UnusedAddresses
+	ChangePolicyNewAddress = "new_address"
+	// ChangePolicyLargestInput sends the change back to the address of the input with the most coins
+	ChangePolicyLargestInput = "largest_input"
+)
+
+var (
+	// ErrInvalidChangePolicy Invalid ChangePolicy
+	ErrInvalidChangePolicy = NewError(errors.New("Invalid ChangePolicy"))
+	// ErrMissingChangeAddress ChangeAddress is required for the fixed ChangePolicy
+	ErrMissingChangeAddress = NewError(errors.New("ChangeAddress is required for the fixed ChangePolicy"))
+	// ErrChangeAddressPolicy ChangeAddress can only be used with the fixed ChangePolicy
+	ErrChangeAddressPolicy = NewError(errors.New("ChangeAddress can only be used with the fixed ChangePolicy"))
+	// ErrNoUnusedChangeAddress is returned if the new_address ChangePolicy can't find an unused address for the change
+	ErrNoUnusedChangeAddress = NewError(errors.New("wallet has no unused address for the change, fill its address pool"))
+)
+
+// validateChangePolicy checks that the change policy is known and is consistent with the change address
+func validateChangePolicy(policy string, changeAddress *cipher.Address) error {
+	switch policy {
+	case "":
+	case ChangePolicyFixed:
+		if changeAddress == nil {
+			return ErrMissingChangeAddress
+		}
+	case ChangePolicyNewAddress, ChangePolicyLargestInput:
+		if changeAddress != nil {
+			return ErrChangeAddressPolicy
+		}
+	default:
+		return ErrInvalidChangePolicy
+	}
+
+	return nil
+}
+
+// chooseChangeAddress returns the address that receives the change of a transaction spending inputs, according to p.ChangePolicy.
+// spends are the outputs chosen by the coin selection, which are the inputs apart from an extra input added to save the change hours.
+// Without a ChangePolicy, the change goes to p.ChangeAddress if set, otherwise to the address of spends that sorts first.
+func (w *Wallet) chooseChangeAddress(p CreateTransactionParams, auxs coin.AddressUxOuts, spends, inputs []UxBalance) (cipher.Address, error) {
+	switch p.ChangePolicy {
+	case "":
+		if p.ChangeAddress != nil {
+			return *p.ChangeAddress, nil
+		}
+		return lowestAddress(spends)
+	case ChangePolicyFixed:
+		return *p.ChangeAddress, nil
+	case ChangePolicyNewAddress:
+		return w.newChangeAddress(p, auxs, inputs)
+	case ChangePolicyLargestInput:
+		return largestInputAddress(inputs)
+	default:
+		return cipher.Address{}, ErrInvalidChangePolicy
+	}
+}
+
+// lowestAddress returns the address of the outputs that sorts first, comparing bytes.
+// This provides deterministic change address selection from a set of unspent outputs
+func lowestAddress(uxb []UxBalance) (cipher.Address, error) {
+	if len(uxb) == 0 {
+		return cipher.Address{}, errors.New("spends is unexpectedly empty when choosing an automatic change address")
+	}
+
+	addressBytes := make([][]byte, len(uxb))
+	for i, s := range uxb {
+		addressBytes[i] = s.Address.Bytes()
+	}
+
+	sort.Slice(addressBytes, func(i, j int) bool {
+		return bytes.Compare(addressBytes[i], addressBytes[j]) < 0
+	})
+
+	changeAddress, err := cipher.AddressFromBytes(addressBytes[0])
+	if err != nil {
+		logger.Critical().WithError(err).Error("cipher.AddressFromBytes failed for change address converted to bytes")
+		return cipher.Address{}, err
+	}
+
+	return changeAddress, nil
+}
+
+// largestInputAddress returns the address of the input with the most coins.
+// Ties are broken by the most coin hours, then by comparing the addresses
+func largestInputAddress(inputs []UxBalance) (cipher.Address, error) {
+	if len(inputs) == 0 {
+		return cipher.Address{}, errors.New("inputs is unexpectedly empty when choosing the largest input's address")
+	}
+
+	largest := inputs[0]
+	for _, in := range inputs[1:] {
+		switch {
+		case in.Coins != largest.Coins:
+			if in.Coins > largest.Coins {
+				largest = in
+			}
+		case in.Hours != largest.Hours:
+			if in.Hours > largest.Hours {
+				largest = in
+			}
+		default:
+			if bytes.Compare(in.Address.Bytes(), largest.Address.Bytes()) < 0 {
+				largest = in
+			}
+		}
+	}
+
+	return largest.Address, nil
+}
+
+// newChangeAddress returns the first unused address of the account spent from that has no unspent outputs in auxs
+// and is neither an input nor a destination of the transaction
+func (w *Wallet) newChangeAddress(p CreateTransactionParams, auxs coin.AddressUxOuts, inputs []UxBalance) (cipher.Address, error) {
+	unused, err := w.UnusedAddresses(p.Wallet.Account)
+	if err != nil {
+		return cipher.Address{}, err
+	}
+
+	excluded := make(map[cipher.Address]struct{}, len(auxs)+len(inputs)+len(p.To))
+	for a := range auxs {
+		excluded[a] = struct{}{}
+	}
+	for _, in := range inputs {
+		excluded[in.Address] = struct{}{}
+	}
+	for _, to := range p.To {
+		excluded[to.Address] = struct{}{}
+	}
+
+	for _, a := range unused {
+		if _, ok := excluded[a]; !ok {
+			return a, nil
+		}
+	}
+
+	return cipher.Address{}, ErrNoUnusedChangeAddress
+}
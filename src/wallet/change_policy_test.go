@@ -0,0 +1,106 @@
+package wallet
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/testutil"
+)
+
+func TestWalletChangePolicy(t *testing.T) {
+	headTime := uint64(time.Now().UTC().Unix())
+	w := makeWallet(t, Options{
+		Seed: "seed",
+	}, 5)
+	addr := func(i int) cipher.Address {
+		return w.Entries[i].SkycoinAddress()
+	}
+
+	auxs := coin.AddressUxOuts{
+		addr(0): []coin.UxOut{makeUxOut(t, w.Entries[0].Secret, 3e6, 100)},
+		addr(1): []coin.UxOut{makeUxOut(t, w.Entries[1].Secret, 5e6, 50)},
+	}
+
+	lowestInputAddr := addr(0)
+	if bytes.Compare(addr(1).Bytes(), addr(0).Bytes()) < 0 {
+		lowestInputAddr = addr(1)
+	}
+
+	changeAddress := testutil.MakeAddress()
+	to := testutil.MakeAddress()
+
+	newParams := func(policy string, changeAddress *cipher.Address) CreateTransactionParams {
+		return CreateTransactionParams{
+			HoursSelection: HoursSelection{
+				Type: HoursSelectionTypeManual,
+			},
+			Wallet: CreateTransactionWalletParams{
+				ID: "t.wlt",
+			},
+			ChangeAddress: changeAddress,
+			ChangePolicy:  policy,
+			To: []coin.TransactionOutput{
+				{
+					Address: to,
+					Coins:   7e6,
+					Hours:   10,
+				},
+			},
+		}
+	}
+
+	requireChange := func(p CreateTransactionParams, w *Wallet, expected cipher.Address) {
+		txn, inputs, err := w.CreateAndSignTransactionAdvanced(p, auxs, headTime)
+		require.NoError(t, err)
+		require.Len(t, inputs, 2)
+		require.Len(t, txn.Out, 2)
+		require.Equal(t, expected, txn.Out[1].Address)
+		require.Equal(t, uint64(1e6), txn.Out[1].Coins)
+	}
+
+	// Invalid policies
+	_, _, err := w.CreateAndSignTransactionAdvanced(newParams("foo", nil), auxs, headTime)
+	require.Equal(t, ErrInvalidChangePolicy, err)
+	_, _, err = w.CreateAndSignTransactionAdvanced(newParams(ChangePolicyFixed, nil), auxs, headTime)
+	require.Equal(t, ErrMissingChangeAddress, err)
+	_, _, err = w.CreateAndSignTransactionAdvanced(newParams(ChangePolicyLargestInput, &changeAddress), auxs, headTime)
+	require.Equal(t, ErrChangeAddressPolicy, err)
+	_, _, err = w.CreateAndSignTransactionAdvanced(newParams(ChangePolicyNewAddress, &changeAddress), auxs, headTime)
+	require.Equal(t, ErrChangeAddressPolicy, err)
+
+	// Without a policy, the change goes to the change address or to the input address that sorts first
+	requireChange(newParams("", &changeAddress), w, changeAddress)
+	requireChange(newParams("", nil), w, lowestInputAddr)
+
+	requireChange(newParams(ChangePolicyFixed, &changeAddress), w, changeAddress)
+	requireChange(newParams(ChangePolicyLargestInput, nil), w, addr(1))
+
+	// The change goes to the first unused address that is not spent from nor sent to
+	requireChange(newParams(ChangePolicyNewAddress, nil), w, addr(2))
+
+	w2 := w.clone()
+	_, err = w2.MarkAddressesUsed([]cipher.Address{addr(2)})
+	require.NoError(t, err)
+	requireChange(newParams(ChangePolicyNewAddress, nil), w2, addr(3))
+
+	p := newParams(ChangePolicyNewAddress, nil)
+	p.To[0].Address = addr(3)
+	requireChange(p, w2, addr(4))
+
+	_, err = w2.MarkAddressesUsed([]cipher.Address{addr(4)})
+	require.NoError(t, err)
+	_, _, err = w2.CreateAndSignTransactionAdvanced(p, auxs, headTime)
+	require.Equal(t, ErrNoUnusedChangeAddress, err)
+
+	// Addresses handed out by the address pool are not used for the change
+	w3 := w.clone()
+	taken, err := w3.TakePoolAddresses("", 3)
+	require.NoError(t, err)
+	require.Equal(t, []cipher.Address{addr(0), addr(1), addr(2)}, taken)
+	requireChange(newParams(ChangePolicyNewAddress, nil), w3, addr(3))
+}
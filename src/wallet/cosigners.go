@@ -0,0 +1,259 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+const (
+	// MaxCosigners is the maximum number of cosigners of a wallet
+	MaxCosigners = 20
+	// MaxCosignerAddresses is the maximum number of addresses of a cosigner
+	MaxCosignerAddresses = 100
+)
+
+var (
+	// ErrTooManyCosigners is returned if a wallet has too many cosigners
+	ErrTooManyCosigners = NewError(fmt.Errorf("wallet can't have more than %d cosigners", MaxCosigners))
+	// ErrCosignerNoAddresses is returned if a cosigner has no addresses
+	ErrCosignerNoAddresses = NewError(errors.New("cosigner has no addresses"))
+	// ErrCosignerTooManyAddresses is returned if a cosigner has too many addresses
+	ErrCosignerTooManyAddresses = NewError(fmt.Errorf("cosigner can't have more than %d addresses", MaxCosignerAddresses))
+	// ErrCosignerNullAddress is returned if an address of a cosigner is the null address
+	ErrCosignerNullAddress = NewError(errors.New("cosigner addresses contain the null address"))
+	// ErrCosignerDuplicateAddress is returned if an address belongs to several cosigners, or twice to the same cosigner
+	ErrCosignerDuplicateAddress = NewError(errors.New("cosigner addresses contain duplicates"))
+	// ErrCosignerDuplicateLabel is returned if two cosigners have the same label
+	ErrCosignerDuplicateLabel = NewError(errors.New("cosigner labels contain duplicates"))
+)
+
+// Cosigner is another party whose signatures are required by the transactions of a wallet.
+// A transaction is co-signed by spending the outputs of the addresses of each party, each party signing
+// the inputs it owns (n-of-n), or by spending the outputs of a multisig address, see Wallet.AddMultisig,
+// in which case the cosigners are the owners of the addresses of the public keys of the multisig (m-of-n).
+// The cosigners are metadata of the wallet, only the multisig addresses are enforced by the network.
+type Cosigner struct {
+	Label string
+	// Addresses are the addresses owned by the cosigner
+	Addresses []cipher.Address
+}
+
+// Cosigners are the cosigners of a wallet
+type Cosigners []Cosigner
+
+// Validate checks the addresses and labels of the cosigners
+func (cs Cosigners) Validate() error {
+	if len(cs) > MaxCosigners {
+		return ErrTooManyCosigners
+	}
+
+	labels := make(map[string]struct{}, len(cs))
+	addrs := make(map[cipher.Address]struct{})
+	for _, c := range cs {
+		if c.Label != "" {
+			if _, ok := labels[c.Label]; ok {
+				return ErrCosignerDuplicateLabel
+			}
+			labels[c.Label] = struct{}{}
+		}
+
+		if len(c.Addresses) == 0 {
+			return ErrCosignerNoAddresses
+		}
+
+		if len(c.Addresses) > MaxCosignerAddresses {
+			return ErrCosignerTooManyAddresses
+		}
+
+		for _, a := range c.Addresses {
+			if a.Null() {
+				return ErrCosignerNullAddress
+			}
+			if _, ok := addrs[a]; ok {
+				return ErrCosignerDuplicateAddress
+			}
+			addrs[a] = struct{}{}
+		}
+	}
+
+	return nil
+}
+
+func (cs Cosigners) clone() Cosigners {
+	if len(cs) == 0 {
+		return nil
+	}
+
+	c := make(Cosigners, len(cs))
+	for i, s := range cs {
+		c[i] = Cosigner{
+			Label:     s.Label,
+			Addresses: append([]cipher.Address(nil), s.Addresses...),
+		}
+	}
+	return c
+}
+
+// SetCosigners sets the cosigners of the wallet, replacing the previous ones. No cosigners removes them
+func (w *Wallet) SetCosigners(cs Cosigners) error {
+	if err := cs.Validate(); err != nil {
+		return err
+	}
+
+	w.Cosigners = cs.clone()
+	return nil
+}
+
+// CosignerStatus reports the inputs of a partial transaction that are owned by a cosigner
+type CosignerStatus struct {
+	Cosigner Cosigner
+	// Inputs is the number of inputs owned by the cosigner
+	Inputs int
+	// UnsignedAddresses are the addresses of the cosigner whose inputs are not signed yet, without duplicates
+	UnsignedAddresses []cipher.Address
+}
+
+// Signed returns true if the cosigner signed all its inputs
+func (s CosignerStatus) Signed() bool {
+	return len(s.UnsignedAddresses) == 0
+}
+
+// CosignerStatus returns the status of each cosigner of the wallet for a partial transaction,
+// in the order of the cosigners
+func (w *Wallet) CosignerStatus(ptx *PartialTransaction) []CosignerStatus {
+	status := make([]CosignerStatus, len(w.Cosigners))
+	owners := make(map[cipher.Address]int)
+	for i, c := range w.Cosigners {
+		status[i].Cosigner = c
+		for _, a := range c.Addresses {
+			owners[a] = i
+		}
+	}
+
+	seen := make(map[cipher.Address]struct{})
+	addUnsigned := func(j int, a cipher.Address) {
+		if _, ok := seen[a]; ok {
+			return
+		}
+		seen[a] = struct{}{}
+		status[j].UnsignedAddresses = append(status[j].UnsignedAddresses, a)
+	}
+
+	ws := ptx.multisigWitnesses()
+	for i, ux := range ptx.Inputs {
+		// The owners of a multisig input are the owners of the addresses of its public keys.
+		// Those that have not signed are unsigned until the input has the required number of signatures.
+		if mw, ok := ws[i]; ok {
+			for k, pk := range mw.Multisig.PubKeys {
+				a := cipher.AddressFromPubKey(pk)
+				j, ok := owners[a]
+				if !ok {
+					continue
+				}
+
+				status[j].Inputs++
+
+				if mw.IsFullySigned() || !mw.Sigs[k].Null() {
+					continue
+				}
+				addUnsigned(j, a)
+			}
+			continue
+		}
+
+		a := ux.Body.Address
+		j, ok := owners[a]
+		if !ok {
+			continue
+		}
+
+		status[j].Inputs++
+
+		if !ptx.Transaction.Sigs[i].Null() {
+			continue
+		}
+		addUnsigned(j, a)
+	}
+
+	return status
+}
+
+// canSignPartialTransaction returns true if the wallet has the keys of unsigned inputs of a partial transaction
+func (w *Wallet) canSignPartialTransaction(ptx *PartialTransaction) bool {
+	if w.IsWatchOnly() {
+		return false
+	}
+
+	ws := ptx.multisigWitnesses()
+	for i, ux := range ptx.Inputs {
+		if ptx.inputSigned(i, ws) {
+			continue
+		}
+
+		if mw, ok := ws[i]; ok {
+			for j, pk := range mw.Multisig.PubKeys {
+				if _, ok := w.entryByPubKey(pk); ok && mw.Sigs[j].Null() {
+					return true
+				}
+			}
+			continue
+		}
+
+		if _, ok := w.GetEntry(ux.Body.Address); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ReadableCosigner is the JSON representation of a Cosigner, in the wallet file
+type ReadableCosigner struct {
+	Label     string   `json:"label,omitempty"`
+	Addresses []string `json:"addresses"`
+}
+
+// newReadableCosigners creates the ReadableCosigners of cosigners, returns nil if there are none
+func newReadableCosigners(cs Cosigners) []ReadableCosigner {
+	if len(cs) == 0 {
+		return nil
+	}
+
+	rcs := make([]ReadableCosigner, len(cs))
+	for i, c := range cs {
+		rcs[i].Label = c.Label
+		rcs[i].Addresses = make([]string, len(c.Addresses))
+		for j, a := range c.Addresses {
+			rcs[i].Addresses[j] = a.String()
+		}
+	}
+
+	return rcs
+}
+
+// toCosigners converts ReadableCosigners to Cosigners
+func toCosigners(rcs []ReadableCosigner) (Cosigners, error) {
+	if len(rcs) == 0 {
+		return nil, nil
+	}
+
+	cs := make(Cosigners, len(rcs))
+	for i, rc := range rcs {
+		cs[i].Label = rc.Label
+		for _, s := range rc.Addresses {
+			addr, err := cipher.DecodeBase58Address(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid cosigner address %q: %v", s, err)
+			}
+			cs[i].Addresses = append(cs[i].Addresses, addr)
+		}
+	}
+
+	if err := cs.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cs, nil
+}
@@ -0,0 +1,172 @@
+package wallet
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/testutil"
+)
+
+func TestCosignersValidate(t *testing.T) {
+	a := testutil.MakeAddress()
+	b := testutil.MakeAddress()
+
+	tooManyAddrs := make([]cipher.Address, MaxCosignerAddresses+1)
+	for i := range tooManyAddrs {
+		tooManyAddrs[i] = testutil.MakeAddress()
+	}
+
+	tooMany := make(Cosigners, MaxCosigners+1)
+	for i := range tooMany {
+		tooMany[i] = Cosigner{
+			Addresses: []cipher.Address{testutil.MakeAddress()},
+		}
+	}
+
+	cases := []struct {
+		name      string
+		cosigners Cosigners
+		err       error
+	}{
+		{
+			name: "no cosigners",
+		},
+		{
+			name: "ok",
+			cosigners: Cosigners{
+				{Label: "alice", Addresses: []cipher.Address{a}},
+				{Addresses: []cipher.Address{b}},
+			},
+		},
+		{
+			name:      "too many cosigners",
+			cosigners: tooMany,
+			err:       ErrTooManyCosigners,
+		},
+		{
+			name: "no addresses",
+			cosigners: Cosigners{
+				{Label: "alice"},
+			},
+			err: ErrCosignerNoAddresses,
+		},
+		{
+			name: "too many addresses",
+			cosigners: Cosigners{
+				{Label: "alice", Addresses: tooManyAddrs},
+			},
+			err: ErrCosignerTooManyAddresses,
+		},
+		{
+			name: "null address",
+			cosigners: Cosigners{
+				{Label: "alice", Addresses: []cipher.Address{a, {}}},
+			},
+			err: ErrCosignerNullAddress,
+		},
+		{
+			name: "duplicate address",
+			cosigners: Cosigners{
+				{Label: "alice", Addresses: []cipher.Address{a}},
+				{Label: "bob", Addresses: []cipher.Address{b, a}},
+			},
+			err: ErrCosignerDuplicateAddress,
+		},
+		{
+			name: "duplicate label",
+			cosigners: Cosigners{
+				{Label: "alice", Addresses: []cipher.Address{a}},
+				{Label: "alice", Addresses: []cipher.Address{b}},
+			},
+			err: ErrCosignerDuplicateLabel,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.err, tc.cosigners.Validate())
+		})
+	}
+}
+
+func TestWalletCosignerStatus(t *testing.T) {
+	w, _ := makePartialTransactionWallet(t, "seed")
+	_, keys1 := makePartialTransactionWallet(t, "seed1")
+	w2, keys2 := makePartialTransactionWallet(t, "seed2")
+
+	addr := func(k cipher.SecKey) cipher.Address {
+		return cipher.MustAddressFromSecKey(k)
+	}
+
+	require.NoError(t, w.SetCosigners(Cosigners{
+		{Label: "alice", Addresses: []cipher.Address{addr(keys1[0]), addr(keys1[1])}},
+		{Label: "bob", Addresses: []cipher.Address{addr(keys2[0])}},
+		{Label: "carol", Addresses: []cipher.Address{testutil.MakeAddress()}},
+	}))
+
+	ptx := makePartialTransaction(t, coin.UxArray{
+		makeUxOut(t, keys1[0], 1e6, 10),
+		makeUxOut(t, keys2[0], 2e6, 10),
+		makeUxOut(t, keys1[0], 3e6, 10),
+	})
+
+	status := w.CosignerStatus(ptx)
+	require.Len(t, status, 3)
+	require.Equal(t, "alice", status[0].Cosigner.Label)
+	require.Equal(t, 2, status[0].Inputs)
+	require.Equal(t, []cipher.Address{addr(keys1[0])}, status[0].UnsignedAddresses)
+	require.False(t, status[0].Signed())
+	require.Equal(t, 1, status[1].Inputs)
+	require.False(t, status[1].Signed())
+	require.Equal(t, 0, status[2].Inputs)
+	require.True(t, status[2].Signed())
+
+	_, err := w2.SignPartialTransaction(ptx)
+	require.NoError(t, err)
+
+	status = w.CosignerStatus(ptx)
+	require.False(t, status[0].Signed())
+	require.True(t, status[1].Signed())
+	require.Empty(t, status[1].UnsignedAddresses)
+
+	// The wallet has none of the keys of the inputs
+	require.False(t, w.canSignPartialTransaction(ptx))
+	require.False(t, w2.canSignPartialTransaction(ptx))
+}
+
+func TestWalletCosignersFile(t *testing.T) {
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	w, err := NewWallet("t.wlt", Options{
+		Seed: "seed",
+	})
+	require.NoError(t, err)
+
+	cs := Cosigners{
+		{Label: "alice", Addresses: []cipher.Address{testutil.MakeAddress(), testutil.MakeAddress()}},
+		{Addresses: []cipher.Address{testutil.MakeAddress()}},
+	}
+	require.NoError(t, w.SetCosigners(cs))
+
+	// The cosigners are copied
+	cs[0].Addresses[0] = testutil.MakeAddress()
+	require.NotEqual(t, cs[0].Addresses[0], w.Cosigners[0].Addresses[0])
+
+	require.NoError(t, w.Save(dir))
+
+	loaded, err := Load(filepath.Join(dir, w.Filename()))
+	require.NoError(t, err)
+	require.Equal(t, w.Cosigners, loaded.Cosigners)
+	require.Equal(t, w.Cosigners, loaded.clone().Cosigners)
+
+	// No cosigners removes them
+	require.NoError(t, w.SetCosigners(nil))
+	require.Nil(t, w.Cosigners)
+	require.Nil(t, NewReadableWallet(w).Cosigners)
+}
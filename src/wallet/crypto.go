@@ -22,6 +22,8 @@ func CryptoTypeFromString(s string) (CryptoType, error) {
 		return CryptoTypeSha256Xor, nil
 	case CryptoTypeScryptChacha20poly1305:
 		return CryptoTypeScryptChacha20poly1305, nil
+	case CryptoTypeArgon2idChacha20poly1305:
+		return CryptoTypeArgon2idChacha20poly1305, nil
 	default:
 		return "", errors.New("unknown crypto type")
 	}
@@ -29,15 +31,17 @@ func CryptoTypeFromString(s string) (CryptoType, error) {
 
 // Crypto types
 const (
-	CryptoTypeSha256Xor              = CryptoType("sha256-xor")
-	CryptoTypeScryptChacha20poly1305 = CryptoType("scrypt-chacha20poly1305")
+	CryptoTypeSha256Xor                = CryptoType("sha256-xor")
+	CryptoTypeScryptChacha20poly1305   = CryptoType("scrypt-chacha20poly1305")
+	CryptoTypeArgon2idChacha20poly1305 = CryptoType("argon2id-chacha20poly1305")
 )
 
 // cryptoTable records all supported wallet crypto methods
 // If want to support new crypto methods, register here.
 var cryptoTable = map[CryptoType]cryptor{
-	CryptoTypeSha256Xor:              encrypt.DefaultSha256Xor,
-	CryptoTypeScryptChacha20poly1305: encrypt.DefaultScryptChacha20poly1305,
+	CryptoTypeSha256Xor:                encrypt.DefaultSha256Xor,
+	CryptoTypeScryptChacha20poly1305:   encrypt.DefaultScryptChacha20poly1305,
+	CryptoTypeArgon2idChacha20poly1305: encrypt.DefaultArgon2idChacha20poly1305,
 }
 
 // getCrypto gets crypto of given type
@@ -49,3 +53,82 @@ func getCrypto(cryptoType CryptoType) (cryptor, error) {
 
 	return c, nil
 }
+
+// getCryptoWithArgon2id gets crypto of given type, with the argon2id parameters if the type is
+// argon2id-chacha20poly1305 and the parameters are not the zero value
+func getCryptoWithArgon2id(cryptoType CryptoType, argon2id encrypt.Argon2idChacha20poly1305) (cryptor, error) {
+	if cryptoType != CryptoTypeArgon2idChacha20poly1305 || argon2id == (encrypt.Argon2idChacha20poly1305{}) {
+		return getCrypto(cryptoType)
+	}
+
+	if err := argon2id.Validate(); err != nil {
+		return nil, NewError(err)
+	}
+
+	return argon2id, nil
+}
+
+// currentCrypto returns the crypto that the wallet is encrypted with, including the key derivation parameters
+// of the argon2id-chacha20poly1305 crypto type, to encrypt the wallet again with the same parameters
+func (w *Wallet) currentCrypto() (cryptor, error) {
+	if w.cryptoType() != CryptoTypeArgon2idChacha20poly1305 {
+		return getCrypto(w.cryptoType())
+	}
+
+	return encrypt.Argon2idChacha20poly1305Params([]byte(w.secrets()))
+}
+
+// KDFParams are the key derivation parameters of an encrypted wallet
+type KDFParams struct {
+	CryptoType CryptoType
+	// Scrypt are the scrypt parameters of the scrypt-chacha20poly1305 crypto type
+	Scrypt *encrypt.ScryptChacha20poly1305
+	// Argon2id are the argon2id parameters of the argon2id-chacha20poly1305 crypto type
+	Argon2id *encrypt.Argon2idChacha20poly1305
+}
+
+// KDF returns the name of the key derivation function of the crypto type
+func (p KDFParams) KDF() string {
+	switch p.CryptoType {
+	case CryptoTypeSha256Xor:
+		return "sha256"
+	case CryptoTypeScryptChacha20poly1305:
+		return "scrypt"
+	case CryptoTypeArgon2idChacha20poly1305:
+		return encrypt.KDFArgon2id
+	default:
+		return ""
+	}
+}
+
+// KDFParams returns the key derivation parameters that the wallet is encrypted with.
+// Returns ErrWalletNotEncrypted if the wallet is not encrypted.
+func (w *Wallet) KDFParams() (*KDFParams, error) {
+	if !w.IsEncrypted() {
+		return nil, ErrWalletNotEncrypted
+	}
+
+	p := &KDFParams{
+		CryptoType: w.cryptoType(),
+	}
+
+	switch p.CryptoType {
+	case CryptoTypeSha256Xor:
+	case CryptoTypeScryptChacha20poly1305:
+		scrypt, err := encrypt.ScryptChacha20poly1305Params([]byte(w.secrets()))
+		if err != nil {
+			return nil, err
+		}
+		p.Scrypt = &scrypt
+	case CryptoTypeArgon2idChacha20poly1305:
+		argon2id, err := encrypt.Argon2idChacha20poly1305Params([]byte(w.secrets()))
+		if err != nil {
+			return nil, err
+		}
+		p.Argon2id = &argon2id
+	default:
+		return nil, fmt.Errorf("can not find crypto %v in crypto table", p.CryptoType)
+	}
+
+	return p, nil
+}
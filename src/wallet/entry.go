@@ -11,6 +11,8 @@ type Entry struct {
 	Address cipher.Addresser
 	Public  cipher.PubKey
 	Secret  cipher.SecKey
+	// Account is the index of the account that the address belongs to, 0 for the default account
+	Account uint32
 }
 
 // SkycoinAddress returns the Skycoin address of an entry. Panics if Address is not a Skycoin address
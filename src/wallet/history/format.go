@@ -0,0 +1,166 @@
+package history
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/skycoin/skycoin/src/util/droplet"
+)
+
+// Format is an export format of the history
+type Format string
+
+const (
+	// FormatCSV writes one CSV row per entry, after a header row
+	FormatCSV Format = "csv"
+	// FormatJSON writes a JSON array of ReadableEntry
+	FormatJSON Format = "json"
+)
+
+// ErrInvalidFormat is returned for an unknown export format
+var ErrInvalidFormat = errors.New("invalid history export format")
+
+// csvHeader are the columns of the CSV export
+var csvHeader = []string{
+	"txid",
+	"block_seq",
+	"time",
+	"direction",
+	"counterparties",
+	"coins",
+	"fee",
+	"balance",
+	"fiat_currency",
+	"fiat_price",
+	"fiat_value",
+}
+
+// ReadableEntry is the JSON representation of an Entry
+type ReadableEntry struct {
+	TxID           string    `json:"txid"`
+	BlockSeq       uint64    `json:"block_seq"`
+	Timestamp      uint64    `json:"timestamp"`
+	Direction      Direction `json:"direction"`
+	Counterparties []string  `json:"counterparties"`
+	Coins          string    `json:"coins"`
+	Fee            uint64    `json:"fee"`
+	Balance        string    `json:"balance"`
+	FiatCurrency   string    `json:"fiat_currency,omitempty"`
+	FiatPrice      string    `json:"fiat_price,omitempty"`
+	FiatValue      string    `json:"fiat_value,omitempty"`
+}
+
+// NewReadableEntry creates a ReadableEntry
+func NewReadableEntry(e Entry) (ReadableEntry, error) {
+	coins, err := droplet.ToString(e.Coins)
+	if err != nil {
+		return ReadableEntry{}, err
+	}
+
+	balance, err := droplet.ToString(e.Balance)
+	if err != nil {
+		return ReadableEntry{}, err
+	}
+
+	counterparties := make([]string, len(e.Counterparties))
+	for i, a := range e.Counterparties {
+		counterparties[i] = a.String()
+	}
+
+	re := ReadableEntry{
+		TxID:           e.TxID.Hex(),
+		BlockSeq:       e.BlockSeq,
+		Timestamp:      e.Time,
+		Direction:      e.Direction,
+		Counterparties: counterparties,
+		Coins:          coins,
+		Fee:            e.Fee,
+		Balance:        balance,
+	}
+
+	if e.Fiat != nil {
+		re.FiatCurrency = e.Fiat.Currency
+		re.FiatPrice = e.Fiat.Price.String()
+		re.FiatValue = e.Fiat.Value.String()
+	}
+
+	return re, nil
+}
+
+// NewReadableEntries creates ReadableEntries
+func NewReadableEntries(entries []Entry) ([]ReadableEntry, error) {
+	res := make([]ReadableEntry, len(entries))
+	for i, e := range entries {
+		re, err := NewReadableEntry(e)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = re
+	}
+
+	return res, nil
+}
+
+// Write writes the entries to w in a format
+func Write(w io.Writer, format Format, entries []Entry) error {
+	switch format {
+	case FormatCSV:
+		return WriteCSV(w, entries)
+	case FormatJSON:
+		return WriteJSON(w, entries)
+	default:
+		return ErrInvalidFormat
+	}
+}
+
+// WriteCSV writes the entries as CSV, after a header row. The time is written in RFC3339 format, in UTC,
+// and the counterparties are separated by spaces. The fiat columns are empty if the entry has no fiat value.
+func WriteCSV(w io.Writer, entries []Entry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		re, err := NewReadableEntry(e)
+		if err != nil {
+			return err
+		}
+
+		if err := cw.Write([]string{
+			re.TxID,
+			strconv.FormatUint(re.BlockSeq, 10),
+			time.Unix(int64(re.Timestamp), 0).UTC().Format(time.RFC3339),
+			string(re.Direction),
+			strings.Join(re.Counterparties, " "),
+			re.Coins,
+			strconv.FormatUint(re.Fee, 10),
+			re.Balance,
+			re.FiatCurrency,
+			re.FiatPrice,
+			re.FiatValue,
+		}); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes the entries as a JSON array of ReadableEntry
+func WriteJSON(w io.Writer, entries []Entry) error {
+	res, err := NewReadableEntries(entries)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "    ")
+	return enc.Encode(res)
+}
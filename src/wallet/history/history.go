@@ -0,0 +1,225 @@
+/*
+Package history exports the transaction history of a wallet, for accounting and tax reporting.
+
+Each confirmed transaction of the wallet becomes an Entry with its direction, counterparties, fee and the running
+balance of the wallet. The entries are written as CSV or JSON, and can be annotated with their historical fiat value
+by a PriceSource.
+*/
+package history
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+)
+
+// Direction is the direction of the coins of a transaction, relative to the wallet
+type Direction string
+
+const (
+	// DirectionReceived is a transaction that sends coins to the wallet
+	DirectionReceived Direction = "received"
+	// DirectionSent is a transaction that sends coins out of the wallet
+	DirectionSent Direction = "sent"
+	// DirectionInternal is a transaction between the addresses of the wallet
+	DirectionInternal Direction = "internal"
+)
+
+// Input is an output spent by a transaction
+type Input struct {
+	Address cipher.Address
+	Coins   uint64
+	// Hours are the coin hours of the output when the transaction was executed
+	Hours uint64
+}
+
+// Transaction is a confirmed transaction of a wallet, with the outputs spent by its inputs
+type Transaction struct {
+	Transaction coin.Transaction
+	BlockSeq    uint64
+	// Time is the time of the block of the transaction
+	Time   uint64
+	Inputs []Input
+}
+
+// Entry is a transaction of the history of a wallet
+type Entry struct {
+	TxID      cipher.SHA256
+	BlockSeq  uint64
+	Time      uint64
+	Direction Direction
+	// Counterparties are the addresses outside of the wallet that sent the coins received, or that received the coins sent,
+	// in the order of the transaction
+	Counterparties []cipher.Address
+	// Coins are the coins received or sent by the wallet, without the change. 0 for internal transactions
+	Coins uint64
+	// Fee are the coin hours burned by the transaction, 0 if the wallet did not spend any output
+	Fee uint64
+	// Balance is the coin balance of the wallet after the transaction
+	Balance uint64
+	// Fiat is the value of Coins in a fiat currency when the transaction was executed, set by AnnotateFiatValue
+	Fiat *FiatValue
+}
+
+// FiatValue is a value in a fiat currency
+type FiatValue struct {
+	Currency string
+	// Price is the price of one coin
+	Price decimal.Decimal
+	Value decimal.Decimal
+}
+
+// NewEntries creates the history entries of a wallet owning addrs, ordered by block seq then by transaction ID.
+// The transactions must include all the confirmed transactions of the wallet, so that the running balance is correct.
+func NewEntries(addrs []cipher.Address, txns []Transaction) ([]Entry, error) {
+	owned := make(map[cipher.Address]struct{}, len(addrs))
+	for _, a := range addrs {
+		owned[a] = struct{}{}
+	}
+
+	sorted := make([]Transaction, len(txns))
+	copy(sorted, txns)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].BlockSeq != sorted[j].BlockSeq {
+			return sorted[i].BlockSeq < sorted[j].BlockSeq
+		}
+		a := sorted[i].Transaction.Hash()
+		b := sorted[j].Transaction.Hash()
+		return bytes.Compare(a[:], b[:]) < 0
+	})
+
+	entries := make([]Entry, 0, len(sorted))
+	seen := make(map[cipher.SHA256]struct{}, len(sorted))
+	var balance uint64
+	for _, txn := range sorted {
+		txID := txn.Transaction.Hash()
+		if _, ok := seen[txID]; ok {
+			return nil, fmt.Errorf("duplicate transaction %s", txID.Hex())
+		}
+		seen[txID] = struct{}{}
+
+		e, err := newEntry(owned, txn)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %s: %v", txID.Hex(), err)
+		}
+
+		switch e.Direction {
+		case DirectionReceived:
+			balance, err = coin.AddUint64(balance, e.Coins)
+			if err != nil {
+				return nil, fmt.Errorf("transaction %s: balance overflow: %v", txID.Hex(), err)
+			}
+		case DirectionSent:
+			if e.Coins > balance {
+				return nil, fmt.Errorf("transaction %s: sends more coins than the balance, transactions are missing", txID.Hex())
+			}
+			balance -= e.Coins
+		}
+
+		e.Balance = balance
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+func newEntry(owned map[cipher.Address]struct{}, txn Transaction) (Entry, error) {
+	if len(txn.Inputs) != len(txn.Transaction.In) {
+		return Entry{}, errors.New("inputs don't match the transaction inputs")
+	}
+
+	var ownedIn, ownedOut, totalInHours, totalOutHours uint64
+	var spendsOwned, allInputsOwned, allOutputsOwned = false, true, true
+	var err error
+
+	for _, in := range txn.Inputs {
+		totalInHours, err = coin.AddUint64(totalInHours, in.Hours)
+		if err != nil {
+			return Entry{}, err
+		}
+
+		if _, ok := owned[in.Address]; !ok {
+			allInputsOwned = false
+			continue
+		}
+
+		spendsOwned = true
+		ownedIn, err = coin.AddUint64(ownedIn, in.Coins)
+		if err != nil {
+			return Entry{}, err
+		}
+	}
+
+	for _, out := range txn.Transaction.Out {
+		totalOutHours, err = coin.AddUint64(totalOutHours, out.Hours)
+		if err != nil {
+			return Entry{}, err
+		}
+
+		if _, ok := owned[out.Address]; !ok {
+			allOutputsOwned = false
+			continue
+		}
+
+		ownedOut, err = coin.AddUint64(ownedOut, out.Coins)
+		if err != nil {
+			return Entry{}, err
+		}
+	}
+
+	e := Entry{
+		TxID:     txn.Transaction.Hash(),
+		BlockSeq: txn.BlockSeq,
+		Time:     txn.Time,
+	}
+
+	switch {
+	case spendsOwned && allInputsOwned && allOutputsOwned:
+		e.Direction = DirectionInternal
+	case ownedIn > ownedOut:
+		e.Direction = DirectionSent
+		e.Coins = ownedIn - ownedOut
+	default:
+		e.Direction = DirectionReceived
+		e.Coins = ownedOut - ownedIn
+	}
+
+	if spendsOwned {
+		if totalOutHours > totalInHours {
+			return Entry{}, errors.New("output hours exceed the input hours")
+		}
+		e.Fee = totalInHours - totalOutHours
+	}
+
+	// The counterparties are the senders of the coins received, or the recipients of the coins sent
+	counterparties := make(map[cipher.Address]struct{})
+	addCounterparty := func(a cipher.Address) {
+		if _, ok := owned[a]; ok {
+			return
+		}
+		if _, ok := counterparties[a]; ok {
+			return
+		}
+		counterparties[a] = struct{}{}
+		e.Counterparties = append(e.Counterparties, a)
+	}
+
+	switch e.Direction {
+	case DirectionSent:
+		for _, out := range txn.Transaction.Out {
+			addCounterparty(out.Address)
+		}
+	case DirectionReceived:
+		for _, in := range txn.Inputs {
+			addCounterparty(in.Address)
+		}
+	}
+
+	return e, nil
+}
@@ -0,0 +1,181 @@
+package history
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/testutil"
+)
+
+func makeTransaction(t *testing.T, seq uint64, inputs []Input, outs []coin.TransactionOutput) Transaction {
+	txn := coin.Transaction{
+		Out: outs,
+	}
+	for range inputs {
+		txn.In = append(txn.In, testutil.RandSHA256(t))
+	}
+	require.NoError(t, txn.UpdateHeader())
+
+	return Transaction{
+		Transaction: txn,
+		BlockSeq:    seq,
+		Time:        1500000000 + seq*10,
+		Inputs:      inputs,
+	}
+}
+
+func TestNewEntries(t *testing.T) {
+	wltAddrs := []cipher.Address{testutil.MakeAddress(), testutil.MakeAddress()}
+	alice := testutil.MakeAddress()
+	bob := testutil.MakeAddress()
+
+	// alice sends 10 coins to the wallet
+	received := makeTransaction(t, 1, []Input{
+		{Address: alice, Coins: 15e6, Hours: 100},
+	}, []coin.TransactionOutput{
+		{Address: wltAddrs[0], Coins: 10e6, Hours: 20},
+		{Address: alice, Coins: 5e6, Hours: 20},
+	})
+
+	// the wallet moves its coins to its second address
+	internal := makeTransaction(t, 2, []Input{
+		{Address: wltAddrs[0], Coins: 10e6, Hours: 30},
+	}, []coin.TransactionOutput{
+		{Address: wltAddrs[1], Coins: 10e6, Hours: 15},
+	})
+
+	// the wallet sends 4 coins to bob and 1 coin to alice, with change
+	sent := makeTransaction(t, 3, []Input{
+		{Address: wltAddrs[1], Coins: 10e6, Hours: 40},
+	}, []coin.TransactionOutput{
+		{Address: bob, Coins: 4e6, Hours: 5},
+		{Address: alice, Coins: 1e6, Hours: 5},
+		{Address: wltAddrs[0], Coins: 5e6, Hours: 10},
+	})
+
+	// the transactions are ordered by block seq
+	entries, err := NewEntries(wltAddrs, []Transaction{sent, received, internal})
+	require.NoError(t, err)
+
+	require.Equal(t, []Entry{
+		{
+			TxID:           received.Transaction.Hash(),
+			BlockSeq:       1,
+			Time:           1500000010,
+			Direction:      DirectionReceived,
+			Counterparties: []cipher.Address{alice},
+			Coins:          10e6,
+			Balance:        10e6,
+		},
+		{
+			TxID:      internal.Transaction.Hash(),
+			BlockSeq:  2,
+			Time:      1500000020,
+			Direction: DirectionInternal,
+			Fee:       15,
+			Balance:   10e6,
+		},
+		{
+			TxID:           sent.Transaction.Hash(),
+			BlockSeq:       3,
+			Time:           1500000030,
+			Direction:      DirectionSent,
+			Counterparties: []cipher.Address{bob, alice},
+			Coins:          5e6,
+			Fee:            20,
+			Balance:        5e6,
+		},
+	}, entries)
+
+	// A transaction can't be exported twice
+	_, err = NewEntries(wltAddrs, []Transaction{received, received})
+	require.Error(t, err)
+
+	// Missing transactions are detected by the balance
+	_, err = NewEntries(wltAddrs, []Transaction{sent})
+	require.Error(t, err)
+
+	// The inputs must match the transaction
+	bad := received
+	bad.Inputs = nil
+	_, err = NewEntries(wltAddrs, []Transaction{bad})
+	require.Error(t, err)
+
+	entries, err = NewEntries(wltAddrs, nil)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+func TestWrite(t *testing.T) {
+	alice := testutil.MakeAddress()
+	bob := testutil.MakeAddress()
+	txID := testutil.RandSHA256(t)
+
+	entries := []Entry{
+		{
+			TxID:           txID,
+			BlockSeq:       7,
+			Time:           1500000000,
+			Direction:      DirectionReceived,
+			Counterparties: []cipher.Address{alice, bob},
+			Coins:          1500000,
+			Balance:        2500000,
+			Fiat: &FiatValue{
+				Currency: "USD",
+				Price:    decimal.New(2, 0),
+				Value:    decimal.New(3, 0),
+			},
+		},
+		{
+			TxID:      txID,
+			BlockSeq:  8,
+			Time:      1500000010,
+			Direction: DirectionInternal,
+			Fee:       12,
+			Balance:   2500000,
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, FormatCSV, entries))
+	require.Equal(t, "txid,block_seq,time,direction,counterparties,coins,fee,balance,fiat_currency,fiat_price,fiat_value\n"+
+		txID.Hex()+",7,2017-07-14T02:40:00Z,received,"+alice.String()+" "+bob.String()+",1.500000,0,2.500000,USD,2,3\n"+
+		txID.Hex()+",8,2017-07-14T02:40:10Z,internal,,0.000000,12,2.500000,,,\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, Write(&buf, FormatJSON, entries))
+	var res []ReadableEntry
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &res))
+	require.Equal(t, []ReadableEntry{
+		{
+			TxID:           txID.Hex(),
+			BlockSeq:       7,
+			Timestamp:      1500000000,
+			Direction:      DirectionReceived,
+			Counterparties: []string{alice.String(), bob.String()},
+			Coins:          "1.500000",
+			Balance:        "2.500000",
+			FiatCurrency:   "USD",
+			FiatPrice:      "2",
+			FiatValue:      "3",
+		},
+		{
+			TxID:           txID.Hex(),
+			BlockSeq:       8,
+			Timestamp:      1500000010,
+			Direction:      DirectionInternal,
+			Counterparties: []string{},
+			Coins:          "0.000000",
+			Fee:            12,
+			Balance:        "2.500000",
+		},
+	}, res)
+
+	require.Equal(t, ErrInvalidFormat, Write(&buf, Format("xml"), entries))
+}
@@ -0,0 +1,159 @@
+package history
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/skycoin/skycoin/src/util/droplet"
+	"github.com/skycoin/skycoin/src/util/logging"
+)
+
+var (
+	logger = logging.MustGetLogger("history")
+
+	// ErrPriceSourceNotRegistered is returned if a price source is not registered
+	ErrPriceSourceNotRegistered = errors.New("price source is not registered")
+	// ErrNoPrice is returned by a PriceSource that has no price at a time
+	ErrNoPrice = errors.New("no price is known at this time")
+)
+
+// PriceSource provides the historical price of a coin in a fiat currency, to annotate the history entries
+// with their fiat value. Price sources, e.g. the client of a market data service, implement PriceSource and are
+// registered with RegisterPriceSource.
+type PriceSource interface {
+	// Currency is the fiat currency of the prices, e.g. "USD"
+	Currency() string
+	// Price returns the price of one coin at time t, or ErrNoPrice
+	Price(t time.Time) (decimal.Decimal, error)
+}
+
+var (
+	priceSourcesLock sync.Mutex
+	priceSources     = make(map[string]PriceSource)
+)
+
+// RegisterPriceSource registers a price source under a name.
+// It panics if a price source is registered twice with the same name.
+func RegisterPriceSource(name string, ps PriceSource) {
+	priceSourcesLock.Lock()
+	defer priceSourcesLock.Unlock()
+
+	if ps == nil {
+		logger.Panic("RegisterPriceSource price source is nil")
+	}
+
+	if _, ok := priceSources[name]; ok {
+		logger.Panicf("RegisterPriceSource called twice for price source %q", name)
+	}
+
+	priceSources[name] = ps
+}
+
+// GetPriceSource returns a registered price source
+func GetPriceSource(name string) (PriceSource, error) {
+	priceSourcesLock.Lock()
+	defer priceSourcesLock.Unlock()
+
+	ps, ok := priceSources[name]
+	if !ok {
+		return nil, ErrPriceSourceNotRegistered
+	}
+
+	return ps, nil
+}
+
+// PriceSources returns the names of the registered price sources, sorted
+func PriceSources() []string {
+	priceSourcesLock.Lock()
+	defer priceSourcesLock.Unlock()
+
+	names := make([]string, 0, len(priceSources))
+	for name := range priceSources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// AnnotateFiatValue sets the fiat value of the coins of the entries, at the price of the time of their transaction.
+// Internal transactions, which don't move coins in or out of the wallet, are not annotated.
+func AnnotateFiatValue(entries []Entry, ps PriceSource) error {
+	for i, e := range entries {
+		if e.Direction == DirectionInternal {
+			continue
+		}
+
+		price, err := ps.Price(time.Unix(int64(e.Time), 0).UTC())
+		if err != nil {
+			return err
+		}
+
+		coins, err := droplet.ToString(e.Coins)
+		if err != nil {
+			return err
+		}
+
+		value, err := decimal.NewFromString(coins)
+		if err != nil {
+			return err
+		}
+
+		entries[i].Fiat = &FiatValue{
+			Currency: ps.Currency(),
+			Price:    price,
+			Value:    value.Mul(price),
+		}
+	}
+
+	return nil
+}
+
+// PricePoint is the price of a coin from a time
+type PricePoint struct {
+	Time  time.Time
+	Price decimal.Decimal
+}
+
+// PriceTable is a PriceSource of known prices, e.g. daily closing prices loaded from a file.
+// The price at a time is the price of the latest point at or before that time.
+type PriceTable struct {
+	currency string
+	points   []PricePoint
+}
+
+// NewPriceTable creates a PriceTable from price points, in any order
+func NewPriceTable(currency string, points []PricePoint) *PriceTable {
+	sorted := make([]PricePoint, len(points))
+	copy(sorted, points)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time.Before(sorted[j].Time)
+	})
+
+	return &PriceTable{
+		currency: currency,
+		points:   sorted,
+	}
+}
+
+// Currency returns the currency of the prices
+func (p *PriceTable) Currency() string {
+	return p.currency
+}
+
+// Price returns the price of the latest point at or before t, or ErrNoPrice if t is before the first point
+func (p *PriceTable) Price(t time.Time) (decimal.Decimal, error) {
+	i := sort.Search(len(p.points), func(i int) bool {
+		return p.points[i].Time.After(t)
+	})
+
+	if i == 0 {
+		return decimal.Decimal{}, ErrNoPrice
+	}
+
+	return p.points[i-1].Price, nil
+}
@@ -0,0 +1,101 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPriceTable(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2018, time.January, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	// The points can be in any order
+	pt := NewPriceTable("EUR", []PricePoint{
+		{Time: day(3), Price: decimal.New(30, 0)},
+		{Time: day(1), Price: decimal.New(10, 0)},
+		{Time: day(2), Price: decimal.New(20, 0)},
+	})
+	require.Equal(t, "EUR", pt.Currency())
+
+	cases := []struct {
+		t     time.Time
+		price decimal.Decimal
+		err   error
+	}{
+		{t: day(1).Add(-time.Second), err: ErrNoPrice},
+		{t: day(1), price: decimal.New(10, 0)},
+		{t: day(2).Add(-time.Second), price: decimal.New(10, 0)},
+		{t: day(2).Add(time.Hour), price: decimal.New(20, 0)},
+		{t: day(10), price: decimal.New(30, 0)},
+	}
+
+	for _, tc := range cases {
+		price, err := pt.Price(tc.t)
+		if tc.err != nil {
+			require.Equal(t, tc.err, err)
+			continue
+		}
+		require.NoError(t, err)
+		require.True(t, tc.price.Equal(price), "%s != %s", tc.price, price)
+	}
+}
+
+func TestAnnotateFiatValue(t *testing.T) {
+	pt := NewPriceTable("USD", []PricePoint{
+		{Time: time.Unix(1000, 0), Price: decimal.New(25, -1)},
+	})
+
+	entries := []Entry{
+		{
+			Time:      1000,
+			Direction: DirectionReceived,
+			Coins:     1500000,
+		},
+		{
+			Time:      2000,
+			Direction: DirectionInternal,
+		},
+		{
+			Time:      3000,
+			Direction: DirectionSent,
+			Coins:     1,
+		},
+	}
+
+	require.NoError(t, AnnotateFiatValue(entries, pt))
+	require.Equal(t, "USD", entries[0].Fiat.Currency)
+	require.Equal(t, "2.5", entries[0].Fiat.Price.String())
+	require.Equal(t, "3.75", entries[0].Fiat.Value.String())
+	require.Nil(t, entries[1].Fiat)
+	require.Equal(t, "0.0000025", entries[2].Fiat.Value.String())
+
+	// Entries before the first price can't be annotated
+	err := AnnotateFiatValue([]Entry{{Time: 999, Direction: DirectionSent}}, pt)
+	require.Equal(t, ErrNoPrice, err)
+}
+
+func TestRegisterPriceSource(t *testing.T) {
+	_, err := GetPriceSource("test-prices")
+	require.Equal(t, ErrPriceSourceNotRegistered, err)
+
+	pt := NewPriceTable("USD", nil)
+	RegisterPriceSource("test-prices", pt)
+	defer func() {
+		priceSourcesLock.Lock()
+		delete(priceSources, "test-prices")
+		priceSourcesLock.Unlock()
+	}()
+
+	ps, err := GetPriceSource("test-prices")
+	require.NoError(t, err)
+	require.Equal(t, pt, ps)
+	require.Contains(t, PriceSources(), "test-prices")
+
+	require.Panics(t, func() {
+		RegisterPriceSource("test-prices", pt)
+	})
+}
@@ -0,0 +1,242 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/params"
+	"github.com/skycoin/skycoin/src/util/fee"
+)
+
+const (
+	// HoursRedistributionConsolidate sends all the coin hours to one address
+	HoursRedistributionConsolidate = "consolidate"
+	// HoursRedistributionEven splits the coin hours evenly between the addresses
+	HoursRedistributionEven = "even"
+	// HoursRedistributionProportional splits the coin hours between the addresses proportionally to their coins
+	HoursRedistributionProportional = "proportional"
+)
+
+var (
+	// ErrInvalidHoursRedistributionMode is returned for an unknown hours redistribution mode
+	ErrInvalidHoursRedistributionMode = NewError(fmt.Errorf("invalid hours redistribution mode, must be one of %q, %q or %q",
+		HoursRedistributionConsolidate, HoursRedistributionEven, HoursRedistributionProportional))
+	// ErrHoursRedistributionMissingTo is returned when consolidating the coin hours without an address to send them to
+	ErrHoursRedistributionMissingTo = NewError(errors.New("the consolidate mode needs the address that receives the coin hours"))
+	// ErrHoursRedistributionUnexpectedTo is returned when an address to send the coin hours to is set for another mode than consolidate
+	ErrHoursRedistributionUnexpectedTo = NewError(errors.New("the address that receives the coin hours can only be set in the consolidate mode"))
+	// ErrHoursRedistributionToNotSpent is returned when the coin hours are consolidated to an address whose outputs are not spent
+	ErrHoursRedistributionToNotSpent = NewError(errors.New("the address that receives the coin hours must be one of the addresses spent from"))
+	// ErrHoursRedistributionNothingToSpend is returned when the addresses have no unspent outputs to redistribute
+	ErrHoursRedistributionNothingToSpend = NewError(errors.New("no unspent outputs to redistribute the coin hours of"))
+)
+
+// HoursRedistributionParams are the parameters of a transaction that redistributes coin hours between the addresses of a wallet
+type HoursRedistributionParams struct {
+	// Wallet are the wallet and the addresses or unspent outputs to spend, all the addresses of the wallet by default
+	Wallet            CreateTransactionWalletParams
+	IgnoreUnconfirmed bool
+	// Mode is how the coin hours are redistributed, one of the HoursRedistribution constants
+	Mode string
+	// To is the address that receives the coin hours in the HoursRedistributionConsolidate mode
+	To cipher.Address
+	// Unsigned creates the transaction without signing it, with null signatures
+	Unsigned bool
+}
+
+// Validate validates HoursRedistributionParams
+func (p HoursRedistributionParams) Validate() error {
+	switch p.Mode {
+	case HoursRedistributionConsolidate:
+		if p.To.Null() {
+			return ErrHoursRedistributionMissingTo
+		}
+	case HoursRedistributionEven, HoursRedistributionProportional:
+		if !p.To.Null() {
+			return ErrHoursRedistributionUnexpectedTo
+		}
+	default:
+		return ErrInvalidHoursRedistributionMode
+	}
+
+	if p.Unsigned && len(p.Wallet.Password) != 0 {
+		return ErrPasswordUnsigned
+	}
+
+	if len(p.Wallet.UxOuts) != 0 && len(p.Wallet.Addresses) != 0 {
+		return NewError(errors.New("wallet.uxouts and wallet.addresses cannot be combined"))
+	}
+
+	return nil
+}
+
+// CreateHoursRedistribution creates a transaction that redistributes the coin hours of the unspent outputs auxs
+// between their addresses, without moving coins: each address receives one output with the coins of its spent outputs,
+// and the coin hours left after burning the fee are split between these outputs according to p.Mode.
+// This relieves the addresses that are short of coin hours, or gathers the coin hours of a wallet in one address,
+// without crafting the transaction by hand. The unspent outputs that don't conform to the droplet precision are not spent.
+// If p.Unsigned is set, the transaction is not signed, and the wallet can be encrypted or watch-only.
+func (w *Wallet) CreateHoursRedistribution(p HoursRedistributionParams, auxs coin.AddressUxOuts, headTime uint64) (*coin.Transaction, []UxBalance, error) {
+	if err := p.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	if p.Wallet.ID != w.Filename() {
+		return nil, nil, NewError(errors.New("p.Wallet.ID does not match wallet"))
+	}
+
+	if !p.Unsigned {
+		if w.IsEncrypted() {
+			return nil, nil, ErrWalletEncrypted
+		}
+
+		if w.IsWatchOnly() {
+			return nil, nil, ErrWalletWatchOnly
+		}
+	}
+
+	var account *Account
+	if p.Wallet.Account != "" {
+		a, err := w.GetAccount(p.Wallet.Account)
+		if err != nil {
+			return nil, nil, err
+		}
+		account = &a
+	}
+
+	entries := make(map[cipher.Address]Entry, len(auxs))
+	for a := range auxs {
+		e, ok := w.GetEntry(a)
+		if !ok || (account != nil && e.Account != account.Index) {
+			return nil, nil, ErrUnknownAddress
+		}
+		entries[a] = e
+	}
+
+	uxb, err := NewUxBalances(headTime, auxs.Flatten())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Group the inputs by address, in the order of the wallet
+	addrInputs := make(map[cipher.Address][]UxBalance)
+	for _, ux := range uxb {
+		if params.DropletPrecisionCheck(ux.Coins) != nil {
+			continue
+		}
+		addrInputs[ux.Address] = append(addrInputs[ux.Address], ux)
+	}
+
+	var addrs []cipher.Address
+	var inputs []UxBalance
+	addrCoins := make(map[cipher.Address]uint64, len(addrInputs))
+	var totalHours uint64
+	for _, e := range w.Entries {
+		a := e.SkycoinAddress()
+		uxs, ok := addrInputs[a]
+		if !ok {
+			continue
+		}
+		addrs = append(addrs, a)
+
+		for _, ux := range uxs {
+			addrCoins[a], err = coin.AddUint64(addrCoins[a], ux.Coins)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			totalHours, err = coin.AddUint64(totalHours, ux.Hours)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			inputs = append(inputs, ux)
+		}
+	}
+
+	if len(inputs) == 0 {
+		return nil, nil, ErrHoursRedistributionNothingToSpend
+	}
+
+	if p.Mode == HoursRedistributionConsolidate {
+		if _, ok := addrCoins[p.To]; !ok {
+			return nil, nil, ErrHoursRedistributionToNotSpent
+		}
+	}
+
+	if fee.RequiredFee(totalHours, params.UserBurnFactor) == 0 {
+		return nil, nil, fee.ErrTxnNoFee
+	}
+
+	hours, err := redistributeHours(p, addrs, addrCoins, fee.RemainingHours(totalHours, params.UserBurnFactor))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	txn := &coin.Transaction{}
+	toSign := make([]cipher.SecKey, len(inputs))
+	for i, ux := range inputs {
+		txn.PushInput(ux.Hash)
+		toSign[i] = entries[ux.Address].Secret
+	}
+
+	for i, a := range addrs {
+		txn.PushOutput(a, addrCoins[a], hours[i])
+	}
+
+	if p.Unsigned {
+		txn.Sigs = make([]cipher.Sig, len(txn.In))
+	} else {
+		txn.SignInputs(toSign)
+	}
+
+	if err := txn.UpdateHeader(); err != nil {
+		logger.Critical().WithError(err).Error("txn.UpdateHeader failed")
+		return nil, nil, err
+	}
+
+	return txn, inputs, nil
+}
+
+// redistributeHours splits hours between the addresses according to p.Mode
+func redistributeHours(p HoursRedistributionParams, addrs []cipher.Address, addrCoins map[cipher.Address]uint64, hours uint64) ([]uint64, error) {
+	addrHours := make([]uint64, len(addrs))
+
+	switch p.Mode {
+	case HoursRedistributionConsolidate:
+		for i, a := range addrs {
+			if a == p.To {
+				addrHours[i] = hours
+			}
+		}
+
+	case HoursRedistributionEven:
+		n := uint64(len(addrs))
+		for i := range addrs {
+			addrHours[i] = hours / n
+			// The first addresses get the remainder
+			if uint64(i) < hours%n {
+				addrHours[i]++
+			}
+		}
+
+	case HoursRedistributionProportional:
+		coins := make([]uint64, len(addrs))
+		for i, a := range addrs {
+			coins[i] = addrCoins[a]
+		}
+
+		var err error
+		addrHours, err = DistributeCoinHoursProportional(coins, hours)
+		if err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, ErrInvalidHoursRedistributionMode
+	}
+
+	return addrHours, nil
+}
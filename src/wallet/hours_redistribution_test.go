@@ -0,0 +1,295 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/util/fee"
+)
+
+func TestHoursRedistributionParamsValidate(t *testing.T) {
+	addr := testutil.MakeAddress()
+
+	cases := []struct {
+		name string
+		p    HoursRedistributionParams
+		err  error
+	}{
+		{
+			name: "missing mode",
+			err:  ErrInvalidHoursRedistributionMode,
+		},
+		{
+			name: "invalid mode",
+			p: HoursRedistributionParams{
+				Mode: "foo",
+			},
+			err: ErrInvalidHoursRedistributionMode,
+		},
+		{
+			name: "consolidate without to",
+			p: HoursRedistributionParams{
+				Mode: HoursRedistributionConsolidate,
+			},
+			err: ErrHoursRedistributionMissingTo,
+		},
+		{
+			name: "even with to",
+			p: HoursRedistributionParams{
+				Mode: HoursRedistributionEven,
+				To:   addr,
+			},
+			err: ErrHoursRedistributionUnexpectedTo,
+		},
+		{
+			name: "unsigned with password",
+			p: HoursRedistributionParams{
+				Mode:     HoursRedistributionProportional,
+				Unsigned: true,
+				Wallet: CreateTransactionWalletParams{
+					Password: []byte("pwd"),
+				},
+			},
+			err: ErrPasswordUnsigned,
+		},
+		{
+			name: "consolidate",
+			p: HoursRedistributionParams{
+				Mode: HoursRedistributionConsolidate,
+				To:   addr,
+			},
+		},
+		{
+			name: "even",
+			p: HoursRedistributionParams{
+				Mode: HoursRedistributionEven,
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.err, tc.p.Validate())
+		})
+	}
+}
+
+func TestWalletCreateHoursRedistribution(t *testing.T) {
+	headTime := uint64(1000)
+
+	w, err := NewWallet("test.wlt", Options{
+		Seed: "seed",
+	})
+	require.NoError(t, err)
+	_, err = w.GenerateAddresses(2)
+	require.NoError(t, err)
+
+	addrs, err := w.GetSkycoinAddresses()
+	require.NoError(t, err)
+	require.Len(t, addrs, 3)
+
+	makeUxOut := func(addr cipher.Address, coins, hours uint64) coin.UxOut {
+		return coin.UxOut{
+			Head: coin.UxHead{
+				Time: headTime,
+			},
+			Body: coin.UxBody{
+				SrcTransaction: testutil.RandSHA256(t),
+				Address:        addr,
+				Coins:          coins,
+				Hours:          hours,
+			},
+		}
+	}
+
+	// addrs[0] has 3 coins and 181 coin hours, addrs[1] has 6 coins and no coin hours,
+	// 90 coin hours are left after the fee is burned.
+	// The output of addrs[0] with too many decimal places is not spent.
+	uxa := coin.UxArray{
+		makeUxOut(addrs[1], 6e6, 0),
+		makeUxOut(addrs[0], 1e6, 100),
+		makeUxOut(addrs[0], 2e6, 81),
+		makeUxOut(addrs[0], 1e6+1, 10),
+	}
+	auxs := coin.NewAddressUxOuts(uxa)
+
+	uxMap := make(map[cipher.SHA256]coin.UxOut, len(uxa))
+	for _, ux := range uxa {
+		uxMap[ux.Hash()] = ux
+	}
+
+	walletParams := CreateTransactionWalletParams{
+		ID: w.Filename(),
+	}
+
+	cases := []struct {
+		name  string
+		p     HoursRedistributionParams
+		auxs  coin.AddressUxOuts
+		hours []uint64
+		err   error
+	}{
+		{
+			name: "consolidate",
+			p: HoursRedistributionParams{
+				Wallet: walletParams,
+				Mode:   HoursRedistributionConsolidate,
+				To:     addrs[1],
+			},
+			auxs:  auxs,
+			hours: []uint64{0, 90},
+		},
+		{
+			name: "even",
+			p: HoursRedistributionParams{
+				Wallet: walletParams,
+				Mode:   HoursRedistributionEven,
+			},
+			auxs:  auxs,
+			hours: []uint64{45, 45},
+		},
+		{
+			name: "proportional",
+			p: HoursRedistributionParams{
+				Wallet: walletParams,
+				Mode:   HoursRedistributionProportional,
+			},
+			auxs:  auxs,
+			hours: []uint64{30, 60},
+		},
+		{
+			name: "unsigned",
+			p: HoursRedistributionParams{
+				Wallet:   walletParams,
+				Mode:     HoursRedistributionEven,
+				Unsigned: true,
+			},
+			auxs:  auxs,
+			hours: []uint64{45, 45},
+		},
+		{
+			name: "wallet id mismatch",
+			p: HoursRedistributionParams{
+				Wallet: CreateTransactionWalletParams{
+					ID: "foo.wlt",
+				},
+				Mode: HoursRedistributionEven,
+			},
+			auxs: auxs,
+			err:  NewError(errors.New("p.Wallet.ID does not match wallet")),
+		},
+		{
+			name: "consolidate to address not spent",
+			p: HoursRedistributionParams{
+				Wallet: walletParams,
+				Mode:   HoursRedistributionConsolidate,
+				To:     addrs[2],
+			},
+			auxs: auxs,
+			err:  ErrHoursRedistributionToNotSpent,
+		},
+		{
+			name: "unknown address",
+			p: HoursRedistributionParams{
+				Wallet: walletParams,
+				Mode:   HoursRedistributionEven,
+			},
+			auxs: coin.NewAddressUxOuts(coin.UxArray{makeUxOut(testutil.MakeAddress(), 1e6, 10)}),
+			err:  ErrUnknownAddress,
+		},
+		{
+			name: "nothing to spend",
+			p: HoursRedistributionParams{
+				Wallet: walletParams,
+				Mode:   HoursRedistributionEven,
+			},
+			auxs: coin.NewAddressUxOuts(coin.UxArray{makeUxOut(addrs[0], 1e6+1, 10)}),
+			err:  ErrHoursRedistributionNothingToSpend,
+		},
+		{
+			name: "no fee",
+			p: HoursRedistributionParams{
+				Wallet: walletParams,
+				Mode:   HoursRedistributionEven,
+			},
+			auxs: coin.NewAddressUxOuts(coin.UxArray{makeUxOut(addrs[0], 1e6, 0)}),
+			err:  fee.ErrTxnNoFee,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			txn, inputs, err := w.CreateHoursRedistribution(tc.p, tc.auxs, headTime)
+			if tc.err != nil {
+				require.Equal(t, tc.err, err)
+				return
+			}
+			require.NoError(t, err)
+
+			// The inputs are grouped by address in the order of the wallet, without the imprecise output
+			require.Len(t, inputs, 3)
+			require.Equal(t, []cipher.Address{addrs[0], addrs[0], addrs[1]}, []cipher.Address{
+				inputs[0].Address,
+				inputs[1].Address,
+				inputs[2].Address,
+			})
+			require.Len(t, txn.In, len(inputs))
+			for i, in := range inputs {
+				require.Equal(t, in.Hash, txn.In[i])
+			}
+
+			// Each address gets its coins back
+			require.Equal(t, []coin.TransactionOutput{
+				{
+					Address: addrs[0],
+					Coins:   3e6,
+					Hours:   tc.hours[0],
+				},
+				{
+					Address: addrs[1],
+					Coins:   6e6,
+					Hours:   tc.hours[1],
+				},
+			}, txn.Out)
+
+			require.Equal(t, txn.HashInner(), txn.InnerHash)
+			if tc.p.Unsigned {
+				require.Equal(t, make([]cipher.Sig, len(txn.In)), txn.Sigs)
+				return
+			}
+
+			require.NoError(t, txn.Verify())
+
+			uxOuts := make(coin.UxArray, len(inputs))
+			for i, in := range inputs {
+				uxOuts[i] = uxMap[in.Hash]
+			}
+			require.NoError(t, txn.VerifyInput(uxOuts))
+		})
+	}
+}
+
+func TestWalletCreateHoursRedistributionEncrypted(t *testing.T) {
+	w, err := NewWallet("test.wlt", Options{
+		Seed:       "seed",
+		Encrypt:    true,
+		Password:   []byte("pwd"),
+		CryptoType: CryptoTypeSha256Xor,
+	})
+	require.NoError(t, err)
+
+	p := HoursRedistributionParams{
+		Wallet: CreateTransactionWalletParams{
+			ID: w.Filename(),
+		},
+		Mode: HoursRedistributionEven,
+	}
+
+	_, _, err = w.CreateHoursRedistribution(p, nil, 0)
+	require.Equal(t, ErrWalletEncrypted, err)
+}
@@ -0,0 +1,457 @@
+package wallet
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/encrypt"
+	"github.com/skycoin/skycoin/src/cipher/go-bip39"
+)
+
+// ImportFormat is the format of the data of an imported wallet
+type ImportFormat string
+
+const (
+	// ImportFormatKeys is a list of private keys, one per line, either hex encoded like the keys of
+	// Skycoin wallets or in the wallet import format (WIF) of Bitcoin. Empty lines and lines starting with # are ignored.
+	ImportFormatKeys ImportFormat = "keys"
+	// ImportFormatSeed is a seed phrase, an Electrum seed or a BIP39 mnemonic
+	ImportFormatSeed ImportFormat = "seed"
+	// ImportFormatKeystore is a JSON keystore, an object with a "keys" array of objects with a "secret_key",
+	// hex or WIF encoded, and optionally the "address" and "public_key" of the key, which are checked.
+	// The "entries" of an unencrypted wallet file are accepted in place of "keys".
+	ImportFormatKeystore ImportFormat = "keystore"
+)
+
+// electrumSeedPrefixes are the prefixes of the hex HMAC of the Electrum seeds, for each type of Electrum wallet
+var electrumSeedPrefixes = []string{
+	"01",  // standard
+	"100", // segwit
+	"101", // two-factor authentication
+	"102", // two-factor authentication segwit
+}
+
+var (
+	// ErrUnknownImportFormat is returned when importing a wallet of an unknown format
+	ErrUnknownImportFormat = NewError(errors.New("unknown import format, must be keys, seed or keystore"))
+	// ErrMissingImportData is returned when importing a wallet without data
+	ErrMissingImportData = NewError(errors.New("missing import data"))
+	// ErrImportWalletExists is returned when importing a wallet with the ID of an existing wallet
+	ErrImportWalletExists = NewError(errors.New("a wallet already exists with this id"))
+)
+
+// ImportFormatFromString returns the ImportFormat of a string
+func ImportFormatFromString(s string) (ImportFormat, error) {
+	switch f := ImportFormat(s); f {
+	case ImportFormatKeys, ImportFormatSeed, ImportFormatKeystore:
+		return f, nil
+	default:
+		return "", ErrUnknownImportFormat
+	}
+}
+
+// ImportOptions are the options of a wallet imported from data of another format
+type ImportOptions struct {
+	Format ImportFormat
+	// Data is the seed phrase, the private key list or the keystore to import
+	Data  []byte
+	Label string
+	// GenerateN is the number of addresses generated from a seed phrase, defaults to 1
+	GenerateN uint64
+	// SeedPassphrase is the BIP39 passphrase of a BIP39 mnemonic seed phrase
+	SeedPassphrase string
+	Encrypt        bool
+	Password       []byte
+	CryptoType     CryptoType
+	// Argon2id are the argon2id parameters if CryptoType is argon2id-chacha20poly1305.
+	// The zero value uses encrypt.DefaultArgon2idChacha20poly1305.
+	Argon2id encrypt.Argon2idChacha20poly1305
+}
+
+// NewImportedWallet creates a wallet from the data of another format.
+// A seed phrase creates a deterministic wallet of the phrase. The keys of an Electrum seed are derived
+// like the keys of any other seed, so the addresses of the wallet are not the addresses of the Electrum wallet.
+// A private key list or a keystore creates a collection wallet of the keys.
+func NewImportedWallet(wltName string, opts ImportOptions) (*Wallet, error) {
+	if len(bytes.TrimSpace(opts.Data)) == 0 {
+		return nil, ErrMissingImportData
+	}
+
+	if opts.Format == ImportFormatSeed {
+		seed, isBIP39, err := parseSeedPhrase(string(opts.Data))
+		if err != nil {
+			return nil, err
+		}
+
+		return newWallet(wltName, Options{
+			Label:          opts.Label,
+			Seed:           seed,
+			GenerateN:      opts.GenerateN,
+			BIP39:          isBIP39,
+			SeedPassphrase: opts.SeedPassphrase,
+			Encrypt:        opts.Encrypt,
+			Password:       opts.Password,
+			CryptoType:     opts.CryptoType,
+			Argon2id:       opts.Argon2id,
+		}, nil)
+	}
+
+	var keys []cipher.SecKey
+	var err error
+	switch opts.Format {
+	case ImportFormatKeys:
+		keys, err = parseKeyList(opts.Data)
+	case ImportFormatKeystore:
+		keys, err = parseKeystore(opts.Data)
+	default:
+		return nil, ErrUnknownImportFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.GenerateN != 0 || opts.SeedPassphrase != "" {
+		return nil, NewError(errors.New("the number of addresses and the seed passphrase are only used for seed phrases"))
+	}
+
+	w, err := NewCollectionWallet(wltName, opts.Label, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	if !opts.Encrypt {
+		if len(opts.Password) != 0 {
+			return nil, ErrMissingEncrypt
+		}
+		return w, nil
+	}
+
+	if len(opts.Password) == 0 {
+		return nil, ErrMissingPassword
+	}
+
+	crypto, err := getCryptoWithArgon2id(opts.CryptoType, opts.Argon2id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := w.lock(opts.Password, opts.CryptoType, crypto); err != nil {
+		return nil, err
+	}
+
+	if err := w.Validate(); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// parseSeedPhrase normalizes a seed phrase, lowercased with its words separated by single spaces,
+// and checks that it is an Electrum seed or a BIP39 mnemonic. Returns true if it is a BIP39 mnemonic.
+func parseSeedPhrase(phrase string) (string, bool, error) {
+	seed := strings.Join(strings.Fields(strings.ToLower(phrase)), " ")
+
+	if bip39.ValidateMnemonic(seed) == nil {
+		return seed, true, nil
+	}
+
+	if isElectrumSeed(seed) {
+		return seed, false, nil
+	}
+
+	return "", false, NewError(errors.New("seed phrase is neither an Electrum seed nor a valid BIP39 mnemonic"))
+}
+
+// isElectrumSeed returns true if a normalized seed phrase is an Electrum seed,
+// whose HMAC-SHA512 keyed with "Seed version" starts with the prefix of a type of Electrum wallet
+func isElectrumSeed(seed string) bool {
+	mac := hmac.New(sha512.New, []byte("Seed version"))
+	mac.Write([]byte(seed)) // nolint: errcheck
+	h := hex.EncodeToString(mac.Sum(nil))
+
+	for _, p := range electrumSeedPrefixes {
+		if strings.HasPrefix(h, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseKey parses a private key, hex encoded or in the wallet import format of Bitcoin
+func parseKey(s string) (cipher.SecKey, error) {
+	var k cipher.SecKey
+	var err error
+	if len(s) == len(k)*2 {
+		k, err = cipher.SecKeyFromHex(s)
+	} else {
+		k, err = cipher.SecKeyFromBitcoinWalletImportFormat(s)
+	}
+	if err != nil {
+		return cipher.SecKey{}, err
+	}
+
+	if err := k.Verify(); err != nil {
+		return cipher.SecKey{}, err
+	}
+
+	return k, nil
+}
+
+// parseKeyList parses a private key list, see ImportFormatKeys.
+// The errors don't include the keys, only their line numbers
+func parseKeyList(data []byte) ([]cipher.SecKey, error) {
+	var keys []cipher.SecKey
+	lines := make(map[cipher.SecKey]int)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	n := 0
+	for scanner.Scan() {
+		n++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		k, err := parseKey(line)
+		if err != nil {
+			return nil, NewError(fmt.Errorf("line %d: invalid private key", n))
+		}
+
+		if prev, ok := lines[k]; ok {
+			return nil, NewError(fmt.Errorf("line %d: duplicate of the private key of line %d", n, prev))
+		}
+		lines[k] = n
+
+		keys = append(keys, k)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, NewError(fmt.Errorf("invalid private key list: %v", err))
+	}
+
+	if len(keys) == 0 {
+		return nil, ErrMissingCollectionKeys
+	}
+
+	return keys, nil
+}
+
+// keystore is a JSON keystore, see ImportFormatKeystore
+type keystore struct {
+	Keys    []ReadableEntry `json:"keys"`
+	Entries []ReadableEntry `json:"entries"`
+}
+
+// parseKeystore parses a JSON keystore, see ImportFormatKeystore.
+// The errors don't include the keys, only their index in the keystore
+func parseKeystore(data []byte) ([]cipher.SecKey, error) {
+	var ks keystore
+	if err := json.Unmarshal(data, &ks); err != nil {
+		return nil, NewError(fmt.Errorf("invalid keystore: %v", err))
+	}
+
+	if len(ks.Keys) != 0 && len(ks.Entries) != 0 {
+		return nil, NewError(errors.New("invalid keystore: keys and entries are both set"))
+	}
+
+	entries := ks.Keys
+	if len(entries) == 0 {
+		entries = ks.Entries
+	}
+
+	if len(entries) == 0 {
+		return nil, ErrMissingCollectionKeys
+	}
+
+	keys := make([]cipher.SecKey, len(entries))
+	indexes := make(map[cipher.SecKey]int, len(entries))
+	for i, e := range entries {
+		if e.Secret == "" {
+			return nil, NewError(fmt.Errorf("key %d: missing secret_key", i))
+		}
+
+		k, err := parseKey(e.Secret)
+		if err != nil {
+			return nil, NewError(fmt.Errorf("key %d: invalid secret_key", i))
+		}
+
+		p := cipher.MustPubKeyFromSecKey(k)
+		if e.Public != "" && e.Public != p.Hex() {
+			return nil, NewError(fmt.Errorf("key %d: public_key is not the public key of secret_key", i))
+		}
+
+		// The address is checked as a skycoin or a bitcoin address, as in the wallet files of both coins
+		if e.Address != "" && e.Address != cipher.AddressFromPubKey(p).String() && e.Address != cipher.BitcoinAddressFromPubKey(p).String() {
+			return nil, NewError(fmt.Errorf("key %d: address is not the address of secret_key", i))
+		}
+
+		if prev, ok := indexes[k]; ok {
+			return nil, NewError(fmt.Errorf("key %d: duplicate of key %d", i, prev))
+		}
+		indexes[k] = i
+
+		keys[i] = k
+	}
+
+	return keys, nil
+}
+
+// validateImportWalletID checks that the ID of an imported wallet is the name of a wallet file,
+// so that a wallet can't be imported out of the wallet directory
+func validateImportWalletID(id string) error {
+	if filepath.Base(id) != id || !strings.HasSuffix(id, "."+WalletExt) {
+		return NewError(fmt.Errorf("invalid wallet id %q", id))
+	}
+	return nil
+}
+
+// importConflict returns an error if an imported wallet conflicts with the existing wallets:
+// if a wallet file already has its ID, or if a wallet that is not watch-only already has one of its addresses,
+// as the wallets of the same seed or keys would.
+// ids are the IDs of the existing wallet files.
+func importConflict(w *Wallet, ids map[string]struct{}, wlts Wallets) error {
+	if _, ok := ids[w.Filename()]; ok {
+		return ErrImportWalletExists
+	}
+
+	addrs := make(map[cipher.Address]struct{}, len(w.Entries))
+	for _, e := range w.Entries {
+		addrs[e.SkycoinAddress()] = struct{}{}
+	}
+
+	// Check the wallets in the order of their IDs, so that the same conflict is reported each time
+	existingIDs := make([]string, 0, len(wlts))
+	for id := range wlts {
+		existingIDs = append(existingIDs, id)
+	}
+	sort.Strings(existingIDs)
+
+	for _, id := range existingIDs {
+		existing := wlts[id]
+		if existing.IsWatchOnly() || existing.coin() != CoinTypeSkycoin {
+			continue
+		}
+
+		for _, e := range existing.Entries {
+			if _, ok := addrs[e.SkycoinAddress()]; ok {
+				return NewError(fmt.Errorf("address %s is already in wallet %s", e.Address, id))
+			}
+		}
+	}
+
+	return nil
+}
+
+// ImportWalletToDir imports a wallet from the data of another format as a wallet file of a directory,
+// see NewImportedWallet. The wallet is not saved if it conflicts with the wallet files of the directory,
+// see Service.ImportWallet. If wltName is empty, a wallet file name is generated.
+func ImportWalletToDir(dir, wltName string, opts ImportOptions) (*Wallet, error) {
+	if wltName == "" {
+		wltName = NewWalletFilename()
+	}
+
+	if err := validateImportWalletID(wltName); err != nil {
+		return nil, err
+	}
+
+	w, err := NewImportedWallet(wltName, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := LoadWallets(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]struct{}, len(existing))
+	for id := range existing {
+		ids[id] = struct{}{}
+	}
+
+	if err := importConflict(w, ids, existing); err != nil {
+		return nil, err
+	}
+
+	w.upgradeVersion()
+	if err := w.Save(dir); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// ImportWallet imports a wallet from the data of another format, see NewImportedWallet,
+// and saves it to the storage of new wallets.
+//
+// The wallet is not imported if it conflicts with the existing wallets: if a wallet file of a storage
+// already has its ID, or if a wallet that is not watch-only already has one of its addresses,
+// because it was created from the same seed or has some of the same keys.
+// If wltName is empty, a wallet file name is generated.
+func (serv *Service) ImportWallet(wltName string, opts ImportOptions) (*Wallet, error) {
+	if !serv.enableWalletAPI {
+		return nil, ErrWalletAPIDisabled
+	}
+
+	if wltName == "" {
+		wltName = serv.generateUniqueWalletFilename()
+	}
+
+	if err := validateImportWalletID(wltName); err != nil {
+		return nil, err
+	}
+
+	// service decides what crypto type the wallet should use.
+	if opts.Encrypt {
+		opts.CryptoType = serv.cryptoType
+		opts.Argon2id = serv.argon2id
+	}
+
+	w, err := NewImportedWallet(wltName, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	unlock := serv.lockWallet(wltName)
+	defer unlock()
+
+	if err := serv.registerImportedWallet(w); err != nil {
+		return nil, err
+	}
+
+	if err := serv.saveWallet(w); err != nil {
+		// If save fails, remove the imported wallet
+		serv.unregisterWallet(w)
+		return nil, err
+	}
+
+	return w.clone(), nil
+}
+
+// registerImportedWallet adds an imported wallet to the registry if it doesn't conflict with the existing wallets,
+// before it is saved. The conflicts are checked and the wallet is added while the registry is locked,
+// so that the wallets created meanwhile can't conflict with it.
+func (serv *Service) registerImportedWallet(w *Wallet) error {
+	serv.Lock()
+	defer serv.Unlock()
+
+	ids, err := serv.walletFileIDsLocked()
+	if err != nil {
+		return err
+	}
+
+	if err := importConflict(w, ids, serv.wallets); err != nil {
+		return err
+	}
+
+	return serv.registerWalletLocked(w)
+}
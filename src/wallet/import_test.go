@@ -0,0 +1,433 @@
+package wallet
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/testutil"
+)
+
+const (
+	// testElectrumSeed is an Electrum seed of a standard wallet, it is not a valid BIP39 mnemonic
+	testElectrumSeed = "wild father tree among universe such mobile favorite target dynamic credit away"
+	testBIP39Seed    = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+)
+
+func TestNewImportedWalletKeys(t *testing.T) {
+	_, s1 := cipher.GenerateKeyPair()
+	_, s2 := cipher.GenerateKeyPair()
+
+	data := fmt.Sprintf("# exported keys\n%s\n\n  %s  \n", s1.Hex(), cipher.BitcoinWalletImportFormatFromSeckey(s2))
+
+	w, err := NewImportedWallet("a.wlt", ImportOptions{
+		Format: ImportFormatKeys,
+		Data:   []byte(data),
+		Label:  "imported",
+	})
+	require.NoError(t, err)
+	require.Equal(t, WalletTypeCollection, w.Type())
+	require.Equal(t, "imported", w.Label())
+	require.Empty(t, w.seed())
+	require.Len(t, w.Entries, 2)
+	require.Equal(t, s1, w.Entries[0].Secret)
+	require.Equal(t, cipher.MustAddressFromSecKey(s1), w.Entries[0].SkycoinAddress())
+	require.Equal(t, s2, w.Entries[1].Secret)
+	require.Equal(t, cipher.MustAddressFromSecKey(s2), w.Entries[1].SkycoinAddress())
+
+	// A collection wallet can't generate addresses
+	_, err = w.GenerateAddresses(1)
+	require.Equal(t, ErrWalletNotDeterministic, err)
+	_, err = w.CreateAccount("savings")
+	require.Equal(t, ErrWalletNotDeterministic, err)
+
+	cases := []struct {
+		name string
+		data string
+		err  error
+	}{
+		{
+			name: "no keys",
+			data: "# nothing\n\n",
+			err:  ErrMissingCollectionKeys,
+		},
+		{
+			name: "empty",
+			data: " \n",
+			err:  ErrMissingImportData,
+		},
+		{
+			name: "invalid key",
+			data: fmt.Sprintf("%s\nfoo\n", s1.Hex()),
+			err:  NewError(fmt.Errorf("line 2: invalid private key")),
+		},
+		{
+			name: "null key",
+			data: cipher.SecKey{}.Hex(),
+			err:  NewError(fmt.Errorf("line 1: invalid private key")),
+		},
+		{
+			name: "duplicate key",
+			data: fmt.Sprintf("%s\n# again\n%s\n", s1.Hex(), cipher.BitcoinWalletImportFormatFromSeckey(s1)),
+			err:  NewError(fmt.Errorf("line 3: duplicate of the private key of line 1")),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewImportedWallet("a.wlt", ImportOptions{
+				Format: ImportFormatKeys,
+				Data:   []byte(tc.data),
+			})
+			require.Equal(t, tc.err, err)
+		})
+	}
+
+	// The number of addresses and the seed passphrase only apply to seed phrases
+	_, err = NewImportedWallet("a.wlt", ImportOptions{
+		Format:    ImportFormatKeys,
+		Data:      []byte(s1.Hex()),
+		GenerateN: 2,
+	})
+	require.Equal(t, NewError(fmt.Errorf("the number of addresses and the seed passphrase are only used for seed phrases")), err)
+}
+
+func TestNewImportedWalletSeed(t *testing.T) {
+	// The Electrum seed is normalized and the wallet is the deterministic wallet of the normalized seed
+	w, err := NewImportedWallet("a.wlt", ImportOptions{
+		Format:    ImportFormatSeed,
+		Data:      []byte("  Wild father tree among universe\nsuch mobile favorite target dynamic credit   away\n"),
+		GenerateN: 3,
+	})
+	require.NoError(t, err)
+	require.Equal(t, WalletTypeDeterministic, w.Type())
+	require.Equal(t, testElectrumSeed, w.seed())
+	require.Len(t, w.Entries, 3)
+
+	w2, err := NewWallet("b.wlt", Options{
+		Seed:      testElectrumSeed,
+		GenerateN: 3,
+	})
+	require.NoError(t, err)
+	require.Equal(t, w2.Entries, w.Entries)
+
+	// A BIP39 mnemonic can have a BIP39 passphrase
+	w, err = NewImportedWallet("a.wlt", ImportOptions{
+		Format:         ImportFormatSeed,
+		Data:           []byte(testBIP39Seed),
+		SeedPassphrase: "TREZOR",
+	})
+	require.NoError(t, err)
+	require.Equal(t, testBIP39Seed, w.seed())
+	require.Equal(t, "TREZOR", w.seedPassphrase())
+	require.Len(t, w.Entries, 1)
+
+	// The Electrum seeds are not BIP39 mnemonics
+	_, err = NewImportedWallet("a.wlt", ImportOptions{
+		Format:         ImportFormatSeed,
+		Data:           []byte(testElectrumSeed),
+		SeedPassphrase: "TREZOR",
+	})
+	require.Error(t, err)
+	require.IsType(t, Error{}, err)
+
+	_, err = NewImportedWallet("a.wlt", ImportOptions{
+		Format: ImportFormatSeed,
+		Data:   []byte("wild father tree among universe such mobile favorite target dynamic credit zoo"),
+	})
+	require.Equal(t, NewError(fmt.Errorf("seed phrase is neither an Electrum seed nor a valid BIP39 mnemonic")), err)
+
+	// The wallet can be encrypted
+	w, err = NewImportedWallet("a.wlt", ImportOptions{
+		Format:     ImportFormatSeed,
+		Data:       []byte(testElectrumSeed),
+		Encrypt:    true,
+		Password:   []byte("pwd"),
+		CryptoType: CryptoTypeSha256Xor,
+	})
+	require.NoError(t, err)
+	require.True(t, w.IsEncrypted())
+	checkNoSensitiveData(t, w)
+
+	_, err = NewImportedWallet("a.wlt", ImportOptions{
+		Format: "electrum",
+		Data:   []byte(testElectrumSeed),
+	})
+	require.Equal(t, ErrUnknownImportFormat, err)
+}
+
+func TestNewImportedWalletKeystore(t *testing.T) {
+	p1, s1 := cipher.GenerateKeyPair()
+	p2, s2 := cipher.GenerateKeyPair()
+
+	ks := map[string][]ReadableEntry{
+		"keys": {
+			{
+				Address: cipher.AddressFromPubKey(p1).String(),
+				Public:  p1.Hex(),
+				Secret:  s1.Hex(),
+			},
+			{
+				Address: cipher.BitcoinAddressFromPubKey(p2).String(),
+				Secret:  cipher.BitcoinWalletImportFormatFromSeckey(s2),
+			},
+		},
+	}
+	data, err := json.Marshal(ks)
+	require.NoError(t, err)
+
+	w, err := NewImportedWallet("a.wlt", ImportOptions{
+		Format:     ImportFormatKeystore,
+		Data:       data,
+		Encrypt:    true,
+		Password:   []byte("pwd"),
+		CryptoType: CryptoTypeSha256Xor,
+	})
+	require.NoError(t, err)
+	require.Equal(t, WalletTypeCollection, w.Type())
+	require.True(t, w.IsEncrypted())
+	require.NoError(t, w.Validate())
+	checkNoSensitiveData(t, w)
+	require.Equal(t, []cipher.Address{cipher.AddressFromPubKey(p1), cipher.AddressFromPubKey(p2)}, []cipher.Address{w.Entries[0].SkycoinAddress(), w.Entries[1].SkycoinAddress()})
+
+	// The keys are encrypted and decrypted like the keys of a deterministic wallet
+	w2, err := w.Unlock([]byte("pwd"))
+	require.NoError(t, err)
+	require.Equal(t, s1, w2.Entries[0].Secret)
+	require.Equal(t, s2, w2.Entries[1].Secret)
+	require.NoError(t, w2.Validate())
+
+	// The entries of a wallet file are a keystore
+	wlt, err := NewWallet("b.wlt", Options{
+		Seed:      "seed",
+		GenerateN: 2,
+	})
+	require.NoError(t, err)
+	data, err = json.Marshal(NewReadableWallet(wlt))
+	require.NoError(t, err)
+
+	w, err = NewImportedWallet("a.wlt", ImportOptions{
+		Format: ImportFormatKeystore,
+		Data:   data,
+	})
+	require.NoError(t, err)
+	require.Equal(t, wlt.Entries, w.Entries)
+
+	cases := []struct {
+		name string
+		data string
+		err  error
+	}{
+		{
+			name: "invalid json",
+			data: "{",
+			err:  NewError(fmt.Errorf("invalid keystore: unexpected end of JSON input")),
+		},
+		{
+			name: "no keys",
+			data: `{"keys": []}`,
+			err:  ErrMissingCollectionKeys,
+		},
+		{
+			name: "keys and entries",
+			data: fmt.Sprintf(`{"keys": [{"secret_key": "%s"}], "entries": [{"secret_key": "%s"}]}`, s1.Hex(), s2.Hex()),
+			err:  NewError(fmt.Errorf("invalid keystore: keys and entries are both set")),
+		},
+		{
+			name: "missing secret key",
+			data: fmt.Sprintf(`{"keys": [{"secret_key": "%s"}, {"address": "%s"}]}`, s1.Hex(), cipher.AddressFromPubKey(p2)),
+			err:  NewError(fmt.Errorf("key 1: missing secret_key")),
+		},
+		{
+			name: "invalid secret key",
+			data: `{"keys": [{"secret_key": "foo"}]}`,
+			err:  NewError(fmt.Errorf("key 0: invalid secret_key")),
+		},
+		{
+			name: "wrong public key",
+			data: fmt.Sprintf(`{"keys": [{"secret_key": "%s", "public_key": "%s"}]}`, s1.Hex(), p2.Hex()),
+			err:  NewError(fmt.Errorf("key 0: public_key is not the public key of secret_key")),
+		},
+		{
+			name: "wrong address",
+			data: fmt.Sprintf(`{"keys": [{"secret_key": "%s", "address": "%s"}]}`, s1.Hex(), cipher.AddressFromPubKey(p2)),
+			err:  NewError(fmt.Errorf("key 0: address is not the address of secret_key")),
+		},
+		{
+			name: "duplicate key",
+			data: fmt.Sprintf(`{"keys": [{"secret_key": "%s"}, {"secret_key": "%s"}]}`, s1.Hex(), s1.Hex()),
+			err:  NewError(fmt.Errorf("key 1: duplicate of key 0")),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewImportedWallet("a.wlt", ImportOptions{
+				Format: ImportFormatKeystore,
+				Data:   []byte(tc.data),
+			})
+			require.Equal(t, tc.err, err)
+		})
+	}
+}
+
+func TestImportWalletToDir(t *testing.T) {
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	w, err := NewWallet("a.wlt", Options{
+		Seed: testElectrumSeed,
+	})
+	require.NoError(t, err)
+	require.NoError(t, w.Save(dir))
+
+	_, err = ImportWalletToDir(dir, "a.wlt", ImportOptions{
+		Format: ImportFormatSeed,
+		Data:   []byte(testBIP39Seed),
+	})
+	require.Equal(t, ErrImportWalletExists, err)
+
+	_, err = ImportWalletToDir(dir, "b.wlt", ImportOptions{
+		Format: ImportFormatSeed,
+		Data:   []byte(testElectrumSeed),
+	})
+	require.Equal(t, NewError(fmt.Errorf("address %s is already in wallet a.wlt", w.Entries[0].Address)), err)
+	testutil.RequireFileNotExists(t, filepath.Join(dir, "b.wlt"))
+
+	_, err = ImportWalletToDir(dir, "../b.wlt", ImportOptions{
+		Format: ImportFormatSeed,
+		Data:   []byte(testBIP39Seed),
+	})
+	require.Equal(t, NewError(fmt.Errorf(`invalid wallet id "../b.wlt"`)), err)
+
+	_, s := cipher.GenerateKeyPair()
+	imported, err := ImportWalletToDir(dir, "", ImportOptions{
+		Format: ImportFormatKeys,
+		Data:   []byte(s.Hex()),
+	})
+	require.NoError(t, err)
+
+	// The collection wallet is loaded from its file
+	loaded, err := Load(filepath.Join(dir, imported.Filename()))
+	require.NoError(t, err)
+	require.Equal(t, WalletTypeCollection, loaded.Type())
+	require.Equal(t, imported.Entries, loaded.Entries)
+}
+
+func TestServiceImportWallet(t *testing.T) {
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	s, err := NewService(Config{
+		WalletDir:       dir,
+		CryptoType:      CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	a, err := s.CreateWallet("a.wlt", Options{
+		Seed:      "seed-a",
+		GenerateN: 3,
+	}, nil)
+	require.NoError(t, err)
+
+	// A watch-only wallet can watch the addresses of an imported wallet
+	_, s1 := cipher.GenerateKeyPair()
+	_, err = s.CreateWatchOnlyWallet("w.wlt", "", []cipher.Address{cipher.MustAddressFromSecKey(s1)})
+	require.NoError(t, err)
+
+	w, err := s.ImportWallet("", ImportOptions{
+		Format:   ImportFormatKeys,
+		Data:     []byte(s1.Hex()),
+		Label:    "imported",
+		Encrypt:  true,
+		Password: []byte("pwd"),
+	})
+	require.NoError(t, err)
+	require.Equal(t, WalletTypeCollection, w.Type())
+	require.Equal(t, string(CryptoTypeSha256Xor), w.Meta[metaCryptoType])
+	checkNoSensitiveData(t, w)
+
+	got, err := s.GetWallet(w.Filename())
+	require.NoError(t, err)
+	require.Equal(t, w, got)
+
+	loaded, err := Load(filepath.Join(dir, w.Filename()))
+	require.NoError(t, err)
+	require.Equal(t, w.Entries, loaded.Entries)
+
+	// The seed of a collection wallet can't be read
+	s.enableSeedAPI = true
+	_, err = s.GetWalletSeed(w.Filename(), []byte("pwd"))
+	require.Equal(t, ErrWalletNotDeterministic, err)
+
+	// The imported keys can't be imported again, nor the keys of another wallet
+	_, err = s.ImportWallet("b.wlt", ImportOptions{
+		Format: ImportFormatKeys,
+		Data:   []byte(s1.Hex()),
+	})
+	require.Equal(t, NewError(fmt.Errorf("address %s is already in wallet %s", cipher.MustAddressFromSecKey(s1), w.Filename())), err)
+
+	_, err = s.ImportWallet("b.wlt", ImportOptions{
+		Format: ImportFormatKeys,
+		Data:   []byte(a.Entries[2].Secret.Hex()),
+	})
+	require.Equal(t, NewError(fmt.Errorf("address %s is already in wallet a.wlt", a.Entries[2].Address)), err)
+
+	_, err = s.ImportWallet("a.wlt", ImportOptions{
+		Format: ImportFormatSeed,
+		Data:   []byte(testElectrumSeed),
+	})
+	require.Equal(t, ErrImportWalletExists, err)
+
+	// The wallet files that were not loaded conflict too
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "c.wlt"), nil, 0600))
+	_, err = s.ImportWallet("c.wlt", ImportOptions{
+		Format: ImportFormatSeed,
+		Data:   []byte(testElectrumSeed),
+	})
+	require.Equal(t, ErrImportWalletExists, err)
+	require.NoError(t, os.Remove(filepath.Join(dir, "c.wlt")))
+
+	b, err := s.ImportWallet("b.wlt", ImportOptions{
+		Format:    ImportFormatSeed,
+		Data:      []byte(testElectrumSeed),
+		GenerateN: 2,
+	})
+	require.NoError(t, err)
+	require.Equal(t, WalletTypeDeterministic, b.Type())
+	require.Len(t, b.Entries, 2)
+
+	// The imported deterministic wallet generates addresses
+	addrs, err := s.NewAddresses("b.wlt", nil, 1)
+	require.NoError(t, err)
+	require.Len(t, addrs, 1)
+
+	// The collection wallet doesn't
+	_, err = s.NewAddresses(w.Filename(), []byte("pwd"), 1)
+	require.Equal(t, ErrWalletNotDeterministic, err)
+
+	// The wallets are loaded again by a new service
+	s2, err := NewService(Config{
+		WalletDir:       dir,
+		CryptoType:      CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+	got, err = s2.GetWallet(w.Filename())
+	require.NoError(t, err)
+	require.Equal(t, w.Entries, got.Entries)
+
+	s.enableWalletAPI = false
+	_, err = s.ImportWallet("d.wlt", ImportOptions{
+		Format: ImportFormatSeed,
+		Data:   []byte(testBIP39Seed),
+	})
+	require.Equal(t, ErrWalletAPIDisabled, err)
+}
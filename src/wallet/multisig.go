@@ -0,0 +1,177 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+)
+
+// MaxMultisigs is the maximum number of multisig addresses of a wallet
+const MaxMultisigs = 100
+
+var (
+	// ErrTooManyMultisigs is returned if a wallet has too many multisig addresses
+	ErrTooManyMultisigs = NewError(fmt.Errorf("wallet can't have more than %d multisig addresses", MaxMultisigs))
+	// ErrMultisigDuplicate is returned if a wallet has the same multisig address twice
+	ErrMultisigDuplicate = NewError(errors.New("multisig addresses contain duplicates"))
+)
+
+// AddMultisig adds the address of an m-of-n multisig to a watch-only wallet, see cipher.Multisig.
+// The unsigned transactions created by the wallet carry the multisig witness of the inputs that spend
+// the outputs of the address, and the wallets of the public keys of the multisig cosign them, see SignPartialTransaction.
+// A multisig that the wallet has already is ignored.
+func (w *Wallet) AddMultisig(ms cipher.Multisig) error {
+	if !w.IsWatchOnly() {
+		return ErrWalletNotWatchOnly
+	}
+
+	if err := ms.Verify(); err != nil {
+		return NewError(fmt.Errorf("invalid multisig: %v", err))
+	}
+
+	addr := ms.Address()
+	if _, ok := w.multisig(addr); ok {
+		return nil
+	}
+
+	if len(w.Multisigs) >= MaxMultisigs {
+		return ErrTooManyMultisigs
+	}
+
+	if _, ok := w.GetEntry(addr); !ok {
+		w.Entries = append(w.Entries, Entry{
+			Address: addr,
+		})
+	}
+
+	w.Multisigs = append(w.Multisigs, cloneMultisig(ms))
+	return nil
+}
+
+// multisig returns the multisig of an address of the wallet
+func (w *Wallet) multisig(addr cipher.Address) (cipher.Multisig, bool) {
+	for _, ms := range w.Multisigs {
+		if ms.Address() == addr {
+			return ms, true
+		}
+	}
+	return cipher.Multisig{}, false
+}
+
+// validateMultisigs checks that the multisigs of the wallet are valid, unique,
+// and that their addresses are addresses of the wallet
+func (w *Wallet) validateMultisigs() error {
+	if len(w.Multisigs) == 0 {
+		return nil
+	}
+
+	if !w.IsWatchOnly() {
+		return errors.New("only watch-only wallets can have multisig addresses")
+	}
+
+	if len(w.Multisigs) > MaxMultisigs {
+		return ErrTooManyMultisigs
+	}
+
+	addrs := make(map[cipher.Address]struct{}, len(w.Multisigs))
+	for _, ms := range w.Multisigs {
+		if err := ms.Verify(); err != nil {
+			return fmt.Errorf("invalid multisig: %v", err)
+		}
+
+		addr := ms.Address()
+		if _, ok := addrs[addr]; ok {
+			return ErrMultisigDuplicate
+		}
+		addrs[addr] = struct{}{}
+
+		if _, ok := w.GetEntry(addr); !ok {
+			return fmt.Errorf("multisig address %s is not an address of the wallet", addr)
+		}
+	}
+
+	return nil
+}
+
+// pushMultisigWitnesses adds the multisig witness of each input of an unsigned transaction
+// that spends an output of a multisig address of the wallet
+func (w *Wallet) pushMultisigWitnesses(txn *coin.Transaction, inputs []UxBalance) error {
+	for i, in := range inputs {
+		ms, ok := w.multisig(in.Address)
+		if !ok {
+			continue
+		}
+
+		if err := txn.PushMultisigWitness(i, ms); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func cloneMultisig(ms cipher.Multisig) cipher.Multisig {
+	return cipher.Multisig{
+		M:       ms.M,
+		PubKeys: append([]cipher.PubKey(nil), ms.PubKeys...),
+	}
+}
+
+func cloneMultisigs(mss []cipher.Multisig) []cipher.Multisig {
+	if len(mss) == 0 {
+		return nil
+	}
+
+	c := make([]cipher.Multisig, len(mss))
+	for i, ms := range mss {
+		c[i] = cloneMultisig(ms)
+	}
+	return c
+}
+
+// ReadableMultisig is the JSON representation of a cipher.Multisig, in the wallet file
+type ReadableMultisig struct {
+	M          uint8    `json:"m"`
+	PublicKeys []string `json:"public_keys"`
+}
+
+// newReadableMultisigs creates the ReadableMultisigs of multisigs, returns nil if there are none
+func newReadableMultisigs(mss []cipher.Multisig) []ReadableMultisig {
+	if len(mss) == 0 {
+		return nil
+	}
+
+	rms := make([]ReadableMultisig, len(mss))
+	for i, ms := range mss {
+		rms[i].M = ms.M
+		rms[i].PublicKeys = make([]string, len(ms.PubKeys))
+		for j, pk := range ms.PubKeys {
+			rms[i].PublicKeys[j] = pk.Hex()
+		}
+	}
+
+	return rms
+}
+
+// toMultisigs converts ReadableMultisigs to multisigs
+func toMultisigs(rms []ReadableMultisig) ([]cipher.Multisig, error) {
+	if len(rms) == 0 {
+		return nil, nil
+	}
+
+	mss := make([]cipher.Multisig, len(rms))
+	for i, rm := range rms {
+		mss[i].M = rm.M
+		for _, s := range rm.PublicKeys {
+			pk, err := cipher.PubKeyFromHex(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid multisig public key %q: %v", s, err)
+			}
+			mss[i].PubKeys = append(mss[i].PubKeys, pk)
+		}
+	}
+
+	return mss, nil
+}
@@ -0,0 +1,177 @@
+package wallet
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/testutil"
+)
+
+// makeMultisigWallets creates three wallets and the 2-of-3 multisig of the first key of each
+func makeMultisigWallets(t *testing.T) ([]*Wallet, cipher.Multisig) {
+	wallets := make([]*Wallet, 3)
+	pks := make([]cipher.PubKey, 3)
+	for i, seed := range []string{"seed1", "seed2", "seed3"} {
+		wallets[i], _ = makePartialTransactionWallet(t, seed)
+		pks[i] = wallets[i].Entries[0].Public
+	}
+
+	ms, err := cipher.NewMultisig(2, pks)
+	require.NoError(t, err)
+
+	return wallets, ms
+}
+
+func TestWalletAddMultisig(t *testing.T) {
+	wallets, ms := makeMultisigWallets(t)
+
+	require.Equal(t, ErrWalletNotWatchOnly, wallets[0].AddMultisig(ms))
+
+	w, err := NewWatchOnlyWallet("t.wlt", "", []cipher.Address{testutil.MakeAddress()})
+	require.NoError(t, err)
+
+	err = w.AddMultisig(cipher.Multisig{M: 1})
+	testutil.RequireError(t, err, "invalid multisig: "+cipher.ErrMultisigRequiredSigs.Error())
+
+	require.NoError(t, w.AddMultisig(ms))
+	require.Len(t, w.Entries, 2)
+	require.Equal(t, ms.Address(), w.Entries[1].SkycoinAddress())
+	require.Equal(t, []cipher.Multisig{ms}, w.Multisigs)
+
+	// A multisig that the wallet has already is ignored
+	require.NoError(t, w.AddMultisig(ms))
+	require.Len(t, w.Entries, 2)
+	require.Len(t, w.Multisigs, 1)
+
+	// The multisigs are saved in the wallet file
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, w.Save(dir))
+	loaded, err := Load(filepath.Join(dir, w.Filename()))
+	require.NoError(t, err)
+	require.Equal(t, w.Multisigs, loaded.Multisigs)
+	require.Equal(t, w.Multisigs, loaded.clone().Multisigs)
+
+	// The address of a multisig must be an address of the wallet
+	rw := NewReadableWallet(w)
+	rw.Entries = rw.Entries[:1]
+	_, err = rw.ToWallet()
+	testutil.RequireError(t, err, "invalid wallet t.wlt: multisig address "+ms.Address().String()+" is not an address of the wallet")
+
+	// The keys of a multisig are sorted
+	rw = NewReadableWallet(w)
+	pks := rw.Multisigs[0].PublicKeys
+	pks[0], pks[1] = pks[1], pks[0]
+	_, err = rw.ToWallet()
+	testutil.RequireError(t, err, "invalid wallet t.wlt: invalid multisig: "+cipher.ErrMultisigPubKeysOrder.Error())
+}
+
+func TestWalletMultisigTransaction(t *testing.T) {
+	headTime := uint64(time.Now().UTC().Unix())
+	wallets, ms := makeMultisigWallets(t)
+
+	w, err := NewWatchOnlyWallet("t.wlt", "", []cipher.Address{testutil.MakeAddress()})
+	require.NoError(t, err)
+	require.NoError(t, w.AddMultisig(ms))
+
+	// The cosigners of the multisig are the owners of the addresses of its keys
+	require.NoError(t, w.SetCosigners(Cosigners{
+		{Label: "alice", Addresses: []cipher.Address{wallets[0].Entries[0].SkycoinAddress()}},
+		{Label: "bob", Addresses: []cipher.Address{wallets[1].Entries[0].SkycoinAddress()}},
+		{Label: "carol", Addresses: []cipher.Address{wallets[2].Entries[0].SkycoinAddress()}},
+	}))
+
+	_, keys := makePartialTransactionWallet(t, "seed1")
+	uxout := makeUxOut(t, keys[0], 2e6, 100)
+	uxout.Body.Address = ms.Address()
+	auxs := coin.AddressUxOuts{
+		ms.Address(): []coin.UxOut{uxout},
+	}
+
+	p := CreateTransactionParams{
+		HoursSelection: HoursSelection{
+			Type: HoursSelectionTypeManual,
+		},
+		Wallet: CreateTransactionWalletParams{
+			ID: "t.wlt",
+		},
+		To: []coin.TransactionOutput{
+			{
+				Address: testutil.MakeAddress(),
+				Coins:   2e6,
+				Hours:   10,
+			},
+		},
+		Unsigned: true,
+	}
+
+	// The unsigned transaction has the witness of the multisig input
+	txn, inputs, err := w.CreateAndSignTransactionAdvanced(p, auxs, headTime)
+	require.NoError(t, err)
+	require.Equal(t, coin.TransactionTypeMultisig, txn.Type)
+	ws, err := txn.MultisigWitnesses()
+	require.NoError(t, err)
+	require.Len(t, ws, 1)
+	require.Equal(t, ms, ws[0].Multisig)
+
+	ptx, err := NewPartialTransaction(*txn, inputs)
+	require.NoError(t, err)
+	require.False(t, ptx.IsFullySigned())
+	require.Equal(t, []cipher.Address{ms.Address()}, ptx.UnsignedAddresses())
+	require.True(t, wallets[0].canSignPartialTransaction(ptx))
+
+	status := w.CosignerStatus(ptx)
+	for _, s := range status {
+		require.Equal(t, 1, s.Inputs)
+		require.False(t, s.Signed())
+	}
+
+	// The first key signs
+	n, err := wallets[0].SignPartialTransaction(ptx)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.False(t, ptx.IsFullySigned())
+	require.Equal(t, []cipher.Address{ms.Address()}, ptx.UnsignedAddresses())
+	require.NoError(t, ptx.Verify())
+	testutil.RequireError(t, ptx.Transaction.Verify(), "Not enough multisig signatures")
+
+	// A key that signed already can't sign again
+	require.False(t, wallets[0].canSignPartialTransaction(ptx))
+	_, err = wallets[0].SignPartialTransaction(ptx)
+	require.Equal(t, ErrNoInputsToSign, err)
+
+	status = w.CosignerStatus(ptx)
+	require.True(t, status[0].Signed())
+	require.False(t, status[1].Signed())
+	require.False(t, status[2].Signed())
+
+	// The third key signs separately, and the partial transactions are combined
+	other, err := NewPartialTransaction(*txn, inputs)
+	require.NoError(t, err)
+	_, err = wallets[2].SignPartialTransaction(other)
+	require.NoError(t, err)
+	require.NoError(t, ptx.Combine(other))
+
+	// 2 of the 3 keys signed
+	require.True(t, ptx.IsFullySigned())
+	require.Empty(t, ptx.UnsignedAddresses())
+	require.NoError(t, ptx.Transaction.Verify())
+	require.NoError(t, ptx.Transaction.VerifyInput(coin.UxArray{uxout}))
+
+	status = w.CosignerStatus(ptx)
+	for _, s := range status {
+		require.True(t, s.Signed())
+	}
+
+	// The second key is not needed
+	require.False(t, wallets[1].canSignPartialTransaction(ptx))
+	_, err = wallets[1].SignPartialTransaction(ptx)
+	require.Equal(t, ErrNoInputsToSign, err)
+}
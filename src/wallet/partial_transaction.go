@@ -0,0 +1,318 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/encoder"
+	"github.com/skycoin/skycoin/src/coin"
+)
+
+// PartialTransactionVersion is the version of the serialization format of PartialTransaction
+const PartialTransactionVersion uint8 = 1
+
+var (
+	// ErrPartialTransactionVersion is returned when deserializing a partial transaction of an unknown version
+	ErrPartialTransactionVersion = NewError(fmt.Errorf("partial transaction version is not %d", PartialTransactionVersion))
+	// ErrPartialTransactionInputs is returned if the inputs of a partial transaction do not match the transaction
+	ErrPartialTransactionInputs = NewError(errors.New("partial transaction inputs do not match the transaction inputs"))
+	// ErrPartialTransactionMismatch is returned when combining partial transactions of different transactions
+	ErrPartialTransactionMismatch = NewError(errors.New("partial transactions are not the same transaction"))
+	// ErrPartialTransactionSigConflict is returned when combining partial transactions with different signatures of an input
+	ErrPartialTransactionSigConflict = NewError(errors.New("partial transactions have different signatures for the same input"))
+	// ErrNoInputsToSign is returned if a wallet has none of the keys of the unsigned inputs of a partial transaction
+	ErrNoInputsToSign = NewError(errors.New("wallet has no keys for the unsigned inputs of the transaction"))
+)
+
+// PartialTransaction is a transaction whose inputs are not all signed yet, with the unspent outputs spent
+// by its inputs. The unspent outputs let a wallet sign the inputs it owns without access to the blockchain,
+// for instance on an offline machine. A transaction spending the outputs of several wallets is signed by each wallet,
+// one after the other or separately, in which case the partial transactions are combined.
+type PartialTransaction struct {
+	Transaction coin.Transaction
+	// Inputs are the unspent outputs spent by Transaction.In, in the same order
+	Inputs []coin.UxOut
+}
+
+// partialTransactionEncoding is the serialization format of a PartialTransaction
+type partialTransactionEncoding struct {
+	Version     uint8
+	Transaction coin.Transaction
+	Inputs      []coin.UxOut
+}
+
+// NewPartialTransaction creates a PartialTransaction of a transaction created with
+// CreateTransactionParams.Unsigned and its inputs
+func NewPartialTransaction(txn coin.Transaction, inputs []UxBalance) (*PartialTransaction, error) {
+	uxOuts := make([]coin.UxOut, len(inputs))
+	for i, in := range inputs {
+		uxOuts[i] = coin.UxOut{
+			Head: coin.UxHead{
+				Time:  in.Time,
+				BkSeq: in.BkSeq,
+			},
+			Body: coin.UxBody{
+				SrcTransaction: in.SrcTransaction,
+				Address:        in.Address,
+				Coins:          in.Coins,
+				Hours:          in.InitialHours,
+			},
+		}
+	}
+
+	ptx := &PartialTransaction{
+		Transaction: txn,
+		Inputs:      uxOuts,
+	}
+
+	if err := ptx.Verify(); err != nil {
+		return nil, err
+	}
+
+	return ptx, nil
+}
+
+// Verify checks that the inputs match the transaction, and that the transaction and its signatures are valid,
+// apart from its missing signatures. It does not check that the inputs are unspent.
+func (ptx *PartialTransaction) Verify() error {
+	if len(ptx.Inputs) != len(ptx.Transaction.In) {
+		return ErrPartialTransactionInputs
+	}
+
+	for i, ux := range ptx.Inputs {
+		if ux.Hash() != ptx.Transaction.In[i] {
+			return ErrPartialTransactionInputs
+		}
+	}
+
+	if err := ptx.Transaction.VerifyUnsigned(); err != nil {
+		return NewError(fmt.Errorf("invalid transaction: %v", err))
+	}
+
+	if err := ptx.Transaction.VerifyInputUnsigned(ptx.Inputs); err != nil {
+		return NewError(fmt.Errorf("invalid transaction: %v", err))
+	}
+
+	return nil
+}
+
+// IsFullySigned returns true if all the inputs of the transaction are signed
+func (ptx *PartialTransaction) IsFullySigned() bool {
+	return ptx.Transaction.IsFullySigned()
+}
+
+// multisigWitnesses returns the multisig witnesses of the transaction by input index.
+// The witnesses of a verified partial transaction can be decoded, the error is not expected.
+func (ptx *PartialTransaction) multisigWitnesses() map[int]coin.MultisigWitness {
+	ws, err := ptx.Transaction.MultisigWitnesses()
+	if err != nil {
+		logger.WithError(err).Error("PartialTransaction.multisigWitnesses failed")
+		return nil
+	}
+
+	m := make(map[int]coin.MultisigWitness, len(ws))
+	for _, w := range ws {
+		m[w.Input] = w
+	}
+	return m
+}
+
+// inputSigned returns true if the input at index i is signed. An input that spends an output
+// of a multisig address is signed when its witness has the required number of signatures.
+func (ptx *PartialTransaction) inputSigned(i int, ws map[int]coin.MultisigWitness) bool {
+	if w, ok := ws[i]; ok {
+		return w.IsFullySigned()
+	}
+	return !ptx.Transaction.Sigs[i].Null()
+}
+
+// UnsignedAddresses returns the owners of the inputs that are not signed yet, without duplicates.
+// The owner of an input that spends an output of a multisig address is the multisig address.
+func (ptx *PartialTransaction) UnsignedAddresses() []cipher.Address {
+	var addrs []cipher.Address
+	seen := make(map[cipher.Address]struct{})
+	ws := ptx.multisigWitnesses()
+	for i := range ptx.Inputs {
+		if ptx.inputSigned(i, ws) {
+			continue
+		}
+
+		a := ptx.Inputs[i].Body.Address
+		if _, ok := seen[a]; ok {
+			continue
+		}
+		seen[a] = struct{}{}
+		addrs = append(addrs, a)
+	}
+
+	return addrs
+}
+
+// Combine adds the signatures of another partial transaction of the same transaction
+func (ptx *PartialTransaction) Combine(other *PartialTransaction) error {
+	if ptx.Transaction.InnerHash != other.Transaction.InnerHash || len(ptx.Transaction.Sigs) != len(other.Transaction.Sigs) {
+		return ErrPartialTransactionMismatch
+	}
+
+	sigs := make([]cipher.Sig, len(ptx.Transaction.Sigs))
+	copy(sigs, ptx.Transaction.Sigs)
+	for i, s := range other.Transaction.Sigs {
+		switch {
+		case s.Null():
+		case sigs[i].Null():
+			sigs[i] = s
+		case sigs[i] != s:
+			return ErrPartialTransactionSigConflict
+		}
+	}
+
+	ptx.Transaction.Sigs = sigs
+	return nil
+}
+
+// Serialize serializes the partial transaction
+func (ptx *PartialTransaction) Serialize() []byte {
+	return encoder.Serialize(partialTransactionEncoding{
+		Version:     PartialTransactionVersion,
+		Transaction: ptx.Transaction,
+		Inputs:      ptx.Inputs,
+	})
+}
+
+// SerializeHex serializes the partial transaction to a hex string
+func (ptx *PartialTransaction) SerializeHex() string {
+	return hex.EncodeToString(ptx.Serialize())
+}
+
+// PartialTransactionDeserialize deserializes a partial transaction and verifies it
+func PartialTransactionDeserialize(b []byte) (*PartialTransaction, error) {
+	if len(b) == 0 || b[0] != PartialTransactionVersion {
+		return nil, ErrPartialTransactionVersion
+	}
+
+	var e partialTransactionEncoding
+	if err := encoder.DeserializeRaw(b, &e); err != nil {
+		return nil, NewError(fmt.Errorf("invalid partial transaction: %v", err))
+	}
+
+	ptx := &PartialTransaction{
+		Transaction: e.Transaction,
+		Inputs:      e.Inputs,
+	}
+
+	if err := ptx.Verify(); err != nil {
+		return nil, err
+	}
+
+	return ptx, nil
+}
+
+// PartialTransactionDeserializeHex deserializes a partial transaction from a hex string and verifies it
+func PartialTransactionDeserializeHex(s string) (*PartialTransaction, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, NewError(fmt.Errorf("invalid partial transaction: %v", err))
+	}
+
+	return PartialTransactionDeserialize(b)
+}
+
+// SignPartialTransaction signs the unsigned inputs of a partial transaction that are owned by the wallet,
+// and returns the number of inputs signed. ErrNoInputsToSign is returned if the wallet owns none of them.
+// An input that spends an output of a multisig address is signed with the keys of the wallet that are keys
+// of the multisig, until it has the required number of signatures.
+func (w *Wallet) SignPartialTransaction(ptx *PartialTransaction) (int, error) {
+	if w.IsWatchOnly() {
+		return 0, ErrWalletWatchOnly
+	}
+
+	if w.IsEncrypted() {
+		return 0, ErrWalletEncrypted
+	}
+
+	if err := ptx.Verify(); err != nil {
+		return 0, err
+	}
+
+	txn := ptx.Transaction
+	txn.Sigs = make([]cipher.Sig, len(ptx.Transaction.Sigs))
+	copy(txn.Sigs, ptx.Transaction.Sigs)
+
+	ws := ptx.multisigWitnesses()
+	n := 0
+	for i, ux := range ptx.Inputs {
+		if ptx.inputSigned(i, ws) {
+			continue
+		}
+
+		if mw, ok := ws[i]; ok {
+			signed, err := w.signMultisigInput(&txn, i, mw)
+			if err != nil {
+				return 0, err
+			}
+			if signed {
+				n++
+			}
+			continue
+		}
+
+		e, ok := w.GetEntry(ux.Body.Address)
+		if !ok || e.Secret.Null() {
+			continue
+		}
+
+		if err := txn.SignInput(e.Secret, i); err != nil {
+			return 0, err
+		}
+		n++
+	}
+
+	if n == 0 {
+		return 0, ErrNoInputsToSign
+	}
+
+	ptx.Transaction = txn
+	return n, nil
+}
+
+// signMultisigInput signs an input that spends an output of a multisig address with the keys of the wallet
+// that are keys of its multisig and have not signed yet, until it has the required number of signatures.
+// Returns true if the wallet added a signature.
+func (w *Wallet) signMultisigInput(txn *coin.Transaction, i int, mw coin.MultisigWitness) (bool, error) {
+	sigs := mw.SigCount()
+	signed := false
+	for j, pk := range mw.Multisig.PubKeys {
+		if sigs >= int(mw.Multisig.M) {
+			break
+		}
+
+		if !mw.Sigs[j].Null() {
+			continue
+		}
+
+		e, ok := w.entryByPubKey(pk)
+		if !ok || e.Secret.Null() {
+			continue
+		}
+
+		if err := txn.SignMultisigInput(e.Secret, i); err != nil {
+			return false, err
+		}
+		sigs++
+		signed = true
+	}
+
+	return signed, nil
+}
+
+// entryByPubKey returns the entry of a public key of the wallet
+func (w *Wallet) entryByPubKey(pk cipher.PubKey) (Entry, bool) {
+	for _, e := range w.Entries {
+		if e.Public == pk {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
@@ -0,0 +1,202 @@
+package wallet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/testutil"
+)
+
+// makePartialTransaction creates an unsigned transaction spending uxouts
+func makePartialTransaction(t *testing.T, uxouts coin.UxArray) *PartialTransaction {
+	var txn coin.Transaction
+	var coins uint64
+	for _, ux := range uxouts {
+		txn.PushInput(ux.Hash())
+		coins += ux.Body.Coins
+	}
+	txn.PushOutput(testutil.MakeAddress(), coins, 1)
+	txn.Sigs = make([]cipher.Sig, len(txn.In))
+	require.NoError(t, txn.UpdateHeader())
+
+	inputs, err := NewUxBalances(uint64(time.Now().UTC().Unix()), uxouts)
+	require.NoError(t, err)
+
+	ptx, err := NewPartialTransaction(txn, inputs)
+	require.NoError(t, err)
+	require.False(t, ptx.IsFullySigned())
+
+	return ptx
+}
+
+func makePartialTransactionWallet(t *testing.T, seed string) (*Wallet, []cipher.SecKey) {
+	w, err := NewWallet("t.wlt", Options{
+		Seed:  seed,
+		Label: "label",
+	})
+	require.NoError(t, err)
+	_, err = w.GenerateAddresses(1)
+	require.NoError(t, err)
+
+	keys := make([]cipher.SecKey, len(w.Entries))
+	for i, e := range w.Entries {
+		keys[i] = e.Secret
+	}
+
+	return w, keys
+}
+
+func TestPartialTransactionSignMultipleWallets(t *testing.T) {
+	w1, keys1 := makePartialTransactionWallet(t, "seed1")
+	w2, keys2 := makePartialTransactionWallet(t, "seed2")
+	w3, _ := makePartialTransactionWallet(t, "seed3")
+
+	// Three inputs of the two addresses of w1 and an input of w2
+	uxouts := coin.UxArray{
+		makeUxOut(t, keys1[0], 1e6, 10),
+		makeUxOut(t, keys2[0], 2e6, 10),
+		makeUxOut(t, keys1[1], 3e6, 10),
+		makeUxOut(t, keys1[0], 4e6, 10),
+	}
+
+	ptx := makePartialTransaction(t, uxouts)
+	require.Equal(t, []cipher.Address{
+		uxouts[0].Body.Address,
+		uxouts[1].Body.Address,
+		uxouts[2].Body.Address,
+	}, ptx.UnsignedAddresses())
+
+	// A wallet without keys of the inputs can not sign
+	_, err := w3.SignPartialTransaction(ptx)
+	require.Equal(t, ErrNoInputsToSign, err)
+
+	// Sign with each wallet, one after the other
+	sequential := *ptx
+	n, err := w1.SignPartialTransaction(&sequential)
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+	require.False(t, sequential.IsFullySigned())
+	require.Equal(t, []cipher.Address{uxouts[1].Body.Address}, sequential.UnsignedAddresses())
+
+	// The inputs already signed are not signed again
+	_, err = w1.SignPartialTransaction(&sequential)
+	require.Equal(t, ErrNoInputsToSign, err)
+
+	n, err = w2.SignPartialTransaction(&sequential)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.True(t, sequential.IsFullySigned())
+	require.Empty(t, sequential.UnsignedAddresses())
+	require.NoError(t, sequential.Transaction.Verify())
+	require.NoError(t, sequential.Transaction.VerifyInput(uxouts))
+
+	// Sign with each wallet separately, then combine
+	signed1 := *ptx
+	_, err = w1.SignPartialTransaction(&signed1)
+	require.NoError(t, err)
+	signed2 := *ptx
+	_, err = w2.SignPartialTransaction(&signed2)
+	require.NoError(t, err)
+
+	// The original partial transaction is not modified
+	require.Equal(t, make([]cipher.Sig, len(uxouts)), ptx.Transaction.Sigs)
+
+	require.NoError(t, signed1.Combine(&signed2))
+	require.True(t, signed1.IsFullySigned())
+	require.NoError(t, signed1.Transaction.Verify())
+	require.NoError(t, signed1.Transaction.VerifyInput(uxouts))
+}
+
+func TestPartialTransactionSignWalletErrors(t *testing.T) {
+	w, keys := makePartialTransactionWallet(t, "seed")
+	ptx := makePartialTransaction(t, coin.UxArray{makeUxOut(t, keys[0], 1e6, 10)})
+
+	wo, err := NewWatchOnlyWallet("t.wlt", "", []cipher.Address{cipher.MustAddressFromSecKey(keys[0])})
+	require.NoError(t, err)
+	_, err = wo.SignPartialTransaction(ptx)
+	require.Equal(t, ErrWalletWatchOnly, err)
+
+	require.NoError(t, w.Lock([]byte("pwd"), CryptoTypeSha256Xor))
+	_, err = w.SignPartialTransaction(ptx)
+	require.Equal(t, ErrWalletEncrypted, err)
+
+	require.NoError(t, w.GuardView([]byte("pwd"), func(w *Wallet) error {
+		n, err := w.SignPartialTransaction(ptx)
+		require.Equal(t, 1, n)
+		return err
+	}))
+	require.True(t, ptx.IsFullySigned())
+}
+
+func TestPartialTransactionCombineErrors(t *testing.T) {
+	w1, keys1 := makePartialTransactionWallet(t, "seed1")
+	_, keys2 := makePartialTransactionWallet(t, "seed2")
+
+	uxouts := coin.UxArray{makeUxOut(t, keys1[0], 1e6, 10)}
+	ptx := makePartialTransaction(t, uxouts)
+	other := makePartialTransaction(t, coin.UxArray{makeUxOut(t, keys2[0], 1e6, 10)})
+
+	err := ptx.Combine(other)
+	require.Equal(t, ErrPartialTransactionMismatch, err)
+
+	signed := *ptx
+	_, err = w1.SignPartialTransaction(&signed)
+	require.NoError(t, err)
+
+	// A different signature of the same input conflicts
+	conflict := *ptx
+	conflict.Transaction.Sigs = []cipher.Sig{cipher.MustSignHash(testutil.RandSHA256(t), keys1[0])}
+	err = signed.Combine(&conflict)
+	require.Equal(t, ErrPartialTransactionSigConflict, err)
+
+	// Combining with an unsigned copy keeps the signatures
+	require.NoError(t, signed.Combine(ptx))
+	require.True(t, signed.IsFullySigned())
+}
+
+func TestPartialTransactionSerialize(t *testing.T) {
+	w, keys := makePartialTransactionWallet(t, "seed")
+	uxouts := coin.UxArray{
+		makeUxOut(t, keys[0], 1e6, 10),
+		makeUxOut(t, keys[1], 2e6, 10),
+	}
+	ptx := makePartialTransaction(t, uxouts)
+
+	ptx2, err := PartialTransactionDeserializeHex(ptx.SerializeHex())
+	require.NoError(t, err)
+	require.Equal(t, ptx, ptx2)
+
+	_, err = w.SignPartialTransaction(ptx2)
+	require.NoError(t, err)
+
+	ptx3, err := PartialTransactionDeserialize(ptx2.Serialize())
+	require.NoError(t, err)
+	require.Equal(t, ptx2, ptx3)
+	require.True(t, ptx3.IsFullySigned())
+
+	// Unknown version
+	b := ptx.Serialize()
+	b[0] = PartialTransactionVersion + 1
+	_, err = PartialTransactionDeserialize(b)
+	require.Equal(t, ErrPartialTransactionVersion, err)
+
+	_, err = PartialTransactionDeserialize(nil)
+	require.Equal(t, ErrPartialTransactionVersion, err)
+
+	_, err = PartialTransactionDeserializeHex("zz")
+	require.Error(t, err)
+
+	// The inputs must match the transaction inputs
+	mismatch := *ptx
+	mismatch.Inputs = []coin.UxOut{uxouts[1], uxouts[0]}
+	_, err = PartialTransactionDeserialize(mismatch.Serialize())
+	require.Equal(t, ErrPartialTransactionInputs, err)
+
+	mismatch.Inputs = uxouts[:1]
+	_, err = PartialTransactionDeserialize(mismatch.Serialize())
+	require.Equal(t, ErrPartialTransactionInputs, err)
+}
@@ -0,0 +1,186 @@
+package wallet
+
+import (
+	"errors"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/params"
+	"github.com/skycoin/skycoin/src/util/fee"
+)
+
+var (
+	// ErrPayoutBatchSigner is returned when creating a payout batch with a Signer
+	ErrPayoutBatchSigner = NewError(errors.New("payout batches can not be signed by a signer"))
+	// ErrInvalidMaxOutputs is returned if the maximum number of payouts per transaction of a payout batch is negative
+	ErrInvalidMaxOutputs = NewError(errors.New("max outputs must not be negative"))
+	// ErrPayoutTransactionTooLarge is returned for a payout that can not be paid by a transaction within the
+	// maximum transaction size, because it needs too many inputs
+	ErrPayoutTransactionTooLarge = NewError(errors.New("transaction is larger than the maximum transaction size"))
+)
+
+// PayoutBatch are the transactions that pay a batch of payouts
+type PayoutBatch struct {
+	Transactions []PayoutBatchTransaction
+	// Failures are the payouts that could not be paid, with the reason
+	Failures []PayoutBatchFailure
+}
+
+// PayoutBatchTransaction is a transaction of a payout batch
+type PayoutBatchTransaction struct {
+	Transaction *coin.Transaction
+	Inputs      []UxBalance
+	// Payouts are the indexes in CreateTransactionParams.To of the payouts paid by the transaction
+	Payouts []int
+}
+
+// PayoutBatchFailure are payouts of a payout batch that could not be paid
+type PayoutBatchFailure struct {
+	// Payouts are the indexes in CreateTransactionParams.To of the payouts that were not paid
+	Payouts []int
+	Err     error
+}
+
+// payoutRange is a range [start, end) of the payouts of a payout batch
+type payoutRange struct {
+	start int
+	end   int
+}
+
+func (r payoutRange) indexes() []int {
+	idxs := make([]int, r.end-r.start)
+	for i := range idxs {
+		idxs[i] = r.start + i
+	}
+	return idxs
+}
+
+// CreatePayoutBatch creates the transactions that pay the outputs of p.To, which can be more than fit in a transaction.
+// The payouts are split in order into transactions of at most maxOutputs payouts, or unlimited if maxOutputs is 0.
+// A transaction larger than the maximum transaction size is split in half again, until it fits.
+// Each transaction spends different unspent outputs of auxs, so that all the transactions can be injected together,
+// and its change is not spent by the following transactions since it is not confirmed yet.
+// Payouts that can not be funded, for lack of coins or coin hours in the remaining unspent outputs,
+// are reported in PayoutBatch.Failures and the following payouts are still paid.
+func (w *Wallet) CreatePayoutBatch(p CreateTransactionParams, maxOutputs int, auxs coin.AddressUxOuts, headTime uint64) (*PayoutBatch, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	if p.Signer != nil {
+		return nil, ErrPayoutBatchSigner
+	}
+
+	if maxOutputs < 0 {
+		return nil, ErrInvalidMaxOutputs
+	}
+
+	if maxOutputs == 0 {
+		maxOutputs = len(p.To)
+	}
+
+	// Copy auxs, the unspent outputs spent by each transaction are removed from it
+	remaining := make(coin.AddressUxOuts, len(auxs))
+	for a, uxa := range auxs {
+		remaining[a] = append(coin.UxArray{}, uxa...)
+	}
+
+	var ranges []payoutRange
+	for i := 0; i < len(p.To); i += maxOutputs {
+		end := i + maxOutputs
+		if end > len(p.To) {
+			end = len(p.To)
+		}
+		ranges = append(ranges, payoutRange{
+			start: i,
+			end:   end,
+		})
+	}
+
+	batch := &PayoutBatch{}
+	for len(ranges) > 0 {
+		r := ranges[0]
+		ranges = ranges[1:]
+
+		txn, inputs, err := w.createPayoutTransaction(p, r, remaining, headTime)
+		switch err {
+		case nil:
+		case ErrPayoutTransactionTooLarge:
+			if r.end-r.start > 1 {
+				mid := r.start + (r.end-r.start)/2
+				ranges = append([]payoutRange{
+					{start: r.start, end: mid},
+					{start: mid, end: r.end},
+				}, ranges...)
+				continue
+			}
+			fallthrough
+		case ErrInsufficientBalance,
+			ErrInsufficientHours,
+			ErrNoUnspents,
+			fee.ErrTxnNoFee,
+			fee.ErrTxnInsufficientCoinHours:
+			batch.Failures = append(batch.Failures, PayoutBatchFailure{
+				Payouts: r.indexes(),
+				Err:     err,
+			})
+			continue
+		default:
+			return nil, err
+		}
+
+		batch.Transactions = append(batch.Transactions, PayoutBatchTransaction{
+			Transaction: txn,
+			Inputs:      inputs,
+			Payouts:     r.indexes(),
+		})
+
+		removeSpentUxOuts(remaining, inputs)
+	}
+
+	return batch, nil
+}
+
+// createPayoutTransaction creates the transaction that pays the payouts of p.To in range r
+func (w *Wallet) createPayoutTransaction(p CreateTransactionParams, r payoutRange, auxs coin.AddressUxOuts, headTime uint64) (*coin.Transaction, []UxBalance, error) {
+	p.To = p.To[r.start:r.end]
+
+	txn, inputs, err := w.CreateAndSignTransactionAdvanced(p, auxs, headTime)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	size, err := txn.Size()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if size > params.UserMaxTransactionSize {
+		return nil, nil, ErrPayoutTransactionTooLarge
+	}
+
+	return txn, inputs, nil
+}
+
+// removeSpentUxOuts removes the unspent outputs spent by inputs from auxs
+func removeSpentUxOuts(auxs coin.AddressUxOuts, inputs []UxBalance) {
+	spent := make(map[cipher.SHA256]struct{}, len(inputs))
+	for _, in := range inputs {
+		spent[in.Hash] = struct{}{}
+	}
+
+	for a, uxa := range auxs {
+		var unspent coin.UxArray
+		for _, ux := range uxa {
+			if _, ok := spent[ux.Hash()]; !ok {
+				unspent = append(unspent, ux)
+			}
+		}
+
+		if len(unspent) == 0 {
+			delete(auxs, a)
+		} else {
+			auxs[a] = unspent
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/params"
+	"github.com/skycoin/skycoin/src/testutil"
+)
+
+func TestWalletCreatePayoutBatch(t *testing.T) {
+	headTime := uint64(1000)
+	w := makeWallet(t, Options{
+		Seed: "seed",
+	}, 1)
+	addr := w.Entries[0].SkycoinAddress()
+	secret := w.Entries[0].Secret
+
+	shareFactor := decimal.New(5, -1)
+	makeParams := func(n int, coins uint64) CreateTransactionParams {
+		to := make([]coin.TransactionOutput, n)
+		for i := range to {
+			to[i] = coin.TransactionOutput{
+				Address: cipher.Address{
+					Key: cipher.HashRipemd160(testutil.RandBytes(t, 32)),
+				},
+				Coins: coins,
+			}
+		}
+
+		return CreateTransactionParams{
+			HoursSelection: HoursSelection{
+				Type:        HoursSelectionTypeAuto,
+				Mode:        HoursSelectionModeShare,
+				ShareFactor: &shareFactor,
+			},
+			Wallet: CreateTransactionWalletParams{
+				ID: w.Filename(),
+			},
+			To: to,
+		}
+	}
+
+	makeAuxs := func(n int, coins uint64) coin.AddressUxOuts {
+		uxa := make(coin.UxArray, n)
+		for i := range uxa {
+			uxa[i] = makeUxOut(t, secret, coins, 1000)
+		}
+		return coin.AddressUxOuts{
+			addr: uxa,
+		}
+	}
+
+	checkBatch := func(t *testing.T, p CreateTransactionParams, batch *PayoutBatch) {
+		spent := make(map[cipher.SHA256]struct{})
+		for _, bt := range batch.Transactions {
+			require.Len(t, bt.Transaction.In, len(bt.Inputs))
+			for _, h := range bt.Transaction.In {
+				_, ok := spent[h]
+				require.False(t, ok, "unspent output spent twice")
+				spent[h] = struct{}{}
+			}
+
+			require.NoError(t, bt.Transaction.Verify())
+			size, err := bt.Transaction.Size()
+			require.NoError(t, err)
+			require.True(t, size <= params.UserMaxTransactionSize)
+
+			// The payouts are the first outputs, followed by the change
+			require.True(t, len(bt.Transaction.Out) >= len(bt.Payouts))
+			for i, j := range bt.Payouts {
+				require.Equal(t, p.To[j].Address, bt.Transaction.Out[i].Address)
+				require.Equal(t, p.To[j].Coins, bt.Transaction.Out[i].Coins)
+			}
+		}
+	}
+
+	t.Run("max outputs", func(t *testing.T) {
+		p := makeParams(5, 1e6)
+		batch, err := w.CreatePayoutBatch(p, 2, makeAuxs(5, 10e6), headTime)
+		require.NoError(t, err)
+		require.Empty(t, batch.Failures)
+		require.Len(t, batch.Transactions, 3)
+		require.Equal(t, []int{0, 1}, batch.Transactions[0].Payouts)
+		require.Equal(t, []int{2, 3}, batch.Transactions[1].Payouts)
+		require.Equal(t, []int{4}, batch.Transactions[2].Payouts)
+		checkBatch(t, p, batch)
+	})
+
+	t.Run("split transactions larger than the max transaction size", func(t *testing.T) {
+		p := makeParams(1000, 1e6)
+		batch, err := w.CreatePayoutBatch(p, 0, makeAuxs(2, 1000e6), headTime)
+		require.NoError(t, err)
+		require.Empty(t, batch.Failures)
+		require.Len(t, batch.Transactions, 2)
+		require.Equal(t, 500, len(batch.Transactions[0].Payouts))
+		require.Equal(t, 0, batch.Transactions[0].Payouts[0])
+		require.Equal(t, 500, batch.Transactions[1].Payouts[0])
+		checkBatch(t, p, batch)
+	})
+
+	t.Run("unfunded payouts", func(t *testing.T) {
+		// The change of the first transaction is not confirmed, so it can not fund the other payouts
+		p := makeParams(3, 1e6)
+		batch, err := w.CreatePayoutBatch(p, 1, makeAuxs(2, 10e6), headTime)
+		require.NoError(t, err)
+		require.Len(t, batch.Transactions, 2)
+		require.Equal(t, []int{0}, batch.Transactions[0].Payouts)
+		require.Equal(t, []int{1}, batch.Transactions[1].Payouts)
+		require.Len(t, batch.Failures, 1)
+		require.Equal(t, []int{2}, batch.Failures[0].Payouts)
+		require.Error(t, batch.Failures[0].Err)
+		checkBatch(t, p, batch)
+	})
+
+	t.Run("invalid params", func(t *testing.T) {
+		p := makeParams(3, 1e6)
+		_, err := w.CreatePayoutBatch(p, -1, makeAuxs(1, 10e6), headTime)
+		require.Equal(t, ErrInvalidMaxOutputs, err)
+
+		p.Signer = w
+		_, err = w.CreatePayoutBatch(p, 0, makeAuxs(1, 10e6), headTime)
+		require.Equal(t, ErrPayoutBatchSigner, err)
+
+		p = makeParams(0, 1e6)
+		_, err = w.CreatePayoutBatch(p, 0, makeAuxs(1, 10e6), headTime)
+		require.Equal(t, ErrMissingTo, err)
+	})
+}
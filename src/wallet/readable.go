@@ -5,7 +5,6 @@ import (
 	"strconv"
 
 	"github.com/skycoin/skycoin/src/cipher"
-	"github.com/skycoin/skycoin/src/util/file"
 )
 
 // ReadableEntry wallet entry with json tags
@@ -13,11 +12,15 @@ type ReadableEntry struct {
 	Address string `json:"address"`
 	Public  string `json:"public_key"`
 	Secret  string `json:"secret_key"`
+	// Account is the index of the account of the address, omitted for the default account
+	Account uint32 `json:"account,omitempty"`
 }
 
 // NewReadableEntry creates readable wallet entry
 func NewReadableEntry(coinType CoinType, w Entry) ReadableEntry {
-	re := ReadableEntry{}
+	re := ReadableEntry{
+		Account: w.Account,
+	}
 	if !w.Address.Null() {
 		re.Address = w.Address.String()
 	}
@@ -83,9 +86,13 @@ func newEntryFromReadable(coinType CoinType, w *ReadableEntry) (*Entry, error) {
 		return nil, err
 	}
 
-	p, err := cipher.PubKeyFromHex(w.Public)
-	if err != nil {
-		return nil, err
+	// The public key is unknown for the addresses of watch-only wallets
+	var p cipher.PubKey
+	if w.Public != "" {
+		p, err = cipher.PubKeyFromHex(w.Public)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// Decodes the secret hex string if any
@@ -108,13 +115,29 @@ func newEntryFromReadable(coinType CoinType, w *ReadableEntry) (*Entry, error) {
 		Address: a,
 		Public:  p,
 		Secret:  secret,
+		Account: w.Account,
 	}, nil
 }
 
 // ReadableWallet used for [de]serialization of a Wallet
 type ReadableWallet struct {
-	Meta    map[string]string `json:"meta"`
-	Entries ReadableEntries   `json:"entries"`
+	Meta        map[string]string    `json:"meta"`
+	Entries     ReadableEntries      `json:"entries"`
+	Annotations *ReadableAnnotations `json:"annotations,omitempty"`
+	// SpendingPolicy is the spending policy of the wallet and its recorded spends
+	SpendingPolicy *ReadableSpendingPolicy `json:"spending_policy,omitempty"`
+	// Cosigners are the other parties whose signatures are required by the transactions of the wallet
+	Cosigners []ReadableCosigner `json:"cosigners,omitempty"`
+	// AddressPool are the addresses of the wallet that have been used or handed out
+	AddressPool *ReadableAddressPool `json:"address_pool,omitempty"`
+	// Accounts are the named accounts of the wallet, the default account is not included
+	Accounts []ReadableAccount `json:"accounts,omitempty"`
+	// Multisigs are the multisigs of the multisig addresses of a watch-only wallet
+	Multisigs []ReadableMultisig `json:"multisigs,omitempty"`
+	// WriteCounter is incremented each time the wallet file is written, since version 0.3
+	WriteCounter uint64 `json:"write_counter,omitempty"`
+	// Checksum is the hex SHA256 of the wallet file with the checksum blanked out, since version 0.3
+	Checksum string `json:"checksum,omitempty"`
 }
 
 // NewReadableWallet creates readable wallet
@@ -130,8 +153,14 @@ func NewReadableWallet(w *Wallet) *ReadableWallet {
 	}
 
 	return &ReadableWallet{
-		Meta:    meta,
-		Entries: readable,
+		Meta:           meta,
+		Entries:        readable,
+		Annotations:    newReadableAnnotations(w.Annotations),
+		SpendingPolicy: newReadableSpendingPolicy(w.SpendingPolicy, w.Spends),
+		Cosigners:      newReadableCosigners(w.Cosigners),
+		AddressPool:    newReadableAddressPool(w),
+		Accounts:       newReadableAccounts(w.Accounts),
+		Multisigs:      newReadableMultisigs(w.Multisigs),
 	}
 }
 
@@ -161,17 +190,73 @@ func (rw *ReadableWallet) ToWallet() (*Wallet, error) {
 
 	w.Entries = ets
 
+	annotations, err := rw.Annotations.toAnnotations()
+	if err != nil {
+		return nil, err
+	}
+
+	w.Annotations = annotations
+
+	policy, spends, err := rw.SpendingPolicy.toSpendingPolicy()
+	if err != nil {
+		return nil, err
+	}
+
+	w.SpendingPolicy = policy
+	w.Spends = spends
+
+	cosigners, err := toCosigners(rw.Cosigners)
+	if err != nil {
+		return nil, err
+	}
+
+	w.Cosigners = cosigners
+
+	pool, err := rw.AddressPool.toAddressPool()
+	if err != nil {
+		return nil, err
+	}
+
+	w.AddressPool = pool
+
+	if err := w.validateAddressPool(); err != nil {
+		return nil, fmt.Errorf("invalid wallet %s: %v", w.Filename(), err)
+	}
+
+	w.Accounts = toAccounts(rw.Accounts)
+
+	if err := w.validateAccounts(); err != nil {
+		return nil, fmt.Errorf("invalid wallet %s: %v", w.Filename(), err)
+	}
+
+	multisigs, err := toMultisigs(rw.Multisigs)
+	if err != nil {
+		return nil, err
+	}
+
+	w.Multisigs = multisigs
+
+	if err := w.validateMultisigs(); err != nil {
+		return nil, fmt.Errorf("invalid wallet %s: %v", w.Filename(), err)
+	}
+
 	return w, nil
 }
 
-// Save saves to filename
+// Save saves to filename, see saveWalletFile
 func (rw *ReadableWallet) Save(filename string) error {
-	return file.SaveJSON(filename, rw, 0600)
+	return saveWalletFile(filename, rw)
 }
 
-// Load loads from filename
+// Load loads from filename, see loadWalletFile
 func (rw *ReadableWallet) Load(filename string) error {
-	return file.LoadJSON(filename, rw)
+	w, err := loadWalletFile(filename)
+	if err != nil {
+		return err
+	}
+
+	*rw = *w
+	return nil
 }
 
 func (rw *ReadableWallet) timestamp() int64 {
@@ -189,6 +274,7 @@ func (rw *ReadableWallet) filename() string {
 func (rw *ReadableWallet) Erase() {
 	delete(rw.Meta, metaSeed)
 	delete(rw.Meta, metaLastSeed)
+	delete(rw.Meta, metaSeedPassphrase)
 	delete(rw.Meta, metaSecrets)
 	for i := range rw.Entries {
 		rw.Entries[i].Secret = ""
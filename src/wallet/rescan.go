@@ -0,0 +1,68 @@
+package wallet
+
+import (
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+// AddressActivityGetter reports whether addresses have ever received or spent coins
+type AddressActivityGetter interface {
+	GetAddressesActivity(addrs []cipher.Address) ([]bool, error)
+}
+
+// ExtendActiveAddresses generates the addresses that follow the last address of the wallet,
+// gapLimit addresses at a time, and adds them up to the last one that has ever been used.
+// The scan stops at the first gapLimit consecutive addresses that were never used.
+// Unlike ScanAddresses, an address that spent all its coins is used. Returns the added addresses.
+func (w *Wallet) ExtendActiveAddresses(gapLimit uint64, ag AddressActivityGetter) ([]cipher.Address, error) {
+	if w.IsEncrypted() {
+		return nil, ErrWalletEncrypted
+	}
+
+	if w.IsWatchOnly() {
+		return nil, ErrWalletWatchOnly
+	}
+
+	if w.Type() == WalletTypeCollection {
+		return nil, ErrWalletNotDeterministic
+	}
+
+	if gapLimit == 0 {
+		return nil, nil
+	}
+
+	// Scan the addresses with a clone, the addresses are generated in the same sequence
+	// on the wallet once the number to add is known
+	w2 := w.clone()
+
+	var nAddAddrs uint64
+	var nScanned uint64
+	for {
+		// Scan enough addresses to have gapLimit addresses after the last used one
+		addrs, err := w2.GenerateSkycoinAddresses(gapLimit - (nScanned - nAddAddrs))
+		if err != nil {
+			return nil, err
+		}
+
+		active, err := ag.GetAddressesActivity(addrs)
+		if err != nil {
+			return nil, err
+		}
+
+		var keepNum uint64
+		for i := len(active) - 1; i >= 0; i-- {
+			if active[i] {
+				keepNum = uint64(i + 1)
+				break
+			}
+		}
+
+		if keepNum == 0 {
+			break
+		}
+
+		nAddAddrs = nScanned + keepNum
+		nScanned += uint64(len(addrs))
+	}
+
+	return w.GenerateSkycoinAddresses(nAddAddrs)
+}
@@ -0,0 +1,201 @@
+package wallet
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+type mockActivityGetter map[cipher.Address]bool
+
+func (ma mockActivityGetter) GetAddressesActivity(addrs []cipher.Address) ([]bool, error) {
+	active := make([]bool, len(addrs))
+	for i, addr := range addrs {
+		active[i] = ma[addr]
+	}
+	return active, nil
+}
+
+func TestWalletExtendActiveAddresses(t *testing.T) {
+	// The addresses of the seed, in the order the wallet generates them
+	seedWlt := makeWallet(t, Options{
+		Seed: "seed",
+	}, 30)
+	seedAddrs := make([]cipher.Address, len(seedWlt.Entries))
+	for i, e := range seedWlt.Entries {
+		seedAddrs[i] = e.SkycoinAddress()
+	}
+
+	cases := []struct {
+		name     string
+		nAddrs   uint64
+		gapLimit uint64
+		active   []int
+		added    int
+	}{
+		{
+			name:     "no activity",
+			nAddrs:   2,
+			gapLimit: 5,
+			active:   []int{0},
+		},
+		{
+			name:     "gap limit 0",
+			nAddrs:   2,
+			gapLimit: 0,
+			active:   []int{3},
+		},
+		{
+			name:     "active within the gap limit",
+			nAddrs:   2,
+			gapLimit: 5,
+			active:   []int{3, 6},
+			added:    5,
+		},
+		{
+			name:     "active in the next scan",
+			nAddrs:   2,
+			gapLimit: 5,
+			active:   []int{6, 11, 15},
+			added:    14,
+		},
+		{
+			name:     "active beyond the gap limit",
+			nAddrs:   2,
+			gapLimit: 5,
+			active:   []int{6, 12},
+			added:    5,
+		},
+		{
+			name:     "only the first address",
+			nAddrs:   1,
+			gapLimit: 3,
+			active:   []int{1, 2, 3},
+			added:    3,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := makeWallet(t, Options{
+				Seed: "seed",
+			}, tc.nAddrs)
+
+			ag := make(mockActivityGetter)
+			for _, i := range tc.active {
+				ag[seedAddrs[i]] = true
+			}
+
+			addrs, err := w.ExtendActiveAddresses(tc.gapLimit, ag)
+			require.NoError(t, err)
+			require.Len(t, addrs, tc.added)
+			require.Len(t, w.Entries, int(tc.nAddrs)+tc.added)
+			for i, e := range w.Entries {
+				require.Equal(t, seedAddrs[i], e.SkycoinAddress())
+			}
+			for i, a := range addrs {
+				require.Equal(t, seedAddrs[int(tc.nAddrs)+i], a)
+			}
+
+			// The next address follows the added ones
+			next, err := w.GenerateSkycoinAddresses(1)
+			require.NoError(t, err)
+			require.Equal(t, seedAddrs[len(w.Entries)-1], next[0])
+		})
+	}
+
+	t.Run("encrypted", func(t *testing.T) {
+		w := makeWallet(t, Options{
+			Seed:       "seed",
+			Encrypt:    true,
+			Password:   []byte("pwd"),
+			CryptoType: CryptoTypeSha256Xor,
+		}, 1)
+		_, err := w.ExtendActiveAddresses(5, mockActivityGetter{})
+		require.Equal(t, ErrWalletEncrypted, err)
+	})
+
+	t.Run("watch-only", func(t *testing.T) {
+		w, err := NewWatchOnlyWallet("t.wlt", "", []cipher.Address{seedAddrs[0]})
+		require.NoError(t, err)
+		_, err = w.ExtendActiveAddresses(5, mockActivityGetter{})
+		require.Equal(t, ErrWalletWatchOnly, err)
+	})
+}
+
+func TestServiceExtendActiveAddresses(t *testing.T) {
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+	s, err := NewService(Config{
+		WalletDir:       dir,
+		CryptoType:      CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	seedWlt := makeWallet(t, Options{
+		Seed: "seed",
+	}, 5)
+	ag := mockActivityGetter{
+		seedWlt.Entries[3].SkycoinAddress(): true,
+	}
+
+	_, err = s.CreateWallet("t.wlt", Options{
+		Seed:     "seed",
+		Encrypt:  true,
+		Password: []byte("pwd"),
+	}, nil)
+	require.NoError(t, err)
+
+	_, err = s.ExtendActiveAddresses("t.wlt", nil, 5, ag)
+	require.Equal(t, ErrMissingPassword, err)
+	_, err = s.ExtendActiveAddresses("t.wlt", []byte("wrong"), 5, ag)
+	require.Equal(t, ErrInvalidPassword, err)
+	_, err = s.ExtendActiveAddresses("foo.wlt", []byte("pwd"), 5, ag)
+	require.Equal(t, ErrWalletNotExist, err)
+
+	addrs, err := s.ExtendActiveAddresses("t.wlt", []byte("pwd"), 5, ag)
+	require.NoError(t, err)
+	require.Equal(t, []cipher.Address{
+		seedWlt.Entries[1].SkycoinAddress(),
+		seedWlt.Entries[2].SkycoinAddress(),
+		seedWlt.Entries[3].SkycoinAddress(),
+	}, addrs)
+
+	// The wallet is saved encrypted with the new addresses
+	s, err = NewService(Config{
+		WalletDir:       dir,
+		CryptoType:      CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+	w, err := s.GetWallet("t.wlt")
+	require.NoError(t, err)
+	require.True(t, w.IsEncrypted())
+	checkNoSensitiveData(t, w)
+	require.Len(t, w.Entries, 4)
+
+	// Nothing more is found
+	addrs, err = s.ExtendActiveAddresses("t.wlt", []byte("pwd"), 5, ag)
+	require.NoError(t, err)
+	require.Empty(t, addrs)
+
+	// Watch-only wallets are not extended
+	_, err = s.CreateWatchOnlyWallet("w.wlt", "", []cipher.Address{seedWlt.Entries[0].SkycoinAddress()})
+	require.NoError(t, err)
+	addrs, err = s.ExtendActiveAddresses("w.wlt", nil, 5, ag)
+	require.NoError(t, err)
+	require.Empty(t, addrs)
+
+	// Disabled wallet API
+	s, err = NewService(Config{
+		WalletDir:  dir,
+		CryptoType: CryptoTypeSha256Xor,
+	})
+	require.NoError(t, err)
+	_, err = s.ExtendActiveAddresses("t.wlt", []byte("pwd"), 5, ag)
+	require.Equal(t, ErrWalletAPIDisabled, err)
+}
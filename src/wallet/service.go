@@ -2,10 +2,12 @@ package wallet
 
 import (
 	"fmt"
-	"os"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/encrypt"
 	"github.com/skycoin/skycoin/src/coin"
 )
 
@@ -14,21 +16,41 @@ type BalanceGetter interface {
 	GetBalanceOfAddrs(addrs []cipher.Address) ([]BalancePair, error)
 }
 
-// Service wallet service struct
+// Service wallet service struct.
+//
+// The wallets of the service are copy-on-write snapshots: a wallet is never modified once it is stored,
+// updates are made to a clone that replaces the snapshot after it is saved.
+// The embedded RWMutex guards the registry of wallets (wallets, firstAddrIDMap, walletStorages and locks)
+// and is only held to look up or replace wallets, never while scanning addresses, deriving keys or saving,
+// so that a slow operation on a wallet doesn't block the other wallets.
+// Each wallet has its own lock: updates hold it for writing, so that the updates of a wallet are serialized,
+// and the operations that view a wallet hold it for reading, so that they wait for the update in progress.
+// Per-wallet locks are always acquired before the registry lock.
 type Service struct {
 	sync.RWMutex
 	wallets         Wallets
-	firstAddrIDMap  map[string]string // Key: first address in wallet; Value: wallet id
-	walletDirectory string
+	locks           map[string]*walletLock   // Key: wallet id; Value: the lock of the wallet
+	firstAddrIDMap  map[string]string        // Key: first address in wallet; Value: wallet id
+	storages        []WalletStorage          // new wallets are created in the first storage
+	walletStorages  map[string]WalletStorage // Key: wallet id; Value: the storage of the wallet
 	cryptoType      CryptoType
+	argon2id        encrypt.Argon2idChacha20poly1305
 	enableWalletAPI bool
 	enableSeedAPI   bool
 }
 
 // Config wallet service config
 type Config struct {
-	WalletDir       string
-	CryptoType      CryptoType
+	// WalletDir is the directory where new wallets are created, unless Storage is set
+	WalletDir string
+	// WalletDirs are additional directories to load wallets from
+	WalletDirs []string
+	// Storage replaces the storage of WalletDir, to keep wallets out of the filesystem
+	Storage    WalletStorage
+	CryptoType CryptoType
+	// Argon2id are the argon2id parameters of the argon2id-chacha20poly1305 crypto type.
+	// The zero value uses encrypt.DefaultArgon2idChacha20poly1305.
+	Argon2id        encrypt.Argon2idChacha20poly1305
 	EnableWalletAPI bool
 	EnableSeedAPI   bool
 }
@@ -36,34 +58,62 @@ type Config struct {
 // NewService new wallet service
 func NewService(c Config) (*Service, error) {
 	serv := &Service{
+		locks:           make(map[string]*walletLock),
 		firstAddrIDMap:  make(map[string]string),
 		cryptoType:      c.CryptoType,
+		argon2id:        c.Argon2id,
 		enableWalletAPI: c.EnableWalletAPI,
 		enableSeedAPI:   c.EnableSeedAPI,
 	}
 
+	if serv.argon2id != (encrypt.Argon2idChacha20poly1305{}) {
+		if err := serv.argon2id.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
 	if !serv.enableWalletAPI {
 		return serv, nil
 	}
 
-	if err := os.MkdirAll(c.WalletDir, os.FileMode(0700)); err != nil {
-		return nil, fmt.Errorf("failed to create wallet directory %s: %v", c.WalletDir, err)
+	storage := c.Storage
+	if storage == nil {
+		fs, err := NewFileStorage(c.WalletDir)
+		if err != nil {
+			return nil, err
+		}
+		storage = fs
 	}
+	serv.storages = append(serv.storages, storage)
 
-	serv.walletDirectory = c.WalletDir
-
-	// Removes .wlt.bak files before loading wallets
-	if err := removeBackupFiles(serv.walletDirectory); err != nil {
-		return nil, fmt.Errorf("remove .wlt.bak files in %v failed: %v", serv.walletDirectory, err)
+	for _, dir := range c.WalletDirs {
+		fs, err := NewFileStorage(dir)
+		if err != nil {
+			return nil, err
+		}
+		serv.storages = append(serv.storages, fs)
 	}
 
 	// Loads wallets
-	w, err := LoadWallets(serv.walletDirectory)
-	if err != nil {
-		return nil, fmt.Errorf("failed to load all wallets: %v", err)
+	wallets := Wallets{}
+	serv.walletStorages = make(map[string]WalletStorage)
+	for _, s := range serv.storages {
+		w, err := loadStorageWallets(s)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load all wallets of %s: %v", s.Name(), err)
+		}
+
+		for id, wlt := range w {
+			if prev, ok := serv.walletStorages[id]; ok {
+				return nil, fmt.Errorf("wallet %s is in both %s and %s", id, prev.Name(), s.Name())
+			}
+
+			wallets[id] = wlt
+			serv.walletStorages[id] = s
+		}
 	}
 
-	serv.wallets = serv.removeDup(w)
+	serv.wallets = serv.removeDup(wallets)
 
 	return serv, nil
 }
@@ -71,8 +121,6 @@ func NewService(c Config) (*Service, error) {
 // CreateWallet creates a wallet with the given wallet file name and options.
 // A address will be automatically generated by default.
 func (serv *Service) CreateWallet(wltName string, options Options, bg BalanceGetter) (*Wallet, error) {
-	serv.Lock()
-	defer serv.Unlock()
 	if !serv.enableWalletAPI {
 		return nil, ErrWalletAPIDisabled
 	}
@@ -83,11 +131,13 @@ func (serv *Service) CreateWallet(wltName string, options Options, bg BalanceGet
 	return serv.loadWallet(wltName, options, bg)
 }
 
-// loadWallet loads wallet from seed and scan the first N addresses
+// loadWallet loads wallet from seed and scan the first N addresses.
+// The addresses are scanned without locking the service.
 func (serv *Service) loadWallet(wltName string, options Options, bg BalanceGetter) (*Wallet, error) {
 	// service decides what crypto type the wallet should use.
 	if options.Encrypt {
 		options.CryptoType = serv.cryptoType
+		options.Argon2id = serv.argon2id
 	}
 
 	w, err := NewWalletScanAhead(wltName, options, bg)
@@ -95,27 +145,90 @@ func (serv *Service) loadWallet(wltName string, options Options, bg BalanceGette
 		return nil, err
 	}
 
-	// Check for duplicate wallets by initial seed
-	if _, ok := serv.firstAddrIDMap[w.Entries[0].Address.String()]; ok {
-		return nil, ErrSeedUsed
+	if err := serv.addWallet(w); err != nil {
+		return nil, err
+	}
+
+	return w.clone(), nil
+}
+
+// CreateWatchOnlyWallet creates a watch-only wallet of the addresses, with the given wallet file name and label
+func (serv *Service) CreateWatchOnlyWallet(wltName, label string, addrs []cipher.Address) (*Wallet, error) {
+	if !serv.enableWalletAPI {
+		return nil, ErrWalletAPIDisabled
+	}
+	if wltName == "" {
+		wltName = serv.generateUniqueWalletFilename()
 	}
 
-	if err := serv.wallets.add(w); err != nil {
+	w, err := NewWatchOnlyWallet(wltName, label, addrs)
+	if err != nil {
 		return nil, err
 	}
 
-	if err := w.Save(serv.walletDirectory); err != nil {
-		// If save fails, remove the added wallet
-		serv.wallets.remove(w.Filename())
+	if err := serv.addWallet(w); err != nil {
+		return nil, err
+	}
+
+	return w.clone(), nil
+}
+
+// AddWatchOnlyAddresses adds addresses to a watch-only wallet
+func (serv *Service) AddWatchOnlyAddresses(wltID string, addrs []cipher.Address) (*Wallet, error) {
+	unlock := serv.lockWallet(wltID)
+	defer unlock()
+	if !serv.enableWalletAPI {
+		return nil, ErrWalletAPIDisabled
+	}
+
+	w, err := serv.getWallet(wltID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := w.AddWatchOnlyAddresses(addrs); err != nil {
+		return nil, err
+	}
+
+	if err := serv.saveWallet(w); err != nil {
+		return nil, err
+	}
+
+	serv.setWallet(w)
+
+	return w.clone(), nil
+}
+
+// AddMultisig adds the address of an m-of-n multisig to a watch-only wallet, see Wallet.AddMultisig
+func (serv *Service) AddMultisig(wltID string, ms cipher.Multisig) (*Wallet, error) {
+	unlock := serv.lockWallet(wltID)
+	defer unlock()
+	if !serv.enableWalletAPI {
+		return nil, ErrWalletAPIDisabled
+	}
+
+	w, err := serv.getWallet(wltID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := w.AddMultisig(ms); err != nil {
+		return nil, err
+	}
+
+	if err := serv.saveWallet(w); err != nil {
 		return nil, err
 	}
 
-	serv.firstAddrIDMap[w.Entries[0].Address.String()] = w.Filename()
+	serv.setWallet(w)
 
 	return w.clone(), nil
 }
 
 func (serv *Service) generateUniqueWalletFilename() string {
+	serv.RLock()
+	defer serv.RUnlock()
+
 	wltName := NewWalletFilename()
 	for {
 		if _, ok := serv.wallets.get(wltName); !ok {
@@ -129,8 +242,8 @@ func (serv *Service) generateUniqueWalletFilename() string {
 
 // EncryptWallet encrypts wallet with password
 func (serv *Service) EncryptWallet(wltID string, password []byte) (*Wallet, error) {
-	serv.Lock()
-	defer serv.Unlock()
+	unlock := serv.lockWallet(wltID)
+	defer unlock()
 	if !serv.enableWalletAPI {
 		return nil, ErrWalletAPIDisabled
 	}
@@ -144,24 +257,29 @@ func (serv *Service) EncryptWallet(wltID string, password []byte) (*Wallet, erro
 		return nil, ErrWalletEncrypted
 	}
 
-	if err := w.Lock(password, serv.cryptoType); err != nil {
+	crypto, err := getCryptoWithArgon2id(serv.cryptoType, serv.argon2id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := w.lock(password, serv.cryptoType, crypto); err != nil {
 		return nil, err
 	}
 
 	// Save to disk first
-	if err := w.Save(serv.walletDirectory); err != nil {
+	if err := serv.saveWallet(w); err != nil {
 		return nil, err
 	}
 
 	// Sets the encrypted wallet
-	serv.wallets.set(w)
+	serv.setWallet(w)
 	return w, nil
 }
 
 // DecryptWallet decrypts wallet with password
 func (serv *Service) DecryptWallet(wltID string, password []byte) (*Wallet, error) {
-	serv.Lock()
-	defer serv.Unlock()
+	unlock := serv.lockWallet(wltID)
+	defer unlock()
 	if !serv.enableWalletAPI {
 		return nil, ErrWalletAPIDisabled
 	}
@@ -183,12 +301,12 @@ func (serv *Service) DecryptWallet(wltID string, password []byte) (*Wallet, erro
 	}
 
 	// Updates the wallet file
-	if err := unlockWlt.Save(serv.walletDirectory); err != nil {
+	if err := serv.saveWallet(unlockWlt); err != nil {
 		return nil, err
 	}
 
 	// Sets the decrypted wallet in memory
-	serv.wallets.set(unlockWlt)
+	serv.setWallet(unlockWlt)
 	return unlockWlt, nil
 }
 
@@ -196,8 +314,8 @@ func (serv *Service) DecryptWallet(wltID string, password []byte) (*Wallet, erro
 // return nil if wallet does not exist.
 // Set password as nil if the wallet is not encrypted, otherwise the password must be provided.
 func (serv *Service) NewAddresses(wltID string, password []byte, num uint64) ([]cipher.Address, error) {
-	serv.Lock()
-	defer serv.Unlock()
+	unlock := serv.lockWallet(wltID)
+	defer unlock()
 
 	if !serv.enableWalletAPI {
 		return nil, ErrWalletAPIDisabled
@@ -216,7 +334,12 @@ func (serv *Service) NewAddresses(wltID string, password []byte, num uint64) ([]
 	}
 
 	if w.IsEncrypted() {
-		if err := w.GuardUpdate(password, f); err != nil {
+		cryptoType, crypto, err := serv.reencryptCrypto(w)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := w.guardUpdate(password, cryptoType, crypto, f); err != nil {
 			return nil, err
 		}
 	} else {
@@ -230,24 +353,199 @@ func (serv *Service) NewAddresses(wltID string, password []byte, num uint64) ([]
 	}
 
 	// Save the wallet first
-	if err := w.Save(serv.walletDirectory); err != nil {
+	if err := serv.saveWallet(w); err != nil {
 		return nil, err
 	}
 
-	serv.wallets.set(w)
+	serv.setWallet(w)
+
+	return addrs, nil
+}
+
+// ExtendActiveAddresses adds the used addresses that follow the last address of a wallet,
+// see Wallet.ExtendActiveAddresses. Watch-only and collection wallets, which can't generate addresses, are not extended.
+// Set password as nil if the wallet is not encrypted, otherwise the password must be provided.
+// The wallet is saved only if addresses were added.
+func (serv *Service) ExtendActiveAddresses(wltID string, password []byte, gapLimit uint64, ag AddressActivityGetter) ([]cipher.Address, error) {
+	unlock := serv.lockWallet(wltID)
+	defer unlock()
+
+	if !serv.enableWalletAPI {
+		return nil, ErrWalletAPIDisabled
+	}
+
+	w, err := serv.getWallet(wltID)
+	if err != nil {
+		return nil, err
+	}
+
+	if w.Type() != WalletTypeDeterministic || gapLimit == 0 {
+		return nil, nil
+	}
+
+	return serv.addAddresses(w, password, func(wlt *Wallet) ([]cipher.Address, error) {
+		return wlt.ExtendActiveAddresses(gapLimit, ag)
+	})
+}
+
+// FillAddressPool generates addresses until an account of a wallet has size unused addresses, see Wallet.FillAddressPool.
+// Set password as nil if the wallet is not encrypted, otherwise the password must be provided.
+// The wallet is saved only if addresses were added.
+func (serv *Service) FillAddressPool(wltID, account string, password []byte, size uint64) ([]cipher.Address, error) {
+	unlock := serv.lockWallet(wltID)
+	defer unlock()
+
+	if !serv.enableWalletAPI {
+		return nil, ErrWalletAPIDisabled
+	}
+
+	w, err := serv.getWallet(wltID)
+	if err != nil {
+		return nil, err
+	}
+
+	return serv.addAddresses(w, password, func(wlt *Wallet) ([]cipher.Address, error) {
+		return wlt.FillAddressPool(account, size)
+	})
+}
+
+// CreateAccount adds a named account to a wallet and generates its first address, see Wallet.CreateAccount.
+// Set password as nil if the wallet is not encrypted, otherwise the password must be provided.
+func (serv *Service) CreateAccount(wltID, name string, password []byte) (Account, error) {
+	unlock := serv.lockWallet(wltID)
+	defer unlock()
+
+	if !serv.enableWalletAPI {
+		return Account{}, ErrWalletAPIDisabled
+	}
+
+	w, err := serv.getWallet(wltID)
+	if err != nil {
+		return Account{}, err
+	}
+
+	var a Account
+	if _, err := serv.addAddresses(w, password, func(wlt *Wallet) ([]cipher.Address, error) {
+		var err error
+		a, err = wlt.CreateAccount(name)
+		if err != nil {
+			return nil, err
+		}
+		return wlt.AccountAddresses(name)
+	}); err != nil {
+		return Account{}, err
+	}
+
+	return a, nil
+}
+
+// addAddresses adds addresses to a wallet with f, decrypting it with password if it is encrypted,
+// and saves it only if addresses were added
+func (serv *Service) addAddresses(w *Wallet, password []byte, f func(*Wallet) ([]cipher.Address, error)) ([]cipher.Address, error) {
+	var addrs []cipher.Address
+	add := func(wlt *Wallet) error {
+		var err error
+		addrs, err = f(wlt)
+		return err
+	}
+
+	if w.IsEncrypted() {
+		// Only replace the wallet if addresses were added, to skip encrypting it again otherwise
+		w2 := w.clone()
+		cryptoType, crypto, err := serv.reencryptCrypto(w2)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := w2.guardUpdate(password, cryptoType, crypto, add); err != nil {
+			return nil, err
+		}
+
+		if len(addrs) == 0 {
+			return nil, nil
+		}
+
+		w = w2
+	} else {
+		if len(password) != 0 {
+			return nil, ErrWalletNotEncrypted
+		}
+
+		w = w.clone()
+		if err := add(w); err != nil {
+			return nil, err
+		}
+
+		if len(addrs) == 0 {
+			return nil, nil
+		}
+	}
+
+	// Save the wallet first
+	if err := serv.saveWallet(w); err != nil {
+		return nil, err
+	}
+
+	serv.setWallet(w)
+
+	return addrs, nil
+}
+
+// MarkAddressesUsed marks addresses of a wallet as seen on the blockchain, see Wallet.MarkAddressesUsed.
+// The wallet is saved only if addresses were marked.
+func (serv *Service) MarkAddressesUsed(wltID string, addrs []cipher.Address) ([]cipher.Address, error) {
+	unlock := serv.lockWallet(wltID)
+	defer unlock()
+
+	if !serv.enableWalletAPI {
+		return nil, ErrWalletAPIDisabled
+	}
+
+	w, err := serv.getWallet(wltID)
+	if err != nil {
+		return nil, err
+	}
+
+	marked, err := w.MarkAddressesUsed(addrs)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(marked) == 0 {
+		return nil, nil
+	}
+
+	if err := serv.saveWallet(w); err != nil {
+		return nil, err
+	}
+
+	serv.setWallet(w)
+
+	return marked, nil
+}
+
+// TakePoolAddresses hands out unused addresses of an account of a wallet, see Wallet.TakePoolAddresses.
+// The password is not needed, even if the wallet is encrypted.
+func (serv *Service) TakePoolAddresses(wltID, account string, n uint64) ([]cipher.Address, error) {
+	var addrs []cipher.Address
+	if err := serv.Update(wltID, func(w *Wallet) error {
+		var err error
+		addrs, err = w.TakePoolAddresses(account, n)
+		return err
+	}); err != nil {
+		return nil, err
+	}
 
 	return addrs, nil
 }
 
 // GetSkycoinAddresses returns all addresses in given wallet
 func (serv *Service) GetSkycoinAddresses(wltID string) ([]cipher.Address, error) {
-	serv.RLock()
-	defer serv.RUnlock()
 	if !serv.enableWalletAPI {
 		return nil, ErrWalletAPIDisabled
 	}
 
-	w, err := serv.getWallet(wltID)
+	w, err := serv.snapshot(wltID)
 	if err != nil {
 		return nil, err
 	}
@@ -255,10 +553,47 @@ func (serv *Service) GetSkycoinAddresses(wltID string) ([]cipher.Address, error)
 	return w.GetSkycoinAddresses()
 }
 
+// AddressWallets returns the IDs of the wallets that have each address of addrs, sorted.
+// The addresses that are not in a wallet are omitted.
+// It reads the snapshots of the wallets and doesn't wait for their pending updates.
+func (serv *Service) AddressWallets(addrs []cipher.Address) map[cipher.Address][]string {
+	serv.RLock()
+	snapshots := make([]*Wallet, 0, len(serv.wallets))
+	for _, w := range serv.wallets {
+		snapshots = append(snapshots, w)
+	}
+	serv.RUnlock()
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Filename() < snapshots[j].Filename()
+	})
+
+	addrSet := make(map[cipher.Address]struct{}, len(addrs))
+	for _, a := range addrs {
+		addrSet[a] = struct{}{}
+	}
+
+	addrWallets := make(map[cipher.Address][]string)
+	for _, w := range snapshots {
+		for _, e := range w.Entries {
+			a := e.SkycoinAddress()
+			if _, ok := addrSet[a]; !ok {
+				continue
+			}
+
+			ids := addrWallets[a]
+			// Skip the duplicate entries of a wallet
+			if len(ids) == 0 || ids[len(ids)-1] != w.Filename() {
+				addrWallets[a] = append(ids, w.Filename())
+			}
+		}
+	}
+
+	return addrWallets
+}
+
 // GetWallet returns wallet by id
 func (serv *Service) GetWallet(wltID string) (*Wallet, error) {
-	serv.RLock()
-	defer serv.RUnlock()
 	if !serv.enableWalletAPI {
 		return nil, ErrWalletAPIDisabled
 	}
@@ -266,25 +601,188 @@ func (serv *Service) GetWallet(wltID string) (*Wallet, error) {
 	return serv.getWallet(wltID)
 }
 
-// returns the clone of the wallet of given id
+// returns the clone of the wallet of given id, which the caller can modify
 func (serv *Service) getWallet(wltID string) (*Wallet, error) {
+	w, err := serv.snapshot(wltID)
+	if err != nil {
+		return nil, err
+	}
+	return w.clone(), nil
+}
+
+// snapshot returns the current snapshot of the wallet of given id, which must not be modified
+func (serv *Service) snapshot(wltID string) (*Wallet, error) {
+	serv.RLock()
+	defer serv.RUnlock()
+
 	w, ok := serv.wallets.get(wltID)
 	if !ok {
 		return nil, ErrWalletNotExist
 	}
-	return w.clone(), nil
+	return w, nil
+}
+
+// setWallet replaces the snapshot of a wallet with a copy of w, once it has been saved.
+// The lock of the wallet must be held for writing.
+func (serv *Service) setWallet(w *Wallet) {
+	serv.Lock()
+	defer serv.Unlock()
+	serv.wallets.set(w)
+}
+
+// addWallet adds a new wallet to the service and saves it.
+// The wallet is registered before it is saved, so that the wallets created meanwhile
+// with the same ID or seed are rejected, and it is removed if it can't be saved.
+func (serv *Service) addWallet(w *Wallet) error {
+	unlock := serv.lockWallet(w.Filename())
+	defer unlock()
+
+	if err := serv.registerWallet(w); err != nil {
+		return err
+	}
+
+	if err := serv.saveWallet(w); err != nil {
+		// If save fails, remove the added wallet
+		serv.unregisterWallet(w)
+		return err
+	}
+
+	return nil
+}
+
+// registerWallet adds a copy of a new wallet to the registry
+func (serv *Service) registerWallet(w *Wallet) error {
+	serv.Lock()
+	defer serv.Unlock()
+	return serv.registerWalletLocked(w)
+}
+
+// registerWalletLocked adds a copy of a new wallet to the registry, which must be locked for writing
+func (serv *Service) registerWalletLocked(w *Wallet) error {
+	// Check for duplicate wallets by initial seed
+	var firstAddr string
+	if !w.IsWatchOnly() && len(w.Entries) != 0 {
+		firstAddr = w.Entries[0].Address.String()
+		if _, ok := serv.firstAddrIDMap[firstAddr]; ok {
+			return ErrSeedUsed
+		}
+	}
+
+	if err := serv.wallets.add(w.clone()); err != nil {
+		return err
+	}
+
+	if firstAddr != "" {
+		serv.firstAddrIDMap[firstAddr] = w.Filename()
+	}
+
+	return nil
+}
+
+// unregisterWallet removes a wallet that could not be saved from the registry
+func (serv *Service) unregisterWallet(w *Wallet) {
+	serv.Lock()
+	defer serv.Unlock()
+
+	serv.wallets.remove(w.Filename())
+	if !w.IsWatchOnly() && len(w.Entries) != 0 {
+		firstAddr := w.Entries[0].Address.String()
+		if serv.firstAddrIDMap[firstAddr] == w.Filename() {
+			delete(serv.firstAddrIDMap, firstAddr)
+		}
+	}
+}
+
+// walletFileIDsLocked returns the IDs of the wallets and of the wallet files of the storages,
+// including the wallet files that were not loaded, such as empty wallets.
+// The registry must be locked.
+func (serv *Service) walletFileIDsLocked() (map[string]struct{}, error) {
+	ids := make(map[string]struct{}, len(serv.wallets))
+	for id := range serv.wallets {
+		ids[id] = struct{}{}
+	}
+	for _, s := range serv.storages {
+		files, err := s.List()
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range files {
+			ids[id] = struct{}{}
+		}
+	}
+	return ids, nil
+}
+
+// walletLock is the lock of a wallet, shared by the callers that hold or wait for it
+type walletLock struct {
+	sync.RWMutex
+	// refs is the number of callers that hold or wait for the lock, guarded by the registry lock
+	refs int
+}
+
+// acquireWalletLock returns the lock of the wallet of given id, creating it if needed.
+// The lock is removed by releaseWalletLock once no caller holds or waits for it, so that the locks
+// don't accumulate for the wallets that were removed or never existed.
+func (serv *Service) acquireWalletLock(wltID string) *walletLock {
+	serv.Lock()
+	defer serv.Unlock()
+
+	l, ok := serv.locks[wltID]
+	if !ok {
+		l = &walletLock{}
+		serv.locks[wltID] = l
+	}
+	l.refs++
+	return l
+}
+
+// releaseWalletLock releases a lock returned by acquireWalletLock, after it is unlocked
+func (serv *Service) releaseWalletLock(wltID string, l *walletLock) {
+	serv.Lock()
+	defer serv.Unlock()
+
+	l.refs--
+	if l.refs == 0 {
+		delete(serv.locks, wltID)
+	}
+}
+
+// lockWallet locks a wallet for an update and returns the function that unlocks it
+func (serv *Service) lockWallet(wltID string) func() {
+	l := serv.acquireWalletLock(wltID)
+	l.Lock()
+	return func() {
+		l.Unlock()
+		serv.releaseWalletLock(wltID, l)
+	}
+}
+
+// rlockWallet locks a wallet for viewing and returns the function that unlocks it
+func (serv *Service) rlockWallet(wltID string) func() {
+	l := serv.acquireWalletLock(wltID)
+	l.RLock()
+	return func() {
+		l.RUnlock()
+		serv.releaseWalletLock(wltID, l)
+	}
 }
 
 // GetWallets returns all wallet clones
 func (serv *Service) GetWallets() (Wallets, error) {
-	serv.RLock()
-	defer serv.RUnlock()
 	if !serv.enableWalletAPI {
 		return nil, ErrWalletAPIDisabled
 	}
 
-	wlts := make(Wallets, len(serv.wallets))
+	// The snapshots are cloned after the registry is unlocked
+	serv.RLock()
+	snapshots := make(Wallets, len(serv.wallets))
 	for k, w := range serv.wallets {
+		snapshots[k] = w
+	}
+	serv.RUnlock()
+
+	wlts := make(Wallets, len(snapshots))
+	for k, w := range snapshots {
 		wlts[k] = w.clone()
 	}
 	return wlts, nil
@@ -293,8 +791,10 @@ func (serv *Service) GetWallets() (Wallets, error) {
 // CreateAndSignTransaction creates and signs a transaction from wallet.
 // Set the password as nil if the wallet is not encrypted, otherwise the password must be provided
 func (serv *Service) CreateAndSignTransaction(wltID string, password []byte, auxs coin.AddressUxOuts, headTime, coins uint64, dest cipher.Address) (*coin.Transaction, error) {
-	serv.RLock()
-	defer serv.RUnlock()
+	var upgrade bool
+	defer serv.upgradeCryptoIf(&upgrade, wltID, password)
+	unlock := serv.rlockWallet(wltID)
+	defer unlock()
 	if !serv.enableWalletAPI {
 		return nil, ErrWalletAPIDisabled
 	}
@@ -315,6 +815,7 @@ func (serv *Service) CreateAndSignTransaction(wltID string, password []byte, aux
 		if err := w.GuardView(password, f); err != nil {
 			return nil, err
 		}
+		upgrade = serv.needsCryptoUpgrade(w)
 	} else {
 		if len(password) != 0 {
 			return nil, ErrWalletNotEncrypted
@@ -330,8 +831,10 @@ func (serv *Service) CreateAndSignTransaction(wltID string, password []byte, aux
 // CreateAndSignTransactionAdvanced creates and signs a transaction based upon CreateTransactionParams.
 // Set the password as nil if the wallet is not encrypted, otherwise the password must be provided
 func (serv *Service) CreateAndSignTransactionAdvanced(params CreateTransactionParams, auxs coin.AddressUxOuts, headTime uint64) (*coin.Transaction, []UxBalance, error) {
-	serv.RLock()
-	defer serv.RUnlock()
+	var upgrade bool
+	defer serv.upgradeCryptoIf(&upgrade, params.Wallet.ID, params.Wallet.Password)
+	unlock := serv.rlockWallet(params.Wallet.ID)
+	defer unlock()
 
 	if !serv.enableWalletAPI {
 		return nil, nil, ErrWalletAPIDisabled
@@ -348,7 +851,7 @@ func (serv *Service) CreateAndSignTransactionAdvanced(params CreateTransactionPa
 
 	// Check if the wallet needs a password
 	if w.IsEncrypted() {
-		if len(params.Wallet.Password) == 0 {
+		if len(params.Wallet.Password) == 0 && !params.Unsigned {
 			return nil, nil, ErrMissingPassword
 		}
 	} else {
@@ -359,12 +862,13 @@ func (serv *Service) CreateAndSignTransactionAdvanced(params CreateTransactionPa
 
 	var tx *coin.Transaction
 	var inputs []UxBalance
-	if w.IsEncrypted() {
+	if w.IsEncrypted() && !params.Unsigned {
 		err = w.GuardView(params.Wallet.Password, func(wlt *Wallet) error {
 			var err error
 			tx, inputs, err = wlt.CreateAndSignTransactionAdvanced(params, auxs, headTime)
 			return err
 		})
+		upgrade = err == nil && serv.needsCryptoUpgrade(w)
 	} else {
 		tx, inputs, err = w.CreateAndSignTransactionAdvanced(params, auxs, headTime)
 	}
@@ -377,8 +881,8 @@ func (serv *Service) CreateAndSignTransactionAdvanced(params CreateTransactionPa
 
 // UpdateWalletLabel updates the wallet label
 func (serv *Service) UpdateWalletLabel(wltID, label string) error {
-	serv.Lock()
-	defer serv.Unlock()
+	unlock := serv.lockWallet(wltID)
+	defer unlock()
 	if !serv.enableWalletAPI {
 		return ErrWalletAPIDisabled
 	}
@@ -390,23 +894,191 @@ func (serv *Service) UpdateWalletLabel(wltID, label string) error {
 
 	w.setLabel(label)
 
-	if err := w.Save(serv.walletDirectory); err != nil {
+	if err := serv.saveWallet(w); err != nil {
 		return err
 	}
 
-	serv.wallets.set(w)
+	serv.setWallet(w)
 	return nil
 }
 
+// SetAddressAnnotation annotates an address of a wallet, an empty annotation removes it.
+// Returns the updated wallet.
+func (serv *Service) SetAddressAnnotation(wltID string, addr cipher.Address, a Annotation) (*Wallet, error) {
+	var wlt *Wallet
+	if err := serv.Update(wltID, func(w *Wallet) error {
+		wlt = w
+		return w.SetAddressAnnotation(addr, a)
+	}); err != nil {
+		return nil, err
+	}
+
+	return wlt, nil
+}
+
+// SetTransactionAnnotation annotates a transaction in a wallet, an empty annotation removes it.
+// Returns the updated wallet.
+func (serv *Service) SetTransactionAnnotation(wltID string, txid cipher.SHA256, a Annotation) (*Wallet, error) {
+	var wlt *Wallet
+	if err := serv.Update(wltID, func(w *Wallet) error {
+		wlt = w
+		return w.SetTransactionAnnotation(txid, a)
+	}); err != nil {
+		return nil, err
+	}
+
+	return wlt, nil
+}
+
+// SetSpendingPolicy sets the spending policy of a wallet, the zero policy removes it.
+// The password of an encrypted wallet is required, so that the policy can't be lifted without it.
+func (serv *Service) SetSpendingPolicy(wltID string, password []byte, p SpendingPolicy) (*Wallet, error) {
+	unlock := serv.lockWallet(wltID)
+	defer unlock()
+	if !serv.enableWalletAPI {
+		return nil, ErrWalletAPIDisabled
+	}
+
+	w, err := serv.getWallet(wltID)
+	if err != nil {
+		return nil, err
+	}
+
+	if w.IsEncrypted() {
+		if len(password) == 0 {
+			return nil, ErrMissingPassword
+		}
+
+		if err := w.GuardView(password, func(*Wallet) error {
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	} else if len(password) != 0 {
+		return nil, ErrWalletNotEncrypted
+	}
+
+	if err := w.SetSpendingPolicy(p); err != nil {
+		return nil, err
+	}
+
+	if err := serv.saveWallet(w); err != nil {
+		return nil, err
+	}
+
+	serv.setWallet(w)
+
+	return w.clone(), nil
+}
+
+// SetCosigners sets the cosigners of a wallet, replacing the previous ones. No cosigners removes them
+func (serv *Service) SetCosigners(wltID string, cs Cosigners) (*Wallet, error) {
+	unlock := serv.lockWallet(wltID)
+	defer unlock()
+	if !serv.enableWalletAPI {
+		return nil, ErrWalletAPIDisabled
+	}
+
+	w, err := serv.getWallet(wltID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := w.SetCosigners(cs); err != nil {
+		return nil, err
+	}
+
+	if err := serv.saveWallet(w); err != nil {
+		return nil, err
+	}
+
+	serv.setWallet(w)
+
+	return w.clone(), nil
+}
+
+// EnforceSpendingPolicy checks that transactions created by a wallet comply with its spending policy,
+// and records their spends for its daily limit, see Wallet.EnforceSpendingPolicy.
+// Transactions are counted when they are signed, whether or not they are broadcast.
+func (serv *Service) EnforceSpendingPolicy(wltID string, txns []coin.Transaction, now time.Time) error {
+	unlock := serv.lockWallet(wltID)
+	defer unlock()
+	if !serv.enableWalletAPI {
+		return ErrWalletAPIDisabled
+	}
+
+	w, err := serv.getWallet(wltID)
+	if err != nil {
+		return err
+	}
+
+	return serv.enforceSpendingPolicy(w, txns, now)
+}
+
+// enforceSpendingPolicy enforces the spending policy of a wallet and saves its recorded spends.
+// The lock of the wallet must be held for writing.
+func (serv *Service) enforceSpendingPolicy(w *Wallet, txns []coin.Transaction, now time.Time) error {
+	if w.SpendingPolicy.Empty() {
+		return nil
+	}
+
+	if err := w.EnforceSpendingPolicy(txns, now); err != nil {
+		return err
+	}
+
+	if w.SpendingPolicy.DailyLimitCoins == 0 {
+		return nil
+	}
+
+	if err := serv.saveWallet(w); err != nil {
+		return err
+	}
+
+	serv.setWallet(w)
+
+	return nil
+}
+
+// CheckSpendingPolicy checks that transactions created unsigned by a wallet comply with its spending policy,
+// without recording their spends, see Wallet.CheckSpendingPolicy.
+func (serv *Service) CheckSpendingPolicy(wltID string, txns []coin.Transaction, now time.Time) error {
+	return serv.View(wltID, func(w *Wallet) error {
+		return w.CheckSpendingPolicy(txns, now)
+	})
+}
+
 // Remove removes wallet of given wallet id from the service
 func (serv *Service) Remove(wltID string) error {
-	serv.Lock()
-	defer serv.Unlock()
+	unlock := serv.lockWallet(wltID)
+	defer unlock()
 	if !serv.enableWalletAPI {
 		return ErrWalletAPIDisabled
 	}
 
+	serv.Lock()
+	defer serv.Unlock()
 	serv.wallets.remove(wltID)
+	delete(serv.walletStorages, wltID)
+	return nil
+}
+
+// saveWallet saves a wallet to its storage. New wallets are saved to the first storage.
+// The registry is not locked while the wallet is saved, the lock of the wallet must be held for writing.
+func (serv *Service) saveWallet(w *Wallet) error {
+	serv.RLock()
+	s, ok := serv.walletStorages[w.Filename()]
+	serv.RUnlock()
+	if !ok {
+		s = serv.storages[0]
+	}
+
+	if err := s.Save(w); err != nil {
+		return err
+	}
+
+	serv.Lock()
+	defer serv.Unlock()
+	serv.walletStorages[w.Filename()] = s
 	return nil
 }
 
@@ -420,6 +1092,11 @@ func (serv *Service) removeDup(wlts Wallets) Wallets {
 			continue
 		}
 
+		// watch-only wallets can watch the addresses of another wallet
+		if wlt.IsWatchOnly() {
+			continue
+		}
+
 		addr := wlt.Entries[0].Address.String()
 		id, ok := serv.firstAddrIDMap[addr]
 
@@ -454,8 +1131,10 @@ func (serv *Service) removeDup(wlts Wallets) Wallets {
 // GetWalletSeed returns seed of encrypted wallet of given wallet id
 // Returns ErrWalletNotEncrypted if it's not encrypted
 func (serv *Service) GetWalletSeed(wltID string, password []byte) (string, error) {
-	serv.RLock()
-	defer serv.RUnlock()
+	var upgrade bool
+	defer serv.upgradeCryptoIf(&upgrade, wltID, password)
+	unlock := serv.rlockWallet(wltID)
+	defer unlock()
 	if !serv.enableWalletAPI {
 		return "", ErrWalletAPIDisabled
 	}
@@ -469,6 +1148,11 @@ func (serv *Service) GetWalletSeed(wltID string, password []byte) (string, error
 		return "", err
 	}
 
+	// Collection wallets have keys but no seed
+	if w.Type() == WalletTypeCollection {
+		return "", ErrWalletNotDeterministic
+	}
+
 	if !w.IsEncrypted() {
 		return "", ErrWalletNotEncrypted
 	}
@@ -480,14 +1164,15 @@ func (serv *Service) GetWalletSeed(wltID string, password []byte) (string, error
 	}); err != nil {
 		return "", err
 	}
+	upgrade = serv.needsCryptoUpgrade(w)
 
 	return seed, nil
 }
 
 // UpdateSecrets opens a wallet for modification of secret data and saves it safely
 func (serv *Service) UpdateSecrets(wltID string, password []byte, f func(*Wallet) error) error {
-	serv.Lock()
-	defer serv.Unlock()
+	unlock := serv.lockWallet(wltID)
+	defer unlock()
 	if !serv.enableWalletAPI {
 		return ErrWalletAPIDisabled
 	}
@@ -498,7 +1183,12 @@ func (serv *Service) UpdateSecrets(wltID string, password []byte, f func(*Wallet
 	}
 
 	if w.IsEncrypted() {
-		if err := w.GuardUpdate(password, f); err != nil {
+		cryptoType, crypto, err := serv.reencryptCrypto(w)
+		if err != nil {
+			return err
+		}
+
+		if err := w.guardUpdate(password, cryptoType, crypto, f); err != nil {
 			return err
 		}
 	} else if len(password) != 0 {
@@ -510,19 +1200,19 @@ func (serv *Service) UpdateSecrets(wltID string, password []byte, f func(*Wallet
 	}
 
 	// Save the wallet first
-	if err := w.Save(serv.walletDirectory); err != nil {
+	if err := serv.saveWallet(w); err != nil {
 		return err
 	}
 
-	serv.wallets.set(w)
+	serv.setWallet(w)
 
 	return nil
 }
 
 // Update opens a wallet for modification of non-secret data and saves it safely
 func (serv *Service) Update(wltID string, f func(*Wallet) error) error {
-	serv.Lock()
-	defer serv.Unlock()
+	unlock := serv.lockWallet(wltID)
+	defer unlock()
 	if !serv.enableWalletAPI {
 		return ErrWalletAPIDisabled
 	}
@@ -537,19 +1227,21 @@ func (serv *Service) Update(wltID string, f func(*Wallet) error) error {
 	}
 
 	// Save the wallet first
-	if err := w.Save(serv.walletDirectory); err != nil {
+	if err := serv.saveWallet(w); err != nil {
 		return err
 	}
 
-	serv.wallets.set(w)
+	serv.setWallet(w)
 
 	return nil
 }
 
 // ViewSecrets opens a wallet for reading secret data
 func (serv *Service) ViewSecrets(wltID string, password []byte, f func(*Wallet) error) error {
-	serv.RLock()
-	defer serv.RUnlock()
+	var upgrade bool
+	defer serv.upgradeCryptoIf(&upgrade, wltID, password)
+	unlock := serv.rlockWallet(wltID)
+	defer unlock()
 	if !serv.enableWalletAPI {
 		return ErrWalletAPIDisabled
 	}
@@ -560,7 +1252,11 @@ func (serv *Service) ViewSecrets(wltID string, password []byte, f func(*Wallet)
 	}
 
 	if w.IsEncrypted() {
-		return w.GuardView(password, f)
+		if err := w.GuardView(password, f); err != nil {
+			return err
+		}
+		upgrade = serv.needsCryptoUpgrade(w)
+		return nil
 	} else if len(password) != 0 {
 		return ErrWalletNotEncrypted
 	} else {
@@ -568,10 +1264,90 @@ func (serv *Service) ViewSecrets(wltID string, password []byte, f func(*Wallet)
 	}
 }
 
+// SignPartialTransaction signs the unsigned inputs of a partial transaction that are owned by a wallet.
+// The password is required if the wallet is encrypted. The partial transaction is not modified,
+// a signed copy is returned.
+// The transaction must comply with the spending policy of the wallet, see Service.EnforceSpendingPolicy.
+func (serv *Service) SignPartialTransaction(wltID string, password []byte, ptx *PartialTransaction) (*PartialTransaction, error) {
+	var upgrade bool
+	defer serv.upgradeCryptoIf(&upgrade, wltID, password)
+	unlock := serv.lockWallet(wltID)
+	defer unlock()
+	if !serv.enableWalletAPI {
+		return nil, ErrWalletAPIDisabled
+	}
+
+	w, err := serv.getWallet(wltID)
+	if err != nil {
+		return nil, err
+	}
+
+	return serv.signPartialTransaction(w, password, ptx, &upgrade)
+}
+
+// signPartialTransaction signs a copy of a partial transaction with a wallet and enforces its spending policy.
+// upgrade is set if the crypto of the wallet needs an upgrade. The lock of the wallet must be held for writing.
+func (serv *Service) signPartialTransaction(w *Wallet, password []byte, ptx *PartialTransaction, upgrade *bool) (*PartialTransaction, error) {
+	// Wallet.SignPartialTransaction replaces the signatures of the copy, ptx's are not modified
+	signed := *ptx
+	sign := func(w *Wallet) error {
+		_, err := w.SignPartialTransaction(&signed)
+		return err
+	}
+
+	if w.IsEncrypted() {
+		if err := w.GuardView(password, sign); err != nil {
+			return nil, err
+		}
+		*upgrade = serv.needsCryptoUpgrade(w)
+	} else if len(password) != 0 {
+		return nil, ErrWalletNotEncrypted
+	} else if err := sign(w); err != nil {
+		return nil, err
+	}
+
+	// The signed copy is dropped if the transaction does not comply with the spending policy
+	if err := serv.enforceSpendingPolicy(w, []coin.Transaction{signed.Transaction}, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return &signed, nil
+}
+
+// CosignPartialTransaction signs the unsigned inputs of a partial transaction that are owned by a wallet, if it has any,
+// and returns the status of the cosigners of the wallet for the signed transaction.
+// The partial transactions signed by the cosigners are combined beforehand, see PartialTransaction.Combine.
+// A watch-only wallet, or a wallet that owns none of the unsigned inputs, does not sign and does not need the password.
+// The wallet is locked while it is checked and signs, so that its addresses and keys can't change in between.
+func (serv *Service) CosignPartialTransaction(wltID string, password []byte, ptx *PartialTransaction) (*PartialTransaction, []CosignerStatus, error) {
+	var upgrade bool
+	defer serv.upgradeCryptoIf(&upgrade, wltID, password)
+	unlock := serv.lockWallet(wltID)
+	defer unlock()
+	if !serv.enableWalletAPI {
+		return nil, nil, ErrWalletAPIDisabled
+	}
+
+	w, err := serv.getWallet(wltID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	signed := ptx
+	if w.canSignPartialTransaction(ptx) {
+		signed, err = serv.signPartialTransaction(w, password, ptx, &upgrade)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return signed, w.CosignerStatus(signed), nil
+}
+
 // View opens a wallet for reading non-secret data
 func (serv *Service) View(wltID string, f func(*Wallet) error) error {
-	serv.RLock()
-	defer serv.RUnlock()
+	unlock := serv.rlockWallet(wltID)
+	defer unlock()
 	if !serv.enableWalletAPI {
 		return ErrWalletAPIDisabled
 	}
@@ -584,11 +1360,11 @@ func (serv *Service) View(wltID string, f func(*Wallet) error) error {
 	return f(w)
 }
 
-// RecoverWallet recovers an encrypted wallet from seed.
+// RecoverWallet recovers an encrypted wallet from seed, and the BIP39 seed passphrase if the wallet has one.
 // The recovered wallet will be encrypted with the new password, if provided.
-func (serv *Service) RecoverWallet(wltName, seed string, password []byte) (*Wallet, error) {
-	serv.Lock()
-	defer serv.Unlock()
+func (serv *Service) RecoverWallet(wltName, seed, seedPassphrase string, password []byte) (*Wallet, error) {
+	unlock := serv.lockWallet(wltName)
+	defer unlock()
 	if !serv.enableWalletAPI {
 		return nil, ErrWalletAPIDisabled
 	}
@@ -608,7 +1384,7 @@ func (serv *Service) RecoverWallet(wltName, seed string, password []byte) (*Wall
 
 	// Generate the first address from the seed
 	var pk cipher.PubKey
-	pk, _, err = cipher.GenerateDeterministicKeyPair([]byte(seed))
+	pk, _, err = cipher.GenerateDeterministicKeyPair(keySeed(seed, seedPassphrase))
 	if err != nil {
 		return nil, err
 	}
@@ -621,13 +1397,15 @@ func (serv *Service) RecoverWallet(wltName, seed string, password []byte) (*Wall
 
 	// Create a new wallet with the same number of addresses, encrypting if needed
 	w2, err := NewWallet(wltName, Options{
-		Coin:       w.coin(),
-		Label:      w.Label(),
-		Seed:       seed,
-		Encrypt:    len(password) != 0,
-		Password:   password,
-		CryptoType: w.cryptoType(),
-		GenerateN:  uint64(len(w.Entries)),
+		Coin:           w.coin(),
+		Label:          w.Label(),
+		Seed:           seed,
+		SeedPassphrase: seedPassphrase,
+		Encrypt:        len(password) != 0,
+		Password:       password,
+		CryptoType:     w.cryptoType(),
+		Argon2id:       serv.argon2id,
+		GenerateN:      uint64(len(w.Entries)),
 	})
 	if err != nil {
 		return nil, err
@@ -636,12 +1414,91 @@ func (serv *Service) RecoverWallet(wltName, seed string, password []byte) (*Wall
 	// Preserve the timestamp of the old wallet
 	w2.setTimestamp(w.timestamp())
 
+	// Preserve the spending policy, recovering a wallet must not lift it
+	w2.SpendingPolicy = w.SpendingPolicy.clone()
+	w2.Spends = append(w2.Spends, w.Spends...)
+
+	// Preserve the cosigners
+	w2.Cosigners = w.Cosigners.clone()
+
+	// Preserve the address pool, the recovered wallet has the same addresses
+	w2.AddressPool = w.AddressPool.clone()
+
 	// Save to disk
-	if err := w2.Save(serv.walletDirectory); err != nil {
+	if err := serv.saveWallet(w2); err != nil {
 		return nil, err
 	}
 
-	serv.wallets.set(w2)
+	serv.setWallet(w2)
 
 	return w2.clone(), nil
 }
+
+// needsCryptoUpgrade returns true if the wallet is encrypted with an older crypto type than
+// argon2id-chacha20poly1305, and the service encrypts wallets with argon2id-chacha20poly1305
+func (serv *Service) needsCryptoUpgrade(w *Wallet) bool {
+	if !w.IsEncrypted() || serv.cryptoType != CryptoTypeArgon2idChacha20poly1305 {
+		return false
+	}
+
+	switch w.cryptoType() {
+	case CryptoTypeSha256Xor, CryptoTypeScryptChacha20poly1305:
+		return true
+	default:
+		return false
+	}
+}
+
+// reencryptCrypto returns the crypto type and the crypto to encrypt a wallet with after updating it.
+// Wallets that need a crypto upgrade are encrypted with the crypto of the service,
+// the others keep their crypto type and key derivation parameters.
+func (serv *Service) reencryptCrypto(w *Wallet) (CryptoType, cryptor, error) {
+	if serv.needsCryptoUpgrade(w) {
+		crypto, err := getCryptoWithArgon2id(serv.cryptoType, serv.argon2id)
+		return serv.cryptoType, crypto, err
+	}
+
+	crypto, err := w.currentCrypto()
+	return w.cryptoType(), crypto, err
+}
+
+// upgradeCryptoIf upgrades the crypto of a wallet if *upgrade is true, once its password is known to be valid.
+// It is deferred before read locking the wallet in the methods that view the secrets of a wallet,
+// so that it runs after the read lock is released.
+// Failing to upgrade does not fail the operation, the wallet is upgraded the next time it is unlocked.
+func (serv *Service) upgradeCryptoIf(upgrade *bool, wltID string, password []byte) {
+	if !*upgrade {
+		return
+	}
+
+	unlock := serv.lockWallet(wltID)
+	defer unlock()
+
+	w, err := serv.getWallet(wltID)
+	if err != nil {
+		logger.WithError(err).WithField("wallet", wltID).Warning("Upgrade wallet crypto failed")
+		return
+	}
+
+	// The wallet may have been upgraded or decrypted meanwhile
+	if !serv.needsCryptoUpgrade(w) {
+		return
+	}
+
+	cryptoType, crypto, err := serv.reencryptCrypto(w)
+	if err == nil {
+		err = w.guardUpdate(password, cryptoType, crypto, func(*Wallet) error {
+			return nil
+		})
+	}
+	if err == nil {
+		err = serv.saveWallet(w)
+	}
+	if err != nil {
+		logger.WithError(err).WithField("wallet", wltID).Warning("Upgrade wallet crypto failed")
+		return
+	}
+
+	serv.setWallet(w)
+	logger.WithField("wallet", wltID).Infof("Upgraded wallet crypto to %s", cryptoType)
+}
@@ -19,6 +19,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/encrypt"
 	"github.com/skycoin/skycoin/src/coin"
 	"github.com/skycoin/skycoin/src/testutil"
 	"github.com/skycoin/skycoin/src/util/fee"
@@ -56,7 +57,7 @@ func TestNewService(t *testing.T) {
 			_, err = os.Stat(dir)
 			require.NoError(t, err)
 
-			require.Equal(t, dir, s.walletDirectory)
+			require.Equal(t, dir, s.storages[0].Name())
 
 			require.Equal(t, 0, len(s.wallets))
 
@@ -1189,6 +1190,50 @@ func TestServiceCreateAndSignTransactionAdvanced(t *testing.T) {
 			},
 		},
 
+		{
+			name: "invalid coin selection",
+			params: CreateTransactionParams{
+				HoursSelection: HoursSelection{
+					Type: HoursSelectionTypeManual,
+				},
+				To: []coin.TransactionOutput{
+					{
+						Address: addrs[0],
+						Hours:   50,
+						Coins:   2e6 + 1,
+					},
+				},
+				CoinSelection: "foo",
+			},
+			unspents: uxouts,
+			err:      ErrInvalidCoinSelection,
+		},
+
+		{
+			name: "manual, 1 output, change, exact coin selection",
+			params: CreateTransactionParams{
+				ChangeAddress: &changeAddress,
+				HoursSelection: HoursSelection{
+					Type: HoursSelectionTypeManual,
+				},
+				To: []coin.TransactionOutput{
+					{
+						Address: addrs[0],
+						Hours:   50,
+						Coins:   2e6 + 1,
+					},
+				},
+				CoinSelection: CoinSelectionExact,
+			},
+			unspents:       originalUxouts[:3],
+			chosenUnspents: originalUxouts[:3],
+			changeOutput: &coin.TransactionOutput{
+				Address: changeAddress,
+				Hours:   101,
+				Coins:   4e6 - 1,
+			},
+		},
+
 		{
 			// there are leftover coin hours and an additional input is added
 			// to force change to save the leftover coin hours
@@ -1853,6 +1898,180 @@ func TestServiceUpdateWalletLabel(t *testing.T) {
 	}
 }
 
+func TestServiceAnnotations(t *testing.T) {
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+	s, err := NewService(Config{
+		WalletDir:       dir,
+		CryptoType:      CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	w, err := s.CreateWallet("t.wlt", Options{
+		Seed:     "seed",
+		Encrypt:  true,
+		Password: []byte("pwd"),
+	}, nil)
+	require.NoError(t, err)
+	addr := w.Entries[0].SkycoinAddress()
+	txid := testutil.RandSHA256(t)
+
+	// Encrypted wallets are annotated without their password
+	_, err = s.SetAddressAnnotation("t.wlt", addr, Annotation{Label: "savings"})
+	require.NoError(t, err)
+	w, err = s.SetTransactionAnnotation("t.wlt", txid, Annotation{Note: "invoice 42"})
+	require.NoError(t, err)
+	require.True(t, w.IsEncrypted())
+	checkNoSensitiveData(t, w)
+
+	_, err = s.SetAddressAnnotation("foo.wlt", addr, Annotation{Label: "savings"})
+	require.Equal(t, ErrWalletNotExist, err)
+	_, err = s.SetAddressAnnotation("t.wlt", testutil.MakeAddress(), Annotation{Label: "savings"})
+	require.Equal(t, ErrUnknownAddress, err)
+
+	expected := Annotations{
+		Addresses: map[cipher.Address]Annotation{
+			addr: {Label: "savings"},
+		},
+		Transactions: map[cipher.SHA256]Annotation{
+			txid: {Note: "invoice 42"},
+		},
+	}
+
+	// The annotations are kept when the wallet is decrypted
+	w, err = s.DecryptWallet("t.wlt", []byte("pwd"))
+	require.NoError(t, err)
+	require.Equal(t, expected, w.Annotations)
+
+	// The annotations are loaded from the wallet file
+	s, err = NewService(Config{
+		WalletDir:       dir,
+		CryptoType:      CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+	w, err = s.GetWallet("t.wlt")
+	require.NoError(t, err)
+	require.Equal(t, expected, w.Annotations)
+
+	// Disabled wallet API
+	s, err = NewService(Config{
+		WalletDir:  dir,
+		CryptoType: CryptoTypeSha256Xor,
+	})
+	require.NoError(t, err)
+	_, err = s.SetTransactionAnnotation("t.wlt", txid, Annotation{})
+	require.Equal(t, ErrWalletAPIDisabled, err)
+}
+
+func TestServiceCryptoUpgrade(t *testing.T) {
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	// Create encrypted wallets with an older crypto type
+	s, err := NewService(Config{
+		WalletDir:       dir,
+		CryptoType:      CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	for _, name := range []string{"a.wlt", "b.wlt", "c.wlt"} {
+		_, err = s.CreateWallet(name, Options{
+			Seed:     name,
+			Encrypt:  true,
+			Password: []byte("pwd"),
+		}, nil)
+		require.NoError(t, err)
+	}
+
+	argon2id := encrypt.Argon2idChacha20poly1305{
+		Time:    1,
+		Memory:  64,
+		Threads: 1,
+		KeyLen:  32,
+	}
+
+	_, err = NewService(Config{
+		WalletDir:       dir,
+		CryptoType:      CryptoTypeArgon2idChacha20poly1305,
+		Argon2id:        encrypt.Argon2idChacha20poly1305{Time: 1},
+		EnableWalletAPI: true,
+		EnableSeedAPI:   true,
+	})
+	require.Error(t, err)
+
+	s, err = NewService(Config{
+		WalletDir:       dir,
+		CryptoType:      CryptoTypeArgon2idChacha20poly1305,
+		Argon2id:        argon2id,
+		EnableWalletAPI: true,
+		EnableSeedAPI:   true,
+	})
+	require.NoError(t, err)
+
+	requireCrypto := func(s *Service, wltID string, ct CryptoType) {
+		w, err := s.GetWallet(wltID)
+		require.NoError(t, err)
+		require.Equal(t, ct, w.cryptoType())
+		checkNoSensitiveData(t, w)
+
+		// The wallet file is updated too
+		w, err = Load(filepath.Join(dir, wltID))
+		require.NoError(t, err)
+		require.Equal(t, ct, w.cryptoType())
+
+		if ct == CryptoTypeArgon2idChacha20poly1305 {
+			p, err := w.KDFParams()
+			require.NoError(t, err)
+			require.Equal(t, &argon2id, p.Argon2id)
+		}
+	}
+
+	// A wrong password does not upgrade the wallet
+	_, err = s.GetWalletSeed("a.wlt", []byte("wrong"))
+	require.Equal(t, ErrInvalidPassword, err)
+	requireCrypto(s, "a.wlt", CryptoTypeSha256Xor)
+
+	// Viewing the secrets upgrades the wallet
+	seed, err := s.GetWalletSeed("a.wlt", []byte("pwd"))
+	require.NoError(t, err)
+	require.Equal(t, "a.wlt", seed)
+	requireCrypto(s, "a.wlt", CryptoTypeArgon2idChacha20poly1305)
+
+	seed, err = s.GetWalletSeed("a.wlt", []byte("pwd"))
+	require.NoError(t, err)
+	require.Equal(t, "a.wlt", seed)
+
+	// Updating the secrets upgrades the wallet
+	_, err = s.NewAddresses("b.wlt", []byte("pwd"), 1)
+	require.NoError(t, err)
+	requireCrypto(s, "b.wlt", CryptoTypeArgon2idChacha20poly1305)
+
+	w, err := s.GetWallet("b.wlt")
+	require.NoError(t, err)
+	require.Len(t, w.Entries, 2)
+
+	// The wallets are not upgraded if the service does not use argon2id
+	s, err = NewService(Config{
+		WalletDir:       dir,
+		CryptoType:      CryptoTypeScryptChacha20poly1305,
+		EnableWalletAPI: true,
+		EnableSeedAPI:   true,
+	})
+	require.NoError(t, err)
+
+	_, err = s.GetWalletSeed("c.wlt", []byte("pwd"))
+	require.NoError(t, err)
+	requireCrypto(s, "c.wlt", CryptoTypeSha256Xor)
+
+	// Upgraded wallets keep their crypto type
+	_, err = s.GetWalletSeed("a.wlt", []byte("pwd"))
+	require.NoError(t, err)
+	requireCrypto(s, "a.wlt", CryptoTypeArgon2idChacha20poly1305)
+}
+
 func TestServiceEncryptWallet(t *testing.T) {
 	tt := []struct {
 		name             string
@@ -2630,6 +2849,276 @@ func TestGetWalletSeed(t *testing.T) {
 	}
 }
 
+func TestServiceRecoverWalletSeedPassphrase(t *testing.T) {
+	mnemonic := "legal winner thank year wave sausage worth useful legal winner thank yellow"
+
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+	s, err := NewService(Config{
+		WalletDir:       dir,
+		CryptoType:      CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	w, err := s.CreateWallet("wallet.wlt", Options{
+		Seed:           mnemonic,
+		SeedPassphrase: "pass",
+		Label:          "label",
+		Encrypt:        true,
+		Password:       []byte("pwd"),
+		GenerateN:      3,
+	}, nil)
+	require.NoError(t, err)
+
+	// The passphrase is part of the seed
+	_, err = s.RecoverWallet("wallet.wlt", mnemonic, "", nil)
+	require.Equal(t, ErrWalletRecoverSeedWrong, err)
+	_, err = s.RecoverWallet("wallet.wlt", mnemonic, "wrong", nil)
+	require.Equal(t, ErrWalletRecoverSeedWrong, err)
+
+	w2, err := s.RecoverWallet("wallet.wlt", mnemonic, "pass", nil)
+	require.NoError(t, err)
+	require.False(t, w2.IsEncrypted())
+	require.Equal(t, "pass", w2.seedPassphrase())
+	require.Len(t, w2.Entries, 3)
+	for i := range w.Entries {
+		require.Equal(t, w.Entries[i].Address, w2.Entries[i].Address)
+	}
+}
+
+func TestServiceCreateWatchOnlyWallet(t *testing.T) {
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+	s, err := NewService(Config{
+		WalletDir:       dir,
+		CryptoType:      CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	addrs := []cipher.Address{testutil.MakeAddress(), testutil.MakeAddress()}
+
+	w, err := s.CreateWatchOnlyWallet("watch.wlt", "label", addrs[:1])
+	require.NoError(t, err)
+	require.True(t, w.IsWatchOnly())
+
+	_, err = s.CreateWatchOnlyWallet("watch.wlt", "label", addrs[:1])
+	require.Equal(t, ErrWalletNameConflict, err)
+
+	// A wallet with the same addresses can be watched by several watch-only wallets
+	w2, err := s.CreateWatchOnlyWallet("", "label", addrs[:1])
+	require.NoError(t, err)
+	require.NotEqual(t, w.Filename(), w2.Filename())
+
+	// Addresses can be added to watch-only wallets only
+	sw, err := s.CreateWallet("seed.wlt", Options{
+		Seed: "seed",
+	}, nil)
+	require.NoError(t, err)
+	_, err = s.AddWatchOnlyAddresses(sw.Filename(), addrs)
+	require.Equal(t, ErrWalletNotWatchOnly, err)
+	_, err = s.AddWatchOnlyAddresses("foo.wlt", addrs)
+	require.Equal(t, ErrWalletNotExist, err)
+
+	w, err = s.AddWatchOnlyAddresses("watch.wlt", addrs)
+	require.NoError(t, err)
+	require.Len(t, w.Entries, 2)
+
+	// Watch-only wallets are reloaded from disk
+	s, err = NewService(Config{
+		WalletDir:       dir,
+		CryptoType:      CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+	w, err = s.GetWallet("watch.wlt")
+	require.NoError(t, err)
+	require.True(t, w.IsWatchOnly())
+	wltAddrs, err := w.GetSkycoinAddresses()
+	require.NoError(t, err)
+	require.Equal(t, addrs, wltAddrs)
+
+	// Multisig addresses can be added to watch-only wallets only
+	_, ms := makeMultisigWallets(t)
+	_, err = s.AddMultisig(sw.Filename(), ms)
+	require.Equal(t, ErrWalletNotWatchOnly, err)
+	_, err = s.AddMultisig("foo.wlt", ms)
+	require.Equal(t, ErrWalletNotExist, err)
+
+	w, err = s.AddMultisig("watch.wlt", ms)
+	require.NoError(t, err)
+	require.Len(t, w.Entries, 3)
+	require.Equal(t, []cipher.Multisig{ms}, w.Multisigs)
+
+	w, err = s.GetWallet("watch.wlt")
+	require.NoError(t, err)
+	require.Equal(t, []cipher.Multisig{ms}, w.Multisigs)
+
+	// Disabled wallet API
+	s, err = NewService(Config{
+		WalletDir:  dir,
+		CryptoType: CryptoTypeSha256Xor,
+	})
+	require.NoError(t, err)
+	_, err = s.CreateWatchOnlyWallet("", "label", addrs)
+	require.Equal(t, ErrWalletAPIDisabled, err)
+	_, err = s.AddWatchOnlyAddresses("watch.wlt", addrs)
+	require.Equal(t, ErrWalletAPIDisabled, err)
+	_, err = s.AddMultisig("watch.wlt", ms)
+	require.Equal(t, ErrWalletAPIDisabled, err)
+}
+
+func TestServiceSignPartialTransaction(t *testing.T) {
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+	s, err := NewService(Config{
+		WalletDir:       dir,
+		CryptoType:      CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	_, err = s.CreateWallet("t.wlt", Options{
+		Seed:     "seed",
+		Encrypt:  true,
+		Password: []byte("pwd"),
+	}, nil)
+	require.NoError(t, err)
+
+	_, keys := makePartialTransactionWallet(t, "seed")
+	ptx := makePartialTransaction(t, coin.UxArray{makeUxOut(t, keys[0], 1e6, 10)})
+
+	_, err = s.SignPartialTransaction("t.wlt", nil, ptx)
+	require.Equal(t, ErrMissingPassword, err)
+
+	_, err = s.SignPartialTransaction("t.wlt", []byte("wrong"), ptx)
+	require.Equal(t, ErrInvalidPassword, err)
+
+	_, err = s.SignPartialTransaction("foo.wlt", []byte("pwd"), ptx)
+	require.Equal(t, ErrWalletNotExist, err)
+
+	signed, err := s.SignPartialTransaction("t.wlt", []byte("pwd"), ptx)
+	require.NoError(t, err)
+	require.True(t, signed.IsFullySigned())
+	require.False(t, ptx.IsFullySigned())
+
+	// The transaction must comply with the spending policy of the wallet
+	_, err = s.SetSpendingPolicy("t.wlt", []byte("pwd"), SpendingPolicy{
+		AllowedDestinations: []cipher.Address{testutil.MakeAddress()},
+	})
+	require.NoError(t, err)
+	_, err = s.SignPartialTransaction("t.wlt", []byte("pwd"), ptx)
+	require.Equal(t, ErrSpendingPolicyDestination, err)
+
+	_, err = s.SetSpendingPolicy("t.wlt", []byte("pwd"), SpendingPolicy{
+		MaxTransactionCoins: ptx.Transaction.Out[0].Coins - 1,
+	})
+	require.NoError(t, err)
+	_, err = s.SignPartialTransaction("t.wlt", []byte("pwd"), ptx)
+	require.Equal(t, ErrSpendingPolicyMaxTransactionCoins, err)
+
+	_, err = s.SetSpendingPolicy("t.wlt", []byte("pwd"), SpendingPolicy{
+		DailyLimitCoins: ptx.Transaction.Out[0].Coins,
+	})
+	require.NoError(t, err)
+	signed, err = s.SignPartialTransaction("t.wlt", []byte("pwd"), ptx)
+	require.NoError(t, err)
+	require.True(t, signed.IsFullySigned())
+
+	// The spend of the signed transaction is recorded for the daily limit, once
+	signed, err = s.SignPartialTransaction("t.wlt", []byte("pwd"), ptx)
+	require.NoError(t, err)
+	require.True(t, signed.IsFullySigned())
+	w, err := s.GetWallet("t.wlt")
+	require.NoError(t, err)
+	require.Len(t, w.Spends, 1)
+	require.Equal(t, ptx.Transaction.HashInner(), w.Spends[0].InnerHash)
+
+	ptx2 := makePartialTransaction(t, coin.UxArray{makeUxOut(t, keys[0], 1e6, 10)})
+	_, err = s.SignPartialTransaction("t.wlt", []byte("pwd"), ptx2)
+	require.Equal(t, ErrSpendingPolicyDailyLimit, err)
+
+	// Disabled wallet API
+	s, err = NewService(Config{
+		WalletDir:  dir,
+		CryptoType: CryptoTypeSha256Xor,
+	})
+	require.NoError(t, err)
+	_, err = s.SignPartialTransaction("t.wlt", []byte("pwd"), ptx)
+	require.Equal(t, ErrWalletAPIDisabled, err)
+}
+
+func TestServiceCosignPartialTransaction(t *testing.T) {
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+	s, err := NewService(Config{
+		WalletDir:       dir,
+		CryptoType:      CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	_, err = s.CreateWallet("t.wlt", Options{
+		Seed:     "seed",
+		Encrypt:  true,
+		Password: []byte("pwd"),
+	}, nil)
+	require.NoError(t, err)
+
+	_, keys := makePartialTransactionWallet(t, "seed")
+	w2, keys2 := makePartialTransactionWallet(t, "seed2")
+	addr2 := cipher.MustAddressFromSecKey(keys2[0])
+
+	_, err = s.SetCosigners("foo.wlt", nil)
+	require.Equal(t, ErrWalletNotExist, err)
+
+	_, err = s.SetCosigners("t.wlt", Cosigners{{Label: "bob"}})
+	require.Equal(t, ErrCosignerNoAddresses, err)
+
+	w, err := s.SetCosigners("t.wlt", Cosigners{{Label: "bob", Addresses: []cipher.Address{addr2}}})
+	require.NoError(t, err)
+	require.Equal(t, Cosigners{{Label: "bob", Addresses: []cipher.Address{addr2}}}, w.Cosigners)
+
+	ptx := makePartialTransaction(t, coin.UxArray{
+		makeUxOut(t, keys[0], 1e6, 10),
+		makeUxOut(t, keys2[0], 2e6, 10),
+	})
+
+	// The wallet signs its input, the cosigner has not signed yet
+	_, _, err = s.CosignPartialTransaction("t.wlt", nil, ptx)
+	require.Equal(t, ErrMissingPassword, err)
+
+	signed, status, err := s.CosignPartialTransaction("t.wlt", []byte("pwd"), ptx)
+	require.NoError(t, err)
+	require.Equal(t, []cipher.Address{addr2}, signed.UnsignedAddresses())
+	require.Len(t, status, 1)
+	require.Equal(t, 1, status[0].Inputs)
+	require.False(t, status[0].Signed())
+
+	// The wallet has no inputs left to sign, the password is not needed to report the cosigners' status
+	_, err = w2.SignPartialTransaction(signed)
+	require.NoError(t, err)
+	signed, status, err = s.CosignPartialTransaction("t.wlt", nil, signed)
+	require.NoError(t, err)
+	require.True(t, signed.IsFullySigned())
+	require.True(t, status[0].Signed())
+
+	// The wallet is checked and signs under a single lock, which is released
+	require.Empty(t, s.locks)
+
+	// Disabled wallet API
+	s, err = NewService(Config{
+		WalletDir:  dir,
+		CryptoType: CryptoTypeSha256Xor,
+	})
+	require.NoError(t, err)
+	_, _, err = s.CosignPartialTransaction("t.wlt", []byte("pwd"), ptx)
+	require.Equal(t, ErrWalletAPIDisabled, err)
+	_, err = s.SetCosigners("t.wlt", nil)
+	require.Equal(t, ErrWalletAPIDisabled, err)
+}
+
 func TestServiceView(t *testing.T) {
 	tt := []struct {
 		name             string
@@ -3229,6 +3718,156 @@ func TestServiceUpdateSecrets(t *testing.T) {
 	}
 }
 
+func TestServiceWalletLocking(t *testing.T) {
+	dir := prepareWltDir()
+	s, err := NewService(Config{
+		WalletDir:       dir,
+		CryptoType:      CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	_, err = s.CreateWallet("t1.wlt", Options{
+		Seed:  "seed1",
+		Label: "label1",
+	}, nil)
+	require.NoError(t, err)
+
+	w2, err := s.CreateWallet("t2.wlt", Options{
+		Seed:  "seed2",
+		Label: "label2",
+	}, nil)
+	require.NoError(t, err)
+
+	// Block an update of t1.wlt
+	updating := make(chan struct{})
+	release := make(chan struct{})
+	updateDone := make(chan error, 1)
+	go func() {
+		updateDone <- s.Update("t1.wlt", func(w *Wallet) error {
+			w.setLabel("label1-updated")
+			close(updating)
+			<-release
+			return nil
+		})
+	}()
+
+	select {
+	case <-updating:
+	case <-time.After(5 * time.Second):
+		t.Fatal("update of t1.wlt did not start")
+	}
+
+	// The other wallets are not blocked by the update of t1.wlt
+	otherDone := make(chan struct{})
+	go func() {
+		defer close(otherDone)
+
+		err := s.View("t2.wlt", func(w *Wallet) error {
+			require.Equal(t, "label2", w.Label())
+			return nil
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, s.UpdateWalletLabel("t2.wlt", "label2-updated"))
+
+		_, err = s.CreateWallet("t3.wlt", Options{
+			Seed: "seed3",
+		}, nil)
+		require.NoError(t, err)
+
+		// The snapshot of t1.wlt can be read, without the pending update
+		w, err := s.GetWallet("t1.wlt")
+		require.NoError(t, err)
+		require.Equal(t, "label1", w.Label())
+
+		wlts, err := s.GetWallets()
+		require.NoError(t, err)
+		require.Len(t, wlts, 3)
+	}()
+
+	select {
+	case <-otherDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("operations on other wallets were blocked by the update of t1.wlt")
+	}
+
+	// Viewing t1.wlt waits for its update
+	viewLabel := make(chan string, 1)
+	go func() {
+		err := s.View("t1.wlt", func(w *Wallet) error {
+			viewLabel <- w.Label()
+			return nil
+		})
+		require.NoError(t, err)
+	}()
+
+	select {
+	case <-viewLabel:
+		t.Fatal("view of t1.wlt did not wait for its update")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	require.NoError(t, <-updateDone)
+
+	select {
+	case label := <-viewLabel:
+		require.Equal(t, "label1-updated", label)
+	case <-time.After(5 * time.Second):
+		t.Fatal("view of t1.wlt did not complete after its update")
+	}
+
+	w, err := s.GetWallet("t2.wlt")
+	require.NoError(t, err)
+	require.Equal(t, "label2-updated", w.Label())
+	require.Equal(t, w2.Entries, w.Entries)
+
+	// The locks are removed once they are released, including the locks of wallets that don't exist
+	for i := 0; i < 10; i++ {
+		err := s.View(fmt.Sprintf("missing%d.wlt", i), func(*Wallet) error {
+			return nil
+		})
+		require.Equal(t, ErrWalletNotExist, err)
+	}
+	require.NoError(t, s.UpdateWalletLabel("t1.wlt", "label1-updated-again"))
+	require.NoError(t, s.Remove("t3.wlt"))
+
+	s.RLock()
+	require.Empty(t, s.locks)
+	s.RUnlock()
+}
+
+func TestServiceAddressWallets(t *testing.T) {
+	dir := prepareWltDir()
+	s, err := NewService(Config{
+		WalletDir:       dir,
+		CryptoType:      CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	w1, err := s.CreateWallet("t1.wlt", Options{
+		Seed:      "seed1",
+		GenerateN: 2,
+	}, nil)
+	require.NoError(t, err)
+	addrs1, err := w1.GetSkycoinAddresses()
+	require.NoError(t, err)
+
+	// A watch-only wallet watches an address of t1.wlt
+	_, err = s.CreateWatchOnlyWallet("t0.wlt", "", []cipher.Address{addrs1[1]})
+	require.NoError(t, err)
+
+	other := testutil.MakeAddress()
+	require.Equal(t, map[cipher.Address][]string{
+		addrs1[0]: {"t1.wlt"},
+		addrs1[1]: {"t0.wlt", "t1.wlt"},
+	}, s.AddressWallets([]cipher.Address{addrs1[0], other, addrs1[1], addrs1[0]}))
+
+	require.Empty(t, s.AddressWallets([]cipher.Address{other}))
+}
+
 func makeUxOut(t *testing.T, s cipher.SecKey, coins, hours uint64) coin.UxOut { // nolint: unparam
 	body := makeUxBody(t, s, coins, hours)
 	tm := rand.Int31n(1000)
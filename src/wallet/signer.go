@@ -0,0 +1,238 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+// DefaultHardwareSignerScanN is the number of addresses of a hardware device that a HardwareSigner
+// derives at most, looking for the owners of the inputs to sign
+const DefaultHardwareSignerScanN = 100
+
+var (
+	// ErrHardwareDeviceNotRegistered is returned when opening a hardware device whose driver is not registered
+	ErrHardwareDeviceNotRegistered = NewError(errors.New("hardware device is not registered"))
+	// ErrHardwareDeviceAddressMismatch is returned if a hardware device confirms an address that differs from the address it derived
+	ErrHardwareDeviceAddressMismatch = NewError(errors.New("hardware device confirmed a different address"))
+	// ErrAddressNotInHardwareDevice is returned when confirming an address that the hardware device does not derive
+	ErrAddressNotInHardwareDevice = NewError(errors.New("address is not derived by the hardware device"))
+	// ErrSignerNotFullySigned is returned if a Signer did not sign all the inputs of a transaction
+	ErrSignerNotFullySigned = NewError(errors.New("signer did not sign all the inputs of the transaction"))
+	// ErrPasswordSigner is returned when a password is provided to create a transaction signed by a Signer
+	ErrPasswordSigner = NewError(errors.New("password must not be provided for transactions signed by a signer"))
+	// ErrUnsignedSigner is returned when creating an unsigned transaction with a Signer
+	ErrUnsignedSigner = NewError(errors.New("unsigned transactions can not have a signer"))
+)
+
+// Signer signs the inputs of partial transactions.
+// *Wallet is a Signer which signs with the keys in memory, HardwareSigner signs on a hardware device,
+// whose keys never leave the device.
+type Signer interface {
+	// SignPartialTransaction signs the unsigned inputs of a partial transaction that are owned by the signer,
+	// and returns the number of inputs signed
+	SignPartialTransaction(ptx *PartialTransaction) (int, error)
+}
+
+// HardwareDevice is a hardware wallet device, such as a device connected over USB/HID.
+// The keys of a device are derived on the device by their index, and they never leave the device.
+// Device drivers implement HardwareDevice and are registered with RegisterHardwareDevice.
+type HardwareDevice interface {
+	// Address returns the address of the key at index n. If confirm is true, the device displays
+	// the address and the user confirms it on the device.
+	Address(n uint32, confirm bool) (cipher.Address, error)
+	// SignHash signs a hash with the key at index n. The device may ask the user to confirm the signature.
+	SignHash(n uint32, hash cipher.SHA256) (cipher.Sig, error)
+	// Close releases the device
+	Close() error
+}
+
+// HardwareSigner is a Signer that signs each input of a transaction on a hardware device
+type HardwareSigner struct {
+	device  HardwareDevice
+	scanN   uint32
+	addrs   []cipher.Address
+	indexes map[cipher.Address]uint32
+}
+
+// NewHardwareSigner creates a HardwareSigner for a device. Up to scanN addresses of the device are derived,
+// looking for the owners of the inputs to sign.
+func NewHardwareSigner(device HardwareDevice, scanN uint32) *HardwareSigner {
+	return &HardwareSigner{
+		device:  device,
+		scanN:   scanN,
+		indexes: make(map[cipher.Address]uint32),
+	}
+}
+
+// Addresses derives the first n addresses of the device, without confirming them on the device
+func (s *HardwareSigner) Addresses(n uint32) ([]cipher.Address, error) {
+	for i := uint32(len(s.addrs)); i < n; i++ {
+		if _, err := s.derive(); err != nil {
+			return nil, err
+		}
+	}
+
+	addrs := make([]cipher.Address, n)
+	copy(addrs, s.addrs)
+	return addrs, nil
+}
+
+// ConfirmAddress displays an address on the device for the user to confirm it,
+// and checks that the device confirmed the address it derived.
+// ErrAddressNotInHardwareDevice is returned if the address is not in the first scanN addresses of the device.
+func (s *HardwareSigner) ConfirmAddress(addr cipher.Address) error {
+	n, ok, err := s.index(addr)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrAddressNotInHardwareDevice
+	}
+
+	confirmed, err := s.device.Address(n, true)
+	if err != nil {
+		return err
+	}
+
+	if confirmed != addr {
+		return ErrHardwareDeviceAddressMismatch
+	}
+
+	return nil
+}
+
+// SignPartialTransaction signs the unsigned inputs of a partial transaction that are owned by the device,
+// one input at a time, and returns the number of inputs signed. ErrNoInputsToSign is returned if the device owns none of them.
+// The signatures returned by the device are verified.
+func (s *HardwareSigner) SignPartialTransaction(ptx *PartialTransaction) (int, error) {
+	if err := ptx.Verify(); err != nil {
+		return 0, err
+	}
+
+	sigs := make([]cipher.Sig, len(ptx.Transaction.Sigs))
+	copy(sigs, ptx.Transaction.Sigs)
+
+	ws := ptx.multisigWitnesses()
+	n := 0
+	for i, ux := range ptx.Inputs {
+		// The device derives the addresses of single keys, it does not sign the inputs of multisig addresses
+		if _, ok := ws[i]; ok || !sigs[i].Null() {
+			continue
+		}
+
+		addr := ux.Body.Address
+		idx, ok, err := s.index(addr)
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			continue
+		}
+
+		h := cipher.AddSHA256(ptx.Transaction.InnerHash, ptx.Transaction.In[i])
+		sig, err := s.device.SignHash(idx, h)
+		if err != nil {
+			return 0, err
+		}
+
+		if err := cipher.VerifyAddressSignedHash(addr, sig, h); err != nil {
+			return 0, NewError(fmt.Errorf("hardware device signature of input %d is invalid: %v", i, err))
+		}
+
+		sigs[i] = sig
+		n++
+	}
+
+	if n == 0 {
+		return 0, ErrNoInputsToSign
+	}
+
+	ptx.Transaction.Sigs = sigs
+	return n, nil
+}
+
+// index returns the index of an address in the device, deriving addresses until it is found or scanN addresses are derived
+func (s *HardwareSigner) index(addr cipher.Address) (uint32, bool, error) {
+	if n, ok := s.indexes[addr]; ok {
+		return n, true, nil
+	}
+
+	for uint32(len(s.addrs)) < s.scanN {
+		a, err := s.derive()
+		if err != nil {
+			return 0, false, err
+		}
+
+		if a == addr {
+			return uint32(len(s.addrs) - 1), true, nil
+		}
+	}
+
+	return 0, false, nil
+}
+
+// derive derives the next address of the device
+func (s *HardwareSigner) derive() (cipher.Address, error) {
+	n := uint32(len(s.addrs))
+	a, err := s.device.Address(n, false)
+	if err != nil {
+		return cipher.Address{}, err
+	}
+
+	s.addrs = append(s.addrs, a)
+	s.indexes[a] = n
+	return a, nil
+}
+
+var (
+	hardwareDevicesLock sync.Mutex
+	hardwareDevices     = make(map[string]func() (HardwareDevice, error))
+)
+
+// RegisterHardwareDevice registers a hardware device driver under a name. open connects to the device.
+// It panics if a driver is registered twice with the same name.
+func RegisterHardwareDevice(name string, open func() (HardwareDevice, error)) {
+	hardwareDevicesLock.Lock()
+	defer hardwareDevicesLock.Unlock()
+
+	if open == nil {
+		logger.Panic("RegisterHardwareDevice open is nil")
+	}
+
+	if _, ok := hardwareDevices[name]; ok {
+		logger.Panicf("RegisterHardwareDevice called twice for device %q", name)
+	}
+
+	hardwareDevices[name] = open
+}
+
+// OpenHardwareDevice connects to a registered hardware device
+func OpenHardwareDevice(name string) (HardwareDevice, error) {
+	hardwareDevicesLock.Lock()
+	open, ok := hardwareDevices[name]
+	hardwareDevicesLock.Unlock()
+
+	if !ok {
+		return nil, ErrHardwareDeviceNotRegistered
+	}
+
+	return open()
+}
+
+// HardwareDevices returns the names of the registered hardware devices, sorted
+func HardwareDevices() []string {
+	hardwareDevicesLock.Lock()
+	defer hardwareDevicesLock.Unlock()
+
+	names := make([]string, 0, len(hardwareDevices))
+	for name := range hardwareDevices {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
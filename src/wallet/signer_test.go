@@ -0,0 +1,193 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/testutil"
+)
+
+// fakeHardwareDevice is a HardwareDevice whose keys are in memory
+type fakeHardwareDevice struct {
+	keys      []cipher.SecKey
+	derived   int
+	confirmed []uint32
+	signed    []uint32
+	// badConfirm confirms a different address than the derived address
+	badConfirm bool
+	// badSig signs with the wrong key
+	badSig bool
+	closed bool
+}
+
+func newFakeHardwareDevice(seed string, n int) *fakeHardwareDevice {
+	_, keys := cipher.MustGenerateDeterministicKeyPairsSeed([]byte(seed), n)
+	return &fakeHardwareDevice{
+		keys: keys,
+	}
+}
+
+func (d *fakeHardwareDevice) Address(n uint32, confirm bool) (cipher.Address, error) {
+	if int(n) >= len(d.keys) {
+		return cipher.Address{}, errors.New("key index out of range")
+	}
+
+	if !confirm {
+		d.derived++
+		return cipher.MustAddressFromSecKey(d.keys[n]), nil
+	}
+
+	d.confirmed = append(d.confirmed, n)
+	if d.badConfirm {
+		return testutil.MakeAddress(), nil
+	}
+	return cipher.MustAddressFromSecKey(d.keys[n]), nil
+}
+
+func (d *fakeHardwareDevice) SignHash(n uint32, hash cipher.SHA256) (cipher.Sig, error) {
+	d.signed = append(d.signed, n)
+	key := d.keys[n]
+	if d.badSig {
+		key = d.keys[(int(n)+1)%len(d.keys)]
+	}
+	return cipher.SignHash(hash, key)
+}
+
+func (d *fakeHardwareDevice) Close() error {
+	d.closed = true
+	return nil
+}
+
+func TestHardwareSignerSignPartialTransaction(t *testing.T) {
+	device := newFakeHardwareDevice("device", 10)
+	_, otherKeys := cipher.MustGenerateDeterministicKeyPairsSeed([]byte("other"), 1)
+
+	uxouts := coin.UxArray{
+		makeUxOut(t, device.keys[3], 1e6, 10),
+		makeUxOut(t, otherKeys[0], 2e6, 10),
+		makeUxOut(t, device.keys[0], 3e6, 10),
+	}
+	ptx := makePartialTransaction(t, uxouts)
+
+	// The device signs the inputs it owns, one at a time
+	signed := *ptx
+	s := NewHardwareSigner(device, 5)
+	n, err := s.SignPartialTransaction(&signed)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.Equal(t, []uint32{3, 0}, device.signed)
+	require.Equal(t, []cipher.Address{uxouts[1].Body.Address}, signed.UnsignedAddresses())
+
+	// Addresses are derived once, up to the owner of the last input, then all scanN addresses are derived
+	// looking for the owner of the input that the device does not own
+	require.Equal(t, 5, device.derived)
+
+	// The original partial transaction is not modified
+	require.Equal(t, make([]cipher.Sig, len(uxouts)), ptx.Transaction.Sigs)
+
+	// Addresses beyond scanN are not found
+	far := makePartialTransaction(t, coin.UxArray{makeUxOut(t, device.keys[7], 1e6, 10)})
+	_, err = s.SignPartialTransaction(far)
+	require.Equal(t, ErrNoInputsToSign, err)
+
+	_, err = NewHardwareSigner(device, 10).SignPartialTransaction(far)
+	require.NoError(t, err)
+	require.True(t, far.IsFullySigned())
+	require.NoError(t, far.Transaction.Verify())
+
+	// Invalid signatures of the device are rejected
+	device.badSig = true
+	_, err = NewHardwareSigner(device, 5).SignPartialTransaction(ptx)
+	require.Error(t, err)
+	require.Equal(t, make([]cipher.Sig, len(uxouts)), ptx.Transaction.Sigs)
+}
+
+func TestHardwareSignerAddresses(t *testing.T) {
+	device := newFakeHardwareDevice("device", 10)
+	s := NewHardwareSigner(device, 5)
+
+	addrs, err := s.Addresses(3)
+	require.NoError(t, err)
+	require.Equal(t, []cipher.Address{
+		cipher.MustAddressFromSecKey(device.keys[0]),
+		cipher.MustAddressFromSecKey(device.keys[1]),
+		cipher.MustAddressFromSecKey(device.keys[2]),
+	}, addrs)
+
+	// Derived addresses are cached
+	_, err = s.Addresses(2)
+	require.NoError(t, err)
+	require.Equal(t, 3, device.derived)
+
+	require.NoError(t, s.ConfirmAddress(addrs[1]))
+	require.Equal(t, []uint32{1}, device.confirmed)
+
+	err = s.ConfirmAddress(cipher.MustAddressFromSecKey(device.keys[6]))
+	require.Equal(t, ErrAddressNotInHardwareDevice, err)
+
+	device.badConfirm = true
+	err = s.ConfirmAddress(addrs[2])
+	require.Equal(t, ErrHardwareDeviceAddressMismatch, err)
+
+	_, err = NewHardwareSigner(device, 20).Addresses(11)
+	require.Error(t, err)
+}
+
+func TestHardwareDeviceRegistry(t *testing.T) {
+	device := newFakeHardwareDevice("device", 1)
+
+	_, err := OpenHardwareDevice("test-fake")
+	require.Equal(t, ErrHardwareDeviceNotRegistered, err)
+
+	RegisterHardwareDevice("test-fake", func() (HardwareDevice, error) {
+		return device, nil
+	})
+	defer func() {
+		hardwareDevicesLock.Lock()
+		delete(hardwareDevices, "test-fake")
+		hardwareDevicesLock.Unlock()
+	}()
+
+	require.Contains(t, HardwareDevices(), "test-fake")
+
+	d, err := OpenHardwareDevice("test-fake")
+	require.NoError(t, err)
+	require.Equal(t, device, d)
+
+	require.Panics(t, func() {
+		RegisterHardwareDevice("test-fake", func() (HardwareDevice, error) {
+			return device, nil
+		})
+	})
+}
+
+func TestCreateTransactionParamsSigner(t *testing.T) {
+	p := CreateTransactionParams{
+		HoursSelection: HoursSelection{
+			Type: HoursSelectionTypeManual,
+		},
+		Wallet: CreateTransactionWalletParams{
+			ID: "t.wlt",
+		},
+		To: []coin.TransactionOutput{
+			{
+				Address: testutil.MakeAddress(),
+				Coins:   1e6,
+				Hours:   10,
+			},
+		},
+		Signer: NewHardwareSigner(newFakeHardwareDevice("device", 1), 1),
+	}
+	require.NoError(t, p.Validate())
+
+	p.Unsigned = true
+	require.Equal(t, ErrUnsignedSigner, p.Validate())
+
+	p.Unsigned = false
+	p.Wallet.Password = []byte("pwd")
+	require.Equal(t, ErrPasswordSigner, p.Validate())
+}
@@ -0,0 +1,375 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+)
+
+const (
+	// SpendingPolicyDailyPeriod is the period of the daily limit of a SpendingPolicy, a rolling window
+	SpendingPolicyDailyPeriod = 24 * time.Hour
+	// MaxSpendingPolicyDestinations is the maximum number of allowed destinations of a SpendingPolicy
+	MaxSpendingPolicyDestinations = 1000
+)
+
+var (
+	// ErrSpendingPolicyMaxTransactionCoins is returned if a transaction sends more coins than the spending policy allows per transaction
+	ErrSpendingPolicyMaxTransactionCoins = NewError(errors.New("transaction sends more coins than the spending policy of the wallet allows per transaction"))
+	// ErrSpendingPolicyDailyLimit is returned if a transaction would exceed the daily limit of the spending policy
+	ErrSpendingPolicyDailyLimit = NewError(errors.New("transaction exceeds the daily limit of the spending policy of the wallet"))
+	// ErrSpendingPolicyDestination is returned if a transaction sends coins to an address that the spending policy does not allow
+	ErrSpendingPolicyDestination = NewError(errors.New("transaction sends coins to an address that the spending policy of the wallet does not allow"))
+	// ErrSpendingPolicyTooManyDestinations is returned if a spending policy has too many allowed destinations
+	ErrSpendingPolicyTooManyDestinations = NewError(fmt.Errorf("spending policy can't have more than %d allowed destinations", MaxSpendingPolicyDestinations))
+	// ErrSpendingPolicyDuplicateDestination is returned if an allowed destination of a spending policy is duplicated
+	ErrSpendingPolicyDuplicateDestination = NewError(errors.New("spending policy allowed destinations contain duplicates"))
+	// ErrSpendingPolicyNullDestination is returned if an allowed destination of a spending policy is the null address
+	ErrSpendingPolicyNullDestination = NewError(errors.New("spending policy allowed destinations contain the null address"))
+)
+
+// SpendingPolicy limits the coins that a wallet sends to other addresses.
+// Coins sent to the wallet's own addresses, such as change, are not limited.
+// The zero value has no limits.
+type SpendingPolicy struct {
+	// MaxTransactionCoins is the maximum number of droplets sent by a transaction, 0 for no limit
+	MaxTransactionCoins uint64
+	// DailyLimitCoins is the maximum number of droplets sent in SpendingPolicyDailyPeriod, 0 for no limit
+	DailyLimitCoins uint64
+	// AllowedDestinations are the only addresses the wallet can send coins to, if not empty
+	AllowedDestinations []cipher.Address
+}
+
+// Empty returns true if the policy has no limits
+func (p SpendingPolicy) Empty() bool {
+	return p.MaxTransactionCoins == 0 && p.DailyLimitCoins == 0 && len(p.AllowedDestinations) == 0
+}
+
+// Validate checks the allowed destinations of the policy
+func (p SpendingPolicy) Validate() error {
+	if len(p.AllowedDestinations) > MaxSpendingPolicyDestinations {
+		return ErrSpendingPolicyTooManyDestinations
+	}
+
+	seen := make(map[cipher.Address]struct{}, len(p.AllowedDestinations))
+	for _, a := range p.AllowedDestinations {
+		if a.Null() {
+			return ErrSpendingPolicyNullDestination
+		}
+		if _, ok := seen[a]; ok {
+			return ErrSpendingPolicyDuplicateDestination
+		}
+		seen[a] = struct{}{}
+	}
+
+	return nil
+}
+
+func (p SpendingPolicy) clone() SpendingPolicy {
+	c := p
+	c.AllowedDestinations = append([]cipher.Address(nil), p.AllowedDestinations...)
+	if len(c.AllowedDestinations) == 0 {
+		c.AllowedDestinations = nil
+	}
+	return c
+}
+
+// Spend records the coins that a transaction created by a wallet sends to other addresses,
+// to enforce the daily limit of its spending policy.
+// The transaction is identified by its inner hash and its inputs, which don't change when it is signed,
+// so that a transaction is counted once however many times it is signed.
+type Spend struct {
+	Time      int64
+	InnerHash cipher.SHA256
+	Inputs    []cipher.SHA256
+	Coins     uint64
+}
+
+// conflicts returns true if the spend is of the same transaction as s,
+// or of a transaction spending the same outputs, of which only one can be confirmed
+func (s Spend) conflicts(t Spend) bool {
+	if s.InnerHash == t.InnerHash {
+		return true
+	}
+
+	if len(s.Inputs) != len(t.Inputs) || len(s.Inputs) == 0 {
+		return false
+	}
+
+	inputs := make(map[cipher.SHA256]struct{}, len(s.Inputs))
+	for _, h := range s.Inputs {
+		inputs[h] = struct{}{}
+	}
+	for _, h := range t.Inputs {
+		if _, ok := inputs[h]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// SetSpendingPolicy sets the spending policy of the wallet, the zero policy removes it.
+// The recorded spends are kept, so that removing the daily limit and setting it again does not reset it.
+func (w *Wallet) SetSpendingPolicy(p SpendingPolicy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	w.SpendingPolicy = p.clone()
+	return nil
+}
+
+// DailySpent returns the coins sent by the recorded spends within SpendingPolicyDailyPeriod before now
+func (w *Wallet) DailySpent(now time.Time) (uint64, error) {
+	since := now.Add(-SpendingPolicyDailyPeriod).Unix()
+
+	var coins uint64
+	for _, s := range w.Spends {
+		if s.Time <= since {
+			continue
+		}
+
+		var err error
+		coins, err = coin.AddUint64(coins, s.Coins)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return coins, nil
+}
+
+// transactionSpend returns the coins that a transaction sends to addresses that are not in the wallet
+// and checks that these addresses are allowed by the spending policy
+func (w *Wallet) transactionSpend(txn coin.Transaction) (uint64, error) {
+	var allowed map[cipher.Address]struct{}
+	if len(w.SpendingPolicy.AllowedDestinations) != 0 {
+		allowed = make(map[cipher.Address]struct{}, len(w.SpendingPolicy.AllowedDestinations))
+		for _, a := range w.SpendingPolicy.AllowedDestinations {
+			allowed[a] = struct{}{}
+		}
+	}
+
+	var coins uint64
+	for _, o := range txn.Out {
+		if _, ok := w.GetEntry(o.Address); ok {
+			continue
+		}
+
+		if allowed != nil {
+			if _, ok := allowed[o.Address]; !ok {
+				return 0, ErrSpendingPolicyDestination
+			}
+		}
+
+		var err error
+		coins, err = coin.AddUint64(coins, o.Coins)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return coins, nil
+}
+
+// EnforceSpendingPolicy checks that transactions created by the wallet comply with its spending policy.
+// If the policy has a daily limit, the spends of the transactions are recorded,
+// and the spends older than SpendingPolicyDailyPeriod are dropped.
+// A transaction whose spend is already recorded is not counted again. A transaction spending the same outputs
+// as a recorded one replaces it, since only one of them can be confirmed, and only its coins above the recorded ones are counted.
+func (w *Wallet) EnforceSpendingPolicy(txns []coin.Transaction, now time.Time) error {
+	if w.SpendingPolicy.Empty() {
+		return nil
+	}
+
+	spends, err := w.checkSpendingPolicy(txns, now)
+	if err != nil {
+		return err
+	}
+
+	if w.SpendingPolicy.DailyLimitCoins == 0 {
+		return nil
+	}
+
+	w.Spends = spends
+	return nil
+}
+
+// CheckSpendingPolicy checks that transactions created by the wallet comply with its spending policy, like EnforceSpendingPolicy,
+// without recording their spends. It is used for the transactions created unsigned, whose spends are recorded when they are signed.
+func (w *Wallet) CheckSpendingPolicy(txns []coin.Transaction, now time.Time) error {
+	if w.SpendingPolicy.Empty() {
+		return nil
+	}
+
+	_, err := w.checkSpendingPolicy(txns, now)
+	return err
+}
+
+// checkSpendingPolicy checks transactions against the spending policy
+// and returns the spends within SpendingPolicyDailyPeriod before now, with the spends of the transactions
+func (w *Wallet) checkSpendingPolicy(txns []coin.Transaction, now time.Time) ([]Spend, error) {
+	p := w.SpendingPolicy
+	since := now.Add(-SpendingPolicyDailyPeriod).Unix()
+
+	var spends []Spend
+	var dailySpent uint64
+	for _, s := range w.Spends {
+		if s.Time <= since {
+			continue
+		}
+
+		var err error
+		dailySpent, err = coin.AddUint64(dailySpent, s.Coins)
+		if err != nil {
+			return nil, err
+		}
+		spends = append(spends, s)
+	}
+
+	for _, txn := range txns {
+		coins, err := w.transactionSpend(txn)
+		if err != nil {
+			return nil, err
+		}
+
+		if p.MaxTransactionCoins != 0 && coins > p.MaxTransactionCoins {
+			return nil, ErrSpendingPolicyMaxTransactionCoins
+		}
+
+		spend := Spend{
+			Time:      now.Unix(),
+			InnerHash: txn.HashInner(),
+			Inputs:    append([]cipher.SHA256(nil), txn.In...),
+			Coins:     coins,
+		}
+
+		replaced := -1
+		for i, s := range spends {
+			if s.conflicts(spend) {
+				replaced = i
+				break
+			}
+		}
+
+		if replaced != -1 {
+			s := spends[replaced]
+			if s.InnerHash == spend.InnerHash || s.Coins >= spend.Coins {
+				continue
+			}
+
+			dailySpent -= s.Coins
+			spends = append(spends[:replaced], spends[replaced+1:]...)
+		}
+
+		dailySpent, err = coin.AddUint64(dailySpent, coins)
+		if err != nil {
+			return nil, ErrSpendingPolicyDailyLimit
+		}
+
+		if p.DailyLimitCoins != 0 && dailySpent > p.DailyLimitCoins {
+			return nil, ErrSpendingPolicyDailyLimit
+		}
+
+		if coins != 0 {
+			spends = append(spends, spend)
+		}
+	}
+
+	return spends, nil
+}
+
+// ReadableSpend is the JSON representation of a Spend, in the wallet file
+type ReadableSpend struct {
+	Time      int64    `json:"time"`
+	InnerHash string   `json:"inner_hash"`
+	Inputs    []string `json:"inputs,omitempty"`
+	Coins     uint64   `json:"coins"`
+}
+
+// ReadableSpendingPolicy is the JSON representation of a SpendingPolicy and the recorded spends, in the wallet file
+type ReadableSpendingPolicy struct {
+	MaxTransactionCoins uint64          `json:"max_transaction_coins,omitempty"`
+	DailyLimitCoins     uint64          `json:"daily_limit_coins,omitempty"`
+	AllowedDestinations []string        `json:"allowed_destinations,omitempty"`
+	Spends              []ReadableSpend `json:"spends,omitempty"`
+}
+
+// newReadableSpendingPolicy creates a ReadableSpendingPolicy, returns nil if there is no policy and no spends
+func newReadableSpendingPolicy(p SpendingPolicy, spends []Spend) *ReadableSpendingPolicy {
+	if p.Empty() && len(spends) == 0 {
+		return nil
+	}
+
+	rp := ReadableSpendingPolicy{
+		MaxTransactionCoins: p.MaxTransactionCoins,
+		DailyLimitCoins:     p.DailyLimitCoins,
+	}
+
+	for _, a := range p.AllowedDestinations {
+		rp.AllowedDestinations = append(rp.AllowedDestinations, a.String())
+	}
+
+	for _, s := range spends {
+		rs := ReadableSpend{
+			Time:      s.Time,
+			InnerHash: s.InnerHash.Hex(),
+			Coins:     s.Coins,
+		}
+		for _, h := range s.Inputs {
+			rs.Inputs = append(rs.Inputs, h.Hex())
+		}
+		rp.Spends = append(rp.Spends, rs)
+	}
+
+	return &rp
+}
+
+// toSpendingPolicy converts a ReadableSpendingPolicy to a SpendingPolicy and the recorded spends
+func (rp *ReadableSpendingPolicy) toSpendingPolicy() (SpendingPolicy, []Spend, error) {
+	var p SpendingPolicy
+	if rp == nil {
+		return p, nil, nil
+	}
+
+	p.MaxTransactionCoins = rp.MaxTransactionCoins
+	p.DailyLimitCoins = rp.DailyLimitCoins
+
+	for _, s := range rp.AllowedDestinations {
+		addr, err := cipher.DecodeBase58Address(s)
+		if err != nil {
+			return SpendingPolicy{}, nil, fmt.Errorf("invalid spending policy destination %q: %v", s, err)
+		}
+		p.AllowedDestinations = append(p.AllowedDestinations, addr)
+	}
+
+	var spends []Spend
+	for _, rs := range rp.Spends {
+		innerHash, err := cipher.SHA256FromHex(rs.InnerHash)
+		if err != nil {
+			return SpendingPolicy{}, nil, fmt.Errorf("invalid spend inner hash %q: %v", rs.InnerHash, err)
+		}
+
+		spend := Spend{
+			Time:      rs.Time,
+			InnerHash: innerHash,
+			Coins:     rs.Coins,
+		}
+
+		for _, in := range rs.Inputs {
+			h, err := cipher.SHA256FromHex(in)
+			if err != nil {
+				return SpendingPolicy{}, nil, fmt.Errorf("invalid spend input %q: %v", in, err)
+			}
+			spend.Inputs = append(spend.Inputs, h)
+		}
+
+		spends = append(spends, spend)
+	}
+
+	return p, spends, nil
+}
@@ -0,0 +1,245 @@
+package wallet
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/testutil"
+)
+
+func makeSpendTxn(w *Wallet, dest cipher.Address, coins, change uint64) coin.Transaction {
+	txn := coin.Transaction{
+		In: []cipher.SHA256{cipher.SumSHA256(cipher.RandByte(32))},
+		Out: []coin.TransactionOutput{
+			{
+				Address: dest,
+				Coins:   coins,
+			},
+		},
+	}
+
+	if change != 0 {
+		txn.Out = append(txn.Out, coin.TransactionOutput{
+			Address: w.Entries[0].SkycoinAddress(),
+			Coins:   change,
+		})
+	}
+
+	return txn
+}
+
+func TestSpendingPolicyValidate(t *testing.T) {
+	addr := testutil.MakeAddress()
+
+	tt := []struct {
+		name   string
+		policy SpendingPolicy
+		err    error
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name: "valid",
+			policy: SpendingPolicy{
+				MaxTransactionCoins: 1e6,
+				DailyLimitCoins:     2e6,
+				AllowedDestinations: []cipher.Address{addr, testutil.MakeAddress()},
+			},
+		},
+		{
+			name: "duplicate destination",
+			policy: SpendingPolicy{
+				AllowedDestinations: []cipher.Address{addr, addr},
+			},
+			err: ErrSpendingPolicyDuplicateDestination,
+		},
+		{
+			name: "null destination",
+			policy: SpendingPolicy{
+				AllowedDestinations: []cipher.Address{{}},
+			},
+			err: ErrSpendingPolicyNullDestination,
+		},
+		{
+			name: "too many destinations",
+			policy: SpendingPolicy{
+				AllowedDestinations: make([]cipher.Address, MaxSpendingPolicyDestinations+1),
+			},
+			err: ErrSpendingPolicyTooManyDestinations,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.err, tc.policy.Validate())
+		})
+	}
+}
+
+func TestWalletEnforceSpendingPolicy(t *testing.T) {
+	w, err := NewWallet("t.wlt", Options{
+		Seed:      "seed",
+		Label:     "t",
+		GenerateN: 1,
+	})
+	require.NoError(t, err)
+
+	allowed := testutil.MakeAddress()
+	other := testutil.MakeAddress()
+	now := time.Unix(1e9, 0)
+
+	// Without a policy, nothing is limited or recorded
+	require.NoError(t, w.EnforceSpendingPolicy([]coin.Transaction{makeSpendTxn(w, other, 100e6, 0)}, now))
+	require.Empty(t, w.Spends)
+
+	require.NoError(t, w.SetSpendingPolicy(SpendingPolicy{
+		MaxTransactionCoins: 3e6,
+		DailyLimitCoins:     5e6,
+		AllowedDestinations: []cipher.Address{allowed},
+	}))
+
+	// The change is not limited
+	txn := makeSpendTxn(w, allowed, 3e6, 100e6)
+	require.NoError(t, w.EnforceSpendingPolicy([]coin.Transaction{txn}, now))
+	require.Equal(t, []Spend{
+		{
+			Time:      now.Unix(),
+			InnerHash: txn.HashInner(),
+			Inputs:    txn.In,
+			Coins:     3e6,
+		},
+	}, w.Spends)
+
+	// The spend of a transaction is recorded once, its signatures don't change it
+	signedTxn := txn
+	signedTxn.Sigs = []cipher.Sig{cipher.MustSignHash(txn.HashInner(), cipher.MustGenerateDeterministicKeyPairs([]byte("seed"), 1)[0])}
+	require.NoError(t, w.EnforceSpendingPolicy([]coin.Transaction{signedTxn}, now))
+	require.NoError(t, w.EnforceSpendingPolicy([]coin.Transaction{txn, signedTxn}, now))
+	require.Len(t, w.Spends, 1)
+
+	err = w.EnforceSpendingPolicy([]coin.Transaction{makeSpendTxn(w, other, 1e6, 0)}, now)
+	require.Equal(t, ErrSpendingPolicyDestination, err)
+
+	err = w.EnforceSpendingPolicy([]coin.Transaction{makeSpendTxn(w, allowed, 3e6+1, 0)}, now)
+	require.Equal(t, ErrSpendingPolicyMaxTransactionCoins, err)
+
+	// The transactions are counted together toward the daily limit
+	err = w.EnforceSpendingPolicy([]coin.Transaction{
+		makeSpendTxn(w, allowed, 1e6, 0),
+		makeSpendTxn(w, allowed, 1e6+1, 0),
+	}, now.Add(time.Hour))
+	require.Equal(t, ErrSpendingPolicyDailyLimit, err)
+	require.Len(t, w.Spends, 1)
+
+	require.NoError(t, w.EnforceSpendingPolicy([]coin.Transaction{
+		makeSpendTxn(w, allowed, 1e6, 0),
+		makeSpendTxn(w, allowed, 1e6, 0),
+	}, now.Add(time.Hour)))
+	require.Len(t, w.Spends, 3)
+
+	spent, err := w.DailySpent(now.Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, uint64(5e6), spent)
+
+	// The daily period is a rolling window, and older spends are dropped
+	spent, err = w.DailySpent(now.Add(SpendingPolicyDailyPeriod))
+	require.NoError(t, err)
+	require.Equal(t, uint64(2e6), spent)
+
+	require.NoError(t, w.EnforceSpendingPolicy([]coin.Transaction{makeSpendTxn(w, allowed, 3e6, 0)}, now.Add(SpendingPolicyDailyPeriod)))
+	require.Len(t, w.Spends, 3)
+
+	// A transaction spending the same outputs as a recorded one replaces it, only the coins above it are counted
+	later := now.Add(SpendingPolicyDailyPeriod)
+	replacement := makeSpendTxn(w, allowed, 1e6, 0)
+	replacement.In = w.Spends[0].Inputs
+	require.NoError(t, w.EnforceSpendingPolicy([]coin.Transaction{replacement}, later))
+	require.Len(t, w.Spends, 3)
+	spent, err = w.DailySpent(later)
+	require.NoError(t, err)
+	require.Equal(t, uint64(5e6), spent)
+
+	replacement = makeSpendTxn(w, allowed, 1e6+1, 0)
+	replacement.In = w.Spends[0].Inputs
+	err = w.EnforceSpendingPolicy([]coin.Transaction{replacement}, later)
+	require.Equal(t, ErrSpendingPolicyDailyLimit, err)
+
+	// CheckSpendingPolicy does not record the spends
+	err = w.CheckSpendingPolicy([]coin.Transaction{makeSpendTxn(w, allowed, 1, 0)}, later)
+	require.Equal(t, ErrSpendingPolicyDailyLimit, err)
+	err = w.CheckSpendingPolicy([]coin.Transaction{makeSpendTxn(w, other, 1, 0)}, later)
+	require.Equal(t, ErrSpendingPolicyDestination, err)
+	spends := w.Spends
+	require.NoError(t, w.CheckSpendingPolicy([]coin.Transaction{makeSpendTxn(w, allowed, 1, 0)}, later.Add(time.Hour)))
+	require.Equal(t, spends, w.Spends)
+
+	// The wallet file keeps the policy and the spends
+	rw := NewReadableWallet(w)
+	w2, err := rw.ToWallet()
+	require.NoError(t, err)
+	require.Equal(t, w.SpendingPolicy, w2.SpendingPolicy)
+	require.Equal(t, w.Spends, w2.Spends)
+}
+
+func TestServiceSpendingPolicy(t *testing.T) {
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	s, err := NewService(Config{
+		WalletDir:       dir,
+		CryptoType:      CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+
+	w, err := s.CreateWallet("t.wlt", Options{
+		Seed:       "seed",
+		Label:      "t",
+		Encrypt:    true,
+		Password:   []byte("pwd"),
+		CryptoType: CryptoTypeSha256Xor,
+	}, nil)
+	require.NoError(t, err)
+
+	policy := SpendingPolicy{
+		DailyLimitCoins: 2e6,
+	}
+
+	_, err = s.SetSpendingPolicy("t.wlt", nil, policy)
+	require.Equal(t, ErrMissingPassword, err)
+
+	_, err = s.SetSpendingPolicy("t.wlt", []byte("wrong"), policy)
+	require.Equal(t, ErrInvalidPassword, err)
+
+	_, err = s.SetSpendingPolicy("x.wlt", []byte("pwd"), policy)
+	require.Equal(t, ErrWalletNotExist, err)
+
+	w, err = s.SetSpendingPolicy("t.wlt", []byte("pwd"), policy)
+	require.NoError(t, err)
+	require.Equal(t, policy, w.SpendingPolicy)
+
+	// The spends are saved to the wallet file
+	dest := testutil.MakeAddress()
+	now := time.Now()
+	require.NoError(t, s.EnforceSpendingPolicy("t.wlt", []coin.Transaction{makeSpendTxn(w, dest, 2e6, 0)}, now))
+	err = s.EnforceSpendingPolicy("t.wlt", []coin.Transaction{makeSpendTxn(w, dest, 1, 0)}, now)
+	require.Equal(t, ErrSpendingPolicyDailyLimit, err)
+
+	w, err = Load(filepath.Join(dir, "t.wlt"))
+	require.NoError(t, err)
+	require.Equal(t, policy, w.SpendingPolicy)
+	require.Len(t, w.Spends, 1)
+
+	// The zero policy removes the policy
+	w, err = s.SetSpendingPolicy("t.wlt", []byte("pwd"), SpendingPolicy{})
+	require.NoError(t, err)
+	require.True(t, w.SpendingPolicy.Empty())
+	require.NoError(t, s.EnforceSpendingPolicy("t.wlt", []coin.Transaction{makeSpendTxn(w, dest, 1e6, 0)}, now))
+}
@@ -0,0 +1,106 @@
+package wallet
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WalletStorage persists wallets. The wallet service loads the wallets of its storages on startup
+// and saves a wallet to the storage it was loaded from, or created in, each time it is updated.
+// The default storage is a FileStorage; integrators can implement WalletStorage to keep wallets
+// in a secrets manager or an encrypted volume.
+type WalletStorage interface {
+	// Name describes the storage in logs and errors, e.g. its location
+	Name() string
+	// List returns the IDs of the wallets in the storage
+	List() ([]string, error)
+	// Load loads the wallet with the given ID
+	Load(id string) (*Wallet, error)
+	// Save saves a wallet, creating it if it does not exist in the storage
+	Save(w *Wallet) error
+}
+
+// FileStorage stores wallets in files of a directory, named after the wallet IDs
+type FileStorage struct {
+	dir string
+}
+
+// NewFileStorage creates the directory if it does not exist
+// and removes the .wlt.bak files of the version 0.1 wallets in it
+func NewFileStorage(dir string) (*FileStorage, error) {
+	if err := os.MkdirAll(dir, os.FileMode(0700)); err != nil {
+		return nil, fmt.Errorf("failed to create wallet directory %s: %v", dir, err)
+	}
+
+	// Removes .wlt.bak files before loading wallets
+	if err := removeBackupFiles(dir); err != nil {
+		return nil, fmt.Errorf("remove .wlt.bak files in %v failed: %v", dir, err)
+	}
+
+	return &FileStorage{
+		dir: dir,
+	}, nil
+}
+
+// Dir returns the directory of the storage
+func (fs *FileStorage) Dir() string {
+	return fs.dir
+}
+
+// Name returns the directory of the storage
+func (fs *FileStorage) Name() string {
+	return fs.dir
+}
+
+// List returns the IDs of the wallet files in the directory, the names of its regular files with extension WalletExt
+func (fs *FileStorage) List() ([]string, error) {
+	entries, err := ioutil.ReadDir(fs.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, e := range entries {
+		if e.Mode().IsRegular() && strings.HasSuffix(e.Name(), WalletExt) {
+			ids = append(ids, e.Name())
+		}
+	}
+
+	return ids, nil
+}
+
+// Load loads a wallet file of the directory
+func (fs *FileStorage) Load(id string) (*Wallet, error) {
+	return loadWallet(filepath.Join(fs.dir, id))
+}
+
+// Save saves a wallet file in the directory, upgrading the wallets of older versions
+func (fs *FileStorage) Save(w *Wallet) error {
+	w.upgradeVersion()
+	return w.Save(fs.dir)
+}
+
+// loadStorageWallets loads all the wallets of a storage.
+// If any wallet fails to load, loading is aborted and error returned.
+func loadStorageWallets(s WalletStorage) (Wallets, error) {
+	ids, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	wallets := Wallets{}
+	for _, id := range ids {
+		w, err := s.Load(id)
+		if err != nil {
+			return nil, err
+		}
+
+		w.setFilename(id)
+		wallets[id] = w
+	}
+
+	return wallets, nil
+}
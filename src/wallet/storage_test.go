@@ -0,0 +1,175 @@
+package wallet
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// memStorage stores wallets in memory
+type memStorage map[string]*Wallet
+
+func (ms memStorage) Name() string {
+	return "memory"
+}
+
+func (ms memStorage) List() ([]string, error) {
+	var ids []string
+	for id := range ms {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func (ms memStorage) Load(id string) (*Wallet, error) {
+	w, ok := ms[id]
+	if !ok {
+		return nil, errors.New("wallet not found")
+	}
+	return w.clone(), nil
+}
+
+func (ms memStorage) Save(w *Wallet) error {
+	ms[w.Filename()] = w.clone()
+	return nil
+}
+
+func saveTestWallet(t *testing.T, dir, wltName, seed string) {
+	w, err := NewWallet(wltName, Options{
+		Seed:      seed,
+		Label:     wltName,
+		GenerateN: 1,
+	})
+	require.NoError(t, err)
+	require.NoError(t, w.Save(dir))
+}
+
+func TestFileStorage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "storage")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// The directory is created
+	dir = filepath.Join(dir, "wallets")
+	fs, err := NewFileStorage(dir)
+	require.NoError(t, err)
+	require.Equal(t, dir, fs.Dir())
+
+	ids, err := fs.List()
+	require.NoError(t, err)
+	require.Empty(t, ids)
+
+	w, err := NewWallet("t.wlt", Options{
+		Seed:      "seed",
+		Label:     "t",
+		GenerateN: 2,
+	})
+	require.NoError(t, err)
+	require.NoError(t, fs.Save(w))
+
+	// Files that are not wallet files are ignored
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "t.txt"), []byte("t"), 0600))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "d.wlt"), 0700))
+
+	ids, err = fs.List()
+	require.NoError(t, err)
+	require.Equal(t, []string{"t.wlt"}, ids)
+
+	w2, err := fs.Load("t.wlt")
+	require.NoError(t, err)
+	require.Equal(t, w.Entries, w2.Entries)
+	require.Equal(t, "t.wlt", w2.Filename())
+}
+
+func TestServiceWalletStorages(t *testing.T) {
+	dir1 := prepareWltDir()
+	defer os.RemoveAll(dir1)
+	dir2 := prepareWltDir()
+	defer os.RemoveAll(dir2)
+
+	saveTestWallet(t, dir1, "t1.wlt", "seed1")
+	saveTestWallet(t, dir2, "t2.wlt", "seed2")
+
+	s, err := NewService(Config{
+		WalletDir:       dir1,
+		WalletDirs:      []string{dir2},
+		CryptoType:      CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+	require.Len(t, s.wallets, 2)
+
+	// Updated wallets are saved in their directory
+	_, err = s.NewAddresses("t2.wlt", nil, 1)
+	require.NoError(t, err)
+	w, err := Load(filepath.Join(dir2, "t2.wlt"))
+	require.NoError(t, err)
+	require.Len(t, w.Entries, 2)
+	_, err = os.Stat(filepath.Join(dir1, "t2.wlt"))
+	require.True(t, os.IsNotExist(err))
+
+	// New wallets are created in the wallet directory
+	_, err = s.CreateWallet("t3.wlt", Options{
+		Seed:  "seed3",
+		Label: "t3",
+	}, nil)
+	require.NoError(t, err)
+	_, err = os.Stat(filepath.Join(dir1, "t3.wlt"))
+	require.NoError(t, err)
+	_, err = os.Stat(filepath.Join(dir2, "t3.wlt"))
+	require.True(t, os.IsNotExist(err))
+
+	// A wallet ID can't be in two directories
+	saveTestWallet(t, dir2, "t1.wlt", "seed4")
+	_, err = NewService(Config{
+		WalletDir:       dir1,
+		WalletDirs:      []string{dir2},
+		CryptoType:      CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "wallet t1.wlt is in both")
+}
+
+func TestServiceCustomWalletStorage(t *testing.T) {
+	dir := prepareWltDir()
+	defer os.RemoveAll(dir)
+
+	w, err := NewWallet("t1.wlt", Options{
+		Seed:      "seed1",
+		Label:     "t1",
+		GenerateN: 1,
+	})
+	require.NoError(t, err)
+	ms := memStorage{
+		"t1.wlt": w,
+	}
+
+	s, err := NewService(Config{
+		WalletDir:       dir,
+		Storage:         ms,
+		CryptoType:      CryptoTypeSha256Xor,
+		EnableWalletAPI: true,
+	})
+	require.NoError(t, err)
+	require.Len(t, s.wallets, 1)
+
+	require.NoError(t, s.UpdateWalletLabel("t1.wlt", "label"))
+	require.Equal(t, "label", ms["t1.wlt"].Label())
+
+	_, err = s.CreateWallet("t2.wlt", Options{
+		Seed:  "seed2",
+		Label: "t2",
+	}, nil)
+	require.NoError(t, err)
+	require.Contains(t, ms, "t2.wlt")
+
+	// The wallet directory is not used
+	dirIsEmpty(t, dir)
+}
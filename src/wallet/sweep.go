@@ -0,0 +1,231 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/params"
+	"github.com/skycoin/skycoin/src/util/fee"
+)
+
+const (
+	// MaxSweepKeys is the maximum number of private keys swept together
+	MaxSweepKeys = 100
+)
+
+var (
+	// ErrSweepNoKeys is returned when sweeping without private keys
+	ErrSweepNoKeys = NewError(errors.New("no private keys to sweep"))
+	// ErrSweepTooManyKeys is returned when sweeping more than MaxSweepKeys private keys
+	ErrSweepTooManyKeys = NewError(fmt.Errorf("can't sweep more than %d private keys", MaxSweepKeys))
+	// ErrSweepNothingToSweep is returned if the private keys have no unspent outputs that can be swept
+	ErrSweepNothingToSweep = NewError(errors.New("the private keys have no unspent outputs that can be swept"))
+	// ErrSweepNullDestination is returned when sweeping to the null address
+	ErrSweepNullDestination = NewError(errors.New("sweep destination is the null address"))
+)
+
+// Sweep are the transactions that send all the coins and coin hours of the unspent outputs of private keys
+// to an address
+type Sweep struct {
+	Transactions []SweepTransaction
+	// Dust are the unspent outputs that can't be swept: the ones whose coins don't conform to the droplet precision,
+	// and the ones without coin hours left over when there are not enough unspent outputs with coin hours
+	// to pay the fee of all the transactions
+	Dust []UxBalance
+}
+
+// SweepTransaction is a transaction of a sweep
+type SweepTransaction struct {
+	Transaction *coin.Transaction
+	Inputs      []UxBalance
+}
+
+// SweepKeys maps the addresses of private keys to the keys
+type SweepKeys map[cipher.Address]cipher.SecKey
+
+// NewSweepKeys checks the private keys to sweep and maps their addresses to them. Duplicate keys are ignored.
+func NewSweepKeys(keys []cipher.SecKey) (SweepKeys, error) {
+	if len(keys) == 0 {
+		return nil, ErrSweepNoKeys
+	}
+
+	if len(keys) > MaxSweepKeys {
+		return nil, ErrSweepTooManyKeys
+	}
+
+	sk := make(SweepKeys, len(keys))
+	for _, k := range keys {
+		if err := k.Verify(); err != nil {
+			return nil, NewError(fmt.Errorf("invalid private key: %v", err))
+		}
+
+		addr, err := cipher.AddressFromSecKey(k)
+		if err != nil {
+			return nil, NewError(fmt.Errorf("invalid private key: %v", err))
+		}
+
+		sk[addr] = k
+	}
+
+	return sk, nil
+}
+
+// Addresses returns the addresses of the private keys
+func (sk SweepKeys) Addresses() []cipher.Address {
+	addrs := make([]cipher.Address, 0, len(sk))
+	for a := range sk {
+		addrs = append(addrs, a)
+	}
+
+	sort.Slice(addrs, func(i, j int) bool {
+		return addrs[i].String() < addrs[j].String()
+	})
+
+	return addrs
+}
+
+// CreateSweep creates the transactions that send the coins and coin hours of the unspent outputs uxb
+// of the private keys to dest, burning the required fee.
+// The unspent outputs are split in as few transactions within maxSize as possible,
+// and the unspent outputs with the most coin hours are spread over the transactions first,
+// so that the unspent outputs without coin hours are swept with them.
+func (sk SweepKeys) CreateSweep(uxb []UxBalance, dest cipher.Address, maxSize uint32) (*Sweep, error) {
+	if dest.Null() {
+		return nil, ErrSweepNullDestination
+	}
+
+	var sweep Sweep
+	var inputs []UxBalance
+	for _, ux := range uxb {
+		if _, ok := sk[ux.Address]; !ok {
+			return nil, fmt.Errorf("unspent output %s is not owned by the private keys", ux.Hash.Hex())
+		}
+
+		if params.DropletPrecisionCheck(ux.Coins) != nil {
+			sweep.Dust = append(sweep.Dust, ux)
+			continue
+		}
+
+		inputs = append(inputs, ux)
+	}
+
+	if len(inputs) == 0 {
+		return nil, ErrSweepNothingToSweep
+	}
+
+	maxInputs, err := maxSweepInputs(maxSize)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(inputs, func(i, j int) bool {
+		if inputs[i].Hours != inputs[j].Hours {
+			return inputs[i].Hours > inputs[j].Hours
+		}
+		if inputs[i].Coins != inputs[j].Coins {
+			return inputs[i].Coins > inputs[j].Coins
+		}
+		return inputs[i].Hash.Hex() < inputs[j].Hash.Hex()
+	})
+
+	// Deal the unspent outputs to the transactions like cards,
+	// so that each transaction gets the most coin hours possible
+	n := (len(inputs) + maxInputs - 1) / maxInputs
+	groups := make([][]UxBalance, n)
+	for i, ux := range inputs {
+		groups[i%n] = append(groups[i%n], ux)
+	}
+
+	for _, g := range groups {
+		txn, err := sk.createSweepTransaction(g, dest)
+		if err != nil {
+			return nil, err
+		}
+
+		if txn == nil {
+			sweep.Dust = append(sweep.Dust, g...)
+			continue
+		}
+
+		sweep.Transactions = append(sweep.Transactions, SweepTransaction{
+			Transaction: txn,
+			Inputs:      g,
+		})
+	}
+
+	if len(sweep.Transactions) == 0 {
+		return nil, ErrSweepNothingToSweep
+	}
+
+	return &sweep, nil
+}
+
+// createSweepTransaction creates a transaction that sends the coins and hours of the inputs to dest.
+// Returns nil if the inputs have no coin hours to pay the fee.
+func (sk SweepKeys) createSweepTransaction(inputs []UxBalance, dest cipher.Address) (*coin.Transaction, error) {
+	var coins, hours uint64
+	for _, ux := range inputs {
+		var err error
+		coins, err = coin.AddUint64(coins, ux.Coins)
+		if err != nil {
+			return nil, err
+		}
+
+		hours, err = coin.AddUint64(hours, ux.Hours)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if hours == 0 {
+		return nil, nil
+	}
+
+	var txn coin.Transaction
+	keys := make([]cipher.SecKey, len(inputs))
+	for i, ux := range inputs {
+		txn.PushInput(ux.Hash)
+		keys[i] = sk[ux.Address]
+	}
+
+	txn.PushOutput(dest, coins, fee.RemainingHours(hours, params.UserBurnFactor))
+	txn.SignInputs(keys)
+
+	if err := txn.UpdateHeader(); err != nil {
+		return nil, err
+	}
+
+	return &txn, nil
+}
+
+// maxSweepInputs returns the maximum number of inputs of a sweep transaction within maxSize
+func maxSweepInputs(maxSize uint32) (int, error) {
+	size := func(n int) (uint32, error) {
+		var txn coin.Transaction
+		for i := 0; i < n; i++ {
+			txn.PushInput(cipher.SHA256{})
+			txn.Sigs = append(txn.Sigs, cipher.Sig{})
+		}
+		txn.PushOutput(cipher.Address{}, 0, 0)
+		return txn.Size()
+	}
+
+	size1, err := size(1)
+	if err != nil {
+		return 0, err
+	}
+
+	size2, err := size(2)
+	if err != nil {
+		return 0, err
+	}
+
+	if size1 > maxSize {
+		return 0, ErrPayoutTransactionTooLarge
+	}
+
+	return 1 + int((maxSize-size1)/(size2-size1)), nil
+}
@@ -0,0 +1,176 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/params"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/util/fee"
+)
+
+func TestNewSweepKeys(t *testing.T) {
+	keys, err := cipher.GenerateDeterministicKeyPairs([]byte("seed"), 2)
+	require.NoError(t, err)
+
+	_, err = NewSweepKeys(nil)
+	require.Equal(t, ErrSweepNoKeys, err)
+
+	_, err = NewSweepKeys(make([]cipher.SecKey, MaxSweepKeys+1))
+	require.Equal(t, ErrSweepTooManyKeys, err)
+
+	_, err = NewSweepKeys([]cipher.SecKey{keys[0], {}})
+	require.Error(t, err)
+	require.IsType(t, Error{}, err)
+
+	// Duplicate keys are ignored
+	sk, err := NewSweepKeys([]cipher.SecKey{keys[0], keys[1], keys[0]})
+	require.NoError(t, err)
+	require.Len(t, sk, 2)
+	require.Len(t, sk.Addresses(), 2)
+	for _, k := range keys {
+		require.Equal(t, k, sk[cipher.MustAddressFromSecKey(k)])
+	}
+}
+
+func TestSweepKeysCreateSweep(t *testing.T) {
+	headTime := uint64(1000)
+	keys, err := cipher.GenerateDeterministicKeyPairs([]byte("seed"), 2)
+	require.NoError(t, err)
+	addrs := []cipher.Address{
+		cipher.MustAddressFromSecKey(keys[0]),
+		cipher.MustAddressFromSecKey(keys[1]),
+	}
+
+	sk, err := NewSweepKeys(keys)
+	require.NoError(t, err)
+
+	dest := testutil.MakeAddress()
+
+	makeUxOuts := func(n int, coins, hours uint64) coin.UxArray {
+		uxa := make(coin.UxArray, n)
+		for i := range uxa {
+			uxa[i] = coin.UxOut{
+				Head: coin.UxHead{
+					Time:  headTime,
+					BkSeq: uint64(i),
+				},
+				Body: coin.UxBody{
+					SrcTransaction: testutil.RandSHA256(t),
+					Address:        addrs[i%len(addrs)],
+					Coins:          coins,
+					Hours:          hours,
+				},
+			}
+		}
+		return uxa
+	}
+
+	toUxBalances := func(uxa coin.UxArray) []UxBalance {
+		uxb, err := NewUxBalances(headTime, uxa)
+		require.NoError(t, err)
+		return uxb
+	}
+
+	verifySweep := func(sweep *Sweep, uxa coin.UxArray, maxSize uint32) {
+		byHash := make(map[cipher.SHA256]coin.UxOut, len(uxa))
+		for _, ux := range uxa {
+			byHash[ux.Hash()] = ux
+		}
+
+		for _, st := range sweep.Transactions {
+			txn := st.Transaction
+			require.NoError(t, txn.Verify())
+
+			size, err := txn.Size()
+			require.NoError(t, err)
+			require.True(t, size <= maxSize)
+
+			uxIn := make(coin.UxArray, len(txn.In))
+			var coins, hours uint64
+			for i, h := range txn.In {
+				uxIn[i] = byHash[h]
+				coins += uxIn[i].Body.Coins
+				hours += uxIn[i].Body.Hours
+			}
+			require.NoError(t, txn.VerifyInput(uxIn))
+
+			require.Len(t, txn.Out, 1)
+			require.Equal(t, dest, txn.Out[0].Address)
+			require.Equal(t, coins, txn.Out[0].Coins)
+			require.Equal(t, fee.RemainingHours(hours, params.UserBurnFactor), txn.Out[0].Hours)
+		}
+	}
+
+	t.Run("null destination", func(t *testing.T) {
+		_, err := sk.CreateSweep(toUxBalances(makeUxOuts(1, 1e6, 100)), cipher.Address{}, params.UserMaxTransactionSize)
+		require.Equal(t, ErrSweepNullDestination, err)
+	})
+
+	t.Run("unspent output not owned by the keys", func(t *testing.T) {
+		uxb := toUxBalances(makeUxOuts(1, 1e6, 100))
+		uxb[0].Address = testutil.MakeAddress()
+		_, err := sk.CreateSweep(uxb, dest, params.UserMaxTransactionSize)
+		require.Error(t, err)
+	})
+
+	t.Run("nothing to sweep", func(t *testing.T) {
+		_, err := sk.CreateSweep(nil, dest, params.UserMaxTransactionSize)
+		require.Equal(t, ErrSweepNothingToSweep, err)
+
+		_, err = sk.CreateSweep(toUxBalances(makeUxOuts(2, 1e6, 0)), dest, params.UserMaxTransactionSize)
+		require.Equal(t, ErrSweepNothingToSweep, err)
+	})
+
+	t.Run("single transaction", func(t *testing.T) {
+		uxa := makeUxOuts(5, 2e6, 10)
+		sweep, err := sk.CreateSweep(toUxBalances(uxa), dest, params.UserMaxTransactionSize)
+		require.NoError(t, err)
+		require.Len(t, sweep.Transactions, 1)
+		require.Len(t, sweep.Transactions[0].Inputs, 5)
+		require.Empty(t, sweep.Dust)
+		verifySweep(sweep, uxa, params.UserMaxTransactionSize)
+	})
+
+	t.Run("droplet precision dust", func(t *testing.T) {
+		uxa := append(makeUxOuts(2, 2e6, 10), makeUxOuts(1, 1e6+1, 10)...)
+		sweep, err := sk.CreateSweep(toUxBalances(uxa), dest, params.UserMaxTransactionSize)
+		require.NoError(t, err)
+		require.Len(t, sweep.Transactions, 1)
+		require.Len(t, sweep.Dust, 1)
+		require.Equal(t, uint64(1e6+1), sweep.Dust[0].Coins)
+		verifySweep(sweep, uxa[:2], params.UserMaxTransactionSize)
+	})
+
+	t.Run("many inputs", func(t *testing.T) {
+		maxSize := uint32(1024)
+		maxInputs, err := maxSweepInputs(maxSize)
+		require.NoError(t, err)
+
+		// The unspent outputs without hours are swept with the ones with hours
+		uxa := append(makeUxOuts(3, 1e6, 10), makeUxOuts(maxInputs*2, 1e6, 0)...)
+		sweep, err := sk.CreateSweep(toUxBalances(uxa), dest, maxSize)
+		require.NoError(t, err)
+		require.Len(t, sweep.Transactions, 3)
+		require.Empty(t, sweep.Dust)
+		verifySweep(sweep, uxa, maxSize)
+
+		n := 0
+		for _, st := range sweep.Transactions {
+			require.True(t, st.Inputs[0].Hours > 0)
+			n += len(st.Inputs)
+		}
+		require.Equal(t, len(uxa), n)
+
+		// The transactions without coin hours to pay the fee are dust
+		uxa = append(makeUxOuts(1, 1e6, 10), makeUxOuts(maxInputs*2, 1e6, 0)...)
+		sweep, err = sk.CreateSweep(toUxBalances(uxa), dest, maxSize)
+		require.NoError(t, err)
+		require.Len(t, sweep.Transactions, 1)
+		require.Len(t, sweep.Dust, len(uxa)-len(sweep.Transactions[0].Inputs))
+		verifySweep(sweep, uxa, maxSize)
+	})
+}
@@ -5,10 +5,12 @@ package wallet
 
 import (
 	"bytes"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"math/big"
+	"math/rand"
 	"os"
 	"path/filepath"
 	"sort"
@@ -19,6 +21,8 @@ import (
 	"encoding/hex"
 
 	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/encrypt"
+	"github.com/skycoin/skycoin/src/cipher/go-bip39"
 	"github.com/skycoin/skycoin/src/coin"
 	"github.com/skycoin/skycoin/src/params"
 
@@ -42,8 +46,9 @@ func NewError(err error) error {
 }
 
 var (
-	// Version represents the current wallet version
-	Version = "0.2"
+	// Version represents the current wallet version.
+	// Wallet files of version 0.3 have a checksum and a write counter, see ReadableWallet.Save
+	Version = "0.3"
 
 	logger = logging.MustGetLogger("wallet")
 
@@ -103,6 +108,8 @@ var (
 	ErrWalletRecoverSeedWrong = NewError(errors.New("wallet recovery seed is wrong"))
 	// ErrNilBalanceGetter is returned if Options.ScanN > 0 but a nil BalanceGetter was provided
 	ErrNilBalanceGetter = NewError(errors.New("scan ahead requested but balance getter is nil"))
+	// ErrMissingCollectionKeys is returned when creating a collection wallet without keys
+	ErrMissingCollectionKeys = NewError(errors.New("missing keys of collection wallet"))
 	// ErrWalletNotDeterministic is returned if a wallet's type is not deterministic but it is necessary for the requested operation
 	ErrWalletNotDeterministic = NewError(errors.New("wallet type is not deterministic"))
 	// ErrInvalidCoinType is returned for invalid coin types
@@ -139,6 +146,16 @@ var (
 	ErrDuplicateUxOuts = NewError(errors.New("Wallet.UxOuts contains duplicate values"))
 	// ErrUnknownWalletID params.Wallet.ID does not match wallet
 	ErrUnknownWalletID = NewError(errors.New("params.Wallet.ID does not match wallet"))
+	// ErrWalletWatchOnly is returned when signing or generating keys with a watch-only wallet
+	ErrWalletWatchOnly = NewError(errors.New("wallet is watch-only, it has no keys"))
+	// ErrWalletNotWatchOnly is returned when adding watched addresses to a wallet that is not watch-only
+	ErrWalletNotWatchOnly = NewError(errors.New("wallet is not watch-only"))
+	// ErrMissingWatchAddresses is returned when creating a watch-only wallet without addresses
+	ErrMissingWatchAddresses = NewError(errors.New("watch-only wallet needs at least one address"))
+	// ErrPasswordUnsigned is returned when a password is provided to create an unsigned transaction
+	ErrPasswordUnsigned = NewError(errors.New("password must not be provided for unsigned transactions"))
+	// ErrInvalidCoinSelection Invalid CoinSelection
+	ErrInvalidCoinSelection = NewError(errors.New("Invalid CoinSelection"))
 )
 
 const (
@@ -155,6 +172,11 @@ const (
 
 	// WalletTypeDeterministic deterministic wallet type
 	WalletTypeDeterministic = "deterministic"
+	// WalletTypeWatchOnly watch-only wallet type, which has addresses but no keys
+	WalletTypeWatchOnly = "watch-only"
+	// WalletTypeCollection collection wallet type, which has the keys of unrelated addresses and no seed.
+	// Its keys are imported, it can't generate addresses
+	WalletTypeCollection = "collection"
 )
 
 // ResolveCoinType normalizes a coin type string to a CoinType constant
@@ -182,6 +204,8 @@ const (
 	metaSeed       = "seed"       // wallet seed
 	metaLastSeed   = "lastSeed"   // seed for generating next address
 	metaSecrets    = "secrets"    // secrets which records the encrypted seeds and secrets of address entries
+
+	metaSeedPassphrase = "seedPassphrase" // BIP39 passphrase of the seed, only set if the wallet has one
 )
 
 // CoinType represents the wallet coin type
@@ -195,6 +219,18 @@ const (
 
 	// HoursSelectionModeShare will distribute coin hours equally amongst destinations
 	HoursSelectionModeShare = "share"
+
+	// CoinSelectionMinimizeUxOuts chooses the least number of outputs to spend, see ChooseSpendsMinimizeUxOuts.
+	// This is the default coin selection strategy
+	CoinSelectionMinimizeUxOuts = "minimize_uxouts"
+	// CoinSelectionMinimizeBurn chooses the outputs with the least coin hours, see ChooseSpendsMinimizeBurn
+	CoinSelectionMinimizeBurn = "minimize_burn"
+	// CoinSelectionOldestFirst chooses the oldest outputs first, see ChooseSpendsOldestFirst
+	CoinSelectionOldestFirst = "oldest_first"
+	// CoinSelectionRandom chooses outputs in random order, see ChooseSpendsRandom
+	CoinSelectionRandom = "random"
+	// CoinSelectionExact spends all the outputs available, see ChooseSpendsExact
+	CoinSelectionExact = "exact"
 )
 
 // HoursSelection defines options for hours distribution
@@ -210,6 +246,9 @@ type CreateTransactionWalletParams struct {
 	UxOuts    []cipher.SHA256
 	Addresses []cipher.Address
 	Password  []byte
+	// Account restricts the spent outputs to the addresses of a named account of the wallet,
+	// whose address pool receives the change of the new_address ChangePolicy. If empty, the whole wallet is spent from
+	Account string
 }
 
 // CreateTransactionParams defines control parameters for transaction construction
@@ -219,6 +258,18 @@ type CreateTransactionParams struct {
 	Wallet            CreateTransactionWalletParams
 	ChangeAddress     *cipher.Address
 	To                []coin.TransactionOutput
+	// Unsigned creates the transaction without signing it, with null signatures.
+	// Watch-only wallets and encrypted wallets without their password can only create unsigned transactions
+	Unsigned bool
+	// Signer signs the transaction instead of the keys of the wallet, such as a HardwareSigner.
+	// The wallet's password is not used, and the wallet can be encrypted or watch-only.
+	Signer Signer
+	// CoinSelection is the strategy to choose the outputs to spend, one of the CoinSelection constants.
+	// Defaults to CoinSelectionMinimizeUxOuts
+	CoinSelection string
+	// ChangePolicy chooses the address that receives the change, one of the ChangePolicy constants.
+	// If empty, the change goes to ChangeAddress if set, otherwise to the input address that sorts first
+	ChangePolicy string
 }
 
 // Validate validates CreateTransactionParams
@@ -227,6 +278,24 @@ func (c CreateTransactionParams) Validate() error {
 		return ErrNullChangeAddress
 	}
 
+	if err := validateChangePolicy(c.ChangePolicy, c.ChangeAddress); err != nil {
+		return err
+	}
+
+	if c.Unsigned && len(c.Wallet.Password) != 0 {
+		return ErrPasswordUnsigned
+	}
+
+	if c.Signer != nil {
+		if c.Unsigned {
+			return ErrUnsignedSigner
+		}
+
+		if len(c.Wallet.Password) != 0 {
+			return ErrPasswordSigner
+		}
+	}
+
 	if len(c.To) == 0 {
 		return ErrMissingTo
 	}
@@ -259,6 +328,12 @@ func (c CreateTransactionParams) Validate() error {
 		return ErrMissingWalletID
 	}
 
+	switch c.CoinSelection {
+	case "", CoinSelectionMinimizeUxOuts, CoinSelectionMinimizeBurn, CoinSelectionOldestFirst, CoinSelectionRandom, CoinSelectionExact:
+	default:
+		return ErrInvalidCoinSelection
+	}
+
 	addressMap := make(map[cipher.Address]struct{}, len(c.Wallet.Addresses))
 	for _, a := range c.Wallet.Addresses {
 		if a.Null() {
@@ -345,9 +420,18 @@ type Options struct {
 	Seed       string     // wallet seed.
 	Encrypt    bool       // whether the wallet need to be encrypted.
 	Password   []byte     // password that would be used for encryption, and would only be used when 'Encrypt' is true.
-	CryptoType CryptoType // wallet encryption type, scrypt-chacha20poly1305 or sha256-xor.
+	CryptoType CryptoType // wallet encryption type, argon2id-chacha20poly1305, scrypt-chacha20poly1305 or sha256-xor.
 	ScanN      uint64     // number of addresses that're going to be scanned for a balance. The highest address with a balance will be used.
 	GenerateN  uint64     // number of addresses to generate, regardless of balance
+	// Argon2id are the argon2id parameters if CryptoType is argon2id-chacha20poly1305.
+	// The zero value uses encrypt.DefaultArgon2idChacha20poly1305.
+	Argon2id encrypt.Argon2idChacha20poly1305
+	// BIP39 requires the seed to be a valid BIP39 mnemonic, with a valid checksum.
+	// The keys are generated from the mnemonic itself, like for any other seed, unless SeedPassphrase is set.
+	BIP39 bool
+	// SeedPassphrase is the BIP39 passphrase of the seed. If set, the seed must be a valid BIP39 mnemonic
+	// and the keys are generated from the BIP39 seed of the mnemonic and the passphrase, instead of from the mnemonic.
+	SeedPassphrase string
 }
 
 // Wallet is consisted of meta and entries.
@@ -359,6 +443,20 @@ type Options struct {
 type Wallet struct {
 	Meta    map[string]string
 	Entries []Entry
+	// Annotations are the user metadata of the wallet's addresses and transactions
+	Annotations Annotations
+	// SpendingPolicy limits the coins that the wallet sends to other addresses
+	SpendingPolicy SpendingPolicy
+	// Spends are the recorded spends of the transactions created by the wallet, for the daily limit of its spending policy
+	Spends []Spend
+	// Cosigners are the other parties whose signatures are required by the transactions of the wallet
+	Cosigners Cosigners
+	// AddressPool tracks the addresses of the wallet that have been used or handed out
+	AddressPool AddressPool
+	// Accounts are the named accounts of the wallet, besides the default account, in the order of their index
+	Accounts []Account
+	// Multisigs are the m-of-n multisigs of the multisig addresses of a watch-only wallet
+	Multisigs []cipher.Multisig
 }
 
 // newWallet creates a wallet instance with given name and options.
@@ -382,6 +480,12 @@ func newWallet(wltName string, opts Options, bg BalanceGetter) (*Wallet, error)
 		return nil, fmt.Errorf("Invalid coin type %q", coin)
 	}
 
+	if opts.BIP39 || opts.SeedPassphrase != "" {
+		if err := bip39.ValidateMnemonic(opts.Seed); err != nil {
+			return nil, NewError(fmt.Errorf("seed is not a valid BIP39 mnemonic: %v", err))
+		}
+	}
+
 	w := &Wallet{
 		Meta: map[string]string{
 			metaFilename:   wltName,
@@ -398,6 +502,10 @@ func newWallet(wltName string, opts Options, bg BalanceGetter) (*Wallet, error)
 		},
 	}
 
+	if opts.SeedPassphrase != "" {
+		w.setSeedPassphrase(opts.SeedPassphrase)
+	}
+
 	// Create a default wallet
 	generateN := opts.GenerateN
 	if generateN == 0 {
@@ -432,12 +540,13 @@ func newWallet(wltName string, opts Options, bg BalanceGetter) (*Wallet, error)
 	}
 
 	// Checks crypto type
-	if _, err := getCrypto(opts.CryptoType); err != nil {
+	crypto, err := getCryptoWithArgon2id(opts.CryptoType, opts.Argon2id)
+	if err != nil {
 		return nil, err
 	}
 
 	// Encrypt the wallet
-	if err := w.Lock(opts.Password, opts.CryptoType); err != nil {
+	if err := w.lock(opts.Password, opts.CryptoType, crypto); err != nil {
 		return nil, err
 	}
 
@@ -454,6 +563,82 @@ func NewWallet(wltName string, opts Options) (*Wallet, error) {
 	return newWallet(wltName, opts, nil)
 }
 
+// NewWatchOnlyWallet creates a watch-only wallet of skycoin addresses.
+// A watch-only wallet has no seed and no keys: it can query the balance and the transactions of its addresses,
+// and create unsigned transactions to be signed by the wallet that has the keys
+func NewWatchOnlyWallet(wltName, label string, addrs []cipher.Address) (*Wallet, error) {
+	if len(addrs) == 0 {
+		return nil, ErrMissingWatchAddresses
+	}
+
+	w := &Wallet{
+		Meta: map[string]string{
+			metaFilename:   wltName,
+			metaVersion:    Version,
+			metaLabel:      label,
+			metaTimestamp:  strconv.FormatInt(time.Now().Unix(), 10),
+			metaType:       WalletTypeWatchOnly,
+			metaCoin:       string(CoinTypeSkycoin),
+			metaEncrypted:  "false",
+			metaCryptoType: "",
+			metaSecrets:    "",
+		},
+	}
+
+	if err := w.AddWatchOnlyAddresses(addrs); err != nil {
+		return nil, err
+	}
+
+	if err := w.Validate(); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// NewCollectionWallet creates a collection wallet of the skycoin addresses of secret keys.
+// A collection wallet has no seed, its addresses are the addresses of its keys, which are not related
+func NewCollectionWallet(wltName, label string, keys []cipher.SecKey) (*Wallet, error) {
+	if len(keys) == 0 {
+		return nil, ErrMissingCollectionKeys
+	}
+
+	w := &Wallet{
+		Meta: map[string]string{
+			metaFilename:   wltName,
+			metaVersion:    Version,
+			metaLabel:      label,
+			metaTimestamp:  strconv.FormatInt(time.Now().Unix(), 10),
+			metaType:       WalletTypeCollection,
+			metaCoin:       string(CoinTypeSkycoin),
+			metaEncrypted:  "false",
+			metaCryptoType: "",
+			metaSecrets:    "",
+		},
+	}
+
+	for _, k := range keys {
+		p, err := cipher.PubKeyFromSecKey(k)
+		if err != nil {
+			return nil, NewError(fmt.Errorf("invalid secret key: %v", err))
+		}
+
+		if err := w.AddEntry(Entry{
+			Address: cipher.AddressFromPubKey(p),
+			Public:  p,
+			Secret:  k,
+		}); err != nil {
+			return nil, NewError(err)
+		}
+	}
+
+	if err := w.Validate(); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
 // NewWalletScanAhead creates wallet and scan ahead N addresses
 func NewWalletScanAhead(wltName string, opts Options, bg BalanceGetter) (*Wallet, error) {
 	return newWallet(wltName, opts, bg)
@@ -461,10 +646,19 @@ func NewWalletScanAhead(wltName string, opts Options, bg BalanceGetter) (*Wallet
 
 // Lock encrypts the wallet with the given password and specific crypto type
 func (w *Wallet) Lock(password []byte, cryptoType CryptoType) error {
+	return w.lock(password, cryptoType, nil)
+}
+
+// lock encrypts the wallet with the given password and crypto, the default crypto of the crypto type if nil
+func (w *Wallet) lock(password []byte, cryptoType CryptoType, crypto cryptor) error {
 	if len(password) == 0 {
 		return ErrMissingPassword
 	}
 
+	if w.IsWatchOnly() {
+		return ErrWalletWatchOnly
+	}
+
 	if w.IsEncrypted() {
 		return ErrWalletEncrypted
 	}
@@ -481,6 +675,9 @@ func (w *Wallet) Lock(password []byte, cryptoType CryptoType) error {
 
 	ss.set(secretSeed, wlt.seed())
 	ss.set(secretLastSeed, wlt.lastSeed())
+	if p := wlt.seedPassphrase(); p != "" {
+		ss.set(secretSeedPassphrase, p)
+	}
 
 	// Saves address's secret keys in secrets
 	for _, e := range wlt.Entries {
@@ -492,9 +689,11 @@ func (w *Wallet) Lock(password []byte, cryptoType CryptoType) error {
 		return err
 	}
 
-	crypto, err := getCrypto(cryptoType)
-	if err != nil {
-		return err
+	if crypto == nil {
+		crypto, err = getCrypto(cryptoType)
+		if err != nil {
+			return err
+		}
 	}
 
 	// Encrypts the secrets
@@ -582,6 +781,10 @@ func (w *Wallet) Unlock(password []byte) (*Wallet, error) {
 	}
 	wlt.setLastSeed(lastSeed)
 
+	if p, ok := ss.get(secretSeedPassphrase); ok {
+		wlt.setSeedPassphrase(p)
+	}
+
 	// Gets addresses related secrets
 	for i, e := range wlt.Entries {
 		sstr, ok := ss.get(e.Address.String())
@@ -619,9 +822,10 @@ func (w *Wallet) copyFrom(src *Wallet) {
 
 // Erase wipes secret fields in wallet
 func (w *Wallet) Erase() {
-	// Wipes the seed and last seed
+	// Wipes the seed, last seed and seed passphrase
 	w.setSeed("")
 	w.setLastSeed("")
+	delete(w.Meta, metaSeedPassphrase)
 
 	// Wipes private keys in entries
 	for i := range w.Entries {
@@ -634,17 +838,31 @@ func (w *Wallet) Erase() {
 }
 
 // GuardUpdate executes a function within the context of a read-write managed decrypted wallet.
+// The wallet is encrypted again with the same crypto type and key derivation parameters.
 // Returns ErrWalletNotEncrypted if wallet is not encrypted.
 func (w *Wallet) GuardUpdate(password []byte, fn func(w *Wallet) error) error {
 	if !w.IsEncrypted() {
 		return ErrWalletNotEncrypted
 	}
 
+	crypto, err := w.currentCrypto()
+	if err != nil {
+		return err
+	}
+
+	return w.guardUpdate(password, w.cryptoType(), crypto, fn)
+}
+
+// guardUpdate is GuardUpdate encrypting the updated wallet with the given crypto type and crypto
+func (w *Wallet) guardUpdate(password []byte, cryptoType CryptoType, crypto cryptor, fn func(w *Wallet) error) error {
+	if !w.IsEncrypted() {
+		return ErrWalletNotEncrypted
+	}
+
 	if len(password) == 0 {
 		return ErrMissingPassword
 	}
 
-	cryptoType := w.cryptoType()
 	wlt, err := w.Unlock(password)
 	if err != nil {
 		return err
@@ -656,7 +874,7 @@ func (w *Wallet) GuardUpdate(password []byte, fn func(w *Wallet) error) error {
 		return err
 	}
 
-	if err := wlt.Lock(password, cryptoType); err != nil {
+	if err := wlt.lock(password, cryptoType, crypto); err != nil {
 		return err
 	}
 
@@ -703,12 +921,20 @@ func Load(wltFile string) (*Wallet, error) {
 	return r.ToWallet()
 }
 
-// Save saves the wallet to given dir
+// Save saves the wallet to given dir, in the file format of its version
 func (w *Wallet) Save(dir string) error {
 	r := NewReadableWallet(w)
 	return r.Save(filepath.Join(dir, w.Filename()))
 }
 
+// upgradeVersion upgrades wallets of versions 0.1 and 0.2 to version 0.3, which only changes the file format.
+// The wallets are upgraded when the node saves them to a wallet directory.
+func (w *Wallet) upgradeVersion() {
+	if c, err := compareVersions(w.Version(), Version); err == nil && c < 0 {
+		w.setVersion(Version)
+	}
+}
+
 // removeBackupFiles removes any *.wlt.bak files whom have version 0.1 and *.wlt matched in the given directory
 func removeBackupFiles(dir string) error {
 	fs, err := filterDir(dir, ".wlt")
@@ -786,7 +1012,9 @@ func (w *Wallet) Validate() error {
 	if !ok {
 		return errors.New("type field not set")
 	}
-	if walletType != WalletTypeDeterministic {
+	switch walletType {
+	case WalletTypeDeterministic, WalletTypeWatchOnly, WalletTypeCollection:
+	default:
 		return errors.New("wallet type invalid")
 	}
 
@@ -804,6 +1032,19 @@ func (w *Wallet) Validate() error {
 		}
 	}
 
+	// watch-only wallets have no secrets
+	if walletType == WalletTypeWatchOnly {
+		if isEncrypted {
+			return errors.New("watch-only wallet can not be encrypted")
+		}
+
+		if s := w.Meta[metaSeed]; s != "" {
+			return errors.New("watch-only wallet has a seed")
+		}
+
+		return nil
+	}
+
 	// checks if the secrets field is empty
 	if isEncrypted {
 		cryptoType, ok := w.Meta[metaCryptoType]
@@ -818,6 +1059,10 @@ func (w *Wallet) Validate() error {
 		if s := w.Meta[metaSecrets]; s == "" {
 			return errors.New("wallet is encrypted, but secrets field not set")
 		}
+	} else if walletType == WalletTypeCollection {
+		if s := w.Meta[metaSeed]; s != "" {
+			return errors.New("collection wallet has a seed")
+		}
 	} else {
 		if s := w.Meta[metaSeed]; s == "" {
 			return errors.New("seed missing in unencrypted wallet")
@@ -836,6 +1081,35 @@ func (w *Wallet) Type() string {
 	return w.Meta[metaType]
 }
 
+// IsWatchOnly returns true if the wallet is a watch-only wallet, that can not sign transactions
+func (w *Wallet) IsWatchOnly() bool {
+	return w.Type() == WalletTypeWatchOnly
+}
+
+// AddWatchOnlyAddresses adds skycoin addresses to a watch-only wallet.
+// The addresses that the wallet has already are ignored
+func (w *Wallet) AddWatchOnlyAddresses(addrs []cipher.Address) error {
+	if !w.IsWatchOnly() {
+		return ErrWalletNotWatchOnly
+	}
+
+	for _, a := range addrs {
+		if a.Null() {
+			return NewError(errors.New("watched address must not be the null address"))
+		}
+
+		if _, ok := w.GetEntry(a); ok {
+			continue
+		}
+
+		w.Entries = append(w.Entries, Entry{
+			Address: a,
+		})
+	}
+
+	return nil
+}
+
 // Version gets the wallet version
 func (w *Wallet) Version() string {
 	return w.Meta[metaVersion]
@@ -882,6 +1156,28 @@ func (w *Wallet) setSeed(seed string) {
 	w.Meta[metaSeed] = seed
 }
 
+func (w *Wallet) seedPassphrase() string {
+	return w.Meta[metaSeedPassphrase]
+}
+
+func (w *Wallet) setSeedPassphrase(p string) {
+	w.Meta[metaSeedPassphrase] = p
+}
+
+// keySeed returns the seed that the first keys of the wallet are generated from
+func (w *Wallet) keySeed() []byte {
+	return keySeed(w.seed(), w.seedPassphrase())
+}
+
+// keySeed returns the seed that the first keys are generated from, given the wallet seed and its BIP39 passphrase.
+// Without a passphrase, the keys are generated from the wallet seed itself
+func keySeed(seed, passphrase string) []byte {
+	if passphrase == "" {
+		return []byte(seed)
+	}
+	return bip39.NewSeed(seed, passphrase)
+}
+
 func (w *Wallet) coin() CoinType {
 	return CoinType(w.Meta[metaCoin])
 }
@@ -952,7 +1248,7 @@ func (w *Wallet) setTimestamp(t int64) {
 	w.Meta[metaTimestamp] = strconv.FormatInt(t, 10)
 }
 
-// GenerateAddresses generates addresses
+// GenerateAddresses generates addresses in the default account
 func (w *Wallet) GenerateAddresses(num uint64) ([]cipher.Addresser, error) {
 	if num == 0 {
 		return nil, nil
@@ -962,10 +1258,18 @@ func (w *Wallet) GenerateAddresses(num uint64) ([]cipher.Addresser, error) {
 		return nil, ErrWalletEncrypted
 	}
 
+	if w.IsWatchOnly() {
+		return nil, ErrWalletWatchOnly
+	}
+
+	if w.Type() == WalletTypeCollection {
+		return nil, ErrWalletNotDeterministic
+	}
+
 	var seckeys []cipher.SecKey
 	var seed []byte
-	if len(w.Entries) == 0 {
-		seed, seckeys = cipher.MustGenerateDeterministicKeyPairsSeed([]byte(w.seed()), int(num))
+	if w.accountSize(0) == 0 {
+		seed, seckeys = cipher.MustGenerateDeterministicKeyPairsSeed(w.keySeed(), int(num))
 	} else {
 		sd, err := hex.DecodeString(w.lastSeed())
 		if err != nil {
@@ -1130,6 +1434,13 @@ func (w *Wallet) clone() *Wallet {
 	}
 
 	wlt.Entries = append(wlt.Entries, w.Entries...)
+	wlt.Annotations = w.Annotations.clone()
+	wlt.SpendingPolicy = w.SpendingPolicy.clone()
+	wlt.Spends = append(wlt.Spends, w.Spends...)
+	wlt.Cosigners = w.Cosigners.clone()
+	wlt.AddressPool = w.AddressPool.clone()
+	wlt.Accounts = append(wlt.Accounts, w.Accounts...)
+	wlt.Multisigs = cloneMultisigs(w.Multisigs)
 
 	return &wlt
 }
@@ -1147,6 +1458,10 @@ func (w *Wallet) CreateAndSignTransaction(auxs coin.AddressUxOuts, headTime, coi
 		return nil, ErrWalletEncrypted
 	}
 
+	if w.IsWatchOnly() {
+		return nil, ErrWalletWatchOnly
+	}
+
 	entriesMap := make(map[cipher.Address]Entry)
 	for a := range auxs {
 		e, ok := w.GetEntry(a)
@@ -1222,8 +1537,8 @@ func (w *Wallet) CreateAndSignTransaction(auxs coin.AddressUxOuts, headTime, coi
 // CreateAndSignTransactionAdvanced creates and signs a transaction based upon CreateTransactionParams.
 // Set the password as nil if the wallet is not encrypted, otherwise the password must be provided.
 // NOTE: Caller must ensure that auxs correspond to params.Wallet.Addresses and params.Wallet.UxOuts options
-// Outputs to spend are chosen from the pool of outputs provided.
-// The outputs are chosen by the following procedure:
+// Outputs to spend are chosen from the pool of outputs provided, by the p.CoinSelection strategy.
+// With the default strategy, CoinSelectionMinimizeUxOuts, the outputs are chosen by the following procedure:
 //   - All outputs are merged into one list and are sorted coins highest, hours lowest, with the hash as a tiebreaker
 //   - Outputs are chosen from the beginning of this list, until the requested amount of coins is met.
 //     If hours are also specified, selection continues until the requested amount of hours are met.
@@ -1232,6 +1547,7 @@ func (w *Wallet) CreateAndSignTransaction(auxs coin.AddressUxOuts, headTime, coi
 //     if the coinhour cost of adding that output is less than the coinhours that would be lost as change
 // If receiving hours are not explicitly specified, hours are allocated amongst the receiving outputs proportional to the number of coins being sent to them.
 // If the change address is not specified, the address whose bytes are lexically sorted first is chosen from the owners of the outputs being spent.
+// If p.Unsigned is set, the transaction is not signed, and the wallet can be encrypted or watch-only.
 func (w *Wallet) CreateAndSignTransactionAdvanced(p CreateTransactionParams, auxs coin.AddressUxOuts, headTime uint64) (*coin.Transaction, []UxBalance, error) {
 	if err := p.Validate(); err != nil {
 		return nil, nil, err
@@ -1241,15 +1557,30 @@ func (w *Wallet) CreateAndSignTransactionAdvanced(p CreateTransactionParams, aux
 		return nil, nil, NewError(errors.New("p.Wallet.ID does not match wallet"))
 	}
 
-	if w.IsEncrypted() {
-		return nil, nil, ErrWalletEncrypted
+	if !p.Unsigned {
+		if w.IsEncrypted() {
+			return nil, nil, ErrWalletEncrypted
+		}
+
+		if w.IsWatchOnly() {
+			return nil, nil, ErrWalletWatchOnly
+		}
+	}
+
+	var account *Account
+	if p.Wallet.Account != "" {
+		a, err := w.GetAccount(p.Wallet.Account)
+		if err != nil {
+			return nil, nil, err
+		}
+		account = &a
 	}
 
 	entriesMap := make(map[cipher.Address]Entry)
 	for a := range auxs {
-		// Check that auxs does not contain addresses that are not known to this wallet
+		// Check that auxs does not contain addresses that are not known to this wallet, or to the account spent from
 		e, ok := w.GetEntry(a)
-		if !ok {
+		if !ok || (account != nil && e.Account != account.Index) {
 			return nil, nil, ErrUnknownAddress
 		}
 		entriesMap[e.SkycoinAddress()] = e
@@ -1289,10 +1620,10 @@ func (w *Wallet) CreateAndSignTransactionAdvanced(p CreateTransactionParams, aux
 		}
 	}
 
-	// Use the MinimizeUxOuts strategy, to use least possible uxouts
+	// Use the MinimizeUxOuts strategy by default, to use least possible uxouts
 	// this will allow more frequent spending
 	// we don't need to check whether we have sufficient balance beforehand as ChooseSpends already checks that
-	spends, err := ChooseSpendsMinimizeUxOuts(uxb, totalOutCoins, requestedHours)
+	spends, err := ChooseSpendsStrategy(p.CoinSelection, uxb, totalOutCoins, requestedHours)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -1459,53 +1790,37 @@ func (w *Wallet) CreateAndSignTransactionAdvanced(p CreateTransactionParams, aux
 		return w.CreateAndSignTransactionAdvanced(p, auxs, headTime)
 	}
 
-	if changeCoins > 0 {
-		var changeAddress cipher.Address
-		if p.ChangeAddress != nil {
-			changeAddress = *p.ChangeAddress
-		} else {
-			// Choose a change address from the unspent outputs
-			// Sort spends by address, comparing bytes, and use the first
-			// This provides deterministic change address selection from a set of unspent outputs
-			if len(spends) == 0 {
-				return nil, nil, errors.New("spends is unexpectedly empty when choosing an automatic change address")
-			}
-
-			addressBytes := make([][]byte, len(spends))
-			for i, s := range spends {
-				addressBytes[i] = s.Address.Bytes()
-			}
-
-			sort.Slice(addressBytes, func(i, j int) bool {
-				return bytes.Compare(addressBytes[i], addressBytes[j]) < 0
-			})
+	inputs := make([]UxBalance, len(txn.In))
+	for i, h := range txn.In {
+		uxBalance, ok := uxbMap[h]
+		if !ok {
+			return nil, nil, errors.New("Created transaction's input is not in the UxBalanceSet, this should not occur")
+		}
+		inputs[i] = uxBalance
+	}
 
-			var err error
-			changeAddress, err = cipher.AddressFromBytes(addressBytes[0])
-			if err != nil {
-				logger.Critical().WithError(err).Error("cipher.AddressFromBytes failed for change address converted to bytes")
-				return nil, nil, err
-			}
+	if changeCoins > 0 {
+		changeAddress, err := w.chooseChangeAddress(p, auxs, spends, inputs)
+		if err != nil {
+			return nil, nil, err
 		}
 
 		txn.PushOutput(changeAddress, changeCoins, changeHours)
 	}
 
-	txn.SignInputs(toSign)
+	if p.Unsigned {
+		txn.Sigs = make([]cipher.Sig, len(txn.In))
+		if err := w.pushMultisigWitnesses(txn, inputs); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		txn.SignInputs(toSign)
+	}
 	if err := txn.UpdateHeader(); err != nil {
 		logger.Critical().WithError(err).Error("txn.UpdateHeader failed")
 		return nil, nil, err
 	}
 
-	inputs := make([]UxBalance, len(txn.In))
-	for i, h := range txn.In {
-		uxBalance, ok := uxbMap[h]
-		if !ok {
-			return nil, nil, errors.New("Created transaction's input is not in the UxBalanceSet, this should not occur")
-		}
-		inputs[i] = uxBalance
-	}
-
 	if err := verifyCreatedTransactionInvariants(p, txn, inputs); err != nil {
 		logger.Critical().WithError(err).Error("CreateAndSignTransactionAdvanced created transaction that violates invariants, aborting")
 		return nil, nil, fmt.Errorf("Created transaction that violates invariants, this is a bug: %v", err)
@@ -1547,7 +1862,7 @@ func verifyCreatedTransactionInvariants(p CreateTransactionParams, txn *coin.Tra
 		}
 	}
 
-	if len(txn.Sigs) != len(txn.In) {
+	if len(txn.Sigs) != len(txn.In) && txn.Type != coin.TransactionTypeMultisig {
 		return errors.New("Number of signatures does not match number of inputs")
 	}
 
@@ -1928,19 +2243,8 @@ func cmpUxBalanceByUxID(a, b UxBalance) bool {
 // It then chooses uxouts with zero coinhours, ordered by sortStrategy
 // It then chooses remaining uxouts with nonzero coinhours, ordered by sortStrategy
 func ChooseSpends(uxa []UxBalance, coins, hours uint64, sortStrategy func([]UxBalance)) ([]UxBalance, error) {
-	if coins == 0 {
-		return nil, ErrZeroSpend
-	}
-
-	if len(uxa) == 0 {
-		return nil, ErrNoUnspents
-	}
-
-	for _, ux := range uxa {
-		if ux.Coins == 0 {
-			logger.Panic("UxOut coins are 0, can't spend")
-			return nil, errors.New("UxOut coins are 0, can't spend")
-		}
+	if err := checkSpends(uxa, coins); err != nil {
+		return nil, err
 	}
 
 	// Split UxBalances into those with and without hours
@@ -1953,11 +2257,6 @@ func ChooseSpends(uxa []UxBalance, coins, hours uint64, sortStrategy func([]UxBa
 		}
 	}
 
-	// Abort if there are no uxouts with non-zero coinhours, they can't be spent yet
-	if len(nonzero) == 0 {
-		return nil, fee.ErrTxnNoFee
-	}
-
 	// Sort uxouts with hours lowest to highest and coins highest to lowest
 	sortSpendsCoinsHighToLow(nonzero)
 
@@ -2020,3 +2319,166 @@ func ChooseSpends(uxa []UxBalance, coins, hours uint64, sortStrategy func([]UxBa
 
 	return nil, ErrInsufficientHours
 }
+
+// ChooseSpendsStrategy chooses uxouts with a CoinSelection strategy.
+// An empty strategy is CoinSelectionMinimizeUxOuts.
+func ChooseSpendsStrategy(strategy string, uxa []UxBalance, coins, hours uint64) ([]UxBalance, error) {
+	switch strategy {
+	case "", CoinSelectionMinimizeUxOuts:
+		return ChooseSpendsMinimizeUxOuts(uxa, coins, hours)
+	case CoinSelectionMinimizeBurn:
+		return ChooseSpendsMinimizeBurn(uxa, coins, hours)
+	case CoinSelectionOldestFirst:
+		return ChooseSpendsOldestFirst(uxa, coins, hours)
+	case CoinSelectionRandom:
+		seed := int64(binary.LittleEndian.Uint64(cipher.RandByte(8)))
+		return ChooseSpendsRandom(uxa, coins, hours, rand.New(rand.NewSource(seed)))
+	case CoinSelectionExact:
+		return ChooseSpendsExact(uxa, coins, hours)
+	default:
+		return nil, ErrInvalidCoinSelection
+	}
+}
+
+// ChooseSpendsMinimizeBurn chooses uxouts with the least coin hours first, to satisfy an amount.
+// The coin hour fee is a fraction of the coin hours of the inputs, so this minimizes the coin hours burned.
+// Uxouts with zero coin hours are chosen first, but at least one uxout with coin hours is chosen to pay the fee.
+func ChooseSpendsMinimizeBurn(uxa []UxBalance, coins, hours uint64) ([]UxBalance, error) {
+	if err := checkSpends(uxa, coins); err != nil {
+		return nil, err
+	}
+
+	uxa = copyUxBalances(uxa)
+	sort.Slice(uxa, makeCmpUxOutByHours(uxa, func(a, b uint64) bool {
+		return a < b
+	}))
+
+	return chooseSpendsInOrder(uxa, coins, hours)
+}
+
+// ChooseSpendsOldestFirst chooses uxouts in the order they were created, to satisfy an amount.
+// Spending the oldest uxouts first keeps the age of the remaining uxouts low.
+func ChooseSpendsOldestFirst(uxa []UxBalance, coins, hours uint64) ([]UxBalance, error) {
+	if err := checkSpends(uxa, coins); err != nil {
+		return nil, err
+	}
+
+	uxa = copyUxBalances(uxa)
+	sort.Slice(uxa, func(i, j int) bool {
+		a := uxa[i]
+		b := uxa[j]
+
+		// Sort by:
+		// oldest first
+		//  tie break with hash comparison
+		if a.BkSeq == b.BkSeq {
+			return cmpUxBalanceByUxID(a, b)
+		}
+		return a.BkSeq < b.BkSeq
+	})
+
+	return chooseSpendsInOrder(uxa, coins, hours)
+}
+
+// ChooseSpendsRandom chooses uxouts in random order, to satisfy an amount.
+// The uxouts chosen are not predictable from the balance of the addresses, which improves privacy.
+// The uxouts are sorted by hash before they are shuffled, so the choice only depends on rnd.
+func ChooseSpendsRandom(uxa []UxBalance, coins, hours uint64, rnd *rand.Rand) ([]UxBalance, error) {
+	if err := checkSpends(uxa, coins); err != nil {
+		return nil, err
+	}
+
+	uxa = copyUxBalances(uxa)
+	sort.Slice(uxa, func(i, j int) bool {
+		return cmpUxBalanceByUxID(uxa[i], uxa[j])
+	})
+	rnd.Shuffle(len(uxa), func(i, j int) {
+		uxa[i], uxa[j] = uxa[j], uxa[i]
+	})
+
+	return chooseSpendsInOrder(uxa, coins, hours)
+}
+
+// ChooseSpendsExact chooses all the uxouts, such as to sweep the uxouts specified for a transaction.
+// Returns an error if the uxouts do not satisfy the amount.
+func ChooseSpendsExact(uxa []UxBalance, coins, hours uint64) ([]UxBalance, error) {
+	if err := checkSpends(uxa, coins); err != nil {
+		return nil, err
+	}
+
+	var have Balance
+	for _, ux := range uxa {
+		have.Coins += ux.Coins
+		have.Hours += ux.Hours
+	}
+
+	if have.Coins < coins {
+		return nil, ErrInsufficientBalance
+	}
+
+	if fee.RemainingHours(have.Hours, params.UserBurnFactor) < hours {
+		return nil, ErrInsufficientHours
+	}
+
+	return copyUxBalances(uxa), nil
+}
+
+// checkSpends checks that uxouts can be chosen to spend coins
+func checkSpends(uxa []UxBalance, coins uint64) error {
+	if coins == 0 {
+		return ErrZeroSpend
+	}
+
+	if len(uxa) == 0 {
+		return ErrNoUnspents
+	}
+
+	var hasHours bool
+	for _, ux := range uxa {
+		if ux.Coins == 0 {
+			logger.Panic("UxOut coins are 0, can't spend")
+			return errors.New("UxOut coins are 0, can't spend")
+		}
+
+		if ux.Hours != 0 {
+			hasHours = true
+		}
+	}
+
+	// Abort if there are no uxouts with non-zero coinhours, they can't be spent yet
+	if !hasHours {
+		return fee.ErrTxnNoFee
+	}
+
+	return nil
+}
+
+// chooseSpendsInOrder chooses uxouts from the beginning of a sorted list, until the amount is satisfied
+// and the uxouts have coin hours to pay the fee
+func chooseSpendsInOrder(uxa []UxBalance, coins, hours uint64) ([]UxBalance, error) {
+	var have Balance
+	var spending []UxBalance
+
+	for _, ux := range uxa {
+		spending = append(spending, ux)
+
+		have.Coins += ux.Coins
+		have.Hours += ux.Hours
+
+		if have.Coins >= coins && have.Hours != 0 && fee.RemainingHours(have.Hours, params.UserBurnFactor) >= hours {
+			return spending, nil
+		}
+	}
+
+	if have.Coins < coins {
+		return nil, ErrInsufficientBalance
+	}
+
+	return nil, ErrInsufficientHours
+}
+
+func copyUxBalances(uxa []UxBalance) []UxBalance {
+	c := make([]UxBalance, len(uxa))
+	copy(c, uxa)
+	return c
+}
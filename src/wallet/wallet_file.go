@@ -0,0 +1,241 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/skycoin/skycoin/src/util/file"
+)
+
+const (
+	// checksumVersion is the first wallet version whose file has a checksum and a write counter
+	checksumVersion = "0.3"
+	// backupExt is appended to the name of a wallet file for its last good copy
+	backupExt = ".bak"
+)
+
+var (
+	// ErrWalletChecksum is returned when the checksum of a wallet file doesn't match its contents
+	ErrWalletChecksum = NewError(errors.New("wallet file checksum mismatch, the file is corrupted"))
+)
+
+// checksumPlaceholder is the checksum of a wallet file while its checksum is computed
+var checksumPlaceholder = strings.Repeat("0", sha256.Size*2)
+
+// walletFileChecksum returns the checksum of a wallet file whose checksum field holds checksum:
+// the hex SHA256 of the raw file with the value of the checksum field blanked out.
+// The file is hashed as it is, so that the fields unknown to this version don't change the checksum.
+func walletFileChecksum(b []byte, checksum string) (string, error) {
+	field := regexp.MustCompile(`"checksum"\s*:\s*"` + regexp.QuoteMeta(checksum) + `"`)
+	locs := field.FindAllIndex(b, 2)
+	if len(locs) != 1 {
+		return "", ErrWalletChecksum
+	}
+
+	h := sha256.New()
+	h.Write(b[:locs[0][0]])          // nolint: errcheck
+	h.Write([]byte(`"checksum":""`)) // nolint: errcheck
+	h.Write(b[locs[0][1]:])          // nolint: errcheck
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// decodeWalletFile decodes the contents of a wallet file.
+// The checksum of wallet files of version 0.3 or later is verified.
+func decodeWalletFile(b []byte) (*ReadableWallet, error) {
+	var rw ReadableWallet
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&rw); err != nil {
+		return nil, err
+	}
+
+	if hasChecksum(rw.Meta[metaVersion]) {
+		checksum, err := walletFileChecksum(b, rw.Checksum)
+		if err != nil {
+			return nil, err
+		}
+
+		if rw.Checksum != checksum {
+			return nil, ErrWalletChecksum
+		}
+	}
+
+	return &rw, nil
+}
+
+// encodeWalletFile encodes a wallet file with its checksum
+func encodeWalletFile(rw *ReadableWallet) ([]byte, error) {
+	rw.Checksum = checksumPlaceholder
+	b, err := json.MarshalIndent(rw, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+
+	checksum, err := walletFileChecksum(b, rw.Checksum)
+	if err != nil {
+		return nil, err
+	}
+	rw.Checksum = checksum
+
+	return json.MarshalIndent(rw, "", "    ")
+}
+
+// hasChecksum returns true if the files of a wallet version have a checksum and a write counter
+func hasChecksum(version string) bool {
+	c, err := compareVersions(version, checksumVersion)
+	return err == nil && c >= 0
+}
+
+// compareVersions compares two wallet versions made of dot separated numbers, such as "0.3".
+// Returns -1 if a is older than b, 1 if a is newer than b and 0 if they are the same version
+func compareVersions(a, b string) (int, error) {
+	av, err := parseVersion(a)
+	if err != nil {
+		return 0, err
+	}
+
+	bv, err := parseVersion(b)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := 0; i < len(av) || i < len(bv); i++ {
+		var x, y uint64
+		if i < len(av) {
+			x = av[i]
+		}
+		if i < len(bv) {
+			y = bv[i]
+		}
+
+		switch {
+		case x < y:
+			return -1, nil
+		case x > y:
+			return 1, nil
+		}
+	}
+
+	return 0, nil
+}
+
+// parseVersion parses the numbers of a wallet version
+func parseVersion(version string) ([]uint64, error) {
+	if version == "" {
+		return nil, fmt.Errorf("invalid wallet version %q", version)
+	}
+
+	parts := strings.Split(version, ".")
+	v := make([]uint64, len(parts))
+	for i, p := range parts {
+		n, err := strconv.ParseUint(p, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid wallet version %q", version)
+		}
+		v[i] = n
+	}
+
+	return v, nil
+}
+
+// readWalletFile reads and decodes a wallet file
+func readWalletFile(filename string) (*ReadableWallet, []byte, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rw, err := decodeWalletFile(b)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return rw, b, nil
+}
+
+// loadWalletFile loads a wallet file. If the file can't be decoded or is corrupted,
+// the wallet is loaded from the last good copy of the file which is kept by saveWalletFile, if there is one.
+func loadWalletFile(filename string) (*ReadableWallet, error) {
+	rw, _, err := readWalletFile(filename)
+	if err == nil || os.IsNotExist(err) {
+		return rw, err
+	}
+
+	backup, _, backupErr := readWalletFile(filename + backupExt)
+	if backupErr != nil {
+		return nil, fmt.Errorf("wallet file %s is invalid: %v", filename, err)
+	}
+
+	logger.WithError(err).Warningf("Wallet file %s is invalid, loaded its backup %s%s written %d times",
+		filename, filename, backupExt, backup.WriteCounter)
+	return backup, nil
+}
+
+// saveWalletFile saves a wallet file.
+//
+// Wallets of version 0.3 or later are written with an incremented write counter and a checksum,
+// to a temporary file that atomically replaces the wallet file.
+// The previous wallet file, if valid, is kept as the last good copy of the wallet,
+// unless it was encrypted differently, so that no secrets are left behind when a wallet is encrypted.
+//
+// Wallets of older versions are written in their file format.
+func saveWalletFile(filename string, rw *ReadableWallet) error {
+	if !hasChecksum(rw.Meta[metaVersion]) {
+		return file.SaveJSON(filename, rw, 0600)
+	}
+
+	backupFilename := filename + backupExt
+	prev, prevData, err := readWalletFile(filename)
+	switch {
+	case err == nil:
+		if rw.WriteCounter < prev.WriteCounter {
+			rw.WriteCounter = prev.WriteCounter
+		}
+
+		if sameEncryption(prev, rw) {
+			if err := file.SaveBinaryAtomic(backupFilename, prevData, 0600); err != nil {
+				return err
+			}
+		} else if err := removeFile(backupFilename); err != nil {
+			return err
+		}
+	case os.IsNotExist(err):
+	default:
+		// The wallet file is corrupted, keep the last good copy if there is one
+		logger.WithError(err).Warningf("Overwriting invalid wallet file %s", filename)
+		if backup, _, err := readWalletFile(backupFilename); err == nil && rw.WriteCounter < backup.WriteCounter {
+			rw.WriteCounter = backup.WriteCounter
+		}
+	}
+
+	rw.WriteCounter++
+
+	b, err := encodeWalletFile(rw)
+	if err != nil {
+		return err
+	}
+
+	return file.SaveBinaryAtomic(filename, b, 0600)
+}
+
+// sameEncryption returns true if two wallets are encrypted the same way
+func sameEncryption(a, b *ReadableWallet) bool {
+	return a.Meta[metaEncrypted] == b.Meta[metaEncrypted] && a.Meta[metaCryptoType] == b.Meta[metaCryptoType]
+}
+
+// removeFile removes a file if it exists
+func removeFile(filename string) error {
+	if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,280 @@
+package wallet
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func readTestWalletFile(t *testing.T, filename string) *ReadableWallet {
+	b, err := ioutil.ReadFile(filename)
+	require.NoError(t, err)
+	rw, err := decodeWalletFile(b)
+	require.NoError(t, err)
+	return rw
+}
+
+func TestWalletFileChecksumAndWriteCounter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wallet-file")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := NewWallet("t.wlt", Options{
+		Seed:  "seed",
+		Label: "t",
+	})
+	require.NoError(t, err)
+	require.Equal(t, Version, w.Version())
+
+	filename := filepath.Join(dir, "t.wlt")
+	require.NoError(t, w.Save(dir))
+
+	rw := readTestWalletFile(t, filename)
+	require.Equal(t, uint64(1), rw.WriteCounter)
+	require.NotEmpty(t, rw.Checksum)
+	_, err = os.Stat(filename + backupExt)
+	require.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filename + ".tmp")
+	require.True(t, os.IsNotExist(err))
+
+	// The previous file is kept as the last good copy
+	_, err = w.GenerateAddresses(1)
+	require.NoError(t, err)
+	require.NoError(t, w.Save(dir))
+
+	rw = readTestWalletFile(t, filename)
+	require.Equal(t, uint64(2), rw.WriteCounter)
+	backup := readTestWalletFile(t, filename+backupExt)
+	require.Equal(t, uint64(1), backup.WriteCounter)
+	require.Len(t, backup.Entries, 1)
+
+	w2, err := Load(filename)
+	require.NoError(t, err)
+	require.Len(t, w2.Entries, 2)
+
+	// Corrupt the wallet file, the last good copy is loaded
+	b, err := ioutil.ReadFile(filename)
+	require.NoError(t, err)
+	b = []byte(strings.Replace(string(b), `"label": "t"`, `"label": "x"`, 1))
+	require.NoError(t, ioutil.WriteFile(filename, b, 0600))
+
+	_, err = decodeWalletFile(b)
+	require.Equal(t, ErrWalletChecksum, err)
+
+	w2, err = Load(filename)
+	require.NoError(t, err)
+	require.Equal(t, "t", w2.Label())
+	require.Len(t, w2.Entries, 1)
+
+	// Saving over the corrupted file keeps the last good copy and the write counter increasing
+	require.NoError(t, w2.Save(dir))
+	rw = readTestWalletFile(t, filename)
+	require.Equal(t, uint64(2), rw.WriteCounter)
+	backup = readTestWalletFile(t, filename+backupExt)
+	require.Equal(t, uint64(1), backup.WriteCounter)
+
+	// Without a valid copy, the corrupted file can't be loaded
+	require.NoError(t, ioutil.WriteFile(filename, b, 0600))
+	require.NoError(t, os.Remove(filename+backupExt))
+	_, err = Load(filename)
+	require.Error(t, err)
+	require.True(t, strings.Contains(err.Error(), ErrWalletChecksum.Error()))
+}
+
+func TestWalletFileEncryptRemovesBackup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wallet-file")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := NewWallet("t.wlt", Options{
+		Seed:      "seed",
+		Label:     "t",
+		GenerateN: 1,
+	})
+	require.NoError(t, err)
+
+	filename := filepath.Join(dir, "t.wlt")
+	require.NoError(t, w.Save(dir))
+	require.NoError(t, w.Save(dir))
+	_, err = os.Stat(filename + backupExt)
+	require.NoError(t, err)
+
+	// The unencrypted copy of the wallet must not be left behind
+	require.NoError(t, w.Lock([]byte("pwd"), CryptoTypeSha256Xor))
+	require.NoError(t, w.Save(dir))
+	_, err = os.Stat(filename + backupExt)
+	require.True(t, os.IsNotExist(err))
+
+	rw := readTestWalletFile(t, filename)
+	require.Equal(t, uint64(3), rw.WriteCounter)
+}
+
+func TestWalletFileUpgrade(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wallet-file")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tt := []struct {
+		name            string
+		version         string
+		expectedVersion string
+		hasChecksum     bool
+	}{
+		{
+			name:            "v0.1 is upgraded",
+			version:         "0.1",
+			expectedVersion: Version,
+			hasChecksum:     true,
+		},
+		{
+			name:            "v0.2 is upgraded",
+			version:         "0.2",
+			expectedVersion: Version,
+			hasChecksum:     true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			w, err := NewWallet("t"+tc.version+".wlt", Options{
+				Seed:  "seed",
+				Label: "t",
+			})
+			require.NoError(t, err)
+			w.setVersion(tc.version)
+
+			// Wallets of older versions are loaded from files without a checksum
+			filename := filepath.Join(dir, w.Filename())
+			require.NoError(t, NewReadableWallet(w).Save(filename))
+			rw := readTestWalletFile(t, filename)
+			require.Empty(t, rw.Checksum)
+
+			w, err = Load(filename)
+			require.NoError(t, err)
+			require.Equal(t, tc.version, w.Version())
+
+			// The wallet is upgraded when it is saved to a wallet directory
+			fs, err := NewFileStorage(dir)
+			require.NoError(t, err)
+			require.NoError(t, fs.Save(w))
+			require.Equal(t, tc.expectedVersion, w.Version())
+
+			rw = readTestWalletFile(t, filename)
+			require.Equal(t, tc.expectedVersion, rw.Meta[metaVersion])
+			require.Equal(t, tc.hasChecksum, rw.Checksum != "")
+			require.Equal(t, tc.hasChecksum, rw.WriteCounter == 1)
+		})
+	}
+}
+
+func TestWalletFileUnknownFields(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wallet-file")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := NewWallet("t.wlt", Options{
+		Seed:  "seed",
+		Label: "t",
+	})
+	require.NoError(t, err)
+
+	filename := filepath.Join(dir, "t.wlt")
+	require.NoError(t, w.Save(dir))
+	b, err := ioutil.ReadFile(filename)
+	require.NoError(t, err)
+	rw := readTestWalletFile(t, filename)
+
+	// A file written by a newer version can have fields that this version doesn't decode,
+	// they are covered by the checksum of the file
+	b = []byte(strings.Replace(string(b), "{", `{
+    "future_field": {"checksum": "x", "n": 1.50},`, 1))
+	checksum, err := walletFileChecksum(b, rw.Checksum)
+	require.NoError(t, err)
+	b = []byte(strings.Replace(string(b), rw.Checksum, checksum, 1))
+
+	rw2, err := decodeWalletFile(b)
+	require.NoError(t, err)
+	require.Equal(t, checksum, rw2.Checksum)
+	require.Equal(t, rw.Entries, rw2.Entries)
+
+	// Changing an unknown field is detected
+	_, err = decodeWalletFile([]byte(strings.Replace(string(b), "1.50", "1.5", 1)))
+	require.Equal(t, ErrWalletChecksum, err)
+
+	// A file without its checksum field is corrupted
+	_, err = decodeWalletFile([]byte(strings.Replace(string(b), `"checksum": "`+checksum+`"`, `"checksum2": "`+checksum+`"`, 1)))
+	require.Equal(t, ErrWalletChecksum, err)
+}
+
+func TestRemoveBackupFilesKeepsLastGoodCopy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wallet-file")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := NewWallet("t.wlt", Options{
+		Seed: "seed",
+	})
+	require.NoError(t, err)
+	require.NoError(t, w.Save(dir))
+	require.NoError(t, w.Save(dir))
+
+	// The last good copy of a wallet of version 0.3 is not a backup of the 0.1 upgrade
+	require.NoError(t, removeBackupFiles(dir))
+	_, err = os.Stat(filepath.Join(dir, "t.wlt"+backupExt))
+	require.NoError(t, err)
+}
+
+func TestHasChecksum(t *testing.T) {
+	tt := []struct {
+		version     string
+		hasChecksum bool
+	}{
+		{"", false},
+		{"0.1", false},
+		{"0.2", false},
+		{"0.3", true},
+		{"0.10", true},
+		{"1.0", true},
+		{"0.3.1", true},
+		{"0.x", false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.version, func(t *testing.T) {
+			require.Equal(t, tc.hasChecksum, hasChecksum(tc.version))
+		})
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tt := []struct {
+		a, b string
+		c    int
+		err  bool
+	}{
+		{a: "0.3", b: "0.3", c: 0},
+		{a: "0.3", b: "0.3.0", c: 0},
+		{a: "0.2", b: "0.3", c: -1},
+		{a: "0.10", b: "0.3", c: 1},
+		{a: "0.3", b: "0.10", c: -1},
+		{a: "1.0", b: "0.10", c: 1},
+		{a: "", b: "0.3", err: true},
+		{a: "0.3", b: "0..3", err: true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.a+" "+tc.b, func(t *testing.T) {
+			c, err := compareVersions(tc.a, tc.b)
+			if tc.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.c, c)
+		})
+	}
+}
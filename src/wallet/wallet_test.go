@@ -9,6 +9,8 @@ import (
 	"io/ioutil"
 	"math"
 	"math/rand"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -17,6 +19,7 @@ import (
 
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/cipher/encrypt"
+	"github.com/skycoin/skycoin/src/cipher/go-bip39"
 	"github.com/skycoin/skycoin/src/coin"
 	"github.com/skycoin/skycoin/src/params"
 	"github.com/skycoin/skycoin/src/testutil"
@@ -280,6 +283,183 @@ func TestNewWallet(t *testing.T) {
 	}
 }
 
+func TestNewWalletBIP39(t *testing.T) {
+	mnemonic := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+	// Invalid mnemonics are rejected with the reason
+	_, err := NewWallet("t.wlt", Options{
+		Seed:  "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon",
+		BIP39: true,
+	})
+	require.Equal(t, NewError(fmt.Errorf("seed is not a valid BIP39 mnemonic: %v", bip39.ErrChecksumMismatch)), err)
+
+	_, err = NewWallet("t.wlt", Options{
+		Seed:           "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abuot",
+		SeedPassphrase: "foo",
+	})
+	require.Equal(t, NewError(fmt.Errorf("seed is not a valid BIP39 mnemonic: %v", bip39.UnknownWordError{
+		Index:    11,
+		Language: bip39.DefaultLanguage,
+	})), err)
+
+	// Without a passphrase, the keys are generated from the mnemonic like any other seed
+	w, err := NewWallet("t.wlt", Options{
+		Seed:      mnemonic,
+		BIP39:     true,
+		GenerateN: 2,
+	})
+	require.NoError(t, err)
+	plain, err := NewWallet("t.wlt", Options{
+		Seed:      mnemonic,
+		GenerateN: 2,
+	})
+	require.NoError(t, err)
+	require.Equal(t, plain.Entries, w.Entries)
+	_, ok := w.Meta[metaSeedPassphrase]
+	require.False(t, ok)
+
+	// With a passphrase, the keys are generated from the BIP39 seed
+	w, err = NewWallet("t.wlt", Options{
+		Seed:           mnemonic,
+		SeedPassphrase: "TREZOR",
+		GenerateN:      2,
+	})
+	require.NoError(t, err)
+	_, seckeys := cipher.MustGenerateDeterministicKeyPairsSeed(bip39.NewSeed(mnemonic, "TREZOR"), 2)
+	require.Len(t, w.Entries, 2)
+	for i, e := range w.Entries {
+		require.Equal(t, seckeys[i], e.Secret)
+	}
+	require.NotEqual(t, plain.Entries[0].Address, w.Entries[0].Address)
+
+	// The passphrase is a secret of encrypted wallets
+	require.NoError(t, w.Lock([]byte("pwd"), CryptoTypeSha256Xor))
+	_, ok = w.Meta[metaSeedPassphrase]
+	require.False(t, ok)
+
+	wlt, err := w.Unlock([]byte("pwd"))
+	require.NoError(t, err)
+	require.Equal(t, "TREZOR", wlt.seedPassphrase())
+
+	// Addresses regenerated after a reset are the same
+	addrs := wlt.GetAddresses()
+	wlt.reset()
+	_, err = wlt.GenerateAddresses(2)
+	require.NoError(t, err)
+	require.Equal(t, addrs, wlt.GetAddresses())
+
+	rw := NewReadableWallet(wlt)
+	rw.Erase()
+	_, ok = rw.Meta[metaSeedPassphrase]
+	require.False(t, ok)
+}
+
+func TestNewWatchOnlyWallet(t *testing.T) {
+	_, err := NewWatchOnlyWallet("t.wlt", "label", nil)
+	require.Equal(t, ErrMissingWatchAddresses, err)
+
+	_, err = NewWatchOnlyWallet("t.wlt", "label", []cipher.Address{{}})
+	require.Equal(t, NewError(errors.New("watched address must not be the null address")), err)
+
+	_, seckeys := cipher.MustGenerateDeterministicKeyPairsSeed([]byte("seed"), 3)
+	addrs := make([]cipher.Address, len(seckeys))
+	for i, s := range seckeys {
+		addrs[i] = cipher.MustAddressFromSecKey(s)
+	}
+
+	// Duplicate addresses are ignored
+	w, err := NewWatchOnlyWallet("t.wlt", "label", []cipher.Address{addrs[0], addrs[1], addrs[0]})
+	require.NoError(t, err)
+	require.True(t, w.IsWatchOnly())
+	require.Equal(t, WalletTypeWatchOnly, w.Type())
+	require.Equal(t, "label", w.Label())
+	wltAddrs, err := w.GetSkycoinAddresses()
+	require.NoError(t, err)
+	require.Equal(t, addrs[:2], wltAddrs)
+	require.Empty(t, w.seed())
+	require.NoError(t, w.Validate())
+
+	// The wallet has no keys
+	_, err = w.GenerateAddresses(1)
+	require.Equal(t, ErrWalletWatchOnly, err)
+	err = w.Lock([]byte("pwd"), CryptoTypeSha256Xor)
+	require.Equal(t, ErrWalletWatchOnly, err)
+
+	require.NoError(t, w.AddWatchOnlyAddresses(addrs[1:]))
+	wltAddrs, err = w.GetSkycoinAddresses()
+	require.NoError(t, err)
+	require.Equal(t, addrs, wltAddrs)
+
+	// Only watch-only wallets can watch addresses
+	sw, err := NewWallet("t.wlt", Options{
+		Seed: "seed",
+	})
+	require.NoError(t, err)
+	require.False(t, sw.IsWatchOnly())
+	err = sw.AddWatchOnlyAddresses(addrs)
+	require.Equal(t, ErrWalletNotWatchOnly, err)
+
+	// The wallet is saved without public keys
+	dir, err := ioutil.TempDir("", "watch-only")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, w.Save(dir))
+	lw, err := Load(filepath.Join(dir, "t.wlt"))
+	require.NoError(t, err)
+	require.True(t, lw.IsWatchOnly())
+	require.Equal(t, w.Entries, lw.Entries)
+}
+
+func TestWatchOnlyWalletCreateTransaction(t *testing.T) {
+	headTime := uint64(time.Now().UTC().Unix())
+	_, seckeys := cipher.MustGenerateDeterministicKeyPairsSeed([]byte("seed"), 1)
+	addr := cipher.MustAddressFromSecKey(seckeys[0])
+
+	w, err := NewWatchOnlyWallet("t.wlt", "", []cipher.Address{addr})
+	require.NoError(t, err)
+
+	uxout := makeUxOut(t, seckeys[0], 2e6, 100)
+	auxs := coin.AddressUxOuts{
+		addr: []coin.UxOut{uxout},
+	}
+
+	p := CreateTransactionParams{
+		HoursSelection: HoursSelection{
+			Type: HoursSelectionTypeManual,
+		},
+		Wallet: CreateTransactionWalletParams{
+			ID: "t.wlt",
+		},
+		To: []coin.TransactionOutput{
+			{
+				Address: testutil.MakeAddress(),
+				Coins:   1e6,
+				Hours:   10,
+			},
+		},
+	}
+
+	// A watch-only wallet can not sign
+	_, _, err = w.CreateAndSignTransactionAdvanced(p, auxs, headTime)
+	require.Equal(t, ErrWalletWatchOnly, err)
+
+	// A password must not be given for an unsigned transaction
+	p.Unsigned = true
+	p.Wallet.Password = []byte("pwd")
+	_, _, err = w.CreateAndSignTransactionAdvanced(p, auxs, headTime)
+	require.Equal(t, ErrPasswordUnsigned, err)
+
+	p.Wallet.Password = nil
+	txn, inputs, err := w.CreateAndSignTransactionAdvanced(p, auxs, headTime)
+	require.NoError(t, err)
+	require.Len(t, inputs, 1)
+	require.Equal(t, []cipher.SHA256{uxout.Hash()}, txn.In)
+	require.Equal(t, []cipher.Sig{{}}, txn.Sigs)
+	require.False(t, txn.IsFullySigned())
+	require.NoError(t, txn.VerifyUnsigned())
+	require.NoError(t, txn.VerifyInputUnsigned(coin.UxArray{uxout}))
+}
+
 func TestWalletLock(t *testing.T) {
 	tt := []struct {
 		name    string
@@ -855,6 +1035,88 @@ func TestWalletGuard(t *testing.T) {
 	}
 }
 
+func TestWalletKDFParams(t *testing.T) {
+	argon2id := encrypt.Argon2idChacha20poly1305{
+		Time:    1,
+		Memory:  64,
+		Threads: 1,
+		KeyLen:  32,
+	}
+
+	w, err := NewWallet("t.wlt", Options{
+		Seed:       "seed",
+		Encrypt:    true,
+		Password:   []byte("pwd"),
+		CryptoType: CryptoTypeArgon2idChacha20poly1305,
+		Argon2id:   argon2id,
+	})
+	require.NoError(t, err)
+
+	p, err := w.KDFParams()
+	require.NoError(t, err)
+	require.Equal(t, &KDFParams{
+		CryptoType: CryptoTypeArgon2idChacha20poly1305,
+		Argon2id:   &argon2id,
+	}, p)
+	require.Equal(t, "argon2id", p.KDF())
+
+	// The argon2id parameters are kept when the wallet is encrypted again
+	err = w.GuardUpdate([]byte("pwd"), func(w *Wallet) error {
+		w.setLabel("label")
+		return nil
+	})
+	require.NoError(t, err)
+	p, err = w.KDFParams()
+	require.NoError(t, err)
+	require.Equal(t, &argon2id, p.Argon2id)
+
+	// The default parameters are used if none are given
+	w, err = NewWallet("t.wlt", Options{
+		Seed:       "seed",
+		Encrypt:    true,
+		Password:   []byte("pwd"),
+		CryptoType: CryptoTypeArgon2idChacha20poly1305,
+	})
+	require.NoError(t, err)
+	p, err = w.KDFParams()
+	require.NoError(t, err)
+	require.Equal(t, &encrypt.DefaultArgon2idChacha20poly1305, p.Argon2id)
+
+	w = makeWallet(t, Options{
+		Seed:       "seed",
+		Encrypt:    true,
+		Password:   []byte("pwd"),
+		CryptoType: CryptoTypeSha256Xor,
+	}, 1)
+	p, err = w.KDFParams()
+	require.NoError(t, err)
+	require.Equal(t, &KDFParams{CryptoType: CryptoTypeSha256Xor}, p)
+	require.Equal(t, "sha256", p.KDF())
+
+	// Invalid argon2id parameters
+	_, err = NewWallet("t.wlt", Options{
+		Seed:       "seed",
+		Encrypt:    true,
+		Password:   []byte("pwd"),
+		CryptoType: CryptoTypeArgon2idChacha20poly1305,
+		Argon2id: encrypt.Argon2idChacha20poly1305{
+			Time:    1,
+			Memory:  64,
+			Threads: 1,
+			KeyLen:  16,
+		},
+	})
+	require.Error(t, err)
+	require.IsType(t, Error{}, err)
+
+	w, err = NewWallet("t.wlt", Options{
+		Seed: "seed",
+	})
+	require.NoError(t, err)
+	_, err = w.KDFParams()
+	require.Equal(t, ErrWalletNotEncrypted, err)
+}
+
 type distributeSpendHoursTestCase struct {
 	name              string
 	inputHours        uint64
@@ -1534,6 +1796,175 @@ func TestWalletChooseSpendsMinimizeUxOutsRandom(t *testing.T) {
 	}
 }
 
+func TestWalletChooseSpendsStrategies(t *testing.T) {
+	uxb := []UxBalance{
+		{
+			Hash:  cipher.SHA256{1},
+			BkSeq: 5,
+			Coins: 10e6,
+			Hours: 100,
+		},
+		{
+			Hash:  cipher.SHA256{2},
+			BkSeq: 1,
+			Coins: 2e6,
+			Hours: 0,
+		},
+		{
+			Hash:  cipher.SHA256{3},
+			BkSeq: 3,
+			Coins: 5e6,
+			Hours: 4,
+		},
+		{
+			Hash:  cipher.SHA256{4},
+			BkSeq: 2,
+			Coins: 1e6,
+			Hours: 50,
+		},
+	}
+
+	randomSeed1 := func(uxa []UxBalance, coins, hours uint64) ([]UxBalance, error) {
+		return ChooseSpendsRandom(uxa, coins, hours, rand.New(rand.NewSource(1)))
+	}
+
+	cases := []struct {
+		name    string
+		choose  func([]UxBalance, uint64, uint64) ([]UxBalance, error)
+		uxb     []UxBalance
+		coins   uint64
+		hours   uint64
+		spends  []UxBalance
+		err     error
+		checkFn func(*testing.T, []UxBalance)
+	}{
+		{
+			name:   "minimize burn",
+			choose: ChooseSpendsMinimizeBurn,
+			uxb:    uxb,
+			coins:  6e6,
+			spends: []UxBalance{uxb[1], uxb[2]},
+		},
+		{
+			name:   "minimize burn hours requested",
+			choose: ChooseSpendsMinimizeBurn,
+			uxb:    uxb,
+			coins:  6e6,
+			hours:  20,
+			spends: []UxBalance{uxb[1], uxb[2], uxb[3]},
+		},
+		{
+			name:   "minimize burn insufficient hours",
+			choose: ChooseSpendsMinimizeBurn,
+			uxb:    uxb,
+			coins:  6e6,
+			hours:  1000,
+			err:    ErrInsufficientHours,
+		},
+		{
+			name:   "oldest first",
+			choose: ChooseSpendsOldestFirst,
+			uxb:    uxb,
+			coins:  6e6,
+			spends: []UxBalance{uxb[1], uxb[3], uxb[2]},
+		},
+		{
+			name:   "oldest first insufficient balance",
+			choose: ChooseSpendsOldestFirst,
+			uxb:    uxb,
+			coins:  20e6,
+			err:    ErrInsufficientBalance,
+		},
+		{
+			name:   "exact",
+			choose: ChooseSpendsExact,
+			uxb:    uxb,
+			coins:  6e6,
+			spends: uxb,
+		},
+		{
+			name:   "exact insufficient balance",
+			choose: ChooseSpendsExact,
+			uxb:    uxb[1:3],
+			coins:  8e6,
+			err:    ErrInsufficientBalance,
+		},
+		{
+			name:   "exact insufficient hours",
+			choose: ChooseSpendsExact,
+			uxb:    uxb[1:3],
+			coins:  6e6,
+			hours:  3,
+			err:    ErrInsufficientHours,
+		},
+		{
+			name:   "random",
+			choose: randomSeed1,
+			uxb:    uxb,
+			coins:  6e6,
+			checkFn: func(t *testing.T, spends []UxBalance) {
+				// The same seed chooses the same uxouts, regardless of their order
+				reversed := []UxBalance{uxb[3], uxb[2], uxb[1], uxb[0]}
+				again, err := randomSeed1(reversed, 6e6, 0)
+				require.NoError(t, err)
+				require.Equal(t, spends, again)
+
+				var have Balance
+				for _, ux := range spends {
+					have.Coins += ux.Coins
+					have.Hours += ux.Hours
+				}
+				require.True(t, have.Coins >= 6e6)
+				require.NotEqual(t, uint64(0), have.Hours)
+			},
+		},
+		{
+			name:   "zero spend",
+			choose: randomSeed1,
+			uxb:    uxb,
+			err:    ErrZeroSpend,
+		},
+		{
+			name:   "no unspents",
+			choose: ChooseSpendsOldestFirst,
+			uxb:    []UxBalance{},
+			coins:  1e6,
+			err:    ErrNoUnspents,
+		},
+		{
+			name:   "no hours",
+			choose: ChooseSpendsMinimizeBurn,
+			uxb:    uxb[1:2],
+			coins:  1e6,
+			err:    fee.ErrTxnNoFee,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			orig := make([]UxBalance, len(tc.uxb))
+			copy(orig, tc.uxb)
+
+			spends, err := tc.choose(tc.uxb, tc.coins, tc.hours)
+
+			// The uxouts are not reordered
+			require.Equal(t, orig, tc.uxb)
+
+			if tc.err != nil {
+				require.Equal(t, tc.err, err)
+				return
+			}
+
+			require.NoError(t, err)
+			if tc.checkFn != nil {
+				tc.checkFn(t, spends)
+				return
+			}
+			require.Equal(t, tc.spends, spends)
+		})
+	}
+}
+
 func TestRemoveBackupFiles(t *testing.T) {
 	type wltInfo struct {
 		wltName string
@@ -20,31 +20,9 @@ type Wallets map[string]*Wallet
 // dir fails to load, loading is aborted and error returned.  Only files with
 // extension WalletExt are considered.
 func LoadWallets(dir string) (Wallets, error) {
-	// TODO -- return error if duplicate wallet (by first address) is found
-	// TODO -- but make sure that the client has a good warning to the user if wallet loading fails
-	entries, err := ioutil.ReadDir(dir)
-	if err != nil {
-		return nil, err
-	}
-
-	wallets := Wallets{}
-	for _, e := range entries {
-		if e.Mode().IsRegular() {
-			name := e.Name()
-			if !strings.HasSuffix(name, WalletExt) {
-				continue
-			}
-
-			fullpath := filepath.Join(dir, name)
-			w, err := loadWallet(fullpath)
-			if err != nil {
-				return nil, err
-			}
-
-			wallets[name] = w
-		}
-	}
-	return wallets, nil
+	return loadStorageWallets(&FileStorage{
+		dir: dir,
+	})
 }
 
 func loadWallet(fn string) (*Wallet, error) {